@@ -31,7 +31,10 @@ type RequestHeader struct {
 	// username is a username that is associated with an auth token of gRPC connection
 	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
 	// auth_revision is a revision number of auth.authStore. It is not related to mvcc
-	AuthRevision         uint64   `protobuf:"varint,3,opt,name=auth_revision,json=authRevision,proto3" json:"auth_revision,omitempty"`
+	AuthRevision uint64 `protobuf:"varint,3,opt,name=auth_revision,json=authRevision,proto3" json:"auth_revision,omitempty"`
+	// roles are the roles of an identity authenticated without being an etcd user, e.g. mapped
+	// from the claims of an OIDC token, for the permissions to be checked against them
+	Roles                []string `protobuf:"bytes,4,rep,name=roles,proto3" json:"roles,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -238,73 +241,74 @@ func init() {
 func init() { proto.RegisterFile("raft_internal.proto", fileDescriptor_b4c9a9be0cfca103) }
 
 var fileDescriptor_b4c9a9be0cfca103 = []byte{
-	// 1054 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x56, 0x5d, 0x6f, 0x1b, 0x45,
-	0x14, 0xad, 0xd3, 0x34, 0x89, 0xc7, 0x49, 0x9a, 0x4e, 0x52, 0x3a, 0x38, 0x92, 0x71, 0x03, 0x2d,
-	0x01, 0x8a, 0x53, 0x1c, 0x78, 0xe1, 0x05, 0x5c, 0x3b, 0x4a, 0x83, 0x4a, 0x15, 0x6d, 0x0b, 0xaa,
-	0x84, 0xd0, 0x32, 0xde, 0xbd, 0xb1, 0xb7, 0x59, 0xef, 0x2e, 0x33, 0x63, 0x37, 0x7d, 0xe5, 0x91,
-	0x67, 0x40, 0xfc, 0x0c, 0x3e, 0xff, 0x43, 0x85, 0xf8, 0x28, 0xf0, 0x07, 0x20, 0xbc, 0xf0, 0x0e,
-	0xbc, 0xa3, 0xf9, 0xd8, 0x5d, 0xaf, 0x3d, 0xce, 0xdb, 0xfa, 0xde, 0x73, 0xcf, 0x39, 0x33, 0x73,
-	0xef, 0x78, 0xd0, 0x3a, 0xa3, 0x47, 0xc2, 0x0d, 0x22, 0x01, 0x2c, 0xa2, 0x61, 0x23, 0x61, 0xb1,
-	0x88, 0xf1, 0x32, 0x08, 0xcf, 0xe7, 0xc0, 0x46, 0xc0, 0x92, 0x6e, 0x75, 0xa3, 0x17, 0xf7, 0x62,
-	0x95, 0xd8, 0x91, 0x5f, 0x1a, 0x53, 0x5d, 0xcb, 0x31, 0x26, 0x52, 0x66, 0x89, 0x67, 0x3e, 0xeb,
-	0x32, 0xb9, 0x43, 0x93, 0x60, 0x67, 0x04, 0x8c, 0x07, 0x71, 0x94, 0x74, 0xd3, 0x2f, 0x83, 0xb8,
-	0x9e, 0x21, 0x06, 0x30, 0xe8, 0x02, 0xe3, 0xfd, 0x20, 0x49, 0xba, 0x63, 0x3f, 0x34, 0x6e, 0x8b,
-	0xa1, 0x15, 0x07, 0x3e, 0x1e, 0x02, 0x17, 0xb7, 0x81, 0xfa, 0xc0, 0xf0, 0x2a, 0x9a, 0x3b, 0xe8,
-	0x90, 0x52, 0xbd, 0xb4, 0x3d, 0xef, 0xcc, 0x1d, 0x74, 0x70, 0x15, 0x2d, 0x0d, 0xb9, 0x34, 0x3f,
-	0x00, 0x32, 0x57, 0x2f, 0x6d, 0x97, 0x9d, 0xec, 0x37, 0xbe, 0x81, 0x56, 0xe8, 0x50, 0xf4, 0x5d,
-	0x06, 0xa3, 0x40, 0x6a, 0x93, 0xf3, 0xb2, 0xec, 0xd6, 0xe2, 0xa7, 0xdf, 0x93, 0xf3, 0xbb, 0x8d,
-	0xd7, 0x9c, 0x65, 0x99, 0x75, 0x4c, 0xf2, 0xcd, 0xc5, 0x4f, 0x54, 0xf8, 0xe6, 0xd6, 0x0f, 0x18,
-	0xad, 0x1f, 0x98, 0x1d, 0x71, 0xe8, 0x91, 0x30, 0x06, 0xf0, 0x2e, 0x5a, 0xe8, 0x2b, 0x13, 0xc4,
-	0xaf, 0x97, 0xb6, 0x2b, 0xcd, 0xcd, 0xc6, 0xf8, 0x3e, 0x35, 0x0a, 0x3e, 0x1d, 0x03, 0x9d, 0xf2,
-	0x7b, 0x0d, 0xcd, 0x8d, 0x9a, 0xca, 0x69, 0xa5, 0x79, 0xd9, 0x4a, 0xe0, 0xcc, 0x8d, 0x9a, 0xf8,
-	0x26, 0xba, 0xc0, 0x68, 0xd4, 0x03, 0x65, 0xb9, 0xd2, 0xac, 0x4e, 0x20, 0x65, 0x2a, 0x85, 0x6b,
-	0x20, 0x7e, 0x19, 0x9d, 0x4f, 0x86, 0x82, 0xcc, 0x2b, 0x3c, 0x29, 0xe2, 0x0f, 0x87, 0xe9, 0x22,
-	0x1c, 0x09, 0xc2, 0x6d, 0xb4, 0xec, 0x43, 0x08, 0x02, 0x5c, 0x2d, 0x72, 0x41, 0x15, 0xd5, 0x8b,
-	0x45, 0x1d, 0x85, 0x28, 0x48, 0x55, 0xfc, 0x3c, 0x26, 0x05, 0xc5, 0x49, 0x44, 0x16, 0x6c, 0x82,
-	0xf7, 0x4f, 0xa2, 0x4c, 0x50, 0x9c, 0x44, 0xf8, 0x2d, 0x84, 0xbc, 0x78, 0x90, 0x50, 0x4f, 0xc8,
-	0x63, 0x58, 0x54, 0x25, 0xcf, 0x15, 0x4b, 0xda, 0x59, 0x3e, 0xad, 0x1c, 0x2b, 0xc1, 0x6f, 0xa3,
-	0x4a, 0x08, 0x94, 0x83, 0xdb, 0x63, 0x34, 0x12, 0x64, 0xc9, 0xc6, 0x70, 0x47, 0x02, 0xf6, 0x65,
-	0x3e, 0x63, 0x08, 0xb3, 0x90, 0x5c, 0xb3, 0x66, 0x60, 0x30, 0x8a, 0x8f, 0x81, 0x94, 0x6d, 0x6b,
-	0x56, 0x14, 0x8e, 0x02, 0x64, 0x6b, 0x0e, 0xf3, 0x98, 0x3c, 0x16, 0x1a, 0x52, 0x36, 0x20, 0xc8,
-	0x76, 0x2c, 0x2d, 0x99, 0xca, 0x8e, 0x45, 0x01, 0xf1, 0x03, 0xb4, 0xa6, 0x65, 0xbd, 0x3e, 0x78,
-	0xc7, 0x49, 0x1c, 0x44, 0x82, 0x54, 0x54, 0xf1, 0x0b, 0x16, 0xe9, 0x76, 0x06, 0x32, 0x34, 0x69,
-	0xb3, 0xbe, 0xee, 0x5c, 0x0c, 0x8b, 0x00, 0xdc, 0x42, 0x15, 0xd5, 0xdd, 0x10, 0xd1, 0x6e, 0x08,
-	0xe4, 0x6f, 0xeb, 0xae, 0xb6, 0x86, 0xa2, 0xbf, 0xa7, 0x00, 0xd9, 0x9e, 0xd0, 0x2c, 0x84, 0x3b,
-	0x48, 0x8d, 0x80, 0xeb, 0x07, 0x5c, 0x71, 0xfc, 0xb3, 0x68, 0xdb, 0x14, 0xc9, 0xd1, 0xd1, 0x88,
-	0x6c, 0x53, 0x68, 0x1e, 0xc3, 0xef, 0x18, 0x23, 0x5c, 0x50, 0x31, 0xe4, 0xe4, 0xbf, 0x99, 0x46,
-	0xee, 0x29, 0xc0, 0xc4, 0xca, 0xde, 0xd0, 0x8e, 0x74, 0x0e, 0xdf, 0xd5, 0x8e, 0x20, 0x12, 0x81,
-	0x47, 0x05, 0x90, 0x7f, 0x35, 0xd9, 0x4b, 0x45, 0xb2, 0x74, 0x3a, 0x5b, 0x63, 0xd0, 0xd4, 0x5a,
-	0xa1, 0x1e, 0xef, 0x99, 0x2b, 0x40, 0xde, 0x09, 0x2e, 0xf5, 0x7d, 0xf2, 0xe3, 0xd2, 0xac, 0x25,
-	0xbe, 0xc7, 0x81, 0xb5, 0x7c, 0xbf, 0xb0, 0x44, 0x13, 0xc3, 0x77, 0xd1, 0x5a, 0x4e, 0xa3, 0x87,
-	0x80, 0xfc, 0xa4, 0x99, 0x9e, 0xb7, 0x33, 0x99, 0xe9, 0x31, 0x64, 0xab, 0xb4, 0x10, 0x2e, 0xda,
-	0xea, 0x81, 0x20, 0x3f, 0x9f, 0x69, 0x6b, 0x1f, 0xc4, 0x94, 0xad, 0x7d, 0x10, 0xb8, 0x87, 0x9e,
-	0xcd, 0x69, 0xbc, 0xbe, 0x1c, 0x4b, 0x37, 0xa1, 0x9c, 0x3f, 0x8a, 0x99, 0x4f, 0x7e, 0xd1, 0x94,
-	0xaf, 0xd8, 0x29, 0xdb, 0x0a, 0x7d, 0x68, 0xc0, 0x29, 0xfb, 0x33, 0xd4, 0x9a, 0xc6, 0x0f, 0xd0,
-	0xc6, 0x98, 0x5f, 0x39, 0x4f, 0x2e, 0x8b, 0x43, 0x20, 0x4f, 0xb5, 0xc6, 0xf5, 0x19, 0xb6, 0xd5,
-	0x2c, 0xc6, 0x79, 0xdb, 0x5c, 0xa2, 0x93, 0x19, 0xfc, 0x01, 0xba, 0x9c, 0x33, 0xeb, 0xd1, 0xd4,
-	0xd4, 0xbf, 0x6a, 0xea, 0x17, 0xed, 0xd4, 0x66, 0x46, 0xc7, 0xb8, 0x31, 0x9d, 0x4a, 0xe1, 0xdb,
-	0x68, 0x35, 0x27, 0x0f, 0x03, 0x2e, 0xc8, 0x6f, 0x9a, 0xf5, 0xaa, 0x9d, 0xf5, 0x4e, 0xc0, 0x45,
-	0xa1, 0x8f, 0xd2, 0x60, 0xc6, 0x24, 0xad, 0x69, 0xa6, 0xdf, 0x67, 0x32, 0x49, 0xe9, 0x29, 0xa6,
-	0x34, 0x98, 0x1d, 0xbd, 0x62, 0x92, 0x1d, 0xf9, 0x55, 0x79, 0xd6, 0xd1, 0xcb, 0x9a, 0xc9, 0x8e,
-	0x34, 0xb1, 0xac, 0x23, 0x15, 0x8d, 0xe9, 0xc8, 0xaf, 0xcb, 0xb3, 0x3a, 0x52, 0x56, 0x59, 0x3a,
-	0x32, 0x0f, 0x17, 0x6d, 0xc9, 0x8e, 0xfc, 0xe6, 0x4c, 0x5b, 0x93, 0x1d, 0x69, 0x62, 0xf8, 0x21,
-	0xaa, 0x8e, 0xd1, 0xa8, 0x46, 0x49, 0x80, 0x0d, 0x02, 0xae, 0xfe, 0x7f, 0xbf, 0xd5, 0x9c, 0x37,
-	0x66, 0x70, 0x4a, 0xf8, 0x61, 0x86, 0x4e, 0xf9, 0xaf, 0x50, 0x7b, 0x1e, 0x0f, 0xd0, 0x66, 0xae,
-	0x65, 0x5a, 0x67, 0x4c, 0xec, 0x3b, 0x2d, 0xf6, 0xaa, 0x5d, 0x4c, 0x77, 0xc9, 0xb4, 0x1a, 0xa1,
-	0x33, 0x00, 0xf8, 0x23, 0xb4, 0xee, 0x85, 0x43, 0x2e, 0x80, 0xb9, 0xe6, 0x2d, 0xe3, 0x72, 0x10,
-	0xe4, 0x33, 0x64, 0x46, 0x60, 0xfc, 0x21, 0xd3, 0x68, 0x6b, 0xe4, 0xfb, 0x1a, 0x78, 0x0f, 0xc4,
-	0xd4, 0xad, 0x77, 0xc9, 0x9b, 0x84, 0xe0, 0x87, 0xe8, 0x4a, 0xaa, 0xa0, 0xc9, 0x5c, 0x2a, 0x04,
-	0x53, 0x2a, 0x9f, 0x23, 0x73, 0x0f, 0xda, 0x54, 0xde, 0x55, 0xb1, 0x96, 0x10, 0xcc, 0x26, 0xb4,
-	0xe1, 0x59, 0x50, 0xf8, 0x43, 0x84, 0xfd, 0xf8, 0x51, 0xd4, 0x63, 0xd4, 0x07, 0x37, 0x88, 0x8e,
-	0x62, 0x25, 0xf3, 0x85, 0x96, 0xb9, 0x56, 0x94, 0xe9, 0xa4, 0xc0, 0x83, 0xe8, 0x28, 0xb6, 0x49,
-	0xac, 0xf9, 0x13, 0x88, 0xfc, 0x31, 0x75, 0x11, 0xad, 0xec, 0x0d, 0x12, 0xf1, 0xd8, 0x01, 0x9e,
-	0xc4, 0x11, 0x87, 0xad, 0xc7, 0x68, 0xf3, 0x8c, 0xeb, 0x1b, 0x63, 0x34, 0xaf, 0xde, 0x72, 0x25,
-	0xf5, 0x96, 0x53, 0xdf, 0xf2, 0x8d, 0x97, 0xdd, 0x6a, 0xe6, 0x8d, 0x97, 0xfe, 0xc6, 0x57, 0xd1,
-	0x32, 0x0f, 0x06, 0x49, 0x08, 0xae, 0x88, 0x8f, 0x41, 0x3f, 0xf1, 0xca, 0x4e, 0x45, 0xc7, 0xee,
-	0xcb, 0x50, 0xe6, 0xe5, 0xd6, 0xc6, 0x93, 0x3f, 0x6b, 0xe7, 0x9e, 0x9c, 0xd6, 0x4a, 0x4f, 0x4f,
-	0x6b, 0xa5, 0x3f, 0x4e, 0x6b, 0xa5, 0x2f, 0xff, 0xaa, 0x9d, 0xeb, 0x2e, 0xa8, 0x97, 0xe6, 0xee,
-	0xff, 0x01, 0x00, 0x00, 0xff, 0xff, 0x30, 0x36, 0x53, 0xc6, 0x0b, 0x0b, 0x00, 0x00,
+	// 1070 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x56, 0x4b, 0x73, 0x1b, 0x45,
+	0x10, 0x8e, 0xfc, 0xd6, 0xc8, 0x76, 0x9c, 0xb1, 0x43, 0x06, 0xbb, 0x30, 0x8a, 0x21, 0xc1, 0x40,
+	0xb0, 0x83, 0x0d, 0x1c, 0xb8, 0x80, 0x22, 0xb9, 0x1c, 0x53, 0x21, 0xe5, 0xda, 0x04, 0x2a, 0x55,
+	0x14, 0xb5, 0x8c, 0x76, 0xdb, 0xd2, 0xc6, 0xab, 0xdd, 0x65, 0x66, 0xa4, 0x38, 0x57, 0x8e, 0xdc,
+	0xa8, 0x02, 0x8a, 0x9f, 0xc1, 0xf3, 0x3f, 0xa4, 0x28, 0x1e, 0x01, 0xfe, 0x00, 0x98, 0x0b, 0x77,
+	0xe0, 0x9e, 0x9a, 0xc7, 0xbe, 0xa4, 0x91, 0x6f, 0xda, 0xee, 0xaf, 0xbf, 0xaf, 0x7b, 0xa6, 0x7b,
+	0xd4, 0x68, 0x99, 0xd1, 0x23, 0xe1, 0x06, 0x91, 0x00, 0x16, 0xd1, 0x70, 0x2b, 0x61, 0xb1, 0x88,
+	0xf1, 0x3c, 0x08, 0xcf, 0xe7, 0xc0, 0x06, 0xc0, 0x92, 0xf6, 0xea, 0x4a, 0x27, 0xee, 0xc4, 0xca,
+	0xb1, 0x2d, 0x7f, 0x69, 0xcc, 0xea, 0x52, 0x8e, 0x31, 0x96, 0x2a, 0x4b, 0x3c, 0xf3, 0xb3, 0x2e,
+	0x9d, 0xdb, 0x34, 0x09, 0xb6, 0x07, 0xc0, 0x78, 0x10, 0x47, 0x49, 0x3b, 0xfd, 0x65, 0x10, 0x57,
+	0x33, 0x44, 0x0f, 0x7a, 0x6d, 0x60, 0xbc, 0x1b, 0x24, 0x49, 0xbb, 0xf0, 0xa1, 0x71, 0x1b, 0x9f,
+	0x55, 0xd0, 0x82, 0x03, 0x1f, 0xf7, 0x81, 0x8b, 0x9b, 0x40, 0x7d, 0x60, 0x78, 0x11, 0x4d, 0x1c,
+	0xb4, 0x48, 0xa5, 0x5e, 0xd9, 0x9c, 0x72, 0x26, 0x0e, 0x5a, 0x78, 0x15, 0xcd, 0xf5, 0xb9, 0xcc,
+	0xbe, 0x07, 0x64, 0xa2, 0x5e, 0xd9, 0xac, 0x3a, 0xd9, 0x37, 0xbe, 0x86, 0x16, 0x68, 0x5f, 0x74,
+	0x5d, 0x06, 0x83, 0x40, 0x8a, 0x93, 0x49, 0x19, 0x76, 0x63, 0xf6, 0xd3, 0x1f, 0xc8, 0xe4, 0xee,
+	0xd6, 0xab, 0xce, 0xbc, 0xf4, 0x3a, 0xc6, 0x89, 0x9f, 0x41, 0xd3, 0x2c, 0x0e, 0x81, 0x93, 0xa9,
+	0xfa, 0xe4, 0x66, 0x35, 0x45, 0xbd, 0xe1, 0x68, 0xeb, 0x9b, 0xb3, 0x9f, 0xa8, 0xef, 0xeb, 0x1b,
+	0x3f, 0x62, 0xb4, 0x7c, 0x60, 0x4e, 0xcc, 0xa1, 0x47, 0xc2, 0xe4, 0x87, 0x77, 0xd1, 0x4c, 0x57,
+	0xe5, 0x48, 0xfc, 0x7a, 0x65, 0xb3, 0xb6, 0xb3, 0xb6, 0x55, 0x3c, 0xc7, 0xad, 0x52, 0x19, 0xce,
+	0x4c, 0xd7, 0x5e, 0xce, 0x15, 0x34, 0x31, 0xd8, 0x51, 0x85, 0xd4, 0x76, 0x2e, 0x5a, 0x09, 0x9c,
+	0x89, 0xc1, 0x0e, 0xbe, 0x8e, 0xa6, 0x19, 0x8d, 0x3a, 0xa0, 0x2a, 0xaa, 0xed, 0xac, 0x0e, 0x21,
+	0xa5, 0x2b, 0x85, 0x6b, 0x20, 0x7e, 0x09, 0x4d, 0x26, 0x7d, 0x41, 0xa6, 0x14, 0x9e, 0x94, 0xf1,
+	0x87, 0xfd, 0xb4, 0x08, 0x47, 0x82, 0x70, 0x13, 0xcd, 0xfb, 0x10, 0x82, 0x00, 0x57, 0x8b, 0x4c,
+	0xab, 0xa0, 0x7a, 0x39, 0xa8, 0xa5, 0x10, 0x25, 0xa9, 0x9a, 0x9f, 0xdb, 0xa4, 0xa0, 0x38, 0x89,
+	0xc8, 0x8c, 0x4d, 0xf0, 0xee, 0x49, 0x94, 0x09, 0x8a, 0x93, 0x08, 0xbf, 0x85, 0x90, 0x17, 0xf7,
+	0x12, 0xea, 0x09, 0x79, 0x4b, 0xb3, 0x2a, 0xe4, 0xd9, 0x72, 0x48, 0x33, 0xf3, 0xa7, 0x91, 0x85,
+	0x10, 0xfc, 0x36, 0xaa, 0x85, 0x40, 0x39, 0xb8, 0x1d, 0x46, 0x23, 0x41, 0xe6, 0x6c, 0x0c, 0xb7,
+	0x24, 0x60, 0x5f, 0xfa, 0x33, 0x86, 0x30, 0x33, 0xc9, 0x9a, 0x35, 0x03, 0x83, 0x41, 0x7c, 0x0c,
+	0xa4, 0x6a, 0xab, 0x59, 0x51, 0x38, 0x0a, 0x90, 0xd5, 0x1c, 0xe6, 0x36, 0x79, 0x2d, 0x34, 0xa4,
+	0xac, 0x47, 0x90, 0xed, 0x5a, 0x1a, 0xd2, 0x95, 0x5d, 0x8b, 0x02, 0xe2, 0x7b, 0x68, 0x49, 0xcb,
+	0x7a, 0x5d, 0xf0, 0x8e, 0x93, 0x38, 0x88, 0x04, 0xa9, 0xa9, 0xe0, 0xe7, 0x2d, 0xd2, 0xcd, 0x0c,
+	0x64, 0x68, 0xd2, 0x2e, 0x7d, 0xcd, 0x39, 0x1f, 0x96, 0x01, 0xb8, 0x81, 0x6a, 0xaa, 0xf9, 0x21,
+	0xa2, 0xed, 0x10, 0xc8, 0x3f, 0xd6, 0x53, 0x6d, 0xf4, 0x45, 0x77, 0x4f, 0x01, 0xb2, 0x33, 0xa1,
+	0x99, 0x09, 0xb7, 0x90, 0x9a, 0x10, 0xd7, 0x0f, 0xb8, 0xe2, 0xf8, 0x77, 0xd6, 0x76, 0x28, 0x92,
+	0xa3, 0x15, 0xf0, 0x22, 0x49, 0x8d, 0xe6, 0x36, 0xfc, 0x8e, 0x49, 0x84, 0x0b, 0x2a, 0xfa, 0x9c,
+	0xfc, 0x3f, 0x36, 0x91, 0x3b, 0x0a, 0x30, 0x54, 0xd9, 0xeb, 0x3a, 0x23, 0xed, 0xc3, 0xb7, 0x75,
+	0x46, 0x10, 0x89, 0xc0, 0xa3, 0x02, 0xc8, 0x7f, 0x9a, 0xec, 0xc5, 0x32, 0x59, 0x3a, 0x9d, 0x8d,
+	0x02, 0x34, 0x4d, 0xad, 0x14, 0x8f, 0xf7, 0xcc, 0x0b, 0xd1, 0xe7, 0xc0, 0x5c, 0xea, 0xfb, 0xe4,
+	0xa7, 0xb9, 0x71, 0x25, 0xbe, 0xc7, 0x81, 0x35, 0x7c, 0xbf, 0x54, 0xa2, 0xb1, 0xe1, 0xdb, 0x68,
+	0x29, 0xa7, 0xd1, 0x43, 0x40, 0x7e, 0xd6, 0x4c, 0xcf, 0xd9, 0x99, 0xcc, 0xf4, 0x18, 0xb2, 0x45,
+	0x5a, 0x32, 0x97, 0xd3, 0xea, 0x80, 0x20, 0xbf, 0x9c, 0x99, 0xd6, 0x3e, 0x88, 0x91, 0xb4, 0xf6,
+	0x41, 0xe0, 0x0e, 0x7a, 0x3a, 0xa7, 0xf1, 0xba, 0x72, 0x2c, 0xdd, 0x84, 0x72, 0xfe, 0x20, 0x66,
+	0x3e, 0xf9, 0x55, 0x53, 0xbe, 0x6c, 0xa7, 0x6c, 0x2a, 0xf4, 0xa1, 0x01, 0xa7, 0xec, 0x4f, 0x51,
+	0xab, 0x1b, 0xdf, 0x43, 0x2b, 0x85, 0x7c, 0xe5, 0x3c, 0xb9, 0xf2, 0xd1, 0x24, 0x8f, 0xb5, 0xc6,
+	0xd5, 0x31, 0x69, 0xab, 0x59, 0x8c, 0xf3, 0xb6, 0xb9, 0x40, 0x87, 0x3d, 0xf8, 0x03, 0x74, 0x31,
+	0x67, 0xd6, 0xa3, 0xa9, 0xa9, 0x7f, 0xd3, 0xd4, 0x2f, 0xd8, 0xa9, 0xcd, 0x8c, 0x16, 0xb8, 0x31,
+	0x1d, 0x71, 0xe1, 0x9b, 0x68, 0x31, 0x27, 0x0f, 0x03, 0x2e, 0xc8, 0xef, 0x9a, 0xf5, 0xb2, 0x9d,
+	0xf5, 0x56, 0xc0, 0x45, 0xa9, 0x8f, 0x52, 0x63, 0xc6, 0x24, 0x53, 0xd3, 0x4c, 0x7f, 0x8c, 0x65,
+	0x92, 0xd2, 0x23, 0x4c, 0xa9, 0x31, 0xbb, 0x7a, 0xc5, 0x24, 0x3b, 0xf2, 0xeb, 0xea, 0xb8, 0xab,
+	0x97, 0x31, 0xc3, 0x1d, 0x69, 0x6c, 0x59, 0x47, 0x2a, 0x1a, 0xd3, 0x91, 0xdf, 0x54, 0xc7, 0x75,
+	0xa4, 0x8c, 0xb2, 0x74, 0x64, 0x6e, 0x2e, 0xa7, 0x25, 0x3b, 0xf2, 0xdb, 0x33, 0xd3, 0x1a, 0xee,
+	0x48, 0x63, 0xc3, 0xf7, 0xd1, 0x6a, 0x81, 0x46, 0x35, 0x4a, 0x02, 0xac, 0x17, 0x70, 0xf5, 0xf7,
+	0xfc, 0x9d, 0xe6, 0xbc, 0x36, 0x86, 0x53, 0xc2, 0x0f, 0x33, 0x74, 0xca, 0x7f, 0x89, 0xda, 0xfd,
+	0xb8, 0x87, 0xd6, 0x72, 0x2d, 0xd3, 0x3a, 0x05, 0xb1, 0xef, 0xb5, 0xd8, 0x2b, 0x76, 0x31, 0xdd,
+	0x25, 0xa3, 0x6a, 0x84, 0x8e, 0x01, 0xe0, 0x8f, 0xd0, 0xb2, 0x17, 0xf6, 0xb9, 0x00, 0xe6, 0x9a,
+	0x5d, 0xc7, 0xe5, 0x20, 0xc8, 0xe7, 0xc8, 0x8c, 0x40, 0x71, 0xd1, 0xd9, 0x6a, 0x6a, 0xe4, 0xfb,
+	0x1a, 0x78, 0x07, 0xc4, 0xc8, 0xab, 0x77, 0xc1, 0x1b, 0x86, 0xe0, 0xfb, 0xe8, 0x52, 0xaa, 0xa0,
+	0xc9, 0x5c, 0x2a, 0x04, 0x53, 0x2a, 0x5f, 0x20, 0xf3, 0x0e, 0xda, 0x54, 0xde, 0x55, 0xb6, 0x86,
+	0x10, 0xcc, 0x26, 0xb4, 0xe2, 0x59, 0x50, 0xf8, 0x43, 0x84, 0xfd, 0xf8, 0x41, 0xd4, 0x61, 0xd4,
+	0x07, 0x37, 0x88, 0x8e, 0x62, 0x25, 0xf3, 0xa5, 0x96, 0xb9, 0x52, 0x96, 0x69, 0xa5, 0xc0, 0x83,
+	0xe8, 0x28, 0xb6, 0x49, 0x2c, 0xf9, 0x43, 0x88, 0x7c, 0x99, 0x3a, 0x8f, 0x16, 0xf6, 0x7a, 0x89,
+	0x78, 0xe8, 0x00, 0x4f, 0xe2, 0x88, 0xc3, 0xc6, 0x43, 0xb4, 0x76, 0xc6, 0xf3, 0x8d, 0x31, 0x9a,
+	0x52, 0xab, 0x5e, 0x45, 0xad, 0x7a, 0xea, 0xb7, 0x5c, 0x01, 0xb3, 0x57, 0xcd, 0xac, 0x80, 0xe9,
+	0x37, 0xbe, 0x8c, 0xe6, 0x79, 0xd0, 0x4b, 0x42, 0x70, 0x45, 0x7c, 0x0c, 0x7a, 0x03, 0xac, 0x3a,
+	0x35, 0x6d, 0xbb, 0x2b, 0x4d, 0x59, 0x2e, 0x37, 0x56, 0x1e, 0xfd, 0xb5, 0x7e, 0xee, 0xd1, 0xe9,
+	0x7a, 0xe5, 0xf1, 0xe9, 0x7a, 0xe5, 0xcf, 0xd3, 0xf5, 0xca, 0x57, 0x7f, 0xaf, 0x9f, 0x6b, 0xcf,
+	0xa8, 0x4d, 0x74, 0xf7, 0xc9, 0x00, 0x59, 0x5a, 0x16, 0xa3, 0x2b, 0x0b, 0x00, 0x00,
 }
 
 func (m *RequestHeader) Marshal() (dAtA []byte, err error) {
@@ -331,6 +335,15 @@ func (m *RequestHeader) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if len(m.Roles) > 0 {
+		for iNdEx := len(m.Roles) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Roles[iNdEx])
+			copy(dAtA[i:], m.Roles[iNdEx])
+			i = encodeVarintRaftInternal(dAtA, i, uint64(len(m.Roles[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
 	if m.AuthRevision != 0 {
 		i = encodeVarintRaftInternal(dAtA, i, uint64(m.AuthRevision))
 		i--
@@ -899,6 +912,12 @@ func (m *RequestHeader) Size() (n int) {
 	if m.AuthRevision != 0 {
 		n += 1 + sovRaftInternal(uint64(m.AuthRevision))
 	}
+	if len(m.Roles) > 0 {
+		for _, s := range m.Roles {
+			l = len(s)
+			n += 1 + l + sovRaftInternal(uint64(l))
+		}
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1185,6 +1204,38 @@ func (m *RequestHeader) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Roles", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRaftInternal
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRaftInternal
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Roles = append(m.Roles, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRaftInternal(dAtA[iNdEx:])
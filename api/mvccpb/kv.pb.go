@@ -10,6 +10,7 @@ import (
 	math_bits "math/bits"
 
 	_ "github.com/gogo/protobuf/gogoproto"
+	github_com_gogo_protobuf_sortkeys "github.com/gogo/protobuf/sortkeys"
 	proto "github.com/golang/protobuf/proto"
 )
 
@@ -65,10 +66,14 @@ type KeyValue struct {
 	// lease is the ID of the lease that attached to key.
 	// When the attached lease expires, the key will be deleted.
 	// If lease is 0, then no lease is attached to the key.
-	Lease                int64    `protobuf:"varint,6,opt,name=lease,proto3" json:"lease,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Lease int64 `protobuf:"varint,6,opt,name=lease,proto3" json:"lease,omitempty"`
+	// annotations is the user-defined metadata attached to the key
+	// by the last put, such as owner, content-type or checksum.
+	// Annotations are not part of the value.
+	Annotations          map[string]string `protobuf:"bytes,7,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *KeyValue) Reset()         { *m = KeyValue{} }
@@ -81,16 +86,12 @@ func (m *KeyValue) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
 }
 func (m *KeyValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	if deterministic {
-		return xxx_messageInfo_KeyValue.Marshal(b, m, deterministic)
-	} else {
-		b = b[:cap(b)]
-		n, err := m.MarshalToSizedBuffer(b)
-		if err != nil {
-			return nil, err
-		}
-		return b[:n], nil
+	b = b[:cap(b)]
+	n, err := m.MarshalToSizedBuffer(b)
+	if err != nil {
+		return nil, err
 	}
+	return b[:n], nil
 }
 func (m *KeyValue) XXX_Merge(src proto.Message) {
 	xxx_messageInfo_KeyValue.Merge(m, src)
@@ -158,32 +159,37 @@ var xxx_messageInfo_Event proto.InternalMessageInfo
 func init() {
 	proto.RegisterEnum("mvccpb.Event_EventType", Event_EventType_name, Event_EventType_value)
 	proto.RegisterType((*KeyValue)(nil), "mvccpb.KeyValue")
+	proto.RegisterMapType((map[string]string)(nil), "mvccpb.KeyValue.AnnotationsEntry")
 	proto.RegisterType((*Event)(nil), "mvccpb.Event")
 }
 
 func init() { proto.RegisterFile("kv.proto", fileDescriptor_2216fe83c9c12408) }
 
 var fileDescriptor_2216fe83c9c12408 = []byte{
-	// 303 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x90, 0x41, 0x4e, 0xc2, 0x40,
-	0x14, 0x86, 0x3b, 0x14, 0x0a, 0x3e, 0x08, 0x36, 0x13, 0x12, 0x27, 0x2e, 0x26, 0x95, 0x8d, 0x18,
-	0x13, 0x4c, 0xf0, 0x06, 0xc6, 0xae, 0x70, 0x61, 0x1a, 0x74, 0x4b, 0x4a, 0x79, 0x21, 0xa4, 0x94,
-	0x69, 0x4a, 0x9d, 0xa4, 0x37, 0x71, 0xef, 0xde, 0x73, 0xb0, 0xe4, 0x08, 0x52, 0x2f, 0x62, 0xfa,
-	0xc6, 0xe2, 0xc6, 0xcd, 0xe4, 0xfd, 0xff, 0xff, 0x65, 0xe6, 0x7f, 0x03, 0x9d, 0x58, 0x8f, 0xd3,
-	0x4c, 0xe5, 0x8a, 0x3b, 0x89, 0x8e, 0xa2, 0x74, 0x71, 0x39, 0x58, 0xa9, 0x95, 0x22, 0xeb, 0xae,
-	0x9a, 0x4c, 0x3a, 0xfc, 0x64, 0xd0, 0x99, 0x62, 0xf1, 0x1a, 0x6e, 0xde, 0x90, 0xbb, 0x60, 0xc7,
-	0x58, 0x08, 0xe6, 0xb1, 0x51, 0x2f, 0xa8, 0x46, 0x7e, 0x0d, 0xe7, 0x51, 0x86, 0x61, 0x8e, 0xf3,
-	0x0c, 0xf5, 0x7a, 0xb7, 0x56, 0x5b, 0xd1, 0xf0, 0xd8, 0xc8, 0x0e, 0xfa, 0xc6, 0x0e, 0x7e, 0x5d,
-	0x7e, 0x05, 0xbd, 0x44, 0x2d, 0xff, 0x28, 0x9b, 0xa8, 0x6e, 0xa2, 0x96, 0x27, 0x44, 0x40, 0x5b,
-	0x63, 0x46, 0x69, 0x93, 0xd2, 0x5a, 0xf2, 0x01, 0xb4, 0x74, 0x55, 0x40, 0xb4, 0xe8, 0x65, 0x23,
-	0x2a, 0x77, 0x83, 0xe1, 0x0e, 0x85, 0x43, 0xb4, 0x11, 0xc3, 0x0f, 0x06, 0x2d, 0x5f, 0xe3, 0x36,
-	0xe7, 0xb7, 0xd0, 0xcc, 0x8b, 0x14, 0xa9, 0x6e, 0x7f, 0x72, 0x31, 0x36, 0x7b, 0x8e, 0x29, 0x34,
-	0xe7, 0xac, 0x48, 0x31, 0x20, 0x88, 0x7b, 0xd0, 0x88, 0x35, 0x75, 0xef, 0x4e, 0xdc, 0x1a, 0xad,
-	0x17, 0x0f, 0x1a, 0xb1, 0xe6, 0x37, 0xd0, 0x4e, 0x33, 0xd4, 0xf3, 0x58, 0x53, 0xf9, 0xff, 0x30,
-	0xa7, 0x02, 0xa6, 0x7a, 0xe8, 0xc1, 0xd9, 0xe9, 0x7e, 0xde, 0x06, 0xfb, 0xf9, 0x65, 0xe6, 0x5a,
-	0x1c, 0xc0, 0x79, 0xf4, 0x9f, 0xfc, 0x99, 0xef, 0xb2, 0x07, 0xb1, 0x3f, 0x4a, 0xeb, 0x70, 0x94,
-	0xd6, 0xbe, 0x94, 0xec, 0x50, 0x4a, 0xf6, 0x55, 0x4a, 0xf6, 0xfe, 0x2d, 0xad, 0x85, 0x43, 0xff,
-	0x7e, 0xff, 0x13, 0x00, 0x00, 0xff, 0xff, 0xb5, 0x45, 0x92, 0x5d, 0xa1, 0x01, 0x00, 0x00,
+	// 364 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x92, 0xcf, 0x6a, 0xea, 0x40,
+	0x18, 0xc5, 0x33, 0x89, 0x26, 0xfa, 0x45, 0xbc, 0x61, 0x10, 0xee, 0xe0, 0x22, 0x37, 0xba, 0xb9,
+	0x5e, 0x2e, 0xa4, 0x60, 0x37, 0xc5, 0x45, 0xa1, 0x7f, 0xb2, 0xb2, 0x8b, 0x12, 0x6c, 0xb7, 0x12,
+	0x75, 0x10, 0x89, 0x66, 0x42, 0x9c, 0x0e, 0xe4, 0x4d, 0xdc, 0x96, 0x3e, 0x45, 0xdf, 0xc0, 0xa5,
+	0x8f, 0x50, 0xed, 0x8b, 0x94, 0xcc, 0xd4, 0x3f, 0x08, 0xdd, 0x84, 0xf9, 0xce, 0xf9, 0xcd, 0x97,
+	0x73, 0x60, 0xa0, 0x12, 0x0b, 0x3f, 0xcd, 0x18, 0x67, 0xd8, 0x5c, 0x88, 0xf1, 0x38, 0x1d, 0x35,
+	0x1b, 0x53, 0x36, 0x65, 0x52, 0xba, 0x28, 0x4e, 0xca, 0x6d, 0xbf, 0xeb, 0x50, 0xe9, 0xd3, 0xfc,
+	0x39, 0x9a, 0xbf, 0x50, 0xec, 0x80, 0x11, 0xd3, 0x9c, 0x20, 0x0f, 0x75, 0x6a, 0x61, 0x71, 0xc4,
+	0x7f, 0xe1, 0xd7, 0x38, 0xa3, 0x11, 0xa7, 0xc3, 0x8c, 0x8a, 0xd9, 0x72, 0xc6, 0x12, 0xa2, 0x7b,
+	0xa8, 0x63, 0x84, 0x75, 0x25, 0x87, 0xdf, 0x2a, 0x6e, 0x41, 0x6d, 0xc1, 0x26, 0x47, 0xca, 0x90,
+	0x94, 0xbd, 0x60, 0x93, 0x03, 0x42, 0xc0, 0x12, 0x34, 0x93, 0x6e, 0x49, 0xba, 0xfb, 0x11, 0x37,
+	0xa0, 0x2c, 0x8a, 0x00, 0xa4, 0x2c, 0xff, 0xac, 0x86, 0x42, 0x9d, 0xd3, 0x68, 0x49, 0x89, 0x29,
+	0x69, 0x35, 0xe0, 0x3b, 0xb0, 0xa3, 0x24, 0x61, 0x3c, 0xe2, 0x33, 0x96, 0x2c, 0x89, 0xe5, 0x19,
+	0x1d, 0xbb, 0xdb, 0xf2, 0x55, 0x49, 0x7f, 0x5f, 0xc5, 0xbf, 0x39, 0x32, 0x41, 0xc2, 0xb3, 0x3c,
+	0x3c, 0xbd, 0xd5, 0xbc, 0x06, 0xe7, 0x1c, 0x38, 0x2d, 0x5f, 0x55, 0xe5, 0x0f, 0xb1, 0x74, 0xa9,
+	0xa9, 0xa1, 0xa7, 0x5f, 0xa1, 0x5e, 0x69, 0xf5, 0xfa, 0x07, 0xb5, 0xdf, 0x10, 0x94, 0x03, 0x41,
+	0x13, 0x8e, 0xff, 0x43, 0x89, 0xe7, 0x29, 0x95, 0x97, 0xeb, 0xdd, 0xdf, 0xfb, 0x34, 0xd2, 0x54,
+	0xdf, 0x41, 0x9e, 0xd2, 0x50, 0x42, 0xd8, 0x03, 0x3d, 0x16, 0x72, 0xa7, 0xdd, 0x75, 0xce, 0x83,
+	0x87, 0x7a, 0x2c, 0xf0, 0x3f, 0xb0, 0xd2, 0x8c, 0x8a, 0x61, 0x2c, 0x88, 0xf1, 0x03, 0x66, 0x16,
+	0x40, 0x5f, 0xb4, 0x3d, 0xa8, 0x1e, 0xf6, 0x63, 0x0b, 0x8c, 0xc7, 0xa7, 0x81, 0xa3, 0x61, 0x00,
+	0xf3, 0x3e, 0x78, 0x08, 0x06, 0x81, 0x83, 0x6e, 0xc9, 0x7a, 0xeb, 0x6a, 0x9b, 0xad, 0xab, 0xad,
+	0x77, 0x2e, 0xda, 0xec, 0x5c, 0xf4, 0xb1, 0x73, 0xd1, 0xea, 0xd3, 0xd5, 0x46, 0xa6, 0x7c, 0x02,
+	0x97, 0x5f, 0x03, 0x00, 0xe0, 0x79, 0x48, 0x21, 0x2c, 0x02, 0x00, 0x00,
 }
 
 func (m *KeyValue) Marshal() (dAtA []byte, err error) {
@@ -210,6 +216,30 @@ func (m *KeyValue) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if len(m.Annotations) > 0 {
+		keysForAnnotations := make([]string, 0, len(m.Annotations))
+		for k := range m.Annotations {
+			keysForAnnotations = append(keysForAnnotations, string(k))
+		}
+		github_com_gogo_protobuf_sortkeys.Strings(keysForAnnotations)
+		for iNdEx := len(keysForAnnotations) - 1; iNdEx >= 0; iNdEx-- {
+			v := m.Annotations[string(keysForAnnotations[iNdEx])]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintKv(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(keysForAnnotations[iNdEx])
+			copy(dAtA[i:], keysForAnnotations[iNdEx])
+			i = encodeVarintKv(dAtA, i, uint64(len(keysForAnnotations[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintKv(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x3a
+		}
+	}
 	if m.Lease != 0 {
 		i = encodeVarintKv(dAtA, i, uint64(m.Lease))
 		i--
@@ -340,6 +370,14 @@ func (m *KeyValue) Size() (n int) {
 	if m.Lease != 0 {
 		n += 1 + sovKv(uint64(m.Lease))
 	}
+	if len(m.Annotations) > 0 {
+		for k, v := range m.Annotations {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovKv(uint64(len(k))) + 1 + len(v) + sovKv(uint64(len(v)))
+			n += mapEntrySize + 1 + sovKv(uint64(mapEntrySize))
+		}
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -548,6 +586,133 @@ func (m *KeyValue) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Annotations", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowKv
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthKv
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthKv
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Annotations == nil {
+				m.Annotations = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowKv
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowKv
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthKv
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthKv
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowKv
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthKv
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthKv
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipKv(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if (skippy < 0) || (iNdEx+skippy) < 0 {
+						return ErrInvalidLengthKv
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Annotations[mapkey] = mapvalue
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipKv(dAtA[iNdEx:])
@@ -0,0 +1,81 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvccpb_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func testAnnotations() map[string]string {
+	annotations := make(map[string]string)
+	for i := 0; i < 16; i++ {
+		annotations[fmt.Sprintf("k%d", i)] = fmt.Sprintf("v%d", i)
+	}
+	return annotations
+}
+
+// TestKeyValueMarshalAnnotations ensures the key-value pairs are marshaled to
+// the same bytes whatever the iteration order of their annotations, the
+// members storing and hashing the marshaled key-value pairs.
+func TestKeyValueMarshalAnnotations(t *testing.T) {
+	kv := mvccpb.KeyValue{Key: []byte("foo"), Value: []byte("bar"), ModRevision: 1, Annotations: testAnnotations()}
+	want, err := kv.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		d, err := kv.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(d, want) {
+			t.Fatalf("#%d: marshaled %x, want %x", i, d, want)
+		}
+	}
+
+	var ukv mvccpb.KeyValue
+	if err = ukv.Unmarshal(want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ukv, kv) {
+		t.Errorf("unmarshaled %+v, want %+v", ukv, kv)
+	}
+}
+
+// TestPutRequestMarshalAnnotations ensures the put requests are marshaled to
+// the same bytes whatever the iteration order of their annotations, the put
+// requests being replicated in the raft log.
+func TestPutRequestMarshalAnnotations(t *testing.T) {
+	r := pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar"), Annotations: testAnnotations()}}
+	want, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		d, err := r.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(d, want) {
+			t.Fatalf("#%d: marshaled %x, want %x", i, d, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpctypes
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the google.rpc.ErrorInfo details of the etcd
+// gRPC errors.
+const ErrorDomain = "etcd.io"
+
+// The reasons of the google.rpc.ErrorInfo details of the etcd gRPC errors.
+const (
+	// ReasonNoSpace is the reason of ErrGRPCNoSpace, with the metadata
+	// MetadataQuotaBytes and MetadataBackendSizeBytes.
+	ReasonNoSpace = "NOSPACE"
+	// ReasonTooManyRequests is the reason of ErrTooManyRequests, with the
+	// metadata MetadataAppliedIndex and MetadataCommittedIndex.
+	ReasonTooManyRequests = "TOO_MANY_REQUESTS"
+	// ReasonPermissionDenied is the reason of ErrGRPCPermissionDenied.
+	ReasonPermissionDenied = "PERMISSION_DENIED"
+	// ReasonCompacted is the reason of ErrGRPCCompacted, with the metadata
+	// MetadataCompactRevision and MetadataCurrentRevision.
+	ReasonCompacted = "COMPACTED"
+	// ReasonWatchStreamExpired is the reason of ErrGRPCWatchStreamExpired,
+	// with the metadata MetadataCurrentRevision, the watches being to resume
+	// on a new stream.
+	ReasonWatchStreamExpired = "WATCH_STREAM_EXPIRED"
+)
+
+// The keys of the metadata of the google.rpc.ErrorInfo details, with decimal
+// values.
+const (
+	MetadataQuotaBytes       = "quota-bytes"
+	MetadataBackendSizeBytes = "backend-size-bytes"
+	MetadataAppliedIndex     = "applied-index"
+	MetadataCommittedIndex   = "committed-index"
+	MetadataCompactRevision  = "compact-revision"
+	MetadataCurrentRevision  = "current-revision"
+)
+
+// ErrorInfo returns the google.rpc.ErrorInfo details of the etcd gRPC error
+// err, if any.
+func ErrorInfo(err error) (*errdetails.ErrorInfo, bool) {
+	ev, ok := status.FromError(err)
+	if !ok {
+		return nil, false
+	}
+	for _, d := range ev.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// RetryDelay returns the delay the request failing with the gRPC error err
+// may be retried after, from its google.rpc.RetryInfo details, if any.
+func RetryDelay(err error) (time.Duration, bool) {
+	ev, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, d := range ev.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.RetryDelay != nil {
+			return ri.RetryDelay.AsDuration(), true
+		}
+	}
+	return 0, false
+}
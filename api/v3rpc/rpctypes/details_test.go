@@ -0,0 +1,51 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpctypes
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestErrorDetails(t *testing.T) {
+	ev, _ := status.FromError(ErrTooManyRequests)
+	ev, err := ev.WithDetails(
+		&errdetails.ErrorInfo{Reason: "OTHER", Domain: "example.com"},
+		&errdetails.ErrorInfo{Reason: ReasonTooManyRequests, Domain: ErrorDomain},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Second)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, ok := ErrorInfo(ev.Err())
+	if !ok || info.Reason != ReasonTooManyRequests {
+		t.Errorf("ErrorInfo = %v, %v, want the reason %s", info, ok, ReasonTooManyRequests)
+	}
+	if d, ok := RetryDelay(ev.Err()); !ok || d != time.Second {
+		t.Errorf("RetryDelay = %v, %v, want 1s", d, ok)
+	}
+
+	if _, ok = ErrorInfo(ErrGRPCNoSpace); ok {
+		t.Errorf("ErrorInfo of an error without details")
+	}
+	if _, ok = RetryDelay(ErrNoSpace); ok {
+		t.Errorf("RetryDelay of an error without details")
+	}
+}
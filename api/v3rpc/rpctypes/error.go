@@ -32,12 +32,17 @@ var (
 	ErrGRPCCompacted               = status.New(codes.OutOfRange, "etcdserver: mvcc: required revision has been compacted").Err()
 	ErrGRPCFutureRev               = status.New(codes.OutOfRange, "etcdserver: mvcc: required revision is a future revision").Err()
 	ErrGRPCNoSpace                 = status.New(codes.ResourceExhausted, "etcdserver: mvcc: database space exceeded").Err()
+	ErrGRPCAnnotationsTooLarge     = status.New(codes.InvalidArgument, "etcdserver: too many or too large annotations").Err()
+	ErrGRPCValueNotInteger         = status.New(codes.FailedPrecondition, "etcdserver: value is not an integer").Err()
+	ErrGRPCIntegerOverflow         = status.New(codes.OutOfRange, "etcdserver: integer overflow").Err()
 
 	ErrGRPCLeaseNotFound    = status.New(codes.NotFound, "etcdserver: requested lease not found").Err()
 	ErrGRPCLeaseExist       = status.New(codes.FailedPrecondition, "etcdserver: lease already exists").Err()
 	ErrGRPCLeaseTTLTooLarge = status.New(codes.OutOfRange, "etcdserver: too large lease TTL").Err()
 
-	ErrGRPCWatchCanceled = status.New(codes.Canceled, "etcdserver: watch canceled").Err()
+	ErrGRPCWatchCanceled      = status.New(codes.Canceled, "etcdserver: watch canceled").Err()
+	ErrGRPCInvalidValueRegex  = status.New(codes.InvalidArgument, "etcdserver: invalid watch value regex").Err()
+	ErrGRPCWatchStreamExpired = status.New(codes.Unavailable, "etcdserver: watch stream exceeded its maximum age").Err()
 
 	ErrGRPCMemberExist            = status.New(codes.FailedPrecondition, "etcdserver: member ID already exist").Err()
 	ErrGRPCPeerURLExist           = status.New(codes.FailedPrecondition, "etcdserver: Peer URLs already exists").Err()
@@ -47,10 +52,24 @@ var (
 	ErrGRPCMemberNotLearner       = status.New(codes.FailedPrecondition, "etcdserver: can only promote a learner member").Err()
 	ErrGRPCLearnerNotReady        = status.New(codes.FailedPrecondition, "etcdserver: can only promote a learner member which is in sync with leader").Err()
 	ErrGRPCTooManyLearners        = status.New(codes.FailedPrecondition, "etcdserver: too many learner members in cluster").Err()
+	ErrGRPCMemberNotVoter         = status.New(codes.FailedPrecondition, "etcdserver: can only demote a voting member").Err()
+	ErrGRPCLastVotingMember       = status.New(codes.FailedPrecondition, "etcdserver: cannot demote the last voting member").Err()
+	ErrGRPCDemoteLeader           = status.New(codes.FailedPrecondition, "etcdserver: cannot demote the leader, move the leadership to another member first").Err()
+	ErrGRPCDemoteNotAcknowledged  = status.New(codes.FailedPrecondition, "etcdserver: demoting the member reduces the fault tolerance of the cluster, it must be acknowledged").Err()
+	ErrGRPCDemoteNotSupported     = status.New(codes.FailedPrecondition, "etcdserver: demoting a member requires cluster version 3.6").Err()
 
 	ErrGRPCRequestTooLarge        = status.New(codes.InvalidArgument, "etcdserver: request is too large").Err()
 	ErrGRPCRequestTooManyRequests = status.New(codes.ResourceExhausted, "etcdserver: too many requests").Err()
 
+	ErrGRPCConnectionMemoryExceeded = status.New(codes.ResourceExhausted, "etcdserver: connection memory limit exceeded").Err()
+	ErrGRPCConnectionEvicted        = status.New(codes.ResourceExhausted, "etcdserver: connection evicted due to memory pressure").Err()
+	ErrGRPCTooManyLargeRequests     = status.New(codes.ResourceExhausted, "etcdserver: too many large requests").Err()
+	ErrGRPCMemoryBudgetExceeded     = status.New(codes.ResourceExhausted, "etcdserver: memory budget exceeded").Err()
+
+	ErrGRPCInvalidLogLevel       = status.New(codes.InvalidArgument, "etcdserver: invalid log level").Err()
+	ErrGRPCLogLevelNotAdjustable = status.New(codes.FailedPrecondition, "etcdserver: log level is not adjustable").Err()
+	ErrGRPCTooManyLoggedRanges   = status.New(codes.ResourceExhausted, "etcdserver: too many logged ranges").Err()
+
 	ErrGRPCRootUserNotExist     = status.New(codes.FailedPrecondition, "etcdserver: root user does not exist").Err()
 	ErrGRPCRootRoleNotExist     = status.New(codes.FailedPrecondition, "etcdserver: root user does not have root role").Err()
 	ErrGRPCUserAlreadyExist     = status.New(codes.FailedPrecondition, "etcdserver: user name already exists").Err()
@@ -98,17 +117,23 @@ var (
 		ErrorDesc(ErrGRPCValueProvided): ErrGRPCValueProvided,
 		ErrorDesc(ErrGRPCLeaseProvided): ErrGRPCLeaseProvided,
 
-		ErrorDesc(ErrGRPCTooManyOps):        ErrGRPCTooManyOps,
-		ErrorDesc(ErrGRPCDuplicateKey):      ErrGRPCDuplicateKey,
-		ErrorDesc(ErrGRPCInvalidSortOption): ErrGRPCInvalidSortOption,
-		ErrorDesc(ErrGRPCCompacted):         ErrGRPCCompacted,
-		ErrorDesc(ErrGRPCFutureRev):         ErrGRPCFutureRev,
-		ErrorDesc(ErrGRPCNoSpace):           ErrGRPCNoSpace,
+		ErrorDesc(ErrGRPCTooManyOps):          ErrGRPCTooManyOps,
+		ErrorDesc(ErrGRPCDuplicateKey):        ErrGRPCDuplicateKey,
+		ErrorDesc(ErrGRPCInvalidSortOption):   ErrGRPCInvalidSortOption,
+		ErrorDesc(ErrGRPCCompacted):           ErrGRPCCompacted,
+		ErrorDesc(ErrGRPCFutureRev):           ErrGRPCFutureRev,
+		ErrorDesc(ErrGRPCNoSpace):             ErrGRPCNoSpace,
+		ErrorDesc(ErrGRPCAnnotationsTooLarge): ErrGRPCAnnotationsTooLarge,
+		ErrorDesc(ErrGRPCValueNotInteger):     ErrGRPCValueNotInteger,
+		ErrorDesc(ErrGRPCIntegerOverflow):     ErrGRPCIntegerOverflow,
 
 		ErrorDesc(ErrGRPCLeaseNotFound):    ErrGRPCLeaseNotFound,
 		ErrorDesc(ErrGRPCLeaseExist):       ErrGRPCLeaseExist,
 		ErrorDesc(ErrGRPCLeaseTTLTooLarge): ErrGRPCLeaseTTLTooLarge,
 
+		ErrorDesc(ErrGRPCInvalidValueRegex):  ErrGRPCInvalidValueRegex,
+		ErrorDesc(ErrGRPCWatchStreamExpired): ErrGRPCWatchStreamExpired,
+
 		ErrorDesc(ErrGRPCMemberExist):            ErrGRPCMemberExist,
 		ErrorDesc(ErrGRPCPeerURLExist):           ErrGRPCPeerURLExist,
 		ErrorDesc(ErrGRPCMemberNotEnoughStarted): ErrGRPCMemberNotEnoughStarted,
@@ -117,10 +142,24 @@ var (
 		ErrorDesc(ErrGRPCMemberNotLearner):       ErrGRPCMemberNotLearner,
 		ErrorDesc(ErrGRPCLearnerNotReady):        ErrGRPCLearnerNotReady,
 		ErrorDesc(ErrGRPCTooManyLearners):        ErrGRPCTooManyLearners,
+		ErrorDesc(ErrGRPCMemberNotVoter):         ErrGRPCMemberNotVoter,
+		ErrorDesc(ErrGRPCLastVotingMember):       ErrGRPCLastVotingMember,
+		ErrorDesc(ErrGRPCDemoteLeader):           ErrGRPCDemoteLeader,
+		ErrorDesc(ErrGRPCDemoteNotAcknowledged):  ErrGRPCDemoteNotAcknowledged,
+		ErrorDesc(ErrGRPCDemoteNotSupported):     ErrGRPCDemoteNotSupported,
 
 		ErrorDesc(ErrGRPCRequestTooLarge):        ErrGRPCRequestTooLarge,
 		ErrorDesc(ErrGRPCRequestTooManyRequests): ErrGRPCRequestTooManyRequests,
 
+		ErrorDesc(ErrGRPCConnectionMemoryExceeded): ErrGRPCConnectionMemoryExceeded,
+		ErrorDesc(ErrGRPCConnectionEvicted):        ErrGRPCConnectionEvicted,
+		ErrorDesc(ErrGRPCTooManyLargeRequests):     ErrGRPCTooManyLargeRequests,
+		ErrorDesc(ErrGRPCMemoryBudgetExceeded):     ErrGRPCMemoryBudgetExceeded,
+
+		ErrorDesc(ErrGRPCInvalidLogLevel):       ErrGRPCInvalidLogLevel,
+		ErrorDesc(ErrGRPCLogLevelNotAdjustable): ErrGRPCLogLevelNotAdjustable,
+		ErrorDesc(ErrGRPCTooManyLoggedRanges):   ErrGRPCTooManyLoggedRanges,
+
 		ErrorDesc(ErrGRPCRootUserNotExist):     ErrGRPCRootUserNotExist,
 		ErrorDesc(ErrGRPCRootRoleNotExist):     ErrGRPCRootRoleNotExist,
 		ErrorDesc(ErrGRPCUserAlreadyExist):     ErrGRPCUserAlreadyExist,
@@ -161,21 +200,27 @@ var (
 
 // client-side error
 var (
-	ErrEmptyKey          = Error(ErrGRPCEmptyKey)
-	ErrKeyNotFound       = Error(ErrGRPCKeyNotFound)
-	ErrValueProvided     = Error(ErrGRPCValueProvided)
-	ErrLeaseProvided     = Error(ErrGRPCLeaseProvided)
-	ErrTooManyOps        = Error(ErrGRPCTooManyOps)
-	ErrDuplicateKey      = Error(ErrGRPCDuplicateKey)
-	ErrInvalidSortOption = Error(ErrGRPCInvalidSortOption)
-	ErrCompacted         = Error(ErrGRPCCompacted)
-	ErrFutureRev         = Error(ErrGRPCFutureRev)
-	ErrNoSpace           = Error(ErrGRPCNoSpace)
+	ErrEmptyKey            = Error(ErrGRPCEmptyKey)
+	ErrKeyNotFound         = Error(ErrGRPCKeyNotFound)
+	ErrValueProvided       = Error(ErrGRPCValueProvided)
+	ErrLeaseProvided       = Error(ErrGRPCLeaseProvided)
+	ErrTooManyOps          = Error(ErrGRPCTooManyOps)
+	ErrDuplicateKey        = Error(ErrGRPCDuplicateKey)
+	ErrInvalidSortOption   = Error(ErrGRPCInvalidSortOption)
+	ErrCompacted           = Error(ErrGRPCCompacted)
+	ErrFutureRev           = Error(ErrGRPCFutureRev)
+	ErrNoSpace             = Error(ErrGRPCNoSpace)
+	ErrAnnotationsTooLarge = Error(ErrGRPCAnnotationsTooLarge)
+	ErrValueNotInteger     = Error(ErrGRPCValueNotInteger)
+	ErrIntegerOverflow     = Error(ErrGRPCIntegerOverflow)
 
 	ErrLeaseNotFound    = Error(ErrGRPCLeaseNotFound)
 	ErrLeaseExist       = Error(ErrGRPCLeaseExist)
 	ErrLeaseTTLTooLarge = Error(ErrGRPCLeaseTTLTooLarge)
 
+	ErrInvalidValueRegex  = Error(ErrGRPCInvalidValueRegex)
+	ErrWatchStreamExpired = Error(ErrGRPCWatchStreamExpired)
+
 	ErrMemberExist            = Error(ErrGRPCMemberExist)
 	ErrPeerURLExist           = Error(ErrGRPCPeerURLExist)
 	ErrMemberNotEnoughStarted = Error(ErrGRPCMemberNotEnoughStarted)
@@ -184,10 +229,24 @@ var (
 	ErrMemberNotLearner       = Error(ErrGRPCMemberNotLearner)
 	ErrMemberLearnerNotReady  = Error(ErrGRPCLearnerNotReady)
 	ErrTooManyLearners        = Error(ErrGRPCTooManyLearners)
+	ErrMemberNotVoter         = Error(ErrGRPCMemberNotVoter)
+	ErrLastVotingMember       = Error(ErrGRPCLastVotingMember)
+	ErrDemoteLeader           = Error(ErrGRPCDemoteLeader)
+	ErrDemoteNotAcknowledged  = Error(ErrGRPCDemoteNotAcknowledged)
+	ErrDemoteNotSupported     = Error(ErrGRPCDemoteNotSupported)
 
 	ErrRequestTooLarge = Error(ErrGRPCRequestTooLarge)
 	ErrTooManyRequests = Error(ErrGRPCRequestTooManyRequests)
 
+	ErrConnectionMemoryExceeded = Error(ErrGRPCConnectionMemoryExceeded)
+	ErrConnectionEvicted        = Error(ErrGRPCConnectionEvicted)
+	ErrTooManyLargeRequests     = Error(ErrGRPCTooManyLargeRequests)
+	ErrMemoryBudgetExceeded     = Error(ErrGRPCMemoryBudgetExceeded)
+
+	ErrInvalidLogLevel       = Error(ErrGRPCInvalidLogLevel)
+	ErrLogLevelNotAdjustable = Error(ErrGRPCLogLevelNotAdjustable)
+	ErrTooManyLoggedRanges   = Error(ErrGRPCTooManyLoggedRanges)
+
 	ErrRootUserNotExist     = Error(ErrGRPCRootUserNotExist)
 	ErrRootRoleNotExist     = Error(ErrGRPCRootRoleNotExist)
 	ErrUserAlreadyExist     = Error(ErrGRPCUserAlreadyExist)
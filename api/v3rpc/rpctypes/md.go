@@ -19,4 +19,8 @@ var (
 	MetadataHasLeader        = "true"
 
 	MetadataClientAPIVersionKey = "client-api-version"
+
+	// MetadataMinRevisionKey is the key of the revision the member serving the
+	// serializable ranges of a request waits to apply before serving them.
+	MetadataMinRevisionKey = "min-revision"
 )
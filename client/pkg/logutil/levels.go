@@ -0,0 +1,156 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels adjusts at runtime the level of the loggers wrapping their core with
+// WrapCore, globally and per subsystem. The subsystem of a log entry is the
+// directory of the file logging it, e.g. "mvcc" or "v3rpc", looked up only
+// while some subsystems have a level. The entries are still filtered by the
+// levels of the wrapped cores, which must be enabled at the lowest level the
+// loggers may be adjusted to.
+type Levels struct {
+	global zap.AtomicLevel
+
+	mu         sync.RWMutex
+	subsystems map[string]zapcore.Level
+	// min is the lowest of the global and the subsystem levels.
+	min zap.AtomicLevel
+}
+
+func NewLevels(lvl zapcore.Level) *Levels {
+	return &Levels{
+		global:     zap.NewAtomicLevelAt(lvl),
+		subsystems: make(map[string]zapcore.Level),
+		min:        zap.NewAtomicLevelAt(lvl),
+	}
+}
+
+// Level returns the global level.
+func (l *Levels) Level() zapcore.Level { return l.global.Level() }
+
+// SetLevel sets the global level, used by the subsystems without a level.
+func (l *Levels) SetLevel(lvl zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.global.SetLevel(lvl)
+	l.updateMin()
+}
+
+// SetSubsystemLevel sets the level of the subsystem, overriding the global level.
+func (l *Levels) SetSubsystemLevel(subsystem string, lvl zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subsystems[subsystem] = lvl
+	l.updateMin()
+}
+
+// ResetSubsystemLevel makes the subsystem use the global level again.
+func (l *Levels) ResetSubsystemLevel(subsystem string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.subsystems, subsystem)
+	l.updateMin()
+}
+
+// SubsystemLevels returns the levels of the subsystems overriding the global level.
+func (l *Levels) SubsystemLevels() map[string]zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	lvls := make(map[string]zapcore.Level, len(l.subsystems))
+	for s, lvl := range l.subsystems {
+		lvls[s] = lvl
+	}
+	return lvls
+}
+
+func (l *Levels) updateMin() {
+	min := l.global.Level()
+	for _, lvl := range l.subsystems {
+		if lvl < min {
+			min = lvl
+		}
+	}
+	l.min.SetLevel(min)
+}
+
+// enabled returns whether an entry at the level is enabled for its caller.
+func (l *Levels) enabled(lvl zapcore.Level) bool {
+	if !l.min.Enabled(lvl) {
+		return false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.subsystems) == 0 {
+		return l.global.Enabled(lvl)
+	}
+	if slvl, ok := l.subsystems[callerSubsystem()]; ok {
+		return slvl.Enabled(lvl)
+	}
+	return l.global.Enabled(lvl)
+}
+
+// callerSubsystem returns the directory of the file of the first caller
+// outside of zap and of the levels.
+func callerSubsystem() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "go.uber.org/zap") &&
+			!strings.HasPrefix(f.Function, "go.etcd.io/etcd/client/pkg/v3/logutil.(*Levels)") &&
+			!strings.HasPrefix(f.Function, "go.etcd.io/etcd/client/pkg/v3/logutil.(*levelsCore)") {
+			return filepath.Base(filepath.Dir(f.File))
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// WrapCore wraps the core to filter its entries with the levels, e.g. with
+// zap.WrapCore(l.WrapCore).
+func (l *Levels) WrapCore(c zapcore.Core) zapcore.Core {
+	return &levelsCore{Core: c, l: l}
+}
+
+type levelsCore struct {
+	zapcore.Core
+	l *Levels
+}
+
+func (c *levelsCore) Enabled(lvl zapcore.Level) bool {
+	return c.l.min.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *levelsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelsCore{Core: c.Core.With(fields), l: c.l}
+}
+
+func (c *levelsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.l.enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
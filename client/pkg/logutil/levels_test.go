@@ -0,0 +1,60 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevels(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := NewLevels(zapcore.InfoLevel)
+	lg := zap.New(core, zap.AddCaller(), zap.WrapCore(l.WrapCore))
+
+	lg.Debug("dropped")
+	lg.Info("logged")
+	if n := logs.TakeAll(); len(n) != 1 || n[0].Message != "logged" {
+		t.Fatalf("logs = %v, want the info log only", n)
+	}
+
+	l.SetLevel(zapcore.DebugLevel)
+	lg.Debug("logged")
+	if n := logs.TakeAll(); len(n) != 1 {
+		t.Fatalf("logs = %v, want the debug log", n)
+	}
+
+	// the subsystem of this file is its directory, "logutil"
+	l.SetLevel(zapcore.WarnLevel)
+	l.SetSubsystemLevel("mvcc", zapcore.DebugLevel)
+	lg.Info("dropped")
+	l.SetSubsystemLevel("logutil", zapcore.DebugLevel)
+	lg.Debug("logged")
+	if n := logs.TakeAll(); len(n) != 1 || n[0].Level != zapcore.DebugLevel {
+		t.Fatalf("logs = %v, want the debug log of the subsystem", n)
+	}
+	if lvls := l.SubsystemLevels(); len(lvls) != 2 || lvls["logutil"] != zapcore.DebugLevel {
+		t.Errorf("subsystem levels = %v", lvls)
+	}
+
+	l.ResetSubsystemLevel("logutil")
+	lg.Info("dropped")
+	if n := logs.TakeAll(); len(n) != 0 {
+		t.Fatalf("logs = %v, want none", n)
+	}
+}
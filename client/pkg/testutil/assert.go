@@ -16,6 +16,7 @@ package testutil
 
 import (
 	"reflect"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -53,6 +54,22 @@ func AssertFalse(t *testing.T, v bool, msg ...string) {
 	assert.Equal(t, false, v, newMsg)
 }
 
+// AssertNoRuntimePanic fails the test if f panics with a runtime error, e.g.
+// of an index out of range, the panics raised deliberately by f being
+// recovered. It checks the parsers of untrusted bytes only fail cleanly.
+func AssertNoRuntimePanic(t *testing.T, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		if r := recover(); r != nil {
+			if err, ok := r.(runtime.Error); ok {
+				t.Fatalf("unexpected runtime panic: %v", err)
+			}
+		}
+	}()
+	f()
+}
+
 func isNil(v interface{}) bool {
 	if v == nil {
 		return true
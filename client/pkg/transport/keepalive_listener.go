@@ -46,7 +46,12 @@ func NewKeepAliveListener(l net.Listener, scheme string, tlscfg *tls.Config) (ne
 	return kal, nil
 }
 
-type keepaliveListener struct{ net.Listener }
+type keepaliveListener struct {
+	net.Listener
+	// sopts are the options of the accepted connections, with the default
+	// keepalive if nil.
+	sopts *SocketOpts
+}
 
 func (kln *keepaliveListener) Accept() (net.Conn, error) {
 	c, err := kln.Listener.Accept()
@@ -58,14 +63,8 @@ func (kln *keepaliveListener) Accept() (net.Conn, error) {
 	if err != nil {
 		return nil, fmt.Errorf("create keepalive connection failed, %w", err)
 	}
-	// detection time: tcp_keepalive_time + tcp_keepalive_probes + tcp_keepalive_intvl
-	// default on linux:  30 + 8 * 30
-	// default on osx:    30 + 8 * 75
-	if err := kac.SetKeepAlive(true); err != nil {
-		return nil, fmt.Errorf("SetKeepAlive failed, %w", err)
-	}
-	if err := kac.SetKeepAlivePeriod(30 * time.Second); err != nil {
-		return nil, fmt.Errorf("SetKeepAlivePeriod failed, %w", err)
+	if err := setConnOpts(kln.sopts, kac.TCPConn); err != nil {
+		return nil, err
 	}
 	return kac, nil
 }
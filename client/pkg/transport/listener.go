@@ -50,12 +50,32 @@ func NewListenerWithOpts(addr, scheme string, opts ...ListenerOption) (net.Liste
 }
 
 func newListener(addr, scheme string, opts ...ListenerOption) (net.Listener, error) {
+	lnOpts := newListenOpts(opts...)
 	if scheme == "unix" || scheme == "unixs" {
+		if lnOpts.inherited != nil {
+			return lnOpts.inherited, nil
+		}
 		// unix sockets via unix://laddr
-		return NewUnixListener(addr)
+		ln, err := NewUnixListener(addr)
+		if err != nil || lnOpts.socketMode == 0 {
+			return ln, err
+		}
+		if err = os.Chmod(addr, lnOpts.socketMode); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set the permissions of unix socket %s (%v)", addr, err)
+		}
+		return ln, nil
+	}
+	if scheme == "npipe" {
+		// windows named pipes via npipe:////./pipe/name
+		return NewPipeListener(addr)
 	}
 
-	lnOpts := newListenOpts(opts...)
+	if lnOpts.socketOpts != nil {
+		if err := lnOpts.socketOpts.Validate(); err != nil {
+			return nil, err
+		}
+	}
 
 	switch {
 	case lnOpts.IsSocketOpts():
@@ -69,7 +89,7 @@ func newListener(addr, scheme string, opts ...ListenerOption) (net.Listener, err
 		fallthrough
 	case lnOpts.IsTimeout(), lnOpts.IsSocketOpts():
 		// timeout listener with socket options.
-		ln, err := newKeepAliveListener(&lnOpts.ListenConfig, addr)
+		ln, err := newKeepAliveListener(&lnOpts.ListenConfig, lnOpts.socketOpts, lnOpts.inherited, lnOpts.network, addr)
 		if err != nil {
 			return nil, err
 		}
@@ -79,7 +99,7 @@ func newListener(addr, scheme string, opts ...ListenerOption) (net.Listener, err
 			writeTimeout: lnOpts.writeTimeout,
 		}
 	case lnOpts.IsTimeout():
-		ln, err := newKeepAliveListener(nil, addr)
+		ln, err := newKeepAliveListener(nil, lnOpts.socketOpts, lnOpts.inherited, lnOpts.network, addr)
 		if err != nil {
 			return nil, err
 		}
@@ -89,7 +109,7 @@ func newListener(addr, scheme string, opts ...ListenerOption) (net.Listener, err
 			writeTimeout: lnOpts.writeTimeout,
 		}
 	default:
-		ln, err := newKeepAliveListener(nil, addr)
+		ln, err := newKeepAliveListener(nil, lnOpts.socketOpts, lnOpts.inherited, lnOpts.network, addr)
 		if err != nil {
 			return nil, err
 		}
@@ -103,17 +123,25 @@ func newListener(addr, scheme string, opts ...ListenerOption) (net.Listener, err
 	return wrapTLS(scheme, lnOpts.tlsInfo, lnOpts.Listener)
 }
 
-func newKeepAliveListener(cfg *net.ListenConfig, addr string) (ln net.Listener, err error) {
-	if cfg != nil {
-		ln, err = cfg.Listen(context.TODO(), "tcp", addr)
-	} else {
-		ln, err = net.Listen("tcp", addr)
+// newKeepAliveListener listens on the address, unless given the inherited
+// listener of the address.
+func newKeepAliveListener(cfg *net.ListenConfig, sopts *SocketOpts, inherited net.Listener, network, addr string) (ln net.Listener, err error) {
+	if network == "" {
+		network = "tcp"
+	}
+	switch {
+	case inherited != nil:
+		ln = inherited
+	case cfg != nil:
+		ln, err = cfg.Listen(context.TODO(), network, addr)
+	default:
+		ln, err = net.Listen(network, addr)
 	}
 	if err != nil {
 		return
 	}
 
-	return NewKeepAliveListener(ln, "tcp", nil)
+	return &keepaliveListener{Listener: ln, sopts: sopts}, nil
 }
 
 func wrapTLS(scheme string, tlsinfo *TLSInfo, l net.Listener) (net.Listener, error) {
@@ -16,6 +16,7 @@ package transport
 
 import (
 	"net"
+	"os"
 	"time"
 )
 
@@ -23,9 +24,12 @@ type ListenerOptions struct {
 	Listener     net.Listener
 	ListenConfig net.ListenConfig
 
+	network          string
 	socketOpts       *SocketOpts
 	tlsInfo          *TLSInfo
 	skipTLSInfoCheck bool
+	socketMode       os.FileMode
+	inherited        net.Listener
 	writeTimeout     time.Duration
 	readTimeout      time.Duration
 }
@@ -50,7 +54,7 @@ func (lo *ListenerOptions) IsSocketOpts() bool {
 	if lo.socketOpts == nil {
 		return false
 	}
-	return lo.socketOpts.ReusePort || lo.socketOpts.ReuseAddress
+	return !lo.socketOpts.Empty()
 }
 
 // IsTLS returns true if listner options includes TLSInfo.
@@ -77,6 +81,26 @@ func WithSocketOpts(s *SocketOpts) ListenerOption {
 	return func(lo *ListenerOptions) { lo.socketOpts = s }
 }
 
+// WithNetwork sets the network of the TCP listener, "tcp" by default. Use
+// "tcp4" or "tcp6" to listen on the IPv4 or the IPv6 wildcard address only,
+// e.g. to listen on both on the same port.
+func WithNetwork(network string) ListenerOption {
+	return func(lo *ListenerOptions) { lo.network = network }
+}
+
+// WithSocketMode sets the permissions of the file of the unix socket
+// listener, those of the umask by default.
+func WithSocketMode(mode os.FileMode) ListenerOption {
+	return func(lo *ListenerOptions) { lo.socketMode = mode }
+}
+
+// WithInheritedListener sets the listener of the address inherited from the
+// parent process, e.g. passed by systemd socket activation, to use instead of
+// listening on the address.
+func WithInheritedListener(l net.Listener) ListenerOption {
+	return func(lo *ListenerOptions) { lo.inherited = l }
+}
+
 // WithTLSInfo adds TLS credentials to the listener.
 func WithTLSInfo(t *TLSInfo) ListenerOption {
 	return func(lo *ListenerOptions) { lo.tlsInfo = t }
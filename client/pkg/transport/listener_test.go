@@ -21,6 +21,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -526,6 +527,43 @@ func TestNewListenerUnixSocket(t *testing.T) {
 	l.Close()
 }
 
+func TestNewListenerUnixSocketMode(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "etcd.sock")
+	// a socket left by a previous listener is removed
+	stale, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := NewListenerWithOpts(addr, "unix", WithSocketMode(0660))
+	if err != nil {
+		t.Fatalf("error listening on unix socket (%v)", err)
+	}
+	defer l.Close()
+	fi, err := os.Stat(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0660 {
+		t.Errorf("expected permissions 0660, got %o", perm)
+	}
+}
+
+func TestNewListenerUnixSocketNotRemovingFile(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "etcd.sock")
+	if err := os.WriteFile(addr, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewListener(addr, "unix", nil); err == nil {
+		t.Fatal("expected error listening on a regular file")
+	}
+	if _, err := os.Stat(addr); err != nil {
+		t.Fatalf("expected the regular file to be kept (%v)", err)
+	}
+}
+
 // TestNewListenerTLSInfoSelfCert tests that a new certificate accepts connections.
 func TestNewListenerTLSInfoSelfCert(t *testing.T) {
 	tmpdir := t.TempDir()
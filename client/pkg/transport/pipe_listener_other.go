@@ -0,0 +1,37 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+var errPipeUnsupported = errors.New("named pipes are only supported on Windows")
+
+// NewPipeListener returns an error, named pipes being only supported on
+// Windows.
+func NewPipeListener(name string) (net.Listener, error) {
+	return nil, errPipeUnsupported
+}
+
+// DialPipe returns an error, named pipes being only supported on Windows.
+func DialPipe(ctx context.Context, name string) (net.Conn, error) {
+	return nil, errPipeUnsupported
+}
@@ -0,0 +1,407 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+// +build windows
+
+package transport
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const pipeBufferSize = 64 * 1024
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "npipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+type pipeListener struct {
+	name string
+	// closec is signaled on close, to abort the pending accept.
+	closec windows.Handle
+	closed int32
+
+	// mu serializes the accepts, each waiting for a client to connect to
+	// the instance of the pipe with the overlapped structure.
+	mu sync.Mutex
+	h  windows.Handle
+	ov windows.Overlapped
+}
+
+// NewPipeListener listens on the windows named pipe of the given name, e.g.
+// `\\.\pipe\etcd`, only accepting local clients. The pipe has the default
+// security descriptor, only letting the clients of the same user, of the
+// administrators and of the system write to it.
+func NewPipeListener(name string) (net.Listener, error) {
+	l := &pipeListener{name: name}
+	var err error
+	if l.closec, err = windows.CreateEvent(nil, 1, 0, nil); err != nil {
+		return nil, l.opError("listen", err)
+	}
+	if l.ov.HEvent, err = windows.CreateEvent(nil, 1, 0, nil); err != nil {
+		windows.CloseHandle(l.closec)
+		return nil, l.opError("listen", err)
+	}
+	// the first instance fails if the pipe is already listened on
+	if l.h, err = l.newInstance(true); err != nil {
+		windows.CloseHandle(l.closec)
+		windows.CloseHandle(l.ov.HEvent)
+		return nil, l.opError("listen", err)
+	}
+	return l, nil
+}
+
+func (l *pipeListener) newInstance(first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(l.name)
+	if err != nil {
+		return 0, err
+	}
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX | windows.FILE_FLAG_OVERLAPPED)
+	if first {
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	mode := uint32(windows.PIPE_TYPE_BYTE | windows.PIPE_READMODE_BYTE | windows.PIPE_WAIT | windows.PIPE_REJECT_REMOTE_CLIENTS)
+	return windows.CreateNamedPipe(name, flags, mode, windows.PIPE_UNLIMITED_INSTANCES, pipeBufferSize, pipeBufferSize, 0, nil)
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if atomic.LoadInt32(&l.closed) == 1 {
+		return nil, l.opError("accept", net.ErrClosed)
+	}
+	h := l.h
+	l.h = 0
+	if h == 0 {
+		var err error
+		if h, err = l.newInstance(false); err != nil {
+			return nil, l.opError("accept", err)
+		}
+	}
+	if err := l.connect(h); err != nil {
+		windows.CloseHandle(h)
+		return nil, l.opError("accept", err)
+	}
+	// the next client connects to the next instance, created again by the
+	// next accept on failure
+	if nh, err := l.newInstance(false); err == nil {
+		l.h = nh
+	}
+	c, err := newPipeConn(h, l.name)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, l.opError("accept", err)
+	}
+	return c, nil
+}
+
+// connect waits for a client to connect to the instance of the pipe.
+func (l *pipeListener) connect(h windows.Handle) error {
+	switch err := windows.ConnectNamedPipe(h, &l.ov); err {
+	case windows.ERROR_PIPE_CONNECTED:
+		return nil
+	case nil, windows.ERROR_IO_PENDING:
+	default:
+		return err
+	}
+	ev, err := windows.WaitForMultipleObjects([]windows.Handle{l.ov.HEvent, l.closec}, false, windows.INFINITE)
+	var n uint32
+	if err == nil && ev == windows.WAIT_OBJECT_0 {
+		return windows.GetOverlappedResult(h, &l.ov, &n, true)
+	}
+	windows.CancelIoEx(h, &l.ov)
+	windows.GetOverlappedResult(h, &l.ov, &n, true)
+	if err != nil {
+		return err
+	}
+	return net.ErrClosed
+}
+
+func (l *pipeListener) Close() error {
+	if !atomic.CompareAndSwapInt32(&l.closed, 0, 1) {
+		return l.opError("close", net.ErrClosed)
+	}
+	windows.SetEvent(l.closec)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.h != 0 {
+		windows.CloseHandle(l.h)
+		l.h = 0
+	}
+	windows.CloseHandle(l.ov.HEvent)
+	windows.CloseHandle(l.closec)
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr { return pipeAddr(l.name) }
+
+func (l *pipeListener) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: "npipe", Addr: pipeAddr(l.name), Err: err}
+}
+
+// DialPipe connects to the windows named pipe of the given name, waiting for
+// an instance of the pipe to be free until the context is done.
+func DialPipe(ctx context.Context, name string) (net.Conn, error) {
+	name16, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: "npipe", Addr: pipeAddr(name), Err: err}
+	}
+	for {
+		// the server is not allowed to impersonate the client
+		flags := uint32(windows.FILE_FLAG_OVERLAPPED | windows.SECURITY_SQOS_PRESENT | windows.SECURITY_ANONYMOUS)
+		h, err := windows.CreateFile(name16, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, flags, 0)
+		if err == nil {
+			c, cerr := newPipeConn(h, name)
+			if cerr != nil {
+				windows.CloseHandle(h)
+				return nil, &net.OpError{Op: "dial", Net: "npipe", Addr: pipeAddr(name), Err: cerr}
+			}
+			return c, nil
+		}
+		if err != windows.ERROR_PIPE_BUSY {
+			return nil, &net.OpError{Op: "dial", Net: "npipe", Addr: pipeAddr(name), Err: err}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, &net.OpError{Op: "dial", Net: "npipe", Addr: pipeAddr(name), Err: ctx.Err()}
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// pipeIO is the state of the reads or of the writes of a pipe connection,
+// serialized to share the same overlapped structure.
+type pipeIO struct {
+	mu sync.Mutex
+	ov windows.Overlapped
+
+	deadlineMu sync.Mutex
+	deadline   time.Time
+	// deadlinec is signaled when the deadline changes, for the pending
+	// operation to check the new deadline.
+	deadlinec windows.Handle
+}
+
+func newPipeIO() (*pipeIO, error) {
+	ev, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		windows.CloseHandle(ev)
+		return nil, err
+	}
+	return &pipeIO{ov: windows.Overlapped{HEvent: ev}, deadlinec: dc}, nil
+}
+
+func (p *pipeIO) setDeadline(t time.Time) {
+	p.deadlineMu.Lock()
+	defer p.deadlineMu.Unlock()
+	p.deadline = t
+	if p.deadlinec != 0 {
+		windows.SetEvent(p.deadlinec)
+	}
+}
+
+// timeout returns the milliseconds left before the deadline, false if the
+// deadline is exceeded.
+func (p *pipeIO) timeout() (uint32, bool) {
+	p.deadlineMu.Lock()
+	deadline := p.deadline
+	p.deadlineMu.Unlock()
+	if deadline.IsZero() {
+		return windows.INFINITE, true
+	}
+	left := time.Until(deadline)
+	switch {
+	case left <= 0:
+		return 0, false
+	case left >= time.Duration(windows.INFINITE-1)*time.Millisecond:
+		return windows.INFINITE - 1, true
+	}
+	return uint32((left + time.Millisecond - 1) / time.Millisecond), true
+}
+
+func (p *pipeIO) close() {
+	windows.CloseHandle(p.ov.HEvent)
+	p.deadlineMu.Lock()
+	defer p.deadlineMu.Unlock()
+	windows.CloseHandle(p.deadlinec)
+	p.deadlinec = 0
+}
+
+type pipeConn struct {
+	h    windows.Handle
+	addr pipeAddr
+	// closec is signaled on close, to abort the pending operations.
+	closec windows.Handle
+	closed int32
+
+	rio, wio *pipeIO
+}
+
+func newPipeConn(h windows.Handle, name string) (*pipeConn, error) {
+	closec, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	rio, err := newPipeIO()
+	if err != nil {
+		windows.CloseHandle(closec)
+		return nil, err
+	}
+	wio, err := newPipeIO()
+	if err != nil {
+		windows.CloseHandle(closec)
+		rio.close()
+		return nil, err
+	}
+	return &pipeConn{h: h, addr: pipeAddr(name), closec: closec, rio: rio, wio: wio}, nil
+}
+
+// do starts the operation and waits for its completion, until the deadline
+// is exceeded or the connection is closed.
+func (c *pipeConn) do(p *pipeIO, start func(*windows.Overlapped) error) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return 0, net.ErrClosed
+	}
+	if _, ok := p.timeout(); !ok {
+		return 0, os.ErrDeadlineExceeded
+	}
+	err := start(&p.ov)
+	if err != nil && err != windows.ERROR_IO_PENDING {
+		return 0, err
+	}
+	var n uint32
+	if err == windows.ERROR_IO_PENDING {
+		if werr := c.wait(p); werr != nil {
+			windows.CancelIoEx(c.h, &p.ov)
+			// the operation may have completed before being canceled
+			if err = windows.GetOverlappedResult(c.h, &p.ov, &n, true); err != nil {
+				return int(n), werr
+			}
+			return int(n), nil
+		}
+	}
+	err = windows.GetOverlappedResult(c.h, &p.ov, &n, true)
+	return int(n), err
+}
+
+// wait waits for the pending operation to complete, returning an error if
+// the deadline is exceeded or the connection is closed first.
+func (c *pipeConn) wait(p *pipeIO) error {
+	for {
+		ms, ok := p.timeout()
+		if !ok {
+			return os.ErrDeadlineExceeded
+		}
+		ev, err := windows.WaitForMultipleObjects([]windows.Handle{p.ov.HEvent, p.deadlinec, c.closec}, false, ms)
+		if err != nil {
+			return err
+		}
+		switch ev {
+		case windows.WAIT_OBJECT_0:
+			return nil
+		case windows.WAIT_OBJECT_0 + 2:
+			return net.ErrClosed
+		}
+		// the deadline changed or is exceeded
+	}
+}
+
+func (c *pipeConn) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := c.do(c.rio, func(ov *windows.Overlapped) error {
+		return windows.ReadFile(c.h, b, nil, ov)
+	})
+	switch err {
+	case nil:
+		return n, nil
+	case windows.ERROR_BROKEN_PIPE, windows.ERROR_PIPE_NOT_CONNECTED:
+		return n, io.EOF
+	}
+	return n, c.opError("read", err)
+}
+
+func (c *pipeConn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		n, err := c.do(c.wio, func(ov *windows.Overlapped) error {
+			return windows.WriteFile(c.h, b[written:], nil, ov)
+		})
+		written += n
+		if err != nil {
+			return written, c.opError("write", err)
+		}
+	}
+	return written, nil
+}
+
+func (c *pipeConn) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return c.opError("close", net.ErrClosed)
+	}
+	windows.SetEvent(c.closec)
+	// the handle is closed once the pending operations are aborted
+	c.rio.mu.Lock()
+	defer c.rio.mu.Unlock()
+	c.wio.mu.Lock()
+	defer c.wio.mu.Unlock()
+	err := windows.CloseHandle(c.h)
+	windows.CloseHandle(c.closec)
+	c.rio.close()
+	c.wio.close()
+	if err != nil {
+		return c.opError("close", err)
+	}
+	return nil
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
+
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	c.rio.setDeadline(t)
+	c.wio.setDeadline(t)
+	return nil
+}
+
+func (c *pipeConn) SetReadDeadline(t time.Time) error {
+	c.rio.setDeadline(t)
+	return nil
+}
+
+func (c *pipeConn) SetWriteDeadline(t time.Time) error {
+	c.wio.setDeadline(t)
+	return nil
+}
+
+func (c *pipeConn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: "npipe", Addr: c.addr, Err: err}
+}
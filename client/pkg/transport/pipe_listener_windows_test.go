@@ -0,0 +1,101 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+// +build windows
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewListenerPipe(t *testing.T) {
+	name := fmt.Sprintf(`\\.\pipe\etcd-test-%d`, time.Now().UnixNano())
+	ln, err := NewListenerWithOpts(name, "npipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if _, err = NewPipeListener(name); err == nil {
+		t.Fatal("expected listening on the pipe listened on to fail")
+	}
+
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		c, aerr := ln.Accept()
+		if aerr != nil {
+			t.Error(aerr)
+			return
+		}
+		defer c.Close()
+		io.Copy(c, c)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := DialPipe(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 4)
+	if _, err = io.ReadFull(c, b); err != nil || string(b) != "ping" {
+		t.Fatalf("read %q, %v, expected %q", b, err, "ping")
+	}
+
+	// the pending read is aborted by the deadline
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c.SetReadDeadline(time.Now())
+	}()
+	if _, err = c.Read(b); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("err = %v, expected %v", err, os.ErrDeadlineExceeded)
+	}
+
+	c.Close()
+	<-donec
+}
+
+func TestPipeListenerCloseAbortsAccept(t *testing.T) {
+	ln, err := NewPipeListener(fmt.Sprintf(`\\.\pipe\etcd-test-%d`, time.Now().UnixNano()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, aerr := ln.Accept()
+		errc <- aerr
+	}()
+	time.Sleep(100 * time.Millisecond)
+	ln.Close()
+	select {
+	case err = <-errc:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("err = %v, expected %v", err, net.ErrClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("accept not aborted by close")
+	}
+}
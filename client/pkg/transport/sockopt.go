@@ -15,9 +15,16 @@
 package transport
 
 import (
+	"fmt"
+	"net"
 	"syscall"
+	"time"
 )
 
+// defaultKeepAliveTime is the idle time of the accepted connections before
+// the first keepalive probe if not configured.
+const defaultKeepAliveTime = 30 * time.Second
+
 type Controls []func(network, addr string, conn syscall.RawConn) error
 
 func (ctls Controls) Control(network, addr string, conn syscall.RawConn) error {
@@ -41,6 +48,28 @@ type SocketOpts struct {
 	// in cases where etcd slow to restart due to excessive `TIME_WAIT`.
 	// [1] https://man7.org/linux/man-pages/man7/socket.7.html
 	ReuseAddress bool `json:"reuse-address"`
+	// KeepAliveTime is the idle time of the accepted connections before the
+	// first TCP keepalive probe (TCP_KEEPIDLE), 30 seconds if zero.
+	KeepAliveTime time.Duration `json:"keep-alive-time"`
+	// KeepAliveInterval is the interval between the TCP keepalive probes
+	// (TCP_KEEPINTVL), the keepalive time if zero. Only supported on Linux.
+	KeepAliveInterval time.Duration `json:"keep-alive-interval"`
+	// KeepAliveCount is the number of the unacknowledged TCP keepalive probes
+	// before the connection is dropped (TCP_KEEPCNT), the system default if
+	// zero. Only supported on Linux.
+	KeepAliveCount int `json:"keep-alive-count"`
+	// UserTimeout is the maximum time the transmitted data may remain
+	// unacknowledged before the connection is dropped (TCP_USER_TIMEOUT), the
+	// system default, up to about 15 minutes of retransmissions, if zero.
+	// Only supported on Linux.
+	// [1] https://man7.org/linux/man-pages/man7/tcp.7.html
+	UserTimeout time.Duration `json:"user-timeout"`
+	// ReadBufferSize is the size in bytes of the receive buffer of the
+	// accepted connections (SO_RCVBUF), the system default if zero.
+	ReadBufferSize int `json:"read-buffer-size"`
+	// WriteBufferSize is the size in bytes of the send buffer of the
+	// accepted connections (SO_SNDBUF), the system default if zero.
+	WriteBufferSize int `json:"write-buffer-size"`
 }
 
 func getControls(sopts *SocketOpts) Controls {
@@ -55,5 +84,66 @@ func getControls(sopts *SocketOpts) Controls {
 }
 
 func (sopts *SocketOpts) Empty() bool {
-	return !sopts.ReuseAddress && !sopts.ReusePort
+	return !sopts.ReuseAddress && !sopts.ReusePort && !sopts.isConnOpts()
+}
+
+// isConnOpts returns whether the options of the accepted connections are
+// set.
+func (sopts *SocketOpts) isConnOpts() bool {
+	return sopts.KeepAliveTime != 0 || sopts.KeepAliveInterval != 0 || sopts.KeepAliveCount != 0 ||
+		sopts.UserTimeout != 0 || sopts.ReadBufferSize != 0 || sopts.WriteBufferSize != 0
+}
+
+// Validate checks the options are not negative, and are supported on the
+// platform.
+func (sopts *SocketOpts) Validate() error {
+	if sopts.KeepAliveTime < 0 || sopts.KeepAliveInterval < 0 || sopts.KeepAliveCount < 0 || sopts.UserTimeout < 0 ||
+		sopts.ReadBufferSize < 0 || sopts.WriteBufferSize < 0 {
+		return fmt.Errorf("socket options must not be negative")
+	}
+	if sopts.KeepAliveInterval != 0 || sopts.KeepAliveCount != 0 || sopts.UserTimeout != 0 {
+		return checkTCPOpts()
+	}
+	return nil
+}
+
+// setConnOpts sets the options of an accepted connection.
+func setConnOpts(sopts *SocketOpts, c *net.TCPConn) error {
+	keepAliveTime := defaultKeepAliveTime
+	if sopts != nil && sopts.KeepAliveTime > 0 {
+		keepAliveTime = sopts.KeepAliveTime
+	}
+	// detection time: tcp_keepalive_time + tcp_keepalive_probes + tcp_keepalive_intvl
+	// default on linux:  30 + 8 * 30
+	// default on osx:    30 + 8 * 75
+	if err := c.SetKeepAlive(true); err != nil {
+		return fmt.Errorf("SetKeepAlive failed, %w", err)
+	}
+	if err := c.SetKeepAlivePeriod(keepAliveTime); err != nil {
+		return fmt.Errorf("SetKeepAlivePeriod failed, %w", err)
+	}
+	if sopts == nil {
+		return nil
+	}
+	if sopts.KeepAliveInterval > 0 || sopts.KeepAliveCount > 0 {
+		if err := setKeepAliveProbes(c, sopts.KeepAliveInterval, sopts.KeepAliveCount); err != nil {
+			return fmt.Errorf("set keepalive probes failed, %w", err)
+		}
+	}
+	if sopts.UserTimeout > 0 {
+		if err := setUserTimeout(c, sopts.UserTimeout); err != nil {
+			return fmt.Errorf("set user timeout failed, %w", err)
+		}
+	}
+	if sopts.ReadBufferSize > 0 {
+		if err := c.SetReadBuffer(sopts.ReadBufferSize); err != nil {
+			return fmt.Errorf("SetReadBuffer failed, %w", err)
+		}
+	}
+	if sopts.WriteBufferSize > 0 {
+		if err := c.SetWriteBuffer(sopts.WriteBufferSize); err != nil {
+			return fmt.Errorf("SetWriteBuffer failed, %w", err)
+		}
+	}
+	return nil
 }
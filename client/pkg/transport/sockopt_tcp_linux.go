@@ -0,0 +1,65 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package transport
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func checkTCPOpts() error { return nil }
+
+func setKeepAliveProbes(c *net.TCPConn, interval time.Duration, count int) error {
+	return setTCPOpts(c, func(fd int) error {
+		if interval > 0 {
+			if err := unix.SetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_KEEPINTVL, roundSeconds(interval)); err != nil {
+				return err
+			}
+		}
+		if count > 0 {
+			return unix.SetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_KEEPCNT, count)
+		}
+		return nil
+	})
+}
+
+func setUserTimeout(c *net.TCPConn, d time.Duration) error {
+	return setTCPOpts(c, func(fd int) error {
+		return unix.SetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, int(d.Milliseconds()))
+	})
+}
+
+func setTCPOpts(c *net.TCPConn, set func(fd int) error) error {
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err := rc.Control(func(fd uintptr) { serr = set(int(fd)) }); err != nil {
+		return err
+	}
+	return serr
+}
+
+// roundSeconds rounds up the duration to seconds, the unit of the keepalive
+// options.
+func roundSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package transport
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestNewListenerWithConnSocketOpts(t *testing.T) {
+	sopts := &SocketOpts{
+		KeepAliveTime:     10 * time.Second,
+		KeepAliveInterval: 3 * time.Second,
+		KeepAliveCount:    4,
+		UserTimeout:       20 * time.Second,
+	}
+	ln, err := NewListenerWithOpts("127.0.0.1:0", "http", WithSocketOpts(sopts))
+	if err != nil {
+		t.Fatalf("unexpected NewListenerWithOpts error: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		if c, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+			defer c.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("unexpected Accept error: %v", err)
+	}
+	defer conn.Close()
+	// the socket options make the listener a timeout listener
+	rc, err := conn.(timeoutConn).Conn.(*keepAliveConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opt  int
+		want int
+	}{
+		{"TCP_KEEPIDLE", unix.TCP_KEEPIDLE, 10},
+		{"TCP_KEEPINTVL", unix.TCP_KEEPINTVL, 3},
+		{"TCP_KEEPCNT", unix.TCP_KEEPCNT, 4},
+		{"TCP_USER_TIMEOUT", unix.TCP_USER_TIMEOUT, 20000},
+	}
+	for _, tt := range tests {
+		var v int
+		var gerr error
+		if err := rc.Control(func(fd uintptr) { v, gerr = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, tt.opt) }); err != nil {
+			t.Fatal(err)
+		}
+		if gerr != nil {
+			t.Fatalf("%s: %v", tt.name, gerr)
+		}
+		if v != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, v, tt.want)
+		}
+	}
+}
+
+func TestSocketOptsValidate(t *testing.T) {
+	tests := []struct {
+		sopts   SocketOpts
+		wantErr bool
+	}{
+		{SocketOpts{}, false},
+		{SocketOpts{KeepAliveTime: time.Second, KeepAliveInterval: time.Second, KeepAliveCount: 3, UserTimeout: time.Second}, false},
+		{SocketOpts{ReadBufferSize: 1 << 20, WriteBufferSize: 1 << 20}, false},
+		{SocketOpts{KeepAliveCount: -1}, true},
+		{SocketOpts{UserTimeout: -time.Second}, true},
+	}
+	for i, tt := range tests {
+		if err := tt.sopts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("#%d: Validate() = %v, want error %v", i, err, tt.wantErr)
+		}
+	}
+}
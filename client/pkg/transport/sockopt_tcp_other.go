@@ -0,0 +1,36 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package transport
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+func checkTCPOpts() error {
+	return fmt.Errorf("keepalive interval, keepalive count and user timeout are only supported on Linux")
+}
+
+func setKeepAliveProbes(c *net.TCPConn, interval time.Duration, count int) error {
+	return checkTCPOpts()
+}
+
+func setUserTimeout(c *net.TCPConn, d time.Duration) error {
+	return checkTCPOpts()
+}
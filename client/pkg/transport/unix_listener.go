@@ -15,13 +15,19 @@
 package transport
 
 import (
+	"fmt"
 	"net"
 	"os"
 )
 
 type unixListener struct{ net.Listener }
 
+// NewUnixListener listens on the unix socket of the given path, removing the
+// socket left by a previous listener, but not a regular file or a directory.
 func NewUnixListener(addr string) (net.Listener, error) {
+	if fi, err := os.Lstat(addr); err == nil && (fi.Mode().IsRegular() || fi.IsDir()) {
+		return nil, fmt.Errorf("cannot listen on unix socket %s: file exists and is not a socket", addr)
+	}
 	if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
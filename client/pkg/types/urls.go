@@ -34,13 +34,16 @@ func NewURLs(strs []string) (URLs, error) {
 		in = strings.TrimSpace(in)
 		u, err := url.Parse(in)
 		if err != nil {
+			if hasUnescapedZone(in) {
+				return nil, fmt.Errorf(`URL IPv6 zone must be escaped as "%%25", e.g. "http://[fe80::1%%25eth0]:2379": %s`, in)
+			}
 			return nil, err
 		}
 
 		switch u.Scheme {
 		case "http", "https":
-			if _, _, err := net.SplitHostPort(u.Host); err != nil {
-				return nil, fmt.Errorf(`URL address does not have the form "host:port": %s`, in)
+			if err := checkHostPort(u); err != nil {
+				return nil, fmt.Errorf("%v: %s", err, in)
 			}
 
 			if u.Path != "" {
@@ -58,6 +61,36 @@ func NewURLs(strs []string) (URLs, error) {
 	return us, nil
 }
 
+// checkHostPort checks that the host of the URL is "host:port", explaining
+// the common mistakes with IPv6 addresses.
+func checkHostPort(u *url.URL) error {
+	_, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		if i := strings.LastIndex(u.Host, ":"); i > 0 && !strings.HasPrefix(u.Host, "[") && net.ParseIP(u.Host[:i]) != nil {
+			return fmt.Errorf(`URL IPv6 address must be enclosed in brackets, e.g. "%s://%s"`, u.Scheme, net.JoinHostPort(u.Host[:i], u.Host[i+1:]))
+		}
+		if net.ParseIP(strings.Trim(u.Host, "[]")) != nil || !strings.Contains(u.Host, ":") {
+			return errors.New(`URL address does not have the form "host:port" (missing port)`)
+		}
+		return errors.New(`URL address does not have the form "host:port"`)
+	}
+	if port == "" {
+		return errors.New(`URL address does not have the form "host:port" (missing port)`)
+	}
+	return nil
+}
+
+// hasUnescapedZone returns whether the URL has an IPv6 zone whose "%" is not
+// escaped as "%25", which url.Parse rejects.
+func hasUnescapedZone(in string) bool {
+	start, end := strings.Index(in, "["), strings.Index(in, "]")
+	if start < 0 || end < start {
+		return false
+	}
+	i := strings.Index(in[start:end], "%")
+	return i >= 0 && !strings.HasPrefix(in[start+i:end], "%25")
+}
+
 func MustNewURLs(strs []string) URLs {
 	urls, err := NewURLs(strs)
 	if err != nil {
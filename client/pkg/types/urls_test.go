@@ -46,6 +46,14 @@ func TestNewURLs(t *testing.T) {
 				"http://127.0.0.2:2379",
 			}),
 		},
+		// IPv6 addresses, with a zone
+		{
+			[]string{"http://[::1]:2379", "http://[fe80::1%25eth0]:2379"},
+			URLs{
+				{Scheme: "http", Host: "[::1]:2379"},
+				{Scheme: "http", Host: "[fe80::1%eth0]:2379"},
+			},
+		},
 	}
 	for i, tt := range tests {
 		urls, _ := NewURLs(tt.strs)
@@ -55,6 +63,26 @@ func TestNewURLs(t *testing.T) {
 	}
 }
 
+func TestNewURLsErrorMessage(t *testing.T) {
+	tests := []struct {
+		str  string
+		werr string
+	}{
+		{"http://::1:2379", `URL IPv6 address must be enclosed in brackets, e.g. "http://[::1]:2379": http://::1:2379`},
+		{"http://[fe80::1%eth0]:2379", `URL IPv6 zone must be escaped as "%25", e.g. "http://[fe80::1%25eth0]:2379": http://[fe80::1%eth0]:2379`},
+		{"http://127.0.0.1", `URL address does not have the form "host:port" (missing port): http://127.0.0.1`},
+		{"http://[::1]", `URL address does not have the form "host:port" (missing port): http://[::1]`},
+		{"http://127.0.0.1:", `URL address does not have the form "host:port" (missing port): http://127.0.0.1:`},
+		{"npipe:////./pipe/etcd", `URL scheme must be http, https, unix, or unixs: npipe:////./pipe/etcd`},
+	}
+	for i, tt := range tests {
+		_, err := NewURLs([]string{tt.str})
+		if err == nil || err.Error() != tt.werr {
+			t.Errorf("#%d: err = %v, want %s", i, err, tt.werr)
+		}
+	}
+}
+
 func TestURLsString(t *testing.T) {
 	tests := []struct {
 		us   URLs
@@ -159,6 +187,12 @@ func TestNewURLsFail(t *testing.T) {
 		{"http://127.0.0.1"},
 		// contain a path
 		{"http://127.0.0.1:2379/path"},
+		// IPv6 address not enclosed in brackets
+		{"http://::1:2379"},
+		// unescaped IPv6 zone
+		{"http://[fe80::1%eth0]:2379"},
+		// IPv6 address missing port
+		{"http://[::1]"},
 	}
 	for i, tt := range tests {
 		_, err := NewURLs(tt)
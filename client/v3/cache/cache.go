@@ -0,0 +1,250 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+// progressRequestInterval is the minimum interval between two progress
+// requests of a watch.
+const progressRequestInterval = time.Second
+
+// rangeCache caches the key-value pairs of the range [begin, end).
+type rangeCache struct {
+	begin, end string
+
+	mu sync.RWMutex
+	// kvs are sorted by key.
+	kvs []*mvccpb.KeyValue
+	// ready is true once the cache is loaded, until it is reset.
+	ready  bool
+	header pb.ResponseHeader
+	// rev is the revision the cache is up to date with.
+	rev int64
+	// wctx is the context of the watch, for its progress requests.
+	wctx context.Context
+
+	// progressRequested is the time of the last progress request, in unix
+	// nanoseconds.
+	progressRequested int64
+}
+
+func newRangeCache(begin, end string) *rangeCache {
+	return &rangeCache{begin: begin, end: end}
+}
+
+// contains returns true if the keys of the get are in the range.
+func (rc *rangeCache) contains(op clientv3.Op) bool {
+	key, end := string(op.KeyBytes()), string(op.RangeBytes())
+	if key < rc.begin {
+		return false
+	}
+	switch {
+	case rc.end == "\x00":
+		return true
+	case end == "":
+		return key < rc.end
+	case end == "\x00":
+		return false
+	}
+	return end <= rc.end
+}
+
+// load fills the cache with the key-value pairs of the range read.
+func (rc *rangeCache) load(wctx context.Context, resp *clientv3.GetResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.kvs = resp.Kvs
+	rc.header = *resp.Header
+	rc.rev = resp.Header.Revision
+	rc.wctx = wctx
+	rc.ready = true
+}
+
+// reset empties the cache, the reads being served by the cluster until it is
+// loaded again.
+func (rc *rangeCache) reset() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.kvs = nil
+	rc.wctx = nil
+	rc.ready = false
+}
+
+// apply applies the events of the watch response, or the revision of the
+// progress notification, to the cache.
+func (rc *rangeCache) apply(wresp clientv3.WatchResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if wresp.IsProgressNotify() {
+		// only sent once the events up to the revision are sent
+		if wresp.Header.Revision > rc.rev {
+			rc.rev = wresp.Header.Revision
+		}
+		return
+	}
+	// the header revision of the events caught up is the current revision,
+	// not the revision of the last event
+	for _, ev := range wresp.Events {
+		i := sort.Search(len(rc.kvs), func(i int) bool { return bytes.Compare(rc.kvs[i].Key, ev.Kv.Key) >= 0 })
+		found := i < len(rc.kvs) && bytes.Equal(rc.kvs[i].Key, ev.Kv.Key)
+		switch {
+		case ev.Type == clientv3.EventTypeDelete && found:
+			rc.kvs = append(rc.kvs[:i], rc.kvs[i+1:]...)
+		case ev.Type == clientv3.EventTypePut && found:
+			rc.kvs[i] = ev.Kv
+		case ev.Type == clientv3.EventTypePut:
+			rc.kvs = append(rc.kvs, nil)
+			copy(rc.kvs[i+1:], rc.kvs[i:])
+			rc.kvs[i] = ev.Kv
+		}
+		if ev.Kv.ModRevision > rc.rev {
+			rc.rev = ev.Kv.ModRevision
+		}
+	}
+}
+
+// get returns the response of the get from the cache, false if the cache is
+// not loaded or older than the given revision, the progress of the watch
+// being requested then.
+func (rc *rangeCache) get(op clientv3.Op, minRev int64, w clientv3.Watcher) (*clientv3.GetResponse, bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	if !rc.ready {
+		return nil, false
+	}
+	if rc.rev < minRev {
+		rc.requestProgress(w)
+		return nil, false
+	}
+
+	key, end := op.KeyBytes(), op.RangeBytes()
+	lo := sort.Search(len(rc.kvs), func(i int) bool { return bytes.Compare(rc.kvs[i].Key, key) >= 0 })
+	hi := lo
+	switch {
+	case len(end) == 0:
+		if lo < len(rc.kvs) && bytes.Equal(rc.kvs[lo].Key, key) {
+			hi = lo + 1
+		}
+	case bytes.Equal(end, []byte{0}):
+		hi = len(rc.kvs)
+	default:
+		hi = sort.Search(len(rc.kvs), func(i int) bool { return bytes.Compare(rc.kvs[i].Key, end) >= 0 })
+		if hi < lo {
+			hi = lo
+		}
+	}
+
+	header := rc.header
+	header.Revision = rc.rev
+	resp := &clientv3.GetResponse{Header: &header, Count: int64(hi - lo)}
+	if op.IsCountOnly() {
+		return resp, true
+	}
+	kvs := make([]*mvccpb.KeyValue, 0, hi-lo)
+	for _, kv := range rc.kvs[lo:hi] {
+		if matchRevisions(op, kv) {
+			kvs = append(kvs, kv)
+		}
+	}
+	sortKVs(op, kvs)
+	if limit := int(op.Limit()); limit > 0 && len(kvs) > limit {
+		kvs = kvs[:limit]
+		resp.More = true
+	}
+	// the cached key-value pairs are shared, and must not be modified
+	resp.Kvs = make([]*mvccpb.KeyValue, len(kvs))
+	for i, kv := range kvs {
+		ckv := *kv
+		ckv.Key = append([]byte(nil), kv.Key...)
+		if op.IsKeysOnly() {
+			ckv.Value = nil
+		} else {
+			ckv.Value = append([]byte(nil), kv.Value...)
+		}
+		resp.Kvs[i] = &ckv
+	}
+	return resp, true
+}
+
+// requestProgress requests the progress of the watch, for the cache to catch
+// up with the revision of the writes outside of its range.
+func (rc *rangeCache) requestProgress(w clientv3.Watcher) {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&rc.progressRequested)
+	if now-last < int64(progressRequestInterval) || !atomic.CompareAndSwapInt64(&rc.progressRequested, last, now) {
+		return
+	}
+	go w.RequestProgress(rc.wctx)
+}
+
+// matchRevisions returns true if the key-value pair matches the revision
+// filters of the get.
+func matchRevisions(op clientv3.Op, kv *mvccpb.KeyValue) bool {
+	switch {
+	case op.MaxModRev() != 0 && kv.ModRevision > op.MaxModRev():
+		return false
+	case op.MinModRev() != 0 && kv.ModRevision < op.MinModRev():
+		return false
+	case op.MaxCreateRev() != 0 && kv.CreateRevision > op.MaxCreateRev():
+		return false
+	case op.MinCreateRev() != 0 && kv.CreateRevision < op.MinCreateRev():
+		return false
+	}
+	return true
+}
+
+// sortKVs sorts the key-value pairs, sorted by key, as the cluster does.
+func sortKVs(op clientv3.Op, kvs []*mvccpb.KeyValue) {
+	so, ok := op.Sort()
+	if !ok {
+		return
+	}
+	order := so.Order
+	if so.Target != clientv3.SortByKey && order == clientv3.SortNone {
+		order = clientv3.SortAscend
+	}
+	if order == clientv3.SortNone || (so.Target == clientv3.SortByKey && order == clientv3.SortAscend) {
+		return
+	}
+	var less func(a, b *mvccpb.KeyValue) bool
+	switch so.Target {
+	case clientv3.SortByKey:
+		less = func(a, b *mvccpb.KeyValue) bool { return bytes.Compare(a.Key, b.Key) < 0 }
+	case clientv3.SortByVersion:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.Version < b.Version }
+	case clientv3.SortByCreateRevision:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.CreateRevision < b.CreateRevision }
+	case clientv3.SortByModRevision:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.ModRevision < b.ModRevision }
+	case clientv3.SortByValue:
+		less = func(a, b *mvccpb.KeyValue) bool { return bytes.Compare(a.Value, b.Value) < 0 }
+	}
+	if order == clientv3.SortDescend {
+		sort.SliceStable(kvs, func(i, j int) bool { return less(kvs[j], kvs[i]) })
+		return
+	}
+	sort.SliceStable(kvs, func(i, j int) bool { return less(kvs[i], kvs[j]) })
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+func TestRangeCacheContains(t *testing.T) {
+	rc := newRangeCache("foo/", clientv3.GetPrefixRangeEnd("foo/"))
+	all := newRangeCache("", clientv3.GetPrefixRangeEnd(""))
+	tests := []struct {
+		op        clientv3.Op
+		contained bool
+		all       bool
+	}{
+		{clientv3.OpGet("foo/a"), true, true},
+		{clientv3.OpGet("foo/", clientv3.WithPrefix()), true, true},
+		{clientv3.OpGet("foo/a", clientv3.WithRange("foo/c")), true, true},
+		{clientv3.OpGet("foo/a", clientv3.WithRange("fop")), false, true},
+		{clientv3.OpGet("foo/a", clientv3.WithFromKey()), false, true},
+		{clientv3.OpGet("fo", clientv3.WithPrefix()), false, true},
+		{clientv3.OpGet("bar"), false, true},
+		{clientv3.OpGet("", clientv3.WithPrefix()), false, true},
+	}
+	for i, tt := range tests {
+		if contained := rc.contains(tt.op); contained != tt.contained {
+			t.Errorf("#%d: contains = %v, expected %v", i, contained, tt.contained)
+		}
+		if contained := all.contains(tt.op); contained != tt.all {
+			t.Errorf("#%d: contains of the whole keyspace = %v, expected %v", i, contained, tt.all)
+		}
+	}
+}
+
+func TestRangeCacheGet(t *testing.T) {
+	rc := newRangeCache("foo/", clientv3.GetPrefixRangeEnd("foo/"))
+	if _, ok := rc.get(clientv3.OpGet("foo/a"), 0, nil); ok {
+		t.Fatal("expected the cache not loaded not to serve gets")
+	}
+	rc.load(context.TODO(), &clientv3.GetResponse{
+		Header: &pb.ResponseHeader{ClusterId: 1, Revision: 10},
+		Kvs: []*mvccpb.KeyValue{
+			{Key: []byte("foo/a"), Value: []byte("3"), CreateRevision: 2, ModRevision: 8, Version: 2},
+			{Key: []byte("foo/b"), Value: []byte("1"), CreateRevision: 3, ModRevision: 3, Version: 1},
+		},
+	})
+	rc.apply(clientv3.WatchResponse{
+		Header: pb.ResponseHeader{Revision: 11},
+		Events: []*clientv3.Event{
+			{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte("foo/c"), Value: []byte("2"), CreateRevision: 11, ModRevision: 11, Version: 1}},
+		},
+	})
+	rc.apply(clientv3.WatchResponse{
+		Header: pb.ResponseHeader{Revision: 13},
+		Events: []*clientv3.Event{
+			{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte("foo/b"), Value: []byte("4"), CreateRevision: 3, ModRevision: 12, Version: 2}},
+			{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("foo/d"), ModRevision: 13}},
+		},
+	})
+
+	tests := []struct {
+		op    clientv3.Op
+		keys  []string
+		count int64
+		more  bool
+	}{
+		{clientv3.OpGet("foo/b"), []string{"foo/b"}, 1, false},
+		{clientv3.OpGet("foo/d"), nil, 0, false},
+		{clientv3.OpGet("foo/", clientv3.WithPrefix()), []string{"foo/a", "foo/b", "foo/c"}, 3, false},
+		{clientv3.OpGet("foo/b", clientv3.WithRange("foo/c")), []string{"foo/b"}, 1, false},
+		{clientv3.OpGet("foo/", clientv3.WithPrefix(), clientv3.WithLimit(2)), []string{"foo/a", "foo/b"}, 3, true},
+		{clientv3.OpGet("foo/", clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend)), []string{"foo/c", "foo/b", "foo/a"}, 3, false},
+		{clientv3.OpGet("foo/", clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByValue, clientv3.SortNone)), []string{"foo/c", "foo/a", "foo/b"}, 3, false},
+		{clientv3.OpGet("foo/", clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByModRevision, clientv3.SortDescend), clientv3.WithLimit(1)), []string{"foo/b"}, 3, true},
+		{clientv3.OpGet("foo/", clientv3.WithPrefix(), clientv3.WithMinCreateRev(3), clientv3.WithMaxModRev(11)), []string{"foo/c"}, 3, false},
+		{clientv3.OpGet("foo/", clientv3.WithPrefix(), clientv3.WithCountOnly()), nil, 3, false},
+	}
+	for i, tt := range tests {
+		resp, ok := rc.get(tt.op, 13, nil)
+		if !ok {
+			t.Fatalf("#%d: expected the get to be served", i)
+		}
+		var keys []string
+		for _, kv := range resp.Kvs {
+			keys = append(keys, string(kv.Key))
+		}
+		if !reflect.DeepEqual(keys, tt.keys) || resp.Count != tt.count || resp.More != tt.more {
+			t.Errorf("#%d: get = %v (count %d, more %v), expected %v (count %d, more %v)", i, keys, resp.Count, resp.More, tt.keys, tt.count, tt.more)
+		}
+		if resp.Header.Revision != 13 || resp.Header.ClusterId != 1 {
+			t.Errorf("#%d: header = %+v, expected revision 13 of cluster 1", i, resp.Header)
+		}
+	}
+
+	resp, _ := rc.get(clientv3.OpGet("foo/b", clientv3.WithKeysOnly()), 0, nil)
+	if v := resp.Kvs[0].Value; v != nil {
+		t.Errorf("value = %q, expected none", v)
+	}
+	// the cached pairs are not shared
+	resp, _ = rc.get(clientv3.OpGet("foo/b"), 0, nil)
+	resp.Kvs[0].Value[0] = 'x'
+	if resp, _ = rc.get(clientv3.OpGet("foo/b"), 0, nil); string(resp.Kvs[0].Value) != "4" {
+		t.Errorf("value = %q, expected %q", resp.Kvs[0].Value, "4")
+	}
+
+	// the progress was just requested
+	rc.progressRequested = 1<<63 - 1
+	if _, ok := rc.get(clientv3.OpGet("foo/a"), 14, nil); ok {
+		t.Error("expected the cache older than the last write not to serve gets")
+	}
+	rc.reset()
+	if _, ok := rc.get(clientv3.OpGet("foo/a"), 0, nil); ok {
+		t.Error("expected the cache reset not to serve gets")
+	}
+}
+
+func TestIsWrite(t *testing.T) {
+	tests := []struct {
+		op    clientv3.Op
+		write bool
+	}{
+		{clientv3.OpGet("a"), false},
+		{clientv3.OpPut("a", "1"), true},
+		{clientv3.OpDelete("a"), true},
+		{clientv3.OpTxn(nil, []clientv3.Op{clientv3.OpGet("a")}, nil), false},
+		{clientv3.OpTxn(nil, []clientv3.Op{clientv3.OpGet("a")}, []clientv3.Op{clientv3.OpTxn(nil, []clientv3.Op{clientv3.OpPut("a", "1")}, nil)}), true},
+	}
+	for i, tt := range tests {
+		if write := isWrite(tt.op); write != tt.write {
+			t.Errorf("#%d: isWrite = %v, expected %v", i, write, tt.write)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache is a clientv3 wrapper that serves the serializable reads of
+// a few key prefixes from an in-process cache. The cache of each prefix is
+// loaded by a linearizable read, then kept coherent by a watch of the prefix
+// from the revision of the read.
+//
+// First, create a client:
+//
+//	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{"localhost:2379"}})
+//	if err != nil {
+//		// handle error!
+//	}
+//
+// Next, override the client interface with the cache wrapper, caching the
+// keys of the prefix "config/":
+//
+//	kv, closeCache := cache.NewKV(cli.KV, cli.Watcher, "config/")
+//	defer closeCache()
+//	cli.KV = kv
+//
+// Now the serializable reads within "config/" are served from the cache:
+//
+//	resp, err := cli.Get(context.TODO(), "config/", clientv3.WithPrefix(), clientv3.WithSerializable())
+//
+// The cached reads are as stale as the watches, which is acceptable for the
+// serializable reads, but reflect the writes issued through the wrapper. The
+// linearizable reads, the reads of past revisions and the reads of other keys
+// are served by the cluster, as are the serializable reads while the cache of
+// their prefix is loaded, or reloaded after its watch failed, for example on
+// the loss of the leader by the member watched.
+package cache
@@ -0,0 +1,215 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+// retryInterval is the time waited before loading the cache of a prefix
+// again after failing to.
+const retryInterval = time.Second
+
+type cachingKV struct {
+	clientv3.KV
+	w      clientv3.Watcher
+	caches []*rangeCache
+	wg     sync.WaitGroup
+
+	// writeRev is the revision of the last write through the wrapper, the
+	// cached reads reflecting it.
+	writeRev int64
+}
+
+// NewKV wraps the KV so that the serializable reads of the keys of the given
+// prefixes are served from a cache, kept coherent by the watcher. The
+// returned function stops the watches.
+func NewKV(kv clientv3.KV, w clientv3.Watcher, prefixes ...string) (clientv3.KV, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ckv := &cachingKV{KV: kv, w: w}
+	for _, pfx := range prefixes {
+		rc := newRangeCache(pfx, clientv3.GetPrefixRangeEnd(pfx))
+		ckv.caches = append(ckv.caches, rc)
+		ckv.wg.Add(1)
+		go func() {
+			defer ckv.wg.Done()
+			ckv.sync(ctx, rc)
+		}()
+	}
+	return ckv, func() {
+		cancel()
+		ckv.wg.Wait()
+	}
+}
+
+// sync loads the cache, then applies the events of its range, loading it
+// again whenever the watch fails.
+func (kv *cachingKV) sync(ctx context.Context, rc *rangeCache) {
+	for ctx.Err() == nil {
+		resp, err := kv.KV.Get(ctx, rc.begin, clientv3.WithRange(rc.end))
+		if err != nil {
+			select {
+			case <-time.After(retryInterval):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		// the watch is canceled, and the cache reset, if the member watched
+		// loses its leader, not to serve stale reads for long
+		wctx, wcancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+		rc.load(wctx, resp)
+		wch := kv.w.Watch(wctx, rc.begin,
+			clientv3.WithRange(rc.end),
+			clientv3.WithRev(resp.Header.Revision+1),
+			clientv3.WithProgressNotify(),
+		)
+		for wresp := range wch {
+			if wresp.Err() != nil {
+				break
+			}
+			rc.apply(wresp)
+		}
+		rc.reset()
+		wcancel()
+	}
+}
+
+func (kv *cachingKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	r, err := kv.Do(ctx, clientv3.OpGet(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(), nil
+}
+
+func (kv *cachingKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	r, err := kv.Do(ctx, clientv3.OpPut(key, val, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Put(), nil
+}
+
+func (kv *cachingKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	r, err := kv.Do(ctx, clientv3.OpDelete(key, opts...))
+	if err != nil {
+		return nil, err
+	}
+	return r.Del(), nil
+}
+
+func (kv *cachingKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	if op.IsGet() {
+		if resp, ok := kv.cachedGet(op); ok {
+			return resp.OpResponse(), nil
+		}
+		return kv.KV.Do(ctx, op)
+	}
+	r, err := kv.KV.Do(ctx, op)
+	if err != nil || !isWrite(op) {
+		return r, err
+	}
+	switch {
+	case r.Put() != nil:
+		kv.wrote(r.Put().Header.Revision)
+	case r.Del() != nil:
+		kv.wrote(r.Del().Header.Revision)
+	case r.Txn() != nil:
+		kv.wrote(r.Txn().Header.Revision)
+	}
+	return r, nil
+}
+
+func (kv *cachingKV) Txn(ctx context.Context) clientv3.Txn {
+	return &txnCaching{Txn: kv.KV.Txn(ctx), kv: kv}
+}
+
+// cachedGet returns the response of the get from the cache of its range,
+// false if it must be served by the cluster.
+func (kv *cachingKV) cachedGet(op clientv3.Op) (*clientv3.GetResponse, bool) {
+	if !op.IsSerializable() || op.Rev() != 0 || !op.IsSortOptionValid() {
+		return nil, false
+	}
+	for _, rc := range kv.caches {
+		if rc.contains(op) {
+			return rc.get(op, atomic.LoadInt64(&kv.writeRev), kv.w)
+		}
+	}
+	return nil, false
+}
+
+// wrote records the revision of a write through the wrapper.
+func (kv *cachingKV) wrote(rev int64) {
+	for {
+		writeRev := atomic.LoadInt64(&kv.writeRev)
+		if rev <= writeRev || atomic.CompareAndSwapInt64(&kv.writeRev, writeRev, rev) {
+			return
+		}
+	}
+}
+
+// txnCaching records the revision of the transactions with write operations.
+type txnCaching struct {
+	clientv3.Txn
+	kv    *cachingKV
+	write bool
+}
+
+func (txn *txnCaching) If(cs ...clientv3.Cmp) clientv3.Txn {
+	txn.Txn = txn.Txn.If(cs...)
+	return txn
+}
+
+func (txn *txnCaching) Then(ops ...clientv3.Op) clientv3.Txn {
+	txn.write = txn.write || hasWrite(ops)
+	txn.Txn = txn.Txn.Then(ops...)
+	return txn
+}
+
+func (txn *txnCaching) Else(ops ...clientv3.Op) clientv3.Txn {
+	txn.write = txn.write || hasWrite(ops)
+	txn.Txn = txn.Txn.Else(ops...)
+	return txn
+}
+
+func (txn *txnCaching) Commit() (*clientv3.TxnResponse, error) {
+	resp, err := txn.Txn.Commit()
+	if err == nil && txn.write {
+		txn.kv.wrote(resp.Header.Revision)
+	}
+	return resp, err
+}
+
+func isWrite(op clientv3.Op) bool {
+	if op.IsTxn() {
+		_, thenOps, elseOps := op.Txn()
+		return hasWrite(thenOps) || hasWrite(elseOps)
+	}
+	return op.IsPut() || op.IsDelete()
+}
+
+func hasWrite(ops []clientv3.Op) bool {
+	for _, op := range ops {
+		if isWrite(op) {
+			return true
+		}
+	}
+	return false
+}
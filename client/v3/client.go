@@ -26,6 +26,7 @@ import (
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/pkg/v3/logutil"
 	"go.etcd.io/etcd/client/v3/credentials"
+	"go.etcd.io/etcd/client/v3/internal/balancer"
 	"go.etcd.io/etcd/client/v3/internal/endpoint"
 	"go.etcd.io/etcd/client/v3/internal/resolver"
 	"go.uber.org/zap"
@@ -43,6 +44,11 @@ var (
 
 // Client provides and manages an etcd v3 client session.
 type Client struct {
+	// readRev is the highest revision of the key-value responses received,
+	// required by the serializable ranges if cfg.ReadYourWrites; must use
+	// atomic operations to access; keep 64-bit aligned.
+	readRev int64
+
 	Cluster
 	KV
 	Lease
@@ -244,6 +250,9 @@ func (c *Client) dialSetupOpts(creds grpccredentials.TransportCredentials, dopts
 		grpc.WithStreamInterceptor(c.streamClientInterceptor(withMax(0), rrBackoff)),
 		grpc.WithUnaryInterceptor(c.unaryClientInterceptor(withMax(defaultUnaryMaxRetries), rrBackoff)),
 	)
+	if c.cfg.ReadYourWrites {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(c.readYourWritesInterceptor()))
+	}
 
 	return opts, nil
 }
@@ -407,6 +416,20 @@ func newClient(cfg *Config) (*Client, error) {
 	}
 
 	client.resolver = resolver.New(cfg.Endpoints...)
+	if hb := cfg.HealthBalancing; hb != nil {
+		if hb.ErrorRateThreshold < 0 || hb.ErrorRateThreshold > 1 {
+			client.cancel()
+			return nil, fmt.Errorf("health balancing error rate threshold %v must be within [0, 1]", hb.ErrorRateThreshold)
+		}
+		if hb.QuarantineDuration < 0 {
+			client.cancel()
+			return nil, fmt.Errorf("health balancing quarantine duration %v must be >= 0", hb.QuarantineDuration)
+		}
+		client.resolver.SetServiceConfig(balancer.ServiceConfig(balancer.Config{
+			ErrorRateThreshold: hb.ErrorRateThreshold,
+			QuarantineDuration: hb.QuarantineDuration,
+		}))
+	}
 
 	if len(cfg.Endpoints) < 1 {
 		client.cancel()
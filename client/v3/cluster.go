@@ -30,6 +30,7 @@ type (
 	MemberRemoveResponse  pb.MemberRemoveResponse
 	MemberUpdateResponse  pb.MemberUpdateResponse
 	MemberPromoteResponse pb.MemberPromoteResponse
+	MemberDemoteResponse  pb.MemberDemoteResponse
 )
 
 type Cluster interface {
@@ -50,6 +51,12 @@ type Cluster interface {
 
 	// MemberPromote promotes a member from raft learner (non-voting) to raft voting member.
 	MemberPromote(ctx context.Context, id uint64) (*MemberPromoteResponse, error)
+
+	// MemberDemote demotes a member from raft voting member to raft learner (non-voting),
+	// for it to leave the quorum until promoted back. acknowledgeReducedFaultTolerance
+	// must be set when the demotion reduces the number of member failures the cluster
+	// tolerates.
+	MemberDemote(ctx context.Context, id uint64, acknowledgeReducedFaultTolerance bool) (*MemberDemoteResponse, error)
 }
 
 type cluster struct {
@@ -139,3 +146,12 @@ func (c *cluster) MemberPromote(ctx context.Context, id uint64) (*MemberPromoteR
 	}
 	return (*MemberPromoteResponse)(resp), nil
 }
+
+func (c *cluster) MemberDemote(ctx context.Context, id uint64, acknowledgeReducedFaultTolerance bool) (*MemberDemoteResponse, error) {
+	r := &pb.MemberDemoteRequest{ID: id, AcknowledgeReducedFaultTolerance: acknowledgeReducedFaultTolerance}
+	resp, err := c.remote.MemberDemote(ctx, r, c.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*MemberDemoteResponse)(resp), nil
+}
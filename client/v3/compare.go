@@ -62,6 +62,12 @@ func Compare(cmp Cmp, result string, v interface{}) Cmp {
 		cmp.TargetUnion = &pb.Compare_ModRevision{ModRevision: mustInt64(v)}
 	case pb.Compare_LEASE:
 		cmp.TargetUnion = &pb.Compare_Lease{Lease: mustInt64orLeaseID(v)}
+	case pb.Compare_FENCING_TOKEN:
+		tok, ok := v.(FencingToken)
+		if !ok {
+			panic("bad compare value")
+		}
+		cmp.TargetUnion = &pb.Compare_FencingToken_{FencingToken: &pb.Compare_FencingToken{Lease: int64(tok.Lease), CreateRevision: tok.CreateRevision}}
 	default:
 		panic("Unknown compare type")
 	}
@@ -90,6 +96,21 @@ func LeaseValue(key string) Cmp {
 	return Cmp{Key: []byte(key), Target: pb.Compare_LEASE}
 }
 
+// FencingToken identifies the holder of a key created with a lease, such as a
+// lock: the key still holds the token if it is attached to the lease and was not
+// deleted and created again.
+type FencingToken struct {
+	Lease          LeaseID
+	CreateRevision int64
+}
+
+// FencingTokenValue compares a key's creation revision then LeaseID to the
+// FencingToken of your choosing, in one comparison: "=" succeeds only if the key
+// is still attached to the lease and was created at the revision.
+func FencingTokenValue(key string) Cmp {
+	return Cmp{Key: []byte(key), Target: pb.Compare_FENCING_TOKEN}
+}
+
 // KeyBytes returns the byte slice holding with the comparison key.
 func (cmp *Cmp) KeyBytes() []byte { return cmp.Key }
 
@@ -160,7 +160,8 @@ func (e *Election) Leader(ctx context.Context) (*v3.GetResponse, error) {
 // Observe returns a channel that reliably observes ordered leader proposals
 // as GetResponse values on every current elected leader key. It will not
 // necessarily fetch all historical leader updates, but will always post the
-// most recent leader value.
+// most recent leader value. The creation revision of the observed leader key
+// is the epoch of the leader, its fencing token.
 //
 // The channel closes when the context is canceled or the underlying watcher
 // is otherwise disrupted.
@@ -250,5 +251,22 @@ func (e *Election) Key() string { return e.leaderKey }
 // Rev returns the leader key's creation revision, if elected.
 func (e *Election) Rev() int64 { return e.leaderRev }
 
+// FencingToken returns the fencing token of the leadership if elected, 0
+// otherwise. The token is the leader key's creation revision, greater than
+// the tokens of all the previous leaders of the election; see FenceCmp to
+// reject the writes of the previous leaders with it.
+func (e *Election) FencingToken() int64 {
+	if e.leaderSession == nil {
+		return 0
+	}
+	return e.leaderRev
+}
+
+// IsLeader returns the comparison succeeding while the leader key of the
+// election is still held, to guard the writes of the leader in a txn.
+func (e *Election) IsLeader() v3.Cmp {
+	return v3.Compare(v3.CreateRevision(e.leaderKey), "=", e.leaderRev)
+}
+
 // Header is the response header from the last successful election proposal.
 func (e *Election) Header() *pb.ResponseHeader { return e.hdr }
@@ -0,0 +1,58 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"fmt"
+
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// FenceCmp returns the comparison succeeding unless the fence at the key
+// prefix fence has been passed with a fencing token greater than token.
+//
+// A fence guards the resources written by the successive leaders of an
+// election against a leader writing after it lost its leadership, e.g. after
+// a long pause, once a newer leader wrote. Each write through the fence is a
+// txn committed with the fencing token of the writer, guarded by FenceCmp and
+// recording the token with FenceOps:
+//
+//	token := e.FencingToken()
+//	resp, err := cli.Txn(ctx).
+//		If(concurrency.FenceCmp("/fence", token)).
+//		Then(append(concurrency.FenceOps("/fence", token), v3.OpPut("/resource", "value"))...).
+//		Commit()
+//	if err == nil && !resp.Succeeded {
+//		// a newer leader wrote through the fence
+//	}
+func FenceCmp(fence string, token int64) v3.Cmp {
+	return v3.Compare(v3.CreateRevision(fenceKey(fence, token+1)), "=", 0).WithRange(v3.GetPrefixRangeEnd(fence + "/"))
+}
+
+// FenceOps returns the operations recording that the fence at the key prefix
+// fence has been passed with the fencing token, deleting the lower tokens, to
+// commit in a txn guarded by FenceCmp.
+func FenceOps(fence string, token int64) []v3.Op {
+	return []v3.Op{
+		v3.OpPut(fenceKey(fence, token), ""),
+		v3.OpDelete(fence+"/", v3.WithRange(fenceKey(fence, token))),
+	}
+}
+
+// fenceKey returns the key recording the fencing token in the fence, ordered
+// as the tokens.
+func fenceKey(fence string, token int64) string {
+	return fmt.Sprintf("%s/%016x", fence, token)
+}
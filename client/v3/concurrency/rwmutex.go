@@ -0,0 +1,238 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	rwMutexReadPrefix  = "read/"
+	rwMutexWritePrefix = "write/"
+)
+
+// RWMutexHooks are the functions called on the acquisitions and releases of
+// an RWMutex, e.g. to export metrics. The nil functions are not called.
+type RWMutexHooks struct {
+	// OnAcquire is called once the read or write lock is acquired, with the
+	// time waited for it.
+	OnAcquire func(write bool, waited time.Duration)
+	// OnContend is called once TryRLock or TryLock fails as the lock is held.
+	OnContend func(write bool)
+	// OnRelease is called once the read or write lock is released, with the
+	// time it was held.
+	OnRelease func(write bool, held time.Duration)
+}
+
+// RWMutexOption configures RWMutex.
+type RWMutexOption func(*RWMutex)
+
+// WithRWMutexHooks configures the hooks called on the acquisitions and
+// releases of the RWMutex.
+func WithRWMutexHooks(hooks RWMutexHooks) RWMutexOption {
+	return func(rw *RWMutex) { rw.hooks = hooks }
+}
+
+// RWMutex is a reader/writer mutual exclusion lock with etcd, held by any
+// number of readers or a single writer. The lock is fair: readers and writers
+// acquire it in the order they asked for it, a reader waiting for the writers
+// that asked before it and a writer waiting for all the readers and writers
+// that asked before it, so that neither readers nor writers starve.
+//
+// A session holds at most one read lock and one write lock on an RWMutex
+// prefix, and the write lock of a session holding the read lock waits for it.
+type RWMutex struct {
+	s     *Session
+	pfx   string
+	hooks RWMutexHooks
+
+	myKey    string
+	myRev    int64
+	write    bool
+	hdr      *pb.ResponseHeader
+	acquired time.Time
+}
+
+// NewRWMutex returns the RWMutex of the session on the key prefix pfx.
+func NewRWMutex(s *Session, pfx string, opts ...RWMutexOption) *RWMutex {
+	rw := &RWMutex{s: s, pfx: pfx + "/", myKey: "\x00", myRev: -1}
+	for _, opt := range opts {
+		opt(rw)
+	}
+	return rw
+}
+
+// RLock locks rw for reading with a cancelable context, waiting for the
+// writers that asked for the lock before. If the context is canceled while
+// trying to acquire the lock, rw tries to clean its stale lock entry.
+func (rw *RWMutex) RLock(ctx context.Context) error { return rw.lock(ctx, false) }
+
+// Lock locks rw for writing with a cancelable context, waiting for the readers
+// and writers that asked for the lock before. If the context is canceled while
+// trying to acquire the lock, rw tries to clean its stale lock entry.
+func (rw *RWMutex) Lock(ctx context.Context) error { return rw.lock(ctx, true) }
+
+// TryRLock locks rw for reading if no writer holds or waits for the lock,
+// returning ErrLocked otherwise.
+func (rw *RWMutex) TryRLock(ctx context.Context) error { return rw.tryLock(ctx, false) }
+
+// TryLock locks rw for writing if no reader or writer holds or waits for the
+// lock, returning ErrLocked otherwise.
+func (rw *RWMutex) TryLock(ctx context.Context) error { return rw.tryLock(ctx, true) }
+
+// RUnlock unlocks rw locked for reading.
+func (rw *RWMutex) RUnlock(ctx context.Context) error { return rw.unlock(ctx) }
+
+// Unlock unlocks rw locked for writing.
+func (rw *RWMutex) Unlock(ctx context.Context) error { return rw.unlock(ctx) }
+
+// blockingPrefix returns the prefix of the keys blocking the lock until they
+// are deleted, if created before: all the keys for a writer, the keys of the
+// writers for a reader.
+func (rw *RWMutex) blockingPrefix(write bool) string {
+	if write {
+		return rw.pfx
+	}
+	return rw.pfx + rwMutexWritePrefix
+}
+
+func (rw *RWMutex) lock(ctx context.Context, write bool) error {
+	start := time.Now()
+	held, err := rw.tryAcquire(ctx, write)
+	if err != nil {
+		return err
+	}
+	if !held {
+		client := rw.s.Client()
+		// wait for deletion of the blocking keys prior to myKey
+		if _, werr := waitDeletes(ctx, client, rw.blockingPrefix(write), rw.myRev-1); werr != nil {
+			rw.release(client.Ctx())
+			return werr
+		}
+
+		// make sure the session is not expired, and the owner key still exists.
+		gresp, werr := client.Get(ctx, rw.myKey)
+		if werr != nil {
+			rw.release(client.Ctx())
+			return werr
+		}
+		if len(gresp.Kvs) == 0 { // is the session key lost?
+			return ErrSessionExpired
+		}
+		rw.hdr = gresp.Header
+	}
+	rw.acquired = time.Now()
+	if rw.hooks.OnAcquire != nil {
+		rw.hooks.OnAcquire(write, rw.acquired.Sub(start))
+	}
+	return nil
+}
+
+func (rw *RWMutex) tryLock(ctx context.Context, write bool) error {
+	held, err := rw.tryAcquire(ctx, write)
+	if err != nil {
+		return err
+	}
+	if !held {
+		// Cannot lock, so delete the key
+		if err = rw.release(ctx); err != nil {
+			return err
+		}
+		if rw.hooks.OnContend != nil {
+			rw.hooks.OnContend(write)
+		}
+		return ErrLocked
+	}
+	rw.acquired = time.Now()
+	if rw.hooks.OnAcquire != nil {
+		rw.hooks.OnAcquire(write, 0)
+	}
+	return nil
+}
+
+// tryAcquire puts the key of the session in the lock waiters, returning
+// whether it holds the lock.
+func (rw *RWMutex) tryAcquire(ctx context.Context, write bool) (bool, error) {
+	s := rw.s
+	client := s.Client()
+
+	kpfx := rwMutexReadPrefix
+	if write {
+		kpfx = rwMutexWritePrefix
+	}
+	rw.myKey = fmt.Sprintf("%s%s%x", rw.pfx, kpfx, s.Lease())
+	rw.write = write
+	cmp := v3.Compare(v3.CreateRevision(rw.myKey), "=", 0)
+	// put self in lock waiters via myKey
+	put := v3.OpPut(rw.myKey, "", v3.WithLease(s.Lease()))
+	// reuse key in case this session already holds the lock
+	get := v3.OpGet(rw.myKey)
+	// fetch the oldest blocking key to complete uncontended path with only one RPC
+	getBlocking := v3.OpGet(rw.blockingPrefix(write), v3.WithFirstCreate()...)
+	resp, err := client.Txn(ctx).If(cmp).Then(put, getBlocking).Else(get, getBlocking).Commit()
+	if err != nil {
+		return false, err
+	}
+	rw.myRev = resp.Header.Revision
+	if !resp.Succeeded {
+		rw.myRev = resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision
+	}
+	// the lock is held if the oldest blocking key is myKey, or not older
+	blocking := resp.Responses[1].GetResponseRange().Kvs
+	if len(blocking) == 0 || blocking[0].CreateRevision >= rw.myRev {
+		rw.hdr = resp.Header
+		return true, nil
+	}
+	return false, nil
+}
+
+func (rw *RWMutex) unlock(ctx context.Context) error {
+	write, acquired := rw.write, rw.acquired
+	if err := rw.release(ctx); err != nil {
+		return err
+	}
+	if rw.hooks.OnRelease != nil {
+		rw.hooks.OnRelease(write, time.Since(acquired))
+	}
+	return nil
+}
+
+// release deletes the key of the session.
+func (rw *RWMutex) release(ctx context.Context) error {
+	client := rw.s.Client()
+	if _, err := client.Delete(ctx, rw.myKey); err != nil {
+		return err
+	}
+	rw.myKey = "\x00"
+	rw.myRev = -1
+	return nil
+}
+
+// IsOwner returns the comparison succeeding if the session still holds the
+// lock.
+func (rw *RWMutex) IsOwner() v3.Cmp {
+	return v3.Compare(v3.CreateRevision(rw.myKey), "=", rw.myRev)
+}
+
+// Key returns the key of the session in the lock waiters.
+func (rw *RWMutex) Key() string { return rw.myKey }
+
+// Header is the response header received from etcd on acquiring the lock.
+func (rw *RWMutex) Header() *pb.ResponseHeader { return rw.hdr }
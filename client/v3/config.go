@@ -90,9 +90,30 @@ type Config struct {
 	// PermitWithoutStream when set will allow client to send keepalive pings to server without any active streams(RPCs).
 	PermitWithoutStream bool `json:"permit-without-stream"`
 
+	// ReadYourWrites when set makes the serializable ranges of the client wait for the member serving them to apply
+	// the highest revision of the key-value responses received by the client, for them to observe its writes and not
+	// to go back in time, even when served by a lagging member or through a proxy.
+	ReadYourWrites bool `json:"read-your-writes"`
+
+	// HealthBalancing when set balances the requests across the endpoints by their health instead of round-robin:
+	// the endpoints are weighted by the latencies and the error rates of their requests, and the endpoints failing
+	// too many requests are quarantined for a while.
+	HealthBalancing *HealthBalancingConfig `json:"health-balancing"`
+
 	// TODO: support custom balancer picker
 }
 
+// HealthBalancingConfig configures the balancing of the requests across the endpoints by their health.
+type HealthBalancingConfig struct {
+	// ErrorRateThreshold is the error rate of the requests of an endpoint, from 0 to 1, at which it is quarantined.
+	// The requests failing with Unavailable, DeadlineExceeded or Internal are errors. 0 defaults to 0.5.
+	ErrorRateThreshold float64 `json:"error-rate-threshold"`
+
+	// QuarantineDuration is how long a quarantined endpoint is not sent any request, unless all the endpoints are
+	// quarantined. 0 defaults to 30 seconds.
+	QuarantineDuration time.Duration `json:"quarantine-duration"`
+}
+
 // ConfigSpec is the configuration from users, which comes from command-line flags,
 // environment variables or config file. It is a fully declarative configuration,
 // and can be serialized & deserialized to/from JSON.
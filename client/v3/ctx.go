@@ -16,6 +16,7 @@ package clientv3
 
 import (
 	"context"
+	"strconv"
 
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/api/v3/version"
@@ -36,6 +37,36 @@ func WithRequireLeader(ctx context.Context) context.Context {
 	return metadata.NewOutgoingContext(ctx, copied)
 }
 
+// WithMinRevision makes the serializable ranges of the client requests wait
+// for the member serving them to apply the revision rev, for at most its
+// request timeout, failing with ErrFutureRev if it does not.
+func WithMinRevision(ctx context.Context, rev int64) context.Context {
+	v := strconv.FormatInt(rev, 10)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok { // no outgoing metadata ctx key, create one
+		md = metadata.Pairs(rpctypes.MetadataMinRevisionKey, v)
+		return metadata.NewOutgoingContext(ctx, md)
+	}
+	copied := md.Copy() // avoid racey updates
+	// overwrite/add 'min-revision' key/value
+	copied.Set(rpctypes.MetadataMinRevisionKey, v)
+	return metadata.NewOutgoingContext(ctx, copied)
+}
+
+// minRevision returns the revision set with WithMinRevision, 0 if none.
+func minRevision(ctx context.Context) int64 {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return 0
+	}
+	vs := md.Get(rpctypes.MetadataMinRevisionKey)
+	if len(vs) == 0 {
+		return 0
+	}
+	rev, _ := strconv.ParseInt(vs[0], 10, 64)
+	return rev
+}
+
 // embeds client version
 func withVersion(ctx context.Context) context.Context {
 	md, ok := metadata.FromOutgoingContext(ctx)
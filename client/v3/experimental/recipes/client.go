@@ -27,6 +27,10 @@ var (
 	ErrWaitMismatch   = errors.New("unexpected wait result")
 	ErrTooManyClients = errors.New("too many clients")
 	ErrNoWatcher      = errors.New("no watcher channel")
+
+	ErrInvalidRateLimit = errors.New("rate limiter rate and burst must be positive")
+	ErrBurstExceeded    = errors.New("tokens acquired exceed the burst of the rate limiter")
+	ErrClaimExpired     = errors.New("work item claim expired")
 )
 
 // deleteRevKey deletes a key by revision, returning false if key is missing
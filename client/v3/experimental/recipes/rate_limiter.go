@@ -0,0 +1,212 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recipe
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// RateLimiter is a token bucket shared by the processes using the same key.
+// The bucket holds up to burst tokens and is refilled at rate tokens per
+// second, each acquisition taking tokens from it.
+//
+// The bucket is kept in the key, updated by transactions comparing its
+// revision so that concurrent acquisitions never take the same tokens. The
+// refill is computed from the clocks of the processes, the time of the bucket
+// never going backwards: a process whose clock is behind refills nothing
+// until it catches up, and one whose clock is ahead refills its skew worth
+// of tokens at most once. The key is attached to a lease outliving the time
+// the bucket takes to fill up, so that the buckets left idle, full again,
+// are deleted.
+type RateLimiter struct {
+	client *v3.Client
+	key    string
+	rate   float64
+	burst  int64
+
+	mu sync.Mutex
+	// lease is the lease the bucket is attached to, granted with twice the
+	// time-to-live of the bucket and attached to for half of it.
+	lease        v3.LeaseID
+	leaseGranted time.Time
+}
+
+// NewRateLimiter creates a rate limiter refilling the bucket of the key at
+// rate tokens per second, up to burst tokens.
+func NewRateLimiter(client *v3.Client, key string, rate float64, burst int64) *RateLimiter {
+	return &RateLimiter{client: client, key: key, rate: rate, burst: burst}
+}
+
+// TryAcquire takes n tokens from the bucket, returning false without taking
+// any if fewer are left.
+func (rl *RateLimiter) TryAcquire(ctx context.Context, n int64) (bool, error) {
+	ok, _, err := rl.take(ctx, n)
+	return ok, err
+}
+
+// Acquire takes n tokens from the bucket, waiting for them to be refilled if
+// fewer are left until the context is done. The waiting processes are not
+// served in order.
+func (rl *RateLimiter) Acquire(ctx context.Context, n int64) error {
+	for {
+		ok, wait, err := rl.take(ctx, n)
+		if err != nil || ok {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// take takes n tokens from the bucket, returning the time to wait for them
+// if fewer are left.
+func (rl *RateLimiter) take(ctx context.Context, n int64) (bool, time.Duration, error) {
+	if rl.rate <= 0 || rl.burst <= 0 {
+		return false, 0, ErrInvalidRateLimit
+	}
+	if n > rl.burst {
+		return false, 0, ErrBurstExceeded
+	}
+	resp, err := rl.client.Get(ctx, rl.key)
+	if err != nil {
+		return false, 0, err
+	}
+	kvs := resp.Kvs
+	for {
+		var rev int64
+		b := tokenBucket{tokens: float64(rl.burst)}
+		if len(kvs) > 0 {
+			rev = kvs[0].ModRevision
+			if b, err = decodeTokenBucket(kvs[0]); err != nil {
+				return false, 0, err
+			}
+		}
+		now := time.Now()
+		b.refill(now, rl.rate, float64(rl.burst))
+		if b.tokens < float64(n) {
+			return false, time.Duration((float64(n) - b.tokens) / rl.rate * float64(time.Second)), nil
+		}
+		b.tokens -= float64(n)
+
+		leaseID, err := rl.leaseID(ctx, now)
+		if err != nil {
+			return false, 0, err
+		}
+		tresp, err := rl.client.Txn(ctx).
+			If(v3.Compare(v3.ModRevision(rl.key), "=", rev)).
+			Then(v3.OpPut(rl.key, b.String(), v3.WithLease(leaseID))).
+			Else(v3.OpGet(rl.key)).
+			Commit()
+		if err == rpctypes.ErrLeaseNotFound {
+			// revoked, or expired while the client was partitioned
+			rl.resetLease(leaseID)
+			continue
+		}
+		if err != nil {
+			return false, 0, err
+		}
+		if tresp.Succeeded {
+			return true, 0, nil
+		}
+		// taken by another process since read
+		kvs = tresp.Responses[0].GetResponseRange().Kvs
+	}
+}
+
+// ttl returns the time-to-live of the bucket, the time an empty bucket takes
+// to fill up.
+func (rl *RateLimiter) ttl() time.Duration {
+	return time.Duration(math.Ceil(float64(rl.burst)/rl.rate)) * time.Second
+}
+
+// leaseID returns a lease with at least the time-to-live of the bucket left,
+// granting a new one if needed.
+func (rl *RateLimiter) leaseID(ctx context.Context, now time.Time) (v3.LeaseID, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	ttl := rl.ttl()
+	if rl.lease != v3.NoLease && now.Sub(rl.leaseGranted) < ttl {
+		return rl.lease, nil
+	}
+	// one second more for the time to grant it
+	resp, err := rl.client.Grant(ctx, int64((2*ttl+time.Second)/time.Second))
+	if err != nil {
+		return v3.NoLease, err
+	}
+	rl.lease, rl.leaseGranted = resp.ID, now
+	return rl.lease, nil
+}
+
+func (rl *RateLimiter) resetLease(leaseID v3.LeaseID) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.lease == leaseID {
+		rl.lease = v3.NoLease
+	}
+}
+
+// tokenBucket is the state of a bucket, stored as "<tokens> <unix nano>".
+type tokenBucket struct {
+	tokens float64
+	// last is the time the bucket was last refilled at.
+	last time.Time
+}
+
+func decodeTokenBucket(kv *mvccpb.KeyValue) (tokenBucket, error) {
+	fields := strings.Fields(string(kv.Value))
+	if len(fields) != 2 {
+		return tokenBucket{}, fmt.Errorf("invalid rate limiter bucket %q", kv.Value)
+	}
+	tokens, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return tokenBucket{}, fmt.Errorf("invalid rate limiter bucket %q (%v)", kv.Value, err)
+	}
+	last, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return tokenBucket{}, fmt.Errorf("invalid rate limiter bucket %q (%v)", kv.Value, err)
+	}
+	return tokenBucket{tokens: tokens, last: time.Unix(0, last)}, nil
+}
+
+// refill adds the tokens of the time elapsed since the last refill, never
+// moving the time of the bucket backwards.
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) {
+	if now.After(b.last) {
+		if !b.last.IsZero() {
+			b.tokens += now.Sub(b.last).Seconds() * rate
+		}
+		b.last = now
+	}
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+}
+
+func (b tokenBucket) String() string {
+	return strconv.FormatFloat(b.tokens, 'g', -1, 64) + " " + strconv.FormatInt(b.last.UnixNano(), 10)
+}
@@ -0,0 +1,336 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recipe
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	v3 "go.etcd.io/etcd/client/v3"
+)
+
+// dequeueBatch is the number of ready items read at once by Dequeue.
+const dequeueBatch = 16
+
+// WorkQueue is a multi-reader, multi-writer distributed queue of work items,
+// delivered at least once: a dequeued item is claimed, invisible to the
+// other readers, until it is acknowledged or its visibility timeout expires,
+// e.g. because its reader crashed, where it is delivered again. An item
+// claimed maxAttempts times without being acknowledged is moved to the
+// dead-letter keys instead.
+//
+// The items are stored under the key prefix:
+//
+//	<prefix>/ready/<id>       items waiting to be claimed, in FIFO order
+//	<prefix>/claimed/<id>     claimed items
+//	<prefix>/visibility/<id>  attached to the lease of the claim, expiring
+//	                          with the visibility timeout
+//	<prefix>/dead/<id>        items out of attempts
+//
+// The values of the items are prefixed by the number of times they were
+// claimed.
+type WorkQueue struct {
+	client *v3.Client
+
+	keyPrefix         string
+	visibilityTimeout time.Duration
+	maxAttempts       int
+}
+
+// NewWorkQueue creates a work queue of the key prefix. The visibility
+// timeout is rounded up to the second, and any number of attempts is
+// allowed if maxAttempts is 0.
+func NewWorkQueue(client *v3.Client, keyPrefix string, visibilityTimeout time.Duration, maxAttempts int) *WorkQueue {
+	return &WorkQueue{
+		client:            client,
+		keyPrefix:         keyPrefix,
+		visibilityTimeout: visibilityTimeout,
+		maxAttempts:       maxAttempts,
+	}
+}
+
+func (q *WorkQueue) readyKey(id string) string      { return q.keyPrefix + "/ready/" + id }
+func (q *WorkQueue) claimedKey(id string) string    { return q.keyPrefix + "/claimed/" + id }
+func (q *WorkQueue) visibilityKey(id string) string { return q.keyPrefix + "/visibility/" + id }
+func (q *WorkQueue) deadKey(id string) string       { return q.keyPrefix + "/dead/" + id }
+
+// Enqueue adds an item of the value to the queue.
+func (q *WorkQueue) Enqueue(ctx context.Context, val string) error {
+	for {
+		id := fmt.Sprintf("%d", time.Now().UnixNano())
+		resp, err := q.client.Txn(ctx).
+			If(
+				v3.Compare(v3.Version(q.readyKey(id)), "=", 0),
+				v3.Compare(v3.Version(q.claimedKey(id)), "=", 0),
+				v3.Compare(v3.Version(q.deadKey(id)), "=", 0),
+			).
+			Then(v3.OpPut(q.readyKey(id), encodeWorkItem(0, val))).
+			Commit()
+		if err != nil || resp.Succeeded {
+			return err
+		}
+	}
+}
+
+// Dequeue claims the first item ready, delivering again the items whose
+// claim expired first. If none is ready, Dequeue blocks until one is or the
+// context is done.
+func (q *WorkQueue) Dequeue(ctx context.Context) (*WorkItem, error) {
+	lease := v3.NoLease
+	defer func() {
+		// granted but not claimed with
+		if lease != v3.NoLease {
+			q.client.Revoke(context.Background(), lease)
+		}
+	}()
+	for {
+		// a consistent view of the queue, for the watch to start right after
+		resp, err := q.client.Txn(ctx).Then(
+			v3.OpGet(q.readyKey(""), v3.WithPrefix(), v3.WithSort(v3.SortByKey, v3.SortAscend), v3.WithLimit(dequeueBatch)),
+			v3.OpGet(q.claimedKey(""), v3.WithPrefix()),
+			v3.OpGet(q.visibilityKey(""), v3.WithPrefix(), v3.WithKeysOnly()),
+		).Commit()
+		if err != nil {
+			return nil, err
+		}
+		ready := resp.Responses[0].GetResponseRange()
+		claimed := resp.Responses[1].GetResponseRange().Kvs
+		visible := make(map[string]struct{})
+		for _, kv := range resp.Responses[2].GetResponseRange().Kvs {
+			visible[strings.TrimPrefix(string(kv.Key), q.visibilityKey(""))] = struct{}{}
+		}
+
+		requeued := false
+		for _, kv := range claimed {
+			id := strings.TrimPrefix(string(kv.Key), q.claimedKey(""))
+			if _, ok := visible[id]; ok {
+				continue
+			}
+			ok, err := q.requeue(ctx, id, kv)
+			if err != nil {
+				return nil, err
+			}
+			requeued = requeued || ok
+		}
+		if requeued {
+			continue
+		}
+
+		for _, kv := range ready.Kvs {
+			if lease == v3.NoLease {
+				lresp, err := q.client.Grant(ctx, int64(math.Ceil(q.visibilityTimeout.Seconds())))
+				if err != nil {
+					return nil, err
+				}
+				lease = lresp.ID
+			}
+			wi, err := q.claim(ctx, kv, lease)
+			if err != nil {
+				return nil, err
+			}
+			if wi != nil {
+				lease = v3.NoLease
+				return wi, nil
+			}
+		}
+		if ready.More {
+			continue
+		}
+
+		// nothing yet; wait on an item ready, or a claim expiring
+		if err := q.waitChange(ctx, resp.Header.Revision+1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// claim claims the ready item with the lease, returning nil if it was
+// claimed by another reader first.
+func (q *WorkQueue) claim(ctx context.Context, kv *mvccpb.KeyValue, lease v3.LeaseID) (*WorkItem, error) {
+	id := strings.TrimPrefix(string(kv.Key), q.readyKey(""))
+	attempts, val, err := decodeWorkItem(kv)
+	if err != nil {
+		return nil, err
+	}
+	attempts++
+	resp, err := q.client.Txn(ctx).
+		If(v3.Compare(v3.ModRevision(q.readyKey(id)), "=", kv.ModRevision)).
+		Then(
+			v3.OpDelete(q.readyKey(id)),
+			v3.OpPut(q.claimedKey(id), encodeWorkItem(attempts, val)),
+			v3.OpPut(q.visibilityKey(id), "", v3.WithLease(lease)),
+		).
+		Commit()
+	if err != nil || !resp.Succeeded {
+		return nil, err
+	}
+	return &WorkItem{q: q, id: id, lease: lease, Value: val, Attempts: attempts}, nil
+}
+
+// requeue moves the claimed item back to the ready items, or to the
+// dead-letter items if out of attempts, unless its claim is still visible or
+// it was already moved.
+func (q *WorkQueue) requeue(ctx context.Context, id string, kv *mvccpb.KeyValue) (bool, error) {
+	attempts, val, err := decodeWorkItem(kv)
+	if err != nil {
+		return false, err
+	}
+	resp, err := q.client.Txn(ctx).
+		If(
+			v3.Compare(v3.ModRevision(q.claimedKey(id)), "=", kv.ModRevision),
+			v3.Compare(v3.Version(q.visibilityKey(id)), "=", 0),
+		).
+		Then(v3.OpDelete(q.claimedKey(id)), q.release(id, attempts, val)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// release returns the put of the item, out of its claim, to the ready items
+// or to the dead-letter items if out of attempts.
+func (q *WorkQueue) release(id string, attempts int, val string) v3.Op {
+	if q.maxAttempts > 0 && attempts >= q.maxAttempts {
+		return v3.OpPut(q.deadKey(id), encodeWorkItem(attempts, val))
+	}
+	return v3.OpPut(q.readyKey(id), encodeWorkItem(attempts, val))
+}
+
+// waitChange waits from the revision for an item to be ready, or a claim to
+// expire.
+func (q *WorkQueue) waitChange(ctx context.Context, rev int64) error {
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wc := q.client.Watch(wctx, q.keyPrefix+"/", v3.WithPrefix(), v3.WithRev(rev))
+	if wc == nil {
+		return ErrNoWatcher
+	}
+	for wresp := range wc {
+		if err := wresp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range wresp.Events {
+			key := string(ev.Kv.Key)
+			if (ev.Type == mvccpb.PUT && strings.HasPrefix(key, q.readyKey(""))) ||
+				(ev.Type == mvccpb.DELETE && strings.HasPrefix(key, q.visibilityKey(""))) {
+				return nil
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// DeadLetters returns the items out of attempts, in FIFO order.
+func (q *WorkQueue) DeadLetters(ctx context.Context) ([]string, error) {
+	resp, err := q.client.Get(ctx, q.deadKey(""), v3.WithPrefix(), v3.WithSort(v3.SortByKey, v3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		_, val, err := decodeWorkItem(kv)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+	return vals, nil
+}
+
+// WorkItem is an item claimed from a WorkQueue.
+type WorkItem struct {
+	q     *WorkQueue
+	id    string
+	lease v3.LeaseID
+
+	Value string
+	// Attempts is the number of times the item was claimed, this claim
+	// included.
+	Attempts int
+}
+
+// Ack removes the item from the queue once processed, failing with
+// ErrClaimExpired if its visibility timeout expired, the item being
+// delivered again.
+func (wi *WorkItem) Ack(ctx context.Context) error {
+	q := wi.q
+	resp, err := q.client.Txn(ctx).
+		If(v3.Compare(v3.LeaseValue(q.visibilityKey(wi.id)), "=", wi.lease)).
+		Then(v3.OpDelete(q.claimedKey(wi.id)), v3.OpDelete(q.visibilityKey(wi.id))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	q.client.Revoke(ctx, wi.lease)
+	if !resp.Succeeded {
+		return ErrClaimExpired
+	}
+	return nil
+}
+
+// Nack gives up the claim of the item, delivering it again right away, or
+// moving it to the dead-letter items if out of attempts.
+func (wi *WorkItem) Nack(ctx context.Context) error {
+	q := wi.q
+	resp, err := q.client.Txn(ctx).
+		If(v3.Compare(v3.LeaseValue(q.visibilityKey(wi.id)), "=", wi.lease)).
+		Then(
+			v3.OpDelete(q.claimedKey(wi.id)),
+			v3.OpDelete(q.visibilityKey(wi.id)),
+			q.release(wi.id, wi.Attempts, wi.Value),
+		).
+		Commit()
+	if err != nil {
+		return err
+	}
+	q.client.Revoke(ctx, wi.lease)
+	if !resp.Succeeded {
+		return ErrClaimExpired
+	}
+	return nil
+}
+
+// Extend restarts the visibility timeout of the item, for a longer
+// processing.
+func (wi *WorkItem) Extend(ctx context.Context) error {
+	_, err := wi.q.client.KeepAliveOnce(ctx, wi.lease)
+	if err == rpctypes.ErrLeaseNotFound {
+		return ErrClaimExpired
+	}
+	return err
+}
+
+func encodeWorkItem(attempts int, val string) string {
+	return strconv.Itoa(attempts) + " " + val
+}
+
+func decodeWorkItem(kv *mvccpb.KeyValue) (attempts int, val string, err error) {
+	s := string(kv.Value)
+	i := strings.IndexByte(s, ' ')
+	if i < 0 {
+		return 0, "", fmt.Errorf("invalid work item %q", kv.Key)
+	}
+	if attempts, err = strconv.Atoi(s[:i]); err != nil {
+		return 0, "", fmt.Errorf("invalid work item %q (%v)", kv.Key, err)
+	}
+	return attempts, s[i+1:], nil
+}
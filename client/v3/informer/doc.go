@@ -0,0 +1,61 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package informer implements informers on etcd, modeled on the reflectors
+// and the shared informers of the Kubernetes clients, for building
+// controllers on top of a key prefix.
+//
+// An informer lists the key-value pairs of a prefix, then watches it from the
+// revision of the list, keeping an indexed cache of the prefix shared by its
+// event handlers. When the revision the watch resumes from is compacted, the
+// prefix is listed again, and the handlers are notified of the differences
+// between the cache and the new list, as if they had watched them.
+//
+// Create an informer of the prefix "jobs/", indexing the jobs by their value:
+//
+//	inf := informer.New(cli.KV, cli.Watcher, "jobs/",
+//		informer.WithIndexers(informer.Indexers{"state": func(kv *mvccpb.KeyValue) []string {
+//			return []string{string(kv.Value)}
+//		}}),
+//		informer.WithResyncPeriod(time.Minute),
+//	)
+//
+// Queue the keys of the events for the workers of a controller, then run the
+// informer:
+//
+//	q := informer.NewQueue()
+//	defer q.ShutDown()
+//	inf.AddEventHandler(informer.QueueHandler(q))
+//	go inf.Run(ctx)
+//	if err := inf.WaitForSync(ctx); err != nil {
+//		// handle error!
+//	}
+//
+// Each worker processes the keys from the queue, reading the key-value pairs
+// from the cache, and retries the failed keys with a backoff:
+//
+//	for {
+//		key, shutdown := q.Get()
+//		if shutdown {
+//			return
+//		}
+//		kv, ok := inf.Indexer().Get(key)
+//		if err := reconcile(key, kv, ok); err != nil {
+//			q.AddRateLimited(key)
+//		} else {
+//			q.Forget(key)
+//		}
+//		q.Done(key)
+//	}
+package informer
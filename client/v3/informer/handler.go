@@ -0,0 +1,141 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"sync"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// Handler handles the notifications of the changes of the cache of an
+// informer. The key-value pairs are shared, and must not be modified.
+type Handler interface {
+	// OnAdd is called when a key is added to the cache.
+	OnAdd(kv *mvccpb.KeyValue)
+	// OnUpdate is called when a key of the cache is updated, and with oldKV
+	// equal to newKV on the periodic resyncs of the cache.
+	OnUpdate(oldKV, newKV *mvccpb.KeyValue)
+	// OnDelete is called when a key is deleted from the cache, with its last
+	// cached key-value pair.
+	OnDelete(kv *mvccpb.KeyValue)
+}
+
+// HandlerFuncs is a Handler calling its funcs, if set.
+type HandlerFuncs struct {
+	AddFunc    func(kv *mvccpb.KeyValue)
+	UpdateFunc func(oldKV, newKV *mvccpb.KeyValue)
+	DeleteFunc func(kv *mvccpb.KeyValue)
+}
+
+func (h HandlerFuncs) OnAdd(kv *mvccpb.KeyValue) {
+	if h.AddFunc != nil {
+		h.AddFunc(kv)
+	}
+}
+
+func (h HandlerFuncs) OnUpdate(oldKV, newKV *mvccpb.KeyValue) {
+	if h.UpdateFunc != nil {
+		h.UpdateFunc(oldKV, newKV)
+	}
+}
+
+func (h HandlerFuncs) OnDelete(kv *mvccpb.KeyValue) {
+	if h.DeleteFunc != nil {
+		h.DeleteFunc(kv)
+	}
+}
+
+// QueueHandler returns a Handler adding the keys notified to q.
+func QueueHandler(q *Queue) Handler {
+	return HandlerFuncs{
+		AddFunc:    func(kv *mvccpb.KeyValue) { q.Add(string(kv.Key)) },
+		UpdateFunc: func(_, kv *mvccpb.KeyValue) { q.Add(string(kv.Key)) },
+		DeleteFunc: func(kv *mvccpb.KeyValue) { q.Add(string(kv.Key)) },
+	}
+}
+
+type notificationType int
+
+const (
+	addNotification notificationType = iota
+	updateNotification
+	deleteNotification
+)
+
+type notification struct {
+	typ     notificationType
+	old, kv *mvccpb.KeyValue
+}
+
+// listener buffers the notifications of a handler, so that a slow handler
+// does not hold up the informer and the other handlers.
+type listener struct {
+	h Handler
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []notification
+	stopped bool
+}
+
+func newListener(h Handler) *listener {
+	l := &listener{h: h}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *listener) add(n notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, n)
+	l.cond.Signal()
+}
+
+// run calls the handler with the notifications, in order, until stopped.
+func (l *listener) run() {
+	for {
+		l.mu.Lock()
+		for len(l.pending) == 0 && !l.stopped {
+			l.cond.Wait()
+		}
+		if l.stopped {
+			l.mu.Unlock()
+			return
+		}
+		n := l.pending[0]
+		l.pending[0] = notification{}
+		l.pending = l.pending[1:]
+		l.mu.Unlock()
+
+		switch n.typ {
+		case addNotification:
+			l.h.OnAdd(n.kv)
+		case updateNotification:
+			l.h.OnUpdate(n.old, n.kv)
+		case deleteNotification:
+			l.h.OnDelete(n.kv)
+		}
+	}
+}
+
+// stop stops the listener, dropping the pending notifications.
+func (l *listener) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopped = true
+	l.pending = nil
+	l.cond.Signal()
+}
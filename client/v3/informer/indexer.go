@@ -0,0 +1,212 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+var ErrUnknownIndex = errors.New("informer: unknown index")
+
+// IndexFunc returns the values a key-value pair is indexed by, e.g. the
+// owner of the object stored.
+type IndexFunc func(kv *mvccpb.KeyValue) []string
+
+// Indexers are the index functions by index name.
+type Indexers map[string]IndexFunc
+
+// Indexer is the cache of the key-value pairs of an informer, indexed by its
+// indexers. The key-value pairs are shared, and must not be modified.
+type Indexer struct {
+	indexers Indexers
+
+	mu  sync.RWMutex
+	kvs map[string]*mvccpb.KeyValue
+	// indices are the keys by index value, by index name.
+	indices map[string]map[string]map[string]struct{}
+	// rev is the revision the cache is up to date with.
+	rev int64
+}
+
+func newIndexer(indexers Indexers) *Indexer {
+	ix := &Indexer{
+		indexers: indexers,
+		kvs:      make(map[string]*mvccpb.KeyValue),
+		indices:  make(map[string]map[string]map[string]struct{}, len(indexers)),
+	}
+	for name := range indexers {
+		ix.indices[name] = make(map[string]map[string]struct{})
+	}
+	return ix
+}
+
+// Get returns the key-value pair of key.
+func (ix *Indexer) Get(key string) (*mvccpb.KeyValue, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	kv, ok := ix.kvs[key]
+	return kv, ok
+}
+
+// List returns the key-value pairs, sorted by key.
+func (ix *Indexer) List() []*mvccpb.KeyValue {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	kvs := make([]*mvccpb.KeyValue, 0, len(ix.kvs))
+	for _, kv := range ix.kvs {
+		kvs = append(kvs, kv)
+	}
+	sortKVs(kvs)
+	return kvs
+}
+
+// Keys returns the keys, sorted.
+func (ix *Indexer) Keys() []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	keys := make([]string, 0, len(ix.kvs))
+	for key := range ix.kvs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ByIndex returns the key-value pairs indexed by value in the index name,
+// sorted by key.
+func (ix *Indexer) ByIndex(name, value string) ([]*mvccpb.KeyValue, error) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	index, ok := ix.indices[name]
+	if !ok {
+		return nil, ErrUnknownIndex
+	}
+	kvs := make([]*mvccpb.KeyValue, 0, len(index[value]))
+	for key := range index[value] {
+		kvs = append(kvs, ix.kvs[key])
+	}
+	sortKVs(kvs)
+	return kvs, nil
+}
+
+// Revision returns the revision the cache is up to date with.
+func (ix *Indexer) Revision() int64 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.rev
+}
+
+// put caches kv at rev, returning the key-value pair it replaces, if any.
+func (ix *Indexer) put(kv *mvccpb.KeyValue, rev int64) *mvccpb.KeyValue {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	key := string(kv.Key)
+	old := ix.kvs[key]
+	if old != nil {
+		ix.unindex(old)
+	}
+	ix.kvs[key] = kv
+	ix.index(kv)
+	ix.rev = rev
+	return old
+}
+
+// delete uncaches key at rev, returning its key-value pair, if any.
+func (ix *Indexer) delete(key string, rev int64) *mvccpb.KeyValue {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	old := ix.kvs[key]
+	if old != nil {
+		ix.unindex(old)
+		delete(ix.kvs, key)
+	}
+	ix.rev = rev
+	return old
+}
+
+// replace replaces the cached key-value pairs by kvs listed at rev,
+// returning the notifications of the differences, the deletions first, then
+// the additions and the updates sorted by key.
+func (ix *Indexer) replace(kvs []*mvccpb.KeyValue, rev int64) []notification {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	listed := make(map[string]struct{}, len(kvs))
+	for _, kv := range kvs {
+		listed[string(kv.Key)] = struct{}{}
+	}
+
+	var deleted, changed []notification
+	for key, old := range ix.kvs {
+		if _, ok := listed[key]; !ok {
+			ix.unindex(old)
+			delete(ix.kvs, key)
+			deleted = append(deleted, notification{typ: deleteNotification, kv: old})
+		}
+	}
+	for _, kv := range kvs {
+		key := string(kv.Key)
+		old, ok := ix.kvs[key]
+		switch {
+		case !ok:
+			changed = append(changed, notification{typ: addNotification, kv: kv})
+		case old.ModRevision != kv.ModRevision:
+			ix.unindex(old)
+			changed = append(changed, notification{typ: updateNotification, old: old, kv: kv})
+		default:
+			continue
+		}
+		ix.kvs[key] = kv
+		ix.index(kv)
+	}
+	ix.rev = rev
+
+	sort.Slice(deleted, func(i, j int) bool { return string(deleted[i].kv.Key) < string(deleted[j].kv.Key) })
+	sort.Slice(changed, func(i, j int) bool { return string(changed[i].kv.Key) < string(changed[j].kv.Key) })
+	return append(deleted, changed...)
+}
+
+func (ix *Indexer) index(kv *mvccpb.KeyValue) {
+	for name, f := range ix.indexers {
+		index := ix.indices[name]
+		for _, value := range f(kv) {
+			keys := index[value]
+			if keys == nil {
+				keys = make(map[string]struct{})
+				index[value] = keys
+			}
+			keys[string(kv.Key)] = struct{}{}
+		}
+	}
+}
+
+func (ix *Indexer) unindex(kv *mvccpb.KeyValue) {
+	for name, f := range ix.indexers {
+		index := ix.indices[name]
+		for _, value := range f(kv) {
+			delete(index[value], string(kv.Key))
+			if len(index[value]) == 0 {
+				delete(index, value)
+			}
+		}
+	}
+}
+
+func sortKVs(kvs []*mvccpb.KeyValue) {
+	sort.Slice(kvs, func(i, j int) bool { return string(kvs[i].Key) < string(kvs[j].Key) })
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func newKV(key, val string, rev int64) *mvccpb.KeyValue {
+	return &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val), ModRevision: rev}
+}
+
+func byValue(kv *mvccpb.KeyValue) []string { return []string{string(kv.Value)} }
+
+func TestIndexerPutDelete(t *testing.T) {
+	ix := newIndexer(Indexers{"value": byValue})
+	a1, b1, a2 := newKV("a", "x", 1), newKV("b", "x", 2), newKV("a", "y", 3)
+	if old := ix.put(a1, 1); old != nil {
+		t.Fatalf("put = %v, want nil", old)
+	}
+	ix.put(b1, 2)
+	if old := ix.put(a2, 3); old != a1 {
+		t.Fatalf("put = %v, want %v", old, a1)
+	}
+
+	kvs, err := ix.ByIndex("value", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(kvs, []*mvccpb.KeyValue{b1}) {
+		t.Errorf("ByIndex(x) = %v, want %v", kvs, []*mvccpb.KeyValue{b1})
+	}
+	if kvs, _ = ix.ByIndex("value", "y"); !reflect.DeepEqual(kvs, []*mvccpb.KeyValue{a2}) {
+		t.Errorf("ByIndex(y) = %v, want %v", kvs, []*mvccpb.KeyValue{a2})
+	}
+	if _, err = ix.ByIndex("owner", "x"); err != ErrUnknownIndex {
+		t.Errorf("ByIndex(owner) error = %v, want %v", err, ErrUnknownIndex)
+	}
+
+	if old := ix.delete("b", 4); old != b1 {
+		t.Fatalf("delete = %v, want %v", old, b1)
+	}
+	if old := ix.delete("c", 5); old != nil {
+		t.Fatalf("delete = %v, want nil", old)
+	}
+	if keys := ix.Keys(); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Errorf("Keys = %v, want [a]", keys)
+	}
+	if kvs, _ = ix.ByIndex("value", "x"); len(kvs) != 0 {
+		t.Errorf("ByIndex(x) = %v, want none", kvs)
+	}
+	if _, ok := ix.indices["value"]["x"]; ok {
+		t.Errorf("index value x kept without keys")
+	}
+	if rev := ix.Revision(); rev != 5 {
+		t.Errorf("Revision = %d, want 5", rev)
+	}
+}
+
+func TestIndexerReplace(t *testing.T) {
+	ix := newIndexer(Indexers{"value": byValue})
+	a1, b1, c1 := newKV("a", "x", 1), newKV("b", "x", 2), newKV("c", "x", 3)
+	ix.put(a1, 1)
+	ix.put(b1, 2)
+	ix.put(c1, 3)
+
+	b2, d1 := newKV("b", "y", 5), newKV("d", "y", 6)
+	ns := ix.replace([]*mvccpb.KeyValue{b2, c1, d1}, 7)
+	wns := []notification{
+		{typ: deleteNotification, kv: a1},
+		{typ: updateNotification, old: b1, kv: b2},
+		{typ: addNotification, kv: d1},
+	}
+	if !reflect.DeepEqual(ns, wns) {
+		t.Errorf("replace = %+v, want %+v", ns, wns)
+	}
+	if kvs := ix.List(); !reflect.DeepEqual(kvs, []*mvccpb.KeyValue{b2, c1, d1}) {
+		t.Errorf("List = %v, want %v", kvs, []*mvccpb.KeyValue{b2, c1, d1})
+	}
+	if kvs, _ := ix.ByIndex("value", "y"); !reflect.DeepEqual(kvs, []*mvccpb.KeyValue{b2, d1}) {
+		t.Errorf("ByIndex(y) = %v, want %v", kvs, []*mvccpb.KeyValue{b2, d1})
+	}
+	if rev := ix.Revision(); rev != 7 {
+		t.Errorf("Revision = %d, want 7", rev)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/mirror"
+)
+
+type options struct {
+	indexers     Indexers
+	resyncPeriod time.Duration
+}
+
+// Option configures an Informer.
+type Option func(*options)
+
+// WithIndexers configures the indexers of the cache.
+func WithIndexers(indexers Indexers) Option {
+	return func(o *options) { o.indexers = indexers }
+}
+
+// WithResyncPeriod configures the period the handlers are notified of all
+// the cached key-value pairs at, as updates with the same old and new
+// key-value pairs, so that the controllers reconcile them again. The cache
+// is not resynced if the period is <= 0, the default.
+func WithResyncPeriod(d time.Duration) Option {
+	return func(o *options) { o.resyncPeriod = d }
+}
+
+// Informer keeps the indexed cache of a key prefix, notifying its handlers
+// of the changes of the cache.
+type Informer struct {
+	kv     clientv3.KV
+	w      clientv3.Watcher
+	prefix string
+	opts   options
+
+	indexer *Indexer
+	syncedc chan struct{}
+
+	// mu serializes the changes of the cache with the notifications of the
+	// handlers, for the handlers added while running to be notified of the
+	// cache, then of its changes.
+	mu        sync.Mutex
+	listeners []*listener
+	running   bool
+	synced    bool
+}
+
+// New returns an informer of the key-value pairs of prefix, listed through
+// kv and watched through w.
+func New(kv clientv3.KV, w clientv3.Watcher, prefix string, opts ...Option) *Informer {
+	inf := &Informer{kv: kv, w: w, prefix: prefix, syncedc: make(chan struct{})}
+	for _, opt := range opts {
+		opt(&inf.opts)
+	}
+	inf.indexer = newIndexer(inf.opts.indexers)
+	return inf
+}
+
+// Indexer returns the cache of the informer.
+func (inf *Informer) Indexer() *Indexer { return inf.indexer }
+
+// AddEventHandler adds a handler of the changes of the cache. A handler
+// added once the cache is synced is first notified of the additions of the
+// cached key-value pairs.
+func (inf *Informer) AddEventHandler(h Handler) {
+	l := newListener(h)
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+	if inf.synced {
+		for _, kv := range inf.indexer.List() {
+			l.add(notification{typ: addNotification, kv: kv})
+		}
+	}
+	inf.listeners = append(inf.listeners, l)
+	if inf.running {
+		go l.run()
+	}
+}
+
+// HasSynced returns true once the cache is loaded from the first list of
+// the prefix.
+func (inf *Informer) HasSynced() bool {
+	select {
+	case <-inf.syncedc:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForSync waits for the cache to be synced, returning the error of ctx
+// if it is done first.
+func (inf *Informer) WaitForSync(ctx context.Context) error {
+	select {
+	case <-inf.syncedc:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run lists and watches the prefix, keeping the cache up to date and
+// notifying the handlers, until ctx is done or the watcher closed. The
+// pending notifications of the handlers are dropped once Run returns. Run
+// must be called at most once.
+func (inf *Informer) Run(ctx context.Context) {
+	inf.mu.Lock()
+	inf.running = true
+	for _, l := range inf.listeners {
+		go l.run()
+	}
+	inf.mu.Unlock()
+	defer func() {
+		inf.mu.Lock()
+		defer inf.mu.Unlock()
+		inf.running = false
+		for _, l := range inf.listeners {
+			l.stop()
+		}
+	}()
+
+	var resyncc <-chan time.Time
+	if inf.opts.resyncPeriod > 0 {
+		t := time.NewTicker(inf.opts.resyncPeriod)
+		defer t.Stop()
+		resyncc = t.C
+	}
+
+	wch := mirror.WatchWithResync(ctx, inf.kv, inf.w, inf.prefix, clientv3.WithPrefix())
+	for {
+		select {
+		case resp, ok := <-wch:
+			if !ok {
+				return
+			}
+			inf.handle(resp)
+		case <-resyncc:
+			inf.resync()
+		}
+	}
+}
+
+// handle applies the resync or the events of resp to the cache, notifying
+// the handlers of the changes.
+func (inf *Informer) handle(resp mirror.ResyncResponse) {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+	if resp.Resync {
+		for _, n := range inf.indexer.replace(resp.Kvs, resp.Header.Revision) {
+			inf.notify(n)
+		}
+		if !inf.synced {
+			inf.synced = true
+			close(inf.syncedc)
+		}
+		return
+	}
+	for _, ev := range resp.Events {
+		switch ev.Type {
+		case mvccpb.PUT:
+			if old := inf.indexer.put(ev.Kv, ev.Kv.ModRevision); old != nil {
+				inf.notify(notification{typ: updateNotification, old: old, kv: ev.Kv})
+			} else {
+				inf.notify(notification{typ: addNotification, kv: ev.Kv})
+			}
+		case mvccpb.DELETE:
+			if old := inf.indexer.delete(string(ev.Kv.Key), ev.Kv.ModRevision); old != nil {
+				inf.notify(notification{typ: deleteNotification, kv: old})
+			}
+		}
+	}
+}
+
+// resync notifies the handlers of the updates of all cached key-value
+// pairs, once the cache is synced.
+func (inf *Informer) resync() {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+	if !inf.synced {
+		return
+	}
+	for _, kv := range inf.indexer.List() {
+		inf.notify(notification{typ: updateNotification, old: kv, kv: kv})
+	}
+}
+
+// notify notifies the handlers of n, with inf.mu held.
+func (inf *Informer) notify(n notification) {
+	for _, l := range inf.listeners {
+		l.add(n)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// retryBaseDelay is the delay of the first retry of a key, doubled at
+	// every retry up to retryMaxDelay.
+	retryBaseDelay = 5 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// Queue is a work queue of keys, with the semantics of the work queues of
+// the Kubernetes controllers: a key added several times before a worker gets
+// it is queued once, and a key is processed by one worker at a time, a key
+// added while processed being queued again once done.
+type Queue struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queue are the keys to process, in order.
+	queue []string
+	// dirty are the keys to process, queued or once done.
+	dirty map[string]struct{}
+	// processing are the keys got by a worker, not done yet.
+	processing map[string]struct{}
+	// requeues are the numbers of retries of the keys.
+	requeues map[string]int
+	shutdown bool
+}
+
+// NewQueue returns an empty work queue.
+func NewQueue() *Queue {
+	q := &Queue{
+		dirty:      make(map[string]struct{}),
+		processing: make(map[string]struct{}),
+		requeues:   make(map[string]int),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Add queues key, unless already queued, or once done if processed.
+func (q *Queue) Add(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.shutdown {
+		return
+	}
+	if _, ok := q.dirty[key]; ok {
+		return
+	}
+	q.dirty[key] = struct{}{}
+	if _, ok := q.processing[key]; ok {
+		return
+	}
+	q.queue = append(q.queue, key)
+	q.cond.Signal()
+}
+
+// AddAfter adds key once d elapsed.
+func (q *Queue) AddAfter(key string, d time.Duration) {
+	if d <= 0 {
+		q.Add(key)
+		return
+	}
+	time.AfterFunc(d, func() { q.Add(key) })
+}
+
+// AddRateLimited adds key after a delay doubling at every retry of the key,
+// until forgotten.
+func (q *Queue) AddRateLimited(key string) {
+	q.mu.Lock()
+	n := q.requeues[key]
+	q.requeues[key] = n + 1
+	q.mu.Unlock()
+
+	d := retryMaxDelay
+	if n < 32 {
+		if backoff := retryBaseDelay << uint(n); backoff > 0 && backoff < retryMaxDelay {
+			d = backoff
+		}
+	}
+	q.AddAfter(key, d)
+}
+
+// Forget resets the retries of key, e.g. once processed successfully.
+func (q *Queue) Forget(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.requeues, key)
+}
+
+// NumRequeues returns the number of retries of key since forgotten.
+func (q *Queue) NumRequeues(key string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.requeues[key]
+}
+
+// Get waits for a key to process, which must be marked done once processed.
+// shutdown is true once the queue is shut down and drained.
+func (q *Queue) Get() (key string, shutdown bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.queue) == 0 && !q.shutdown {
+		q.cond.Wait()
+	}
+	if len(q.queue) == 0 {
+		return "", true
+	}
+	key, q.queue = q.queue[0], q.queue[1:]
+	q.processing[key] = struct{}{}
+	delete(q.dirty, key)
+	return key, false
+}
+
+// Done marks key processed, queuing it again if added while processed.
+func (q *Queue) Done(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.processing, key)
+	if _, ok := q.dirty[key]; ok {
+		q.queue = append(q.queue, key)
+		q.cond.Signal()
+	}
+}
+
+// Len returns the number of keys queued.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// ShutDown shuts down the queue, ignoring the keys added from then on. The
+// workers get the keys already queued before being notified of the shutdown.
+func (q *Queue) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutdown = true
+	q.cond.Broadcast()
+}
+
+// ShuttingDown returns true once the queue is shut down.
+func (q *Queue) ShuttingDown() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.shutdown
+}
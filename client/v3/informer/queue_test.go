@@ -0,0 +1,97 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueDeduplicates(t *testing.T) {
+	q := NewQueue()
+	q.Add("a")
+	q.Add("b")
+	q.Add("a")
+	if n := q.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+
+	key, _ := q.Get()
+	if key != "a" {
+		t.Fatalf("Get = %q, want a", key)
+	}
+	// a is added while processed, and queued once done
+	q.Add("a")
+	q.Add("a")
+	if n := q.Len(); n != 1 {
+		t.Fatalf("Len = %d, want 1", n)
+	}
+	q.Done("a")
+	if n := q.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+	for _, want := range []string{"b", "a"} {
+		if key, _ = q.Get(); key != want {
+			t.Fatalf("Get = %q, want %q", key, want)
+		}
+		q.Done(key)
+	}
+	if n := q.Len(); n != 0 {
+		t.Fatalf("Len = %d, want 0", n)
+	}
+}
+
+func TestQueueShutDown(t *testing.T) {
+	q := NewQueue()
+	q.Add("a")
+	q.ShutDown()
+	q.Add("b")
+
+	if key, shutdown := q.Get(); key != "a" || shutdown {
+		t.Fatalf("Get = %q, %v, want a, false", key, shutdown)
+	}
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		if _, shutdown := q.Get(); !shutdown {
+			t.Errorf("Get shutdown = false, want true")
+		}
+	}()
+	select {
+	case <-donec:
+	case <-time.After(time.Second):
+		t.Fatal("Get blocked on a drained queue shut down")
+	}
+}
+
+func TestQueueAddRateLimited(t *testing.T) {
+	q := NewQueue()
+	defer q.ShutDown()
+	for i := 0; i < 3; i++ {
+		q.AddRateLimited("a")
+		key, _ := q.Get()
+		if key != "a" {
+			t.Fatalf("Get = %q, want a", key)
+		}
+		q.Done(key)
+	}
+	if n := q.NumRequeues("a"); n != 3 {
+		t.Fatalf("NumRequeues = %d, want 3", n)
+	}
+	q.Forget("a")
+	if n := q.NumRequeues("a"); n != 0 {
+		t.Fatalf("NumRequeues = %d, want 0", n)
+	}
+}
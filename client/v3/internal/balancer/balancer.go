@@ -0,0 +1,322 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package balancer implements the load balancing of the client requests
+// across the endpoints weighted by their health, scored by the latencies and
+// the error rates of their requests.
+package balancer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/serviceconfig"
+	"google.golang.org/grpc/status"
+)
+
+// Name is the name of the health scoring load balancing policy.
+const Name = "etcd_health_scoring"
+
+const (
+	defaultErrorRateThreshold = 0.5
+	defaultQuarantineDuration = 30 * time.Second
+
+	// decay is the weight of the latest request in the moving averages of
+	// the latency and of the error rate of an endpoint.
+	decay = 0.2
+	// minLatency bounds the weight of the endpoints with tiny latencies.
+	minLatency = time.Millisecond
+)
+
+// streamMethods are the streaming methods, whose latencies are the lifetimes
+// of the streams and not scored.
+var streamMethods = map[string]struct{}{
+	"/etcdserverpb.Watch/Watch":               {},
+	"/etcdserverpb.Lease/LeaseKeepAlive":      {},
+	"/etcdserverpb.Maintenance/Snapshot":      {},
+	"/etcdserverpb.Maintenance/SnapshotDelta": {},
+	"/v3electionpb.Election/Observe":          {},
+}
+
+func init() {
+	balancer.Register(builder{})
+}
+
+// Config is the load balancing config of the health scoring policy.
+type Config struct {
+	serviceconfig.LoadBalancingConfig `json:"-"`
+
+	// ErrorRateThreshold is the error rate, from 0 to 1, at which an endpoint
+	// is quarantined. 0 defaults to 0.5.
+	ErrorRateThreshold float64 `json:"errorRateThreshold,omitempty"`
+	// QuarantineDuration is how long a quarantined endpoint is not sent any
+	// request unless all the endpoints are quarantined. 0 defaults to 30s.
+	QuarantineDuration time.Duration `json:"quarantineDuration,omitempty"`
+}
+
+// ServiceConfig returns the service config balancing the load by the health
+// of the endpoints with the config.
+func ServiceConfig(cfg Config) string {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf(`{"loadBalancingConfig": [{%q: %s}]}`, Name, b)
+}
+
+type builder struct{}
+
+func (builder) Name() string { return Name }
+
+func (builder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	hb := &healthBalancer{scorer: newScorer(Config{}, time.Now)}
+	hb.Balancer = base.NewBalancerBuilder(Name, hb.scorer, base.Config{HealthCheck: true}).Build(cc, opts)
+	return hb
+}
+
+func (builder) ParseConfig(js json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	var cfg Config
+	if err := json.Unmarshal(js, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s config %s: %v", Name, js, err)
+	}
+	if cfg.ErrorRateThreshold < 0 || cfg.ErrorRateThreshold > 1 {
+		return nil, fmt.Errorf("invalid %s config %s: the error rate threshold must be within [0, 1]", Name, js)
+	}
+	if cfg.QuarantineDuration < 0 {
+		return nil, fmt.Errorf("invalid %s config %s: the quarantine duration must be >= 0", Name, js)
+	}
+	return &cfg, nil
+}
+
+// healthBalancer is the base balancer, picking the ready endpoints by their
+// scores kept across the pickers.
+type healthBalancer struct {
+	balancer.Balancer
+	scorer *scorer
+}
+
+func (hb *healthBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	if cfg, ok := s.BalancerConfig.(*Config); ok {
+		hb.scorer.setConfig(*cfg)
+	}
+	addrs := make([]string, len(s.ResolverState.Addresses))
+	for i, a := range s.ResolverState.Addresses {
+		addrs[i] = a.Addr
+	}
+	hb.scorer.retain(addrs)
+	return hb.Balancer.UpdateClientConnState(s)
+}
+
+// score is the health of an endpoint.
+type score struct {
+	// requests is the number of requests scored.
+	requests int
+	// latency and errorRate are the moving averages of the latency, in
+	// seconds, and of the error rate of the requests.
+	latency   float64
+	errorRate float64
+	// quarantinedUntil is when the endpoint is released from the quarantine.
+	quarantinedUntil time.Time
+}
+
+func (s *score) weight() float64 {
+	return (1 - s.errorRate) / math.Max(s.latency, minLatency.Seconds())
+}
+
+// scorer scores the endpoints by their requests, and builds the pickers
+// picking the endpoints weighted by their scores.
+type scorer struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	cfg    Config
+	rand   *rand.Rand
+	scores map[string]*score
+}
+
+func newScorer(cfg Config, now func() time.Time) *scorer {
+	s := &scorer{
+		now:    now,
+		rand:   rand.New(rand.NewSource(now().UnixNano())),
+		scores: make(map[string]*score),
+	}
+	s.setConfig(cfg)
+	return s
+}
+
+func (s *scorer) setConfig(cfg Config) {
+	if cfg.ErrorRateThreshold == 0 {
+		cfg.ErrorRateThreshold = defaultErrorRateThreshold
+	}
+	if cfg.QuarantineDuration == 0 {
+		cfg.QuarantineDuration = defaultQuarantineDuration
+	}
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+}
+
+// retain drops the scores of the endpoints not in addrs.
+func (s *scorer) retain(addrs []string) {
+	keep := make(map[string]struct{}, len(addrs))
+	for _, a := range addrs {
+		keep[a] = struct{}{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for a := range s.scores {
+		if _, ok := keep[a]; !ok {
+			delete(s.scores, a)
+		}
+	}
+}
+
+// Build implements base.PickerBuilder.
+func (s *scorer) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &picker{scorer: s}
+	for sc, sci := range info.ReadySCs {
+		p.subConns = append(p.subConns, sc)
+		p.addrs = append(p.addrs, sci.Address.Addr)
+	}
+	return p
+}
+
+// pick returns the index in addrs of the endpoint to send a request to,
+// picked at random weighted by the scores of the endpoints not quarantined.
+// The endpoints without any score yet are weighted by the average of the
+// scored ones.
+func (s *scorer) pick(addrs []string) int {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	weights := make([]float64, len(addrs))
+	var candidates []int
+	var scored int
+	var sum float64
+	for i, a := range addrs {
+		sc := s.score(a)
+		if !sc.quarantinedUntil.IsZero() {
+			if now.Before(sc.quarantinedUntil) {
+				continue
+			}
+			// released, the endpoint is scored again from scratch
+			*sc = score{}
+		}
+		candidates = append(candidates, i)
+		if sc.requests > 0 {
+			weights[i] = sc.weight()
+			sum += weights[i]
+			scored++
+		}
+	}
+	if len(candidates) == 0 {
+		// all the endpoints are quarantined, fall back to any of them
+		return s.rand.Intn(len(addrs))
+	}
+	unscored := 1.0
+	if scored > 0 {
+		unscored = sum / float64(scored)
+	}
+	var total float64
+	for _, i := range candidates {
+		if s.score(addrs[i]).requests == 0 {
+			weights[i] = unscored
+		}
+		total += weights[i]
+	}
+	r := s.rand.Float64() * total
+	for _, i := range candidates {
+		if r < weights[i] {
+			return i
+		}
+		r -= weights[i]
+	}
+	return candidates[len(candidates)-1]
+}
+
+func (s *scorer) score(addr string) *score {
+	sc, ok := s.scores[addr]
+	if !ok {
+		sc = &score{}
+		s.scores[addr] = sc
+	}
+	return sc
+}
+
+// record scores the endpoint by a request that took latency and finished
+// with err. The requests canceled by the client are not scored, nor the
+// latencies of the streams.
+func (s *scorer) record(addr string, latency time.Duration, err error, stream bool) {
+	code := status.Code(err)
+	if code == codes.Canceled {
+		return
+	}
+	failed := 0.0
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal:
+		failed = 1
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc := s.score(addr)
+	if !sc.quarantinedUntil.IsZero() {
+		// the requests picked before the quarantine
+		return
+	}
+	sc.errorRate = (1-decay)*sc.errorRate + decay*failed
+	if !stream {
+		if sc.requests == 0 {
+			sc.latency = latency.Seconds()
+		} else {
+			sc.latency = (1-decay)*sc.latency + decay*latency.Seconds()
+		}
+	}
+	sc.requests++
+	if failed > 0 && sc.errorRate >= s.cfg.ErrorRateThreshold {
+		sc.quarantinedUntil = now.Add(s.cfg.QuarantineDuration)
+	}
+}
+
+type picker struct {
+	scorer *scorer
+	// subConns and addrs are the ready endpoints when the picker was built.
+	subConns []balancer.SubConn
+	addrs    []string
+}
+
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	i := p.scorer.pick(p.addrs)
+	addr := p.addrs[i]
+	_, stream := streamMethods[info.FullMethodName]
+	start := p.scorer.now()
+	return balancer.PickResult{
+		SubConn: p.subConns[i],
+		Done: func(di balancer.DoneInfo) {
+			p.scorer.record(addr, p.scorer.now().Sub(start), di.Err, stream)
+		},
+	}, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package balancer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errUnavailable = status.Error(codes.Unavailable, "unavailable")
+	errCanceled    = status.Error(codes.Canceled, "canceled")
+	errNotFound    = status.Error(codes.NotFound, "not found")
+)
+
+func newTestScorer() (*scorer, *time.Time) {
+	now := time.Unix(0, 0)
+	return newScorer(Config{}, func() time.Time { return now }), &now
+}
+
+func picks(s *scorer, addrs []string, n int) map[string]int {
+	ps := make(map[string]int)
+	for i := 0; i < n; i++ {
+		ps[addrs[s.pick(addrs)]]++
+	}
+	return ps
+}
+
+func TestScorerWeights(t *testing.T) {
+	s, _ := newTestScorer()
+	addrs := []string{"fast", "slow", "failing", "new"}
+	for i := 0; i < 10; i++ {
+		s.record("fast", 10*time.Millisecond, nil, false)
+		s.record("slow", 100*time.Millisecond, nil, false)
+		s.record("failing", 10*time.Millisecond, errNotFound, false)
+		s.record("failing", 10*time.Millisecond, nil, false)
+	}
+	// the stream lifetimes and the canceled requests are not scored
+	s.record("fast", time.Hour, nil, true)
+	s.record("fast", time.Hour, errCanceled, false)
+
+	ps := picks(s, addrs, 10000)
+	if ps["fast"] < 5*ps["slow"] {
+		t.Errorf("picks = %v, want the fast endpoint picked about 10 times the slow one", ps)
+	}
+	// the application errors are not failures of the endpoint
+	if ps["failing"] < 5*ps["slow"] {
+		t.Errorf("picks = %v, want the endpoint failing with application errors picked as the fast one", ps)
+	}
+	// the new endpoint is weighted by the average
+	if ps["new"] < ps["slow"] || ps["new"] > ps["fast"] {
+		t.Errorf("picks = %v, want the new endpoint picked between the slow and the fast ones", ps)
+	}
+}
+
+func TestScorerQuarantine(t *testing.T) {
+	s, now := newTestScorer()
+	s.setConfig(Config{ErrorRateThreshold: 0.5, QuarantineDuration: time.Minute})
+	addrs := []string{"a", "b"}
+	for i := 0; i < 3; i++ {
+		s.record("a", time.Millisecond, errUnavailable, false)
+		if ps := picks(s, addrs, 100); ps["a"] == 0 {
+			t.Fatalf("#%d: picks = %v, want a not quarantined yet", i, ps)
+		}
+	}
+	s.record("a", time.Millisecond, errUnavailable, false)
+	if ps := picks(s, addrs, 100); !reflect.DeepEqual(ps, map[string]int{"b": 100}) {
+		t.Fatalf("picks = %v, want a quarantined", ps)
+	}
+
+	// all the endpoints are quarantined
+	for i := 0; i < 4; i++ {
+		s.record("b", time.Millisecond, status.Error(codes.DeadlineExceeded, "timeout"), false)
+	}
+	if ps := picks(s, addrs, 100); ps["a"] == 0 || ps["b"] == 0 {
+		t.Fatalf("picks = %v, want any endpoint picked", ps)
+	}
+
+	*now = now.Add(time.Minute)
+	if ps := picks(s, addrs, 100); ps["a"] == 0 || ps["b"] == 0 {
+		t.Fatalf("picks = %v, want the endpoints released", ps)
+	}
+	if sc := s.scores["a"]; !reflect.DeepEqual(*sc, score{}) {
+		t.Errorf("score of a = %+v, want scored again from scratch", *sc)
+	}
+
+	s.retain([]string{"b"})
+	if _, ok := s.scores["a"]; ok {
+		t.Errorf("score of a kept, want it dropped with the endpoint")
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg := Config{ErrorRateThreshold: 0.3, QuarantineDuration: time.Second}
+	sc := ServiceConfig(cfg)
+	wsc := `{"loadBalancingConfig": [{"etcd_health_scoring": {"errorRateThreshold":0.3,"quarantineDuration":1000000000}}]}`
+	if sc != wsc {
+		t.Fatalf("service config = %s, want %s", sc, wsc)
+	}
+	lbc, err := builder{}.ParseConfig([]byte(`{"errorRateThreshold":0.3,"quarantineDuration":1000000000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lbc, &cfg) {
+		t.Errorf("config = %+v, want %+v", lbc, cfg)
+	}
+	for _, js := range []string{`{"errorRateThreshold":2}`, `{"quarantineDuration":-1}`, `[]`} {
+		if _, err := (builder{}).ParseConfig([]byte(js)); err == nil {
+			t.Errorf("ParseConfig(%s) error = nil, want an error", js)
+		}
+	}
+}
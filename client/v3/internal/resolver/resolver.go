@@ -31,6 +31,9 @@ type EtcdManualResolver struct {
 	*manual.Resolver
 	endpoints     []string
 	serviceConfig *serviceconfig.ParseResult
+	// serviceConfigJSON is the service config, the round-robin load
+	// balancing if empty.
+	serviceConfigJSON string
 }
 
 func New(endpoints ...string) *EtcdManualResolver {
@@ -40,7 +43,11 @@ func New(endpoints ...string) *EtcdManualResolver {
 
 // Build returns itself for Resolver, because it's both a builder and a resolver.
 func (r *EtcdManualResolver) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
-	r.serviceConfig = cc.ParseServiceConfig(`{"loadBalancingPolicy": "round_robin"}`)
+	sc := r.serviceConfigJSON
+	if sc == "" {
+		sc = `{"loadBalancingPolicy": "round_robin"}`
+	}
+	r.serviceConfig = cc.ParseServiceConfig(sc)
 	if r.serviceConfig.Err != nil {
 		return nil, r.serviceConfig.Err
 	}
@@ -53,6 +60,12 @@ func (r *EtcdManualResolver) Build(target resolver.Target, cc resolver.ClientCon
 	return res, nil
 }
 
+// SetServiceConfig sets the service config of the resolver, e.g. its load
+// balancing policy. It must be called before the resolver is built.
+func (r *EtcdManualResolver) SetServiceConfig(sc string) {
+	r.serviceConfigJSON = sc
+}
+
 func (r *EtcdManualResolver) SetEndpoints(endpoints []string) {
 	r.endpoints = endpoints
 	r.updateState()
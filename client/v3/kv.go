@@ -16,6 +16,9 @@ package clientv3
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
+
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
@@ -24,11 +27,12 @@ import (
 )
 
 type (
-	CompactResponse pb.CompactionResponse
-	PutResponse     pb.PutResponse
-	GetResponse     pb.RangeResponse
-	DeleteResponse  pb.DeleteRangeResponse
-	TxnResponse     pb.TxnResponse
+	CompactResponse   pb.CompactionResponse
+	PutResponse       pb.PutResponse
+	GetResponse       pb.RangeResponse
+	DeleteResponse    pb.DeleteRangeResponse
+	TxnResponse       pb.TxnResponse
+	IncrementResponse pb.IncrementResponse
 )
 
 type KV interface {
@@ -70,6 +74,7 @@ type OpResponse struct {
 	get *GetResponse
 	del *DeleteResponse
 	txn *TxnResponse
+	inc *IncrementResponse
 }
 
 func (op OpResponse) Put() *PutResponse    { return op.put }
@@ -77,6 +82,9 @@ func (op OpResponse) Get() *GetResponse    { return op.get }
 func (op OpResponse) Del() *DeleteResponse { return op.del }
 func (op OpResponse) Txn() *TxnResponse    { return op.txn }
 
+// Increment returns the response of an increment Op.
+func (op OpResponse) Increment() *IncrementResponse { return op.inc }
+
 func (resp *PutResponse) OpResponse() OpResponse {
 	return OpResponse{put: resp}
 }
@@ -89,6 +97,9 @@ func (resp *DeleteResponse) OpResponse() OpResponse {
 func (resp *TxnResponse) OpResponse() OpResponse {
 	return OpResponse{txn: resp}
 }
+func (resp *IncrementResponse) OpResponse() OpResponse {
+	return OpResponse{inc: resp}
+}
 
 type kv struct {
 	remote   pb.KVClient
@@ -148,7 +159,11 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 	case tRange:
 		if op.IsSortOptionValid() {
 			var resp *pb.RangeResponse
-			resp, err = kv.remote.Range(ctx, op.toRangeRequest(), kv.callOpts...)
+			if op.serializable && op.hedgeDelay > 0 {
+				resp, err = kv.hedgedRange(ctx, op.toRangeRequest(), op.hedgeDelay)
+			} else {
+				resp, err = kv.remote.Range(ctx, op.toRangeRequest(), kv.callOpts...)
+			}
 			if err == nil {
 				return OpResponse{get: (*GetResponse)(resp)}, nil
 			}
@@ -157,14 +172,14 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 		}
 	case tPut:
 		var resp *pb.PutResponse
-		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease}
+		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease, Annotations: op.annotations}
 		resp, err = kv.remote.Put(ctx, r, kv.callOpts...)
 		if err == nil {
 			return OpResponse{put: (*PutResponse)(resp)}, nil
 		}
 	case tDeleteRange:
 		var resp *pb.DeleteRangeResponse
-		r := &pb.DeleteRangeRequest{Key: op.key, RangeEnd: op.end, PrevKv: op.prevKV}
+		r := &pb.DeleteRangeRequest{Key: op.key, RangeEnd: op.end, PrevKv: op.prevKV, Paced: op.paced}
 		resp, err = kv.remote.DeleteRange(ctx, r, kv.callOpts...)
 		if err == nil {
 			return OpResponse{del: (*DeleteResponse)(resp)}, nil
@@ -175,8 +190,95 @@ func (kv *kv) Do(ctx context.Context, op Op) (OpResponse, error) {
 		if err == nil {
 			return OpResponse{txn: (*TxnResponse)(resp)}, nil
 		}
+	case tIncrement:
+		// increments are only applied in txns
+		var resp *pb.TxnResponse
+		r := &pb.TxnRequest{Success: []*pb.RequestOp{op.toRequestOp()}}
+		resp, err = kv.remote.Txn(ctx, r, kv.callOpts...)
+		if err == nil {
+			inc := resp.Responses[0].GetResponseIncrement()
+			inc.Header = resp.Header
+			return OpResponse{inc: (*IncrementResponse)(inc)}, nil
+		}
 	default:
 		panic("Unknown op")
 	}
 	return OpResponse{}, toErr(ctx, err)
 }
+
+// hedgedRange sends the range, and a duplicate of it if no response is
+// received within the delay, returning the first successful response, or
+// the last error if both fail.
+func (kv *kv) hedgedRange(ctx context.Context, r *pb.RangeRequest, delay time.Duration) (*pb.RangeResponse, error) {
+	// cancels the pending request once one succeeds
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type rangeResult struct {
+		resp *pb.RangeResponse
+		err  error
+	}
+	resc := make(chan rangeResult, 2)
+	send := func() {
+		resp, err := kv.remote.Range(ctx, r, kv.callOpts...)
+		resc <- rangeResult{resp, err}
+	}
+	go send()
+	pending := 1
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			go send()
+			pending++
+		case res := <-resc:
+			pending--
+			if res.err == nil || pending == 0 {
+				return res.resp, res.err
+			}
+		}
+	}
+}
+
+// readYourWritesInterceptor records the highest revision of the key-value
+// responses, and makes the serializable ranges wait for the member serving
+// them to apply it.
+func (c *Client) readYourWritesInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if r, ok := req.(*pb.RangeRequest); ok && r.Serializable {
+			if rev := atomic.LoadInt64(&c.readRev); rev > minRevision(ctx) {
+				ctx = WithMinRevision(ctx, rev)
+			}
+		}
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+		var hdr *pb.ResponseHeader
+		switch resp := reply.(type) {
+		case *pb.RangeResponse:
+			hdr = resp.Header
+		case *pb.PutResponse:
+			hdr = resp.Header
+		case *pb.DeleteRangeResponse:
+			hdr = resp.Header
+		case *pb.TxnResponse:
+			hdr = resp.Header
+		}
+		if hdr != nil {
+			c.observeRevision(hdr.Revision)
+		}
+		return nil
+	}
+}
+
+// observeRevision raises the highest revision of the key-value responses to
+// rev.
+func (c *Client) observeRevision(rev int64) {
+	for {
+		cur := atomic.LoadInt64(&c.readRev)
+		if rev <= cur || atomic.CompareAndSwapInt64(&c.readRev, cur, rev) {
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+
+	"google.golang.org/grpc"
+)
+
+// slowFirstKVClient serves the first range after a second and the next ones
+// right away, or fails all of them if failing.
+type slowFirstKVClient struct {
+	pb.KVClient
+	calls   int32
+	failing bool
+}
+
+func (c *slowFirstKVClient) Range(ctx context.Context, r *pb.RangeRequest, opts ...grpc.CallOption) (*pb.RangeResponse, error) {
+	call := atomic.AddInt32(&c.calls, 1)
+	if c.failing {
+		return nil, errors.New("unavailable")
+	}
+	if call > 1 {
+		return &pb.RangeResponse{Header: &pb.ResponseHeader{MemberId: uint64(call)}}, nil
+	}
+	select {
+	case <-time.After(time.Second):
+		return &pb.RangeResponse{Header: &pb.ResponseHeader{MemberId: 1}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestKVHedgedRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []OpOption
+		calls int32
+		// member is the member id of the response, 0 for an error
+		member uint64
+	}{
+		{"hedged", []OpOption{WithSerializable(), WithHedging(10 * time.Millisecond)}, 2, 2},
+		{"linearizable", []OpOption{WithHedging(10 * time.Millisecond)}, 1, 1},
+		{"not hedged", []OpOption{WithSerializable()}, 1, 1},
+		{"not delayed", []OpOption{WithSerializable(), WithHedging(2 * time.Second)}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &slowFirstKVClient{}
+			resp, err := NewKVFromKVClient(remote, nil).Get(context.Background(), "foo", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Header.MemberId != tt.member {
+				t.Errorf("served by %d, expected %d", resp.Header.MemberId, tt.member)
+			}
+			if calls := atomic.LoadInt32(&remote.calls); calls != tt.calls {
+				t.Errorf("sent %d ranges, expected %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestKVHedgedRangeErrors(t *testing.T) {
+	remote := &slowFirstKVClient{failing: true}
+	_, err := NewKVFromKVClient(remote, nil).Get(context.Background(), "foo", WithSerializable(), WithHedging(10*time.Millisecond))
+	if err == nil {
+		t.Fatal("expected the error of the range")
+	}
+	// failed before the delay
+	if calls := atomic.LoadInt32(&remote.calls); calls != 1 {
+		t.Errorf("sent %d ranges, expected 1", calls)
+	}
+}
@@ -15,10 +15,15 @@
 package clientv3
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.uber.org/zap"
@@ -26,13 +31,20 @@ import (
 )
 
 type (
-	DefragmentResponse pb.DefragmentResponse
-	AlarmResponse      pb.AlarmResponse
-	AlarmMember        pb.AlarmMember
-	StatusResponse     pb.StatusResponse
-	HashKVResponse     pb.HashKVResponse
-	MoveLeaderResponse pb.MoveLeaderResponse
-	DowngradeResponse  pb.DowngradeResponse
+	DefragmentResponse         pb.DefragmentResponse
+	AlarmResponse              pb.AlarmResponse
+	AlarmMember                pb.AlarmMember
+	StatusResponse             pb.StatusResponse
+	HashKVResponse             pb.HashKVResponse
+	RangeEstimateResponse      pb.RangeEstimateResponse
+	DefragmentEstimateResponse pb.DefragmentEstimateResponse
+	EventsResponse             pb.EventsResponse
+	LogLevelResponse           pb.LogLevelResponse
+	LogRangeResponse           pb.LogRangeResponse
+	FeatureGatesResponse       pb.FeatureGatesResponse
+	PrefixStatsResponse        pb.PrefixStatsResponse
+	MoveLeaderResponse         pb.MoveLeaderResponse
+	DowngradeResponse          pb.DowngradeResponse
 
 	DowngradeAction pb.DowngradeRequest_DowngradeAction
 )
@@ -59,6 +71,12 @@ type Maintenance interface {
 	// times with different endpoints.
 	Defragment(ctx context.Context, endpoint string) (*DefragmentResponse, error)
 
+	// DefragmentEstimate returns an estimate of the space defragmenting the
+	// endpoint would reclaim, and the tombstones of the deleted keys taking
+	// space until they are compacted.
+	// Supported since etcd 3.6.
+	DefragmentEstimate(ctx context.Context, endpoint string) (*DefragmentEstimateResponse, error)
+
 	// Status gets the status of the endpoint.
 	Status(ctx context.Context, endpoint string) (*StatusResponse, error)
 
@@ -67,6 +85,44 @@ type Maintenance interface {
 	// is non-zero, the hash is computed on all keys at or below the given revision.
 	HashKV(ctx context.Context, endpoint string, rev int64) (*HashKVResponse, error)
 
+	// RangeEstimate returns the approximate number of keys and their total size
+	// in the given range of the endpoint, without reading the whole range.
+	// The range is set by the key and the WithRange, WithPrefix and WithFromKey
+	// options; other options are ignored.
+	// Supported since etcd 3.6.
+	RangeEstimate(ctx context.Context, endpoint, key string, opts ...OpOption) (*RangeEstimateResponse, error)
+
+	// Events returns up to limit latest significant events of the endpoint,
+	// such as leader changes, compactions and alarms, from the oldest to the
+	// newest. All the events kept by the endpoint are returned if limit is zero.
+	// Supported since etcd 3.6.
+	Events(ctx context.Context, endpoint string, limit int64) (*EventsResponse, error)
+
+	// LogLevel sets the log level of the endpoint, globally if subsystem is empty,
+	// and returns its log levels. The subsystems are the directories of the files
+	// logging, e.g. "mvcc"; an empty level resets a subsystem to the global level.
+	// The levels are left unchanged if both level and subsystem are empty.
+	// Supported since etcd 3.6.
+	LogLevel(ctx context.Context, endpoint, level, subsystem string) (*LogLevelResponse, error)
+
+	// LogRange makes the endpoint log at the warn level the requests touching the
+	// range for the duration d, or stop logging them if d is zero.
+	// The range is set by the key and the WithRange, WithPrefix and WithFromKey
+	// options; other options are ignored.
+	// Supported since etcd 3.6.
+	LogRange(ctx context.Context, endpoint, key string, d time.Duration, opts ...OpOption) (*LogRangeResponse, error)
+
+	// FeatureGates returns the feature gates of the endpoint, with their stage,
+	// default and whether they are enabled.
+	// Supported since etcd 3.6.
+	FeatureGates(ctx context.Context, endpoint string) (*FeatureGatesResponse, error)
+
+	// PrefixStats returns the number of the reads and writes served by the
+	// endpoint, and of their bytes, for each of its configured key prefixes,
+	// counted since the endpoint started.
+	// Supported since etcd 3.6.
+	PrefixStats(ctx context.Context, endpoint string) (*PrefixStatsResponse, error)
+
 	// SnapshotWithVersion returns a reader for a point-in-time snapshot and version of etcd that created it.
 	// If the context "ctx" is canceled or timed out, reading from returned
 	// "io.ReadCloser" would error out (e.g. context.Canceled, context.DeadlineExceeded).
@@ -78,6 +134,15 @@ type Maintenance interface {
 	// Deprecated: use SnapshotWithVersion instead.
 	Snapshot(ctx context.Context) (io.ReadCloser, error)
 
+	// SnapshotDelta provides a reader for the changes of the key-value store
+	// after the revision sinceRev, up to a point-in-time revision of etcd: the
+	// responses of the delta stream, each marshaled after its uvarint length,
+	// the last one holding the manifest of the delta. Reading from the returned
+	// "io.ReadCloser" errors out if the digest of the events does not match the
+	// one of the manifest, or if the revisions after sinceRev were compacted.
+	// Supported since etcd 3.6.
+	SnapshotDelta(ctx context.Context, sinceRev int64) (io.ReadCloser, error)
+
 	// MoveLeader requests current leader to transfer its leadership to the transferee.
 	// Request must be made to the leader.
 	MoveLeader(ctx context.Context, transfereeID uint64) (*MoveLeaderResponse, error)
@@ -238,6 +303,99 @@ func (m *maintenance) HashKV(ctx context.Context, endpoint string, rev int64) (*
 	return (*HashKVResponse)(resp), nil
 }
 
+func (m *maintenance) DefragmentEstimate(ctx context.Context, endpoint string) (*DefragmentEstimateResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.DefragmentEstimate(ctx, &pb.DefragmentEstimateRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*DefragmentEstimateResponse)(resp), nil
+}
+
+func (m *maintenance) RangeEstimate(ctx context.Context, endpoint, key string, opts ...OpOption) (*RangeEstimateResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	op := OpGet(key, opts...)
+	resp, err := remote.RangeEstimate(ctx, &pb.RangeEstimateRequest{Key: op.key, RangeEnd: op.end}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*RangeEstimateResponse)(resp), nil
+}
+
+func (m *maintenance) Events(ctx context.Context, endpoint string, limit int64) (*EventsResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.Events(ctx, &pb.EventsRequest{Limit: limit}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*EventsResponse)(resp), nil
+}
+
+func (m *maintenance) LogLevel(ctx context.Context, endpoint, level, subsystem string) (*LogLevelResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.LogLevel(ctx, &pb.LogLevelRequest{Level: level, Subsystem: subsystem}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*LogLevelResponse)(resp), nil
+}
+
+func (m *maintenance) LogRange(ctx context.Context, endpoint, key string, d time.Duration, opts ...OpOption) (*LogRangeResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	op := OpGet(key, opts...)
+	resp, err := remote.LogRange(ctx, &pb.LogRangeRequest{Key: op.key, RangeEnd: op.end, Duration: int64(d / time.Second)}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*LogRangeResponse)(resp), nil
+}
+
+func (m *maintenance) FeatureGates(ctx context.Context, endpoint string) (*FeatureGatesResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.FeatureGates(ctx, &pb.FeatureGatesRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*FeatureGatesResponse)(resp), nil
+}
+
+func (m *maintenance) PrefixStats(ctx context.Context, endpoint string) (*PrefixStatsResponse, error) {
+	remote, cancel, err := m.dial(endpoint)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	defer cancel()
+	resp, err := remote.PrefixStats(ctx, &pb.PrefixStatsRequest{}, m.callOpts...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+	return (*PrefixStatsResponse)(resp), nil
+}
+
 func (m *maintenance) SnapshotWithVersion(ctx context.Context) (*SnapshotResponse, error) {
 	ss, err := m.remote.Snapshot(ctx, &pb.SnapshotRequest{}, append(m.callOpts, withMax(defaultStreamMaxRetries))...)
 	if err != nil {
@@ -304,6 +462,56 @@ func (m *maintenance) Snapshot(ctx context.Context) (io.ReadCloser, error) {
 	return &snapshotReadCloser{ctx: ctx, ReadCloser: pr}, err
 }
 
+func (m *maintenance) SnapshotDelta(ctx context.Context, sinceRev int64) (io.ReadCloser, error) {
+	ss, err := m.remote.SnapshotDelta(ctx, &pb.SnapshotDeltaRequest{SinceRevision: sinceRev}, append(m.callOpts, withMax(defaultStreamMaxRetries))...)
+	if err != nil {
+		return nil, toErr(ctx, err)
+	}
+
+	m.lg.Info("opened snapshot delta stream; downloading", zap.Int64("since-revision", sinceRev))
+	pr, pw := io.Pipe()
+
+	go func() {
+		h := sha256.New()
+		for {
+			resp, err := ss.Recv()
+			if err == nil {
+				err = m.saveDelta(resp, h, pw)
+			}
+			if err != nil {
+				m.logAndCloseWithError(err, pw)
+				return
+			}
+		}
+	}()
+	return &snapshotReadCloser{ctx: ctx, ReadCloser: pr}, nil
+}
+
+func (m *maintenance) saveDelta(resp *pb.SnapshotDeltaResponse, h hash.Hash, pw *io.PipeWriter) error {
+	for _, ev := range resp.Events {
+		b, err := ev.Marshal()
+		if err != nil {
+			return err
+		}
+		h.Write(b)
+	}
+	if resp.Manifest != nil && !bytes.Equal(h.Sum(nil), resp.Manifest.Sha256) {
+		return errors.New("etcdclient: snapshot delta sha256 checksum mismatch")
+	}
+	b, err := resp.Marshal()
+	if err != nil {
+		return err
+	}
+	var l [binary.MaxVarintLen64]byte
+	if _, werr := pw.Write(l[:binary.PutUvarint(l[:], uint64(len(b)))]); werr != nil {
+		return werr
+	}
+	if _, werr := pw.Write(b); werr != nil {
+		return werr
+	}
+	return nil
+}
+
 func (m *maintenance) logAndCloseWithError(err error, pw *io.PipeWriter) {
 	switch err {
 	case io.EOF:
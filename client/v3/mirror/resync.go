@@ -0,0 +1,180 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mirror
+
+import (
+	"context"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// resyncRetryInterval is the interval the failed lists and the canceled
+// watches are retried at.
+const resyncRetryInterval = time.Second
+
+// ResyncResponse is a watch response, or the key-value pairs of the watched
+// range listed as the watch missed events.
+type ResyncResponse struct {
+	clientv3.WatchResponse
+	// Resync is true if Kvs are the key-value pairs of the range at
+	// Header.Revision, replacing the ones previously listed or watched. A
+	// resync response has no events.
+	Resync bool
+	Kvs    []*mvccpb.KeyValue
+}
+
+// WatchWithResync lists the key-value pairs of the range of key and opts,
+// and watches it from the revision of the list. When the revision the watch
+// resumes from is compacted, it lists the range again at the current
+// revision and resumes watching from there, so that the consumer of the
+// responses rebuilds its state from the resync response instead of missing
+// events. If opts set a revision, the range is watched from that revision,
+// and only listed once compacted. The other canceled watches, e.g. by the
+// loss of the leader, are resumed from the last revision watched.
+//
+// The options are of the watch, the range listed being the range of key
+// and opts. The returned chan is closed once ctx is done, or the watcher
+// closed.
+func WatchWithResync(ctx context.Context, kv clientv3.KV, w clientv3.Watcher, key string, opts ...clientv3.OpOption) <-chan ResyncResponse {
+	respc := make(chan ResyncResponse)
+	go func() {
+		defer close(respc)
+		r := &resyncer{ctx: ctx, kv: kv, w: w, key: key, opts: opts, respc: respc}
+		r.run()
+	}()
+	return respc
+}
+
+type resyncer struct {
+	ctx   context.Context
+	kv    clientv3.KV
+	w     clientv3.Watcher
+	key   string
+	opts  []clientv3.OpOption
+	respc chan<- ResyncResponse
+}
+
+func (r *resyncer) run() {
+	// rev is the last revision listed or watched
+	rev := clientv3.OpGet(r.key, r.opts...).Rev() - 1
+	for {
+		if rev < 0 {
+			resp, err := r.list()
+			if err != nil {
+				if !r.wait() {
+					return
+				}
+				continue
+			}
+			rev = resp.Header.Revision
+			if !r.send(ResyncResponse{WatchResponse: clientv3.WatchResponse{Header: *resp.Header}, Resync: true, Kvs: resp.Kvs}) {
+				return
+			}
+		}
+
+		var compacted, canceled bool
+		rev, compacted, canceled = r.watch(rev)
+		switch {
+		case r.ctx.Err() != nil:
+			return
+		case compacted:
+			rev = -1
+		case !canceled:
+			// the watcher is closed
+			return
+		case !r.wait():
+			return
+		}
+	}
+}
+
+// list lists the key-value pairs of the range in batches, at the revision
+// of the first batch.
+func (r *resyncer) list() (*clientv3.GetResponse, error) {
+	op := clientv3.OpGet(r.key, r.opts...)
+	key, end := string(op.KeyBytes()), string(op.RangeBytes())
+	var (
+		resp *clientv3.GetResponse
+		rev  int64
+	)
+	for {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(batchLimit), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend)}
+		if rev != 0 {
+			opts = append(opts, clientv3.WithRev(rev))
+		}
+		batch, err := r.kv.Get(r.ctx, key, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			resp, rev = batch, batch.Header.Revision
+		} else {
+			resp.Kvs = append(resp.Kvs, batch.Kvs...)
+		}
+		if !batch.More || end == "" {
+			resp.More, resp.Count = false, int64(len(resp.Kvs))
+			return resp, nil
+		}
+		key = string(append(batch.Kvs[len(batch.Kvs)-1].Key, 0))
+	}
+}
+
+// watch forwards the responses of the watch of the range from rev+1 until
+// it ends, returning the last revision watched, and whether it was canceled
+// as rev is compacted, or otherwise.
+func (r *resyncer) watch(rev int64) (_ int64, compacted, canceled bool) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+	opts := append(append([]clientv3.OpOption{}, r.opts...), clientv3.WithRev(rev+1))
+	for wresp := range r.w.Watch(ctx, r.key, opts...) {
+		if wresp.CompactRevision != 0 {
+			return rev, true, true
+		}
+		if wresp.Canceled {
+			return rev, false, true
+		}
+		switch {
+		case len(wresp.Events) > 0:
+			rev = wresp.Events[len(wresp.Events)-1].Kv.ModRevision
+		case wresp.IsProgressNotify():
+			rev = wresp.Header.Revision
+		}
+		if !r.send(ResyncResponse{WatchResponse: wresp}) {
+			return rev, false, false
+		}
+	}
+	return rev, false, false
+}
+
+func (r *resyncer) send(resp ResyncResponse) bool {
+	select {
+	case r.respc <- resp:
+		return true
+	case <-r.ctx.Done():
+		return false
+	}
+}
+
+// wait waits for the retry interval, returning false if ctx is done.
+func (r *resyncer) wait() bool {
+	select {
+	case <-time.After(resyncRetryInterval):
+		return true
+	case <-r.ctx.Done():
+		return false
+	}
+}
@@ -28,13 +28,18 @@ type watcherPrefix struct {
 	wg       sync.WaitGroup
 	stopc    chan struct{}
 	stopOnce sync.Once
+
+	// mu protects wchs
+	mu sync.Mutex
+	// wchs are the watch channels of the Watcher, by the channels returned
+	wchs map[clientv3.WatchChan]clientv3.WatchChan
 }
 
 // NewWatcher wraps a Watcher instance so that all Watch requests
 // are prefixed with a given string and all Watch responses have
 // the prefix removed.
 func NewWatcher(w clientv3.Watcher, prefix string) clientv3.Watcher {
-	return &watcherPrefix{Watcher: w, pfx: prefix, stopc: make(chan struct{})}
+	return &watcherPrefix{Watcher: w, pfx: prefix, stopc: make(chan struct{}), wchs: make(map[clientv3.WatchChan]clientv3.WatchChan)}
 }
 
 func (w *watcherPrefix) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
@@ -50,9 +55,15 @@ func (w *watcherPrefix) Watch(ctx context.Context, key string, opts ...clientv3.
 
 	// translate watch events from prefixed to unprefixed
 	pfxWch := make(chan clientv3.WatchResponse)
+	w.mu.Lock()
+	w.wchs[pfxWch] = wch
+	w.mu.Unlock()
 	w.wg.Add(1)
 	go func() {
 		defer func() {
+			w.mu.Lock()
+			delete(w.wchs, pfxWch)
+			w.mu.Unlock()
 			close(pfxWch)
 			w.wg.Done()
 		}()
@@ -75,6 +86,15 @@ func (w *watcherPrefix) Watch(ctx context.Context, key string, opts ...clientv3.
 	return pfxWch
 }
 
+func (w *watcherPrefix) RequestWatchProgress(ctx context.Context, wch clientv3.WatchChan) error {
+	w.mu.Lock()
+	if pwch, ok := w.wchs[wch]; ok {
+		wch = pwch
+	}
+	w.mu.Unlock()
+	return w.Watcher.RequestWatchProgress(ctx, wch)
+}
+
 func (w *watcherPrefix) Close() error {
 	err := w.Watcher.Close()
 	w.stopOnce.Do(func() { close(w.stopc) })
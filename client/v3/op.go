@@ -14,7 +14,11 @@
 
 package clientv3
 
-import pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
 
 type opType int
 
@@ -24,6 +28,7 @@ const (
 	tPut
 	tDeleteRange
 	tTxn
+	tIncrement
 )
 
 var noPrefixEnd = []byte{0}
@@ -44,6 +49,15 @@ type Op struct {
 	maxModRev    int64
 	minCreateRev int64
 	maxCreateRev int64
+	// hedgeDelay is the delay before a duplicate of a serializable range
+	// is sent, 0 not to send any.
+	hedgeDelay time.Duration
+	// compactionBarrier registers a compaction barrier at the revision of
+	// the range.
+	compactionBarrier bool
+	// minRevWait is the revision the member serving the range waits to
+	// apply before serving it, 0 not to wait.
+	minRevWait int64
 
 	// for range, watch
 	rev int64
@@ -60,19 +74,30 @@ type Op struct {
 	// for put
 	ignoreValue bool
 	ignoreLease bool
+	annotations map[string]string
+
+	// for delete
+	paced bool
 
 	// progressNotify is for progress updates.
 	progressNotify bool
+	// progressNotifyInterval is the interval of the progress updates.
+	progressNotifyInterval time.Duration
 	// createdNotify is for created event
 	createdNotify bool
 	// filters for watchers
 	filterPut    bool
 	filterDelete bool
+	valuePrefix  string
+	valueRegex   string
 
 	// for put
 	val     []byte
 	leaseID LeaseID
 
+	// for increment
+	delta int64
+
 	// txn
 	cmps    []Cmp
 	thenOps []Op
@@ -115,6 +140,12 @@ func (op Op) IsGet() bool { return op.t == tRange }
 // IsDelete returns true iff the operation is a Delete.
 func (op Op) IsDelete() bool { return op.t == tDeleteRange }
 
+// IsIncrement returns true iff the operation is an Increment.
+func (op Op) IsIncrement() bool { return op.t == tIncrement }
+
+// Delta returns the number added by an Increment.
+func (op Op) Delta() int64 { return op.delta }
+
 // IsSerializable returns true if the serializable field is true.
 func (op Op) IsSerializable() bool { return op.serializable }
 
@@ -136,6 +167,17 @@ func (op Op) MinCreateRev() int64 { return op.minCreateRev }
 // MaxCreateRev returns the operation's maximum create revision.
 func (op Op) MaxCreateRev() int64 { return op.maxCreateRev }
 
+// Limit returns the operation's maximum number of keys to return, if any.
+func (op Op) Limit() int64 { return op.limit }
+
+// Sort returns the operation's sort option, if any.
+func (op Op) Sort() (SortOption, bool) {
+	if op.sort == nil {
+		return SortOption{}, false
+	}
+	return *op.sort, true
+}
+
 // WithRangeBytes sets the byte slice for the Op's range end.
 func (op *Op) WithRangeBytes(end []byte) { op.end = end }
 
@@ -161,6 +203,8 @@ func (op Op) toRangeRequest() *pb.RangeRequest {
 		MaxModRevision:    op.maxModRev,
 		MinCreateRevision: op.minCreateRev,
 		MaxCreateRevision: op.maxCreateRev,
+		CompactionBarrier: op.compactionBarrier,
+		MinRevisionWait:   op.minRevWait,
 	}
 	if op.sort != nil {
 		r.SortOrder = pb.RangeRequest_SortOrder(op.sort.Order)
@@ -190,13 +234,16 @@ func (op Op) toRequestOp() *pb.RequestOp {
 	case tRange:
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: op.toRangeRequest()}}
 	case tPut:
-		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease}
+		r := &pb.PutRequest{Key: op.key, Value: op.val, Lease: int64(op.leaseID), PrevKv: op.prevKV, IgnoreValue: op.ignoreValue, IgnoreLease: op.ignoreLease, Annotations: op.annotations}
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: r}}
 	case tDeleteRange:
-		r := &pb.DeleteRangeRequest{Key: op.key, RangeEnd: op.end, PrevKv: op.prevKV}
+		r := &pb.DeleteRangeRequest{Key: op.key, RangeEnd: op.end, PrevKv: op.prevKV, Paced: op.paced}
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: r}}
 	case tTxn:
 		return &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: op.toTxnRequest()}}
+	case tIncrement:
+		r := &pb.IncrementRequest{Key: op.key, Delta: op.delta, Lease: int64(op.leaseID)}
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestIncrement{RequestIncrement: r}}
 	default:
 		panic("Unknown Op")
 	}
@@ -253,16 +300,24 @@ func OpDelete(key string, opts ...OpOption) Op {
 		panic("unexpected sort in delete")
 	case ret.serializable:
 		panic("unexpected serializable in delete")
+	case ret.hedgeDelay != 0:
+		panic("unexpected hedging in delete")
 	case ret.countOnly:
 		panic("unexpected countOnly in delete")
 	case ret.minModRev != 0, ret.maxModRev != 0:
 		panic("unexpected mod revision filter in delete")
 	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
 		panic("unexpected create revision filter in delete")
-	case ret.filterDelete, ret.filterPut:
+	case ret.compactionBarrier:
+		panic("unexpected compaction barrier in delete")
+	case ret.minRevWait != 0:
+		panic("unexpected minimum revision wait in delete")
+	case ret.filterDelete, ret.filterPut, ret.valuePrefix != "", ret.valueRegex != "":
 		panic("unexpected filter in delete")
 	case ret.createdNotify:
 		panic("unexpected createdNotify in delete")
+	case ret.annotations != nil:
+		panic("unexpected annotations in delete")
 	}
 	return ret
 }
@@ -282,16 +337,69 @@ func OpPut(key, val string, opts ...OpOption) Op {
 		panic("unexpected sort in put")
 	case ret.serializable:
 		panic("unexpected serializable in put")
+	case ret.hedgeDelay != 0:
+		panic("unexpected hedging in put")
 	case ret.countOnly:
 		panic("unexpected countOnly in put")
 	case ret.minModRev != 0, ret.maxModRev != 0:
 		panic("unexpected mod revision filter in put")
 	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
 		panic("unexpected create revision filter in put")
-	case ret.filterDelete, ret.filterPut:
+	case ret.compactionBarrier:
+		panic("unexpected compaction barrier in put")
+	case ret.minRevWait != 0:
+		panic("unexpected minimum revision wait in put")
+	case ret.filterDelete, ret.filterPut, ret.valuePrefix != "", ret.valueRegex != "":
 		panic("unexpected filter in put")
 	case ret.createdNotify:
 		panic("unexpected createdNotify in put")
+	case ret.paced:
+		panic("unexpected paced in put")
+	}
+	return ret
+}
+
+// OpIncrement returns "increment" operation adding the delta to the decimal
+// integer of the key, 0 if the key does not exist, atomically. The only option
+// accepted is WithLease, attaching the key to the lease. The increment fails
+// if the value of the key is not an integer, and does not change the key if it
+// overflows.
+func OpIncrement(key string, delta int64, opts ...OpOption) Op {
+	ret := Op{t: tIncrement, key: []byte(key), delta: delta}
+	ret.applyOpts(opts)
+	switch {
+	case ret.end != nil:
+		panic("unexpected range in increment")
+	case ret.limit != 0:
+		panic("unexpected limit in increment")
+	case ret.rev != 0:
+		panic("unexpected revision in increment")
+	case ret.sort != nil:
+		panic("unexpected sort in increment")
+	case ret.serializable:
+		panic("unexpected serializable in increment")
+	case ret.hedgeDelay != 0:
+		panic("unexpected hedging in increment")
+	case ret.countOnly:
+		panic("unexpected countOnly in increment")
+	case ret.minModRev != 0, ret.maxModRev != 0:
+		panic("unexpected mod revision filter in increment")
+	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
+		panic("unexpected create revision filter in increment")
+	case ret.compactionBarrier:
+		panic("unexpected compaction barrier in increment")
+	case ret.minRevWait != 0:
+		panic("unexpected minimum revision wait in increment")
+	case ret.filterDelete, ret.filterPut, ret.valuePrefix != "", ret.valueRegex != "":
+		panic("unexpected filter in increment")
+	case ret.createdNotify:
+		panic("unexpected createdNotify in increment")
+	case ret.prevKV:
+		panic("unexpected prevKV in increment")
+	case ret.ignoreValue, ret.ignoreLease:
+		panic("unexpected ignore flag in increment")
+	case ret.annotations != nil:
+		panic("unexpected annotations in increment")
 	}
 	return ret
 }
@@ -313,12 +421,18 @@ func opWatch(key string, opts ...OpOption) Op {
 		panic("unexpected sort in watch")
 	case ret.serializable:
 		panic("unexpected serializable in watch")
+	case ret.hedgeDelay != 0:
+		panic("unexpected hedging in watch")
 	case ret.countOnly:
 		panic("unexpected countOnly in watch")
 	case ret.minModRev != 0, ret.maxModRev != 0:
 		panic("unexpected mod revision filter in watch")
 	case ret.minCreateRev != 0, ret.maxCreateRev != 0:
 		panic("unexpected create revision filter in watch")
+	case ret.compactionBarrier:
+		panic("unexpected compaction barrier in watch")
+	case ret.minRevWait != 0:
+		panic("unexpected minimum revision wait in watch")
 	}
 	return ret
 }
@@ -425,6 +539,35 @@ func WithSerializable() OpOption {
 	return func(op *Op) { op.serializable = true }
 }
 
+// WithHedging makes a serializable 'Get' request send a duplicate of itself
+// if no response is received within the delay, returning the first response
+// of either. The balancer sends the duplicate to the next endpoint, so that a
+// single slow member does not delay the request for long. Linearizable
+// requests, served through the leader, are not hedged.
+func WithHedging(delay time.Duration) OpOption {
+	return func(op *Op) { op.hedgeDelay = delay }
+}
+
+// WithCompactionBarrier makes the 'Get' request register a compaction barrier
+// at its revision on the member serving it, holding back the compaction above
+// the revision for the paginated 'Get' requests at the revision not to fail
+// with ErrCompacted, until the barrier expires after the maximum duration or
+// number of revisions configured on the member. The barrier only holds back
+// the compaction of the member serving the request, so the paginated requests
+// should be served by the same member, e.g. through a single endpoint.
+func WithCompactionBarrier() OpOption {
+	return func(op *Op) { op.compactionBarrier = true }
+}
+
+// WithMinRevisionWait makes the member serving the 'Get' request wait until it
+// applies the revision rev before serving it, e.g. for a serializable 'Get'
+// request to a lagging member to return the writes up to rev. The member waits
+// for at most its request timeout, failing the request with
+// rpctypes.ErrFutureRev if it does not apply rev in time.
+func WithMinRevisionWait(rev int64) OpOption {
+	return func(op *Op) { op.minRevWait = rev }
+}
+
 // WithKeysOnly makes the 'Get' request return only the keys and the corresponding
 // values will be omitted.
 func WithKeysOnly() OpOption {
@@ -463,6 +606,12 @@ func WithLastKey() []OpOption { return withTop(SortByKey, SortDescend) }
 // WithFirstRev gets the key with the oldest modification revision in the request range.
 func WithFirstRev() []OpOption { return withTop(SortByModRevision, SortAscend) }
 
+// WithDescendKey returns the keys of a 'Get' request in lexically descending
+// order. The server walks its index backwards, so combined with 'WithLimit'
+// only the requested number of keys is fetched, e.g. the latest items under
+// a prefix with sequential keys.
+func WithDescendKey() OpOption { return WithSort(SortByKey, SortDescend) }
+
 // WithLastRev gets the key with the latest modification revision in the request range.
 func WithLastRev() []OpOption { return withTop(SortByModRevision, SortDescend) }
 
@@ -480,6 +629,16 @@ func WithProgressNotify() OpOption {
 	}
 }
 
+// WithProgressNotifyInterval makes watch server send periodic progress
+// updates every interval when there is no incoming events, instead of the
+// progress notify interval of the server. Supported since etcd 3.6.
+func WithProgressNotifyInterval(interval time.Duration) OpOption {
+	return func(op *Op) {
+		op.progressNotify = true
+		op.progressNotifyInterval = interval
+	}
+}
+
 // WithCreatedNotify makes watch server sends the created event.
 func WithCreatedNotify() OpOption {
 	return func(op *Op) {
@@ -497,6 +656,20 @@ func WithFilterDelete() OpOption {
 	return func(op *Op) { op.filterDelete = true }
 }
 
+// WithValuePrefix discards the PUT events whose value does not have the prefix
+// from the watcher. DELETE events are not filtered by value.
+func WithValuePrefix(prefix string) OpOption {
+	return func(op *Op) { op.valuePrefix = prefix }
+}
+
+// WithValueRegex discards the PUT events whose value does not match the RE2
+// regular expression from the watcher. DELETE events are not filtered by value.
+// The watch fails with rpctypes.ErrInvalidValueRegex if the regular expression
+// is invalid.
+func WithValueRegex(re string) OpOption {
+	return func(op *Op) { op.valueRegex = re }
+}
+
 // WithPrevKV gets the previous key-value pair before the event happens. If the previous KV is already compacted,
 // nothing will be returned.
 func WithPrevKV() OpOption {
@@ -534,6 +707,24 @@ func WithIgnoreLease() OpOption {
 	}
 }
 
+// WithAnnotations attaches the given user-defined metadata, such as owner,
+// content-type or checksum, to the key. The annotations are returned with
+// the key-value pair but are not part of its value. A put without annotations
+// removes the ones of the previous put, unless WithIgnoreValue is also given.
+// Supported since etcd 3.6.
+func WithAnnotations(annotations map[string]string) OpOption {
+	return func(op *Op) { op.annotations = annotations }
+}
+
+// WithPaced makes the server delete the range in batches of at most
+// "--experimental-max-delete-batch-size" keys, each its own transaction, not
+// to spike the backend commit latency deleting a large range. The paced delete
+// is not atomic: if it fails, the range may be partially deleted. It is
+// ignored for the deletes of a transaction. Supported since etcd 3.6.
+func WithPaced() OpOption {
+	return func(op *Op) { op.paced = true }
+}
+
 // LeaseOp represents an Operation that lease can execute.
 type LeaseOp struct {
 	id LeaseID
@@ -37,6 +37,46 @@ func TestOpWithSort(t *testing.T) {
 	}
 }
 
+func TestOpWithDescendKey(t *testing.T) {
+	opReq := OpGet("foo", WithPrefix(), WithDescendKey(), WithLimit(10)).toRequestOp().Request
+	q, ok := opReq.(*pb.RequestOp_RequestRange)
+	if !ok {
+		t.Fatalf("expected range request, got %v", reflect.TypeOf(opReq))
+	}
+	req := q.RequestRange
+	wreq := &pb.RangeRequest{Key: []byte("foo"), RangeEnd: []byte("fop"), SortOrder: pb.RangeRequest_DESCEND, SortTarget: pb.RangeRequest_KEY, Limit: 10}
+	if !reflect.DeepEqual(req, wreq) {
+		t.Fatalf("expected %+v, got %+v", wreq, req)
+	}
+}
+
+func TestOpWithAnnotations(t *testing.T) {
+	annotations := map[string]string{"owner": "app"}
+	opReq := OpPut("foo", "bar", WithAnnotations(annotations)).toRequestOp().Request
+	q, ok := opReq.(*pb.RequestOp_RequestPut)
+	if !ok {
+		t.Fatalf("expected put request, got %v", reflect.TypeOf(opReq))
+	}
+	req := q.RequestPut
+	wreq := &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar"), Annotations: annotations}
+	if !reflect.DeepEqual(req, wreq) {
+		t.Fatalf("expected %+v, got %+v", wreq, req)
+	}
+}
+
+func TestOpWithPaced(t *testing.T) {
+	opReq := OpDelete("foo", WithPrefix(), WithPaced()).toRequestOp().Request
+	q, ok := opReq.(*pb.RequestOp_RequestDeleteRange)
+	if !ok {
+		t.Fatalf("expected delete request, got %v", reflect.TypeOf(opReq))
+	}
+	req := q.RequestDeleteRange
+	wreq := &pb.DeleteRangeRequest{Key: []byte("foo"), RangeEnd: []byte("fop"), Paced: true}
+	if !reflect.DeepEqual(req, wreq) {
+		t.Fatalf("expected %+v, got %+v", wreq, req)
+	}
+}
+
 func TestIsSortOptionValid(t *testing.T) {
 	rangeReqs := []struct {
 		sortOrder     pb.RangeRequest_SortOrder
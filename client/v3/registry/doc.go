@@ -0,0 +1,43 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registry implements a service registry on etcd: the instances of a
+// service register themselves under a lease kept alive for as long as they
+// run, with their address, metadata and health state, and the clients
+// discover the instances by watching the registry, or through the gRPC
+// resolver of the registry.
+//
+// An instance is stored at "<prefix>/<service>/<instance ID>". Its key is
+// deleted once the instance deregisters, or once its lease expires if it
+// stops heartbeating. A registration whose lease expires, e.g. after a
+// network partition, registers the instance again under a new lease.
+//
+// Register an instance of a service, then deregister it on shutdown:
+//
+//	reg := registry.New(cli, "/services")
+//	r, err := reg.Register(ctx, "foo", registry.Instance{ID: "foo-1", Addr: "10.0.0.1:8080"}, registry.WithTTL(10))
+//	if err != nil {
+//		// handle error!
+//	}
+//	defer r.Close(context.Background())
+//
+// Take the instance out of rotation without deregistering it:
+//
+//	err = r.SetHealth(ctx, registry.NotServing)
+//
+// Dial the service through the gRPC resolver, balancing over the instances
+// serving:
+//
+//	conn, err := grpc.Dial("etcd-registry:///foo", grpc.WithResolvers(reg.NewResolverBuilder()))
+package registry
@@ -0,0 +1,371 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const (
+	defaultTTL = 10
+
+	// retryInterval is the interval between the attempts to register again an
+	// instance whose lease expired.
+	retryInterval = time.Second
+)
+
+var (
+	ErrEmptyService    = errors.New("registry: empty service name")
+	ErrEmptyInstanceID = errors.New("registry: empty instance ID")
+	ErrClosed          = errors.New("registry: registration closed")
+)
+
+// Health is the health state of an instance.
+type Health string
+
+const (
+	// Serving is the health state of an instance serving requests, the
+	// default one.
+	Serving Health = "SERVING"
+	// NotServing is the health state of an instance registered but not
+	// serving requests, e.g. while starting or draining.
+	NotServing Health = "NOT_SERVING"
+)
+
+// Instance is an instance of a service registered in the registry.
+type Instance struct {
+	// ID identifies the instance among the instances of the service.
+	ID string `json:"id"`
+	// Addr is the address the instance serves on.
+	Addr string `json:"addr"`
+	// Metadata is the information on the instance, e.g. its version or zone.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Health is the health state of the instance, Serving if empty.
+	Health Health `json:"health"`
+}
+
+// Registry is a service registry stored under a key prefix.
+type Registry struct {
+	c      *v3.Client
+	prefix string
+}
+
+// New returns the service registry stored under prefix.
+func New(c *v3.Client, prefix string) *Registry {
+	return &Registry{c: c, prefix: strings.TrimSuffix(prefix, "/")}
+}
+
+// serviceKey returns the key prefix of the instances of the service.
+func (r *Registry) serviceKey(service string) string {
+	return r.prefix + "/" + service + "/"
+}
+
+type registerOptions struct {
+	ttl int
+}
+
+// RegisterOption configures Register.
+type RegisterOption func(*registerOptions)
+
+// WithTTL configures the TTL in seconds of the lease of the registration,
+// the time a stopped instance stays registered. If TTL is <= 0, the default
+// 10 seconds TTL will be used.
+func WithTTL(ttl int) RegisterOption {
+	return func(o *registerOptions) {
+		if ttl > 0 {
+			o.ttl = ttl
+		}
+	}
+}
+
+// Registration is the registration of an instance, kept alive until closed.
+type Registration struct {
+	r   *Registry
+	key string
+	ttl int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	donec  chan struct{}
+
+	mu      sync.Mutex
+	inst    Instance
+	session *concurrency.Session
+	closed  bool
+}
+
+// Register registers the instance of the service under a lease kept alive
+// until the registration is closed. The instance is registered again under a
+// new lease if its lease expires, e.g. after the client lost the connection to
+// the cluster for longer than the TTL.
+func (r *Registry) Register(ctx context.Context, service string, inst Instance, opts ...RegisterOption) (*Registration, error) {
+	if service == "" {
+		return nil, ErrEmptyService
+	}
+	if inst.ID == "" {
+		return nil, ErrEmptyInstanceID
+	}
+	if inst.Health == "" {
+		inst.Health = Serving
+	}
+	ops := &registerOptions{ttl: defaultTTL}
+	for _, opt := range opts {
+		opt(ops)
+	}
+
+	reg := &Registration{
+		r:     r,
+		key:   r.serviceKey(service) + inst.ID,
+		ttl:   ops.ttl,
+		donec: make(chan struct{}),
+		inst:  inst,
+	}
+	reg.ctx, reg.cancel = context.WithCancel(r.c.Ctx())
+	if err := reg.register(ctx); err != nil {
+		reg.cancel()
+		return nil, err
+	}
+	go reg.keepRegistered()
+	return reg, nil
+}
+
+// register puts the instance under a new lease.
+func (reg *Registration) register(ctx context.Context) error {
+	resp, err := reg.r.c.Grant(ctx, int64(reg.ttl))
+	if err != nil {
+		return err
+	}
+	s, err := concurrency.NewSession(reg.r.c,
+		concurrency.WithLease(resp.ID),
+		concurrency.WithTTL(reg.ttl),
+		concurrency.WithContext(reg.ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.closed {
+		s.Close()
+		return ErrClosed
+	}
+	reg.session = s
+	return reg.put(ctx)
+}
+
+// put puts the instance under the lease of the session, with reg.mu held.
+func (reg *Registration) put(ctx context.Context) error {
+	b, err := json.Marshal(reg.inst)
+	if err != nil {
+		return err
+	}
+	_, err = reg.r.c.Put(ctx, reg.key, string(b), v3.WithLease(reg.session.Lease()))
+	return err
+}
+
+// keepRegistered registers the instance again whenever its lease expires,
+// until the registration is closed.
+func (reg *Registration) keepRegistered() {
+	defer close(reg.donec)
+	for {
+		reg.mu.Lock()
+		donec := reg.session.Done()
+		reg.mu.Unlock()
+
+		select {
+		case <-donec:
+		case <-reg.ctx.Done():
+			return
+		}
+		for {
+			if err := reg.register(reg.ctx); err == nil {
+				break
+			}
+			select {
+			case <-time.After(retryInterval):
+			case <-reg.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Key returns the key the instance is registered at.
+func (reg *Registration) Key() string { return reg.key }
+
+// Lease returns the current lease of the registration.
+func (reg *Registration) Lease() v3.LeaseID {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.session.Lease()
+}
+
+// SetHealth updates the health state of the instance.
+func (reg *Registration) SetHealth(ctx context.Context, h Health) error {
+	return reg.update(ctx, func(inst *Instance) { inst.Health = h })
+}
+
+// SetMetadata replaces the metadata of the instance.
+func (reg *Registration) SetMetadata(ctx context.Context, md map[string]string) error {
+	return reg.update(ctx, func(inst *Instance) { inst.Metadata = md })
+}
+
+func (reg *Registration) update(ctx context.Context, f func(*Instance)) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.closed {
+		return ErrClosed
+	}
+	f(&reg.inst)
+	return reg.put(ctx)
+}
+
+// Close deregisters the instance, deleting its key and revoking its lease.
+func (reg *Registration) Close(ctx context.Context) error {
+	reg.mu.Lock()
+	if reg.closed {
+		reg.mu.Unlock()
+		return ErrClosed
+	}
+	reg.closed = true
+	reg.mu.Unlock()
+
+	reg.cancel()
+	<-reg.donec
+
+	_, err := reg.r.c.Delete(ctx, reg.key)
+	if _, rerr := reg.r.c.Revoke(ctx, reg.session.Lease()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// List returns the instances of the service sorted by ID, and the revision of
+// the registry they were read at.
+func (r *Registry) List(ctx context.Context, service string) ([]Instance, int64, error) {
+	resp, err := r.c.Get(ctx, r.serviceKey(service), v3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+	insts := make(map[string]Instance, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if inst, ok := decodeInstance(kv.Value); ok {
+			insts[string(kv.Key)] = inst
+		}
+	}
+	return sortedInstances(insts), resp.Header.Revision, nil
+}
+
+// Watch returns a channel receiving all the instances of the service sorted by
+// ID, once at first, then whenever they change, until ctx is canceled.
+func (r *Registry) Watch(ctx context.Context, service string) <-chan []Instance {
+	ch := make(chan []Instance, 1)
+	go func() {
+		defer close(ch)
+		for ctx.Err() == nil {
+			r.watch(ctx, service, ch)
+			select {
+			case <-time.After(retryInterval):
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ch
+}
+
+// watch sends the instances of the service to ch until the watch fails, e.g.
+// as its revision is compacted, or ctx is canceled.
+func (r *Registry) watch(ctx context.Context, service string, ch chan []Instance) {
+	key := r.serviceKey(service)
+	resp, err := r.c.Get(ctx, key, v3.WithPrefix())
+	if err != nil {
+		return
+	}
+	insts := make(map[string]Instance, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if inst, ok := decodeInstance(kv.Value); ok {
+			insts[string(kv.Key)] = inst
+		}
+	}
+	if !sendInstances(ctx, ch, insts) {
+		return
+	}
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wch := r.c.Watch(wctx, key, v3.WithPrefix(), v3.WithRev(resp.Header.Revision+1))
+	for wresp := range wch {
+		if wresp.Err() != nil {
+			return
+		}
+		for _, ev := range wresp.Events {
+			inst, ok := decodeInstance(ev.Kv.Value)
+			if ev.Type == v3.EventTypeDelete || !ok {
+				delete(insts, string(ev.Kv.Key))
+			} else {
+				insts[string(ev.Kv.Key)] = inst
+			}
+		}
+		if len(wresp.Events) > 0 && !sendInstances(ctx, ch, insts) {
+			return
+		}
+	}
+}
+
+// sendInstances sends the instances to ch, replacing the instances not yet
+// received, returning false if ctx is canceled.
+func sendInstances(ctx context.Context, ch chan []Instance, insts map[string]Instance) bool {
+	sorted := sortedInstances(insts)
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- sorted:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func decodeInstance(b []byte) (Instance, bool) {
+	var inst Instance
+	if err := json.Unmarshal(b, &inst); err != nil || inst.ID == "" {
+		return Instance{}, false
+	}
+	if inst.Health == "" {
+		inst.Health = Serving
+	}
+	return inst, true
+}
+
+func sortedInstances(insts map[string]Instance) []Instance {
+	sorted := make([]Instance, 0, len(insts))
+	for _, inst := range insts {
+		sorted = append(sorted, inst)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/attributes"
+	gresolver "google.golang.org/grpc/resolver"
+)
+
+// Scheme is the scheme of the targets of the gRPC resolver of the registry,
+// "etcd-registry:///<service>".
+const Scheme = "etcd-registry"
+
+// instanceKey is the key of the instance in the attributes of its address.
+type instanceKey struct{}
+
+// InstanceFromAddress returns the instance the address resolved by the gRPC
+// resolver of the registry belongs to, e.g. for a balancer to read its
+// metadata.
+func InstanceFromAddress(addr gresolver.Address) (Instance, bool) {
+	inst, ok := addr.BalancerAttributes.Value(instanceKey{}).(*Instance)
+	if !ok {
+		return Instance{}, false
+	}
+	return *inst, true
+}
+
+type builder struct {
+	r *Registry
+}
+
+// NewResolverBuilder returns a gRPC resolver builder resolving the targets
+// "etcd-registry:///<service>" to the addresses of the instances of the
+// service serving.
+func (r *Registry) NewResolverBuilder() gresolver.Builder {
+	return builder{r: r}
+}
+
+func (b builder) Build(target gresolver.Target, cc gresolver.ClientConn, opts gresolver.BuildOptions) (gresolver.Resolver, error) {
+	res := &resolver{cc: cc}
+	var ctx context.Context
+	ctx, res.cancel = context.WithCancel(b.r.c.Ctx())
+	ch := b.r.Watch(ctx, target.Endpoint)
+
+	res.wg.Add(1)
+	go res.watch(ch)
+	return res, nil
+}
+
+func (b builder) Scheme() string {
+	return Scheme
+}
+
+type resolver struct {
+	cc     gresolver.ClientConn
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (r *resolver) watch(ch <-chan []Instance) {
+	defer r.wg.Done()
+	for insts := range ch {
+		var addrs []gresolver.Address
+		for i := range insts {
+			if insts[i].Health != Serving {
+				continue
+			}
+			addrs = append(addrs, gresolver.Address{
+				Addr:               insts[i].Addr,
+				BalancerAttributes: attributes.New(instanceKey{}, &insts[i]),
+			})
+		}
+		r.cc.UpdateState(gresolver.State{Addresses: addrs})
+	}
+}
+
+// ResolveNow is a no-op here, the instances being watched.
+func (r *resolver) ResolveNow(gresolver.ResolveNowOptions) {}
+
+func (r *resolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
@@ -183,6 +183,10 @@ func (rcc *retryClusterClient) MemberPromote(ctx context.Context, in *pb.MemberP
 	return rcc.cc.MemberPromote(ctx, in, opts...)
 }
 
+func (rcc *retryClusterClient) MemberDemote(ctx context.Context, in *pb.MemberDemoteRequest, opts ...grpc.CallOption) (resp *pb.MemberDemoteResponse, err error) {
+	return rcc.cc.MemberDemote(ctx, in, opts...)
+}
+
 type retryMaintenanceClient struct {
 	mc pb.MaintenanceClient
 }
@@ -210,10 +214,42 @@ func (rmc *retryMaintenanceClient) HashKV(ctx context.Context, in *pb.HashKVRequ
 	return rmc.mc.HashKV(ctx, in, append(opts, withRetryPolicy(repeatable))...)
 }
 
+func (rmc *retryMaintenanceClient) DefragmentEstimate(ctx context.Context, in *pb.DefragmentEstimateRequest, opts ...grpc.CallOption) (resp *pb.DefragmentEstimateResponse, err error) {
+	return rmc.mc.DefragmentEstimate(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) RangeEstimate(ctx context.Context, in *pb.RangeEstimateRequest, opts ...grpc.CallOption) (resp *pb.RangeEstimateResponse, err error) {
+	return rmc.mc.RangeEstimate(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) Events(ctx context.Context, in *pb.EventsRequest, opts ...grpc.CallOption) (resp *pb.EventsResponse, err error) {
+	return rmc.mc.Events(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) FeatureGates(ctx context.Context, in *pb.FeatureGatesRequest, opts ...grpc.CallOption) (resp *pb.FeatureGatesResponse, err error) {
+	return rmc.mc.FeatureGates(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) PrefixStats(ctx context.Context, in *pb.PrefixStatsRequest, opts ...grpc.CallOption) (resp *pb.PrefixStatsResponse, err error) {
+	return rmc.mc.PrefixStats(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) LogLevel(ctx context.Context, in *pb.LogLevelRequest, opts ...grpc.CallOption) (resp *pb.LogLevelResponse, err error) {
+	return rmc.mc.LogLevel(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
+func (rmc *retryMaintenanceClient) LogRange(ctx context.Context, in *pb.LogRangeRequest, opts ...grpc.CallOption) (resp *pb.LogRangeResponse, err error) {
+	return rmc.mc.LogRange(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
 func (rmc *retryMaintenanceClient) Snapshot(ctx context.Context, in *pb.SnapshotRequest, opts ...grpc.CallOption) (stream pb.Maintenance_SnapshotClient, err error) {
 	return rmc.mc.Snapshot(ctx, in, append(opts, withRetryPolicy(repeatable))...)
 }
 
+func (rmc *retryMaintenanceClient) SnapshotDelta(ctx context.Context, in *pb.SnapshotDeltaRequest, opts ...grpc.CallOption) (stream pb.Maintenance_SnapshotDeltaClient, err error) {
+	return rmc.mc.SnapshotDelta(ctx, in, append(opts, withRetryPolicy(repeatable))...)
+}
+
 func (rmc *retryMaintenanceClient) MoveLeader(ctx context.Context, in *pb.MoveLeaderRequest, opts ...grpc.CallOption) (resp *pb.MoveLeaderResponse, err error) {
 	return rmc.mc.MoveLeader(ctx, in, append(opts, withRetryPolicy(repeatable))...)
 }
@@ -77,6 +77,11 @@ func (c *Client) unaryClientInterceptor(optFuncs ...retryOption) grpc.UnaryClien
 				continue
 			}
 			if c.shouldRefreshToken(lastErr, callOpts) {
+				if c.authTokenBundle == nil {
+					// the token is passed in the metadata of the request, e.g. an
+					// OIDC token, and cannot be refreshed without username and password.
+					return lastErr
+				}
 				// clear auth token before refreshing it.
 				// call c.Auth.Authenticate with an invalid token will always fail the auth check on the server-side,
 				// if the server has not apply the patch of pr #12165 (https://github.com/etcd-io/etcd/pull/12165)
@@ -262,6 +267,11 @@ func (s *serverStreamingRetryingStream) receiveMsgAndIndicateRetry(m interface{}
 		return true, err
 	}
 	if s.client.shouldRefreshToken(err, s.callOpts) {
+		if s.client.authTokenBundle == nil {
+			// the token is passed in the metadata of the request, e.g. an
+			// OIDC token, and cannot be refreshed without username and password.
+			return false, err
+		}
 		// clear auth token to avoid failure when call getToken
 		s.client.authTokenBundle.UpdateAuthToken("")
 
@@ -0,0 +1,222 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureVersion is the version of the Blob service REST API of the requests.
+const azureVersion = "2020-10-02"
+
+// azure is the storage of a container of Azure Blob Storage, the requests
+// being authorized by a shared key or a SAS token.
+type azure struct {
+	container string
+	opts      Options
+	// endpoint is the URL of the container, the blob names being appended to
+	// its path.
+	endpoint *url.URL
+	account  string
+	// key is the decoded shared key of the account, nil with a SAS token.
+	key []byte
+	sas url.Values
+}
+
+func openAzure(container string, opts Options) (Storage, error) {
+	s := &azure{container: container, opts: opts, account: os.Getenv("AZURE_STORAGE_ACCOUNT")}
+	if s.account == "" {
+		return nil, fmt.Errorf("storage: AZURE_STORAGE_ACCOUNT must be set for Azure")
+	}
+	if key := os.Getenv("AZURE_STORAGE_KEY"); key != "" {
+		var err error
+		if s.key, err = base64.StdEncoding.DecodeString(key); err != nil {
+			return nil, fmt.Errorf("storage: invalid AZURE_STORAGE_KEY (%v)", err)
+		}
+	} else if sas := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); sas != "" {
+		var err error
+		if s.sas, err = url.ParseQuery(strings.TrimPrefix(sas, "?")); err != nil {
+			return nil, fmt.Errorf("storage: invalid AZURE_STORAGE_SAS_TOKEN (%v)", err)
+		}
+	} else {
+		return nil, fmt.Errorf("storage: AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN must be set for Azure")
+	}
+	if opts.ServerSideEncryption != "" {
+		return nil, fmt.Errorf("storage: the server-side encryption of Azure is set by its encryption scope only")
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", s.account)
+	}
+	var err error
+	if s.endpoint, err = url.Parse(strings.TrimSuffix(endpoint, "/") + "/" + container + "/"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Put writes the blob as a block blob, the parts being its blocks.
+func (s *azure) Put(ctx context.Context, name string, r io.Reader) error {
+	var ids []string
+	err := forEachPart(r, s.opts.partSize(), func(part []byte, last bool) error {
+		if last && len(ids) == 0 {
+			// the blob fits in a block
+			h := http.Header{"X-Ms-Blob-Type": {"BlockBlob"}}
+			return s.put(ctx, "Put Blob", name, nil, h, part)
+		}
+		// the IDs of the blocks of a blob all have the same length
+		id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d", len(ids))))
+		q := url.Values{"comp": {"block"}, "blockid": {id}}
+		if err := s.put(ctx, "Put Block", name, q, nil, part); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		if !last {
+			return nil
+		}
+		var list struct {
+			XMLName xml.Name `xml:"BlockList"`
+			Latest  []string `xml:"Latest"`
+		}
+		list.Latest = ids
+		body, err := xml.Marshal(list)
+		if err != nil {
+			return err
+		}
+		return s.put(ctx, "Put Block List", name, url.Values{"comp": {"blocklist"}}, nil, append([]byte(xml.Header), body...))
+	})
+	// the uncommitted blocks are discarded after a week
+	return err
+}
+
+func (s *azure) put(ctx context.Context, op, name string, q url.Values, h http.Header, body []byte) error {
+	if h == nil {
+		h = make(http.Header)
+	}
+	if s.opts.EncryptionKey != "" {
+		h.Set("X-Ms-Encryption-Scope", s.opts.EncryptionKey)
+	}
+	sum := md5.Sum(body)
+	h.Set("Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := s.do(ctx, http.MethodPut, name, q, h, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return httpError("Azure "+op, resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *azure) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, name, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError("Azure Get Blob", resp)
+	}
+	return resp.Body, nil
+}
+
+// do sends the authorized request of the blob name.
+func (s *azure) do(ctx context.Context, method, name string, q url.Values, h http.Header, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path += name
+	all := make(url.Values)
+	for k, vs := range q {
+		all[k] = vs
+	}
+	for k, vs := range s.sas {
+		all[k] = vs
+	}
+	u.RawQuery = all.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range h {
+		req.Header[k] = vs
+	}
+	req.Header.Set("X-Ms-Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureVersion)
+	if s.key != nil {
+		s.sign(req, q)
+	}
+	return s.opts.client().Do(req)
+}
+
+// sign authorizes req, of the query q, with the shared key of the account.
+func (s *azure) sign(req *http.Request, q url.Values) {
+	var headers []string
+	for k, vs := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") {
+			headers = append(headers, lk+":"+strings.TrimSpace(strings.Join(vs, ",")))
+		}
+	}
+	sort.Strings(headers)
+
+	resource := "/" + s.account + req.URL.EscapedPath()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(vs, ",")
+	}
+
+	var length string
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-Md5"),
+		req.Header.Get("Content-Type"),
+		"", // the date is in x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}, "\n") + "\n" + strings.Join(headers, "\n") + "\n" + resource
+
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(stringToSign))
+	req.Header.Set("Authorization", "SharedKey "+s.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
@@ -0,0 +1,141 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	gcsEndpoint = "https://storage.googleapis.com"
+	// gcsChunkAlign is the size the chunks of a resumable upload but the last
+	// are a multiple of.
+	gcsChunkAlign = 256 * 1024
+	// gcsResumeIncomplete is the status of the responses to the chunks of a
+	// resumable upload but the last.
+	gcsResumeIncomplete = 308
+)
+
+// gcs is the storage of a bucket of Google Cloud Storage, through its JSON
+// API authorized by an OAuth 2.0 access token.
+type gcs struct {
+	bucket   string
+	opts     Options
+	endpoint string
+	token    string
+}
+
+func openGCS(bucket string, opts Options) (Storage, error) {
+	s := &gcs{bucket: bucket, opts: opts, endpoint: gcsEndpoint, token: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")}
+	if opts.Endpoint != "" {
+		s.endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	}
+	if s.token == "" {
+		return nil, fmt.Errorf("storage: GOOGLE_OAUTH_ACCESS_TOKEN must be set for GCS, e.g. to the output of 'gcloud auth print-access-token'")
+	}
+	if opts.ServerSideEncryption != "" {
+		return nil, fmt.Errorf("storage: the server-side encryption of GCS is set by its KMS key only")
+	}
+	if opts.partSize()%gcsChunkAlign != 0 {
+		return nil, fmt.Errorf("storage: the part size of GCS must be a multiple of %d", gcsChunkAlign)
+	}
+	return s, nil
+}
+
+// Put writes the object with a resumable upload, the parts being its chunks.
+func (s *gcs) Put(ctx context.Context, name string, r io.Reader) error {
+	q := url.Values{"uploadType": {"resumable"}, "name": {name}}
+	if s.opts.EncryptionKey != "" {
+		q.Set("kmsKeyName", s.opts.EncryptionKey)
+	}
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", s.endpoint, url.PathEscape(s.bucket), q.Encode())
+	resp, err := s.do(ctx, http.MethodPost, u, nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpError("GCS resumable upload", resp)
+	}
+	resp.Body.Close()
+	session := resp.Header.Get("Location")
+	if session == "" {
+		return fmt.Errorf("storage: GCS resumable upload returned no session URI")
+	}
+
+	var offset int64
+	err = forEachPart(r, s.opts.partSize(), func(part []byte, last bool) error {
+		h := make(http.Header)
+		end := offset + int64(len(part))
+		switch {
+		case !last:
+			h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/*", offset, end-1))
+		case len(part) == 0:
+			h.Set("Content-Range", fmt.Sprintf("bytes */%d", end))
+		default:
+			h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, end-1, end))
+		}
+		resp, err := s.do(ctx, http.MethodPut, session, h, part)
+		if err != nil {
+			return err
+		}
+		switch {
+		case last && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated):
+		case !last && resp.StatusCode == gcsResumeIncomplete:
+		default:
+			return httpError("GCS upload of a chunk", resp)
+		}
+		resp.Body.Close()
+		offset = end
+		return nil
+	})
+	if err != nil {
+		// the upload sessions expire after a week otherwise
+		if resp, derr := s.do(ctx, http.MethodDelete, session, nil, nil); derr == nil {
+			resp.Body.Close()
+		}
+	}
+	return err
+}
+
+func (s *gcs) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", s.endpoint, url.PathEscape(s.bucket), url.PathEscape(name))
+	resp, err := s.do(ctx, http.MethodGet, u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError("GCS get object", resp)
+	}
+	return resp.Body, nil
+}
+
+func (s *gcs) do(ctx context.Context, method, u string, h http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range h {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	return s.opts.client().Do(req)
+}
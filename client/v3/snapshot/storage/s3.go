@@ -0,0 +1,352 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3 is the storage of a bucket of Amazon S3 or of an S3 compatible service,
+// the requests being signed with the AWS signature version 4.
+type s3 struct {
+	bucket string
+	opts   Options
+	// endpoint is the URL of the bucket, the object names being appended to
+	// its path.
+	endpoint *url.URL
+	region   string
+
+	accessKey, secretKey, sessionToken string
+}
+
+func openS3(bucket string, opts Options) (Storage, error) {
+	s := &s3{
+		bucket:       bucket,
+		opts:         opts,
+		region:       opts.Region,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_REGION")
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("storage: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for S3")
+	}
+	switch opts.ServerSideEncryption {
+	case "", "AES256", "aws:kms":
+	default:
+		return nil, fmt.Errorf("storage: unknown S3 server-side encryption %q, 'AES256' or 'aws:kms'", opts.ServerSideEncryption)
+	}
+
+	var err error
+	if opts.Endpoint == "" {
+		// virtual-hosted style
+		s.endpoint, err = url.Parse(fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", bucket, s.region))
+	} else {
+		// path style, as supported by the S3 compatible services
+		s.endpoint, err = url.Parse(strings.TrimSuffix(opts.Endpoint, "/") + "/" + bucket + "/")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *s3) Put(ctx context.Context, name string, r io.Reader) error {
+	var (
+		uploadID string
+		etags    []string
+	)
+	err := forEachPart(r, s.opts.partSize(), func(part []byte, last bool) error {
+		if last && uploadID == "" {
+			// the object fits in a part
+			return s.putObject(ctx, name, part)
+		}
+		if uploadID == "" {
+			id, err := s.createMultipartUpload(ctx, name)
+			if err != nil {
+				return err
+			}
+			uploadID = id
+		}
+		etag, err := s.uploadPart(ctx, name, uploadID, len(etags)+1, part)
+		if err != nil {
+			return err
+		}
+		etags = append(etags, etag)
+		if last {
+			return s.completeMultipartUpload(ctx, name, uploadID, etags)
+		}
+		return nil
+	})
+	if err != nil && uploadID != "" {
+		// the parts uploaded are billed until the upload is aborted
+		q := url.Values{"uploadId": {uploadID}}
+		if resp, aerr := s.do(ctx, http.MethodDelete, name, q, nil, nil); aerr == nil {
+			resp.Body.Close()
+		}
+	}
+	return err
+}
+
+func (s *s3) encryptionHeaders(h http.Header) {
+	if s.opts.ServerSideEncryption != "" {
+		h.Set("X-Amz-Server-Side-Encryption", s.opts.ServerSideEncryption)
+	}
+	if s.opts.ServerSideEncryption == "aws:kms" && s.opts.EncryptionKey != "" {
+		h.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", s.opts.EncryptionKey)
+	}
+}
+
+func (s *s3) putObject(ctx context.Context, name string, body []byte) error {
+	h := make(http.Header)
+	s.encryptionHeaders(h)
+	resp, err := s.do(ctx, http.MethodPut, name, nil, h, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpError("S3 PutObject", resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *s3) createMultipartUpload(ctx context.Context, name string) (string, error) {
+	h := make(http.Header)
+	s.encryptionHeaders(h)
+	resp, err := s.do(ctx, http.MethodPost, name, url.Values{"uploads": {""}}, h, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", httpError("S3 CreateMultipartUpload", resp)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.UploadID == "" {
+		return "", fmt.Errorf("storage: S3 CreateMultipartUpload returned no upload ID")
+	}
+	return result.UploadID, nil
+}
+
+func (s *s3) uploadPart(ctx context.Context, name, uploadID string, n int, part []byte) (string, error) {
+	q := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {uploadID}}
+	resp, err := s.do(ctx, http.MethodPut, name, q, nil, part)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", httpError("S3 UploadPart", resp)
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+type s3CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+func (s *s3) completeMultipartUpload(ctx context.Context, name, uploadID string, etags []string) error {
+	var req struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}
+	for i, etag := range etags {
+		req.Parts = append(req.Parts, s3CompletedPart{PartNumber: i + 1, ETag: etag})
+	}
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPost, name, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpError("S3 CompleteMultipartUpload", resp)
+	}
+	defer resp.Body.Close()
+	// the errors occurring after the response started are in its body
+	var result struct {
+		XMLName xml.Name
+		Code    string
+		Message string
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("storage: S3 CompleteMultipartUpload failed: %s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+func (s *s3) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, name, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError("S3 GetObject", resp)
+	}
+	return resp.Body, nil
+}
+
+// do sends the signed request of the object name.
+func (s *s3) do(ctx context.Context, method, name string, q url.Values, h http.Header, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path += name
+	// the path is sent escaped as signed
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = s3Query(q)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range h {
+		req.Header[k] = vs
+	}
+	s.sign(req, body, time.Now())
+	return s.opts.client().Do(req)
+}
+
+// sign signs req, of the payload body, with the AWS signature version 4 at t.
+func (s *s3) sign(req *http.Request, body []byte, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	signed := map[string]string{"host": req.URL.Host}
+	for k, vs := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "range" || lk == "content-md5" || lk == "content-type" {
+			signed[lk] = strings.TrimSpace(strings.Join(vs, ","))
+		}
+	}
+	names := make([]string, 0, len(signed))
+	for k := range signed {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, k := range names {
+		headers.WriteString(k + ":" + signed[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.EscapedPath()),
+		req.URL.RawQuery,
+		headers.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Query returns the canonical query string of q, sorted by key and
+// escaped as the signature version 4 requires.
+func s3Query(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3EscapePath returns the canonical URI of the escaped path p.
+func s3EscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// s3Escape escapes all the bytes of s but the unreserved characters of RFC
+// 3986, and "/" unless escapeSlash.
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,238 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultPartSize is the size of the parts the objects are uploaded in.
+const DefaultPartSize = 16 * 1024 * 1024
+
+// ChecksumSuffix is the suffix of the name of the object holding the
+// hex-encoded sha256 digest of the object uploaded by Upload.
+const ChecksumSuffix = ".sha256"
+
+var (
+	// ErrNotFound is returned by Get for an object that does not exist.
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrChecksumMismatch is returned by Download for an object whose content
+	// does not match the digest saved with it.
+	ErrChecksumMismatch = errors.New("storage: checksum mismatch")
+)
+
+// Storage reads and writes the objects of a bucket of an object storage.
+type Storage interface {
+	// Put writes the content streamed from r to the object name, replacing
+	// it. The content is sent in parts without being buffered entirely, the
+	// object being written only once r returns io.EOF, not if it returns
+	// another error.
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get returns the content of the object name, or ErrNotFound.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// Options configures the access to an object storage. The credentials are
+// read from the environment set by the tools of each provider:
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN for S3,
+// GOOGLE_OAUTH_ACCESS_TOKEN for GCS, and AZURE_STORAGE_ACCOUNT with either
+// AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN for Azure.
+type Options struct {
+	// Endpoint is the URL of the service, e.g. of an S3 compatible service,
+	// the endpoint of the provider if empty.
+	Endpoint string
+	// Region is the region of the S3 bucket, AWS_REGION or "us-east-1" if empty.
+	Region string
+	// PartSize is the size of the parts the objects are uploaded in,
+	// DefaultPartSize if zero.
+	PartSize int
+	// ServerSideEncryption is the server-side encryption of the objects
+	// written to S3, "AES256" or "aws:kms".
+	ServerSideEncryption string
+	// EncryptionKey is the key of the server-side encryption of the objects
+	// written: the KMS key ID of the "aws:kms" encryption of S3, the Cloud
+	// KMS key name of GCS or the encryption scope of Azure.
+	EncryptionKey string
+	// Client is the HTTP client of the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+func (o Options) partSize() int {
+	if o.PartSize > 0 {
+		return o.PartSize
+	}
+	return DefaultPartSize
+}
+
+func (o Options) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return http.DefaultClient
+}
+
+// OpenFunc returns the storage of the bucket.
+type OpenFunc func(bucket string, opts Options) (Storage, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]OpenFunc)
+)
+
+// Register makes the storages opened by open available for the object URLs
+// with the scheme.
+func Register(scheme string, open OpenFunc) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[scheme] = open
+}
+
+func init() {
+	Register("s3", openS3)
+	Register("gs", openGCS)
+	Register("azblob", openAzure)
+}
+
+// IsURL returns whether s is the URL of an object of a registered storage,
+// rather than a file path.
+func IsURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	_, ok := providers[u.Scheme]
+	return ok
+}
+
+// Open returns the storage of the bucket of the object URL rawURL, e.g.
+// "s3://bucket/path/snapshot.db", "gs://bucket/path/snapshot.db" or
+// "azblob://container/path/snapshot.db", and the name of the object.
+func Open(rawURL string, opts Options) (Storage, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	providersMu.RLock()
+	open, ok := providers[u.Scheme]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, "", fmt.Errorf("storage: unknown scheme %q", u.Scheme)
+	}
+	name := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || name == "" {
+		return nil, "", fmt.Errorf("storage: %q is not the URL of an object, <scheme>://<bucket>/<name>", rawURL)
+	}
+	s, err := open(u.Host, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return s, name, nil
+}
+
+// Upload writes the content streamed from r to the object name of s, and its
+// sha256 digest to the object name followed by ChecksumSuffix. It returns the
+// hex-encoded digest.
+func Upload(ctx context.Context, s Storage, name string, r io.Reader) (string, error) {
+	h := sha256.New()
+	if err := s.Put(ctx, name, io.TeeReader(r, h)); err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	if err := s.Put(ctx, name+ChecksumSuffix, strings.NewReader(digest)); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// Download writes the content of the object name of s to w, checking it
+// against the digest written with it by Upload, if any. It returns
+// ErrChecksumMismatch once the content is written if it does not match.
+func Download(ctx context.Context, s Storage, name string, w io.Writer) error {
+	rc, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(w, h), rc); err != nil {
+		return err
+	}
+
+	src, err := s.Get(ctx, name+ChecksumSuffix)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	want, err := io.ReadAll(io.LimitReader(src, 2*sha256.Size+1))
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != string(bytes.TrimSpace(want)) {
+		return fmt.Errorf("%w: object %q has sha256 %s, want %s", ErrChecksumMismatch, name, got, bytes.TrimSpace(want))
+	}
+	return nil
+}
+
+// forEachPart calls fn with the successive parts of up to size bytes read
+// from r, the last one being flagged, possibly empty if r is.
+func forEachPart(r io.Reader, size int, fn func(part []byte, last bool) error) error {
+	br := bufio.NewReader(r)
+	buf := make([]byte, size)
+	for {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := err != nil
+		if !last {
+			// the part is full, the last one if r has nothing left
+			if _, perr := br.Peek(1); perr == io.EOF {
+				last = true
+			} else if perr != nil {
+				return perr
+			}
+		}
+		if err = fn(buf[:n], last); err != nil || last {
+			return err
+		}
+	}
+}
+
+// httpError returns the error of the unexpected response resp of the request
+// of op, closing its body.
+func httpError(op string, resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return fmt.Errorf("storage: %s failed: %s: %s", op, resp.Status, strings.TrimSpace(string(body)))
+}
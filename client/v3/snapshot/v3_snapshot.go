@@ -15,16 +15,23 @@
 package snapshot
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/client/pkg/v3/fileutil"
 	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/snapshot/storage"
 	"go.uber.org/zap"
 )
 
@@ -100,6 +107,62 @@ func SaveWithVersion(ctx context.Context, lg *zap.Logger, cfg clientv3.Config, d
 	return resp.Version, nil
 }
 
+// SaveToStorage fetches snapshot from remote etcd server, uploads it as the
+// object name of the storage st, streaming it without a local copy, and
+// returns server version. The object is only written once the snapshot is
+// entirely fetched with its sha256 checksum, its digest being written
+// along with it by storage.Upload. Make sure to specify only one endpoint
+// in client configuration. Supported since etcd 3.6.
+func SaveToStorage(ctx context.Context, lg *zap.Logger, cfg clientv3.Config, st storage.Storage, name string) (version string, err error) {
+	cfg.Logger = lg.Named("client")
+	if len(cfg.Endpoints) != 1 {
+		return "", fmt.Errorf("snapshot must be requested to one selected node, not multiple %v", cfg.Endpoints)
+	}
+	cli, err := clientv3.New(cfg)
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	start := time.Now()
+	resp, err := cli.SnapshotWithVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Snapshot.Close()
+	lg.Info("fetching snapshot", zap.String("endpoint", cfg.Endpoints[0]), zap.String("object", name))
+	cr := &checksumReader{r: resp.Snapshot}
+	digest, err := storage.Upload(ctx, st, name, cr)
+	if err != nil {
+		return resp.Version, err
+	}
+	lg.Info("uploaded snapshot",
+		zap.String("endpoint", cfg.Endpoints[0]),
+		zap.String("object", name),
+		zap.String("size", humanize.Bytes(uint64(cr.n))),
+		zap.String("sha256", digest),
+		zap.Duration("took", time.Since(start)),
+		zap.String("etcd-version", resp.Version),
+	)
+	return resp.Version, nil
+}
+
+// checksumReader reads a snapshot, failing at its end if it has no sha256
+// checksum appended, for the snapshot not to be uploaded.
+type checksumReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *checksumReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	if err == io.EOF && !hasChecksum(cr.n) {
+		return n, fmt.Errorf("sha256 checksum not found [bytes: %d]", cr.n)
+	}
+	return n, err
+}
+
 // Save fetches snapshot from remote etcd server and saves data
 // to target path. If the context "ctx" is canceled or timed out,
 // snapshot save stream will error out (e.g. context.Canceled,
@@ -112,3 +175,140 @@ func Save(ctx context.Context, lg *zap.Logger, cfg clientv3.Config, dbPath strin
 	_, err := SaveWithVersion(ctx, lg, cfg, dbPath)
 	return err
 }
+
+// SaveDelta fetches the changes of the key-value store after the revision
+// sinceRev from remote etcd server, saves them to target path and returns
+// the manifest of the delta. Make sure to specify only one endpoint in
+// client configuration, the delta is up to a point-in-time revision of the
+// selected node. Supported since etcd 3.6.
+func SaveDelta(ctx context.Context, lg *zap.Logger, cfg clientv3.Config, sinceRev int64, path string) (*pb.SnapshotDeltaManifest, error) {
+	cfg.Logger = lg.Named("client")
+	if len(cfg.Endpoints) != 1 {
+		return nil, fmt.Errorf("snapshot delta must be requested to one selected node, not multiple %v", cfg.Endpoints)
+	}
+	cli, err := clientv3.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	partpath := path + ".part"
+	defer os.RemoveAll(partpath)
+
+	f, err := os.OpenFile(partpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileutil.PrivateFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s (%v)", partpath, err)
+	}
+	defer f.Close()
+	lg.Info("created temporary delta file", zap.String("path", partpath))
+
+	start := time.Now()
+	rc, err := cli.SnapshotDelta(ctx, sinceRev)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	lg.Info("fetching snapshot delta", zap.String("endpoint", cfg.Endpoints[0]), zap.Int64("since-revision", sinceRev))
+	var manifest *pb.SnapshotDeltaManifest
+	size, err := io.Copy(f, io.TeeReader(rc, &deltaManifestWriter{m: &manifest}))
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("snapshot delta manifest not found [bytes: %d]", size)
+	}
+	if err = fileutil.Fsync(f); err != nil {
+		return nil, err
+	}
+	if err = f.Close(); err != nil {
+		return nil, err
+	}
+	lg.Info("fetched snapshot delta",
+		zap.String("endpoint", cfg.Endpoints[0]),
+		zap.Int64("since-revision", manifest.SinceRevision),
+		zap.Int64("revision", manifest.Revision),
+		zap.Int64("events", manifest.Events),
+		zap.String("size", humanize.Bytes(uint64(size))),
+		zap.Duration("took", time.Since(start)),
+	)
+
+	if err = os.Rename(partpath, path); err != nil {
+		return nil, fmt.Errorf("could not rename %s to %s (%v)", partpath, path, err)
+	}
+	lg.Info("saved", zap.String("path", path))
+	return manifest, nil
+}
+
+// ReadDelta reads the events and the manifest of a delta saved by SaveDelta,
+// checking the digest of the events.
+func ReadDelta(r io.Reader) ([]*mvccpb.Event, *pb.SnapshotDeltaManifest, error) {
+	br := bufio.NewReader(r)
+	h := sha256.New()
+	var evs []*mvccpb.Event
+	for {
+		resp, err := readDeltaResponse(br)
+		if err == io.EOF {
+			return nil, nil, errors.New("snapshot delta manifest not found")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ev := range resp.Events {
+			b, err := ev.Marshal()
+			if err != nil {
+				return nil, nil, err
+			}
+			h.Write(b)
+		}
+		evs = append(evs, resp.Events...)
+		if m := resp.Manifest; m != nil {
+			if !bytes.Equal(h.Sum(nil), m.Sha256) {
+				return nil, nil, errors.New("snapshot delta sha256 checksum mismatch")
+			}
+			if int64(len(evs)) != m.Events {
+				return nil, nil, fmt.Errorf("snapshot delta has %d events, expected %d", len(evs), m.Events)
+			}
+			return evs, m, nil
+		}
+	}
+}
+
+func readDeltaResponse(br *bufio.Reader) (*pb.SnapshotDeltaResponse, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err = io.ReadFull(br, b); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	var resp pb.SnapshotDeltaResponse
+	if err = resp.Unmarshal(b); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// deltaManifestWriter keeps the manifest of the delta written to it.
+type deltaManifestWriter struct {
+	buf []byte
+	m   **pb.SnapshotDeltaManifest
+}
+
+func (w *deltaManifestWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		n, l := binary.Uvarint(w.buf)
+		if l <= 0 || uint64(len(w.buf)-l) < n {
+			return len(p), nil
+		}
+		var resp pb.SnapshotDeltaResponse
+		if err := resp.Unmarshal(w.buf[l : l+int(n)]); err != nil {
+			return 0, err
+		}
+		if resp.Manifest != nil {
+			*w.m = resp.Manifest
+		}
+		w.buf = w.buf[l+int(n):]
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typedwatch
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/proto"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// Decoder decodes the values of the key-value pairs watched.
+type Decoder interface {
+	Decode(kv *mvccpb.KeyValue) (interface{}, error)
+}
+
+// DecoderFunc is a Decoder calling the func.
+type DecoderFunc func(kv *mvccpb.KeyValue) (interface{}, error)
+
+func (f DecoderFunc) Decode(kv *mvccpb.KeyValue) (interface{}, error) { return f(kv) }
+
+// JSONDecoder returns a Decoder unmarshaling the values from JSON into the
+// values returned by newValue, e.g. pointers to a struct.
+func JSONDecoder(newValue func() interface{}) Decoder {
+	return DecoderFunc(func(kv *mvccpb.KeyValue) (interface{}, error) {
+		v := newValue()
+		if err := json.Unmarshal(kv.Value, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// ProtoDecoder returns a Decoder unmarshaling the values from their protobuf
+// wire format into the messages returned by newMessage.
+func ProtoDecoder(newMessage func() proto.Message) Decoder {
+	return DecoderFunc(func(kv *mvccpb.KeyValue) (interface{}, error) {
+		m := newMessage()
+		if err := proto.Unmarshal(kv.Value, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	})
+}
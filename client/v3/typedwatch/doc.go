@@ -0,0 +1,52 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package typedwatch is a clientv3 watcher wrapper decoding the values of
+// the watched key-value pairs, so that the watchers receive the events of
+// the objects stored rather than of their bytes.
+//
+// A Watcher decodes the values with its Decoder, e.g. unmarshaling them from
+// JSON into the type of the objects stored:
+//
+//	type Job struct {
+//		State string `json:"state"`
+//	}
+//
+//	tw := typedwatch.New(cli.Watcher, typedwatch.JSONDecoder(func() interface{} { return &Job{} }))
+//	wch, errc := tw.Watch(ctx, "jobs/", clientv3.WithPrefix(), clientv3.WithPrevKV())
+//
+// The decoded values are those returned by the decoder, asserted by the
+// watchers to their type. The events whose values fail to decode are dropped
+// from the responses, and their errors received from the error channel:
+//
+//	for {
+//		select {
+//		case resp, ok := <-wch:
+//			if !ok {
+//				return
+//			}
+//			for _, ev := range resp.Events {
+//				if ev.Type == mvccpb.PUT {
+//					job := ev.Value.(*Job)
+//					// ...
+//				}
+//			}
+//		case err := <-errc:
+//			// handle error!
+//		}
+//	}
+//
+// Both channels are closed once the watch ends, and must be received from
+// until then.
+package typedwatch
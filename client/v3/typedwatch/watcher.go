@@ -0,0 +1,132 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typedwatch
+
+import (
+	"context"
+	"fmt"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Event is a watch event with the decoded values of its key-value pairs.
+type Event struct {
+	Type mvccpb.Event_EventType
+	// Kv is the key-value pair of the event, and Value its decoded value, nil
+	// for the deletions.
+	Kv    *mvccpb.KeyValue
+	Value interface{}
+	// PrevKv is the previous key-value pair of the key, if watched with
+	// clientv3.WithPrevKV, and PrevValue its decoded value.
+	PrevKv    *mvccpb.KeyValue
+	PrevValue interface{}
+}
+
+// IsCreate returns true if the event tells that the key is newly created.
+func (e *Event) IsCreate() bool {
+	return e.Type == mvccpb.PUT && e.Kv.CreateRevision == e.Kv.ModRevision
+}
+
+// IsModify returns true if the event tells that a new value is put on existing key.
+func (e *Event) IsModify() bool {
+	return e.Type == mvccpb.PUT && e.Kv.CreateRevision != e.Kv.ModRevision
+}
+
+// WatchResponse is a watch response with the decoded events.
+type WatchResponse struct {
+	clientv3.WatchResponse
+	// Events are the decoded events, without the events failing to decode.
+	// The raw events are WatchResponse.Events.
+	Events []*Event
+}
+
+type WatchChan <-chan WatchResponse
+
+// DecodeError is the error of the decoding of a key-value pair watched.
+type DecodeError struct {
+	Kv  *mvccpb.KeyValue
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("typedwatch: failed to decode %q at revision %d: %v", e.Kv.Key, e.Kv.ModRevision, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// Watcher watches the keys through a clientv3.Watcher, decoding their
+// values with a Decoder.
+type Watcher struct {
+	w   clientv3.Watcher
+	dec Decoder
+}
+
+// New returns a Watcher watching through w, decoding the values with dec.
+func New(w clientv3.Watcher, dec Decoder) *Watcher {
+	return &Watcher{w: w, dec: dec}
+}
+
+// Watch watches the key, or the range of key and opts, as
+// clientv3.Watcher.Watch, decoding the events. The errors of the events
+// failing to decode, dropped from the responses, are received from the
+// returned error chan before the response of the event. Both chans are
+// closed once the watch ends, and must be received from until then.
+func (tw *Watcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) (WatchChan, <-chan *DecodeError) {
+	respc := make(chan WatchResponse)
+	errc := make(chan *DecodeError)
+	wch := tw.w.Watch(ctx, key, opts...)
+	go func() {
+		defer close(respc)
+		defer close(errc)
+		for wresp := range wch {
+			resp := WatchResponse{WatchResponse: wresp, Events: make([]*Event, 0, len(wresp.Events))}
+			for _, ev := range wresp.Events {
+				tev, err := tw.decode(ev)
+				if err != nil {
+					select {
+					case errc <- err:
+						continue
+					case <-ctx.Done():
+						return
+					}
+				}
+				resp.Events = append(resp.Events, tev)
+			}
+			select {
+			case respc <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return respc, errc
+}
+
+func (tw *Watcher) decode(ev *clientv3.Event) (*Event, *DecodeError) {
+	tev := &Event{Type: ev.Type, Kv: ev.Kv, PrevKv: ev.PrevKv}
+	var err error
+	if ev.Type == mvccpb.PUT {
+		if tev.Value, err = tw.dec.Decode(ev.Kv); err != nil {
+			return nil, &DecodeError{Kv: ev.Kv, Err: err}
+		}
+	}
+	if ev.PrevKv != nil {
+		if tev.PrevValue, err = tw.dec.Decode(ev.PrevKv); err != nil {
+			return nil, &DecodeError{Kv: ev.PrevKv, Err: err}
+		}
+	}
+	return tev, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typedwatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"go.etcd.io/etcd/api/v3/authpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeWatcher sends the responses of its chan to the watches.
+type fakeWatcher struct {
+	clientv3.Watcher
+	wch chan clientv3.WatchResponse
+}
+
+func (w *fakeWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	return w.wch
+}
+
+type job struct {
+	State string `json:"state"`
+}
+
+func TestWatchJSON(t *testing.T) {
+	w := &fakeWatcher{wch: make(chan clientv3.WatchResponse, 1)}
+	tw := New(w, JSONDecoder(func() interface{} { return &job{} }))
+	wch, errc := tw.Watch(context.Background(), "jobs/", clientv3.WithPrefix())
+
+	a1 := &mvccpb.KeyValue{Key: []byte("jobs/a"), Value: []byte(`{"state":"pending"}`), CreateRevision: 2, ModRevision: 2}
+	a2 := &mvccpb.KeyValue{Key: []byte("jobs/a"), Value: []byte(`{"state":"done"}`), CreateRevision: 2, ModRevision: 4}
+	b := &mvccpb.KeyValue{Key: []byte("jobs/b"), Value: []byte(`pending`), CreateRevision: 3, ModRevision: 3}
+	w.wch <- clientv3.WatchResponse{Events: []*clientv3.Event{
+		{Type: mvccpb.PUT, Kv: a1},
+		{Type: mvccpb.PUT, Kv: b},
+		{Type: mvccpb.PUT, Kv: a2, PrevKv: a1},
+		{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("jobs/a"), ModRevision: 5}},
+	}}
+
+	derr := <-errc
+	if derr.Kv != b {
+		t.Errorf("decode error of %q, want of %q", derr.Kv.Key, b.Key)
+	}
+	var serr *json.SyntaxError
+	if !errors.As(derr, &serr) {
+		t.Errorf("decode error = %v, want a JSON syntax error", derr)
+	}
+
+	resp := <-wch
+	if len(resp.WatchResponse.Events) != 4 {
+		t.Errorf("raw events = %d, want 4", len(resp.WatchResponse.Events))
+	}
+	if len(resp.Events) != 3 {
+		t.Fatalf("events = %d, want 3", len(resp.Events))
+	}
+	if ev := resp.Events[0]; !ev.IsCreate() || !reflect.DeepEqual(ev.Value, &job{State: "pending"}) {
+		t.Errorf("event #0 = %+v, want the creation of a pending job", ev)
+	}
+	if ev := resp.Events[1]; !ev.IsModify() || !reflect.DeepEqual(ev.Value, &job{State: "done"}) || !reflect.DeepEqual(ev.PrevValue, &job{State: "pending"}) {
+		t.Errorf("event #1 = %+v, want the modification of the job done", ev)
+	}
+	if ev := resp.Events[2]; ev.Type != mvccpb.DELETE || ev.Value != nil {
+		t.Errorf("event #2 = %+v, want a deletion", ev)
+	}
+
+	close(w.wch)
+	if _, ok := <-wch; ok {
+		t.Errorf("watch chan not closed")
+	}
+	if _, ok := <-errc; ok {
+		t.Errorf("error chan not closed")
+	}
+}
+
+func TestProtoDecoder(t *testing.T) {
+	want := &authpb.User{Name: []byte("root"), Roles: []string{"root"}}
+	b, err := want.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := ProtoDecoder(func() proto.Message { return &authpb.User{} })
+	v, err := dec.Decode(&mvccpb.KeyValue{Value: b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("decoded %v, want %v", v, want)
+	}
+	if _, err = dec.Decode(&mvccpb.KeyValue{Value: []byte{0xff}}); err == nil {
+		t.Errorf("decoded an invalid message")
+	}
+}
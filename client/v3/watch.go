@@ -39,6 +39,8 @@ const (
 	closeSendErrTimeout = 250 * time.Millisecond
 )
 
+var errWatchChanNotFound = errors.New("watch channel not found on the stream of the context")
+
 type Event mvccpb.Event
 
 type WatchChan <-chan WatchResponse
@@ -77,6 +79,13 @@ type Watcher interface {
 	// RequestProgress requests a progress notify response be sent in all watch channels.
 	RequestProgress(ctx context.Context) error
 
+	// RequestWatchProgress requests a progress notify response be sent in the
+	// watch channel wch only, returned by Watch with a context of the same
+	// stream as ctx. The response is sent once the watcher is synced, a
+	// watcher catching up with the revisions sending its events instead.
+	// Supported since etcd 3.6.
+	RequestWatchProgress(ctx context.Context, wch WatchChan) error
+
 	// Close closes the watcher and cancels all watch requests.
 	Close() error
 }
@@ -200,6 +209,9 @@ type watchRequest struct {
 	createdNotify bool
 	// progressNotify is for progress updates
 	progressNotify bool
+	// progressNotifyInterval is the interval of the progress updates, the
+	// interval of the server if zero
+	progressNotifyInterval time.Duration
 	// fragmentation should be disabled by default
 	// if true, split watch events when total exceeds
 	// "--max-request-bytes" flag value + 512-byte
@@ -207,6 +219,10 @@ type watchRequest struct {
 
 	// filters is the list of events to filter out
 	filters []pb.WatchCreateRequest_FilterType
+	// valuePrefix and valueRegex filter out the put events whose value does
+	// not match them
+	valuePrefix string
+	valueRegex  string
 	// get the previous key-value pair before the event happens
 	prevKV bool
 	// retc receives a chan WatchResponse once the watcher is established
@@ -215,6 +231,13 @@ type watchRequest struct {
 
 // progressRequest is issued by the subscriber to request watch progress
 type progressRequest struct {
+	// wch is the watch channel of the watcher to request the progress of,
+	// nil for all the watchers of the stream
+	wch WatchChan
+	// errc receives the error of the request of the progress of wch
+	errc chan error
+	// watchIDs are the IDs of the watchers to request the progress of
+	watchIDs []int64
 }
 
 // watcherStream represents a registered watcher
@@ -299,16 +322,19 @@ func (w *watcher) Watch(ctx context.Context, key string, opts ...OpOption) Watch
 	}
 
 	wr := &watchRequest{
-		ctx:            ctx,
-		createdNotify:  ow.createdNotify,
-		key:            string(ow.key),
-		end:            string(ow.end),
-		rev:            ow.rev,
-		progressNotify: ow.progressNotify,
-		fragment:       ow.fragment,
-		filters:        filters,
-		prevKV:         ow.prevKV,
-		retc:           make(chan chan WatchResponse, 1),
+		ctx:                    ctx,
+		createdNotify:          ow.createdNotify,
+		key:                    string(ow.key),
+		end:                    string(ow.end),
+		rev:                    ow.rev,
+		progressNotify:         ow.progressNotify,
+		progressNotifyInterval: ow.progressNotifyInterval,
+		fragment:               ow.fragment,
+		filters:                filters,
+		valuePrefix:            ow.valuePrefix,
+		valueRegex:             ow.valueRegex,
+		prevKV:                 ow.prevKV,
+		retc:                   make(chan chan WatchResponse, 1),
 	}
 
 	ok := false
@@ -428,6 +454,47 @@ func (w *watcher) RequestProgress(ctx context.Context) (err error) {
 	}
 }
 
+// RequestWatchProgress requests a progress notify response be sent in the
+// watch channel wch only.
+func (w *watcher) RequestWatchProgress(ctx context.Context, wch WatchChan) (err error) {
+	ctxKey := streamKeyFromCtx(ctx)
+
+	w.mu.Lock()
+	if w.streams == nil {
+		w.mu.Unlock()
+		return fmt.Errorf("no stream found for context")
+	}
+	wgs := w.streams[ctxKey]
+	w.mu.Unlock()
+	if wgs == nil {
+		return errWatchChanNotFound
+	}
+
+	pr := &progressRequest{wch: wch, errc: make(chan error, 1)}
+
+	select {
+	case wgs.reqc <- pr:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wgs.donec:
+		if wgs.closeErr != nil {
+			return wgs.closeErr
+		}
+		return errWatchChanNotFound
+	}
+	select {
+	case err = <-pr.errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wgs.donec:
+		if wgs.closeErr != nil {
+			return wgs.closeErr
+		}
+		return errWatchChanNotFound
+	}
+}
+
 func (w *watchGrpcStream) close() (err error) {
 	w.cancel()
 	<-w.donec
@@ -493,6 +560,28 @@ func (w *watchGrpcStream) closeSubstream(ws *watcherStream) {
 	}
 }
 
+// sendWatchProgressRequest requests the progress of the watcher of the watch
+// channel of pr, returning errWatchChanNotFound if it is not a watcher of the
+// stream. The request of a watcher being resumed is dropped, its creation
+// response carrying its progress.
+func (w *watchGrpcStream) sendWatchProgressRequest(wc pb.Watch_WatchClient, pr *progressRequest) error {
+	for _, ws := range w.resuming {
+		if ws != nil && ws.outc == pr.wch {
+			return nil
+		}
+	}
+	for id, ws := range w.substreams {
+		if ws.outc == pr.wch {
+			pr.watchIDs = []int64{id}
+			if err := wc.Send(pr.toPB()); err != nil {
+				w.lg.Debug("error when sending request", zap.Error(err))
+			}
+			return nil
+		}
+	}
+	return errWatchChanNotFound
+}
+
 // run is the root of the goroutines for managing a watcher client
 func (w *watchGrpcStream) run() {
 	var wc pb.Watch_WatchClient
@@ -562,6 +651,10 @@ func (w *watchGrpcStream) run() {
 					}
 				}
 			case *progressRequest:
+				if wreq.wch != nil {
+					wreq.errc <- w.sendWatchProgressRequest(wc, wreq)
+					break
+				}
 				if err := wc.Send(wreq.toPB()); err != nil {
 					w.lg.Debug("error when sending request", zap.Error(err))
 				}
@@ -1001,13 +1094,16 @@ func (w *watchGrpcStream) openWatchClient() (ws pb.Watch_WatchClient, err error)
 // toPB converts an internal watch request structure to its protobuf WatchRequest structure.
 func (wr *watchRequest) toPB() *pb.WatchRequest {
 	req := &pb.WatchCreateRequest{
-		StartRevision:  wr.rev,
-		Key:            []byte(wr.key),
-		RangeEnd:       []byte(wr.end),
-		ProgressNotify: wr.progressNotify,
-		Filters:        wr.filters,
-		PrevKv:         wr.prevKV,
-		Fragment:       wr.fragment,
+		StartRevision:          wr.rev,
+		Key:                    []byte(wr.key),
+		RangeEnd:               []byte(wr.end),
+		ProgressNotify:         wr.progressNotify,
+		ProgressNotifyInterval: int64(wr.progressNotifyInterval),
+		Filters:                wr.filters,
+		PrevKv:                 wr.prevKV,
+		Fragment:               wr.fragment,
+		ValuePrefix:            []byte(wr.valuePrefix),
+		ValueRegex:             wr.valueRegex,
 	}
 	cr := &pb.WatchRequest_CreateRequest{CreateRequest: req}
 	return &pb.WatchRequest{RequestUnion: cr}
@@ -1015,7 +1111,7 @@ func (wr *watchRequest) toPB() *pb.WatchRequest {
 
 // toPB converts an internal progress request structure to its protobuf WatchRequest structure.
 func (pr *progressRequest) toPB() *pb.WatchRequest {
-	req := &pb.WatchProgressRequest{}
+	req := &pb.WatchProgressRequest{WatchIds: pr.watchIDs}
 	cr := &pb.WatchRequest_ProgressRequest{ProgressRequest: req}
 	return &pb.WatchRequest{RequestUnion: cr}
 }
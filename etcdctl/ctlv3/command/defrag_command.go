@@ -19,10 +19,13 @@ import (
 	"os"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/pkg/v3/cobrautl"
 )
 
+var defragEstimate bool
+
 // NewDefragCommand returns the cobra command for "Defrag".
 func NewDefragCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -31,10 +34,15 @@ func NewDefragCommand() *cobra.Command {
 		Run:   defragCommandFunc,
 	}
 	cmd.PersistentFlags().BoolVar(&epClusterEndpoints, "cluster", false, "use all endpoints from the cluster member list")
+	cmd.Flags().BoolVar(&defragEstimate, "estimate", false, "print the space defragmenting would reclaim instead of defragmenting")
 	return cmd
 }
 
 func defragCommandFunc(cmd *cobra.Command, args []string) {
+	if defragEstimate {
+		defragEstimateCommandFunc(cmd)
+		return
+	}
 
 	failures := 0
 	c := mustClientFromCmd(cmd)
@@ -56,3 +64,30 @@ func defragCommandFunc(cmd *cobra.Command, args []string) {
 		os.Exit(cobrautl.ExitError)
 	}
 }
+
+// defragEstimateCommandFunc prints the space defragmenting the endpoints would reclaim.
+func defragEstimateCommandFunc(cmd *cobra.Command) {
+	failures := 0
+	c := mustClientFromCmd(cmd)
+	for _, ep := range endpointsFromCluster(cmd) {
+		ctx, cancel := commandCtx(cmd)
+		resp, err := c.DefragmentEstimate(ctx, ep)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to estimate the defragmentation of etcd member[%s]. (%v)\n", ep, err)
+			failures++
+			continue
+		}
+		fmt.Printf("Defragmenting etcd member[%s] would reclaim %s of %s. %d tombstones of %s until compacted\n",
+			ep,
+			humanize.Bytes(uint64(resp.ReclaimableSize)),
+			humanize.Bytes(uint64(resp.DbSize)),
+			resp.Tombstones,
+			humanize.Bytes(uint64(resp.TombstonesSize)),
+		)
+	}
+
+	if failures != 0 {
+		os.Exit(cobrautl.ExitError)
+	}
+}
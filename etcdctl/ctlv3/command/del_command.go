@@ -29,6 +29,7 @@ var (
 	delPrevKV  bool
 	delFromKey bool
 	delRange   bool
+	delPaced   bool
 )
 
 // NewDelCommand returns the cobra command for "del".
@@ -43,6 +44,7 @@ func NewDelCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&delPrevKV, "prev-kv", false, "return deleted key-value pairs")
 	cmd.Flags().BoolVar(&delFromKey, "from-key", false, "delete keys that are greater than or equal to the given key using byte compare")
 	cmd.Flags().BoolVar(&delRange, "range", false, "delete range of keys")
+	cmd.Flags().BoolVar(&delPaced, "paced", false, "delete the range in bounded batches, each its own transaction, not to spike the backend commit latency (not atomic)")
 	return cmd
 }
 
@@ -92,6 +94,9 @@ func getDelOp(args []string) (string, []clientv3.OpOption) {
 	if delPrevKV {
 		opts = append(opts, clientv3.WithPrevKV())
 	}
+	if delPaced {
+		opts = append(opts, clientv3.WithPaced())
+	}
 
 	if delFromKey {
 		if len(key) == 0 {
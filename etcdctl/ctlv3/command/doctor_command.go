@@ -0,0 +1,341 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+const (
+	doctorCritical = "critical"
+	doctorWarning  = "warning"
+	doctorInfo     = "info"
+
+	// metricQuotaBackendBytes and the following are the metrics of the
+	// members the checks read.
+	metricQuotaBackendBytes = "etcd_server_quota_backend_bytes"
+	metricWatchers          = "etcd_debugging_mvcc_watcher_total"
+	metricCompactRevision   = "etcd_debugging_mvcc_compact_revision"
+)
+
+var doctorCfg = defaultDoctorThresholds()
+
+// doctorThresholds are the thresholds above which the checks report findings.
+type doctorThresholds struct {
+	quotaWarning     float64
+	quotaCritical    float64
+	fragmentation    float64
+	minFragmentBytes int64
+	clockSkew        time.Duration
+	raftLag          uint64
+	compactionLag    int64
+	leases           int
+	watchers         float64
+}
+
+func defaultDoctorThresholds() doctorThresholds {
+	return doctorThresholds{
+		quotaWarning:     0.8,
+		quotaCritical:    0.95,
+		fragmentation:    0.5,
+		minFragmentBytes: 64 * 1024 * 1024,
+		clockSkew:        time.Second,
+		raftLag:          5000,
+		compactionLag:    1000000,
+		leases:           10000,
+		watchers:         10000,
+	}
+}
+
+// NewDoctorCommand returns the cobra command for "doctor".
+func NewDoctorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Checks the etcd cluster for common problems",
+		Long: `Checks the health of the members of the cluster the endpoints belong to: quorum and
+leadership, alarms, db size against the quota, fragmentation, clock skew, slow
+members, compaction and the numbers of leases and watchers.
+
+The findings are printed by priority, critical first, with the commands to
+remediate them. The members must serve their metrics on their client URLs for
+the quota, compaction and watcher checks.`,
+		Run: doctorCommandFunc,
+	}
+	cmd.Flags().DurationVar(&doctorCfg.clockSkew, "max-clock-skew", doctorCfg.clockSkew, "Clock skew of a member relative to its peers above which a finding is reported")
+	cmd.Flags().Uint64Var(&doctorCfg.raftLag, "max-raft-lag", doctorCfg.raftLag, "Number of raft entries a member applies behind the leader above which it is reported as slow")
+	cmd.Flags().Int64Var(&doctorCfg.compactionLag, "max-compaction-lag", doctorCfg.compactionLag, "Number of revisions of history kept above which the compaction is reported as too old")
+	cmd.Flags().IntVar(&doctorCfg.leases, "max-leases", doctorCfg.leases, "Number of leases above which a finding is reported")
+	cmd.Flags().Float64Var(&doctorCfg.watchers, "max-watchers", doctorCfg.watchers, "Number of watchers of a member above which a finding is reported")
+	return cmd
+}
+
+type doctorFinding struct {
+	Severity    string `json:"severity"`
+	Check       string `json:"check"`
+	Endpoint    string `json:"endpoint,omitempty"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// doctorMember is the state of a member read by the checks.
+type doctorMember struct {
+	member     *pb.Member
+	ep         string
+	status     *clientv3.StatusResponse
+	err        error
+	metrics    map[string]float64
+	metricsErr error
+}
+
+// doctorCluster is the state of the cluster read by the checks.
+type doctorCluster struct {
+	members []doctorMember
+	alarms  []*pb.AlarmMember
+	// leases is the number of leases, -1 if unknown.
+	leases int
+}
+
+// doctorCommandFunc executes the "doctor" command.
+func doctorCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("doctor command accepts no arguments"))
+	}
+	c := mustClientFromCmd(cmd)
+	sec := secureCfgFromCmd(cmd)
+
+	ctx, cancel := commandCtx(cmd)
+	membs, err := c.MemberList(ctx)
+	cancel()
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError, err)
+	}
+
+	cl := doctorCluster{leases: -1}
+	for _, m := range membs.Members {
+		dm := doctorMember{member: m}
+		if len(m.ClientURLs) == 0 {
+			// the member has not started yet
+			cl.members = append(cl.members, dm)
+			continue
+		}
+		dm.ep = m.ClientURLs[0]
+		ctx, cancel = commandCtx(cmd)
+		dm.status, dm.err = c.Status(ctx, dm.ep)
+		cancel()
+		if dm.err == nil {
+			dm.metrics, dm.metricsErr = endpointMetrics(dm.ep, sec, metricQuotaBackendBytes, metricWatchers, metricCompactRevision)
+		}
+		cl.members = append(cl.members, dm)
+	}
+
+	ctx, cancel = commandCtx(cmd)
+	if resp, aerr := c.AlarmList(ctx); aerr == nil {
+		cl.alarms = resp.Alarms
+	} else {
+		fmt.Fprintf(os.Stderr, "Failed to get the alarms (%v)\n", aerr)
+	}
+	if resp, lerr := c.Leases(ctx); lerr == nil {
+		cl.leases = len(resp.Leases)
+	} else {
+		fmt.Fprintf(os.Stderr, "Failed to get the leases (%v)\n", lerr)
+	}
+	cancel()
+
+	findings := diagnose(cl, doctorCfg)
+	display.Doctor(findings)
+
+	for _, f := range findings {
+		if f.Severity == doctorCritical {
+			os.Exit(cobrautl.ExitError)
+		}
+	}
+}
+
+// diagnose returns the findings of the checks of the cluster, critical first.
+func diagnose(cl doctorCluster, th doctorThresholds) []doctorFinding {
+	var findings []doctorFinding
+	add := func(severity, check, ep, remediation, format string, a ...interface{}) {
+		findings = append(findings, doctorFinding{
+			Severity:    severity,
+			Check:       check,
+			Endpoint:    ep,
+			Message:     fmt.Sprintf(format, a...),
+			Remediation: remediation,
+		})
+	}
+
+	// quorum and leadership
+	voters, reachable := 0, 0
+	leaders := make(map[uint64]struct{})
+	var leader *doctorMember
+	for i, m := range cl.members {
+		if m.status == nil {
+			switch {
+			case m.ep == "":
+				add(doctorWarning, "quorum", "", fmt.Sprintf("etcdctl member remove %s", types.ID(m.member.ID)), "member %s was added but has not started", types.ID(m.member.ID))
+			case m.member.IsLearner:
+				add(doctorWarning, "quorum", m.ep, "", "learner %s is unreachable: %v", memberName(m.member), m.err)
+			default:
+				add(doctorWarning, "quorum", m.ep, "", "member %s is unreachable: %v", memberName(m.member), m.err)
+			}
+		}
+		if m.member.IsLearner {
+			continue
+		}
+		voters++
+		if m.status == nil {
+			continue
+		}
+		reachable++
+		leaders[m.status.Leader] = struct{}{}
+		if m.status.Leader == m.status.Header.MemberId {
+			leader = &cl.members[i]
+		}
+	}
+	quorum := voters/2 + 1
+	switch {
+	case reachable < quorum:
+		add(doctorCritical, "quorum", "", "restore the unreachable members, or restore the cluster from a snapshot with 'etcdutl snapshot restore'",
+			"quorum lost: %d of the %d voting members are reachable, %d needed", reachable, voters, quorum)
+	case reachable == quorum && reachable < voters:
+		add(doctorWarning, "quorum", "", "restore the unreachable members",
+			"quorum at risk: losing one more of the %d reachable voting members loses the quorum", reachable)
+	}
+	if _, none := leaders[0]; none || len(leaders) > 1 {
+		add(doctorCritical, "leader", "", "check the network between the members and their logs for elections",
+			"the reachable members do not agree on a leader")
+	}
+
+	// alarms
+	for _, a := range cl.alarms {
+		id := types.ID(a.MemberID).String()
+		switch a.Alarm {
+		case pb.AlarmType_NOSPACE:
+			add(doctorCritical, "alarm", "", "etcdctl compact <revision>; etcdctl defrag --cluster; etcdctl alarm disarm",
+				"member %s raised the NOSPACE alarm, the cluster only accepts reads and deletes", id)
+		case pb.AlarmType_CORRUPT:
+			add(doctorCritical, "alarm", "", fmt.Sprintf("etcdctl member remove %s, then add it back with an empty data dir", id),
+				"member %s raised the CORRUPT alarm, its data is inconsistent with the cluster", id)
+		default:
+			add(doctorCritical, "alarm", "", "etcdctl alarm list", "member %s raised the %v alarm", id, a.Alarm)
+		}
+	}
+
+	for _, m := range cl.members {
+		if m.status == nil {
+			continue
+		}
+		st := m.status
+
+		// db size vs quota
+		if quota := m.metrics[metricQuotaBackendBytes]; quota > 0 {
+			usage := float64(st.DbSize) / quota
+			remediation := fmt.Sprintf("etcdctl compact <revision>; etcdctl defrag --endpoints=%s, or raise --quota-backend-bytes", m.ep)
+			switch {
+			case usage >= th.quotaCritical:
+				add(doctorCritical, "db-size", m.ep, remediation, "db size %s is %.0f%% of the quota %s", humanize.Bytes(uint64(st.DbSize)), usage*100, humanize.Bytes(uint64(quota)))
+			case usage >= th.quotaWarning:
+				add(doctorWarning, "db-size", m.ep, remediation, "db size %s is %.0f%% of the quota %s", humanize.Bytes(uint64(st.DbSize)), usage*100, humanize.Bytes(uint64(quota)))
+			}
+		}
+
+		// fragmentation
+		if free := st.DbSize - st.DbSizeInUse; st.DbSizeInUse > 0 && free >= th.minFragmentBytes && float64(free)/float64(st.DbSize) >= th.fragmentation {
+			add(doctorWarning, "fragmentation", m.ep, fmt.Sprintf("etcdctl defrag --endpoints=%s", m.ep),
+				"%s of the db size %s is free, %.0f%% fragmented", humanize.Bytes(uint64(free)), humanize.Bytes(uint64(st.DbSize)), float64(free)/float64(st.DbSize)*100)
+		}
+
+		// clock skew
+		if skew := time.Duration(st.MaxClockSkew); skew > th.clockSkew || -skew > th.clockSkew {
+			add(doctorWarning, "clock-skew", m.ep, "synchronize the clocks of the members, e.g. with NTP",
+				"clock skew of %v relative to the peers, above %v", skew, th.clockSkew)
+		}
+
+		// slow members
+		if leader != nil && leader.status.RaftIndex > st.RaftAppliedIndex && leader.status.RaftIndex-st.RaftAppliedIndex > th.raftLag {
+			add(doctorWarning, "slow-member", m.ep, "check the disk and network latency of the member",
+				"applied index %d is %d entries behind the leader", st.RaftAppliedIndex, leader.status.RaftIndex-st.RaftAppliedIndex)
+		}
+		if st.DiskDegraded {
+			add(doctorWarning, "slow-member", m.ep, "check the disk of the member, see etcd_disk_wal_fsync_duration_seconds and etcd_disk_backend_commit_duration_seconds",
+				"the WAL fsyncs or the backend commits are slower than their thresholds")
+		}
+		for _, e := range st.Errors {
+			add(doctorWarning, "member-errors", m.ep, "", "member reports: %s", e)
+		}
+
+		// watchers
+		if w := m.metrics[metricWatchers]; w > th.watchers {
+			add(doctorWarning, "watchers", m.ep, "check for clients leaking watchers, or use the gRPC proxy to coalesce them",
+				"%.0f watchers, above %.0f", w, th.watchers)
+		}
+
+		if m.metricsErr != nil {
+			add(doctorInfo, "metrics", m.ep, "serve the metrics on the client URLs, see --listen-metrics-urls",
+				"the quota, compaction and watcher checks are skipped: %v", m.metricsErr)
+		}
+	}
+
+	// compaction, the members sharing the compaction and the revision
+	if leader != nil && leader.metrics != nil {
+		if _, ok := leader.metrics[metricCompactRevision]; ok {
+			compactRev := int64(leader.metrics[metricCompactRevision])
+			if lag := leader.status.Header.Revision - compactRev; lag > th.compactionLag {
+				add(doctorWarning, "compaction", "", fmt.Sprintf("etcdctl compact %d, or set --auto-compaction-retention", leader.status.Header.Revision-th.compactionLag),
+					"%d revisions of history are kept, the last compaction is at revision %d", lag, compactRev)
+			}
+		}
+	}
+
+	// leases
+	if cl.leases > th.leases {
+		add(doctorWarning, "leases", "", "etcdctl lease list; check for clients leaking leases",
+			"%d leases, above %d", cl.leases, th.leases)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank(findings[i].Severity) < severityRank(findings[j].Severity)
+	})
+	return findings
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case doctorCritical:
+		return 0
+	case doctorWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func memberName(m *pb.Member) string {
+	if m.Name == "" {
+		return types.ID(m.ID).String()
+	}
+	return fmt.Sprintf("%s (%s)", m.Name, types.ID(m.ID))
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/v3"
+)
+
+func TestDiagnose(t *testing.T) {
+	member := func(id uint64, leader uint64, mod func(st *clientv3.StatusResponse)) doctorMember {
+		st := &clientv3.StatusResponse{
+			Header:           &pb.ResponseHeader{MemberId: id, Revision: 100},
+			Leader:           leader,
+			DbSize:           1000,
+			DbSizeInUse:      900,
+			RaftIndex:        50,
+			RaftAppliedIndex: 50,
+		}
+		if mod != nil {
+			mod(st)
+		}
+		return doctorMember{
+			member:  &pb.Member{ID: id, ClientURLs: []string{"ep"}},
+			ep:      "ep",
+			status:  st,
+			metrics: map[string]float64{metricQuotaBackendBytes: 10000, metricCompactRevision: 90},
+		}
+	}
+	unreachable := func(id uint64) doctorMember {
+		return doctorMember{member: &pb.Member{ID: id, ClientURLs: []string{"ep"}}, ep: "ep", err: errors.New("unreachable")}
+	}
+	healthy := func() []doctorMember {
+		return []doctorMember{member(1, 1, nil), member(2, 1, nil), member(3, 1, nil)}
+	}
+
+	tests := []struct {
+		name string
+		cl   doctorCluster
+		// wchecks are the severities and checks of the findings, in order.
+		wchecks [][2]string
+	}{
+		{
+			name: "healthy",
+			cl:   doctorCluster{members: healthy()},
+		},
+		{
+			name:    "quorum at risk",
+			cl:      doctorCluster{members: []doctorMember{member(1, 1, nil), member(2, 1, nil), unreachable(3)}},
+			wchecks: [][2]string{{doctorWarning, "quorum"}, {doctorWarning, "quorum"}},
+		},
+		{
+			name: "quorum lost",
+			cl:   doctorCluster{members: []doctorMember{member(1, 1, nil), unreachable(2), unreachable(3)}},
+			wchecks: [][2]string{
+				{doctorCritical, "quorum"}, {doctorWarning, "quorum"}, {doctorWarning, "quorum"},
+			},
+		},
+		{
+			name:    "no leader",
+			cl:      doctorCluster{members: []doctorMember{member(1, 0, nil), member(2, 0, nil), member(3, 0, nil)}},
+			wchecks: [][2]string{{doctorCritical, "leader"}},
+		},
+		{
+			name:    "alarm",
+			cl:      doctorCluster{members: healthy(), alarms: []*pb.AlarmMember{{MemberID: 1, Alarm: pb.AlarmType_NOSPACE}}},
+			wchecks: [][2]string{{doctorCritical, "alarm"}},
+		},
+		{
+			name: "member problems",
+			cl: doctorCluster{members: []doctorMember{
+				member(1, 1, nil),
+				member(2, 1, func(st *clientv3.StatusResponse) {
+					st.DbSize, st.DbSizeInUse = 9600, 9000
+					st.MaxClockSkew = int64(2 * time.Second)
+					st.RaftAppliedIndex = 1
+					st.Errors = []string{"some error"}
+				}),
+				member(3, 1, func(st *clientv3.StatusResponse) {
+					st.DbSize, st.DbSizeInUse = 8500, 1000
+					st.DiskDegraded = true
+				}),
+			}},
+			wchecks: [][2]string{
+				{doctorCritical, "db-size"},
+				{doctorWarning, "clock-skew"},
+				{doctorWarning, "slow-member"},
+				{doctorWarning, "member-errors"},
+				{doctorWarning, "db-size"},
+				{doctorWarning, "fragmentation"},
+				{doctorWarning, "slow-member"},
+			},
+		},
+		{
+			name: "compaction, leases and watchers",
+			cl: func() doctorCluster {
+				ms := healthy()
+				ms[0].metrics[metricCompactRevision] = 0
+				ms[1].metrics[metricWatchers] = 20
+				ms[2].metrics, ms[2].metricsErr = nil, errors.New("not found")
+				return doctorCluster{members: ms, leases: 20}
+			}(),
+			wchecks: [][2]string{
+				{doctorWarning, "watchers"},
+				{doctorWarning, "compaction"},
+				{doctorWarning, "leases"},
+				{doctorInfo, "metrics"},
+			},
+		},
+	}
+	th := defaultDoctorThresholds()
+	th.minFragmentBytes = 1000
+	th.raftLag = 10
+	th.compactionLag = 50
+	th.leases = 10
+	th.watchers = 10
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checks [][2]string
+			for _, f := range diagnose(tt.cl, th) {
+				checks = append(checks, [2]string{f.Severity, f.Check})
+			}
+			if !reflect.DeepEqual(checks, tt.wchecks) {
+				t.Errorf("findings = %v, want %v", checks, tt.wchecks)
+			}
+		})
+	}
+}
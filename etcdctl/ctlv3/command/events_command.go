@@ -0,0 +1,77 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+var eventsLimit int64
+
+// NewEventsCommand returns the cobra command for "events".
+func NewEventsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Prints the latest significant events of the etcd members with given endpoints",
+		Long: `Prints the latest significant events kept by the etcd members, such as
+leader changes, compactions, defragmentations, snapshots, alarms and
+membership changes, from the oldest to the newest.`,
+		Run: eventsCommandFunc,
+	}
+	cmd.Flags().Int64Var(&eventsLimit, "limit", 0, "maximum number of the latest events to print per endpoint (default: all events)")
+	cmd.Flags().BoolVar(&epClusterEndpoints, "cluster", false, "use all endpoints from the cluster member list")
+	return cmd
+}
+
+type epEvents struct {
+	Ep   string                   `json:"Endpoint"`
+	Resp *clientv3.EventsResponse `json:"Events"`
+}
+
+// eventsCommandFunc executes the "events" command.
+func eventsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("events command accepts no arguments"))
+	}
+	if eventsLimit < 0 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("--limit must not be negative"))
+	}
+	c := mustClientFromCmd(cmd)
+
+	eventsList := []epEvents{}
+	var err error
+	for _, ep := range endpointsFromCluster(cmd) {
+		ctx, cancel := commandCtx(cmd)
+		resp, serr := c.Events(ctx, ep, eventsLimit)
+		cancel()
+		if serr != nil {
+			err = serr
+			fmt.Fprintf(os.Stderr, "Failed to get the events of endpoint %s (%v)\n", ep, serr)
+			continue
+		}
+		eventsList = append(eventsList, epEvents{Ep: ep, Resp: resp})
+	}
+
+	display.Events(eventsList)
+
+	if err != nil {
+		os.Exit(cobrautl.ExitError)
+	}
+}
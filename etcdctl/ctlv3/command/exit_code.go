@@ -0,0 +1,114 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+// etcdErrorExitCodes are the exit codes of the etcd errors.
+var etcdErrorExitCodes = map[error]int{
+	rpctypes.ErrAuthFailed:       cobrautl.ExitAuthFailed,
+	rpctypes.ErrInvalidAuthToken: cobrautl.ExitAuthFailed,
+	rpctypes.ErrUserEmpty:        cobrautl.ExitAuthFailed,
+
+	rpctypes.ErrPermissionDenied: cobrautl.ExitPermissionDenied,
+
+	rpctypes.ErrKeyNotFound:          cobrautl.ExitNotFound,
+	rpctypes.ErrLeaseNotFound:        cobrautl.ExitNotFound,
+	rpctypes.ErrMemberNotFound:       cobrautl.ExitNotFound,
+	rpctypes.ErrUserNotFound:         cobrautl.ExitNotFound,
+	rpctypes.ErrRoleNotFound:         cobrautl.ExitNotFound,
+	rpctypes.ErrRoleNotGranted:       cobrautl.ExitNotFound,
+	rpctypes.ErrPermissionNotGranted: cobrautl.ExitNotFound,
+
+	rpctypes.ErrLeaseExist:       cobrautl.ExitAlreadyExists,
+	rpctypes.ErrMemberExist:      cobrautl.ExitAlreadyExists,
+	rpctypes.ErrPeerURLExist:     cobrautl.ExitAlreadyExists,
+	rpctypes.ErrUserAlreadyExist: cobrautl.ExitAlreadyExists,
+	rpctypes.ErrRoleAlreadyExist: cobrautl.ExitAlreadyExists,
+
+	rpctypes.ErrCompacted: cobrautl.ExitRevisionCompacted,
+	rpctypes.ErrFutureRev: cobrautl.ExitRevisionCompacted,
+
+	rpctypes.ErrNoSpace: cobrautl.ExitNoSpace,
+}
+
+// retryableEtcdErrors are the etcd errors retrying may succeed after.
+var retryableEtcdErrors = map[error]bool{
+	rpctypes.ErrFutureRev:                  true,
+	rpctypes.ErrAuthOldRevision:            true,
+	rpctypes.ErrNoLeader:                   true,
+	rpctypes.ErrNotLeader:                  true,
+	rpctypes.ErrLeaderChanged:              true,
+	rpctypes.ErrStopped:                    true,
+	rpctypes.ErrTimeout:                    true,
+	rpctypes.ErrTimeoutDueToLeaderFail:     true,
+	rpctypes.ErrTimeoutDueToConnectionLost: true,
+	rpctypes.ErrTimeoutWaitAppliedIndex:    true,
+	rpctypes.ErrUnhealthy:                  true,
+	rpctypes.ErrTooManyRequests:            true,
+	rpctypes.ErrTooManyLargeRequests:       true,
+	rpctypes.ErrMemoryBudgetExceeded:       true,
+	rpctypes.ErrConnectionEvicted:          true,
+	rpctypes.ErrMemberNotEnoughStarted:     true,
+	rpctypes.ErrMemberLearnerNotReady:      true,
+}
+
+// ClassifyError classifies the error a command exits with the code. The codes
+// of the generic and connection errors are refined with the etcd error they
+// are caused by, e.g. the authentication failing on connecting, and the other
+// retryable generic errors exit with cobrautl.ExitUnavailable.
+func ClassifyError(code int, err error) cobrautl.ErrorEnvelope {
+	e := cobrautl.ErrorEnvelope{Code: code, Error: err.Error()}
+
+	var ev rpctypes.EtcdError
+	if !errors.As(err, &ev) {
+		ev, _ = rpctypes.Error(err).(rpctypes.EtcdError)
+	}
+	if ev != (rpctypes.EtcdError{}) {
+		e.EtcdError = ev.Error()
+	}
+
+	switch {
+	case e.EtcdError != "":
+		e.Retryable = retryableEtcdErrors[ev]
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Retryable = true
+	default:
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+			e.Retryable = true
+		}
+	}
+
+	switch {
+	case code != cobrautl.ExitError && code != cobrautl.ExitBadConnection:
+	case etcdErrorExitCodes[ev] != 0:
+		e.Code = etcdErrorExitCodes[ev]
+	case code == cobrautl.ExitBadConnection:
+		e.Retryable = true
+	case e.Retryable:
+		e.Code = cobrautl.ExitUnavailable
+	}
+	return e
+}
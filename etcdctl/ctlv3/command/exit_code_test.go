@@ -0,0 +1,112 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		err  error
+		want cobrautl.ErrorEnvelope
+	}{
+		{
+			name: "permission denied",
+			code: cobrautl.ExitError,
+			err:  rpctypes.ErrPermissionDenied,
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitPermissionDenied, Error: "etcdserver: permission denied", EtcdError: "etcdserver: permission denied"},
+		},
+		{
+			name: "grpc status",
+			code: cobrautl.ExitError,
+			err:  rpctypes.ErrGRPCUserNotFound,
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitNotFound, Error: rpctypes.ErrGRPCUserNotFound.Error(), EtcdError: "etcdserver: user name not found"},
+		},
+		{
+			name: "wrapped",
+			code: cobrautl.ExitError,
+			err:  fmt.Errorf("get failed: %w", rpctypes.ErrCompacted),
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitRevisionCompacted, Error: "get failed: etcdserver: mvcc: required revision has been compacted", EtcdError: "etcdserver: mvcc: required revision has been compacted"},
+		},
+		{
+			name: "future revision",
+			code: cobrautl.ExitError,
+			err:  rpctypes.ErrFutureRev,
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitRevisionCompacted, Error: "etcdserver: mvcc: required revision is a future revision", EtcdError: "etcdserver: mvcc: required revision is a future revision", Retryable: true},
+		},
+		{
+			name: "no leader",
+			code: cobrautl.ExitError,
+			err:  rpctypes.ErrNoLeader,
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitUnavailable, Error: "etcdserver: no leader", EtcdError: "etcdserver: no leader", Retryable: true},
+		},
+		{
+			name: "deadline exceeded",
+			code: cobrautl.ExitError,
+			err:  context.DeadlineExceeded,
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitUnavailable, Error: "context deadline exceeded", Retryable: true},
+		},
+		{
+			name: "unavailable",
+			code: cobrautl.ExitError,
+			err:  status.Error(codes.Unavailable, "connection refused"),
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitUnavailable, Error: "rpc error: code = Unavailable desc = connection refused", Retryable: true},
+		},
+		{
+			name: "bad connection",
+			code: cobrautl.ExitBadConnection,
+			err:  errors.New("dial failed"),
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitBadConnection, Error: "dial failed", Retryable: true},
+		},
+		{
+			name: "bad connection by etcd error",
+			code: cobrautl.ExitBadConnection,
+			err:  rpctypes.ErrAuthFailed,
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitAuthFailed, Error: "etcdserver: authentication failed, invalid user ID or password", EtcdError: "etcdserver: authentication failed, invalid user ID or password"},
+		},
+		{
+			name: "specific code",
+			code: cobrautl.ExitBadArgs,
+			err:  rpctypes.ErrPermissionDenied,
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitBadArgs, Error: "etcdserver: permission denied", EtcdError: "etcdserver: permission denied"},
+		},
+		{
+			name: "generic",
+			code: cobrautl.ExitError,
+			err:  errors.New("unexpected"),
+			want: cobrautl.ErrorEnvelope{Code: cobrautl.ExitError, Error: "unexpected"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.code, tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClassifyError() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
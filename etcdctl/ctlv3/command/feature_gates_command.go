@@ -0,0 +1,70 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+// NewFeatureGatesCommand returns the cobra command for "feature-gates".
+func NewFeatureGatesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "feature-gates",
+		Short: "Prints the feature gates of the etcd members with given endpoints",
+		Long: `Prints the feature gates of the etcd members, with their stage, their
+default and whether they are enabled.`,
+		Run: featureGatesCommandFunc,
+	}
+	cmd.Flags().BoolVar(&epClusterEndpoints, "cluster", false, "use all endpoints from the cluster member list")
+	return cmd
+}
+
+type epFeatureGates struct {
+	Ep   string                         `json:"Endpoint"`
+	Resp *clientv3.FeatureGatesResponse `json:"FeatureGates"`
+}
+
+// featureGatesCommandFunc executes the "feature-gates" command.
+func featureGatesCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("feature-gates command accepts no arguments"))
+	}
+	c := mustClientFromCmd(cmd)
+
+	gatesList := []epFeatureGates{}
+	var err error
+	for _, ep := range endpointsFromCluster(cmd) {
+		ctx, cancel := commandCtx(cmd)
+		resp, serr := c.FeatureGates(ctx, ep)
+		cancel()
+		if serr != nil {
+			err = serr
+			fmt.Fprintf(os.Stderr, "Failed to get the feature gates of endpoint %s (%v)\n", ep, serr)
+			continue
+		}
+		gatesList = append(gatesList, epFeatureGates{Ep: ep, Resp: resp})
+	}
+
+	display.FeatureGates(gatesList)
+
+	if err != nil {
+		os.Exit(cobrautl.ExitError)
+	}
+}
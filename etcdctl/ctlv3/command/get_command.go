@@ -28,6 +28,7 @@ var (
 	getLimit       int64
 	getSortOrder   string
 	getSortTarget  string
+	getDescendKey  bool
 	getPrefix      bool
 	getFromKey     bool
 	getRev         int64
@@ -47,6 +48,7 @@ func NewGetCommand() *cobra.Command {
 	cmd.Flags().StringVar(&getConsistency, "consistency", "l", "Linearizable(l) or Serializable(s)")
 	cmd.Flags().StringVar(&getSortOrder, "order", "", "Order of results; ASCEND or DESCEND (ASCEND by default)")
 	cmd.Flags().StringVar(&getSortTarget, "sort-by", "", "Sort target; CREATE, KEY, MODIFY, VALUE, or VERSION")
+	cmd.Flags().BoolVar(&getDescendKey, "descend-key", false, "Get keys in descending key order; same as '--sort-by=KEY --order=DESCEND'")
 	cmd.Flags().Int64Var(&getLimit, "limit", 0, "Maximum number of results")
 	cmd.Flags().BoolVar(&getPrefix, "prefix", false, "Get keys with matching prefix")
 	cmd.Flags().BoolVar(&getFromKey, "from-key", false, "Get keys that are greater than or equal to the given key using byte compare")
@@ -107,6 +109,10 @@ func getGetOp(args []string) (string, []clientv3.OpOption) {
 		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("`--keys-only` and `--count-only` cannot be set at the same time, choose one"))
 	}
 
+	if getDescendKey && (getSortOrder != "" || getSortTarget != "") {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("`--descend-key` cannot be set together with `--order` or `--sort-by`"))
+	}
+
 	opts := []clientv3.OpOption{}
 	switch getConsistency {
 	case "s":
@@ -161,7 +167,11 @@ func getGetOp(args []string) (string, []clientv3.OpOption) {
 		cobrautl.ExitWithError(cobrautl.ExitBadFeature, fmt.Errorf("bad sort target %v", getSortTarget))
 	}
 
-	opts = append(opts, clientv3.WithSort(sortByTarget, sortByOrder))
+	if getDescendKey {
+		opts = append(opts, clientv3.WithDescendKey())
+	} else {
+		opts = append(opts, clientv3.WithSort(sortByTarget, sortByOrder))
+	}
 
 	if getPrefix {
 		if len(key) == 0 {
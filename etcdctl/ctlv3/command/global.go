@@ -58,6 +58,8 @@ type GlobalFlags struct {
 	Password string
 
 	Debug bool
+
+	ExitCodes bool
 }
 
 type discoveryCfg struct {
@@ -0,0 +1,165 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+var (
+	logLevelSubsystem string
+
+	logRangePrefix   bool
+	logRangeFromKey  bool
+	logRangeDuration time.Duration
+)
+
+// NewLogCommand returns the cobra command for "log".
+func NewLogCommand() *cobra.Command {
+	lc := &cobra.Command{
+		Use:   "log <subcommand>",
+		Short: "Runtime logging related commands",
+	}
+
+	lc.PersistentFlags().BoolVar(&epClusterEndpoints, "cluster", false, "use all endpoints from the cluster member list")
+	lc.AddCommand(newLogLevelCommand())
+	lc.AddCommand(newLogRangeCommand())
+
+	return lc
+}
+
+func newLogLevelCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "level [level]",
+		Short: "Sets or prints the log levels of the etcd members with given endpoints",
+		Long: `Sets the log level of the etcd members at runtime, globally or for a subsystem
+with --subsystem, and prints their log levels. The subsystems are the source
+directories of the logs, e.g. "mvcc", "raft" or "v3rpc". The level of a
+subsystem is reset to the global level without a level argument. The levels
+are printed without arguments.`,
+		Run: logLevelCommandFunc,
+	}
+	cmd.Flags().StringVar(&logLevelSubsystem, "subsystem", "", "subsystem whose log level is set")
+	return cmd
+}
+
+func newLogRangeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "range <key> [range_end]",
+		Short: "Logs the requests touching a key range on the etcd members with given endpoints",
+		Long: `Makes the etcd members log at the warn level the requests touching the key range
+for --duration, whatever their log level, and prints the logged ranges. The
+range is no longer logged with a zero --duration.`,
+		Run: logRangeCommandFunc,
+	}
+	cmd.Flags().BoolVar(&logRangePrefix, "prefix", false, "log the requests touching the keys with matching prefix")
+	cmd.Flags().BoolVar(&logRangeFromKey, "from-key", false, "log the requests touching the keys greater than or equal to the given key using byte compare")
+	cmd.Flags().DurationVar(&logRangeDuration, "duration", time.Minute, "how long the requests are logged for")
+	return cmd
+}
+
+type epLogLevel struct {
+	Ep   string                     `json:"Endpoint"`
+	Resp *clientv3.LogLevelResponse `json:"LogLevel"`
+}
+
+type epLogRange struct {
+	Ep   string                     `json:"Endpoint"`
+	Resp *clientv3.LogRangeResponse `json:"LogRange"`
+}
+
+// logLevelCommandFunc executes the "log level" command.
+func logLevelCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("log level command accepts at most one argument as level"))
+	}
+	level := ""
+	if len(args) == 1 {
+		level = args[0]
+	}
+	c := mustClientFromCmd(cmd)
+
+	levels := []epLogLevel{}
+	var err error
+	for _, ep := range endpointsFromCluster(cmd) {
+		ctx, cancel := commandCtx(cmd)
+		resp, serr := c.LogLevel(ctx, ep, level, logLevelSubsystem)
+		cancel()
+		if serr != nil {
+			err = serr
+			fmt.Fprintf(os.Stderr, "Failed to set the log level of endpoint %s (%v)\n", ep, serr)
+			continue
+		}
+		levels = append(levels, epLogLevel{Ep: ep, Resp: resp})
+	}
+
+	display.LogLevel(levels)
+
+	if err != nil {
+		os.Exit(cobrautl.ExitError)
+	}
+}
+
+// logRangeCommandFunc executes the "log range" command.
+func logRangeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("log range command needs one argument as key and an optional argument as range_end"))
+	}
+	if logRangePrefix && logRangeFromKey {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("`--prefix` and `--from-key` cannot be set at the same time, choose one"))
+	}
+	if logRangeDuration < 0 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("--duration must not be negative"))
+	}
+	var opts []clientv3.OpOption
+	switch {
+	case len(args) == 2:
+		if logRangePrefix || logRangeFromKey {
+			cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("too many arguments, only accept one argument when `--prefix` or `--from-key` is set"))
+		}
+		opts = append(opts, clientv3.WithRange(args[1]))
+	case logRangePrefix:
+		opts = append(opts, clientv3.WithPrefix())
+	case logRangeFromKey:
+		opts = append(opts, clientv3.WithFromKey())
+	}
+	c := mustClientFromCmd(cmd)
+
+	ranges := []epLogRange{}
+	var err error
+	for _, ep := range endpointsFromCluster(cmd) {
+		ctx, cancel := commandCtx(cmd)
+		resp, serr := c.LogRange(ctx, ep, args[0], logRangeDuration, opts...)
+		cancel()
+		if serr != nil {
+			err = serr
+			fmt.Fprintf(os.Stderr, "Failed to log the range of endpoint %s (%v)\n", ep, serr)
+			continue
+		}
+		ranges = append(ranges, epLogRange{Ep: ep, Resp: resp})
+	}
+
+	display.LogRange(ranges)
+
+	if err != nil {
+		os.Exit(cobrautl.ExitError)
+	}
+}
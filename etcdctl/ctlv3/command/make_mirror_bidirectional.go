@@ -0,0 +1,284 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/mirror"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+const (
+	// mirrorOriginAnnotation annotates the keys mirrored in bidirectional mode
+	// with the ID of the cluster the change was written to.
+	mirrorOriginAnnotation = "etcd.io/mirror-origin"
+	// mirrorRevisionAnnotation annotates the keys mirrored in bidirectional
+	// mode with the mod revision of the change in the cluster it was written to.
+	mirrorRevisionAnnotation = "etcd.io/mirror-revision"
+
+	conflictPolicyLastWriterWins = "last-writer-wins"
+	conflictPolicySourcePriority = "source-priority"
+)
+
+// mirrorCluster is a cluster mirrored in bidirectional mode.
+type mirrorCluster struct {
+	c *clientv3.Client
+	// id is the ID of the cluster, in hex.
+	id     string
+	prefix string
+	// rev is the revision the cluster is mirrored from.
+	rev int64
+}
+
+func newMirrorCluster(ctx context.Context, c *clientv3.Client, prefix string) (*mirrorCluster, error) {
+	// an empty prefix mirrors the entire key-value space
+	checkPath := prefix
+	if len(checkPath) == 0 {
+		checkPath = "foo"
+	}
+	resp, err := c.Get(ctx, checkPath, clientv3.WithCountOnly())
+	if err != nil {
+		return nil, err
+	}
+	return &mirrorCluster{
+		c:      c,
+		id:     fmt.Sprintf("%x", resp.Header.ClusterId),
+		prefix: prefix,
+		rev:    resp.Header.Revision,
+	}, nil
+}
+
+// mirrorVersion identifies a version of a key by the cluster its change was
+// written to, and the mod revision of the change there.
+type mirrorVersion struct {
+	origin string
+	rev    int64
+}
+
+// versionOf returns the version of the key-value of the cluster with the id.
+func versionOf(kv *mvccpb.KeyValue, id string) mirrorVersion {
+	if origin, ok := kv.Annotations[mirrorOriginAnnotation]; ok {
+		if rev, err := strconv.ParseInt(kv.Annotations[mirrorRevisionAnnotation], 10, 64); err == nil {
+			return mirrorVersion{origin: origin, rev: rev}
+		}
+	}
+	return mirrorVersion{origin: id, rev: kv.ModRevision}
+}
+
+// mirrorDirection mirrors the changes of a cluster to the other one.
+type mirrorDirection struct {
+	from, to *mirrorCluster
+	// fromSource is whether the changes are mirrored from the source cluster.
+	fromSource bool
+	policy     string
+	total      *int64
+}
+
+// makeBidirectionalMirror mirrors the changes of the source and destination
+// clusters to each other, until either direction fails.
+//
+// The keys written by the mirror are annotated with the version of the change
+// mirrored, i.e. the cluster it was written to and its mod revision there, for
+// the mirror not to loop the changes back to the cluster they were written to,
+// and to tell the newer versions of a key written to a cluster apart from the
+// concurrent changes of it written to both clusters. The latter are resolved
+// with the conflict policy.
+func makeBidirectionalMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) error {
+	if mmnodestprefix && len(mmdestprefix) > 0 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, errors.New("`--dest-prefix` and `--no-dest-prefix` cannot be set at the same time, choose one"))
+	}
+	if mmrev != 0 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, errors.New("`--rev` is not supported with `--bidirectional`"))
+	}
+	switch mmconflictPolicy {
+	case conflictPolicyLastWriterWins, conflictPolicySourcePriority:
+	default:
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("unknown `--conflict-policy` %q, expected %q or %q",
+			mmconflictPolicy, conflictPolicyLastWriterWins, conflictPolicySourcePriority))
+	}
+	if !mmnodestprefix && len(mmdestprefix) == 0 {
+		mmdestprefix = mmprefix
+	}
+
+	src, err := newMirrorCluster(ctx, c, mmprefix)
+	if err != nil {
+		return err
+	}
+	dst, err := newMirrorCluster(ctx, dc, mmdestprefix)
+	if err != nil {
+		return err
+	}
+	if src.id == dst.id {
+		return errors.New("the source and destination clusters are the same cluster")
+	}
+
+	total := int64(0)
+	go reportMirrorTotal(&total)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errc := make(chan error, 2)
+	for _, d := range []*mirrorDirection{
+		{from: src, to: dst, fromSource: true, policy: mmconflictPolicy, total: &total},
+		{from: dst, to: src, fromSource: false, policy: mmconflictPolicy, total: &total},
+	} {
+		go func(d *mirrorDirection) { errc <- d.run(ctx) }(d)
+	}
+	return <-errc
+}
+
+// run mirrors the key-values of the cluster at the revision it is mirrored
+// from, then the changes after it.
+func (d *mirrorDirection) run(ctx context.Context) error {
+	s := mirror.NewSyncer(d.from.c, d.from.prefix, d.from.rev)
+	rc, errc := s.SyncBase(ctx)
+	for r := range rc {
+		for _, kv := range r.Kvs {
+			if err := d.put(ctx, kv); err != nil {
+				return err
+			}
+		}
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+
+	wc := d.from.c.Watch(ctx, d.from.prefix, clientv3.WithPrefix(), clientv3.WithRev(d.from.rev+1), clientv3.WithPrevKV())
+	for wr := range wc {
+		if wr.CompactRevision != 0 {
+			return rpctypes.ErrCompacted
+		}
+		if err := wr.Err(); err != nil {
+			return err
+		}
+		for _, ev := range wr.Events {
+			var err error
+			switch ev.Type {
+			case mvccpb.PUT:
+				err = d.put(ctx, ev.Kv)
+			case mvccpb.DELETE:
+				err = d.delete(ctx, ev)
+			default:
+				panic("unexpected event type")
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// put mirrors the key-value put, unless it is the mirror of a change of the
+// other cluster, or the other cluster has a newer version of the key.
+func (d *mirrorDirection) put(ctx context.Context, kv *mvccpb.KeyValue) error {
+	v := versionOf(kv, d.from.id)
+	if v.origin == d.to.id {
+		// mirrored from the other cluster
+		return nil
+	}
+	annotations := make(map[string]string, len(kv.Annotations)+2)
+	for k, val := range kv.Annotations {
+		annotations[k] = val
+	}
+	annotations[mirrorOriginAnnotation] = v.origin
+	annotations[mirrorRevisionAnnotation] = strconv.FormatInt(v.rev, 10)
+
+	key := d.toKey(kv.Key)
+	return d.apply(ctx, key, func(cur *mvccpb.KeyValue) bool {
+		if cur == nil {
+			return true
+		}
+		curv := versionOf(cur, d.to.id)
+		if curv.origin == v.origin {
+			return v.rev > curv.rev
+		}
+		return d.resolve(v.rev, curv.rev)
+	}, clientv3.OpPut(key, string(kv.Value), clientv3.WithAnnotations(annotations)))
+}
+
+// delete mirrors the delete of the key-value, if the other cluster has the
+// version deleted, an older one, or a concurrent one the delete wins over.
+func (d *mirrorDirection) delete(ctx context.Context, ev *clientv3.Event) error {
+	if ev.PrevKv == nil {
+		// the key did not exist
+		return nil
+	}
+	v := versionOf(ev.PrevKv, d.from.id)
+	key := d.toKey(ev.Kv.Key)
+	return d.apply(ctx, key, func(cur *mvccpb.KeyValue) bool {
+		if cur == nil {
+			return false
+		}
+		curv := versionOf(cur, d.to.id)
+		if curv.origin == v.origin {
+			return v.rev >= curv.rev
+		}
+		return d.resolve(ev.Kv.ModRevision, curv.rev)
+	}, clientv3.OpDelete(key))
+}
+
+// resolve returns whether the change at the revision rev of the cluster it is
+// mirrored from wins over the concurrent change of the key at the revision
+// curRev of the other cluster, with the conflict policy.
+func (d *mirrorDirection) resolve(rev, curRev int64) bool {
+	if d.policy == conflictPolicySourcePriority {
+		return d.fromSource
+	}
+	return rev > curRev || (rev == curRev && d.fromSource)
+}
+
+// apply commits op on the key of the other cluster if override returns true
+// for the current key-value of the key, nil if it does not exist, retrying if
+// the key changes meanwhile.
+func (d *mirrorDirection) apply(ctx context.Context, key string, override func(cur *mvccpb.KeyValue) bool, op clientv3.Op) error {
+	for {
+		resp, err := d.to.c.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		var cur *mvccpb.KeyValue
+		cmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+		if len(resp.Kvs) != 0 {
+			cur = resp.Kvs[0]
+			cmp = clientv3.Compare(clientv3.ModRevision(key), "=", cur.ModRevision)
+		}
+		if !override(cur) {
+			return nil
+		}
+		tresp, err := d.to.c.Txn(ctx).If(cmp).Then(op).Commit()
+		if err != nil {
+			return err
+		}
+		if tresp.Succeeded {
+			atomic.AddInt64(d.total, 1)
+			return nil
+		}
+	}
+}
+
+// toKey returns the key of the other cluster the key is mirrored to.
+func (d *mirrorDirection) toKey(key []byte) string {
+	return d.to.prefix + strings.TrimPrefix(string(key), d.from.prefix)
+}
@@ -38,17 +38,19 @@ const (
 )
 
 var (
-	mminsecureTr   bool
-	mmcert         string
-	mmkey          string
-	mmcacert       string
-	mmprefix       string
-	mmdestprefix   string
-	mmuser         string
-	mmpassword     string
-	mmnodestprefix bool
-	mmrev          int64
-	mmmaxTxnOps    uint
+	mminsecureTr     bool
+	mmcert           string
+	mmkey            string
+	mmcacert         string
+	mmprefix         string
+	mmdestprefix     string
+	mmuser           string
+	mmpassword       string
+	mmnodestprefix   bool
+	mmrev            int64
+	mmmaxTxnOps      uint
+	mmbidirectional  bool
+	mmconflictPolicy string
 )
 
 // NewMakeMirrorCommand returns the cobra command for "makeMirror".
@@ -71,6 +73,8 @@ func NewMakeMirrorCommand() *cobra.Command {
 	c.Flags().BoolVar(&mminsecureTr, "dest-insecure-transport", true, "Disable transport security for client connections")
 	c.Flags().StringVar(&mmuser, "dest-user", "", "Destination username[:password] for authentication (prompt if password is not supplied)")
 	c.Flags().StringVar(&mmpassword, "dest-password", "", "Destination password for authentication (if this option is used, --user option shouldn't include password)")
+	c.Flags().BoolVar(&mmbidirectional, "bidirectional", false, "Mirror the changes of the destination cluster back to the source cluster as well")
+	c.Flags().StringVar(&mmconflictPolicy, "conflict-policy", conflictPolicyLastWriterWins, "Policy resolving the changes of a key written to both clusters in bidirectional mode: 'last-writer-wins' (the change with the higher mod revision wins) or 'source-priority' (the change of the source cluster wins)")
 
 	return c
 }
@@ -131,7 +135,12 @@ func makeMirrorCommandFunc(cmd *cobra.Command, args []string) {
 	dc := mustClient(cc)
 	c := mustClientFromCmd(cmd)
 
-	err := makeMirror(context.TODO(), c, dc)
+	var err error
+	if mmbidirectional {
+		err = makeBidirectionalMirror(context.TODO(), c, dc)
+	} else {
+		err = makeMirror(context.TODO(), c, dc)
+	}
 	cobrautl.ExitWithError(cobrautl.ExitError, err)
 }
 
@@ -143,12 +152,7 @@ func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) er
 		cobrautl.ExitWithError(cobrautl.ExitBadArgs, errors.New("`--dest-prefix` and `--no-dest-prefix` cannot be set at the same time, choose one"))
 	}
 
-	go func() {
-		for {
-			time.Sleep(30 * time.Second)
-			fmt.Println(atomic.LoadInt64(&total))
-		}
-	}()
+	go reportMirrorTotal(&total)
 
 	startRev := mmrev - 1
 	if startRev < 0 {
@@ -235,6 +239,14 @@ func makeMirror(ctx context.Context, c *clientv3.Client, dc *clientv3.Client) er
 	return nil
 }
 
+// reportMirrorTotal prints the total number of keys mirrored every 30 seconds.
+func reportMirrorTotal(total *int64) {
+	for {
+		time.Sleep(30 * time.Second)
+		fmt.Println(atomic.LoadInt64(total))
+	}
+}
+
 func modifyPrefix(key string) string {
 	return strings.Replace(key, mmprefix, mmdestprefix, 1)
 }
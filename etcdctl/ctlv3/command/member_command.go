@@ -28,6 +28,8 @@ import (
 var (
 	memberPeerURLs string
 	isLearner      bool
+
+	acknowledgeReducedFaultTolerance bool
 )
 
 // NewMemberCommand returns the cobra command for "member".
@@ -42,6 +44,7 @@ func NewMemberCommand() *cobra.Command {
 	mc.AddCommand(NewMemberUpdateCommand())
 	mc.AddCommand(NewMemberListCommand())
 	mc.AddCommand(NewMemberPromoteCommand())
+	mc.AddCommand(NewMemberDemoteCommand())
 
 	return mc
 }
@@ -116,6 +119,23 @@ func NewMemberPromoteCommand() *cobra.Command {
 	return cc
 }
 
+// NewMemberDemoteCommand returns the cobra command for "member demote".
+func NewMemberDemoteCommand() *cobra.Command {
+	cc := &cobra.Command{
+		Use:   "demote <memberID> [options]",
+		Short: "Demotes a voting member in the cluster",
+		Long: `Demotes a voting member to a non-voting learner one in the cluster, for it to
+leave the quorum during a maintenance. The member rejoins the quorum once promoted.
+`,
+
+		Run: memberDemoteCommandFunc,
+	}
+
+	cc.Flags().BoolVar(&acknowledgeReducedFaultTolerance, "acknowledge-reduced-fault-tolerance", false, "acknowledges the cluster tolerates the failure of fewer members after the demotion")
+
+	return cc
+}
+
 // memberAddCommandFunc executes the "member add" command.
 func memberAddCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) < 1 {
@@ -254,3 +274,23 @@ func memberPromoteCommandFunc(cmd *cobra.Command, args []string) {
 	}
 	display.MemberPromote(id, *resp)
 }
+
+// memberDemoteCommandFunc executes the "member demote" command.
+func memberDemoteCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("member ID is not provided"))
+	}
+
+	id, err := strconv.ParseUint(args[0], 16, 64)
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("bad member ID arg (%v), expecting ID in Hex", err))
+	}
+
+	ctx, cancel := commandCtx(cmd)
+	resp, err := mustClientFromCmd(cmd).MemberDemote(ctx, id, acknowledgeReducedFaultTolerance)
+	cancel()
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError, err)
+	}
+	display.MemberDemote(id, *resp)
+}
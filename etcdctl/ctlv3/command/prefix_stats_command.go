@@ -0,0 +1,72 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+// NewPrefixStatsCommand returns the cobra command for "prefix-stats".
+func NewPrefixStatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prefix-stats",
+		Short: "Prints the statistics of the key prefixes of the etcd members with given endpoints",
+		Long: `Prints the number of the reads and writes served by the etcd members, and
+of their bytes, for each of the key prefixes of their --experimental-prefix-stats,
+counted since the members started. The keys having none of the prefixes are
+counted under the empty prefix.`,
+		Run: prefixStatsCommandFunc,
+	}
+	cmd.Flags().BoolVar(&epClusterEndpoints, "cluster", false, "use all endpoints from the cluster member list")
+	return cmd
+}
+
+type epPrefixStats struct {
+	Ep   string                        `json:"Endpoint"`
+	Resp *clientv3.PrefixStatsResponse `json:"PrefixStats"`
+}
+
+// prefixStatsCommandFunc executes the "prefix-stats" command.
+func prefixStatsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("prefix-stats command accepts no arguments"))
+	}
+	c := mustClientFromCmd(cmd)
+
+	statsList := []epPrefixStats{}
+	var err error
+	for _, ep := range endpointsFromCluster(cmd) {
+		ctx, cancel := commandCtx(cmd)
+		resp, serr := c.PrefixStats(ctx, ep)
+		cancel()
+		if serr != nil {
+			err = serr
+			fmt.Fprintf(os.Stderr, "Failed to get the prefix statistics of endpoint %s (%v)\n", ep, serr)
+			continue
+		}
+		statsList = append(statsList, epPrefixStats{Ep: ep, Resp: resp})
+	}
+
+	display.PrefixStats(statsList)
+
+	if err != nil {
+		os.Exit(cobrautl.ExitError)
+	}
+}
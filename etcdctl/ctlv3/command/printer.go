@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	v3 "go.etcd.io/etcd/client/v3"
@@ -43,11 +44,18 @@ type printer interface {
 	MemberRemove(id uint64, r v3.MemberRemoveResponse)
 	MemberUpdate(id uint64, r v3.MemberUpdateResponse)
 	MemberPromote(id uint64, r v3.MemberPromoteResponse)
+	MemberDemote(id uint64, r v3.MemberDemoteResponse)
 	MemberList(v3.MemberListResponse)
 
 	EndpointHealth([]epHealth)
 	EndpointStatus([]epStatus)
 	EndpointHashKV([]epHashKV)
+	Events([]epEvents)
+	FeatureGates([]epFeatureGates)
+	PrefixStats([]epPrefixStats)
+	LogLevel([]epLogLevel)
+	LogRange([]epLogRange)
+	Doctor([]doctorFinding)
 	MoveLeader(leader, target uint64, r v3.MoveLeaderResponse)
 
 	DowngradeValidate(r v3.DowngradeResponse)
@@ -117,6 +125,9 @@ func (p *printerRPC) MemberUpdate(id uint64, r v3.MemberUpdateResponse) {
 func (p *printerRPC) MemberPromote(id uint64, r v3.MemberPromoteResponse) {
 	p.p((*pb.MemberPromoteResponse)(&r))
 }
+func (p *printerRPC) MemberDemote(id uint64, r v3.MemberDemoteResponse) {
+	p.p((*pb.MemberDemoteResponse)(&r))
+}
 func (p *printerRPC) MemberList(r v3.MemberListResponse) { p.p((*pb.MemberListResponse)(&r)) }
 func (p *printerRPC) Alarm(r v3.AlarmResponse)           { p.p((*pb.AlarmResponse)(&r)) }
 func (p *printerRPC) MoveLeader(leader, target uint64, r v3.MoveLeaderResponse) {
@@ -165,9 +176,15 @@ func newPrinterUnsupported(n string) printer {
 	return &printerUnsupported{printerRPC{nil, f}}
 }
 
-func (p *printerUnsupported) EndpointHealth([]epHealth) { p.p(nil) }
-func (p *printerUnsupported) EndpointStatus([]epStatus) { p.p(nil) }
-func (p *printerUnsupported) EndpointHashKV([]epHashKV) { p.p(nil) }
+func (p *printerUnsupported) EndpointHealth([]epHealth)     { p.p(nil) }
+func (p *printerUnsupported) EndpointStatus([]epStatus)     { p.p(nil) }
+func (p *printerUnsupported) EndpointHashKV([]epHashKV)     { p.p(nil) }
+func (p *printerUnsupported) Events([]epEvents)             { p.p(nil) }
+func (p *printerUnsupported) FeatureGates([]epFeatureGates) { p.p(nil) }
+func (p *printerUnsupported) PrefixStats([]epPrefixStats)   { p.p(nil) }
+func (p *printerUnsupported) LogLevel([]epLogLevel)         { p.p(nil) }
+func (p *printerUnsupported) LogRange([]epLogRange)         { p.p(nil) }
+func (p *printerUnsupported) Doctor([]doctorFinding)        { p.p(nil) }
 
 func (p *printerUnsupported) MoveLeader(leader, target uint64, r v3.MoveLeaderResponse) { p.p(nil) }
 func (p *printerUnsupported) DowngradeValidate(r v3.DowngradeResponse)                  { p.p(nil) }
@@ -242,3 +259,91 @@ func makeEndpointHashKVTable(hashList []epHashKV) (hdr []string, rows [][]string
 	}
 	return hdr, rows
 }
+
+func makeEventsTable(eventsList []epEvents) (hdr []string, rows [][]string) {
+	hdr = []string{"endpoint", "time", "type", "message"}
+	for _, e := range eventsList {
+		for _, ev := range e.Resp.Events {
+			rows = append(rows, []string{
+				e.Ep,
+				time.Unix(0, ev.Time).UTC().Format(time.RFC3339Nano),
+				ev.Type,
+				ev.Message,
+			})
+		}
+	}
+	return hdr, rows
+}
+
+func makeFeatureGatesTable(gatesList []epFeatureGates) (hdr []string, rows [][]string) {
+	hdr = []string{"endpoint", "feature", "enabled", "default", "stage"}
+	for _, g := range gatesList {
+		for _, f := range g.Resp.Features {
+			rows = append(rows, []string{
+				g.Ep,
+				f.Name,
+				fmt.Sprint(f.Enabled),
+				fmt.Sprint(f.Default),
+				f.Stage,
+			})
+		}
+	}
+	return hdr, rows
+}
+
+func makePrefixStatsTable(statsList []epPrefixStats) (hdr []string, rows [][]string) {
+	hdr = []string{"endpoint", "prefix", "reads", "writes", "read bytes", "write bytes"}
+	for _, s := range statsList {
+		for _, st := range s.Resp.Stats {
+			rows = append(rows, []string{
+				s.Ep,
+				string(st.Prefix),
+				fmt.Sprint(st.Reads),
+				fmt.Sprint(st.Writes),
+				humanize.Bytes(uint64(st.ReadBytes)),
+				humanize.Bytes(uint64(st.WriteBytes)),
+			})
+		}
+	}
+	return hdr, rows
+}
+
+func makeLogLevelTable(levels []epLogLevel) (hdr []string, rows [][]string) {
+	hdr = []string{"endpoint", "subsystem", "level"}
+	for _, l := range levels {
+		rows = append(rows, []string{l.Ep, "*", l.Resp.Level})
+		for _, s := range l.Resp.Subsystems {
+			rows = append(rows, []string{l.Ep, s.Subsystem, s.Level})
+		}
+	}
+	return hdr, rows
+}
+
+func makeLogRangeTable(ranges []epLogRange) (hdr []string, rows [][]string) {
+	hdr = []string{"endpoint", "key", "range end", "expire"}
+	for _, r := range ranges {
+		for _, lr := range r.Resp.Ranges {
+			rows = append(rows, []string{
+				r.Ep,
+				string(lr.Key),
+				string(lr.RangeEnd),
+				time.Unix(0, lr.Expire).UTC().Format(time.RFC3339),
+			})
+		}
+	}
+	return hdr, rows
+}
+
+func makeDoctorTable(findings []doctorFinding) (hdr []string, rows [][]string) {
+	hdr = []string{"severity", "check", "endpoint", "finding", "remediation"}
+	for _, f := range findings {
+		rows = append(rows, []string{
+			strings.ToUpper(f.Severity),
+			f.Check,
+			f.Endpoint,
+			f.Message,
+			f.Remediation,
+		})
+	}
+	return hdr, rows
+}
@@ -93,6 +93,8 @@ func (p *fieldsPrinter) Txn(r v3.TxnResponse) {
 			p.Put((v3.PutResponse)(*v.ResponsePut))
 		case *pb.ResponseOp_ResponseRange:
 			p.Get((v3.GetResponse)(*v.ResponseRange))
+		case *pb.ResponseOp_ResponseTxn:
+			p.Txn((v3.TxnResponse)(*v.ResponseTxn))
 		default:
 			fmt.Printf("\"Unknown\" : %q\n", fmt.Sprintf("%+v", v))
 		}
@@ -201,6 +203,8 @@ func (p *fieldsPrinter) EndpointStatus(eps []epStatus) {
 		fmt.Println(`"RaftIndex" :`, ep.Resp.RaftIndex)
 		fmt.Println(`"RaftTerm" :`, ep.Resp.RaftTerm)
 		fmt.Println(`"RaftAppliedIndex" :`, ep.Resp.RaftAppliedIndex)
+		fmt.Println(`"DiskDegraded" :`, ep.Resp.DiskDegraded)
+		fmt.Println(`"MaxClockSkew" :`, ep.Resp.MaxClockSkew)
 		fmt.Println(`"Errors" :`, ep.Resp.Errors)
 		fmt.Printf("\"Endpoint\" : %q\n", ep.Ep)
 		fmt.Println()
@@ -216,6 +220,87 @@ func (p *fieldsPrinter) EndpointHashKV(hs []epHashKV) {
 	}
 }
 
+func (p *fieldsPrinter) Events(es []epEvents) {
+	for _, e := range es {
+		p.hdr(e.Resp.Header)
+		fmt.Printf("\"Endpoint\" : %q\n", e.Ep)
+		for _, ev := range e.Resp.Events {
+			fmt.Println(`"Time" :`, ev.Time)
+			fmt.Printf("\"Type\" : %q\n", ev.Type)
+			fmt.Printf("\"Message\" : %q\n", ev.Message)
+		}
+		fmt.Println()
+	}
+}
+
+func (p *fieldsPrinter) FeatureGates(gs []epFeatureGates) {
+	for _, g := range gs {
+		p.hdr(g.Resp.Header)
+		fmt.Printf("\"Endpoint\" : %q\n", g.Ep)
+		for _, f := range g.Resp.Features {
+			fmt.Printf("\"Name\" : %q\n", f.Name)
+			fmt.Println(`"Enabled" :`, f.Enabled)
+			fmt.Println(`"Default" :`, f.Default)
+			fmt.Printf("\"Stage\" : %q\n", f.Stage)
+			fmt.Println(`"LockToDefault" :`, f.LockToDefault)
+		}
+		fmt.Println()
+	}
+}
+
+func (p *fieldsPrinter) PrefixStats(ss []epPrefixStats) {
+	for _, s := range ss {
+		p.hdr(s.Resp.Header)
+		fmt.Printf("\"Endpoint\" : %q\n", s.Ep)
+		fmt.Println(`"Start" :`, s.Resp.Start)
+		for _, st := range s.Resp.Stats {
+			fmt.Printf("\"Prefix\" : %q\n", string(st.Prefix))
+			fmt.Println(`"Reads" :`, st.Reads)
+			fmt.Println(`"Writes" :`, st.Writes)
+			fmt.Println(`"ReadBytes" :`, st.ReadBytes)
+			fmt.Println(`"WriteBytes" :`, st.WriteBytes)
+		}
+		fmt.Println()
+	}
+}
+
+func (p *fieldsPrinter) LogLevel(ls []epLogLevel) {
+	for _, l := range ls {
+		p.hdr(l.Resp.Header)
+		fmt.Printf("\"Endpoint\" : %q\n", l.Ep)
+		fmt.Printf("\"Level\" : %q\n", l.Resp.Level)
+		for _, s := range l.Resp.Subsystems {
+			fmt.Printf("\"Subsystem\" : %q\n", s.Subsystem)
+			fmt.Printf("\"SubsystemLevel\" : %q\n", s.Level)
+		}
+		fmt.Println()
+	}
+}
+
+func (p *fieldsPrinter) LogRange(rs []epLogRange) {
+	for _, r := range rs {
+		p.hdr(r.Resp.Header)
+		fmt.Printf("\"Endpoint\" : %q\n", r.Ep)
+		for _, lr := range r.Resp.Ranges {
+			fmt.Printf("\"Key\" : %q\n", string(lr.Key))
+			fmt.Printf("\"RangeEnd\" : %q\n", string(lr.RangeEnd))
+			fmt.Println(`"Expire" :`, lr.Expire)
+		}
+		fmt.Println()
+	}
+}
+
+func (p *fieldsPrinter) Doctor(fs []doctorFinding) {
+	for _, f := range fs {
+		fmt.Printf("\"Severity\" : %q\n", f.Severity)
+		fmt.Printf("\"Check\" : %q\n", f.Check)
+		fmt.Printf("\"Endpoint\" : %q\n", f.Endpoint)
+		fmt.Printf("\"Message\" : %q\n", f.Message)
+		fmt.Printf("\"Remediation\" : %q\n", f.Remediation)
+		fmt.Println()
+	}
+}
+
 func (p *fieldsPrinter) Alarm(r v3.AlarmResponse) {
 	p.hdr(r.Header)
 	for _, a := range r.Alarms {
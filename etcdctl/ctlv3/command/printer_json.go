@@ -36,9 +36,15 @@ func newJSONPrinter(isHex bool) printer {
 	}
 }
 
-func (p *jsonPrinter) EndpointHealth(r []epHealth) { printJSON(r) }
-func (p *jsonPrinter) EndpointStatus(r []epStatus) { printJSON(r) }
-func (p *jsonPrinter) EndpointHashKV(r []epHashKV) { printJSON(r) }
+func (p *jsonPrinter) EndpointHealth(r []epHealth)     { printJSON(r) }
+func (p *jsonPrinter) EndpointStatus(r []epStatus)     { printJSON(r) }
+func (p *jsonPrinter) EndpointHashKV(r []epHashKV)     { printJSON(r) }
+func (p *jsonPrinter) Events(r []epEvents)             { printJSON(r) }
+func (p *jsonPrinter) FeatureGates(r []epFeatureGates) { printJSON(r) }
+func (p *jsonPrinter) PrefixStats(r []epPrefixStats)   { printJSON(r) }
+func (p *jsonPrinter) LogLevel(r []epLogLevel)         { printJSON(r) }
+func (p *jsonPrinter) LogRange(r []epLogRange)         { printJSON(r) }
+func (p *jsonPrinter) Doctor(r []doctorFinding)        { printJSON(r) }
 
 func (p *jsonPrinter) MemberList(r clientv3.MemberListResponse) {
 	if p.isHex {
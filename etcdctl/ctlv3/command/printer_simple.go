@@ -67,6 +67,8 @@ func (s *simplePrinter) Txn(resp v3.TxnResponse) {
 			s.Put((v3.PutResponse)(*v.ResponsePut))
 		case *pb.ResponseOp_ResponseRange:
 			s.Get(((v3.GetResponse)(*v.ResponseRange)))
+		case *pb.ResponseOp_ResponseTxn:
+			s.Txn((v3.TxnResponse)(*v.ResponseTxn))
 		default:
 			fmt.Printf("unexpected response %+v\n", r)
 		}
@@ -141,6 +143,10 @@ func (s *simplePrinter) MemberPromote(id uint64, r v3.MemberPromoteResponse) {
 	fmt.Printf("Member %16x promoted in cluster %16x\n", id, r.Header.ClusterId)
 }
 
+func (s *simplePrinter) MemberDemote(id uint64, r v3.MemberDemoteResponse) {
+	fmt.Printf("Member %16x demoted in cluster %16x\n", id, r.Header.ClusterId)
+}
+
 func (s *simplePrinter) MemberList(resp v3.MemberListResponse) {
 	_, rows := makeMemberListTable(resp)
 	for _, row := range rows {
@@ -172,6 +178,58 @@ func (s *simplePrinter) EndpointHashKV(hashList []epHashKV) {
 	}
 }
 
+func (s *simplePrinter) Events(eventsList []epEvents) {
+	_, rows := makeEventsTable(eventsList)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ", "))
+	}
+}
+
+func (s *simplePrinter) FeatureGates(gatesList []epFeatureGates) {
+	_, rows := makeFeatureGatesTable(gatesList)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ", "))
+	}
+}
+
+func (s *simplePrinter) PrefixStats(statsList []epPrefixStats) {
+	_, rows := makePrefixStatsTable(statsList)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ", "))
+	}
+}
+
+func (s *simplePrinter) LogLevel(levels []epLogLevel) {
+	_, rows := makeLogLevelTable(levels)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ", "))
+	}
+}
+
+func (s *simplePrinter) LogRange(ranges []epLogRange) {
+	_, rows := makeLogRangeTable(ranges)
+	for _, row := range rows {
+		fmt.Println(strings.Join(row, ", "))
+	}
+}
+
+func (s *simplePrinter) Doctor(findings []doctorFinding) {
+	if len(findings) == 0 {
+		fmt.Println("No problems found")
+		return
+	}
+	for _, f := range findings {
+		ep := ""
+		if f.Endpoint != "" {
+			ep = f.Endpoint + ": "
+		}
+		fmt.Printf("[%s] %s: %s%s\n", strings.ToUpper(f.Severity), f.Check, ep, f.Message)
+		if f.Remediation != "" {
+			fmt.Printf("  remediation: %s\n", f.Remediation)
+		}
+	}
+}
+
 func (s *simplePrinter) MoveLeader(leader, target uint64, r v3.MoveLeaderResponse) {
 	fmt.Printf("Leadership transferred from %s to %s\n", types.ID(leader), types.ID(target))
 }
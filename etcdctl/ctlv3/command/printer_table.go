@@ -64,3 +64,63 @@ func (tp *tablePrinter) EndpointHashKV(r []epHashKV) {
 	table.SetAlignment(tablewriter.ALIGN_RIGHT)
 	table.Render()
 }
+func (tp *tablePrinter) LogLevel(r []epLogLevel) {
+	hdr, rows := makeLogLevelTable(r)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
+func (tp *tablePrinter) LogRange(r []epLogRange) {
+	hdr, rows := makeLogRangeTable(r)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.Render()
+}
+func (tp *tablePrinter) Doctor(r []doctorFinding) {
+	hdr, rows := makeDoctorTable(r)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+}
+func (tp *tablePrinter) Events(r []epEvents) {
+	hdr, rows := makeEventsTable(r)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+}
+func (tp *tablePrinter) PrefixStats(r []epPrefixStats) {
+	hdr, rows := makePrefixStatsTable(r)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+}
+func (tp *tablePrinter) FeatureGates(r []epFeatureGates) {
+	hdr, rows := makeFeatureGatesTable(r)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hdr)
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+}
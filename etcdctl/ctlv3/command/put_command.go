@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -29,6 +30,7 @@ var (
 	putPrevKV      bool
 	putIgnoreVal   bool
 	putIgnoreLease bool
+	putAnnotations []string
 )
 
 // NewPutCommand returns the cobra command for "put".
@@ -61,6 +63,7 @@ will store the content of the file to <key>.
 	cmd.Flags().BoolVar(&putPrevKV, "prev-kv", false, "return the previous key-value pair before modification")
 	cmd.Flags().BoolVar(&putIgnoreVal, "ignore-value", false, "updates the key using its current value")
 	cmd.Flags().BoolVar(&putIgnoreLease, "ignore-lease", false, "updates the key using its current lease")
+	cmd.Flags().StringArrayVar(&putAnnotations, "annotation", nil, "annotation to attach to the key as name=value, can be given multiple times")
 	return cmd
 }
 
@@ -114,6 +117,17 @@ func getPutOp(args []string) (string, string, []clientv3.OpOption) {
 	if putIgnoreLease {
 		opts = append(opts, clientv3.WithIgnoreLease())
 	}
+	if len(putAnnotations) != 0 {
+		annotations := make(map[string]string, len(putAnnotations))
+		for _, a := range putAnnotations {
+			kv := strings.SplitN(a, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("bad annotation %q, expecting name=value", a))
+			}
+			annotations[kv[0]] = kv[1]
+		}
+		opts = append(opts, clientv3.WithAnnotations(annotations))
+	}
 
 	return key, value, opts
 }
@@ -17,14 +17,18 @@ package command
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/client/pkg/v3/logutil"
 	snapshot "go.etcd.io/etcd/client/v3/snapshot"
+	"go.etcd.io/etcd/client/v3/snapshot/storage"
 	"go.etcd.io/etcd/pkg/v3/cobrautl"
 	"go.uber.org/zap"
 )
 
+var snapshotStorageOptions storage.Options
+
 // NewSnapshotCommand returns the cobra command for "snapshot".
 func NewSnapshotCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -32,15 +36,31 @@ func NewSnapshotCommand() *cobra.Command {
 		Short: "Manages etcd node snapshots",
 	}
 	cmd.AddCommand(NewSnapshotSaveCommand())
+	cmd.AddCommand(NewSnapshotDeltaCommand())
+	cmd.AddCommand(NewSnapshotRestoreClusterCommand())
 	return cmd
 }
 
 func NewSnapshotSaveCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "save <filename>",
+	cmd := &cobra.Command{
+		Use:   "save <filename or object URL>",
 		Short: "Stores an etcd node backend snapshot to a given file",
-		Run:   snapshotSaveCommandFunc,
+		Long: `Stores an etcd node backend snapshot to a given file.
+
+The snapshot is streamed to an object storage instead given the URL of its object: s3://<bucket>/<key> for Amazon S3,
+gs://<bucket>/<name> for Google Cloud Storage or azblob://<container>/<blob> for Azure Blob Storage. Its sha256 digest
+is saved alongside, in the object of the same name followed by ".sha256". The credentials are read from the environment:
+AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN for S3, GOOGLE_OAUTH_ACCESS_TOKEN for GCS, and
+AZURE_STORAGE_ACCOUNT with AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN for Azure.
+`,
+		Run: snapshotSaveCommandFunc,
 	}
+	cmd.Flags().StringVar(&snapshotStorageOptions.Endpoint, "storage-endpoint", "", "Endpoint of the object storage, e.g. of an S3 compatible service (the endpoint of the provider if none given)")
+	cmd.Flags().StringVar(&snapshotStorageOptions.Region, "storage-region", "", "Region of the S3 bucket (AWS_REGION if none given)")
+	cmd.Flags().IntVar(&snapshotStorageOptions.PartSize, "storage-part-size", storage.DefaultPartSize, "Size in bytes of the parts the snapshot is uploaded in")
+	cmd.Flags().StringVar(&snapshotStorageOptions.ServerSideEncryption, "storage-sse", "", "Server-side encryption of the S3 object, 'AES256' or 'aws:kms'")
+	cmd.Flags().StringVar(&snapshotStorageOptions.EncryptionKey, "storage-encryption-key", "", "Key of the server-side encryption: the KMS key ID of S3 'aws:kms', the Cloud KMS key name of GCS or the encryption scope of Azure")
+	return cmd
 }
 
 func snapshotSaveCommandFunc(cmd *cobra.Command, args []string) {
@@ -63,7 +83,18 @@ func snapshotSaveCommandFunc(cmd *cobra.Command, args []string) {
 	defer cancel()
 
 	path := args[0]
-	version, err := snapshot.SaveWithVersion(ctx, lg, *cfg, path)
+	var version string
+	if storage.IsURL(path) {
+		var st storage.Storage
+		var name string
+		st, name, err = storage.Open(path, snapshotStorageOptions)
+		if err != nil {
+			cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
+		}
+		version, err = snapshot.SaveToStorage(ctx, lg, *cfg, st, name)
+	} else {
+		version, err = snapshot.SaveWithVersion(ctx, lg, *cfg, path)
+	}
 	if err != nil {
 		cobrautl.ExitWithError(cobrautl.ExitInterrupted, err)
 	}
@@ -72,3 +103,46 @@ func snapshotSaveCommandFunc(cmd *cobra.Command, args []string) {
 		fmt.Printf("Server version %s\n", version)
 	}
 }
+
+func NewSnapshotDeltaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delta <since-revision> <filename>",
+		Short: "Stores the changes of an etcd node key-value store after a given revision to a given file",
+		Run:   snapshotDeltaCommandFunc,
+	}
+}
+
+func snapshotDeltaCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		err := fmt.Errorf("snapshot delta expects two arguments")
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
+	}
+	sinceRev, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("bad since-revision %q (%v)", args[0], err))
+	}
+
+	lg, err := logutil.CreateDefaultZapLogger(zap.InfoLevel)
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError, err)
+	}
+	cfg := mustClientCfgFromCmd(cmd)
+
+	// if user does not specify "--command-timeout" flag, there will be no timeout for snapshot delta command
+	ctx, cancel := context.WithCancel(context.Background())
+	if isCommandTimeoutFlagSet(cmd) {
+		ctx, cancel = commandCtx(cmd)
+	}
+	defer cancel()
+
+	path := args[1]
+	manifest, err := snapshot.SaveDelta(ctx, lg, *cfg, sinceRev, path)
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitInterrupted, err)
+	}
+	fmt.Printf("Snapshot delta saved at %s\n", path)
+	fmt.Printf("Revisions %d to %d, %d events\n", manifest.SinceRevision+1, manifest.Revision, manifest.Events)
+	if manifest.Version != "" {
+		fmt.Printf("Server version %s\n", manifest.Version)
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+)
+
+var (
+	restoreClusterMembers       string
+	restoreClusterToken         string
+	restoreClusterDataDir       string
+	restoreClusterWalDir        string
+	restoreClusterRunner        string
+	restoreClusterEtcdutl       string
+	restoreClusterSSHHosts      string
+	restoreClusterSSHOptions    []string
+	restoreClusterSkipHashCheck bool
+	restoreClusterDryRun        bool
+)
+
+// NewSnapshotRestoreClusterCommand returns the cobra command for "snapshot restore-cluster".
+func NewSnapshotRestoreClusterCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore-cluster <filename> --members <name>=<peer-url>,...",
+		Short: "Restores an etcd member snapshot on all the members of a new cluster",
+		Long: `Restores an etcd member snapshot on all the members of a new cluster.
+
+The members are restored by running "etcdutl snapshot restore" on their hosts, over ssh by default, all with the same
+initial cluster configuration. The data directories are checked not to exist on all the hosts before restoring any
+member, and the restored members are removed again if the restore of a member fails.
+
+The hosts of the members are the hosts of their first peer URLs, unless given by --ssh-hosts. The "{name}" in the
+data and WAL directories is replaced by the names of the members.
+`,
+		Run: snapshotRestoreClusterCommandFunc,
+	}
+	cmd.Flags().StringVar(&restoreClusterMembers, "members", "", "Members of the restored cluster as <name>=<peer-url>, the initial cluster configuration")
+	cmd.Flags().StringVar(&restoreClusterToken, "initial-cluster-token", "etcd-cluster", "Initial cluster token for the restored cluster")
+	cmd.Flags().StringVar(&restoreClusterDataDir, "data-dir", "{name}.etcd", "Path to the data directories of the members on their hosts")
+	cmd.Flags().StringVar(&restoreClusterWalDir, "wal-dir", "", "Path to the WAL directories of the members on their hosts (use --data-dir if none given)")
+	cmd.Flags().StringVar(&restoreClusterRunner, "runner", "ssh", "How to run the restore of the members, 'ssh' on their hosts or 'local' on this host")
+	cmd.Flags().StringVar(&restoreClusterEtcdutl, "etcdutl", "etcdutl", "Path to the etcdutl binary on the hosts of the members")
+	cmd.Flags().StringVar(&restoreClusterSSHHosts, "ssh-hosts", "", "SSH hosts of the members as <name>=[<user>@]<host>, the hosts of their peer URLs by default")
+	cmd.Flags().StringArrayVar(&restoreClusterSSHOptions, "ssh-option", nil, "Option passed to ssh and scp with -o, can be given multiple times")
+	cmd.Flags().BoolVar(&restoreClusterSkipHashCheck, "skip-hash-check", false, "Ignore snapshot integrity hash value (required if copied from data directory)")
+	cmd.Flags().BoolVar(&restoreClusterDryRun, "dry-run", false, "Print the commands restoring the members instead of running them")
+	return cmd
+}
+
+func snapshotRestoreClusterCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		err := fmt.Errorf("snapshot restore-cluster requires exactly one argument")
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
+	}
+	if _, err := os.Stat(args[0]); err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
+	}
+	members, err := restoreClusterMembersFromFlags()
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
+	}
+
+	var r restoreRunner
+	switch restoreClusterRunner {
+	case "ssh":
+		r = sshRunner{options: restoreClusterSSHOptions}
+	case "local":
+		r = localRunner{}
+	default:
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("unknown runner %q, want 'ssh' or 'local'", restoreClusterRunner))
+	}
+	run := execCommand
+	if restoreClusterDryRun {
+		run = printCommand(os.Stdout)
+	}
+
+	// if user does not specify "--command-timeout" flag, there will be no timeout for snapshot restore-cluster command
+	ctx, cancel := context.WithCancel(context.Background())
+	if isCommandTimeoutFlagSet(cmd) {
+		ctx, cancel = commandCtx(cmd)
+	}
+	defer cancel()
+
+	rc := &clusterRestore{
+		runner:        r,
+		exec:          run,
+		members:       members,
+		snapshot:      args[0],
+		etcdutl:       restoreClusterEtcdutl,
+		token:         restoreClusterToken,
+		skipHashCheck: restoreClusterSkipHashCheck,
+		out:           os.Stdout,
+	}
+	if err := rc.restore(ctx); err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError, err)
+	}
+}
+
+// restoreMember is a member of the restored cluster.
+type restoreMember struct {
+	name     string
+	peerURLs []string
+	// host is where the member is restored, the ssh destination.
+	host    string
+	dataDir string
+	walDir  string
+}
+
+func restoreClusterMembersFromFlags() ([]restoreMember, error) {
+	if restoreClusterMembers == "" {
+		return nil, fmt.Errorf("--members is required")
+	}
+	urlsmap, err := types.NewURLsMap(restoreClusterMembers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --members %q (%v)", restoreClusterMembers, err)
+	}
+	hosts := make(map[string]string)
+	if restoreClusterSSHHosts != "" {
+		for _, h := range strings.Split(restoreClusterSSHHosts, ",") {
+			kv := strings.SplitN(h, "=", 2)
+			if len(kv) != 2 || kv[1] == "" {
+				return nil, fmt.Errorf("invalid --ssh-hosts %q, want <name>=[<user>@]<host>", h)
+			}
+			if _, ok := urlsmap[kv[0]]; !ok {
+				return nil, fmt.Errorf("invalid --ssh-hosts %q, %q is not a member", h, kv[0])
+			}
+			hosts[kv[0]] = kv[1]
+		}
+	}
+	if restoreClusterRunner == "local" && urlsmap.Len() > 1 && !strings.Contains(restoreClusterDataDir, "{name}") {
+		return nil, fmt.Errorf("--data-dir %q must contain {name} to restore several members on this host", restoreClusterDataDir)
+	}
+
+	var members []restoreMember
+	for name, urls := range urlsmap {
+		m := restoreMember{
+			name:     name,
+			peerURLs: urls.StringSlice(),
+			host:     hosts[name],
+			dataDir:  strings.ReplaceAll(restoreClusterDataDir, "{name}", name),
+			walDir:   strings.ReplaceAll(restoreClusterWalDir, "{name}", name),
+		}
+		if m.host == "" {
+			m.host = urls[0].Hostname()
+		}
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].name < members[j].name })
+	return members, nil
+}
+
+// clusterRestore restores a snapshot on all the members of a cluster.
+type clusterRestore struct {
+	runner        restoreRunner
+	exec          func(ctx context.Context, args []string) error
+	members       []restoreMember
+	snapshot      string
+	etcdutl       string
+	token         string
+	skipHashCheck bool
+	out           io.Writer
+}
+
+func (rc *clusterRestore) initialCluster() string {
+	var ic []string
+	for _, m := range rc.members {
+		for _, u := range m.peerURLs {
+			ic = append(ic, m.name+"="+u)
+		}
+	}
+	return strings.Join(ic, ",")
+}
+
+// snapshotPath is where the snapshot is copied to on the host of the member.
+func (rc *clusterRestore) snapshotPath(m restoreMember) string {
+	return strings.TrimSuffix(m.dataDir, "/") + ".snapshot"
+}
+
+// restore checks all the members can be restored, then copies the snapshot
+// to the members and restores them, removing the restored members again on a
+// failure so that the restore can be retried.
+func (rc *clusterRestore) restore(ctx context.Context) error {
+	for _, m := range rc.members {
+		fmt.Fprintf(rc.out, "Checking member %s on %s...\n", m.name, m.host)
+		if err := rc.run(ctx, m, rc.etcdutl, "version"); err != nil {
+			return fmt.Errorf("etcdutl cannot be run for member %s on %s (%v)", m.name, m.host, err)
+		}
+		for _, dir := range []string{m.dataDir, m.walDir} {
+			if dir == "" {
+				continue
+			}
+			if err := rc.run(ctx, m, "test", "!", "-e", dir); err != nil {
+				return fmt.Errorf("%s of member %s on %s already exists or cannot be checked (%v)", dir, m.name, m.host, err)
+			}
+		}
+	}
+
+	defer func() {
+		for _, m := range rc.members {
+			if err := rc.run(ctx, m, "rm", "-f", rc.snapshotPath(m)); err != nil {
+				fmt.Fprintf(rc.out, "Failed to remove the snapshot %s of member %s on %s (%v)\n", rc.snapshotPath(m), m.name, m.host, err)
+			}
+		}
+	}()
+	for _, m := range rc.members {
+		fmt.Fprintf(rc.out, "Copying the snapshot to member %s on %s...\n", m.name, m.host)
+		if err := rc.exec(ctx, rc.runner.copyCommand(m, rc.snapshot, rc.snapshotPath(m))); err != nil {
+			return fmt.Errorf("failed to copy the snapshot to member %s on %s (%v)", m.name, m.host, err)
+		}
+	}
+
+	for i, m := range rc.members {
+		fmt.Fprintf(rc.out, "Restoring member %s on %s...\n", m.name, m.host)
+		if err := rc.run(ctx, m, rc.restoreArgs(m)...); err != nil {
+			rc.rollback(ctx, rc.members[:i+1])
+			return fmt.Errorf("failed to restore member %s on %s (%v)", m.name, m.host, err)
+		}
+	}
+
+	fmt.Fprintf(rc.out, "Restored the snapshot on %d members, start them with the flags:\n", len(rc.members))
+	for _, m := range rc.members {
+		fmt.Fprintf(rc.out, "%s: %s\n", m.name, strings.Join(rc.startFlags(m), " "))
+	}
+	return nil
+}
+
+func (rc *clusterRestore) run(ctx context.Context, m restoreMember, args ...string) error {
+	return rc.exec(ctx, rc.runner.command(m, args...))
+}
+
+func (rc *clusterRestore) restoreArgs(m restoreMember) []string {
+	args := []string{
+		rc.etcdutl, "snapshot", "restore", rc.snapshotPath(m),
+		"--name", m.name,
+		"--initial-cluster", rc.initialCluster(),
+		"--initial-cluster-token", rc.token,
+		"--initial-advertise-peer-urls", strings.Join(m.peerURLs, ","),
+		"--data-dir", m.dataDir,
+	}
+	if m.walDir != "" {
+		args = append(args, "--wal-dir", m.walDir)
+	}
+	if rc.skipHashCheck {
+		args = append(args, "--skip-hash-check")
+	}
+	return args
+}
+
+func (rc *clusterRestore) startFlags(m restoreMember) []string {
+	flags := []string{
+		"--name", m.name,
+		"--data-dir", m.dataDir,
+	}
+	if m.walDir != "" {
+		flags = append(flags, "--wal-dir", m.walDir)
+	}
+	return append(flags,
+		"--initial-cluster", rc.initialCluster(),
+		"--initial-cluster-token", rc.token,
+		"--initial-advertise-peer-urls", strings.Join(m.peerURLs, ","),
+	)
+}
+
+// rollback removes the directories of the members, checked not to exist
+// before the restore.
+func (rc *clusterRestore) rollback(ctx context.Context, members []restoreMember) {
+	for _, m := range members {
+		args := []string{"rm", "-rf", m.dataDir}
+		if m.walDir != "" {
+			args = append(args, m.walDir)
+		}
+		if err := rc.run(ctx, m, args...); err != nil {
+			fmt.Fprintf(rc.out, "Failed to remove the restored member %s on %s, remove %s manually (%v)\n", m.name, m.host, strings.Join(args[2:], " "), err)
+		}
+	}
+}
+
+// restoreRunner gives the commands restoring the members on their hosts.
+type restoreRunner interface {
+	// copyCommand copies the local file src to dst on the host of the member.
+	copyCommand(m restoreMember, src, dst string) []string
+	// command runs args on the host of the member.
+	command(m restoreMember, args ...string) []string
+}
+
+// sshRunner restores the members on their hosts with ssh and scp.
+type sshRunner struct {
+	options []string
+}
+
+func (r sshRunner) optionArgs() []string {
+	var args []string
+	for _, o := range r.options {
+		args = append(args, "-o", o)
+	}
+	return args
+}
+
+func (r sshRunner) copyCommand(m restoreMember, src, dst string) []string {
+	return append(append([]string{"scp"}, r.optionArgs()...), src, scpHost(m.host)+":"+dst)
+}
+
+func (r sshRunner) command(m restoreMember, args ...string) []string {
+	return append(append([]string{"ssh"}, r.optionArgs()...), m.host, shellJoin(args))
+}
+
+// scpHost brackets the IPv6 addresses, which scp would split at a colon.
+func scpHost(host string) string {
+	user, h := "", host
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		user, h = host[:i+1], host[i+1:]
+	}
+	if strings.Contains(h, ":") {
+		h = "[" + h + "]"
+	}
+	return user + h
+}
+
+// localRunner restores all the members on this host.
+type localRunner struct{}
+
+func (localRunner) copyCommand(_ restoreMember, src, dst string) []string {
+	return []string{"cp", src, dst}
+}
+
+func (localRunner) command(_ restoreMember, args ...string) []string {
+	return args
+}
+
+// execCommand runs the command, its output written to stderr.
+func execCommand(ctx context.Context, args []string) error {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	return cmd.Run()
+}
+
+// printCommand prints the command instead of running it.
+func printCommand(out io.Writer) func(context.Context, []string) error {
+	return func(_ context.Context, args []string) error {
+		_, err := fmt.Fprintln(out, shellJoin(args))
+		return err
+	}
+}
+
+// shellJoin joins the args quoted for a POSIX shell.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes s for a POSIX shell, unless it only has safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%_+=:,./-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
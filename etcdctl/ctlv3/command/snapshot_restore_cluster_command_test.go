@@ -0,0 +1,135 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClusterRestore(t *testing.T) {
+	members := []restoreMember{
+		{name: "m1", peerURLs: []string{"http://10.0.0.1:2380"}, host: "root@a", dataDir: "/var/lib/etcd/m1"},
+		{name: "m2", peerURLs: []string{"http://10.0.0.2:2380"}, host: "10.0.0.2", dataDir: "/var/lib/etcd/m2"},
+	}
+	ic := "m1=http://10.0.0.1:2380,m2=http://10.0.0.2:2380"
+	ssh := func(host, command string) string {
+		return "ssh -o BatchMode=yes " + host + " " + shellQuote(command)
+	}
+	restore := func(host, name, ep string) string {
+		return ssh(host, "etcdutl snapshot restore /var/lib/etcd/"+name+".snapshot --name "+name+
+			" --initial-cluster "+ic+" --initial-cluster-token tok --initial-advertise-peer-urls "+ep+
+			" --data-dir /var/lib/etcd/"+name)
+	}
+	check := func(host, name string) []string {
+		return []string{ssh(host, "etcdutl version"), ssh(host, "test '!' -e /var/lib/etcd/"+name)}
+	}
+	checks := append(check("root@a", "m1"), check("10.0.0.2", "m2")...)
+
+	copies := []string{
+		"scp -o BatchMode=yes db root@a:/var/lib/etcd/m1.snapshot",
+		"scp -o BatchMode=yes db 10.0.0.2:/var/lib/etcd/m2.snapshot",
+	}
+	restores := []string{
+		restore("root@a", "m1", "http://10.0.0.1:2380"),
+		restore("10.0.0.2", "m2", "http://10.0.0.2:2380"),
+	}
+	cleanups := []string{
+		ssh("root@a", "rm -f /var/lib/etcd/m1.snapshot"),
+		ssh("10.0.0.2", "rm -f /var/lib/etcd/m2.snapshot"),
+	}
+	concat := func(css ...[]string) (cs []string) {
+		for _, c := range css {
+			cs = append(cs, c...)
+		}
+		return cs
+	}
+
+	tests := []struct {
+		name string
+		// fail is the command failing
+		fail      string
+		wcommands []string
+		werr      bool
+	}{
+		{
+			name:      "restored",
+			wcommands: concat(checks, copies, restores, cleanups),
+		},
+		{
+			name:      "data dir exists",
+			fail:      checks[3],
+			wcommands: checks,
+			werr:      true,
+		},
+		{
+			name: "restore failed",
+			fail: restores[1],
+			wcommands: concat(checks, copies, restores, []string{
+				ssh("root@a", "rm -rf /var/lib/etcd/m1"),
+				ssh("10.0.0.2", "rm -rf /var/lib/etcd/m2"),
+			}, cleanups),
+			werr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commands []string
+			rc := &clusterRestore{
+				runner: sshRunner{options: []string{"BatchMode=yes"}},
+				exec: func(_ context.Context, args []string) error {
+					c := shellJoin(args)
+					commands = append(commands, c)
+					if c == tt.fail {
+						return errors.New("failed")
+					}
+					return nil
+				},
+				members:  members,
+				snapshot: "db",
+				etcdutl:  "etcdutl",
+				token:    "tok",
+				out:      io.Discard,
+			}
+			err := rc.restore(context.TODO())
+			if (err != nil) != tt.werr {
+				t.Fatalf("error = %v, want error %v", err, tt.werr)
+			}
+			if !reflect.DeepEqual(commands, tt.wcommands) {
+				t.Errorf("commands =\n%s\nwant\n%s", strings.Join(commands, "\n"), strings.Join(tt.wcommands, "\n"))
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":                   "''",
+		"a=http://b:1,c/d.e": "a=http://b:1,c/d.e",
+		"a b":                "'a b'",
+		"it's":               `'it'\''s'`,
+		"http://[::1]:2380":  "'http://[::1]:2380'",
+		"$(rm -rf /)":        "'$(rm -rf /)'",
+	}
+	for s, want := range tests {
+		if got := shellQuote(s); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -29,7 +30,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var txnInteractive bool
+var (
+	txnInteractive bool
+	txnFile        string
+)
 
 // NewTxnCommand returns the cobra command for "txn".
 func NewTxnCommand() *cobra.Command {
@@ -39,6 +43,7 @@ func NewTxnCommand() *cobra.Command {
 		Run:   txnCommandFunc,
 	}
 	cmd.Flags().BoolVarP(&txnInteractive, "interactive", "i", false, "Input transaction in interactive mode")
+	cmd.Flags().StringVar(&txnFile, "file", "", "Read the transaction from a JSON or YAML document file, '-' for the standard input")
 	return cmd
 }
 
@@ -48,6 +53,14 @@ func txnCommandFunc(cmd *cobra.Command, args []string) {
 		cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("txn command does not accept argument"))
 	}
 
+	if txnFile != "" {
+		if txnInteractive {
+			cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("--file and --interactive are exclusive"))
+		}
+		txnDocumentCommandFunc(cmd)
+		return
+	}
+
 	reader := bufio.NewReader(os.Stdin)
 
 	txn := mustClientFromCmd(cmd).Txn(context.Background())
@@ -66,6 +79,37 @@ func txnCommandFunc(cmd *cobra.Command, args []string) {
 	display.Txn(*resp)
 }
 
+// txnDocumentCommandFunc commits the transaction of the document of txnFile.
+func txnDocumentCommandFunc(cmd *cobra.Command) {
+	var (
+		data []byte
+		err  error
+	)
+	if txnFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(txnFile)
+	}
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitInvalidInput, err)
+	}
+	doc, err := parseTxnDocument(data)
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitInvalidInput, err)
+	}
+	cmps, thenOps, elseOps, err := doc.ops()
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitInvalidInput, fmt.Errorf("invalid txn document: %v", err))
+	}
+
+	resp, err := mustClientFromCmd(cmd).Txn(context.Background()).If(cmps...).Then(thenOps...).Else(elseOps...).Commit()
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError, err)
+	}
+
+	display.Txn(*resp)
+}
+
 func promptInteractive(s string) {
 	if txnInteractive {
 		fmt.Println(s)
@@ -0,0 +1,318 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"sigs.k8s.io/yaml"
+)
+
+// txnDocument is a transaction described by a JSON or YAML document.
+type txnDocument struct {
+	Compare []txnCompare `json:"compare"`
+	Success []txnOp      `json:"success"`
+	Failure []txnOp      `json:"failure"`
+}
+
+// txnCompare compares the target of a key, e.g.
+// {"key": "k", "target": "mod", "result": ">", "value": 0}.
+type txnCompare struct {
+	Key string `json:"key"`
+	// Target is one of version, create, mod, value and lease, or their
+	// short forms of the txn input format. The lease IDs are in hex.
+	Target string `json:"target"`
+	// Result is one of =, !=, > and <.
+	Result string   `json:"result"`
+	Value  txnValue `json:"value"`
+}
+
+// txnValue is a string or a number.
+type txnValue string
+
+func (v *txnValue) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*v = txnValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("compare value must be a string or a number, got %s", data)
+	}
+	*v = txnValue(n)
+	return nil
+}
+
+// txnOp is a request of a transaction, exactly one of its fields being set.
+type txnOp struct {
+	Get    *txnRange    `json:"get,omitempty"`
+	Put    *txnPut      `json:"put,omitempty"`
+	Delete *txnRange    `json:"delete,omitempty"`
+	Txn    *txnDocument `json:"txn,omitempty"`
+}
+
+// txnRange is the range of keys of a get or a delete request, with the
+// options of the get and del commands.
+type txnRange struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"rangeEnd,omitempty"`
+	Prefix   bool   `json:"prefix,omitempty"`
+	FromKey  bool   `json:"fromKey,omitempty"`
+	// PrevKV returns the deleted key-value pairs, for the delete requests.
+	PrevKV bool `json:"prevKv,omitempty"`
+
+	// the options of the get requests
+	Limit        int64  `json:"limit,omitempty"`
+	Rev          int64  `json:"rev,omitempty"`
+	SortOrder    string `json:"sortOrder,omitempty"`
+	SortTarget   string `json:"sortTarget,omitempty"`
+	Serializable bool   `json:"serializable,omitempty"`
+	KeysOnly     bool   `json:"keysOnly,omitempty"`
+	CountOnly    bool   `json:"countOnly,omitempty"`
+}
+
+// txnPut is a put request, with the options of the put command.
+type txnPut struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Lease is the lease ID in hex.
+	Lease       string `json:"lease,omitempty"`
+	PrevKV      bool   `json:"prevKv,omitempty"`
+	IgnoreValue bool   `json:"ignoreValue,omitempty"`
+	IgnoreLease bool   `json:"ignoreLease,omitempty"`
+}
+
+// parseTxnDocument parses the JSON or YAML transaction document, rejecting
+// the unknown fields.
+func parseTxnDocument(data []byte) (*txnDocument, error) {
+	js, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid txn document: %v", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.DisallowUnknownFields()
+	var doc txnDocument
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid txn document: %v", err)
+	}
+	return &doc, nil
+}
+
+// ops returns the compares, and the success and failure requests of the
+// transaction.
+func (doc *txnDocument) ops() (cmps []clientv3.Cmp, thenOps, elseOps []clientv3.Op, err error) {
+	for i, c := range doc.Compare {
+		cmp, err := c.cmp()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("compare #%d: %v", i, err)
+		}
+		cmps = append(cmps, cmp)
+	}
+	if thenOps, err = txnOps(doc.Success); err != nil {
+		return nil, nil, nil, fmt.Errorf("success %v", err)
+	}
+	if elseOps, err = txnOps(doc.Failure); err != nil {
+		return nil, nil, nil, fmt.Errorf("failure %v", err)
+	}
+	return cmps, thenOps, elseOps, nil
+}
+
+func txnOps(tops []txnOp) (ops []clientv3.Op, err error) {
+	for i, top := range tops {
+		op, err := top.op()
+		if err != nil {
+			return nil, fmt.Errorf("request #%d: %v", i, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func (c txnCompare) cmp() (clientv3.Cmp, error) {
+	switch c.Result {
+	case "=", "!=", ">", "<":
+	default:
+		return clientv3.Cmp{}, fmt.Errorf("unknown result %q", c.Result)
+	}
+	switch c.Target {
+	case "val", "value":
+		return clientv3.Compare(clientv3.Value(c.Key), c.Result, string(c.Value)), nil
+	case "lease":
+		// the lease ID in hex, as by the put requests
+		id, err := strconv.ParseInt(string(c.Value), 16, 64)
+		if err != nil {
+			return clientv3.Cmp{}, fmt.Errorf("bad lease ID (%v), expecting ID in Hex", err)
+		}
+		return clientv3.Compare(clientv3.LeaseValue(c.Key), c.Result, id), nil
+	}
+	v, err := strconv.ParseInt(string(c.Value), 10, 64)
+	if err != nil {
+		return clientv3.Cmp{}, fmt.Errorf("invalid %s %q", c.Target, c.Value)
+	}
+	switch c.Target {
+	case "ver", "version":
+		return clientv3.Compare(clientv3.Version(c.Key), c.Result, v), nil
+	case "c", "create":
+		return clientv3.Compare(clientv3.CreateRevision(c.Key), c.Result, v), nil
+	case "m", "mod":
+		return clientv3.Compare(clientv3.ModRevision(c.Key), c.Result, v), nil
+	}
+	return clientv3.Cmp{}, fmt.Errorf("unknown target %q", c.Target)
+}
+
+func (top txnOp) op() (clientv3.Op, error) {
+	var (
+		op clientv3.Op
+		n  int
+	)
+	if top.Get != nil {
+		opts, err := top.Get.getOptions()
+		if err != nil {
+			return op, err
+		}
+		op, n = clientv3.OpGet(top.Get.Key, opts...), n+1
+	}
+	if top.Put != nil {
+		opts, err := top.Put.options()
+		if err != nil {
+			return op, err
+		}
+		op, n = clientv3.OpPut(top.Put.Key, top.Put.Value, opts...), n+1
+	}
+	if top.Delete != nil {
+		opts, err := top.Delete.rangeOptions()
+		if err != nil {
+			return op, err
+		}
+		if d := top.Delete; d.Limit != 0 || d.Rev != 0 || d.SortOrder != "" || d.SortTarget != "" || d.Serializable || d.KeysOnly || d.CountOnly {
+			return op, fmt.Errorf("limit, rev, sortOrder, sortTarget, serializable, keysOnly and countOnly are options of get")
+		}
+		if top.Delete.PrevKV {
+			opts = append(opts, clientv3.WithPrevKV())
+		}
+		op, n = clientv3.OpDelete(top.Delete.Key, opts...), n+1
+	}
+	if top.Txn != nil {
+		cmps, thenOps, elseOps, err := top.Txn.ops()
+		if err != nil {
+			return op, fmt.Errorf("txn %v", err)
+		}
+		op, n = clientv3.OpTxn(cmps, thenOps, elseOps), n+1
+	}
+	if n != 1 {
+		return op, fmt.Errorf("want exactly one of get, put, delete and txn, got %d", n)
+	}
+	return op, nil
+}
+
+func (r *txnRange) rangeOptions() ([]clientv3.OpOption, error) {
+	var opts []clientv3.OpOption
+	n := 0
+	if r.RangeEnd != "" {
+		opts, n = append(opts, clientv3.WithRange(r.RangeEnd)), n+1
+	}
+	if r.Prefix {
+		opts, n = append(opts, clientv3.WithPrefix()), n+1
+	}
+	if r.FromKey {
+		opts, n = append(opts, clientv3.WithFromKey()), n+1
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("rangeEnd, prefix and fromKey are exclusive")
+	}
+	return opts, nil
+}
+
+func (r *txnRange) getOptions() ([]clientv3.OpOption, error) {
+	opts, err := r.rangeOptions()
+	if err != nil {
+		return nil, err
+	}
+	if r.PrevKV {
+		return nil, fmt.Errorf("prevKv is not an option of get")
+	}
+	if r.Limit != 0 {
+		opts = append(opts, clientv3.WithLimit(r.Limit))
+	}
+	if r.Rev != 0 {
+		opts = append(opts, clientv3.WithRev(r.Rev))
+	}
+	if r.SortOrder != "" || r.SortTarget != "" {
+		order := clientv3.SortAscend
+		switch strings.ToUpper(r.SortOrder) {
+		case "", "ASCEND":
+		case "DESCEND":
+			order = clientv3.SortDescend
+		default:
+			return nil, fmt.Errorf("bad sort order %q", r.SortOrder)
+		}
+		target := clientv3.SortByKey
+		switch strings.ToUpper(r.SortTarget) {
+		case "", "KEY":
+		case "CREATE":
+			target = clientv3.SortByCreateRevision
+		case "MODIFY":
+			target = clientv3.SortByModRevision
+		case "VALUE":
+			target = clientv3.SortByValue
+		case "VERSION":
+			target = clientv3.SortByVersion
+		default:
+			return nil, fmt.Errorf("bad sort target %q", r.SortTarget)
+		}
+		opts = append(opts, clientv3.WithSort(target, order))
+	}
+	if r.Serializable {
+		opts = append(opts, clientv3.WithSerializable())
+	}
+	if r.KeysOnly {
+		opts = append(opts, clientv3.WithKeysOnly())
+	}
+	if r.CountOnly {
+		opts = append(opts, clientv3.WithCountOnly())
+	}
+	return opts, nil
+}
+
+func (p *txnPut) options() ([]clientv3.OpOption, error) {
+	var opts []clientv3.OpOption
+	if p.Lease != "" {
+		id, err := strconv.ParseInt(p.Lease, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad lease ID (%v), expecting ID in Hex", err)
+		}
+		opts = append(opts, clientv3.WithLease(clientv3.LeaseID(id)))
+	}
+	if p.PrevKV {
+		opts = append(opts, clientv3.WithPrevKV())
+	}
+	if p.IgnoreValue {
+		opts = append(opts, clientv3.WithIgnoreValue())
+	}
+	if p.IgnoreLease {
+		opts = append(opts, clientv3.WithIgnoreLease())
+	}
+	return opts, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestTxnDocument(t *testing.T) {
+	json := `{
+		"compare": [
+			{"key": "k1", "target": "mod", "result": ">", "value": 3},
+			{"key": "k2", "target": "value", "result": "!=", "value": "v"},
+			{"key": "k3", "target": "lease", "result": "=", "value": "7b"}
+		],
+		"success": [
+			{"put": {"key": "k1", "value": "v1", "lease": "7b", "prevKv": true}},
+			{"txn": {"compare": [{"key": "k1", "target": "ver", "result": "<", "value": "2"}], "failure": [{"delete": {"key": "k", "prefix": true}}]}}
+		],
+		"failure": [
+			{"get": {"key": "a", "rangeEnd": "z", "limit": 10, "sortOrder": "descend", "sortTarget": "modify", "keysOnly": true}}
+		]
+	}`
+	yaml := `compare:
+- {key: k1, target: mod, result: ">", value: 3}
+- {key: k2, target: value, result: "!=", value: v}
+- {key: k3, target: lease, result: "=", value: 7b}
+success:
+- put: {key: k1, value: v1, lease: 7b, prevKv: true}
+- txn:
+    compare:
+    - {key: k1, target: ver, result: "<", value: "2"}
+    failure:
+    - delete: {key: k, prefix: true}
+failure:
+- get: {key: a, rangeEnd: z, limit: 10, sortOrder: descend, sortTarget: modify, keysOnly: true}
+`
+	wcmps := []clientv3.Cmp{
+		clientv3.Compare(clientv3.ModRevision("k1"), ">", 3),
+		clientv3.Compare(clientv3.Value("k2"), "!=", "v"),
+		clientv3.Compare(clientv3.LeaseValue("k3"), "=", 0x7b),
+	}
+	wthen := []clientv3.Op{
+		clientv3.OpPut("k1", "v1", clientv3.WithLease(0x7b), clientv3.WithPrevKV()),
+		clientv3.OpTxn(
+			[]clientv3.Cmp{clientv3.Compare(clientv3.Version("k1"), "<", 2)},
+			nil,
+			[]clientv3.Op{clientv3.OpDelete("k", clientv3.WithPrefix())},
+		),
+	}
+	welse := []clientv3.Op{
+		clientv3.OpGet("a", clientv3.WithRange("z"), clientv3.WithLimit(10),
+			clientv3.WithSort(clientv3.SortByModRevision, clientv3.SortDescend), clientv3.WithKeysOnly()),
+	}
+	for name, data := range map[string]string{"json": json, "yaml": yaml} {
+		t.Run(name, func(t *testing.T) {
+			doc, err := parseTxnDocument([]byte(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmps, thenOps, elseOps, err := doc.ops()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cmps, wcmps) {
+				t.Errorf("compares = %+v, want %+v", cmps, wcmps)
+			}
+			if !reflect.DeepEqual(thenOps, wthen) {
+				t.Errorf("success = %+v, want %+v", thenOps, wthen)
+			}
+			if !reflect.DeepEqual(elseOps, welse) {
+				t.Errorf("failure = %+v, want %+v", elseOps, welse)
+			}
+		})
+	}
+}
+
+func TestTxnDocumentInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":   `{"compares": []}`,
+		"unknown target":  `{"compare": [{"key": "k", "target": "size", "result": "=", "value": 1}]}`,
+		"unknown result":  `{"compare": [{"key": "k", "target": "mod", "result": ">=", "value": 1}]}`,
+		"bad revision":    `{"compare": [{"key": "k", "target": "mod", "result": ">", "value": "one"}]}`,
+		"no request":      `{"success": [{}]}`,
+		"two requests":    `{"success": [{"get": {"key": "k"}, "put": {"key": "k", "value": "v"}}]}`,
+		"exclusive range": `{"success": [{"get": {"key": "k", "prefix": true, "fromKey": true}}]}`,
+		"delete limit":    `{"success": [{"delete": {"key": "k", "limit": 1}}]}`,
+		"bad lease":       `{"success": [{"put": {"key": "k", "value": "v", "lease": "xyz"}}]}`,
+		"nested invalid":  `{"failure": [{"txn": {"success": [{"get": {"key": "k", "sortOrder": "up"}}]}}]}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc, err := parseTxnDocument([]byte(data))
+			if err == nil {
+				_, _, _, err = doc.ops()
+			}
+			if err == nil {
+				t.Errorf("error = nil, want an error")
+			}
+		})
+	}
+}
@@ -95,7 +95,23 @@ func isCommandTimeoutFlagSet(cmd *cobra.Command) bool {
 // get the process_resident_memory_bytes from <server>/metrics
 func endpointMemoryMetrics(host string, scfg *clientv3.SecureConfig) float64 {
 	residentMemoryKey := "process_resident_memory_bytes"
-	var residentMemoryValue string
+	metrics, err := endpointMetrics(host, scfg, residentMemoryKey)
+	if err != nil {
+		fmt.Println(err)
+		return 0.0
+	}
+	residentMemoryBytes, ok := metrics[residentMemoryKey]
+	if !ok {
+		fmt.Println(fmt.Sprintf("could not find: %v", residentMemoryKey))
+		return 0.0
+	}
+	return residentMemoryBytes
+}
+
+// endpointMetrics gets the values of the metrics without labels, or the sums
+// of the values of their label sets, from <server>/metrics. The metrics not
+// found are missing from the values.
+func endpointMetrics(host string, scfg *clientv3.SecureConfig, names ...string) (map[string]float64, error) {
 	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
 		host = "http://" + host
 	}
@@ -104,8 +120,7 @@ func endpointMemoryMetrics(host string, scfg *clientv3.SecureConfig) float64 {
 		// load client certificate
 		cert, err := tls.LoadX509KeyPair(scfg.Cert, scfg.Key)
 		if err != nil {
-			fmt.Println(fmt.Sprintf("client certificate error: %v", err))
-			return 0.0
+			return nil, fmt.Errorf("client certificate error: %v", err)
 		}
 		http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{
 			Certificates:       []tls.Certificate{cert},
@@ -114,33 +129,37 @@ func endpointMemoryMetrics(host string, scfg *clientv3.SecureConfig) float64 {
 	}
 	resp, err := http.Get(url)
 	if err != nil {
-		fmt.Println(fmt.Sprintf("fetch error: %v", err))
-		return 0.0
+		return nil, fmt.Errorf("fetch error: %v", err)
 	}
 	byts, readerr := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if readerr != nil {
-		fmt.Println(fmt.Sprintf("fetch error: reading %s: %v", url, readerr))
-		return 0.0
+		return nil, fmt.Errorf("fetch error: reading %s: %v", url, readerr)
 	}
 
+	metrics := make(map[string]float64)
 	for _, line := range strings.Split(string(byts), "\n") {
-		if strings.HasPrefix(line, residentMemoryKey) {
-			residentMemoryValue = strings.TrimSpace(strings.TrimPrefix(line, residentMemoryKey))
-			break
+		for _, name := range names {
+			if !strings.HasPrefix(line, name) {
+				continue
+			}
+			value := strings.TrimPrefix(line, name)
+			if strings.HasPrefix(value, "{") {
+				if i := strings.LastIndex(value, "}"); i >= 0 {
+					value = value[i+1:]
+				}
+			} else if !strings.HasPrefix(value, " ") {
+				// another metric with the name as prefix
+				continue
+			}
+			v, parseErr := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if parseErr != nil {
+				return nil, fmt.Errorf("parse error: %v", parseErr)
+			}
+			metrics[name] += v
 		}
 	}
-	if residentMemoryValue == "" {
-		fmt.Println(fmt.Sprintf("could not find: %v", residentMemoryKey))
-		return 0.0
-	}
-	residentMemoryBytes, parseErr := strconv.ParseFloat(residentMemoryValue, 64)
-	if parseErr != nil {
-		fmt.Println(fmt.Sprintf("parse error: %v", parseErr))
-		return 0.0
-	}
-
-	return residentMemoryBytes
+	return metrics, nil
 }
 
 // compact keyspace history to a provided revision
@@ -21,7 +21,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/pkg/v3/cobrautl"
@@ -42,6 +45,10 @@ var (
 	watchInteractive bool
 	watchPrevKey     bool
 	progressNotify   bool
+	progressInterval time.Duration
+	watchResumeFile  string
+	watchValuePrefix string
+	watchValueRegex  string
 )
 
 // NewWatchCommand returns the cobra command for "watch".
@@ -57,6 +64,10 @@ func NewWatchCommand() *cobra.Command {
 	cmd.Flags().Int64Var(&watchRev, "rev", 0, "Revision to start watching")
 	cmd.Flags().BoolVar(&watchPrevKey, "prev-kv", false, "get the previous key-value pair before the event happens")
 	cmd.Flags().BoolVar(&progressNotify, "progress-notify", false, "get periodic watch progress notification from server")
+	cmd.Flags().DurationVar(&progressInterval, "progress-notify-interval", 0, "Interval of the watch progress notifications, implying --progress-notify (server default if 0)")
+	cmd.Flags().StringVar(&watchValuePrefix, "value-prefix", "", "Only get the put events whose value has the prefix")
+	cmd.Flags().StringVar(&watchValueRegex, "value-regex", "", "Only get the put events whose value matches the RE2 regular expression")
+	cmd.Flags().StringVar(&watchResumeFile, "resume-from-file", "", "File persisting the last seen revision, the watch resuming after it when the file exists")
 
 	return cmd
 }
@@ -69,6 +80,9 @@ func watchCommandFunc(cmd *cobra.Command, args []string) {
 	}
 
 	if watchInteractive {
+		if watchResumeFile != "" {
+			cobrautl.ExitWithError(cobrautl.ExitBadArgs, fmt.Errorf("--resume-from-file is not supported in interactive mode"))
+		}
 		watchInteractiveFunc(cmd, os.Args, envKey, envRange)
 		return
 	}
@@ -78,13 +92,27 @@ func watchCommandFunc(cmd *cobra.Command, args []string) {
 		cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
 	}
 
+	if watchResumeFile != "" {
+		rev, rerr := readWatchResumeRevision(watchResumeFile)
+		if rerr != nil {
+			cobrautl.ExitWithError(cobrautl.ExitError, rerr)
+		}
+		if rev != 0 {
+			watchRev = rev + 1
+		}
+	}
+
 	c := mustClientFromCmd(cmd)
 	wc, err := getWatchChan(c, watchArgs)
 	if err != nil {
 		cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
 	}
 
-	printWatchCh(c, wc, execArgs)
+	if watchResumeFile != "" {
+		resumeWatchCh(c, wc, watchArgs, execArgs)
+	} else {
+		printWatchCh(c, wc, execArgs)
+	}
 	if err = c.Close(); err != nil {
 		cobrautl.ExitWithError(cobrautl.ExitBadConnection, err)
 	}
@@ -160,35 +188,131 @@ func getWatchChan(c *clientv3.Client, args []string) (clientv3.WatchChan, error)
 	if progressNotify {
 		opts = append(opts, clientv3.WithProgressNotify())
 	}
+	if progressInterval > 0 {
+		opts = append(opts, clientv3.WithProgressNotifyInterval(progressInterval))
+	}
+	if watchValuePrefix != "" {
+		opts = append(opts, clientv3.WithValuePrefix(watchValuePrefix))
+	}
+	if watchValueRegex != "" {
+		opts = append(opts, clientv3.WithValueRegex(watchValueRegex))
+	}
 	return c.Watch(clientv3.WithRequireLeader(context.Background()), key, opts...), nil
 }
 
 func printWatchCh(c *clientv3.Client, ch clientv3.WatchChan, execArgs []string) {
 	for resp := range ch {
-		if resp.Canceled {
-			fmt.Fprintf(os.Stderr, "watch was canceled (%v)\n", resp.Err())
+		printWatchResp(c, resp, execArgs)
+	}
+}
+
+func printWatchResp(c *clientv3.Client, resp clientv3.WatchResponse, execArgs []string) {
+	if resp.Canceled {
+		fmt.Fprintf(os.Stderr, "watch was canceled (%v)\n", resp.Err())
+	}
+	if resp.IsProgressNotify() {
+		fmt.Fprintf(os.Stdout, "progress notify: %d\n", resp.Header.Revision)
+	}
+	display.Watch(resp)
+
+	if len(execArgs) > 0 {
+		for _, ev := range resp.Events {
+			cmd := exec.CommandContext(c.Ctx(), execArgs[0], execArgs[1:]...)
+			cmd.Env = os.Environ()
+			cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_REVISION=%d", resp.Header.Revision))
+			cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_EVENT_TYPE=%q", ev.Type))
+			cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_KEY=%q", ev.Kv.Key))
+			cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_VALUE=%q", ev.Kv.Value))
+			cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+			if err := cmd.Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "command %q error (%v)\n", execArgs, err)
+				os.Exit(1)
+			}
 		}
-		if resp.IsProgressNotify() {
-			fmt.Fprintf(os.Stdout, "progress notify: %d\n", resp.Header.Revision)
+	}
+}
+
+// resumeWatchCh prints the watch responses like printWatchCh, persisting the
+// last seen revision to the resume file once each response is handled. If the
+// revision to resume from is compacted, the watch restarts from the current
+// revision, losing the compacted events.
+func resumeWatchCh(c *clientv3.Client, ch clientv3.WatchChan, watchArgs, execArgs []string) {
+	for {
+		resp, ok := <-ch
+		if !ok {
+			return
 		}
-		display.Watch(resp)
-
-		if len(execArgs) > 0 {
-			for _, ev := range resp.Events {
-				cmd := exec.CommandContext(c.Ctx(), execArgs[0], execArgs[1:]...)
-				cmd.Env = os.Environ()
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_REVISION=%d", resp.Header.Revision))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_EVENT_TYPE=%q", ev.Type))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_KEY=%q", ev.Kv.Key))
-				cmd.Env = append(cmd.Env, fmt.Sprintf("ETCD_WATCH_VALUE=%q", ev.Kv.Value))
-				cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-				if err := cmd.Run(); err != nil {
-					fmt.Fprintf(os.Stderr, "command %q error (%v)\n", execArgs, err)
-					os.Exit(1)
-				}
+		if resp.CompactRevision != 0 {
+			fmt.Fprintf(os.Stderr, "warning: revision %d to resume from is compacted at %d, resuming from the current revision, events are lost\n", watchRev, resp.CompactRevision)
+			watchRev = 0
+			var err error
+			if ch, err = getWatchChan(c, watchArgs); err != nil {
+				cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
 			}
+			continue
 		}
+
+		printWatchResp(c, resp, execArgs)
+		if rev := watchRespRevision(resp); rev != 0 {
+			if err := writeWatchResumeRevision(watchResumeFile, rev); err != nil {
+				cobrautl.ExitWithError(cobrautl.ExitError, err)
+			}
+		}
+	}
+}
+
+// watchRespRevision returns the revision up to which the events have been
+// received with the watch response, or 0 if unknown. The header revision of a
+// response with events may be ahead of its last event, while the watch catches
+// up with the past revisions.
+func watchRespRevision(resp clientv3.WatchResponse) int64 {
+	if n := len(resp.Events); n > 0 {
+		return resp.Events[n-1].Kv.ModRevision
+	}
+	if resp.IsProgressNotify() {
+		return resp.Header.Revision
+	}
+	return 0
+}
+
+// readWatchResumeRevision returns the revision persisted to the resume file,
+// or 0 if the file does not exist.
+func readWatchResumeRevision(path string) (int64, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read resume file: %v", err)
+	}
+	rev, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil || rev < 0 {
+		return 0, fmt.Errorf("invalid revision %q in resume file %s", strings.TrimSpace(string(b)), path)
+	}
+	return rev, nil
+}
+
+// writeWatchResumeRevision persists the revision to the resume file, replacing
+// it atomically not to leave a truncated file behind if interrupted.
+func writeWatchResumeRevision(path string, rev int64) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write resume file: %v", err)
+	}
+	if _, err = fmt.Fprintf(f, "%d\n", rev); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write resume file: %v", err)
 	}
+	return nil
 }
 
 // "commandArgs" is the command arguments after "spf13/cobra" parses
@@ -15,6 +15,8 @@
 package command
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -556,3 +558,30 @@ func Test_parseWatchArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestWatchResumeRevision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.rev")
+
+	rev, err := readWatchResumeRevision(path)
+	if err != nil || rev != 0 {
+		t.Fatalf("readWatchResumeRevision() = %d, %v, want 0, nil for a missing file", rev, err)
+	}
+	for _, wrev := range []int64{5, 12} {
+		if err = writeWatchResumeRevision(path, wrev); err != nil {
+			t.Fatal(err)
+		}
+		if rev, err = readWatchResumeRevision(path); err != nil || rev != wrev {
+			t.Fatalf("readWatchResumeRevision() = %d, %v, want %d, nil", rev, err, wrev)
+		}
+	}
+	if fs, _ := os.ReadDir(filepath.Dir(path)); len(fs) != 1 {
+		t.Errorf("got %d files, want only the resume file", len(fs))
+	}
+
+	if err = os.WriteFile(path, []byte("foo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = readWatchResumeRevision(path); err == nil {
+		t.Error("readWatchResumeRevision() succeeded for an invalid revision")
+	}
+}
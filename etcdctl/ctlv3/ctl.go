@@ -57,6 +57,8 @@ func init() {
 	rootCmd.RegisterFlagCompletionFunc("write-out", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
 		return []string{"fields", "json", "protobuf", "simple", "table"}, cobra.ShellCompDirectiveDefault
 	})
+	rootCmd.PersistentFlags().BoolVar(&globalFlags.ExitCodes, "exit-codes", false, "exit with the codes of the etcd errors, e.g. 8 if the permission is denied, instead of 1 (implied by the json output format)")
+	cobra.OnInitialize(setErrorOutput)
 
 	rootCmd.PersistentFlags().DurationVar(&globalFlags.DialTimeout, "dial-timeout", defaultDialTimeout, "dial timeout for client connections")
 	rootCmd.PersistentFlags().DurationVar(&globalFlags.CommandTimeOut, "command-timeout", defaultCommandTimeOut, "timeout for short running command (excluding dial timeout)")
@@ -83,6 +85,11 @@ func init() {
 		command.NewCompactionCommand(),
 		command.NewAlarmCommand(),
 		command.NewDefragCommand(),
+		command.NewDoctorCommand(),
+		command.NewEventsCommand(),
+		command.NewFeatureGatesCommand(),
+		command.NewPrefixStatsCommand(),
+		command.NewLogCommand(),
 		command.NewEndpointCommand(),
 		command.NewMoveLeaderCommand(),
 		command.NewWatchCommand(),
@@ -113,8 +120,22 @@ func Start() error {
 	return rootCmd.Execute()
 }
 
+// setErrorOutput writes the errors the commands exit with as JSON envelopes
+// with the json output format. The generic errors exit with the codes of the
+// etcd errors they are caused by only with --exit-codes or the json output
+// format, keeping exit code 1 for the scripts relying on it.
+func setErrorOutput() {
+	json := globalFlags.OutputFormat == "json"
+	var classify cobrautl.ErrorClassifier
+	if json || globalFlags.ExitCodes {
+		classify = command.ClassifyError
+	}
+	cobrautl.SetErrorOutput(json, classify)
+}
+
 func MustStart() {
 	if err := Start(); err != nil {
+		setErrorOutput()
 		if rootCmd.SilenceErrors {
 			cobrautl.ExitWithError(cobrautl.ExitError, err)
 		} else {
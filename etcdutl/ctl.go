@@ -18,6 +18,7 @@ package main
 import (
 	"github.com/spf13/cobra"
 	"go.etcd.io/etcd/etcdutl/v3/etcdutl"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
 )
 
 const (
@@ -38,6 +39,7 @@ func init() {
 	rootCmd.RegisterFlagCompletionFunc("write-out", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
 		return []string{"fields", "json", "protobuf", "simple", "table"}, cobra.ShellCompDirectiveDefault
 	})
+	cobra.OnInitialize(setErrorOutput)
 
 	rootCmd.AddCommand(
 		etcdutl.NewBackupCommand(),
@@ -46,9 +48,17 @@ func init() {
 		etcdutl.NewVersionCommand(),
 		etcdutl.NewCompletionCommand(),
 		etcdutl.NewMigrateCommand(),
+		etcdutl.NewWALCommand(),
+		etcdutl.NewDBCommand(),
 	)
 }
 
+// setErrorOutput writes the errors the commands exit with as JSON envelopes
+// with the json output format.
+func setErrorOutput() {
+	cobrautl.SetErrorOutput(etcdutl.OutputFormat == "json", nil)
+}
+
 func Start() error {
 	// Make help just show the usage
 	rootCmd.SetHelpTemplate(`{{.UsageString}}`)
@@ -0,0 +1,285 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdutl
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+	"go.etcd.io/etcd/pkg/v3/pbutil"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/snap"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2store"
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/datadir"
+	"go.etcd.io/etcd/server/v3/storage/mvcc"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.etcd.io/etcd/server/v3/storage/wal"
+	"go.etcd.io/etcd/server/v3/storage/wal/walpb"
+	"go.uber.org/zap"
+)
+
+var (
+	dbOptimizeDataDir        string
+	dbOptimizeWALDir         string
+	dbOptimizeRevision       int64
+	dbOptimizeSkipCompaction bool
+)
+
+// NewDBCommand returns the cobra command for "db".
+func NewDBCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db <subcommand>",
+		Short: "Manages the backend database of an etcd member",
+	}
+	cmd.AddCommand(NewDBOptimizeCommand())
+	return cmd
+}
+
+// NewDBOptimizeCommand returns the cobra command for "db optimize".
+func NewDBOptimizeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "optimize",
+		Short: "Compacts and defragments the backend of a member not running",
+		Run:   dbOptimizeCommandFunc,
+	}
+	cmd.Flags().StringVar(&dbOptimizeDataDir, "data-dir", "", "Required. Path to the data directory of the member.")
+	cmd.Flags().StringVar(&dbOptimizeWALDir, "wal-dir", "", "Path to the WAL directory, if not in the data directory.")
+	cmd.Flags().Int64Var(&dbOptimizeRevision, "revision", 0, "Revision to compact the history up to. Defaults to the current revision.")
+	cmd.Flags().BoolVar(&dbOptimizeSkipCompaction, "skip-compaction", false, "Only defragment the backend and save a snapshot, as required for a member of a cluster of several members.")
+	cmd.MarkFlagRequired("data-dir")
+	cmd.MarkFlagDirname("data-dir")
+	cmd.MarkFlagDirname("wal-dir")
+	return cmd
+}
+
+func dbOptimizeCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := OptimizeData(dbOptimizeDataDir, dbOptimizeWALDir, dbOptimizeRevision, dbOptimizeSkipCompaction)
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError,
+			fmt.Errorf("failed to optimize etcd data[%s] (%v)", dbOptimizeDataDir, err))
+	}
+	switch {
+	case dbOptimizeSkipCompaction:
+		fmt.Println("Skipped compaction")
+	case r.CompactRevision > 0:
+		fmt.Printf("Compacted history up to revision %d\n", r.CompactRevision)
+	default:
+		fmt.Println("History already compacted up to the revision, skipped compaction")
+	}
+	fmt.Printf("Defragmented backend from %s to %s\n",
+		humanize.Bytes(uint64(r.SizeBefore)), humanize.Bytes(uint64(r.SizeAfter)))
+	if r.SnapshotIndex > 0 {
+		fmt.Printf("Saved snapshot at index %d\n", r.SnapshotIndex)
+	} else {
+		fmt.Println("Snapshot up to date with the backend, skipped saving snapshot")
+	}
+}
+
+// OptimizeResult is the result of optimizing the data directory of a member.
+type OptimizeResult struct {
+	// CompactRevision is the revision the history was compacted up to, 0 if
+	// it was already compacted up to the requested revision or the compaction
+	// was skipped.
+	CompactRevision int64
+	// SizeBefore and SizeAfter are the sizes of the backend before and after
+	// it was optimized.
+	SizeBefore int64
+	SizeAfter  int64
+	// SnapshotIndex is the index of the snapshot saved at the consistent
+	// index of the backend, 0 if the newest snapshot was already at it.
+	SnapshotIndex uint64
+}
+
+// OptimizeData compacts the history of the backend of a member not running up
+// to rev, or to the current revision if rev is 0, unless skipCompaction is set,
+// and defragments it. It then saves a snapshot at the consistent index of the
+// backend, recorded in the WAL, for the member to restart from it instead of
+// replaying the WAL since the previous snapshot.
+//
+// The history of a member of a cluster of several members is not compacted:
+// the compact revision of the member would diverge from the one of its peers,
+// failing the comparisons of their hashes by the corruption checks, and
+// OptimizeData returns an error unless skipCompaction is set.
+func OptimizeData(dataDir, walDir string, rev int64, skipCompaction bool) (*OptimizeResult, error) {
+	lg := GetLogger()
+	if walDir == "" {
+		walDir = datadir.ToWalDir(dataDir)
+	}
+	be := openOfflineBackend(lg, datadir.ToBackendFileName(dataDir))
+	defer be.Close()
+
+	r := &OptimizeResult{SizeBefore: be.Size()}
+	if !skipCompaction {
+		members, _ := schema.NewMembershipBackend(lg, be).MustReadMembersFromBackend()
+		if len(members) > 1 {
+			return nil, fmt.Errorf("refusing to compact the history of a member of a cluster of %d members, "+
+				"its compact revision diverging from the one of its peers: compact the cluster with `etcdctl compaction` "+
+				"and use --skip-compaction", len(members))
+		}
+		compacted, err := compactBackend(lg, be, rev)
+		if err != nil {
+			return nil, err
+		}
+		r.CompactRevision = compacted
+	}
+
+	var err error
+	if err = be.Defrag(); err != nil {
+		return nil, err
+	}
+	r.SizeAfter = be.Size()
+
+	if r.SnapshotIndex, err = saveBackendSnapshot(lg, be, dataDir, walDir); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// openOfflineBackend opens the backend at dbPath, waiting for a member still
+// running on it to close.
+func openOfflineBackend(lg *zap.Logger, dbPath string) backend.Backend {
+	var be backend.Backend
+	bch := make(chan struct{})
+	go func() {
+		defer close(bch)
+		cfg := backend.DefaultBackendConfig(lg)
+		cfg.Path = dbPath
+		be = backend.New(cfg)
+	}()
+	select {
+	case <-bch:
+	case <-time.After(time.Second):
+		fmt.Fprintf(os.Stderr, "waiting for etcd to close and release its lock on %q. "+
+			"To compact and defragment a running etcd instance, use `etcdctl compaction --physical` and `etcdctl defrag` instead.\n", dbPath)
+		<-bch
+	}
+	return be
+}
+
+// compactBackend compacts the history of the backend up to rev, or to the
+// current revision if rev is 0, returning the revision compacted up to, or 0
+// if the history was already compacted up to rev.
+func compactBackend(lg *zap.Logger, be backend.Backend, rev int64) (int64, error) {
+	s := mvcc.NewStore(lg, be, nil, mvcc.StoreConfig{})
+	defer s.Close()
+	if rev == 0 {
+		rev = s.Rev()
+	}
+	donec, err := s.Compact(traceutil.TODO(), rev)
+	switch err {
+	case nil:
+	case mvcc.ErrCompacted:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("failed to compact up to revision %d (%v)", rev, err)
+	}
+	<-donec
+	s.Commit()
+	return rev, nil
+}
+
+// saveBackendSnapshot saves a snapshot at the consistent index of the backend
+// and records it in the WAL, returning its index, or 0 if the newest snapshot
+// was already at the consistent index. The membership of the v2 store of the
+// snapshot is the one of the backend.
+func saveBackendSnapshot(lg *zap.Logger, be backend.Backend, dataDir, walDir string) (uint64, error) {
+	index, term := schema.ReadConsistentIndex(be.ReadTx())
+	tx := be.ReadTx()
+	tx.RLock()
+	confState := schema.UnsafeConfStateFromBackend(lg, tx)
+	tx.RUnlock()
+	if index == 0 || term == 0 || confState == nil {
+		// the backend was written by etcd < v3.5, not recording the term and
+		// the conf state of its consistent index.
+		lg.Warn("backend does not record the term and the conf state of its consistent index, skipped saving snapshot")
+		return 0, nil
+	}
+
+	walSnaps, err := wal.ValidSnapshotEntries(lg, walDir)
+	if err != nil {
+		return 0, err
+	}
+	ss := snap.New(lg, datadir.ToSnapDir(dataDir))
+	snapshot, err := ss.LoadNewestAvailable(walSnaps)
+	if err != nil && err != snap.ErrNoSnapshot {
+		return 0, err
+	}
+	var walsnap walpb.Snapshot
+	if snapshot != nil {
+		if snapshot.Metadata.Index >= index {
+			return 0, nil
+		}
+		walsnap.Index, walsnap.Term = snapshot.Metadata.Index, snapshot.Metadata.Term
+	}
+
+	w, err := wal.Open(lg, walDir, walsnap)
+	if err != nil {
+		return 0, err
+	}
+	defer w.Close()
+	wmetadata, hardState, _, err := w.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	if hardState.Commit < index {
+		return 0, fmt.Errorf("consistent index %d of the backend is beyond the committed index %d of the WAL", index, hardState.Commit)
+	}
+
+	st := v2store.New(etcdserver.StoreClusterPrefix, etcdserver.StoreKeysPrefix)
+	if snapshot != nil {
+		if err = st.Recovery(snapshot.Data); err != nil {
+			return 0, err
+		}
+	}
+	var metadata etcdserverpb.Metadata
+	pbutil.MustUnmarshal(&metadata, wmetadata)
+	members, _ := schema.NewMembershipBackend(lg, be).MustReadMembersFromBackend()
+	membs := make([]*membership.Member, 0, len(members))
+	for _, m := range members {
+		membs = append(membs, m)
+	}
+	cl := membership.NewClusterFromMembers(lg, types.ID(metadata.ClusterID), membs)
+	cl.SetStore(st)
+	cl.PushMembershipToStorage()
+	data, err := st.Save()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = ss.SaveSnap(raftpb.Snapshot{
+		Data: data,
+		Metadata: raftpb.SnapshotMetadata{
+			Index:     index,
+			Term:      term,
+			ConfState: *confState,
+		},
+	}); err != nil {
+		return 0, err
+	}
+	if err = w.SaveSnapshot(walpb.Snapshot{Index: index, Term: term, ConfState: confState}); err != nil {
+		return 0, err
+	}
+	return index, nil
+}
@@ -16,8 +16,11 @@ package etcdutl
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
+	"go.etcd.io/etcd/client/v3/snapshot/storage"
 	"go.etcd.io/etcd/etcdutl/v3/snapshot"
 	"go.etcd.io/etcd/pkg/v3/cobrautl"
 	"go.etcd.io/etcd/server/v3/storage/datadir"
@@ -38,6 +41,11 @@ var (
 	restorePeerURLs     string
 	restoreName         string
 	skipHashCheck       bool
+
+	restoreFromS3         string
+	restoreStorageOptions storage.Options
+
+	importRevision int64
 )
 
 // NewSnapshotCommand returns the cobra command for "snapshot".
@@ -48,6 +56,7 @@ func NewSnapshotCommand() *cobra.Command {
 	}
 	cmd.AddCommand(NewSnapshotRestoreCommand())
 	cmd.AddCommand(newSnapshotStatusCommand())
+	cmd.AddCommand(NewSnapshotImportCommand())
 	return cmd
 }
 
@@ -64,9 +73,17 @@ The items in the lists are hash, revision, total keys, total size.
 
 func NewSnapshotRestoreCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "restore <filename> --data-dir {output dir} [options]",
+		Use:   "restore <filename or object URL> --data-dir {output dir} [options]",
 		Short: "Restores an etcd member snapshot to an etcd directory",
-		Run:   snapshotRestoreCommandFunc,
+		Long: `Restores an etcd member snapshot to an etcd directory.
+
+The snapshot is read from a file, or streamed from an object storage given the URL of its object: s3://<bucket>/<key>
+for Amazon S3, gs://<bucket>/<name> for Google Cloud Storage or azblob://<container>/<blob> for Azure Blob Storage.
+The credentials are read from the environment: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN for S3,
+GOOGLE_OAUTH_ACCESS_TOKEN for GCS, and AZURE_STORAGE_ACCOUNT with AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN for Azure.
+The snapshot is checked against the sha256 digest saved with it by "etcdctl snapshot save", if any.
+`,
+		Run: snapshotRestoreCommandFunc,
 	}
 	cmd.Flags().StringVar(&restoreDataDir, "data-dir", "", "Path to the output data directory")
 	cmd.Flags().StringVar(&restoreWalDir, "wal-dir", "", "Path to the WAL directory (use --data-dir if none given)")
@@ -75,6 +92,9 @@ func NewSnapshotRestoreCommand() *cobra.Command {
 	cmd.Flags().StringVar(&restorePeerURLs, "initial-advertise-peer-urls", defaultInitialAdvertisePeerURLs, "List of this member's peer URLs to advertise to the rest of the cluster")
 	cmd.Flags().StringVar(&restoreName, "name", defaultName, "Human-readable name for this member")
 	cmd.Flags().BoolVar(&skipHashCheck, "skip-hash-check", false, "Ignore snapshot integrity hash value (required if copied from data directory)")
+	cmd.Flags().StringVar(&restoreFromS3, "from-s3", "", "Restore the snapshot of the S3 object <bucket>/<key> instead of a file, as s3://<bucket>/<key>")
+	cmd.Flags().StringVar(&restoreStorageOptions.Endpoint, "storage-endpoint", "", "Endpoint of the object storage, e.g. of an S3 compatible service (the endpoint of the provider if none given)")
+	cmd.Flags().StringVar(&restoreStorageOptions.Region, "storage-region", "", "Region of the S3 bucket (AWS_REGION if none given)")
 
 	cmd.MarkFlagDirname("data-dir")
 	cmd.MarkFlagDirname("wal-dir")
@@ -82,6 +102,24 @@ func NewSnapshotRestoreCommand() *cobra.Command {
 	return cmd
 }
 
+func NewSnapshotImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <input file or -> <filename> [options]",
+		Short: "Writes a snapshot holding the key-value pairs of a file, to seed a new cluster",
+		Long: `Writes a snapshot holding the key-value pairs of a file, all at a single revision, to seed a new cluster.
+
+The key-value pairs are read from the input file, or from the standard input if "-", as a stream of JSON objects
+with the base64-encoded "key" and "value" fields, in strictly ascending key order, e.g. the output of
+"etcdctl get '' --prefix -w json | jq -c '.kvs[]'". They are written directly to the backend of the snapshot,
+without going through raft, and the snapshot is restored on the members of the new cluster with "snapshot restore"
+before they start.
+`,
+		Run: snapshotImportCommandFunc,
+	}
+	cmd.Flags().Int64Var(&importRevision, "revision", 1, "Revision of the imported key-value pairs")
+	return cmd
+}
+
 func SnapshotStatusCommandFunc(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
 		err := fmt.Errorf("snapshot status requires exactly one argument")
@@ -98,9 +136,40 @@ func SnapshotStatusCommandFunc(cmd *cobra.Command, args []string) {
 	printer.DBStatus(ds)
 }
 
+func snapshotImportCommandFunc(_ *cobra.Command, args []string) {
+	if len(args) != 2 {
+		err := fmt.Errorf("snapshot import requires exactly two arguments")
+		cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
+	}
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	lg := GetLogger()
+	sp := snapshot.NewV3(lg)
+	n, err := sp.Import(r, args[1], importRevision)
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError, err)
+	}
+	fmt.Printf("Imported %d keys at revision %d to %s\n", n, importRevision, args[1])
+}
+
 func snapshotRestoreCommandFunc(_ *cobra.Command, args []string) {
+	if restoreFromS3 != "" {
+		if len(args) != 0 {
+			err := fmt.Errorf("snapshot restore with --from-s3 accepts no argument")
+			cobrautl.ExitWithError(cobrautl.ExitBadArgs, err)
+		}
+		args = []string{"s3://" + strings.TrimPrefix(restoreFromS3, "s3://")}
+	}
 	SnapshotRestoreCommandFunc(restoreCluster, restoreClusterToken, restoreDataDir, restoreWalDir,
-		restorePeerURLs, restoreName, skipHashCheck, args)
+		restorePeerURLs, restoreName, skipHashCheck, restoreStorageOptions, args)
 }
 
 func SnapshotRestoreCommandFunc(restoreCluster string,
@@ -110,6 +179,7 @@ func SnapshotRestoreCommandFunc(restoreCluster string,
 	restorePeerURLs string,
 	restoreName string,
 	skipHashCheck bool,
+	storageOptions storage.Options,
 	args []string) {
 	if len(args) != 1 {
 		err := fmt.Errorf("snapshot restore requires exactly one argument")
@@ -138,6 +208,7 @@ func SnapshotRestoreCommandFunc(restoreCluster string,
 		InitialCluster:      restoreCluster,
 		InitialClusterToken: restoreClusterToken,
 		SkipHashCheck:       skipHashCheck,
+		StorageOptions:      storageOptions,
 	}); err != nil {
 		cobrautl.ExitWithError(cobrautl.ExitError, err)
 	}
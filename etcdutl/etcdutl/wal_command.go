@@ -0,0 +1,95 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdutl
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/pkg/v3/cobrautl"
+	"go.etcd.io/etcd/server/v3/storage/datadir"
+	"go.etcd.io/etcd/server/v3/storage/wal"
+)
+
+var (
+	walRepairDataDir string
+	walRepairWALDir  string
+	walRepairForce   bool
+)
+
+// NewWALCommand returns the cobra command for "wal".
+func NewWALCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wal <subcommand>",
+		Short: "Manages the WAL of an etcd member",
+	}
+	cmd.AddCommand(NewWALRepairCommand())
+	return cmd
+}
+
+// NewWALRepairCommand returns the cobra command for "wal repair".
+func NewWALRepairCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Repairs the tail of the WAL of a member not running",
+		Run:   walRepairCommandFunc,
+	}
+	cmd.Flags().StringVar(&walRepairDataDir, "data-dir", "", "Required. Path to the data directory of the member.")
+	cmd.Flags().StringVar(&walRepairWALDir, "wal-dir", "", "Path to the WAL directory, if not in the data directory.")
+	cmd.Flags().BoolVar(&walRepairForce, "force", false, "Truncate a corrupted tail, not torn by a partial write, losing its records that may have been committed.")
+	cmd.MarkFlagRequired("data-dir")
+	cmd.MarkFlagDirname("data-dir")
+	cmd.MarkFlagDirname("wal-dir")
+	return cmd
+}
+
+func walRepairCommandFunc(cmd *cobra.Command, args []string) {
+	walDir := walRepairWALDir
+	if walDir == "" {
+		walDir = datadir.ToWalDir(walRepairDataDir)
+	}
+	lg := GetLogger()
+
+	r, err := wal.InspectTail(lg, walDir)
+	if err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError, fmt.Errorf("failed to inspect WAL %q (%v)", walDir, err))
+	}
+	printTailReport(r)
+	switch {
+	case r.State == wal.TailClean:
+		fmt.Println("WAL is not corrupted, nothing to repair")
+		return
+	case !r.Repairable() && !walRepairForce:
+		cobrautl.ExitWithError(cobrautl.ExitError, fmt.Errorf("the tail of WAL %q is corrupted, not torn by a partial write: "+
+			"its records may have been committed, restore the member from the cluster or repair it with --force, losing them", walDir))
+	}
+
+	if _, err = wal.ForceRepair(lg, walDir); err != nil {
+		cobrautl.ExitWithError(cobrautl.ExitError, fmt.Errorf("failed to repair WAL %q (%v)", walDir, err))
+	}
+	fmt.Printf("Repaired WAL, truncated %s to offset %d, its original backed up to %s.broken\n", r.Path, r.ValidOffset, r.Path)
+}
+
+func printTailReport(r *wal.TailReport) {
+	fmt.Printf("File: %s\n", r.Path)
+	fmt.Printf("Tail state: %s\n", r.State)
+	if r.Err != nil {
+		fmt.Printf("Decode error: %v\n", r.Err)
+	}
+	fmt.Printf("Valid records: %d\n", r.Records)
+	fmt.Printf("Last valid entry index: %d\n", r.LastIndex)
+	fmt.Printf("Valid offset: %d\n", r.ValidOffset)
+	fmt.Printf("Discarded bytes: %d\n", r.DiscardedBytes)
+}
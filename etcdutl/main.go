@@ -21,6 +21,7 @@ import (
 
 func main() {
 	if err := Start(); err != nil {
+		setErrorOutput()
 		cobrautl.ExitWithError(cobrautl.ExitError, err)
 	}
 }
@@ -32,6 +32,7 @@ import (
 	"go.etcd.io/etcd/client/pkg/v3/types"
 	"go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/snapshot"
+	"go.etcd.io/etcd/client/v3/snapshot/storage"
 	"go.etcd.io/etcd/raft/v3"
 	"go.etcd.io/etcd/raft/v3/raftpb"
 	"go.etcd.io/etcd/server/v3/config"
@@ -66,6 +67,12 @@ type Manager interface {
 	// file. It returns an error if specified data directory already
 	// exists, to prevent unintended data directory overwrites.
 	Restore(cfg RestoreConfig) error
+
+	// Import writes a new snapshot file at dbPath holding the key-value
+	// pairs decoded from r, all at the revision rev, and returns their
+	// number. The snapshot is restored with Restore to seed the members of
+	// a new cluster, the key-value pairs never going through raft.
+	Import(r io.Reader, dbPath string, rev int64) (int64, error)
 }
 
 // NewV3 returns a new snapshot Manager for v3.x snapshot.
@@ -82,6 +89,8 @@ type v3Manager struct {
 	snapDir   string
 	cl        *membership.RaftCluster
 
+	storageOpts storage.Options
+
 	skipHashCheck bool
 }
 
@@ -93,8 +102,17 @@ func hasChecksum(n int64) bool {
 	return (n % 512) == sha256.Size
 }
 
-// Save fetches snapshot from remote etcd server and saves data to target path.
+// Save fetches snapshot from remote etcd server and saves data to target path,
+// or to the object of the object storage if the path is an object URL, with
+// the default storage options.
 func (s *v3Manager) Save(ctx context.Context, cfg clientv3.Config, dbPath string) (version string, err error) {
+	if storage.IsURL(dbPath) {
+		st, name, err := storage.Open(dbPath, storage.Options{})
+		if err != nil {
+			return "", err
+		}
+		return snapshot.SaveToStorage(ctx, s.lg, cfg, st, name)
+	}
 	return snapshot.SaveWithVersion(ctx, s.lg, cfg, dbPath)
 }
 
@@ -175,8 +193,13 @@ func (s *v3Manager) Status(dbPath string) (ds Status, err error) {
 
 // RestoreConfig configures snapshot restore operation.
 type RestoreConfig struct {
-	// SnapshotPath is the path of snapshot file to restore from.
+	// SnapshotPath is the path of snapshot file to restore from, or the
+	// object URL of the snapshot, e.g. "s3://bucket/snapshot.db", to stream
+	// it from the object storage.
 	SnapshotPath string
+	// StorageOptions configures the access to the object storage of the
+	// snapshot, if SnapshotPath is an object URL.
+	StorageOptions storage.Options
 
 	// Name is the human-readable name of this member.
 	Name string
@@ -252,6 +275,7 @@ func (s *v3Manager) Restore(cfg RestoreConfig) error {
 	s.walDir = walDir
 	s.snapDir = filepath.Join(dataDir, "member", "snap")
 	s.skipHashCheck = cfg.SkipHashCheck
+	s.storageOpts = cfg.StorageOptions
 
 	s.lg.Info(
 		"restoring snapshot",
@@ -311,22 +335,22 @@ func (s *v3Manager) saveDB() error {
 }
 
 func (s *v3Manager) copyAndVerifyDB() error {
-	srcf, ferr := os.Open(s.srcDbPath)
-	if ferr != nil {
-		return ferr
-	}
-	defer srcf.Close()
-
-	// get snapshot integrity hash
-	if _, err := srcf.Seek(-sha256.Size, io.SeekEnd); err != nil {
-		return err
-	}
-	sha := make([]byte, sha256.Size)
-	if _, err := srcf.Read(sha); err != nil {
-		return err
-	}
-	if _, err := srcf.Seek(0, io.SeekStart); err != nil {
-		return err
+	// the snapshot of an object storage is streamed to the output directly
+	var (
+		srcf *os.File
+		st   storage.Storage
+		name string
+		err  error
+	)
+	if storage.IsURL(s.srcDbPath) {
+		if st, name, err = storage.Open(s.srcDbPath, s.storageOpts); err != nil {
+			return err
+		}
+	} else {
+		if srcf, err = os.Open(s.srcDbPath); err != nil {
+			return err
+		}
+		defer srcf.Close()
 	}
 
 	if err := fileutil.CreateDirAll(s.lg, s.snapDir); err != nil {
@@ -346,7 +370,11 @@ func (s *v3Manager) copyAndVerifyDB() error {
 			dbClosed = true
 		}
 	}()
-	if _, err := io.Copy(db, srcf); err != nil {
+	if st != nil {
+		if err := storage.Download(context.Background(), st, name, db); err != nil {
+			return err
+		}
+	} else if _, err := io.Copy(db, srcf); err != nil {
 		return err
 	}
 
@@ -356,7 +384,12 @@ func (s *v3Manager) copyAndVerifyDB() error {
 		return serr
 	}
 	hasHash := hasChecksum(off)
+	// get snapshot integrity hash
+	sha := make([]byte, sha256.Size)
 	if hasHash {
+		if _, err := db.ReadAt(sha, off-sha256.Size); err != nil {
+			return err
+		}
 		if err := db.Truncate(off - sha256.Size); err != nil {
 			return err
 		}
@@ -15,10 +15,12 @@
 package cobrautl
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 )
 
+// The exit codes are stable: new codes are only ever added.
 const (
 	// http://tldp.org/LDP/abs/html/exitcodes.html
 	ExitSuccess = iota
@@ -28,13 +30,64 @@ const (
 	ExitBadFeature   // provided a valid flag with an unsupported value
 	ExitInterrupted
 	ExitIO
-	ExitBadArgs = 128
+	ExitAuthFailed        // the authentication failed, or the auth token is invalid
+	ExitPermissionDenied  // the user has no permission for the request
+	ExitNotFound          // the key, lease, member, user or role does not exist
+	ExitAlreadyExists     // the lease, member, user or role already exists
+	ExitRevisionCompacted // the revision is compacted, or not yet applied
+	ExitUnavailable       // the cluster is temporarily unavailable, e.g. it has no leader
+	ExitNoSpace           // the cluster ran out of space
+	ExitBadArgs           = 128
 
+	// Deprecated: ExitServerError and ExitClusterNotHealthy collide with
+	// ExitBadFeature and ExitInterrupted; use ExitError and ExitUnavailable.
 	ExitServerError       = 4
 	ExitClusterNotHealthy = 5
 )
 
+// ErrorEnvelope is the error a command exits with, written to standard error
+// as JSON with the json output format.
+type ErrorEnvelope struct {
+	// Code is the exit code.
+	Code int `json:"code"`
+	// Error is the error message.
+	Error string `json:"error"`
+	// EtcdError is the error returned by etcd, if any, e.g.
+	// "etcdserver: permission denied".
+	EtcdError string `json:"etcd_error,omitempty"`
+	// Retryable is whether retrying the command may succeed.
+	Retryable bool `json:"retryable"`
+}
+
+// ErrorClassifier returns the envelope of the error a command exits with the
+// code, refining the code of a generic error.
+type ErrorClassifier func(code int, err error) ErrorEnvelope
+
+var (
+	errorJSON       bool
+	errorClassifier ErrorClassifier
+)
+
+// SetErrorOutput sets whether ExitWithError writes the errors as JSON
+// envelopes, and the classifier of the errors, if not nil.
+func SetErrorOutput(json bool, classify ErrorClassifier) {
+	errorJSON, errorClassifier = json, classify
+}
+
+// ExitWithError writes the error to standard error and exits with the code,
+// or the code the error is classified with.
 func ExitWithError(code int, err error) {
+	e := ErrorEnvelope{Code: code, Error: err.Error(), Retryable: code == ExitBadConnection}
+	if errorClassifier != nil {
+		e = errorClassifier(code, err)
+	}
+	if errorJSON {
+		b, merr := json.Marshal(e)
+		if merr == nil {
+			fmt.Fprintln(os.Stderr, string(b))
+			os.Exit(e.Code)
+		}
+	}
 	fmt.Fprintln(os.Stderr, "Error:", err)
-	os.Exit(code)
+	os.Exit(e.Code)
 }
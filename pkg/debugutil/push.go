@@ -0,0 +1,223 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debugutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ProfileCPU       = "cpu"
+	ProfileHeap      = "heap"
+	ProfileMutex     = "mutex"
+	ProfileGoroutine = "goroutine"
+
+	// MinProfilePushInterval is the minimum interval between the captures of
+	// the pushed profiles.
+	MinProfilePushInterval = 10 * time.Second
+
+	// maxProfilePushBackoff is the maximum number of the captures skipped
+	// after failed pushes.
+	maxProfilePushBackoff = 16
+	profilePushTimeout    = 30 * time.Second
+)
+
+// DefaultPushedProfiles are the profiles pushed when no profile is configured.
+var DefaultPushedProfiles = []string{ProfileCPU, ProfileHeap, ProfileMutex}
+
+// ProfilePushConfig configures the periodic capture and push of profiles.
+type ProfilePushConfig struct {
+	// URL is the endpoint the profiles are posted to.
+	URL string
+	// Name is the application name reported with the profiles.
+	Name string
+	// Instance identifies the process pushing the profiles.
+	Instance string
+	// Profiles are the types of the captured profiles.
+	Profiles []string
+	// Interval is the interval between the captures.
+	Interval time.Duration
+	// CPUDuration is how long the CPU profile is captured for.
+	CPUDuration time.Duration
+}
+
+// Validate checks the profiles and the rate limits of the configuration:
+// the captures are at least MinProfilePushInterval apart, and the CPU is
+// profiled at most half of the time.
+func (cfg ProfilePushConfig) Validate() error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return fmt.Errorf("invalid profile push URL %q (%v)", cfg.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid profile push URL %q (expected http or https scheme)", cfg.URL)
+	}
+	for _, p := range cfg.Profiles {
+		switch p {
+		case ProfileCPU, ProfileHeap, ProfileMutex, ProfileGoroutine:
+		default:
+			return fmt.Errorf("unknown profile %q (expected one of %q, %q, %q, %q)", p, ProfileCPU, ProfileHeap, ProfileMutex, ProfileGoroutine)
+		}
+	}
+	if cfg.Interval < MinProfilePushInterval {
+		return fmt.Errorf("profile push interval %v is shorter than %v", cfg.Interval, MinProfilePushInterval)
+	}
+	if cfg.CPUDuration <= 0 || cfg.CPUDuration > cfg.Interval/2 {
+		return fmt.Errorf("CPU profile duration %v must be positive and at most half of the profile push interval %v", cfg.CPUDuration, cfg.Interval)
+	}
+	return nil
+}
+
+// ProfilePusher periodically captures profiles and posts them in the pprof
+// format to an endpoint, with the ingestion query parameters of Pyroscope.
+// Captures are skipped with an exponential backoff while the pushes fail.
+type ProfilePusher struct {
+	lg     *zap.Logger
+	cfg    ProfilePushConfig
+	client *http.Client
+}
+
+func NewProfilePusher(lg *zap.Logger, cfg ProfilePushConfig) *ProfilePusher {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	if len(cfg.Profiles) == 0 {
+		cfg.Profiles = DefaultPushedProfiles
+	}
+	for _, p := range cfg.Profiles {
+		// set only when there's no existing setting
+		if p == ProfileMutex && runtime.SetMutexProfileFraction(-1) == 0 {
+			runtime.SetMutexProfileFraction(5)
+		}
+	}
+	return &ProfilePusher{lg: lg, cfg: cfg, client: &http.Client{Timeout: profilePushTimeout}}
+}
+
+// Run captures and pushes the profiles every interval until stopc is closed.
+func (p *ProfilePusher) Run(stopc <-chan struct{}) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	failures, skip := 0, 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-stopc:
+			return
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if p.pushAll(stopc) {
+			failures = 0
+			continue
+		}
+		failures++
+		skip = 1<<uint(failures) - 1
+		if skip > maxProfilePushBackoff {
+			skip = maxProfilePushBackoff
+		}
+	}
+}
+
+// pushAll captures and pushes each profile, returning false if any push fails.
+func (p *ProfilePusher) pushAll(stopc <-chan struct{}) bool {
+	ok := true
+	for _, name := range p.cfg.Profiles {
+		from := time.Now()
+		var buf bytes.Buffer
+		if err := p.capture(stopc, name, &buf); err != nil {
+			p.lg.Warn("failed to capture profile", zap.String("profile", name), zap.Error(err))
+			continue
+		}
+		select {
+		case <-stopc:
+			return ok
+		default:
+		}
+		if err := p.push(name, from, time.Now(), buf.Bytes()); err != nil {
+			p.lg.Warn("failed to push profile", zap.String("url", p.cfg.URL), zap.String("profile", name), zap.Error(err))
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (p *ProfilePusher) capture(stopc <-chan struct{}, name string, w io.Writer) error {
+	if name != ProfileCPU {
+		prof := pprof.Lookup(name)
+		if prof == nil {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+		return prof.WriteTo(w, 0)
+	}
+	// fails if the CPU is already profiled, e.g. through /debug/pprof/profile
+	if err := pprof.StartCPUProfile(w); err != nil {
+		return err
+	}
+	select {
+	case <-time.After(p.cfg.CPUDuration):
+	case <-stopc:
+	}
+	pprof.StopCPUProfile()
+	return nil
+}
+
+func (p *ProfilePusher) push(name string, from, until time.Time, profile []byte) error {
+	u, err := url.Parse(p.cfg.URL)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	appName := p.cfg.Name + "." + name
+	if p.cfg.Instance != "" {
+		appName += "{instance=" + p.cfg.Instance + "}"
+	}
+	q.Set("name", appName)
+	q.Set("from", strconv.FormatInt(from.Unix(), 10))
+	q.Set("until", strconv.FormatInt(until.Unix(), 10))
+	q.Set("format", "pprof")
+	q.Set("spyName", "gospy")
+	u.RawQuery = q.Encode()
+
+	ctx, cancel := context.WithTimeout(context.Background(), profilePushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(profile))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %q", resp.Status)
+	}
+	return nil
+}
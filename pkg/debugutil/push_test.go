@@ -0,0 +1,84 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debugutil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+)
+
+func TestProfilePusher(t *testing.T) {
+	namec := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		// pprof profiles are gzip compressed
+		if !bytes.HasPrefix(b, []byte{0x1f, 0x8b}) || r.URL.Query().Get("format") != "pprof" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		namec <- r.URL.Query().Get("name")
+	}))
+	defer srv.Close()
+
+	p := NewProfilePusher(zaptest.NewLogger(t), ProfilePushConfig{
+		URL:         srv.URL + "/ingest",
+		Name:        "etcd",
+		Instance:    "infra1",
+		Profiles:    []string{ProfileCPU, ProfileHeap},
+		Interval:    50 * time.Millisecond,
+		CPUDuration: 10 * time.Millisecond,
+	})
+	stopc := make(chan struct{})
+	defer close(stopc)
+	go p.Run(stopc)
+
+	for _, w := range []string{"etcd.cpu{instance=infra1}", "etcd.heap{instance=infra1}"} {
+		select {
+		case name := <-namec:
+			if name != w {
+				t.Errorf("name = %q, want %q", name, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the %q profile", w)
+		}
+	}
+}
+
+func TestProfilePushConfigValidate(t *testing.T) {
+	valid := ProfilePushConfig{URL: "http://localhost:4040/ingest", Interval: time.Minute, CPUDuration: 10 * time.Second}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []func(*ProfilePushConfig){
+		func(cfg *ProfilePushConfig) { cfg.URL = "localhost:4040" },
+		func(cfg *ProfilePushConfig) { cfg.Profiles = []string{"threadcreate"} },
+		func(cfg *ProfilePushConfig) { cfg.Interval = time.Second },
+		func(cfg *ProfilePushConfig) { cfg.CPUDuration = 40 * time.Second },
+		func(cfg *ProfilePushConfig) { cfg.CPUDuration = 0 },
+	}
+	for i, tt := range tests {
+		cfg := valid
+		tt(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("#%d: expected error for %+v", i, cfg)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package featuregate implements gates enabling or disabling features by
+// name, e.g. with "--feature-gates=A=true,B=false".
+package featuregate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Feature is the name of a feature.
+type Feature string
+
+// Stage is the maturity of a feature.
+type Stage string
+
+const (
+	// Alpha features are disabled by default, and may change or be removed.
+	Alpha = Stage("ALPHA")
+	// Beta features are well tested, and usually enabled by default.
+	Beta = Stage("BETA")
+	// GA features are always enabled, their gates are kept for compatibility.
+	GA = Stage("GA")
+	// Deprecated features are going to be removed.
+	Deprecated = Stage("DEPRECATED")
+)
+
+// FeatureSpec describes a feature.
+type FeatureSpec struct {
+	// Default is whether the feature is enabled by default.
+	Default bool
+	// LockToDefault makes setting the feature to another value than its
+	// default an error, e.g. once it is GA.
+	LockToDefault bool
+	// Stage is the maturity of the feature.
+	Stage Stage
+}
+
+// FeatureStatus is the state of a feature of a gate.
+type FeatureStatus struct {
+	Name Feature
+	FeatureSpec
+	Enabled bool
+}
+
+// FeatureGate is the set of the known features, with the values set by the
+// user overriding their defaults. It implements flag.Value.
+type FeatureGate struct {
+	lg *zap.Logger
+
+	mu    sync.RWMutex
+	known map[Feature]FeatureSpec
+	// set are the values set explicitly.
+	set map[Feature]bool
+}
+
+// New returns a gate of the known features, all at their default.
+func New(lg *zap.Logger, known map[Feature]FeatureSpec) *FeatureGate {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	fg := &FeatureGate{lg: lg, known: make(map[Feature]FeatureSpec, len(known)), set: make(map[Feature]bool)}
+	for f, spec := range known {
+		fg.known[f] = spec
+	}
+	return fg
+}
+
+// Set sets the features of a comma-separated list of "feature=bool" pairs,
+// e.g. "A=true,B=false".
+func (fg *FeatureGate) Set(value string) error {
+	m := make(map[string]bool)
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("missing bool value for feature gate %q (expected %s=true or %s=false)", s, s, s)
+		}
+		k := strings.TrimSpace(kv[0])
+		v, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return fmt.Errorf("invalid value %q of feature gate %q (%v)", kv[1], k, err)
+		}
+		m[k] = v
+	}
+	return fg.SetFromMap(m)
+}
+
+// SetFromMap sets the features of the map. No feature is set if any of them
+// is unknown or locked to another value.
+func (fg *FeatureGate) SetFromMap(m map[string]bool) error {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		spec, ok := fg.known[Feature(k)]
+		if !ok {
+			return fmt.Errorf("unknown feature gate %q (known feature gates: %s)", k, strings.Join(fg.knownFeatures(), ", "))
+		}
+		if spec.LockToDefault && m[k] != spec.Default {
+			return fmt.Errorf("cannot set feature gate %q to %v, locked to %v", k, m[k], spec.Default)
+		}
+	}
+	for _, k := range names {
+		f := Feature(k)
+		fg.set[f] = m[k]
+		switch fg.known[f].Stage {
+		case GA:
+			fg.lg.Warn("setting GA feature gate, the gate will be removed in a future release", zap.String("feature-gate", k), zap.Bool("value", m[k]))
+		case Deprecated:
+			fg.lg.Warn("setting deprecated feature gate, the feature will be removed in a future release", zap.String("feature-gate", k), zap.Bool("value", m[k]))
+		}
+	}
+	return nil
+}
+
+// String returns the explicitly set features, as accepted by Set.
+func (fg *FeatureGate) String() string {
+	if fg == nil {
+		return ""
+	}
+	fg.mu.RLock()
+	defer fg.mu.RUnlock()
+	pairs := make([]string, 0, len(fg.set))
+	for f, v := range fg.set {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", f, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Enabled returns whether the feature is enabled. It panics if the feature
+// is unknown, which is a programming error.
+func (fg *FeatureGate) Enabled(f Feature) bool {
+	fg.mu.RLock()
+	defer fg.mu.RUnlock()
+	if v, ok := fg.set[f]; ok {
+		return v
+	}
+	spec, ok := fg.known[f]
+	if !ok {
+		panic(fmt.Errorf("feature %q is not registered in the feature gate", f))
+	}
+	return spec.Default
+}
+
+// IsSet returns whether the feature has been set explicitly.
+func (fg *FeatureGate) IsSet(f Feature) bool {
+	fg.mu.RLock()
+	defer fg.mu.RUnlock()
+	_, ok := fg.set[f]
+	return ok
+}
+
+// Features returns the status of the known features, sorted by name.
+func (fg *FeatureGate) Features() []FeatureStatus {
+	fg.mu.RLock()
+	defer fg.mu.RUnlock()
+	fs := make([]FeatureStatus, 0, len(fg.known))
+	for f, spec := range fg.known {
+		enabled, ok := fg.set[f]
+		if !ok {
+			enabled = spec.Default
+		}
+		fs = append(fs, FeatureStatus{Name: f, FeatureSpec: spec, Enabled: enabled})
+	}
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Name < fs[j].Name })
+	return fs
+}
+
+// KnownFeatures returns the descriptions of the known features, sorted by
+// name, e.g. "A=true|false (ALPHA - default=false)".
+func (fg *FeatureGate) KnownFeatures() []string {
+	fg.mu.RLock()
+	defer fg.mu.RUnlock()
+	return fg.knownFeatures()
+}
+
+func (fg *FeatureGate) knownFeatures() []string {
+	known := make([]string, 0, len(fg.known))
+	for f, spec := range fg.known {
+		known = append(known, fmt.Sprintf("%s=true|false (%s - default=%t)", f, spec.Stage, spec.Default))
+	}
+	sort.Strings(known)
+	return known
+}
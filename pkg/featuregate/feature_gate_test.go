@@ -0,0 +1,126 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuregate
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap/zaptest"
+)
+
+const (
+	testAlpha      Feature = "TestAlpha"
+	testBeta       Feature = "TestBeta"
+	testGA         Feature = "TestGA"
+	testDeprecated Feature = "TestDeprecated"
+)
+
+var testFeatures = map[Feature]FeatureSpec{
+	testAlpha:      {Default: false, Stage: Alpha},
+	testBeta:       {Default: true, Stage: Beta},
+	testGA:         {Default: true, Stage: GA, LockToDefault: true},
+	testDeprecated: {Default: false, Stage: Deprecated},
+}
+
+func TestFeatureGateSet(t *testing.T) {
+	tests := []struct {
+		value    string
+		wenabled map[Feature]bool
+		wstring  string
+		werr     bool
+	}{
+		{
+			value:    "",
+			wenabled: map[Feature]bool{testAlpha: false, testBeta: true, testGA: true, testDeprecated: false},
+		},
+		{
+			value:    "TestAlpha=true, TestBeta=false",
+			wenabled: map[Feature]bool{testAlpha: true, testBeta: false, testGA: true, testDeprecated: false},
+			wstring:  "TestAlpha=true,TestBeta=false",
+		},
+		{
+			value:    "TestGA=true,TestDeprecated=true",
+			wenabled: map[Feature]bool{testAlpha: false, testBeta: true, testGA: true, testDeprecated: true},
+			wstring:  "TestDeprecated=true,TestGA=true",
+		},
+		{value: "TestAlpha", werr: true},
+		{value: "TestAlpha=yes", werr: true},
+		{value: "TestUnknown=true", werr: true},
+		// locked to default
+		{value: "TestGA=false", werr: true},
+		// nothing is set on errors
+		{value: "TestAlpha=true,TestUnknown=true", werr: true},
+	}
+	for i, tt := range tests {
+		fg := New(zaptest.NewLogger(t), testFeatures)
+		err := fg.Set(tt.value)
+		if (err != nil) != tt.werr {
+			t.Fatalf("#%d: Set(%q) = %v, want error %v", i, tt.value, err, tt.werr)
+		}
+		if err != nil {
+			if s := fg.String(); s != "" {
+				t.Errorf("#%d: String() = %q after error, want empty", i, s)
+			}
+			continue
+		}
+		for f, w := range tt.wenabled {
+			if g := fg.Enabled(f); g != w {
+				t.Errorf("#%d: Enabled(%s) = %v, want %v", i, f, g, w)
+			}
+		}
+		if s := fg.String(); s != tt.wstring {
+			t.Errorf("#%d: String() = %q, want %q", i, s, tt.wstring)
+		}
+	}
+}
+
+func TestFeatureGateIsSet(t *testing.T) {
+	fg := New(zaptest.NewLogger(t), testFeatures)
+	if err := fg.Set("TestBeta=true"); err != nil {
+		t.Fatal(err)
+	}
+	if !fg.IsSet(testBeta) {
+		t.Errorf("IsSet(%s) = false, want true", testBeta)
+	}
+	if fg.IsSet(testAlpha) {
+		t.Errorf("IsSet(%s) = true, want false", testAlpha)
+	}
+}
+
+func TestFeatureGateFeatures(t *testing.T) {
+	fg := New(zaptest.NewLogger(t), testFeatures)
+	if err := fg.Set("TestAlpha=true"); err != nil {
+		t.Fatal(err)
+	}
+	wfs := []FeatureStatus{
+		{Name: testAlpha, FeatureSpec: testFeatures[testAlpha], Enabled: true},
+		{Name: testBeta, FeatureSpec: testFeatures[testBeta], Enabled: true},
+		{Name: testDeprecated, FeatureSpec: testFeatures[testDeprecated], Enabled: false},
+		{Name: testGA, FeatureSpec: testFeatures[testGA], Enabled: true},
+	}
+	if fs := fg.Features(); !reflect.DeepEqual(fs, wfs) {
+		t.Errorf("Features() = %+v, want %+v", fs, wfs)
+	}
+	wknown := []string{
+		"TestAlpha=true|false (ALPHA - default=false)",
+		"TestBeta=true|false (BETA - default=true)",
+		"TestDeprecated=true|false (DEPRECATED - default=false)",
+		"TestGA=true|false (GA - default=true)",
+	}
+	if known := fg.KnownFeatures(); !reflect.DeepEqual(known, wknown) {
+		t.Errorf("KnownFeatures() = %q, want %q", known, wknown)
+	}
+}
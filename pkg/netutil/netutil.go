@@ -21,6 +21,7 @@ import (
 	"net/url"
 	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"go.etcd.io/etcd/client/pkg/v3/types"
@@ -33,8 +34,9 @@ var resolveTCPAddr = resolveTCPAddrDefault
 
 const retryInterval = time.Second
 
-// taken from go's ResolveTCP code but uses configurable ctx
-func resolveTCPAddrDefault(ctx context.Context, addr string) (*net.TCPAddr, error) {
+// taken from go's ResolveTCP code but uses configurable ctx, and returns
+// all the addresses of the host, e.g. both its IPv4 and IPv6 addresses
+func resolveTCPAddrDefault(ctx context.Context, addr string) ([]*net.TCPAddr, error) {
 	host, port, serr := net.SplitHostPort(addr)
 	if serr != nil {
 		return nil, serr
@@ -45,8 +47,8 @@ func resolveTCPAddrDefault(ctx context.Context, addr string) (*net.TCPAddr, erro
 	}
 
 	var ips []net.IPAddr
-	if ip := net.ParseIP(host); ip != nil {
-		ips = []net.IPAddr{{IP: ip}}
+	if ip, zone := parseIPZone(host); ip != nil {
+		ips = []net.IPAddr{{IP: ip, Zone: zone}}
 	} else {
 		// Try as a DNS name.
 		ipss, err := net.DefaultResolver.LookupIPAddr(ctx, host)
@@ -55,9 +57,44 @@ func resolveTCPAddrDefault(ctx context.Context, addr string) (*net.TCPAddr, erro
 		}
 		ips = ipss
 	}
-	// randomize?
-	ip := ips[0]
-	return &net.TCPAddr{IP: ip.IP, Port: portnum, Zone: ip.Zone}, nil
+	addrs := make([]*net.TCPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = &net.TCPAddr{IP: ip.IP, Port: portnum, Zone: ip.Zone}
+	}
+	return addrs, nil
+}
+
+// parseIPZone parses an IP address with an optional IPv6 zone, e.g.
+// "fe80::1%eth0". It returns a nil IP if host is not an IP address.
+func parseIPZone(host string) (net.IP, string) {
+	var zone string
+	if i := strings.LastIndex(host, "%"); i >= 0 {
+		host, zone = host[:i], host[i+1:]
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || (zone != "" && ip.To4() != nil) {
+		return nil, ""
+	}
+	return ip, zone
+}
+
+// canonicalHost returns the host of a URL in a canonical form, so that the
+// equal hosts compare equal: IP addresses are formatted the same way, e.g.
+// "[::1]:2380" for "[0:0::1]:2380", and hostnames are lower-cased. IPv6
+// zones are kept as they are, since interface names are case-sensitive.
+func canonicalHost(hostport string) string {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport
+	}
+	if ip, zone := parseIPZone(host); ip != nil {
+		host = ip.String()
+		if zone != "" {
+			host += "%" + zone
+		}
+		return net.JoinHostPort(host, port)
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
 }
 
 // resolveTCPAddrs is a convenience wrapper for net.ResolveTCPAddr.
@@ -103,19 +140,36 @@ func resolveURL(ctx context.Context, lg *zap.Logger, u url.URL) (string, error)
 		)
 		return "", err
 	}
-	if host == "localhost" || net.ParseIP(host) != nil {
+	if host == "localhost" {
 		return "", nil
 	}
+	if ip, _ := parseIPZone(host); ip != nil {
+		return "", nil
+	}
+	addrs, err := resolveURLAddrs(ctx, lg, u)
+	if err != nil {
+		return "", err
+	}
+	// randomize?
+	return addrs[0].String(), nil
+}
+
+// resolveURLAddrs resolves the host of the URL to all its addresses,
+// retrying until ctx is done.
+func resolveURLAddrs(ctx context.Context, lg *zap.Logger, u url.URL) ([]*net.TCPAddr, error) {
 	for ctx.Err() == nil {
-		tcpAddr, err := resolveTCPAddr(ctx, u.Host)
+		tcpAddrs, err := resolveTCPAddr(ctx, u.Host)
+		if err == nil && len(tcpAddrs) == 0 {
+			err = fmt.Errorf("no address found for %q", u.Host)
+		}
 		if err == nil {
 			lg.Info(
 				"resolved URL Host",
 				zap.String("url", u.String()),
 				zap.String("host", u.Host),
-				zap.String("resolved-addr", tcpAddr.String()),
+				zap.Strings("resolved-addrs", tcpAddrsToStrings(tcpAddrs)),
 			)
-			return tcpAddr.String(), nil
+			return tcpAddrs, nil
 		}
 
 		lg.Warn(
@@ -135,20 +189,30 @@ func resolveURL(ctx context.Context, lg *zap.Logger, u url.URL) (string, error)
 				zap.Duration("retry-interval", retryInterval),
 				zap.Error(err),
 			)
-			return "", err
+			return nil, err
 		case <-time.After(retryInterval):
 		}
 	}
-	return "", ctx.Err()
+	return nil, ctx.Err()
+}
+
+func tcpAddrsToStrings(addrs []*net.TCPAddr) []string {
+	ss := make([]string, len(addrs))
+	for i := range addrs {
+		ss[i] = addrs[i].String()
+	}
+	return ss
 }
 
 // urlsEqual checks equality of url.URLS between two arrays.
-// This check pass even if an URL is in hostname and opposite is in IP address.
+// This check pass even if an URL is in hostname and opposite is in IP address,
+// including any of the addresses of a dual-stack hostname.
 func urlsEqual(ctx context.Context, lg *zap.Logger, a []url.URL, b []url.URL) (bool, error) {
 	if len(a) != len(b) {
 		return false, fmt.Errorf("len(%q) != len(%q)", urlsToStrings(a), urlsToStrings(b))
 	}
 
+	a, b = canonicalURLs(a), canonicalURLs(b)
 	sort.Sort(types.URLs(a))
 	sort.Sort(types.URLs(b))
 	var needResolve bool
@@ -167,17 +231,110 @@ func urlsEqual(ctx context.Context, lg *zap.Logger, a []url.URL, b []url.URL) (b
 	if err != nil {
 		return false, err
 	}
-	a, b = urls[0], urls[1]
-	sort.Sort(types.URLs(a))
-	sort.Sort(types.URLs(b))
-	for i := range a {
-		if !reflect.DeepEqual(a[i], b[i]) {
-			return false, fmt.Errorf("resolved urls: %q != %q", a[i].String(), b[i].String())
+	ra, rb := urls[0], urls[1]
+	sort.Sort(types.URLs(ra))
+	sort.Sort(types.URLs(rb))
+	for i := range ra {
+		if !reflect.DeepEqual(ra[i], rb[i]) {
+			// The first addresses of the hostnames may not be of the family
+			// of the opposite addresses, e.g. on dual-stack hosts.
+			if urlsMatchAnyAddr(ctx, lg, a, b) {
+				return true, nil
+			}
+			return false, fmt.Errorf("resolved urls: %q != %q", ra[i].String(), rb[i].String())
 		}
 	}
 	return true, nil
 }
 
+// canonicalURLs returns copies of the URLs with canonical hosts.
+func canonicalURLs(us []url.URL) []url.URL {
+	cus := make([]url.URL, len(us))
+	for i, u := range us {
+		if u.Scheme != "unix" && u.Scheme != "unixs" {
+			u.Host = canonicalHost(u.Host)
+		}
+		cus[i] = u
+	}
+	return cus
+}
+
+// urlsMatchAnyAddr returns whether each URL of a can be paired with a
+// distinct URL of b sharing one of its resolved addresses.
+func urlsMatchAnyAddr(ctx context.Context, lg *zap.Logger, a []url.URL, b []url.URL) bool {
+	addrsA, err := urlsAddrs(ctx, lg, a)
+	if err != nil {
+		return false
+	}
+	addrsB, err := urlsAddrs(ctx, lg, b)
+	if err != nil {
+		return false
+	}
+	shareAddr := func(i, j int) bool {
+		for addr := range addrsA[i] {
+			if addrsB[j][addr] {
+				return true
+			}
+		}
+		return false
+	}
+
+	// bipartite matching of the URLs with augmenting paths
+	matchB := make([]int, len(b))
+	for j := range matchB {
+		matchB[j] = -1
+	}
+	var augment func(i int, seen []bool) bool
+	augment = func(i int, seen []bool) bool {
+		for j := range b {
+			if seen[j] || !shareAddr(i, j) {
+				continue
+			}
+			seen[j] = true
+			if matchB[j] == -1 || augment(matchB[j], seen) {
+				matchB[j] = i
+				return true
+			}
+		}
+		return false
+	}
+	for i := range a {
+		if !augment(i, make([]bool, len(b))) {
+			return false
+		}
+	}
+	return true
+}
+
+// urlsAddrs returns the set of the URLs of all the resolved addresses of
+// each URL.
+func urlsAddrs(ctx context.Context, lg *zap.Logger, us []url.URL) ([]map[string]bool, error) {
+	sets := make([]map[string]bool, len(us))
+	for i, u := range us {
+		sets[i] = map[string]bool{u.String(): true}
+		if u.Scheme == "unix" || u.Scheme == "unixs" {
+			continue
+		}
+		host, _, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		if ip, _ := parseIPZone(host); host == "localhost" || ip != nil {
+			continue
+		}
+		addrs, err := resolveURLAddrs(ctx, lg, u)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			nu := u
+			nu.Host = addr.String()
+			sets[i][nu.String()] = true
+		}
+	}
+	return sets, nil
+}
+
 // URLStringsEqual returns "true" if given URLs are valid
 // and resolved to same IP addresses. Otherwise, return "false"
 // and error, if any.
@@ -115,7 +115,7 @@ func TestResolveTCPAddrs(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		resolveTCPAddr = func(ctx context.Context, addr string) (*net.TCPAddr, error) {
+		resolveTCPAddr = func(ctx context.Context, addr string) ([]*net.TCPAddr, error) {
 			host, port, err := net.SplitHostPort(addr)
 			if err != nil {
 				return nil, err
@@ -127,7 +127,7 @@ func TestResolveTCPAddrs(t *testing.T) {
 			if err != nil {
 				return nil, err
 			}
-			return &net.TCPAddr{IP: net.ParseIP(tt.hostMap[host]), Port: i, Zone: ""}, nil
+			return []*net.TCPAddr{{IP: net.ParseIP(tt.hostMap[host]), Port: i, Zone: ""}}, nil
 		}
 		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
 		urls, err := resolveTCPAddrs(ctx, zaptest.NewLogger(t), tt.urls)
@@ -151,7 +151,7 @@ func TestURLsEqual(t *testing.T) {
 		"first.com":   "10.0.11.1",
 		"second.com":  "10.0.11.2",
 	}
-	resolveTCPAddr = func(ctx context.Context, addr string) (*net.TCPAddr, error) {
+	resolveTCPAddr = func(ctx context.Context, addr string) ([]*net.TCPAddr, error) {
 		host, port, herr := net.SplitHostPort(addr)
 		if herr != nil {
 			return nil, herr
@@ -163,7 +163,7 @@ func TestURLsEqual(t *testing.T) {
 		if err != nil {
 			return nil, err
 		}
-		return &net.TCPAddr{IP: net.ParseIP(hostm[host]), Port: i, Zone: ""}, nil
+		return []*net.TCPAddr{{IP: net.ParseIP(hostm[host]), Port: i, Zone: ""}}, nil
 	}
 
 	tests := []struct {
@@ -314,13 +314,13 @@ func TestURLsEqual(t *testing.T) {
 }
 func TestURLStringsEqual(t *testing.T) {
 	defer func() { resolveTCPAddr = resolveTCPAddrDefault }()
-	errOnResolve := func(ctx context.Context, addr string) (*net.TCPAddr, error) {
+	errOnResolve := func(ctx context.Context, addr string) ([]*net.TCPAddr, error) {
 		return nil, fmt.Errorf("unexpected attempt to resolve: %q", addr)
 	}
 	cases := []struct {
 		urlsA    []string
 		urlsB    []string
-		resolver func(ctx context.Context, addr string) (*net.TCPAddr, error)
+		resolver func(ctx context.Context, addr string) ([]*net.TCPAddr, error)
 	}{
 		{[]string{"http://127.0.0.1:8080"}, []string{"http://127.0.0.1:8080"}, resolveTCPAddrDefault},
 		{[]string{
@@ -343,3 +343,97 @@ func TestURLStringsEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestURLsEqualIPv6(t *testing.T) {
+	defer func() { resolveTCPAddr = resolveTCPAddrDefault }()
+	resolveTCPAddr = func(ctx context.Context, addr string) ([]*net.TCPAddr, error) {
+		return nil, fmt.Errorf("unexpected attempt to resolve: %q", addr)
+	}
+	tests := []struct {
+		a, b []string
+	}{
+		{[]string{"http://[::1]:2380"}, []string{"http://[0:0::1]:2380"}},
+		{[]string{"http://[FD00::1]:2380"}, []string{"http://[fd00::1]:2380"}},
+		{[]string{"http://[fe80::1%25eth0]:2380"}, []string{"http://[FE80::1%25eth0]:2380"}},
+		{[]string{"http://Infra0.Example.com:2380"}, []string{"http://infra0.example.com:2380"}},
+		{
+			[]string{"http://10.0.0.1:2380", "http://[fd00::1]:2380"},
+			[]string{"http://[fd00:0::1]:2380", "http://10.0.0.1:2380"},
+		},
+	}
+	for i, tt := range tests {
+		ok, err := URLStringsEqual(context.TODO(), zaptest.NewLogger(t), tt.a, tt.b)
+		if !ok || err != nil {
+			t.Errorf("#%d: URLStringsEqual(%q, %q) = %v, %v, want true", i, tt.a, tt.b, ok, err)
+		}
+	}
+}
+
+func TestURLsEqualDualStack(t *testing.T) {
+	defer func() { resolveTCPAddr = resolveTCPAddrDefault }()
+	hostm := map[string][]string{
+		"dual.example.com": {"10.0.0.1", "fd00::1"},
+		"v6.example.com":   {"fd00::2"},
+	}
+	resolveTCPAddr = func(ctx context.Context, addr string) ([]*net.TCPAddr, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := hostm[host]; !ok {
+			return nil, errors.New("cannot resolve host")
+		}
+		i, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, err
+		}
+		var addrs []*net.TCPAddr
+		for _, ip := range hostm[host] {
+			addrs = append(addrs, &net.TCPAddr{IP: net.ParseIP(ip), Port: i})
+		}
+		return addrs, nil
+	}
+	tests := []struct {
+		a, b   []string
+		expect bool
+		err    string
+	}{
+		{[]string{"http://dual.example.com:2380"}, []string{"http://10.0.0.1:2380"}, true, ""},
+		{[]string{"http://dual.example.com:2380"}, []string{"http://[fd00::1]:2380"}, true, ""},
+		{
+			[]string{"http://dual.example.com:2380", "http://v6.example.com:2380"},
+			[]string{"http://[fd00::2]:2380", "http://[fd00::1]:2380"},
+			true, "",
+		},
+		{
+			[]string{"http://dual.example.com:2380"},
+			[]string{"http://[fd00::2]:2380"},
+			false, `resolved urls: "http://10.0.0.1:2380" != "http://[fd00::2]:2380"`,
+		},
+		{
+			// each URL must match a distinct URL
+			[]string{"http://dual.example.com:2380", "http://v6.example.com:2380"},
+			[]string{"http://10.0.0.1:2380", "http://[fd00::1]:2380"},
+			false, `resolved urls: "http://[fd00::2]:2380" != "http://[fd00::1]:2380"`,
+		},
+	}
+	for i, tt := range tests {
+		ok, err := URLStringsEqual(context.TODO(), zaptest.NewLogger(t), tt.a, tt.b)
+		if ok != tt.expect {
+			t.Errorf("#%d: URLStringsEqual(%q, %q) = %v, want %v", i, tt.a, tt.b, ok, tt.expect)
+		}
+		if (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("#%d: err = %v, want %q", i, err, tt.err)
+		}
+	}
+}
+
+func TestResolveTCPAddrDefaultZone(t *testing.T) {
+	addrs, err := resolveTCPAddrDefault(context.TODO(), "[fe80::1%eth0]:2380")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].String() != "[fe80::1%eth0]:2380" {
+		t.Errorf("addrs = %v, want [[fe80::1%%eth0]:2380]", addrs)
+	}
+}
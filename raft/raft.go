@@ -124,6 +124,12 @@ type Config struct {
 	// HeartbeatTick. We suggest ElectionTick = 10 * HeartbeatTick to avoid
 	// unnecessary leader switching.
 	ElectionTick int
+	// ElectionJitterTick is the number of distinct random values, in ticks,
+	// added to ElectionTick to compute the election timeout of each election,
+	// which is in [ElectionTick, ElectionTick + ElectionJitterTick - 1]. A
+	// larger range lowers the chance of split votes, a smaller one bounds the
+	// time of an election. It defaults to ElectionTick if 0.
+	ElectionJitterTick int
 	// HeartbeatTick is the number of Node.Tick invocations that must pass between
 	// heartbeats. That is, a leader sends heartbeat messages to maintain its
 	// leadership every HeartbeatTick ticks.
@@ -211,6 +217,13 @@ func (c *Config) validate() error {
 		return errors.New("election tick must be greater than heartbeat tick")
 	}
 
+	if c.ElectionJitterTick < 0 {
+		return errors.New("election jitter tick cannot be negative")
+	}
+	if c.ElectionJitterTick == 0 {
+		c.ElectionJitterTick = c.ElectionTick
+	}
+
 	if c.Storage == nil {
 		return errors.New("storage cannot be nil")
 	}
@@ -297,8 +310,9 @@ type raft struct {
 
 	heartbeatTimeout int
 	electionTimeout  int
+	electionJitter   int
 	// randomizedElectionTimeout is a random number between
-	// [electiontimeout, 2 * electiontimeout - 1]. It gets reset
+	// [electiontimeout, electiontimeout + electionjitter - 1]. It gets reset
 	// when raft changes its state to follower or candidate.
 	randomizedElectionTimeout int
 	disableProposalForwarding bool
@@ -334,6 +348,7 @@ func newRaft(c *Config) *raft {
 		maxUncommittedSize:        c.MaxUncommittedEntriesSize,
 		prs:                       tracker.MakeProgressTracker(c.MaxInflightMsgs),
 		electionTimeout:           c.ElectionTick,
+		electionJitter:            c.ElectionJitterTick,
 		heartbeatTimeout:          c.HeartbeatTick,
 		logger:                    c.Logger,
 		checkQuorum:               c.CheckQuorum,
@@ -1710,13 +1725,13 @@ func (r *raft) loadState(state pb.HardState) {
 
 // pastElectionTimeout returns true iff r.electionElapsed is greater
 // than or equal to the randomized election timeout in
-// [electiontimeout, 2 * electiontimeout - 1].
+// [electiontimeout, electiontimeout + electionjitter - 1].
 func (r *raft) pastElectionTimeout() bool {
 	return r.electionElapsed >= r.randomizedElectionTimeout
 }
 
 func (r *raft) resetRandomizedElectionTimeout() {
-	r.randomizedElectionTimeout = r.electionTimeout + globalRand.Intn(r.electionTimeout)
+	r.randomizedElectionTimeout = r.electionTimeout + globalRand.Intn(r.electionJitter)
 }
 
 func (r *raft) sendTimeoutNow(to uint64) {
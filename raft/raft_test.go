@@ -1207,6 +1207,46 @@ func TestPastElectionTimeout(t *testing.T) {
 	}
 }
 
+// TestElectionJitterTick ensures that the randomized election timeout is in
+// [ElectionTick, ElectionTick + ElectionJitterTick - 1].
+func TestElectionJitterTick(t *testing.T) {
+	tests := []struct {
+		jitter     int
+		wmin, wmax int
+	}{
+		{0, 10, 19},
+		{1, 10, 10},
+		{3, 10, 12},
+		{30, 10, 39},
+	}
+	for i, tt := range tests {
+		cfg := newTestConfig(1, 10, 1, newTestMemoryStorage(withPeers(1)))
+		cfg.ElectionJitterTick = tt.jitter
+		sm := newRaft(cfg)
+		seen := make(map[int]bool)
+		for j := 0; j < 10000; j++ {
+			sm.resetRandomizedElectionTimeout()
+			seen[sm.randomizedElectionTimeout] = true
+		}
+		for d := range seen {
+			if d < tt.wmin || d > tt.wmax {
+				t.Errorf("#%d: randomized election timeout = %d, want in [%d, %d]", i, d, tt.wmin, tt.wmax)
+			}
+		}
+		for d := tt.wmin; d <= tt.wmax; d++ {
+			if !seen[d] {
+				t.Errorf("#%d: randomized election timeout %d should happen", i, d)
+			}
+		}
+	}
+
+	cfg := newTestConfig(1, 10, 1, newTestMemoryStorage(withPeers(1)))
+	cfg.ElectionJitterTick = -1
+	if err := cfg.validate(); err == nil {
+		t.Errorf("validate() = nil with negative election jitter tick, want error")
+	}
+}
+
 // ensure that the Step function ignores the message from old term and does not pass it to the
 // actual stepX function.
 func TestStepIgnoreOldTermMsg(t *testing.T) {
@@ -216,6 +216,25 @@ function grpcproxy_e2e_pass {
       -timeout=30m -tags cluster_proxy "${COMMON_TEST_FLAGS[@]}" "$@"
 }
 
+# fuzz_pass runs each fuzz target for FUZZ_TIME, native fuzzing requiring
+# go1.18+. The inputs failing are written to the testdata/fuzz directory of
+# the package, to be run as the seeds of the target by the unit tests.
+function fuzz_pass {
+  local targets=(
+    "server:./storage/wal:FuzzDecodeRecord"
+    "server:./storage/wal:FuzzReadAll"
+    "server:./etcdserver/api/snap:FuzzRead"
+    "server:./storage/schema:FuzzReaders"
+    "server:./etcdserver/api/v3rpc:FuzzTxnRequest"
+  )
+  local target module pkg fuzz
+  for target in "${targets[@]}"; do
+    IFS=':' read -r module pkg fuzz <<< "${target}"
+    log_callout "Fuzzing ${fuzz} of ${module}/${pkg#./}"
+    run_for_module "${module}" run go test "${pkg}" -run='^$' -fuzz="^${fuzz}\$" -fuzztime="${FUZZ_TIME:-1m}" "$@" || return $?
+  done
+}
+
 ################# COVERAGE #####################################################
 
 # Builds artifacts used by tests/e2e in coverage mode.
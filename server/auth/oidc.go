@@ -0,0 +1,459 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+const (
+	optOIDCIssuer         = "issuer"
+	optOIDCAudience       = "audience"
+	optOIDCJWKSURL        = "jwks-url"
+	optOIDCCAFile         = "ca-file"
+	optOIDCUsernameClaim  = "username-claim"
+	optOIDCUsernamePrefix = "username-prefix"
+	optOIDCRoleMapping    = "role-mapping"
+
+	defaultOIDCUsernameClaim  = "sub"
+	defaultOIDCUsernamePrefix = "oidc:"
+)
+
+var knownOIDCOptions = map[string]bool{
+	optOIDCIssuer:         true,
+	optOIDCAudience:       true,
+	optOIDCJWKSURL:        true,
+	optOIDCCAFile:         true,
+	optOIDCUsernameClaim:  true,
+	optOIDCUsernamePrefix: true,
+	optOIDCRoleMapping:    true,
+}
+
+var (
+	// oidcRequestTimeout bounds the requests to the OIDC issuer.
+	oidcRequestTimeout = 10 * time.Second
+	// oidcKeysMinRefreshInterval is the minimum interval between the fetches
+	// of the keys of the OIDC issuer, for the tokens signed with unknown keys
+	// not to flood it.
+	oidcKeysMinRefreshInterval = 10 * time.Second
+	// oidcSigningMethods are the signing methods of the OIDC tokens accepted,
+	// the asymmetric ones the public keys of the issuer verify.
+	oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+)
+
+// tokenOIDC validates the OIDC bearer tokens of an issuer, mapping the
+// values of their claims to etcd roles, e.g. with the role mapping
+// {"groups": {"platform": ["root"], "team-a": ["team-a-rw"]}} the tokens
+// with "team-a" in their "groups" claim have the permissions of the role
+// "team-a-rw". The tokens mapped to no role are rejected.
+//
+// The etcd users still authenticate with the simple tokens assigned on
+// authentication.
+type tokenOIDC struct {
+	lg             *zap.Logger
+	issuer         string
+	audience       string
+	usernameClaim  string
+	usernamePrefix string
+	// roleMapping maps the values of the claims of the tokens to etcd roles,
+	// by claim name.
+	roleMapping map[string]map[string][]string
+	keys        *oidcKeySet
+	// simple assigns and validates the tokens of the etcd users.
+	simple *tokenSimple
+}
+
+func (t *tokenOIDC) enable()                         { t.simple.enable() }
+func (t *tokenOIDC) disable()                        { t.simple.disable() }
+func (t *tokenOIDC) invalidateUser(username string)  { t.simple.invalidateUser(username) }
+func (t *tokenOIDC) genTokenPrefix() (string, error) { return t.simple.genTokenPrefix() }
+
+func (t *tokenOIDC) assign(ctx context.Context, username string, revision uint64) (string, error) {
+	return t.simple.assign(ctx, username, revision)
+}
+
+func (t *tokenOIDC) info(ctx context.Context, token string, rev uint64) (*AuthInfo, bool) {
+	// the simple tokens have two dot separated parts, JWTs three
+	if strings.Count(token, ".") != 2 {
+		return t.simple.info(ctx, token, rev)
+	}
+
+	parser := jwt.Parser{ValidMethods: oidcSigningMethods}
+	parsed, err := parser.Parse(token, func(tk *jwt.Token) (interface{}, error) {
+		kid, _ := tk.Header["kid"].(string)
+		return t.keys.key(ctx, kid)
+	})
+	if err != nil {
+		t.lg.Warn("failed to parse an OIDC token", zap.Error(err))
+		return nil, false
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !parsed.Valid || !ok {
+		t.lg.Warn("failed to obtain claims from an OIDC token")
+		return nil, false
+	}
+	if !claims.VerifyIssuer(t.issuer, true) || !claims.VerifyAudience(t.audience, true) || !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		t.lg.Warn(
+			"invalid OIDC token issuer, audience or expiry",
+			zap.Any("issuer", claims["iss"]),
+			zap.Any("audience", claims["aud"]),
+			zap.Any("expiry", claims["exp"]),
+		)
+		return nil, false
+	}
+
+	username, _ := claims[t.usernameClaim].(string)
+	if username == "" {
+		t.lg.Warn("OIDC token without username claim", zap.String("claim", t.usernameClaim))
+		return nil, false
+	}
+	username = t.usernamePrefix + username
+	roles := t.roles(claims)
+	if len(roles) == 0 {
+		t.lg.Warn("OIDC token mapped to no role", zap.String("user-name", username))
+		return nil, false
+	}
+	return &AuthInfo{Username: username, Revision: rev, Roles: roles}, true
+}
+
+// roles returns the sorted roles the claims are mapped to.
+func (t *tokenOIDC) roles(claims jwt.MapClaims) []string {
+	set := make(map[string]struct{})
+	for claim, mapping := range t.roleMapping {
+		var values []string
+		switch v := claims[claim].(type) {
+		case string:
+			values = append(values, v)
+		case []interface{}:
+			for _, e := range v {
+				if s, ok := e.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+		for _, v := range values {
+			for _, role := range mapping[v] {
+				set[role] = struct{}{}
+			}
+		}
+	}
+	roles := make([]string, 0, len(set))
+	for role := range set {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func newTokenProviderOIDC(lg *zap.Logger, optMap map[string]string, simple *tokenSimple) (*tokenOIDC, error) {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+
+	var keys = make([]string, 0, len(optMap))
+	for k := range optMap {
+		if !knownOIDCOptions[k] {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		lg.Warn("unknown OIDC options", zap.Strings("keys", keys))
+	}
+
+	t := &tokenOIDC{
+		lg:             lg,
+		issuer:         optMap[optOIDCIssuer],
+		audience:       optMap[optOIDCAudience],
+		usernameClaim:  defaultOIDCUsernameClaim,
+		usernamePrefix: defaultOIDCUsernamePrefix,
+		simple:         simple,
+	}
+	if u, err := url.Parse(t.issuer); err != nil || u.Scheme != "https" || u.Host == "" {
+		lg.Error("OIDC issuer must be an https URL", zap.String("issuer", t.issuer))
+		return nil, ErrInvalidAuthOpts
+	}
+	if t.audience == "" {
+		lg.Error("OIDC audience is required")
+		return nil, ErrInvalidAuthOpts
+	}
+	if claim := optMap[optOIDCUsernameClaim]; claim != "" {
+		t.usernameClaim = claim
+	}
+	if prefix, ok := optMap[optOIDCUsernamePrefix]; ok {
+		t.usernamePrefix = prefix
+	}
+
+	file := optMap[optOIDCRoleMapping]
+	if file == "" {
+		lg.Error("OIDC role mapping is required")
+		return nil, ErrInvalidAuthOpts
+	}
+	b, err := os.ReadFile(file)
+	if err == nil {
+		err = json.Unmarshal(b, &t.roleMapping)
+	}
+	if err != nil {
+		lg.Error("problem loading OIDC role mapping", zap.String("file", file), zap.Error(err))
+		return nil, ErrInvalidAuthOpts
+	}
+
+	client := &http.Client{Timeout: oidcRequestTimeout}
+	if file := optMap[optOIDCCAFile]; file != "" {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			lg.Error("problem loading OIDC CA file", zap.String("file", file), zap.Error(err))
+			return nil, ErrInvalidAuthOpts
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			lg.Error("no certificate in OIDC CA file", zap.String("file", file))
+			return nil, ErrInvalidAuthOpts
+		}
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		}
+	}
+	t.keys = &oidcKeySet{lg: lg, client: client, issuer: t.issuer, url: optMap[optOIDCJWKSURL]}
+	return t, nil
+}
+
+// oidcKeySet is the set of the public keys of an OIDC issuer, fetched from
+// its JWKS endpoint, and fetched again for the tokens signed with an unknown
+// key as the issuer rotates its keys.
+type oidcKeySet struct {
+	lg     *zap.Logger
+	client *http.Client
+	issuer string
+
+	// mu protects the fields below, not held while the keys are fetched for
+	// the tokens signed with the known keys not to wait for the issuer.
+	mu sync.Mutex
+	// url is the JWKS endpoint, discovered from the issuer if empty.
+	url     string
+	keys    map[string]interface{}
+	fetched time.Time
+	// fetching is closed once the keys being fetched are swapped in, nil if
+	// no fetch is in flight, the tokens signed with unknown keys waiting for
+	// a single fetch.
+	fetching chan struct{}
+	// fetchErr is the error of the last fetch.
+	fetchErr error
+}
+
+// key returns the public key with the key ID.
+func (ks *oidcKeySet) key(ctx context.Context, kid string) (interface{}, error) {
+	ks.mu.Lock()
+	if k, ok := ks.keys[kid]; ok {
+		ks.mu.Unlock()
+		return k, nil
+	}
+	donec := ks.fetching
+	if donec == nil {
+		if time.Since(ks.fetched) < oidcKeysMinRefreshInterval {
+			ks.mu.Unlock()
+			return nil, fmt.Errorf("unknown OIDC issuer key %q", kid)
+		}
+		ks.fetched = time.Now()
+		donec = make(chan struct{})
+		ks.fetching = donec
+		go ks.refresh(ks.url, donec)
+	}
+	ks.mu.Unlock()
+
+	select {
+	case <-donec:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if k, ok := ks.keys[kid]; ok {
+		return k, nil
+	}
+	if ks.fetchErr != nil {
+		return nil, ks.fetchErr
+	}
+	return nil, fmt.Errorf("unknown OIDC issuer key %q", kid)
+}
+
+// refresh fetches the keys from the JWKS endpoint jwksURL, discovered from the
+// issuer if empty, swapping them in and closing donec once fetched. The fetch
+// is not bound to the context of a token, the other tokens waiting for it.
+func (ks *oidcKeySet) refresh(jwksURL string, donec chan struct{}) {
+	jwksURL, keys, err := ks.fetch(context.Background(), jwksURL)
+	if err != nil {
+		ks.lg.Warn("failed to fetch OIDC issuer keys", zap.String("issuer", ks.issuer), zap.Error(err))
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err == nil {
+		ks.url, ks.keys = jwksURL, keys
+	}
+	ks.fetchErr = err
+	ks.fetching = nil
+	close(donec)
+}
+
+// fetch returns the JWKS endpoint, discovered from the issuer if jwksURL is empty,
+// and the keys fetched from it.
+func (ks *oidcKeySet) fetch(ctx context.Context, jwksURL string) (string, map[string]interface{}, error) {
+	if jwksURL == "" {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := ks.get(ctx, strings.TrimSuffix(ks.issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+			return "", nil, err
+		}
+		if doc.Issuer != ks.issuer {
+			return "", nil, fmt.Errorf("OIDC discovery issuer %q does not match %q", doc.Issuer, ks.issuer)
+		}
+		if doc.JWKSURI == "" {
+			return "", nil, errors.New("OIDC discovery without jwks_uri")
+		}
+		jwksURL = doc.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := ks.get(ctx, jwksURL, &set); err != nil {
+		return "", nil, err
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		k, err := jwk.publicKey()
+		if err != nil {
+			ks.lg.Warn("ignored an OIDC issuer key", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[jwk.Kid] = k
+	}
+	ks.lg.Info("fetched OIDC issuer keys", zap.String("url", jwksURL), zap.Int("keys", len(keys)))
+	return jwksURL, keys, nil
+}
+
+func (ks *oidcKeySet) get(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set, RFC 7517.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// N and E are the modulus and exponent of the RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// Crv, X and Y are the curve and coordinates of the EC keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,341 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"go.uber.org/zap/zaptest"
+)
+
+// testOIDCIssuer is an OIDC issuer serving its discovery document and keys.
+type testOIDCIssuer struct {
+	srv    *httptest.Server
+	caFile string
+
+	mu   sync.Mutex
+	keys map[string]interface{}
+	// fetches is the number of times the keys were fetched.
+	fetches int
+}
+
+func newTestOIDCIssuer(t *testing.T) *testOIDCIssuer {
+	iss := &testOIDCIssuer{keys: make(map[string]interface{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": iss.srv.URL, "jwks_uri": iss.srv.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		iss.mu.Lock()
+		defer iss.mu.Unlock()
+		iss.fetches++
+		var keys []map[string]string
+		for kid, k := range iss.keys {
+			enc := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+			switch k := k.(type) {
+			case *rsa.PrivateKey:
+				keys = append(keys, map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": enc(k.N), "e": enc(big.NewInt(int64(k.E)))})
+			case *ecdsa.PrivateKey:
+				keys = append(keys, map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": enc(k.X), "y": enc(k.Y)})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+	iss.srv = httptest.NewTLSServer(mux)
+	t.Cleanup(iss.srv.Close)
+
+	iss.caFile = filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: iss.srv.Certificate().Raw})
+	if err := os.WriteFile(iss.caFile, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return iss
+}
+
+// addKey adds a new signing key with the key ID to the keys of the issuer.
+func (iss *testOIDCIssuer) addKey(t *testing.T, kid string, ec bool) {
+	var (
+		k   interface{}
+		err error
+	)
+	if ec {
+		k, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	} else {
+		k, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.keys[kid] = k
+}
+
+// token returns a token with the claims signed with the key of the key ID,
+// the claims overriding the default claims of a valid token.
+func (iss *testOIDCIssuer) token(t *testing.T, kid string, claims jwt.MapClaims) string {
+	c := jwt.MapClaims{
+		"iss":    iss.srv.URL,
+		"aud":    "etcd",
+		"sub":    "alice",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []string{"team-a"},
+	}
+	for k, v := range claims {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	iss.mu.Lock()
+	k := iss.keys[kid]
+	iss.mu.Unlock()
+	method := jwt.SigningMethod(jwt.SigningMethodRS256)
+	if _, ok := k.(*ecdsa.PrivateKey); ok {
+		method = jwt.SigningMethodES256
+	}
+	tk := jwt.NewWithClaims(method, c)
+	tk.Header["kid"] = kid
+	token, err := tk.SignedString(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func newTestTokenProviderOIDC(t *testing.T, iss *testOIDCIssuer) *tokenOIDC {
+	mapping := filepath.Join(t.TempDir(), "role-mapping.json")
+	if err := os.WriteFile(mapping, []byte(`{"groups": {"team-a": ["team-a-rw", "readers"], "admins": ["root"]}, "sub": {"bob": ["readers"]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tp, err := newTokenProviderOIDC(zaptest.NewLogger(t), map[string]string{
+		optOIDCIssuer:      iss.srv.URL,
+		optOIDCAudience:    "etcd",
+		optOIDCCAFile:      iss.caFile,
+		optOIDCRoleMapping: mapping,
+	}, newTokenProviderSimple(zaptest.NewLogger(t), dummyIndexWaiter, simpleTokenTTLDefault))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tp
+}
+
+func TestOIDCInfo(t *testing.T) {
+	iss := newTestOIDCIssuer(t)
+	iss.addKey(t, "rsa", false)
+	iss.addKey(t, "ec", true)
+	tp := newTestTokenProviderOIDC(t, iss)
+
+	other := newTestOIDCIssuer(t)
+	other.addKey(t, "rsa", false)
+
+	tests := []struct {
+		name   string
+		token  string
+		wroles []string
+	}{
+		{name: "rsa", token: iss.token(t, "rsa", nil), wroles: []string{"readers", "team-a-rw"}},
+		{name: "ec", token: iss.token(t, "ec", nil), wroles: []string{"readers", "team-a-rw"}},
+		{name: "string claim", token: iss.token(t, "rsa", jwt.MapClaims{"sub": "bob", "groups": nil}), wroles: []string{"readers"}},
+		{name: "audience list", token: iss.token(t, "rsa", jwt.MapClaims{"aud": []string{"other", "etcd"}}), wroles: []string{"readers", "team-a-rw"}},
+		{name: "root", token: iss.token(t, "rsa", jwt.MapClaims{"groups": []string{"admins", "unknown"}}), wroles: []string{"root"}},
+		{name: "no role", token: iss.token(t, "rsa", jwt.MapClaims{"groups": []string{"unknown"}})},
+		{name: "wrong audience", token: iss.token(t, "rsa", jwt.MapClaims{"aud": "other"})},
+		{name: "wrong issuer", token: iss.token(t, "rsa", jwt.MapClaims{"iss": "https://other.example.com"})},
+		{name: "expired", token: iss.token(t, "rsa", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})},
+		{name: "no expiry", token: iss.token(t, "rsa", jwt.MapClaims{"exp": nil})},
+		{name: "no username", token: iss.token(t, "rsa", jwt.MapClaims{"sub": nil})},
+		{name: "other issuer key", token: other.token(t, "rsa", jwt.MapClaims{"iss": iss.srv.URL})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ai, ok := tp.info(context.TODO(), tt.token, 123)
+			if tt.wroles == nil {
+				if ok {
+					t.Fatalf("info() = %+v, want the token to be rejected", ai)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("info() rejected the token")
+			}
+			if ai.Revision != 123 || !reflect.DeepEqual(ai.Roles, tt.wroles) {
+				t.Fatalf("info() = %+v, want revision 123 and roles %v", ai, tt.wroles)
+			}
+		})
+	}
+
+	ai, _ := tp.info(context.TODO(), iss.token(t, "rsa", nil), 123)
+	if ai.Username != "oidc:alice" {
+		t.Fatalf("username = %q, want %q", ai.Username, "oidc:alice")
+	}
+}
+
+// TestOIDCKeyRotation ensures the tokens signed with a new key of the issuer
+// are validated once the keys are fetched again.
+func TestOIDCKeyRotation(t *testing.T) {
+	defer func(d time.Duration) { oidcKeysMinRefreshInterval = d }(oidcKeysMinRefreshInterval)
+	oidcKeysMinRefreshInterval = time.Hour
+
+	iss := newTestOIDCIssuer(t)
+	iss.addKey(t, "old", false)
+	tp := newTestTokenProviderOIDC(t, iss)
+	if _, ok := tp.info(context.TODO(), iss.token(t, "old", nil), 1); !ok {
+		t.Fatal("info() rejected the token of the old key")
+	}
+
+	iss.addKey(t, "new", false)
+	token := iss.token(t, "new", nil)
+	if _, ok := tp.info(context.TODO(), token, 1); ok {
+		t.Fatal("info() accepted the token of the new key before the keys could be fetched again")
+	}
+	oidcKeysMinRefreshInterval = 0
+	if _, ok := tp.info(context.TODO(), token, 1); !ok {
+		t.Fatal("info() rejected the token of the new key")
+	}
+}
+
+// TestOIDCKeyFetch ensures the tokens signed with the known keys are validated
+// while the keys are fetched for a new key, the tokens of the new key waiting
+// for a single fetch.
+func TestOIDCKeyFetch(t *testing.T) {
+	defer func(d time.Duration) { oidcKeysMinRefreshInterval = d }(oidcKeysMinRefreshInterval)
+	oidcKeysMinRefreshInterval = 0
+
+	iss := newTestOIDCIssuer(t)
+	iss.addKey(t, "old", false)
+	tp := newTestTokenProviderOIDC(t, iss)
+	oldToken := iss.token(t, "old", nil)
+	if _, ok := tp.info(context.TODO(), oldToken, 1); !ok {
+		t.Fatal("info() rejected the token of the old key")
+	}
+	iss.addKey(t, "new", false)
+	newToken := iss.token(t, "new", nil)
+
+	// the issuer does not serve its keys until unlocked
+	iss.mu.Lock()
+	var wg sync.WaitGroup
+	errc := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := tp.info(context.TODO(), newToken, 1); !ok {
+				errc <- "info() rejected the token of the new key"
+			}
+		}()
+	}
+	for {
+		tp.keys.mu.Lock()
+		fetching := tp.keys.fetching != nil
+		tp.keys.mu.Unlock()
+		if fetching {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	donec := make(chan bool)
+	go func() {
+		_, ok := tp.info(context.TODO(), oldToken, 1)
+		donec <- ok
+	}()
+	select {
+	case ok := <-donec:
+		if !ok {
+			t.Error("info() rejected the token of the old key while fetching the keys")
+		}
+	case <-time.After(time.Second):
+		t.Error("info() of the token of the old key waited for the keys to be fetched")
+	}
+
+	iss.mu.Unlock()
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		t.Fatal(err)
+	}
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	if iss.fetches != 2 {
+		t.Fatalf("keys fetched %d times, want 2", iss.fetches)
+	}
+}
+
+// TestOIDCSimpleToken ensures the etcd users authenticate with simple tokens.
+func TestOIDCSimpleToken(t *testing.T) {
+	iss := newTestOIDCIssuer(t)
+	tp := newTestTokenProviderOIDC(t, iss)
+	tp.enable()
+	defer tp.disable()
+
+	prefix, err := tp.genTokenPrefix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.WithValue(context.TODO(), AuthenticateParamIndex{}, uint64(1)), AuthenticateParamSimpleTokenPrefix{}, prefix)
+	token, err := tp.assign(ctx, "user1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai, ok := tp.info(context.TODO(), token, 123)
+	if !ok || ai.Username != "user1" || ai.Roles != nil {
+		t.Fatalf("info() = %+v, %v, want user1 without roles", ai, ok)
+	}
+	if simpleTokenOf(tp) != tp.simple {
+		t.Fatal("simpleTokenOf() did not return the simple token provider")
+	}
+}
+
+func TestNewTokenProviderOIDCOptions(t *testing.T) {
+	mapping := filepath.Join(t.TempDir(), "role-mapping.json")
+	if err := os.WriteFile(mapping, []byte(`{"groups": {"a": ["a"]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]map[string]string{
+		"missing issuer":      {optOIDCAudience: "etcd", optOIDCRoleMapping: mapping},
+		"http issuer":         {optOIDCIssuer: "http://example.com", optOIDCAudience: "etcd", optOIDCRoleMapping: mapping},
+		"missing audience":    {optOIDCIssuer: "https://example.com", optOIDCRoleMapping: mapping},
+		"missing mapping":     {optOIDCIssuer: "https://example.com", optOIDCAudience: "etcd"},
+		"invalid mapping":     {optOIDCIssuer: "https://example.com", optOIDCAudience: "etcd", optOIDCRoleMapping: "/nonexistent"},
+		"invalid CA file":     {optOIDCIssuer: "https://example.com", optOIDCAudience: "etcd", optOIDCRoleMapping: mapping, optOIDCCAFile: mapping},
+		"nonexistent CA file": {optOIDCIssuer: "https://example.com", optOIDCAudience: "etcd", optOIDCRoleMapping: mapping, optOIDCCAFile: "/nonexistent"},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newTokenProviderOIDC(zaptest.NewLogger(t), opts, nil); err != ErrInvalidAuthOpts {
+				t.Fatalf("newTokenProviderOIDC() = %v, want %v", err, ErrInvalidAuthOpts)
+			}
+		})
+	}
+}
@@ -25,11 +25,14 @@ func getMergedPerms(tx AuthReadTx, userName string) *unifiedRangePermissions {
 	if user == nil {
 		return nil
 	}
+	return getRolesMergedPerms(tx, user.Roles)
+}
 
+func getRolesMergedPerms(tx AuthReadTx, roles []string) *unifiedRangePermissions {
 	readPerms := adt.NewIntervalTree()
 	writePerms := adt.NewIntervalTree()
 
-	for _, roleName := range user.Roles {
+	for _, roleName := range roles {
 		role := tx.UnsafeGetRole(roleName)
 		if role == nil {
 			continue
@@ -116,12 +119,14 @@ func (as *authStore) isRangeOpPermitted(userName string, key, rangeEnd []byte, p
 		)
 		return false
 	}
+	return checkRangePerms(as.lg, rangePerm, key, rangeEnd, permtyp)
+}
 
+func checkRangePerms(lg *zap.Logger, perms *unifiedRangePermissions, key, rangeEnd []byte, permtyp authpb.Permission_Type) bool {
 	if len(rangeEnd) == 0 {
-		return checkKeyPoint(as.lg, rangePerm, key, permtyp)
+		return checkKeyPoint(lg, perms, key, permtyp)
 	}
-
-	return checkKeyInterval(as.lg, rangePerm, key, rangeEnd, permtyp)
+	return checkKeyInterval(lg, perms, key, rangeEnd, permtyp)
 }
 
 func (as *authStore) refreshRangePermCache(tx AuthReadTx) {
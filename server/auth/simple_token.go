@@ -244,6 +244,19 @@ func (t *tokenSimple) isValidSimpleToken(ctx context.Context, token string) bool
 	return false
 }
 
+// setTTL changes the TTL of the tokens assigned or refreshed from now on.
+func (t *tokenSimple) setTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = simpleTokenTTLDefault
+	}
+	t.simpleTokensMu.Lock()
+	defer t.simpleTokensMu.Unlock()
+	t.simpleTokenTTL = ttl
+	if t.simpleTokenKeeper != nil {
+		t.simpleTokenKeeper.simpleTokenTTL = ttl
+	}
+}
+
 func newTokenProviderSimple(lg *zap.Logger, indexWaiter func(uint64) <-chan struct{}, TokenTTL time.Duration) *tokenSimple {
 	if lg == nil {
 		lg = zap.NewNop()
@@ -17,6 +17,7 @@ package auth
 import (
 	"context"
 	"testing"
+	"time"
 
 	"go.uber.org/zap/zaptest"
 )
@@ -68,3 +69,25 @@ func TestSimpleTokenAssign(t *testing.T) {
 		t.Errorf("expected ok == false after user is invalidated")
 	}
 }
+
+// TestSimpleTokenSetTTL ensures that the TTL of TokenProviderSimple applies to
+// the tokens assigned after it is changed.
+func TestSimpleTokenSetTTL(t *testing.T) {
+	tp := newTokenProviderSimple(zaptest.NewLogger(t), dummyIndexWaiter, simpleTokenTTLDefault)
+	tp.enable()
+	defer tp.disable()
+	tp.setTTL(time.Minute)
+
+	ctx := context.WithValue(context.WithValue(context.TODO(), AuthenticateParamIndex{}, uint64(1)), AuthenticateParamSimpleTokenPrefix{}, "dummy")
+	before := time.Now()
+	token, err := tp.assign(ctx, "user1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp.simpleTokensMu.Lock()
+	expiry := tp.simpleTokenKeeper.tokens[token]
+	tp.simpleTokensMu.Unlock()
+	if expiry.Before(before.Add(time.Minute)) || expiry.After(time.Now().Add(time.Minute)) {
+		t.Errorf("token expires at %v, want a minute after %v", expiry, before)
+	}
+}
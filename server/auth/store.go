@@ -73,11 +73,15 @@ const (
 
 	tokenTypeSimple = "simple"
 	tokenTypeJWT    = "jwt"
+	tokenTypeOIDC   = "oidc"
 )
 
 type AuthInfo struct {
 	Username string
 	Revision uint64
+	// Roles are the roles of an identity authenticated without being an etcd
+	// user, e.g. mapped from the claims of an OIDC token, nil for etcd users.
+	Roles []string
 }
 
 // AuthenticateParamIndex is used for a key of context in the parameters of Authenticate()
@@ -181,6 +185,10 @@ type AuthStore interface {
 
 	// BcryptCost gets strength of hashing bcrypted auth password
 	BcryptCost() int
+
+	// SetSimpleTokenTTL changes the TTL of the simple tokens, assigned or
+	// refreshed from now on. It does nothing with the other token providers.
+	SetSimpleTokenTTL(ttl time.Duration)
 }
 
 type TokenProvider interface {
@@ -843,19 +851,8 @@ func (as *authStore) isOpPermitted(userName string, revision uint64, key, rangeE
 	if !as.IsAuthEnabled() {
 		return nil
 	}
-
-	// only gets rev == 0 when passed AuthInfo{}; no user given
-	if revision == 0 {
-		return ErrUserEmpty
-	}
-	rev := as.Revision()
-	if revision < rev {
-		as.lg.Warn("request auth revision is less than current node auth revision",
-			zap.Uint64("current node auth revision", rev),
-			zap.Uint64("request auth revision", revision),
-			zap.ByteString("request key", key),
-			zap.Error(ErrAuthOldRevision))
-		return ErrAuthOldRevision
+	if err := as.checkAuthRevision(revision, key); err != nil {
+		return err
 	}
 
 	tx := as.be.ReadTx()
@@ -880,16 +877,65 @@ func (as *authStore) isOpPermitted(userName string, revision uint64, key, rangeE
 	return ErrPermissionDenied
 }
 
+// isRolesOpPermitted checks the permission of an identity authenticated
+// without being an etcd user with the permissions of its roles.
+func (as *authStore) isRolesOpPermitted(roles []string, revision uint64, key, rangeEnd []byte, permTyp authpb.Permission_Type) error {
+	if !as.IsAuthEnabled() {
+		return nil
+	}
+	if err := as.checkAuthRevision(revision, key); err != nil {
+		return err
+	}
+	if hasRole(roles, rootRole) {
+		return nil
+	}
+
+	tx := as.be.ReadTx()
+	tx.Lock()
+	defer tx.Unlock()
+
+	if checkRangePerms(as.lg, getRolesMergedPerms(tx, roles), key, rangeEnd, permTyp) {
+		return nil
+	}
+	return ErrPermissionDenied
+}
+
+// checkAuthRevision checks the auth revision of a request is not older than
+// the current one.
+func (as *authStore) checkAuthRevision(revision uint64, key []byte) error {
+	// only gets rev == 0 when passed AuthInfo{}; no user given
+	if revision == 0 {
+		return ErrUserEmpty
+	}
+	rev := as.Revision()
+	if revision < rev {
+		as.lg.Warn("request auth revision is less than current node auth revision",
+			zap.Uint64("current node auth revision", rev),
+			zap.Uint64("request auth revision", revision),
+			zap.ByteString("request key", key),
+			zap.Error(ErrAuthOldRevision))
+		return ErrAuthOldRevision
+	}
+	return nil
+}
+
+func (as *authStore) isAuthInfoOpPermitted(authInfo *AuthInfo, key, rangeEnd []byte, permTyp authpb.Permission_Type) error {
+	if authInfo.Roles != nil {
+		return as.isRolesOpPermitted(authInfo.Roles, authInfo.Revision, key, rangeEnd, permTyp)
+	}
+	return as.isOpPermitted(authInfo.Username, authInfo.Revision, key, rangeEnd, permTyp)
+}
+
 func (as *authStore) IsPutPermitted(authInfo *AuthInfo, key []byte) error {
-	return as.isOpPermitted(authInfo.Username, authInfo.Revision, key, nil, authpb.WRITE)
+	return as.isAuthInfoOpPermitted(authInfo, key, nil, authpb.WRITE)
 }
 
 func (as *authStore) IsRangePermitted(authInfo *AuthInfo, key, rangeEnd []byte) error {
-	return as.isOpPermitted(authInfo.Username, authInfo.Revision, key, rangeEnd, authpb.READ)
+	return as.isAuthInfoOpPermitted(authInfo, key, rangeEnd, authpb.READ)
 }
 
 func (as *authStore) IsDeleteRangePermitted(authInfo *AuthInfo, key, rangeEnd []byte) error {
-	return as.isOpPermitted(authInfo.Username, authInfo.Revision, key, rangeEnd, authpb.WRITE)
+	return as.isAuthInfoOpPermitted(authInfo, key, rangeEnd, authpb.WRITE)
 }
 
 func (as *authStore) IsAdminPermitted(authInfo *AuthInfo) error {
@@ -899,6 +945,12 @@ func (as *authStore) IsAdminPermitted(authInfo *AuthInfo) error {
 	if authInfo == nil || authInfo.Username == "" {
 		return ErrUserEmpty
 	}
+	if authInfo.Roles != nil {
+		if !hasRole(authInfo.Roles, rootRole) {
+			return ErrPermissionDenied
+		}
+		return nil
+	}
 
 	tx := as.be.ReadTx()
 	tx.Lock()
@@ -974,9 +1026,15 @@ func NewAuthStore(lg *zap.Logger, be AuthBackend, tp TokenProvider, bcryptCost i
 }
 
 func hasRootRole(u *authpb.User) bool {
-	// u.Roles is sorted in UserGrantRole(), so we can use binary search.
-	idx := sort.SearchStrings(u.Roles, rootRole)
-	return idx != len(u.Roles) && u.Roles[idx] == rootRole
+	return hasRole(u.Roles, rootRole)
+}
+
+// hasRole returns whether the sorted roles contain the role.
+func hasRole(roles []string, role string) bool {
+	// the roles of a user are sorted in UserGrantRole(), the roles of an
+	// identity by its token provider, so we can use binary search.
+	idx := sort.SearchStrings(roles, role)
+	return idx != len(roles) && roles[idx] == role
 }
 
 func (as *authStore) commitRevision(tx AuthBatchTx) {
@@ -1122,6 +1180,9 @@ func NewTokenProvider(
 	case tokenTypeJWT:
 		return newTokenProviderJWT(lg, typeSpecificOpts)
 
+	case tokenTypeOIDC:
+		return newTokenProviderOIDC(lg, typeSpecificOpts, newTokenProviderSimple(lg, indexWaiter, TokenTTL))
+
 	case "":
 		return newTokenProviderNop()
 
@@ -1143,7 +1204,7 @@ func (as *authStore) WithRoot(ctx context.Context) context.Context {
 	}
 
 	var ctxForAssign context.Context
-	if ts, ok := as.tokenProvider.(*tokenSimple); ok && ts != nil {
+	if ts := simpleTokenOf(as.tokenProvider); ts != nil {
 		ctx1 := context.WithValue(ctx, AuthenticateParamIndex{}, uint64(0))
 		prefix, err := ts.genTokenPrefix()
 		if err != nil {
@@ -1204,6 +1265,24 @@ func (as *authStore) BcryptCost() int {
 	return as.bcryptCost
 }
 
+func (as *authStore) SetSimpleTokenTTL(ttl time.Duration) {
+	if t := simpleTokenOf(as.tokenProvider); t != nil {
+		t.setTTL(ttl)
+	}
+}
+
+// simpleTokenOf returns the provider of the simple tokens assigned by tp, nil
+// if tp does not assign simple tokens.
+func simpleTokenOf(tp TokenProvider) *tokenSimple {
+	switch t := tp.(type) {
+	case *tokenSimple:
+		return t
+	case *tokenOIDC:
+		return t.simple
+	}
+	return nil
+}
+
 func (as *authStore) setupMetricsReporter() {
 	reportCurrentAuthRevMu.Lock()
 	reportCurrentAuthRev = func() float64 {
@@ -404,6 +404,45 @@ func TestIsOpPermitted(t *testing.T) {
 	}
 }
 
+// TestIsRolesOpPermitted ensures the permissions of the identities
+// authenticated without being an etcd user are the ones of their roles.
+func TestIsRolesOpPermitted(t *testing.T) {
+	as, tearDown := setupAuthStore(t)
+	defer tearDown(t)
+
+	_, err := as.RoleGrantPermission(&pb.AuthRoleGrantPermissionRequest{
+		Name: "role-test",
+		Perm: &authpb.Permission{PermType: authpb.READ, Key: []byte("foo"), RangeEnd: []byte("fop")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ai := &AuthInfo{Username: "oidc:foo", Revision: as.Revision(), Roles: []string{"role-test"}}
+	if err = as.IsRangePermitted(ai, []byte("foo/bar"), nil); err != nil {
+		t.Fatalf("IsRangePermitted() with the role permission = %v, want nil", err)
+	}
+	if err = as.IsPutPermitted(ai, []byte("foo/bar")); err != ErrPermissionDenied {
+		t.Fatalf("IsPutPermitted() without the role permission = %v, want %v", err, ErrPermissionDenied)
+	}
+	if err = as.IsAdminPermitted(ai); err != ErrPermissionDenied {
+		t.Fatalf("IsAdminPermitted() without the root role = %v, want %v", err, ErrPermissionDenied)
+	}
+
+	// the etcd user of the same name has no permission
+	if err = as.IsRangePermitted(&AuthInfo{Username: "foo", Revision: as.Revision()}, []byte("foo/bar"), nil); err != ErrPermissionDenied {
+		t.Fatalf("IsRangePermitted() of the user = %v, want %v", err, ErrPermissionDenied)
+	}
+
+	root := &AuthInfo{Username: "oidc:root", Revision: as.Revision(), Roles: []string{"root"}}
+	if err = as.IsPutPermitted(root, []byte("bar")); err != nil {
+		t.Fatalf("IsPutPermitted() with the root role = %v, want nil", err)
+	}
+	if err = as.IsAdminPermitted(root); err != nil {
+		t.Fatalf("IsAdminPermitted() with the root role = %v, want nil", err)
+	}
+}
+
 func TestGetUser(t *testing.T) {
 	as, tearDown := setupAuthStore(t)
 	defer tearDown(t)
@@ -22,8 +22,10 @@ import (
 	"strings"
 	"time"
 
+	"go.etcd.io/etcd/client/pkg/v3/logutil"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/pkg/v3/featuregate"
 	"go.etcd.io/etcd/pkg/v3/netutil"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3discovery"
 	"go.etcd.io/etcd/server/v3/storage/datadir"
@@ -31,6 +33,17 @@ import (
 
 	bolt "go.etcd.io/bbolt"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// BootstrapVerifyFull is the ExperimentalBootstrapVerify mode verifying all the persisted state.
+const BootstrapVerifyFull = "full"
+
+// The ExperimentalLargeRequestPolicy policies applied to the identities sending too many large requests.
+const (
+	LargeRequestPolicyWarn     = "warn"
+	LargeRequestPolicyThrottle = "throttle"
+	LargeRequestPolicyReject   = "reject"
 )
 
 // ServerConfig holds the configuration of etcd as taken from the command line or discovery.
@@ -57,6 +70,11 @@ type ServerConfig struct {
 	// follower to catch up.
 	// WARNING: only change this for tests. Always use "DefaultSnapshotCatchUpEntries"
 	SnapshotCatchUpEntries uint64
+	// ExperimentalMaxSnapshotCatchUpEntries is the maximum number of entries
+	// kept in memory for the active followers lagging behind to catch up from,
+	// adapting the catch-up entries to their lag. Not above
+	// SnapshotCatchUpEntries, the catch-up entries are fixed.
+	ExperimentalMaxSnapshotCatchUpEntries uint64
 
 	MaxSnapFiles uint
 	MaxWALFiles  uint
@@ -68,6 +86,20 @@ type ServerConfig struct {
 
 	// BackendFreelistType is the type of the backend boltdb freelist.
 	BackendFreelistType bolt.FreelistType
+	// BackendFreelistSync persists the backend boltdb freelist on each commit.
+	BackendFreelistSync bool
+	// BackendInitialMmapSize is the initial size of the mmap of the backend,
+	// derived from the quota if zero.
+	BackendInitialMmapSize uint64
+	// ExperimentalSnapshotSendRateBytes is the maximum rate in bytes per second
+	// the snapshots are sent to the clients at, unlimited if zero.
+	ExperimentalSnapshotSendRateBytes int64
+	// ExperimentalBackendMmapAdvice is the madvise advice of the mmap of the
+	// backend, the boltdb default if empty.
+	ExperimentalBackendMmapAdvice string
+	// BackendWarmUp reads the backend in the background after opening it
+	// instead of prefaulting its pages on open.
+	BackendWarmUp bool
 
 	InitialPeerURLsMap  types.URLsMap
 	InitialClusterToken string
@@ -83,6 +115,14 @@ type ServerConfig struct {
 
 	TickMs        uint
 	ElectionTicks int
+	// ElectionJitterTicks is the range of the random number of ticks added to
+	// ElectionTicks for each election. It defaults to ElectionTicks if 0.
+	ElectionJitterTicks int
+	// ExperimentalTickSkewTolerance is the delay of a heartbeat tick above
+	// which the member is starved, e.g. by a long GC pause or CPU throttling,
+	// and a follower does not count the tick towards its election timeout.
+	// 0 disables the compensation.
+	ExperimentalTickSkewTolerance time.Duration
 
 	// WaitClusterReadyTimeout is the maximum time to wait for the
 	// cluster to be ready on startup before serving client requests.
@@ -126,6 +166,27 @@ type ServerConfig struct {
 	QuotaBackendBytes       int64
 	MaxTxnOps               uint
 
+	// CompactionPauseBackendCommitThreshold is the latency of the last backend
+	// commit above which the compaction pauses between its batches. 0 disables it.
+	CompactionPauseBackendCommitThreshold time.Duration
+	// CompactionPausePendingProposals is the number of pending proposals above
+	// which the compaction pauses between its batches. 0 disables it.
+	CompactionPausePendingProposals int
+	// CompactionMaxPause is the maximum pause of the compaction between two batches.
+	CompactionMaxPause time.Duration
+	// CompactionBarrierMaxDuration is the maximum time a compaction barrier
+	// holds back the compaction. 0 disables the compaction barriers.
+	CompactionBarrierMaxDuration time.Duration
+	// CompactionBarrierMaxRevisions is the maximum number of revisions the
+	// current revision moves past a compaction barrier before it expires. 0
+	// means unlimited.
+	CompactionBarrierMaxRevisions int64
+	// HistoryRetention are the rules of the history retained by the compactions,
+	// "<prefix>=<versions>" or "<prefix>=<period>".
+	HistoryRetention []string
+	// PrefixStats are the key prefixes the requests served are counted for.
+	PrefixStats []string
+
 	// MaxRequestBytes is the maximum request size to send over raft.
 	MaxRequestBytes uint
 
@@ -138,6 +199,12 @@ type ServerConfig struct {
 
 	StrictReconfigCheck bool
 
+	// AutoPromoteLearners promotes the learners lagging behind the leader by at most
+	// AutoPromoteLearnersMaxLag raft log entries for AutoPromoteLearnersSyncedDuration.
+	AutoPromoteLearners               bool
+	AutoPromoteLearnersMaxLag         uint64
+	AutoPromoteLearnersSyncedDuration time.Duration
+
 	// ClientCertAuthEnabled is true when cert has been signed by the client CA.
 	ClientCertAuthEnabled bool
 
@@ -154,12 +221,20 @@ type ServerConfig struct {
 
 	// PreVote is true to enable Raft Pre-Vote.
 	PreVote bool
+	// CheckQuorum is true to enable Raft CheckQuorum, the leader stepping
+	// down when it does not hear from a quorum for an election timeout.
+	CheckQuorum bool
+	// ExperimentalElectionPriority is the priority of the member to be the
+	// leader, published in its attributes. 0 is the lowest.
+	ExperimentalElectionPriority uint `json:"experimental-election-priority"`
 
 	// SocketOpts are socket options passed to listener config.
 	SocketOpts transport.SocketOpts
 
 	// Logger logs server-side operations.
 	Logger *zap.Logger
+	// LogLevels adjusts the level of Logger at runtime, nil if not adjustable.
+	LogLevels *logutil.Levels
 
 	ForceNewCluster bool
 
@@ -177,11 +252,26 @@ type ServerConfig struct {
 	// ExperimentalTracerOptions are options for OpenTelemetry gRPC interceptor.
 	ExperimentalTracerOptions []otelgrpc.Option
 
+	// UnaryInterceptors are the users' interceptors chained after the etcd
+	// ones for the unary RPCs.
+	UnaryInterceptors []grpc.UnaryServerInterceptor
+	// StreamInterceptors are the users' interceptors chained after the etcd
+	// ones for the stream RPCs.
+	StreamInterceptors []grpc.StreamServerInterceptor
+
 	WatchProgressNotifyInterval time.Duration
+	// WatchMaxStreamAge is the maximum age of the watch streams, with a jitter
+	// of up to a tenth, after which they are closed for the clients to resume
+	// their watches on a new stream. 0 means unlimited.
+	WatchMaxStreamAge time.Duration
 
 	// UnsafeNoFsync disables all uses of fsync.
 	// Setting this is unsafe and will cause data loss.
 	UnsafeNoFsync bool `json:"unsafe-no-fsync"`
+	// ExperimentalWALGroupSyncMaxDelay is the maximum latency added to the
+	// WAL fsync of a raft ready waiting for the following readies to share it.
+	// 0 disables the group sync.
+	ExperimentalWALGroupSyncMaxDelay time.Duration
 
 	DowngradeCheckTime time.Duration
 
@@ -197,13 +287,86 @@ type ServerConfig struct {
 	// a shared buffer in its readonly check operations.
 	ExperimentalTxnModeWriteWithSharedBuffer bool `json:"experimental-txn-mode-write-with-shared-buffer"`
 
+	// BatchApply applies the independent puts, deletes and write txns
+	// committed together in one write batch of the store.
+	BatchApply bool `json:"batch-apply"`
+
 	// ExperimentalBootstrapDefragThresholdMegabytes is the minimum number of megabytes needed to be freed for etcd server to
 	// consider running defrag during bootstrap. Needs to be set to non-zero value to take effect.
 	ExperimentalBootstrapDefragThresholdMegabytes uint `json:"experimental-bootstrap-defrag-threshold-megabytes"`
 
+	// ExperimentalBootstrapVerify is the verification of the persisted state performed during bootstrap.
+	// BootstrapVerifyFull verifies the WAL, the backend and the rebuilt key index, and fails the bootstrap
+	// on mismatch. No verification when empty.
+	ExperimentalBootstrapVerify string `json:"experimental-bootstrap-verify"`
+
 	// ExperimentalMaxLearners sets a limit to the number of learner members that can exist in the cluster membership.
 	ExperimentalMaxLearners int `json:"experimental-max-learners"`
 
+	// ExperimentalGRPCCompressionRPCs lists the RPCs whose responses may be compressed, all RPCs when empty.
+	ExperimentalGRPCCompressionRPCs []string `json:"experimental-grpc-compression-rpcs"`
+	// ExperimentalGRPCCompressionMinBytes is the minimum size of the compressed responses.
+	ExperimentalGRPCCompressionMinBytes int `json:"experimental-grpc-compression-min-bytes"`
+	// ExperimentalGRPCResponseCompression is the compressor of all the responses, the compressor of the request when empty.
+	ExperimentalGRPCResponseCompression string `json:"experimental-grpc-response-compression"`
+
+	// ExperimentalMaxConnectionMemoryBytes is the maximum memory held by the pending responses
+	// and watch buffers of a client connection, unlimited when 0.
+	ExperimentalMaxConnectionMemoryBytes int64 `json:"experimental-max-connection-memory-bytes"`
+	// ExperimentalMaxTotalConnectionMemoryBytes is the maximum memory held by all client connections,
+	// above which the heaviest connection is evicted. Unlimited when 0.
+	ExperimentalMaxTotalConnectionMemoryBytes int64 `json:"experimental-max-total-connection-memory-bytes"`
+
+	// ExperimentalLargeRequestPolicy is applied to the identities, i.e. the users or the client IPs without
+	// authentication, sending more large requests than ExperimentalLargeRequestsPerMinute: LargeRequestPolicyWarn
+	// logs them, LargeRequestPolicyThrottle delays them and LargeRequestPolicyReject fails them. Disabled when empty.
+	ExperimentalLargeRequestPolicy string `json:"experimental-large-request-policy"`
+	// ExperimentalLargeRequestBytes is the size from which a request is large, 90% of MaxRequestBytes when 0.
+	ExperimentalLargeRequestBytes int `json:"experimental-large-request-bytes"`
+	// ExperimentalLargeRequestsPerMinute is the number of large requests an identity may send per minute.
+	ExperimentalLargeRequestsPerMinute int `json:"experimental-large-requests-per-minute"`
+	// ExperimentalLargeRequestAllowlist are the identities not subject to ExperimentalLargeRequestPolicy.
+	ExperimentalLargeRequestAllowlist []string `json:"experimental-large-request-allowlist"`
+
+	// ExperimentalWebhookURLs are the endpoints posted on alarm and leadership changes.
+	ExperimentalWebhookURLs []string `json:"experimental-webhook-urls"`
+	// ExperimentalWebhookTemplate is the text/template of the webhook payloads, JSON events when empty.
+	ExperimentalWebhookTemplate string `json:"experimental-webhook-template"`
+	// ExperimentalWebhookRetries is the number of retries of a failed webhook post.
+	ExperimentalWebhookRetries int `json:"experimental-webhook-retries"`
+
+	// ExperimentalProfilingPushURL is the endpoint the profiles are periodically pushed to.
+	ExperimentalProfilingPushURL string `json:"experimental-profiling-push-url"`
+	// ExperimentalProfilingPushProfiles are the types of the pushed profiles.
+	ExperimentalProfilingPushProfiles []string `json:"experimental-profiling-push-profiles"`
+	// ExperimentalProfilingPushInterval is the interval between the profile captures.
+	ExperimentalProfilingPushInterval time.Duration `json:"experimental-profiling-push-interval"`
+	// ExperimentalProfilingPushCPUDuration is how long the pushed CPU profiles are captured for.
+	ExperimentalProfilingPushCPUDuration time.Duration `json:"experimental-profiling-push-cpu-duration"`
+
+	// ExperimentalDiskDegradedWALFsyncThreshold is the WAL fsync latency above which the disk is degraded.
+	ExperimentalDiskDegradedWALFsyncThreshold time.Duration `json:"experimental-disk-degraded-wal-fsync-threshold"`
+	// ExperimentalDiskDegradedBackendCommitThreshold is the backend commit latency above which the disk is degraded.
+	ExperimentalDiskDegradedBackendCommitThreshold time.Duration `json:"experimental-disk-degraded-backend-commit-threshold"`
+	// ExperimentalDiskDegradedTransferLeadership transfers the leadership away from the member once its disk is degraded.
+	ExperimentalDiskDegradedTransferLeadership bool `json:"experimental-disk-degraded-transfer-leadership"`
+	// ExperimentalStarvationThreshold is the scheduling delay of the raft ticks and of the applies above which
+	// the member is starved.
+	ExperimentalStarvationThreshold time.Duration `json:"experimental-starvation-threshold"`
+	// StarvationUnhealthy fails the health checks of the member while it is starved.
+	StarvationUnhealthy bool `json:"starvation-unhealthy"`
+	// ExperimentalMaxClockSkew is the clock skew with a peer above which the clock of the member is skewed.
+	ExperimentalMaxClockSkew time.Duration `json:"experimental-max-clock-skew"`
+	// ExperimentalMemoryBudgetRatio is the ratio of the cgroup memory limit above which the heap sheds load.
+	ExperimentalMemoryBudgetRatio float64 `json:"experimental-memory-budget-ratio"`
+	// ExperimentalMaxDeleteBatchSize is the maximum number of keys deleted by each batch of a paced delete range.
+	ExperimentalMaxDeleteBatchSize int `json:"experimental-max-delete-batch-size"`
+	// ExperimentalMaxConcurrentRequests is the maximum number of concurrent requests queued by priority, 0 for unlimited.
+	ExperimentalMaxConcurrentRequests int `json:"experimental-max-concurrent-requests"`
+
+	// ServerFeatureGate is the feature gate of the server.
+	ServerFeatureGate *featuregate.FeatureGate
+
 	// V2Deprecation defines a phase of v2store deprecation process.
 	V2Deprecation V2DeprecationEnum `json:"v2-deprecation"`
 }
@@ -33,6 +33,8 @@ import (
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	"go.etcd.io/etcd/client/pkg/v3/types"
 	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/debugutil"
+	"go.etcd.io/etcd/pkg/v3/featuregate"
 	"go.etcd.io/etcd/pkg/v3/flags"
 	"go.etcd.io/etcd/pkg/v3/netutil"
 	"go.etcd.io/etcd/server/v3/config"
@@ -40,6 +42,10 @@ import (
 	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3compactor"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3discovery"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3rpc"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/webhook"
+	"go.etcd.io/etcd/server/v3/features"
+	"go.etcd.io/etcd/server/v3/storage/backend"
 
 	bolt "go.etcd.io/bbolt"
 	"go.uber.org/multierr"
@@ -58,14 +64,29 @@ const (
 	DefaultMaxWALs                     = 5
 	DefaultMaxTxnOps                   = uint(128)
 	DefaultWarningApplyDuration        = 100 * time.Millisecond
+	DefaultCompactionMaxPause          = time.Second
 	DefaultWarningUnaryRequestDuration = 300 * time.Millisecond
 	DefaultMaxRequestBytes             = 1.5 * 1024 * 1024
 	DefaultMaxConcurrentStreams        = math.MaxUint32
 	DefaultGRPCKeepAliveMinTime        = 5 * time.Second
 	DefaultGRPCKeepAliveInterval       = 2 * time.Hour
 	DefaultGRPCKeepAliveTimeout        = 20 * time.Second
+	DefaultClientSocketMode            = 0600
 	DefaultDowngradeCheckTime          = 5 * time.Second
 	DefaultWaitClusterReadyTimeout     = 5 * time.Second
+	DefaultProfilingPushInterval       = time.Minute
+	DefaultProfilingPushCPUDuration    = 10 * time.Second
+
+	DefaultDiskDegradedWALFsyncThreshold      = time.Second
+	DefaultDiskDegradedBackendCommitThreshold = time.Second
+
+	DefaultStarvationThreshold = 200 * time.Millisecond
+
+	DefaultMaxClockSkew = time.Second
+
+	DefaultLargeRequestsPerMinute = 60
+
+	DefaultMaxDeleteBatchSize = 1000
 
 	DefaultDiscoveryDialTimeout      = 2 * time.Second
 	DefaultDiscoveryRequestTimeOut   = 5 * time.Second
@@ -94,6 +115,11 @@ const (
 	// ExperimentalDistributedTracingServiceName is the default etcd service name.
 	ExperimentalDistributedTracingServiceName = "etcd"
 
+	// DefaultAutoPromoteLearnersMaxLag is the default value for "--auto-promote-learners-max-lag" flag.
+	DefaultAutoPromoteLearnersMaxLag = 1000
+	// DefaultAutoPromoteLearnersSyncedDuration is the default value for "--auto-promote-learners-synced-duration" flag.
+	DefaultAutoPromoteLearnersSyncedDuration = 30 * time.Second
+
 	// DefaultStrictReconfigCheck is the default value for "--strict-reconfig-check" flag.
 	// It's enabled by default.
 	DefaultStrictReconfigCheck = true
@@ -101,8 +127,10 @@ const (
 	// maxElectionMs specifies the maximum value of election timeout.
 	// More details are listed on etcd.io/docs > version > tuning/#time-parameters
 	maxElectionMs = 50000
-	// backend freelist map type
+	// backend freelist array type
 	freelistArrayType = "array"
+	// backend freelist map type
+	freelistMapType = "map"
 )
 
 var (
@@ -158,6 +186,10 @@ type Config struct {
 	// WARNING: only change this for tests.
 	// Always use "DefaultSnapshotCatchUpEntries"
 	SnapshotCatchUpEntries uint64
+	// ExperimentalMaxSnapshotCatchUpEntries is the maximum number of entries kept for the active followers
+	// lagging behind to catch up from after compacting the raft storage entries, instead of being sent a
+	// snapshot. Not above SnapshotCatchUpEntries, the catch-up entries are fixed.
+	ExperimentalMaxSnapshotCatchUpEntries uint64 `json:"experimental-max-snapshot-catchup-entries"`
 
 	MaxSnapFiles uint `json:"max-snapshots"`
 	MaxWalFiles  uint `json:"max-wals"`
@@ -167,6 +199,13 @@ type Config struct {
 	// make ticks a cluster wide configuration.
 	TickMs     uint `json:"heartbeat-interval"`
 	ElectionMs uint `json:"election-timeout"`
+	// ExperimentalElectionJitterMs is the range, in milliseconds, of the random time added to the election
+	// timeout of each election. It defaults to the election timeout if 0.
+	ExperimentalElectionJitterMs uint `json:"experimental-election-timeout-jitter"`
+	// ExperimentalTickSkewTolerance is the delay of a heartbeat tick above which the member is starved, e.g. by
+	// a long GC pause or CPU throttling, and a follower does not count the tick towards its election timeout.
+	// 0 disables the compensation.
+	ExperimentalTickSkewTolerance time.Duration `json:"experimental-tick-skew-tolerance"`
 
 	// InitialElectionTickAdvance is true, then local member fast-forwards
 	// election ticks to speed up "initial" leader election trigger. This
@@ -203,9 +242,15 @@ type Config struct {
 	BackendBatchLimit int `json:"backend-batch-limit"`
 	// BackendFreelistType specifies the type of freelist that boltdb backend uses (array and map are supported types).
 	BackendFreelistType string `json:"backend-bbolt-freelist-type"`
-	QuotaBackendBytes   int64  `json:"quota-backend-bytes"`
-	MaxTxnOps           uint   `json:"max-txn-ops"`
-	MaxRequestBytes     uint   `json:"max-request-bytes"`
+	// BackendFreelistSync persists the freelist of the boltdb backend on each commit instead of rebuilding it
+	// by scanning the whole database on open, for large databases to open faster at the cost of slower commits.
+	BackendFreelistSync bool `json:"backend-bbolt-freelist-sync"`
+	// BackendInitialMmapSize is the initial size in bytes of the mmap of the boltdb backend. It defaults to the
+	// quota plus 10%, or 10GiB without quota, and avoids remapping the database as it grows if larger than it.
+	BackendInitialMmapSize uint64 `json:"backend-bbolt-initial-mmap-size"`
+	QuotaBackendBytes      int64  `json:"quota-backend-bytes"`
+	MaxTxnOps              uint   `json:"max-txn-ops"`
+	MaxRequestBytes        uint   `json:"max-request-bytes"`
 
 	// MaxConcurrentStreams specifies the maximum number of concurrent
 	// streams that each client can open at a time.
@@ -240,6 +285,12 @@ type Config struct {
 	StrictReconfigCheck                 bool          `json:"strict-reconfig-check"`
 	ExperimentalWaitClusterReadyTimeout time.Duration `json:"wait-cluster-ready-timeout"`
 
+	// AutoPromoteLearners makes the leader promote the learners to voting members once their raft logs lag
+	// behind its own by at most AutoPromoteLearnersMaxLag entries for AutoPromoteLearnersSyncedDuration.
+	AutoPromoteLearners               bool          `json:"auto-promote-learners"`
+	AutoPromoteLearnersMaxLag         uint64        `json:"auto-promote-learners-max-lag"`
+	AutoPromoteLearnersSyncedDuration time.Duration `json:"auto-promote-learners-synced-duration"`
+
 	// AutoCompactionMode is either 'periodic' or 'revision'.
 	AutoCompactionMode string `json:"auto-compaction-mode"`
 	// AutoCompactionRetention is either duration string with time unit
@@ -266,11 +317,33 @@ type Config struct {
 	// SocketOpts are socket options passed to listener config.
 	SocketOpts transport.SocketOpts `json:"socket-options"`
 
+	// ClientSocketMode is the permissions of the unix sockets listened on
+	// for client traffic, e.g. 0660 to let the group of the etcd user connect.
+	ClientSocketMode os.FileMode `json:"listen-client-socket-mode"`
+
+	// EnableSocketActivation uses the listeners passed by systemd socket
+	// activation for the peer, client and metrics URLs of their addresses,
+	// instead of listening on them.
+	EnableSocketActivation bool `json:"enable-socket-activation"`
+	// inherited are the listeners passed by systemd, set up by StartEtcd.
+	inherited *inheritedListeners
+
 	// PreVote is true to enable Raft Pre-Vote.
 	// If enabled, Raft runs an additional election phase
 	// to check whether it would get enough votes to win
 	// an election, thus minimizing disruptions.
 	PreVote bool `json:"pre-vote"`
+	// CheckQuorum is true to enable Raft CheckQuorum.
+	// If enabled, the leader steps down when it does not hear
+	// from a quorum of the members for an election timeout, and
+	// the followers hearing from their leader ignore the votes
+	// of the other members, thus minimizing disruptions.
+	// It should be the same on all the members of the cluster.
+	CheckQuorum bool `json:"check-quorum"`
+	// ExperimentalElectionPriority is the priority of the member to be the leader. The leader transfers the
+	// leadership to the member of highest priority connected to it for a while, so that the leadership
+	// converges to the preferred members, e.g. of the primary zone, after restarts. 0 is the lowest.
+	ExperimentalElectionPriority uint `json:"experimental-election-priority"`
 
 	CORS map[string]struct{}
 
@@ -313,6 +386,21 @@ type Config struct {
 	//	}
 	//	embed.StartEtcd(cfg)
 	ServiceRegister func(*grpc.Server) `json:"-"`
+	// UnaryInterceptors and StreamInterceptors are for injecting users' gRPC
+	// middlewares, e.g. for auditing or rate limiting the requests, into the
+	// gRPC servers serving the clients. They are chained in order after the
+	// interceptors of etcd, so they are only called for the requests the
+	// server accepts. A simple usage example:
+	//	cfg := embed.NewConfig()
+	//	cfg.UnaryInterceptors = []grpc.UnaryServerInterceptor{
+	//		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	//			log.Printf("request %s", info.FullMethod)
+	//			return handler(ctx, req)
+	//		},
+	//	}
+	//	embed.StartEtcd(cfg)
+	UnaryInterceptors  []grpc.UnaryServerInterceptor  `json:"-"`
+	StreamInterceptors []grpc.StreamServerInterceptor `json:"-"`
 
 	AuthToken  string `json:"auth-token"`
 	BcryptCost uint   `json:"bcrypt-cost"`
@@ -332,21 +420,135 @@ type Config struct {
 	// Deprecated in v3.6.
 	// TODO: Delete in v3.7
 	ExperimentalEnableLeaseCheckpointPersist bool `json:"experimental-enable-lease-checkpoint-persist"`
-	ExperimentalCompactionBatchLimit         int  `json:"experimental-compaction-batch-limit"`
+	// ExperimentalLeaseCheckpointInterval is the interval between the checkpoints of the remaining TTL of a
+	// lease longer than it, which the members count down on leader change. 0 means the default, 5 minutes.
+	ExperimentalLeaseCheckpointInterval time.Duration `json:"experimental-lease-checkpoint-interval"`
+	ExperimentalCompactionBatchLimit    int           `json:"experimental-compaction-batch-limit"`
 	// ExperimentalCompactionSleepInterval is the sleep interval between every etcd compaction loop.
-	ExperimentalCompactionSleepInterval     time.Duration `json:"experimental-compaction-sleep-interval"`
+	ExperimentalCompactionSleepInterval time.Duration `json:"experimental-compaction-sleep-interval"`
+	// ExperimentalCompactionPauseBackendCommitThreshold is the latency of the last backend commit above which the
+	// compaction pauses between its batches to yield to the foreground traffic. 0 disables it.
+	ExperimentalCompactionPauseBackendCommitThreshold time.Duration `json:"experimental-compaction-pause-backend-commit-threshold"`
+	// ExperimentalCompactionPausePendingProposals is the number of pending proposals above which the compaction
+	// pauses between its batches to yield to the foreground traffic. 0 disables it.
+	ExperimentalCompactionPausePendingProposals int `json:"experimental-compaction-pause-pending-proposals"`
+	// ExperimentalCompactionMaxPause is the maximum pause of the compaction between two batches, for it to progress
+	// under sustained load.
+	ExperimentalCompactionMaxPause time.Duration `json:"experimental-compaction-max-pause"`
+	// ExperimentalCompactionBarrierMaxDuration is the maximum time the compaction barrier registered by the ranges at
+	// a revision holds back the compaction above it. 0 disables the compaction barriers.
+	ExperimentalCompactionBarrierMaxDuration time.Duration `json:"experimental-compaction-barrier-max-duration"`
+	// ExperimentalCompactionBarrierMaxRevisions is the maximum number of revisions the current revision moves past the
+	// revision of a compaction barrier before it expires. 0 means unlimited.
+	ExperimentalCompactionBarrierMaxRevisions int64 `json:"experimental-compaction-barrier-max-revisions"`
+	// ExperimentalHistoryRetention are the rules of the history retained by the compactions for the keys with a
+	// prefix: "<prefix>=<versions>" retains the latest versions of each key and "<prefix>=<period>" the history
+	// of the last period, e.g. "audit/=5" or "audit/=24h".
+	ExperimentalHistoryRetention []string `json:"experimental-history-retention"`
+	// ExperimentalPrefixStats are the key prefixes the reads and writes served, and their bytes, are counted for,
+	// a key being counted under the longest of its prefixes, e.g. "tenants/a/" and "tenants/b/".
+	ExperimentalPrefixStats                 []string      `json:"experimental-prefix-stats"`
 	ExperimentalWatchProgressNotifyInterval time.Duration `json:"experimental-watch-progress-notify-interval"`
+	// ExperimentalWatchMaxStreamAge is the maximum age of the watch streams after which they are closed, for the
+	// clients to resume their watches on a new stream, re-balanced across the endpoints. 0 means unlimited.
+	ExperimentalWatchMaxStreamAge time.Duration `json:"experimental-watch-max-stream-age"`
 	// ExperimentalWarningApplyDuration is the time duration after which a warning is generated if applying request
 	// takes more time than this value.
 	ExperimentalWarningApplyDuration time.Duration `json:"experimental-warning-apply-duration"`
 	// ExperimentalBootstrapDefragThresholdMegabytes is the minimum number of megabytes needed to be freed for etcd server to
 	// consider running defrag during bootstrap. Needs to be set to non-zero value to take effect.
 	ExperimentalBootstrapDefragThresholdMegabytes uint `json:"experimental-bootstrap-defrag-threshold-megabytes"`
+	// ExperimentalBootstrapVerify set to "full" verifies the WAL tail, the consistent index of the backend and
+	// the key index rebuilt from the backend during bootstrap, and refuses to start on mismatch.
+	ExperimentalBootstrapVerify string `json:"experimental-bootstrap-verify"`
 	// ExperimentalWarningUnaryRequestDuration is the time duration after which a warning is generated if applying
 	// unary request takes more time than this value.
 	ExperimentalWarningUnaryRequestDuration time.Duration `json:"experimental-warning-unary-request-duration"`
 	// ExperimentalMaxLearners sets a limit to the number of learner members that can exist in the cluster membership.
 	ExperimentalMaxLearners int `json:"experimental-max-learners"`
+	// ExperimentalGRPCCompressionRPCs lists the RPCs, e.g. "Range", whose responses may be compressed
+	// for clients compressing their requests with gzip or zstd. All RPCs when empty.
+	ExperimentalGRPCCompressionRPCs []string `json:"experimental-grpc-compression-rpcs"`
+	// ExperimentalGRPCCompressionMinBytes is the minimum size of the responses compressed by the server.
+	ExperimentalGRPCCompressionMinBytes int `json:"experimental-grpc-compression-min-bytes"`
+	// ExperimentalGRPCResponseCompression is the compressor, "gzip" or "zstd", of all the responses
+	// compressed by the server, whatever the compressor of the requests. All the clients must be able to
+	// decompress it. The responses are compressed with the compressor of the request when empty.
+	ExperimentalGRPCResponseCompression string `json:"experimental-grpc-response-compression"`
+	// ExperimentalMaxConnectionMemoryBytes is the maximum memory held by the pending responses and watch
+	// buffers of a client connection. Requests exceeding it fail. Unlimited when 0.
+	ExperimentalMaxConnectionMemoryBytes int64 `json:"experimental-max-connection-memory-bytes"`
+	// ExperimentalMaxTotalConnectionMemoryBytes is the maximum memory held by all client connections.
+	// Above it, the streams of the heaviest connection are canceled. Unlimited when 0.
+	ExperimentalMaxTotalConnectionMemoryBytes int64 `json:"experimental-max-total-connection-memory-bytes"`
+	// ExperimentalLargeRequestPolicy is applied to the identities, i.e. the users or the client IPs without
+	// authentication, sending more large requests than ExperimentalLargeRequestsPerMinute: "warn" logs them,
+	// "throttle" delays them and "reject" fails them. Disabled when empty.
+	ExperimentalLargeRequestPolicy string `json:"experimental-large-request-policy"`
+	// ExperimentalLargeRequestBytes is the size from which a request is large, 90% of MaxRequestBytes when 0.
+	ExperimentalLargeRequestBytes int `json:"experimental-large-request-bytes"`
+	// ExperimentalLargeRequestsPerMinute is the number of large requests an identity may send per minute.
+	ExperimentalLargeRequestsPerMinute int `json:"experimental-large-requests-per-minute"`
+	// ExperimentalLargeRequestAllowlist are the user names and client IPs not subject to the large request policy.
+	ExperimentalLargeRequestAllowlist []string `json:"experimental-large-request-allowlist"`
+	// ExperimentalWebhookURLs are the endpoints posted when alarms are raised or cleared and when the leader changes.
+	ExperimentalWebhookURLs []string `json:"experimental-webhook-urls"`
+	// ExperimentalWebhookTemplate is the text/template of the webhook payloads, executed on a webhook.Event.
+	// The payloads are the JSON encoding of the events when empty.
+	ExperimentalWebhookTemplate string `json:"experimental-webhook-template"`
+	// ExperimentalWebhookRetries is the number of retries of a failed webhook post.
+	ExperimentalWebhookRetries int `json:"experimental-webhook-retries"`
+	// ExperimentalProfilingPushURL is the endpoint the CPU, heap and mutex profiles are periodically pushed to,
+	// in the pprof format with Pyroscope ingestion parameters. The push is disabled when empty.
+	ExperimentalProfilingPushURL string `json:"experimental-profiling-push-url"`
+	// ExperimentalProfilingPushProfiles are the types of the pushed profiles: cpu, heap, mutex or goroutine.
+	ExperimentalProfilingPushProfiles []string `json:"experimental-profiling-push-profiles"`
+	// ExperimentalProfilingPushInterval is the interval between the profile captures, at least 10s.
+	ExperimentalProfilingPushInterval time.Duration `json:"experimental-profiling-push-interval"`
+	// ExperimentalProfilingPushCPUDuration is how long the pushed CPU profiles are captured for,
+	// at most half of ExperimentalProfilingPushInterval.
+	ExperimentalProfilingPushCPUDuration time.Duration `json:"experimental-profiling-push-cpu-duration"`
+	// ExperimentalDiskDegradedWALFsyncThreshold is the WAL fsync latency above which the disk of the member
+	// is degraded, when exceeded by more than 10% of the fsyncs for 15 seconds. 0 disables the check.
+	ExperimentalDiskDegradedWALFsyncThreshold time.Duration `json:"experimental-disk-degraded-wal-fsync-threshold"`
+	// ExperimentalDiskDegradedBackendCommitThreshold is the backend commit latency above which the disk of the
+	// member is degraded, when exceeded by more than 10% of the commits for 15 seconds. 0 disables the check.
+	ExperimentalDiskDegradedBackendCommitThreshold time.Duration `json:"experimental-disk-degraded-backend-commit-threshold"`
+	// ExperimentalDiskDegradedTransferLeadership transfers the leadership away from the member while its disk is degraded.
+	ExperimentalDiskDegradedTransferLeadership bool `json:"experimental-disk-degraded-transfer-leadership"`
+	// ExperimentalStarvationThreshold is the scheduling delay of the raft ticks and of the applies above which
+	// the member is starved of CPU, e.g. by the CPU quota of its cgroup, when exceeded by more than 10% of them
+	// for 15 seconds. 0 disables the check.
+	ExperimentalStarvationThreshold time.Duration `json:"experimental-starvation-threshold"`
+	// ExperimentalMaxClockSkew is the clock skew with a peer, measured by probing it, above which the clock of
+	// the member is reported as skewed in the logs and the status.
+	ExperimentalMaxClockSkew time.Duration `json:"experimental-max-clock-skew"`
+	// ExperimentalMemoryBudgetRatio is the ratio of the memory limit of the cgroup of the member making its
+	// memory budget, the Go soft memory limit unless set by GOMEMLIMIT. The range requests and the watch
+	// creations are rejected while the heap exceeds the budget. Disabled when 0.
+	ExperimentalMemoryBudgetRatio float64 `json:"experimental-memory-budget-ratio"`
+	// ExperimentalMaxDeleteBatchSize is the maximum number of keys deleted by each batch, its own transaction,
+	// of the paced delete range requests, bounding the size of the backend commits deleting large ranges.
+	ExperimentalMaxDeleteBatchSize int `json:"experimental-max-delete-batch-size"`
+	// ExperimentalMaxConcurrentRequests is the maximum number of concurrent key-value and lease requests of the
+	// member. The requests over the limit wait in a lane per priority, leases before writes before reads, while
+	// the health checks are never queued. Unlimited when 0.
+	ExperimentalMaxConcurrentRequests int `json:"experimental-max-concurrent-requests"`
+
+	// FeatureGates is a comma-separated list of "feature=bool" pairs enabling or disabling the features of
+	// features.DefaultEtcdServerFeatureGates, e.g. "InitialCorruptCheck=true,LeaseCheckpoint=true". They
+	// supersede the experimental flags of the same features, which are folded into them by Validate.
+	FeatureGates string `json:"feature-gates"`
+	// ServerFeatureGate is the feature gate of the server, set up by Validate from FeatureGates.
+	ServerFeatureGate *featuregate.FeatureGate `json:"-"`
+
+	// ConfigFile is the path of the configuration file the config was loaded from by ConfigFromFile.
+	// When set, the file is watched and the changes of the settings safe to change at runtime are
+	// applied without restart, see reloadableSettings.
+	ConfigFile string `json:"-"`
+	// ExperimentalEnableV2V3 serves the v2 keys API on the client URLs, emulated on top of the v3 store
+	// under the given key prefix. The emulation is disabled when empty.
+	ExperimentalEnableV2V3 string `json:"experimental-enable-v2v3"`
 
 	// ForceNewCluster starts a new cluster even if previously started; unsafe.
 	ForceNewCluster bool `json:"force-new-cluster"`
@@ -399,6 +601,9 @@ type Config struct {
 	// Do not set logger directly.
 	loggerMu *sync.RWMutex
 	logger   *zap.Logger
+	// logLevels adjusts the level of logger at runtime, nil if logger is
+	// built by a custom ZapLoggerBuilder.
+	logLevels *logutil.Levels
 	// EnableGRPCGateway enables grpc gateway.
 	// The gateway translates a RESTful HTTP API into gRPC.
 	EnableGRPCGateway bool `json:"enable-grpc-gateway"`
@@ -406,6 +611,9 @@ type Config struct {
 	// UnsafeNoFsync disables all uses of fsync.
 	// Setting this is unsafe and will cause data loss.
 	UnsafeNoFsync bool `json:"unsafe-no-fsync"`
+	// ExperimentalWALGroupSyncMaxDelay is the maximum latency added to the WAL fsync of a raft ready waiting for the
+	// following readies to share it. The readies whose entries are committed are synced at once. 0 disables it.
+	ExperimentalWALGroupSyncMaxDelay time.Duration `json:"experimental-wal-group-sync-max-delay"`
 
 	ExperimentalDowngradeCheckTime time.Duration `json:"experimental-downgrade-check-time"`
 
@@ -417,6 +625,15 @@ type Config struct {
 	// be refined to mlock in-use area of bbolt only.
 	ExperimentalMemoryMlock bool `json:"experimental-memory-mlock"`
 
+	// ExperimentalSnapshotSendRateBytes is the maximum rate in bytes per second the snapshots are sent to the
+	// clients at, unlimited if 0. Without the SnapshotSpool feature, a paced snapshot holds a read transaction
+	// of the backend open for longer.
+	ExperimentalSnapshotSendRateBytes int64 `json:"experimental-snapshot-send-rate-bytes"`
+
+	// ExperimentalBackendMmapAdvice is the madvise advice of the mmap of the boltdb backend, "normal", "random"
+	// or "willneed", the boltdb default "random" if empty. It is only applied on linux.
+	ExperimentalBackendMmapAdvice string `json:"experimental-backend-mmap-advice"`
+
 	// ExperimentalTxnModeWriteWithSharedBuffer enables write transaction to use a shared buffer in its readonly check operations.
 	ExperimentalTxnModeWriteWithSharedBuffer bool `json:"experimental-txn-mode-write-with-shared-buffer"`
 
@@ -469,10 +686,13 @@ func NewConfig() *Config {
 		SnapshotCount:          etcdserver.DefaultSnapshotCount,
 		SnapshotCatchUpEntries: etcdserver.DefaultSnapshotCatchUpEntries,
 
+		ExperimentalMaxSnapshotCatchUpEntries: etcdserver.DefaultMaxSnapshotCatchUpEntries,
+
 		MaxTxnOps:                        DefaultMaxTxnOps,
 		MaxRequestBytes:                  DefaultMaxRequestBytes,
 		MaxConcurrentStreams:             DefaultMaxConcurrentStreams,
 		ExperimentalWarningApplyDuration: DefaultWarningApplyDuration,
+		ExperimentalCompactionMaxPause:   DefaultCompactionMaxPause,
 
 		ExperimentalWarningUnaryRequestDuration: DefaultWarningUnaryRequestDuration,
 
@@ -484,6 +704,7 @@ func NewConfig() *Config {
 			ReusePort:    false,
 			ReuseAddress: false,
 		},
+		ClientSocketMode: DefaultClientSocketMode,
 
 		TickMs:                     100,
 		ElectionMs:                 1000,
@@ -501,6 +722,9 @@ func NewConfig() *Config {
 		StrictReconfigCheck: DefaultStrictReconfigCheck,
 		Metrics:             "basic",
 
+		AutoPromoteLearnersMaxLag:         DefaultAutoPromoteLearnersMaxLag,
+		AutoPromoteLearnersSyncedDuration: DefaultAutoPromoteLearnersSyncedDuration,
+
 		CORS:          map[string]struct{}{"*": {}},
 		HostWhitelist: map[string]struct{}{"*": {}},
 
@@ -508,7 +732,8 @@ func NewConfig() *Config {
 		BcryptCost:   uint(bcrypt.DefaultCost),
 		AuthTokenTTL: 300,
 
-		PreVote: true,
+		PreVote:     true,
+		CheckQuorum: true,
 
 		loggerMu:              new(sync.RWMutex),
 		logger:                nil,
@@ -523,6 +748,21 @@ func NewConfig() *Config {
 		ExperimentalMemoryMlock:                  false,
 		ExperimentalTxnModeWriteWithSharedBuffer: true,
 		ExperimentalMaxLearners:                  membership.DefaultMaxLearners,
+		ExperimentalGRPCCompressionMinBytes:      v3rpc.DefaultGRPCCompressionMinBytes,
+		ExperimentalWebhookRetries:               webhook.DefaultRetries,
+		ExperimentalProfilingPushProfiles:        debugutil.DefaultPushedProfiles,
+		ExperimentalProfilingPushInterval:        DefaultProfilingPushInterval,
+		ExperimentalProfilingPushCPUDuration:     DefaultProfilingPushCPUDuration,
+
+		ExperimentalDiskDegradedWALFsyncThreshold:      DefaultDiskDegradedWALFsyncThreshold,
+		ExperimentalDiskDegradedBackendCommitThreshold: DefaultDiskDegradedBackendCommitThreshold,
+		ExperimentalStarvationThreshold:                DefaultStarvationThreshold,
+
+		ExperimentalMaxClockSkew: DefaultMaxClockSkew,
+
+		ExperimentalLargeRequestsPerMinute: DefaultLargeRequestsPerMinute,
+
+		ExperimentalMaxDeleteBatchSize: DefaultMaxDeleteBatchSize,
 
 		ExperimentalCompactHashCheckEnabled: false,
 		ExperimentalCompactHashCheckTime:    time.Minute,
@@ -550,6 +790,7 @@ func ConfigFromFile(path string) (*Config, error) {
 	if err := cfg.configFromFile(path); err != nil {
 		return nil, err
 	}
+	cfg.ConfigFile = path
 	return &cfg.Config, nil
 }
 
@@ -558,10 +799,18 @@ func (cfg *configYAML) configFromFile(path string) error {
 	if err != nil {
 		return err
 	}
+	if err = cfg.parseConfigFile(b); err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
 
+// parseConfigFile sets the options of the content of a configuration file,
+// without validating them.
+func (cfg *configYAML) parseConfigFile(b []byte) error {
 	defaultInitialCluster := cfg.InitialCluster
 
-	err = yaml.Unmarshal(b, cfg)
+	err := yaml.Unmarshal(b, cfg)
 	if err != nil {
 		return err
 	}
@@ -569,17 +818,15 @@ func (cfg *configYAML) configFromFile(path string) error {
 	if cfg.LPUrlsJSON != "" {
 		u, err := types.NewURLs(strings.Split(cfg.LPUrlsJSON, ","))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "unexpected error setting up listen-peer-urls: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("unexpected error setting up listen-peer-urls: %v", err)
 		}
 		cfg.LPUrls = []url.URL(u)
 	}
 
 	if cfg.LCUrlsJSON != "" {
-		u, err := types.NewURLs(strings.Split(cfg.LCUrlsJSON, ","))
+		u, err := NewClientURLs(strings.Split(cfg.LCUrlsJSON, ","))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "unexpected error setting up listen-client-urls: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("unexpected error setting up listen-client-urls: %v", err)
 		}
 		cfg.LCUrls = []url.URL(u)
 	}
@@ -587,26 +834,26 @@ func (cfg *configYAML) configFromFile(path string) error {
 	if cfg.APUrlsJSON != "" {
 		u, err := types.NewURLs(strings.Split(cfg.APUrlsJSON, ","))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "unexpected error setting up initial-advertise-peer-urls: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("unexpected error setting up initial-advertise-peer-urls: %v", err)
 		}
 		cfg.APUrls = []url.URL(u)
 	}
 
 	if cfg.ACUrlsJSON != "" {
-		u, err := types.NewURLs(strings.Split(cfg.ACUrlsJSON, ","))
+		u, err := NewClientURLs(strings.Split(cfg.ACUrlsJSON, ","))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "unexpected error setting up advertise-peer-urls: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("unexpected error setting up advertise-peer-urls: %v", err)
 		}
 		cfg.ACUrls = []url.URL(u)
+	} else if cfg.LCUrlsJSON != "" && isLocalURLs(cfg.LCUrls) {
+		// advertise the sockets listened on, as with flags
+		cfg.ACUrls = cfg.LCUrls
 	}
 
 	if cfg.ListenMetricsUrlsJSON != "" {
 		u, err := types.NewURLs(strings.Split(cfg.ListenMetricsUrlsJSON, ","))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "unexpected error setting up listen-metrics-urls: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("unexpected error setting up listen-metrics-urls: %v", err)
 		}
 		cfg.ListenMetricsUrls = []url.URL(u)
 	}
@@ -644,7 +891,7 @@ func (cfg *configYAML) configFromFile(path string) error {
 	if cfg.SelfSignedCertValidity == 0 {
 		cfg.SelfSignedCertValidity = 1
 	}
-	return cfg.Validate()
+	return nil
 }
 
 func updateCipherSuites(tls *transport.TLSInfo, ss []string) error {
@@ -670,6 +917,9 @@ func (cfg *Config) Validate() error {
 	if err := cfg.setupLogging(); err != nil {
 		return err
 	}
+	if err := cfg.setupFeatureGates(); err != nil {
+		return err
+	}
 	if err := checkBindURLs(cfg.LPUrls); err != nil {
 		return err
 	}
@@ -679,6 +929,18 @@ func (cfg *Config) Validate() error {
 	if err := checkBindURLs(cfg.ListenMetricsUrls); err != nil {
 		return err
 	}
+	if err := checkNoPipeURLs(cfg.LPUrls); err != nil {
+		return err
+	}
+	if err := checkNoPipeURLs(cfg.APUrls); err != nil {
+		return err
+	}
+	if err := checkNoPipeURLs(cfg.ListenMetricsUrls); err != nil {
+		return err
+	}
+	if err := cfg.SocketOpts.Validate(); err != nil {
+		return fmt.Errorf("invalid socket options (%v)", err)
+	}
 	if err := checkHostURLs(cfg.APUrls); err != nil {
 		addrs := cfg.getAPURLs()
 		return fmt.Errorf(`--initial-advertise-peer-urls %q must be "host:port" (%v)`, strings.Join(addrs, ","), err)
@@ -737,11 +999,139 @@ func (cfg *Config) Validate() error {
 	if cfg.ElectionMs > maxElectionMs {
 		return fmt.Errorf("--election-timeout[%vms] is too long, and should be set less than %vms", cfg.ElectionMs, maxElectionMs)
 	}
+	if cfg.ExperimentalElectionJitterMs != 0 && cfg.ExperimentalElectionJitterMs < cfg.TickMs {
+		return fmt.Errorf("--experimental-election-timeout-jitter[%vms] should be at least --heartbeat-interval[%vms]", cfg.ExperimentalElectionJitterMs, cfg.TickMs)
+	}
+	if cfg.ExperimentalElectionJitterMs > maxElectionMs {
+		return fmt.Errorf("--experimental-election-timeout-jitter[%vms] is too long, and should be set less than %vms", cfg.ExperimentalElectionJitterMs, maxElectionMs)
+	}
+	if cfg.ExperimentalElectionPriority > math.MaxUint32 {
+		return fmt.Errorf("--experimental-election-priority must be <=%d (set to %d)", uint32(math.MaxUint32), cfg.ExperimentalElectionPriority)
+	}
+	if cfg.ExperimentalTickSkewTolerance < 0 {
+		return fmt.Errorf("--experimental-tick-skew-tolerance must be >=0 (set to %v)", cfg.ExperimentalTickSkewTolerance)
+	}
+	if cfg.ExperimentalWatchMaxStreamAge < 0 {
+		return fmt.Errorf("--experimental-watch-max-stream-age must be >=0 (set to %v)", cfg.ExperimentalWatchMaxStreamAge)
+	}
+
+	switch cfg.BackendFreelistType {
+	case "", freelistMapType, freelistArrayType:
+	default:
+		return fmt.Errorf("unknown --backend-bbolt-freelist-type %q (%s and %s are supported)", cfg.BackendFreelistType, freelistArrayType, freelistMapType)
+	}
 
 	// check this last since proxying in etcdmain may make this OK
 	if cfg.LCUrls != nil && cfg.ACUrls == nil {
 		return ErrUnsetAdvertiseClientURLsFlag
 	}
+	if cfg.ClientSocketMode&^os.ModePerm != 0 {
+		return fmt.Errorf("--listen-client-socket-mode must be file permissions, e.g. 0660 (set to %#o)", uint32(cfg.ClientSocketMode))
+	}
+
+	if _, err := v3rpc.CompressibleResponses(cfg.ExperimentalGRPCCompressionRPCs); err != nil {
+		return fmt.Errorf("invalid --experimental-grpc-compression-rpcs (%v)", err)
+	}
+	if cfg.ExperimentalGRPCCompressionMinBytes < 0 {
+		return fmt.Errorf("--experimental-grpc-compression-min-bytes must be >=0 (set to %d)", cfg.ExperimentalGRPCCompressionMinBytes)
+	}
+	if _, err := v3rpc.ResponseCompressor(cfg.ExperimentalGRPCResponseCompression); err != nil {
+		return fmt.Errorf("invalid --experimental-grpc-response-compression (%v)", err)
+	}
+	switch cfg.ExperimentalBootstrapVerify {
+	case "", config.BootstrapVerifyFull:
+	default:
+		return fmt.Errorf("unknown --experimental-bootstrap-verify %q", cfg.ExperimentalBootstrapVerify)
+	}
+	if cfg.ExperimentalMaxConnectionMemoryBytes < 0 {
+		return fmt.Errorf("--experimental-max-connection-memory-bytes must be >=0 (set to %d)", cfg.ExperimentalMaxConnectionMemoryBytes)
+	}
+	if cfg.ExperimentalMaxTotalConnectionMemoryBytes < 0 {
+		return fmt.Errorf("--experimental-max-total-connection-memory-bytes must be >=0 (set to %d)", cfg.ExperimentalMaxTotalConnectionMemoryBytes)
+	}
+	switch cfg.ExperimentalLargeRequestPolicy {
+	case "", config.LargeRequestPolicyWarn, config.LargeRequestPolicyThrottle, config.LargeRequestPolicyReject:
+	default:
+		return fmt.Errorf("unknown --experimental-large-request-policy %q", cfg.ExperimentalLargeRequestPolicy)
+	}
+	if cfg.ExperimentalLargeRequestBytes < 0 {
+		return fmt.Errorf("--experimental-large-request-bytes must be >=0 (set to %d)", cfg.ExperimentalLargeRequestBytes)
+	}
+	if cfg.ExperimentalLargeRequestsPerMinute <= 0 {
+		return fmt.Errorf("--experimental-large-requests-per-minute must be >0 (set to %d)", cfg.ExperimentalLargeRequestsPerMinute)
+	}
+	for _, u := range cfg.ExperimentalWebhookURLs {
+		if pu, err := url.Parse(u); err != nil || (pu.Scheme != "http" && pu.Scheme != "https") {
+			return fmt.Errorf("invalid --experimental-webhook-urls %q", u)
+		}
+	}
+	if _, err := webhook.ParseTemplate(cfg.ExperimentalWebhookTemplate); err != nil {
+		return fmt.Errorf("invalid --experimental-webhook-template (%v)", err)
+	}
+	if cfg.ExperimentalWebhookRetries < 0 {
+		return fmt.Errorf("--experimental-webhook-retries must be >=0 (set to %d)", cfg.ExperimentalWebhookRetries)
+	}
+	if cfg.ExperimentalProfilingPushURL != "" {
+		if err := cfg.profilePushConfig().Validate(); err != nil {
+			return fmt.Errorf("invalid profiling push configuration (%v)", err)
+		}
+	}
+	if cfg.ExperimentalDiskDegradedWALFsyncThreshold < 0 {
+		return fmt.Errorf("--experimental-disk-degraded-wal-fsync-threshold must be >=0 (set to %v)", cfg.ExperimentalDiskDegradedWALFsyncThreshold)
+	}
+	if cfg.ExperimentalDiskDegradedBackendCommitThreshold < 0 {
+		return fmt.Errorf("--experimental-disk-degraded-backend-commit-threshold must be >=0 (set to %v)", cfg.ExperimentalDiskDegradedBackendCommitThreshold)
+	}
+	if cfg.ExperimentalMemoryBudgetRatio < 0 || cfg.ExperimentalMemoryBudgetRatio > 1 {
+		return fmt.Errorf("--experimental-memory-budget-ratio must be between 0 and 1 (set to %v)", cfg.ExperimentalMemoryBudgetRatio)
+	}
+	if cfg.AutoPromoteLearners && cfg.AutoPromoteLearnersSyncedDuration <= 0 {
+		return fmt.Errorf("--auto-promote-learners-synced-duration must be >0 (set to %v)", cfg.AutoPromoteLearnersSyncedDuration)
+	}
+	if cfg.ExperimentalMaxConcurrentRequests < 0 {
+		return fmt.Errorf("--experimental-max-concurrent-requests must be >=0 (set to %v)", cfg.ExperimentalMaxConcurrentRequests)
+	}
+	if cfg.ExperimentalMaxDeleteBatchSize <= 0 {
+		return fmt.Errorf("--experimental-max-delete-batch-size must be >0 (set to %v)", cfg.ExperimentalMaxDeleteBatchSize)
+	}
+	if cfg.ExperimentalStarvationThreshold < 0 {
+		return fmt.Errorf("--experimental-starvation-threshold must be >=0 (set to %v)", cfg.ExperimentalStarvationThreshold)
+	}
+	if cfg.ExperimentalCompactionPauseBackendCommitThreshold < 0 {
+		return fmt.Errorf("--experimental-compaction-pause-backend-commit-threshold must be >=0 (set to %v)", cfg.ExperimentalCompactionPauseBackendCommitThreshold)
+	}
+	if cfg.ExperimentalCompactionPausePendingProposals < 0 {
+		return fmt.Errorf("--experimental-compaction-pause-pending-proposals must be >=0 (set to %d)", cfg.ExperimentalCompactionPausePendingProposals)
+	}
+	if cfg.ExperimentalCompactionMaxPause < 0 {
+		return fmt.Errorf("--experimental-compaction-max-pause must be >=0 (set to %v)", cfg.ExperimentalCompactionMaxPause)
+	}
+	if cfg.ExperimentalCompactionBarrierMaxDuration < 0 {
+		return fmt.Errorf("--experimental-compaction-barrier-max-duration must be >=0 (set to %v)", cfg.ExperimentalCompactionBarrierMaxDuration)
+	}
+	if cfg.ExperimentalCompactionBarrierMaxRevisions < 0 {
+		return fmt.Errorf("--experimental-compaction-barrier-max-revisions must be >=0 (set to %d)", cfg.ExperimentalCompactionBarrierMaxRevisions)
+	}
+	if _, err := v3compactor.ParseRetentionRules(cfg.ExperimentalHistoryRetention); err != nil {
+		return fmt.Errorf("invalid --experimental-history-retention (%v)", err)
+	}
+	if err := etcdserver.ValidateStatsPrefixes(cfg.ExperimentalPrefixStats); err != nil {
+		return fmt.Errorf("invalid --experimental-prefix-stats (%v)", err)
+	}
+	if cfg.ExperimentalSnapshotSendRateBytes < 0 {
+		return fmt.Errorf("--experimental-snapshot-send-rate-bytes must be >=0 (set to %d)", cfg.ExperimentalSnapshotSendRateBytes)
+	}
+	if cfg.ExperimentalWALGroupSyncMaxDelay < 0 {
+		return fmt.Errorf("--experimental-wal-group-sync-max-delay must be >=0 (set to %v)", cfg.ExperimentalWALGroupSyncMaxDelay)
+	}
+	switch cfg.ExperimentalBackendMmapAdvice {
+	case "", backend.MmapAdviceNormal, backend.MmapAdviceRandom, backend.MmapAdviceWillNeed:
+	default:
+		return fmt.Errorf("unknown --experimental-backend-mmap-advice %q (%s, %s and %s are supported)", cfg.ExperimentalBackendMmapAdvice, backend.MmapAdviceNormal, backend.MmapAdviceRandom, backend.MmapAdviceWillNeed)
+	}
+	if cfg.ExperimentalMaxClockSkew <= 0 {
+		return fmt.Errorf("--experimental-max-clock-skew must be >0 (set to %v)", cfg.ExperimentalMaxClockSkew)
+	}
 
 	switch cfg.AutoCompactionMode {
 	case "":
@@ -765,6 +1155,10 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("setting experimental-enable-lease-checkpoint-persist requires experimental-enable-lease-checkpoint")
 	}
 
+	if cfg.ExperimentalLeaseCheckpointInterval < 0 {
+		return fmt.Errorf("--experimental-lease-checkpoint-interval must be >=0 (set to %v)", cfg.ExperimentalLeaseCheckpointInterval)
+	}
+
 	if cfg.ExperimentalCompactHashCheckTime <= 0 {
 		return fmt.Errorf("--experimental-compact-hash-check-time must be >0 (set to %v)", cfg.ExperimentalCompactHashCheckTime)
 	}
@@ -891,6 +1285,10 @@ func (cfg Config) InitialClusterFromName(name string) (ret string) {
 func (cfg Config) IsNewCluster() bool { return cfg.ClusterState == ClusterStateFlagNew }
 func (cfg Config) ElectionTicks() int { return int(cfg.ElectionMs / cfg.TickMs) }
 
+func (cfg Config) ElectionJitterTicks() int {
+	return int(cfg.ExperimentalElectionJitterMs / cfg.TickMs)
+}
+
 func (cfg Config) V2DeprecationEffective() config.V2DeprecationEnum {
 	if cfg.V2Deprecation == "" {
 		return config.V2_DEPR_DEFAULT
@@ -984,10 +1382,76 @@ func (cfg *Config) UpdateDefaultClusterFromName(defaultInitialCluster string) (s
 	return dhost, defaultHostStatus
 }
 
+// UpdateDefaultAdvertiseClientURLs advertises the client URLs listened on if
+// none are advertised and they all are unix sockets or named pipes, a member
+// only listening on them for client traffic being for local use.
+func (cfg *Config) UpdateDefaultAdvertiseClientURLs() {
+	if cfg.ACUrls == nil && isLocalURLs(cfg.LCUrls) {
+		cfg.ACUrls = cfg.LCUrls
+	}
+}
+
+// isLocalURLs returns true if all the URLs are unix sockets or named pipes.
+func isLocalURLs(urls []url.URL) bool {
+	for _, u := range urls {
+		if u.Scheme != "unix" && u.Scheme != "unixs" && u.Scheme != "npipe" {
+			return false
+		}
+	}
+	return len(urls) > 0
+}
+
+// NewClientURLs parses the URLs to listen on or to advertise for client
+// traffic which, unlike the peer URLs, can be windows named pipes, e.g.
+// npipe:////./pipe/etcd.
+func NewClientURLs(strs []string) (types.URLs, error) {
+	var pipes types.URLs
+	others := make([]string, 0, len(strs))
+	for _, s := range strs {
+		s = strings.TrimSpace(s)
+		if !strings.HasPrefix(s, "npipe:") {
+			others = append(others, s)
+			continue
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		pipes = append(pipes, *u)
+	}
+	us := pipes
+	if len(others) > 0 || len(pipes) == 0 {
+		parsed, err := types.NewURLs(others)
+		if err != nil {
+			return nil, err
+		}
+		us = append(us, parsed...)
+	}
+	us.Sort()
+	return us, nil
+}
+
+// checkNoPipeURLs returns an error if any URL is a named pipe, named pipes
+// being only supported for client traffic.
+func checkNoPipeURLs(urls []url.URL) error {
+	for _, u := range urls {
+		if u.Scheme == "npipe" {
+			return fmt.Errorf("named pipes are only supported for client traffic (%s)", u.String())
+		}
+	}
+	return nil
+}
+
+// pipeName returns the name of the windows named pipe of the URL, e.g.
+// `\\.\pipe\etcd` for npipe:////./pipe/etcd.
+func pipeName(u url.URL) string {
+	return `\\` + strings.TrimLeft(strings.ReplaceAll(u.Host+u.Path, "/", `\`), `\`)
+}
+
 // checkBindURLs returns an error if any URL uses a domain name.
 func checkBindURLs(urls []url.URL) error {
 	for _, url := range urls {
-		if url.Scheme == "unix" || url.Scheme == "unixs" {
+		if url.Scheme == "unix" || url.Scheme == "unixs" || url.Scheme == "npipe" {
 			continue
 		}
 		host, _, err := net.SplitHostPort(url.Host)
@@ -999,15 +1463,82 @@ func checkBindURLs(urls []url.URL) error {
 			// TODO: support /etc/hosts ?
 			continue
 		}
+		if i := strings.LastIndex(host, "%"); i >= 0 {
+			// IPv6 address with a zone, e.g. "fe80::1%eth0"
+			host = host[:i]
+		}
 		if net.ParseIP(host) == nil {
-			return fmt.Errorf("expected IP in URL for binding (%s)", url.String())
+			return fmt.Errorf(`expected IP in URL for binding (%s); only IP addresses and "localhost" can be listened on, e.g. "0.0.0.0" or "[::]" for all the addresses`, url.String())
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) profilePushConfig() debugutil.ProfilePushConfig {
+	return debugutil.ProfilePushConfig{
+		URL:         cfg.ExperimentalProfilingPushURL,
+		Profiles:    cfg.ExperimentalProfilingPushProfiles,
+		Interval:    cfg.ExperimentalProfilingPushInterval,
+		CPUDuration: cfg.ExperimentalProfilingPushCPUDuration,
+	}
+}
+
+// experimentalFeatureFlags are the experimental flags superseded by feature gates.
+var experimentalFeatureFlags = []struct {
+	flag    string
+	feature featuregate.Feature
+	value   func(cfg *Config) *bool
+}{
+	{"experimental-enable-distributed-tracing", features.DistributedTracing, func(cfg *Config) *bool { return &cfg.ExperimentalEnableDistributedTracing }},
+	{"experimental-initial-corrupt-check", features.InitialCorruptCheck, func(cfg *Config) *bool { return &cfg.ExperimentalInitialCorruptCheck }},
+	{"experimental-compact-hash-check-enabled", features.CompactHashCheck, func(cfg *Config) *bool { return &cfg.ExperimentalCompactHashCheckEnabled }},
+	{"experimental-enable-lease-checkpoint", features.LeaseCheckpoint, func(cfg *Config) *bool { return &cfg.ExperimentalEnableLeaseCheckpoint }},
+	{"experimental-enable-lease-checkpoint-persist", features.LeaseCheckpointPersist, func(cfg *Config) *bool { return &cfg.ExperimentalEnableLeaseCheckpointPersist }},
+	{"experimental-memory-mlock", features.MemoryMlock, func(cfg *Config) *bool { return &cfg.ExperimentalMemoryMlock }},
+	{"experimental-txn-mode-write-with-shared-buffer", features.TxnModeWriteWithSharedBuffer, func(cfg *Config) *bool { return &cfg.ExperimentalTxnModeWriteWithSharedBuffer }},
+	{"experimental-disk-degraded-transfer-leadership", features.DiskDegradedTransferLeadership, func(cfg *Config) *bool { return &cfg.ExperimentalDiskDegradedTransferLeadership }},
+}
+
+// setupFeatureGates sets up the feature gate of the server from FeatureGates,
+// folding in the experimental flags not at the default of their feature, and
+// sets the experimental fields to the values of their features.
+func (cfg *Config) setupFeatureGates() error {
+	fg := features.NewDefaultServerFeatureGate(cfg.logger)
+	if err := fg.Set(cfg.FeatureGates); err != nil {
+		return fmt.Errorf("invalid --feature-gates %q (%v)", cfg.FeatureGates, err)
+	}
+	for _, ef := range experimentalFeatureFlags {
+		v := ef.value(cfg)
+		if *v == features.DefaultEtcdServerFeatureGates[ef.feature].Default || (fg.IsSet(ef.feature) && fg.Enabled(ef.feature) == *v) {
+			*v = fg.Enabled(ef.feature)
+			continue
+		}
+		if fg.IsSet(ef.feature) {
+			return fmt.Errorf("--%s=%t conflicts with --feature-gates=%s=%t", ef.flag, *v, ef.feature, fg.Enabled(ef.feature))
+		}
+		cfg.logger.Warn(
+			"experimental flag is deprecated, use the feature gate instead",
+			zap.String("flag", "--"+ef.flag),
+			zap.String("feature-gate", fmt.Sprintf("%s=%t", ef.feature, *v)),
+		)
+		if err := fg.SetFromMap(map[string]bool{string(ef.feature): *v}); err != nil {
+			return err
 		}
 	}
+	// the folded experimental flags are not warned about again on the next validation
+	cfg.FeatureGates = fg.String()
+	cfg.ServerFeatureGate = fg
 	return nil
 }
 
 func checkHostURLs(urls []url.URL) error {
 	for _, url := range urls {
+		if url.Scheme == "unix" || url.Scheme == "unixs" || url.Scheme == "npipe" {
+			if strings.Trim(url.Host+url.Path, "/") == "" {
+				return fmt.Errorf("unexpected empty socket path (%s)", url.String())
+			}
+			continue
+		}
 		host, _, err := net.SplitHostPort(url.Host)
 		if err != nil {
 			return err
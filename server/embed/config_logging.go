@@ -112,11 +112,14 @@ func (cfg *Config) setupLogging() error {
 			}
 			copied.Encoding = encoding
 			if cfg.ZapLoggerBuilder == nil {
+				// the levels filter the entries instead, to be adjustable at runtime
+				copied.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
 				lg, err := copied.Build()
 				if err != nil {
 					return err
 				}
-				cfg.ZapLoggerBuilder = NewZapLoggerBuilder(lg)
+				cfg.logLevels = logutil.NewLevels(logutil.ConvertToZapLevel(cfg.LogLevel))
+				cfg.ZapLoggerBuilder = NewZapLoggerBuilder(lg.WithOptions(zap.WrapCore(cfg.logLevels.WrapCore)))
 			}
 		} else {
 			if len(cfg.LogOutputs) > 1 {
@@ -133,8 +136,6 @@ func (cfg *Config) setupLogging() error {
 				return lerr
 			}
 
-			lvl := zap.NewAtomicLevelAt(logutil.ConvertToZapLevel(cfg.LogLevel))
-
 			var encoder zapcore.Encoder
 			encoding, err := logutil.ConvertToZapFormat(cfg.LogFormat)
 			if err != nil {
@@ -149,13 +150,15 @@ func (cfg *Config) setupLogging() error {
 
 			// WARN: do not change field names in encoder config
 			// journald logging writer assumes field names of "level" and "caller"
+			// the levels filter the entries instead, to be adjustable at runtime
 			cr := zapcore.NewCore(
 				encoder,
 				syncer,
-				lvl,
+				zap.NewAtomicLevelAt(zap.DebugLevel),
 			)
 			if cfg.ZapLoggerBuilder == nil {
-				cfg.ZapLoggerBuilder = NewZapLoggerBuilder(zap.New(cr, zap.AddCaller(), zap.ErrorOutput(syncer)))
+				cfg.logLevels = logutil.NewLevels(logutil.ConvertToZapLevel(cfg.LogLevel))
+				cfg.ZapLoggerBuilder = NewZapLoggerBuilder(zap.New(cr, zap.AddCaller(), zap.ErrorOutput(syncer), zap.WrapCore(cfg.logLevels.WrapCore)))
 			}
 		}
 
@@ -0,0 +1,222 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package embed
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/etcdserver"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// configFileCheckInterval is the interval the configuration file is checked
+// for changes at.
+var configFileCheckInterval = 5 * time.Second
+
+// reloadableSetting checks the new value of a setting of the configuration
+// file, returning the function applying it to the running server.
+type reloadableSetting func(e *Etcd, cfg *Config) (apply func() error, err error)
+
+// reloadableSettings are the settings of the configuration file, by name,
+// applied without restart when they change. Changing any other setting
+// requires a restart, and makes the whole change rejected.
+var reloadableSettings = map[string]reloadableSetting{
+	"log-level":                 reloadLogLevel,
+	"auto-compaction-retention": reloadAutoCompactionRetention,
+	"auth-token-ttl":            reloadAuthTokenTTL,
+}
+
+func reloadLogLevel(e *Etcd, cfg *Config) (func() error, error) {
+	ll := e.Server.Cfg.LogLevels
+	if ll == nil {
+		return nil, fmt.Errorf("the log level of the configured logger cannot be changed at runtime")
+	}
+	var lvl zapcore.Level
+	if err := lvl.Set(cfg.LogLevel); err != nil {
+		return nil, err
+	}
+	return func() error {
+		ll.SetLevel(lvl)
+		return nil
+	}, nil
+}
+
+func reloadAutoCompactionRetention(e *Etcd, cfg *Config) (func() error, error) {
+	retention := cfg.AutoCompactionRetention
+	if retention == "" {
+		retention = "0"
+	}
+	d, err := parseCompactionRetention(cfg.AutoCompactionMode, retention)
+	if err != nil {
+		return nil, err
+	}
+	if d != 0 && cfg.AutoCompactionMode != CompactorModePeriodic && cfg.AutoCompactionMode != CompactorModeRevision {
+		return nil, fmt.Errorf("unsupported auto-compaction-mode %q", cfg.AutoCompactionMode)
+	}
+	return func() error { return e.Server.SetAutoCompactionRetention(d) }, nil
+}
+
+func reloadAuthTokenTTL(e *Etcd, cfg *Config) (func() error, error) {
+	ttl := time.Duration(cfg.AuthTokenTTL) * time.Second
+	return func() error {
+		e.Server.AuthStore().SetSimpleTokenTTL(ttl)
+		return nil
+	}, nil
+}
+
+// untaggedSettings are the names in the configuration file of the fields set
+// from other options of the file.
+var untaggedSettings = map[string]string{
+	"LPUrls":            "listen-peer-urls",
+	"LCUrls":            "listen-client-urls",
+	"APUrls":            "initial-advertise-peer-urls",
+	"ACUrls":            "advertise-client-urls",
+	"ListenMetricsUrls": "listen-metrics-urls",
+	"CORS":              "cors",
+	"HostWhitelist":     "host-whitelist",
+	"ClientTLSInfo":     "client-transport-security",
+	"ClientAutoTLS":     "client-transport-security",
+	"PeerTLSInfo":       "peer-transport-security",
+	"PeerAutoTLS":       "peer-transport-security",
+}
+
+// parseConfigFile returns the configuration of the content of a
+// configuration file, without validating it.
+func parseConfigFile(b []byte) (*Config, error) {
+	cfg := &configYAML{Config: *NewConfig()}
+	if err := cfg.parseConfigFile(b); err != nil {
+		return nil, err
+	}
+	return &cfg.Config, nil
+}
+
+// changedSettings returns the names of the settings differing between the
+// configurations. The fields not set from the configuration file, i.e.
+// unexported, functions or not serialized, are ignored.
+func changedSettings(prev, next *Config) []string {
+	pv, nv := reflect.ValueOf(*prev), reflect.ValueOf(*next)
+	t := pv.Type()
+	var changed []string
+	seen := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || f.Type.Kind() == reflect.Func || name == "-" {
+			continue
+		}
+		if name == "" {
+			if name = untaggedSettings[f.Name]; name == "" {
+				name = f.Name
+			}
+		}
+		if !seen[name] && !reflect.DeepEqual(pv.Field(i).Interface(), nv.Field(i).Interface()) {
+			seen[name] = true
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// reloadConfig applies to the server the settings changed from prev to next.
+// Nothing is applied if a setting not in reloadableSettings changed, or if a
+// new value is invalid.
+func (e *Etcd) reloadConfig(prev, next *Config) (changed []string, err error) {
+	changed = changedSettings(prev, next)
+	var immutable []string
+	for _, name := range changed {
+		if _, ok := reloadableSettings[name]; !ok {
+			immutable = append(immutable, name)
+		}
+	}
+	if len(immutable) > 0 {
+		return changed, fmt.Errorf("cannot change %s without restart", strings.Join(immutable, ", "))
+	}
+	applies := make([]func() error, 0, len(changed))
+	for _, name := range changed {
+		apply, err := reloadableSettings[name](e, next)
+		if err != nil {
+			return changed, fmt.Errorf("invalid %s (%v)", name, err)
+		}
+		applies = append(applies, apply)
+	}
+	for i, apply := range applies {
+		if err = apply(); err != nil {
+			return changed, fmt.Errorf("failed to change %s (%v)", changed[i], err)
+		}
+	}
+	return changed, nil
+}
+
+// startConfigFileWatch reads the configuration file, then reloads it whenever
+// its content changes until the server stops.
+func (e *Etcd) startConfigFileWatch() {
+	lg, path := e.GetLogger(), e.cfg.ConfigFile
+	last, err := os.ReadFile(path)
+	if err == nil {
+		var cur *Config
+		if cur, err = parseConfigFile(last); err == nil {
+			go e.watchConfigFile(path, configFileCheckInterval, last, cur)
+			return
+		}
+	}
+	lg.Warn("failed to read configuration file, its changes will not be reloaded", zap.String("path", path), zap.Error(err))
+}
+
+func (e *Etcd) watchConfigFile(path string, interval time.Duration, last []byte, cur *Config) {
+	lg := e.GetLogger()
+	for {
+		select {
+		case <-time.After(interval):
+		case <-e.stopc:
+			return
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			if last != nil {
+				lg.Warn("failed to read configuration file", zap.String("path", path), zap.Error(err))
+			}
+			last = nil
+			continue
+		}
+		if last != nil && bytes.Equal(b, last) {
+			continue
+		}
+		last = b
+
+		next, err := parseConfigFile(b)
+		var changed []string
+		if err == nil {
+			changed, err = e.reloadConfig(cur, next)
+		}
+		if err != nil {
+			lg.Warn("rejected configuration file change", zap.String("path", path), zap.Error(err))
+			e.Server.RecordEvent(etcdserver.EventConfigRejected, fmt.Sprintf("rejected change of configuration file %s: %v", path, err))
+			continue
+		}
+		cur = next
+		if len(changed) > 0 {
+			lg.Info("reloaded configuration file", zap.String("path", path), zap.Strings("changed", changed))
+			e.Server.RecordEvent(etcdserver.EventConfigReloaded, fmt.Sprintf("changed %s from configuration file %s", strings.Join(changed, ", "), path))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package embed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/etcdserver"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestConfigFileReload(t *testing.T) {
+	defer func(d time.Duration) { configFileCheckInterval = d }(configFileCheckInterval)
+	configFileCheckInterval = 10 * time.Millisecond
+
+	tdir := t.TempDir()
+	urls := newEmbedURLs(2)
+	base := fmt.Sprintf(`data-dir: %s
+listen-client-urls: %s
+advertise-client-urls: %s
+listen-peer-urls: %s
+initial-advertise-peer-urls: %s
+initial-cluster: default=%s
+log-outputs: [/dev/null]
+auto-compaction-mode: periodic
+`, filepath.Join(tdir, "data"), urls[0].String(), urls[0].String(), urls[1].String(), urls[1].String(), urls[1].String())
+	path := filepath.Join(tdir, "etcd.conf.yml")
+	writeConfig := func(extra string) {
+		if err := os.WriteFile(path, []byte(base+extra), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var e *Etcd
+	waitEvent := func(typ string) {
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+			evs := e.Server.Events(1)
+			if len(evs) == 1 && evs[0].Type == typ {
+				return
+			}
+		}
+		t.Fatalf("no %s event", typ)
+	}
+
+	writeConfig("log-level: info\n")
+	cfg, err := ConfigFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err = StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	<-e.Server.ReadyNotify()
+
+	writeConfig("log-level: debug\nauto-compaction-retention: 1h\nauth-token-ttl: 60\n")
+	waitEvent(etcdserver.EventConfigReloaded)
+	if lvl := e.Server.Cfg.LogLevels.Level(); lvl != zapcore.DebugLevel {
+		t.Errorf("log level = %v, want %v", lvl, zapcore.DebugLevel)
+	}
+
+	writeConfig("log-level: warn\nname: infra1\n")
+	waitEvent(etcdserver.EventConfigRejected)
+	if lvl := e.Server.Cfg.LogLevels.Level(); lvl != zapcore.DebugLevel {
+		t.Errorf("log level = %v after rejected change, want %v", lvl, zapcore.DebugLevel)
+	}
+}
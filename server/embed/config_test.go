@@ -20,6 +20,9 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -181,6 +184,28 @@ func TestAutoCompactionModeInvalid(t *testing.T) {
 	}
 }
 
+func TestBackendFreelistTypeInvalid(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Logger = "zap"
+	cfg.LogOutputs = []string{"/dev/null"}
+	cfg.BackendFreelistType = "hashmap"
+	err := cfg.Validate()
+	if err == nil {
+		t.Errorf("expected non-nil error, got %v", err)
+	}
+}
+
+func TestBackendMmapAdviceInvalid(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Logger = "zap"
+	cfg.LogOutputs = []string{"/dev/null"}
+	cfg.ExperimentalBackendMmapAdvice = "sequential"
+	err := cfg.Validate()
+	if err == nil {
+		t.Errorf("expected non-nil error, got %v", err)
+	}
+}
+
 func TestAutoCompactionModeParse(t *testing.T) {
 	tests := []struct {
 		mode      string
@@ -428,3 +453,327 @@ func TestLogRotation(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckBindURLs(t *testing.T) {
+	tests := []struct {
+		urls    []string
+		wantErr bool
+	}{
+		{[]string{"http://127.0.0.1:2379", "http://[::1]:2379"}, false},
+		{[]string{"http://0.0.0.0:2379", "http://[::]:2379"}, false},
+		{[]string{"http://[fe80::1%25eth0]:2380"}, false},
+		{[]string{"http://localhost:2379", "unix://localhost:2379"}, false},
+		{[]string{"http://infra0.example.com:2380"}, true},
+	}
+	for i, tt := range tests {
+		err := checkBindURLs(types.MustNewURLs(tt.urls))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("#%d: checkBindURLs(%q) = %v, want error %v", i, tt.urls, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckHostURLs(t *testing.T) {
+	tests := []struct {
+		urls    []string
+		wantErr bool
+	}{
+		{[]string{"http://127.0.0.1:2379", "https://infra0.example.com:2379"}, false},
+		{[]string{"unix:///var/run/etcd/etcd.sock", "unixs://localhost:2379"}, false},
+		{[]string{"unix://"}, true},
+		{[]string{"npipe:////./pipe/etcd"}, false},
+		{[]string{"npipe://"}, true},
+		{[]string{"http://:2379"}, true},
+	}
+	for i, tt := range tests {
+		urls, err := NewClientURLs(tt.urls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = checkHostURLs(urls)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("#%d: checkHostURLs(%q) = %v, want error %v", i, tt.urls, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigFileUnixSocketClientURLs(t *testing.T) {
+	yc := struct {
+		LCUrlsJSON       string `json:"listen-client-urls"`
+		ClientSocketMode uint32 `json:"listen-client-socket-mode"`
+	}{"unix:///var/run/etcd/etcd.sock", 0660}
+	b, err := yaml.Marshal(&yc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpfile := mustCreateCfgFile(t, b)
+	defer os.Remove(tmpfile.Name())
+
+	cfg, err := ConfigFromFile(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.ACUrls, cfg.LCUrls) {
+		t.Errorf("advertise client urls = %v, want %v", cfg.ACUrls, cfg.LCUrls)
+	}
+	if cfg.ClientSocketMode != 0660 {
+		t.Errorf("client socket mode = %#o, want 0660", cfg.ClientSocketMode)
+	}
+
+	cfg.ClientSocketMode = os.ModeSocket | 0660
+	if err = cfg.Validate(); err == nil {
+		t.Error("expected error for a client socket mode other than permissions")
+	}
+}
+
+func TestUpdateDefaultAdvertiseClientURLs(t *testing.T) {
+	cfg := NewConfig()
+	cfg.LCUrls = types.MustNewURLs([]string{"unix:///var/run/etcd/etcd.sock"})
+	cfg.ACUrls = nil
+	if err := cfg.Validate(); err != ErrUnsetAdvertiseClientURLsFlag {
+		t.Fatalf("Validate() = %v, want %v", err, ErrUnsetAdvertiseClientURLsFlag)
+	}
+	if cfg.ACUrls != nil {
+		t.Fatalf("Validate() set the advertise client urls to %v", cfg.ACUrls)
+	}
+
+	cfg.UpdateDefaultAdvertiseClientURLs()
+	if !reflect.DeepEqual(cfg.ACUrls, cfg.LCUrls) {
+		t.Errorf("advertise client urls = %v, want %v", cfg.ACUrls, cfg.LCUrls)
+	}
+
+	cfg.LCUrls = types.MustNewURLs([]string{"unix:///var/run/etcd/etcd.sock", "http://127.0.0.1:2379"})
+	cfg.ACUrls = nil
+	cfg.UpdateDefaultAdvertiseClientURLs()
+	if cfg.ACUrls != nil {
+		t.Errorf("advertise client urls = %v, want none for a TCP listener", cfg.ACUrls)
+	}
+}
+
+func TestPipeName(t *testing.T) {
+	tests := []struct {
+		url  string
+		name string
+	}{
+		{"npipe:////./pipe/etcd", `\\.\pipe\etcd`},
+		{"npipe://./pipe/etcd", `\\.\pipe\etcd`},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := pipeName(*u); name != tt.name {
+			t.Errorf("pipeName(%s) = %s, want %s", tt.url, name, tt.name)
+		}
+	}
+}
+
+func TestNewClientURLs(t *testing.T) {
+	urls, err := NewClientURLs([]string{"npipe:////./pipe/etcd", "http://127.0.0.1:2379"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.URLs{{Scheme: "http", Host: "127.0.0.1:2379"}, {Scheme: "npipe", Path: "//./pipe/etcd"}}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("urls = %+v, want %+v", urls, want)
+	}
+	if _, err = NewClientURLs([]string{"npipe:////./pipe/etcd", "mailto://127.0.0.1:2379"}); err == nil {
+		t.Error("expected error for an unsupported scheme")
+	}
+}
+
+func TestConfigFilePipeClientURLs(t *testing.T) {
+	yc := struct {
+		LCUrlsJSON string `json:"listen-client-urls"`
+	}{"npipe:////./pipe/etcd"}
+	b, err := yaml.Marshal(&yc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpfile := mustCreateCfgFile(t, b)
+	defer os.Remove(tmpfile.Name())
+
+	cfg, err := ConfigFromFile(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []url.URL{{Scheme: "npipe", Path: "//./pipe/etcd"}}
+	if !reflect.DeepEqual(cfg.LCUrls, want) || !reflect.DeepEqual(cfg.ACUrls, want) {
+		t.Errorf("listen and advertise client urls = %v and %v, want %v", cfg.LCUrls, cfg.ACUrls, want)
+	}
+}
+
+func TestValidatePipeURLs(t *testing.T) {
+	cfg := NewConfig()
+	cfg.LCUrls = []url.URL{{Scheme: "npipe", Path: "//./pipe/etcd"}}
+	cfg.ACUrls = nil
+	cfg.UpdateDefaultAdvertiseClientURLs()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.ACUrls, cfg.LCUrls) {
+		t.Errorf("advertise client urls = %v, want %v", cfg.ACUrls, cfg.LCUrls)
+	}
+
+	cfg = NewConfig()
+	cfg.LPUrls = []url.URL{{Scheme: "npipe", Path: "//./pipe/etcd-peer"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for a peer named pipe")
+	}
+}
+
+func TestListenNetwork(t *testing.T) {
+	urls := types.MustNewURLs([]string{
+		"http://0.0.0.0:2379", "http://[::]:2379",
+		"http://0.0.0.0:2380",
+		"http://[::]:2381",
+		"http://10.0.0.1:2382", "http://[fd00::1]:2382",
+	})
+	tests := []struct {
+		url     string
+		network string
+	}{
+		{"http://0.0.0.0:2379", "tcp4"},
+		{"http://[::]:2379", "tcp6"},
+		{"http://0.0.0.0:2380", "tcp"},
+		{"http://[::]:2381", "tcp"},
+		{"http://10.0.0.1:2382", "tcp"},
+		{"http://[fd00::1]:2382", "tcp"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if network := listenNetwork(*u, urls); network != tt.network {
+			t.Errorf("listenNetwork(%s) = %q, want %q", tt.url, network, tt.network)
+		}
+	}
+}
+
+func TestSetupFeatureGates(t *testing.T) {
+	tests := []struct {
+		name          string
+		featureGates  string
+		configFunc    func(cfg *Config)
+		wFeatureGates string
+		wErr          bool
+		check         func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.ExperimentalInitialCorruptCheck || !cfg.ExperimentalTxnModeWriteWithSharedBuffer {
+					t.Errorf("experimental fields not at their default")
+				}
+			},
+		},
+		{
+			name:          "feature gates set the experimental fields",
+			featureGates:  "InitialCorruptCheck=true,TxnModeWriteWithSharedBuffer=false",
+			wFeatureGates: "InitialCorruptCheck=true,TxnModeWriteWithSharedBuffer=false",
+			check: func(t *testing.T, cfg *Config) {
+				if !cfg.ExperimentalInitialCorruptCheck || cfg.ExperimentalTxnModeWriteWithSharedBuffer {
+					t.Errorf("experimental fields not set from the feature gates")
+				}
+			},
+		},
+		{
+			name:          "experimental flags are folded into the feature gates",
+			configFunc:    func(cfg *Config) { cfg.ExperimentalMemoryMlock = true },
+			wFeatureGates: "MemoryMlock=true",
+		},
+		{
+			name:          "experimental flags agreeing with the feature gates",
+			featureGates:  "MemoryMlock=true",
+			configFunc:    func(cfg *Config) { cfg.ExperimentalMemoryMlock = true },
+			wFeatureGates: "MemoryMlock=true",
+		},
+		{
+			name:         "experimental flags conflicting with the feature gates",
+			featureGates: "TxnModeWriteWithSharedBuffer=true",
+			configFunc:   func(cfg *Config) { cfg.ExperimentalTxnModeWriteWithSharedBuffer = false },
+			wErr:         true,
+		},
+		{
+			name:         "unknown feature gate",
+			featureGates: "Unknown=true",
+			wErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			cfg.FeatureGates = tt.featureGates
+			if tt.configFunc != nil {
+				tt.configFunc(cfg)
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.FeatureGates != tt.wFeatureGates {
+				t.Errorf("FeatureGates = %q, want %q", cfg.FeatureGates, tt.wFeatureGates)
+			}
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+			// validating again gives the same result
+			if err = cfg.Validate(); err != nil {
+				t.Fatalf("second Validate() = %v", err)
+			}
+			if cfg.FeatureGates != tt.wFeatureGates {
+				t.Errorf("FeatureGates = %q after second Validate(), want %q", cfg.FeatureGates, tt.wFeatureGates)
+			}
+		})
+	}
+}
+
+func TestChangedSettings(t *testing.T) {
+	prev, err := parseConfigFile([]byte("name: infra1\nlog-level: info\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file     string
+		wchanged []string
+	}{
+		{"name: infra1\nlog-level: info\n", nil},
+		// explicitly set to the default
+		{"name: infra1\nlog-level: info\nauth-token-ttl: 300\n", nil},
+		{"name: infra1\nlog-level: debug\nauth-token-ttl: 60\n", []string{"auth-token-ttl", "log-level"}},
+		{"name: infra2\nlisten-client-urls: http://localhost:12379\n", []string{"name", "listen-client-urls"}},
+		{"listen-metrics-urls: http://localhost:12381\n", []string{"name", "listen-metrics-urls"}},
+	}
+	for i, tt := range tests {
+		next, err := parseConfigFile([]byte(tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed := changedSettings(prev, next)
+		sort.Strings(changed)
+		sort.Strings(tt.wchanged)
+		if !reflect.DeepEqual(changed, tt.wchanged) {
+			t.Errorf("#%d: changedSettings() = %q, want %q", i, changed, tt.wchanged)
+		}
+	}
+}
+
+func TestReloadConfigImmutable(t *testing.T) {
+	prev, err := parseConfigFile([]byte("name: infra1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := parseConfigFile([]byte("name: infra2\nlog-level: debug\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the server is not used since nothing is applied
+	if _, err = (&Etcd{}).reloadConfig(prev, next); err == nil || !strings.Contains(err.Error(), "cannot change name without restart") {
+		t.Errorf("reloadConfig() = %v, want error about name", err)
+	}
+}
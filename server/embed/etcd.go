@@ -38,6 +38,9 @@ import (
 	"go.etcd.io/etcd/server/v3/etcdserver"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/etcdhttp"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/rafthttp"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2v3"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3client"
+	"go.etcd.io/etcd/server/v3/features"
 	"go.etcd.io/etcd/server/v3/storage"
 	"go.etcd.io/etcd/server/v3/verify"
 
@@ -111,11 +114,26 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		e = nil
 	}()
 
+	if cfg.EnableSocketActivation {
+		urls := append(append(append([]url.URL{}, cfg.LPUrls...), cfg.LCUrls...), cfg.ListenMetricsUrls...)
+		if cfg.inherited, err = newInheritedListeners(cfg.logger, urls); err != nil {
+			return e, err
+		}
+		// the listeners left untaken, on error, are closed
+		defer cfg.inherited.close()
+	}
+
 	if !cfg.SocketOpts.Empty() {
 		cfg.logger.Info(
 			"configuring socket options",
 			zap.Bool("reuse-address", cfg.SocketOpts.ReuseAddress),
 			zap.Bool("reuse-port", cfg.SocketOpts.ReusePort),
+			zap.Duration("keep-alive-time", cfg.SocketOpts.KeepAliveTime),
+			zap.Duration("keep-alive-interval", cfg.SocketOpts.KeepAliveInterval),
+			zap.Int("keep-alive-count", cfg.SocketOpts.KeepAliveCount),
+			zap.Duration("user-timeout", cfg.SocketOpts.UserTimeout),
+			zap.Int("read-buffer-size", cfg.SocketOpts.ReadBufferSize),
+			zap.Int("write-buffer-size", cfg.SocketOpts.WriteBufferSize),
 		)
 	}
 	e.cfg.logger.Info(
@@ -170,6 +188,7 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		DedicatedWALDir:                          cfg.WalDir,
 		SnapshotCount:                            cfg.SnapshotCount,
 		SnapshotCatchUpEntries:                   cfg.SnapshotCatchUpEntries,
+		ExperimentalMaxSnapshotCatchUpEntries:    cfg.ExperimentalMaxSnapshotCatchUpEntries,
 		MaxSnapFiles:                             cfg.MaxSnapFiles,
 		MaxWALFiles:                              cfg.MaxWalFiles,
 		InitialPeerURLsMap:                       urlsmap,
@@ -181,6 +200,8 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		PeerTLSInfo:                              cfg.PeerTLSInfo,
 		TickMs:                                   cfg.TickMs,
 		ElectionTicks:                            cfg.ElectionTicks(),
+		ElectionJitterTicks:                      cfg.ElectionJitterTicks(),
+		ExperimentalTickSkewTolerance:            cfg.ExperimentalTickSkewTolerance,
 		WaitClusterReadyTimeout:                  cfg.ExperimentalWaitClusterReadyTimeout,
 		InitialElectionTickAdvance:               cfg.InitialElectionTickAdvance,
 		AutoCompactionRetention:                  autoCompactionRetention,
@@ -188,12 +209,20 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		QuotaBackendBytes:                        cfg.QuotaBackendBytes,
 		BackendBatchLimit:                        cfg.BackendBatchLimit,
 		BackendFreelistType:                      backendFreelistType,
+		BackendFreelistSync:                      cfg.BackendFreelistSync,
+		BackendInitialMmapSize:                   cfg.BackendInitialMmapSize,
+		ExperimentalBackendMmapAdvice:            cfg.ExperimentalBackendMmapAdvice,
+		ExperimentalSnapshotSendRateBytes:        cfg.ExperimentalSnapshotSendRateBytes,
+		BackendWarmUp:                            cfg.ServerFeatureGate.Enabled(features.BackendWarmUp),
 		BackendBatchInterval:                     cfg.BackendBatchInterval,
 		MaxTxnOps:                                cfg.MaxTxnOps,
 		MaxRequestBytes:                          cfg.MaxRequestBytes,
 		MaxConcurrentStreams:                     cfg.MaxConcurrentStreams,
 		SocketOpts:                               cfg.SocketOpts,
 		StrictReconfigCheck:                      cfg.StrictReconfigCheck,
+		AutoPromoteLearners:                      cfg.AutoPromoteLearners,
+		AutoPromoteLearnersMaxLag:                cfg.AutoPromoteLearnersMaxLag,
+		AutoPromoteLearnersSyncedDuration:        cfg.AutoPromoteLearnersSyncedDuration,
 		ClientCertAuthEnabled:                    cfg.ClientTLSInfo.ClientCertAuth,
 		AuthToken:                                cfg.AuthToken,
 		BcryptCost:                               cfg.BcryptCost,
@@ -205,24 +234,67 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		CompactHashCheckEnabled:                  cfg.ExperimentalCompactHashCheckEnabled,
 		CompactHashCheckTime:                     cfg.ExperimentalCompactHashCheckTime,
 		PreVote:                                  cfg.PreVote,
+		CheckQuorum:                              cfg.CheckQuorum,
+		ExperimentalElectionPriority:             cfg.ExperimentalElectionPriority,
 		Logger:                                   cfg.logger,
+		LogLevels:                                cfg.logLevels,
 		ForceNewCluster:                          cfg.ForceNewCluster,
 		EnableGRPCGateway:                        cfg.EnableGRPCGateway,
 		ExperimentalEnableDistributedTracing:     cfg.ExperimentalEnableDistributedTracing,
+		UnaryInterceptors:                        cfg.UnaryInterceptors,
+		StreamInterceptors:                       cfg.StreamInterceptors,
 		UnsafeNoFsync:                            cfg.UnsafeNoFsync,
+		ExperimentalWALGroupSyncMaxDelay:         cfg.ExperimentalWALGroupSyncMaxDelay,
 		EnableLeaseCheckpoint:                    cfg.ExperimentalEnableLeaseCheckpoint,
 		LeaseCheckpointPersist:                   cfg.ExperimentalEnableLeaseCheckpointPersist,
+		LeaseCheckpointInterval:                  cfg.ExperimentalLeaseCheckpointInterval,
 		CompactionBatchLimit:                     cfg.ExperimentalCompactionBatchLimit,
 		CompactionSleepInterval:                  cfg.ExperimentalCompactionSleepInterval,
+		CompactionPauseBackendCommitThreshold:    cfg.ExperimentalCompactionPauseBackendCommitThreshold,
+		CompactionPausePendingProposals:          cfg.ExperimentalCompactionPausePendingProposals,
+		CompactionMaxPause:                       cfg.ExperimentalCompactionMaxPause,
+		CompactionBarrierMaxDuration:             cfg.ExperimentalCompactionBarrierMaxDuration,
+		CompactionBarrierMaxRevisions:            cfg.ExperimentalCompactionBarrierMaxRevisions,
+		HistoryRetention:                         cfg.ExperimentalHistoryRetention,
+		PrefixStats:                              cfg.ExperimentalPrefixStats,
 		WatchProgressNotifyInterval:              cfg.ExperimentalWatchProgressNotifyInterval,
+		WatchMaxStreamAge:                        cfg.ExperimentalWatchMaxStreamAge,
 		DowngradeCheckTime:                       cfg.ExperimentalDowngradeCheckTime,
 		WarningApplyDuration:                     cfg.ExperimentalWarningApplyDuration,
 		WarningUnaryRequestDuration:              cfg.ExperimentalWarningUnaryRequestDuration,
 		ExperimentalMemoryMlock:                  cfg.ExperimentalMemoryMlock,
 		ExperimentalTxnModeWriteWithSharedBuffer: cfg.ExperimentalTxnModeWriteWithSharedBuffer,
-		ExperimentalBootstrapDefragThresholdMegabytes: cfg.ExperimentalBootstrapDefragThresholdMegabytes,
-		ExperimentalMaxLearners:                       cfg.ExperimentalMaxLearners,
-		V2Deprecation:                                 cfg.V2DeprecationEffective(),
+		BatchApply:                               cfg.ServerFeatureGate.Enabled(features.BatchApply),
+		ExperimentalBootstrapDefragThresholdMegabytes:  cfg.ExperimentalBootstrapDefragThresholdMegabytes,
+		ExperimentalBootstrapVerify:                    cfg.ExperimentalBootstrapVerify,
+		ExperimentalMaxConnectionMemoryBytes:           cfg.ExperimentalMaxConnectionMemoryBytes,
+		ExperimentalMaxTotalConnectionMemoryBytes:      cfg.ExperimentalMaxTotalConnectionMemoryBytes,
+		ExperimentalLargeRequestPolicy:                 cfg.ExperimentalLargeRequestPolicy,
+		ExperimentalLargeRequestBytes:                  cfg.ExperimentalLargeRequestBytes,
+		ExperimentalLargeRequestsPerMinute:             cfg.ExperimentalLargeRequestsPerMinute,
+		ExperimentalLargeRequestAllowlist:              cfg.ExperimentalLargeRequestAllowlist,
+		ExperimentalMaxLearners:                        cfg.ExperimentalMaxLearners,
+		ExperimentalGRPCCompressionRPCs:                cfg.ExperimentalGRPCCompressionRPCs,
+		ExperimentalGRPCCompressionMinBytes:            cfg.ExperimentalGRPCCompressionMinBytes,
+		ExperimentalGRPCResponseCompression:            cfg.ExperimentalGRPCResponseCompression,
+		ExperimentalWebhookURLs:                        cfg.ExperimentalWebhookURLs,
+		ExperimentalWebhookTemplate:                    cfg.ExperimentalWebhookTemplate,
+		ExperimentalWebhookRetries:                     cfg.ExperimentalWebhookRetries,
+		ExperimentalProfilingPushURL:                   cfg.ExperimentalProfilingPushURL,
+		ExperimentalProfilingPushProfiles:              cfg.ExperimentalProfilingPushProfiles,
+		ExperimentalProfilingPushInterval:              cfg.ExperimentalProfilingPushInterval,
+		ExperimentalProfilingPushCPUDuration:           cfg.ExperimentalProfilingPushCPUDuration,
+		ExperimentalDiskDegradedWALFsyncThreshold:      cfg.ExperimentalDiskDegradedWALFsyncThreshold,
+		ExperimentalDiskDegradedBackendCommitThreshold: cfg.ExperimentalDiskDegradedBackendCommitThreshold,
+		ExperimentalDiskDegradedTransferLeadership:     cfg.ExperimentalDiskDegradedTransferLeadership,
+		ExperimentalStarvationThreshold:                cfg.ExperimentalStarvationThreshold,
+		StarvationUnhealthy:                            cfg.ServerFeatureGate.Enabled(features.StarvationUnhealthy),
+		ExperimentalMaxClockSkew:                       cfg.ExperimentalMaxClockSkew,
+		ExperimentalMemoryBudgetRatio:                  cfg.ExperimentalMemoryBudgetRatio,
+		ExperimentalMaxDeleteBatchSize:                 cfg.ExperimentalMaxDeleteBatchSize,
+		ExperimentalMaxConcurrentRequests:              cfg.ExperimentalMaxConcurrentRequests,
+		ServerFeatureGate:                              cfg.ServerFeatureGate,
+		V2Deprecation:                                  cfg.V2DeprecationEffective(),
 	}
 
 	if srvcfg.ExperimentalEnableDistributedTracing {
@@ -285,6 +357,9 @@ func StartEtcd(inCfg *Config) (e *Etcd, err error) {
 		zap.Strings("listen-client-urls", e.cfg.getLCURLs()),
 		zap.Strings("listen-metrics-urls", e.cfg.getMetricsURLs()),
 	)
+	if e.cfg.ConfigFile != "" {
+		e.startConfigFileWatch()
+	}
 	serving = true
 	return e, nil
 }
@@ -329,6 +404,7 @@ func print(lg *zap.Logger, ec Config, sc config.ServerConfig, memberInitialized
 		zap.Bool("initial-election-tick-advance", sc.InitialElectionTickAdvance),
 		zap.Uint64("snapshot-count", sc.SnapshotCount),
 		zap.Uint64("snapshot-catchup-entries", sc.SnapshotCatchUpEntries),
+		zap.Uint64("max-snapshot-catchup-entries", sc.ExperimentalMaxSnapshotCatchUpEntries),
 		zap.Strings("initial-advertise-peer-urls", ec.getAPURLs()),
 		zap.Strings("listen-peer-urls", ec.getLPURLs()),
 		zap.Strings("advertise-client-urls", ec.getACURLs()),
@@ -344,6 +420,7 @@ func print(lg *zap.Logger, ec Config, sc config.ServerConfig, memberInitialized
 		zap.Uint32("max-concurrent-streams", sc.MaxConcurrentStreams),
 
 		zap.Bool("pre-vote", sc.PreVote),
+		zap.Bool("check-quorum", sc.CheckQuorum),
 		zap.Bool("initial-corrupt-check", sc.InitialCorruptCheck),
 		zap.String("corrupt-check-time-interval", sc.CorruptCheckTime.String()),
 		zap.Bool("compact-check-time-enabled", sc.CompactHashCheckEnabled),
@@ -536,6 +613,8 @@ func configurePeerListeners(cfg *Config) (peers []*peerListener, err error) {
 		}
 		peers[i] = &peerListener{close: func(context.Context) error { return nil }}
 		peers[i].Listener, err = transport.NewListenerWithOpts(u.Host, u.Scheme,
+			transport.WithInheritedListener(cfg.inherited.take(u)),
+			transport.WithNetwork(listenNetwork(u, cfg.LPUrls)),
 			transport.WithTLSInfo(&cfg.PeerTLSInfo),
 			transport.WithSocketOpts(&cfg.SocketOpts),
 			transport.WithTimeout(rafthttp.ConnReadTimeout, rafthttp.ConnWriteTimeout),
@@ -603,6 +682,36 @@ func (e *Etcd) servePeers() (err error) {
 	return nil
 }
 
+// listenNetwork returns the network to listen on the address of the URL. The
+// IPv4 and IPv6 wildcard addresses listened on the same port are listened
+// on as "tcp4" and "tcp6", since listening on the IPv6 wildcard address
+// otherwise also listens on the IPv4 one.
+func listenNetwork(u url.URL, urls []url.URL) string {
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return "tcp"
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsUnspecified() {
+		return "tcp"
+	}
+	for _, ou := range urls {
+		ohost, oport, err := net.SplitHostPort(ou.Host)
+		if err != nil || oport != port {
+			continue
+		}
+		oip := net.ParseIP(ohost)
+		if oip == nil || !oip.IsUnspecified() || (oip.To4() == nil) == (ip.To4() == nil) {
+			continue
+		}
+		if ip.To4() != nil {
+			return "tcp4"
+		}
+		return "tcp6"
+	}
+	return "tcp"
+}
+
 func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err error) {
 	if err = updateCipherSuites(&cfg.ClientTLSInfo, cfg.CipherSuites); err != nil {
 		return nil, err
@@ -617,7 +726,7 @@ func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err erro
 	sctxs = make(map[string]*serveCtx)
 	for _, u := range cfg.LCUrls {
 		sctx := newServeCtx(cfg.logger)
-		if u.Scheme == "http" || u.Scheme == "unix" {
+		if u.Scheme == "http" || u.Scheme == "unix" || u.Scheme == "npipe" {
 			if !cfg.ClientTLSInfo.Empty() {
 				cfg.logger.Warn("scheme is HTTP while key and cert files are present; ignoring key and cert files", zap.String("client-url", u.String()))
 			}
@@ -635,6 +744,10 @@ func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err erro
 			network = "unix"
 			addr = u.Host + u.Path
 		}
+		if u.Scheme == "npipe" {
+			network = "npipe"
+			addr = pipeName(u)
+		}
 		sctx.network = network
 
 		sctx.secure = u.Scheme == "https" || u.Scheme == "unixs"
@@ -646,7 +759,10 @@ func configureClientListeners(cfg *Config) (sctxs map[string]*serveCtx, err erro
 		}
 
 		if sctx.l, err = transport.NewListenerWithOpts(addr, u.Scheme,
+			transport.WithInheritedListener(cfg.inherited.take(u)),
+			transport.WithNetwork(listenNetwork(u, cfg.LCUrls)),
 			transport.WithSocketOpts(&cfg.SocketOpts),
+			transport.WithSocketMode(cfg.ClientSocketMode),
 			transport.WithSkipTLSInfoCheck(true),
 		); err != nil {
 			return nil, err
@@ -707,6 +823,9 @@ func (e *Etcd) serveClients() (err error) {
 	etcdhttp.HandleVersion(mux, e.Server)
 	etcdhttp.HandleMetrics(mux)
 	etcdhttp.HandleHealth(e.cfg.logger, mux, e.Server)
+	if e.cfg.ExperimentalEnableV2V3 != "" {
+		v2v3.HandleKeys(e.cfg.logger, mux, v2v3.NewStore(v3client.New(e.Server), e.cfg.ExperimentalEnableV2V3))
+	}
 
 	gopts := []grpc.ServerOption{}
 	if e.cfg.GRPCKeepAliveMinTime > time.Duration(0) {
@@ -748,6 +867,8 @@ func (e *Etcd) serveMetrics() (err error) {
 				tlsInfo = nil
 			}
 			ml, err := transport.NewListenerWithOpts(murl.Host, murl.Scheme,
+				transport.WithInheritedListener(e.cfg.inherited.take(murl)),
+				transport.WithNetwork(listenNetwork(murl, e.cfg.ListenMetricsUrls)),
 				transport.WithTLSInfo(tlsInfo),
 				transport.WithSocketOpts(&e.cfg.SocketOpts),
 			)
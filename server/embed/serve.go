@@ -25,6 +25,7 @@ import (
 	"strings"
 	"time"
 
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	etcdservergw "go.etcd.io/etcd/api/v3/etcdserverpb/gw"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	"go.etcd.io/etcd/client/v3/credentials"
@@ -32,6 +33,7 @@ import (
 	"go.etcd.io/etcd/pkg/v3/httputil"
 	"go.etcd.io/etcd/server/v3/config"
 	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/etcdhttp"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3client"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3election"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3election/v3electionpb"
@@ -137,15 +139,16 @@ func (sctx *serveCtx) serve(
 		go func() { errHandler(gs.Serve(grpcl)) }()
 
 		var gwmux *gw.ServeMux
+		var gwconn *grpc.ClientConn
 		if s.Cfg.EnableGRPCGateway {
-			gwmux, err = sctx.registerGateway([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())})
+			gwmux, gwconn, err = sctx.registerGateway([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())})
 			if err != nil {
 				sctx.lg.Error("registerGateway failed", zap.Error(err))
 				return err
 			}
 		}
 
-		httpmux := sctx.createMux(gwmux, handler)
+		httpmux := sctx.createMux(s, gwmux, gwconn, handler)
 
 		srvhttp := &http.Server{
 			Handler:  createAccessController(sctx.lg, s, httpmux),
@@ -179,13 +182,14 @@ func (sctx *serveCtx) serve(
 		handler = grpcHandlerFunc(gs, handler)
 
 		var gwmux *gw.ServeMux
+		var gwconn *grpc.ClientConn
 		if s.Cfg.EnableGRPCGateway {
 			dtls := tlscfg.Clone()
 			// trust local server
 			dtls.InsecureSkipVerify = true
 			bundle := credentials.NewBundle(credentials.Config{TLSConfig: dtls})
 			opts := []grpc.DialOption{grpc.WithTransportCredentials(bundle.TransportCredentials())}
-			gwmux, err = sctx.registerGateway(opts)
+			gwmux, gwconn, err = sctx.registerGateway(opts)
 			if err != nil {
 				return err
 			}
@@ -197,7 +201,7 @@ func (sctx *serveCtx) serve(
 			return err
 		}
 		// TODO: add debug flag; enable logging when debug flag is set
-		httpmux := sctx.createMux(gwmux, handler)
+		httpmux := sctx.createMux(s, gwmux, gwconn, handler)
 
 		srv := &http.Server{
 			Handler:   createAccessController(sctx.lg, s, httpmux),
@@ -246,13 +250,18 @@ func grpcHandlerFunc(grpcServer *grpc.Server, otherHandler http.Handler) http.Ha
 
 type registerHandlerFunc func(context.Context, *gw.ServeMux, *grpc.ClientConn) error
 
-func (sctx *serveCtx) registerGateway(opts []grpc.DialOption) (*gw.ServeMux, error) {
+func (sctx *serveCtx) registerGateway(opts []grpc.DialOption) (*gw.ServeMux, *grpc.ClientConn, error) {
 	ctx := sctx.ctx
 
 	addr := sctx.addr
-	if network := sctx.network; network == "unix" {
+	switch network := sctx.network; network {
+	case "unix":
 		// explicitly define unix network for gRPC socket support
 		addr = fmt.Sprintf("%s:%s", network, addr)
+	case "npipe":
+		// gRPC does not dial named pipes itself
+		addr = "passthrough:///" + addr
+		opts = append(opts, grpc.WithContextDialer(transport.DialPipe), grpc.WithAuthority("localhost"))
 	}
 
 	opts = append(opts, grpc.WithDefaultCallOptions([]grpc.CallOption{
@@ -262,7 +271,7 @@ func (sctx *serveCtx) registerGateway(opts []grpc.DialOption) (*gw.ServeMux, err
 	conn, err := grpc.DialContext(ctx, addr, opts...)
 	if err != nil {
 		sctx.lg.Error("registerGateway failed to dial", zap.String("addr", addr), zap.Error(err))
-		return nil, err
+		return nil, nil, err
 	}
 	gwmux := gw.NewServeMux()
 
@@ -278,7 +287,7 @@ func (sctx *serveCtx) registerGateway(opts []grpc.DialOption) (*gw.ServeMux, err
 	}
 	for _, h := range handlers {
 		if err := h(ctx, gwmux, conn); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 	go func() {
@@ -292,10 +301,10 @@ func (sctx *serveCtx) registerGateway(opts []grpc.DialOption) (*gw.ServeMux, err
 		}
 	}()
 
-	return gwmux, nil
+	return gwmux, conn, nil
 }
 
-func (sctx *serveCtx) createMux(gwmux *gw.ServeMux, handler http.Handler) *http.ServeMux {
+func (sctx *serveCtx) createMux(s *etcdserver.EtcdServer, gwmux *gw.ServeMux, gwconn *grpc.ClientConn, handler http.Handler) *http.ServeMux {
 	httpmux := http.NewServeMux()
 	for path, h := range sctx.userHandlers {
 		httpmux.Handle(path, h)
@@ -316,6 +325,8 @@ func (sctx *serveCtx) createMux(gwmux *gw.ServeMux, handler http.Handler) *http.
 				wsproxy.WithMaxRespBodyBufferSize(0x7fffffff),
 			),
 		)
+		etcdhttp.HandleLeaseKeepAlive(sctx.lg, httpmux, s)
+		etcdhttp.HandleWatchSSE(sctx.lg, httpmux, pb.NewWatchClient(gwconn))
 	}
 	if handler != nil {
 		httpmux.Handle("/", handler)
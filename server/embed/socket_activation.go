@@ -0,0 +1,107 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package embed
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/coreos/go-systemd/v22/activation"
+	"go.uber.org/zap"
+)
+
+// inheritedListeners are the listeners passed by systemd socket activation,
+// used instead of listening on the listen URLs of their addresses.
+type inheritedListeners struct {
+	ls []net.Listener
+}
+
+// newInheritedListeners takes the listeners passed by systemd, each of them
+// expected to be of one of the given listen URLs.
+func newInheritedListeners(lg *zap.Logger, urls []url.URL) (*inheritedListeners, error) {
+	ls, err := activation.Listeners()
+	if err != nil {
+		return nil, err
+	}
+	il := &inheritedListeners{}
+	addrs := make([]string, 0, len(ls))
+	for _, l := range ls {
+		if l != nil {
+			il.ls = append(il.ls, l)
+			addrs = append(addrs, l.Addr().String())
+		}
+	}
+	for _, l := range il.ls {
+		matched := false
+		for _, u := range urls {
+			if listenerOfURL(l, u) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			il.close()
+			return nil, fmt.Errorf("listener %s passed by systemd socket activation matches no listen URL", l.Addr())
+		}
+	}
+	lg.Info("inherited listeners from systemd socket activation", zap.Strings("addresses", addrs))
+	return il, nil
+}
+
+// take returns the inherited listener of the address of the URL, nil if none.
+// A listener is only taken once.
+func (il *inheritedListeners) take(u url.URL) net.Listener {
+	if il == nil {
+		return nil
+	}
+	for i, l := range il.ls {
+		if listenerOfURL(l, u) {
+			il.ls = append(il.ls[:i], il.ls[i+1:]...)
+			return l
+		}
+	}
+	return nil
+}
+
+// close closes the listeners not taken.
+func (il *inheritedListeners) close() {
+	if il == nil {
+		return
+	}
+	for _, l := range il.ls {
+		l.Close()
+	}
+	il.ls = nil
+}
+
+// listenerOfURL returns true if the listener listens on the address of the
+// URL, the IPv4 and IPv6 wildcard addresses of a port being the same.
+func listenerOfURL(l net.Listener, u url.URL) bool {
+	switch addr := l.Addr().(type) {
+	case *net.UnixAddr:
+		return (u.Scheme == "unix" || u.Scheme == "unixs") && addr.Name == u.Host+u.Path
+	case *net.TCPAddr:
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return false
+		}
+		uaddr, err := net.ResolveTCPAddr("tcp", u.Host)
+		if err != nil || uaddr.Port != addr.Port {
+			return false
+		}
+		return uaddr.IP.Equal(addr.IP) || (uaddr.IP.IsUnspecified() && addr.IP.IsUnspecified())
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package embed
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestInheritedListenersTake(t *testing.T) {
+	tcpl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpl.Close()
+	port := tcpl.Addr().(*net.TCPAddr).Port
+	sock := filepath.Join(t.TempDir(), "etcd.sock")
+	unixl, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unixl.Close()
+
+	il := &inheritedListeners{ls: []net.Listener{tcpl, unixl}}
+	tests := []struct {
+		url string
+		wl  net.Listener
+	}{
+		{fmt.Sprintf("http://127.0.0.1:%d", port+1), nil},
+		{fmt.Sprintf("unix://127.0.0.1:%d", port), nil},
+		{"unix://" + sock + "x", nil},
+		{fmt.Sprintf("https://127.0.0.1:%d", port), tcpl},
+		// taken only once
+		{fmt.Sprintf("http://127.0.0.1:%d", port), nil},
+		{"unixs://" + sock, unixl},
+	}
+	for i, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := il.take(*u); l != tt.wl {
+			t.Errorf("#%d: take(%s) = %v, expected %v", i, tt.url, l, tt.wl)
+		}
+	}
+	if len(il.ls) != 0 {
+		t.Errorf("listeners left = %v, expected none", il.ls)
+	}
+	// nil if not socket activated
+	var nilil *inheritedListeners
+	if l := nilil.take(url.URL{Scheme: "http", Host: "127.0.0.1:2379"}); l != nil {
+		t.Errorf("take = %v, expected nil", l)
+	}
+	nilil.close()
+}
@@ -19,16 +19,20 @@ package etcdmain
 import (
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"runtime"
+	"strings"
 
 	"go.etcd.io/etcd/api/v3/version"
 	"go.etcd.io/etcd/client/pkg/v3/logutil"
+	"go.etcd.io/etcd/client/pkg/v3/types"
 	"go.etcd.io/etcd/pkg/v3/flags"
 	cconfig "go.etcd.io/etcd/server/v3/config"
 	"go.etcd.io/etcd/server/v3/embed"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/rafthttp"
+	"go.etcd.io/etcd/server/v3/features"
 
 	"go.uber.org/zap"
 )
@@ -100,7 +104,7 @@ func newConfig() *config {
 		fmt.Fprintln(os.Stderr, usageline)
 	}
 
-	fs.StringVar(&cfg.configFile, "config-file", "", "Path to the server configuration file. Note that if a configuration file is provided, other command line flags and environment variables will be ignored.")
+	fs.StringVar(&cfg.configFile, "config-file", "", "Path to the server configuration file. Note that if a configuration file is provided, other command line flags and environment variables will be ignored. Changes of log-level, auto-compaction-retention and auth-token-ttl in the file are applied without restart.")
 
 	// member
 	fs.StringVar(&cfg.ec.Dir, "data-dir", cfg.ec.Dir, "Path to the data directory.")
@@ -111,23 +115,29 @@ func newConfig() *config {
 		"List of URLs to listen on for peer traffic.",
 	)
 	fs.Var(
-		flags.NewUniqueURLsWithExceptions(embed.DefaultListenClientURLs, ""), "listen-client-urls",
+		newClientURLsValue(embed.DefaultListenClientURLs), "listen-client-urls",
 		"List of URLs to listen on for client traffic.",
 	)
+	fs.Var(flags.NewUint32Value(embed.DefaultClientSocketMode), "listen-client-socket-mode", "Permissions of the unix sockets listened on for client traffic, e.g. 0660 to let the group of the etcd user connect.")
 	fs.Var(
 		flags.NewUniqueURLsWithExceptions("", ""),
 		"listen-metrics-urls",
 		"List of URLs to listen on for the metrics and health endpoints.",
 	)
+	fs.BoolVar(&cfg.ec.EnableSocketActivation, "enable-socket-activation", false, "Use the listeners passed by systemd socket activation for the listen URLs of their addresses.")
 	fs.UintVar(&cfg.ec.MaxSnapFiles, "max-snapshots", cfg.ec.MaxSnapFiles, "Maximum number of snapshot files to retain (0 is unlimited).")
 	fs.UintVar(&cfg.ec.MaxWalFiles, "max-wals", cfg.ec.MaxWalFiles, "Maximum number of wal files to retain (0 is unlimited).")
 	fs.StringVar(&cfg.ec.Name, "name", cfg.ec.Name, "Human-readable name for this member.")
 	fs.Uint64Var(&cfg.ec.SnapshotCount, "snapshot-count", cfg.ec.SnapshotCount, "Number of committed transactions to trigger a snapshot to disk.")
 	fs.UintVar(&cfg.ec.TickMs, "heartbeat-interval", cfg.ec.TickMs, "Time (in milliseconds) of a heartbeat interval.")
 	fs.UintVar(&cfg.ec.ElectionMs, "election-timeout", cfg.ec.ElectionMs, "Time (in milliseconds) for an election to timeout.")
+	fs.UintVar(&cfg.ec.ExperimentalElectionJitterMs, "experimental-election-timeout-jitter", cfg.ec.ExperimentalElectionJitterMs, "Range (in milliseconds) of the random time added to the election timeout of each election. Defaults to --election-timeout if 0.")
+	fs.DurationVar(&cfg.ec.ExperimentalTickSkewTolerance, "experimental-tick-skew-tolerance", cfg.ec.ExperimentalTickSkewTolerance, "Delay of a heartbeat tick above which the member is starved of CPU and a follower does not count the tick towards its election timeout. 0 disables it.")
 	fs.BoolVar(&cfg.ec.InitialElectionTickAdvance, "initial-election-tick-advance", cfg.ec.InitialElectionTickAdvance, "Whether to fast-forward initial election ticks on boot for faster election.")
 	fs.Int64Var(&cfg.ec.QuotaBackendBytes, "quota-backend-bytes", cfg.ec.QuotaBackendBytes, "Raise alarms when backend size exceeds the given quota. 0 means use the default quota.")
 	fs.StringVar(&cfg.ec.BackendFreelistType, "backend-bbolt-freelist-type", cfg.ec.BackendFreelistType, "BackendFreelistType specifies the type of freelist that boltdb backend uses(array and map are supported types)")
+	fs.BoolVar(&cfg.ec.BackendFreelistSync, "backend-bbolt-freelist-sync", false, "Persist the freelist of the boltdb backend on each commit, for large backends to open faster at the cost of slower commits.")
+	fs.Uint64Var(&cfg.ec.BackendInitialMmapSize, "backend-bbolt-initial-mmap-size", 0, "Initial size in bytes of the mmap of the boltdb backend (0 defaults to the quota plus 10%, or 10GiB without quota).")
 	fs.DurationVar(&cfg.ec.BackendBatchInterval, "backend-batch-interval", cfg.ec.BackendBatchInterval, "BackendBatchInterval is the maximum time before commit the backend transaction.")
 	fs.IntVar(&cfg.ec.BackendBatchLimit, "backend-batch-limit", cfg.ec.BackendBatchLimit, "BackendBatchLimit is the maximum operations before commit the backend transaction.")
 	fs.UintVar(&cfg.ec.MaxTxnOps, "max-txn-ops", cfg.ec.MaxTxnOps, "Maximum number of operations permitted in a transaction.")
@@ -137,6 +147,12 @@ func newConfig() *config {
 	fs.DurationVar(&cfg.ec.GRPCKeepAliveTimeout, "grpc-keepalive-timeout", cfg.ec.GRPCKeepAliveTimeout, "Additional duration of wait before closing a non-responsive connection (0 to disable).")
 	fs.BoolVar(&cfg.ec.SocketOpts.ReusePort, "socket-reuse-port", cfg.ec.SocketOpts.ReusePort, "Enable to set socket option SO_REUSEPORT on listeners allowing rebinding of a port already in use.")
 	fs.BoolVar(&cfg.ec.SocketOpts.ReuseAddress, "socket-reuse-address", cfg.ec.SocketOpts.ReuseAddress, "Enable to set socket option SO_REUSEADDR on listeners allowing binding to an address in `TIME_WAIT` state.")
+	fs.DurationVar(&cfg.ec.SocketOpts.KeepAliveTime, "socket-keepalive-time", cfg.ec.SocketOpts.KeepAliveTime, "Idle time of the connections accepted by the client and peer listeners before the first TCP keepalive probe (TCP_KEEPIDLE); 30s if 0.")
+	fs.DurationVar(&cfg.ec.SocketOpts.KeepAliveInterval, "socket-keepalive-interval", cfg.ec.SocketOpts.KeepAliveInterval, "Interval between the TCP keepalive probes of the connections accepted by the client and peer listeners (TCP_KEEPINTVL); the keepalive time if 0. Linux only.")
+	fs.IntVar(&cfg.ec.SocketOpts.KeepAliveCount, "socket-keepalive-count", cfg.ec.SocketOpts.KeepAliveCount, "Number of unacknowledged TCP keepalive probes before dropping the connections accepted by the client and peer listeners (TCP_KEEPCNT); the system default if 0. Linux only.")
+	fs.DurationVar(&cfg.ec.SocketOpts.UserTimeout, "socket-user-timeout", cfg.ec.SocketOpts.UserTimeout, "Maximum time transmitted data may remain unacknowledged before dropping the connections accepted by the client and peer listeners (TCP_USER_TIMEOUT); the system default if 0. Linux only.")
+	fs.IntVar(&cfg.ec.SocketOpts.ReadBufferSize, "socket-read-buffer-size", cfg.ec.SocketOpts.ReadBufferSize, "Size in bytes of the receive buffer of the connections accepted by the client and peer listeners (SO_RCVBUF); the system default if 0.")
+	fs.IntVar(&cfg.ec.SocketOpts.WriteBufferSize, "socket-write-buffer-size", cfg.ec.SocketOpts.WriteBufferSize, "Size in bytes of the send buffer of the connections accepted by the client and peer listeners (SO_SNDBUF); the system default if 0.")
 
 	fs.Var(flags.NewUint32Value(cfg.ec.MaxConcurrentStreams), "max-concurrent-streams", "Maximum concurrent streams that each client can open at a time.")
 
@@ -151,7 +167,7 @@ func newConfig() *config {
 		"List of this member's peer URLs to advertise to the rest of the cluster.",
 	)
 	fs.Var(
-		flags.NewUniqueURLsWithExceptions(embed.DefaultAdvertiseClientURLs, ""),
+		newClientURLsValue(embed.DefaultAdvertiseClientURLs),
 		"advertise-client-urls",
 		"List of this member's client URLs to advertise to the public.",
 	)
@@ -185,8 +201,13 @@ func newConfig() *config {
 	fs.Var(cfg.cf.clusterState, "initial-cluster-state", "Initial cluster state ('new' or 'existing').")
 
 	fs.BoolVar(&cfg.ec.StrictReconfigCheck, "strict-reconfig-check", cfg.ec.StrictReconfigCheck, "Reject reconfiguration requests that would cause quorum loss.")
+	fs.BoolVar(&cfg.ec.AutoPromoteLearners, "auto-promote-learners", false, "Promote the learners to voting members once their raft logs lag behind the leader by at most --auto-promote-learners-max-lag entries for --auto-promote-learners-synced-duration.")
+	fs.Uint64Var(&cfg.ec.AutoPromoteLearnersMaxLag, "auto-promote-learners-max-lag", cfg.ec.AutoPromoteLearnersMaxLag, "Maximum number of raft log entries a learner lags behind the leader by to be in sync, for --auto-promote-learners.")
+	fs.DurationVar(&cfg.ec.AutoPromoteLearnersSyncedDuration, "auto-promote-learners-synced-duration", cfg.ec.AutoPromoteLearnersSyncedDuration, "Duration a learner must stay in sync with the leader for before being promoted, for --auto-promote-learners.")
 
 	fs.BoolVar(&cfg.ec.PreVote, "pre-vote", cfg.ec.PreVote, "Enable to run an additional Raft election phase.")
+	fs.UintVar(&cfg.ec.ExperimentalElectionPriority, "experimental-election-priority", cfg.ec.ExperimentalElectionPriority, "Priority of the member to be the leader, the leader transferring the leadership to the connected members of higher priority. 0 is the lowest.")
+	fs.BoolVar(&cfg.ec.CheckQuorum, "check-quorum", cfg.ec.CheckQuorum, "Enable the leader to step down when it does not hear from a quorum, and the followers to ignore the votes while they hear from their leader.")
 
 	fs.Var(cfg.cf.v2deprecation, "v2-deprecation", fmt.Sprintf("v2store deprecation stage: %q. ", cfg.cf.v2deprecation.Valids()))
 
@@ -242,7 +263,7 @@ func newConfig() *config {
 	fs.StringVar(&cfg.ec.Metrics, "metrics", cfg.ec.Metrics, "Set level of detail for exported metrics, specify 'extensive' to include server side grpc histogram metrics")
 
 	// experimental distributed tracing
-	fs.BoolVar(&cfg.ec.ExperimentalEnableDistributedTracing, "experimental-enable-distributed-tracing", false, "Enable experimental distributed  tracing using OpenTelemetry Tracing.")
+	fs.BoolVar(&cfg.ec.ExperimentalEnableDistributedTracing, "experimental-enable-distributed-tracing", false, "Enable experimental distributed  tracing using OpenTelemetry Tracing. Deprecated, use --feature-gates=DistributedTracing instead.")
 	fs.StringVar(&cfg.ec.ExperimentalDistributedTracingAddress, "experimental-distributed-tracing-address", embed.ExperimentalDistributedTracingAddress, "Address for distributed tracing used for OpenTelemetry Tracing (if enabled with experimental-enable-distributed-tracing flag).")
 	fs.StringVar(&cfg.ec.ExperimentalDistributedTracingServiceName, "experimental-distributed-tracing-service-name", embed.ExperimentalDistributedTracingServiceName, "Configures service name for distributed tracing to be used to define service name for OpenTelemetry Tracing (if enabled with experimental-enable-distributed-tracing flag). 'etcd' is the default service name. Use the same service name for all instances of etcd.")
 	fs.StringVar(&cfg.ec.ExperimentalDistributedTracingServiceInstanceID, "experimental-distributed-tracing-instance-id", "", "Configures service instance ID for distributed tracing to be used to define service instance ID key for OpenTelemetry Tracing (if enabled with experimental-enable-distributed-tracing flag). There is no default value set. This ID must be unique per etcd instance.")
@@ -257,24 +278,64 @@ func newConfig() *config {
 	fs.BoolVar(&cfg.ec.EnableGRPCGateway, "enable-grpc-gateway", cfg.ec.EnableGRPCGateway, "Enable GRPC gateway.")
 
 	// experimental
-	fs.BoolVar(&cfg.ec.ExperimentalInitialCorruptCheck, "experimental-initial-corrupt-check", cfg.ec.ExperimentalInitialCorruptCheck, "Enable to check data corruption before serving any client/peer traffic.")
+	fs.BoolVar(&cfg.ec.ExperimentalInitialCorruptCheck, "experimental-initial-corrupt-check", cfg.ec.ExperimentalInitialCorruptCheck, "Enable to check data corruption before serving any client/peer traffic. Deprecated, use --feature-gates=InitialCorruptCheck instead.")
 	fs.DurationVar(&cfg.ec.ExperimentalCorruptCheckTime, "experimental-corrupt-check-time", cfg.ec.ExperimentalCorruptCheckTime, "Duration of time between cluster corruption check passes.")
-	fs.BoolVar(&cfg.ec.ExperimentalCompactHashCheckEnabled, "experimental-compact-hash-check-enabled", cfg.ec.ExperimentalCompactHashCheckEnabled, "Enable leader to periodically check followers compaction hashes.")
+	fs.BoolVar(&cfg.ec.ExperimentalCompactHashCheckEnabled, "experimental-compact-hash-check-enabled", cfg.ec.ExperimentalCompactHashCheckEnabled, "Enable leader to periodically check followers compaction hashes. Deprecated, use --feature-gates=CompactHashCheck instead.")
 	fs.DurationVar(&cfg.ec.ExperimentalCompactHashCheckTime, "experimental-compact-hash-check-time", cfg.ec.ExperimentalCompactHashCheckTime, "Duration of time between leader checks followers compaction hashes.")
 
-	fs.BoolVar(&cfg.ec.ExperimentalEnableLeaseCheckpoint, "experimental-enable-lease-checkpoint", false, "Enable leader to send regular checkpoints to other members to prevent reset of remaining TTL on leader change.")
+	fs.BoolVar(&cfg.ec.ExperimentalEnableLeaseCheckpoint, "experimental-enable-lease-checkpoint", false, "Enable leader to send regular checkpoints to other members to prevent reset of remaining TTL on leader change. Deprecated, use --feature-gates=LeaseCheckpoint instead.")
 	// TODO: delete in v3.7
-	fs.BoolVar(&cfg.ec.ExperimentalEnableLeaseCheckpointPersist, "experimental-enable-lease-checkpoint-persist", false, "Enable persisting remainingTTL to prevent indefinite auto-renewal of long lived leases. Always enabled in v3.6. Should be used to ensure smooth upgrade from v3.5 clusters with this feature enabled. Requires experimental-enable-lease-checkpoint to be enabled.")
+	fs.BoolVar(&cfg.ec.ExperimentalEnableLeaseCheckpointPersist, "experimental-enable-lease-checkpoint-persist", false, "Enable persisting remainingTTL to prevent indefinite auto-renewal of long lived leases. Always enabled in v3.6. Should be used to ensure smooth upgrade from v3.5 clusters with this feature enabled. Requires experimental-enable-lease-checkpoint to be enabled. Deprecated, use --feature-gates=LeaseCheckpointPersist instead.")
+	fs.DurationVar(&cfg.ec.ExperimentalLeaseCheckpointInterval, "experimental-lease-checkpoint-interval", cfg.ec.ExperimentalLeaseCheckpointInterval, "Interval between the checkpoints of the remaining TTL of a lease longer than it, which the members count down on leader change. Requires experimental-enable-lease-checkpoint to be enabled. 0 means 5m.")
 	fs.IntVar(&cfg.ec.ExperimentalCompactionBatchLimit, "experimental-compaction-batch-limit", cfg.ec.ExperimentalCompactionBatchLimit, "Sets the maximum revisions deleted in each compaction batch.")
 	fs.DurationVar(&cfg.ec.ExperimentalCompactionSleepInterval, "experimental-compaction-sleep-interval", cfg.ec.ExperimentalCompactionSleepInterval, "Sets the sleep interval between each compaction batch.")
+	fs.DurationVar(&cfg.ec.ExperimentalCompactionPauseBackendCommitThreshold, "experimental-compaction-pause-backend-commit-threshold", cfg.ec.ExperimentalCompactionPauseBackendCommitThreshold, "Latency of the last backend commit above which the compaction pauses between its batches. 0 disables it.")
+	fs.IntVar(&cfg.ec.ExperimentalCompactionPausePendingProposals, "experimental-compaction-pause-pending-proposals", cfg.ec.ExperimentalCompactionPausePendingProposals, "Number of pending proposals above which the compaction pauses between its batches. 0 disables it.")
+	fs.DurationVar(&cfg.ec.ExperimentalCompactionMaxPause, "experimental-compaction-max-pause", cfg.ec.ExperimentalCompactionMaxPause, "Maximum pause of the compaction between two batches.")
+	fs.DurationVar(&cfg.ec.ExperimentalCompactionBarrierMaxDuration, "experimental-compaction-barrier-max-duration", cfg.ec.ExperimentalCompactionBarrierMaxDuration, "Maximum time the compaction barrier of the ranges at a revision holds back the compaction above it. 0 disables the compaction barriers.")
+	fs.Int64Var(&cfg.ec.ExperimentalCompactionBarrierMaxRevisions, "experimental-compaction-barrier-max-revisions", cfg.ec.ExperimentalCompactionBarrierMaxRevisions, "Maximum number of revisions the current revision moves past a compaction barrier before it expires. 0 means unlimited.")
+	fs.Var(flags.NewStringsValue(""), "experimental-history-retention", "Comma-separated list of the history retained by the compactions for the keys with a prefix: '<prefix>=<versions>' retains the latest versions of each key and '<prefix>=<period>' the history of the last period, e.g. 'audit/=5,events/=24h'.")
+	fs.Var(flags.NewStringsValue(""), "experimental-prefix-stats", "Comma-separated list of the key prefixes the reads and writes served, and their bytes, are counted for, e.g. 'tenants/a/,tenants/b/'.")
+	fs.StringVar(&cfg.ec.ExperimentalBackendMmapAdvice, "experimental-backend-mmap-advice", cfg.ec.ExperimentalBackendMmapAdvice, "Madvise advice of the mmap of the backend: 'normal', 'random' or 'willneed'. Empty means the boltdb default, 'random'.")
+	fs.Uint64Var(&cfg.ec.ExperimentalMaxSnapshotCatchUpEntries, "experimental-max-snapshot-catchup-entries", cfg.ec.ExperimentalMaxSnapshotCatchUpEntries, "Maximum number of entries kept for the active followers lagging behind to catch up from after a snapshot, instead of being sent a snapshot.")
+	fs.Int64Var(&cfg.ec.ExperimentalSnapshotSendRateBytes, "experimental-snapshot-send-rate-bytes", cfg.ec.ExperimentalSnapshotSendRateBytes, "Maximum rate in bytes per second the snapshots are sent to the clients at. 0 means unlimited.")
+	fs.DurationVar(&cfg.ec.ExperimentalWALGroupSyncMaxDelay, "experimental-wal-group-sync-max-delay", cfg.ec.ExperimentalWALGroupSyncMaxDelay, "Maximum latency added to the WAL fsync of a raft ready waiting for the following readies to share it. 0 disables it.")
 	fs.DurationVar(&cfg.ec.ExperimentalWatchProgressNotifyInterval, "experimental-watch-progress-notify-interval", cfg.ec.ExperimentalWatchProgressNotifyInterval, "Duration of periodic watch progress notifications.")
+	fs.DurationVar(&cfg.ec.ExperimentalWatchMaxStreamAge, "experimental-watch-max-stream-age", cfg.ec.ExperimentalWatchMaxStreamAge, "Maximum age of the watch streams after which they are closed, for the clients to resume their watches on a new stream. 0 means unlimited.")
 	fs.DurationVar(&cfg.ec.ExperimentalDowngradeCheckTime, "experimental-downgrade-check-time", cfg.ec.ExperimentalDowngradeCheckTime, "Duration of time between two downgrade status check.")
 	fs.DurationVar(&cfg.ec.ExperimentalWarningApplyDuration, "experimental-warning-apply-duration", cfg.ec.ExperimentalWarningApplyDuration, "Time duration after which a warning is generated if request takes more time.")
 	fs.DurationVar(&cfg.ec.ExperimentalWarningUnaryRequestDuration, "experimental-warning-unary-request-duration", cfg.ec.ExperimentalWarningUnaryRequestDuration, "Time duration after which a warning is generated if a unary request takes more time.")
-	fs.BoolVar(&cfg.ec.ExperimentalMemoryMlock, "experimental-memory-mlock", cfg.ec.ExperimentalMemoryMlock, "Enable to enforce etcd pages (in particular bbolt) to stay in RAM.")
-	fs.BoolVar(&cfg.ec.ExperimentalTxnModeWriteWithSharedBuffer, "experimental-txn-mode-write-with-shared-buffer", true, "Enable the write transaction to use a shared buffer in its readonly check operations.")
+	fs.BoolVar(&cfg.ec.ExperimentalMemoryMlock, "experimental-memory-mlock", cfg.ec.ExperimentalMemoryMlock, "Enable to enforce etcd pages (in particular bbolt) to stay in RAM. Deprecated, use --feature-gates=MemoryMlock instead.")
+	fs.BoolVar(&cfg.ec.ExperimentalTxnModeWriteWithSharedBuffer, "experimental-txn-mode-write-with-shared-buffer", true, "Enable the write transaction to use a shared buffer in its readonly check operations. Deprecated, use --feature-gates=TxnModeWriteWithSharedBuffer instead.")
 	fs.UintVar(&cfg.ec.ExperimentalBootstrapDefragThresholdMegabytes, "experimental-bootstrap-defrag-threshold-megabytes", 0, "Enable the defrag during etcd server bootstrap on condition that it will free at least the provided threshold of disk space. Needs to be set to non-zero value to take effect.")
+	fs.StringVar(&cfg.ec.ExperimentalBootstrapVerify, "experimental-bootstrap-verify", "", "Verification of the persisted state during bootstrap. 'full' verifies the WAL tail, the backend consistent index and the rebuilt key index, and refuses to start on mismatch. Empty means disabled.")
 	fs.IntVar(&cfg.ec.ExperimentalMaxLearners, "experimental-max-learners", membership.DefaultMaxLearners, "Sets the maximum number of learners that can be available in the cluster membership.")
+	fs.Var(flags.NewStringsValue(""), "experimental-grpc-compression-rpcs", "Comma-separated list of RPCs (e.g. 'Range,Snapshot') whose responses may be compressed for clients compressing their requests with gzip or zstd. Empty means all RPCs.")
+	fs.IntVar(&cfg.ec.ExperimentalGRPCCompressionMinBytes, "experimental-grpc-compression-min-bytes", cfg.ec.ExperimentalGRPCCompressionMinBytes, "Minimum size of the gRPC responses compressed by the server.")
+	fs.StringVar(&cfg.ec.ExperimentalGRPCResponseCompression, "experimental-grpc-response-compression", "", "Compressor ('gzip' or 'zstd') of all the gRPC responses compressed by the server, whatever the compressor of the requests. All the clients must be able to decompress it. Empty means the compressor of the request.")
+	fs.Int64Var(&cfg.ec.ExperimentalMaxConnectionMemoryBytes, "experimental-max-connection-memory-bytes", 0, "Maximum bytes of pending responses and watch buffers held by a client connection. 0 means unlimited.")
+	fs.Int64Var(&cfg.ec.ExperimentalMaxTotalConnectionMemoryBytes, "experimental-max-total-connection-memory-bytes", 0, "Maximum bytes held by all client connections, above which the heaviest connection is evicted. 0 means unlimited.")
+	fs.StringVar(&cfg.ec.ExperimentalLargeRequestPolicy, "experimental-large-request-policy", "", "Policy applied to the users, or the client IPs without authentication, sending more large requests than allowed: 'warn' logs them, 'throttle' delays them and 'reject' fails them. Empty means disabled.")
+	fs.IntVar(&cfg.ec.ExperimentalLargeRequestBytes, "experimental-large-request-bytes", 0, "Size from which a request is large. 0 means 90% of max-request-bytes.")
+	fs.IntVar(&cfg.ec.ExperimentalLargeRequestsPerMinute, "experimental-large-requests-per-minute", cfg.ec.ExperimentalLargeRequestsPerMinute, "Number of large requests a user or client IP may send per minute before the large request policy applies.")
+	fs.Var(flags.NewStringsValue(""), "experimental-large-request-allowlist", "Comma-separated list of users and client IPs not subject to the large request policy.")
+	fs.Var(flags.NewStringsValue(""), "experimental-webhook-urls", "Comma-separated list of URLs posted when alarms are raised or cleared and when the leader changes.")
+	fs.StringVar(&cfg.ec.ExperimentalWebhookTemplate, "experimental-webhook-template", "", "Go text/template of the webhook payloads, executed on the event. Empty means the JSON encoding of the event.")
+	fs.IntVar(&cfg.ec.ExperimentalWebhookRetries, "experimental-webhook-retries", cfg.ec.ExperimentalWebhookRetries, "Number of retries of a failed webhook post.")
+	fs.StringVar(&cfg.ec.ExperimentalProfilingPushURL, "experimental-profiling-push-url", "", "URL the CPU, heap and mutex profiles are periodically pushed to, in the pprof format with Pyroscope ingestion parameters. Empty means disabled.")
+	fs.Var(flags.NewStringsValue(strings.Join(cfg.ec.ExperimentalProfilingPushProfiles, ",")), "experimental-profiling-push-profiles", "Comma-separated list of the pushed profiles: 'cpu', 'heap', 'mutex' or 'goroutine'.")
+	fs.DurationVar(&cfg.ec.ExperimentalProfilingPushInterval, "experimental-profiling-push-interval", cfg.ec.ExperimentalProfilingPushInterval, "Interval between the pushed profile captures, at least 10s.")
+	fs.DurationVar(&cfg.ec.ExperimentalProfilingPushCPUDuration, "experimental-profiling-push-cpu-duration", cfg.ec.ExperimentalProfilingPushCPUDuration, "Duration of the pushed CPU profiles, at most half of the push interval.")
+	fs.DurationVar(&cfg.ec.ExperimentalDiskDegradedWALFsyncThreshold, "experimental-disk-degraded-wal-fsync-threshold", cfg.ec.ExperimentalDiskDegradedWALFsyncThreshold, "WAL fsync latency above which the disk is degraded, when exceeded by more than 10% of the fsyncs for 15 seconds. 0 disables the check.")
+	fs.DurationVar(&cfg.ec.ExperimentalDiskDegradedBackendCommitThreshold, "experimental-disk-degraded-backend-commit-threshold", cfg.ec.ExperimentalDiskDegradedBackendCommitThreshold, "Backend commit latency above which the disk is degraded, when exceeded by more than 10% of the commits for 15 seconds. 0 disables the check.")
+	fs.BoolVar(&cfg.ec.ExperimentalDiskDegradedTransferLeadership, "experimental-disk-degraded-transfer-leadership", false, "Transfer the leadership away from the member while its disk is degraded. Deprecated, use --feature-gates=DiskDegradedTransferLeadership instead.")
+	fs.DurationVar(&cfg.ec.ExperimentalStarvationThreshold, "experimental-starvation-threshold", cfg.ec.ExperimentalStarvationThreshold, "Scheduling delay of the raft ticks and of the applies above which the member is starved of CPU, when exceeded by more than 10% of them for 15 seconds. 0 disables the check.")
+	fs.StringVar(&cfg.ec.FeatureGates, "feature-gates", cfg.ec.FeatureGates, "Comma-separated list of feature=true|false pairs enabling or disabling the features of the server. Known features: "+strings.Join(features.NewDefaultServerFeatureGate(nil).KnownFeatures(), ", ")+".")
+	fs.DurationVar(&cfg.ec.ExperimentalMaxClockSkew, "experimental-max-clock-skew", cfg.ec.ExperimentalMaxClockSkew, "Clock skew with a peer, measured over the peer protocol, above which the clock of the member is reported as skewed.")
+	fs.Float64Var(&cfg.ec.ExperimentalMemoryBudgetRatio, "experimental-memory-budget-ratio", 0, "Ratio of the cgroup memory limit making the memory budget, above which the heap sheds range requests and watch creations. Also sets the Go memory limit unless GOMEMLIMIT is set. 0 disables the budget.")
+	fs.IntVar(&cfg.ec.ExperimentalMaxDeleteBatchSize, "experimental-max-delete-batch-size", cfg.ec.ExperimentalMaxDeleteBatchSize, "Maximum number of keys deleted by each batch, its own transaction, of the paced delete range requests.")
+	fs.IntVar(&cfg.ec.ExperimentalMaxConcurrentRequests, "experimental-max-concurrent-requests", 0, "Maximum number of concurrent key-value and lease requests, over which they wait in a lane per priority: leases, then writes, then reads. The health checks are never queued. 0 means unlimited.")
+	fs.StringVar(&cfg.ec.ExperimentalEnableV2V3, "experimental-enable-v2v3", cfg.ec.ExperimentalEnableV2V3, "v3 prefix for serving emulated v2 keys API. Empty means disabled.")
 	fs.DurationVar(&cfg.ec.ExperimentalWaitClusterReadyTimeout, "experimental-wait-cluster-ready-timeout", cfg.ec.ExperimentalWaitClusterReadyTimeout, "Maximum duration to wait for the cluster to be ready.")
 
 	// unsafe
@@ -373,9 +434,10 @@ func (cfg *config) configFromCmdLine() error {
 
 	cfg.ec.LPUrls = flags.UniqueURLsFromFlag(cfg.cf.flagSet, "listen-peer-urls")
 	cfg.ec.APUrls = flags.UniqueURLsFromFlag(cfg.cf.flagSet, "initial-advertise-peer-urls")
-	cfg.ec.LCUrls = flags.UniqueURLsFromFlag(cfg.cf.flagSet, "listen-client-urls")
-	cfg.ec.ACUrls = flags.UniqueURLsFromFlag(cfg.cf.flagSet, "advertise-client-urls")
+	cfg.ec.LCUrls = clientURLsFromFlag(cfg.cf.flagSet, "listen-client-urls")
+	cfg.ec.ACUrls = clientURLsFromFlag(cfg.cf.flagSet, "advertise-client-urls")
 	cfg.ec.ListenMetricsUrls = flags.UniqueURLsFromFlag(cfg.cf.flagSet, "listen-metrics-urls")
+	cfg.ec.ClientSocketMode = os.FileMode(flags.Uint32FromFlag(cfg.cf.flagSet, "listen-client-socket-mode"))
 
 	cfg.ec.DiscoveryCfg.Endpoints = flags.UniqueStringsFromFlag(cfg.cf.flagSet, "discovery-endpoints")
 
@@ -383,6 +445,12 @@ func (cfg *config) configFromCmdLine() error {
 	cfg.ec.HostWhitelist = flags.UniqueStringsMapFromFlag(cfg.cf.flagSet, "host-whitelist")
 
 	cfg.ec.CipherSuites = flags.StringsFromFlag(cfg.cf.flagSet, "cipher-suites")
+	cfg.ec.ExperimentalGRPCCompressionRPCs = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-grpc-compression-rpcs")
+	cfg.ec.ExperimentalWebhookURLs = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-webhook-urls")
+	cfg.ec.ExperimentalHistoryRetention = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-history-retention")
+	cfg.ec.ExperimentalPrefixStats = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-prefix-stats")
+	cfg.ec.ExperimentalLargeRequestAllowlist = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-large-request-allowlist")
+	cfg.ec.ExperimentalProfilingPushProfiles = flags.StringsFromFlag(cfg.cf.flagSet, "experimental-profiling-push-profiles")
 
 	cfg.ec.MaxConcurrentStreams = flags.Uint32FromFlag(cfg.cf.flagSet, "max-concurrent-streams")
 
@@ -396,6 +464,7 @@ func (cfg *config) configFromCmdLine() error {
 	missingAC := flags.IsSet(cfg.cf.flagSet, "listen-client-urls") && !flags.IsSet(cfg.cf.flagSet, "advertise-client-urls")
 	if missingAC {
 		cfg.ec.ACUrls = nil
+		cfg.ec.UpdateDefaultAdvertiseClientURLs()
 	}
 
 	// disable default initial-cluster if discovery is set
@@ -422,3 +491,46 @@ func (cfg *config) validate() error {
 	}
 	return cfg.ec.Validate()
 }
+
+// clientURLsValue implements the flag.Value interface for the URLs to listen
+// on or to advertise for client traffic, which can also be windows named pipes.
+type clientURLsValue struct {
+	urls types.URLs
+}
+
+func newClientURLsValue(s string) *clientURLsValue {
+	v := &clientURLsValue{}
+	if err := v.Set(s); err != nil {
+		panic(fmt.Sprintf("new clientURLsValue should never fail: %v", err))
+	}
+	return v
+}
+
+// Set parses a comma separated list of URLs, e.g.
+// http://127.0.0.1:2379,npipe:////./pipe/etcd, ignoring an empty value.
+func (v *clientURLsValue) Set(s string) error {
+	if s == "" {
+		return nil
+	}
+	urls, err := embed.NewClientURLs(strings.Split(s, ","))
+	if err != nil {
+		return err
+	}
+	v.urls = make(types.URLs, 0, len(urls))
+	for i, u := range urls {
+		if i > 0 && u.String() == urls[i-1].String() {
+			continue
+		}
+		v.urls = append(v.urls, u)
+	}
+	return nil
+}
+
+func (v *clientURLsValue) String() string {
+	return v.urls.String()
+}
+
+// clientURLsFromFlag returns the client URLs set to the given flag.
+func clientURLsFromFlag(fs *flag.FlagSet, name string) []url.URL {
+	return fs.Lookup(name).Value.(*clientURLsValue).urls
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"testing"
 
+	"go.etcd.io/etcd/client/pkg/v3/types"
 	"go.etcd.io/etcd/server/v3/embed"
 	"sigs.k8s.io/yaml"
 )
@@ -305,6 +306,48 @@ func TestConfigParsingMissedAdvertiseClientURLsFlag(t *testing.T) {
 	}
 }
 
+func TestConfigParsingUnixSocketClientURLs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		wurls []string
+		wmode os.FileMode
+	}{
+		{
+			[]string{"-listen-client-urls=unix:///tmp/etcd.sock"},
+			[]string{"unix:///tmp/etcd.sock"},
+			0600,
+		},
+		{
+			[]string{"-listen-client-urls=unix:///tmp/etcd.sock,unixs:///tmp/etcds.sock", "-listen-client-socket-mode=0660"},
+			[]string{"unix:///tmp/etcd.sock", "unixs:///tmp/etcds.sock"},
+			0660,
+		},
+		{
+			[]string{"-listen-client-urls=unix:///tmp/etcd.sock", "-advertise-client-urls=http://127.0.0.1:2379"},
+			[]string{"http://127.0.0.1:2379"},
+			0600,
+		},
+	}
+
+	for i, tt := range tests {
+		cfg := newConfig()
+		if err := cfg.parse(tt.args); err != nil {
+			t.Fatalf("%d: unexpected error %v", i, err)
+		}
+		if urls := cfg.ec.ACUrls; !reflect.DeepEqual(urls, []url.URL(types.MustNewURLs(tt.wurls))) {
+			t.Errorf("%d: advertise client urls = %v, want %v", i, urls, tt.wurls)
+		}
+		if cfg.ec.ClientSocketMode != tt.wmode {
+			t.Errorf("%d: client socket mode = %#o, want %#o", i, cfg.ec.ClientSocketMode, tt.wmode)
+		}
+	}
+
+	cfg := newConfig()
+	if err := cfg.parse([]string{"-listen-client-urls=unix:///tmp/etcd.sock,http://127.0.0.1:2379"}); err != embed.ErrUnsetAdvertiseClientURLsFlag {
+		t.Errorf("err = %v, want %v", err, embed.ErrUnsetAdvertiseClientURLsFlag)
+	}
+}
+
 func TestConfigIsNewCluster(t *testing.T) {
 	tests := []struct {
 		state  string
@@ -447,3 +490,17 @@ func validateClusteringFlags(t *testing.T, cfg *config) {
 		t.Errorf("advertise-client-urls = %v, want %v", cfg.ec.ACUrls, wcfg.ec.ACUrls)
 	}
 }
+
+func TestConfigParsingPipeClientURLs(t *testing.T) {
+	cfg := newConfig()
+	if err := cfg.parse([]string{"-listen-client-urls=npipe:////./pipe/etcd,http://127.0.0.1:2379", "-advertise-client-urls=npipe:////./pipe/etcd"}); err != nil {
+		t.Fatal(err)
+	}
+	wlc := []url.URL{{Scheme: "http", Host: "127.0.0.1:2379"}, {Scheme: "npipe", Path: "//./pipe/etcd"}}
+	if !reflect.DeepEqual(cfg.ec.LCUrls, wlc) {
+		t.Errorf("listen client urls = %v, want %v", cfg.ec.LCUrls, wlc)
+	}
+	if wac := wlc[1:]; !reflect.DeepEqual(cfg.ec.ACUrls, wac) {
+		t.Errorf("advertise client urls = %v, want %v", cfg.ec.ACUrls, wac)
+	}
+}
@@ -40,7 +40,9 @@ var (
 	dirEmpty  = dirType("empty")
 )
 
-func startEtcdOrProxyV2(args []string) {
+// startEtcdOrProxyV2 starts etcd, exiting once it stops, unless it is
+// stopped by closing the stop channel.
+func startEtcdOrProxyV2(args []string, stopc <-chan struct{}) {
 	grpc.EnableTracing = false
 
 	cfg := newConfig()
@@ -98,8 +100,7 @@ func startEtcdOrProxyV2(args []string) {
 		)
 	}
 
-	var stopped <-chan struct{}
-	var errc <-chan error
+	var e *embed.Etcd
 
 	which := identifyDataDirOrDie(cfg.ec.GetLogger(), cfg.ec.Dir)
 	if which != dirEmpty {
@@ -110,7 +111,7 @@ func startEtcdOrProxyV2(args []string) {
 		)
 		switch which {
 		case dirMember:
-			stopped, errc, err = startEtcd(&cfg.ec)
+			e, err = startEtcd(&cfg.ec)
 		case dirProxy:
 			lg.Panic("v2 http proxy has already been deprecated in 3.6", zap.String("dir-type", string(which)))
 		default:
@@ -120,7 +121,7 @@ func startEtcdOrProxyV2(args []string) {
 			)
 		}
 	} else {
-		stopped, errc, err = startEtcd(&cfg.ec)
+		e, err = startEtcd(&cfg.ec)
 		if err != nil {
 			lg.Warn("failed to start etcd", zap.Error(err))
 		}
@@ -186,23 +187,26 @@ func startEtcdOrProxyV2(args []string) {
 	// for accepting connections. The etcd instance should be
 	// joined with the cluster and ready to serve incoming
 	// connections.
-	notifySystemd(lg)
+	go superviseSystemd(lg, e.Server)
 
 	select {
-	case lerr := <-errc:
+	case lerr := <-e.Err():
 		// fatal out on listener errors
 		lg.Fatal("listener failed", zap.Error(lerr))
-	case <-stopped:
+	case <-e.Server.StopNotify():
+	case <-stopc:
+		e.Close()
+		return
 	}
 
 	osutil.Exit(0)
 }
 
 // startEtcd runs StartEtcd in addition to hooks needed for standalone etcd.
-func startEtcd(cfg *embed.Config) (<-chan struct{}, <-chan error, error) {
+func startEtcd(cfg *embed.Config) (*embed.Etcd, error) {
 	e, err := embed.StartEtcd(cfg)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	osutil.RegisterInterruptHandler(e.Close)
 	select {
@@ -211,7 +215,7 @@ func startEtcd(cfg *embed.Config) (<-chan struct{}, <-chan error, error) {
 	case <-time.After(cfg.ExperimentalWaitClusterReadyTimeout):
 		e.GetLogger().Warn("startEtcd: timed out waiting for the ready notification")
 	}
-	return e.Server.StopNotify(), e.Err(), nil
+	return e, nil
 }
 
 // identifyDataDirOrDie returns the type of the data dir.
@@ -15,13 +15,17 @@
 package etcdmain
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"go.etcd.io/etcd/client/pkg/v3/logutil"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
 	"go.etcd.io/etcd/server/v3/proxy/tcpproxy"
 
 	"github.com/spf13/cobra"
@@ -36,6 +40,13 @@ var (
 	gatewayInsecureDiscovery     bool
 	gatewayRetryDelay            time.Duration
 	gatewayCA                    string
+	gatewayBackends              []string
+	gatewayCertFile              string
+	gatewayKeyFile               string
+	gatewayBackendCertFile       string
+	gatewayBackendKeyFile        string
+	gatewayBackendCA             string
+	gatewayHealthCheckInterval   time.Duration
 )
 
 var (
@@ -78,6 +89,14 @@ func newGatewayStartCommand() *cobra.Command {
 
 	cmd.Flags().DurationVar(&gatewayRetryDelay, "retry-delay", time.Minute, "duration of delay before retrying failed endpoints")
 
+	cmd.Flags().StringArrayVar(&gatewayBackends, "backend", nil, "cluster the TLS connections requesting a server name are routed to, as <server-name>=<endpoint>[,<endpoint>...]. May be repeated.")
+	cmd.Flags().StringVar(&gatewayCertFile, "cert-file", "", "path to the TLS cert file terminating the TLS connections of the clients. The TLS connections are passed through if not provided.")
+	cmd.Flags().StringVar(&gatewayKeyFile, "key-file", "", "path to the TLS key file terminating the TLS connections of the clients.")
+	cmd.Flags().StringVar(&gatewayBackendCertFile, "backend-cert-file", "", "path to the client TLS cert file connecting to the https endpoints.")
+	cmd.Flags().StringVar(&gatewayBackendKeyFile, "backend-key-file", "", "path to the client TLS key file connecting to the https endpoints.")
+	cmd.Flags().StringVar(&gatewayBackendCA, "backend-trusted-ca-file", "", "path to the TLS CA file verifying the https endpoints.")
+	cmd.Flags().DurationVar(&gatewayHealthCheckInterval, "health-check-interval", 0, "interval of the /health checks of the endpoints, routing no connections to the unhealthy ones. If 0, the endpoints failing to connect are retried after retry-delay.")
+
 	return &cmd
 }
 
@@ -103,23 +122,30 @@ func startGateway(cmd *cobra.Command, args []string) {
 	lg.Info("Running: ", zap.Strings("args", os.Args))
 
 	srvs := discoverEndpoints(lg, gatewayDNSCluster, gatewayCA, gatewayInsecureDiscovery, gatewayDNSClusterServiceName)
-	if len(srvs.Endpoints) == 0 {
+	if len(srvs.Endpoints) == 0 && (len(gatewayBackends) == 0 || cmd.Flags().Changed("endpoints")) {
 		// no endpoints discovered, fall back to provided endpoints
 		srvs.Endpoints = gatewayEndpoints
 	}
-	// Strip the schema from the endpoints because we start just a TCP proxy
-	srvs.Endpoints = stripSchema(srvs.Endpoints)
-	if len(srvs.SRVs) == 0 {
-		for _, ep := range srvs.Endpoints {
-			h, p, serr := net.SplitHostPort(ep)
-			if serr != nil {
-				fmt.Printf("error parsing endpoint %q", ep)
-				os.Exit(1)
-			}
-			var port uint16
-			fmt.Sscanf(p, "%d", &port)
-			srvs.SRVs = append(srvs.SRVs, &net.SRV{Target: h, Port: port})
+
+	var backends []tcpproxy.Backend
+	if len(srvs.Endpoints) > 0 {
+		tlsConfig := mustGatewayEndpointsTLSConfig(lg, srvs.Endpoints)
+		if len(srvs.SRVs) == 0 {
+			srvs.SRVs = mustGatewaySRVs(srvs.Endpoints)
 		}
+		backends = append(backends, tcpproxy.Backend{Endpoints: srvs.SRVs, TLSConfig: tlsConfig})
+	}
+	for _, b := range gatewayBackends {
+		serverName, eps, perr := parseGatewayBackend(b)
+		if perr != nil {
+			fmt.Println(perr)
+			os.Exit(1)
+		}
+		backends = append(backends, tcpproxy.Backend{
+			ServerName: serverName,
+			Endpoints:  mustGatewaySRVs(eps),
+			TLSConfig:  mustGatewayEndpointsTLSConfig(lg, eps),
+		})
 	}
 
 	lhost, lport, err := net.SplitHostPort(gatewayListenAddr)
@@ -138,7 +164,11 @@ func startGateway(cmd *cobra.Command, args []string) {
 		laddrsMap[addr] = true
 	}
 
-	for _, srv := range srvs.SRVs {
+	var allSRVs []*net.SRV
+	for _, b := range backends {
+		allSRVs = append(allSRVs, b.Endpoints...)
+	}
+	for _, srv := range allSRVs {
 		var eaddrs []string
 		eaddrs, err = net.LookupHost(srv.Target)
 		if err != nil {
@@ -157,11 +187,23 @@ func startGateway(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	if len(srvs.Endpoints) == 0 {
+	if len(backends) == 0 {
 		fmt.Println("no endpoints found")
 		os.Exit(1)
 	}
 
+	var tlsConfig *tls.Config
+	if gatewayCertFile != "" || gatewayKeyFile != "" {
+		tlsInfo := transport.TLSInfo{CertFile: gatewayCertFile, KeyFile: gatewayKeyFile, Logger: lg}
+		tlsConfig, err = tlsInfo.ServerConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to load the TLS cert:", err)
+			os.Exit(1)
+		}
+		// serve the HTTP/1.1 clients of the endpoints too, e.g. of /health
+		tlsConfig.NextProtos = append(tlsConfig.NextProtos, "http/1.1")
+	}
+
 	var l net.Listener
 	l, err = net.Listen("tcp", gatewayListenAddr)
 	if err != nil {
@@ -170,10 +212,13 @@ func startGateway(cmd *cobra.Command, args []string) {
 	}
 
 	tp := tcpproxy.TCPProxy{
-		Logger:          lg,
-		Listener:        l,
-		Endpoints:       srvs.SRVs,
-		MonitorInterval: gatewayRetryDelay,
+		Logger:              lg,
+		Listener:            l,
+		MonitorInterval:     gatewayRetryDelay,
+		Backends:            backends,
+		TLSConfig:           tlsConfig,
+		HealthCheckInterval: gatewayHealthCheckInterval,
+		HealthCheck:         checkGatewayEndpointHealth,
 	}
 
 	// At this point, etcd gateway listener is initialized
@@ -181,3 +226,81 @@ func startGateway(cmd *cobra.Command, args []string) {
 
 	tp.Run()
 }
+
+// parseGatewayBackend parses a backend of the gateway, as
+// <server-name>=<endpoint>[,<endpoint>...].
+func parseGatewayBackend(s string) (serverName string, endpoints []string, err error) {
+	i := strings.Index(s, "=")
+	if i <= 0 || i == len(s)-1 {
+		return "", nil, fmt.Errorf("invalid backend %q, expecting <server-name>=<endpoint>[,<endpoint>...]", s)
+	}
+	return s[:i], strings.Split(s[i+1:], ","), nil
+}
+
+// mustGatewaySRVs returns the SRVs of the endpoints, exiting if they are
+// invalid.
+func mustGatewaySRVs(eps []string) []*net.SRV {
+	// Strip the schema from the endpoints because we start just a TCP proxy
+	var srvs []*net.SRV
+	for _, ep := range stripSchema(eps) {
+		h, p, serr := net.SplitHostPort(ep)
+		if serr != nil {
+			fmt.Printf("error parsing endpoint %q", ep)
+			os.Exit(1)
+		}
+		var port uint16
+		fmt.Sscanf(p, "%d", &port)
+		srvs = append(srvs, &net.SRV{Target: h, Port: port})
+	}
+	return srvs
+}
+
+// mustGatewayEndpointsTLSConfig returns the TLS config connecting to the
+// endpoints, nil unless they all serve TLS by their https scheme.
+func mustGatewayEndpointsTLSConfig(lg *zap.Logger, eps []string) *tls.Config {
+	secure := 0
+	for _, ep := range eps {
+		if strings.HasPrefix(ep, "https://") {
+			secure++
+		}
+	}
+	if secure == 0 {
+		return nil
+	}
+	if secure != len(eps) {
+		lg.Warn("endpoints mix the http and the https schemes, connecting to them in plain TCP", zap.Strings("endpoints", eps))
+		return nil
+	}
+	tlsInfo := transport.TLSInfo{
+		CertFile:      gatewayBackendCertFile,
+		KeyFile:       gatewayBackendKeyFile,
+		TrustedCAFile: gatewayBackendCA,
+	}
+	cfg, err := tlsInfo.ClientConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load the backend TLS config:", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// checkGatewayEndpointHealth checks the /health of an endpoint, over TLS if
+// the endpoint serves TLS.
+func checkGatewayEndpointHealth(addr string, tlsConfig *tls.Config) error {
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	tr := &http.Transport{TLSClientConfig: tlsConfig}
+	defer tr.CloseIdleConnections()
+	cli := &http.Client{Transport: tr, Timeout: gatewayHealthCheckInterval}
+	resp, err := cli.Get(scheme + "://" + addr + "/health")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unhealthy endpoint: %s", resp.Status)
+	}
+	return nil
+}
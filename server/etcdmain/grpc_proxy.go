@@ -88,13 +88,18 @@ var (
 	grpcProxyResolverPrefix     string
 	grpcProxyResolverTTL        int
 
-	grpcProxyNamespace string
-	grpcProxyLeasing   string
+	grpcProxyNamespace           string
+	grpcProxyNamespaceFromCertCN bool
+	grpcProxyNamespaceFromHeader string
+	grpcProxyLeasing             string
 
 	grpcProxyEnablePprof    bool
 	grpcProxyEnableOrdering bool
 	grpcProxyEnableLogging  bool
 
+	grpcProxyEnableCacheAdmin   bool
+	grpcProxyCacheStatsPrefixes []string
+
 	grpcProxyDebug bool
 
 	// GRPC keep alive related options.
@@ -140,6 +145,8 @@ func newGRPCProxyStartCommand() *cobra.Command {
 	cmd.Flags().StringVar(&grpcProxyResolverPrefix, "resolver-prefix", "", "prefix to use for registering proxy (must be shared with other grpc-proxy members)")
 	cmd.Flags().IntVar(&grpcProxyResolverTTL, "resolver-ttl", 0, "specify TTL, in seconds, when registering proxy endpoints")
 	cmd.Flags().StringVar(&grpcProxyNamespace, "namespace", "", "string to prefix to all keys for namespacing requests")
+	cmd.Flags().BoolVar(&grpcProxyNamespaceFromCertCN, "namespace-from-cert-cn", false, `Confine each client to the keys prefixed with the namespace, its certificate common name and "/" (requires --trusted-ca-file).`)
+	cmd.Flags().StringVar(&grpcProxyNamespaceFromHeader, "namespace-from-header", "", `Confine each client to the keys prefixed with the namespace, the value of this request header and "/" (CAUTION: the header is not authenticated, the proxy should only be reachable through a gateway setting it).`)
 	cmd.Flags().BoolVar(&grpcProxyEnablePprof, "enable-pprof", false, `Enable runtime profiling data via HTTP server. Address is at client URL + "/debug/pprof/"`)
 	cmd.Flags().StringVar(&grpcProxyDataDir, "data-dir", "default.proxy", "Data directory for persistent data")
 	cmd.Flags().IntVar(&grpcMaxCallSendMsgSize, "max-send-bytes", defaultGRPCMaxCallSendMsgSize, "message send limits in bytes (default value is 1.5 MiB)")
@@ -166,6 +173,8 @@ func newGRPCProxyStartCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&grpcProxyEnableOrdering, "experimental-serializable-ordering", false, "Ensure serializable reads have monotonically increasing store revisions across endpoints.")
 	cmd.Flags().StringVar(&grpcProxyLeasing, "experimental-leasing-prefix", "", "leasing metadata prefix for disconnected linearized reads.")
 	cmd.Flags().BoolVar(&grpcProxyEnableLogging, "experimental-enable-grpc-logging", false, "logging all grpc requests and responses")
+	cmd.Flags().BoolVar(&grpcProxyEnableCacheAdmin, "experimental-enable-cache-admin", false, `Enable the cache admin API via HTTP server, the cache statistics at client URL + "/proxy/cache" and the cache flush at client URL + "/proxy/cache/flush?prefix=<prefix>".`)
+	cmd.Flags().StringSliceVar(&grpcProxyCacheStatsPrefixes, "experimental-cache-stats-prefixes", nil, "comma separated key prefixes to track the cache hits and misses under, in the cache statistics and metrics")
 
 	cmd.Flags().BoolVar(&grpcProxyDebug, "debug", false, "Enable debug-level logging for grpc-proxy.")
 
@@ -223,7 +232,8 @@ func startGRPCProxy(cmd *cobra.Command, args []string) {
 	}
 	httpClient := mustNewHTTPClient(lg)
 
-	srvhttp, httpl := mustHTTPListener(lg, m, tlsinfo, client, proxyClient)
+	cacheAdmin := grpcproxy.NewCacheAdmin(grpcProxyCacheStatsPrefixes)
+	srvhttp, httpl := mustHTTPListener(lg, m, tlsinfo, client, proxyClient, cacheAdmin)
 
 	if err := http2.ConfigureServer(srvhttp, &http2.Server{
 		MaxConcurrentStreams: maxConcurrentStreams,
@@ -232,7 +242,7 @@ func startGRPCProxy(cmd *cobra.Command, args []string) {
 	}
 
 	errc := make(chan error, 3)
-	go func() { errc <- newGRPCProxyServer(lg, client).Serve(grpcl) }()
+	go func() { errc <- newGRPCProxyServer(lg, client, cacheAdmin).Serve(grpcl) }()
 	go func() { errc <- srvhttp.Serve(httpl) }()
 	go func() { errc <- m.Serve() }()
 	if len(grpcProxyMetricsListenAddr) > 0 {
@@ -243,6 +253,9 @@ func startGRPCProxy(cmd *cobra.Command, args []string) {
 			grpcproxy.HandleHealth(lg, mux, client)
 			grpcproxy.HandleProxyMetrics(mux)
 			grpcproxy.HandleProxyHealth(lg, mux, proxyClient)
+			if grpcProxyEnableCacheAdmin {
+				grpcproxy.HandleCacheAdmin(mux, cacheAdmin)
+			}
 			lg.Info("gRPC proxy server metrics URL serving")
 			herr := http.Serve(mhttpl, mux)
 			if herr != nil {
@@ -279,6 +292,14 @@ func checkArgs() {
 		fmt.Fprintln(os.Stderr, fmt.Errorf("selfSignedCertValidity is invalid,it should be greater than 0"))
 		os.Exit(1)
 	}
+	if grpcProxyNamespaceFromCertCN && grpcProxyNamespaceFromHeader != "" {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("cannot set both namespace-from-cert-cn and namespace-from-header"))
+		os.Exit(1)
+	}
+	if grpcProxyNamespaceFromCertCN && grpcProxyListenCA == "" {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("namespace-from-cert-cn requires trusted-ca-file to verify the client certificates"))
+		os.Exit(1)
+	}
 }
 
 func mustNewClient(lg *zap.Logger) *clientv3.Client {
@@ -400,7 +421,7 @@ func mustListenCMux(lg *zap.Logger, tlsinfo *transport.TLSInfo) cmux.CMux {
 	return cmux.New(l)
 }
 
-func newGRPCProxyServer(lg *zap.Logger, client *clientv3.Client) *grpc.Server {
+func newGRPCProxyServer(lg *zap.Logger, client *clientv3.Client, cacheAdmin *grpcproxy.CacheAdmin) *grpc.Server {
 	if grpcProxyEnableOrdering {
 		vf := ordering.NewOrderViolationSwitchEndpointClosure(client)
 		client.KV = ordering.NewKV(client.KV, vf)
@@ -425,13 +446,24 @@ func newGRPCProxyServer(lg *zap.Logger, client *clientv3.Client) *grpc.Server {
 		client.KV, _, _ = leasing.NewKV(client, grpcProxyLeasing)
 	}
 
-	kvp, _ := grpcproxy.NewKvProxy(client)
-	watchp, _ := grpcproxy.NewWatchProxy(client.Ctx(), lg, client)
+	var (
+		kvp    pb.KVServer
+		watchp pb.WatchServer
+		leasep pb.LeaseServer
+	)
+	if tenant := tenantFunc(); tenant != nil {
+		// the tenant namespaces are nested in the namespace of the client
+		tp := grpcproxy.NewTenantProxy(lg, client, "", tenant)
+		kvp, watchp, leasep = tp, tp, tp
+	} else {
+		kvp, _ = grpcproxy.NewKvProxyWithCacheAdmin(client, cacheAdmin)
+		watchp, _ = grpcproxy.NewWatchProxy(client.Ctx(), lg, client)
+		leasep, _ = grpcproxy.NewLeaseProxy(client.Ctx(), client)
+	}
 	if grpcProxyResolverPrefix != "" {
 		grpcproxy.Register(lg, client, grpcProxyResolverPrefix, grpcProxyAdvertiseClientURL, grpcProxyResolverTTL)
 	}
 	clusterp, _ := grpcproxy.NewClusterProxy(lg, client, grpcProxyAdvertiseClientURL, grpcProxyResolverPrefix)
-	leasep, _ := grpcproxy.NewLeaseProxy(client.Ctx(), client)
 
 	mainp := grpcproxy.NewMaintenanceProxy(client)
 	authp := grpcproxy.NewAuthProxy(client)
@@ -466,6 +498,9 @@ func newGRPCProxyServer(lg *zap.Logger, client *clientv3.Client) *grpc.Server {
 		)),
 		grpc.MaxConcurrentStreams(math.MaxUint32),
 	}
+	if grpcProxyNamespaceFromCertCN {
+		gopts = append(gopts, grpc.Creds(grpcproxy.TenantCredentials()))
+	}
 	if grpcKeepAliveMinTime > time.Duration(0) {
 		gopts = append(gopts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
 			MinTime:             grpcKeepAliveMinTime,
@@ -494,7 +529,20 @@ func newGRPCProxyServer(lg *zap.Logger, client *clientv3.Client) *grpc.Server {
 	return server
 }
 
-func mustHTTPListener(lg *zap.Logger, m cmux.CMux, tlsinfo *transport.TLSInfo, c *clientv3.Client, proxy *clientv3.Client) (*http.Server, net.Listener) {
+// tenantFunc returns the TenantFunc of the namespace-from-* flags, or nil if
+// the clients are not namespaced per tenant.
+func tenantFunc() grpcproxy.TenantFunc {
+	switch {
+	case grpcProxyNamespaceFromCertCN:
+		return grpcproxy.TenantFromCertCN
+	case grpcProxyNamespaceFromHeader != "":
+		return grpcproxy.TenantFromHeader(grpcProxyNamespaceFromHeader)
+	default:
+		return nil
+	}
+}
+
+func mustHTTPListener(lg *zap.Logger, m cmux.CMux, tlsinfo *transport.TLSInfo, c *clientv3.Client, proxy *clientv3.Client, cacheAdmin *grpcproxy.CacheAdmin) (*http.Server, net.Listener) {
 	httpClient := mustNewHTTPClient(lg)
 	httpmux := http.NewServeMux()
 	httpmux.HandleFunc("/", http.NotFound)
@@ -502,6 +550,10 @@ func mustHTTPListener(lg *zap.Logger, m cmux.CMux, tlsinfo *transport.TLSInfo, c
 	grpcproxy.HandleHealth(lg, httpmux, c)
 	grpcproxy.HandleProxyMetrics(httpmux)
 	grpcproxy.HandleProxyHealth(lg, httpmux, proxy)
+	if grpcProxyEnableCacheAdmin {
+		grpcproxy.HandleCacheAdmin(httpmux, cacheAdmin)
+		lg.Info("gRPC proxy enabled cache admin", zap.String("path", grpcproxy.PathProxyCache))
+	}
 	if grpcProxyEnablePprof {
 		for p, h := range debugutil.PProfHandlers() {
 			httpmux.Handle(p, h)
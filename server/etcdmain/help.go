@@ -18,9 +18,11 @@ package etcdmain
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	cconfig "go.etcd.io/etcd/server/v3/config"
 	"go.etcd.io/etcd/server/v3/embed"
+	"go.etcd.io/etcd/server/v3/features"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -37,7 +39,7 @@ var (
     Show the help information about etcd.
 
   etcd --config-file
-    Path to the server configuration file. Note that if a configuration file is provided, other command line flags and environment variables will be ignored.
+    Path to the server configuration file. Note that if a configuration file is provided, other command line flags and environment variables will be ignored. Changes of log-level, auto-compaction-retention and auth-token-ttl in the file are applied without restart.
 
   etcd gateway
     Run the stateless pass-through etcd TCP connection forwarding proxy.
@@ -64,7 +66,11 @@ Member:
   --listen-peer-urls 'http://localhost:2380'
     List of URLs to listen on for peer traffic.
   --listen-client-urls 'http://localhost:2379'
-    List of URLs to listen on for client traffic.
+    List of URLs to listen on for client traffic, e.g. 'unix:///var/run/etcd/etcd.sock', or 'npipe:////./pipe/etcd' on Windows, for local clients only.
+  --listen-client-socket-mode '0600'
+    Permissions of the unix sockets listened on for client traffic, e.g. 0660 to let the group of the etcd user connect.
+  --enable-socket-activation 'false'
+    Use the listeners passed by systemd socket activation for the listen URLs of their addresses.
   --max-snapshots '` + strconv.Itoa(embed.DefaultMaxSnapshots) + `'
     Maximum number of snapshot files to retain (0 is unlimited).
   --max-wals '` + strconv.Itoa(embed.DefaultMaxWALs) + `'
@@ -73,6 +79,13 @@ Member:
     Raise alarms when backend size exceeds the given quota (0 defaults to low space quota).
   --backend-bbolt-freelist-type 'map'
     BackendFreelistType specifies the type of freelist that boltdb backend uses(array and map are supported types).
+    The map freelist is recommended for backends larger than 1GiB.
+  --backend-bbolt-freelist-sync 'false'
+    Persist the freelist of the boltdb backend on each commit instead of rebuilding it by scanning the whole
+    database on open, for backends of several GiB to open faster at the cost of slower commits.
+  --backend-bbolt-initial-mmap-size '0'
+    Initial size in bytes of the mmap of the boltdb backend (0 defaults to the quota plus 10%, or 10GiB without quota).
+    Set it above the expected size of the backend, so that it is not remapped as it grows, blocking the transactions.
   --backend-batch-interval ''
     BackendBatchInterval is the maximum time before commit the backend transaction.
   --backend-batch-limit '0'
@@ -93,6 +106,18 @@ Member:
     Enable to set socket option SO_REUSEPORT on listeners allowing rebinding of a port already in use.
   --socket-reuse-address 'false'
 	Enable to set socket option SO_REUSEADDR on listeners allowing binding to an address in TIME_WAIT state.
+  --socket-keepalive-time '0s'
+    Idle time of the connections accepted by the client and peer listeners before the first TCP keepalive probe (TCP_KEEPIDLE); 30s if 0.
+  --socket-keepalive-interval '0s'
+    Interval between the TCP keepalive probes of the accepted connections (TCP_KEEPINTVL); the keepalive time if 0. Linux only.
+  --socket-keepalive-count 0
+    Number of unacknowledged TCP keepalive probes before dropping the accepted connections (TCP_KEEPCNT); the system default if 0. Linux only.
+  --socket-user-timeout '0s'
+    Maximum time transmitted data may remain unacknowledged before dropping the accepted connections (TCP_USER_TIMEOUT); the system default if 0. Linux only.
+  --socket-read-buffer-size 0
+    Size in bytes of the receive buffer of the accepted connections (SO_RCVBUF); the system default if 0.
+  --socket-write-buffer-size 0
+    Size in bytes of the send buffer of the accepted connections (SO_SNDBUF); the system default if 0.
 
 Clustering:
   --initial-advertise-peer-urls 'http://localhost:2380'
@@ -107,6 +132,7 @@ Clustering:
   --advertise-client-urls 'http://localhost:2379'
     List of this member's client URLs to advertise to the public.
     The client URLs advertised should be accessible to machines that talk to etcd cluster. etcd client libraries parse these URLs to connect to the cluster.
+    Defaults to the listened client URLs if they all are unix sockets.
   --discovery ''
     Discovery URL used to bootstrap the cluster for v2 discovery. Will be deprecated in v3.7, and be decommissioned in v3.8.
   --discovery-token ''
@@ -145,8 +171,16 @@ Clustering:
     Suffix to the dns srv name queried when bootstrapping.
   --strict-reconfig-check '` + strconv.FormatBool(embed.DefaultStrictReconfigCheck) + `'
     Reject reconfiguration requests that would cause quorum loss.
+  --auto-promote-learners 'false'
+    Promote the learners to voting members once their raft logs lag behind the leader by at most --auto-promote-learners-max-lag entries for --auto-promote-learners-synced-duration.
+  --auto-promote-learners-max-lag '1000'
+    Maximum number of raft log entries a learner lags behind the leader by to be in sync, for --auto-promote-learners.
+  --auto-promote-learners-synced-duration '30s'
+    Duration a learner must stay in sync with the leader for before being promoted, for --auto-promote-learners.
   --pre-vote 'true'
     Enable to run an additional Raft election phase.
+  --check-quorum 'true'
+    Enable the leader to step down when it does not hear from a quorum, and the followers to ignore the votes while they hear from their leader.
   --auto-compaction-retention '0'
     Auto compaction retention length. 0 means disable auto compaction.
   --auto-compaction-mode 'periodic'
@@ -201,7 +235,7 @@ Security:
 
 Auth:
   --auth-token 'simple'
-    Specify a v3 authentication token type and its options ('simple' or 'jwt').
+    Specify a v3 authentication token type and its options ('simple', 'jwt' or 'oidc'), e.g. 'oidc,issuer=https://issuer.example.com,audience=etcd,role-mapping=/path/roles.json' to validate OIDC bearer tokens with the etcd roles their claims map to.
   --bcrypt-cost ` + fmt.Sprintf("%d", bcrypt.DefaultCost) + `
     Specify the cost / strength of the bcrypt algorithm for hashing auth passwords. Valid values are between ` + fmt.Sprintf("%d", bcrypt.MinCost) + ` and ` + fmt.Sprintf("%d", bcrypt.MaxCost) + `.
   --auth-token-ttl 300
@@ -231,7 +265,7 @@ Logging:
 
 Experimental distributed tracing:
   --experimental-enable-distributed-tracing 'false'
-    Enable experimental distributed tracing.
+    Enable experimental distributed tracing. Deprecated, use --feature-gates=DistributedTracing instead.
   --experimental-distributed-tracing-address 'localhost:4317'
     Distributed tracing collector address.
   --experimental-distributed-tracing-service-name 'etcd'
@@ -241,31 +275,128 @@ Experimental distributed tracing:
   --experimental-distributed-tracing-sampling-rate '0'
     Number of samples to collect per million spans for distributed tracing. Disabled by default.
 
+Feature gates:
+  --feature-gates ''
+    Comma-separated list of feature=true|false pairs enabling or disabling the features of the server, superseding the experimental flags of the same features:
+      ` + strings.Join(features.NewDefaultServerFeatureGate(nil).KnownFeatures(), "\n      ") + `
+
 Experimental feature:
   --experimental-initial-corrupt-check 'false'
-    Enable to check data corruption before serving any client/peer traffic.
+    Enable to check data corruption before serving any client/peer traffic. Deprecated, use --feature-gates=InitialCorruptCheck instead.
   --experimental-corrupt-check-time '0s'
     Duration of time between cluster corruption check passes.
   --experimental-enable-lease-checkpoint 'false'
-    ExperimentalEnableLeaseCheckpoint enables primary lessor to persist lease remainingTTL to prevent indefinite auto-renewal of long lived leases.
+    ExperimentalEnableLeaseCheckpoint enables primary lessor to persist lease remainingTTL to prevent indefinite auto-renewal of long lived leases. Deprecated, use --feature-gates=LeaseCheckpoint instead.
+  --experimental-lease-checkpoint-interval '0s'
+    Interval between the checkpoints of the remaining TTL of a lease longer than it, which the members count down on leader change. Requires experimental-enable-lease-checkpoint to be enabled. 0 means 5m.
   --experimental-compaction-batch-limit 1000
     ExperimentalCompactionBatchLimit sets the maximum revisions deleted in each compaction batch.
+  --experimental-compaction-pause-backend-commit-threshold '0s'
+    Latency of the last backend commit above which the compaction pauses between its batches to yield to the foreground traffic. 0 means disabled.
+  --experimental-compaction-pause-pending-proposals 0
+    Number of pending proposals above which the compaction pauses between its batches to yield to the foreground traffic. 0 means disabled.
+  --experimental-compaction-max-pause '1s'
+    Maximum pause of the compaction between two batches, for it to progress under sustained load.
+  --experimental-compaction-barrier-max-duration '0s'
+    Maximum time the compaction barrier registered by the ranges at a revision holds back the compaction above it. 0 disables the compaction barriers.
+  --experimental-compaction-barrier-max-revisions '0'
+    Maximum number of revisions the current revision moves past a compaction barrier before it expires. 0 means unlimited.
+  --experimental-history-retention ''
+    Comma-separated list of the history retained by the compactions for the keys with a prefix: '<prefix>=<versions>' retains
+    the latest versions of each key and '<prefix>=<period>' the history of the last period, e.g. 'audit/=5,events/=24h'.
+    The retained history is read at the compacted revisions.
+  --experimental-prefix-stats ''
+    Comma-separated list of the key prefixes the reads and writes served, and their bytes, are counted for, e.g. 'tenants/a/,tenants/b/',
+    a key being counted under the longest of its prefixes. At most 64 prefixes, the keys having none being counted under the empty prefix.
+  --experimental-backend-mmap-advice ''
+    Madvise advice of the mmap of the backend on linux: 'normal' reads ahead the pages around the ones read, 'random' does not and
+    'willneed' reads ahead the whole backend. Empty means the boltdb default, 'random'. See also --feature-gates=BackendWarmUp.
+  --experimental-max-snapshot-catchup-entries 50000
+    Maximum number of entries kept for the active followers lagging behind to catch up from after a snapshot, instead of
+    being sent a snapshot. The entries kept adapt to the lag of the slowest active follower, from 5000 up to this maximum.
+  --experimental-snapshot-send-rate-bytes 0
+    Maximum rate in bytes per second the snapshots are sent to the clients at, to limit their impact on the disk and network
+    of the member. 0 means unlimited. Use with --feature-gates=SnapshotSpool, not to hold a backend transaction open for longer.
+  --experimental-wal-group-sync-max-delay '0s'
+    Maximum latency added to the WAL fsync of a raft ready waiting for the following readies to share a single fsync, improving
+    the write throughput on disks of high fsync latency. The readies whose entries are committed are synced at once. 0 means disabled.
   --experimental-peer-skip-client-san-verification 'false'
     Skip verification of SAN field in client certificate for peer connections.
   --experimental-watch-progress-notify-interval '10m'
     Duration of periodical watch progress notification.
+  --experimental-watch-max-stream-age '0s'
+    Maximum age of the watch streams, with a jitter of up to a tenth, after which they are closed gracefully for the clients
+    to resume their watches on a new stream, re-balancing them across the members and proxies. 0 means unlimited.
   --experimental-warning-apply-duration '100ms'
     Warning is generated if requests take more than this duration.
   --experimental-txn-mode-write-with-shared-buffer 'true'
-    Enable the write transaction to use a shared buffer in its readonly check operations.
+    Enable the write transaction to use a shared buffer in its readonly check operations. Deprecated, use --feature-gates=TxnModeWriteWithSharedBuffer instead.
   --experimental-bootstrap-defrag-threshold-megabytes
     Enable the defrag during etcd server bootstrap on condition that it will free at least the provided threshold of disk space. Needs to be set to non-zero value to take effect.
+  --experimental-bootstrap-verify ''
+    Verification of the persisted state during bootstrap. 'full' verifies the WAL tail, the backend consistent index and the rebuilt key index, and refuses to start on mismatch. Empty means disabled.
   --experimental-warning-unary-request-duration '300ms'
     Set time duration after which a warning is generated if a unary request takes more than this duration.
   --experimental-max-learners '1'
     Set the max number of learner members allowed in the cluster membership.
   --experimental-wait-cluster-ready-timeout '5s'
     Set the maximum time duration to wait for the cluster to be ready.
+  --experimental-grpc-compression-rpcs ''
+    Comma-separated list of RPCs (e.g. 'Range,Snapshot') whose responses may be compressed for clients compressing their requests with gzip or zstd. Empty means all RPCs.
+  --experimental-grpc-compression-min-bytes 1024
+    Minimum size of the gRPC responses compressed by the server.
+  --experimental-grpc-response-compression ''
+    Compressor ('gzip' or 'zstd') of all the gRPC responses compressed by the server, whatever the compressor of the requests. All the clients must be able to decompress it. Empty means the compressor of the request.
+  --experimental-max-connection-memory-bytes 0
+    Maximum bytes of pending responses and watch buffers held by a client connection. 0 means unlimited.
+  --experimental-max-total-connection-memory-bytes 0
+    Maximum bytes held by all client connections, above which the heaviest connection is evicted. 0 means unlimited.
+  --experimental-large-request-policy ''
+    Policy applied to the users, or the client IPs without authentication, sending more large requests than allowed: 'warn' logs them, 'throttle' delays them and 'reject' fails them. Empty means disabled.
+  --experimental-large-request-bytes 0
+    Size from which a request is large. 0 means 90% of max-request-bytes.
+  --experimental-large-requests-per-minute 60
+    Number of large requests a user or client IP may send per minute before the large request policy applies.
+  --experimental-large-request-allowlist ''
+    Comma-separated list of users and client IPs not subject to the large request policy.
+  --experimental-webhook-urls ''
+    Comma-separated list of URLs posted when alarms are raised or cleared and when the leader changes.
+  --experimental-webhook-template ''
+    Go text/template of the webhook payloads, executed on the event. Empty means the JSON encoding of the event.
+  --experimental-webhook-retries 3
+    Number of retries of a failed webhook post.
+  --experimental-profiling-push-url ''
+    URL the CPU, heap and mutex profiles are periodically pushed to, in the pprof format with Pyroscope ingestion parameters. Empty means disabled.
+  --experimental-profiling-push-profiles 'cpu,heap,mutex'
+    Comma-separated list of the pushed profiles: 'cpu', 'heap', 'mutex' or 'goroutine'.
+  --experimental-profiling-push-interval '1m'
+    Interval between the pushed profile captures, at least 10s.
+  --experimental-profiling-push-cpu-duration '10s'
+    Duration of the pushed CPU profiles, at most half of the push interval.
+  --experimental-disk-degraded-wal-fsync-threshold '1s'
+    WAL fsync latency above which the disk is degraded, when exceeded by more than 10% of the fsyncs for 15 seconds. 0 disables the check.
+  --experimental-disk-degraded-backend-commit-threshold '1s'
+    Backend commit latency above which the disk is degraded, when exceeded by more than 10% of the commits for 15 seconds. 0 disables the check.
+  --experimental-disk-degraded-transfer-leadership 'false'
+    Transfer the leadership away from the member while its disk is degraded. Deprecated, use --feature-gates=DiskDegradedTransferLeadership instead.
+  --experimental-starvation-threshold '200ms'
+    Scheduling delay of the raft ticks and of the applies above which the member is starved of CPU, when exceeded by more than 10% of them for 15 seconds. 0 disables the check. See also --feature-gates=StarvationUnhealthy.
+  --experimental-max-clock-skew '1s'
+    Clock skew with a peer, measured over the peer protocol, above which the clock of the member is reported as skewed.
+  --experimental-memory-budget-ratio '0'
+    Ratio of the cgroup memory limit making the memory budget, above which the heap sheds range requests and watch creations. Also sets the Go memory limit unless GOMEMLIMIT is set. 0 disables the budget.
+  --experimental-max-delete-batch-size '1000'
+    Maximum number of keys deleted by each batch, its own transaction, of the paced delete range requests.
+  --experimental-max-concurrent-requests '0'
+    Maximum number of concurrent key-value and lease requests, over which they wait in a lane per priority: leases, then writes, then reads. The health checks are never queued. 0 means unlimited.
+  --experimental-election-timeout-jitter '0'
+    Range (in milliseconds) of the random time added to the election timeout of each election. 0 means --election-timeout.
+  --experimental-election-priority '0'
+    Priority of the member to be the leader, the leader transferring the leadership to the members of higher priority connected to it for a while. 0 is the lowest.
+  --experimental-tick-skew-tolerance '0s'
+    Delay of a heartbeat tick above which the member is starved of CPU, e.g. by a long GC pause or cgroup throttling, and a follower does not count the tick towards its election timeout. 0 means disabled.
+  --experimental-enable-v2v3 ''
+    Serve the v2 keys API emulated on top of the v3 store under the given prefix. Empty means disabled.
 
 Unsafe feature:
   --force-new-cluster 'false'
@@ -37,7 +37,10 @@ func Main(args []string) {
 		}
 	}
 
-	startEtcdOrProxyV2(args)
+	if runWindowsService(args) {
+		return
+	}
+	startEtcdOrProxyV2(args, nil)
 }
 
 func notifySystemd(lg *zap.Logger) {
@@ -0,0 +1,108 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+// +build windows
+
+package etcdmain
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// windowsServiceName is the name of the service, and the source of its
+// events in the event log.
+const windowsServiceName = "etcd"
+
+// the IDs of the events of the service
+const (
+	eventStarted uint32 = iota + 1
+	eventStopped
+	eventFailed
+)
+
+// runWindowsService runs etcd as a Windows service if started by the service
+// control manager, returning false otherwise. etcd stops gracefully on the
+// stop and shutdown requests, reporting it to the event log.
+func runWindowsService(args []string) bool {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to check if running as a Windows service: %v\n", err)
+		return false
+	}
+	if !isService {
+		return false
+	}
+
+	s := &windowsService{args: args}
+	// the events are logged even if the event source is not registered,
+	// without their message format
+	if s.elog, err = eventlog.Open(windowsServiceName); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open the event log: %v\n", err)
+	} else {
+		defer s.elog.Close()
+	}
+	if err = svc.Run(windowsServiceName, s); err != nil {
+		s.report(eventFailed, fmt.Sprintf("etcd service failed: %v", err))
+		os.Exit(1)
+	}
+	return true
+}
+
+type windowsService struct {
+	args []string
+	elog *eventlog.Log
+}
+
+// Execute runs etcd until the service is requested to stop, the service
+// being reported running once etcd is started.
+func (s *windowsService) Execute(_ []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	stopc, donec := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(donec)
+		startEtcdOrProxyV2(s.args, stopc)
+	}()
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	s.report(eventStarted, "etcd service started")
+
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			changes <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			changes <- svc.Status{State: svc.StopPending}
+			close(stopc)
+			<-donec
+			s.report(eventStopped, "etcd service stopped")
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+func (s *windowsService) report(eid uint32, msg string) {
+	if s.elog == nil {
+		return
+	}
+	if eid == eventFailed {
+		s.elog.Error(eid, msg)
+		return
+	}
+	s.elog.Info(eid, msg)
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdmain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.uber.org/zap"
+)
+
+const (
+	sdStatusServing = "STATUS=serving"
+	sdStatusWaiting = "STATUS=waiting for the cluster to be ready"
+)
+
+// superviseSystemd notifies systemd that the member started, without waiting
+// for the cluster to be ready not to block the start of the other members.
+// If the systemd watchdog is enabled, e.g. by WatchdogSec, it is pinged as
+// long as the member progresses, so that systemd restarts a wedged member.
+func superviseSystemd(lg *zap.Logger, s *etcdserver.EtcdServer) {
+	readyc := s.ReadyNotify()
+	status := sdStatusWaiting
+	select {
+	case <-readyc:
+		readyc, status = nil, sdStatusServing
+	default:
+	}
+	notifySystemdState(lg, daemon.SdNotifyReady+"\n"+status)
+
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		lg.Warn("failed to check if the systemd watchdog is enabled", zap.Error(err))
+	}
+	var pingc <-chan time.Time
+	// a single ping missed is tolerated
+	period := interval / 3
+	if interval > 0 {
+		lg.Info("pinging the systemd watchdog", zap.Duration("watchdog-interval", interval), zap.Duration("ping-interval", period))
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		pingc = ticker.C
+	}
+	pc := newProgressChecker(time.Duration(s.Cfg.TickMs)*time.Millisecond, period, s.AppliedIndex())
+
+	for {
+		select {
+		case <-readyc:
+			readyc = nil
+			notifySystemdState(lg, sdStatusServing)
+		case now := <-pingc:
+			if err = pc.check(now, s.LastTick(), s.AppliedIndex(), s.CommittedIndex()); err != nil {
+				lg.Warn("not pinging the systemd watchdog; member is not progressing", zap.Error(err))
+				continue
+			}
+			if _, err = daemon.SdNotify(false, daemon.SdNotifyWatchdog); err != nil {
+				lg.Warn("failed to ping the systemd watchdog", zap.Error(err))
+			}
+		case <-s.StoppingNotify():
+			notifySystemdState(lg, daemon.SdNotifyStopping)
+			return
+		}
+	}
+}
+
+func notifySystemdState(lg *zap.Logger, state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		lg.Error("failed to notify systemd", zap.String("state", state), zap.Error(err))
+	}
+}
+
+// progressChecker checks that a member is not wedged, its raft loop receiving
+// the heartbeat ticks and applying the committed entries.
+type progressChecker struct {
+	// maxTickDelay is the delay of the last tick after which the raft loop
+	// is wedged.
+	maxTickDelay time.Duration
+	lastApplied  uint64
+}
+
+func newProgressChecker(heartbeat, period time.Duration, applied uint64) *progressChecker {
+	return &progressChecker{maxTickDelay: heartbeat + period, lastApplied: applied}
+}
+
+// check returns an error if the raft loop did not tick for longer than the
+// check period, or if no entry was applied since the last check while some
+// committed entries are not applied.
+func (pc *progressChecker) check(now, lastTick time.Time, applied, committed uint64) error {
+	lastApplied := pc.lastApplied
+	pc.lastApplied = applied
+	if d := now.Sub(lastTick); d > pc.maxTickDelay {
+		return fmt.Errorf("raft loop did not tick for %v", d)
+	}
+	if applied < committed && applied == lastApplied {
+		return fmt.Errorf("applied index %d did not progress towards committed index %d", applied, committed)
+	}
+	return nil
+}
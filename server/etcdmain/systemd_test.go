@@ -0,0 +1,48 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdmain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressCheckerCheck(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		lastTick  time.Time
+		applied   uint64
+		committed uint64
+		wErr      bool
+	}{
+		{"ticking and applied", now.Add(-100 * time.Millisecond), 10, 10, false},
+		{"ticking and applying", now, 8, 10, false},
+		{"not ticking", now.Add(-2 * time.Second), 10, 10, true},
+		{"not applying", now, 5, 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := newProgressChecker(100*time.Millisecond, time.Second, 5)
+			err := pc.check(now, tt.lastTick, tt.applied, tt.committed)
+			if (err != nil) != tt.wErr {
+				t.Errorf("err = %v, expected error %v", err, tt.wErr)
+			}
+			if pc.lastApplied != tt.applied {
+				t.Errorf("lastApplied = %d, expected %d", pc.lastApplied, tt.applied)
+			}
+		})
+	}
+}
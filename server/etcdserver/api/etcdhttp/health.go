@@ -27,6 +27,7 @@ import (
 	"go.etcd.io/etcd/raft/v3"
 	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/config"
+	"go.etcd.io/etcd/server/v3/etcdserver"
 	"go.uber.org/zap"
 )
 
@@ -40,6 +41,8 @@ type ServerHealth interface {
 	Leader() types.ID
 	Range(context.Context, *pb.RangeRequest) (*pb.RangeResponse, error)
 	Config() config.ServerConfig
+	DiskDegraded() bool
+	Starved() bool
 }
 
 // HandleHealth registers metrics and health handlers. it checks health by using v3 range request
@@ -52,6 +55,12 @@ func HandleHealth(lg *zap.Logger, mux *http.ServeMux, srv ServerHealth) {
 		if h := checkLeader(lg, srv, serializable); h.Health != "true" {
 			return h
 		}
+		if h := checkDisk(lg, srv, serializable); h.Health != "true" {
+			return h
+		}
+		if h := checkStarvation(lg, srv, serializable); h.Health != "true" {
+			return h
+		}
 		return checkAPI(lg, srv, serializable)
 	}))
 }
@@ -178,10 +187,36 @@ func checkLeader(lg *zap.Logger, srv ServerHealth, serializable bool) Health {
 	return h
 }
 
+// checkDisk fails when the disk is degraded, unless only the liveness of the
+// member is checked with serializable.
+func checkDisk(lg *zap.Logger, srv ServerHealth, serializable bool) Health {
+	h := Health{Health: "true"}
+	if !serializable && srv.DiskDegraded() {
+		h.Health = "false"
+		h.Reason = "DISK DEGRADED"
+		lg.Warn("serving /health false; disk degraded")
+	}
+	return h
+}
+
+// checkStarvation fails when the member is starved of CPU and configured to
+// be unhealthy then, unless only the liveness of the member is checked with
+// serializable.
+func checkStarvation(lg *zap.Logger, srv ServerHealth, serializable bool) Health {
+	h := Health{Health: "true"}
+	if !serializable && srv.Config().StarvationUnhealthy && srv.Starved() {
+		h.Health = "false"
+		h.Reason = "STARVED"
+		lg.Warn("serving /health false; member starved of CPU")
+	}
+	return h
+}
+
 func checkAPI(lg *zap.Logger, srv ServerHealth, serializable bool) Health {
 	h := Health{Health: "true"}
 	cfg := srv.Config()
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.ReqTimeout())
+	// the health checks are never queued behind the other requests
+	ctx, cancel := context.WithTimeout(etcdserver.WithRequestPriority(context.Background(), etcdserver.PrioritySystem), cfg.ReqTimeout())
 	_, err := srv.Range(ctx, &etcdserverpb.RangeRequest{KeysOnly: true, Limit: 1, Serializable: serializable})
 	cancel()
 	if err != nil && err != auth.ErrUserEmpty && err != auth.ErrPermissionDenied {
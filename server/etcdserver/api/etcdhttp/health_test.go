@@ -27,8 +27,11 @@ func (s *fakeStats) StoreStats() []byte  { return nil }
 
 type fakeHealthServer struct {
 	fakeServer
-	health   string
-	apiError error
+	health       string
+	apiError     error
+	diskDegraded bool
+	starved      bool
+	cfg          config.ServerConfig
 }
 
 func (s *fakeHealthServer) Range(ctx context.Context, request *pb.RangeRequest) (*pb.RangeResponse, error) {
@@ -36,7 +39,7 @@ func (s *fakeHealthServer) Range(ctx context.Context, request *pb.RangeRequest)
 }
 
 func (s *fakeHealthServer) Config() config.ServerConfig {
-	return config.ServerConfig{}
+	return s.cfg
 }
 
 func (s *fakeHealthServer) Leader() types.ID {
@@ -52,6 +55,8 @@ func (s *fakeHealthServer) Do(ctx context.Context, r pb.Request) (etcdserver.Res
 	return etcdserver.Response{}, fmt.Errorf("fail health check")
 }
 func (s *fakeHealthServer) ClientCertAuthEnabled() bool { return false }
+func (s *fakeHealthServer) DiskDegraded() bool          { return s.diskDegraded }
+func (s *fakeHealthServer) Starved() bool               { return s.starved }
 
 func TestHealthHandler(t *testing.T) {
 	// define the input and expected output
@@ -170,6 +175,78 @@ func TestHealthHandler(t *testing.T) {
 	}
 }
 
+func TestHealthHandlerDiskDegraded(t *testing.T) {
+	tests := []struct {
+		healthCheckURL string
+
+		expectStatusCode int
+		expectReason     string
+	}{
+		{"/health", http.StatusServiceUnavailable, "DISK DEGRADED"},
+		{"/health?serializable=true", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.healthCheckURL, func(t *testing.T) {
+			mux := http.NewServeMux()
+			HandleHealth(zaptest.NewLogger(t), mux, &fakeHealthServer{health: "true", diskDegraded: true})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			res, err := ts.Client().Do(&http.Request{Method: http.MethodGet, URL: testutil.MustNewURL(t, ts.URL+tt.healthCheckURL)})
+			if err != nil {
+				t.Fatalf("fail serve http request %s %v", tt.healthCheckURL, err)
+			}
+			if res.StatusCode != tt.expectStatusCode {
+				t.Errorf("want statusCode %d but got %d", tt.expectStatusCode, res.StatusCode)
+			}
+			health, err := parseHealthOutput(res.Body)
+			if err != nil {
+				t.Errorf("fail parse health check output %v", err)
+			}
+			if health.Reason != tt.expectReason {
+				t.Errorf("want reason %q but got %q", tt.expectReason, health.Reason)
+			}
+		})
+	}
+}
+
+func TestHealthHandlerStarved(t *testing.T) {
+	tests := []struct {
+		healthCheckURL string
+		unhealthy      bool
+
+		expectStatusCode int
+		expectReason     string
+	}{
+		{"/health", true, http.StatusServiceUnavailable, "STARVED"},
+		{"/health?serializable=true", true, http.StatusOK, ""},
+		{"/health", false, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s unhealthy=%v", tt.healthCheckURL, tt.unhealthy), func(t *testing.T) {
+			mux := http.NewServeMux()
+			HandleHealth(zaptest.NewLogger(t), mux, &fakeHealthServer{health: "true", starved: true, cfg: config.ServerConfig{StarvationUnhealthy: tt.unhealthy}})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			res, err := ts.Client().Do(&http.Request{Method: http.MethodGet, URL: testutil.MustNewURL(t, ts.URL+tt.healthCheckURL)})
+			if err != nil {
+				t.Fatalf("fail serve http request %s %v", tt.healthCheckURL, err)
+			}
+			if res.StatusCode != tt.expectStatusCode {
+				t.Errorf("want statusCode %d but got %d", tt.expectStatusCode, res.StatusCode)
+			}
+			health, err := parseHealthOutput(res.Body)
+			if err != nil {
+				t.Errorf("fail parse health check output %v", err)
+			}
+			if health.Reason != tt.expectReason {
+				t.Errorf("want reason %q but got %q", tt.expectReason, health.Reason)
+			}
+		})
+	}
+}
+
 func parseHealthOutput(body io.Reader) (Health, error) {
 	obj := Health{}
 	d, derr := io.ReadAll(body)
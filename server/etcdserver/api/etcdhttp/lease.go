@@ -0,0 +1,127 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	gw "github.com/grpc-ecosystem/grpc-gateway/runtime"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
+	"go.etcd.io/etcd/server/v3/lease"
+	"go.uber.org/zap"
+)
+
+const (
+	// PathLeaseKeepAliveOnce renews a lease with a single unary request.
+	// It complements the streaming "/v3/lease/keepalive" gateway endpoint
+	// for environments where long-lived HTTP or gRPC streams are cut by
+	// middleboxes. The lease TTL can be queried through the existing
+	// "/v3/lease/timetolive" gateway endpoint.
+	PathLeaseKeepAliveOnce = "/v3/lease/keepalive/once"
+
+	maxLeaseKeepAliveRequestBytes = 4 * 1024
+)
+
+// LeaseRenewer renews leases on behalf of HTTP clients.
+type LeaseRenewer interface {
+	LeaseRenew(ctx context.Context, id lease.LeaseID) (int64, error)
+}
+
+// HandleLeaseKeepAlive registers the unary lease keepalive handler.
+// The request and response bodies use the same JSON encoding as the
+// gRPC gateway, e.g. '{"ID": "7587862072907329541"}'.
+func HandleLeaseKeepAlive(lg *zap.Logger, mux *http.ServeMux, s *etcdserver.EtcdServer) {
+	mux.Handle(PathLeaseKeepAliveOnce, newLeaseKeepAliveHandler(lg, s, func(rh *pb.ResponseHeader) {
+		rh.ClusterId = uint64(s.Cluster().ID())
+		rh.MemberId = uint64(s.MemberId())
+		rh.RaftTerm = s.Term()
+		rh.Revision = s.KV().Rev()
+	}))
+}
+
+func newLeaseKeepAliveHandler(lg *zap.Logger, lr LeaseRenewer, fillHeader func(*pb.ResponseHeader)) http.HandlerFunc {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	marshaler := &gw.JSONPb{OrigName: true}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		defer r.Body.Close()
+		b, err := io.ReadAll(io.LimitReader(r.Body, maxLeaseKeepAliveRequestBytes))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "error reading body")
+			return
+		}
+		var req pb.LeaseKeepAliveRequest
+		if err = marshaler.Unmarshal(b, &req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "error unmarshalling request")
+			return
+		}
+
+		// Same as the gRPC keepalive stream, the header is created before
+		// renewing so that the revision is never greater than the one at
+		// which the renewal happened.
+		resp := &pb.LeaseKeepAliveResponse{ID: req.ID, Header: &pb.ResponseHeader{}}
+		fillHeader(resp.Header)
+
+		ttl, err := lr.LeaseRenew(r.Context(), lease.LeaseID(req.ID))
+		if err == lease.ErrLeaseNotFound {
+			err = nil
+			ttl = 0
+		}
+		if err != nil {
+			lg.Warn("failed to renew lease over HTTP", zap.Int64("lease-id", req.ID), zap.Error(err))
+			writeJSONError(w, leaseErrorStatus(err), err.Error())
+			return
+		}
+		resp.TTL = ttl
+
+		d, err := marshaler.Marshal(resp)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	}
+}
+
+func leaseErrorStatus(err error) int {
+	switch err {
+	case errors.ErrTimeout, errors.ErrTimeoutDueToLeaderFail, errors.ErrTimeoutDueToConnectionLost,
+		errors.ErrNoLeader, errors.ErrNotEnoughStartedMembers:
+		return http.StatusServiceUnavailable
+	case errors.ErrCanceled, context.Canceled:
+		return http.StatusRequestTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, code int, msg string) {
+	d, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{Error: msg})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(d)
+}
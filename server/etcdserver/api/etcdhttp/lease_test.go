@@ -0,0 +1,118 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gw "github.com/grpc-ecosystem/grpc-gateway/runtime"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
+	"go.etcd.io/etcd/server/v3/lease"
+	"go.uber.org/zap/zaptest"
+)
+
+type fakeLeaseRenewer struct {
+	ttl int64
+	err error
+	id  lease.LeaseID
+}
+
+func (f *fakeLeaseRenewer) LeaseRenew(_ context.Context, id lease.LeaseID) (int64, error) {
+	f.id = id
+	return f.ttl, f.err
+}
+
+func TestLeaseKeepAliveOnce(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		renewer  *fakeLeaseRenewer
+		wantCode int
+		wantTTL  int64
+	}{
+		{
+			name:     "renewed",
+			method:   http.MethodPost,
+			body:     `{"ID": "7587862072907329541"}`,
+			renewer:  &fakeLeaseRenewer{ttl: 30},
+			wantCode: http.StatusOK,
+			wantTTL:  30,
+		},
+		{
+			name:     "not found returns zero TTL",
+			method:   http.MethodPost,
+			body:     `{"ID": "1"}`,
+			renewer:  &fakeLeaseRenewer{ttl: -1, err: lease.ErrLeaseNotFound},
+			wantCode: http.StatusOK,
+			wantTTL:  0,
+		},
+		{
+			name:     "no leader",
+			method:   http.MethodPost,
+			body:     `{"ID": "1"}`,
+			renewer:  &fakeLeaseRenewer{ttl: -1, err: errors.ErrNoLeader},
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "malformed body",
+			method:   http.MethodPost,
+			body:     `{"ID":`,
+			renewer:  &fakeLeaseRenewer{},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "wrong method",
+			method:   http.MethodGet,
+			renewer:  &fakeLeaseRenewer{},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newLeaseKeepAliveHandler(zaptest.NewLogger(t), tt.renewer, func(rh *pb.ResponseHeader) {
+				rh.MemberId = 2
+				rh.Revision = 5
+			})
+			req := httptest.NewRequest(tt.method, PathLeaseKeepAliveOnce, strings.NewReader(tt.body))
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, req)
+			if rw.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d (body %q)", rw.Code, tt.wantCode, rw.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var resp pb.LeaseKeepAliveResponse
+			if err := (&gw.JSONPb{OrigName: true}).Unmarshal(rw.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.ID != int64(tt.renewer.id) {
+				t.Errorf("ID = %d, want %d", resp.ID, tt.renewer.id)
+			}
+			if resp.TTL != tt.wantTTL {
+				t.Errorf("TTL = %d, want %d", resp.TTL, tt.wantTTL)
+			}
+			if resp.Header == nil || resp.Header.MemberId != 2 || resp.Header.Revision != 5 {
+				t.Errorf("unexpected header %+v", resp.Header)
+			}
+		})
+	}
+}
@@ -73,6 +73,9 @@ func (s *fakeServer) UpdateMember(ctx context.Context, updateMemb membership.Mem
 func (s *fakeServer) PromoteMember(ctx context.Context, id uint64) ([]*membership.Member, error) {
 	return nil, fmt.Errorf("PromoteMember not implemented in fakeServer")
 }
+func (s *fakeServer) DemoteMember(ctx context.Context, id uint64, acknowledgeReducedFaultTolerance bool) ([]*membership.Member, error) {
+	return nil, fmt.Errorf("DemoteMember not implemented in fakeServer")
+}
 func (s *fakeServer) ClusterVersion() *semver.Version      { return nil }
 func (s *fakeServer) StorageVersion() *semver.Version      { return nil }
 func (s *fakeServer) Cluster() api.Cluster                 { return s.cluster }
@@ -0,0 +1,187 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	gw "github.com/grpc-ecosystem/grpc-gateway/runtime"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// PathWatchSSE streams watch responses as Server-Sent Events.
+//
+// The bidirectional "/v3/watch" and "/v3/lease/keepalive" gateway endpoints
+// take a stream of newline delimited JSON requests in the body, or a stream
+// of text messages over WebSocket, and respond with a stream of JSON objects
+// of the form '{"result": <response>}'. PathWatchSSE covers the common case of
+// a single watcher for clients that cannot stream a request body, such as
+// browsers using EventSource:
+//
+//	GET /v3/watch/sse?key=Zm9v&range_end=Zm9w&start_revision=5&prev_kv=true
+//
+// The key and range_end parameters are base64 encoded, like bytes fields of
+// the JSON gateway, and follow the semantics of WatchCreateRequest, as do
+// start_revision, prev_kv and progress_notify. Each watch response is sent as
+//
+//	id: <revision>
+//	data: <WatchResponse as JSON>
+//
+// where revision is the mod_revision of the last event, or the header revision
+// of a progress notification, so that a reconnecting EventSource resumes the
+// watch after the last received revision through the "Last-Event-ID" header. Errors are sent as an "error"
+// event with a '{"error": <message>}' body, after which the stream is closed.
+const PathWatchSSE = "/v3/watch/sse"
+
+// HandleWatchSSE registers the Server-Sent Events watch handler. Watches are
+// opened through wc with the credentials of the "Authorization" header.
+func HandleWatchSSE(lg *zap.Logger, mux *http.ServeMux, wc pb.WatchClient) {
+	mux.Handle(PathWatchSSE, newWatchSSEHandler(lg, wc))
+}
+
+func newWatchSSEHandler(lg *zap.Logger, wc pb.WatchClient) http.HandlerFunc {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	marshaler := &gw.JSONPb{OrigName: true}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeJSONError(w, http.StatusInternalServerError, "streaming unsupported")
+			return
+		}
+		creq, err := parseWatchSSERequest(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		if token := r.Header.Get("Authorization"); token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, rpctypes.TokenFieldNameSwagger, token)
+		}
+		stream, err := wc.Watch(ctx)
+		if err == nil {
+			err = stream.Send(&pb.WatchRequest{RequestUnion: &pb.WatchRequest_CreateRequest{CreateRequest: creq}})
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		defer stream.CloseSend()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() == nil {
+					lg.Debug("watch over server-sent events closed", zap.Error(err))
+					writeSSEError(w, err.Error())
+					flusher.Flush()
+				}
+				return
+			}
+			d, err := marshaler.Marshal(resp)
+			if err != nil {
+				writeSSEError(w, err.Error())
+				flusher.Flush()
+				return
+			}
+			if id := watchSSEEventID(resp); id != 0 {
+				fmt.Fprintf(w, "id: %d\n", id)
+			}
+			fmt.Fprintf(w, "data: %s\n\n", d)
+			flusher.Flush()
+			if resp.Canceled {
+				return
+			}
+		}
+	}
+}
+
+func parseWatchSSERequest(r *http.Request) (*pb.WatchCreateRequest, error) {
+	q := r.URL.Query()
+	creq := &pb.WatchCreateRequest{}
+	var err error
+	if creq.Key, err = base64.StdEncoding.DecodeString(q.Get("key")); err != nil || len(creq.Key) == 0 {
+		return nil, fmt.Errorf("key must be a non-empty base64 encoded string")
+	}
+	if creq.RangeEnd, err = base64.StdEncoding.DecodeString(q.Get("range_end")); err != nil {
+		return nil, fmt.Errorf("range_end must be a base64 encoded string")
+	}
+	if v := q.Get("start_revision"); v != "" {
+		if creq.StartRevision, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid start_revision %q", v)
+		}
+	}
+	// resume after the last event received by a reconnecting client
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		rev, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Last-Event-ID %q", v)
+		}
+		creq.StartRevision = rev + 1
+	}
+	if creq.PrevKv, err = parseBoolParam(q.Get("prev_kv")); err != nil {
+		return nil, fmt.Errorf("invalid prev_kv %q", q.Get("prev_kv"))
+	}
+	if creq.ProgressNotify, err = parseBoolParam(q.Get("progress_notify")); err != nil {
+		return nil, fmt.Errorf("invalid progress_notify %q", q.Get("progress_notify"))
+	}
+	return creq, nil
+}
+
+// watchSSEEventID returns the revision up to which the client has received
+// all events once resp is delivered, or 0 if resp does not advance it.
+func watchSSEEventID(resp *pb.WatchResponse) int64 {
+	if n := len(resp.Events); n != 0 {
+		return resp.Events[n-1].Kv.ModRevision
+	}
+	// a progress notification
+	if !resp.Created && !resp.Canceled && resp.Header != nil {
+		return resp.Header.Revision
+	}
+	return 0
+}
+
+func parseBoolParam(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+func writeSSEError(w http.ResponseWriter, msg string) {
+	d, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{Error: msg})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", d)
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type fakeWatchClient struct {
+	resps []*pb.WatchResponse
+	err   error
+
+	ctx  context.Context
+	reqs []*pb.WatchRequest
+}
+
+func (f *fakeWatchClient) Watch(ctx context.Context, _ ...grpc.CallOption) (pb.Watch_WatchClient, error) {
+	f.ctx = ctx
+	return &fakeWatchStream{f: f}, nil
+}
+
+type fakeWatchStream struct {
+	grpc.ClientStream
+	f *fakeWatchClient
+}
+
+func (s *fakeWatchStream) Send(r *pb.WatchRequest) error {
+	s.f.reqs = append(s.f.reqs, r)
+	return nil
+}
+
+func (s *fakeWatchStream) Recv() (*pb.WatchResponse, error) {
+	if len(s.f.resps) == 0 {
+		return nil, s.f.err
+	}
+	resp := s.f.resps[0]
+	s.f.resps = s.f.resps[1:]
+	return resp, nil
+}
+
+func (s *fakeWatchStream) CloseSend() error { return nil }
+
+func TestWatchSSE(t *testing.T) {
+	wc := &fakeWatchClient{
+		resps: []*pb.WatchResponse{
+			{Header: &pb.ResponseHeader{Revision: 5}, Created: true},
+			{Header: &pb.ResponseHeader{Revision: 6}, Events: []*mvccpb.Event{{Kv: &mvccpb.KeyValue{Key: []byte("foo"), ModRevision: 6}}}},
+		},
+		err: rpctypes.ErrGRPCCompacted,
+	}
+	h := newWatchSSEHandler(zaptest.NewLogger(t), wc)
+
+	req := httptest.NewRequest(http.MethodGet, PathWatchSSE+"?key=Zm9v&range_end=Zm9w&prev_kv=true", nil)
+	req.Header.Set("Authorization", "token")
+	req.Header.Set("Last-Event-ID", "3")
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("code = %d, want %d", rw.Code, http.StatusOK)
+	}
+	if ct := rw.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type = %q, want text/event-stream", ct)
+	}
+	wreq := &pb.WatchCreateRequest{Key: []byte("foo"), RangeEnd: []byte("fop"), StartRevision: 4, PrevKv: true}
+	if len(wc.reqs) != 1 || !reflect.DeepEqual(wc.reqs[0].GetCreateRequest(), wreq) {
+		t.Errorf("watch requests = %+v, want %+v", wc.reqs, wreq)
+	}
+	md, _ := metadata.FromOutgoingContext(wc.ctx)
+	if tokens := md.Get(rpctypes.TokenFieldNameSwagger); len(tokens) != 1 || tokens[0] != "token" {
+		t.Errorf("token = %v, want [token]", tokens)
+	}
+
+	body, _ := io.ReadAll(rw.Body)
+	events := strings.Split(strings.TrimSuffix(string(body), "\n\n"), "\n\n")
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %q", len(events), body)
+	}
+	if strings.HasPrefix(events[0], "id:") || !strings.Contains(events[0], `"created":true`) {
+		t.Errorf("unexpected created event %q", events[0])
+	}
+	if !strings.HasPrefix(events[1], "id: 6\ndata: ") {
+		t.Errorf("unexpected watch event %q", events[1])
+	}
+	if !strings.HasPrefix(events[2], "event: error\ndata: ") || !strings.Contains(events[2], "compacted") {
+		t.Errorf("unexpected error event %q", events[2])
+	}
+}
+
+func TestWatchSSEBadRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		wcode  int
+	}{
+		{"no key", http.MethodGet, PathWatchSSE, http.StatusBadRequest},
+		{"key not base64", http.MethodGet, PathWatchSSE + "?key=foo!", http.StatusBadRequest},
+		{"bad start revision", http.MethodGet, PathWatchSSE + "?key=Zm9v&start_revision=x", http.StatusBadRequest},
+		{"bad prev_kv", http.MethodGet, PathWatchSSE + "?key=Zm9v&prev_kv=x", http.StatusBadRequest},
+		{"wrong method", http.MethodPost, PathWatchSSE + "?key=Zm9v", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc := &fakeWatchClient{}
+			h := newWatchSSEHandler(zaptest.NewLogger(t), wc)
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, httptest.NewRequest(tt.method, tt.target, nil))
+			if rw.Code != tt.wcode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wcode)
+			}
+			if len(wc.reqs) != 0 {
+				t.Errorf("unexpected watch requests %+v", wc.reqs)
+			}
+		})
+	}
+}
@@ -69,6 +69,10 @@ type ConfigChangeContext struct {
 	// This flag is needed because both adding a new member and promoting a learner member
 	// uses the same config change type 'ConfChangeAddNode'.
 	IsPromote bool `json:"isPromote"`
+	// IsDemote indicates if the config change is for demoting a voting member.
+	// This flag is needed because both adding a new learner member and demoting
+	// a voting member uses the same config change type 'ConfChangeAddLearnerNode'.
+	IsDemote bool `json:"isDemote,omitempty"`
 }
 
 type ShouldApplyV3 bool
@@ -325,6 +329,28 @@ func (c *RaftCluster) ValidateConfigurationChange(cc raftpb.ConfChange) error {
 			if !membersMap[id].IsLearner {
 				return ErrMemberNotLearner
 			}
+		} else if confChangeContext.IsDemote { // demoting a voting member to learner member
+			if membersMap[id] == nil {
+				return ErrIDNotFound
+			}
+			if membersMap[id].IsLearner {
+				return ErrMemberNotVoter
+			}
+			var members []*Member
+			voters := 0
+			for _, m := range membersMap {
+				members = append(members, m)
+				if !m.IsLearner {
+					voters++
+				}
+			}
+			if voters == 1 {
+				return ErrLastVotingMember
+			}
+			scaleUpLearners := true
+			if err := ValidateMaxLearnerConfig(c.maxLearners, members, scaleUpLearners); err != nil {
+				return err
+			}
 		} else { // adding a new member
 			if membersMap[id] != nil {
 				return ErrIDExists
@@ -501,6 +527,28 @@ func (c *RaftCluster) PromoteMember(id types.ID, shouldApplyV3 ShouldApplyV3) {
 	)
 }
 
+// DemoteMember demotes a voting member to a learner member.
+func (c *RaftCluster) DemoteMember(id types.ID, shouldApplyV3 ShouldApplyV3) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.members[id].RaftAttributes.IsLearner = true
+	c.updateMembershipMetric(id, true)
+	if c.v2store != nil {
+		mustUpdateMemberInStore(c.lg, c.v2store, c.members[id])
+	}
+	if c.be != nil && shouldApplyV3 {
+		c.be.MustSaveMemberToBackend(c.members[id])
+	}
+
+	c.lg.Info(
+		"demote member",
+		zap.String("cluster-id", c.cid.String()),
+		zap.String("local-member-id", c.localID.String()),
+		zap.String("demoted-member-id", id.String()),
+	)
+}
+
 func (c *RaftCluster) UpdateRaftAttributes(id types.ID, raftAttr RaftAttributes, shouldApplyV3 ShouldApplyV3) {
 	c.Lock()
 	defer c.Unlock()
@@ -339,6 +339,21 @@ func TestClusterValidateConfigurationChange(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	ctxDemote1, err := json.Marshal(&ConfigChangeContext{Member: Member{ID: types.ID(1)}, IsDemote: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctxDemote2, err := json.Marshal(&ConfigChangeContext{Member: Member{ID: types.ID(2)}, IsDemote: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctxDemote6, err := json.Marshal(&ConfigChangeContext{Member: Member{ID: types.ID(6)}, IsDemote: true})
+	if err != nil {
+		t.Fatal(err)
+	}
 	tests := []struct {
 		cc   raftpb.ConfChange
 		werr error
@@ -452,6 +467,30 @@ func TestClusterValidateConfigurationChange(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			raftpb.ConfChange{
+				Type:    raftpb.ConfChangeAddLearnerNode,
+				NodeID:  1,
+				Context: ctxDemote1,
+			},
+			ErrMemberNotVoter,
+		},
+		{
+			raftpb.ConfChange{
+				Type:    raftpb.ConfChangeAddLearnerNode,
+				NodeID:  2,
+				Context: ctxDemote2,
+			},
+			ErrTooManyLearners,
+		},
+		{
+			raftpb.ConfChange{
+				Type:    raftpb.ConfChangeAddLearnerNode,
+				NodeID:  6,
+				Context: ctxDemote6,
+			},
+			ErrIDNotFound,
+		},
 	}
 	for i, tt := range tests {
 		err := cl.ValidateConfigurationChange(tt.cc)
@@ -594,6 +633,37 @@ func TestClusterRemoveMember(t *testing.T) {
 	}
 }
 
+func TestClusterDemoteMember(t *testing.T) {
+	cl := NewCluster(zaptest.NewLogger(t), WithMaxLearners(DefaultMaxLearners))
+	cl.SetStore(v2store.New())
+	for i := 1; i <= 2; i++ {
+		attr := RaftAttributes{PeerURLs: []string{fmt.Sprintf("http://127.0.0.1:%d", i)}}
+		cl.AddMember(&Member{ID: types.ID(i), RaftAttributes: attr}, true)
+	}
+	demote := func(id types.ID) raftpb.ConfChange {
+		b, err := json.Marshal(&ConfigChangeContext{Member: Member{ID: id}, IsDemote: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raftpb.ConfChange{Type: raftpb.ConfChangeAddLearnerNode, NodeID: uint64(id), Context: b}
+	}
+
+	if err := cl.ValidateConfigurationChange(demote(1)); err != nil {
+		t.Fatalf("validateConfigurationChange error = %v, want nil", err)
+	}
+	cl.DemoteMember(1, true)
+	if !cl.Member(1).IsLearner {
+		t.Errorf("member 1 is not a learner, want demoted")
+	}
+	if vms := cl.VotingMembers(); len(vms) != 1 || vms[0].ID != 2 {
+		t.Errorf("voting members = %v, want only member 2", vms)
+	}
+	// the last voting member cannot leave the quorum
+	if err := cl.ValidateConfigurationChange(demote(2)); err != ErrLastVotingMember {
+		t.Errorf("validateConfigurationChange error = %v, want %v", err, ErrLastVotingMember)
+	}
+}
+
 func TestClusterUpdateAttributes(t *testing.T) {
 	name := "etcd"
 	clientURLs := []string{"http://127.0.0.1:4001"}
@@ -27,6 +27,8 @@ var (
 	ErrPeerURLexists    = errors.New("membership: peerURL exists")
 	ErrMemberNotLearner = errors.New("membership: can only promote a learner member")
 	ErrTooManyLearners  = errors.New("membership: too many learner members in cluster")
+	ErrMemberNotVoter   = errors.New("membership: can only demote a voting member")
+	ErrLastVotingMember = errors.New("membership: cannot demote the last voting member")
 )
 
 func isKeyNotFound(err error) bool {
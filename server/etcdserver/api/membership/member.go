@@ -39,6 +39,8 @@ type RaftAttributes struct {
 type Attributes struct {
 	Name       string   `json:"name,omitempty"`
 	ClientURLs []string `json:"clientURLs,omitempty"`
+	// ElectionPriority is the priority of the member to be the leader.
+	ElectionPriority uint32 `json:"electionPriority,omitempty"`
 }
 
 type Member struct {
@@ -388,6 +388,7 @@ func (pr *fakePeer) sendSnap(m snap.Message) {
 func (pr *fakePeer) update(urls types.URLs)                { pr.peerURLs = urls }
 func (pr *fakePeer) attachOutgoingConn(conn *outgoingConn) { pr.connc <- conn }
 func (pr *fakePeer) activeSince() time.Time                { return time.Time{} }
+func (pr *fakePeer) disconnects() uint64                   { return 0 }
 func (pr *fakePeer) stop()                                 {}
 func (pr *fakePeer) Pause()                                { pr.paused = true }
 func (pr *fakePeer) Resume()                               { pr.paused = false }
@@ -163,10 +163,20 @@ var (
 	},
 		[]string{"To"},
 	)
+
+	peerClockSkewSec = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "network",
+		Name:      "peer_clock_skew_seconds",
+		Help:      "The clock skew of the local member relative to the peer, positive if the local clock is ahead.",
+	},
+		[]string{"To"},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(activePeers)
+	prometheus.MustRegister(peerClockSkewSec)
 	prometheus.MustRegister(disconnectedPeers)
 	prometheus.MustRegister(sentBytes)
 	prometheus.MustRegister(receivedBytes)
@@ -82,6 +82,9 @@ type Peer interface {
 	// activeSince returns the time that the connection with the
 	// peer becomes active.
 	activeSince() time.Time
+	// disconnects returns the number of times the connection with the peer
+	// was lost.
+	disconnects() uint64
 	// stop performs any necessary finalization and terminates the peer
 	// elegantly.
 	stop()
@@ -292,6 +295,8 @@ func (p *peer) attachOutgoingConn(conn *outgoingConn) {
 
 func (p *peer) activeSince() time.Time { return p.status.activeSince() }
 
+func (p *peer) disconnects() uint64 { return p.status.disconnectCount() }
+
 // Pause pauses the peer. The peer will simply drops all incoming
 // messages without returning an error.
 func (p *peer) Pause() {
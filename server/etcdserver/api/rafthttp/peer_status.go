@@ -37,6 +37,8 @@ type peerStatus struct {
 	mu     sync.Mutex // protect variables below
 	active bool
 	since  time.Time
+	// disconnects is the number of times the peer became inactive.
+	disconnects uint64
 }
 
 func newPeerStatus(lg *zap.Logger, local, id types.ID) *peerStatus {
@@ -66,6 +68,7 @@ func (s *peerStatus) deactivate(failure failureType, reason string) {
 		s.lg.Warn("peer became inactive (message send to peer failed)", zap.String("peer-id", s.id.String()), zap.Error(errors.New(msg)))
 		s.active = false
 		s.since = time.Time{}
+		s.disconnects++
 
 		activePeers.WithLabelValues(s.local.String(), s.id.String()).Dec()
 		disconnectedPeers.WithLabelValues(s.local.String(), s.id.String()).Inc()
@@ -88,3 +91,9 @@ func (s *peerStatus) activeSince() time.Time {
 	defer s.mu.Unlock()
 	return s.since
 }
+
+func (s *peerStatus) disconnectCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.disconnects
+}
@@ -38,7 +38,11 @@ var (
 	statusErrorInterval      = 5 * time.Second
 )
 
-func addPeerToProber(lg *zap.Logger, p probing.Prober, id string, us []string, roundTripperName string, rttSecProm *prometheus.HistogramVec) {
+// DefaultMaxClockSkew is the clock skew with a peer above which it is warned
+// about if not configured.
+const DefaultMaxClockSkew = time.Second
+
+func addPeerToProber(lg *zap.Logger, p probing.Prober, id string, us []string, roundTripperName string, rttSecProm *prometheus.HistogramVec, maxClockSkew time.Duration) {
 	hus := make([]string, len(us))
 	for i := range us {
 		hus[i] = us[i] + ProbingPrefix
@@ -54,10 +58,10 @@ func addPeerToProber(lg *zap.Logger, p probing.Prober, id string, us []string, r
 		return
 	}
 
-	go monitorProbingStatus(lg, s, id, roundTripperName, rttSecProm)
+	go monitorProbingStatus(lg, s, id, roundTripperName, rttSecProm, maxClockSkew)
 }
 
-func monitorProbingStatus(lg *zap.Logger, s probing.Status, id string, roundTripperName string, rttSecProm *prometheus.HistogramVec) {
+func monitorProbingStatus(lg *zap.Logger, s probing.Status, id string, roundTripperName string, rttSecProm *prometheus.HistogramVec, maxClockSkew time.Duration) {
 	// set the first interval short to log error early.
 	interval := statusErrorInterval
 	for {
@@ -77,22 +81,36 @@ func monitorProbingStatus(lg *zap.Logger, s probing.Status, id string, roundTrip
 			} else {
 				interval = statusMonitoringInterval
 			}
-			if s.ClockDiff() > time.Second {
+			if abs(s.ClockDiff()) > maxClockSkew {
 				if lg != nil {
 					lg.Warn(
 						"prober found high clock drift",
 						zap.String("round-tripper-name", roundTripperName),
 						zap.String("remote-peer-id", id),
 						zap.Duration("clock-drift", s.ClockDiff()),
+						zap.Duration("max-clock-skew", maxClockSkew),
 						zap.Duration("rtt", s.SRTT()),
 						zap.Error(s.Err()),
 					)
 				}
 			}
 			rttSecProm.WithLabelValues(id).Observe(s.SRTT().Seconds())
+			if roundTripperName == RoundTripperNameRaftMessage && s.Health() {
+				peerClockSkewSec.WithLabelValues(id).Set(s.ClockDiff().Seconds())
+			}
 
 		case <-s.StopNotify():
+			if roundTripperName == RoundTripperNameRaftMessage {
+				peerClockSkewSec.DeleteLabelValues(id)
+			}
 			return
 		}
 	}
 }
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -84,6 +84,12 @@ type Transporter interface {
 	ActiveSince(id types.ID) time.Time
 	// ActivePeers returns the number of active peers.
 	ActivePeers() int
+	// ClockSkews returns the clock skews of the local member relative to the
+	// peers answering the probes, positive if the local clock is ahead.
+	ClockSkews() map[types.ID]time.Duration
+	// Disconnects returns the number of times the connections with the peers
+	// were lost.
+	Disconnects() map[types.ID]uint64
 	// Stop closes the connections and stops the transporter.
 	Stop()
 }
@@ -118,6 +124,9 @@ type Transport struct {
 	// When an error is received from ErrorC, user should stop raft state
 	// machine and thus stop the Transport.
 	ErrorC chan error
+	// MaxClockSkew is the clock skew with a peer above which it is warned
+	// about, DefaultMaxClockSkew if zero.
+	MaxClockSkew time.Duration
 
 	streamRt   http.RoundTripper // roundTripper used by streams
 	pipelineRt http.RoundTripper // roundTripper used by pipelines
@@ -310,8 +319,8 @@ func (t *Transport) AddPeer(id types.ID, us []string) {
 	}
 	fs := t.LeaderStats.Follower(id.String())
 	t.peers[id] = startPeer(t, urls, id, fs)
-	addPeerToProber(t.Logger, t.pipelineProber, id.String(), us, RoundTripperNameSnapshot, rttSec)
-	addPeerToProber(t.Logger, t.streamProber, id.String(), us, RoundTripperNameRaftMessage, rttSec)
+	addPeerToProber(t.Logger, t.pipelineProber, id.String(), us, RoundTripperNameSnapshot, rttSec, t.maxClockSkew())
+	addPeerToProber(t.Logger, t.streamProber, id.String(), us, RoundTripperNameRaftMessage, rttSec, t.maxClockSkew())
 
 	if t.Logger != nil {
 		t.Logger.Info(
@@ -382,9 +391,9 @@ func (t *Transport) UpdatePeer(id types.ID, us []string) {
 	t.peers[id].update(urls)
 
 	t.pipelineProber.Remove(id.String())
-	addPeerToProber(t.Logger, t.pipelineProber, id.String(), us, RoundTripperNameSnapshot, rttSec)
+	addPeerToProber(t.Logger, t.pipelineProber, id.String(), us, RoundTripperNameSnapshot, rttSec, t.maxClockSkew())
 	t.streamProber.Remove(id.String())
-	addPeerToProber(t.Logger, t.streamProber, id.String(), us, RoundTripperNameRaftMessage, rttSec)
+	addPeerToProber(t.Logger, t.streamProber, id.String(), us, RoundTripperNameRaftMessage, rttSec, t.maxClockSkew())
 
 	if t.Logger != nil {
 		t.Logger.Info(
@@ -441,6 +450,37 @@ func (t *Transport) Resume() {
 // ActivePeers returns a channel that closes when an initial
 // peer connection has been established. Use this to wait until the
 // first peer connection becomes active.
+func (t *Transport) ClockSkews() map[types.ID]time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	skews := make(map[types.ID]time.Duration, len(t.peers))
+	for id := range t.peers {
+		s, err := t.streamProber.Status(id.String())
+		if err != nil || !s.Health() {
+			continue
+		}
+		skews[id] = s.ClockDiff()
+	}
+	return skews
+}
+
+func (t *Transport) Disconnects() map[types.ID]uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	disconnects := make(map[types.ID]uint64, len(t.peers))
+	for id, p := range t.peers {
+		disconnects[id] = p.disconnects()
+	}
+	return disconnects
+}
+
+func (t *Transport) maxClockSkew() time.Duration {
+	if t.MaxClockSkew > 0 {
+		return t.MaxClockSkew
+	}
+	return DefaultMaxClockSkew
+}
+
 func (t *Transport) ActivePeers() (cnt int) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -15,7 +15,9 @@
 package rafthttp
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -204,3 +206,33 @@ func TestTransportErrorc(t *testing.T) {
 		t.Fatalf("cannot receive error from errorc")
 	}
 }
+
+func TestTransportClockSkews(t *testing.T) {
+	// the peer clock is two seconds ahead
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(probing.Health{OK: true, Now: time.Now().Add(2 * time.Second)})
+	}))
+	defer srv.Close()
+
+	tr := &Transport{
+		peers:        map[types.ID]Peer{1: &fakePeer{}, 2: &fakePeer{}},
+		streamProber: probing.NewProber(http.DefaultTransport),
+	}
+	defer tr.streamProber.RemoveAll()
+	tr.streamProber.AddHTTP("1", 10*time.Millisecond, []string{srv.URL})
+
+	var skews map[types.ID]time.Duration
+	for i := 0; i < 100; i++ {
+		if skews = tr.ClockSkews(); len(skews) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// peer 2 is not probed
+	if len(skews) != 1 {
+		t.Fatalf("skews = %v, want the skew with peer 1 only", skews)
+	}
+	if skew := skews[1]; skew > -1500*time.Millisecond || skew < -2500*time.Millisecond {
+		t.Errorf("skew = %v, want about -2s", skew)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+
+package snap
+
+import (
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/client/pkg/v3/testutil"
+	"go.etcd.io/etcd/pkg/v3/pbutil"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/snap/snappb"
+	"go.uber.org/zap"
+)
+
+// FuzzRead reads a snap file of arbitrary bytes or, crc being set, of the
+// arbitrary snapshot data of a valid CRC, the snapshot read being expected
+// to be saved and loaded back unchanged.
+func FuzzRead(f *testing.F) {
+	data := pbutil.MustMarshal(testSnap)
+	f.Add(pbutil.MustMarshal(&snappb.Snapshot{Crc: crc32.Update(0, crcTable, data), Data: data}), false)
+	f.Add(data, true)
+	f.Add([]byte{}, false)
+
+	f.Fuzz(func(t *testing.T, b []byte, crc bool) {
+		if crc {
+			b = pbutil.MustMarshal(&snappb.Snapshot{Crc: crc32.Update(0, crcTable, b), Data: b})
+		}
+		path := filepath.Join(t.TempDir(), "fuzz"+snapSuffix)
+		if err := os.WriteFile(path, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		testutil.AssertNoRuntimePanic(t, func() {
+			snap, err := Read(zap.NewNop(), path)
+			if err != nil {
+				return
+			}
+			ss := New(zap.NewNop(), t.TempDir())
+			if err = ss.save(snap); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := ss.Load()
+			if err != nil {
+				t.Fatalf("failed to load the saved snapshot (%v)", err)
+			}
+			if !reflect.DeepEqual(loaded, snap) {
+				t.Fatalf("loaded snapshot %#v, want %#v", loaded, snap)
+			}
+		})
+	})
+}
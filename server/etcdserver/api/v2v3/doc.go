@@ -0,0 +1,29 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v2v3 provides a v2 keys API emulation layer backed by the v3 store.
+//
+// The v2 nodes are stored as v3 keys under a dedicated prefix, partitioned by
+// the depth of the node so that the children of a directory are a single
+// v3 key range:
+//
+//	<prefix>/<depth>/k<path>   file node
+//	<prefix>/<depth>/k<path>/  directory node
+//
+// The v2 index of a node is the v3 revision of its last modification and
+// TTLs are implemented with v3 leases. Every mutation also writes the v2
+// action to "<prefix>/act", so that watchers can report the v2 action of
+// the events of each revision. The emulation works on a plain v3 client,
+// so it does not support v2 authentication.
+package v2v3
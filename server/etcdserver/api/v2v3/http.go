@@ -0,0 +1,290 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2v3
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2error"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2store"
+	"go.uber.org/zap"
+)
+
+// PathKeys is the prefix of the v2 keys API.
+const PathKeys = "/v2/keys"
+
+// HandleKeys registers the v2 keys API handler backed by st.
+func HandleKeys(lg *zap.Logger, mux *http.ServeMux, st v2store.Store) {
+	h := NewHandler(lg, st)
+	mux.Handle(PathKeys, h)
+	mux.Handle(PathKeys+"/", h)
+}
+
+// NewHandler returns a handler serving the v2 keys API, including waits,
+// on top of st.
+func NewHandler(lg *zap.Logger, st v2store.Store) http.Handler {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	return &keysHandler{lg: lg, st: st}
+}
+
+type keysHandler struct {
+	lg *zap.Logger
+	st v2store.Store
+}
+
+// keysRequest holds the parameters of a v2 keys request.
+type keysRequest struct {
+	path string
+
+	value     string
+	dir       bool
+	ttl       *uint64
+	refresh   bool
+	prevExist *bool
+	prevValue string
+	prevIndex uint64
+
+	recursive bool
+	sorted    bool
+	wait      bool
+	waitIndex uint64
+	stream    bool
+}
+
+func (h *keysHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPost, http.MethodDelete:
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kr, err := parseKeysRequest(r)
+	if err != nil {
+		writeError(h.lg, w, err, h.st.Index())
+		return
+	}
+
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && kr.wait {
+		h.serveWatch(w, r, kr)
+		return
+	}
+
+	var ev *v2store.Event
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		ev, err = h.st.Get(kr.path, kr.recursive, kr.sorted)
+	case http.MethodPut:
+		ev, err = h.put(kr)
+	case http.MethodPost:
+		ev, err = h.st.Create(kr.path, kr.dir, kr.value, true, kr.ttlOptions())
+	case http.MethodDelete:
+		if kr.prevValue != "" || kr.prevIndex != 0 {
+			ev, err = h.st.CompareAndDelete(kr.path, kr.prevValue, kr.prevIndex)
+		} else {
+			ev, err = h.st.Delete(kr.path, kr.dir, kr.recursive)
+		}
+	}
+	if err != nil {
+		writeError(h.lg, w, err, h.st.Index())
+		return
+	}
+	writeEvent(h.lg, w, r, ev)
+}
+
+func (h *keysHandler) put(kr *keysRequest) (*v2store.Event, error) {
+	opts := kr.ttlOptions()
+	switch {
+	case kr.prevValue != "" || kr.prevIndex != 0:
+		if kr.prevExist != nil && !*kr.prevExist {
+			return nil, v2error.NewError(v2error.EcodeInvalidField, "prevValue or prevIndex with prevExist=false", h.st.Index())
+		}
+		return h.st.CompareAndSwap(kr.path, kr.prevValue, kr.prevIndex, kr.value, opts)
+	case kr.prevExist != nil && *kr.prevExist:
+		if kr.dir && !kr.refresh {
+			return h.st.Update(kr.path, "", opts)
+		}
+		return h.st.Update(kr.path, kr.value, opts)
+	case kr.prevExist != nil:
+		return h.st.Create(kr.path, kr.dir, kr.value, false, opts)
+	}
+	return h.st.Set(kr.path, kr.dir, kr.value, opts)
+}
+
+func (h *keysHandler) serveWatch(w http.ResponseWriter, r *http.Request, kr *keysRequest) {
+	wa, err := h.st.Watch(kr.path, kr.recursive, kr.stream, kr.waitIndex)
+	if err != nil {
+		writeError(h.lg, w, err, h.st.Index())
+		return
+	}
+	defer wa.Remove()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Etcd-Index", strconv.FormatUint(wa.StartIndex(), 10))
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		// send the headers so that the client knows the watch is established
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-wa.EventChan():
+			if !ok {
+				// the watch was closed by the store; the client retries
+				return
+			}
+			if err := json.NewEncoder(w).Encode(ev); err != nil {
+				h.lg.Debug("failed to write v2 watch event", zap.Error(err))
+				return
+			}
+			if !kr.stream {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func parseKeysRequest(r *http.Request) (*keysRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, newRequestError(v2error.EcodeInvalidForm, err.Error())
+	}
+	kr := &keysRequest{
+		path:      strings.TrimPrefix(r.URL.Path, PathKeys),
+		value:     r.FormValue("value"),
+		prevValue: r.FormValue("prevValue"),
+	}
+	if kr.path == "" {
+		kr.path = "/"
+	}
+
+	var err error
+	if kr.prevIndex, err = parseUintField(r, "prevIndex", v2error.EcodeIndexNaN); err != nil {
+		return nil, err
+	}
+	if kr.waitIndex, err = parseUintField(r, "waitIndex", v2error.EcodeIndexNaN); err != nil {
+		return nil, err
+	}
+	if v := r.FormValue("ttl"); v != "" {
+		ttl, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, newRequestError(v2error.EcodeTTLNaN, `invalid value for "ttl"`)
+		}
+		kr.ttl = &ttl
+	}
+	if v := r.FormValue("prevExist"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, newRequestError(v2error.EcodeInvalidField, `invalid value for "prevExist"`)
+		}
+		kr.prevExist = &b
+	}
+	for _, f := range []struct {
+		name string
+		v    *bool
+	}{
+		{"dir", &kr.dir},
+		{"refresh", &kr.refresh},
+		{"recursive", &kr.recursive},
+		{"sorted", &kr.sorted},
+		{"wait", &kr.wait},
+		{"stream", &kr.stream},
+	} {
+		if v := r.FormValue(f.name); v != "" {
+			if *f.v, err = strconv.ParseBool(v); err != nil {
+				return nil, newRequestError(v2error.EcodeInvalidField, `invalid value for "`+f.name+`"`)
+			}
+		}
+	}
+
+	if kr.refresh {
+		if kr.ttl == nil {
+			return nil, newRequestError(v2error.EcodeRefreshTTLRequired, "")
+		}
+		if _, ok := r.Form["value"]; ok {
+			return nil, newRequestError(v2error.EcodeRefreshValue, "")
+		}
+	}
+	if kr.dir && kr.value != "" {
+		return nil, newRequestError(v2error.EcodeInvalidField, "dir with value")
+	}
+	return kr, nil
+}
+
+func parseUintField(r *http.Request, name string, ecode int) (uint64, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, newRequestError(ecode, `invalid value for "`+name+`"`)
+	}
+	return i, nil
+}
+
+func (kr *keysRequest) ttlOptions() v2store.TTLOptionSet {
+	opts := v2store.TTLOptionSet{Refresh: kr.refresh}
+	if kr.ttl != nil && *kr.ttl != 0 {
+		opts.ExpireTime = time.Now().Add(time.Duration(*kr.ttl) * time.Second)
+	}
+	return opts
+}
+
+func writeEvent(lg *zap.Logger, w http.ResponseWriter, r *http.Request, ev *v2store.Event) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Etcd-Index", strconv.FormatUint(ev.EtcdIndex, 10))
+	if ev.IsCreated() {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := json.NewEncoder(w).Encode(ev); err != nil {
+		lg.Debug("failed to write v2 event", zap.Error(err))
+	}
+}
+
+func writeError(lg *zap.Logger, w http.ResponseWriter, err error, index uint64) {
+	verr, ok := err.(*v2error.Error)
+	if !ok {
+		lg.Warn("v2 keys request failed", zap.Error(err))
+		verr = v2error.NewError(v2error.EcodeRaftInternal, err.Error(), index)
+	}
+	if werr := verr.WriteTo(w); werr != nil {
+		lg.Debug("failed to write v2 error", zap.Error(werr))
+	}
+}
+
+// newRequestError returns the error of a malformed request, which does not
+// depend on the store state.
+func newRequestError(ecode int, cause string) *v2error.Error {
+	return v2error.NewError(ecode, cause, 0)
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2v3
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2error"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2store"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestKeysHandler(t *testing.T) {
+	// the in-memory v2 store serves as the reference implementation
+	h := NewHandler(zaptest.NewLogger(t), v2store.New())
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		form   url.Values
+
+		wcode   int
+		waction string
+		wvalue  string
+		werr    int
+	}{
+		{"create", http.MethodPut, "/v2/keys/foo?prevExist=false", url.Values{"value": {"bar"}}, http.StatusCreated, "create", "bar", 0},
+		{"create exists", http.MethodPut, "/v2/keys/foo?prevExist=false", url.Values{"value": {"bar"}}, http.StatusPreconditionFailed, "", "", v2error.EcodeNodeExist},
+		{"get", http.MethodGet, "/v2/keys/foo", nil, http.StatusOK, "get", "bar", 0},
+		{"set", http.MethodPut, "/v2/keys/foo", url.Values{"value": {"baz"}}, http.StatusOK, "set", "baz", 0},
+		{"cas failed", http.MethodPut, "/v2/keys/foo?prevValue=bar", url.Values{"value": {"qux"}}, http.StatusPreconditionFailed, "", "", v2error.EcodeTestFailed},
+		{"cas", http.MethodPut, "/v2/keys/foo?prevValue=baz", url.Values{"value": {"qux"}}, http.StatusOK, "compareAndSwap", "qux", 0},
+		{"update", http.MethodPut, "/v2/keys/foo?prevExist=true", url.Values{"value": {"quux"}}, http.StatusOK, "update", "quux", 0},
+		{"invalid ttl", http.MethodPut, "/v2/keys/foo?ttl=x", nil, http.StatusBadRequest, "", "", v2error.EcodeTTLNaN},
+		{"refresh without ttl", http.MethodPut, "/v2/keys/foo?refresh=true", nil, http.StatusBadRequest, "", "", v2error.EcodeRefreshTTLRequired},
+		{"in-order create", http.MethodPost, "/v2/keys/queue", url.Values{"value": {"job"}}, http.StatusCreated, "create", "job", 0},
+		{"compare and delete", http.MethodDelete, "/v2/keys/foo?prevValue=quux", nil, http.StatusOK, "compareAndDelete", "", 0},
+		{"delete not found", http.MethodDelete, "/v2/keys/foo", nil, http.StatusNotFound, "", "", v2error.EcodeKeyNotFound},
+		{"wrong method", http.MethodPatch, "/v2/keys/foo", nil, http.StatusMethodNotAllowed, "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, req)
+
+			if rw.Code != tt.wcode {
+				t.Fatalf("code = %d, want %d (body %q)", rw.Code, tt.wcode, rw.Body.String())
+			}
+			if rw.Code == http.StatusMethodNotAllowed {
+				return
+			}
+			if rw.Header().Get("X-Etcd-Index") == "" {
+				t.Errorf("missing X-Etcd-Index header")
+			}
+			if tt.werr != 0 {
+				var verr v2error.Error
+				if err := json.Unmarshal(rw.Body.Bytes(), &verr); err != nil {
+					t.Fatal(err)
+				}
+				if verr.ErrorCode != tt.werr {
+					t.Errorf("error code = %d, want %d", verr.ErrorCode, tt.werr)
+				}
+				return
+			}
+			var ev v2store.Event
+			if err := json.Unmarshal(rw.Body.Bytes(), &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Action != tt.waction {
+				t.Errorf("action = %q, want %q", ev.Action, tt.waction)
+			}
+			if tt.wvalue != "" && (ev.Node.Value == nil || *ev.Node.Value != tt.wvalue) {
+				t.Errorf("value = %v, want %q", ev.Node.Value, tt.wvalue)
+			}
+		})
+	}
+}
+
+func TestKeysHandlerWait(t *testing.T) {
+	st := v2store.New()
+	h := NewHandler(zaptest.NewLogger(t), st)
+	ev, err := st.Create("/foo", false, "bar", false, v2store.TTLOptionSet{ExpireTime: v2store.Permanent})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waiting from the index of the create returns it at once
+	req := httptest.NewRequest(http.MethodGet, "/v2/keys/foo?wait=true&waitIndex="+strconv.FormatUint(ev.EtcdIndex, 10), nil)
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("code = %d, want %d", rw.Code, http.StatusOK)
+	}
+	var wev v2store.Event
+	if err := json.Unmarshal(rw.Body.Bytes(), &wev); err != nil {
+		t.Fatal(err)
+	}
+	if wev.Action != "create" || wev.Node.Key != "/foo" {
+		t.Errorf("unexpected event %+v", wev)
+	}
+}
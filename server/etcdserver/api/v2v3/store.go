@@ -0,0 +1,543 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2v3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2error"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2store"
+)
+
+// maxPathDepth is the max number of path components of a node.
+const maxPathDepth = 63
+
+var errUnsupported = errors.New("v2v3: operation not supported by the v2 emulation layer")
+
+type v2v3Store struct {
+	c *clientv3.Client
+	// pfx is the v3 key prefix holding the v2 nodes.
+	pfx string
+	ctx context.Context
+}
+
+// NewStore returns a v2store.Store that keeps its nodes under the pfx
+// prefix of the v3 key space using the given client.
+// Save, Recovery, Clone and SaveNoCopy are not supported.
+func NewStore(c *clientv3.Client, pfx string) v2store.Store {
+	return &v2v3Store{c: c, pfx: strings.TrimSuffix(pfx, "/"), ctx: c.Ctx()}
+}
+
+func (s *v2v3Store) Version() int { return 2 }
+
+func (s *v2v3Store) Index() uint64 {
+	resp, err := s.c.Get(s.ctx, s.actKey(), clientv3.WithCountOnly())
+	if err != nil {
+		return 0
+	}
+	return uint64(resp.Header.Revision)
+}
+
+func (s *v2v3Store) Get(nodePath string, recursive, sorted bool) (*v2store.Event, error) {
+	p := cleanPath(nodePath)
+	if p == "/" {
+		resp, err := s.c.Get(s.ctx, s.childrenPrefix(p), clientv3.WithPrefix())
+		if err != nil {
+			return nil, err
+		}
+		n := &v2store.NodeExtern{Key: "/", Dir: true}
+		if err = s.loadDir(n, resp, recursive); err != nil {
+			return nil, err
+		}
+		return &v2store.Event{Action: v2store.Get, Node: n, EtcdIndex: uint64(resp.Header.Revision)}, nil
+	}
+
+	r, err := s.read(p)
+	if err != nil {
+		return nil, err
+	}
+	if r.kv == nil {
+		return nil, v2error.NewError(v2error.EcodeKeyNotFound, p, r.rev)
+	}
+	n := s.mkNode(r.kv)
+	if n.Dir {
+		resp, err := s.c.Get(s.ctx, s.childrenPrefix(p), clientv3.WithPrefix(), clientv3.WithRev(int64(r.rev)))
+		if err != nil {
+			return nil, err
+		}
+		if err = s.loadDir(n, resp, recursive); err != nil {
+			return nil, err
+		}
+	}
+	return &v2store.Event{Action: v2store.Get, Node: n, EtcdIndex: r.rev}, nil
+}
+
+// loadDir adds the children listed by resp to the directory node n.
+// Children are sorted by key since v3 ranges are sorted.
+func (s *v2v3Store) loadDir(n *v2store.NodeExtern, resp *clientv3.GetResponse, recursive bool) error {
+	for _, kv := range resp.Kvs {
+		child := s.mkNode(kv)
+		if strings.HasPrefix(path.Base(child.Key), "_") {
+			// hidden node
+			continue
+		}
+		if child.Dir && recursive {
+			cresp, err := s.c.Get(s.ctx, s.childrenPrefix(child.Key), clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision))
+			if err != nil {
+				return err
+			}
+			if err = s.loadDir(child, cresp, true); err != nil {
+				return err
+			}
+		}
+		n.Nodes = append(n.Nodes, child)
+	}
+	return nil
+}
+
+func (s *v2v3Store) Set(nodePath string, dir bool, value string, expireOpts v2store.TTLOptionSet) (*v2store.Event, error) {
+	return s.put(v2store.Set, nodePath, dir, func(r *readResult) (string, error) {
+		if r.kv != nil && r.isDir() {
+			return "", v2error.NewError(v2error.EcodeNotFile, r.path, r.rev)
+		}
+		if expireOpts.Refresh {
+			if r.kv == nil {
+				return "", v2error.NewError(v2error.EcodeKeyNotFound, r.path, r.rev)
+			}
+			return string(r.kv.Value), nil
+		}
+		return value, nil
+	}, expireOpts)
+}
+
+func (s *v2v3Store) Create(nodePath string, dir bool, value string, unique bool, expireOpts v2store.TTLOptionSet) (*v2store.Event, error) {
+	if unique {
+		return s.createUnique(nodePath, dir, value, expireOpts)
+	}
+	return s.put(v2store.Create, nodePath, dir, func(r *readResult) (string, error) {
+		if r.kv != nil {
+			return "", v2error.NewError(v2error.EcodeNodeExist, r.path, r.rev)
+		}
+		return value, nil
+	}, expireOpts)
+}
+
+// createUnique creates an in-order node named after the next index in the
+// directory at nodePath.
+func (s *v2v3Store) createUnique(nodePath string, dir bool, value string, expireOpts v2store.TTLOptionSet) (*v2store.Event, error) {
+	for {
+		resp, err := s.c.Get(s.ctx, s.actKey(), clientv3.WithCountOnly())
+		if err != nil {
+			return nil, err
+		}
+		p := path.Join(cleanPath(nodePath), fmt.Sprintf("%020d", resp.Header.Revision+1))
+		ev, err := s.put(v2store.Create, p, dir, func(r *readResult) (string, error) {
+			if r.kv != nil {
+				return "", v2error.NewError(v2error.EcodeNodeExist, r.path, r.rev)
+			}
+			return value, nil
+		}, expireOpts)
+		if verr, ok := err.(*v2error.Error); ok && verr.ErrorCode == v2error.EcodeNodeExist {
+			// lost the race for the name against another creator
+			continue
+		}
+		return ev, err
+	}
+}
+
+func (s *v2v3Store) Update(nodePath string, newValue string, expireOpts v2store.TTLOptionSet) (*v2store.Event, error) {
+	return s.put(v2store.Update, nodePath, false, func(r *readResult) (string, error) {
+		if r.kv == nil {
+			return "", v2error.NewError(v2error.EcodeKeyNotFound, r.path, r.rev)
+		}
+		if r.isDir() {
+			if newValue != "" {
+				return "", v2error.NewError(v2error.EcodeNotFile, r.path, r.rev)
+			}
+			return "", nil
+		}
+		if expireOpts.Refresh {
+			return string(r.kv.Value), nil
+		}
+		return newValue, nil
+	}, expireOpts)
+}
+
+func (s *v2v3Store) CompareAndSwap(nodePath string, prevValue string, prevIndex uint64, value string, expireOpts v2store.TTLOptionSet) (*v2store.Event, error) {
+	return s.put(v2store.CompareAndSwap, nodePath, false, func(r *readResult) (string, error) {
+		if err := r.compare(prevValue, prevIndex); err != nil {
+			return "", err
+		}
+		if expireOpts.Refresh {
+			return string(r.kv.Value), nil
+		}
+		return value, nil
+	}, expireOpts)
+}
+
+// put writes the node at nodePath with the value returned by check, which
+// validates the current node. A directory keeps its kind on update. Missing
+// parent directories are created. The write is retried if the node or its
+// parents changed between the read and the write.
+func (s *v2v3Store) put(action string, nodePath string, dir bool, check func(r *readResult) (string, error), expireOpts v2store.TTLOptionSet) (*v2store.Event, error) {
+	p := cleanPath(nodePath)
+	if p == "/" {
+		return nil, v2error.NewError(v2error.EcodeRootROnly, "/", s.Index())
+	}
+	if strings.Count(p, "/") > maxPathDepth {
+		return nil, v2error.NewError(v2error.EcodeInvalidField, p, s.Index())
+	}
+
+	var leaseID clientv3.LeaseID
+	var ttl int64
+	if !expireOpts.ExpireTime.IsZero() {
+		ttl = int64(time.Until(expireOpts.ExpireTime).Round(time.Second) / time.Second)
+		if ttl <= 0 {
+			ttl = 1
+		}
+		lresp, err := s.c.Grant(s.ctx, ttl)
+		if err != nil {
+			return nil, err
+		}
+		leaseID = lresp.ID
+	}
+
+	for {
+		r, err := s.read(p)
+		if err != nil {
+			return nil, err
+		}
+		if r.parentFile != "" {
+			return nil, v2error.NewError(v2error.EcodeNotDir, r.parentFile, r.rev)
+		}
+		value, err := check(r)
+		if err != nil {
+			return nil, err
+		}
+		if r.kv != nil && action != v2store.Set {
+			// updates do not change the kind of the node
+			dir = r.isDir()
+		}
+
+		cmps := append(r.cmps(), s.parentCmps(p)...)
+		ops := s.mkParentOps(p)
+		key := s.fileKey(p)
+		if dir {
+			key = s.dirKey(p)
+			value = ""
+		}
+		if r.kv != nil && string(r.kv.Key) != key {
+			// a set replaced the file with a directory
+			ops = append(ops, clientv3.OpDelete(string(r.kv.Key)))
+		}
+		ops = append(ops, clientv3.OpPut(key, value, clientv3.WithLease(leaseID)), s.actOp(action, p))
+
+		resp, err := s.c.Txn(s.ctx).If(cmps...).Then(ops...).Commit()
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Succeeded {
+			continue
+		}
+
+		rev := resp.Header.Revision
+		n := &v2store.NodeExtern{Key: p, Dir: dir, ModifiedIndex: uint64(rev), CreatedIndex: uint64(rev)}
+		if !dir {
+			n.Value = &value
+		}
+		if r.kv != nil && string(r.kv.Key) == key {
+			n.CreatedIndex = uint64(r.kv.CreateRevision)
+		}
+		if ttl > 0 {
+			exp := time.Now().Add(time.Duration(ttl) * time.Second)
+			n.TTL, n.Expiration = ttl, &exp
+		}
+		ev := &v2store.Event{Action: action, Node: n, EtcdIndex: uint64(rev), Refresh: expireOpts.Refresh}
+		if r.kv != nil {
+			ev.PrevNode = s.mkNode(r.kv)
+		}
+		return ev, nil
+	}
+}
+
+func (s *v2v3Store) Delete(nodePath string, dir, recursive bool) (*v2store.Event, error) {
+	return s.delete(v2store.Delete, nodePath, func(r *readResult) error {
+		if r.kv == nil {
+			return v2error.NewError(v2error.EcodeKeyNotFound, r.path, r.rev)
+		}
+		if r.isDir() && !dir && !recursive {
+			return v2error.NewError(v2error.EcodeNotFile, r.path, r.rev)
+		}
+		return nil
+	}, recursive)
+}
+
+func (s *v2v3Store) CompareAndDelete(nodePath string, prevValue string, prevIndex uint64) (*v2store.Event, error) {
+	return s.delete(v2store.CompareAndDelete, nodePath, func(r *readResult) error {
+		return r.compare(prevValue, prevIndex)
+	}, false)
+}
+
+// delete removes the node at nodePath after check validated it.
+// A directory must be empty unless recursive is set.
+func (s *v2v3Store) delete(action string, nodePath string, check func(r *readResult) error, recursive bool) (*v2store.Event, error) {
+	p := cleanPath(nodePath)
+	if p == "/" {
+		return nil, v2error.NewError(v2error.EcodeRootROnly, "/", s.Index())
+	}
+	for {
+		r, err := s.read(p)
+		if err != nil {
+			return nil, err
+		}
+		if err = check(r); err != nil {
+			return nil, err
+		}
+
+		cmps := r.cmps()
+		ops := []clientv3.Op{clientv3.OpDelete(string(r.kv.Key))}
+		if r.isDir() {
+			depth := strings.Count(p, "/") + 1
+			for ; depth <= maxPathDepth+1; depth++ {
+				cpfx := s.descendantsPrefix(p, depth)
+				cresp, err := s.c.Get(s.ctx, cpfx, clientv3.WithPrefix(), clientv3.WithCountOnly(), clientv3.WithRev(int64(r.rev)))
+				if err != nil {
+					return nil, err
+				}
+				if cresp.Count == 0 {
+					break
+				}
+				if !recursive {
+					return nil, v2error.NewError(v2error.EcodeDirNotEmpty, p, r.rev)
+				}
+				ops = append(ops, clientv3.OpDelete(cpfx, clientv3.WithPrefix()))
+			}
+			// no node was created below the deepest level seen
+			cmps = append(cmps, clientv3.Compare(clientv3.Version(s.descendantsPrefix(p, depth)).WithPrefix(), "=", 0))
+		}
+		ops = append(ops, s.actOp(action, p))
+
+		resp, err := s.c.Txn(s.ctx).If(cmps...).Then(ops...).Commit()
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Succeeded {
+			continue
+		}
+
+		rev := resp.Header.Revision
+		n := &v2store.NodeExtern{Key: p, Dir: r.isDir(), ModifiedIndex: uint64(rev), CreatedIndex: uint64(r.kv.CreateRevision)}
+		return &v2store.Event{Action: action, Node: n, PrevNode: s.mkNode(r.kv), EtcdIndex: uint64(rev)}, nil
+	}
+}
+
+func (s *v2v3Store) Watch(prefix string, recursive, stream bool, sinceIndex uint64) (v2store.Watcher, error) {
+	return s.newWatcher(cleanPath(prefix), recursive, stream, sinceIndex)
+}
+
+func (s *v2v3Store) Save() ([]byte, error)       { return nil, errUnsupported }
+func (s *v2v3Store) Recovery(state []byte) error { return errUnsupported }
+func (s *v2v3Store) Clone() v2store.Store        { return s }
+func (s *v2v3Store) SaveNoCopy() ([]byte, error) { return nil, errUnsupported }
+func (s *v2v3Store) JsonStats() []byte           { return []byte("{}") }
+func (s *v2v3Store) DeleteExpiredKeys(time.Time) {}
+func (s *v2v3Store) HasTTLKeys() bool            { return false }
+
+// readResult is the state of a node and its parents at a revision.
+type readResult struct {
+	path string
+	rev  uint64
+	// kv is the file or directory key of the node, nil if it does not exist.
+	kv *mvccpb.KeyValue
+	// parentFile is the path of a parent that is a file, if any.
+	parentFile string
+
+	fileKey, dirKey string
+}
+
+func (r *readResult) isDir() bool { return strings.HasSuffix(string(r.kv.Key), "/") }
+
+// cmps returns the conditions for the node to be unchanged since the read.
+func (r *readResult) cmps() []clientv3.Cmp {
+	var fileRev, dirRev int64
+	if r.kv != nil {
+		if r.isDir() {
+			dirRev = r.kv.ModRevision
+		} else {
+			fileRev = r.kv.ModRevision
+		}
+	}
+	return []clientv3.Cmp{
+		clientv3.Compare(clientv3.ModRevision(r.fileKey), "=", fileRev),
+		clientv3.Compare(clientv3.ModRevision(r.dirKey), "=", dirRev),
+	}
+}
+
+func (r *readResult) compare(prevValue string, prevIndex uint64) error {
+	if r.kv == nil {
+		return v2error.NewError(v2error.EcodeKeyNotFound, r.path, r.rev)
+	}
+	if r.isDir() {
+		return v2error.NewError(v2error.EcodeNotFile, r.path, r.rev)
+	}
+	var causes []string
+	if prevValue != "" && prevValue != string(r.kv.Value) {
+		causes = append(causes, fmt.Sprintf("[%v != %v]", prevValue, string(r.kv.Value)))
+	}
+	if prevIndex != 0 && prevIndex != uint64(r.kv.ModRevision) {
+		causes = append(causes, fmt.Sprintf("[%v != %v]", prevIndex, r.kv.ModRevision))
+	}
+	if len(causes) != 0 {
+		return v2error.NewError(v2error.EcodeTestFailed, strings.Join(causes, " "), r.rev)
+	}
+	return nil
+}
+
+// read reads the node at p and the file keys of its parents at one revision.
+func (s *v2v3Store) read(p string) (*readResult, error) {
+	r := &readResult{path: p, fileKey: s.fileKey(p), dirKey: s.dirKey(p)}
+	parents := parentPaths(p)
+	ops := []clientv3.Op{clientv3.OpGet(r.fileKey), clientv3.OpGet(r.dirKey)}
+	for _, pp := range parents {
+		ops = append(ops, clientv3.OpGet(s.fileKey(pp), clientv3.WithCountOnly()))
+	}
+	resp, err := s.c.Txn(s.ctx).Then(ops...).Commit()
+	if err != nil {
+		return nil, err
+	}
+	r.rev = uint64(resp.Header.Revision)
+	for _, rr := range resp.Responses[:2] {
+		if kvs := rr.GetResponseRange().Kvs; len(kvs) != 0 {
+			r.kv = kvs[0]
+		}
+	}
+	for i, rr := range resp.Responses[2:] {
+		if rr.GetResponseRange().Count != 0 {
+			r.parentFile = parents[i]
+			break
+		}
+	}
+	return r, nil
+}
+
+// parentCmps returns the conditions for the parents of p not to be files.
+func (s *v2v3Store) parentCmps(p string) []clientv3.Cmp {
+	var cmps []clientv3.Cmp
+	for _, pp := range parentPaths(p) {
+		cmps = append(cmps, clientv3.Compare(clientv3.Version(s.fileKey(pp)), "=", 0))
+	}
+	return cmps
+}
+
+// mkParentOps returns the operations creating the missing parents of p.
+func (s *v2v3Store) mkParentOps(p string) []clientv3.Op {
+	var ops []clientv3.Op
+	for _, pp := range parentPaths(p) {
+		dk := s.dirKey(pp)
+		ops = append(ops, clientv3.OpTxn(
+			[]clientv3.Cmp{clientv3.Compare(clientv3.Version(dk), "=", 0)},
+			[]clientv3.Op{clientv3.OpPut(dk, "")},
+			nil,
+		))
+	}
+	return ops
+}
+
+func (s *v2v3Store) actOp(action, p string) clientv3.Op {
+	return clientv3.OpPut(s.actKey(), action+" "+p)
+}
+
+// mkNode converts a node key to a v2 node without children.
+func (s *v2v3Store) mkNode(kv *mvccpb.KeyValue) *v2store.NodeExtern {
+	p, dir := s.nodePath(string(kv.Key))
+	n := &v2store.NodeExtern{
+		Key:           p,
+		Dir:           dir,
+		ModifiedIndex: uint64(kv.ModRevision),
+		CreatedIndex:  uint64(kv.CreateRevision),
+	}
+	if !dir {
+		v := string(kv.Value)
+		n.Value = &v
+	}
+	if kv.Lease != 0 {
+		if resp, err := s.c.TimeToLive(s.ctx, clientv3.LeaseID(kv.Lease)); err == nil && resp.TTL >= 0 {
+			// like v2store, round the remaining time of a live node up
+			ttl := resp.TTL
+			if ttl == 0 {
+				ttl = 1
+			}
+			exp := time.Now().Add(time.Duration(ttl) * time.Second)
+			n.TTL, n.Expiration = ttl, &exp
+		}
+	}
+	return n
+}
+
+func (s *v2v3Store) actKey() string { return s.pfx + "/act" }
+
+// descendantsPrefix is the key prefix of the descendants of p at the given depth.
+func (s *v2v3Store) descendantsPrefix(p string, depth int) string {
+	if p == "/" {
+		return fmt.Sprintf("%s/%03d/k/", s.pfx, depth)
+	}
+	return fmt.Sprintf("%s/%03d/k%s/", s.pfx, depth, p)
+}
+
+func (s *v2v3Store) childrenPrefix(p string) string {
+	if p == "/" {
+		return s.descendantsPrefix(p, 1)
+	}
+	return s.descendantsPrefix(p, strings.Count(p, "/")+1)
+}
+
+func (s *v2v3Store) fileKey(p string) string {
+	return fmt.Sprintf("%s/%03d/k%s", s.pfx, strings.Count(p, "/"), p)
+}
+
+func (s *v2v3Store) dirKey(p string) string { return s.fileKey(p) + "/" }
+
+// nodePath returns the v2 path of a node key and whether it is a directory.
+func (s *v2v3Store) nodePath(key string) (p string, dir bool) {
+	// strip "<prefix>/<depth>/k"
+	p = key[len(s.pfx)+len("/000/k"):]
+	if strings.HasSuffix(p, "/") {
+		return p[:len(p)-1], true
+	}
+	return p, false
+}
+
+// isNodeKey reports whether key is a node key of s.
+func (s *v2v3Store) isNodeKey(key string) bool {
+	return len(key) > len(s.pfx)+len("/000/k") && strings.HasPrefix(key, s.pfx+"/") && key[len(s.pfx)+4:len(s.pfx)+6] == "/k"
+}
+
+func cleanPath(p string) string { return path.Clean(path.Join("/", p)) }
+
+// parentPaths returns the parent directories of p, excluding the root.
+func parentPaths(p string) []string {
+	var parents []string
+	for d := path.Dir(p); d != "/"; d = path.Dir(d) {
+		parents = append([]string{d}, parents...)
+	}
+	return parents
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2v3
+
+import (
+	"context"
+	"strings"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2error"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2store"
+)
+
+// watcherChanSize matches the event buffer of v2store watchers.
+const watcherChanSize = 100
+
+type v2v3Watcher struct {
+	startRev int64
+	evc      chan *v2store.Event
+	cancel   context.CancelFunc
+	donec    chan struct{}
+}
+
+func (s *v2v3Store) newWatcher(p string, recursive, stream bool, sinceIndex uint64) (v2store.Watcher, error) {
+	resp, err := s.c.Get(s.ctx, s.actKey(), clientv3.WithCountOnly())
+	if err != nil {
+		return nil, err
+	}
+	startRev := resp.Header.Revision
+	wrev := startRev + 1
+	if sinceIndex != 0 {
+		wrev = int64(sinceIndex)
+		if _, err = s.c.Get(s.ctx, s.actKey(), clientv3.WithCountOnly(), clientv3.WithRev(wrev)); err == rpctypes.ErrCompacted {
+			return nil, v2error.NewError(v2error.EcodeEventIndexCleared, "", uint64(startRev))
+		} else if err != nil && wrev <= startRev {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	wch := s.c.Watch(clientv3.WithRequireLeader(ctx), s.pfx+"/", clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(wrev))
+	w := &v2v3Watcher{
+		startRev: startRev,
+		evc:      make(chan *v2store.Event, watcherChanSize),
+		cancel:   cancel,
+		donec:    make(chan struct{}),
+	}
+	go func() {
+		defer func() {
+			close(w.evc)
+			close(w.donec)
+		}()
+		for wr := range wch {
+			if wr.Err() != nil {
+				return
+			}
+			for _, ev := range s.mkV2Events(wr.Events) {
+				if !matchWatch(ev, p, recursive) {
+					continue
+				}
+				select {
+				case w.evc <- ev:
+				case <-ctx.Done():
+					return
+				}
+				if !stream {
+					return
+				}
+			}
+		}
+	}()
+	return w, nil
+}
+
+func (w *v2v3Watcher) EventChan() chan *v2store.Event { return w.evc }
+
+func (w *v2v3Watcher) StartIndex() uint64 { return uint64(w.startRev) }
+
+func (w *v2v3Watcher) Remove() {
+	w.cancel()
+	<-w.donec
+}
+
+// mkV2Events converts the v3 events of a watch response to v2 events. The
+// mutation of each revision is described by its action key; deletions without
+// an action key are lease expirations.
+func (s *v2v3Store) mkV2Events(evs []*clientv3.Event) []*v2store.Event {
+	var v2evs []*v2store.Event
+	for len(evs) != 0 {
+		rev := evs[0].Kv.ModRevision
+		i := 1
+		for i < len(evs) && evs[i].Kv.ModRevision == rev {
+			i++
+		}
+		v2evs = append(v2evs, s.mkV2EventsAtRev(evs[:i])...)
+		evs = evs[i:]
+	}
+	return v2evs
+}
+
+func (s *v2v3Store) mkV2EventsAtRev(evs []*clientv3.Event) []*v2store.Event {
+	var act *clientv3.Event
+	for _, ev := range evs {
+		if string(ev.Kv.Key) == s.actKey() && ev.Type == mvccpb.PUT {
+			act = ev
+		}
+	}
+	if act == nil {
+		var v2evs []*v2store.Event
+		for _, ev := range evs {
+			if ev.Type != mvccpb.DELETE || ev.PrevKv == nil || !s.isNodeKey(string(ev.Kv.Key)) {
+				continue
+			}
+			v2evs = append(v2evs, s.mkDeleteEvent(v2store.Expire, ev))
+		}
+		return v2evs
+	}
+
+	fields := strings.SplitN(string(act.Kv.Value), " ", 2)
+	if len(fields) != 2 {
+		return nil
+	}
+	action, p := fields[0], fields[1]
+	fk, dk := s.fileKey(p), s.dirKey(p)
+	for _, ev := range evs {
+		if k := string(ev.Kv.Key); k != fk && k != dk {
+			continue
+		}
+		switch {
+		case ev.Type == mvccpb.PUT:
+			v2ev := &v2store.Event{Action: action, Node: s.mkNode(ev.Kv), EtcdIndex: uint64(ev.Kv.ModRevision)}
+			if ev.PrevKv != nil {
+				v2ev.PrevNode = s.mkNode(ev.PrevKv)
+			}
+			return []*v2store.Event{v2ev}
+		case ev.PrevKv != nil && isDeleteAction(action):
+			return []*v2store.Event{s.mkDeleteEvent(action, ev)}
+		}
+	}
+	return nil
+}
+
+func (s *v2v3Store) mkDeleteEvent(action string, ev *clientv3.Event) *v2store.Event {
+	prev := s.mkNode(ev.PrevKv)
+	n := &v2store.NodeExtern{
+		Key:           prev.Key,
+		Dir:           prev.Dir,
+		ModifiedIndex: uint64(ev.Kv.ModRevision),
+		CreatedIndex:  prev.CreatedIndex,
+	}
+	return &v2store.Event{Action: action, Node: n, PrevNode: prev, EtcdIndex: uint64(ev.Kv.ModRevision)}
+}
+
+func isDeleteAction(action string) bool {
+	return action == v2store.Delete || action == v2store.CompareAndDelete || action == v2store.Expire
+}
+
+// matchWatch reports whether a watcher on p is notified of ev, following
+// the v2store rules: recursive watchers skip hidden nodes below the watched
+// path and a deleted directory also notifies the watchers of its descendants.
+func matchWatch(ev *v2store.Event, p string, recursive bool) bool {
+	k := ev.Node.Key
+	switch {
+	case k == p:
+		return true
+	case recursive && isDescendant(k, p):
+		return !strings.Contains(strings.TrimPrefix(k, p), "/_")
+	case isDeleteAction(ev.Action) && isDescendant(p, k):
+		return true
+	}
+	return false
+}
+
+// isDescendant reports whether p is below the directory dir.
+func isDescendant(p, dir string) bool {
+	if dir == "/" {
+		return p != "/"
+	}
+	return strings.HasPrefix(p, dir+"/")
+}
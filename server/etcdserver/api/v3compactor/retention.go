@@ -0,0 +1,164 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3compactor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// RetentionRule retains the history of the keys with the prefix on
+// compaction: the latest Versions versions of each key, or the history of
+// the last Period.
+type RetentionRule struct {
+	Prefix   string
+	Versions int64
+	Period   time.Duration
+}
+
+// ParseRetentionRules parses the history retention rules "<prefix>=<versions>"
+// or "<prefix>=<period>", e.g. "audit/=5" or "audit/=24h".
+func ParseRetentionRules(rules []string) ([]RetentionRule, error) {
+	var rs []RetentionRule
+	seen := make(map[string]struct{})
+	for _, rule := range rules {
+		i := strings.LastIndex(rule, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid history retention rule %q, want <prefix>=<versions> or <prefix>=<period>", rule)
+		}
+		r := RetentionRule{Prefix: rule[:i]}
+		if _, ok := seen[r.Prefix]; ok {
+			return nil, fmt.Errorf("duplicate history retention rule for the prefix %q", r.Prefix)
+		}
+		seen[r.Prefix] = struct{}{}
+		v := rule[i+1:]
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid history retention rule %q, the versions must be > 0", rule)
+			}
+			r.Versions = n
+		} else {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid history retention rule %q, the period must be a duration > 0", rule)
+			}
+			r.Period = d
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+type revSample struct {
+	t   time.Time
+	rev int64
+}
+
+// HistoryRetention gives the retentions of the compactions from the retention
+// rules, the periods of the rules converted to the revisions of the member at
+// the beginning of the periods.
+type HistoryRetention struct {
+	clock    clockwork.Clock
+	rules    []RetentionRule
+	interval time.Duration
+	period   time.Duration
+
+	mu      sync.Mutex
+	samples []revSample
+}
+
+// NewHistoryRetention returns the HistoryRetention of the rules.
+func NewHistoryRetention(rules []RetentionRule) *HistoryRetention {
+	return newHistoryRetention(clockwork.NewRealClock(), rules)
+}
+
+func newHistoryRetention(clock clockwork.Clock, rules []RetentionRule) *HistoryRetention {
+	h := &HistoryRetention{clock: clock, rules: rules}
+	for _, r := range rules {
+		if r.Period == 0 {
+			continue
+		}
+		// the revisions are recorded for every 1/10 of the shortest period
+		if h.interval == 0 || r.Period/10 < h.interval {
+			h.interval = r.Period / 10
+		}
+		if r.Period > h.period {
+			h.period = r.Period
+		}
+	}
+	return h
+}
+
+// Run records the revisions of the member until stopc is closed, for the
+// periods of the rules to be converted to revisions.
+func (h *HistoryRetention) Run(stopc <-chan struct{}, rg RevGetter) {
+	if h.interval == 0 {
+		return
+	}
+	for {
+		h.sample(rg.Rev())
+		select {
+		case <-h.clock.After(h.interval):
+		case <-stopc:
+			return
+		}
+	}
+}
+
+func (h *HistoryRetention) sample(rev int64) {
+	now := h.clock.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = append(h.samples, revSample{t: now, rev: rev})
+	// keep the latest sample older than the longest period
+	i := 0
+	for i+1 < len(h.samples) && now.Sub(h.samples[i+1].t) >= h.period {
+		i++
+	}
+	h.samples = h.samples[i:]
+}
+
+// Retention returns the retentions of a compaction, nil for a nil
+// HistoryRetention. The keys of a rule with a period are retained from 0, i.e.
+// all their history, until a revision was recorded as long ago as the period.
+func (h *HistoryRetention) Retention() []*pb.CompactionRetention {
+	if h == nil || len(h.rules) == 0 {
+		return nil
+	}
+	now := h.clock.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rs := make([]*pb.CompactionRetention, 0, len(h.rules))
+	for _, r := range h.rules {
+		cr := &pb.CompactionRetention{Prefix: []byte(r.Prefix), Versions: r.Versions}
+		if r.Period > 0 {
+			for _, s := range h.samples {
+				if now.Sub(s.t) < r.Period {
+					break
+				}
+				cr.Revision = s.rev
+			}
+		}
+		rs = append(rs, cr)
+	}
+	return rs
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3compactor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+
+	"github.com/jonboulle/clockwork"
+)
+
+func TestParseRetentionRules(t *testing.T) {
+	rules, err := ParseRetentionRules([]string{"audit/=5", "events/=24h", "=1", "a=b=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrules := []RetentionRule{
+		{Prefix: "audit/", Versions: 5},
+		{Prefix: "events/", Period: 24 * time.Hour},
+		{Prefix: "", Versions: 1},
+		{Prefix: "a=b", Versions: 2},
+	}
+	if !reflect.DeepEqual(rules, wrules) {
+		t.Errorf("rules = %+v, want %+v", rules, wrules)
+	}
+
+	for _, rs := range [][]string{{"audit/"}, {"audit/=0"}, {"audit/=-1h"}, {"audit/=x"}, {"a=1", "a=2"}} {
+		if _, err := ParseRetentionRules(rs); err == nil {
+			t.Errorf("ParseRetentionRules(%q) error = nil, want an error", rs)
+		}
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	fc := clockwork.NewFakeClock()
+	h := newHistoryRetention(fc, []RetentionRule{{Prefix: "audit/", Versions: 5}, {Prefix: "events/", Period: time.Hour}})
+	if h.interval != 6*time.Minute {
+		t.Fatalf("interval = %v, want %v", h.interval, 6*time.Minute)
+	}
+
+	retention := func(rev int64) []*pb.CompactionRetention {
+		return []*pb.CompactionRetention{
+			{Prefix: []byte("audit/"), Versions: 5},
+			{Prefix: []byte("events/"), Revision: rev},
+		}
+	}
+	// all the history is retained until a revision was recorded an hour ago
+	h.sample(10)
+	fc.Advance(30 * time.Minute)
+	h.sample(20)
+	if rs := h.Retention(); !reflect.DeepEqual(rs, retention(0)) {
+		t.Fatalf("retention = %+v, want %+v", rs, retention(0))
+	}
+	fc.Advance(30 * time.Minute)
+	h.sample(30)
+	if rs := h.Retention(); !reflect.DeepEqual(rs, retention(10)) {
+		t.Fatalf("retention = %+v, want %+v", rs, retention(10))
+	}
+	fc.Advance(40 * time.Minute)
+	h.sample(40)
+	if rs := h.Retention(); !reflect.DeepEqual(rs, retention(20)) {
+		t.Fatalf("retention = %+v, want %+v", rs, retention(20))
+	}
+	if len(h.samples) != 3 {
+		t.Errorf("samples = %+v, want the samples of the last hour and the one before", h.samples)
+	}
+
+	if rs := (*HistoryRetention)(nil).Retention(); rs != nil {
+		t.Errorf("retention of nil = %+v, want nil", rs)
+	}
+}
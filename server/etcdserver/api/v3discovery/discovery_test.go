@@ -762,6 +762,10 @@ func (fw *fakeBaseWatcher) RequestProgress(ctx context.Context) error {
 	return nil
 }
 
+func (fw *fakeBaseWatcher) RequestWatchProgress(ctx context.Context, wch clientv3.WatchChan) error {
+	return nil
+}
+
 func (fw *fakeBaseWatcher) Close() error {
 	return nil
 }
@@ -16,12 +16,38 @@ package v3rpc
 
 import "github.com/golang/protobuf/proto"
 
-type codec struct{}
+type codec struct {
+	compression *compressionPolicy
+}
+
+// sizedMarshaler is implemented by the gogoproto generated messages.
+type sizedMarshaler interface {
+	Size() int
+	MarshalToSizedBuffer([]byte) (int, error)
+}
 
+// Marshal encodes v followed by its compression mark, see compressionPolicy.
 func (c *codec) Marshal(v interface{}) ([]byte, error) {
-	b, err := proto.Marshal(v.(proto.Message))
+	var b []byte
+	if m, ok := v.(sizedMarshaler); ok {
+		size := m.Size()
+		b = make([]byte, size, size+1)
+		if _, err := m.MarshalToSizedBuffer(b); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if b, err = proto.Marshal(v.(proto.Message)); err != nil {
+			return nil, err
+		}
+	}
+	mark := markCompress
+	if !c.compression.compress(v, len(b)) {
+		mark = markStore
+	}
+	b = append(b, mark)[:len(b)]
 	sentBytes.Add(float64(len(b)))
-	return b, err
+	return b, nil
 }
 
 func (c *codec) Unmarshal(data []byte, v interface{}) error {
@@ -0,0 +1,273 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// gRPC responses are compressed with the compressor of the request, so the
+// server compresses responses only for clients that compress their requests
+// with one of the registered compressors, e.g. with grpc.UseCompressor("zstd"),
+// unless it is set to compress all the responses with a response compressor.
+//
+// grpc-go chooses the compressor of an RPC before calling its handler, and a
+// compressor only sees the encoded messages. The server codec therefore tells
+// the compressors which responses should not be compressed by a mark byte
+// right after the end of the encoded message, within its capacity. Those
+// responses are sent with the format of the compressor but without
+// compression, which is valid for any client at little cost.
+const (
+	markCompress byte = 0
+	markStore    byte = 0xc5
+)
+
+const (
+	// DefaultGRPCCompressionMinBytes is the default minimum size of the
+	// responses compressed by the server.
+	DefaultGRPCCompressionMinBytes = 1024
+
+	// zstdMaxBlockSize is the maximum size of a zstd block.
+	zstdMaxBlockSize = 128 * 1024
+)
+
+var compressors = map[string]*storingCompressor{
+	"gzip": {name: "gzip", encode: encodeGzip},
+	"zstd": {name: "zstd", encode: encodeZstd},
+}
+
+func init() {
+	for _, c := range compressors {
+		encoding.RegisterCompressor(c)
+	}
+}
+
+// ResponseCompressor returns the compressor of all the responses of the
+// server, "gzip" or "zstd", or nil if name is empty so that the responses are
+// compressed with the compressor of the request. All the clients must then be
+// able to decompress the responses, whatever the compressor of their requests.
+func ResponseCompressor(name string) (grpc.Compressor, error) {
+	if name == "" {
+		return nil, nil
+	}
+	c, ok := compressors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown compressor %q", name)
+	}
+	return c, nil
+}
+
+// compressionPolicy decides which responses the server compresses.
+type compressionPolicy struct {
+	// minBytes is the minimum size of a compressed response.
+	minBytes int
+	// responses are the names of the response messages of the RPCs that
+	// may be compressed, all RPCs when nil.
+	responses map[string]struct{}
+}
+
+func (p *compressionPolicy) compress(msg interface{}, size int) bool {
+	if p == nil {
+		return true
+	}
+	if size < p.minBytes {
+		return false
+	}
+	if p.responses == nil {
+		return true
+	}
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return false
+	}
+	_, ok = p.responses[proto.MessageName(m)]
+	return ok
+}
+
+// CompressibleResponses returns the names of the response messages of the
+// given etcd RPCs, e.g. "Range" or "Snapshot", or nil if rpcs is empty.
+func CompressibleResponses(rpcs []string) (map[string]struct{}, error) {
+	if len(rpcs) == 0 {
+		return nil, nil
+	}
+	fd, err := protoregistry.GlobalFiles.FindFileByPath("rpc.proto")
+	if err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]string)
+	svcs := fd.Services()
+	for i := 0; i < svcs.Len(); i++ {
+		methods := svcs.Get(i).Methods()
+		for j := 0; j < methods.Len(); j++ {
+			outputs[string(methods.Get(j).Name())] = string(methods.Get(j).Output().FullName())
+		}
+	}
+	responses := make(map[string]struct{}, len(rpcs))
+	for _, rpc := range rpcs {
+		n, ok := outputs[rpc]
+		if !ok {
+			return nil, fmt.Errorf("unknown RPC %q", rpc)
+		}
+		responses[n] = struct{}{}
+	}
+	return responses, nil
+}
+
+// storingCompressor compresses messages with encode, which stores the
+// messages marked by the server codec without compressing them.
+type storingCompressor struct {
+	name     string
+	encode   func(w io.Writer, p []byte, store bool) error
+	decoders sync.Pool
+}
+
+func (c *storingCompressor) Name() string { return c.name }
+
+// Type and Do implement the legacy grpc.Compressor, which grpc-go uses for
+// all the responses instead of the compressor of the request.
+func (c *storingCompressor) Type() string { return c.name }
+
+func (c *storingCompressor) Do(w io.Writer, p []byte) error {
+	return c.encode(w, p, isMarkedStore(p))
+}
+
+func (c *storingCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return &storingWriter{c: c, w: w}, nil
+}
+
+func (c *storingCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	if c.name == "gzip" {
+		return gzip.NewReader(r)
+	}
+	d, ok := c.decoders.Get().(*zstd.Decoder)
+	if !ok {
+		var err error
+		if d, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1)); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.Reset(r); err != nil {
+		c.decoders.Put(d)
+		return nil, err
+	}
+	return &zstdReader{d: d, pool: &c.decoders}, nil
+}
+
+// storingWriter buffers a message until it can decide whether to compress it.
+type storingWriter struct {
+	c     *storingCompressor
+	w     io.Writer
+	buf   bytes.Buffer
+	store bool
+}
+
+func (w *storingWriter) Write(p []byte) (int, error) {
+	if isMarkedStore(p) {
+		w.store = true
+	}
+	return w.buf.Write(p)
+}
+
+func (w *storingWriter) Close() error {
+	return w.c.encode(w.w, w.buf.Bytes(), w.store)
+}
+
+// isMarkedStore returns whether the server codec marked the message p not to
+// be compressed.
+func isMarkedStore(p []byte) bool {
+	return cap(p) > len(p) && p[:len(p)+1][len(p)] == markStore
+}
+
+func encodeGzip(w io.Writer, p []byte, store bool) error {
+	level := gzip.DefaultCompression
+	if store {
+		level = gzip.NoCompression
+	}
+	zw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return err
+	}
+	if _, err = zw.Write(p); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+var zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+
+func encodeZstd(w io.Writer, p []byte, store bool) error {
+	var frame []byte
+	if store {
+		frame = appendZstdRawFrame(nil, p)
+	} else {
+		frame = zstdEncoder.EncodeAll(p, nil)
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// appendZstdRawFrame appends a zstd frame holding p in raw blocks.
+func appendZstdRawFrame(dst, p []byte) []byte {
+	// magic number, then a single segment frame header with an 8 bytes
+	// content size and no checksum
+	dst = append(dst, 0x28, 0xb5, 0x2f, 0xfd, 0xe0)
+	dst = append(dst, make([]byte, 8)...)
+	binary.LittleEndian.PutUint64(dst[len(dst)-8:], uint64(len(p)))
+	for {
+		n := len(p)
+		if n > zstdMaxBlockSize {
+			n = zstdMaxBlockSize
+		}
+		// block header: last block flag, raw block type and block size
+		hdr := uint32(n) << 3
+		if n == len(p) {
+			hdr |= 1
+		}
+		dst = append(dst, byte(hdr), byte(hdr>>8), byte(hdr>>16))
+		dst = append(dst, p[:n]...)
+		if p = p[n:]; len(p) == 0 {
+			return dst
+		}
+	}
+}
+
+type zstdReader struct {
+	d    *zstd.Decoder
+	pool *sync.Pool
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.d == nil {
+		return 0, io.EOF
+	}
+	n, err := r.d.Read(p)
+	if err == io.EOF {
+		r.d.Reset(nil)
+		r.pool.Put(r.d)
+		r.d = nil
+	}
+	return n, err
+}
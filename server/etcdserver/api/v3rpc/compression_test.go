@@ -0,0 +1,124 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCompressibleResponses(t *testing.T) {
+	responses, err := CompressibleResponses([]string{"Range", "Compact", "Snapshot", "Watch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []string{"etcdserverpb.RangeResponse", "etcdserverpb.CompactionResponse", "etcdserverpb.SnapshotResponse", "etcdserverpb.WatchResponse"} {
+		if _, ok := responses[n]; !ok {
+			t.Errorf("missing %s in %v", n, responses)
+		}
+	}
+	if responses, err = CompressibleResponses(nil); err != nil || responses != nil {
+		t.Errorf("CompressibleResponses(nil) = %v, %v; want nil, nil", responses, err)
+	}
+	if _, err = CompressibleResponses([]string{"Get"}); err == nil {
+		t.Error("expected error for unknown RPC")
+	}
+}
+
+func TestCodecCompression(t *testing.T) {
+	responses, err := CompressibleResponses([]string{"Range"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &codec{compression: &compressionPolicy{minBytes: 1024, responses: responses}}
+
+	large := &pb.RangeResponse{Header: &pb.ResponseHeader{Revision: 1}}
+	for i := 0; i < 100; i++ {
+		large.Kvs = append(large.Kvs, &mvccpb.KeyValue{Key: []byte("foo"), Value: []byte(strings.Repeat("bar", 10))})
+	}
+	small := &pb.RangeResponse{Header: &pb.ResponseHeader{Revision: 1}}
+
+	tests := []struct {
+		name      string
+		msg       interface{}
+		wcompress bool
+	}{
+		{"large range", large, true},
+		{"small range", small, false},
+		{"not allowed RPC", &pb.DeleteRangeResponse{Header: &pb.ResponseHeader{}, PrevKvs: large.Kvs}, false},
+	}
+	for _, tt := range tests {
+		for _, name := range []string{"gzip", "zstd"} {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				b, err := c.Marshal(tt.msg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				comp := encoding.GetCompressor(name)
+				var buf bytes.Buffer
+				w, err := comp.Compress(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err = w.Write(b); err != nil {
+					t.Fatal(err)
+				}
+				if err = w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				if compressed := buf.Len() < len(b); compressed != tt.wcompress {
+					t.Errorf("compressed = %v (%d into %d bytes), want %v", compressed, len(b), buf.Len(), tt.wcompress)
+				}
+
+				r, err := comp.Decompress(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				d, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(d, b) {
+					t.Errorf("decompressed %d bytes, want %d", len(d), len(b))
+				}
+			})
+		}
+	}
+}
+
+func TestAppendZstdRawFrameMultipleBlocks(t *testing.T) {
+	p := bytes.Repeat([]byte("a"), 2*zstdMaxBlockSize+1)
+	var buf bytes.Buffer
+	if err := encodeZstd(&buf, p, true); err != nil {
+		t.Fatal(err)
+	}
+	r, err := encoding.GetCompressor("zstd").Decompress(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(d, p) {
+		t.Errorf("decompressed %d bytes, want %d", len(d), len(p))
+	}
+}
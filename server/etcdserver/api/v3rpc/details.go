@@ -0,0 +1,115 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.etcd.io/etcd/server/v3/storage"
+	"go.etcd.io/etcd/server/v3/storage/mvcc"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// tooManyRequestsRetryDelay is the delay advised to retry the requests
+// rejected while the apply lags behind the commit, for a part of the
+// backlog to be applied.
+const tooManyRequestsRetryDelay = 500 * time.Millisecond
+
+// withErrorDetails attaches the google.rpc error details of the common
+// failures to the gRPC error err, for the clients to handle them without
+// matching their messages.
+func withErrorDetails(s *etcdserver.EtcdServer, err error) error {
+	var (
+		reason  string
+		md      map[string]string
+		details []proto.Message
+	)
+	switch err {
+	case rpctypes.ErrGRPCNoSpace:
+		quota, size := quotaBackendBytes(s.Cfg.QuotaBackendBytes), s.Backend().Size()
+		reason = rpctypes.ReasonNoSpace
+		md = map[string]string{
+			rpctypes.MetadataQuotaBytes:       strconv.FormatInt(quota, 10),
+			rpctypes.MetadataBackendSizeBytes: strconv.FormatInt(size, 10),
+		}
+		details = append(details, &errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     "backend",
+			Description: fmt.Sprintf("the request exceeds the backend quota of %d bytes, the backend size being %d bytes", quota, size),
+		}}})
+	case rpctypes.ErrTooManyRequests:
+		reason = rpctypes.ReasonTooManyRequests
+		md = map[string]string{
+			rpctypes.MetadataAppliedIndex:   strconv.FormatUint(s.AppliedIndex(), 10),
+			rpctypes.MetadataCommittedIndex: strconv.FormatUint(s.CommittedIndex(), 10),
+		}
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(tooManyRequestsRetryDelay)})
+	case rpctypes.ErrGRPCPermissionDenied:
+		reason = rpctypes.ReasonPermissionDenied
+	case rpctypes.ErrGRPCCompacted:
+		txn := s.KV().Read(mvcc.ConcurrentReadTxMode, traceutil.TODO())
+		compactRev, rev := txn.FirstRev(), txn.Rev()
+		txn.End()
+		reason = rpctypes.ReasonCompacted
+		md = map[string]string{
+			rpctypes.MetadataCompactRevision: strconv.FormatInt(compactRev, 10),
+			rpctypes.MetadataCurrentRevision: strconv.FormatInt(rev, 10),
+		}
+	default:
+		return err
+	}
+
+	details = append([]proto.Message{&errdetails.ErrorInfo{Reason: reason, Domain: rpctypes.ErrorDomain, Metadata: md}}, details...)
+	ev, _ := status.FromError(err)
+	dev, derr := ev.WithDetails(details...)
+	if derr != nil {
+		return err
+	}
+	return dev.Err()
+}
+
+// watchStreamExpiredError returns ErrGRPCWatchStreamExpired with its error
+// details, at the current revision rev.
+func watchStreamExpiredError(rev int64) error {
+	ev, _ := status.FromError(rpctypes.ErrGRPCWatchStreamExpired)
+	dev, err := ev.WithDetails(
+		&errdetails.ErrorInfo{
+			Reason:   rpctypes.ReasonWatchStreamExpired,
+			Domain:   rpctypes.ErrorDomain,
+			Metadata: map[string]string{rpctypes.MetadataCurrentRevision: strconv.FormatInt(rev, 10)},
+		},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(0)},
+	)
+	if err != nil {
+		return rpctypes.ErrGRPCWatchStreamExpired
+	}
+	return dev.Err()
+}
+
+// quotaBackendBytes returns the backend quota of the configuration cfg.
+func quotaBackendBytes(cfg int64) int64 {
+	if cfg == 0 {
+		return storage.DefaultQuotaBytes
+	}
+	return cfg
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+
+package v3rpc
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/testutil"
+)
+
+// FuzzTxnRequest validates an arbitrary txn request as the kv server does
+// before applying it.
+func FuzzTxnRequest(f *testing.F) {
+	put := &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar")}}}
+	del := &pb.RequestOp{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: &pb.DeleteRangeRequest{Key: []byte("a"), RangeEnd: []byte("z")}}}
+	get := &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte("foo")}}}
+	nested := &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: &pb.TxnRequest{Success: []*pb.RequestOp{put}, Failure: []*pb.RequestOp{del}}}}
+	for _, r := range []*pb.TxnRequest{
+		{},
+		{
+			Compare: []*pb.Compare{{Key: []byte("foo"), Target: pb.Compare_VALUE, TargetUnion: &pb.Compare_Value{Value: []byte("bar")}}},
+			Success: []*pb.RequestOp{put, get},
+			Failure: []*pb.RequestOp{del},
+		},
+		{Success: []*pb.RequestOp{nested, del}},
+	} {
+		b, err := r.Marshal()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b, uint8(128))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, maxTxnOps uint8) {
+		r := &pb.TxnRequest{}
+		if err := r.Unmarshal(data); err != nil {
+			return
+		}
+		testutil.AssertNoRuntimePanic(t, func() {
+			if err := checkTxnRequest(r, int(maxTxnOps)); err != nil {
+				return
+			}
+			if _, _, err := checkIntervals(r.Success); err != nil {
+				return
+			}
+			checkIntervals(r.Failure)
+		})
+	})
+}
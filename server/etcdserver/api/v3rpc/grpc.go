@@ -37,7 +37,16 @@ const (
 
 func Server(s *etcdserver.EtcdServer, tls *tls.Config, interceptor grpc.UnaryServerInterceptor, gopts ...grpc.ServerOption) *grpc.Server {
 	var opts []grpc.ServerOption
-	opts = append(opts, grpc.CustomCodec(&codec{}))
+	// the RPC names are validated by the embed config
+	responses, _ := CompressibleResponses(s.Cfg.ExperimentalGRPCCompressionRPCs)
+	opts = append(opts, grpc.CustomCodec(&codec{compression: &compressionPolicy{
+		minBytes:  s.Cfg.ExperimentalGRPCCompressionMinBytes,
+		responses: responses,
+	}}))
+	// the compressor name is validated by the embed config
+	if cp, _ := ResponseCompressor(s.Cfg.ExperimentalGRPCResponseCompression); cp != nil {
+		opts = append(opts, grpc.RPCCompressor(cp))
+	}
 	if tls != nil {
 		bundle := credentials.NewBundle(credentials.Config{TLSConfig: tls})
 		opts = append(opts, grpc.Creds(bundle.TransportCredentials()))
@@ -56,12 +65,29 @@ func Server(s *etcdserver.EtcdServer, tls *tls.Config, interceptor grpc.UnarySer
 		grpc_prometheus.StreamServerInterceptor,
 	}
 
+	if s.Cfg.ExperimentalMaxConnectionMemoryBytes > 0 || s.Cfg.ExperimentalMaxTotalConnectionMemoryBytes > 0 || s.MemoryBudgetEnabled() {
+		t := newConnMemoryTracker(s.Logger(), s.Cfg.ExperimentalMaxConnectionMemoryBytes, s.Cfg.ExperimentalMaxTotalConnectionMemoryBytes)
+		if s.MemoryBudgetEnabled() {
+			t.accountRangeResponse = s.AccountRangeResponse
+		}
+		opts = append(opts, grpc.StatsHandler(t))
+		chainUnaryInterceptors = append(chainUnaryInterceptors, t.unaryInterceptor)
+		chainStreamInterceptors = append(chainStreamInterceptors, t.streamInterceptor)
+	}
+
+	if s.Cfg.ExperimentalLargeRequestPolicy != "" {
+		chainUnaryInterceptors = append(chainUnaryInterceptors, newServerLargeRequestLimiter(s).unaryInterceptor)
+	}
+
 	if s.Cfg.ExperimentalEnableDistributedTracing {
 		chainUnaryInterceptors = append(chainUnaryInterceptors, otelgrpc.UnaryServerInterceptor(s.Cfg.ExperimentalTracerOptions...))
 		chainStreamInterceptors = append(chainStreamInterceptors, otelgrpc.StreamServerInterceptor(s.Cfg.ExperimentalTracerOptions...))
 
 	}
 
+	chainUnaryInterceptors = append(chainUnaryInterceptors, s.Cfg.UnaryInterceptors...)
+	chainStreamInterceptors = append(chainStreamInterceptors, s.Cfg.StreamInterceptors...)
+
 	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(chainUnaryInterceptors...)))
 	opts = append(opts, grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(chainStreamInterceptors...)))
 
@@ -81,9 +107,11 @@ func Server(s *etcdserver.EtcdServer, tls *tls.Config, interceptor grpc.UnarySer
 	// server should register all the services manually
 	// use empty service name for all etcd services' health status,
 	// see https://github.com/grpc/grpc/blob/master/doc/health-checking.md for more
+	// and report the health of each service under its full name
 	hsrv := health.NewServer()
 	hsrv.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
 	healthpb.RegisterHealthServer(grpcServer, hsrv)
+	s.GoAttach(func() { reportServicesHealth(s.Logger(), hsrv, s, s.StoppingNotify()) })
 
 	// set zero values for metrics registered for this grpc server
 	grpc_prometheus.Register(grpcServer)
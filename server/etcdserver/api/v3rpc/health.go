@@ -0,0 +1,125 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/raft/v3"
+	"go.etcd.io/etcd/server/v3/etcdserver/api"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serviceHealthCheckInterval is the interval the health of the services is
+// checked at.
+const serviceHealthCheckInterval = time.Second
+
+// The names of the services reported by the gRPC health service.
+const (
+	healthServiceKV          = "etcdserverpb.KV"
+	healthServiceWatch       = "etcdserverpb.Watch"
+	healthServiceLease       = "etcdserverpb.Lease"
+	healthServiceCluster     = "etcdserverpb.Cluster"
+	healthServiceAuth        = "etcdserverpb.Auth"
+	healthServiceMaintenance = "etcdserverpb.Maintenance"
+)
+
+var healthServices = []string{
+	healthServiceKV,
+	healthServiceWatch,
+	healthServiceLease,
+	healthServiceCluster,
+	healthServiceAuth,
+	healthServiceMaintenance,
+}
+
+type ServiceHealthGetter interface {
+	Leader() types.ID
+	Alarms() []*pb.AlarmMember
+	MemberId() types.ID
+	Cluster() api.Cluster
+}
+
+// servicesHealth returns the health of the services of the member: the
+// services needing a consensus are not serving without a leader, or from a
+// learner, the lease grants are rejected once out of space, and no service
+// but the maintenance one is serving once the data is corrupted or the
+// member removed from the cluster.
+func servicesHealth(hg ServiceHealthGetter) map[string]healthpb.HealthCheckResponse_ServingStatus {
+	var nospace, corrupt bool
+	for _, a := range hg.Alarms() {
+		switch a.Alarm {
+		case pb.AlarmType_NOSPACE:
+			nospace = true
+		case pb.AlarmType_CORRUPT:
+			corrupt = true
+		}
+	}
+	noLeader := uint64(hg.Leader()) == raft.None
+	// the member is looked up as it might have been removed, the reporter
+	// running until the server stops
+	m := hg.Cluster().Member(hg.MemberId())
+	removed := m == nil
+	learner := m != nil && m.IsLearner
+
+	status := func(down bool) healthpb.HealthCheckResponse_ServingStatus {
+		if down {
+			return healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return map[string]healthpb.HealthCheckResponse_ServingStatus{
+		healthServiceKV:          status(corrupt || removed || noLeader || learner),
+		healthServiceWatch:       status(corrupt || removed || learner),
+		healthServiceLease:       status(corrupt || removed || noLeader || learner || nospace),
+		healthServiceCluster:     status(corrupt || removed || noLeader),
+		healthServiceAuth:        status(corrupt || removed || noLeader || learner),
+		healthServiceMaintenance: status(false),
+	}
+}
+
+// reportServicesHealth sets the serving status of the services in hsrv,
+// checking their health until stopc is closed, then shuts hsrv down.
+func reportServicesHealth(lg *zap.Logger, hsrv *health.Server, hg ServiceHealthGetter, stopc <-chan struct{}) {
+	last := make(map[string]healthpb.HealthCheckResponse_ServingStatus, len(healthServices))
+	for {
+		for service, st := range servicesHealth(hg) {
+			if prev, ok := last[service]; ok {
+				if prev == st {
+					continue
+				}
+				if st == healthpb.HealthCheckResponse_SERVING {
+					lg.Info("gRPC service serving again", zap.String("service", service))
+				} else {
+					lg.Warn("gRPC service not serving", zap.String("service", service))
+				}
+			}
+			last[service] = st
+			hsrv.SetServingStatus(service, st)
+		}
+
+		select {
+		case <-time.After(serviceHealthCheckInterval):
+		case <-stopc:
+			hsrv.Shutdown()
+			return
+		}
+	}
+}
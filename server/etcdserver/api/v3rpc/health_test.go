@@ -0,0 +1,107 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/server/v3/etcdserver/api"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakeServiceHealthGetter struct {
+	leader  types.ID
+	alarms  []*pb.AlarmMember
+	learner bool
+	removed bool
+}
+
+func (hg fakeServiceHealthGetter) Leader() types.ID          { return hg.leader }
+func (hg fakeServiceHealthGetter) Alarms() []*pb.AlarmMember { return hg.alarms }
+func (hg fakeServiceHealthGetter) MemberId() types.ID        { return 1 }
+func (hg fakeServiceHealthGetter) Cluster() api.Cluster      { return fakeHealthCluster{hg: hg} }
+
+type fakeHealthCluster struct {
+	api.Cluster
+	hg fakeServiceHealthGetter
+}
+
+func (c fakeHealthCluster) Member(id types.ID) *membership.Member {
+	if c.hg.removed {
+		return nil
+	}
+	return &membership.Member{ID: id, RaftAttributes: membership.RaftAttributes{IsLearner: c.hg.learner}}
+}
+
+func TestServicesHealth(t *testing.T) {
+	tests := []struct {
+		name string
+		hg   fakeServiceHealthGetter
+		// down are the services not serving
+		down []string
+	}{
+		{"healthy", fakeServiceHealthGetter{leader: 1}, nil},
+		{
+			"no leader",
+			fakeServiceHealthGetter{},
+			[]string{healthServiceKV, healthServiceLease, healthServiceCluster, healthServiceAuth},
+		},
+		{
+			"learner",
+			fakeServiceHealthGetter{leader: 1, learner: true},
+			[]string{healthServiceKV, healthServiceWatch, healthServiceLease, healthServiceAuth},
+		},
+		{
+			"removed",
+			fakeServiceHealthGetter{leader: 1, removed: true},
+			[]string{healthServiceKV, healthServiceWatch, healthServiceLease, healthServiceCluster, healthServiceAuth},
+		},
+		{
+			"no space",
+			fakeServiceHealthGetter{leader: 1, alarms: []*pb.AlarmMember{{MemberID: 1, Alarm: pb.AlarmType_NOSPACE}}},
+			[]string{healthServiceLease},
+		},
+		{
+			"corrupt",
+			fakeServiceHealthGetter{leader: 1, alarms: []*pb.AlarmMember{{MemberID: 2, Alarm: pb.AlarmType_CORRUPT}}},
+			[]string{healthServiceKV, healthServiceWatch, healthServiceLease, healthServiceCluster, healthServiceAuth},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := make(map[string]bool)
+			for _, service := range tt.down {
+				down[service] = true
+			}
+			health := servicesHealth(tt.hg)
+			if len(health) != len(healthServices) {
+				t.Fatalf("health of %d services, want %d", len(health), len(healthServices))
+			}
+			for _, service := range healthServices {
+				want := healthpb.HealthCheckResponse_SERVING
+				if down[service] {
+					want = healthpb.HealthCheckResponse_NOT_SERVING
+				}
+				if health[service] != want {
+					t.Errorf("health of %s = %v, want %v", service, health[service], want)
+				}
+			}
+		})
+	}
+}
@@ -36,6 +36,7 @@ import (
 const (
 	maxNoLeaderCnt = 3
 	snapshotMethod = "/etcdserverpb.Maintenance/Snapshot"
+	rangeMethod    = "/etcdserverpb.KV/Range"
 )
 
 type streamsMap struct {
@@ -71,7 +72,11 @@ func newUnaryInterceptor(s *etcdserver.EtcdServer) grpc.UnaryServerInterceptor {
 			}
 		}
 
-		return handler(ctx, req)
+		resp, err := handler(ctx, req)
+		if err != nil {
+			err = withErrorDetails(s, err)
+		}
+		return resp, err
 	}
 }
 
@@ -81,22 +86,65 @@ func newLogUnaryInterceptor(s *etcdserver.EtcdServer) grpc.UnaryServerIntercepto
 		resp, err := handler(ctx, req)
 		lg := s.Logger()
 		if lg != nil { // acquire stats if debug level is enabled or RequestInfo is expensive
-			defer logUnaryRequestStats(ctx, lg, s.Cfg.WarningUnaryRequestDuration, info, startTime, req, resp)
+			defer logUnaryRequestStats(ctx, lg, s.Cfg.WarningUnaryRequestDuration, info, startTime, req, resp, isLoggedRequest(s, req))
 		}
 		return resp, err
 	}
 }
 
-func logUnaryRequestStats(ctx context.Context, lg *zap.Logger, warnLatency time.Duration, info *grpc.UnaryServerInfo, startTime time.Time, req interface{}, resp interface{}) {
+// isLoggedRequest returns whether the request touches a key range whose
+// requests are logged.
+func isLoggedRequest(s *etcdserver.EtcdServer, req interface{}) bool {
+	switch r := req.(type) {
+	case *pb.RangeRequest:
+		return s.IsLoggedRange(r.Key, r.RangeEnd)
+	case *pb.PutRequest:
+		return s.IsLoggedRange(r.Key, nil)
+	case *pb.DeleteRangeRequest:
+		return s.IsLoggedRange(r.Key, r.RangeEnd)
+	case *pb.IncrementRequest:
+		return s.IsLoggedRange(r.Key, nil)
+	case *pb.TxnRequest:
+		for _, c := range r.Compare {
+			if s.IsLoggedRange(c.Key, c.RangeEnd) {
+				return true
+			}
+		}
+		for _, ops := range [][]*pb.RequestOp{r.Success, r.Failure} {
+			for _, op := range ops {
+				var opReq interface{}
+				switch {
+				case op.GetRequestRange() != nil:
+					opReq = op.GetRequestRange()
+				case op.GetRequestPut() != nil:
+					opReq = op.GetRequestPut()
+				case op.GetRequestDeleteRange() != nil:
+					opReq = op.GetRequestDeleteRange()
+				case op.GetRequestTxn() != nil:
+					opReq = op.GetRequestTxn()
+				case op.GetRequestIncrement() != nil:
+					opReq = op.GetRequestIncrement()
+				}
+				if isLoggedRequest(s, opReq) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func logUnaryRequestStats(ctx context.Context, lg *zap.Logger, warnLatency time.Duration, info *grpc.UnaryServerInfo, startTime time.Time, req interface{}, resp interface{}, loggedRequest bool) {
 	duration := time.Since(startTime)
 	var enabledDebugLevel, expensiveRequest bool
-	if lg.Core().Enabled(zap.DebugLevel) {
+	// checked rather than Enabled, to apply the log level of the subsystem
+	if lg.Check(zap.DebugLevel, "request stats") != nil {
 		enabledDebugLevel = true
 	}
 	if duration > warnLatency {
 		expensiveRequest = true
 	}
-	if !enabledDebugLevel && !expensiveRequest {
+	if !enabledDebugLevel && !expensiveRequest && !loggedRequest {
 		return
 	}
 	remote := "No remote client info."
@@ -177,6 +225,8 @@ func logUnaryRequestStats(ctx context.Context, lg *zap.Logger, warnLatency time.
 		logGenericRequestStats(lg, startTime, duration, remote, responseType, reqCount, reqSize, respCount, respSize, reqContent)
 	} else if expensiveRequest {
 		logExpensiveRequestStats(lg, startTime, duration, remote, responseType, reqCount, reqSize, respCount, respSize, reqContent)
+	} else if loggedRequest {
+		logLoggedRangeRequestStats(lg, startTime, duration, remote, responseType, reqCount, reqSize, respCount, respSize, reqContent)
 	}
 }
 
@@ -210,6 +260,21 @@ func logExpensiveRequestStats(lg *zap.Logger, startTime time.Time, duration time
 	)
 }
 
+func logLoggedRangeRequestStats(lg *zap.Logger, startTime time.Time, duration time.Duration, remote string, responseType string,
+	reqCount int64, reqSize int, respCount int64, respSize int, reqContent string) {
+	lg.Warn("logged range request stats",
+		zap.Time("start time", startTime),
+		zap.Duration("time spent", duration),
+		zap.String("remote", remote),
+		zap.String("response type", responseType),
+		zap.Int64("request count", reqCount),
+		zap.Int("request size", reqSize),
+		zap.Int64("response count", respCount),
+		zap.Int("response size", respSize),
+		zap.String("request content", reqContent),
+	)
+}
+
 func newStreamInterceptor(s *etcdserver.EtcdServer) grpc.StreamServerInterceptor {
 	smap := monitorLeader(s)
 
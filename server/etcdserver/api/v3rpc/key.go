@@ -17,11 +17,22 @@ package v3rpc
 
 import (
 	"context"
+	"strconv"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/pkg/v3/adt"
 	"go.etcd.io/etcd/server/v3/etcdserver"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// maxAnnotations is the max number of annotations attached to a key.
+	maxAnnotations = 16
+	// maxAnnotationsBytes is the max total size of the names and values
+	// of the annotations attached to a key.
+	maxAnnotationsBytes = 4 * 1024
 )
 
 type kvServer struct {
@@ -32,26 +43,60 @@ type kvServer struct {
 	// Txn.Success can have at most 128 operations,
 	// and Txn.Failure can have at most 128 operations.
 	maxTxnOps uint
+	// waitRev waits for the member to apply a revision.
+	waitRev func(ctx context.Context, rev int64) error
+	// ps counts the requests per prefix, nil without prefixes.
+	ps *etcdserver.PrefixStats
 }
 
 func NewKVServer(s *etcdserver.EtcdServer) pb.KVServer {
-	return &kvServer{hdr: newHeader(s), kv: s, maxTxnOps: s.Cfg.MaxTxnOps}
+	return &kvServer{hdr: newHeader(s), kv: s, maxTxnOps: s.Cfg.MaxTxnOps, waitRev: s.WaitAppliedRevision, ps: s.PrefixStats()}
 }
 
 func (s *kvServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
 	if err := checkRangeRequest(r); err != nil {
 		return nil, err
 	}
+	rev := r.MinRevisionWait
+	if mrev := MinRevisionFromContext(ctx); r.Serializable && mrev > rev {
+		rev = mrev
+	}
+	if rev > 0 {
+		if err := s.waitRev(ctx, rev); err != nil {
+			return nil, togRPCError(err)
+		}
+	}
 
 	resp, err := s.kv.Range(ctx, r)
 	if err != nil {
 		return nil, togRPCError(err)
 	}
+	observeRange(resp)
+	s.ps.Read(r.Key, rangeBytes(resp))
 
 	s.hdr.fill(resp.Header)
 	return resp, nil
 }
 
+// MinRevisionFromContext returns the revision the member serving the
+// serializable ranges of the request of ctx waits to apply before serving
+// them, 0 if none.
+func MinRevisionFromContext(ctx context.Context) int64 {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0
+	}
+	vs := md.Get(rpctypes.MetadataMinRevisionKey)
+	if len(vs) == 0 {
+		return 0
+	}
+	rev, err := strconv.ParseInt(vs[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return rev
+}
+
 func (s *kvServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
 	if err := checkPutRequest(r); err != nil {
 		return nil, err
@@ -61,6 +106,8 @@ func (s *kvServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse,
 	if err != nil {
 		return nil, togRPCError(err)
 	}
+	putValueBytes.Observe(float64(len(r.Value)))
+	s.ps.Write(r.Key, len(r.Key)+len(r.Value))
 
 	s.hdr.fill(resp.Header)
 	return resp, nil
@@ -75,6 +122,7 @@ func (s *kvServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*
 	if err != nil {
 		return nil, togRPCError(err)
 	}
+	s.ps.Write(r.Key, len(r.Key))
 
 	s.hdr.fill(resp.Header)
 	return resp, nil
@@ -96,11 +144,86 @@ func (s *kvServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse,
 	if err != nil {
 		return nil, togRPCError(err)
 	}
+	txnOps.Observe(float64(len(executedOps(r, resp))))
+	observeTxn(r, resp)
+	if s.ps != nil {
+		countTxn(s.ps, r, resp)
+	}
 
 	s.hdr.fill(resp.Header)
 	return resp, nil
 }
 
+// rangeBytes returns the size of the keys and values of the range response.
+func rangeBytes(resp *pb.RangeResponse) (n int) {
+	for _, kv := range resp.Kvs {
+		n += len(kv.Key) + len(kv.Value)
+	}
+	return n
+}
+
+// countTxn counts the operations of the executed branch of the transaction,
+// and of its nested transactions, in the prefix statistics ps.
+func countTxn(ps *etcdserver.PrefixStats, r *pb.TxnRequest, resp *pb.TxnResponse) {
+	ops := executedOps(r, resp)
+	for i, op := range ops {
+		if i >= len(resp.Responses) {
+			return
+		}
+		switch tv := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			if rr := resp.Responses[i].GetResponseRange(); rr != nil {
+				ps.Read(tv.RequestRange.Key, rangeBytes(rr))
+			}
+		case *pb.RequestOp_RequestPut:
+			ps.Write(tv.RequestPut.Key, len(tv.RequestPut.Key)+len(tv.RequestPut.Value))
+		case *pb.RequestOp_RequestDeleteRange:
+			ps.Write(tv.RequestDeleteRange.Key, len(tv.RequestDeleteRange.Key))
+		case *pb.RequestOp_RequestIncrement:
+			ps.Write(tv.RequestIncrement.Key, len(tv.RequestIncrement.Key))
+		case *pb.RequestOp_RequestTxn:
+			if tr := resp.Responses[i].GetResponseTxn(); tr != nil {
+				countTxn(ps, tv.RequestTxn, tr)
+			}
+		}
+	}
+}
+
+func observeRange(resp *pb.RangeResponse) {
+	rangeResultKeys.Observe(float64(len(resp.Kvs)))
+	rangeResultBytes.Observe(float64(resp.Size()))
+}
+
+// observeTxn observes the ranges and the puts of the executed branch of the
+// transaction, and of its nested transactions.
+func observeTxn(r *pb.TxnRequest, resp *pb.TxnResponse) {
+	ops := executedOps(r, resp)
+	for i, op := range ops {
+		if i >= len(resp.Responses) {
+			return
+		}
+		switch tv := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			if rr := resp.Responses[i].GetResponseRange(); rr != nil {
+				observeRange(rr)
+			}
+		case *pb.RequestOp_RequestPut:
+			putValueBytes.Observe(float64(len(tv.RequestPut.Value)))
+		case *pb.RequestOp_RequestTxn:
+			if tr := resp.Responses[i].GetResponseTxn(); tr != nil {
+				observeTxn(tv.RequestTxn, tr)
+			}
+		}
+	}
+}
+
+func executedOps(r *pb.TxnRequest, resp *pb.TxnResponse) []*pb.RequestOp {
+	if resp.Succeeded {
+		return r.Success
+	}
+	return r.Failure
+}
+
 func (s *kvServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error) {
 	resp, err := s.kv.Compact(ctx, r)
 	if err != nil {
@@ -137,6 +260,20 @@ func checkPutRequest(r *pb.PutRequest) error {
 	if r.IgnoreLease && r.Lease != 0 {
 		return rpctypes.ErrGRPCLeaseProvided
 	}
+	return checkAnnotations(r.Annotations)
+}
+
+func checkAnnotations(annotations map[string]string) error {
+	if len(annotations) > maxAnnotations {
+		return rpctypes.ErrGRPCAnnotationsTooLarge
+	}
+	size := 0
+	for k, v := range annotations {
+		size += len(k) + len(v)
+	}
+	if size > maxAnnotationsBytes {
+		return rpctypes.ErrGRPCAnnotationsTooLarge
+	}
 	return nil
 }
 
@@ -147,6 +284,13 @@ func checkDeleteRequest(r *pb.DeleteRangeRequest) error {
 	return nil
 }
 
+func checkIncrementRequest(r *pb.IncrementRequest) error {
+	if len(r.Key) == 0 {
+		return rpctypes.ErrGRPCEmptyKey
+	}
+	return nil
+}
+
 func checkTxnRequest(r *pb.TxnRequest, maxTxnOps int) error {
 	opc := len(r.Compare)
 	if opc < len(r.Success) {
@@ -244,13 +388,23 @@ func checkIntervals(reqs []*pb.RequestOp) (map[string]struct{}, adt.IntervalTree
 		dels.Union(delsElse, adt.NewStringAffineInterval("\x00", ""))
 	}
 
-	// collect and check this level's puts
+	// collect and check this level's puts, increments included
 	for _, req := range reqs {
-		tv, ok := req.Request.(*pb.RequestOp_RequestPut)
-		if !ok || tv.RequestPut == nil {
+		var k string
+		switch tv := req.Request.(type) {
+		case *pb.RequestOp_RequestPut:
+			if tv.RequestPut == nil {
+				continue
+			}
+			k = string(tv.RequestPut.Key)
+		case *pb.RequestOp_RequestIncrement:
+			if tv.RequestIncrement == nil {
+				continue
+			}
+			k = string(tv.RequestIncrement.Key)
+		default:
 			continue
 		}
-		k := string(tv.RequestPut.Key)
 		if _, ok := puts[k]; ok {
 			return nil, dels, rpctypes.ErrGRPCDuplicateKey
 		}
@@ -273,6 +427,8 @@ func checkRequestOp(u *pb.RequestOp, maxTxnOps int) error {
 		return checkDeleteRequest(uv.RequestDeleteRange)
 	case *pb.RequestOp_RequestTxn:
 		return checkTxnRequest(uv.RequestTxn, maxTxnOps)
+	case *pb.RequestOp_RequestIncrement:
+		return checkIncrementRequest(uv.RequestIncrement)
 	default:
 		// empty op / nil entry
 		return rpctypes.ErrGRPCKeyNotFound
@@ -17,6 +17,7 @@ package v3rpc
 import (
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"strings"
 	"testing"
 )
 
@@ -58,6 +59,46 @@ func TestCheckRangeRequest(t *testing.T) {
 	}
 }
 
+func TestCheckPutRequestAnnotations(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= maxAnnotations; i++ {
+		tooMany[strings.Repeat("a", i+1)] = "v"
+	}
+	putReqs := []struct {
+		annotations   map[string]string
+		expectedError error
+	}{
+		{
+			annotations:   nil,
+			expectedError: nil,
+		},
+		{
+			annotations:   map[string]string{"owner": "app", "content-type": "application/json"},
+			expectedError: nil,
+		},
+		{
+			annotations:   tooMany,
+			expectedError: rpctypes.ErrGRPCAnnotationsTooLarge,
+		},
+		{
+			annotations:   map[string]string{"checksum": strings.Repeat("0", maxAnnotationsBytes)},
+			expectedError: rpctypes.ErrGRPCAnnotationsTooLarge,
+		},
+	}
+
+	for i, req := range putReqs {
+		putReq := pb.PutRequest{
+			Key:         []byte{1, 2, 3},
+			Annotations: req.annotations,
+		}
+
+		actualRet := checkPutRequest(&putReq)
+		if getError(actualRet) != getError(req.expectedError) {
+			t.Errorf("#%d: expected %q, but got %q", i, getError(req.expectedError), getError(actualRet))
+		}
+	}
+}
+
 func getError(err error) string {
 	if err == nil {
 		return ""
@@ -0,0 +1,211 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/server/v3/config"
+	"go.etcd.io/etcd/server/v3/etcdserver"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// largeRequestIdleTimeout is the time after which the large requests of an
+// identity are forgotten, its limiter being full again.
+const largeRequestIdleTimeout = time.Minute
+
+// largeRequestLimiter applies the large request policy to the identities, i.e.
+// the users or the client IPs without authentication, sending more requests
+// of at least minBytes than their limiters allow.
+type largeRequestLimiter struct {
+	lg       *zap.Logger
+	policy   string
+	minBytes int
+	limit    rate.Limit
+	burst    int
+	// allowlist are the identities not subject to the policy.
+	allowlist map[string]struct{}
+	// identity returns the identity sending the request of the context.
+	identity func(ctx context.Context) string
+
+	mu         sync.Mutex
+	identities map[string]*identityLargeRequests
+	lastPrune  time.Time
+}
+
+// identityLargeRequests are the large requests of an identity.
+type identityLargeRequests struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+	// lastWarn is the time the identity was last logged.
+	lastWarn time.Time
+}
+
+func newLargeRequestLimiter(lg *zap.Logger, policy string, minBytes, perMinute int, allowlist []string, identity func(ctx context.Context) string) *largeRequestLimiter {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	l := &largeRequestLimiter{
+		lg:         lg,
+		policy:     policy,
+		minBytes:   minBytes,
+		limit:      rate.Limit(float64(perMinute) / time.Minute.Seconds()),
+		burst:      perMinute,
+		allowlist:  make(map[string]struct{}, len(allowlist)),
+		identity:   identity,
+		identities: make(map[string]*identityLargeRequests),
+	}
+	for _, id := range allowlist {
+		l.allowlist[id] = struct{}{}
+	}
+	return l
+}
+
+// newServerLargeRequestLimiter returns the large request limiter of the
+// server, large requests being of 90% of the maximum request size if not
+// configured.
+func newServerLargeRequestLimiter(s *etcdserver.EtcdServer) *largeRequestLimiter {
+	minBytes := s.Cfg.ExperimentalLargeRequestBytes
+	if minBytes == 0 {
+		minBytes = int(s.Cfg.MaxRequestBytes) / 10 * 9
+	}
+	identity := func(ctx context.Context) string {
+		if ai, err := s.AuthInfoFromCtx(ctx); err == nil && ai != nil {
+			return ai.Username
+		}
+		return clientIP(ctx)
+	}
+	return newLargeRequestLimiter(s.Logger(), s.Cfg.ExperimentalLargeRequestPolicy, minBytes, s.Cfg.ExperimentalLargeRequestsPerMinute, s.Cfg.ExperimentalLargeRequestAllowlist, identity)
+}
+
+// clientIP returns the IP of the client of the context, its address if it
+// has no IP, e.g. over a unix socket.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// unaryInterceptor applies the policy to the large unary requests.
+func (l *largeRequestLimiter) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if m, ok := req.(interface{ Size() int }); ok {
+		if size := m.Size(); size >= l.minBytes {
+			if err := l.check(ctx, info.FullMethod, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return handler(ctx, req)
+}
+
+// check applies the policy to a large request, returning an error if it
+// must fail.
+func (l *largeRequestLimiter) check(ctx context.Context, method string, size int) error {
+	id := l.identity(ctx)
+	if _, ok := l.allowlist[id]; ok {
+		largeRequests.WithLabelValues("allowed").Inc()
+		return nil
+	}
+	now := time.Now()
+	ir := l.identityRequests(id, now)
+
+	r := ir.limiter.ReserveN(now, 1)
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		largeRequests.WithLabelValues("allowed").Inc()
+		return nil
+	}
+	switch l.policy {
+	case config.LargeRequestPolicyWarn:
+		// the request is let through, not to be counted against the identity
+		r.CancelAt(now)
+		largeRequests.WithLabelValues("warned").Inc()
+		l.warn(ir, id, method, size, now)
+		return nil
+	case config.LargeRequestPolicyThrottle:
+		largeRequests.WithLabelValues("throttled").Inc()
+		l.warn(ir, id, method, size, now)
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return nil
+		case <-ctx.Done():
+			r.Cancel()
+			return togRPCError(ctx.Err())
+		}
+	default:
+		r.CancelAt(now)
+		largeRequests.WithLabelValues("rejected").Inc()
+		l.warn(ir, id, method, size, now)
+		return rpctypes.ErrGRPCTooManyLargeRequests
+	}
+}
+
+// identityRequests returns the large requests of an identity, forgetting
+// the identities idle for largeRequestIdleTimeout.
+func (l *largeRequestLimiter) identityRequests(id string, now time.Time) *identityLargeRequests {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastPrune) > largeRequestIdleTimeout {
+		for k, ir := range l.identities {
+			if now.Sub(ir.lastSeen) > largeRequestIdleTimeout {
+				delete(l.identities, k)
+			}
+		}
+		l.lastPrune = now
+	}
+	ir, ok := l.identities[id]
+	if !ok {
+		ir = &identityLargeRequests{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.identities[id] = ir
+	}
+	ir.lastSeen = now
+	return ir
+}
+
+// warn logs the identity exceeding its large requests at most once per
+// largeRequestIdleTimeout.
+func (l *largeRequestLimiter) warn(ir *identityLargeRequests, id, method string, size int, now time.Time) {
+	l.mu.Lock()
+	if now.Sub(ir.lastWarn) < largeRequestIdleTimeout {
+		l.mu.Unlock()
+		return
+	}
+	ir.lastWarn = now
+	l.mu.Unlock()
+	l.lg.Warn(
+		"identity sending too many large requests",
+		zap.String("identity", id),
+		zap.String("method", method),
+		zap.Int("request-bytes", size),
+		zap.Int("large-request-bytes", l.minBytes),
+		zap.Int("large-requests-per-minute", l.burst),
+		zap.String("policy", l.policy),
+	)
+}
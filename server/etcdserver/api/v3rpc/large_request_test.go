@@ -0,0 +1,140 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/server/v3/config"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+type identityKey struct{}
+
+func withIdentity(id string) context.Context {
+	return context.WithValue(context.Background(), identityKey{}, id)
+}
+
+func newTestLargeRequestLimiter(t *testing.T, policy string, allowlist ...string) *largeRequestLimiter {
+	return newLargeRequestLimiter(zaptest.NewLogger(t), policy, 100, 2, allowlist, func(ctx context.Context) string {
+		return ctx.Value(identityKey{}).(string)
+	})
+}
+
+// callLarge sends a request of size bytes through the interceptor, returning
+// whether the handler was called.
+func callLarge(ctx context.Context, l *largeRequestLimiter, size int) (bool, error) {
+	called := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	}
+	req := &pb.PutRequest{Key: []byte("foo"), Value: make([]byte, size)}
+	_, err := l.unaryInterceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/etcdserverpb.KV/Put"}, handler)
+	return called, err
+}
+
+func TestLargeRequestLimiterReject(t *testing.T) {
+	l := newTestLargeRequestLimiter(t, config.LargeRequestPolicyReject)
+	alice, bob := withIdentity("alice"), withIdentity("bob")
+
+	// small requests are not counted
+	for i := 0; i < 5; i++ {
+		if _, err := callLarge(alice, l, 10); err != nil {
+			t.Fatalf("small request #%d: %v", i, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := callLarge(alice, l, 200); err != nil {
+			t.Fatalf("large request #%d: %v", i, err)
+		}
+	}
+	called, err := callLarge(alice, l, 200)
+	if err != rpctypes.ErrGRPCTooManyLargeRequests || called {
+		t.Fatalf("third large request: called %v, err %v, want rejected with %v", called, err, rpctypes.ErrGRPCTooManyLargeRequests)
+	}
+	if _, err = callLarge(alice, l, 10); err != nil {
+		t.Errorf("small request after rejection: %v", err)
+	}
+	// identities are limited independently
+	if _, err = callLarge(bob, l, 200); err != nil {
+		t.Errorf("large request of another identity: %v", err)
+	}
+}
+
+func TestLargeRequestLimiterWarn(t *testing.T) {
+	l := newTestLargeRequestLimiter(t, config.LargeRequestPolicyWarn)
+	for i := 0; i < 5; i++ {
+		if called, err := callLarge(withIdentity("alice"), l, 200); err != nil || !called {
+			t.Fatalf("large request #%d: called %v, err %v", i, called, err)
+		}
+	}
+}
+
+func TestLargeRequestLimiterThrottle(t *testing.T) {
+	l := newTestLargeRequestLimiter(t, config.LargeRequestPolicyThrottle)
+	for i := 0; i < 2; i++ {
+		if _, err := callLarge(withIdentity("alice"), l, 200); err != nil {
+			t.Fatalf("large request #%d: %v", i, err)
+		}
+	}
+	// the next request waits for the limiter, 30s at 2 per minute
+	ctx, cancel := context.WithTimeout(withIdentity("alice"), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	called, err := callLarge(ctx, l, 200)
+	if err != context.DeadlineExceeded || called {
+		t.Fatalf("throttled request: called %v, err %v, want %v", called, err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("throttled request returned after %v, want delayed until its deadline", d)
+	}
+}
+
+func TestLargeRequestLimiterAllowlist(t *testing.T) {
+	l := newTestLargeRequestLimiter(t, config.LargeRequestPolicyReject, "alice")
+	for i := 0; i < 5; i++ {
+		if _, err := callLarge(withIdentity("alice"), l, 200); err != nil {
+			t.Fatalf("large request #%d of allowed identity: %v", i, err)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		addr net.Addr
+		wip  string
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}, "10.0.0.1"},
+		{&net.TCPAddr{IP: net.ParseIP("::1"), Port: 1234}, "::1"},
+		{&net.UnixAddr{Name: "/tmp/etcd.sock", Net: "unix"}, "/tmp/etcd.sock"},
+	}
+	for i, tt := range tests {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tt.addr})
+		if ip := clientIP(ctx); ip != tt.wip {
+			t.Errorf("#%d: clientIP() = %q, want %q", i, ip, tt.wip)
+		}
+	}
+	if ip := clientIP(context.Background()); ip != "" {
+		t.Errorf("clientIP() = %q without peer, want empty", ip)
+	}
+}
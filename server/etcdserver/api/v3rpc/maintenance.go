@@ -17,24 +17,35 @@ package v3rpc
 import (
 	"context"
 	"crypto/sha256"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/api/v3/version"
+	"go.etcd.io/etcd/client/pkg/v3/logutil"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/pkg/v3/featuregate"
 	"go.etcd.io/etcd/raft/v3"
 	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/etcdserver"
 	"go.etcd.io/etcd/server/v3/etcdserver/apply"
 	"go.etcd.io/etcd/server/v3/etcdserver/errors"
 	serverversion "go.etcd.io/etcd/server/v3/etcdserver/version"
+	"go.etcd.io/etcd/server/v3/features"
 	"go.etcd.io/etcd/server/v3/storage/backend"
 	"go.etcd.io/etcd/server/v3/storage/mvcc"
 	"go.etcd.io/etcd/server/v3/storage/schema"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/time/rate"
 )
 
 type KVGetter interface {
@@ -69,10 +80,46 @@ type ClusterStatusGetter interface {
 	IsLearner() bool
 }
 
+type EventRecorder interface {
+	// RecordEvent adds an event to the event log of the server.
+	RecordEvent(typ, message string)
+	// Events returns up to limit latest events of the event log.
+	Events(limit int) []*pb.ServerEvent
+}
+
+type RangeLogger interface {
+	// LogRange logs the requests touching the key range for d, or stops
+	// logging them if d is not positive.
+	LogRange(key, end []byte, d time.Duration) ([]*pb.LoggedRange, error)
+}
+
+type DiskHealthGetter interface {
+	// DiskDegraded returns whether the disk of the server is degraded.
+	DiskDegraded() bool
+}
+
+type ClockSkewGetter interface {
+	// MaxClockSkew returns the largest clock skew of the server relative to its peers.
+	MaxClockSkew() time.Duration
+	// ClockSkewed returns whether the clock skew exceeds the configured maximum.
+	ClockSkewed() bool
+}
+
+type PeerMisbehaviorGetter interface {
+	// MisbehavingPeers returns the peers of the server likely misbehaving.
+	MisbehavingPeers() []*pb.MisbehavingPeer
+}
+
+type PrefixStatsGetter interface {
+	// PrefixStats returns the statistics of the configured prefixes, nil if none.
+	PrefixStats() *etcdserver.PrefixStats
+}
+
 type maintenanceServer struct {
 	lg     *zap.Logger
 	rg     apply.RaftStatusGetter
 	hasher mvcc.HashStorage
+	kv     mvcc.KV
 	bg     BackendGetter
 	a      Alarmer
 	lt     LeaderTransferrer
@@ -80,13 +127,36 @@ type maintenanceServer struct {
 	cs     ClusterStatusGetter
 	d      Downgrader
 	vs     serverversion.Server
+	er     EventRecorder
+	rl     RangeLogger
+	dh     DiskHealthGetter
+	csk    ClockSkewGetter
+	pm     PeerMisbehaviorGetter
+	ps     PrefixStatsGetter
+	// ll adjusts the log levels, nil if they are not adjustable.
+	ll *logutil.Levels
+	fg *featuregate.FeatureGate
+	// snapshotSpoolDir is the directory the snapshots are spooled to with
+	// the SnapshotSpool feature.
+	snapshotSpoolDir string
+	// snapshotSendRate is the maximum rate in bytes per second the
+	// snapshots are sent at, unlimited if zero.
+	snapshotSendRate int64
 }
 
 func NewMaintenanceServer(s *etcdserver.EtcdServer) pb.MaintenanceServer {
-	srv := &maintenanceServer{lg: s.Cfg.Logger, rg: s, hasher: s.KV().HashStorage(), bg: s, a: s, lt: s, hdr: newHeader(s), cs: s, d: s, vs: etcdserver.NewServerVersionAdapter(s)}
+	srv := &maintenanceServer{lg: s.Cfg.Logger, rg: s, hasher: s.KV().HashStorage(), kv: s.KV(), bg: s, a: s, lt: s, hdr: newHeader(s), cs: s, d: s, vs: etcdserver.NewServerVersionAdapter(s), er: s, rl: s, dh: s, csk: s, pm: s, ps: s, ll: s.Cfg.LogLevels, fg: s.Cfg.ServerFeatureGate}
 	if srv.lg == nil {
 		srv.lg = zap.NewNop()
 	}
+	if srv.fg == nil {
+		srv.fg = features.NewDefaultServerFeatureGate(srv.lg)
+	}
+	if srv.fg.Enabled(features.SnapshotSpool) {
+		srv.snapshotSpoolDir = s.Cfg.SnapDir()
+		removeSnapshotSpools(srv.lg, srv.snapshotSpoolDir)
+	}
+	srv.snapshotSendRate = s.Cfg.ExperimentalSnapshotSendRateBytes
 	return &authMaintenanceServer{srv, s}
 }
 
@@ -98,6 +168,7 @@ func (ms *maintenanceServer) Defragment(ctx context.Context, sr *pb.DefragmentRe
 		return nil, err
 	}
 	ms.lg.Info("finished defragment")
+	ms.er.RecordEvent(etcdserver.EventDefrag, "defragmented backend")
 	return &pb.DefragmentResponse{}, nil
 }
 
@@ -110,25 +181,22 @@ func (ms *maintenanceServer) Snapshot(sr *pb.SnapshotRequest, srv pb.Maintenance
 	if ver != nil {
 		storageVersion = ver.String()
 	}
-	snap := ms.bg.Backend().Snapshot()
-	pr, pw := io.Pipe()
-
-	defer pr.Close()
+	snap, total, err := ms.openSnapshot()
+	if err != nil {
+		return togRPCError(err)
+	}
+	defer snap.Close()
 
-	go func() {
-		snap.WriteTo(pw)
-		if err := snap.Close(); err != nil {
-			ms.lg.Warn("failed to close snapshot", zap.Error(err))
-		}
-		pw.Close()
-	}()
+	var limiter *rate.Limiter
+	if ms.snapshotSendRate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(ms.snapshotSendRate), snapshotSendBufferSize)
+	}
 
 	// record SHA digest of snapshot data
 	// used for integrity checks during snapshot restore operation
 	h := sha256.New()
 
 	sent := int64(0)
-	total := snap.Size()
 	size := humanize.Bytes(uint64(total))
 
 	start := time.Now()
@@ -145,11 +213,16 @@ func (ms *maintenanceServer) Snapshot(sr *pb.SnapshotRequest, srv pb.Maintenance
 		// Therefore the buffer can not be safely reused between Send operations
 		buf := make([]byte, snapshotSendBufferSize)
 
-		n, err := io.ReadFull(pr, buf)
+		n, err := io.ReadFull(snap, buf)
 		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 			return togRPCError(err)
 		}
 		sent += int64(n)
+		if limiter != nil {
+			if err = limiter.WaitN(srv.Context(), n); err != nil {
+				return togRPCError(err)
+			}
+		}
 
 		// if total is x * snapshotSendBufferSize. it is possible that
 		// resp.RemainingBytes == 0
@@ -192,6 +265,149 @@ func (ms *maintenanceServer) Snapshot(sr *pb.SnapshotRequest, srv pb.Maintenance
 	return nil
 }
 
+// snapshotDeltaEvents is the number of events sent in a response of a
+// snapshot delta, unless a single revision has more.
+const snapshotDeltaEvents = 1000
+
+func (ms *maintenanceServer) SnapshotDelta(sr *pb.SnapshotDeltaRequest, srv pb.Maintenance_SnapshotDeltaServer) error {
+	ver := schema.ReadStorageVersion(ms.bg.Backend().ReadTx())
+	storageVersion := ""
+	if ver != nil {
+		storageVersion = ver.String()
+	}
+	rev := ms.kv.Rev()
+	if sr.SinceRevision > rev {
+		return rpctypes.ErrGRPCFutureRev
+	}
+
+	// record SHA digest of the events
+	// used for integrity checks of the delta
+	h := sha256.New()
+	manifest := &pb.SnapshotDeltaManifest{SinceRevision: sr.SinceRevision, Revision: rev, Version: storageVersion}
+
+	start := time.Now()
+	ms.lg.Info("sending snapshot delta to client",
+		zap.Int64("since-revision", sr.SinceRevision),
+		zap.Int64("revision", rev),
+	)
+	// the revisions are checked not to be compacted as they are read
+	for next := sr.SinceRevision + 1; next != 0 && next <= rev; {
+		evs, n, err := ms.kv.Events(next, rev+1, snapshotDeltaEvents)
+		if err != nil {
+			return togRPCError(err)
+		}
+		next = n
+		resp := &pb.SnapshotDeltaResponse{Header: &pb.ResponseHeader{}, Events: make([]*mvccpb.Event, len(evs))}
+		for i := range evs {
+			resp.Events[i] = &evs[i]
+			b, err := evs[i].Marshal()
+			if err != nil {
+				return togRPCError(err)
+			}
+			h.Write(b)
+		}
+		ms.hdr.fill(resp.Header)
+		if err := srv.Send(resp); err != nil {
+			return togRPCError(err)
+		}
+		manifest.Events += int64(len(evs))
+	}
+
+	manifest.Sha256 = h.Sum(nil)
+	resp := &pb.SnapshotDeltaResponse{Header: &pb.ResponseHeader{}, Manifest: manifest}
+	ms.hdr.fill(resp.Header)
+	if err := srv.Send(resp); err != nil {
+		return togRPCError(err)
+	}
+
+	ms.lg.Info("successfully sent snapshot delta to client",
+		zap.Int64("since-revision", sr.SinceRevision),
+		zap.Int64("revision", rev),
+		zap.Int64("events", manifest.Events),
+		zap.Duration("took", time.Since(start)),
+	)
+	return nil
+}
+
+// openSnapshot returns a reader of a snapshot of the backend and its size.
+//
+// The snapshot holds a read transaction of the backend open until read,
+// blocking the backend commits growing its mmap, and so the applies. With
+// the SnapshotSpool feature, the snapshot is first copied to a file of the
+// spool directory, so that the transaction is held open for as long as the
+// copy on the local disk takes, not as long as the client takes to receive
+// the snapshot.
+func (ms *maintenanceServer) openSnapshot() (io.ReadCloser, int64, error) {
+	snap := ms.bg.Backend().Snapshot()
+	total := snap.Size()
+	if ms.snapshotSpoolDir == "" {
+		pr, pw := io.Pipe()
+		go func() {
+			snap.WriteTo(pw)
+			if err := snap.Close(); err != nil {
+				ms.lg.Warn("failed to close snapshot", zap.Error(err))
+			}
+			pw.Close()
+		}()
+		return pr, total, nil
+	}
+
+	start := time.Now()
+	f, err := os.CreateTemp(ms.snapshotSpoolDir, snapshotSpoolPattern)
+	if err == nil {
+		_, err = snap.WriteTo(f)
+	}
+	if cerr := snap.Close(); cerr != nil {
+		ms.lg.Warn("failed to close snapshot", zap.Error(cerr))
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		if f != nil {
+			(&spooledSnapshot{f}).Close()
+		}
+		ms.lg.Warn("failed to spool snapshot", zap.String("dir", ms.snapshotSpoolDir), zap.Error(err))
+		return nil, 0, err
+	}
+	ms.lg.Info(
+		"spooled database snapshot",
+		zap.String("path", f.Name()),
+		zap.Int64("total-bytes", total),
+		zap.Duration("took", time.Since(start)),
+	)
+	return &spooledSnapshot{f}, total, nil
+}
+
+// snapshotSpoolPattern is the pattern of the names of the snapshot spools.
+const snapshotSpoolPattern = "snapshot-*.spool"
+
+// spooledSnapshot is a snapshot spooled to a file, removed once closed.
+type spooledSnapshot struct {
+	*os.File
+}
+
+func (s *spooledSnapshot) Close() error {
+	err := s.File.Close()
+	if rerr := os.Remove(s.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// removeSnapshotSpools removes the snapshot spools left by a previous run.
+func removeSnapshotSpools(lg *zap.Logger, dir string) {
+	spools, err := filepath.Glob(filepath.Join(dir, snapshotSpoolPattern))
+	if err != nil {
+		return
+	}
+	for _, spool := range spools {
+		if err = os.Remove(spool); err != nil {
+			lg.Warn("failed to remove snapshot spool", zap.String("path", spool), zap.Error(err))
+		}
+	}
+}
+
 func (ms *maintenanceServer) Hash(ctx context.Context, r *pb.HashRequest) (*pb.HashResponse, error) {
 	h, rev, err := ms.hasher.Hash()
 	if err != nil {
@@ -213,6 +429,33 @@ func (ms *maintenanceServer) HashKV(ctx context.Context, r *pb.HashKVRequest) (*
 	return resp, nil
 }
 
+func (ms *maintenanceServer) DefragmentEstimate(ctx context.Context, r *pb.DefragmentEstimateRequest) (*pb.DefragmentEstimateResponse, error) {
+	be := ms.bg.Backend()
+	ts := ms.kv.Tombstones()
+	resp := &pb.DefragmentEstimateResponse{
+		Header:         &pb.ResponseHeader{},
+		DbSize:         be.Size(),
+		DbSizeInUse:    be.SizeInUse(),
+		DbSizePending:  be.SizePending(),
+		Tombstones:     ts.Count,
+		TombstonesSize: ts.Size,
+	}
+	resp.ReclaimableSize = resp.DbSize - resp.DbSizeInUse + resp.DbSizePending
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) RangeEstimate(ctx context.Context, r *pb.RangeEstimateRequest) (*pb.RangeEstimateResponse, error) {
+	if len(r.Key) == 0 {
+		return nil, rpctypes.ErrGRPCEmptyKey
+	}
+	e := ms.kv.Estimate(r.Key, r.RangeEnd, int(r.SampleSize))
+
+	resp := &pb.RangeEstimateResponse{Header: &pb.ResponseHeader{Revision: e.Rev}, Count: e.Count, TotalSize: e.Size, Sampled: e.Sampled}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
 func (ms *maintenanceServer) Alarm(ctx context.Context, ar *pb.AlarmRequest) (*pb.AlarmResponse, error) {
 	resp, err := ms.a.Alarm(ctx, ar)
 	if err != nil {
@@ -238,6 +481,9 @@ func (ms *maintenanceServer) Status(ctx context.Context, ar *pb.StatusRequest) (
 		DbSize:           ms.bg.Backend().Size(),
 		DbSizeInUse:      ms.bg.Backend().SizeInUse(),
 		IsLearner:        ms.cs.IsLearner(),
+		DiskDegraded:     ms.dh.DiskDegraded(),
+		MaxClockSkew:     int64(ms.csk.MaxClockSkew()),
+		MisbehavingPeers: ms.pm.MisbehavingPeers(),
 	}
 	if storageVersion := ms.vs.GetStorageVersion(); storageVersion != nil {
 		resp.StorageVersion = storageVersion.String()
@@ -248,6 +494,97 @@ func (ms *maintenanceServer) Status(ctx context.Context, ar *pb.StatusRequest) (
 	for _, a := range ms.a.Alarms() {
 		resp.Errors = append(resp.Errors, a.String())
 	}
+	if resp.DiskDegraded {
+		resp.Errors = append(resp.Errors, errors.ErrDiskDegraded.Error())
+	}
+	if ms.csk.ClockSkewed() {
+		resp.Errors = append(resp.Errors, errors.ErrClockSkewed.Error())
+	}
+	for _, p := range resp.MisbehavingPeers {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("%s: %s", errors.ErrPeerMisbehaving, types.ID(p.ID)))
+	}
+	return resp, nil
+}
+
+func (ms *maintenanceServer) Events(ctx context.Context, r *pb.EventsRequest) (*pb.EventsResponse, error) {
+	resp := &pb.EventsResponse{Header: &pb.ResponseHeader{}, Events: ms.er.Events(int(r.Limit))}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) LogLevel(ctx context.Context, r *pb.LogLevelRequest) (*pb.LogLevelResponse, error) {
+	if ms.ll == nil {
+		return nil, rpctypes.ErrGRPCLogLevelNotAdjustable
+	}
+	if r.Level != "" || r.Subsystem != "" {
+		var lvl zapcore.Level
+		if r.Level != "" {
+			if err := lvl.Set(r.Level); err != nil {
+				return nil, rpctypes.ErrGRPCInvalidLogLevel
+			}
+		}
+		switch {
+		case r.Subsystem == "":
+			ms.ll.SetLevel(lvl)
+		case r.Level == "":
+			ms.ll.ResetSubsystemLevel(r.Subsystem)
+		default:
+			ms.ll.SetSubsystemLevel(r.Subsystem, lvl)
+		}
+		ms.lg.Info("changed log level", zap.String("level", r.Level), zap.String("subsystem", r.Subsystem))
+	}
+
+	resp := &pb.LogLevelResponse{Header: &pb.ResponseHeader{}, Level: ms.ll.Level().String()}
+	for s, lvl := range ms.ll.SubsystemLevels() {
+		resp.Subsystems = append(resp.Subsystems, &pb.SubsystemLogLevel{Subsystem: s, Level: lvl.String()})
+	}
+	sort.Slice(resp.Subsystems, func(i, j int) bool { return resp.Subsystems[i].Subsystem < resp.Subsystems[j].Subsystem })
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) FeatureGates(ctx context.Context, r *pb.FeatureGatesRequest) (*pb.FeatureGatesResponse, error) {
+	resp := &pb.FeatureGatesResponse{Header: &pb.ResponseHeader{}}
+	for _, f := range ms.fg.Features() {
+		resp.Features = append(resp.Features, &pb.FeatureGate{
+			Name:          string(f.Name),
+			Enabled:       f.Enabled,
+			Default:       f.Default,
+			Stage:         string(f.Stage),
+			LockToDefault: f.LockToDefault,
+		})
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) PrefixStats(ctx context.Context, r *pb.PrefixStatsRequest) (*pb.PrefixStatsResponse, error) {
+	resp := &pb.PrefixStatsResponse{Header: &pb.ResponseHeader{}}
+	if stats, start := ms.ps.PrefixStats().Stats(); stats != nil {
+		resp.Stats, resp.Start = stats, start.UnixNano()
+	}
+	ms.hdr.fill(resp.Header)
+	return resp, nil
+}
+
+func (ms *maintenanceServer) LogRange(ctx context.Context, r *pb.LogRangeRequest) (*pb.LogRangeResponse, error) {
+	if len(r.Key) == 0 {
+		return nil, rpctypes.ErrGRPCEmptyKey
+	}
+	d := time.Duration(r.Duration) * time.Second
+	ranges, err := ms.rl.LogRange(r.Key, r.RangeEnd, d)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	ms.lg.Info(
+		"changed logged range",
+		zap.String("key", string(r.Key)),
+		zap.String("range-end", string(r.RangeEnd)),
+		zap.Duration("duration", d),
+	)
+
+	resp := &pb.LogRangeResponse{Header: &pb.ResponseHeader{}, Ranges: ranges}
+	ms.hdr.fill(resp.Header)
 	return resp, nil
 }
 
@@ -302,6 +639,14 @@ func (ams *authMaintenanceServer) Snapshot(sr *pb.SnapshotRequest, srv pb.Mainte
 	return ams.maintenanceServer.Snapshot(sr, srv)
 }
 
+func (ams *authMaintenanceServer) SnapshotDelta(sr *pb.SnapshotDeltaRequest, srv pb.Maintenance_SnapshotDeltaServer) error {
+	if err := ams.isAuthenticated(srv.Context()); err != nil {
+		return err
+	}
+
+	return ams.maintenanceServer.SnapshotDelta(sr, srv)
+}
+
 func (ams *authMaintenanceServer) Hash(ctx context.Context, r *pb.HashRequest) (*pb.HashResponse, error) {
 	if err := ams.isAuthenticated(ctx); err != nil {
 		return nil, err
@@ -317,10 +662,61 @@ func (ams *authMaintenanceServer) HashKV(ctx context.Context, r *pb.HashKVReques
 	return ams.maintenanceServer.HashKV(ctx, r)
 }
 
+func (ams *authMaintenanceServer) DefragmentEstimate(ctx context.Context, r *pb.DefragmentEstimateRequest) (*pb.DefragmentEstimateResponse, error) {
+	return ams.maintenanceServer.DefragmentEstimate(ctx, r)
+}
+
+func (ams *authMaintenanceServer) RangeEstimate(ctx context.Context, r *pb.RangeEstimateRequest) (*pb.RangeEstimateResponse, error) {
+	authInfo, err := ams.ag.AuthInfoFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if authInfo == nil {
+		// IsRangePermitted expects non-nil AuthInfo; use empty credentials
+		authInfo = &auth.AuthInfo{}
+	}
+	if err = ams.ag.AuthStore().IsRangePermitted(authInfo, r.Key, r.RangeEnd); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.RangeEstimate(ctx, r)
+}
+
 func (ams *authMaintenanceServer) Status(ctx context.Context, ar *pb.StatusRequest) (*pb.StatusResponse, error) {
 	return ams.maintenanceServer.Status(ctx, ar)
 }
 
+func (ams *authMaintenanceServer) Events(ctx context.Context, r *pb.EventsRequest) (*pb.EventsResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.Events(ctx, r)
+}
+
+func (ams *authMaintenanceServer) LogLevel(ctx context.Context, r *pb.LogLevelRequest) (*pb.LogLevelResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.LogLevel(ctx, r)
+}
+
+func (ams *authMaintenanceServer) LogRange(ctx context.Context, r *pb.LogRangeRequest) (*pb.LogRangeResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.LogRange(ctx, r)
+}
+
+func (ams *authMaintenanceServer) FeatureGates(ctx context.Context, r *pb.FeatureGatesRequest) (*pb.FeatureGatesResponse, error) {
+	return ams.maintenanceServer.FeatureGates(ctx, r)
+}
+
+func (ams *authMaintenanceServer) PrefixStats(ctx context.Context, r *pb.PrefixStatsRequest) (*pb.PrefixStatsResponse, error) {
+	if err := ams.isAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+	return ams.maintenanceServer.PrefixStats(ctx, r)
+}
+
 func (ams *authMaintenanceServer) MoveLeader(ctx context.Context, tr *pb.MoveLeaderRequest) (*pb.MoveLeaderResponse, error) {
 	return ams.maintenanceServer.MoveLeader(ctx, tr)
 }
@@ -0,0 +1,89 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.uber.org/zap/zaptest"
+)
+
+type fakeBackendGetter struct {
+	be backend.Backend
+}
+
+func (bg fakeBackendGetter) Backend() backend.Backend { return bg.be }
+
+func TestOpenSnapshotSpool(t *testing.T) {
+	be, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, be)
+	tx := be.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	tx.UnsafePut(schema.Test, []byte("foo"), []byte("bar"))
+	tx.Unlock()
+
+	dir := t.TempDir()
+	ms := &maintenanceServer{lg: zaptest.NewLogger(t), bg: fakeBackendGetter{be}, snapshotSpoolDir: dir}
+	snap, total, err := ms.openSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spools, _ := filepath.Glob(filepath.Join(dir, snapshotSpoolPattern))
+	if len(spools) != 1 {
+		t.Fatalf("spools = %v, want a single spool", spools)
+	}
+	// the backend is not held by the spooled snapshot
+	if err = be.Defrag(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(b)) != total {
+		t.Errorf("read %d bytes, want %d", len(b), total)
+	}
+	if err = snap.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(spools[0]); !os.IsNotExist(err) {
+		t.Errorf("spool %s not removed once closed: %v", spools[0], err)
+	}
+}
+
+func TestRemoveSnapshotSpools(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"snapshot-1.spool", "snapshot-2.spool", "0000000000000001-0000000000000001.snap"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removeSnapshotSpools(zaptest.NewLogger(t), dir)
+	names, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0].Name() != "0000000000000001-0000000000000001.snap" {
+		t.Errorf("files = %v, want only the snap file", names)
+	}
+}
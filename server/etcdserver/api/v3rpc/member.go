@@ -105,6 +105,14 @@ func (cs *ClusterServer) MemberPromote(ctx context.Context, r *pb.MemberPromoteR
 	return &pb.MemberPromoteResponse{Header: cs.header(), Members: membersToProtoMembers(membs)}, nil
 }
 
+func (cs *ClusterServer) MemberDemote(ctx context.Context, r *pb.MemberDemoteRequest) (*pb.MemberDemoteResponse, error) {
+	membs, err := cs.server.DemoteMember(ctx, r.ID, r.AcknowledgeReducedFaultTolerance)
+	if err != nil {
+		return nil, togRPCError(err)
+	}
+	return &pb.MemberDemoteResponse{Header: cs.header(), Members: membersToProtoMembers(membs)}, nil
+}
+
 func (cs *ClusterServer) header() *pb.ResponseHeader {
 	return &pb.ResponseHeader{ClusterId: uint64(cs.cluster.ID()), MemberId: uint64(cs.server.MemberId()), RaftTerm: cs.server.Term()}
 }
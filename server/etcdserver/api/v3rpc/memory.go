@@ -0,0 +1,304 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// connMemoryTracker accounts the memory held by the pending responses and
+// the watch buffers of each client connection and keeps it under the
+// configured limits. A request exceeding the limit of its connection fails,
+// and when all connections exceed the total limit, the streams of the
+// heaviest connection are canceled so that the member is not killed for
+// running out of memory.
+//
+// It is the stats handler of the gRPC server, which tags the contexts of
+// the connections and RPCs.
+type connMemoryTracker struct {
+	lg *zap.Logger
+	// maxConnBytes is the limit of a connection, unlimited when 0.
+	maxConnBytes int64
+	// maxTotalBytes is the limit of all connections, unlimited when 0.
+	maxTotalBytes int64
+	// accountRangeResponse, if set, is called with the bytes of the range
+	// responses acquired, negative when released.
+	accountRangeResponse func(n int64)
+
+	mu sync.Mutex
+	// total is the memory held by all connections.
+	total int64
+	// evicted is the memory still held by the evicted connections.
+	evicted int64
+	conns   map[*connMemory]struct{}
+}
+
+// connMemory is the memory held by a client connection.
+type connMemory struct {
+	remote string
+
+	// fields below are protected by connMemoryTracker.mu
+	bytes   int64
+	evicted bool
+	streams map[*streamMemory]struct{}
+}
+
+// streamMemory is the memory held by a stream RPC.
+type streamMemory struct {
+	t  *connMemoryTracker
+	cm *connMemory
+	// ctx is the context of the stream, canceled on eviction.
+	ctx *cancellableContext
+}
+
+// unaryMemory is the memory held by the response of a unary RPC until the
+// response is sent.
+type unaryMemory struct {
+	bytes int64
+	// rangeBytes are the bytes of a range response.
+	rangeBytes int64
+}
+
+type connMemoryKey struct{}
+type unaryMemoryKey struct{}
+type streamMemoryKey struct{}
+
+func newConnMemoryTracker(lg *zap.Logger, maxConnBytes, maxTotalBytes int64) *connMemoryTracker {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	return &connMemoryTracker{
+		lg:            lg,
+		maxConnBytes:  maxConnBytes,
+		maxTotalBytes: maxTotalBytes,
+		conns:         make(map[*connMemory]struct{}),
+	}
+}
+
+func (t *connMemoryTracker) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	cm := &connMemory{streams: make(map[*streamMemory]struct{})}
+	if info.RemoteAddr != nil {
+		cm.remote = info.RemoteAddr.String()
+	}
+	t.mu.Lock()
+	t.conns[cm] = struct{}{}
+	t.mu.Unlock()
+	return context.WithValue(ctx, connMemoryKey{}, cm)
+}
+
+func (t *connMemoryTracker) HandleConn(ctx context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnEnd); !ok {
+		return
+	}
+	if cm, ok := ctx.Value(connMemoryKey{}).(*connMemory); ok {
+		// RPCs of the connection release their memory as they end
+		t.mu.Lock()
+		delete(t.conns, cm)
+		t.mu.Unlock()
+	}
+}
+
+func (t *connMemoryTracker) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, unaryMemoryKey{}, &unaryMemory{})
+}
+
+func (t *connMemoryTracker) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if _, ok := s.(*stats.End); !ok {
+		return
+	}
+	cm, _ := ctx.Value(connMemoryKey{}).(*connMemory)
+	um, _ := ctx.Value(unaryMemoryKey{}).(*unaryMemory)
+	if cm != nil && um != nil {
+		t.release(cm, atomic.SwapInt64(&um.bytes, 0))
+	}
+	if um != nil && t.accountRangeResponse != nil {
+		if n := atomic.SwapInt64(&um.rangeBytes, 0); n != 0 {
+			t.accountRangeResponse(-n)
+		}
+	}
+}
+
+// acquire accounts n bytes to cm, evicting the heaviest connection if all
+// connections exceed the total limit.
+func (t *connMemoryTracker) acquire(cm *connMemory, n int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cm.evicted {
+		return rpctypes.ErrGRPCConnectionEvicted
+	}
+	if t.maxConnBytes > 0 && cm.bytes+n > t.maxConnBytes {
+		return rpctypes.ErrGRPCConnectionMemoryExceeded
+	}
+	cm.bytes += n
+	t.total += n
+	connectionMemoryBytes.Add(float64(n))
+
+	if t.maxTotalBytes == 0 || t.total-t.evicted <= t.maxTotalBytes {
+		return nil
+	}
+	var heaviest *connMemory
+	for c := range t.conns {
+		if !c.evicted && (heaviest == nil || c.bytes > heaviest.bytes) {
+			heaviest = c
+		}
+	}
+	t.evictLocked(heaviest)
+	if heaviest == cm {
+		t.releaseLocked(cm, n)
+		return rpctypes.ErrGRPCConnectionEvicted
+	}
+	return nil
+}
+
+func (t *connMemoryTracker) evictLocked(cm *connMemory) {
+	t.lg.Warn(
+		"evicting client connection due to memory pressure",
+		zap.String("remote", cm.remote),
+		zap.Int64("connection-bytes", cm.bytes),
+		zap.Int64("total-bytes", t.total),
+		zap.Int64("max-total-bytes", t.maxTotalBytes),
+		zap.Int("streams", len(cm.streams)),
+	)
+	cm.evicted = true
+	t.evicted += cm.bytes
+	connectionEvictions.Inc()
+	for sm := range cm.streams {
+		sm.ctx.Cancel(rpctypes.ErrGRPCConnectionEvicted)
+	}
+}
+
+func (t *connMemoryTracker) release(cm *connMemory, n int64) {
+	if n == 0 {
+		return
+	}
+	t.mu.Lock()
+	t.releaseLocked(cm, n)
+	t.mu.Unlock()
+}
+
+func (t *connMemoryTracker) releaseLocked(cm *connMemory, n int64) {
+	cm.bytes -= n
+	t.total -= n
+	if cm.evicted {
+		t.evicted -= n
+	}
+	connectionMemoryBytes.Sub(float64(n))
+}
+
+// unaryInterceptor accounts the response of a unary RPC until it is sent.
+func (t *connMemoryTracker) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	cm, _ := ctx.Value(connMemoryKey{}).(*connMemory)
+	um, _ := ctx.Value(unaryMemoryKey{}).(*unaryMemory)
+	if cm == nil || um == nil {
+		return resp, nil
+	}
+	n := messageSize(resp)
+	if err = t.acquire(cm, n); err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&um.bytes, n)
+	if t.accountRangeResponse != nil && info.FullMethod == rangeMethod {
+		t.accountRangeResponse(n)
+		atomic.AddInt64(&um.rangeBytes, n)
+	}
+	return resp, nil
+}
+
+// streamInterceptor accounts the messages sent on a stream RPC and allows
+// evicting it.
+func (t *connMemoryTracker) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	cm, ok := ss.Context().Value(connMemoryKey{}).(*connMemory)
+	if !ok {
+		return handler(srv, ss)
+	}
+	sm := &streamMemory{t: t, cm: cm}
+	sm.ctx = newCancellableContext(context.WithValue(ss.Context(), streamMemoryKey{}, sm))
+
+	t.mu.Lock()
+	evicted := cm.evicted
+	if !evicted {
+		cm.streams[sm] = struct{}{}
+	}
+	t.mu.Unlock()
+	if evicted {
+		return rpctypes.ErrGRPCConnectionEvicted
+	}
+	defer func() {
+		t.mu.Lock()
+		delete(cm.streams, sm)
+		t.mu.Unlock()
+		sm.ctx.Cancel(nil)
+	}()
+	return handler(srv, &memoryAccountingStream{serverStreamWithCtx: serverStreamWithCtx{ServerStream: ss, ctx: sm.ctx}, sm: sm})
+}
+
+// streamMemoryFromContext returns the memory accounting of the stream of
+// ctx, or nil if memory is not tracked.
+func streamMemoryFromContext(ctx context.Context) *streamMemory {
+	sm, _ := ctx.Value(streamMemoryKey{}).(*streamMemory)
+	return sm
+}
+
+// acquire accounts n bytes buffered by the stream. If the limits are
+// exceeded, the stream is canceled with the returned error.
+func (sm *streamMemory) acquire(n int64) error {
+	if sm == nil {
+		return nil
+	}
+	err := sm.t.acquire(sm.cm, n)
+	if err != nil {
+		sm.ctx.Cancel(err)
+	}
+	return err
+}
+
+func (sm *streamMemory) release(n int64) {
+	if sm != nil {
+		sm.t.release(sm.cm, n)
+	}
+}
+
+type memoryAccountingStream struct {
+	serverStreamWithCtx
+	sm *streamMemory
+}
+
+func (s *memoryAccountingStream) SendMsg(m interface{}) error {
+	n := messageSize(m)
+	if err := s.sm.acquire(n); err != nil {
+		return err
+	}
+	defer s.sm.release(n)
+	return s.ServerStream.SendMsg(m)
+}
+
+func messageSize(m interface{}) int64 {
+	if s, ok := m.(interface{ Size() int }); ok {
+		return int64(s.Size())
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent int
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func (s *fakeServerStream) SendMsg(m interface{}) error {
+	s.sent++
+	return nil
+}
+
+func newTrackedConn(t *connMemoryTracker, port int) context.Context {
+	return t.TagConn(context.Background(), &stats.ConnTagInfo{RemoteAddr: &net.TCPAddr{Port: port}})
+}
+
+func TestConnMemoryTrackerConnectionLimit(t *testing.T) {
+	tr := newConnMemoryTracker(zaptest.NewLogger(t), 100, 0)
+	cm := newTrackedConn(tr, 1).Value(connMemoryKey{}).(*connMemory)
+
+	if err := tr.acquire(cm, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.acquire(cm, 60); err != rpctypes.ErrGRPCConnectionMemoryExceeded {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrGRPCConnectionMemoryExceeded)
+	}
+	tr.release(cm, 60)
+	if err := tr.acquire(cm, 60); err != nil {
+		t.Fatal(err)
+	}
+	if cm.bytes != 60 || tr.total != 60 {
+		t.Errorf("connection bytes = %d, total = %d, want 60, 60", cm.bytes, tr.total)
+	}
+}
+
+func TestConnMemoryTrackerEviction(t *testing.T) {
+	tr := newConnMemoryTracker(zaptest.NewLogger(t), 0, 100)
+	heavy := newTrackedConn(tr, 1)
+	light := newTrackedConn(tr, 2)
+
+	// a stream of the heavy connection buffers 80 bytes
+	acquired := make(chan struct{})
+	done := make(chan error, 1)
+	ss := &fakeServerStream{ctx: heavy}
+	go func() {
+		done <- tr.streamInterceptor(nil, ss, &grpc.StreamServerInfo{}, func(_ interface{}, stream grpc.ServerStream) error {
+			if err := streamMemoryFromContext(stream.Context()).acquire(80); err != nil {
+				return err
+			}
+			close(acquired)
+			<-stream.Context().Done()
+			return stream.Context().Err()
+		})
+	}()
+	<-acquired
+
+	lcm := light.Value(connMemoryKey{}).(*connMemory)
+	if err := tr.acquire(lcm, 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != rpctypes.ErrGRPCConnectionEvicted {
+		t.Fatalf("stream err = %v, want %v", err, rpctypes.ErrGRPCConnectionEvicted)
+	}
+	hcm := heavy.Value(connMemoryKey{}).(*connMemory)
+	if err := tr.acquire(hcm, 1); err != rpctypes.ErrGRPCConnectionEvicted {
+		t.Errorf("err = %v, want %v", err, rpctypes.ErrGRPCConnectionEvicted)
+	}
+
+	// the light connection is not evicted while the heavy one releases its memory
+	if err := tr.acquire(lcm, 30); err != nil {
+		t.Fatal(err)
+	}
+	tr.release(hcm, 80)
+	if tr.total != 60 || tr.evicted != 0 {
+		t.Errorf("total = %d, evicted = %d, want 60, 0", tr.total, tr.evicted)
+	}
+}
+
+func TestConnMemoryTrackerStreamSend(t *testing.T) {
+	tr := newConnMemoryTracker(zaptest.NewLogger(t), 10, 0)
+	ctx := newTrackedConn(tr, 1)
+	ss := &fakeServerStream{ctx: ctx}
+
+	err := tr.streamInterceptor(nil, ss, &grpc.StreamServerInfo{}, func(_ interface{}, stream grpc.ServerStream) error {
+		if err := stream.SendMsg(sizedMessage(8)); err != nil {
+			return err
+		}
+		// the memory of a message is released once it is sent
+		if err := stream.SendMsg(sizedMessage(8)); err != nil {
+			return err
+		}
+		return stream.SendMsg(sizedMessage(11))
+	})
+	if err != rpctypes.ErrGRPCConnectionMemoryExceeded {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrGRPCConnectionMemoryExceeded)
+	}
+	if ss.sent != 2 {
+		t.Errorf("sent %d messages, want 2", ss.sent)
+	}
+	if cm := ctx.Value(connMemoryKey{}).(*connMemory); cm.bytes != 0 || len(cm.streams) != 0 {
+		t.Errorf("connection bytes = %d, streams = %d, want 0, 0", cm.bytes, len(cm.streams))
+	}
+}
+
+func TestConnMemoryTrackerRangeResponse(t *testing.T) {
+	tr := newConnMemoryTracker(zaptest.NewLogger(t), 0, 0)
+	var ranges int64
+	tr.accountRangeResponse = func(n int64) { ranges += n }
+	conn := newTrackedConn(tr, 1)
+	call := func(method string) context.Context {
+		ctx := tr.TagRPC(conn, &stats.RPCTagInfo{FullMethodName: method})
+		_, err := tr.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, interface{}) (interface{}, error) {
+			return sizedMessage(10), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ctx
+	}
+
+	rangeCtx := call(rangeMethod)
+	putCtx := call("/etcdserverpb.KV/Put")
+	tr.HandleRPC(putCtx, &stats.End{})
+	if ranges != 10 {
+		t.Errorf("range response bytes = %d, want 10", ranges)
+	}
+	// the range response is released once sent
+	tr.HandleRPC(rangeCtx, &stats.End{})
+	if ranges != 0 {
+		t.Errorf("range response bytes = %d after sent, want 0", ranges)
+	}
+}
+
+type sizedMessage int
+
+func (m sizedMessage) Size() int { return int(m) }
@@ -48,6 +48,89 @@ var (
 	},
 		[]string{"type", "client_api_version"},
 	)
+
+	connectionMemoryBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "client_connection_memory_bytes",
+		Help:      "The number of bytes held by the pending responses and watch buffers of client connections.",
+	})
+
+	connectionEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "client_connection_evictions_total",
+		Help:      "The total number of client connections evicted due to memory pressure.",
+	})
+
+	largeRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "large_requests_total",
+		Help:      "The total number of large requests by the action of the large request policy.",
+	}, []string{"action"})
+
+	rangeResultKeys = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "range_result_keys",
+		Help:      "The distribution of the number of keys returned by ranges, including the ranges of transactions.",
+
+		// lowest bucket start of upper bound 1 with factor 4
+		// highest bucket start of 1 * 4^9 == 262144
+		Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+	})
+
+	rangeResultBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "range_result_bytes",
+		Help:      "The distribution of the size in bytes of range responses, including the ranges of transactions.",
+
+		// lowest bucket start of upper bound 64 bytes with factor 4
+		// highest bucket start of 64 bytes * 4^10 == 64 MiB
+		Buckets: prometheus.ExponentialBuckets(64, 4, 11),
+	})
+
+	txnOps = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "txn_ops",
+		Help:      "The distribution of the number of operations of the executed branch of transactions.",
+
+		// lowest bucket start of upper bound 1 with factor 2
+		// highest bucket start of 1 * 2^10 == 1024
+		Buckets: prometheus.ExponentialBuckets(1, 2, 11),
+	})
+
+	putValueBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "put_value_bytes",
+		Help:      "The distribution of the size in bytes of put values, including the puts of transactions.",
+
+		// lowest bucket start of upper bound 16 bytes with factor 4
+		// highest bucket start of 16 bytes * 4^9 == 4 MiB
+		Buckets: prometheus.ExponentialBuckets(16, 4, 10),
+	})
+
+	watchResponseEvents = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "watch_response_events",
+		Help:      "The distribution of the number of events sent in a watch response, before fragmentation.",
+
+		// lowest bucket start of upper bound 1 with factor 2
+		// highest bucket start of 1 * 2^12 == 4096
+		Buckets: prometheus.ExponentialBuckets(1, 2, 13),
+	})
+
+	watchStreamsExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "watch_streams_expired_total",
+		Help:      "The total number of watch streams closed for exceeding their maximum age.",
+	})
 )
 
 func init() {
@@ -55,4 +138,13 @@ func init() {
 	prometheus.MustRegister(receivedBytes)
 	prometheus.MustRegister(streamFailures)
 	prometheus.MustRegister(clientRequests)
+	prometheus.MustRegister(connectionMemoryBytes)
+	prometheus.MustRegister(connectionEvictions)
+	prometheus.MustRegister(largeRequests)
+	prometheus.MustRegister(rangeResultKeys)
+	prometheus.MustRegister(rangeResultBytes)
+	prometheus.MustRegister(txnOps)
+	prometheus.MustRegister(putValueBytes)
+	prometheus.MustRegister(watchResponseEvents)
+	prometheus.MustRegister(watchStreamsExpired)
 }
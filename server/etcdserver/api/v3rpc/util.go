@@ -38,8 +38,13 @@ var toGRPCErrorMap = map[error]error{
 	membership.ErrPeerURLexists:       rpctypes.ErrGRPCPeerURLExist,
 	membership.ErrMemberNotLearner:    rpctypes.ErrGRPCMemberNotLearner,
 	membership.ErrTooManyLearners:     rpctypes.ErrGRPCTooManyLearners,
+	membership.ErrMemberNotVoter:      rpctypes.ErrGRPCMemberNotVoter,
+	membership.ErrLastVotingMember:    rpctypes.ErrGRPCLastVotingMember,
 	errors.ErrNotEnoughStartedMembers: rpctypes.ErrMemberNotEnoughStarted,
 	errors.ErrLearnerNotReady:         rpctypes.ErrGRPCLearnerNotReady,
+	errors.ErrDemoteLeader:            rpctypes.ErrGRPCDemoteLeader,
+	errors.ErrDemoteNotAcknowledged:   rpctypes.ErrGRPCDemoteNotAcknowledged,
+	errors.ErrDemoteNotSupported:      rpctypes.ErrGRPCDemoteNotSupported,
 
 	mvcc.ErrCompacted:         rpctypes.ErrGRPCCompacted,
 	mvcc.ErrFutureRev:         rpctypes.ErrGRPCFutureRev,
@@ -57,8 +62,13 @@ var toGRPCErrorMap = map[error]error{
 	errors.ErrTimeoutWaitAppliedIndex:    rpctypes.ErrGRPCTimeoutWaitAppliedIndex,
 	errors.ErrUnhealthy:                  rpctypes.ErrGRPCUnhealthy,
 	errors.ErrKeyNotFound:                rpctypes.ErrGRPCKeyNotFound,
+	errors.ErrValueNotInteger:            rpctypes.ErrGRPCValueNotInteger,
+	errors.ErrIntegerOverflow:            rpctypes.ErrGRPCIntegerOverflow,
 	errors.ErrCorrupt:                    rpctypes.ErrGRPCCorrupt,
 	errors.ErrBadLeaderTransferee:        rpctypes.ErrGRPCBadLeaderTransferee,
+	errors.ErrTooManyLoggedRanges:        rpctypes.ErrGRPCTooManyLoggedRanges,
+	errors.ErrMemoryBudgetExceeded:       rpctypes.ErrGRPCMemoryBudgetExceeded,
+	errors.ErrInvalidValueRegex:          rpctypes.ErrGRPCInvalidValueRegex,
 
 	errors.ErrClusterVersionUnavailable:      rpctypes.ErrGRPCClusterVersionUnavailable,
 	errors.ErrWrongDowngradeVersionFormat:    rpctypes.ErrGRPCWrongDowngradeVersionFormat,
@@ -15,9 +15,11 @@
 package v3rpc
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"math/rand"
+	"regexp"
 	"sync"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/etcdserver"
 	"go.etcd.io/etcd/server/v3/etcdserver/apply"
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
 	"go.etcd.io/etcd/server/v3/storage/mvcc"
 
 	"go.uber.org/zap"
@@ -41,10 +44,14 @@ type watchServer struct {
 	memberID  int64
 
 	maxRequestBytes int
+	// maxStreamAge is the maximum age of the streams, 0 if unlimited.
+	maxStreamAge time.Duration
 
 	sg        apply.RaftStatusGetter
 	watchable mvcc.WatchableKV
 	ag        AuthGetter
+	// memoryBudgetExceeded returns whether to shed a request of the type.
+	memoryBudgetExceeded func(requestType string) bool
 }
 
 // NewWatchServer returns a new watch server.
@@ -56,10 +63,13 @@ func NewWatchServer(s *etcdserver.EtcdServer) pb.WatchServer {
 		memberID:  int64(s.MemberId()),
 
 		maxRequestBytes: int(s.Cfg.MaxRequestBytes + grpcOverheadBytes),
+		maxStreamAge:    s.Cfg.WatchMaxStreamAge,
 
 		sg:        s,
 		watchable: s.Watchable(),
 		ag:        s,
+
+		memoryBudgetExceeded: s.MemoryBudgetExceeded,
 	}
 	if srv.lg == nil {
 		srv.lg = zap.NewNop()
@@ -106,6 +116,16 @@ func SetProgressReportInterval(newTimeout time.Duration) {
 	progressReportIntervalMu.Unlock()
 }
 
+// streamAge returns the age the stream expires at, the maximum age with
+// rand(1/10*maxAge) as jitter for the streams created around the same time
+// not to expire at once.
+func streamAge(maxAge time.Duration) time.Duration {
+	if maxAge < 10 {
+		return maxAge
+	}
+	return maxAge + time.Duration(rand.Int63n(int64(maxAge)/10))
+}
+
 // We send ctrl response inside the read loop. We do not want
 // send to block read, but we still want ctrl response we sent to
 // be serialized. Thus we use a buffered chan to solve the problem.
@@ -124,20 +144,26 @@ type serverWatchStream struct {
 	memberID  int64
 
 	maxRequestBytes int
+	maxAge          time.Duration
 
 	sg        apply.RaftStatusGetter
 	watchable mvcc.WatchableKV
 	ag        AuthGetter
 
+	memoryBudgetExceeded func(requestType string) bool
+
 	gRPCStream  pb.Watch_WatchServer
 	watchStream mvcc.WatchStream
 	ctrlStream  chan *pb.WatchResponse
 
-	// mu protects progress, prevKV, fragment
+	// mu protects progress, progressIntervals, prevKV, fragment
 	mu sync.RWMutex
 	// tracks the watchID that stream might need to send progress to
 	// TODO: combine progress and prevKV into a single struct?
 	progress map[mvcc.WatchID]bool
+	// records the progress notify intervals of the watchers requesting
+	// their own, notified apart from the progress report interval
+	progressIntervals map[mvcc.WatchID]time.Duration
 	// record watch IDs that need return previous key-value pair
 	prevKV map[mvcc.WatchID]bool
 	// records fragmented watch IDs
@@ -145,6 +171,9 @@ type serverWatchStream struct {
 
 	// closec indicates the stream is closed.
 	closec chan struct{}
+	// expiredc is closed once the stream exceeded its maximum age, the
+	// progress of its watches sent.
+	expiredc chan struct{}
 
 	// wg waits for the send loop to complete
 	wg sync.WaitGroup
@@ -158,21 +187,26 @@ func (ws *watchServer) Watch(stream pb.Watch_WatchServer) (err error) {
 		memberID:  ws.memberID,
 
 		maxRequestBytes: ws.maxRequestBytes,
+		maxAge:          ws.maxStreamAge,
 
 		sg:        ws.sg,
 		watchable: ws.watchable,
 		ag:        ws.ag,
 
+		memoryBudgetExceeded: ws.memoryBudgetExceeded,
+
 		gRPCStream:  stream,
 		watchStream: ws.watchable.NewWatchStream(),
 		// chan for sending control response like watcher created and canceled.
 		ctrlStream: make(chan *pb.WatchResponse, ctrlStreamBufLen),
 
-		progress: make(map[mvcc.WatchID]bool),
-		prevKV:   make(map[mvcc.WatchID]bool),
-		fragment: make(map[mvcc.WatchID]bool),
+		progress:          make(map[mvcc.WatchID]bool),
+		progressIntervals: make(map[mvcc.WatchID]time.Duration),
+		prevKV:            make(map[mvcc.WatchID]bool),
+		fragment:          make(map[mvcc.WatchID]bool),
 
-		closec: make(chan struct{}),
+		closec:   make(chan struct{}),
+		expiredc: make(chan struct{}),
 	}
 
 	sws.wg.Add(1)
@@ -218,6 +252,11 @@ func (ws *watchServer) Watch(stream pb.Watch_WatchServer) (err error) {
 		if err == context.Canceled {
 			err = rpctypes.ErrGRPCWatchCanceled
 		}
+	case <-sws.expiredc:
+		// the clients resume the watches on a new stream
+		sws.lg.Debug("closing watch stream exceeding its maximum age", zap.Duration("max-stream-age", sws.maxAge))
+		watchStreamsExpired.Inc()
+		err = watchStreamExpiredError(sws.watchStream.Rev())
 	}
 
 	sws.close()
@@ -267,13 +306,23 @@ func (sws *serverWatchStream) recvLoop() error {
 				creq.RangeEnd = []byte{}
 			}
 
+			filters, ferr := FiltersFromRequest(creq)
+
+			var cancelReason string
 			if !sws.isWatchPermitted(creq) {
+				cancelReason = rpctypes.ErrGRPCPermissionDenied.Error()
+			} else if ferr != nil {
+				cancelReason = ferr.Error()
+			} else if sws.memoryBudgetExceeded("watch") {
+				cancelReason = rpctypes.ErrGRPCMemoryBudgetExceeded.Error()
+			}
+			if cancelReason != "" {
 				wr := &pb.WatchResponse{
 					Header:       sws.newResponseHeader(sws.watchStream.Rev()),
 					WatchId:      creq.WatchId,
 					Canceled:     true,
 					Created:      true,
-					CancelReason: rpctypes.ErrGRPCPermissionDenied.Error(),
+					CancelReason: cancelReason,
 				}
 
 				select {
@@ -284,8 +333,6 @@ func (sws *serverWatchStream) recvLoop() error {
 				}
 			}
 
-			filters := FiltersFromRequest(creq)
-
 			wsrev := sws.watchStream.Rev()
 			rev := creq.StartRevision
 			if rev == 0 {
@@ -294,9 +341,16 @@ func (sws *serverWatchStream) recvLoop() error {
 			id, err := sws.watchStream.Watch(mvcc.WatchID(creq.WatchId), creq.Key, creq.RangeEnd, rev, filters...)
 			if err == nil {
 				sws.mu.Lock()
-				if creq.ProgressNotify {
+				if creq.ProgressNotify || creq.ProgressNotifyInterval > 0 {
 					sws.progress[id] = true
 				}
+				if creq.ProgressNotifyInterval > 0 {
+					interval := time.Duration(creq.ProgressNotifyInterval)
+					if interval < minWatchProgressInterval {
+						interval = minWatchProgressInterval
+					}
+					sws.progressIntervals[id] = interval
+				}
 				if creq.PrevKv {
 					sws.prevKV[id] = true
 				}
@@ -332,13 +386,19 @@ func (sws *serverWatchStream) recvLoop() error {
 					}
 					sws.mu.Lock()
 					delete(sws.progress, mvcc.WatchID(id))
+					delete(sws.progressIntervals, mvcc.WatchID(id))
 					delete(sws.prevKV, mvcc.WatchID(id))
 					delete(sws.fragment, mvcc.WatchID(id))
 					sws.mu.Unlock()
 				}
 			}
 		case *pb.WatchRequest_ProgressRequest:
-			if uv.ProgressRequest != nil {
+			if uv.ProgressRequest != nil && len(uv.ProgressRequest.WatchIds) > 0 {
+				// sent through the watch stream, once the watchers are synced
+				for _, id := range uv.ProgressRequest.WatchIds {
+					sws.watchStream.RequestProgress(mvcc.WatchID(id))
+				}
+			} else if uv.ProgressRequest != nil {
 				sws.ctrlStream <- &pb.WatchResponse{
 					Header:  sws.newResponseHeader(sws.watchStream.Rev()),
 					WatchId: -1, // response is not associated with any WatchId and will be broadcast to all watch channels
@@ -359,34 +419,84 @@ func (sws *serverWatchStream) sendLoop() {
 	ids := make(map[mvcc.WatchID]struct{})
 	// watch responses pending on a watch id creation message
 	pending := make(map[mvcc.WatchID][]*pb.WatchResponse)
+	// memory accounting of the stream, nil if memory is not tracked
+	sm := streamMemoryFromContext(sws.gRPCStream.Context())
+	var pendingBytes int64
 
 	interval := GetProgressReportInterval()
 	progressTicker := time.NewTicker(interval)
 
+	// the watchers with their own progress notify interval are notified at
+	// their deadlines, the progress timer firing at the closest one
+	progressDeadlines := make(map[mvcc.WatchID]time.Time)
+	var progressTimer *time.Timer
+	var progressTimerc <-chan time.Time
+	resetProgressTimer := func() {
+		if progressTimer != nil {
+			progressTimer.Stop()
+		}
+		progressTimerc = nil
+		var next time.Time
+		for _, d := range progressDeadlines {
+			if next.IsZero() || d.Before(next) {
+				next = d
+			}
+		}
+		if !next.IsZero() {
+			progressTimer = time.NewTimer(time.Until(next))
+			progressTimerc = progressTimer.C
+		}
+	}
+
+	var expirec <-chan time.Time
+	if sws.maxAge > 0 {
+		expireTimer := time.NewTimer(streamAge(sws.maxAge))
+		defer expireTimer.Stop()
+		expirec = expireTimer.C
+	}
+	// flushing is the number of responses left to send before closing the
+	// expired stream, queued up to the progress of its watches for the
+	// clients to resume them from their current revision; -1 until the
+	// stream expires.
+	flushing := -1
+
 	defer func() {
 		progressTicker.Stop()
+		if progressTimer != nil {
+			progressTimer.Stop()
+		}
 		// drain the chan to clean up pending events
 		for ws := range sws.watchStream.Chan() {
 			mvcc.ReportEventReceived(len(ws.Events))
+			mvcc.ReportEventBytesReceived(mvcc.EventsBytes(ws.Events))
 		}
 		for _, wrs := range pending {
 			for _, ws := range wrs {
 				mvcc.ReportEventReceived(len(ws.Events))
 			}
 		}
+		sm.release(pendingBytes)
 	}()
 
 	for {
+		if flushing == 0 {
+			close(sws.expiredc)
+			return
+		}
 		select {
 		case wresp, ok := <-sws.watchStream.Chan():
 			if !ok {
 				return
 			}
+			if flushing > 0 {
+				flushing--
+			}
 
 			// TODO: evs is []mvccpb.Event type
 			// either return []*mvccpb.Event from the mvcc package
 			// or define protocol buffer with []mvccpb.Event.
 			evs := wresp.Events
+			mvcc.ReportEventBytesReceived(mvcc.EventsBytes(evs))
 			events := make([]*mvccpb.Event, len(evs))
 			sws.mu.RLock()
 			needPrevKV := sws.prevKV[wresp.WatchID]
@@ -413,12 +523,21 @@ func (sws *serverWatchStream) sendLoop() {
 
 			if _, okID := ids[wresp.WatchID]; !okID {
 				// buffer if id not yet announced
+				n := messageSize(wr)
+				if err := sm.acquire(n); err != nil {
+					sws.lg.Warn("failed to buffer watch response", zap.Int64("watch-id", int64(wresp.WatchID)), zap.Error(err))
+					return
+				}
+				pendingBytes += n
 				wrs := append(pending[wresp.WatchID], wr)
 				pending[wresp.WatchID] = wrs
 				continue
 			}
 
 			mvcc.ReportEventReceived(len(evs))
+			if len(evs) > 0 {
+				watchResponseEvents.Observe(float64(len(evs)))
+			}
 
 			sws.mu.RLock()
 			fragmented, ok := sws.fragment[wresp.WatchID]
@@ -467,13 +586,28 @@ func (sws *serverWatchStream) sendLoop() {
 			wid := mvcc.WatchID(c.WatchId)
 			if c.Canceled {
 				delete(ids, wid)
+				if _, ok := progressDeadlines[wid]; ok {
+					delete(progressDeadlines, wid)
+					resetProgressTimer()
+				}
 				continue
 			}
 			if c.Created {
+				sws.mu.RLock()
+				progressInterval, ok := sws.progressIntervals[wid]
+				sws.mu.RUnlock()
+				if ok {
+					progressDeadlines[wid] = time.Now().Add(progressInterval)
+					resetProgressTimer()
+				}
+
 				// flush buffered events
 				ids[wid] = struct{}{}
 				for _, v := range pending[wid] {
 					mvcc.ReportEventReceived(len(v.Events))
+					if len(v.Events) > 0 {
+						watchResponseEvents.Observe(float64(len(v.Events)))
+					}
 					if err := sws.gRPCStream.Send(v); err != nil {
 						if isClientCtxErr(sws.gRPCStream.Context().Err(), err) {
 							sws.lg.Debug("failed to send pending watch response to gRPC stream", zap.Error(err))
@@ -483,6 +617,9 @@ func (sws *serverWatchStream) sendLoop() {
 						}
 						return
 					}
+					n := messageSize(v)
+					sm.release(n)
+					pendingBytes -= n
 				}
 				delete(pending, wid)
 			}
@@ -490,6 +627,9 @@ func (sws *serverWatchStream) sendLoop() {
 		case <-progressTicker.C:
 			sws.mu.Lock()
 			for id, ok := range sws.progress {
+				if _, own := sws.progressIntervals[id]; own {
+					continue
+				}
 				if ok {
 					sws.watchStream.RequestProgress(id)
 				}
@@ -497,6 +637,34 @@ func (sws *serverWatchStream) sendLoop() {
 			}
 			sws.mu.Unlock()
 
+		case <-progressTimerc:
+			now := time.Now()
+			sws.mu.Lock()
+			for id, deadline := range progressDeadlines {
+				if deadline.After(now) {
+					continue
+				}
+				progressInterval, ok := sws.progressIntervals[id]
+				if !ok {
+					// canceled, its cancel response not yet sent
+					delete(progressDeadlines, id)
+					continue
+				}
+				if sws.progress[id] {
+					sws.watchStream.RequestProgress(id)
+				}
+				sws.progress[id] = true
+				progressDeadlines[id] = now.Add(progressInterval)
+			}
+			sws.mu.Unlock()
+			resetProgressTimer()
+
+		case <-expirec:
+			for id := range ids {
+				sws.watchStream.RequestProgress(id)
+			}
+			flushing = len(sws.watchStream.Chan())
+
 		case <-sws.closec:
 			return
 		}
@@ -569,9 +737,27 @@ func filterNoPut(e mvccpb.Event) bool {
 	return e.Type == mvccpb.PUT
 }
 
+// filterValuePrefix returns the filter of the put events whose value does not
+// have the prefix.
+func filterValuePrefix(prefix []byte) mvcc.FilterFunc {
+	return func(e mvccpb.Event) bool {
+		return e.Type == mvccpb.PUT && !bytes.HasPrefix(e.Kv.Value, prefix)
+	}
+}
+
+// filterValueRegex returns the filter of the put events whose value does not
+// match re.
+func filterValueRegex(re *regexp.Regexp) mvcc.FilterFunc {
+	return func(e mvccpb.Event) bool {
+		return e.Type == mvccpb.PUT && !re.Match(e.Kv.Value)
+	}
+}
+
 // FiltersFromRequest returns "mvcc.FilterFunc" from a given watch create request.
-func FiltersFromRequest(creq *pb.WatchCreateRequest) []mvcc.FilterFunc {
-	filters := make([]mvcc.FilterFunc, 0, len(creq.Filters))
+// It returns errors.ErrInvalidValueRegex if the value regex of the
+// request is not a valid RE2 regular expression.
+func FiltersFromRequest(creq *pb.WatchCreateRequest) ([]mvcc.FilterFunc, error) {
+	filters := make([]mvcc.FilterFunc, 0, len(creq.Filters)+2)
 	for _, ft := range creq.Filters {
 		switch ft {
 		case pb.WatchCreateRequest_NOPUT:
@@ -581,5 +767,15 @@ func FiltersFromRequest(creq *pb.WatchCreateRequest) []mvcc.FilterFunc {
 		default:
 		}
 	}
-	return filters
+	if len(creq.ValuePrefix) != 0 {
+		filters = append(filters, filterValuePrefix(creq.ValuePrefix))
+	}
+	if creq.ValueRegex != "" {
+		re, err := regexp.Compile(creq.ValueRegex)
+		if err != nil {
+			return nil, errors.ErrInvalidValueRegex
+		}
+		filters = append(filters, filterValueRegex(re))
+	}
+	return filters, nil
 }
@@ -0,0 +1,198 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	EventAlarmRaised   = "alarm-raised"
+	EventAlarmCleared  = "alarm-cleared"
+	EventLeaderChanged = "leader-changed"
+
+	// DefaultRetries is the default number of retries of a failed post.
+	DefaultRetries = 3
+
+	eventQueueLen = 64
+	postTimeout   = 5 * time.Second
+)
+
+// Event is a notification posted to the webhooks, and the data of the
+// payload templates.
+type Event struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	ClusterID string    `json:"cluster-id"`
+	// MemberID is the member posting the event.
+	MemberID string `json:"member-id"`
+
+	// Alarm is the alarm raised or cleared on the member AlarmMemberID.
+	Alarm         string `json:"alarm,omitempty"`
+	AlarmMemberID string `json:"alarm-member-id,omitempty"`
+
+	// Leader is the new leader, empty if there is no leader.
+	Leader string `json:"leader,omitempty"`
+}
+
+type Config struct {
+	// URLs are the endpoints the events are posted to.
+	URLs []string
+	// Template is the text/template of the payloads, executed on an Event.
+	// The payloads are the JSON encoding of the events when empty.
+	Template string
+	// Retries is the number of retries of a failed post.
+	Retries int
+}
+
+// ParseTemplate parses a payload template. The templates may call json to
+// encode a value, e.g. {"text": {{json .Type}}}.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+}
+
+// Notifier posts events to the webhooks in background.
+type Notifier struct {
+	lg      *zap.Logger
+	urls    []string
+	tmpl    *template.Template
+	retries int
+
+	client        *http.Client
+	retryInterval time.Duration
+
+	eventc chan Event
+}
+
+func NewNotifier(lg *zap.Logger, cfg Config) (*Notifier, error) {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	n := &Notifier{
+		lg:            lg,
+		urls:          cfg.URLs,
+		retries:       cfg.Retries,
+		client:        &http.Client{Timeout: postTimeout},
+		retryInterval: time.Second,
+		eventc:        make(chan Event, eventQueueLen),
+	}
+	if cfg.Template != "" {
+		tmpl, err := ParseTemplate(cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+		n.tmpl = tmpl
+	}
+	return n, nil
+}
+
+// Notify queues the event without blocking. The event is dropped if too
+// many events are queued.
+func (n *Notifier) Notify(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	select {
+	case n.eventc <- ev:
+	default:
+		n.lg.Warn("dropped webhook event; too many events queued", zap.String("type", ev.Type))
+	}
+}
+
+// Run posts the queued events until stopc is closed.
+func (n *Notifier) Run(stopc <-chan struct{}) {
+	for {
+		select {
+		case ev := <-n.eventc:
+			payload, err := n.payload(ev)
+			if err != nil {
+				n.lg.Warn("failed to execute webhook payload template", zap.String("type", ev.Type), zap.Error(err))
+				continue
+			}
+			for _, u := range n.urls {
+				if err = n.post(stopc, u, payload); err != nil {
+					n.lg.Warn(
+						"failed to post webhook event",
+						zap.String("url", u),
+						zap.String("type", ev.Type),
+						zap.Int("retries", n.retries),
+						zap.Error(err),
+					)
+				}
+			}
+		case <-stopc:
+			return
+		}
+	}
+}
+
+func (n *Notifier) payload(ev Event) ([]byte, error) {
+	if n.tmpl == nil {
+		return json.Marshal(ev)
+	}
+	var buf bytes.Buffer
+	err := n.tmpl.Execute(&buf, ev)
+	return buf.Bytes(), err
+}
+
+// post posts the payload to u, retrying with an exponential backoff.
+func (n *Notifier) post(stopc <-chan struct{}, u string, payload []byte) (err error) {
+	wait := n.retryInterval
+	for i := 0; ; i++ {
+		if err = n.postOnce(u, payload); err == nil || i >= n.retries {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+			wait *= 2
+		case <-stopc:
+			return err
+		}
+	}
+}
+
+func (n *Notifier) postOnce(u string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %q", resp.Status)
+	}
+	return nil
+}
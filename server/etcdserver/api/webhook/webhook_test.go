@@ -0,0 +1,82 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+)
+
+func TestNotifierRetries(t *testing.T) {
+	bodyc := make(chan []byte, 10)
+	failures := 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		bodyc <- b
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(zaptest.NewLogger(t), Config{URLs: []string{srv.URL}, Retries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.retryInterval = time.Millisecond
+	stopc := make(chan struct{})
+	defer close(stopc)
+	go n.Run(stopc)
+
+	n.Notify(Event{Type: EventAlarmRaised, Alarm: "NOSPACE", AlarmMemberID: "1"})
+	select {
+	case b := <-bodyc:
+		var ev Event
+		if err = json.Unmarshal(b, &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type != EventAlarmRaised || ev.Alarm != "NOSPACE" || ev.Time.IsZero() {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the webhook post")
+	}
+}
+
+func TestNotifierTemplate(t *testing.T) {
+	n, err := NewNotifier(zaptest.NewLogger(t), Config{Template: `{"text": {{json (printf "%s on %s" .Type .Leader)}}}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := n.payload(Event{Type: EventLeaderChanged, Leader: "8e9e05c52164694d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := `{"text": "leader-changed on 8e9e05c52164694d"}`; string(b) != w {
+		t.Errorf("payload = %s, want %s", b, w)
+	}
+
+	if _, err = NewNotifier(zaptest.NewLogger(t), Config{Template: "{{"}); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
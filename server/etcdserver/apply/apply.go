@@ -180,7 +180,11 @@ func (a *applierV3backend) Compaction(compaction *pb.CompactionRequest) (*pb.Com
 		traceutil.Field{Key: "revision", Value: compaction.Revision},
 	)
 
-	ch, err := a.kv.Compact(trace, compaction.Revision)
+	var rs []mvcc.Retention
+	for _, r := range compaction.Retention {
+		rs = append(rs, mvcc.Retention{Prefix: r.Prefix, Versions: r.Versions, Revision: r.Revision})
+	}
+	ch, err := a.kv.CompactWithRetention(trace, compaction.Revision, rs)
 	if err != nil {
 		return nil, ch, nil, err
 	}
@@ -422,8 +426,9 @@ func (a *applierV3backend) ClusterMemberAttrSet(r *membershippb.ClusterMemberAtt
 	a.cluster.UpdateAttributes(
 		types.ID(r.Member_ID),
 		membership.Attributes{
-			Name:       r.MemberAttributes.Name,
-			ClientURLs: r.MemberAttributes.ClientUrls,
+			Name:             r.MemberAttributes.Name,
+			ClientURLs:       r.MemberAttributes.ClientUrls,
+			ElectionPriority: r.MemberAttributes.ElectionPriority,
 		},
 		shouldApplyV3,
 	)
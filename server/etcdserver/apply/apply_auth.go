@@ -51,17 +51,20 @@ func (aa *authApplierV3) Apply(ctx context.Context, r *pb.InternalRaftRequest, s
 		// does not have header field
 		aa.authInfo.Username = r.Header.Username
 		aa.authInfo.Revision = r.Header.AuthRevision
+		aa.authInfo.Roles = r.Header.Roles
 	}
 	if needAdminPermission(r) {
 		if err := aa.as.IsAdminPermitted(&aa.authInfo); err != nil {
 			aa.authInfo.Username = ""
 			aa.authInfo.Revision = 0
+			aa.authInfo.Roles = nil
 			return &Result{Err: err}
 		}
 	}
 	ret := aa.applierV3.Apply(ctx, r, shouldApplyV3, applyFunc)
 	aa.authInfo.Username = ""
 	aa.authInfo.Revision = 0
+	aa.authInfo.Roles = nil
 	return ret
 }
 
@@ -148,7 +151,7 @@ func (aa *authApplierV3) UserGet(r *pb.AuthUserGetRequest) (*pb.AuthUserGetRespo
 
 func (aa *authApplierV3) RoleGet(r *pb.AuthRoleGetRequest) (*pb.AuthRoleGetResponse, error) {
 	err := aa.as.IsAdminPermitted(&aa.authInfo)
-	if err != nil && !aa.as.HasRole(aa.authInfo.Username, r.Role) {
+	if err != nil && !aa.hasRole(r.Role) {
 		aa.authInfo.Username = ""
 		aa.authInfo.Revision = 0
 		return &pb.AuthRoleGetResponse{}, err
@@ -157,6 +160,19 @@ func (aa *authApplierV3) RoleGet(r *pb.AuthRoleGetRequest) (*pb.AuthRoleGetRespo
 	return aa.applierV3.RoleGet(r)
 }
 
+// hasRole returns whether the user of the request has the role.
+func (aa *authApplierV3) hasRole(role string) bool {
+	if aa.authInfo.Roles != nil {
+		for _, r := range aa.authInfo.Roles {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+	return aa.as.HasRole(aa.authInfo.Username, role)
+}
+
 func needAdminPermission(r *pb.InternalRaftRequest) bool {
 	switch {
 	case r.AuthEnable != nil:
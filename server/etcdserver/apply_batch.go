@@ -0,0 +1,193 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/adt"
+	"go.etcd.io/etcd/pkg/v3/pbutil"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/server/v3/etcdserver/apply"
+	"go.etcd.io/etcd/server/v3/etcdserver/txn"
+	"go.etcd.io/etcd/server/v3/storage/mvcc"
+)
+
+// maxBatchApplyEntries bounds the entries applied in one write batch of the
+// store, for the reads not to wait for the writes of too many entries.
+const maxBatchApplyEntries = 64
+
+// applyEntriesNormalBatch applies the independent entries at the start of es
+// in one write batch of the store, returning the number of entries applied, 0
+// if fewer than two of them are independent.
+func (s *EtcdServer) applyEntriesNormalBatch(es []raftpb.Entry, committed time.Time) int {
+	if !s.Cfg.BatchApply {
+		return 0
+	}
+	wb, ok := s.KV().(mvcc.WriteBatcher)
+	if !ok {
+		return 0
+	}
+	reqs := s.independentRequests(es)
+	if len(reqs) < 2 {
+		return 0
+	}
+	es = es[:len(reqs)]
+	last := es[len(es)-1]
+
+	// the consistent index of the batch is the one of its last entry, set
+	// along with all their changes
+	s.consistIndex.SetConsistentApplyingIndex(last.Index, last.Term)
+	applyStart := time.Now()
+	ids := make([]uint64, len(reqs))
+	needResults := make([]bool, len(reqs))
+	results := make([]*apply.Result, len(reqs))
+	wb.BeginWriteBatch()
+	for i, r := range reqs {
+		ids[i] = r.ID
+		if ids[i] == 0 {
+			ids[i] = r.Header.ID
+		}
+		needResults[i] = s.w.IsRegistered(ids[i])
+		if !needResults[i] && r.Txn != nil {
+			removeNeedlessRangeReqs(r.Txn)
+		}
+		results[i] = s.uberApply.Apply(r, membership.ApplyBoth)
+	}
+	wb.EndWriteBatch()
+	// none of the entries may have changed the store.
+	if s.consistIndex.ConsistentIndex() < last.Index {
+		s.consistIndex.SetConsistentIndex(last.Index, last.Term)
+	}
+
+	now := time.Now()
+	for i := range reqs {
+		if needResults[i] {
+			s.proposalTimes.observeApplied(ids[i], committed, applyStart, now)
+		}
+		s.triggerApplied(ids[i], results[i])
+		s.setAppliedIndex(es[i].Index)
+		s.setTerm(es[i].Term)
+	}
+	return len(reqs)
+}
+
+// independentRequests returns the requests of the normal entries at the start
+// of es that can be applied in one write batch of the store: the puts, the
+// deletes and the write txns not touching the keys written by the requests
+// before them, their reads of the store as of before the batch being the same
+// as if they were applied one after the other.
+func (s *EtcdServer) independentRequests(es []raftpb.Entry) []*pb.InternalRaftRequest {
+	index := s.consistIndex.ConsistentIndex()
+	written := adt.NewIntervalTree()
+	var reqs []*pb.InternalRaftRequest
+	for _, e := range es {
+		if len(reqs) == maxBatchApplyEntries || e.Type != raftpb.EntryNormal || e.Index <= index || len(e.Data) == 0 {
+			break
+		}
+		var r pb.InternalRaftRequest
+		if !pbutil.MaybeUnmarshal(&r, e.Data) || r.Header == nil {
+			break
+		}
+		touched, writes, ok := requestKeys(&r)
+		if !ok {
+			break
+		}
+		for _, iv := range touched {
+			if written.Intersects(iv) {
+				return reqs
+			}
+		}
+		for _, iv := range writes {
+			written.Insert(iv, struct{}{})
+		}
+		reqs = append(reqs, &r)
+	}
+	return reqs
+}
+
+// requestKeys returns the keys touched and the keys written by the put, delete
+// or write txn r, or false for the other requests.
+func requestKeys(r *pb.InternalRaftRequest) (touched, writes []adt.Interval, ok bool) {
+	switch {
+	case r.Put != nil:
+		iv := keyInterval(r.Put.Key, nil)
+		return []adt.Interval{iv}, []adt.Interval{iv}, true
+	case r.DeleteRange != nil:
+		iv := keyInterval(r.DeleteRange.Key, r.DeleteRange.RangeEnd)
+		return []adt.Interval{iv}, []adt.Interval{iv}, true
+	case r.Txn != nil && !txn.IsTxnReadonly(r.Txn):
+		return txnKeys(r.Txn, nil, nil)
+	}
+	return nil, nil, false
+}
+
+func txnKeys(rt *pb.TxnRequest, touched, writes []adt.Interval) ([]adt.Interval, []adt.Interval, bool) {
+	for _, c := range rt.GetCompare() {
+		touched = append(touched, keyInterval(c.Key, c.RangeEnd))
+	}
+	for _, ops := range [][]*pb.RequestOp{rt.GetSuccess(), rt.GetFailure()} {
+		for _, op := range ops {
+			var iv adt.Interval
+			switch tv := op.Request.(type) {
+			case *pb.RequestOp_RequestRange:
+				// the ranges of a past revision check the revision of the store
+				if tv.RequestRange.GetRevision() != 0 {
+					return nil, nil, false
+				}
+				touched = append(touched, keyInterval(tv.RequestRange.GetKey(), tv.RequestRange.GetRangeEnd()))
+				continue
+			case *pb.RequestOp_RequestPut:
+				iv = keyInterval(tv.RequestPut.GetKey(), nil)
+			case *pb.RequestOp_RequestIncrement:
+				iv = keyInterval(tv.RequestIncrement.GetKey(), nil)
+			case *pb.RequestOp_RequestDeleteRange:
+				iv = keyInterval(tv.RequestDeleteRange.GetKey(), tv.RequestDeleteRange.GetRangeEnd())
+			case *pb.RequestOp_RequestTxn:
+				var ok bool
+				if touched, writes, ok = txnKeys(tv.RequestTxn, touched, writes); !ok {
+					return nil, nil, false
+				}
+				continue
+			default:
+				return nil, nil, false
+			}
+			touched = append(touched, iv)
+			writes = append(writes, iv)
+		}
+	}
+	return touched, writes, true
+}
+
+// keyInterval returns the interval of the keys from key to end, conservatively
+// all the keys for an empty key or range.
+func keyInterval(key, end []byte) adt.Interval {
+	all := adt.NewStringAffineInterval("\x00", "")
+	switch {
+	case len(key) == 0:
+		return all
+	case len(end) == 0:
+		return adt.NewStringAffinePoint(string(key))
+	case len(end) == 1 && end[0] == 0:
+		// all the keys from key
+		return adt.NewStringAffineInterval(string(key), "")
+	case bytes.Compare(end, key) <= 0:
+		return all
+	}
+	return adt.NewStringAffineInterval(string(key), string(end))
+}
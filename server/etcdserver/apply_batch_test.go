@@ -0,0 +1,75 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/pkg/v3/pbutil"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/server/v3/etcdserver/cindex"
+)
+
+func TestIndependentRequests(t *testing.T) {
+	put := func(k string) *pb.InternalRaftRequest {
+		return &pb.InternalRaftRequest{Put: &pb.PutRequest{Key: []byte(k)}}
+	}
+	del := func(k, end string) *pb.InternalRaftRequest {
+		return &pb.InternalRaftRequest{DeleteRange: &pb.DeleteRangeRequest{Key: []byte(k), RangeEnd: []byte(end)}}
+	}
+	txn := func(cmp string, ops ...*pb.RequestOp) *pb.InternalRaftRequest {
+		return &pb.InternalRaftRequest{Txn: &pb.TxnRequest{
+			Compare: []*pb.Compare{{Key: []byte(cmp), Target: pb.Compare_VERSION}},
+			Success: ops,
+		}}
+	}
+	opPut := func(k string) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte(k)}}}
+	}
+	opRange := func(k string, rev int64) *pb.RequestOp {
+		return &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte(k), Revision: rev}}}
+	}
+
+	tests := []struct {
+		name string
+		reqs []*pb.InternalRaftRequest
+		n    int
+	}{
+		{"puts", []*pb.InternalRaftRequest{put("a"), put("b"), put("c")}, 3},
+		{"same key", []*pb.InternalRaftRequest{put("a"), put("b"), put("a")}, 2},
+		{"deleted range", []*pb.InternalRaftRequest{del("a", "c"), put("d"), put("b")}, 2},
+		{"deleted from key", []*pb.InternalRaftRequest{del("b", "\x00"), put("a"), put("z")}, 2},
+		{"txns", []*pb.InternalRaftRequest{txn("a", opPut("a")), txn("b", opPut("b"))}, 2},
+		{"compare written key", []*pb.InternalRaftRequest{put("a"), txn("a", opPut("b"))}, 1},
+		{"read written key", []*pb.InternalRaftRequest{put("a"), txn("b", opPut("b"), opRange("a", 0))}, 1},
+		{"past revision", []*pb.InternalRaftRequest{put("a"), txn("b", opPut("b"), opRange("c", 1))}, 1},
+		{"read-only txn", []*pb.InternalRaftRequest{put("a"), txn("b", opRange("b", 0))}, 1},
+		{"lease grant", []*pb.InternalRaftRequest{put("a"), {LeaseGrant: &pb.LeaseGrantRequest{ID: 1}}, put("b")}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EtcdServer{consistIndex: cindex.NewFakeConsistentIndex(0)}
+			var es []raftpb.Entry
+			for i, r := range tt.reqs {
+				r.Header = &pb.RequestHeader{ID: uint64(i + 1)}
+				es = append(es, raftpb.Entry{Index: uint64(i + 1), Data: pbutil.MustMarshal(r)})
+			}
+			if n := len(s.independentRequests(es)); n != tt.n {
+				t.Errorf("independent requests = %d, want %d", n, tt.n)
+			}
+		})
+	}
+}
@@ -48,6 +48,7 @@ import (
 	"go.etcd.io/etcd/server/v3/storage/schema"
 	"go.etcd.io/etcd/server/v3/storage/wal"
 	"go.etcd.io/etcd/server/v3/storage/wal/walpb"
+	"go.etcd.io/etcd/server/v3/verify"
 )
 
 func bootstrap(cfg config.ServerConfig) (b *bootstrappedServer, err error) {
@@ -76,6 +77,16 @@ func bootstrap(cfg config.ServerConfig) (b *bootstrappedServer, err error) {
 	}
 
 	haveWAL := wal.Exist(cfg.WALDir())
+	if haveWAL && cfg.ExperimentalBootstrapVerify == config.BootstrapVerifyFull && fileutil.Exist(cfg.BackendPath()) {
+		if err = verify.Verify(verify.Config{
+			Logger:  cfg.Logger,
+			DataDir: cfg.DataDir,
+			WALDir:  cfg.WALDir(),
+			Full:    true,
+		}); err != nil {
+			return nil, fmt.Errorf("bootstrap verification failed: %v", err)
+		}
+	}
 	st := v2store.New(StoreClusterPrefix, StoreKeysPrefix)
 	backend, err := bootstrapBackend(cfg, haveWAL, st, ss)
 	if err != nil {
@@ -160,8 +171,10 @@ type bootstrapedCluster struct {
 }
 
 type bootstrappedRaft struct {
-	lg        *zap.Logger
-	heartbeat time.Duration
+	lg                   *zap.Logger
+	heartbeat            time.Duration
+	tickSkewTolerance    time.Duration
+	walGroupSyncMaxDelay time.Duration
 
 	peers   []raft.Peer
 	config  *raft.Config
@@ -503,35 +516,40 @@ func bootstrapRaftFromCluster(cfg config.ServerConfig, cl *membership.RaftCluste
 	)
 	s := bwal.MemoryStorage()
 	return &bootstrappedRaft{
-		lg:        cfg.Logger,
-		heartbeat: time.Duration(cfg.TickMs) * time.Millisecond,
-		config:    raftConfig(cfg, uint64(member.ID), s),
-		peers:     peers,
-		storage:   s,
+		lg:                   cfg.Logger,
+		heartbeat:            time.Duration(cfg.TickMs) * time.Millisecond,
+		tickSkewTolerance:    cfg.ExperimentalTickSkewTolerance,
+		walGroupSyncMaxDelay: cfg.ExperimentalWALGroupSyncMaxDelay,
+		config:               raftConfig(cfg, uint64(member.ID), s),
+		peers:                peers,
+		storage:              s,
 	}
 }
 
 func bootstrapRaftFromWAL(cfg config.ServerConfig, bwal *bootstrappedWAL) *bootstrappedRaft {
 	s := bwal.MemoryStorage()
 	return &bootstrappedRaft{
-		lg:        cfg.Logger,
-		heartbeat: time.Duration(cfg.TickMs) * time.Millisecond,
-		config:    raftConfig(cfg, uint64(bwal.meta.nodeID), s),
-		storage:   s,
+		lg:                   cfg.Logger,
+		heartbeat:            time.Duration(cfg.TickMs) * time.Millisecond,
+		tickSkewTolerance:    cfg.ExperimentalTickSkewTolerance,
+		walGroupSyncMaxDelay: cfg.ExperimentalWALGroupSyncMaxDelay,
+		config:               raftConfig(cfg, uint64(bwal.meta.nodeID), s),
+		storage:              s,
 	}
 }
 
 func raftConfig(cfg config.ServerConfig, id uint64, s *raft.MemoryStorage) *raft.Config {
 	return &raft.Config{
-		ID:              id,
-		ElectionTick:    cfg.ElectionTicks,
-		HeartbeatTick:   1,
-		Storage:         s,
-		MaxSizePerMsg:   maxSizePerMsg,
-		MaxInflightMsgs: maxInflightMsgs,
-		CheckQuorum:     true,
-		PreVote:         cfg.PreVote,
-		Logger:          NewRaftLoggerZap(cfg.Logger.Named("raft")),
+		ID:                 id,
+		ElectionTick:       cfg.ElectionTicks,
+		ElectionJitterTick: cfg.ElectionJitterTicks,
+		HeartbeatTick:      1,
+		Storage:            s,
+		MaxSizePerMsg:      maxSizePerMsg,
+		MaxInflightMsgs:    maxInflightMsgs,
+		CheckQuorum:        cfg.CheckQuorum,
+		PreVote:            cfg.PreVote,
+		Logger:             NewRaftLoggerZap(cfg.Logger.Named("raft")),
 	}
 }
 
@@ -547,12 +565,15 @@ func (b *bootstrappedRaft) newRaftNode(ss *snap.Snapshotter, wal *wal.WAL, cl *m
 	raftStatusMu.Unlock()
 	return newRaftNode(
 		raftNodeConfig{
-			lg:          b.lg,
-			isIDRemoved: func(id uint64) bool { return cl.IsIDRemoved(types.ID(id)) },
-			Node:        n,
-			heartbeat:   b.heartbeat,
-			raftStorage: b.storage,
-			storage:     serverstorage.NewStorage(b.lg, wal, ss),
+			lg:                   b.lg,
+			isIDRemoved:          func(id uint64) bool { return cl.IsIDRemoved(types.ID(id)) },
+			Node:                 n,
+			heartbeat:            b.heartbeat,
+			tickSkewTolerance:    b.tickSkewTolerance,
+			walGroupSyncMaxDelay: b.walGroupSyncMaxDelay,
+			preVote:              b.config.PreVote,
+			raftStorage:          b.storage,
+			storage:              serverstorage.NewStorage(b.lg, wal, ss),
 		},
 	)
 }
@@ -614,10 +635,10 @@ func openWALFromSnapshot(cfg config.ServerConfig, snapshot *raftpb.Snapshot) (*w
 			w.Close()
 			// we can only repair ErrUnexpectedEOF and we never repair twice.
 			if repaired || err != io.ErrUnexpectedEOF {
-				cfg.Logger.Fatal("failed to read WAL, cannot be repaired", zap.Error(err))
+				cfg.Logger.Fatal("failed to read WAL, cannot be repaired automatically, inspect and repair it with 'etcdutl wal repair'", zap.Error(err))
 			}
 			if !wal.Repair(cfg.Logger, cfg.WALDir()) {
-				cfg.Logger.Fatal("failed to repair WAL", zap.Error(err))
+				cfg.Logger.Fatal("failed to repair WAL, inspect and repair it with 'etcdutl wal repair'", zap.Error(err))
 			} else {
 				cfg.Logger.Info("repaired WAL", zap.Error(err))
 				repaired = true
@@ -0,0 +1,41 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import "time"
+
+// MaxClockSkew returns the largest absolute clock skew of the member relative
+// to its peers, measured by the probes of the peer protocol.
+func (s *EtcdServer) MaxClockSkew() time.Duration {
+	if s.r.transport == nil {
+		return 0
+	}
+	var max time.Duration
+	for _, skew := range s.r.transport.ClockSkews() {
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > max {
+			max = skew
+		}
+	}
+	return max
+}
+
+// ClockSkewed returns whether the clock skew of the member relative to a peer
+// exceeds the configured maximum.
+func (s *EtcdServer) ClockSkewed() bool {
+	return s.Cfg.ExperimentalMaxClockSkew > 0 && s.MaxClockSkew() > s.Cfg.ExperimentalMaxClockSkew
+}
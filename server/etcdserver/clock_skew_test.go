@@ -0,0 +1,57 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/server/v3/config"
+	"go.uber.org/zap/zaptest"
+)
+
+type clockSkewTransporter struct {
+	nopTransporter
+	skews map[types.ID]time.Duration
+}
+
+func (s *clockSkewTransporter) ClockSkews() map[types.ID]time.Duration { return s.skews }
+
+func TestMaxClockSkew(t *testing.T) {
+	tests := []struct {
+		skews   map[types.ID]time.Duration
+		wskew   time.Duration
+		wskewed bool
+		maxSkew time.Duration
+	}{
+		{nil, 0, false, time.Second},
+		{map[types.ID]time.Duration{1: 100 * time.Millisecond, 2: -300 * time.Millisecond}, 300 * time.Millisecond, false, time.Second},
+		{map[types.ID]time.Duration{1: 100 * time.Millisecond, 2: -3 * time.Second}, 3 * time.Second, true, time.Second},
+		{map[types.ID]time.Duration{1: 2 * time.Second}, 2 * time.Second, false, 5 * time.Second},
+	}
+	for i, tt := range tests {
+		s := &EtcdServer{
+			Cfg: config.ServerConfig{ExperimentalMaxClockSkew: tt.maxSkew},
+			r:   *newRaftNode(raftNodeConfig{lg: zaptest.NewLogger(t), transport: &clockSkewTransporter{skews: tt.skews}}),
+		}
+		if skew := s.MaxClockSkew(); skew != tt.wskew {
+			t.Errorf("#%d: MaxClockSkew() = %v, want %v", i, skew, tt.wskew)
+		}
+		if skewed := s.ClockSkewed(); skewed != tt.wskewed {
+			t.Errorf("#%d: ClockSkewed() = %v, want %v", i, skewed, tt.wskewed)
+		}
+	}
+}
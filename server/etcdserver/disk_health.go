@@ -0,0 +1,182 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// diskHealthCheckInterval is the length of the windows the disk latency
+	// is checked over.
+	diskHealthCheckInterval = 5 * time.Second
+	// diskHealthWindows is the number of consecutive slow windows degrading
+	// the disk, and of consecutive healthy windows recovering it.
+	diskHealthWindows = 3
+	// slowSamplesPercent is the percentage of the samples of a window exceeding
+	// the threshold above which the window is slow.
+	slowSamplesPercent = 10
+	// diskDegradedTransferInterval is the minimum interval between the
+	// leadership transfers away from a member with a degraded disk.
+	diskDegradedTransferInterval = time.Minute
+)
+
+// latencyWindow counts the samples of an operation exceeding the threshold
+// during a window.
+type latencyWindow struct {
+	threshold     time.Duration
+	samples, slow int
+	// max is the highest sample of the window.
+	max time.Duration
+}
+
+func (l *latencyWindow) observe(d time.Duration) {
+	if l.threshold <= 0 {
+		return
+	}
+	l.samples++
+	if d > l.max {
+		l.max = d
+	}
+	if d > l.threshold {
+		l.slow++
+	}
+}
+
+// endWindow returns whether the window is slow, and starts a new window.
+func (l *latencyWindow) endWindow() bool {
+	slow := l.samples > 0 && l.slow*100 > l.samples*slowSamplesPercent
+	l.samples, l.slow, l.max = 0, 0, 0
+	return slow
+}
+
+// diskMonitor degrades the disk after diskHealthWindows consecutive windows
+// where more than slowSamplesPercent of the WAL fsyncs or the backend commits
+// exceed their threshold, and recovers it after as many healthy windows.
+type diskMonitor struct {
+	mu             sync.Mutex
+	wal, backend   latencyWindow
+	slowWindows    int
+	healthyWindows int
+	degraded       bool
+}
+
+func newDiskMonitor(walThreshold, backendThreshold time.Duration) *diskMonitor {
+	return &diskMonitor{
+		wal:     latencyWindow{threshold: walThreshold},
+		backend: latencyWindow{threshold: backendThreshold},
+	}
+}
+
+func (m *diskMonitor) observeWALFsync(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wal.observe(d)
+}
+
+func (m *diskMonitor) observeBackendCommit(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backend.observe(d)
+}
+
+// check ends the current window, returning whether the disk is degraded and
+// whether the window changed it.
+func (m *diskMonitor) check() (degraded, changed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	walSlow, backendSlow := m.wal.endWindow(), m.backend.endWindow()
+	if walSlow || backendSlow {
+		m.slowWindows++
+		m.healthyWindows = 0
+	} else {
+		m.healthyWindows++
+		m.slowWindows = 0
+	}
+	switch {
+	case !m.degraded && m.slowWindows >= diskHealthWindows:
+		m.degraded, changed = true, true
+	case m.degraded && m.healthyWindows >= diskHealthWindows:
+		m.degraded, changed = false, true
+	}
+	return m.degraded, changed
+}
+
+func (m *diskMonitor) isDegraded() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.degraded
+}
+
+// DiskDegraded returns whether the WAL fsyncs or the backend commits of the
+// member have been slower than their thresholds.
+func (s *EtcdServer) DiskDegraded() bool {
+	return s.diskMonitor.isDegraded()
+}
+
+// monitorDiskHealth checks the disk latency every diskHealthCheckInterval,
+// and transfers the leadership away from the member while its disk is
+// degraded if configured to.
+func (s *EtcdServer) monitorDiskHealth() {
+	lg := s.Logger()
+	var lastTransfer time.Time
+	for {
+		select {
+		case <-time.After(diskHealthCheckInterval):
+		case <-s.stopping:
+			return
+		}
+
+		degraded, changed := s.diskMonitor.check()
+		if changed {
+			if degraded {
+				diskDegraded.Set(1)
+				lg.Warn(
+					"disk degraded",
+					zap.Duration("wal-fsync-threshold", s.Cfg.ExperimentalDiskDegradedWALFsyncThreshold),
+					zap.Duration("backend-commit-threshold", s.Cfg.ExperimentalDiskDegradedBackendCommitThreshold),
+				)
+				s.RecordEvent(EventDiskDegraded, fmt.Sprintf("disk degraded on member %s", s.MemberId()))
+			} else {
+				diskDegraded.Set(0)
+				lg.Info("disk recovered")
+				s.RecordEvent(EventDiskRecovered, fmt.Sprintf("disk recovered on member %s", s.MemberId()))
+			}
+		}
+
+		if !degraded || !s.Cfg.ExperimentalDiskDegradedTransferLeadership || !s.isLeader() ||
+			time.Since(lastTransfer) < diskDegradedTransferInterval {
+			continue
+		}
+		lastTransfer = time.Now()
+		lg.Warn("transferring leadership away from member with degraded disk", zap.String("local-member-id", s.MemberId().String()))
+		if err := s.TransferLeadership(); err != nil {
+			lg.Warn("failed to transfer leadership away from member with degraded disk", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiskMonitor(t *testing.T) {
+	m := newDiskMonitor(100*time.Millisecond, 0)
+	slowWindow := func() (bool, bool) {
+		for i := 0; i < 8; i++ {
+			m.observeWALFsync(time.Millisecond)
+		}
+		m.observeWALFsync(time.Second)
+		m.observeWALFsync(time.Second)
+		return m.check()
+	}
+
+	for i := 0; i < diskHealthWindows-1; i++ {
+		if degraded, changed := slowWindow(); degraded || changed {
+			t.Fatalf("#%d: degraded, changed = %v, %v after %d slow windows", i, degraded, changed, i+1)
+		}
+	}
+	if degraded, changed := slowWindow(); !degraded || !changed {
+		t.Fatalf("degraded, changed = %v, %v, want true, true", degraded, changed)
+	}
+	if !m.isDegraded() {
+		t.Fatal("expected degraded disk")
+	}
+
+	// a single healthy window does not recover the disk
+	m.observeWALFsync(time.Millisecond)
+	if degraded, _ := m.check(); !degraded {
+		t.Fatal("recovered after a single healthy window")
+	}
+	// the backend commits are not checked with a zero threshold
+	m.observeBackendCommit(time.Hour)
+	for i := 0; i < diskHealthWindows-2; i++ {
+		m.check()
+	}
+	if degraded, changed := m.check(); degraded || !changed {
+		t.Fatalf("degraded, changed = %v, %v, want false, true", degraded, changed)
+	}
+
+	var nilMonitor *diskMonitor
+	nilMonitor.observeWALFsync(time.Second)
+	if nilMonitor.isDegraded() {
+		t.Error("nil monitor is degraded")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/rafthttp"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// electionPriorityCheckInterval is the interval the leader checks the
+	// election priorities of the members at.
+	electionPriorityCheckInterval = 5 * time.Second
+	// electionPriorityMinConnected is the time a member of higher election
+	// priority must have been connected to the leader for before being
+	// transferred the leadership, e.g. to catch up after a restart.
+	electionPriorityMinConnected = 10 * time.Second
+	// electionPriorityTransferInterval is the minimum interval between the
+	// leadership transfers to members of higher election priority.
+	electionPriorityTransferInterval = time.Minute
+)
+
+// monitorElectionPriority transfers the leadership to the voting member of
+// highest election priority connected to the leader, if higher than its own.
+func (s *EtcdServer) monitorElectionPriority() {
+	lg := s.Logger()
+	var lastTransfer time.Time
+	for {
+		select {
+		case <-time.After(electionPriorityCheckInterval):
+		case <-s.stopping:
+			return
+		}
+
+		if !s.isLeader() || s.DiskDegraded() || time.Since(lastTransfer) < electionPriorityTransferInterval {
+			continue
+		}
+		self := s.cluster.Member(s.MemberId())
+		if self == nil {
+			continue
+		}
+		transferee, ok := preferredLeader(s.r.transport, time.Now().Add(-electionPriorityMinConnected), self, s.cluster.VotingMembers())
+		if !ok {
+			continue
+		}
+		lastTransfer = time.Now()
+		lg.Info(
+			"transferring leadership to member of higher election priority",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.Uint32("local-member-election-priority", self.ElectionPriority),
+			zap.String("transferee-member-id", transferee.ID.String()),
+			zap.Uint32("transferee-election-priority", transferee.ElectionPriority),
+		)
+		ctx, cancel := context.WithTimeout(s.ctx, s.Cfg.ReqTimeout())
+		err := s.MoveLeader(ctx, s.Lead(), uint64(transferee.ID))
+		cancel()
+		if err != nil {
+			lg.Warn("failed to transfer leadership to member of higher election priority", zap.Error(err))
+		}
+	}
+}
+
+// preferredLeader returns the member of highest election priority, higher
+// than the one of self, connected since the given time. Members of the same
+// priority are ordered by ID.
+func preferredLeader(tp rafthttp.Transporter, since time.Time, self *membership.Member, members []*membership.Member) (*membership.Member, bool) {
+	var preferred *membership.Member
+	for _, m := range members {
+		if m.ID == self.ID || m.IsLearner || m.ElectionPriority <= self.ElectionPriority {
+			continue
+		}
+		if !isConnectedSince(tp, since, m.ID) {
+			continue
+		}
+		if preferred == nil || m.ElectionPriority > preferred.ElectionPriority ||
+			(m.ElectionPriority == preferred.ElectionPriority && m.ID < preferred.ID) {
+			preferred = m
+		}
+	}
+	return preferred, preferred != nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
+)
+
+func TestPreferredLeader(t *testing.T) {
+	now := time.Now()
+	member := func(id types.ID, priority uint32, learner bool) *membership.Member {
+		return &membership.Member{
+			ID:             id,
+			RaftAttributes: membership.RaftAttributes{IsLearner: learner},
+			Attributes:     membership.Attributes{ElectionPriority: priority},
+		}
+	}
+	tr := &nopTransporterWithActiveTime{activeMap: map[types.ID]time.Time{
+		1: now.Add(-time.Minute),
+		2: now.Add(-time.Minute),
+		3: now.Add(-time.Minute),
+		4: now.Add(-time.Minute),
+		// connected recently
+		5: now.Add(-time.Second),
+	}}
+	since := now.Add(-electionPriorityMinConnected)
+
+	tests := []struct {
+		self    *membership.Member
+		members []*membership.Member
+		wid     types.ID
+	}{
+		// no priorities
+		{member(1, 0, false), []*membership.Member{member(1, 0, false), member(2, 0, false), member(3, 0, false)}, 0},
+		{member(1, 0, false), []*membership.Member{member(1, 0, false), member(2, 1, false), member(3, 2, false)}, 3},
+		// same priority ordered by ID
+		{member(1, 0, false), []*membership.Member{member(1, 0, false), member(3, 2, false), member(2, 2, false)}, 2},
+		// already of highest priority
+		{member(1, 2, false), []*membership.Member{member(1, 2, false), member(2, 2, false), member(3, 1, false)}, 0},
+		// learners and recently connected members are skipped
+		{member(1, 0, false), []*membership.Member{member(1, 0, false), member(4, 3, true), member(5, 3, false), member(2, 1, false)}, 2},
+		// not connected
+		{member(1, 0, false), []*membership.Member{member(1, 0, false), member(6, 3, false)}, 0},
+	}
+	for i, tt := range tests {
+		m, ok := preferredLeader(tr, since, tt.self, tt.members)
+		if ok != (tt.wid != 0) {
+			t.Fatalf("#%d: preferredLeader() ok = %v, want %v", i, ok, tt.wid != 0)
+		}
+		if ok && m.ID != tt.wid {
+			t.Errorf("#%d: preferredLeader() = %s, want %s", i, m.ID, tt.wid)
+		}
+	}
+}
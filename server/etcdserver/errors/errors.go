@@ -31,7 +31,13 @@ var (
 	ErrLeaderChanged               = errors.New("etcdserver: leader changed")
 	ErrNotEnoughStartedMembers     = errors.New("etcdserver: re-configuration failed due to not enough started members")
 	ErrLearnerNotReady             = errors.New("etcdserver: can only promote a learner member which is in sync with leader")
+	ErrDemoteLeader                = errors.New("etcdserver: cannot demote the leader, move the leadership to another member first")
+	ErrDemoteNotAcknowledged       = errors.New("etcdserver: demoting the member reduces the fault tolerance of the cluster, it must be acknowledged")
+	ErrDemoteNotSupported          = errors.New("etcdserver: demoting a member requires cluster version 3.6")
 	ErrNoLeader                    = errors.New("etcdserver: no leader")
+	ErrDiskDegraded                = errors.New("etcdserver: disk degraded")
+	ErrClockSkewed                 = errors.New("etcdserver: clock skewed relative to a peer")
+	ErrPeerMisbehaving             = errors.New("etcdserver: peer misbehaving")
 	ErrNotLeader                   = errors.New("etcdserver: not leader")
 	ErrRequestTooLarge             = errors.New("etcdserver: request is too large")
 	ErrNoSpace                     = errors.New("etcdserver: no space")
@@ -42,6 +48,11 @@ var (
 	ErrClusterVersionUnavailable   = errors.New("etcdserver: cluster version not found during downgrade")
 	ErrWrongDowngradeVersionFormat = errors.New("etcdserver: wrong downgrade target version format")
 	ErrKeyNotFound                 = errors.New("etcdserver: key not found")
+	ErrValueNotInteger             = errors.New("etcdserver: value is not an integer")
+	ErrIntegerOverflow             = errors.New("etcdserver: integer overflow")
+	ErrTooManyLoggedRanges         = errors.New("etcdserver: too many logged ranges")
+	ErrMemoryBudgetExceeded        = errors.New("etcdserver: memory budget exceeded")
+	ErrInvalidValueRegex           = errors.New("etcdserver: invalid watch value regex")
 )
 
 type DiscoveryError struct {
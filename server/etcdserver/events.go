@@ -0,0 +1,105 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+// Types of the events kept in the event log of the server.
+const (
+	EventLeaderChanged   = "leader-changed"
+	EventCompaction      = "compaction"
+	EventDefrag          = "defrag"
+	EventSnapshotSaved   = "snapshot-saved"
+	EventSnapshotApplied = "snapshot-applied"
+	EventAlarmRaised     = "alarm-raised"
+	EventAlarmCleared    = "alarm-cleared"
+	EventMemberAdded     = "member-added"
+	EventMemberPromoted  = "member-promoted"
+	EventMemberDemoted   = "member-demoted"
+	EventMemberRemoved   = "member-removed"
+	EventMemberUpdated   = "member-updated"
+	EventDiskDegraded    = "disk-degraded"
+	EventDiskRecovered   = "disk-recovered"
+	EventStarved         = "starved"
+	EventStarvationEnded = "starvation-ended"
+	EventConfigReloaded  = "config-reloaded"
+	EventConfigRejected  = "config-rejected"
+	EventPeerMisbehaving = "peer-misbehaving"
+)
+
+// maxEvents is the number of the latest events kept in the event log.
+const maxEvents = 1024
+
+// eventLog is a ring buffer of the latest significant events of the server.
+type eventLog struct {
+	mu     sync.Mutex
+	events []*pb.ServerEvent
+	// next is the position of the next event once the buffer is full.
+	next int
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{events: make([]*pb.ServerEvent, 0, maxEvents)}
+}
+
+func (l *eventLog) record(ev *pb.ServerEvent) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.events) < cap(l.events) {
+		l.events = append(l.events, ev)
+		return
+	}
+	l.events[l.next] = ev
+	l.next = (l.next + 1) % len(l.events)
+}
+
+// latest returns up to limit latest events from the oldest to the newest,
+// all the events if limit is not positive.
+func (l *eventLog) latest(limit int) []*pb.ServerEvent {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(l.events)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+	evs := make([]*pb.ServerEvent, 0, limit)
+	for i := n - limit; i < n; i++ {
+		evs = append(evs, l.events[(l.next+i)%n])
+	}
+	return evs
+}
+
+// RecordEvent adds an event of the given type to the event log of the server.
+func (s *EtcdServer) RecordEvent(typ, message string) {
+	s.events.record(&pb.ServerEvent{Time: time.Now().UnixNano(), Type: typ, Message: message})
+}
+
+// Events returns up to limit latest events of the event log of the server,
+// from the oldest to the newest. All the kept events are returned if limit
+// is not positive.
+func (s *EtcdServer) Events(limit int) []*pb.ServerEvent {
+	return s.events.latest(limit)
+}
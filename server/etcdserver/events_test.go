@@ -0,0 +1,47 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+func TestEventLogLatest(t *testing.T) {
+	l := newEventLog()
+	if evs := l.latest(0); len(evs) != 0 {
+		t.Fatalf("latest of empty log = %v, want none", evs)
+	}
+	for i := 0; i < maxEvents+10; i++ {
+		l.record(&pb.ServerEvent{Time: int64(i), Message: fmt.Sprint(i)})
+	}
+
+	evs := l.latest(0)
+	if len(evs) != maxEvents {
+		t.Fatalf("len(latest) = %d, want %d", len(evs), maxEvents)
+	}
+	for i, ev := range evs {
+		if ev.Time != int64(i+10) {
+			t.Fatalf("#%d: event time = %d, want %d", i, ev.Time, i+10)
+		}
+	}
+
+	evs = l.latest(3)
+	if len(evs) != 3 || evs[0].Time != maxEvents+7 || evs[2].Time != maxEvents+9 {
+		t.Errorf("latest(3) = %v, want the 3 latest events", evs)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/raft/v3"
+
+	"go.uber.org/zap"
+)
+
+// learnerPromotionCheckInterval is the interval the leader checks the lags
+// of the learners at.
+const learnerPromotionCheckInterval = time.Second
+
+// learnerPromotion tracks since when the learners are in sync with the
+// leader, their raft logs lagging behind its own by at most maxLag entries.
+type learnerPromotion struct {
+	maxLag uint64
+	synced time.Duration
+	// since are the times the learners got in sync.
+	since map[types.ID]time.Time
+}
+
+func newLearnerPromotion(maxLag uint64, synced time.Duration) *learnerPromotion {
+	return &learnerPromotion{maxLag: maxLag, synced: synced, since: make(map[types.ID]time.Time)}
+}
+
+// ready returns the learners in sync for the synced duration at now, ordered
+// by ID, from the raft status of the leader, untracking the members which
+// are not learners anymore.
+func (lp *learnerPromotion) ready(now time.Time, rs raft.Status, learners []types.ID) []types.ID {
+	isLearner := make(map[types.ID]bool, len(learners))
+	for _, id := range learners {
+		isLearner[id] = true
+	}
+	for id := range lp.since {
+		if !isLearner[id] {
+			delete(lp.since, id)
+		}
+	}
+
+	leaderMatch := rs.Progress[rs.ID].Match
+	var ready []types.ID
+	for _, id := range learners {
+		pr, ok := rs.Progress[uint64(id)]
+		if !ok || pr.Match+lp.maxLag < leaderMatch {
+			delete(lp.since, id)
+			continue
+		}
+		since, ok := lp.since[id]
+		if !ok {
+			lp.since[id] = now
+			continue
+		}
+		if now.Sub(since) >= lp.synced {
+			ready = append(ready, id)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i] < ready[j] })
+	return ready
+}
+
+// reset restarts the synced duration of all the learners.
+func (lp *learnerPromotion) reset() {
+	lp.since = make(map[types.ID]time.Time)
+}
+
+// monitorLearnerPromotion promotes the learners once in sync with the leader
+// for the synced duration, while the member is the leader.
+func (s *EtcdServer) monitorLearnerPromotion() {
+	lg := s.Logger()
+	lp := newLearnerPromotion(s.Cfg.AutoPromoteLearnersMaxLag, s.Cfg.AutoPromoteLearnersSyncedDuration)
+	for {
+		select {
+		case <-time.After(learnerPromotionCheckInterval):
+		case <-s.stopping:
+			return
+		}
+
+		rs := s.raftStatus()
+		if rs.Progress == nil {
+			// the learners are only tracked by the leader
+			lp.reset()
+			continue
+		}
+		var learners []types.ID
+		for _, m := range s.cluster.Members() {
+			if m.IsLearner {
+				learners = append(learners, m.ID)
+			}
+		}
+
+		for _, id := range lp.ready(time.Now(), rs, learners) {
+			lg.Info(
+				"promoting learner in sync with the leader",
+				zap.String("local-member-id", s.MemberId().String()),
+				zap.String("learner-member-id", id.String()),
+				zap.Uint64("max-lag", s.Cfg.AutoPromoteLearnersMaxLag),
+				zap.Duration("synced-duration", s.Cfg.AutoPromoteLearnersSyncedDuration),
+			)
+			ctx, cancel := context.WithTimeout(s.ctx, s.Cfg.ReqTimeout())
+			_, err := s.promoteLearner(ctx, uint64(id))
+			cancel()
+			if err != nil {
+				lg.Warn("failed to promote learner", zap.String("learner-member-id", id.String()), zap.Error(err))
+				learnerPromoteFailed.WithLabelValues(err.Error()).Inc()
+				// the learner is retried after another synced duration
+				delete(lp.since, id)
+				continue
+			}
+			learnerPromoteSucceed.Inc()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/raft/v3"
+	"go.etcd.io/etcd/raft/v3/tracker"
+)
+
+func TestLearnerPromotionReady(t *testing.T) {
+	status := func(matches map[uint64]uint64) raft.Status {
+		rs := raft.Status{BasicStatus: raft.BasicStatus{ID: 1}, Progress: make(map[uint64]tracker.Progress)}
+		for id, match := range matches {
+			rs.Progress[id] = tracker.Progress{Match: match}
+		}
+		return rs
+	}
+	lp := newLearnerPromotion(100, 10*time.Second)
+	now := time.Unix(0, 0)
+	learners := []types.ID{2, 3}
+
+	tests := []struct {
+		after    time.Duration
+		matches  map[uint64]uint64
+		learners []types.ID
+		wready   []types.ID
+	}{
+		// learner 2 gets in sync, 3 lags behind
+		{0, map[uint64]uint64{1: 1000, 2: 900, 3: 899}, learners, nil},
+		{9 * time.Second, map[uint64]uint64{1: 1000, 2: 950, 3: 950}, learners, nil},
+		{10 * time.Second, map[uint64]uint64{1: 1100, 2: 1050, 3: 1050}, learners, []types.ID{2}},
+		// learner 3 is in sync since 9s, 2 lags behind again
+		{19 * time.Second, map[uint64]uint64{1: 2000, 2: 1000, 3: 2000}, learners, []types.ID{3}},
+		{30 * time.Second, map[uint64]uint64{1: 2000, 2: 2000, 3: 2000}, learners, []types.ID{3}},
+		// learner 3 is not a learner anymore, and 2 in sync since 30s
+		{40 * time.Second, map[uint64]uint64{1: 2000, 2: 2000, 3: 2000}, []types.ID{2}, []types.ID{2}},
+	}
+	for i, tt := range tests {
+		ready := lp.ready(now.Add(tt.after), status(tt.matches), tt.learners)
+		if !reflect.DeepEqual(ready, tt.wready) {
+			t.Errorf("#%d: ready = %v, want %v", i, ready, tt.wready)
+		}
+	}
+	if _, ok := lp.since[3]; ok {
+		t.Errorf("member 3 tracked, want it untracked as not a learner")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
+)
+
+// maxLoggedRanges is the maximum number of the ranges logged at once.
+const maxLoggedRanges = 64
+
+// loggedRanges are the key ranges whose requests are logged until they expire.
+type loggedRanges struct {
+	// n is the number of ranges, to skip the lookups without ranges.
+	n int32
+
+	mu     sync.Mutex
+	ranges []*pb.LoggedRange
+}
+
+// set logs the range for d, or stops logging it if d is not positive.
+func (lr *loggedRanges) set(key, end []byte, d time.Duration) ([]*pb.LoggedRange, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	now := time.Now()
+	ranges := lr.ranges[:0]
+	for _, r := range lr.ranges {
+		if r.Expire > now.UnixNano() && !(bytes.Equal(r.Key, key) && bytes.Equal(r.RangeEnd, end)) {
+			ranges = append(ranges, r)
+		}
+	}
+	if d > 0 {
+		if len(ranges) >= maxLoggedRanges {
+			lr.ranges = ranges
+			atomic.StoreInt32(&lr.n, int32(len(ranges)))
+			return nil, errors.ErrTooManyLoggedRanges
+		}
+		ranges = append(ranges, &pb.LoggedRange{Key: key, RangeEnd: end, Expire: now.Add(d).UnixNano()})
+	}
+	lr.ranges = ranges
+	atomic.StoreInt32(&lr.n, int32(len(ranges)))
+	return append([]*pb.LoggedRange(nil), ranges...), nil
+}
+
+// logged returns whether the range overlaps a logged range.
+func (lr *loggedRanges) logged(key, end []byte) bool {
+	if atomic.LoadInt32(&lr.n) == 0 {
+		return false
+	}
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	now := time.Now().UnixNano()
+	for _, r := range lr.ranges {
+		if r.Expire > now && rangesOverlap(r.Key, r.RangeEnd, key, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// rangesOverlap returns whether two ranges overlap, with the semantics of
+// RangeRequest.range_end: a single key if the end is empty, all the keys from
+// the key if the end is "\x00".
+func rangesOverlap(key1, end1, key2, end2 []byte) bool {
+	end1, end2 = exclusiveEnd(key1, end1), exclusiveEnd(key2, end2)
+	return (end2 == nil || bytes.Compare(key1, end2) < 0) && (end1 == nil || bytes.Compare(key2, end1) < 0)
+}
+
+// exclusiveEnd returns the end of the range excluded from it, nil if the range
+// has no end.
+func exclusiveEnd(key, end []byte) []byte {
+	switch {
+	case len(end) == 0:
+		return append(append([]byte{}, key...), 0)
+	case len(end) == 1 && end[0] == 0:
+		return nil
+	}
+	return end
+}
+
+// LogRange logs at the warn level the requests touching the key range for d,
+// or stops logging them if d is not positive. It returns the ranges currently
+// logged.
+func (s *EtcdServer) LogRange(key, end []byte, d time.Duration) ([]*pb.LoggedRange, error) {
+	return s.loggedRanges.set(key, end, d)
+}
+
+// IsLoggedRange returns whether the requests touching the key range are logged.
+func (s *EtcdServer) IsLoggedRange(key, end []byte) bool {
+	return s.loggedRanges.logged(key, end)
+}
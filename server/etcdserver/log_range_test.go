@@ -0,0 +1,95 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
+)
+
+func TestRangesOverlap(t *testing.T) {
+	tests := []struct {
+		key1, end1, key2, end2 string
+		want                   bool
+	}{
+		{"a", "", "a", "", true},
+		{"a", "", "b", "", false},
+		{"a", "c", "b", "", true},
+		{"a", "c", "c", "", false},
+		{"a", "c", "b", "d", true},
+		{"a", "c", "c", "d", false},
+		{"b", "", "a", "\x00", true},
+		{"b", "\x00", "a", "b", false},
+		{"b", "\x00", "a", "c", true},
+		{"a", "\x00", "b", "\x00", true},
+	}
+	for i, tt := range tests {
+		if got := rangesOverlap([]byte(tt.key1), []byte(tt.end1), []byte(tt.key2), []byte(tt.end2)); got != tt.want {
+			t.Errorf("#%d: rangesOverlap(%q, %q, %q, %q) = %v, want %v", i, tt.key1, tt.end1, tt.key2, tt.end2, got, tt.want)
+		}
+		if got := rangesOverlap([]byte(tt.key2), []byte(tt.end2), []byte(tt.key1), []byte(tt.end1)); got != tt.want {
+			t.Errorf("#%d: rangesOverlap(%q, %q, %q, %q) = %v, want %v", i, tt.key2, tt.end2, tt.key1, tt.end1, got, tt.want)
+		}
+	}
+}
+
+func TestLoggedRanges(t *testing.T) {
+	var lr loggedRanges
+	if lr.logged([]byte("foo"), nil) {
+		t.Fatal("logged without ranges")
+	}
+
+	ranges, err := lr.set([]byte("foo"), []byte("fop"), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 {
+		t.Fatalf("len(ranges) = %d, want 1", len(ranges))
+	}
+	if !lr.logged([]byte("foo1"), nil) || lr.logged([]byte("bar"), nil) {
+		t.Error("unexpected logged keys of range [foo, fop)")
+	}
+
+	// setting the same range again replaces it
+	if ranges, _ = lr.set([]byte("foo"), []byte("fop"), time.Minute); len(ranges) != 1 {
+		t.Errorf("len(ranges) = %d, want 1", len(ranges))
+	}
+	if ranges, _ = lr.set([]byte("foo"), []byte("fop"), 0); len(ranges) != 0 {
+		t.Errorf("len(ranges) = %d, want 0 once stopped", len(ranges))
+	}
+	if lr.logged([]byte("foo1"), nil) {
+		t.Error("logged stopped range")
+	}
+
+	if _, err = lr.set([]byte("exp"), nil, time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if lr.logged([]byte("exp"), nil) {
+		t.Error("logged expired range")
+	}
+
+	for i := 0; i < maxLoggedRanges; i++ {
+		if _, err = lr.set([]byte(fmt.Sprint(i)), nil, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = lr.set([]byte("one-too-many"), nil, time.Minute); err != errors.ErrTooManyLoggedRanges {
+		t.Errorf("err = %v, want %v", err, errors.ErrTooManyLoggedRanges)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/storage/mvcc"
+
+	"github.com/dustin/go-humanize"
+	"go.uber.org/zap"
+)
+
+const (
+	// memoryBudgetCheckInterval is the interval the heap is checked against
+	// the memory budget at.
+	memoryBudgetCheckInterval = time.Second
+	// memoryBudgetRecoveryPercent is the percentage of the memory budget the
+	// heap must get under for the member to stop shedding load.
+	memoryBudgetRecoveryPercent = 90
+
+	// cgroupRoot is the mount point of the cgroup file systems.
+	cgroupRoot = "/sys/fs/cgroup"
+	// cgroupV1Unlimited is the limit above which a cgroup v1 is unlimited,
+	// which reports the largest page-aligned value instead of "max".
+	cgroupV1Unlimited = 1 << 62
+)
+
+// heapObjectsMetric is the runtime metric of the memory occupied by the live
+// and not yet freed objects of the heap.
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// memoryBudget sheds the range requests and the watch creations while the
+// heap exceeds the budget, a fraction of the memory limit of the cgroup of
+// the member, so that the member is not killed for running out of memory.
+type memoryBudget struct {
+	budget int64
+	// exceeded is 1 while the heap exceeds the budget.
+	exceeded int32
+	// rangeResponses is the size of the responses of the range requests
+	// until they are sent.
+	rangeResponses int64
+}
+
+// newMemoryBudget returns the memory budget of ratio of the memory limit of
+// the cgroup, or nil if ratio is 0 or the cgroup is not limited. It sets the
+// Go soft memory limit to the budget unless set by GOMEMLIMIT.
+func newMemoryBudget(lg *zap.Logger, ratio float64) *memoryBudget {
+	if ratio <= 0 {
+		return nil
+	}
+	self, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		lg.Warn("failed to read cgroup, memory budget disabled", zap.Error(err))
+		return nil
+	}
+	limit, err := cgroupMemoryLimit(cgroupRoot, self)
+	if err != nil {
+		lg.Warn("failed to read cgroup memory limit, memory budget disabled", zap.Error(err))
+		return nil
+	}
+	if limit == 0 {
+		lg.Warn("cgroup memory is not limited, memory budget disabled")
+		return nil
+	}
+	b := &memoryBudget{budget: int64(float64(limit) * ratio)}
+	memoryBudgetBytes.Set(float64(b.budget))
+
+	fields := []zap.Field{
+		zap.String("cgroup-memory-limit", humanize.IBytes(uint64(limit))),
+		zap.Float64("ratio", ratio),
+		zap.String("memory-budget", humanize.IBytes(uint64(b.budget))),
+	}
+	switch {
+	case os.Getenv("GOMEMLIMIT") != "":
+		lg.Info("enabled memory budget, keeping Go memory limit set by GOMEMLIMIT", append(fields, zap.String("GOMEMLIMIT", os.Getenv("GOMEMLIMIT")))...)
+	case setMemoryLimit(b.budget):
+		lg.Info("enabled memory budget, set Go memory limit to the budget", fields...)
+	default:
+		lg.Warn("enabled memory budget, Go memory limit is not supported by the Go version", fields...)
+	}
+	return b
+}
+
+// cgroupMemoryLimit returns the memory limit of the cgroup self of the
+// process, as read from /proc/self/cgroup, with the cgroup file systems
+// mounted at root, or 0 if the cgroup is not limited. The cgroup v2 is
+// preferred over the cgroup v1.
+func cgroupMemoryLimit(root string, self []byte) (uint64, error) {
+	// the files of the cgroup, then of the root of the hierarchy, which is
+	// the cgroup of a process in a cgroup namespace, e.g. in a container
+	var v2, v1 []string
+	s := bufio.NewScanner(bytes.NewReader(self))
+	for s.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fs := strings.SplitN(s.Text(), ":", 3)
+		if len(fs) != 3 {
+			continue
+		}
+		if fs[0] == "0" && fs[1] == "" {
+			v2 = []string{filepath.Join(root, fs[2], "memory.max"), filepath.Join(root, "memory.max")}
+			continue
+		}
+		for _, c := range strings.Split(fs[1], ",") {
+			if c == "memory" {
+				v1 = []string{filepath.Join(root, "memory", fs[2], "memory.limit_in_bytes"), filepath.Join(root, "memory", "memory.limit_in_bytes")}
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, f := range append(v2, v1...) {
+		b, err := os.ReadFile(f)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		v := strings.TrimSpace(string(b))
+		if v == "max" {
+			return 0, nil
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if n >= cgroupV1Unlimited {
+			return 0, nil
+		}
+		return n, nil
+	}
+	return 0, nil
+}
+
+func (b *memoryBudget) isExceeded() bool {
+	return b != nil && atomic.LoadInt32(&b.exceeded) == 1
+}
+
+// check updates whether the heap exceeds the budget, returning whether it
+// changed.
+func (b *memoryBudget) check(heap int64) (exceeded, changed bool) {
+	exceeded = b.isExceeded()
+	switch {
+	case !exceeded && heap > b.budget:
+		exceeded = true
+	case exceeded && heap <= b.budget/100*memoryBudgetRecoveryPercent:
+		exceeded = false
+	default:
+		return exceeded, false
+	}
+	if exceeded {
+		atomic.StoreInt32(&b.exceeded, 1)
+	} else {
+		atomic.StoreInt32(&b.exceeded, 0)
+	}
+	return exceeded, true
+}
+
+// MemoryBudgetEnabled returns whether the member sheds load while its heap
+// exceeds the memory budget.
+func (s *EtcdServer) MemoryBudgetEnabled() bool {
+	return s.memoryBudget != nil
+}
+
+// AccountRangeResponse accounts n bytes of range responses being sent to the
+// memory budget, released when n is negative.
+func (s *EtcdServer) AccountRangeResponse(n int64) {
+	if s.memoryBudget != nil {
+		atomic.AddInt64(&s.memoryBudget.rangeResponses, n)
+	}
+}
+
+// MemoryBudgetExceeded returns whether a request of the type must be shed as
+// the heap exceeds the memory budget, counting it as shed if so.
+func (s *EtcdServer) MemoryBudgetExceeded(requestType string) bool {
+	if !s.memoryBudget.isExceeded() {
+		return false
+	}
+	memoryShedRequests.WithLabelValues(requestType).Inc()
+	return true
+}
+
+// monitorMemoryBudget reports the memory of the major consumers and checks
+// the heap against the memory budget.
+func (s *EtcdServer) monitorMemoryBudget() {
+	b := s.memoryBudget
+	if b == nil {
+		return
+	}
+	lg := s.Logger()
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	for {
+		select {
+		case <-time.After(memoryBudgetCheckInterval):
+		case <-s.stopping:
+			return
+		}
+
+		metrics.Read(sample)
+		heap := int64(sample[0].Value.Uint64())
+		memoryConsumerBytes.WithLabelValues("heap").Set(float64(heap))
+		memoryConsumerBytes.WithLabelValues("index").Set(float64(s.KV().IndexBytes()))
+		memoryConsumerBytes.WithLabelValues("watch_buffers").Set(float64(mvcc.PendingEventsBytes()))
+		memoryConsumerBytes.WithLabelValues("range_responses").Set(float64(atomic.LoadInt64(&b.rangeResponses)))
+
+		exceeded, changed := b.check(heap)
+		if !changed {
+			continue
+		}
+		fields := []zap.Field{
+			zap.String("heap", humanize.IBytes(uint64(heap))),
+			zap.String("memory-budget", humanize.IBytes(uint64(b.budget))),
+		}
+		if exceeded {
+			memoryBudgetExceeded.Set(1)
+			lg.Warn("heap exceeded memory budget, shedding range requests and watch creations", fields...)
+		} else {
+			memoryBudgetExceeded.Set(0)
+			lg.Info("heap recovered under memory budget", fields...)
+		}
+	}
+}
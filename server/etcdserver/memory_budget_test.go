@@ -0,0 +1,87 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCgroupMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		self  string
+		want  uint64
+	}{
+		{"v2", map[string]string{"etcd.slice/memory.max": "1073741824\n"}, "0::/etcd.slice\n", 1 << 30},
+		{"v2 unlimited", map[string]string{"etcd.slice/memory.max": "max\n"}, "0::/etcd.slice\n", 0},
+		{"v2 container root", map[string]string{"memory.max": "536870912\n"}, "0::/kubepods/pod1\n", 1 << 29},
+		{"v1", map[string]string{"memory/docker/c1/memory.limit_in_bytes": "268435456\n"}, "12:cpu,cpuacct:/docker/c1\n4:memory:/docker/c1\n", 1 << 28},
+		{"v1 unlimited", map[string]string{"memory/memory.limit_in_bytes": "9223372036854771712\n"}, "4:memory:/\n", 0},
+		{"v2 over v1", map[string]string{"memory/memory.limit_in_bytes": "268435456\n", "memory.max": "1073741824\n"}, "4:memory:/\n0::/\n", 1 << 30},
+		{"no memory controller", nil, "12:cpu,cpuacct:/\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for f, v := range tt.files {
+				p := filepath.Join(root, f)
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(v), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := cgroupMemoryLimit(root, []byte(tt.self))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("limit = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryBudgetCheck(t *testing.T) {
+	b := &memoryBudget{budget: 1000}
+	steps := []struct {
+		heap              int64
+		exceeded, changed bool
+	}{
+		{900, false, false},
+		{1001, true, true},
+		{950, true, false},
+		{900, false, true},
+		{1000, false, false},
+	}
+	for i, s := range steps {
+		exceeded, changed := b.check(s.heap)
+		if exceeded != s.exceeded || changed != s.changed {
+			t.Errorf("#%d: heap %d: exceeded, changed = %v, %v, want %v, %v", i, s.heap, exceeded, changed, s.exceeded, s.changed)
+		}
+		if b.isExceeded() != s.exceeded {
+			t.Errorf("#%d: isExceeded = %v, want %v", i, b.isExceeded(), s.exceeded)
+		}
+	}
+
+	var nilBudget *memoryBudget
+	if nilBudget.isExceeded() {
+		t.Error("nil budget exceeded")
+	}
+}
@@ -70,6 +70,59 @@ var (
 		Name:      "heartbeat_send_failures_total",
 		Help:      "The total number of leader heartbeat send failures (likely overloaded from slow disk).",
 	})
+	campaigns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "campaigns_total",
+		Help:      "The total number of campaigns started by this member among its peers, by cause.",
+	},
+		[]string{"cause"},
+	)
+	rejectedVotes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "rejected_votes_total",
+		Help:      "The total number of vote and pre-vote requests rejected by this member.",
+	},
+		[]string{"type"},
+	)
+	peerMisbehaviorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "peer_misbehaviors_total",
+		Help:      "The total number of disruptive vote requests and of stale term messages received from the peers. The stream resets are counted by etcd_network_disconnected_peers_total.",
+	},
+		[]string{"From", "type"},
+	)
+	misbehavingPeers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "misbehaving_peers",
+		Help:      "Whether or not the peer is flagged as misbehaving, by its disruptive votes, stale term messages or stream resets over the last minute. 1 is misbehaving, 0 is not.",
+	},
+		[]string{"From"},
+	)
+	leaderLease = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "leader_lease",
+		Help:      "Whether or not the member is in a leader lease of CheckQuorum: 1 on the leader, and on the followers having heard from it within the election timeout, which ignore the votes of the other members.",
+	})
+	tickStarvations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "tick_starvations_total",
+		Help:      "The total number of heartbeat ticks delayed by more than the tick skew tolerance (likely starved of CPU).",
+	})
+	walGroupSyncSaves = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "disk",
+		Name:      "wal_group_sync_saves",
+		Help:      "The distributions of the number of raft readies saved to the WAL sharing an fsync, with the group sync enabled.",
+
+		// 1 to 128 readies
+		Buckets: prometheus.ExponentialBuckets(1, 2, 8),
+	})
 	applySnapshotInProgress = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -100,12 +153,115 @@ var (
 		Name:      "proposals_failed_total",
 		Help:      "The total number of failed proposals seen.",
 	})
+	proposalStageSec = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "proposal_stage_duration_seconds",
+		Help: "The latency distributions of the stages of the local proposals: waiting to enter the raft log (queue), " +
+			"raft commit including the network and the WAL fsync (raft_commit), waiting for the apply loop (apply_wait), " +
+			"and applying to the backend (apply).",
+
+		// lowest bucket start of upper bound 0.0001 sec (0.1 ms) with factor 2
+		// highest bucket start of 0.0001 sec * 2^16 == 6.5536 sec
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 17),
+	},
+		[]string{"stage"},
+	)
+	diskDegraded = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "disk_degraded",
+		Help:      "Whether or not the WAL fsyncs or the backend commits of the member are slower than their thresholds. 1 is degraded, 0 is not.",
+	})
+	memoryBudgetBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "memory_budget_bytes",
+		Help:      "The memory budget of the member, above which its heap sheds load. 0 if disabled.",
+	})
+	memoryBudgetExceeded = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "memory_budget_exceeded",
+		Help:      "Whether or not the heap of the member exceeds its memory budget. 1 is exceeded, 0 is not.",
+	})
+	memoryConsumerBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "memory_consumer_bytes",
+		Help:      "The estimated memory of the major consumers of the member when the memory budget is enabled.",
+	},
+		[]string{"consumer"},
+	)
+	memoryShedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "memory_shed_requests_total",
+		Help:      "The total number of requests rejected as the heap exceeds the memory budget.",
+	},
+		[]string{"type"},
+	)
+	requestLaneQueued = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "request_lane_queued_requests",
+		Help:      "The number of requests waiting in the lane of their priority for a slot of the concurrent requests limit.",
+	},
+		[]string{"lane"},
+	)
+	requestLaneWaitSec = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "request_lane_wait_duration_seconds",
+		Help:      "The wait distributions of the requests queued in the lane of their priority.",
+
+		// lowest bucket start of upper bound 0.0001 sec (0.1 ms) with factor 2
+		// highest bucket start of 0.0001 sec * 2^16 == 6.5536 sec
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 17),
+	},
+		[]string{"lane"},
+	)
+	schedulingDelaySec = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "scheduling_delay_seconds",
+		Help: "The delay distributions of the heartbeat ticks past the heartbeat interval (tick), and of the applies " +
+			"past the time they could start (apply). Growing delays indicate a member starved of CPU.",
+
+		// lowest bucket start of upper bound 0.0001 sec (0.1 ms) with factor 2
+		// highest bucket start of 0.0001 sec * 2^16 == 6.5536 sec
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 17),
+	},
+		[]string{"loop"},
+	)
+	memberStarved = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "starved",
+		Help:      "Whether or not the heartbeat ticks or the applies of the member are delayed by more than the starvation threshold. 1 is starved, 0 is not.",
+	})
 	slowReadIndex = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
 		Name:      "slow_read_indexes_total",
 		Help:      "The total number of pending read indexes not in sync with leader's or timed out read index requests.",
 	})
+	prefixRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "prefix_requests_total",
+		Help:      "The total number of reads and writes served by this member of the keys of the prefixes of --experimental-prefix-stats, the keys of none under the empty prefix.",
+	},
+		[]string{"prefix", "type"},
+	)
+	prefixBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "server",
+		Name:      "prefix_bytes_total",
+		Help:      "The total size of the keys and values read and written by this member of the prefixes of --experimental-prefix-stats, the keys of none under the empty prefix.",
+	},
+		[]string{"prefix", "type"},
+	)
 	readIndexFailed = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd",
 		Subsystem: "server",
@@ -160,13 +316,32 @@ func init() {
 	prometheus.MustRegister(isLeader)
 	prometheus.MustRegister(leaderChanges)
 	prometheus.MustRegister(heartbeatSendFailures)
+	prometheus.MustRegister(tickStarvations)
+	prometheus.MustRegister(campaigns)
+	prometheus.MustRegister(rejectedVotes)
+	prometheus.MustRegister(leaderLease)
+	prometheus.MustRegister(peerMisbehaviorsTotal)
+	prometheus.MustRegister(misbehavingPeers)
+	prometheus.MustRegister(walGroupSyncSaves)
 	prometheus.MustRegister(applySnapshotInProgress)
 	prometheus.MustRegister(proposalsCommitted)
 	prometheus.MustRegister(proposalsApplied)
 	prometheus.MustRegister(proposalsPending)
 	prometheus.MustRegister(proposalsFailed)
+	prometheus.MustRegister(proposalStageSec)
+	prometheus.MustRegister(diskDegraded)
+	prometheus.MustRegister(memoryBudgetBytes)
+	prometheus.MustRegister(memoryBudgetExceeded)
+	prometheus.MustRegister(memoryConsumerBytes)
+	prometheus.MustRegister(memoryShedRequests)
+	prometheus.MustRegister(requestLaneQueued)
+	prometheus.MustRegister(requestLaneWaitSec)
+	prometheus.MustRegister(schedulingDelaySec)
+	prometheus.MustRegister(memberStarved)
 	prometheus.MustRegister(slowReadIndex)
 	prometheus.MustRegister(readIndexFailed)
+	prometheus.MustRegister(prefixRequests)
+	prometheus.MustRegister(prefixBytes)
 	prometheus.MustRegister(leaseExpired)
 	prometheus.MustRegister(currentVersion)
 	prometheus.MustRegister(currentGoVersion)
@@ -0,0 +1,149 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/server/v3/auth"
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
+	"go.etcd.io/etcd/server/v3/etcdserver/txn"
+
+	"go.uber.org/zap"
+)
+
+// defaultPacedDeleteInterval is the interval between the batches of a paced
+// delete range when the backend batch interval is not configured, the
+// default backend batch interval.
+const defaultPacedDeleteInterval = 100 * time.Millisecond
+
+// pacedDeleteRange deletes the range of r in batches of at most
+// ExperimentalMaxDeleteBatchSize keys, each proposed as its own delete range
+// a backend batch interval after the previous one is applied. The batches
+// are bounded by the keys of the range in the local store; the last one
+// deletes up to the end of the range.
+func (s *EtcdServer) pacedDeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	batchSize := int64(s.Cfg.ExperimentalMaxDeleteBatchSize)
+	if len(r.RangeEnd) == 0 || batchSize <= 0 {
+		return s.deleteRange(ctx, &pb.DeleteRangeRequest{Key: r.Key, RangeEnd: r.RangeEnd, PrevKv: r.PrevKv})
+	}
+	interval := s.Cfg.BackendBatchInterval
+	if interval == 0 {
+		interval = defaultPacedDeleteInterval
+	}
+
+	var (
+		resp    = &pb.DeleteRangeResponse{}
+		batches int
+		start   = time.Now()
+	)
+	for key := r.Key; ; {
+		dr := &pb.DeleteRangeRequest{Key: key, RangeEnd: r.RangeEnd, PrevKv: r.PrevKv}
+		end, err := s.pacedDeleteBatchEnd(ctx, key, r.RangeEnd, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if end != nil {
+			dr.RangeEnd = end
+		}
+
+		br, err := s.deleteRangeBackoff(ctx, dr, interval)
+		if err != nil {
+			s.Logger().Warn(
+				"paced delete range failed, the range is partially deleted",
+				zap.String("key", string(r.Key)),
+				zap.String("range-end", string(r.RangeEnd)),
+				zap.Int("batches", batches),
+				zap.Int64("deleted", resp.Deleted),
+				zap.Error(err),
+			)
+			return nil, err
+		}
+		batches++
+		resp.Header = br.Header
+		resp.Deleted += br.Deleted
+		resp.PrevKvs = append(resp.PrevKvs, br.PrevKvs...)
+		if end == nil {
+			break
+		}
+		key = end
+
+		// pace the batches so that each of them is committed by the backend
+		// on its own instead of piling up into a single commit
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.stopping:
+			return nil, errors.ErrStopped
+		}
+	}
+
+	s.Logger().Info(
+		"paced delete range finished",
+		zap.String("key", string(r.Key)),
+		zap.String("range-end", string(r.RangeEnd)),
+		zap.Int("batches", batches),
+		zap.Int64("deleted", resp.Deleted),
+		zap.Duration("took", time.Since(start)),
+	)
+	return resp, nil
+}
+
+// pacedDeleteBatchEnd returns the key following the last of the batchSize
+// first keys of the range [key, rangeEnd) in the local store, or nil if the
+// range has at most batchSize keys.
+func (s *EtcdServer) pacedDeleteBatchEnd(ctx context.Context, key, rangeEnd []byte, batchSize int64) ([]byte, error) {
+	chk := func(ai *auth.AuthInfo) error {
+		return s.authStore.IsDeleteRangePermitted(ai, key, rangeEnd)
+	}
+	var (
+		rr  *pb.RangeResponse
+		err error
+	)
+	rreq := &pb.RangeRequest{Key: key, RangeEnd: rangeEnd, Limit: batchSize, KeysOnly: true, Serializable: true}
+	get := func() { rr, err = txn.Range(ctx, s.Logger(), s.KV(), nil, rreq) }
+	if serr := s.doSerialize(ctx, chk, get); serr != nil {
+		return nil, serr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !rr.More {
+		return nil, nil
+	}
+	last := rr.Kvs[len(rr.Kvs)-1].Key
+	return append(append(make([]byte, 0, len(last)+1), last...), 0), nil
+}
+
+// deleteRangeBackoff proposes the delete range, retrying after the interval
+// while the member has too many proposals not yet applied.
+func (s *EtcdServer) deleteRangeBackoff(ctx context.Context, r *pb.DeleteRangeRequest, interval time.Duration) (*pb.DeleteRangeResponse, error) {
+	for {
+		resp, err := s.deleteRange(ctx, r)
+		if err != errors.ErrTooManyRequests {
+			return resp, err
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.stopping:
+			return nil, errors.ErrStopped
+		}
+	}
+}
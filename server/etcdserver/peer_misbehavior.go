@@ -0,0 +1,227 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// peerMisbehaviorCheckInterval is the length of the windows the
+	// misbehaviors of the peers are counted over.
+	peerMisbehaviorCheckInterval = 10 * time.Second
+	// peerMisbehaviorWindows is the number of the last windows the
+	// misbehaviors of a peer are summed over to flag it, a minute.
+	peerMisbehaviorWindows = 6
+)
+
+// peerMisbehaviorThresholds are the misbehaviors of a peer over the last
+// windows any of which flags it as misbehaving. A member losing its leader
+// campaigns every election timeout, a second by default, and an election
+// only briefly delays the messages of the previous term.
+var peerMisbehaviorThresholds = peerMisbehaviors{
+	disruptiveVotes:   5,
+	staleTermMessages: 100,
+	streamResets:      5,
+}
+
+// campaignTransfer is the context of the vote requests of a leadership
+// transfer, legitimately disrupting the leader.
+var campaignTransfer = []byte("CampaignTransfer")
+
+type peerMisbehaviors struct {
+	disruptiveVotes   uint64
+	staleTermMessages uint64
+	streamResets      uint64
+}
+
+func (m *peerMisbehaviors) add(o peerMisbehaviors) {
+	m.disruptiveVotes += o.disruptiveVotes
+	m.staleTermMessages += o.staleTermMessages
+	m.streamResets += o.streamResets
+}
+
+func (m peerMisbehaviors) exceeds(th peerMisbehaviors) bool {
+	return m.disruptiveVotes >= th.disruptiveVotes ||
+		m.staleTermMessages >= th.staleTermMessages ||
+		m.streamResets >= th.streamResets
+}
+
+// peerWindows are the misbehaviors of a peer over the last windows.
+type peerWindows struct {
+	windows [peerMisbehaviorWindows]peerMisbehaviors
+	// current is the index of the current window in windows.
+	current int
+	// disconnects is the number of disconnects of the peer last checked.
+	disconnects uint64
+	misbehaving bool
+}
+
+func (w *peerWindows) sum() (m peerMisbehaviors) {
+	for _, wm := range w.windows {
+		m.add(wm)
+	}
+	return m
+}
+
+// peerMonitor counts the misbehaviors of the peers, flagging as misbehaving
+// the ones exceeding peerMisbehaviorThresholds over the last windows.
+type peerMonitor struct {
+	mu    sync.Mutex
+	peers map[types.ID]*peerWindows
+}
+
+func newPeerMonitor() *peerMonitor {
+	return &peerMonitor{peers: make(map[types.ID]*peerWindows)}
+}
+
+func (pm *peerMonitor) peer(id types.ID) *peerWindows {
+	w, ok := pm.peers[id]
+	if !ok {
+		w = &peerWindows{}
+		pm.peers[id] = w
+	}
+	return w
+}
+
+func (pm *peerMonitor) observe(id types.ID, m peerMisbehaviors) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	w := pm.peer(id)
+	w.windows[w.current].add(m)
+}
+
+// check counts the stream resets of the peers by their numbers of
+// disconnects, dropping the peers not in disconnects, and ends the current
+// window. It returns the peers which the window flagged as misbehaving, and
+// the ones it cleared or dropped.
+func (pm *peerMonitor) check(disconnects map[types.ID]uint64) (flagged, cleared []types.ID) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	for id, w := range pm.peers {
+		if _, ok := disconnects[id]; !ok {
+			if w.misbehaving {
+				cleared = append(cleared, id)
+			}
+			delete(pm.peers, id)
+		}
+	}
+	for id, d := range disconnects {
+		w := pm.peer(id)
+		if d > w.disconnects {
+			w.windows[w.current].streamResets += d - w.disconnects
+		}
+		w.disconnects = d
+
+		misbehaving := w.sum().exceeds(peerMisbehaviorThresholds)
+		switch {
+		case misbehaving && !w.misbehaving:
+			flagged = append(flagged, id)
+		case !misbehaving && w.misbehaving:
+			cleared = append(cleared, id)
+		}
+		w.misbehaving = misbehaving
+
+		w.current = (w.current + 1) % peerMisbehaviorWindows
+		w.windows[w.current] = peerMisbehaviors{}
+	}
+	return flagged, cleared
+}
+
+// misbehaving returns the misbehaviors of the peers flagged as misbehaving,
+// ordered by their IDs.
+func (pm *peerMonitor) misbehaving() []*pb.MisbehavingPeer {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	var peers []*pb.MisbehavingPeer
+	for id, w := range pm.peers {
+		if !w.misbehaving {
+			continue
+		}
+		m := w.sum()
+		peers = append(peers, &pb.MisbehavingPeer{
+			ID:                uint64(id),
+			DisruptiveVotes:   m.disruptiveVotes,
+			StaleTermMessages: m.staleTermMessages,
+			StreamResets:      m.streamResets,
+		})
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].ID < peers[j].ID })
+	return peers
+}
+
+// observePeerMessage counts the message of a peer if it is a misbehavior:
+// a vote request while the member is in a leader lease, or a message of a
+// term older than the term of the member. Raft rejects both.
+func (s *EtcdServer) observePeerMessage(m raftpb.Message) {
+	from := types.ID(m.From)
+	switch {
+	case m.Term != 0 && m.Term < s.Term():
+		s.peerMonitor.observe(from, peerMisbehaviors{staleTermMessages: 1})
+		peerMisbehaviorsTotal.WithLabelValues(from.String(), "stale_term_message").Inc()
+	case (m.Type == raftpb.MsgVote || m.Type == raftpb.MsgPreVote) &&
+		!bytes.Equal(m.Context, campaignTransfer) && m.From != s.getLead() && s.inLeaderLease():
+		s.peerMonitor.observe(from, peerMisbehaviors{disruptiveVotes: 1})
+		peerMisbehaviorsTotal.WithLabelValues(from.String(), "disruptive_vote").Inc()
+	}
+}
+
+// monitorPeerMisbehaviors flags the peers misbehaving over the last minute
+// every peerMisbehaviorCheckInterval.
+func (s *EtcdServer) monitorPeerMisbehaviors() {
+	lg := s.Logger()
+	for {
+		select {
+		case <-time.After(peerMisbehaviorCheckInterval):
+		case <-s.stopping:
+			return
+		}
+
+		flagged, cleared := s.peerMonitor.check(s.r.transport.Disconnects())
+		for _, id := range flagged {
+			misbehavingPeers.WithLabelValues(id.String()).Set(1)
+			lg.Warn(
+				"peer is likely misbehaving",
+				zap.String("local-member-id", s.MemberId().String()),
+				zap.String("peer-id", id.String()),
+			)
+			s.RecordEvent(EventPeerMisbehaving, fmt.Sprintf("peer %s misbehaving", id))
+		}
+		for _, id := range cleared {
+			misbehavingPeers.WithLabelValues(id.String()).Set(0)
+			lg.Info(
+				"peer is no longer misbehaving",
+				zap.String("local-member-id", s.MemberId().String()),
+				zap.String("peer-id", id.String()),
+			)
+		}
+	}
+}
+
+// MisbehavingPeers returns the peers of the member likely misbehaving, with
+// their misbehaviors over the last minute.
+func (s *EtcdServer) MisbehavingPeers() []*pb.MisbehavingPeer {
+	return s.peerMonitor.misbehaving()
+}
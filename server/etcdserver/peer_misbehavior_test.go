@@ -0,0 +1,101 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/types"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+func TestPeerMonitor(t *testing.T) {
+	pm := newPeerMonitor()
+	disconnects := map[types.ID]uint64{1: 0, 2: 0}
+
+	pm.observe(1, peerMisbehaviors{disruptiveVotes: 4})
+	if flagged, cleared := pm.check(disconnects); flagged != nil || cleared != nil {
+		t.Fatalf("check() = %v, %v, want no peer flagged nor cleared", flagged, cleared)
+	}
+	// the misbehaviors are summed over the windows
+	pm.observe(1, peerMisbehaviors{disruptiveVotes: 1})
+	if flagged, _ := pm.check(disconnects); !reflect.DeepEqual(flagged, []types.ID{1}) {
+		t.Fatalf("flagged = %v, want [1]", flagged)
+	}
+	// the stream resets are counted by the disconnects since the last check
+	disconnects[2] = 5
+	if flagged, _ := pm.check(disconnects); !reflect.DeepEqual(flagged, []types.ID{2}) {
+		t.Fatalf("flagged = %v, want [2]", flagged)
+	}
+	wpeers := []*pb.MisbehavingPeer{{ID: 1, DisruptiveVotes: 5}, {ID: 2, StreamResets: 5}}
+	if peers := pm.misbehaving(); !reflect.DeepEqual(peers, wpeers) {
+		t.Fatalf("misbehaving() = %v, want %v", peers, wpeers)
+	}
+
+	// peer 1 is cleared as its votes leave the windows
+	for i := 0; i < peerMisbehaviorWindows-3; i++ {
+		if flagged, cleared := pm.check(disconnects); flagged != nil || cleared != nil {
+			t.Fatalf("#%d: check() = %v, %v, want no peer flagged nor cleared", i, flagged, cleared)
+		}
+	}
+	if _, cleared := pm.check(disconnects); !reflect.DeepEqual(cleared, []types.ID{1}) {
+		t.Fatalf("cleared = %v, want [1]", cleared)
+	}
+
+	// peer 2 is cleared as it is removed
+	if _, cleared := pm.check(map[types.ID]uint64{1: 0}); !reflect.DeepEqual(cleared, []types.ID{2}) {
+		t.Fatalf("cleared = %v, want [2]", cleared)
+	}
+	if _, ok := pm.peers[2]; ok {
+		t.Errorf("peer 2 kept, want it dropped")
+	}
+	if peers := pm.misbehaving(); peers != nil {
+		t.Errorf("misbehaving() = %v, want no peer", peers)
+	}
+}
+
+func TestObservePeerMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		m    raftpb.Message
+		// lead is the leader known by the member, 1 being the member
+		lead uint64
+		w    peerMisbehaviors
+	}{
+		{"append", raftpb.Message{Type: raftpb.MsgApp, From: 2, Term: 5}, 2, peerMisbehaviors{}},
+		{"stale term", raftpb.Message{Type: raftpb.MsgApp, From: 2, Term: 4}, 2, peerMisbehaviors{staleTermMessages: 1}},
+		{"vote to the leader", raftpb.Message{Type: raftpb.MsgVote, From: 2, Term: 6}, 1, peerMisbehaviors{disruptiveVotes: 1}},
+		{"pre-vote to the leader", raftpb.Message{Type: raftpb.MsgPreVote, From: 2, Term: 6}, 1, peerMisbehaviors{disruptiveVotes: 1}},
+		{"vote without leader", raftpb.Message{Type: raftpb.MsgVote, From: 2, Term: 6}, 0, peerMisbehaviors{}},
+		{"leadership transfer", raftpb.Message{Type: raftpb.MsgVote, From: 2, Term: 6, Context: campaignTransfer}, 1, peerMisbehaviors{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EtcdServer{memberId: 1, peerMonitor: newPeerMonitor()}
+			s.setTerm(5)
+			s.setLead(tt.lead)
+			s.observePeerMessage(tt.m)
+			var m peerMisbehaviors
+			if w, ok := s.peerMonitor.peers[2]; ok {
+				m = w.sum()
+			}
+			if m != tt.w {
+				t.Errorf("misbehaviors = %+v, want %+v", m, tt.w)
+			}
+		})
+	}
+}
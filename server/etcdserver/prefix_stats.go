@@ -0,0 +1,146 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MaxStatsPrefixes is the maximum number of the prefixes counted by the
+// prefix statistics, bounding the cardinality of their metrics.
+const MaxStatsPrefixes = 64
+
+// ValidateStatsPrefixes returns an error if the prefixes cannot be counted
+// by the prefix statistics.
+func ValidateStatsPrefixes(prefixes []string) error {
+	if len(prefixes) > MaxStatsPrefixes {
+		return fmt.Errorf("%d prefixes exceed the maximum of %d", len(prefixes), MaxStatsPrefixes)
+	}
+	seen := make(map[string]struct{}, len(prefixes))
+	for _, p := range prefixes {
+		if p == "" {
+			return fmt.Errorf("empty prefix")
+		}
+		if _, ok := seen[p]; ok {
+			return fmt.Errorf("duplicate prefix %q", p)
+		}
+		seen[p] = struct{}{}
+	}
+	return nil
+}
+
+// PrefixStats counts the reads and writes served by the member, and their
+// bytes, per configured key prefix. A key is counted under the longest
+// prefix it has, the keys having none under the empty prefix.
+type PrefixStats struct {
+	start time.Time
+	// counters are the counters of the prefixes sorted by prefix, the
+	// first one being of the empty prefix.
+	counters []*prefixCounters
+}
+
+type prefixCounters struct {
+	// the counters are first, for their 64-bit alignment
+	reads, writes, readBytes, writeBytes int64
+	prefix                               []byte
+	readsMetric, writesMetric            prometheus.Counter
+	readBytesMetric, writeBytesMetric    prometheus.Counter
+}
+
+// NewPrefixStats returns the statistics of the prefixes, validated by
+// ValidateStatsPrefixes.
+func NewPrefixStats(prefixes []string) *PrefixStats {
+	ps := &PrefixStats{start: time.Now()}
+	sorted := append([]string{""}, prefixes...)
+	sort.Strings(sorted)
+	for _, p := range sorted {
+		ps.counters = append(ps.counters, &prefixCounters{
+			prefix:           []byte(p),
+			readsMetric:      prefixRequests.WithLabelValues(p, "read"),
+			writesMetric:     prefixRequests.WithLabelValues(p, "write"),
+			readBytesMetric:  prefixBytes.WithLabelValues(p, "read"),
+			writeBytesMetric: prefixBytes.WithLabelValues(p, "write"),
+		})
+	}
+	return ps
+}
+
+// countersOf returns the counters of the longest prefix of key.
+func (ps *PrefixStats) countersOf(key []byte) *prefixCounters {
+	var longest *prefixCounters
+	for _, c := range ps.counters {
+		if bytes.HasPrefix(key, c.prefix) && (longest == nil || len(c.prefix) > len(longest.prefix)) {
+			longest = c
+		}
+	}
+	return longest
+}
+
+// Read counts a read of the keys from key, of n bytes.
+func (ps *PrefixStats) Read(key []byte, n int) {
+	if ps == nil {
+		return
+	}
+	c := ps.countersOf(key)
+	atomic.AddInt64(&c.reads, 1)
+	atomic.AddInt64(&c.readBytes, int64(n))
+	c.readsMetric.Inc()
+	c.readBytesMetric.Add(float64(n))
+}
+
+// Write counts a write of the keys from key, of n bytes.
+func (ps *PrefixStats) Write(key []byte, n int) {
+	if ps == nil {
+		return
+	}
+	c := ps.countersOf(key)
+	atomic.AddInt64(&c.writes, 1)
+	atomic.AddInt64(&c.writeBytes, int64(n))
+	c.writesMetric.Inc()
+	c.writeBytesMetric.Add(float64(n))
+}
+
+// Stats returns the statistics of the prefixes, and the time the member
+// started counting.
+func (ps *PrefixStats) Stats() ([]*pb.PrefixStat, time.Time) {
+	if ps == nil {
+		return nil, time.Time{}
+	}
+	stats := make([]*pb.PrefixStat, 0, len(ps.counters))
+	for _, c := range ps.counters {
+		stats = append(stats, &pb.PrefixStat{
+			Prefix:     c.prefix,
+			Reads:      atomic.LoadInt64(&c.reads),
+			Writes:     atomic.LoadInt64(&c.writes),
+			ReadBytes:  atomic.LoadInt64(&c.readBytes),
+			WriteBytes: atomic.LoadInt64(&c.writeBytes),
+		})
+	}
+	return stats, ps.start
+}
+
+// PrefixStats returns the statistics of the prefixes configured by
+// --experimental-prefix-stats, nil if none.
+func (s *EtcdServer) PrefixStats() *PrefixStats {
+	return s.prefixStats
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+)
+
+func TestValidateStatsPrefixes(t *testing.T) {
+	tooMany := make([]string, MaxStatsPrefixes+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("p%d/", i)
+	}
+	tests := []struct {
+		prefixes []string
+		ok       bool
+	}{
+		{nil, true},
+		{[]string{"a/", "a/b/", "b/"}, true},
+		{tooMany[:MaxStatsPrefixes], true},
+		{tooMany, false},
+		{[]string{"a/", ""}, false},
+		{[]string{"a/", "a/"}, false},
+	}
+	for i, tt := range tests {
+		if err := ValidateStatsPrefixes(tt.prefixes); (err == nil) != tt.ok {
+			t.Errorf("#%d: ValidateStatsPrefixes(%q) = %v, want ok %v", i, tt.prefixes, err, tt.ok)
+		}
+	}
+}
+
+func TestPrefixStatsLongestPrefix(t *testing.T) {
+	ps := NewPrefixStats([]string{"b/", "a/", "a/b/"})
+	ps.Read([]byte("a/x"), 10)
+	ps.Read([]byte("a/b/x"), 20)
+	ps.Write([]byte("a/b/y"), 5)
+	ps.Write([]byte("a/b/y"), 6)
+	ps.Write([]byte("b/"), 1)
+	ps.Read([]byte("c"), 3)
+	ps.Write([]byte("a"), 2)
+
+	stats, _ := ps.Stats()
+	want := []*pb.PrefixStat{
+		{Prefix: []byte(""), Reads: 1, Writes: 1, ReadBytes: 3, WriteBytes: 2},
+		{Prefix: []byte("a/"), Reads: 1, ReadBytes: 10},
+		{Prefix: []byte("a/b/"), Reads: 1, Writes: 2, ReadBytes: 20, WriteBytes: 11},
+		{Prefix: []byte("b/"), Writes: 1, WriteBytes: 1},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("stats = %v, want %v", stats, want)
+	}
+}
+
+func TestPrefixStatsNil(t *testing.T) {
+	var ps *PrefixStats
+	ps.Read([]byte("a"), 1)
+	ps.Write([]byte("a"), 1)
+	if stats, _ := ps.Stats(); stats != nil {
+		t.Errorf("stats of nil = %v, want none", stats)
+	}
+}
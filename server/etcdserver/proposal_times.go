@@ -0,0 +1,92 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"sync"
+	"time"
+)
+
+// Stages of the proposal pipeline, labeling proposalStageSec.
+const (
+	proposalStageQueue      = "queue"
+	proposalStageRaftCommit = "raft_commit"
+	proposalStageApplyWait  = "apply_wait"
+	proposalStageApply      = "apply"
+)
+
+// proposalTimes keeps the times of the pending local proposals, to observe
+// the latency of their stages once applied.
+type proposalTimes struct {
+	mu        sync.Mutex
+	proposals map[uint64]*proposalTime
+}
+
+type proposalTime struct {
+	// start is when the proposal was submitted to the raft node.
+	start time.Time
+	// proposed is when the raft node accepted the proposal, zero until then.
+	proposed time.Time
+}
+
+// start notes the submission of the proposal to the raft node.
+func (pt *proposalTimes) start(id uint64, t time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if pt.proposals == nil {
+		pt.proposals = make(map[uint64]*proposalTime)
+	}
+	pt.proposals[id] = &proposalTime{start: t}
+}
+
+// propose notes the acceptance of the proposal by the raft node.
+func (pt *proposalTimes) propose(id uint64, t time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if p, ok := pt.proposals[id]; ok {
+		p.proposed = t
+	}
+}
+
+func (pt *proposalTimes) forget(id uint64) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	delete(pt.proposals, id)
+}
+
+// observeApplied observes the stages of the proposal with the given id, if it
+// is a pending local proposal. Only the apply stage is observed without the
+// commit time.
+func (pt *proposalTimes) observeApplied(id uint64, committed, applyStart, applyEnd time.Time) {
+	pt.mu.Lock()
+	p, ok := pt.proposals[id]
+	delete(pt.proposals, id)
+	pt.mu.Unlock()
+	if !ok {
+		return
+	}
+	if !committed.IsZero() {
+		// the proposer may note the acceptance of the proposal after the raft
+		// node got it as committed, which then ends the queue stage.
+		proposed := p.proposed
+		if proposed.IsZero() || proposed.After(committed) {
+			proposed = committed
+		}
+		proposalStageSec.WithLabelValues(proposalStageQueue).Observe(proposed.Sub(p.start).Seconds())
+		proposalStageSec.WithLabelValues(proposalStageRaftCommit).Observe(committed.Sub(proposed).Seconds())
+		proposalStageSec.WithLabelValues(proposalStageApplyWait).Observe(applyStart.Sub(committed).Seconds())
+	}
+	proposalStageSec.WithLabelValues(proposalStageApply).Observe(applyEnd.Sub(applyStart).Seconds())
+}
@@ -15,10 +15,12 @@
 package etcdserver
 
 import (
+	"bytes"
 	"expvar"
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.etcd.io/etcd/client/pkg/v3/logutil"
@@ -70,9 +72,15 @@ type toApply struct {
 	snapshot raftpb.Snapshot
 	// notifyc synchronizes etcd server applies with the raft node
 	notifyc chan struct{}
+	// committed is when the raft node got the entries as committed.
+	committed time.Time
 }
 
 type raftNode struct {
+	// lastTick is the time in nanoseconds the loop of the node last received
+	// a heartbeat tick, accessed atomically.
+	lastTick int64
+
 	lg *zap.Logger
 
 	tickMu *sync.Mutex
@@ -105,6 +113,14 @@ type raftNodeConfig struct {
 	raftStorage *raft.MemoryStorage
 	storage     serverstorage.Storage
 	heartbeat   time.Duration // for logging
+	// tickSkewTolerance is the delay of a tick above which the member is
+	// starved, 0 not to detect it.
+	tickSkewTolerance time.Duration
+	// walGroupSyncMaxDelay is the maximum delay of the WAL fsync shared by
+	// consecutive readies, 0 to sync each of them.
+	walGroupSyncMaxDelay time.Duration
+	// preVote is whether raft runs Pre-Vote, for the campaign metrics.
+	preVote bool
 	// transport specifies the transport to send and receive msgs to members.
 	// Sending messages MUST NOT block. It is okay to drop messages, since
 	// clients should timeout and reissue their messages.
@@ -161,10 +177,50 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 	go func() {
 		defer r.onStop()
 		islead := false
+		lastTick := time.Now()
+		atomic.StoreInt64(&r.lastTick, lastTick.UnixNano())
+		skipped := false
+		// the readies saved to the WAL waiting for a shared fsync
+		var group walSyncGroup
+		defer group.reset()
 
 		for {
 			select {
+			case <-group.timeout():
+				r.syncWALGroup(rh, &group)
 			case <-r.ticker.C:
+				now := time.Now()
+				// a tick delayed by a long GC pause or CPU throttling covers a
+				// time the member could not process the messages of the leader,
+				// already queued. A follower skips it not to campaign before
+				// processing them, but never two ticks in a row so that it still
+				// campaigns if the leader is lost.
+				elapsed := now.Sub(lastTick)
+				lastTick = now
+				atomic.StoreInt64(&r.lastTick, now.UnixNano())
+				if rh.observeTickDelay != nil {
+					delay := elapsed - r.heartbeat
+					if delay < 0 {
+						delay = 0
+					}
+					rh.observeTickDelay(delay)
+				}
+				if r.tickSkewTolerance > 0 && elapsed > r.heartbeat+r.tickSkewTolerance {
+					skip := !islead && !skipped
+					tickStarvations.Inc()
+					r.lg.Warn(
+						"heartbeat tick delayed; member is likely starved of CPU",
+						zap.Duration("heartbeat-interval", r.heartbeat),
+						zap.Duration("tick-skew-tolerance", r.tickSkewTolerance),
+						zap.Duration("tick-interval", elapsed),
+						zap.Bool("skipped", skip),
+					)
+					if skip {
+						skipped = true
+						continue
+					}
+				}
+				skipped = false
 				r.tick()
 			case rd := <-r.Ready():
 				if rd.SoftState != nil {
@@ -202,19 +258,26 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 
 				notifyc := make(chan struct{}, 1)
 				ap := toApply{
-					entries:  rd.CommittedEntries,
-					snapshot: rd.Snapshot,
-					notifyc:  notifyc,
+					entries:   rd.CommittedEntries,
+					snapshot:  rd.Snapshot,
+					notifyc:   notifyc,
+					committed: time.Now(),
 				}
 
 				updateCommittedIndex(&ap, rh)
 
+				// the fsync of the ready is shared with the following ones,
+				// unless its entries must be on stable storage before being
+				// applied, or it changes the state of the member.
+				groupSync := r.walGroupSyncMaxDelay > 0 && rd.SoftState == nil && raft.IsEmptySnap(rd.Snapshot) && !shouldWaitWALSync(rd)
+				if group.readies > 0 && (!groupSync || group.saved(rd.CommittedEntries)) {
+					r.syncWALGroup(rh, &group)
+				}
+
 				waitWALSync := shouldWaitWALSync(rd)
 				if waitWALSync {
 					// gofail: var raftBeforeSaveWaitWalSync struct{}
-					if err := r.storage.Save(rd.HardState, rd.Entries); err != nil {
-						r.lg.Fatal("failed to save Raft hard state and entries", zap.Error(err))
-					}
+					r.saveToWAL(rh, rd)
 				}
 
 				select {
@@ -243,8 +306,10 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 
 				if !waitWALSync {
 					// gofail: var raftBeforeSave struct{}
-					if err := r.storage.Save(rd.HardState, rd.Entries); err != nil {
-						r.lg.Fatal("failed to save Raft hard state and entries", zap.Error(err))
+					if groupSync {
+						r.saveToWALGroup(rd, &group)
+					} else {
+						r.saveToWAL(rh, rd)
 					}
 				}
 				if !raft.IsEmptyHardState(rd.HardState) {
@@ -310,7 +375,12 @@ func (r *raftNode) start(rh *raftReadyHandler) {
 					}
 
 					// gofail: var raftBeforeFollowerSend struct{}
-					r.transport.Send(msgs)
+					if group.readies > 0 {
+						// the messages may acknowledge the entries not synced yet
+						group.msgs = append(group.msgs, msgs...)
+					} else {
+						r.transport.Send(msgs)
+					}
 				} else {
 					// leader already processed 'MsgSnap' and signaled
 					notifyc <- struct{}{}
@@ -360,6 +430,88 @@ func shouldWaitWALSync(rd raft.Ready) bool {
 		(lastCommittedEntry.Term == firstUnstableEntry.Term && lastCommittedEntry.Index >= firstUnstableEntry.Index)
 }
 
+// saveToWAL saves the hard state and the entries of the ready to the WAL,
+// observing the duration of the saves synced to disk.
+func (r *raftNode) saveToWAL(rh *raftReadyHandler, rd raft.Ready) {
+	start := time.Now()
+	if err := r.storage.Save(rd.HardState, rd.Entries); err != nil {
+		r.lg.Fatal("failed to save Raft hard state and entries", zap.Error(err))
+	}
+	// the WAL is synced when saving entries
+	if len(rd.Entries) > 0 && rh.observeWALFsync != nil {
+		rh.observeWALFsync(time.Since(start))
+	}
+}
+
+// walSyncGroup is the readies saved to the WAL sharing the next fsync, with
+// the messages of the follower held until they are on stable storage.
+type walSyncGroup struct {
+	readies int
+	// firstIndex is the lowest index of the entries saved, 0 if none.
+	firstIndex uint64
+	msgs       []raftpb.Message
+	// timer fires when the first ready waited for the maximum delay.
+	timer *time.Timer
+}
+
+func (g *walSyncGroup) add(rd raft.Ready, maxDelay time.Duration) {
+	if g.readies == 0 {
+		g.timer = time.NewTimer(maxDelay)
+	}
+	g.readies++
+	if len(rd.Entries) > 0 && (g.firstIndex == 0 || rd.Entries[0].Index < g.firstIndex) {
+		g.firstIndex = rd.Entries[0].Index
+	}
+}
+
+// saved returns true if the last of the given entries was saved by the group.
+func (g *walSyncGroup) saved(ents []raftpb.Entry) bool {
+	return g.firstIndex != 0 && len(ents) > 0 && ents[len(ents)-1].Index >= g.firstIndex
+}
+
+func (g *walSyncGroup) timeout() <-chan time.Time {
+	if g.timer == nil {
+		return nil
+	}
+	return g.timer.C
+}
+
+func (g *walSyncGroup) reset() {
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	*g = walSyncGroup{}
+}
+
+// saveToWALGroup saves the hard state and the entries of the ready to the WAL
+// without syncing them, adding the ready to the group sharing the next fsync.
+func (r *raftNode) saveToWALGroup(rd raft.Ready, g *walSyncGroup) {
+	mustSync, err := r.storage.SaveNoSync(rd.HardState, rd.Entries)
+	if err != nil {
+		r.lg.Fatal("failed to save Raft hard state and entries", zap.Error(err))
+	}
+	if mustSync {
+		g.add(rd, r.walGroupSyncMaxDelay)
+	}
+}
+
+// syncWALGroup syncs the readies of the group saved to the WAL, then sends
+// the messages held until then.
+func (r *raftNode) syncWALGroup(rh *raftReadyHandler, g *walSyncGroup) {
+	start := time.Now()
+	if err := r.storage.Sync(); err != nil {
+		r.lg.Fatal("failed to sync Raft hard state and entries", zap.Error(err))
+	}
+	if g.firstIndex != 0 && rh.observeWALFsync != nil {
+		rh.observeWALFsync(time.Since(start))
+	}
+	walGroupSyncSaves.Observe(float64(g.readies))
+	if len(g.msgs) > 0 {
+		r.transport.Send(g.msgs)
+	}
+	g.reset()
+}
+
 func updateCommittedIndex(ap *toApply, rh *raftReadyHandler) {
 	var ci uint64
 	if len(ap.entries) != 0 {
@@ -375,7 +527,20 @@ func updateCommittedIndex(ap *toApply, rh *raftReadyHandler) {
 
 func (r *raftNode) processMessages(ms []raftpb.Message) []raftpb.Message {
 	sentAppResp := false
+	campaigned := false
 	for i := len(ms) - 1; i >= 0; i-- {
+		if cause, ok := campaignCause(ms[i], r.preVote); ok && !campaigned {
+			// a campaign sends a vote request to each of the voters
+			campaigned = true
+			campaigns.WithLabelValues(cause).Inc()
+		}
+		switch {
+		case ms[i].Type == raftpb.MsgVoteResp && ms[i].Reject:
+			rejectedVotes.WithLabelValues("vote").Inc()
+		case ms[i].Type == raftpb.MsgPreVoteResp && ms[i].Reject:
+			rejectedVotes.WithLabelValues("pre-vote").Inc()
+		}
+
 		if r.isIDRemoved(ms[i].To) {
 			ms[i].To = 0
 		}
@@ -418,6 +583,23 @@ func (r *raftNode) processMessages(ms []raftpb.Message) []raftpb.Message {
 	return ms
 }
 
+// campaignTransferContext is the context of the vote requests of a campaign
+// started by a leadership transfer, raft skipping Pre-Vote for them.
+var campaignTransferContext = []byte("CampaignTransfer")
+
+// campaignCause returns the cause of the campaign started by the message, and
+// false if it does not start one, e.g. the vote requests following a won
+// Pre-Vote.
+func campaignCause(m raftpb.Message, preVote bool) (string, bool) {
+	switch {
+	case m.Type == raftpb.MsgVote && bytes.Equal(m.Context, campaignTransferContext):
+		return "leader-transfer", true
+	case m.Type == raftpb.MsgPreVote, m.Type == raftpb.MsgVote && !preVote:
+		return "election-timeout", true
+	}
+	return "", false
+}
+
 func (r *raftNode) apply() chan toApply {
 	return r.applyc
 }
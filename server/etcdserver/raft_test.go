@@ -319,6 +319,102 @@ func TestStopRaftNodeMoreThanOnce(t *testing.T) {
 	}
 }
 
+// TestWALGroupSync ensures the consecutive readies of a follower share a
+// single fsync of the WAL, their messages being held until then.
+func TestWALGroupSync(t *testing.T) {
+	n := newNopReadyNode()
+	st := mockstorage.NewStorageRecorder("")
+	tr, sendc := newSendMsgAppRespTransporter()
+	r := newRaftNode(raftNodeConfig{
+		lg:                   zaptest.NewLogger(t),
+		isIDRemoved:          func(id uint64) bool { return false },
+		Node:                 n,
+		storage:              st,
+		raftStorage:          raft.NewMemoryStorage(),
+		transport:            tr,
+		walGroupSyncMaxDelay: time.Minute,
+	})
+	r.start(&raftReadyHandler{updateCommittedIndex: func(uint64) {}})
+
+	readies := 10
+	for i := 1; i <= readies; i++ {
+		n.readyc <- raft.Ready{
+			HardState: raftpb.HardState{Term: 1, Commit: uint64(i - 1)},
+			Entries:   []raftpb.Entry{{Term: 1, Index: uint64(i)}},
+			Messages:  []raftpb.Message{{Type: raftpb.MsgAppResp, To: 1, Term: 1, Index: uint64(i)}},
+		}
+		<-r.applyc
+	}
+	select {
+	case <-sendc:
+		t.Fatal("unexpected messages sent before the readies are synced")
+	default:
+	}
+
+	// the saved entries are synced before being applied
+	n.readyc <- raft.Ready{CommittedEntries: []raftpb.Entry{{Term: 1, Index: uint64(readies)}}}
+	<-r.applyc
+	if got := <-sendc; got != readies {
+		t.Errorf("sent %d messages, want %d", got, readies)
+	}
+	r.stop()
+
+	actions := map[string]int{}
+	for _, a := range st.Action() {
+		actions[a.Name]++
+	}
+	want := map[string]int{"SaveNoSync": readies + 1, "Sync": 1}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("storage actions = %v, want %v", actions, want)
+	}
+}
+
+// TestWALGroupSyncMaxDelay ensures the readies sharing an fsync are synced
+// after the maximum delay.
+func TestWALGroupSyncMaxDelay(t *testing.T) {
+	n := newNopReadyNode()
+	st := mockstorage.NewStorageRecorder("")
+	tr, sendc := newSendMsgAppRespTransporter()
+	r := newRaftNode(raftNodeConfig{
+		lg:                   zaptest.NewLogger(t),
+		isIDRemoved:          func(id uint64) bool { return false },
+		Node:                 n,
+		storage:              st,
+		raftStorage:          raft.NewMemoryStorage(),
+		transport:            tr,
+		walGroupSyncMaxDelay: 10 * time.Millisecond,
+	})
+	r.start(&raftReadyHandler{})
+	defer r.stop()
+
+	readies := 3
+	for i := 1; i <= readies; i++ {
+		n.readyc <- raft.Ready{
+			Entries:  []raftpb.Entry{{Term: 1, Index: uint64(i)}},
+			Messages: []raftpb.Message{{Type: raftpb.MsgAppResp, To: 1, Term: 1, Index: uint64(i)}},
+		}
+		<-r.applyc
+	}
+	select {
+	case got := <-sendc:
+		if got != readies {
+			t.Errorf("sent %d messages, want %d", got, readies)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("readies not synced after the maximum delay")
+	}
+
+	syncs := 0
+	for _, a := range st.Action() {
+		if a.Name == "Sync" {
+			syncs++
+		}
+	}
+	if syncs != 1 {
+		t.Errorf("synced %d times, want 1", syncs)
+	}
+}
+
 func TestShouldWaitWALSync(t *testing.T) {
 	testcases := []struct {
 		name            string
@@ -394,3 +490,80 @@ func TestShouldWaitWALSync(t *testing.T) {
 		})
 	}
 }
+
+// TestRaftNodeSkipsStarvedTick ensures that a follower does not count a tick
+// delayed by more than the tick skew tolerance, but never two in a row.
+func TestRaftNodeSkipsStarvedTick(t *testing.T) {
+	n := newReadyNode()
+	r := newRaftNode(raftNodeConfig{
+		lg:                zaptest.NewLogger(t),
+		Node:              n,
+		heartbeat:         10 * time.Millisecond,
+		tickSkewTolerance: 200 * time.Millisecond,
+		storage:           mockstorage.NewStorageRecorder(""),
+		raftStorage:       raft.NewMemoryStorage(),
+		transport:         newNopTransporter(),
+	})
+	r.ticker.Stop()
+	tickc := make(chan time.Time)
+	r.ticker = &time.Ticker{C: tickc}
+	r.start(&raftReadyHandler{})
+
+	tests := []struct {
+		delay  time.Duration
+		wticks bool
+	}{
+		{0, true},
+		{300 * time.Millisecond, false},
+		{0, true},
+		{300 * time.Millisecond, false},
+		// not skipped twice in a row
+		{300 * time.Millisecond, true},
+	}
+	for i, tt := range tests {
+		time.Sleep(tt.delay)
+		tickc <- time.Now()
+		select {
+		case a := <-n.Chan():
+			if !tt.wticks || a.Name != "Tick" {
+				t.Fatalf("#%d: action = %s, want ticked %v", i, a.Name, tt.wticks)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if tt.wticks {
+				t.Fatalf("#%d: tick skipped, want ticked", i)
+			}
+		}
+	}
+
+	go r.stop()
+	for a := range n.Chan() {
+		if a.Name == "Stop" {
+			break
+		}
+	}
+	<-r.done
+}
+
+func TestCampaignCause(t *testing.T) {
+	tests := []struct {
+		m       raftpb.Message
+		preVote bool
+		wcause  string
+		wok     bool
+	}{
+		{raftpb.Message{Type: raftpb.MsgPreVote}, true, "election-timeout", true},
+		{raftpb.Message{Type: raftpb.MsgVote}, false, "election-timeout", true},
+		// following a won Pre-Vote
+		{raftpb.Message{Type: raftpb.MsgVote}, true, "", false},
+		{raftpb.Message{Type: raftpb.MsgVote, Context: []byte("CampaignTransfer")}, true, "leader-transfer", true},
+		{raftpb.Message{Type: raftpb.MsgVote, Context: []byte("CampaignTransfer")}, false, "leader-transfer", true},
+		{raftpb.Message{Type: raftpb.MsgVoteResp}, false, "", false},
+		{raftpb.Message{Type: raftpb.MsgHeartbeat}, false, "", false},
+	}
+	for i, tt := range tests {
+		cause, ok := campaignCause(tt.m, tt.preVote)
+		if cause != tt.wcause || ok != tt.wok {
+			t.Errorf("#%d: campaignCause() = %q, %v, want %q, %v", i, cause, ok, tt.wcause, tt.wok)
+		}
+	}
+}
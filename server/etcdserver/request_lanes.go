@@ -0,0 +1,175 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
+)
+
+// RequestPriority is the priority class of a request, the lane it waits in
+// for a slot of the member while its concurrent requests are limited.
+type RequestPriority int
+
+const (
+	// PrioritySystem is the priority of the health checks, which are never
+	// queued.
+	PrioritySystem RequestPriority = iota
+	// PriorityLease is the priority of the lease requests.
+	PriorityLease
+	// PriorityWrite is the priority of the write requests.
+	PriorityWrite
+	// PriorityRead is the priority of the range and read-only txn requests.
+	PriorityRead
+
+	numRequestPriorities = iota
+)
+
+func (p RequestPriority) String() string {
+	switch p {
+	case PrioritySystem:
+		return "system"
+	case PriorityLease:
+		return "lease"
+	case PriorityWrite:
+		return "write"
+	case PriorityRead:
+		return "read"
+	}
+	return "unknown"
+}
+
+type requestPriorityKey struct{}
+
+// WithRequestPriority returns a context making the requests served with it of
+// priority p, instead of the priority of their type.
+func WithRequestPriority(ctx context.Context, p RequestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, p)
+}
+
+// requestPriorityFromContext returns the priority set in ctx, or def.
+func requestPriorityFromContext(ctx context.Context, def RequestPriority) RequestPriority {
+	if p, ok := ctx.Value(requestPriorityKey{}).(RequestPriority); ok {
+		return p
+	}
+	return def
+}
+
+// requestLanes limits the concurrent requests of the member, queueing the
+// requests over the limit in a lane per priority. A freed slot goes to the
+// oldest request of the highest priority lane, so that a burst of expensive
+// range requests does not delay the writes, the leases and, never queued,
+// the health checks.
+type requestLanes struct {
+	mu       sync.Mutex
+	limit    int
+	inflight int
+	lanes    [numRequestPriorities][]chan struct{}
+}
+
+// newRequestLanes returns the request lanes of limit concurrent requests, or
+// nil if limit is 0.
+func newRequestLanes(limit int) *requestLanes {
+	if limit <= 0 {
+		return nil
+	}
+	return &requestLanes{limit: limit}
+}
+
+// acquire waits for a slot for a request of priority p until ctx is done or
+// stopc is closed. The slot is freed by release.
+func (l *requestLanes) acquire(ctx context.Context, stopc <-chan struct{}, p RequestPriority) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if p == PrioritySystem || (l.inflight < l.limit && l.queued() == 0) {
+		l.inflight++
+		l.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	l.lanes[p] = append(l.lanes[p], ch)
+	requestLaneQueued.WithLabelValues(p.String()).Inc()
+	l.mu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		requestLaneWaitSec.WithLabelValues(p.String()).Observe(time.Since(start).Seconds())
+	}()
+	var err error
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-stopc:
+		err = errors.ErrStopped
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, c := range l.lanes[p] {
+		if c == ch {
+			l.lanes[p] = append(l.lanes[p][:i], l.lanes[p][i+1:]...)
+			requestLaneQueued.WithLabelValues(p.String()).Dec()
+			return err
+		}
+	}
+	// the slot was given as the wait ended
+	l.releaseLocked()
+	return err
+}
+
+// release frees the slot of a request.
+func (l *requestLanes) release() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked()
+}
+
+func (l *requestLanes) releaseLocked() {
+	l.inflight--
+	for p := range l.lanes {
+		for l.inflight < l.limit && len(l.lanes[p]) > 0 {
+			close(l.lanes[p][0])
+			l.lanes[p] = l.lanes[p][1:]
+			requestLaneQueued.WithLabelValues(RequestPriority(p).String()).Dec()
+			l.inflight++
+		}
+	}
+}
+
+func (l *requestLanes) queued() (n int) {
+	for _, lane := range l.lanes {
+		n += len(lane)
+	}
+	return n
+}
+
+// acquireRequestLane waits for a slot for a request of priority def, unless
+// set otherwise by ctx, returning the function freeing it.
+func (s *EtcdServer) acquireRequestLane(ctx context.Context, def RequestPriority) (func(), error) {
+	if err := s.requestLanes.acquire(ctx, s.stopping, requestPriorityFromContext(ctx, def)); err != nil {
+		return nil, err
+	}
+	return s.requestLanes.release, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitQueued waits for n requests to be queued in l.
+func waitQueued(t *testing.T, l *requestLanes, n int) {
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		l.mu.Lock()
+		queued := l.queued()
+		l.mu.Unlock()
+		if queued == n {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %d queued requests", n)
+}
+
+func TestRequestLanesPriority(t *testing.T) {
+	l := newRequestLanes(1)
+	stopc := make(chan struct{})
+	if err := l.acquire(context.TODO(), stopc, PriorityRead); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan RequestPriority, 3)
+	for i, p := range []RequestPriority{PriorityRead, PriorityWrite, PriorityLease} {
+		go func(p RequestPriority) {
+			if err := l.acquire(context.TODO(), stopc, p); err != nil {
+				t.Error(err)
+			}
+			acquired <- p
+		}(p)
+		waitQueued(t, l, i+1)
+	}
+
+	// the health checks are never queued
+	if err := l.acquire(context.TODO(), stopc, PrioritySystem); err != nil {
+		t.Fatal(err)
+	}
+	l.release()
+
+	var order []RequestPriority
+	for i := 0; i < 3; i++ {
+		l.release()
+		order = append(order, <-acquired)
+	}
+	if w := []RequestPriority{PriorityLease, PriorityWrite, PriorityRead}; !reflect.DeepEqual(order, w) {
+		t.Errorf("acquired = %v, want %v", order, w)
+	}
+}
+
+func TestRequestLanesCanceled(t *testing.T) {
+	l := newRequestLanes(1)
+	stopc := make(chan struct{})
+	if err := l.acquire(context.TODO(), stopc, PriorityWrite); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, stopc, PriorityRead); err != context.DeadlineExceeded {
+		t.Fatalf("acquire error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := l.queued(); n != 0 {
+		t.Fatalf("queued = %d, want the canceled request dequeued", n)
+	}
+
+	l.release()
+	if err := l.acquire(context.TODO(), stopc, PriorityRead); err != nil {
+		t.Fatalf("acquire error = %v, want the slot freed", err)
+	}
+	if l.inflight != 1 {
+		t.Errorf("inflight = %d, want 1", l.inflight)
+	}
+}
+
+func TestRequestPriorityFromContext(t *testing.T) {
+	if p := requestPriorityFromContext(context.TODO(), PriorityRead); p != PriorityRead {
+		t.Errorf("priority = %v, want %v", p, PriorityRead)
+	}
+	ctx := WithRequestPriority(context.TODO(), PrioritySystem)
+	if p := requestPriorityFromContext(ctx, PriorityRead); p != PrioritySystem {
+		t.Errorf("priority = %v, want %v", p, PrioritySystem)
+	}
+}
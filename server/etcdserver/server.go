@@ -45,6 +45,7 @@ import (
 	"go.etcd.io/etcd/client/pkg/v3/fileutil"
 	"go.etcd.io/etcd/client/pkg/v3/types"
 	"go.etcd.io/etcd/client/pkg/v3/verify"
+	"go.etcd.io/etcd/pkg/v3/debugutil"
 	"go.etcd.io/etcd/pkg/v3/idutil"
 	"go.etcd.io/etcd/pkg/v3/pbutil"
 	"go.etcd.io/etcd/pkg/v3/schedule"
@@ -52,6 +53,7 @@ import (
 	"go.etcd.io/etcd/pkg/v3/wait"
 	"go.etcd.io/etcd/raft/v3"
 	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/raft/v3/tracker"
 	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/etcdserver/api"
 	httptypes "go.etcd.io/etcd/server/v3/etcdserver/api/etcdhttp/types"
@@ -62,6 +64,7 @@ import (
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v2store"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3alarm"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3compactor"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/webhook"
 	"go.etcd.io/etcd/server/v3/etcdserver/cindex"
 	serverversion "go.etcd.io/etcd/server/v3/etcdserver/version"
 	"go.etcd.io/etcd/server/v3/lease"
@@ -82,6 +85,11 @@ const (
 	// follower to catch up.
 	DefaultSnapshotCatchUpEntries uint64 = 5000
 
+	// DefaultMaxSnapshotCatchUpEntries is the maximum number of entries kept
+	// for the active followers lagging behind to catch up from, instead of
+	// being sent a snapshot.
+	DefaultMaxSnapshotCatchUpEntries uint64 = 10 * DefaultSnapshotCatchUpEntries
+
 	StoreClusterPrefix = "/0"
 	StoreKeysPrefix    = "/1"
 
@@ -172,6 +180,12 @@ type Server interface {
 	// return ErrLearnerNotReady if the member are not ready.
 	// return ErrMemberNotLearner if the member is not a learner.
 	PromoteMember(ctx context.Context, id uint64) ([]*membership.Member, error)
+	// DemoteMember attempts to demote a voting node to a non-voting node. It will
+	// return ErrIDNotFound if the member ID does not exist.
+	// return ErrMemberNotVoter if the member is already a learner.
+	// return ErrDemoteNotAcknowledged if demoting the member reduces the fault
+	// tolerance of the cluster and acknowledgeReducedFaultTolerance is false.
+	DemoteMember(ctx context.Context, id uint64, acknowledgeReducedFaultTolerance bool) ([]*membership.Member, error)
 
 	// ClusterVersion is the cluster-wide minimum major.minor version.
 	// Cluster version is set to the min version that an etcd member is
@@ -212,6 +226,12 @@ type EtcdServer struct {
 	committedIndex    uint64 // must use atomic operations to access; keep 64-bit aligned.
 	term              uint64 // must use atomic operations to access; keep 64-bit aligned.
 	lead              uint64 // must use atomic operations to access; keep 64-bit aligned.
+	// leaderContact is the time in unix nanoseconds of the last message
+	// received from the leader.
+	leaderContact int64 // must use atomic operations to access; keep 64-bit aligned.
+	// lastBackendCommit is the latency of the last backend commit.
+	lastBackendCommit int64 // must use atomic operations to access; keep 64-bit aligned.
+	pendingProposals  int64 // must use atomic operations to access; keep 64-bit aligned.
 
 	consistIndex cindex.ConsistentIndexer // consistIndex is used to get/set/save consistentIndex
 	r            raftNode                 // uses 64-bit atomics; keep 64-bit aligned.
@@ -268,8 +288,38 @@ type EtcdServer struct {
 	lstats *stats.LeaderStats
 
 	SyncTicker *time.Ticker
+	// compactorMu protects compactor, replaced when the retention changes.
+	compactorMu sync.Mutex
 	// compactor is used to auto-compact the KV.
 	compactor v3compactor.Compactor
+	// webhooks posts alarm and leadership changes, nil without webhooks.
+	webhooks *webhook.Notifier
+	// events keeps the latest significant events of the server.
+	events *eventLog
+	// loggedRanges are the key ranges whose requests are logged.
+	loggedRanges loggedRanges
+	// proposalTimes are the times of the pending local proposals, to
+	// observe the latency of their stages.
+	proposalTimes proposalTimes
+	// historyRetention gives the history retained by the compactions, nil
+	// without retention rules.
+	historyRetention *v3compactor.HistoryRetention
+	// prefixStats counts the requests per prefix, nil without prefixes.
+	prefixStats *PrefixStats
+	// profilePusher pushes profiles of the server, nil when disabled.
+	profilePusher *debugutil.ProfilePusher
+	// diskMonitor tracks the latency of the WAL fsyncs and of the backend commits.
+	diskMonitor *diskMonitor
+	// peerMonitor tracks the misbehaviors of the peers.
+	peerMonitor *peerMonitor
+	// requestLanes limits the concurrent requests by priority, nil if they
+	// are not limited.
+	requestLanes *requestLanes
+	// memoryBudget sheds load while the heap exceeds it, nil when disabled.
+	memoryBudget *memoryBudget
+	// starvationMonitor tracks the scheduling delays of the raft ticks and of
+	// the applies.
+	starvationMonitor *starvationMonitor
 
 	// peerRt used to send requests (version, lease) to peers.
 	peerRt   http.RoundTripper
@@ -327,7 +377,13 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 		snapshotter:           b.ss,
 		r:                     *b.raft.newRaftNode(b.ss, b.storage.wal.w, b.cluster.cl),
 		memberId:              b.cluster.nodeID,
-		attributes:            membership.Attributes{Name: cfg.Name, ClientURLs: cfg.ClientURLs.StringSlice()},
+		events:                newEventLog(),
+		diskMonitor:           newDiskMonitor(cfg.ExperimentalDiskDegradedWALFsyncThreshold, cfg.ExperimentalDiskDegradedBackendCommitThreshold),
+		peerMonitor:           newPeerMonitor(),
+		requestLanes:          newRequestLanes(cfg.ExperimentalMaxConcurrentRequests),
+		memoryBudget:          newMemoryBudget(cfg.Logger, cfg.ExperimentalMemoryBudgetRatio),
+		starvationMonitor:     newStarvationMonitor(cfg.ExperimentalStarvationThreshold),
+		attributes:            membership.Attributes{Name: cfg.Name, ClientURLs: cfg.ClientURLs.StringSlice(), ElectionPriority: uint32(cfg.ExperimentalElectionPriority)},
 		cluster:               b.cluster.cl,
 		stats:                 sstats,
 		lstats:                lstats,
@@ -370,6 +426,13 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 	mvccStoreConfig := mvcc.StoreConfig{
 		CompactionBatchLimit:    cfg.CompactionBatchLimit,
 		CompactionSleepInterval: cfg.CompactionSleepInterval,
+		CompactionMaxPause:      cfg.CompactionMaxPause,
+
+		CompactionBarrierMaxDuration:  cfg.CompactionBarrierMaxDuration,
+		CompactionBarrierMaxRevisions: cfg.CompactionBarrierMaxRevisions,
+	}
+	if cfg.CompactionPauseBackendCommitThreshold > 0 || cfg.CompactionPausePendingProposals > 0 {
+		mvccStoreConfig.CompactionPreempted = srv.compactionPreempted
 	}
 	srv.kv = mvcc.New(srv.Logger(), srv.be, srv.lessor, mvccStoreConfig)
 	srv.corruptionChecker = newCorruptionChecker(cfg.Logger, srv, srv.kv.HashStorage())
@@ -407,20 +470,22 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 	// Set the hook after EtcdServer finishes the initialization to avoid
 	// the hook being called during the initialization process.
 	srv.be.SetTxPostLockInsideApplyHook(srv.getTxPostLockInsideApplyHook())
+	srv.be.SetCommitObserver(srv.observeBackendCommit)
 
 	// TODO: move transport initialization near the definition of remote
 	tr := &rafthttp.Transport{
-		Logger:      cfg.Logger,
-		TLSInfo:     cfg.PeerTLSInfo,
-		DialTimeout: cfg.PeerDialTimeout(),
-		ID:          b.cluster.nodeID,
-		URLs:        cfg.PeerURLs,
-		ClusterID:   b.cluster.cl.ID(),
-		Raft:        srv,
-		Snapshotter: b.ss,
-		ServerStats: sstats,
-		LeaderStats: lstats,
-		ErrorC:      srv.errorc,
+		Logger:       cfg.Logger,
+		TLSInfo:      cfg.PeerTLSInfo,
+		DialTimeout:  cfg.PeerDialTimeout(),
+		ID:           b.cluster.nodeID,
+		URLs:         cfg.PeerURLs,
+		ClusterID:    b.cluster.cl.ID(),
+		Raft:         srv,
+		Snapshotter:  b.ss,
+		ServerStats:  sstats,
+		LeaderStats:  lstats,
+		ErrorC:       srv.errorc,
+		MaxClockSkew: cfg.ExperimentalMaxClockSkew,
 	}
 	if err = tr.Start(); err != nil {
 		return nil, err
@@ -438,6 +503,43 @@ func NewServer(cfg config.ServerConfig) (srv *EtcdServer, err error) {
 	}
 	srv.r.transport = tr
 
+	if len(cfg.ExperimentalWebhookURLs) > 0 {
+		srv.webhooks, err = webhook.NewNotifier(cfg.Logger, webhook.Config{
+			URLs:     cfg.ExperimentalWebhookURLs,
+			Template: cfg.ExperimentalWebhookTemplate,
+			Retries:  cfg.ExperimentalWebhookRetries,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.HistoryRetention) > 0 {
+		rules, err := v3compactor.ParseRetentionRules(cfg.HistoryRetention)
+		if err != nil {
+			return nil, err
+		}
+		srv.historyRetention = v3compactor.NewHistoryRetention(rules)
+	}
+
+	if len(cfg.PrefixStats) > 0 {
+		if err = ValidateStatsPrefixes(cfg.PrefixStats); err != nil {
+			return nil, err
+		}
+		srv.prefixStats = NewPrefixStats(cfg.PrefixStats)
+	}
+
+	if cfg.ExperimentalProfilingPushURL != "" {
+		srv.profilePusher = debugutil.NewProfilePusher(cfg.Logger, debugutil.ProfilePushConfig{
+			URL:         cfg.ExperimentalProfilingPushURL,
+			Name:        "etcd",
+			Instance:    cfg.Name,
+			Profiles:    cfg.ExperimentalProfilingPushProfiles,
+			Interval:    cfg.ExperimentalProfilingPushInterval,
+			CPUDuration: cfg.ExperimentalProfilingPushCPUDuration,
+		})
+	}
+
 	return srv, nil
 }
 
@@ -534,6 +636,24 @@ func (s *EtcdServer) Start() {
 	s.GoAttach(s.monitorKVHash)
 	s.GoAttach(s.monitorCompactHash)
 	s.GoAttach(s.monitorDowngrade)
+	s.GoAttach(s.monitorDiskHealth)
+	s.GoAttach(s.monitorMemoryBudget)
+	s.GoAttach(s.monitorStarvation)
+	s.GoAttach(s.monitorLeaderLease)
+	s.GoAttach(s.monitorElectionPriority)
+	s.GoAttach(s.monitorPeerMisbehaviors)
+	if s.Cfg.AutoPromoteLearners {
+		s.GoAttach(s.monitorLearnerPromotion)
+	}
+	if s.webhooks != nil {
+		s.GoAttach(func() { s.webhooks.Run(s.stopping) })
+	}
+	if s.profilePusher != nil {
+		s.GoAttach(func() { s.profilePusher.Run(s.stopping) })
+	}
+	if s.historyRetention != nil {
+		s.GoAttach(func() { s.historyRetention.Run(s.stopping, s.kv) })
+	}
 }
 
 // start prepares and starts server in a new goroutine. It is no longer safe to
@@ -708,6 +828,10 @@ func (s *EtcdServer) Process(ctx context.Context, m raftpb.Message) error {
 	if m.Type == raftpb.MsgApp {
 		s.stats.RecvAppendReq(types.ID(m.From).String(), m.Size())
 	}
+	if m.From == s.getLead() {
+		atomic.StoreInt64(&s.leaderContact, time.Now().UnixNano())
+	}
+	s.observePeerMessage(m)
 	return s.r.Step(ctx, m)
 }
 
@@ -736,6 +860,8 @@ type raftReadyHandler struct {
 	updateLead           func(lead uint64)
 	updateLeadership     func(newLeader bool)
 	updateCommittedIndex func(uint64)
+	observeWALFsync      func(time.Duration)
+	observeTickDelay     func(time.Duration)
 }
 
 func (s *EtcdServer) run() {
@@ -772,9 +898,11 @@ func (s *EtcdServer) run() {
 				if s.lessor != nil {
 					s.lessor.Demote()
 				}
+				s.compactorMu.Lock()
 				if s.compactor != nil {
 					s.compactor.Pause()
 				}
+				s.compactorMu.Unlock()
 				setSyncC(nil)
 			} else {
 				if newLeader {
@@ -784,12 +912,16 @@ func (s *EtcdServer) run() {
 					s.leadTimeMu.Unlock()
 				}
 				setSyncC(s.SyncTicker.C)
+				s.compactorMu.Lock()
 				if s.compactor != nil {
 					s.compactor.Resume()
 				}
+				s.compactorMu.Unlock()
 			}
 			if newLeader {
 				s.leaderChanged.Notify()
+				s.RecordEvent(EventLeaderChanged, fmt.Sprintf("member %s elected leader", types.ID(s.Lead())))
+				s.notifyWebhooks(webhook.Event{Type: webhook.EventLeaderChanged, Leader: types.ID(s.Lead()).String()})
 			}
 			// TODO: remove the nil checking
 			// current test utility does not provide the stats
@@ -803,6 +935,8 @@ func (s *EtcdServer) run() {
 				s.setCommittedIndex(ci)
 			}
 		},
+		observeWALFsync:  s.diskMonitor.observeWALFsync,
+		observeTickDelay: s.starvationMonitor.observeTickDelay,
 	}
 	s.r.start(rh)
 
@@ -839,10 +973,24 @@ func (s *EtcdServer) run() {
 		expiredLeaseC = s.lessor.ExpiredLeasesC()
 	}
 
+	// applyDone is the end of the last apply, only accessed by the applies,
+	// run one at a time.
+	var applyDone time.Time
 	for {
 		select {
 		case ap := <-s.r.apply():
-			f := schedule.NewJob("server_applyAll", func(context.Context) { s.applyAll(&ep, &ap) })
+			scheduled := time.Now()
+			f := schedule.NewJob("server_applyAll", func(context.Context) {
+				// the delay past the end of the previous apply only, not to
+				// count the time spent applying the backlog
+				start := time.Now()
+				if applyDone.After(scheduled) {
+					scheduled = applyDone
+				}
+				s.starvationMonitor.observeApplyDelay(start.Sub(scheduled))
+				s.applyAll(&ep, &ap)
+				applyDone = time.Now()
+			})
 			sched.Schedule(f)
 		case leases := <-expiredLeaseC:
 			s.revokeExpiredLeases(leases)
@@ -912,9 +1060,47 @@ func (s *EtcdServer) Cleanup() {
 	if s.be != nil {
 		s.be.Close()
 	}
+	s.compactorMu.Lock()
 	if s.compactor != nil {
 		s.compactor.Stop()
 	}
+	s.compactorMu.Unlock()
+}
+
+// SetAutoCompactionRetention replaces the auto compactor by one of the
+// configured mode with the given retention, or stops the auto compaction if
+// the retention is zero.
+func (s *EtcdServer) SetAutoCompactionRetention(retention time.Duration) error {
+	s.compactorMu.Lock()
+	defer s.compactorMu.Unlock()
+	select {
+	case <-s.stopping:
+		return errors.ErrStopped
+	default:
+	}
+	var c v3compactor.Compactor
+	if retention != 0 {
+		var err error
+		if c, err = v3compactor.New(s.Logger(), s.Cfg.AutoCompactionMode, retention, s.kv, s); err != nil {
+			return err
+		}
+	}
+	if s.compactor != nil {
+		s.compactor.Stop()
+	}
+	s.compactor = c
+	if c != nil {
+		if !s.isLeader() {
+			c.Pause()
+		}
+		c.Run()
+	}
+	s.Logger().Info(
+		"changed auto compaction retention",
+		zap.String("auto-compaction-mode", s.Cfg.AutoCompactionMode),
+		zap.Duration("auto-compaction-retention", retention),
+	)
+	return nil
 }
 
 func (s *EtcdServer) applyAll(ep *etcdProgress, apply *toApply) {
@@ -961,6 +1147,7 @@ func (s *EtcdServer) applySnapshot(ep *etcdProgress, toApply *toApply) {
 			zap.Uint64("incoming-leader-snapshot-index", toApply.snapshot.Metadata.Index),
 			zap.Uint64("incoming-leader-snapshot-term", toApply.snapshot.Metadata.Term),
 		)
+		s.RecordEvent(EventSnapshotApplied, fmt.Sprintf("applied snapshot from leader at index %d", toApply.snapshot.Metadata.Index))
 		applySnapshotInProgress.Dec()
 	}()
 
@@ -1007,6 +1194,7 @@ func (s *EtcdServer) applySnapshot(ep *etcdProgress, toApply *toApply) {
 	}
 
 	newbe.SetTxPostLockInsideApplyHook(s.getTxPostLockInsideApplyHook())
+	newbe.SetCommitObserver(s.observeBackendCommit)
 
 	lg.Info("restored mvcc store", zap.Uint64("consistent-index", s.consistIndex.ConsistentIndex()))
 
@@ -1123,7 +1311,7 @@ func (s *EtcdServer) applyEntries(ep *etcdProgress, apply *toApply) {
 		return
 	}
 	var shouldstop bool
-	if ep.appliedt, ep.appliedi, shouldstop = s.apply(ents, &ep.confState); shouldstop {
+	if ep.appliedt, ep.appliedi, shouldstop = s.apply(ents, apply.committed, &ep.confState); shouldstop {
 		go s.stopWithDelay(10*100*time.Millisecond, fmt.Errorf("the member has been permanently removed from the cluster"))
 	}
 }
@@ -1428,7 +1616,12 @@ func (s *EtcdServer) promoteMember(ctx context.Context, id uint64) ([]*membershi
 	if err := s.checkMembershipOperationPermission(ctx); err != nil {
 		return nil, err
 	}
+	return s.promoteLearner(ctx, id)
+}
 
+// promoteLearner proposes the promotion of the learner if it is ready, as
+// promoteMember without checking the permission of the request.
+func (s *EtcdServer) promoteLearner(ctx context.Context, id uint64) ([]*membership.Member, error) {
 	// check if we can promote this learner.
 	if err := s.mayPromoteMember(types.ID(id)); err != nil {
 		return nil, err
@@ -1513,6 +1706,73 @@ func (s *EtcdServer) isLearnerReady(id uint64) error {
 	return nil
 }
 
+// DemoteMember demotes a voting node to a learner node, for it to leave the
+// quorum during a planned maintenance. It rejoins the quorum when promoted.
+func (s *EtcdServer) DemoteMember(ctx context.Context, id uint64, acknowledgeReducedFaultTolerance bool) ([]*membership.Member, error) {
+	if err := s.checkMembershipOperationPermission(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := s.mayDemoteMember(types.ID(id), acknowledgeReducedFaultTolerance); err != nil {
+		return nil, err
+	}
+
+	// build the context for the demote confChange. mark IsLearner to true and IsDemote to true.
+	demoteChangeContext := membership.ConfigChangeContext{
+		Member: membership.Member{
+			ID:             types.ID(id),
+			RaftAttributes: membership.RaftAttributes{IsLearner: true},
+		},
+		IsDemote: true,
+	}
+
+	b, err := json.Marshal(demoteChangeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	cc := raftpb.ConfChange{
+		Type:    raftpb.ConfChangeAddLearnerNode,
+		NodeID:  id,
+		Context: b,
+	}
+
+	return s.configure(ctx, cc)
+}
+
+func (s *EtcdServer) mayDemoteMember(id types.ID, acknowledgeReducedFaultTolerance bool) error {
+	lg := s.Logger()
+	// the members of older versions would not apply the demotion
+	if cv := s.ClusterVersion(); cv == nil || cv.LessThan(version.V3_6) {
+		return errors.ErrDemoteNotSupported
+	}
+	if !s.cluster.IsMemberExist(id) {
+		return membership.ErrIDNotFound
+	}
+	if s.cluster.Member(id).IsLearner {
+		return membership.ErrMemberNotVoter
+	}
+	if id == s.Leader() {
+		return errors.ErrDemoteLeader
+	}
+
+	// the cluster tolerates the failure of (n-1)/2 of its n voting members
+	n := len(s.cluster.VotingMembers())
+	if (n-2)/2 < (n-1)/2 && !acknowledgeReducedFaultTolerance {
+		lg.Warn(
+			"rejecting member demote request; fault tolerance reduction is not acknowledged",
+			zap.String("local-member-id", s.MemberId().String()),
+			zap.String("requested-member-demote-id", id.String()),
+			zap.Int("voting-members", n),
+			zap.Error(errors.ErrDemoteNotAcknowledged),
+		)
+		return errors.ErrDemoteNotAcknowledged
+	}
+
+	// the member leaves the quorum as if removed
+	return s.mayRemoveMember(id)
+}
+
 func (s *EtcdServer) mayRemoveMember(id types.ID) error {
 	if !s.Cfg.StrictReconfigCheck {
 		return nil
@@ -1630,6 +1890,12 @@ func (s *EtcdServer) AppliedIndex() uint64 { return s.getAppliedIndex() }
 
 func (s *EtcdServer) Term() uint64 { return s.getTerm() }
 
+// LastTick returns the time the raft loop last received a heartbeat tick, for
+// its liveness to be checked.
+func (s *EtcdServer) LastTick() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&s.r.lastTick))
+}
+
 type confChangeResponse struct {
 	membs []*membership.Member
 	err   error
@@ -1700,8 +1966,9 @@ func (s *EtcdServer) publishV3(timeout time.Duration) {
 	req := &membershippb.ClusterMemberAttrSetRequest{
 		Member_ID: uint64(s.MemberId()),
 		MemberAttributes: &membershippb.Attributes{
-			Name:       s.attributes.Name,
-			ClientUrls: s.attributes.ClientURLs,
+			Name:             s.attributes.Name,
+			ClientUrls:       s.attributes.ClientURLs,
+			ElectionPriority: s.attributes.ElectionPriority,
 		},
 	}
 	lg := s.Logger()
@@ -1791,10 +2058,11 @@ func (s *EtcdServer) sendMergedSnap(merged snap.Message) {
 // The given entries should not be empty.
 func (s *EtcdServer) apply(
 	es []raftpb.Entry,
+	committed time.Time,
 	confState *raftpb.ConfState,
 ) (appliedt uint64, appliedi uint64, shouldStop bool) {
 	s.lg.Debug("Applying entries", zap.Int("num-entries", len(es)))
-	for i := range es {
+	for i := 0; i < len(es); i++ {
 		e := es[i]
 		s.lg.Debug("Applying entry",
 			zap.Uint64("index", e.Index),
@@ -1802,9 +2070,14 @@ func (s *EtcdServer) apply(
 			zap.Stringer("type", e.Type))
 		switch e.Type {
 		case raftpb.EntryNormal:
-			s.applyEntryNormal(&e)
-			s.setAppliedIndex(e.Index)
-			s.setTerm(e.Term)
+			if n := s.applyEntriesNormalBatch(es[i:], committed); n > 0 {
+				i += n - 1
+				e = es[i]
+			} else {
+				s.applyEntryNormal(&e, committed)
+				s.setAppliedIndex(e.Index)
+				s.setTerm(e.Term)
+			}
 
 		case raftpb.EntryConfChange:
 			// We need to toApply all WAL entries on top of v2store
@@ -1838,7 +2111,8 @@ func (s *EtcdServer) apply(
 }
 
 // applyEntryNormal applies an EntryNormal type raftpb request to the EtcdServer
-func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
+func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry, committed time.Time) {
+	applyStart := time.Now()
 	shouldApplyV3 := membership.ApplyV2storeOnly
 	var ar *apply.Result
 	index := s.consistIndex.ConsistentIndex()
@@ -1898,6 +2172,11 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 		id = raftReq.Header.ID
 	}
 
+	var alarmEvent *webhook.Event
+	if raftReq.Alarm != nil && shouldApplyV3 {
+		alarmEvent = s.alarmChangeEvent(raftReq.Alarm)
+	}
+
 	needResult := s.w.IsRegistered(id)
 	if needResult || !noSideEffect(&raftReq) {
 		if !needResult && raftReq.Txn != nil {
@@ -1905,6 +2184,9 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 		}
 		ar = s.uberApply.Apply(&raftReq, shouldApplyV3)
 	}
+	if needResult {
+		s.proposalTimes.observeApplied(id, committed, applyStart, time.Now())
+	}
 
 	// do not re-toApply applied entries.
 	if !shouldApplyV3 {
@@ -1915,6 +2197,23 @@ func (s *EtcdServer) applyEntryNormal(e *raftpb.Entry) {
 		return
 	}
 
+	if alarmEvent != nil && ar.Err == nil {
+		typ, verb := EventAlarmRaised, "raised"
+		if alarmEvent.Type == webhook.EventAlarmCleared {
+			typ, verb = EventAlarmCleared, "cleared"
+		}
+		s.RecordEvent(typ, fmt.Sprintf("alarm %s %s on member %s", alarmEvent.Alarm, verb, alarmEvent.AlarmMemberID))
+		s.notifyWebhooks(*alarmEvent)
+	}
+	if raftReq.Compaction != nil && ar.Err == nil {
+		s.RecordEvent(EventCompaction, fmt.Sprintf("compacted at revision %d", raftReq.Compaction.Revision))
+	}
+	s.triggerApplied(id, ar)
+}
+
+// triggerApplied returns the result of the applied request to its proposer,
+// raising the NOSPACE alarm first if the request exceeded the backend quota.
+func (s *EtcdServer) triggerApplied(id uint64, ar *apply.Result) {
 	if ar.Err != errors.ErrNoSpace || len(s.alarmStore.Get(pb.AlarmType_NOSPACE)) > 0 {
 		s.w.Trigger(id, ar)
 		return
@@ -1995,8 +2294,13 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 		}
 		if confChangeContext.IsPromote {
 			s.cluster.PromoteMember(confChangeContext.Member.ID, shouldApplyV3)
+			s.recordMemberEvent(shouldApplyV3, EventMemberPromoted, fmt.Sprintf("member %s promoted", confChangeContext.Member.ID))
+		} else if confChangeContext.IsDemote {
+			s.cluster.DemoteMember(confChangeContext.Member.ID, shouldApplyV3)
+			s.recordMemberEvent(shouldApplyV3, EventMemberDemoted, fmt.Sprintf("member %s demoted", confChangeContext.Member.ID))
 		} else {
 			s.cluster.AddMember(&confChangeContext.Member, shouldApplyV3)
+			s.recordMemberEvent(shouldApplyV3, EventMemberAdded, fmt.Sprintf("member %s added with peer URLs %v", confChangeContext.Member.ID, confChangeContext.PeerURLs))
 
 			if confChangeContext.Member.ID != s.MemberId() {
 				s.r.transport.AddPeer(confChangeContext.Member.ID, confChangeContext.PeerURLs)
@@ -2015,6 +2319,7 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 	case raftpb.ConfChangeRemoveNode:
 		id := types.ID(cc.NodeID)
 		s.cluster.RemoveMember(id, shouldApplyV3)
+		s.recordMemberEvent(shouldApplyV3, EventMemberRemoved, fmt.Sprintf("member %s removed", id))
 		if id == s.MemberId() {
 			return true, nil
 		}
@@ -2033,6 +2338,7 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 			)
 		}
 		s.cluster.UpdateRaftAttributes(m.ID, m.RaftAttributes, shouldApplyV3)
+		s.recordMemberEvent(shouldApplyV3, EventMemberUpdated, fmt.Sprintf("member %s updated with peer URLs %v", m.ID, m.PeerURLs))
 		if m.ID != s.MemberId() {
 			s.r.transport.UpdatePeer(m.ID, m.PeerURLs)
 		}
@@ -2040,6 +2346,14 @@ func (s *EtcdServer) applyConfChange(cc raftpb.ConfChange, confState *raftpb.Con
 	return false, nil
 }
 
+// recordMemberEvent records a membership change, unless its entry is replayed
+// on restart.
+func (s *EtcdServer) recordMemberEvent(shouldApplyV3 membership.ShouldApplyV3, typ, message string) {
+	if shouldApplyV3 {
+		s.RecordEvent(typ, message)
+	}
+}
+
 // TODO: non-blocking snapshot
 func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
 	clone := s.v2store.Clone()
@@ -2084,6 +2398,7 @@ func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
 			"saved snapshot",
 			zap.Uint64("snapshot-index", snap.Metadata.Index),
 		)
+		s.RecordEvent(EventSnapshotSaved, fmt.Sprintf("saved snapshot at index %d", snap.Metadata.Index))
 
 		// When sending a snapshot, etcd will pause compaction.
 		// After receives a snapshot, the slow follower needs to get all the entries right after
@@ -2096,9 +2411,10 @@ func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
 		}
 
 		// keep some in memory log entries for slow followers.
+		catchUpEntries := snapshotCatchUpEntries(s.raftStatus(), snapi, s.Cfg.SnapshotCatchUpEntries, s.Cfg.ExperimentalMaxSnapshotCatchUpEntries)
 		compacti := uint64(1)
-		if snapi > s.Cfg.SnapshotCatchUpEntries {
-			compacti = snapi - s.Cfg.SnapshotCatchUpEntries
+		if snapi > catchUpEntries {
+			compacti = snapi - catchUpEntries
 		}
 
 		err = s.r.raftStorage.Compact(compacti)
@@ -2113,10 +2429,28 @@ func (s *EtcdServer) snapshot(snapi uint64, confState raftpb.ConfState) {
 		lg.Info(
 			"compacted Raft logs",
 			zap.Uint64("compact-index", compacti),
+			zap.Uint64("catchup-entries", catchUpEntries),
 		)
 	})
 }
 
+// snapshotCatchUpEntries returns the number of entries to keep in memory
+// before the snapshot index for the slow followers to catch up from: the lag
+// of the slowest active follower of the leader, between min and max. The
+// followers lagging by more than max are sent a snapshot anyway.
+func snapshotCatchUpEntries(st raft.Status, snapi, min, max uint64) uint64 {
+	n := min
+	for id, pr := range st.Progress {
+		if id == st.ID || !pr.RecentActive || pr.State == tracker.StateSnapshot || pr.Match >= snapi {
+			continue
+		}
+		if lag := snapi - pr.Match; lag > n && lag <= max {
+			n = lag
+		}
+	}
+	return n
+}
+
 // CutPeer drops messages to the specified peer.
 func (s *EtcdServer) CutPeer(id types.ID) {
 	tr, ok := s.r.transport.(*rafthttp.Transport)
@@ -2331,6 +2665,52 @@ func (s *EtcdServer) monitorDowngrade() {
 	}
 }
 
+func (s *EtcdServer) observeBackendCommit(d time.Duration) {
+	atomic.StoreInt64(&s.lastBackendCommit, int64(d))
+	s.diskMonitor.observeBackendCommit(d)
+}
+
+// compactionPreempted returns whether the last backend commit or the number
+// of pending proposals exceed their thresholds, the compaction pausing between
+// its batches to yield to the foreground traffic while they do.
+func (s *EtcdServer) compactionPreempted() bool {
+	if t := s.Cfg.CompactionPauseBackendCommitThreshold; t > 0 && time.Duration(atomic.LoadInt64(&s.lastBackendCommit)) > t {
+		return true
+	}
+	n := s.Cfg.CompactionPausePendingProposals
+	return n > 0 && atomic.LoadInt64(&s.pendingProposals) > int64(n)
+}
+
+// monitorLeaderLease updates the leader lease metric every heartbeat while
+// CheckQuorum is enabled.
+func (s *EtcdServer) monitorLeaderLease() {
+	if !s.Cfg.CheckQuorum {
+		return
+	}
+	heartbeat := time.Duration(s.Cfg.TickMs) * time.Millisecond
+	for {
+		select {
+		case <-time.After(heartbeat):
+		case <-s.stopping:
+			return
+		}
+
+		if s.inLeaderLease() {
+			leaderLease.Set(1)
+		} else {
+			leaderLease.Set(0)
+		}
+	}
+}
+
+// inLeaderLease returns whether the member is the leader, or has heard from
+// the leader within the election timeout.
+func (s *EtcdServer) inLeaderLease() bool {
+	election := time.Duration(s.Cfg.ElectionTicks) * time.Duration(s.Cfg.TickMs) * time.Millisecond
+	contact := time.Unix(0, atomic.LoadInt64(&s.leaderContact))
+	return s.isLeader() || (s.getLead() != raft.None && time.Since(contact) < election)
+}
+
 func (s *EtcdServer) parseProposeCtxErr(err error, start time.Time) error {
 	switch err {
 	case context.Canceled:
@@ -2408,6 +2788,39 @@ func (s *EtcdServer) Alarms() []*pb.AlarmMember {
 	return s.alarmStore.Get(pb.AlarmType_NONE)
 }
 
+// alarmChangeEvent returns the webhook event of the alarm request about to
+// be applied, or nil if the request does not raise or clear an alarm.
+func (s *EtcdServer) alarmChangeEvent(ar *pb.AlarmRequest) *webhook.Event {
+	if ar.Alarm == pb.AlarmType_NONE {
+		return nil
+	}
+	active := false
+	for _, m := range s.alarmStore.Get(ar.Alarm) {
+		if m.MemberID == ar.MemberID {
+			active = true
+		}
+	}
+	ev := &webhook.Event{Alarm: ar.Alarm.String(), AlarmMemberID: types.ID(ar.MemberID).String()}
+	switch {
+	case ar.Action == pb.AlarmRequest_ACTIVATE && !active:
+		ev.Type = webhook.EventAlarmRaised
+	case ar.Action == pb.AlarmRequest_DEACTIVATE && active:
+		ev.Type = webhook.EventAlarmCleared
+	default:
+		return nil
+	}
+	return ev
+}
+
+func (s *EtcdServer) notifyWebhooks(ev webhook.Event) {
+	if s.webhooks == nil {
+		return
+	}
+	ev.ClusterID = s.Cluster().ID().String()
+	ev.MemberID = s.MemberId().String()
+	s.webhooks.Notify(ev)
+}
+
 // IsLearner returns if the local member is raft learner
 func (s *EtcdServer) IsLearner() bool {
 	return s.cluster.IsLocalMemberLearner()
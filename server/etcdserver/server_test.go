@@ -40,6 +40,7 @@ import (
 	"go.etcd.io/etcd/pkg/v3/wait"
 	"go.etcd.io/etcd/raft/v3"
 	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/raft/v3/tracker"
 	"go.etcd.io/etcd/server/v3/auth"
 	"go.etcd.io/etcd/server/v3/config"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/membership"
@@ -688,7 +689,7 @@ func TestApplyConfigChangeUpdatesConsistIndex(t *testing.T) {
 		Data:  pbutil.MustMarshal(cc),
 	}}
 
-	_, appliedi, _ := srv.apply(ents, &raftpb.ConfState{})
+	_, appliedi, _ := srv.apply(ents, time.Now(), &raftpb.ConfState{})
 	consistIndex := srv.consistIndex.ConsistentIndex()
 	assert.Equal(t, uint64(2), appliedi)
 
@@ -762,7 +763,7 @@ func TestApplyMultiConfChangeShouldStop(t *testing.T) {
 		ents = append(ents, ent)
 	}
 
-	_, _, shouldStop := srv.apply(ents, &raftpb.ConfState{})
+	_, _, shouldStop := srv.apply(ents, time.Now(), &raftpb.ConfState{})
 	if !shouldStop {
 		t.Errorf("shouldStop = %t, want %t", shouldStop, true)
 	}
@@ -1077,6 +1078,33 @@ func TestSnapshot(t *testing.T) {
 	<-ch
 }
 
+func TestSnapshotCatchUpEntries(t *testing.T) {
+	progress := func(match uint64, active bool, state tracker.StateType) tracker.Progress {
+		return tracker.Progress{Match: match, RecentActive: active, State: state}
+	}
+	tests := []struct {
+		name     string
+		progress map[uint64]tracker.Progress
+		want     uint64
+	}{
+		{"follower", nil, 100},
+		{"up to date", map[uint64]tracker.Progress{1: progress(10000, true, tracker.StateReplicate), 2: progress(9990, true, tracker.StateReplicate)}, 100},
+		{"lagging", map[uint64]tracker.Progress{1: progress(10000, true, tracker.StateReplicate), 2: progress(9700, true, tracker.StateReplicate), 3: progress(9500, true, tracker.StateProbe)}, 500},
+		{"inactive", map[uint64]tracker.Progress{1: progress(10000, true, tracker.StateReplicate), 2: progress(9500, false, tracker.StateProbe)}, 100},
+		{"receiving snapshot", map[uint64]tracker.Progress{1: progress(10000, true, tracker.StateReplicate), 2: progress(9500, true, tracker.StateSnapshot)}, 100},
+		{"beyond max", map[uint64]tracker.Progress{1: progress(10000, true, tracker.StateReplicate), 2: progress(8000, true, tracker.StateProbe)}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := raft.Status{Progress: tt.progress}
+			st.ID = 1
+			if n := snapshotCatchUpEntries(st, 10000, 100, 1000); n != tt.want {
+				t.Errorf("catch-up entries = %d, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
 // TestSnapshotOrdering ensures raft persists snapshot onto disk before
 // snapshot db is applied.
 func TestSnapshotOrdering(t *testing.T) {
@@ -1864,20 +1892,22 @@ func newNopTransporter() rafthttp.Transporter {
 	return &nopTransporter{}
 }
 
-func (s *nopTransporter) Start() error                        { return nil }
-func (s *nopTransporter) Handler() http.Handler               { return nil }
-func (s *nopTransporter) Send(m []raftpb.Message)             {}
-func (s *nopTransporter) SendSnapshot(m snap.Message)         {}
-func (s *nopTransporter) AddRemote(id types.ID, us []string)  {}
-func (s *nopTransporter) AddPeer(id types.ID, us []string)    {}
-func (s *nopTransporter) RemovePeer(id types.ID)              {}
-func (s *nopTransporter) RemoveAllPeers()                     {}
-func (s *nopTransporter) UpdatePeer(id types.ID, us []string) {}
-func (s *nopTransporter) ActiveSince(id types.ID) time.Time   { return time.Time{} }
-func (s *nopTransporter) ActivePeers() int                    { return 0 }
-func (s *nopTransporter) Stop()                               {}
-func (s *nopTransporter) Pause()                              {}
-func (s *nopTransporter) Resume()                             {}
+func (s *nopTransporter) Start() error                           { return nil }
+func (s *nopTransporter) Handler() http.Handler                  { return nil }
+func (s *nopTransporter) Send(m []raftpb.Message)                {}
+func (s *nopTransporter) SendSnapshot(m snap.Message)            {}
+func (s *nopTransporter) AddRemote(id types.ID, us []string)     {}
+func (s *nopTransporter) AddPeer(id types.ID, us []string)       {}
+func (s *nopTransporter) RemovePeer(id types.ID)                 {}
+func (s *nopTransporter) RemoveAllPeers()                        {}
+func (s *nopTransporter) UpdatePeer(id types.ID, us []string)    {}
+func (s *nopTransporter) ActiveSince(id types.ID) time.Time      { return time.Time{} }
+func (s *nopTransporter) ActivePeers() int                       { return 0 }
+func (s *nopTransporter) ClockSkews() map[types.ID]time.Duration { return nil }
+func (s *nopTransporter) Disconnects() map[types.ID]uint64       { return nil }
+func (s *nopTransporter) Stop()                                  {}
+func (s *nopTransporter) Pause()                                 {}
+func (s *nopTransporter) Resume()                                {}
 
 type snapTransporter struct {
 	nopTransporter
@@ -1975,3 +2005,31 @@ func TestWaitAppliedIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestCompactionPreempted(t *testing.T) {
+	tests := []struct {
+		commitThreshold   time.Duration
+		pendingThreshold  int
+		lastBackendCommit time.Duration
+		pendingProposals  int64
+		wpreempted        bool
+	}{
+		{0, 0, time.Second, 100, false},
+		{100 * time.Millisecond, 0, 50 * time.Millisecond, 100, false},
+		{100 * time.Millisecond, 0, 200 * time.Millisecond, 0, true},
+		{0, 10, time.Second, 10, false},
+		{0, 10, time.Second, 11, true},
+		{100 * time.Millisecond, 10, 50 * time.Millisecond, 11, true},
+	}
+	for i, tt := range tests {
+		s := &EtcdServer{Cfg: config.ServerConfig{
+			CompactionPauseBackendCommitThreshold: tt.commitThreshold,
+			CompactionPausePendingProposals:       tt.pendingThreshold,
+		}}
+		s.observeBackendCommit(tt.lastBackendCommit)
+		s.pendingProposals = tt.pendingProposals
+		if preempted := s.compactionPreempted(); preempted != tt.wpreempted {
+			t.Errorf("#%d: compactionPreempted() = %v, want %v", i, preempted, tt.wpreempted)
+		}
+	}
+}
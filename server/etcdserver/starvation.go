@@ -0,0 +1,211 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// starvationCheckInterval is the length of the windows the scheduling
+	// delays are checked over.
+	starvationCheckInterval = 5 * time.Second
+	// starvationWindows is the number of consecutive slow windows starving
+	// the member, and of consecutive healthy windows ending the starvation.
+	starvationWindows = 3
+)
+
+// cgroupCPUStatFiles are the CPU statistics of the cgroup of the process,
+// of cgroup v2 and v1.
+var cgroupCPUStatFiles = []string{"/sys/fs/cgroup/cpu.stat", "/sys/fs/cgroup/cpu/cpu.stat"}
+
+// starvationMonitor starves the member after starvationWindows consecutive
+// windows where more than slowSamplesPercent of the heartbeat ticks or of
+// the applies are delayed by more than the threshold, and ends the
+// starvation after as many healthy windows. The delays are the time the
+// goroutines waited to run once due, not the time spent running, so that
+// they only grow when the process is throttled or short of CPU.
+type starvationMonitor struct {
+	mu             sync.Mutex
+	tick, apply    latencyWindow
+	slowWindows    int
+	healthyWindows int
+	starved        bool
+}
+
+func newStarvationMonitor(threshold time.Duration) *starvationMonitor {
+	return &starvationMonitor{
+		tick:  latencyWindow{threshold: threshold},
+		apply: latencyWindow{threshold: threshold},
+	}
+}
+
+// observeTickDelay observes the delay of a heartbeat tick past the heartbeat
+// interval.
+func (m *starvationMonitor) observeTickDelay(d time.Duration) {
+	schedulingDelaySec.WithLabelValues("tick").Observe(d.Seconds())
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tick.observe(d)
+}
+
+// observeApplyDelay observes the delay of an apply, from the later of its
+// scheduling and the end of the previous apply, to its start.
+func (m *starvationMonitor) observeApplyDelay(d time.Duration) {
+	schedulingDelaySec.WithLabelValues("apply").Observe(d.Seconds())
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apply.observe(d)
+}
+
+// check ends the current window, returning whether the member is starved,
+// whether the window changed it, and the highest delays of the window.
+func (m *starvationMonitor) check() (starved, changed bool, maxTick, maxApply time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	maxTick, maxApply = m.tick.max, m.apply.max
+	tickSlow, applySlow := m.tick.endWindow(), m.apply.endWindow()
+	if tickSlow || applySlow {
+		m.slowWindows++
+		m.healthyWindows = 0
+	} else {
+		m.healthyWindows++
+		m.slowWindows = 0
+	}
+	switch {
+	case !m.starved && m.slowWindows >= starvationWindows:
+		m.starved, changed = true, true
+	case m.starved && m.healthyWindows >= starvationWindows:
+		m.starved, changed = false, true
+	}
+	return m.starved, changed, maxTick, maxApply
+}
+
+func (m *starvationMonitor) isStarved() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.starved
+}
+
+// Starved returns whether the raft ticks or the applies of the member have
+// been delayed by more than the starvation threshold, e.g. by the CPU quota
+// of its cgroup or by noisy neighbors.
+func (s *EtcdServer) Starved() bool {
+	return s.starvationMonitor.isStarved()
+}
+
+// monitorStarvation checks the scheduling delays every
+// starvationCheckInterval.
+func (s *EtcdServer) monitorStarvation() {
+	if s.Cfg.ExperimentalStarvationThreshold <= 0 {
+		return
+	}
+	lg := s.Logger()
+	lastThrottled, _ := readCgroupThrottled()
+	for {
+		select {
+		case <-time.After(starvationCheckInterval):
+		case <-s.stopping:
+			return
+		}
+
+		starved, changed, maxTick, maxApply := s.starvationMonitor.check()
+		throttled, err := readCgroupThrottled()
+		throttledWindow := throttled - lastThrottled
+		lastThrottled = throttled
+		if !changed {
+			continue
+		}
+		if !starved {
+			memberStarved.Set(0)
+			lg.Info("member no longer starved of CPU")
+			s.RecordEvent(EventStarvationEnded, fmt.Sprintf("starvation ended on member %s", s.MemberId()))
+			continue
+		}
+		memberStarved.Set(1)
+		fields := []zap.Field{
+			zap.Duration("threshold", s.Cfg.ExperimentalStarvationThreshold),
+			zap.Duration("max-tick-delay", maxTick),
+			zap.Duration("max-apply-delay", maxApply),
+		}
+		if err == nil {
+			fields = append(fields, zap.Duration("cgroup-throttled-time", throttledWindow))
+		}
+		lg.Warn("member starved of CPU; raft ticks or applies delayed", fields...)
+		s.RecordEvent(EventStarved, fmt.Sprintf("member %s starved of CPU", s.MemberId()))
+	}
+}
+
+// readCgroupThrottled returns the total time the cgroup of the process was
+// throttled for exceeding its CPU quota.
+func readCgroupThrottled() (time.Duration, error) {
+	var lastErr error
+	for _, f := range cgroupCPUStatFiles {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return parseCgroupThrottled(data)
+	}
+	return 0, lastErr
+}
+
+// parseCgroupThrottled parses the throttled time of the cpu.stat of a cgroup,
+// in microseconds in cgroup v2 and in nanoseconds in cgroup v1.
+func parseCgroupThrottled(data []byte) (time.Duration, error) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := bytes.Fields(sc.Bytes())
+		if len(fields) != 2 {
+			continue
+		}
+		var unit time.Duration
+		switch string(fields[0]) {
+		case "throttled_usec":
+			unit = time.Microsecond
+		case "throttled_time":
+			unit = time.Nanosecond
+		default:
+			continue
+		}
+		v, err := strconv.ParseInt(string(fields[1]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cgroup %s %q (%v)", fields[0], fields[1], err)
+		}
+		return time.Duration(v) * unit, nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no throttled time in cgroup cpu.stat")
+}
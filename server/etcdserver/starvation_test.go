@@ -0,0 +1,89 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStarvationMonitor(t *testing.T) {
+	m := newStarvationMonitor(100 * time.Millisecond)
+	slowWindow := func() (bool, bool) {
+		for i := 0; i < 8; i++ {
+			m.observeTickDelay(time.Millisecond)
+		}
+		m.observeTickDelay(time.Second)
+		m.observeApplyDelay(2 * time.Second)
+		starved, changed, maxTick, maxApply := m.check()
+		if maxTick != time.Second || maxApply != 2*time.Second {
+			t.Fatalf("max delays = %v, %v, want 1s, 2s", maxTick, maxApply)
+		}
+		return starved, changed
+	}
+
+	for i := 0; i < starvationWindows-1; i++ {
+		if starved, changed := slowWindow(); starved || changed {
+			t.Fatalf("#%d: starved, changed = %v, %v after %d slow windows", i, starved, changed, i+1)
+		}
+	}
+	if starved, changed := slowWindow(); !starved || !changed {
+		t.Fatalf("starved, changed = %v, %v, want true, true", starved, changed)
+	}
+	if !m.isStarved() {
+		t.Fatal("expected starved member")
+	}
+
+	// a few delayed ticks among many do not starve the member
+	for i := 0; i < starvationWindows; i++ {
+		for j := 0; j < 20; j++ {
+			m.observeTickDelay(0)
+		}
+		m.observeTickDelay(time.Second)
+		m.check()
+	}
+	if m.isStarved() {
+		t.Fatal("still starved after healthy windows")
+	}
+
+	var nilMonitor *starvationMonitor
+	nilMonitor.observeTickDelay(time.Second)
+	nilMonitor.observeApplyDelay(time.Second)
+	if nilMonitor.isStarved() {
+		t.Error("nil monitor is starved")
+	}
+}
+
+func TestParseCgroupThrottled(t *testing.T) {
+	tests := []struct {
+		data      string
+		throttled time.Duration
+		err       bool
+	}{
+		{"usage_usec 1000\nnr_periods 10\nnr_throttled 2\nthrottled_usec 1500\n", 1500 * time.Microsecond, false},
+		{"nr_periods 10\nnr_throttled 2\nthrottled_time 2000000\n", 2 * time.Millisecond, false},
+		{"usage_usec 1000\n", 0, true},
+		{"throttled_usec x\n", 0, true},
+	}
+	for i, tt := range tests {
+		throttled, err := parseCgroupThrottled([]byte(tt.data))
+		if (err != nil) != tt.err {
+			t.Fatalf("#%d: err = %v, want error %v", i, err, tt.err)
+		}
+		if throttled != tt.throttled {
+			t.Errorf("#%d: throttled = %v, want %v", i, throttled, tt.throttled)
+		}
+	}
+}
@@ -18,7 +18,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/mvccpb"
@@ -69,8 +71,12 @@ func Put(ctx context.Context, lg *zap.Logger, lessor lease.Lessor, kv mvcc.KV, t
 			return nil, nil, errors.ErrKeyNotFound
 		}
 	}
+	annotations := p.Annotations
 	if p.IgnoreValue {
 		val = rr.KVs[0].Value
+		if len(annotations) == 0 {
+			annotations = rr.KVs[0].Annotations
+		}
 	}
 	if p.IgnoreLease {
 		leaseID = lease.LeaseID(rr.KVs[0].Lease)
@@ -81,11 +87,53 @@ func Put(ctx context.Context, lg *zap.Logger, lessor lease.Lessor, kv mvcc.KV, t
 		}
 	}
 
-	resp.Header.Revision = txnWrite.Put(p.Key, val, leaseID)
+	if len(annotations) != 0 {
+		resp.Header.Revision = txnWrite.PutWithAnnotations(p.Key, val, leaseID, annotations)
+	} else {
+		resp.Header.Revision = txnWrite.Put(p.Key, val, leaseID)
+	}
 	trace.AddField(traceutil.Field{Key: "response_revision", Value: resp.Header.Revision})
 	return resp, trace, nil
 }
 
+// Increment adds the delta to the decimal integer of the key in the txn, the
+// integer of a key that does not exist being 0. The annotations of the key are
+// kept.
+func Increment(txnWrite mvcc.TxnWrite, r *pb.IncrementRequest) (*pb.IncrementResponse, error) {
+	rr, err := txnWrite.Range(context.TODO(), r.Key, nil, mvcc.RangeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	val, err := incremented(rr, r.Delta)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.IncrementResponse{Header: &pb.ResponseHeader{}, Value: val}
+	encoded := []byte(strconv.FormatInt(val, 10))
+	if len(rr.KVs) != 0 && len(rr.KVs[0].Annotations) != 0 {
+		resp.Header.Revision = txnWrite.PutWithAnnotations(r.Key, encoded, lease.LeaseID(r.Lease), rr.KVs[0].Annotations)
+	} else {
+		resp.Header.Revision = txnWrite.Put(r.Key, encoded, lease.LeaseID(r.Lease))
+	}
+	return resp, nil
+}
+
+// incremented returns the integer of the key ranged, added the delta.
+func incremented(rr *mvcc.RangeResult, delta int64) (int64, error) {
+	var cur int64
+	if rr != nil && len(rr.KVs) != 0 {
+		v, err := strconv.ParseInt(string(rr.KVs[0].Value), 10, 64)
+		if err != nil {
+			return 0, errors.ErrValueNotInteger
+		}
+		cur = v
+	}
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return 0, errors.ErrIntegerOverflow
+	}
+	return cur + delta, nil
+}
+
 func DeleteRange(kv mvcc.KV, txnWrite mvcc.TxnWrite, dr *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
 	resp := &pb.DeleteRangeResponse{}
 	resp.Header = &pb.ResponseHeader{}
@@ -124,8 +172,12 @@ func Range(ctx context.Context, lg *zap.Logger, kv mvcc.KV, txnRead mvcc.TxnRead
 		defer txnRead.End()
 	}
 
+	// mvcc.Range can walk the index in descending key order, so
+	// a descending sort by key needs neither a full fetch nor a re-sort.
+	descendByKey := r.SortTarget == pb.RangeRequest_KEY && r.SortOrder == pb.RangeRequest_DESCEND
+
 	limit := r.Limit
-	if r.SortOrder != pb.RangeRequest_NONE ||
+	if (r.SortOrder != pb.RangeRequest_NONE && !descendByKey) ||
 		r.MinModRevision != 0 || r.MaxModRevision != 0 ||
 		r.MinCreateRevision != 0 || r.MaxCreateRevision != 0 {
 		// fetch everything; sort and truncate afterwards
@@ -137,9 +189,12 @@ func Range(ctx context.Context, lg *zap.Logger, kv mvcc.KV, txnRead mvcc.TxnRead
 	}
 
 	ro := mvcc.RangeOptions{
-		Limit: limit,
-		Rev:   r.Revision,
-		Count: r.CountOnly,
+		Limit:   limit,
+		Rev:     r.Revision,
+		Count:   r.CountOnly,
+		Descend: descendByKey,
+
+		CompactionBarrier: r.CompactionBarrier,
 	}
 
 	rr, err := txnRead.Range(ctx, r.Key, mkGteRange(r.RangeEnd), ro)
@@ -175,6 +230,9 @@ func Range(ctx context.Context, lg *zap.Logger, kv mvcc.KV, txnRead mvcc.TxnRead
 		// sorted by keys in lexiographically ascending order,
 		// don't re-sort when target is 'KEY' and order is ASCEND
 		sortOrder = pb.RangeRequest_NONE
+	} else if descendByKey {
+		// already returned in descending key order by mvcc.Range
+		sortOrder = pb.RangeRequest_NONE
 	}
 	if sortOrder != pb.RangeRequest_NONE {
 		var sorter sort.Interface
@@ -246,7 +304,12 @@ func Txn(ctx context.Context, lg *zap.Logger, rt *pb.TxnRequest, txnModeWriteWit
 	if isWrite {
 		trace.AddField(traceutil.Field{Key: "read_only", Value: false})
 		if _, err := checkRequests(txnWrite, rt, txnPath,
-			func(rv mvcc.ReadView, ro *pb.RequestOp) error { return checkRequestPut(rv, lessor, ro) }); err != nil {
+			func(rv mvcc.ReadView, ro *pb.RequestOp) error {
+				if err := checkRequestPut(rv, lessor, ro); err != nil {
+					return err
+				}
+				return checkRequestIncrement(rv, lessor, ro)
+			}); err != nil {
 			txnWrite.End()
 			return nil, nil, err
 		}
@@ -312,6 +375,8 @@ func newTxnResp(rt *pb.TxnRequest, txnPath []bool) (txnResp *pb.TxnResponse, txn
 			resps[i] = &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{}}
 		case *pb.RequestOp_RequestDeleteRange:
 			resps[i] = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{}}
+		case *pb.RequestOp_RequestIncrement:
+			resps[i] = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseIncrement{}}
 		case *pb.RequestOp_RequestTxn:
 			resp, txns := newTxnResp(tv.RequestTxn, txnPath[1:])
 			resps[i] = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: resp}}
@@ -361,6 +426,12 @@ func applyTxn(ctx context.Context, lg *zap.Logger, kv mvcc.KV, lessor lease.Less
 				return 0, fmt.Errorf("applyTxn: failed DeleteRange: %w", err)
 			}
 			respi.(*pb.ResponseOp_ResponseDeleteRange).ResponseDeleteRange = resp
+		case *pb.RequestOp_RequestIncrement:
+			resp, err := Increment(txnWrite, tv.RequestIncrement)
+			if err != nil {
+				return 0, fmt.Errorf("applyTxn: failed Increment: %w", err)
+			}
+			respi.(*pb.ResponseOp_ResponseIncrement).ResponseIncrement = resp
 		case *pb.RequestOp_RequestTxn:
 			resp := respi.(*pb.ResponseOp_ResponseTxn).ResponseTxn
 			applyTxns, err := applyTxn(ctx, lg, kv, lessor, txnWrite, tv.RequestTxn, txnPath[1:], resp)
@@ -405,6 +476,28 @@ func checkRequestPut(rv mvcc.ReadView, lessor lease.Lessor, reqOp *pb.RequestOp)
 	return nil
 }
 
+func checkRequestIncrement(rv mvcc.ReadView, lessor lease.Lessor, reqOp *pb.RequestOp) error {
+	tv, ok := reqOp.Request.(*pb.RequestOp_RequestIncrement)
+	if !ok || tv.RequestIncrement == nil {
+		return nil
+	}
+	req := tv.RequestIncrement
+	// the increment must not fail once the txn writes
+	rr, err := rv.Range(context.TODO(), req.Key, nil, mvcc.RangeOptions{})
+	if err != nil {
+		return err
+	}
+	if _, err := incremented(rr, req.Delta); err != nil {
+		return err
+	}
+	if lease.LeaseID(req.Lease) != lease.NoLease {
+		if l := lessor.Lookup(lease.LeaseID(req.Lease)); l == nil {
+			return lease.ErrLeaseNotFound
+		}
+	}
+	return nil
+}
+
 func checkRequestRange(rv mvcc.ReadView, reqOp *pb.RequestOp) error {
 	tv, ok := reqOp.Request.(*pb.RequestOp_RequestRange)
 	if !ok || tv.RequestRange == nil {
@@ -600,6 +693,14 @@ func compareKV(c *pb.Compare, ckv mvccpb.KeyValue) bool {
 			rev = tv.Lease
 		}
 		result = compareInt64(ckv.Lease, rev)
+	case pb.Compare_FENCING_TOKEN:
+		var lease int64
+		if tv, _ := c.TargetUnion.(*pb.Compare_FencingToken_); tv != nil && tv.FencingToken != nil {
+			rev, lease = tv.FencingToken.CreateRevision, tv.FencingToken.Lease
+		}
+		if result = compareInt64(ckv.CreateRevision, rev); result == 0 {
+			result = compareInt64(ckv.Lease, lease)
+		}
 	}
 	switch c.Result {
 	case pb.Compare_EQUAL:
@@ -691,6 +792,19 @@ func checkTxnReqsPermission(as auth.AuthStore, ai *auth.AuthInfo, reqs []*pb.Req
 			if err != nil {
 				return err
 			}
+
+		case *pb.RequestOp_RequestIncrement:
+			if tv.RequestIncrement == nil {
+				continue
+			}
+
+			// the new value is returned
+			if err := as.IsRangePermitted(ai, tv.RequestIncrement.Key, nil); err != nil {
+				return err
+			}
+			if err := as.IsPutPermitted(ai, tv.RequestIncrement.Key); err != nil {
+				return err
+			}
 		}
 	}
 
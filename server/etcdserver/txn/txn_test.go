@@ -2,10 +2,13 @@ package txn
 
 import (
 	"context"
+	"math"
+	"strconv"
 	"strings"
 	"testing"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/server/v3/etcdserver/errors"
 	"go.etcd.io/etcd/server/v3/lease"
 	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
 	"go.etcd.io/etcd/server/v3/storage/mvcc"
@@ -79,3 +82,110 @@ func TestWriteTxnPanic(t *testing.T) {
 
 	assert.Panics(t, func() { Txn(ctx, zaptest.NewLogger(t), txn, false, s, &lease.FakeLessor{}) }, "Expected panic in Txn with writes")
 }
+
+func TestPutAnnotations(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+	s := mvcc.NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	defer s.Close()
+
+	lg := zaptest.NewLogger(t)
+	annotations := map[string]string{"owner": "app", "content-type": "text/plain"}
+	tests := []struct {
+		name     string
+		req      *pb.PutRequest
+		wvalue   string
+		wannotes map[string]string
+	}{
+		{
+			name:     "put with annotations",
+			req:      &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar"), Annotations: annotations},
+			wvalue:   "bar",
+			wannotes: annotations,
+		},
+		{
+			name:     "ignore value keeps annotations",
+			req:      &pb.PutRequest{Key: []byte("foo"), IgnoreValue: true},
+			wvalue:   "bar",
+			wannotes: annotations,
+		},
+		{
+			name:     "ignore value replaces given annotations",
+			req:      &pb.PutRequest{Key: []byte("foo"), IgnoreValue: true, Annotations: map[string]string{"owner": "other"}},
+			wvalue:   "bar",
+			wannotes: map[string]string{"owner": "other"},
+		},
+		{
+			name:   "put without annotations clears them",
+			req:    &pb.PutRequest{Key: []byte("foo"), Value: []byte("baz")},
+			wvalue: "baz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Put(context.TODO(), lg, &lease.FakeLessor{}, s, nil, tt.req)
+			assert.NoError(t, err)
+			rr, err := s.Range(context.TODO(), []byte("foo"), nil, mvcc.RangeOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, rr.KVs, 1)
+			assert.Equal(t, tt.wvalue, string(rr.KVs[0].Value))
+			assert.Equal(t, tt.wannotes, rr.KVs[0].Annotations)
+		})
+	}
+}
+
+func TestTxnIncrement(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+	s := mvcc.NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, mvcc.StoreConfig{})
+	defer s.Close()
+
+	s.PutWithAnnotations([]byte("max"), []byte(strconv.FormatInt(math.MaxInt64-1, 10)), lease.NoLease, map[string]string{"owner": "app"})
+	s.Put([]byte("text"), []byte("bar"), lease.NoLease)
+
+	lg := zaptest.NewLogger(t)
+	tests := []struct {
+		name   string
+		key    string
+		delta  int64
+		wvalue int64
+		werr   error
+	}{
+		{name: "missing key is 0", key: "foo", delta: 2, wvalue: 2},
+		{name: "increment", key: "foo", delta: 3, wvalue: 5},
+		{name: "decrement", key: "foo", delta: -7, wvalue: -2},
+		{name: "up to the max", key: "max", delta: 1, wvalue: math.MaxInt64},
+		{name: "overflow", key: "max", delta: 1, werr: errors.ErrIntegerOverflow},
+		{name: "not an integer", key: "text", delta: 1, werr: errors.ErrValueNotInteger},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := &pb.TxnRequest{
+				Success: []*pb.RequestOp{
+					{
+						Request: &pb.RequestOp_RequestIncrement{
+							RequestIncrement: &pb.IncrementRequest{Key: []byte(tt.key), Delta: tt.delta},
+						},
+					},
+				},
+			}
+			resp, _, err := Txn(context.TODO(), lg, txn, false, s, &lease.FakeLessor{})
+			assert.Equal(t, tt.werr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tt.wvalue, resp.Responses[0].GetResponseIncrement().Value)
+			rr, err := s.Range(context.TODO(), []byte(tt.key), nil, mvcc.RangeOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, strconv.FormatInt(tt.wvalue, 10), string(rr.KVs[0].Value))
+		})
+	}
+
+	// the failed increments did not change the keys, and the annotations are kept
+	rr, err := s.Range(context.TODO(), []byte("max"), []byte("tey"), mvcc.RangeOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, rr.KVs, 2)
+	assert.Equal(t, strconv.FormatInt(math.MaxInt64, 10), string(rr.KVs[0].Value))
+	assert.Equal(t, map[string]string{"owner": "app"}, rr.KVs[0].Annotations)
+	assert.Equal(t, "bar", string(rr.KVs[1].Value))
+}
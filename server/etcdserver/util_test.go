@@ -75,21 +75,23 @@ func newNopTransporterWithActiveTime(memberIDs []types.ID) rafthttp.Transporter
 	return &nopTransporterWithActiveTime{activeMap: am}
 }
 
-func (s *nopTransporterWithActiveTime) Start() error                        { return nil }
-func (s *nopTransporterWithActiveTime) Handler() http.Handler               { return nil }
-func (s *nopTransporterWithActiveTime) Send(m []raftpb.Message)             {}
-func (s *nopTransporterWithActiveTime) SendSnapshot(m snap.Message)         {}
-func (s *nopTransporterWithActiveTime) AddRemote(id types.ID, us []string)  {}
-func (s *nopTransporterWithActiveTime) AddPeer(id types.ID, us []string)    {}
-func (s *nopTransporterWithActiveTime) RemovePeer(id types.ID)              {}
-func (s *nopTransporterWithActiveTime) RemoveAllPeers()                     {}
-func (s *nopTransporterWithActiveTime) UpdatePeer(id types.ID, us []string) {}
-func (s *nopTransporterWithActiveTime) ActiveSince(id types.ID) time.Time   { return s.activeMap[id] }
-func (s *nopTransporterWithActiveTime) ActivePeers() int                    { return 0 }
-func (s *nopTransporterWithActiveTime) Stop()                               {}
-func (s *nopTransporterWithActiveTime) Pause()                              {}
-func (s *nopTransporterWithActiveTime) Resume()                             {}
-func (s *nopTransporterWithActiveTime) reset(am map[types.ID]time.Time)     { s.activeMap = am }
+func (s *nopTransporterWithActiveTime) Start() error                           { return nil }
+func (s *nopTransporterWithActiveTime) Handler() http.Handler                  { return nil }
+func (s *nopTransporterWithActiveTime) Send(m []raftpb.Message)                {}
+func (s *nopTransporterWithActiveTime) SendSnapshot(m snap.Message)            {}
+func (s *nopTransporterWithActiveTime) AddRemote(id types.ID, us []string)     {}
+func (s *nopTransporterWithActiveTime) AddPeer(id types.ID, us []string)       {}
+func (s *nopTransporterWithActiveTime) RemovePeer(id types.ID)                 {}
+func (s *nopTransporterWithActiveTime) RemoveAllPeers()                        {}
+func (s *nopTransporterWithActiveTime) UpdatePeer(id types.ID, us []string)    {}
+func (s *nopTransporterWithActiveTime) ActiveSince(id types.ID) time.Time      { return s.activeMap[id] }
+func (s *nopTransporterWithActiveTime) ActivePeers() int                       { return 0 }
+func (s *nopTransporterWithActiveTime) ClockSkews() map[types.ID]time.Duration { return nil }
+func (s *nopTransporterWithActiveTime) Disconnects() map[types.ID]uint64       { return nil }
+func (s *nopTransporterWithActiveTime) Stop()                                  {}
+func (s *nopTransporterWithActiveTime) Pause()                                 {}
+func (s *nopTransporterWithActiveTime) Resume()                                {}
+func (s *nopTransporterWithActiveTime) reset(am map[types.ID]time.Time)        { s.activeMap = am }
 
 func TestPanicAlternativeStringer(t *testing.T) {
 	p := panicAlternativeStringer{alternative: func() string { return "alternative" }}
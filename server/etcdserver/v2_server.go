@@ -16,6 +16,7 @@ package etcdserver
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
@@ -105,7 +106,11 @@ func (a *reqV2HandlerEtcdServer) processRaftRequest(ctx context.Context, r *Requ
 	start := time.Now()
 	a.s.r.Propose(ctx, data)
 	proposalsPending.Inc()
-	defer proposalsPending.Dec()
+	atomic.AddInt64(&a.s.pendingProposals, 1)
+	defer func() {
+		proposalsPending.Dec()
+		atomic.AddInt64(&a.s.pendingProposals, -1)
+	}()
 
 	select {
 	case x := <-ch:
@@ -20,6 +20,7 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
@@ -107,8 +108,16 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 	)
 	ctx = context.WithValue(ctx, traceutil.TraceKey, trace)
 
+	if s.MemoryBudgetExceeded("range") {
+		return nil, errors.ErrMemoryBudgetExceeded
+	}
+	release, err := s.acquireRequestLane(ctx, PriorityRead)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	var resp *pb.RangeResponse
-	var err error
 	defer func(start time.Time) {
 		txn.WarnOfExpensiveReadOnlyRangeRequest(s.Logger(), s.Cfg.WarningApplyDuration, start, r, resp, err)
 		if resp != nil {
@@ -140,6 +149,11 @@ func (s *EtcdServer) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRe
 }
 
 func (s *EtcdServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
+	release, err := s.acquireRequestLane(ctx, PriorityWrite)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	ctx = context.WithValue(ctx, traceutil.StartTimeKey, time.Now())
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{Put: r})
 	if err != nil {
@@ -149,6 +163,18 @@ func (s *EtcdServer) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse
 }
 
 func (s *EtcdServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	if r.Paced {
+		return s.pacedDeleteRange(ctx, r)
+	}
+	return s.deleteRange(ctx, r)
+}
+
+func (s *EtcdServer) deleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	release, err := s.acquireRequestLane(ctx, PriorityWrite)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{DeleteRange: r})
 	if err != nil {
 		return nil, err
@@ -158,6 +184,14 @@ func (s *EtcdServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest)
 
 func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
 	if txn.IsTxnReadonly(r) {
+		if s.MemoryBudgetExceeded("txn") {
+			return nil, errors.ErrMemoryBudgetExceeded
+		}
+		release, err := s.acquireRequestLane(ctx, PriorityRead)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 		trace := traceutil.New("transaction",
 			s.Logger(),
 			traceutil.Field{Key: "read_only", Value: true},
@@ -171,7 +205,6 @@ func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse
 			}
 		}
 		var resp *pb.TxnResponse
-		var err error
 		chk := func(ai *auth.AuthInfo) error {
 			return txn.CheckTxnAuth(s.authStore, ai, r)
 		}
@@ -190,6 +223,11 @@ func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse
 		return resp, err
 	}
 
+	release, err := s.acquireRequestLane(ctx, PriorityWrite)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	ctx = context.WithValue(ctx, traceutil.StartTimeKey, time.Now())
 	resp, err := s.raftRequest(ctx, pb.InternalRaftRequest{Txn: r})
 	if err != nil {
@@ -200,6 +238,13 @@ func (s *EtcdServer) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse
 
 func (s *EtcdServer) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error) {
 	startTime := time.Now()
+	if s.historyRetention != nil || len(r.Retention) > 0 {
+		// the retained history is set in the request from the rules of the
+		// member, for all the members to compact retaining the same history
+		cr := *r
+		cr.Retention = s.historyRetention.Retention()
+		r = &cr
+	}
 	result, err := s.processInternalRaftRequestOnce(ctx, pb.InternalRaftRequest{Compaction: r})
 	trace := traceutil.TODO()
 	if result != nil && result.Trace != nil {
@@ -249,6 +294,11 @@ func (s *EtcdServer) LeaseGrant(ctx context.Context, r *pb.LeaseGrantRequest) (*
 		// only use positive int64 id's
 		r.ID = int64(s.reqIDGen.Next() & ((1 << 63) - 1))
 	}
+	release, err := s.acquireRequestLane(ctx, PriorityLease)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	resp, err := s.raftRequestOnce(ctx, pb.InternalRaftRequest{LeaseGrant: r})
 	if err != nil {
 		return nil, err
@@ -269,6 +319,11 @@ func (s *EtcdServer) waitAppliedIndex() error {
 }
 
 func (s *EtcdServer) LeaseRevoke(ctx context.Context, r *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	release, err := s.acquireRequestLane(ctx, PriorityLease)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	resp, err := s.raftRequestOnce(ctx, pb.InternalRaftRequest{LeaseRevoke: r})
 	if err != nil {
 		return nil, err
@@ -277,6 +332,11 @@ func (s *EtcdServer) LeaseRevoke(ctx context.Context, r *pb.LeaseRevokeRequest)
 }
 
 func (s *EtcdServer) LeaseRenew(ctx context.Context, id lease.LeaseID) (int64, error) {
+	release, err := s.acquireRequestLane(ctx, PriorityLease)
+	if err != nil {
+		return -1, err
+	}
+	defer release()
 	if s.isLeader() {
 		if err := s.waitAppliedIndex(); err != nil {
 			return 0, err
@@ -676,6 +736,7 @@ func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.In
 		if authInfo != nil {
 			r.Header.Username = authInfo.Username
 			r.Header.AuthRevision = authInfo.Revision
+			r.Header.Roles = authInfo.Roles
 		}
 	}
 
@@ -698,14 +759,21 @@ func (s *EtcdServer) processInternalRaftRequestOnce(ctx context.Context, r pb.In
 	defer cancel()
 
 	start := time.Now()
+	s.proposalTimes.start(id, start)
+	defer s.proposalTimes.forget(id)
 	err = s.r.Propose(cctx, data)
 	if err != nil {
 		proposalsFailed.Inc()
 		s.w.Trigger(id, nil) // GC wait
 		return nil, err
 	}
+	s.proposalTimes.propose(id, time.Now())
 	proposalsPending.Inc()
-	defer proposalsPending.Dec()
+	atomic.AddInt64(&s.pendingProposals, 1)
+	defer func() {
+		proposalsPending.Dec()
+		atomic.AddInt64(&s.pendingProposals, -1)
+	}()
 
 	select {
 	case x := <-ch:
@@ -902,6 +970,30 @@ func (s *EtcdServer) linearizableReadNotify(ctx context.Context) error {
 	}
 }
 
+// WaitAppliedRevision waits for the member to apply the revision rev, for at
+// most the request timeout, returning mvcc.ErrFutureRev if it does not.
+func (s *EtcdServer) WaitAppliedRevision(ctx context.Context, rev int64) error {
+	timer := time.NewTimer(s.Cfg.ReqTimeout())
+	defer timer.Stop()
+	for {
+		// the applied index is read before the revision, for the wait to end
+		// once the entries applied after the read are.
+		ai := s.getAppliedIndex()
+		if s.KV().Rev() >= rev {
+			return nil
+		}
+		select {
+		case <-s.applyWait.Wait(ai + 1):
+		case <-timer.C:
+			return mvcc.ErrFutureRev
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			return errors.ErrStopped
+		}
+	}
+}
+
 func (s *EtcdServer) AuthInfoFromCtx(ctx context.Context) (*auth.AuthInfo, error) {
 	authInfo, err := s.AuthStore().AuthInfoFromCtx(ctx)
 	if authInfo != nil || err != nil {
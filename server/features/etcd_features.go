@@ -0,0 +1,86 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package features defines the feature gates of the etcd server.
+package features
+
+import (
+	"go.etcd.io/etcd/pkg/v3/featuregate"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DistributedTracing enables the distributed tracing using OpenTelemetry.
+	DistributedTracing featuregate.Feature = "DistributedTracing"
+	// InitialCorruptCheck checks the data corruption before serving any
+	// client or peer traffic.
+	InitialCorruptCheck featuregate.Feature = "InitialCorruptCheck"
+	// CompactHashCheck makes the leader periodically check the compaction
+	// hashes of the followers.
+	CompactHashCheck featuregate.Feature = "CompactHashCheck"
+	// LeaseCheckpoint makes the leader send regular checkpoints to the other
+	// members to prevent the reset of the remaining TTLs on leader change.
+	LeaseCheckpoint featuregate.Feature = "LeaseCheckpoint"
+	// LeaseCheckpointPersist persists the remaining TTLs to prevent the
+	// indefinite auto-renewal of long lived leases.
+	LeaseCheckpointPersist featuregate.Feature = "LeaseCheckpointPersist"
+	// MemoryMlock locks the pages of etcd, in particular of bbolt, in RAM.
+	MemoryMlock featuregate.Feature = "MemoryMlock"
+	// TxnModeWriteWithSharedBuffer makes the write transactions use a shared
+	// buffer in their read-only check operations.
+	TxnModeWriteWithSharedBuffer featuregate.Feature = "TxnModeWriteWithSharedBuffer"
+	// DiskDegradedTransferLeadership transfers the leadership away from the
+	// member while its disk is degraded.
+	DiskDegradedTransferLeadership featuregate.Feature = "DiskDegradedTransferLeadership"
+	// BackendWarmUp reads the backend in the background after opening it, for
+	// its pages to be in the page cache, instead of prefaulting them on open.
+	BackendWarmUp featuregate.Feature = "BackendWarmUp"
+	// SnapshotSpool copies the snapshots sent to the clients to a file before
+	// sending them, not to hold a read transaction of the backend open while
+	// the client receives the snapshot, which blocks the backend commits
+	// growing its mmap and so the applies. The member needs free disk space
+	// for the copy of its backend.
+	SnapshotSpool featuregate.Feature = "SnapshotSpool"
+	// StarvationUnhealthy fails the health checks of the member while it is
+	// starved of CPU, for the load balancers and the orchestrators to route
+	// the clients away from it.
+	StarvationUnhealthy featuregate.Feature = "StarvationUnhealthy"
+	// BatchApply applies the consecutive committed puts, deletes and write
+	// txns not touching the keys written by each other in one write batch of
+	// the store, taking its locks once for all of them.
+	BatchApply featuregate.Feature = "BatchApply"
+)
+
+// DefaultEtcdServerFeatureGates are the features of the etcd server.
+var DefaultEtcdServerFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	DistributedTracing:             {Default: false, Stage: featuregate.Alpha},
+	InitialCorruptCheck:            {Default: false, Stage: featuregate.Alpha},
+	CompactHashCheck:               {Default: false, Stage: featuregate.Alpha},
+	LeaseCheckpoint:                {Default: false, Stage: featuregate.Alpha},
+	LeaseCheckpointPersist:         {Default: false, Stage: featuregate.Alpha},
+	MemoryMlock:                    {Default: false, Stage: featuregate.Alpha},
+	TxnModeWriteWithSharedBuffer:   {Default: true, Stage: featuregate.Beta},
+	DiskDegradedTransferLeadership: {Default: false, Stage: featuregate.Alpha},
+	BackendWarmUp:                  {Default: false, Stage: featuregate.Alpha},
+	SnapshotSpool:                  {Default: false, Stage: featuregate.Alpha},
+	StarvationUnhealthy:            {Default: false, Stage: featuregate.Alpha},
+	BatchApply:                     {Default: false, Stage: featuregate.Alpha},
+}
+
+// NewDefaultServerFeatureGate returns a gate of the features of the etcd
+// server, all at their default.
+func NewDefaultServerFeatureGate(lg *zap.Logger) *featuregate.FeatureGate {
+	return featuregate.New(lg, DefaultEtcdServerFeatureGates)
+}
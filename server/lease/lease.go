@@ -28,6 +28,9 @@ type Lease struct {
 	ID           LeaseID
 	ttl          int64 // time to live of the lease in seconds
 	remainingTTL int64 // remaining time to live in seconds, if zero valued it is considered unset and the full ttl should be used
+	// checkpointed is the time the remaining TTL was last known on the member, at the grant or the last
+	// checkpoint applied, zero if recovered from the backend. Protected by the lessor mutex.
+	checkpointed time.Time
 	// expiryMu protects concurrent accesses to expiry
 	expiryMu sync.RWMutex
 	// expiry is time when lease should expire. no expiration when expiry.IsZero() is true
@@ -72,6 +75,24 @@ func (l *Lease) refresh(extend time.Duration) {
 	l.expiry = newExpiry
 }
 
+// refreshFromCheckpoint refreshes the expiry of the lease from its last checkpoint applied on the member, less the
+// time elapsed since, for the remaining TTL not to jump back by this time on leader change. Without a checkpoint
+// since the member started, the lease is refreshed from its remaining TTL.
+func (l *Lease) refreshFromCheckpoint(extend time.Duration) {
+	if l.remainingTTL <= 0 || l.checkpointed.IsZero() {
+		l.refresh(extend)
+		return
+	}
+	remaining := time.Duration(l.remainingTTL)*time.Second - time.Since(l.checkpointed)
+	if remaining < 0 {
+		remaining = 0
+	}
+	newExpiry := time.Now().Add(extend + remaining)
+	l.expiryMu.Lock()
+	defer l.expiryMu.Unlock()
+	l.expiry = newExpiry
+}
+
 // forever sets the expiry of lease to be forever.
 func (l *Lease) forever() {
 	l.expiryMu.Lock()
@@ -279,10 +279,11 @@ func (le *lessor) Grant(id LeaseID, ttl int64) (*Lease, error) {
 	// TODO: when lessor is under high load, it should give out lease
 	// with longer TTL to reduce renew load.
 	l := &Lease{
-		ID:      id,
-		ttl:     ttl,
-		itemSet: make(map[LeaseItem]struct{}),
-		revokec: make(chan struct{}),
+		ID:           id,
+		ttl:          ttl,
+		itemSet:      make(map[LeaseItem]struct{}),
+		revokec:      make(chan struct{}),
+		checkpointed: time.Now(),
 	}
 
 	if l.ttl < le.minLeaseTTL {
@@ -307,6 +308,7 @@ func (le *lessor) Grant(id LeaseID, ttl int64) (*Lease, error) {
 
 	leaseTotalTTLs.Observe(float64(l.ttl))
 	leaseGranted.Inc()
+	leaseActive.WithLabelValues(activeLeaseTTLLabel(l.ttl)).Inc()
 
 	if le.isPrimary() {
 		item := &LeaseWithTime{id: l.ID, time: l.expiry}
@@ -330,6 +332,7 @@ func (le *lessor) Revoke(id LeaseID) error {
 	// it may lead to deadlock with Grant or Checkpoint operations, which
 	// acquire the le.mu firstly and then the batchTx lock.
 	delete(le.leaseMap, id)
+	leaseActive.WithLabelValues(activeLeaseTTLLabel(l.ttl)).Dec()
 
 	defer close(l.revokec)
 	// unlock before doing external work
@@ -367,6 +370,14 @@ func (le *lessor) Checkpoint(id LeaseID, remainingTTL int64) error {
 	if l, ok := le.leaseMap[id]; ok {
 		// when checkpointing, we only update the remainingTTL, Promote is responsible for applying this to lease expiry
 		l.remainingTTL = remainingTTL
+		leaseCheckpointApplied.Inc()
+		// the remaining TTL of a lease read from the backend on restart jumps
+		// back by the lag
+		now := time.Now()
+		if remainingTTL > 0 && !l.checkpointed.IsZero() {
+			leaseCheckpointLag.Observe(now.Sub(l.checkpointed).Seconds())
+		}
+		l.checkpointed = now
 		if le.shouldPersistCheckpoints() {
 			l.persistTo(le.b)
 		}
@@ -390,6 +401,7 @@ func greaterOrEqual(first, second semver.Version) bool {
 // Renew renews an existing lease. If the given lease does not exist or
 // has expired, an error will be returned.
 func (le *lessor) Renew(id LeaseID) (int64, error) {
+	start := time.Now()
 	le.mu.RLock()
 	if !le.isPrimary() {
 		// forward renew request to primary instead of returning error.
@@ -429,6 +441,7 @@ func (le *lessor) Renew(id LeaseID) (int64, error) {
 	// of RAFT entries written per lease to a max of 2 per checkpoint interval.
 	if clearRemainingTTL {
 		le.cp(context.Background(), &pb.LeaseCheckpointRequest{Checkpoints: []*pb.LeaseCheckpoint{{ID: int64(l.ID), Remaining_TTL: 0}}})
+		leaseCheckpointSubmitted.Inc()
 	}
 
 	le.mu.Lock()
@@ -438,6 +451,7 @@ func (le *lessor) Renew(id LeaseID) (int64, error) {
 	le.mu.Unlock()
 
 	leaseRenewed.Inc()
+	leaseRenewDuration.Observe(time.Since(start).Seconds())
 	return l.ttl, nil
 }
 
@@ -469,9 +483,10 @@ func (le *lessor) Promote(extend time.Duration) {
 
 	le.demotec = make(chan struct{})
 
-	// refresh the expiries of all leases.
+	// refresh the expiries of all leases, counting down the remaining TTLs
+	// checkpointed by the previous leader.
 	for _, l := range le.leaseMap {
-		l.refresh(extend)
+		l.refreshFromCheckpoint(extend)
 		item := &LeaseWithTime{id: l.ID, time: l.expiry}
 		le.leaseExpiredNotifier.RegisterOrUpdate(item)
 		le.scheduleCheckpointIfNeeded(l)
@@ -510,7 +525,7 @@ func (le *lessor) Promote(extend time.Duration) {
 		rateDelay -= float64(remaining - baseWindow)
 		delay := time.Duration(rateDelay)
 		nextWindow = baseWindow + delay
-		l.refresh(delay + extend)
+		l.refreshFromCheckpoint(delay + extend)
 		item := &LeaseWithTime{id: l.ID, time: l.expiry}
 		le.leaseExpiredNotifier.RegisterOrUpdate(item)
 		le.scheduleCheckpointIfNeeded(l)
@@ -589,6 +604,9 @@ func (le *lessor) Recover(b backend.Backend, rd RangeDeleter) {
 
 	le.b = b
 	le.rd = rd
+	for _, l := range le.leaseMap {
+		leaseActive.WithLabelValues(activeLeaseTTLLabel(l.ttl)).Dec()
+	}
 	le.leaseMap = make(map[LeaseID]*Lease)
 	le.itemMap = make(map[LeaseItem]LeaseID)
 	le.initAndRecover()
@@ -637,6 +655,7 @@ func (le *lessor) revokeExpiredLeases() {
 		case <-le.stopC:
 			return
 		case le.expiredC <- ls:
+			leaseExpired.Add(float64(len(ls)))
 		default:
 			// the receiver of expiredC is probably busy handling
 			// other stuff
@@ -660,6 +679,7 @@ func (le *lessor) checkpointScheduledLeases() {
 
 		if len(cps) != 0 {
 			le.cp(context.Background(), &pb.LeaseCheckpointRequest{Checkpoints: cps})
+			leaseCheckpointSubmitted.Add(float64(len(cps)))
 		}
 		if len(cps) < maxLeaseCheckpointBatchSize {
 			return
@@ -811,6 +831,7 @@ func (le *lessor) initAndRecover() {
 			revokec:      make(chan struct{}),
 			remainingTTL: lpb.RemainingTTL,
 		}
+		leaseActive.WithLabelValues(activeLeaseTTLLabel(lpb.TTL)).Inc()
 	}
 	le.leaseExpiredNotifier.Init()
 	heap.Init(&le.leaseCheckpointHeap)
@@ -26,6 +26,8 @@ import (
 	"time"
 
 	"github.com/coreos/go-semver/semver"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/version"
 	"go.etcd.io/etcd/server/v3/storage/backend"
@@ -582,6 +584,87 @@ func TestLessorCheckpointsRestoredOnPromote(t *testing.T) {
 	}
 }
 
+// TestLessorCheckpointsCountedDownOnPromote ensures the remaining TTL checkpointed is counted down
+// from the time the checkpoint was applied, not to jump back on leader change.
+func TestLessorCheckpointsCountedDownOnPromote(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	le := newLessor(lg, be, clusterLatest(), LessorConfig{MinLeaseTTL: minLeaseTTL})
+	defer le.Stop()
+	l, err := le.Grant(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	le.Checkpoint(l.ID, 50)
+	// the checkpoint was applied 20 seconds before the member is promoted
+	le.mu.Lock()
+	l.checkpointed = l.checkpointed.Add(-20 * time.Second)
+	le.mu.Unlock()
+	le.Promote(0)
+	remaining := l.Remaining().Seconds()
+	if !(remaining > 29 && remaining <= 30) {
+		t.Fatalf("expected expiry in 30s, but got %f seconds", remaining)
+	}
+}
+
+// TestLessorCheckpointInterval ensures the checkpoints of a lease are applied at the configured
+// interval, observing their lag.
+func TestLessorCheckpointInterval(t *testing.T) {
+	lg := zap.NewNop()
+	dir, be := NewTestBackend(t)
+	defer os.RemoveAll(dir)
+	defer be.Close()
+
+	le := newLessor(lg, be, clusterLatest(), LessorConfig{MinLeaseTTL: minLeaseTTL, CheckpointInterval: 1 * time.Second})
+	defer le.Stop()
+	le.minLeaseTTL = 1
+	count, sum := readHistogram(leaseCheckpointLag)
+
+	checkpointedC := make(chan int64, 10)
+	le.SetCheckpointer(func(ctx context.Context, lc *pb.LeaseCheckpointRequest) {
+		for _, c := range lc.Checkpoints {
+			le.Checkpoint(LeaseID(c.ID), c.Remaining_TTL)
+			checkpointedC <- c.Remaining_TTL
+		}
+	})
+	if _, err := le.Grant(1, 10); err != nil {
+		t.Fatal(err)
+	}
+	le.Promote(0)
+
+	var remainingTTLs []int64
+	for len(remainingTTLs) < 2 {
+		select {
+		case remainingTTL := <-checkpointedC:
+			remainingTTLs = append(remainingTTLs, remainingTTL)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("expected 2 checkpoints, got %v", remainingTTLs)
+		}
+	}
+	if remainingTTLs[0] != 9 || remainingTTLs[1] != 8 {
+		t.Errorf("expected checkpoints with Remaining_TTL 9 and 8, got %v", remainingTTLs)
+	}
+
+	newCount, newSum := readHistogram(leaseCheckpointLag)
+	if newCount-count != 2 {
+		t.Fatalf("expected 2 checkpoint lags observed, got %d", newCount-count)
+	}
+	if lag := (newSum - sum) / 2; lag < 0.9 || lag > 2 {
+		t.Errorf("expected a checkpoint lag of the interval, 1s, got %fs", lag)
+	}
+}
+
+func readHistogram(h prometheus.Histogram) (uint64, float64) {
+	m := &dto.Metric{}
+	if err := h.Write(m); err != nil {
+		panic(err)
+	}
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
 func TestLessorCheckpointPersistenceAfterRestart(t *testing.T) {
 	const ttl int64 = 10
 	const checkpointTTL int64 = 5
@@ -696,3 +779,22 @@ type fakeCluster struct {
 func (c fakeCluster) Version() *semver.Version {
 	return c.version
 }
+
+func TestActiveLeaseTTLLabel(t *testing.T) {
+	tests := []struct {
+		ttl  int64
+		want string
+	}{
+		{ttl: 1, want: "10"},
+		{ttl: 10, want: "10"},
+		{ttl: 11, want: "60"},
+		{ttl: 3600, want: "3600"},
+		{ttl: 86400, want: "86400"},
+		{ttl: MaxLeaseTTL, want: "+Inf"},
+	}
+	for _, tt := range tests {
+		if got := activeLeaseTTLLabel(tt.ttl); got != tt.want {
+			t.Errorf("activeLeaseTTLLabel(%d) = %q, want %q", tt.ttl, got, tt.want)
+		}
+	}
+}
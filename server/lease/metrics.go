@@ -15,9 +15,15 @@
 package lease
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// activeLeaseTTLBuckets are the upper bounds in seconds of the "ttl" label
+// of the active lease gauge. Leases with larger TTLs are reported as "+Inf".
+var activeLeaseTTLBuckets = []int64{10, 60, 300, 3600, 86400}
+
 var (
 	leaseGranted = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "etcd_debugging",
@@ -49,6 +55,57 @@ var (
 			// 1 second -> 3 months
 			Buckets: prometheus.ExponentialBuckets(1, 2, 24),
 		})
+
+	leaseActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "active",
+		Help:      "The number of active leases, bucketed by granted TTL in seconds.",
+	},
+		[]string{"ttl"})
+
+	leaseExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "expired_total",
+		Help:      "The total number of expired leases submitted for revocation by the leader.",
+	})
+
+	leaseRenewDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "renew_duration_seconds",
+		Help:      "Bucketed histogram of lease renewal processing time on the leader.",
+
+		// lowest bucket start of upper bound 0.0001 sec (0.1 ms) with factor 2
+		// highest bucket start of 0.0001 sec * 2^15 == 3.2768 sec
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 16),
+	})
+
+	leaseCheckpointSubmitted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "checkpoint_submitted_total",
+		Help:      "The total number of lease checkpoints submitted to the consensus log by the leader.",
+	})
+
+	leaseCheckpointApplied = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "checkpoint_applied_total",
+		Help:      "The total number of lease checkpoints applied.",
+	})
+
+	leaseCheckpointLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "etcd_debugging",
+		Subsystem: "lease",
+		Name:      "checkpoint_lag_seconds",
+		Help: "Bucketed histogram of the time between the grant or the previous checkpoint of a lease and its checkpoint " +
+			"applied on the member, which its remaining TTL jumps back by at most on restart.",
+
+		// 0.5 second -> 68 minutes
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 14),
+	})
 )
 
 func init() {
@@ -56,4 +113,21 @@ func init() {
 	prometheus.MustRegister(leaseRevoked)
 	prometheus.MustRegister(leaseRenewed)
 	prometheus.MustRegister(leaseTotalTTLs)
+	prometheus.MustRegister(leaseActive)
+	prometheus.MustRegister(leaseExpired)
+	prometheus.MustRegister(leaseRenewDuration)
+	prometheus.MustRegister(leaseCheckpointSubmitted)
+	prometheus.MustRegister(leaseCheckpointApplied)
+	prometheus.MustRegister(leaseCheckpointLag)
+}
+
+// activeLeaseTTLLabel returns the "ttl" label value of the active lease
+// gauge for the given TTL.
+func activeLeaseTTLLabel(ttl int64) string {
+	for _, b := range activeLeaseTTLBuckets {
+		if ttl <= b {
+			return strconv.FormatInt(b, 10)
+		}
+	}
+	return "+Inf"
 }
@@ -39,6 +39,11 @@ func (p *storageRecorder) Save(st raftpb.HardState, ents []raftpb.Entry) error {
 	return nil
 }
 
+func (p *storageRecorder) SaveNoSync(st raftpb.HardState, ents []raftpb.Entry) (bool, error) {
+	p.Record(testutil.Action{Name: "SaveNoSync"})
+	return !raft.IsEmptyHardState(st) || len(ents) != 0, nil
+}
+
 func (p *storageRecorder) SaveSnap(st raftpb.Snapshot) error {
 	if !raft.IsEmptySnap(st) {
 		p.Record(testutil.Action{Name: "SaveSnap"})
@@ -48,6 +48,34 @@ func (s *mts2mtc) HashKV(ctx context.Context, r *pb.HashKVRequest, opts ...grpc.
 	return s.mts.HashKV(ctx, r)
 }
 
+func (s *mts2mtc) DefragmentEstimate(ctx context.Context, r *pb.DefragmentEstimateRequest, opts ...grpc.CallOption) (*pb.DefragmentEstimateResponse, error) {
+	return s.mts.DefragmentEstimate(ctx, r)
+}
+
+func (s *mts2mtc) RangeEstimate(ctx context.Context, r *pb.RangeEstimateRequest, opts ...grpc.CallOption) (*pb.RangeEstimateResponse, error) {
+	return s.mts.RangeEstimate(ctx, r)
+}
+
+func (s *mts2mtc) Events(ctx context.Context, r *pb.EventsRequest, opts ...grpc.CallOption) (*pb.EventsResponse, error) {
+	return s.mts.Events(ctx, r)
+}
+
+func (s *mts2mtc) FeatureGates(ctx context.Context, r *pb.FeatureGatesRequest, opts ...grpc.CallOption) (*pb.FeatureGatesResponse, error) {
+	return s.mts.FeatureGates(ctx, r)
+}
+
+func (s *mts2mtc) PrefixStats(ctx context.Context, r *pb.PrefixStatsRequest, opts ...grpc.CallOption) (*pb.PrefixStatsResponse, error) {
+	return s.mts.PrefixStats(ctx, r)
+}
+
+func (s *mts2mtc) LogLevel(ctx context.Context, r *pb.LogLevelRequest, opts ...grpc.CallOption) (*pb.LogLevelResponse, error) {
+	return s.mts.LogLevel(ctx, r)
+}
+
+func (s *mts2mtc) LogRange(ctx context.Context, r *pb.LogRangeRequest, opts ...grpc.CallOption) (*pb.LogRangeResponse, error) {
+	return s.mts.LogRange(ctx, r)
+}
+
 func (s *mts2mtc) MoveLeader(ctx context.Context, r *pb.MoveLeaderRequest, opts ...grpc.CallOption) (*pb.MoveLeaderResponse, error) {
 	return s.mts.MoveLeader(ctx, r)
 }
@@ -90,3 +118,38 @@ func (s *ss2scServerStream) Recv() (*pb.SnapshotRequest, error) {
 	}
 	return v.(*pb.SnapshotRequest), nil
 }
+
+func (s *mts2mtc) SnapshotDelta(ctx context.Context, in *pb.SnapshotDeltaRequest, opts ...grpc.CallOption) (pb.Maintenance_SnapshotDeltaClient, error) {
+	cs := newPipeStream(ctx, func(ss chanServerStream) error {
+		return s.mts.SnapshotDelta(in, &sds2sdcServerStream{ss})
+	})
+	return &sds2sdcClientStream{cs}, nil
+}
+
+// sds2sdcClientStream implements Maintenance_SnapshotDeltaClient
+type sds2sdcClientStream struct{ chanClientStream }
+
+// sds2sdcServerStream implements Maintenance_SnapshotDeltaServer
+type sds2sdcServerStream struct{ chanServerStream }
+
+func (s *sds2sdcClientStream) Send(rr *pb.SnapshotDeltaRequest) error {
+	return s.SendMsg(rr)
+}
+func (s *sds2sdcClientStream) Recv() (*pb.SnapshotDeltaResponse, error) {
+	var v interface{}
+	if err := s.RecvMsg(&v); err != nil {
+		return nil, err
+	}
+	return v.(*pb.SnapshotDeltaResponse), nil
+}
+
+func (s *sds2sdcServerStream) Send(rr *pb.SnapshotDeltaResponse) error {
+	return s.SendMsg(rr)
+}
+func (s *sds2sdcServerStream) Recv() (*pb.SnapshotDeltaRequest, error) {
+	var v interface{}
+	if err := s.RecvMsg(&v); err != nil {
+		return nil, err
+	}
+	return v.(*pb.SnapshotDeltaRequest), nil
+}
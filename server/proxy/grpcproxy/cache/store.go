@@ -35,7 +35,8 @@ type Cache interface {
 	Add(req *pb.RangeRequest, resp *pb.RangeResponse)
 	Get(req *pb.RangeRequest) (*pb.RangeResponse, error)
 	Compact(revision int64)
-	Invalidate(key []byte, endkey []byte)
+	Invalidate(key []byte, endkey []byte) int
+	Flush(key []byte, endkey []byte) int
 	Size() int
 	Close()
 }
@@ -128,11 +129,16 @@ func (c *cache) Get(req *pb.RangeRequest) (*pb.RangeResponse, error) {
 	return nil, errors.New("not exist")
 }
 
-// Invalidate invalidates the cache entries that intersecting with the given range from key to endkey.
-func (c *cache) Invalidate(key, endkey []byte) {
+// Invalidate invalidates the cache entries that intersecting with the given range from key to endkey,
+// returning the number of entries removed.
+func (c *cache) Invalidate(key, endkey []byte) int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.invalidate(key, endkey)
+}
 
+func (c *cache) invalidate(key, endkey []byte) int {
+	n := c.lru.Len()
 	var (
 		ivs []*adt.IntervalValue
 		ivl adt.Interval
@@ -152,6 +158,25 @@ func (c *cache) Invalidate(key, endkey []byte) {
 	}
 	// delete after removing all keys since it is destructive to 'ivs'
 	c.cachedRanges.Delete(ivl)
+	return n - c.lru.Len()
+}
+
+// Flush invalidates the cache entries intersecting with the given range from
+// key to endkey like Invalidate, or all the cache entries if key is empty,
+// returning the number of entries removed. The entries of the requests with
+// a revision specified stay unless flushing all the entries, as they should
+// never be stale.
+func (c *cache) Flush(key, endkey []byte) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(key) != 0 {
+		return c.invalidate(key, endkey)
+	}
+	n := c.lru.Len()
+	c.lru.Clear()
+	c.cachedRanges = adt.NewIntervalTree()
+	return n
 }
 
 // Compact invalidate all caching response before the given rev.
@@ -0,0 +1,206 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/proxy/grpcproxy/cache"
+)
+
+const (
+	// PathProxyCache is the path of the cache statistics of the proxy.
+	PathProxyCache = "/proxy/cache"
+	// PathProxyCacheFlush is the path flushing the cache of the proxy.
+	PathProxyCacheFlush = "/proxy/cache/flush"
+)
+
+// CacheAdmin tracks the hits and misses of the cache of a kv proxy under the
+// given key prefixes and serves its admin API.
+type CacheAdmin struct {
+	// prefixes are the tracked key prefixes, longest first.
+	prefixes []string
+
+	mu     sync.Mutex
+	cache  cache.Cache
+	hits   map[string]uint64
+	misses map[string]uint64
+}
+
+// NewCacheAdmin returns the cache admin tracking the hits and misses under
+// each of the key prefixes, to pass to NewKvProxyWithCacheAdmin.
+func NewCacheAdmin(prefixes []string) *CacheAdmin {
+	ps := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p != "" {
+			ps = append(ps, p)
+		}
+	}
+	sort.Slice(ps, func(i, j int) bool { return len(ps[i]) > len(ps[j]) })
+	return &CacheAdmin{
+		prefixes: ps,
+		hits:     make(map[string]uint64),
+		misses:   make(map[string]uint64),
+	}
+}
+
+// prefixOf returns the longest tracked prefix of key, or "" if none.
+func (a *CacheAdmin) prefixOf(key []byte) string {
+	for _, p := range a.prefixes {
+		if strings.HasPrefix(string(key), p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// observe counts a lookup of the range from key in the cache.
+func (a *CacheAdmin) observe(key []byte, hit bool) {
+	if hit {
+		cacheHits.Inc()
+	} else {
+		cachedMisses.Inc()
+	}
+	if a == nil {
+		return
+	}
+	p := a.prefixOf(key)
+	a.mu.Lock()
+	if hit {
+		a.hits[p]++
+	} else {
+		a.misses[p]++
+	}
+	a.mu.Unlock()
+	if hit {
+		cacheRequests.WithLabelValues(p, "hit").Inc()
+	} else {
+		cacheRequests.WithLabelValues(p, "miss").Inc()
+	}
+}
+
+func (a *CacheAdmin) setCache(c cache.Cache) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.cache = c
+	a.mu.Unlock()
+}
+
+func (a *CacheAdmin) getCache() cache.Cache {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cache
+}
+
+// CachePrefixStats is the hits and misses of the cache under a key prefix.
+type CachePrefixStats struct {
+	Prefix string `json:"prefix"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// CacheStats is the statistics of the cache of the proxy, served on
+// PathProxyCache.
+type CacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	// Prefixes is the hits and misses under each tracked prefix, and under
+	// none of them for the empty prefix.
+	Prefixes []CachePrefixStats `json:"prefixes"`
+}
+
+// CacheFlushResponse is the response of PathProxyCacheFlush.
+type CacheFlushResponse struct {
+	Flushed int `json:"flushed"`
+}
+
+// Stats returns the statistics of the cache.
+func (a *CacheAdmin) Stats() CacheStats {
+	var st CacheStats
+	if c := a.getCache(); c != nil {
+		st.Entries = c.Size()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range append([]string{""}, a.prefixes...) {
+		ps := CachePrefixStats{Prefix: p, Hits: a.hits[p], Misses: a.misses[p]}
+		st.Hits += ps.Hits
+		st.Misses += ps.Misses
+		st.Prefixes = append(st.Prefixes, ps)
+	}
+	sort.Slice(st.Prefixes, func(i, j int) bool { return st.Prefixes[i].Prefix < st.Prefixes[j].Prefix })
+	return st
+}
+
+// Flush flushes the cache entries of the ranges intersecting with the keys
+// under prefix, or all the cache entries if prefix is empty, returning the
+// number of entries removed.
+func (a *CacheAdmin) Flush(prefix string) int {
+	c := a.getCache()
+	if c == nil {
+		return 0
+	}
+	var n int
+	if prefix == "" {
+		n = c.Flush(nil, nil)
+	} else {
+		n = c.Flush([]byte(prefix), []byte(clientv3.GetPrefixRangeEnd(prefix)))
+	}
+	cacheInvalidatedEntries.WithLabelValues("flush").Add(float64(n))
+	cacheKeys.Set(float64(c.Size()))
+	return n
+}
+
+// HandleCacheAdmin registers the cache admin handlers on '/proxy/cache', a GET
+// returning the cache statistics, and on '/proxy/cache/flush', a POST flushing
+// the cache for the key prefix given by the "prefix" query parameter, or the
+// whole cache if not given.
+func HandleCacheAdmin(mux *http.ServeMux, a *CacheAdmin) {
+	mux.HandleFunc(PathProxyCache, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeCacheAdminJSON(w, a.Stats())
+	})
+	mux.HandleFunc(PathProxyCacheFlush, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeCacheAdminJSON(w, CacheFlushResponse{Flushed: a.Flush(r.URL.Query().Get("prefix"))})
+	})
+}
+
+func writeCacheAdminJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -175,3 +175,8 @@ func (cp *clusterProxy) MemberPromote(ctx context.Context, r *pb.MemberPromoteRe
 	// TODO: implement
 	return nil, errors.New("not implemented")
 }
+
+func (cp *clusterProxy) MemberDemote(ctx context.Context, r *pb.MemberDemoteRequest) (*pb.MemberDemoteResponse, error) {
+	// TODO: implement
+	return nil, errors.New("not implemented")
+}
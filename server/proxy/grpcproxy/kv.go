@@ -19,39 +19,56 @@ import (
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3rpc"
 	"go.etcd.io/etcd/server/v3/proxy/grpcproxy/cache"
 )
 
 type kvProxy struct {
 	kv    clientv3.KV
 	cache cache.Cache
+	admin *CacheAdmin
 }
 
 func NewKvProxy(c *clientv3.Client) (pb.KVServer, <-chan struct{}) {
+	return NewKvProxyWithCacheAdmin(c, nil)
+}
+
+// NewKvProxyWithCacheAdmin returns the kv proxy like NewKvProxy, with its
+// cache tracked and administered by admin if not nil.
+func NewKvProxyWithCacheAdmin(c *clientv3.Client, admin *CacheAdmin) (pb.KVServer, <-chan struct{}) {
 	kv := &kvProxy{
 		kv:    c.KV,
 		cache: cache.NewCache(cache.DefaultMaxEntries),
+		admin: admin,
 	}
+	admin.setCache(kv.cache)
 	donec := make(chan struct{})
 	close(donec)
 	return kv, donec
 }
 
 func (p *kvProxy) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
-	if r.Serializable {
+	// the ranges registering a compaction barrier are not served from the
+	// cache, for the barrier to be registered.
+	minRev := v3rpc.MinRevisionFromContext(ctx)
+	if r.Serializable && !r.CompactionBarrier {
 		resp, err := p.cache.Get(r)
-		switch err {
-		case nil:
-			cacheHits.Inc()
+		switch {
+		case err == nil && resp.Header.Revision >= minRev && resp.Header.Revision >= r.MinRevisionWait:
+			p.admin.observe(r.Key, true)
 			return resp, nil
-		case cache.ErrCompacted:
-			cacheHits.Inc()
+		case err == cache.ErrCompacted:
+			p.admin.observe(r.Key, true)
 			return nil, err
 		}
 
-		cachedMisses.Inc()
+		p.admin.observe(r.Key, false)
 	}
 
+	if minRev > 0 {
+		// forward the revision for the member serving the range to wait for it
+		ctx = clientv3.WithMinRevision(ctx, minRev)
+	}
 	resp, err := p.kv.Do(ctx, RangeRequestToOp(r))
 	if err != nil {
 		return nil, err
@@ -68,7 +85,7 @@ func (p *kvProxy) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeRespo
 }
 
 func (p *kvProxy) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
-	p.cache.Invalidate(r.Key, nil)
+	p.invalidate(r.Key, nil)
 	cacheKeys.Set(float64(p.cache.Size()))
 
 	resp, err := p.kv.Do(ctx, PutRequestToOp(r))
@@ -76,21 +93,31 @@ func (p *kvProxy) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, e
 }
 
 func (p *kvProxy) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
-	p.cache.Invalidate(r.Key, r.RangeEnd)
+	p.invalidate(r.Key, r.RangeEnd)
 	cacheKeys.Set(float64(p.cache.Size()))
 
 	resp, err := p.kv.Do(ctx, DelRequestToOp(r))
 	return (*pb.DeleteRangeResponse)(resp.Del()), err
 }
 
+// invalidate invalidates the cache entries of the ranges intersecting with the
+// written range from key to endkey.
+func (p *kvProxy) invalidate(key, endkey []byte) {
+	if n := p.cache.Invalidate(key, endkey); n > 0 {
+		cacheInvalidatedEntries.WithLabelValues("write").Add(float64(n))
+	}
+}
+
 func (p *kvProxy) txnToCache(reqs []*pb.RequestOp, resps []*pb.ResponseOp) {
 	for i := range resps {
 		switch tv := resps[i].Response.(type) {
 		case *pb.ResponseOp_ResponsePut:
-			p.cache.Invalidate(reqs[i].GetRequestPut().Key, nil)
+			p.invalidate(reqs[i].GetRequestPut().Key, nil)
+		case *pb.ResponseOp_ResponseIncrement:
+			p.invalidate(reqs[i].GetRequestIncrement().Key, nil)
 		case *pb.ResponseOp_ResponseDeleteRange:
 			rdr := reqs[i].GetRequestDeleteRange()
-			p.cache.Invalidate(rdr.Key, rdr.RangeEnd)
+			p.invalidate(rdr.Key, rdr.RangeEnd)
 		case *pb.ResponseOp_ResponseRange:
 			req := *(reqs[i].GetRequestRange())
 			req.Serializable = true
@@ -109,7 +136,7 @@ func (p *kvProxy) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, e
 
 	// txn may claim an outdated key is updated; be safe and invalidate
 	for _, cmp := range r.Compare {
-		p.cache.Invalidate(cmp.Key, cmp.RangeEnd)
+		p.invalidate(cmp.Key, cmp.RangeEnd)
 	}
 	// update any fetched keys
 	if resp.Succeeded {
@@ -157,6 +184,11 @@ func requestOpToOp(union *pb.RequestOp) clientv3.Op {
 		if tv.RequestTxn != nil {
 			return TxnRequestToOp(tv.RequestTxn)
 		}
+	case *pb.RequestOp_RequestIncrement:
+		if tv.RequestIncrement != nil {
+			r := tv.RequestIncrement
+			return clientv3.OpIncrement(string(r.Key), r.Delta, clientv3.WithLease(clientv3.LeaseID(r.Lease)))
+		}
 	}
 	panic("unknown request")
 }
@@ -185,6 +217,12 @@ func RangeRequestToOp(r *pb.RangeRequest) clientv3.Op {
 	if r.Serializable {
 		opts = append(opts, clientv3.WithSerializable())
 	}
+	if r.CompactionBarrier {
+		opts = append(opts, clientv3.WithCompactionBarrier())
+	}
+	if r.MinRevisionWait > 0 {
+		opts = append(opts, clientv3.WithMinRevisionWait(r.MinRevisionWait))
+	}
 
 	return clientv3.OpGet(string(r.Key), opts...)
 }
@@ -212,6 +250,9 @@ func DelRequestToOp(r *pb.DeleteRangeRequest) clientv3.Op {
 	if r.PrevKv {
 		opts = append(opts, clientv3.WithPrevKV())
 	}
+	if r.Paced {
+		opts = append(opts, clientv3.WithPaced())
+	}
 	return clientv3.OpDelete(string(r.Key), opts...)
 }
 
@@ -62,6 +62,32 @@ func (mp *maintenanceProxy) Snapshot(sr *pb.SnapshotRequest, stream pb.Maintenan
 	}
 }
 
+func (mp *maintenanceProxy) SnapshotDelta(sr *pb.SnapshotDeltaRequest, stream pb.Maintenance_SnapshotDeltaServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	ctx = withClientAuthToken(ctx, stream.Context())
+
+	sc, err := mp.maintenanceClient.SnapshotDelta(ctx, sr)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rr, err := sc.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		err = stream.Send(rr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
 func (mp *maintenanceProxy) Hash(ctx context.Context, r *pb.HashRequest) (*pb.HashResponse, error) {
 	return mp.maintenanceClient.Hash(ctx, r)
 }
@@ -70,6 +96,14 @@ func (mp *maintenanceProxy) HashKV(ctx context.Context, r *pb.HashKVRequest) (*p
 	return mp.maintenanceClient.HashKV(ctx, r)
 }
 
+func (mp *maintenanceProxy) DefragmentEstimate(ctx context.Context, r *pb.DefragmentEstimateRequest) (*pb.DefragmentEstimateResponse, error) {
+	return mp.maintenanceClient.DefragmentEstimate(ctx, r)
+}
+
+func (mp *maintenanceProxy) RangeEstimate(ctx context.Context, r *pb.RangeEstimateRequest) (*pb.RangeEstimateResponse, error) {
+	return mp.maintenanceClient.RangeEstimate(ctx, r)
+}
+
 func (mp *maintenanceProxy) Alarm(ctx context.Context, r *pb.AlarmRequest) (*pb.AlarmResponse, error) {
 	return mp.maintenanceClient.Alarm(ctx, r)
 }
@@ -78,6 +112,26 @@ func (mp *maintenanceProxy) Status(ctx context.Context, r *pb.StatusRequest) (*p
 	return mp.maintenanceClient.Status(ctx, r)
 }
 
+func (mp *maintenanceProxy) Events(ctx context.Context, r *pb.EventsRequest) (*pb.EventsResponse, error) {
+	return mp.maintenanceClient.Events(ctx, r)
+}
+
+func (mp *maintenanceProxy) FeatureGates(ctx context.Context, r *pb.FeatureGatesRequest) (*pb.FeatureGatesResponse, error) {
+	return mp.maintenanceClient.FeatureGates(ctx, r)
+}
+
+func (mp *maintenanceProxy) PrefixStats(ctx context.Context, r *pb.PrefixStatsRequest) (*pb.PrefixStatsResponse, error) {
+	return mp.maintenanceClient.PrefixStats(ctx, r)
+}
+
+func (mp *maintenanceProxy) LogLevel(ctx context.Context, r *pb.LogLevelRequest) (*pb.LogLevelResponse, error) {
+	return mp.maintenanceClient.LogLevel(ctx, r)
+}
+
+func (mp *maintenanceProxy) LogRange(ctx context.Context, r *pb.LogRangeRequest) (*pb.LogRangeResponse, error) {
+	return mp.maintenanceClient.LogRange(ctx, r)
+}
+
 func (mp *maintenanceProxy) MoveLeader(ctx context.Context, r *pb.MoveLeaderRequest) (*pb.MoveLeaderResponse, error) {
 	return mp.maintenanceClient.MoveLeader(ctx, r)
 }
@@ -58,6 +58,18 @@ var (
 		Name:      "cache_misses_total",
 		Help:      "Total number of cache misses",
 	})
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "grpc_proxy",
+		Name:      "cache_requests_total",
+		Help:      "Total number of serializable range requests looked up in the cache by tracked key prefix, empty for the other keys, and result (hit or miss).",
+	}, []string{"prefix", "result"})
+	cacheInvalidatedEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "grpc_proxy",
+		Name:      "cache_invalidated_entries_total",
+		Help:      "Total number of cache entries invalidated by reason (write or flush).",
+	}, []string{"reason"})
 )
 
 func init() {
@@ -66,6 +78,8 @@ func init() {
 	prometheus.MustRegister(cacheKeys)
 	prometheus.MustRegister(cacheHits)
 	prometheus.MustRegister(cachedMisses)
+	prometheus.MustRegister(cacheRequests)
+	prometheus.MustRegister(cacheInvalidatedEntries)
 }
 
 // HandleMetrics performs a GET request against etcd endpoint and returns '/metrics'.
@@ -0,0 +1,252 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcproxy
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
+
+	"github.com/soheilhy/cmux"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TenantFunc returns the tenant of the client of the request context ctx,
+// or false if the client has none.
+type TenantFunc func(ctx context.Context) (string, bool)
+
+// TenantFromCertCN is the TenantFunc returning the common name of the
+// verified client certificate, requiring the gRPC server to be created
+// with the TenantCredentials.
+func TenantFromCertCN(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName, true
+}
+
+// TenantFromHeader returns the TenantFunc returning the value of the request
+// metadata key. The tenant is not authenticated, the proxy should only be
+// reachable through a trusted gateway setting the header.
+func TenantFromHeader(key string) TenantFunc {
+	return func(ctx context.Context) (string, bool) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return "", false
+		}
+		vs := md.Get(key)
+		if len(vs) == 0 {
+			return "", false
+		}
+		return vs[0], true
+	}
+}
+
+// TenantCredentials returns the gRPC server credentials exposing the TLS
+// state of the connections accepted from a TLS listener, directly or through
+// cmux, for TenantFromCertCN. The TLS handshake is left to the listener.
+func TenantCredentials() credentials.TransportCredentials {
+	return &tenantCredentials{TransportCredentials: insecure.NewCredentials()}
+}
+
+type tenantCredentials struct {
+	credentials.TransportCredentials
+}
+
+func (tc *tenantCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	c := conn
+	if mc, ok := c.(*cmux.MuxConn); ok {
+		c = mc.Conn
+	}
+	tlsConn, ok := c.(*tls.Conn)
+	if !ok {
+		return tc.TransportCredentials.ServerHandshake(conn)
+	}
+	return conn, credentials.TLSInfo{
+		State:          tlsConn.ConnectionState(),
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+	}, nil
+}
+
+func (tc *tenantCredentials) Clone() credentials.TransportCredentials {
+	return &tenantCredentials{TransportCredentials: tc.TransportCredentials.Clone()}
+}
+
+// TenantProxy is the kv, watch and lease proxy confining each tenant to the
+// keys under its own namespace, the prefix followed by the tenant and "/".
+// The proxies of a tenant are created on its first request, the watches
+// being coalesced per tenant only.
+type TenantProxy struct {
+	lg     *zap.Logger
+	c      *clientv3.Client
+	prefix string
+	tenant TenantFunc
+
+	mu      sync.Mutex
+	proxies map[string]*tenantProxies
+}
+
+type tenantProxies struct {
+	kv    pb.KVServer
+	watch pb.WatchServer
+	lease pb.LeaseServer
+}
+
+// NewTenantProxy returns the tenant proxy forwarding the requests through c,
+// with the tenant of each request returned by tenant.
+func NewTenantProxy(lg *zap.Logger, c *clientv3.Client, prefix string, tenant TenantFunc) *TenantProxy {
+	return &TenantProxy{
+		lg:      lg,
+		c:       c,
+		prefix:  prefix,
+		tenant:  tenant,
+		proxies: make(map[string]*tenantProxies),
+	}
+}
+
+func (tp *TenantProxy) proxiesOf(ctx context.Context) (*tenantProxies, error) {
+	t, ok := tp.tenant(ctx)
+	if !ok || t == "" {
+		return nil, status.Error(codes.Unauthenticated, "grpcproxy: no tenant for the request")
+	}
+	if strings.Contains(t, "/") {
+		return nil, status.Errorf(codes.PermissionDenied, "grpcproxy: invalid tenant %q", t)
+	}
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if ps, ok := tp.proxies[t]; ok {
+		return ps, nil
+	}
+	ns := tp.prefix + t + "/"
+	// the proxies of the tenant share the connection of c, only its kv,
+	// watcher and lessor being namespaced.
+	tc := *tp.c
+	tc.KV = namespace.NewKV(tp.c.KV, ns)
+	tc.Watcher = namespace.NewWatcher(tp.c.Watcher, ns)
+	tc.Lease = namespace.NewLease(tp.c.Lease, ns)
+	ps := &tenantProxies{}
+	ps.kv, _ = NewKvProxy(&tc)
+	ps.watch, _ = NewWatchProxy(tp.c.Ctx(), tp.lg, &tc)
+	ps.lease, _ = NewLeaseProxy(tp.c.Ctx(), &tc)
+	tp.proxies[t] = ps
+	tp.lg.Info("created tenant proxies", zap.String("tenant", t), zap.String("namespace", ns))
+	return ps, nil
+}
+
+func (tp *TenantProxy) Range(ctx context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.kv.Range(ctx, r)
+}
+
+func (tp *TenantProxy) Put(ctx context.Context, r *pb.PutRequest) (*pb.PutResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.kv.Put(ctx, r)
+}
+
+func (tp *TenantProxy) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.kv.DeleteRange(ctx, r)
+}
+
+func (tp *TenantProxy) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.kv.Txn(ctx, r)
+}
+
+func (tp *TenantProxy) Compact(ctx context.Context, r *pb.CompactionRequest) (*pb.CompactionResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.kv.Compact(ctx, r)
+}
+
+func (tp *TenantProxy) Watch(stream pb.Watch_WatchServer) error {
+	ps, err := tp.proxiesOf(stream.Context())
+	if err != nil {
+		return err
+	}
+	return ps.watch.Watch(stream)
+}
+
+func (tp *TenantProxy) LeaseGrant(ctx context.Context, r *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.lease.LeaseGrant(ctx, r)
+}
+
+func (tp *TenantProxy) LeaseRevoke(ctx context.Context, r *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.lease.LeaseRevoke(ctx, r)
+}
+
+func (tp *TenantProxy) LeaseKeepAlive(stream pb.Lease_LeaseKeepAliveServer) error {
+	ps, err := tp.proxiesOf(stream.Context())
+	if err != nil {
+		return err
+	}
+	return ps.lease.LeaseKeepAlive(stream)
+}
+
+func (tp *TenantProxy) LeaseTimeToLive(ctx context.Context, r *pb.LeaseTimeToLiveRequest) (*pb.LeaseTimeToLiveResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.lease.LeaseTimeToLive(ctx, r)
+}
+
+func (tp *TenantProxy) LeaseLeases(ctx context.Context, r *pb.LeaseLeasesRequest) (*pb.LeaseLeasesResponse, error) {
+	ps, err := tp.proxiesOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ps.lease.LeaseLeases(ctx, r)
+}
@@ -22,6 +22,7 @@ import (
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3rpc"
+	"go.etcd.io/etcd/server/v3/storage/mvcc"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -235,7 +236,12 @@ func (wps *watchProxyStream) recvLoop() error {
 		case *pb.WatchRequest_CreateRequest:
 			cr := uv.CreateRequest
 
-			if err := wps.checkPermissionForWatch(cr.Key, cr.RangeEnd); err != nil {
+			var filters []mvcc.FilterFunc
+			err := wps.checkPermissionForWatch(cr.Key, cr.RangeEnd)
+			if err == nil {
+				filters, err = v3rpc.FiltersFromRequest(cr)
+			}
+			if err != nil {
 				wps.watchCh <- &pb.WatchResponse{
 					Header:       &pb.ResponseHeader{},
 					WatchId:      -1,
@@ -255,7 +261,7 @@ func (wps *watchProxyStream) recvLoop() error {
 				nextrev:  cr.StartRevision,
 				progress: cr.ProgressNotify,
 				prevKV:   cr.PrevKv,
-				filters:  v3rpc.FiltersFromRequest(cr),
+				filters:  filters,
 			}
 			if !w.wr.valid() {
 				w.post(&pb.WatchResponse{WatchId: -1, Created: true, Canceled: true})
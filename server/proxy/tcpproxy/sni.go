@@ -0,0 +1,88 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcpproxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	// routeTimeout is how long the proxy waits for the TLS handshake or the
+	// ClientHello of a connection to route it.
+	routeTimeout = 10 * time.Second
+
+	// recordTypeHandshake is the type of the TLS record of the ClientHello.
+	recordTypeHandshake = 0x16
+)
+
+var errClientHelloRead = errors.New("tcpproxy: ClientHello read")
+
+// peekServerName returns the connection, replaying what was read from conn,
+// and the server name requested by its TLS ClientHello, empty if it is not a
+// TLS connection.
+func peekServerName(conn net.Conn) (net.Conn, string, error) {
+	br := bufio.NewReader(conn)
+	hdr, err := br.Peek(1)
+	if err != nil {
+		return conn, "", err
+	}
+	if hdr[0] != recordTypeHandshake {
+		return &peekedConn{Conn: conn, r: br}, "", nil
+	}
+
+	var (
+		read       bytes.Buffer
+		serverName string
+	)
+	err = tls.Server(readOnlyConn{r: io.TeeReader(br, &read)}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, errClientHelloRead
+		},
+	}).Handshake()
+	if !errors.Is(err, errClientHelloRead) {
+		return conn, "", err
+	}
+	return &peekedConn{Conn: conn, r: io.MultiReader(&read, br)}, serverName, nil
+}
+
+// peekedConn is a connection whose reads start with what was peeked at.
+type peekedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// readOnlyConn is a connection only reading from r, for the TLS server to
+// read the ClientHello without writing to the client.
+type readOnlyConn struct {
+	r io.Reader
+}
+
+func (c readOnlyConn) Read(p []byte) (int, error)         { return c.r.Read(p) }
+func (c readOnlyConn) Write(p []byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (c readOnlyConn) Close() error                       { return nil }
+func (c readOnlyConn) LocalAddr() net.Addr                { return nil }
+func (c readOnlyConn) RemoteAddr() net.Addr               { return nil }
+func (c readOnlyConn) SetDeadline(t time.Time) error      { return nil }
+func (c readOnlyConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c readOnlyConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -15,6 +15,7 @@
 package tcpproxy
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
 	"math/rand"
@@ -45,10 +46,14 @@ func (r *remote) tryReactivate() error {
 		return err
 	}
 	conn.Close()
+	r.activate()
+	return nil
+}
+
+func (r *remote) activate() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.inactive = false
-	return nil
 }
 
 func (r *remote) isActive() bool {
@@ -63,8 +68,51 @@ type TCPProxy struct {
 	Endpoints       []*net.SRV
 	MonitorInterval time.Duration
 
+	// Backends are the clusters the connections are routed to by the TLS
+	// server name (SNI) requested by the clients. The connections requesting
+	// no server name, or none of the backends, are routed to the Endpoints,
+	// or to the backend of an empty server name. They are closed when there
+	// are no such endpoints.
+	Backends []Backend
+	// TLSConfig terminates the TLS connections of the clients if set.
+	// Otherwise the TLS connections are passed through to the endpoints,
+	// routed by the server name of their ClientHello.
+	TLSConfig *tls.Config
+	// HealthCheckInterval is the interval of the health checks of all the
+	// endpoints, deactivating the unhealthy ones and activating the healthy
+	// ones again. If zero, the endpoints are deactivated when failing to
+	// connect to them, and activated again when connected to after the
+	// MonitorInterval.
+	HealthCheckInterval time.Duration
+	// HealthCheck checks the health of an endpoint of a backend connected to
+	// with the TLS config of the backend. It defaults to connecting to it.
+	HealthCheck func(addr string, tlsConfig *tls.Config) error
+
 	donec chan struct{}
 
+	// backends are the backend of the Endpoints, if any, and the Backends.
+	backends []*backend
+	// routes are the backends by their server names.
+	routes map[string]*backend
+}
+
+// Backend is a cluster the proxy routes the connections to.
+type Backend struct {
+	// ServerName is the TLS server name requested by the connections routed
+	// to the backend.
+	ServerName string
+	Endpoints  []*net.SRV
+	// TLSConfig is the config of the TLS connections to the endpoints,
+	// nil if they do not serve TLS. It is used by the health checks, and by
+	// the proxy to connect to the endpoints when terminating the TLS
+	// connections of the clients.
+	TLSConfig *tls.Config
+}
+
+type backend struct {
+	serverName string
+	tlsConfig  *tls.Config
+
 	mu        sync.Mutex // guards the following fields
 	remotes   []*remote
 	pickCount int // for round robin
@@ -91,20 +139,42 @@ func (tp *TCPProxy) Run() error {
 	if tp.MonitorInterval == 0 {
 		tp.MonitorInterval = 5 * time.Minute
 	}
-	for _, srv := range tp.Endpoints {
-		addr := formatAddr(srv.Target, srv.Port)
-		tp.remotes = append(tp.remotes, &remote{srv: srv, addr: addr})
+	if tp.HealthCheck == nil {
+		tp.HealthCheck = tp.dialHealthCheck
 	}
-
-	eps := []string{}
-	for _, ep := range tp.Endpoints {
-		eps = append(eps, fmt.Sprintf("%s:%d", ep.Target, ep.Port))
+	backends := tp.Backends
+	if len(tp.Endpoints) > 0 {
+		backends = append([]Backend{{Endpoints: tp.Endpoints}}, backends...)
+	}
+	tp.routes = make(map[string]*backend)
+	for _, b := range backends {
+		if _, ok := tp.routes[b.ServerName]; ok {
+			return fmt.Errorf("duplicate backend for server name %q", b.ServerName)
+		}
+		be := &backend{serverName: b.ServerName, tlsConfig: b.TLSConfig}
+		for _, srv := range b.Endpoints {
+			addr := formatAddr(srv.Target, srv.Port)
+			be.remotes = append(be.remotes, &remote{srv: srv, addr: addr})
+		}
+		tp.backends = append(tp.backends, be)
+		tp.routes[b.ServerName] = be
 	}
-	if tp.Logger != nil {
-		tp.Logger.Info("ready to proxy client requests", zap.Strings("endpoints", eps))
+
+	for _, b := range tp.backends {
+		eps := []string{}
+		for _, r := range b.remotes {
+			eps = append(eps, fmt.Sprintf("%s:%d", r.srv.Target, r.srv.Port))
+		}
+		if tp.Logger != nil {
+			tp.Logger.Info("ready to proxy client requests", zap.String("server-name", b.serverName), zap.Strings("endpoints", eps))
+		}
 	}
 
-	go tp.runMonitor()
+	if tp.HealthCheckInterval > 0 {
+		go tp.runHealthChecks()
+	} else {
+		go tp.runMonitor()
+	}
 	for {
 		in, err := tp.Listener.Accept()
 		if err != nil {
@@ -115,14 +185,14 @@ func (tp *TCPProxy) Run() error {
 	}
 }
 
-func (tp *TCPProxy) pick() *remote {
+func (b *backend) pick() *remote {
 	var weighted []*remote
 	var unweighted []*remote
 
 	bestPr := uint16(65535)
 	w := 0
 	// find best priority class
-	for _, r := range tp.remotes {
+	for _, r := range b.remotes {
 		switch {
 		case !r.isActive():
 		case r.srv.Priority < bestPr:
@@ -145,8 +215,8 @@ func (tp *TCPProxy) pick() *remote {
 			// In the presence of records containing weights greater
 			// than 0, records with weight 0 should have a very small
 			// chance of being selected.
-			r := unweighted[tp.pickCount%len(unweighted)]
-			tp.pickCount++
+			r := unweighted[b.pickCount%len(unweighted)]
+			b.pickCount++
 			return r
 		}
 		// choose a uniform random number between 0 and the sum computed
@@ -161,9 +231,9 @@ func (tp *TCPProxy) pick() *remote {
 		}
 	}
 	if unweighted != nil {
-		for i := 0; i < len(tp.remotes); i++ {
-			picked := tp.remotes[tp.pickCount%len(tp.remotes)]
-			tp.pickCount++
+		for i := 0; i < len(b.remotes); i++ {
+			picked := b.remotes[b.pickCount%len(b.remotes)]
+			b.pickCount++
 			if picked.isActive() {
 				return picked
 			}
@@ -178,21 +248,30 @@ func (tp *TCPProxy) serve(in net.Conn) {
 		out net.Conn
 	)
 
-	for {
-		tp.mu.Lock()
-		remote := tp.pick()
-		tp.mu.Unlock()
+	in, b, err := tp.route(in)
+	if err != nil {
+		if tp.Logger != nil {
+			tp.Logger.Warn("failed to route connection", zap.String("remote-address", in.RemoteAddr().String()), zap.Error(err))
+		}
+		in.Close()
+		return
+	}
+
+	for b != nil {
+		b.mu.Lock()
+		remote := b.pick()
+		b.mu.Unlock()
 		if remote == nil {
 			break
 		}
 		// TODO: add timeout
-		out, err = net.Dial("tcp", remote.addr)
+		out, err = tp.dial(in, b, remote)
 		if err == nil {
 			break
 		}
 		remote.inactivate()
 		if tp.Logger != nil {
-			tp.Logger.Warn("deactivated endpoint", zap.String("address", remote.addr), zap.Duration("interval", tp.MonitorInterval), zap.Error(err))
+			tp.Logger.Warn("deactivated endpoint", zap.String("server-name", b.serverName), zap.String("address", remote.addr), zap.Duration("interval", tp.MonitorInterval), zap.Error(err))
 		}
 	}
 
@@ -212,34 +291,138 @@ func (tp *TCPProxy) serve(in net.Conn) {
 	in.Close()
 }
 
+// route returns the connection of the client, with its TLS terminated or
+// its ClientHello peeked at, and the backend it is routed to, nil if none.
+func (tp *TCPProxy) route(in net.Conn) (net.Conn, *backend, error) {
+	if tp.TLSConfig == nil && (len(tp.backends) == 0 || len(tp.backends) == 1 && tp.backends[0].serverName == "") {
+		// no routing, the connection is passed through as is
+		return in, tp.routes[""], nil
+	}
+
+	in.SetReadDeadline(time.Now().Add(routeTimeout))
+	var serverName string
+	if tp.TLSConfig != nil {
+		tlsConn := tls.Server(in, tp.TLSConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return in, nil, err
+		}
+		serverName = tlsConn.ConnectionState().ServerName
+		in = tlsConn
+	} else {
+		var err error
+		if in, serverName, err = peekServerName(in); err != nil {
+			return in, nil, err
+		}
+	}
+	in.SetReadDeadline(time.Time{})
+
+	b, ok := tp.routes[serverName]
+	if !ok {
+		b = tp.routes[""]
+	}
+	return in, b, nil
+}
+
+// dial connects to the remote of the backend for the connection in, over
+// TLS if the proxy terminated the TLS of in and the backend serves TLS.
+func (tp *TCPProxy) dial(in net.Conn, b *backend, r *remote) (net.Conn, error) {
+	tlsIn, ok := in.(*tls.Conn)
+	if !ok || b.tlsConfig == nil {
+		return net.Dial("tcp", r.addr)
+	}
+	cfg := b.tlsConfig.Clone()
+	if p := tlsIn.ConnectionState().NegotiatedProtocol; p != "" {
+		// the endpoint must speak the protocol the client negotiated
+		cfg.NextProtos = []string{p}
+	}
+	return tls.Dial("tcp", r.addr, cfg)
+}
+
 func (tp *TCPProxy) runMonitor() {
 	for {
 		select {
 		case <-time.After(tp.MonitorInterval):
-			tp.mu.Lock()
-			for _, rem := range tp.remotes {
-				if rem.isActive() {
-					continue
-				}
-				go func(r *remote) {
-					if err := r.tryReactivate(); err != nil {
-						if tp.Logger != nil {
-							tp.Logger.Warn("failed to activate endpoint (stay inactive for another interval)", zap.String("address", r.addr), zap.Duration("interval", tp.MonitorInterval), zap.Error(err))
-						}
-					} else {
-						if tp.Logger != nil {
-							tp.Logger.Info("activated", zap.String("address", r.addr))
-						}
+			for _, b := range tp.backends {
+				b.mu.Lock()
+				for _, rem := range b.remotes {
+					if rem.isActive() {
+						continue
 					}
-				}(rem)
+					go func(r *remote) {
+						if err := r.tryReactivate(); err != nil {
+							if tp.Logger != nil {
+								tp.Logger.Warn("failed to activate endpoint (stay inactive for another interval)", zap.String("address", r.addr), zap.Duration("interval", tp.MonitorInterval), zap.Error(err))
+							}
+						} else {
+							if tp.Logger != nil {
+								tp.Logger.Info("activated", zap.String("address", r.addr))
+							}
+						}
+					}(rem)
+				}
+				b.mu.Unlock()
+			}
+		case <-tp.donec:
+			return
+		}
+	}
+}
+
+func (tp *TCPProxy) runHealthChecks() {
+	for {
+		select {
+		case <-time.After(tp.HealthCheckInterval):
+			var wg sync.WaitGroup
+			for _, b := range tp.backends {
+				for _, rem := range b.remotes {
+					wg.Add(1)
+					go func(b *backend, r *remote) {
+						defer wg.Done()
+						tp.checkHealth(b, r)
+					}(b, rem)
+				}
 			}
-			tp.mu.Unlock()
+			// do not pile up the checks of the endpoints slower than the interval
+			wg.Wait()
 		case <-tp.donec:
 			return
 		}
 	}
 }
 
+func (tp *TCPProxy) checkHealth(b *backend, r *remote) {
+	err := tp.HealthCheck(r.addr, b.tlsConfig)
+	switch {
+	case err != nil && r.isActive():
+		r.inactivate()
+		if tp.Logger != nil {
+			tp.Logger.Warn("deactivated unhealthy endpoint", zap.String("server-name", b.serverName), zap.String("address", r.addr), zap.Duration("interval", tp.HealthCheckInterval), zap.Error(err))
+		}
+	case err == nil && !r.isActive():
+		r.activate()
+		if tp.Logger != nil {
+			tp.Logger.Info("activated healthy endpoint", zap.String("server-name", b.serverName), zap.String("address", r.addr))
+		}
+	}
+}
+
+// dialHealthCheck checks the endpoint accepts connections, over TLS if the
+// endpoint serves TLS.
+func (tp *TCPProxy) dialHealthCheck(addr string, tlsConfig *tls.Config) error {
+	d := &net.Dialer{Timeout: tp.HealthCheckInterval}
+	var conn net.Conn
+	var err error
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(d, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = d.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
 func (tp *TCPProxy) Stop() {
 	// graceful shutdown?
 	// shutdown current connections?
@@ -15,13 +15,17 @@
 package tcpproxy
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestUserspaceProxy(t *testing.T) {
@@ -130,6 +134,176 @@ func TestUserspaceProxyPriority(t *testing.T) {
 	}
 }
 
+func srvOf(t *testing.T, ts *httptest.Server) *net.SRV {
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var port uint16
+	fmt.Sscanf(u.Port(), "%d", &port)
+	return &net.SRV{Target: u.Hostname(), Port: port}
+}
+
+func newPayloadServer(payload string, useTLS bool) *httptest.Server {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, payload)
+	})
+	if useTLS {
+		return httptest.NewTLSServer(h)
+	}
+	return httptest.NewServer(h)
+}
+
+// get returns the payload of the server the proxy routes the request to the
+// server name to, over TLS if serverName is not empty.
+func get(t *testing.T, addr, serverName string) (string, error) {
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{ServerName: serverName, InsecureSkipVerify: true},
+	}}
+	defer client.CloseIdleConnections()
+	u := "http://" + addr
+	if serverName != "" {
+		u = "https://" + addr
+	}
+	res, err := client.Get(u)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	got, err := io.ReadAll(res.Body)
+	return string(got), err
+}
+
+func TestUserspaceProxySNIRouting(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	ta, tb, tdefault := newPayloadServer("a", true), newPayloadServer("b", true), newPayloadServer("default", false)
+	defer ta.Close()
+	defer tb.Close()
+	defer tdefault.Close()
+
+	p := TCPProxy{
+		Listener:  l,
+		Endpoints: []*net.SRV{srvOf(t, tdefault)},
+		Backends: []Backend{
+			{ServerName: "a.example.com", Endpoints: []*net.SRV{srvOf(t, ta)}},
+			{ServerName: "b.example.com", Endpoints: []*net.SRV{srvOf(t, tb)}},
+		},
+	}
+	go p.Run()
+	defer p.Stop()
+
+	for serverName, want := range map[string]string{"a.example.com": "a", "b.example.com": "b", "": "default"} {
+		got, err := get(t, l.Addr().String(), serverName)
+		if err != nil {
+			t.Fatalf("server name %q: %v", serverName, err)
+		}
+		if got != want {
+			t.Errorf("server name %q: got = %s, want %s", serverName, got, want)
+		}
+	}
+	// the TLS connections of unknown server names are passed through to the
+	// plain default endpoint
+	if _, err = get(t, l.Addr().String(), "c.example.com"); err == nil {
+		t.Errorf("unknown server name routed to a TLS endpoint, want routed to the default endpoint")
+	}
+}
+
+func TestUserspaceProxyTLSTermination(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// the certificate of the TLS servers is used by the proxy too
+	ta, tb := newPayloadServer("a", true), newPayloadServer("b", false)
+	defer ta.Close()
+	defer tb.Close()
+
+	p := TCPProxy{
+		Listener:  l,
+		TLSConfig: &tls.Config{Certificates: ta.TLS.Certificates},
+		Backends: []Backend{
+			{ServerName: "a.example.com", Endpoints: []*net.SRV{srvOf(t, ta)}, TLSConfig: &tls.Config{InsecureSkipVerify: true}},
+			{ServerName: "b.example.com", Endpoints: []*net.SRV{srvOf(t, tb)}},
+		},
+	}
+	go p.Run()
+	defer p.Stop()
+
+	for serverName, want := range map[string]string{"a.example.com": "a", "b.example.com": "b"} {
+		got, err := get(t, l.Addr().String(), serverName)
+		if err != nil {
+			t.Fatalf("server name %q: %v", serverName, err)
+		}
+		if got != want {
+			t.Errorf("server name %q: got = %s, want %s", serverName, got, want)
+		}
+	}
+	// no default backend
+	if _, err = get(t, l.Addr().String(), "c.example.com"); err == nil {
+		t.Errorf("unknown server name routed, want the connection closed")
+	}
+}
+
+func TestUserspaceProxyHealthCheck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	t1, t2 := newPayloadServer("1", false), newPayloadServer("2", false)
+	defer t1.Close()
+	defer t2.Close()
+	srv1, srv2 := srvOf(t, t1), srvOf(t, t2)
+	// the endpoint of the best priority is unhealthy while accepting connections
+	srv1.Priority, srv2.Priority = 1, 2
+	addr1 := formatAddr(srv1.Target, srv1.Port)
+
+	var mu sync.Mutex
+	unhealthy := true
+	p := TCPProxy{
+		Listener:            l,
+		Endpoints:           []*net.SRV{srv1, srv2},
+		HealthCheckInterval: 10 * time.Millisecond,
+		HealthCheck: func(addr string, tlsConfig *tls.Config) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if addr == addr1 && unhealthy {
+				return errors.New("unhealthy")
+			}
+			return nil
+		},
+	}
+	go p.Run()
+	defer p.Stop()
+
+	waitRouted := func(want string) {
+		var got string
+		var err error
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+			if got, err = get(t, l.Addr().String(), ""); err == nil && got == want {
+				return
+			}
+		}
+		t.Fatalf("got = %s, %v, want %s", got, err, want)
+	}
+	// the unhealthy endpoint is deactivated
+	waitRouted("2")
+
+	mu.Lock()
+	unhealthy = false
+	mu.Unlock()
+	// and activated again once healthy
+	waitRouted("1")
+}
+
 func TestFormatAddr(t *testing.T) {
 	addrs := []struct {
 		host         string
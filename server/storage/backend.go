@@ -19,6 +19,7 @@ import (
 	"os"
 	"time"
 
+	bolt "go.etcd.io/bbolt"
 	"go.etcd.io/etcd/raft/v3/raftpb"
 	"go.etcd.io/etcd/server/v3/config"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/snap"
@@ -28,6 +29,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// largeBackendBytes is the size from which the bbolt options are checked to
+// fit the backend.
+const largeBackendBytes = 1024 * 1024 * 1024
+
 func newBackend(cfg config.ServerConfig, hooks backend.Hooks) backend.Backend {
 	bcfg := backend.DefaultBackendConfig(cfg.Logger)
 	bcfg.Path = cfg.BackendPath()
@@ -45,14 +50,42 @@ func newBackend(cfg config.ServerConfig, hooks backend.Hooks) backend.Backend {
 		}
 	}
 	bcfg.BackendFreelistType = cfg.BackendFreelistType
+	bcfg.FreelistSync = cfg.BackendFreelistSync
 	bcfg.Logger = cfg.Logger
-	if cfg.QuotaBackendBytes > 0 && cfg.QuotaBackendBytes != DefaultQuotaBytes {
+	if cfg.BackendInitialMmapSize != 0 {
+		bcfg.MmapSize = cfg.BackendInitialMmapSize
+	} else if cfg.QuotaBackendBytes > 0 && cfg.QuotaBackendBytes != DefaultQuotaBytes {
 		// permit 10% excess over quota for disarm
 		bcfg.MmapSize = uint64(cfg.QuotaBackendBytes + cfg.QuotaBackendBytes/10)
 	}
 	bcfg.Mlock = cfg.ExperimentalMemoryMlock
+	bcfg.MmapAdvice = cfg.ExperimentalBackendMmapAdvice
+	bcfg.WarmUp = cfg.BackendWarmUp
 	bcfg.Hooks = hooks
-	return backend.New(bcfg)
+	be := backend.New(bcfg)
+	checkBackendOptions(cfg.Logger, bcfg, be.Size())
+	return be
+}
+
+// checkBackendOptions warns about the bbolt options unfit for the size of the
+// backend, once it is large.
+func checkBackendOptions(lg *zap.Logger, bcfg backend.BackendConfig, size int64) {
+	if lg == nil || size < largeBackendBytes {
+		return
+	}
+	if bcfg.BackendFreelistType == bolt.FreelistArrayType {
+		lg.Warn(
+			"array freelist is slow to allocate the pages of large backends; consider --backend-bbolt-freelist-type=map",
+			zap.Int64("db-size-bytes", size),
+		)
+	}
+	if bcfg.MmapSize != 0 && uint64(size) > bcfg.MmapSize {
+		lg.Warn(
+			"backend is larger than its initial mmap size, remapping it as it grows blocks the transactions; consider raising --backend-bbolt-initial-mmap-size",
+			zap.Int64("db-size-bytes", size),
+			zap.Uint64("initial-mmap-size-bytes", bcfg.MmapSize),
+		)
+	}
 }
 
 // OpenSnapshotBackend renames a snapshot db to the current etcd db and opens it.
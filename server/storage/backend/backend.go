@@ -34,6 +34,9 @@ var (
 	defaultBatchInterval = 100 * time.Millisecond
 
 	defragLimit = 10000
+	// defragMaxRounds is the maximum number of rounds copying the keys
+	// written during the defragmentation while the writes proceed.
+	defragMaxRounds = 8
 
 	// initialMmapSize is the initial size of the mmapped region. Setting this larger than
 	// the potential max db size can prevent writer from blocking reader.
@@ -42,6 +45,18 @@ var (
 
 	// minSnapshotWarningTimeout is the minimum threshold to trigger a long running snapshot warning.
 	minSnapshotWarningTimeout = 30 * time.Second
+
+	// warmUpChunkSize is the size of the reads of the db file warming up the page cache.
+	warmUpChunkSize = 4 * 1024 * 1024
+)
+
+const (
+	// MmapAdviceNormal lets the kernel read ahead the pages around the ones read.
+	MmapAdviceNormal = "normal"
+	// MmapAdviceRandom disables the read-ahead, the default of boltdb.
+	MmapAdviceRandom = "random"
+	// MmapAdviceWillNeed reads ahead the whole db, as it is mapped and remapped.
+	MmapAdviceWillNeed = "willneed"
 )
 
 type Backend interface {
@@ -62,6 +77,10 @@ type Backend interface {
 	// Since the backend can manage free space in a non-byte unit such as
 	// number of pages, the returned value can be not exactly accurate in bytes.
 	SizeInUse() int64
+	// SizePending returns the current size of the pages of the backend freed
+	// but still referenced by open read transactions, which are reused once
+	// these transactions are closed.
+	SizePending() int64
 	// OpenReadTxN returns the number of currently open read transactions in the backend.
 	OpenReadTxN() int64
 	Defrag() error
@@ -70,6 +89,8 @@ type Backend interface {
 
 	// SetTxPostLockInsideApplyHook sets a txPostLockInsideApplyHook.
 	SetTxPostLockInsideApplyHook(func())
+	// SetCommitObserver sets a commitObserver.
+	SetCommitObserver(func(time.Duration))
 }
 
 type Snapshot interface {
@@ -95,12 +116,20 @@ type backend struct {
 	size int64
 	// sizeInUse is the number of bytes actually used in the backend
 	sizeInUse int64
+	// sizePending is the number of bytes freed but still used by open read transactions
+	sizePending int64
 	// commits counts number of commits since start
 	commits int64
 	// openReadTxN is the number of currently open read transactions in the backend
 	openReadTxN int64
 	// mlock prevents backend database file to be swapped
 	mlock bool
+	// mmapAdvice is the madvise advice of the mmap of the db, empty for the
+	// boltdb default. Remapping the db resets it, so it is applied again each
+	// time advisedData or advisedSize change.
+	mmapAdvice  string
+	advisedData uintptr
+	advisedSize int64
 
 	mu    sync.RWMutex
 	bopts *bolt.Options
@@ -111,6 +140,11 @@ type backend struct {
 	batchTx       *batchTxBuffered
 
 	readTx *readTx
+	// defragMu serializes the defragmentations.
+	defragMu sync.Mutex
+	// defragDirty tracks the keys written while the db is defragmented, nil
+	// otherwise. It is guarded by the lock of batchTx.
+	defragDirty *defragDirty
 	// txReadBufferCache mirrors "txReadBuffer" within "readTx" -- readTx.baseReadTx.buf.
 	// When creating "concurrentReadTx":
 	// - if the cache is up-to-date, "readTx.baseReadTx.buf" copy can be skipped
@@ -119,11 +153,15 @@ type backend struct {
 
 	stopc chan struct{}
 	donec chan struct{}
+	// warmUpWg waits for the warm-up of the db to stop on close.
+	warmUpWg sync.WaitGroup
 
 	hooks Hooks
 
 	// txPostLockInsideApplyHook is called each time right after locking the tx.
 	txPostLockInsideApplyHook func()
+	// commitObserver is called with the duration of each commit of the batch tx.
+	commitObserver func(time.Duration)
 
 	lg *zap.Logger
 }
@@ -139,12 +177,22 @@ type BackendConfig struct {
 	BackendFreelistType bolt.FreelistType
 	// MmapSize is the number of bytes to mmap for the backend.
 	MmapSize uint64
+	// FreelistSync persists the freelist of boltdb on each commit instead of
+	// rebuilding it by scanning the database on open, for large databases
+	// to open faster at the cost of slower commits.
+	FreelistSync bool
 	// Logger logs backend-side operations.
 	Logger *zap.Logger
 	// UnsafeNoFsync disables all uses of fsync.
 	UnsafeNoFsync bool `json:"unsafe-no-fsync"`
 	// Mlock prevents backend database file to be swapped
 	Mlock bool
+	// MmapAdvice is the madvise advice applied to the mmap of the backend,
+	// the boltdb default if empty. It is only applied on linux.
+	MmapAdvice string
+	// WarmUp reads the backend file in the background after opening it, for
+	// its pages to be in the page cache instead of prefaulting them on open.
+	WarmUp bool
 
 	// Hooks are getting executed during lifecycle of Backend's transactions.
 	Hooks Hooks
@@ -176,6 +224,13 @@ func newBackend(bcfg BackendConfig) *backend {
 	}
 	bopts.InitialMmapSize = bcfg.mmapSize()
 	bopts.FreelistType = bcfg.BackendFreelistType
+	if bcfg.FreelistSync {
+		bopts.NoFreelistSync = false
+	}
+	if bcfg.WarmUp {
+		// the pages are read in the background instead
+		bopts.MmapFlags &^= mmapPopulate
+	}
 	bopts.NoSync = bcfg.UnsafeNoFsync
 	bopts.NoGrowSync = bcfg.UnsafeNoFsync
 	bopts.Mlock = bcfg.Mlock
@@ -194,6 +249,7 @@ func newBackend(bcfg BackendConfig) *backend {
 		batchInterval: bcfg.BatchInterval,
 		batchLimit:    bcfg.BatchLimit,
 		mlock:         bcfg.Mlock,
+		mmapAdvice:    bcfg.MmapAdvice,
 
 		readTx: &readTx{
 			baseReadTx: baseReadTx{
@@ -222,10 +278,69 @@ func newBackend(bcfg BackendConfig) *backend {
 	// We set it after newBatchTxBuffered to skip the 'empty' commit.
 	b.hooks = bcfg.Hooks
 
+	if bcfg.WarmUp {
+		b.warmUpWg.Add(1)
+		go b.warmUp()
+	}
 	go b.run()
 	return b
 }
 
+// warmUp reads the db file for its pages to be in the page cache before they
+// are first read through the mmap, stopping when the backend is closed.
+func (b *backend) warmUp() {
+	defer b.warmUpWg.Done()
+	start := time.Now()
+	path := b.db.Path()
+	f, err := os.Open(path)
+	if err != nil {
+		b.lg.Warn("failed to warm up backend db", zap.String("path", path), zap.Error(err))
+		return
+	}
+	defer f.Close()
+
+	buf := make([]byte, warmUpChunkSize)
+	var read int64
+	for {
+		select {
+		case <-b.stopc:
+			return
+		default:
+		}
+		n, err := f.Read(buf)
+		read += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			b.lg.Warn("failed to warm up backend db", zap.String("path", path), zap.Error(err))
+			return
+		}
+	}
+	b.lg.Info(
+		"warmed up backend db",
+		zap.String("path", path),
+		zap.Int64("read-bytes", read),
+		zap.Duration("took", time.Since(start)),
+	)
+}
+
+// adviseMmap applies the mmap advice to the db if it has been remapped since
+// last applied. It must be called with the db not being written.
+func (b *backend) adviseMmap(db *bolt.DB, size int64) {
+	if b.mmapAdvice == "" {
+		return
+	}
+	data := db.Info().Data
+	if data == b.advisedData && size <= b.advisedSize {
+		return
+	}
+	if err := madvise(data, size, b.mmapAdvice); err != nil {
+		b.lg.Warn("failed to advise backend db mmap", zap.String("advice", b.mmapAdvice), zap.Error(err))
+	}
+	b.advisedData, b.advisedSize = data, size
+}
+
 // BatchTx returns the current batch tx in coalescer. The tx can be used for read and
 // write operations. The write result can be retrieved within the same tx immediately.
 // The write result is isolated with other txs until the current one get committed.
@@ -241,6 +356,13 @@ func (b *backend) SetTxPostLockInsideApplyHook(hook func()) {
 	b.txPostLockInsideApplyHook = hook
 }
 
+func (b *backend) SetCommitObserver(observer func(time.Duration)) {
+	// the batchTx is locked while committing
+	b.batchTx.lock()
+	defer b.batchTx.Unlock()
+	b.commitObserver = observer
+}
+
 func (b *backend) ReadTx() ReadTx { return b.readTx }
 
 // ConcurrentReadTx creates and returns a new ReadTx, which:
@@ -409,6 +531,10 @@ func (b *backend) SizeInUse() int64 {
 	return atomic.LoadInt64(&b.sizeInUse)
 }
 
+func (b *backend) SizePending() int64 {
+	return atomic.LoadInt64(&b.sizePending)
+}
+
 func (b *backend) run() {
 	defer close(b.donec)
 	t := time.NewTimer(b.batchInterval)
@@ -430,6 +556,7 @@ func (b *backend) run() {
 func (b *backend) Close() error {
 	close(b.stopc)
 	<-b.donec
+	b.warmUpWg.Wait()
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	return b.db.Close()
@@ -445,28 +572,13 @@ func (b *backend) Defrag() error {
 }
 
 func (b *backend) defrag() error {
+	b.defragMu.Lock()
+	defer b.defragMu.Unlock()
+
 	now := time.Now()
 	isDefragActive.Set(1)
 	defer isDefragActive.Set(0)
 
-	// TODO: make this non-blocking?
-	// lock batchTx to ensure nobody is using previous tx, and then
-	// close previous ongoing tx.
-	b.batchTx.LockOutsideApply()
-	defer b.batchTx.Unlock()
-
-	// lock database after lock tx to avoid deadlock.
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	// block concurrent read requests while resetting tx
-	b.readTx.Lock()
-	defer b.readTx.Unlock()
-
-	b.batchTx.unsafeCommit(true)
-
-	b.batchTx.tx = nil
-
 	// Create a temporary file to ensure we start with a clean slate.
 	// Snapshotter.cleanupSnapdir cleans up any of these that are found during startup.
 	dir := filepath.Dir(b.db.Path())
@@ -501,9 +613,21 @@ func (b *backend) defrag() error {
 			zap.String("current-db-size-in-use", humanize.Bytes(uint64(sizeInUse1))),
 		)
 	}
+
+	// the db is copied while the writes proceed, the keys they write being
+	// tracked to be copied again, until few enough are left to be copied
+	// while blocking the writes. The pending writes are committed for the
+	// copy to see them.
+	b.batchTx.LockOutsideApply()
+	b.batchTx.commit(false)
+	b.defragDirty = newDefragDirty()
+	b.batchTx.Unlock()
+
 	// gofail: var defragBeforeCopy struct{}
-	err = defragdb(b.db, tmpdb, defragLimit)
-	if err != nil {
+	if err = b.defragCopy(tmpdb); err != nil {
+		b.batchTx.LockOutsideApply()
+		b.defragDirty = nil
+		b.batchTx.Unlock()
 		tmpdb.Close()
 		if rmErr := os.RemoveAll(tmpdb.Path()); rmErr != nil {
 			b.lg.Error("failed to remove db.tmp after defragmentation completed", zap.Error(rmErr))
@@ -511,36 +635,16 @@ func (b *backend) defrag() error {
 		return err
 	}
 
-	err = b.db.Close()
+	blockStart := time.Now()
+	n, err := b.defragSwap(tmpdb, tdbp)
+	blocked := time.Since(blockStart)
 	if err != nil {
-		b.lg.Fatal("failed to close database", zap.Error(err))
-	}
-	err = tmpdb.Close()
-	if err != nil {
-		b.lg.Fatal("failed to close tmp database", zap.Error(err))
-	}
-	// gofail: var defragBeforeRename struct{}
-	err = os.Rename(tdbp, dbp)
-	if err != nil {
-		b.lg.Fatal("failed to rename tmp database", zap.Error(err))
-	}
-
-	b.db, err = bolt.Open(dbp, 0600, b.bopts)
-	if err != nil {
-		b.lg.Fatal("failed to open database", zap.String("path", dbp), zap.Error(err))
+		return err
 	}
-	b.batchTx.tx = b.unsafeBegin(true)
-
-	b.readTx.reset()
-	b.readTx.tx = b.unsafeBegin(false)
-
-	size := b.readTx.tx.Size()
-	db := b.readTx.tx.DB()
-	atomic.StoreInt64(&b.size, size)
-	atomic.StoreInt64(&b.sizeInUse, size-(int64(db.Stats().FreePageN)*int64(db.Info().PageSize)))
 
 	took := time.Since(now)
 	defragSec.Observe(took.Seconds())
+	defragBlockingSec.Observe(blocked.Seconds())
 
 	size2, sizeInUse2 := b.Size(), b.SizeInUse()
 	if b.lg != nil {
@@ -553,69 +657,117 @@ func (b *backend) defrag() error {
 			zap.Int64("current-db-size-in-use-bytes-diff", sizeInUse2-sizeInUse1),
 			zap.Int64("current-db-size-in-use-bytes", sizeInUse2),
 			zap.String("current-db-size-in-use", humanize.Bytes(uint64(sizeInUse2))),
+			zap.Int("keys-copied-while-blocking", n),
+			zap.Duration("blocked", blocked),
 			zap.Duration("took", took),
 		)
 	}
 	return nil
 }
 
-func defragdb(odb, tmpdb *bolt.DB, limit int) error {
-	// open a tx on tmpdb for writes
-	tmptx, err := tmpdb.Begin(true)
-	if err != nil {
+// defragCopy copies the db to tmpdb in chunks, then the keys written in the
+// meantime, in rounds while the writes proceed.
+func (b *backend) defragCopy(tmpdb *bolt.DB) error {
+	// the db is only replaced by the defragmentation
+	db := b.db
+	if err := defragdb(db, tmpdb, defragLimit); err != nil {
 		return err
 	}
-	defer func() {
-		if err != nil {
-			tmptx.Rollback()
+	for i := 0; i < defragMaxRounds; i++ {
+		// commit the writes for the read tx to see them
+		b.batchTx.LockOutsideApply()
+		b.batchTx.commit(false)
+		dirty := b.defragDirty
+		if dirty.len() <= defragLimit {
+			b.batchTx.Unlock()
+			return nil
 		}
-	}()
+		b.defragDirty = newDefragDirty()
+		b.batchTx.Unlock()
 
-	// open a tx on old db for read
-	tx, err := odb.Begin(false)
-	if err != nil {
-		return err
+		tx, err := db.Begin(false)
+		if err != nil {
+			return err
+		}
+		err = dirty.copy(tx, tmpdb, defragLimit)
+		tx.Rollback()
+		if err != nil {
+			return err
+		}
 	}
-	defer tx.Rollback()
+	return nil
+}
 
-	c := tx.Cursor()
+// defragSwap copies the keys left to copy to tmpdb and replaces the db with
+// it, blocking the reads and writes. It returns the number of keys copied.
+func (b *backend) defragSwap(tmpdb *bolt.DB, tdbp string) (int, error) {
+	// lock batchTx to ensure nobody is using previous tx, and then
+	// close previous ongoing tx.
+	b.batchTx.LockOutsideApply()
+	defer b.batchTx.Unlock()
 
-	count := 0
-	for next, _ := c.First(); next != nil; next, _ = c.Next() {
-		b := tx.Bucket(next)
-		if b == nil {
-			return fmt.Errorf("backend: cannot defrag bucket %s", string(next))
-		}
+	// lock database after lock tx to avoid deadlock.
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-		tmpb, berr := tmptx.CreateBucketIfNotExists(next)
-		if berr != nil {
-			return berr
-		}
-		tmpb.FillPercent = 0.9 // for bucket2seq write in for each
-
-		if err = b.ForEach(func(k, v []byte) error {
-			count++
-			if count > limit {
-				err = tmptx.Commit()
-				if err != nil {
-					return err
-				}
-				tmptx, err = tmpdb.Begin(true)
-				if err != nil {
-					return err
-				}
-				tmpb = tmptx.Bucket(next)
-				tmpb.FillPercent = 0.9 // for bucket2seq write in for each
+	// block concurrent read requests while resetting tx
+	b.readTx.Lock()
+	defer b.readTx.Unlock()
 
-				count = 0
-			}
-			return tmpb.Put(k, v)
-		}); err != nil {
-			return err
+	b.batchTx.unsafeCommit(true)
+
+	b.batchTx.tx = nil
+
+	dirty := b.defragDirty
+	b.defragDirty = nil
+	n := dirty.len()
+	tx, err := b.db.Begin(false)
+	if err == nil {
+		err = dirty.copy(tx, tmpdb, defragLimit)
+		tx.Rollback()
+	}
+	if err != nil {
+		tmpdb.Close()
+		if rmErr := os.RemoveAll(tmpdb.Path()); rmErr != nil {
+			b.lg.Error("failed to remove db.tmp after defragmentation completed", zap.Error(rmErr))
 		}
+		b.batchTx.tx = b.unsafeBegin(true)
+		b.readTx.tx = b.unsafeBegin(false)
+		return 0, err
 	}
 
-	return tmptx.Commit()
+	dbp := b.db.Path()
+	err = b.db.Close()
+	if err != nil {
+		b.lg.Fatal("failed to close database", zap.Error(err))
+	}
+	err = tmpdb.Close()
+	if err != nil {
+		b.lg.Fatal("failed to close tmp database", zap.Error(err))
+	}
+	// gofail: var defragBeforeRename struct{}
+	err = os.Rename(tdbp, dbp)
+	if err != nil {
+		b.lg.Fatal("failed to rename tmp database", zap.Error(err))
+	}
+
+	b.db, err = bolt.Open(dbp, 0600, b.bopts)
+	if err != nil {
+		b.lg.Fatal("failed to open database", zap.String("path", dbp), zap.Error(err))
+	}
+	b.batchTx.tx = b.unsafeBegin(true)
+
+	b.readTx.reset()
+	b.readTx.tx = b.unsafeBegin(false)
+
+	size := b.readTx.tx.Size()
+	db := b.readTx.tx.DB()
+	stats := db.Stats()
+	atomic.StoreInt64(&b.size, size)
+	atomic.StoreInt64(&b.sizeInUse, size-(int64(stats.FreePageN)*int64(db.Info().PageSize)))
+	atomic.StoreInt64(&b.sizePending, int64(stats.PendingPageN)*int64(db.Info().PageSize))
+	b.adviseMmap(db, size)
+	return n, nil
 }
 
 func (b *backend) begin(write bool) *bolt.Tx {
@@ -628,7 +780,11 @@ func (b *backend) begin(write bool) *bolt.Tx {
 	stats := db.Stats()
 	atomic.StoreInt64(&b.size, size)
 	atomic.StoreInt64(&b.sizeInUse, size-(int64(stats.FreePageN)*int64(db.Info().PageSize)))
+	atomic.StoreInt64(&b.sizePending, int64(stats.PendingPageN)*int64(db.Info().PageSize))
 	atomic.StoreInt64(&b.openReadTxN, int64(stats.OpenTxN))
+	if !write {
+		b.adviseMmap(db, size)
+	}
 
 	return tx
 }
@@ -15,8 +15,10 @@
 package backend_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -193,7 +195,132 @@ func TestBackendDefrag(t *testing.T) {
 	b.ForceCommit()
 }
 
+// TestBackendDefragConcurrentWrites ensures the writes made while the db is
+// defragmented are kept.
+func TestBackendDefragConcurrentWrites(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+
+	want := make(map[string]string)
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafeCreateBucket(schema.Test)
+	for i := 0; i < 2*backend.DefragLimitForTest(); i++ {
+		k, v := fmt.Sprintf("foo_%d", i), fmt.Sprintf("bar_%d", i)
+		tx.UnsafePut(schema.Test, []byte(k), []byte(v))
+		want[k] = v
+	}
+	tx.Unlock()
+	b.ForceCommit()
+	// the writes not committed yet are kept too
+	tx.Lock()
+	tx.UnsafePut(schema.Test, []byte("pending"), []byte("bar"))
+	want["pending"] = "bar"
+	tx.Unlock()
+
+	donec := make(chan struct{})
+	writec := make(chan struct{})
+	go func() {
+		defer close(writec)
+		for i := 0; ; i++ {
+			select {
+			case <-donec:
+				return
+			default:
+			}
+			tx := b.BatchTx()
+			tx.Lock()
+			k := fmt.Sprintf("foo_%d", (i*7919)%(3*backend.DefragLimitForTest()))
+			if i%3 == 0 {
+				tx.UnsafeDelete(schema.Test, []byte(k))
+				delete(want, k)
+			} else {
+				v := fmt.Sprintf("baz_%d", i)
+				tx.UnsafePut(schema.Test, []byte(k), []byte(v))
+				want[k] = v
+			}
+			// the buckets created and deleted are copied again too
+			if i%100 == 0 {
+				tx.UnsafeCreateBucket(schema.Lease)
+				tx.UnsafePut(schema.Lease, []byte("lease"), []byte(k))
+			} else if i%100 == 50 {
+				tx.UnsafeDeleteBucket(schema.Lease)
+			}
+			tx.Unlock()
+		}
+	}()
+
+	err := b.Defrag()
+	close(donec)
+	<-writec
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.ForceCommit()
+
+	got := make(map[string]string)
+	assert.NoError(t, backend.DbFromBackendForTest(b).View(func(tx *bolt.Tx) error {
+		return tx.Bucket(schema.Test.Name()).ForEach(func(k, v []byte) error {
+			got[string(k)] = string(v)
+			return nil
+		})
+	}))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %d keys, want %d keys", len(got), len(want))
+	}
+}
+
 // TestBackendWriteback ensures writes are stored to the read txn on write txn unlock.
+func TestBackendFreelistSync(t *testing.T) {
+	bcfg := backend.DefaultBackendConfig(zaptest.NewLogger(t))
+	bcfg.FreelistSync = true
+	b, _ := betesting.NewTmpBackendFromCfg(t, bcfg)
+	defer betesting.Close(t, b)
+
+	if backend.DbFromBackendForTest(b).NoFreelistSync {
+		t.Fatal("NoFreelistSync = true, want the freelist synced")
+	}
+	// the freelist sync must survive the reopening of the db
+	if err := b.Defrag(); err != nil {
+		t.Fatal(err)
+	}
+	if backend.DbFromBackendForTest(b).NoFreelistSync {
+		t.Error("NoFreelistSync = true after defrag, want the freelist synced")
+	}
+}
+
+func TestBackendWarmUpAndMmapAdvice(t *testing.T) {
+	for _, advice := range []string{backend.MmapAdviceNormal, backend.MmapAdviceRandom, backend.MmapAdviceWillNeed} {
+		t.Run(advice, func(t *testing.T) {
+			bcfg := backend.DefaultBackendConfig(zaptest.NewLogger(t))
+			bcfg.WarmUp = true
+			bcfg.MmapAdvice = advice
+			bcfg.Path = filepath.Join(t.TempDir(), "database")
+			b := backend.New(bcfg)
+
+			tx := b.BatchTx()
+			tx.Lock()
+			tx.UnsafeCreateBucket(schema.Test)
+			for i := 0; i < 1000; i++ {
+				tx.UnsafePut(schema.Test, []byte(fmt.Sprintf("foo_%d", i)), bytes.Repeat([]byte("bar"), 100))
+			}
+			tx.Unlock()
+			b.ForceCommit()
+			betesting.Close(t, b)
+
+			// reopen the db with its pages read in the background
+			b = backend.New(bcfg)
+			defer betesting.Close(t, b)
+			rtx := b.ReadTx()
+			rtx.RLock()
+			defer rtx.RUnlock()
+			if _, vs := rtx.UnsafeRange(schema.Test, []byte("foo_999"), nil, 0); len(vs) != 1 {
+				t.Fatalf("len(vs) = %d, want 1", len(vs))
+			}
+		})
+	}
+}
+
 func TestBackendWriteback(t *testing.T) {
 	b, _ := betesting.NewDefaultTmpBackend(t)
 	defer betesting.Close(t, b)
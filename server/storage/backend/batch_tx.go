@@ -121,6 +121,9 @@ func (t *batchTx) UnsafeCreateBucket(bucket Bucket) {
 			zap.Error(err),
 		)
 	}
+	if t.backend.defragDirty != nil {
+		t.backend.defragDirty.addBucket(bucket.Name())
+	}
 	t.pending++
 }
 
@@ -133,6 +136,9 @@ func (t *batchTx) UnsafeDeleteBucket(bucket Bucket) {
 			zap.Error(err),
 		)
 	}
+	if t.backend.defragDirty != nil {
+		t.backend.defragDirty.addBucket(bucket.Name())
+	}
 	t.pending++
 }
 
@@ -167,6 +173,9 @@ func (t *batchTx) unsafePut(bucketType Bucket, key []byte, value []byte, seq boo
 			zap.Error(err),
 		)
 	}
+	if t.backend.defragDirty != nil {
+		t.backend.defragDirty.addKey(bucketType.Name(), key)
+	}
 	t.pending++
 }
 
@@ -223,6 +232,9 @@ func (t *batchTx) UnsafeDelete(bucketType Bucket, key []byte) {
 			zap.Error(err),
 		)
 	}
+	if t.backend.defragDirty != nil {
+		t.backend.defragDirty.addKey(bucketType.Name(), key)
+	}
 	t.pending++
 }
 
@@ -274,8 +286,12 @@ func (t *batchTx) commit(stop bool) {
 		rebalanceSec.Observe(t.tx.Stats().RebalanceTime.Seconds())
 		spillSec.Observe(t.tx.Stats().SpillTime.Seconds())
 		writeSec.Observe(t.tx.Stats().WriteTime.Seconds())
-		commitSec.Observe(time.Since(start).Seconds())
+		took := time.Since(start)
+		commitSec.Observe(took.Seconds())
 		atomic.AddInt64(&t.backend.commits, 1)
+		if t.backend.commitObserver != nil {
+			t.backend.commitObserver(took)
+		}
 
 		t.pending = 0
 		if err != nil {
@@ -22,3 +22,10 @@ import bolt "go.etcd.io/bbolt"
 var boltOpenOptions *bolt.Options
 
 func (bcfg *BackendConfig) mmapSize() int { return int(bcfg.MmapSize) }
+
+// mmapPopulate is the mmap flag prefaulting the pages of the db on open, only
+// set on linux.
+const mmapPopulate = 0
+
+// madvise is a no-op, the mmap advices being only applied on linux.
+func madvise(data uintptr, size int64, advice string) error { return nil }
@@ -15,6 +15,7 @@
 package backend
 
 import (
+	"fmt"
 	"syscall"
 
 	bolt "go.etcd.io/bbolt"
@@ -32,3 +33,24 @@ var boltOpenOptions = &bolt.Options{
 }
 
 func (bcfg *BackendConfig) mmapSize() int { return int(bcfg.MmapSize) }
+
+// mmapPopulate is the mmap flag prefaulting the pages of the db on open.
+const mmapPopulate = syscall.MAP_POPULATE
+
+var mmapAdvices = map[string]uintptr{
+	MmapAdviceNormal:   syscall.MADV_NORMAL,
+	MmapAdviceRandom:   syscall.MADV_RANDOM,
+	MmapAdviceWillNeed: syscall.MADV_WILLNEED,
+}
+
+// madvise applies the advice to size bytes of the mmap at data.
+func madvise(data uintptr, size int64, advice string) error {
+	a, ok := mmapAdvices[advice]
+	if !ok {
+		return fmt.Errorf("unknown mmap advice %q", advice)
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_MADVISE, data, uintptr(size), a); errno != 0 {
+		return errno
+	}
+	return nil
+}
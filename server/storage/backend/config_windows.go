@@ -25,3 +25,10 @@ var boltOpenOptions *bolt.Options = nil
 // mmap size for the file, instead of growing it. So, force 0.
 
 func (bcfg *BackendConfig) mmapSize() int { return 0 }
+
+// mmapPopulate is the mmap flag prefaulting the pages of the db on open, only
+// set on linux.
+const mmapPopulate = 0
+
+// madvise is a no-op, the mmap advices being only applied on linux.
+func madvise(data uintptr, size int64, advice string) error { return nil }
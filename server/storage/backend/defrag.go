@@ -0,0 +1,285 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// defragDirty is the set of the buckets and keys written while the db is
+// copied by a defragmentation, to copy again.
+type defragDirty struct {
+	// buckets are the buckets created or deleted, copied again entirely.
+	buckets map[string]struct{}
+	// keys are the keys put or deleted, per bucket.
+	keys map[string]map[string]struct{}
+	n    int
+}
+
+func newDefragDirty() *defragDirty {
+	return &defragDirty{
+		buckets: make(map[string]struct{}),
+		keys:    make(map[string]map[string]struct{}),
+	}
+}
+
+func (d *defragDirty) addBucket(bucket []byte) {
+	if _, ok := d.buckets[string(bucket)]; !ok {
+		d.buckets[string(bucket)] = struct{}{}
+		d.n++
+	}
+}
+
+func (d *defragDirty) addKey(bucket, key []byte) {
+	keys, ok := d.keys[string(bucket)]
+	if !ok {
+		keys = make(map[string]struct{})
+		d.keys[string(bucket)] = keys
+	}
+	if _, ok = keys[string(key)]; !ok {
+		keys[string(key)] = struct{}{}
+		d.n++
+	}
+}
+
+// len returns the number of buckets and keys to copy again.
+func (d *defragDirty) len() int { return d.n }
+
+// copy copies the buckets and keys of the set from tx to tmpdb, committing
+// every limit keys, the keys missing from tx being deleted from tmpdb.
+func (d *defragDirty) copy(tx *bolt.Tx, tmpdb *bolt.DB, limit int) error {
+	w, err := newDefragWriter(tmpdb, limit)
+	if err != nil {
+		return err
+	}
+	defer w.rollback()
+
+	for _, name := range sortedKeys(d.buckets) {
+		bn := []byte(name)
+		if err = w.deleteBucket(bn); err != nil {
+			return err
+		}
+		b := tx.Bucket(bn)
+		if b == nil {
+			continue
+		}
+		if err = w.createBucket(bn); err != nil {
+			return err
+		}
+		if err = b.ForEach(func(k, v []byte) error { return w.put(bn, k, v) }); err != nil {
+			return err
+		}
+	}
+
+	for name, keys := range d.keys {
+		if _, ok := d.buckets[name]; ok {
+			continue
+		}
+		bn := []byte(name)
+		b := tx.Bucket(bn)
+		if b == nil {
+			// deleted since, thus tracked as a bucket
+			continue
+		}
+		if err = w.createBucket(bn); err != nil {
+			return err
+		}
+		c := b.Cursor()
+		for _, key := range sortedKeys(keys) {
+			k := []byte(key)
+			if ck, cv := c.Seek(k); bytes.Equal(ck, k) {
+				err = w.put(bn, k, cv)
+			} else {
+				err = w.delete(bn, k)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return w.commit()
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// defragdb copies the buckets of odb to tmpdb, limit keys at a time, each
+// chunk being read from its own read tx for odb not to pin the pages freed
+// in the meantime. The chunks are not a consistent snapshot of odb, the keys
+// written during the copy are thus to be copied again.
+func defragdb(odb, tmpdb *bolt.DB, limit int) error {
+	var names [][]byte
+	if err := odb.View(func(tx *bolt.Tx) error {
+		c := tx.Cursor()
+		for next, _ := c.First(); next != nil; next, _ = c.Next() {
+			if tx.Bucket(next) == nil {
+				return fmt.Errorf("backend: cannot defrag bucket %s", string(next))
+			}
+			names = append(names, append([]byte(nil), next...))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		var (
+			from []byte
+			err  error
+		)
+		for {
+			if from, err = defragChunk(odb, tmpdb, name, from, limit); err != nil {
+				return err
+			}
+			if from == nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// defragChunk copies up to limit keys of the bucket name of odb following
+// the key after, from the first key if nil, to tmpdb. It returns the last key
+// copied, or nil once the bucket is copied.
+func defragChunk(odb, tmpdb *bolt.DB, name, after []byte, limit int) ([]byte, error) {
+	tx, err := odb.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	b := tx.Bucket(name)
+	if b == nil {
+		// deleted since, thus tracked to be copied again
+		return nil, nil
+	}
+
+	w, err := newDefragWriter(tmpdb, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer w.rollback()
+	if err = w.createBucket(name); err != nil {
+		return nil, err
+	}
+
+	c := b.Cursor()
+	k, v := c.First()
+	if after != nil {
+		if k, v = c.Seek(after); bytes.Equal(k, after) {
+			k, v = c.Next()
+		}
+	}
+	var last []byte
+	for count := 0; k != nil; k, v = c.Next() {
+		if count == limit {
+			last = append([]byte(nil), last...)
+			break
+		}
+		if err = w.put(name, k, v); err != nil {
+			return nil, err
+		}
+		last = k
+		count++
+	}
+	if k == nil {
+		last = nil
+	}
+	return last, w.commit()
+}
+
+// defragWriter writes to the tmp db of a defragmentation, committing every
+// limit writes.
+type defragWriter struct {
+	db    *bolt.DB
+	tx    *bolt.Tx
+	limit int
+	count int
+}
+
+func newDefragWriter(db *bolt.DB, limit int) (*defragWriter, error) {
+	tx, err := db.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	return &defragWriter{db: db, tx: tx, limit: limit}, nil
+}
+
+func (w *defragWriter) createBucket(name []byte) error {
+	_, err := w.tx.CreateBucketIfNotExists(name)
+	return err
+}
+
+func (w *defragWriter) deleteBucket(name []byte) error {
+	if err := w.tx.DeleteBucket(name); err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+	return nil
+}
+
+func (w *defragWriter) put(name, k, v []byte) error {
+	if err := w.next(); err != nil {
+		return err
+	}
+	b := w.tx.Bucket(name)
+	b.FillPercent = 0.9 // for bucket2seq write in for each
+	return b.Put(k, v)
+}
+
+func (w *defragWriter) delete(name, k []byte) error {
+	if err := w.next(); err != nil {
+		return err
+	}
+	return w.tx.Bucket(name).Delete(k)
+}
+
+// next commits the tx every limit writes.
+func (w *defragWriter) next() error {
+	w.count++
+	if w.count <= w.limit {
+		return nil
+	}
+	if err := w.commit(); err != nil {
+		return err
+	}
+	tx, err := w.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	w.tx, w.count = tx, 1
+	return nil
+}
+
+func (w *defragWriter) commit() error {
+	tx := w.tx
+	w.tx = nil
+	return tx.Commit()
+}
+
+func (w *defragWriter) rollback() {
+	if w.tx != nil {
+		w.tx.Rollback()
+	}
+}
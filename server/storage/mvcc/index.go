@@ -15,7 +15,9 @@
 package mvcc
 
 import (
+	"bytes"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/btree"
 	"go.uber.org/zap"
@@ -25,21 +27,31 @@ type index interface {
 	Get(key []byte, atRev int64) (rev, created revision, ver int64, err error)
 	Range(key, end []byte, atRev int64) ([][]byte, []revision)
 	Revisions(key, end []byte, atRev int64, limit int) ([]revision, int)
+	RevisionsDescend(key, end []byte, atRev int64, limit int) ([]revision, int)
 	CountRevisions(key, end []byte, atRev int64) int
+	SampleRevisions(key, end []byte, atRev int64, n int) ([]revision, int)
 	Put(key []byte, rev revision)
 	Tombstone(key []byte, rev revision) error
-	Compact(rev int64) map[revision]struct{}
+	Compact(rev int64, rs []retainedHistory) map[revision]struct{}
 	Keep(rev int64) map[revision]struct{}
+	TombstoneFloor(prefix []byte, atRev, versions int64) int64
+	CompactedAt(key, end []byte, atRev int64) bool
 	Equal(b index) bool
 
 	Insert(ki *keyIndex)
 	KeyIndex(ki *keyIndex) *keyIndex
+
+	// Bytes returns an estimate of the memory held by the index.
+	Bytes() int64
 }
 
 type treeIndex struct {
 	sync.RWMutex
 	tree *btree.BTree
 	lg   *zap.Logger
+	// bytes is the estimate of the memory held by the keyIndexes, updated
+	// under the lock and read atomically.
+	bytes int64
 }
 
 func newTreeIndex(lg *zap.Logger) index {
@@ -58,10 +70,13 @@ func (ti *treeIndex) Put(key []byte, rev revision) {
 	if item == nil {
 		keyi.put(ti.lg, rev.main, rev.sub)
 		ti.tree.ReplaceOrInsert(keyi)
+		atomic.AddInt64(&ti.bytes, keyi.bytes())
 		return
 	}
 	okeyi := item.(*keyIndex)
+	n := okeyi.bytes()
 	okeyi.put(ti.lg, rev.main, rev.sub)
+	atomic.AddInt64(&ti.bytes, okeyi.bytes()-n)
 }
 
 func (ti *treeIndex) Get(key []byte, atRev int64) (modified, created revision, ver int64, err error) {
@@ -105,6 +120,30 @@ func (ti *treeIndex) unsafeVisit(key, end []byte, f func(ki *keyIndex) bool) {
 	})
 }
 
+// unsafeVisitDescend is the same as unsafeVisit, but visits the keys
+// in descending order starting from the largest key smaller than end.
+func (ti *treeIndex) unsafeVisitDescend(key, end []byte, f func(ki *keyIndex) bool) {
+	keyi, endi := &keyIndex{key: key}, &keyIndex{key: end}
+
+	visit := func(item btree.Item) bool {
+		if item.Less(keyi) {
+			return false
+		}
+		return f(item.(*keyIndex))
+	}
+	if len(endi.key) == 0 {
+		ti.tree.Descend(visit)
+		return
+	}
+	ti.tree.DescendLessOrEqual(endi, func(item btree.Item) bool {
+		if !item.Less(endi) {
+			// end is excluded
+			return true
+		}
+		return visit(item)
+	})
+}
+
 // Revisions returns limited number of revisions from key(included) to end(excluded)
 // at the given rev. The returned slice is sorted in the order of key. There is no limit if limit <= 0.
 // The second return parameter isn't capped by the limit and reflects the total number of revisions.
@@ -131,6 +170,68 @@ func (ti *treeIndex) Revisions(key, end []byte, atRev int64, limit int) (revs []
 	return revs, total
 }
 
+// RevisionsDescend is the same as Revisions, but the returned slice is sorted
+// in descending order of key. The index is walked backwards, so the limit
+// applies to the largest keys of the range without visiting it twice.
+func (ti *treeIndex) RevisionsDescend(key, end []byte, atRev int64, limit int) (revs []revision, total int) {
+	if end == nil {
+		return ti.Revisions(key, end, atRev, limit)
+	}
+
+	ti.RLock()
+	defer ti.RUnlock()
+
+	ti.unsafeVisitDescend(key, end, func(ki *keyIndex) bool {
+		if rev, _, _, err := ki.get(ti.lg, atRev); err == nil {
+			if limit <= 0 || len(revs) < limit {
+				revs = append(revs, rev)
+			}
+			total++
+		}
+		return true
+	})
+	return revs, total
+}
+
+// SampleRevisions returns at most n revisions evenly spread over the keys from
+// key(included) to end(excluded) at the given rev, in the order of key.
+// The second return parameter is the total number of revisions in the range.
+// The index is walked once; whenever the sample is full, every other sampled
+// revision is dropped and the sampling stride is doubled.
+func (ti *treeIndex) SampleRevisions(key, end []byte, atRev int64, n int) (revs []revision, total int) {
+	if end == nil || n <= 0 {
+		return ti.Revisions(key, end, atRev, n)
+	}
+
+	ti.RLock()
+	defer ti.RUnlock()
+
+	stride := 1
+	ti.unsafeVisit(key, end, func(ki *keyIndex) bool {
+		rev, _, _, err := ki.get(ti.lg, atRev)
+		if err != nil {
+			return true
+		}
+		if total%stride == 0 {
+			if len(revs) == n {
+				j := 0
+				for i := 0; i < len(revs); i += 2 {
+					revs[j] = revs[i]
+					j++
+				}
+				revs = revs[:j]
+				stride *= 2
+			}
+			if total%stride == 0 {
+				revs = append(revs, rev)
+			}
+		}
+		total++
+		return true
+	})
+	return revs, total
+}
+
 // CountRevisions returns the number of revisions
 // from key(included) to end(excluded) at the given rev.
 func (ti *treeIndex) CountRevisions(key, end []byte, atRev int64) int {
@@ -186,10 +287,15 @@ func (ti *treeIndex) Tombstone(key []byte, rev revision) error {
 	}
 
 	ki := item.(*keyIndex)
-	return ki.tombstone(ti.lg, rev.main, rev.sub)
+	n := ki.bytes()
+	err := ki.tombstone(ti.lg, rev.main, rev.sub)
+	atomic.AddInt64(&ti.bytes, ki.bytes()-n)
+	return err
 }
 
-func (ti *treeIndex) Compact(rev int64) map[revision]struct{} {
+// Compact compacts the index at rev, retaining the history of the keys with
+// the prefixes of rs. It returns the revisions to keep in the backend.
+func (ti *treeIndex) Compact(rev int64, rs []retainedHistory) map[revision]struct{} {
 	available := make(map[revision]struct{})
 	ti.lg.Info("compact tree index", zap.Int64("revision", rev))
 	ti.Lock()
@@ -201,12 +307,21 @@ func (ti *treeIndex) Compact(rev int64) map[revision]struct{} {
 		// Lock is needed here to prevent modification to the keyIndex while
 		// compaction is going on or revision added to empty before deletion
 		ti.Lock()
-		keyi.compact(ti.lg, rev, available)
+		n := keyi.bytes()
+		if keyRev := retainedRev(keyi, rev, rs); keyRev < rev {
+			keyi.retain(keyRev, rev, available)
+			keyi.compact(ti.lg, keyRev, available)
+		} else {
+			keyi.compact(ti.lg, rev, available)
+		}
 		if keyi.isEmpty() {
 			item := ti.tree.Delete(keyi)
 			if item == nil {
 				ti.lg.Panic("failed to delete during compaction")
 			}
+			atomic.AddInt64(&ti.bytes, -n)
+		} else {
+			atomic.AddInt64(&ti.bytes, keyi.bytes()-n)
 		}
 		ti.Unlock()
 		return true
@@ -227,6 +342,43 @@ func (ti *treeIndex) Keep(rev int64) map[revision]struct{} {
 	return available
 }
 
+// TombstoneFloor returns the revision of the latest tombstone of the keys with
+// the prefix removed on compaction at atRev, their latest versions retained.
+func (ti *treeIndex) TombstoneFloor(prefix []byte, atRev, versions int64) int64 {
+	floor := int64(0)
+	ti.RLock()
+	defer ti.RUnlock()
+	ti.unsafeVisit(prefix, nil, func(ki *keyIndex) bool {
+		if !bytes.HasPrefix(ki.key, prefix) {
+			return false
+		}
+		if t := ki.tombstoneRev(ki.versionsRev(atRev, versions)); t > floor {
+			floor = t
+		}
+		return true
+	})
+	return floor
+}
+
+// CompactedAt returns whether the version at atRev of any key of the range
+// [key, end) is compacted.
+func (ti *treeIndex) CompactedAt(key, end []byte, atRev int64) bool {
+	compacted := false
+	ti.RLock()
+	defer ti.RUnlock()
+	if end == nil {
+		if ki := ti.keyIndex(&keyIndex{key: key}); ki != nil {
+			compacted = ki.compactedAt(atRev)
+		}
+		return compacted
+	}
+	ti.unsafeVisit(key, end, func(ki *keyIndex) bool {
+		compacted = ki.compactedAt(atRev)
+		return !compacted
+	})
+	return compacted
+}
+
 func (ti *treeIndex) Equal(bi index) bool {
 	b := bi.(*treeIndex)
 
@@ -258,5 +410,13 @@ func (ti *treeIndex) Equal(bi index) bool {
 func (ti *treeIndex) Insert(ki *keyIndex) {
 	ti.Lock()
 	defer ti.Unlock()
-	ti.tree.ReplaceOrInsert(ki)
+	n := ki.bytes()
+	if item := ti.tree.ReplaceOrInsert(ki); item != nil {
+		n -= item.(*keyIndex).bytes()
+	}
+	atomic.AddInt64(&ti.bytes, n)
+}
+
+func (ti *treeIndex) Bytes() int64 {
+	return atomic.LoadInt64(&ti.bytes)
 }
@@ -37,6 +37,6 @@ func benchmarkIndexCompact(b *testing.B, size int) {
 	}
 	b.ResetTimer()
 	for i := 1; i < b.N; i++ {
-		kvindex.Compact(int64(i))
+		kvindex.Compact(int64(i), nil)
 	}
 }
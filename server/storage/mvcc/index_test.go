@@ -15,6 +15,7 @@
 package mvcc
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -139,6 +140,36 @@ func TestIndexTombstone(t *testing.T) {
 	}
 }
 
+func TestIndexBytes(t *testing.T) {
+	ti := newTreeIndex(zaptest.NewLogger(t)).(*treeIndex)
+	check := func(name string) {
+		var want int64
+		ti.tree.Ascend(func(item btree.Item) bool {
+			want += item.(*keyIndex).bytes()
+			return true
+		})
+		if got := ti.Bytes(); got != want {
+			t.Errorf("%s: bytes = %d, want %d", name, got, want)
+		}
+	}
+
+	for i := int64(1); i <= 10; i++ {
+		ti.Put([]byte(fmt.Sprintf("foo%d", i%3)), revision{main: i})
+	}
+	check("put")
+	if err := ti.Tombstone([]byte("foo1"), revision{main: 11}); err != nil {
+		t.Fatal(err)
+	}
+	check("tombstone")
+	ti.Compact(8, nil)
+	check("compact")
+	ti.Insert(&keyIndex{key: []byte("bar"), generations: []generation{{ver: 1, created: revision{main: 12}, revs: []revision{{main: 12}}}}})
+	check("insert")
+	ti.Compact(12, nil)
+	ti.Compact(13, nil)
+	check("compact all")
+}
+
 func TestIndexRevision(t *testing.T) {
 	allKeys := [][]byte{[]byte("foo"), []byte("foo1"), []byte("foo2"), []byte("foo2"), []byte("foo1"), []byte("foo")}
 	allRevs := []revision{{main: 1}, {main: 2}, {main: 3}, {main: 4}, {main: 5}, {main: 6}}
@@ -233,6 +264,105 @@ func TestIndexRevision(t *testing.T) {
 	}
 }
 
+func TestIndexRevisionsDescend(t *testing.T) {
+	allKeys := [][]byte{[]byte("foo"), []byte("foo1"), []byte("foo2"), []byte("foo2"), []byte("foo1"), []byte("foo")}
+	allRevs := []revision{{main: 1}, {main: 2}, {main: 3}, {main: 4}, {main: 5}, {main: 6}}
+
+	ti := newTreeIndex(zaptest.NewLogger(t))
+	for i := range allKeys {
+		ti.Put(allKeys[i], allRevs[i])
+	}
+
+	tests := []struct {
+		key, end []byte
+		atRev    int64
+		limit    int
+		wrevs    []revision
+		wcounts  int
+	}{
+		// single key that not found
+		{
+			[]byte("bar"), nil, 6, 0, nil, 0,
+		},
+		// single key that found
+		{
+			[]byte("foo"), nil, 6, 0, []revision{{main: 6}}, 1,
+		},
+		// various range keys, fixed atRev, unlimited
+		{
+			[]byte("foo"), []byte("foo1"), 6, 0, []revision{{main: 6}}, 1,
+		},
+		{
+			[]byte("foo"), []byte("foo2"), 6, 0, []revision{{main: 5}, {main: 6}}, 2,
+		},
+		{
+			[]byte("foo"), []byte("fop"), 6, 0, []revision{{main: 4}, {main: 5}, {main: 6}}, 3,
+		},
+		{
+			[]byte("foo1"), []byte("fop"), 6, 0, []revision{{main: 4}, {main: 5}}, 2,
+		},
+		{
+			[]byte("foo3"), []byte("fop"), 6, 0, nil, 0,
+		},
+		// all keys greater than or equal to the given key
+		{
+			[]byte("foo1"), []byte{}, 6, 0, []revision{{main: 4}, {main: 5}}, 2,
+		},
+		// fixed range keys, various atRev, unlimited
+		{
+			[]byte("foo1"), []byte("fop"), 3, 0, []revision{{main: 3}, {main: 2}}, 2,
+		},
+		// fixed range keys, fixed atRev, various limit
+		{
+			[]byte("foo"), []byte("fop"), 6, 1, []revision{{main: 4}}, 3,
+		},
+		{
+			[]byte("foo"), []byte("fop"), 6, 2, []revision{{main: 4}, {main: 5}}, 3,
+		},
+		{
+			[]byte("foo"), []byte("fop"), 3, 2, []revision{{main: 3}, {main: 2}}, 3,
+		},
+	}
+	for i, tt := range tests {
+		revs, total := ti.RevisionsDescend(tt.key, tt.end, tt.atRev, tt.limit)
+		if !reflect.DeepEqual(revs, tt.wrevs) {
+			t.Errorf("#%d limit %d: revs = %+v, want %+v", i, tt.limit, revs, tt.wrevs)
+		}
+		if total != tt.wcounts {
+			t.Errorf("#%d: total = %d, want %v", i, total, tt.wcounts)
+		}
+	}
+}
+
+func TestIndexSampleRevisions(t *testing.T) {
+	ti := newTreeIndex(zaptest.NewLogger(t))
+	for i := 0; i < 10; i++ {
+		ti.Put([]byte(fmt.Sprintf("foo%d", i)), revision{main: int64(i + 1)})
+	}
+
+	tests := []struct {
+		n       int
+		wrevs   []revision
+		wcounts int
+	}{
+		{1, []revision{{main: 1}}, 10},
+		{3, []revision{{main: 1}, {main: 5}, {main: 9}}, 10},
+		{4, []revision{{main: 1}, {main: 5}, {main: 9}}, 10},
+		{5, []revision{{main: 1}, {main: 3}, {main: 5}, {main: 7}, {main: 9}}, 10},
+		{10, []revision{{main: 1}, {main: 2}, {main: 3}, {main: 4}, {main: 5}, {main: 6}, {main: 7}, {main: 8}, {main: 9}, {main: 10}}, 10},
+		{100, []revision{{main: 1}, {main: 2}, {main: 3}, {main: 4}, {main: 5}, {main: 6}, {main: 7}, {main: 8}, {main: 9}, {main: 10}}, 10},
+	}
+	for i, tt := range tests {
+		revs, total := ti.SampleRevisions([]byte("foo"), []byte("fop"), 10, tt.n)
+		if !reflect.DeepEqual(revs, tt.wrevs) {
+			t.Errorf("#%d n %d: revs = %+v, want %+v", i, tt.n, revs, tt.wrevs)
+		}
+		if total != tt.wcounts {
+			t.Errorf("#%d n %d: total = %d, want %d", i, tt.n, total, tt.wcounts)
+		}
+	}
+}
+
 func TestIndexCompactAndKeep(t *testing.T) {
 	maxRev := int64(20)
 	tests := []struct {
@@ -265,7 +395,7 @@ func TestIndexCompactAndKeep(t *testing.T) {
 		}
 	}
 	for i := int64(1); i < maxRev; i++ {
-		am := ti.Compact(i)
+		am := ti.Compact(i, nil)
 		keep := ti.Keep(i)
 		if !(reflect.DeepEqual(am, keep)) {
 			t.Errorf("#%d: compact keep %v != Keep keep %v", i, am, keep)
@@ -295,7 +425,7 @@ func TestIndexCompactAndKeep(t *testing.T) {
 				ti.Put(tt.key, tt.rev)
 			}
 		}
-		am := ti.Compact(i)
+		am := ti.Compact(i, nil)
 		keep := ti.Keep(i)
 		if !(reflect.DeepEqual(am, keep)) {
 			t.Errorf("#%d: compact keep %v != Keep keep %v", i, am, keep)
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"unsafe"
 
 	"github.com/google/btree"
 	"go.uber.org/zap"
@@ -270,10 +271,73 @@ func (ki *keyIndex) doCompact(atRev int64, available map[revision]struct{}) (gen
 	return genIdx, revIndex
 }
 
+// versionsRev returns the revision of the n-th latest version of the key as
+// of atRev, or 0 if the key has less versions.
+func (ki *keyIndex) versionsRev(atRev, n int64) int64 {
+	for gi := len(ki.generations) - 1; gi >= 0; gi-- {
+		revs := ki.generations[gi].revs
+		for i := len(revs) - 1; i >= 0; i-- {
+			if revs[i].main > atRev {
+				continue
+			}
+			if n--; n == 0 {
+				return revs[i].main
+			}
+		}
+	}
+	return 0
+}
+
+// tombstoneRev returns the revision of the latest tombstone of the key at or
+// below atRev, i.e. of the latest generation removed on compaction at atRev,
+// or 0 if there is none.
+func (ki *keyIndex) tombstoneRev(atRev int64) int64 {
+	// the last generation has no tombstone
+	for gi := len(ki.generations) - 2; gi >= 0; gi-- {
+		revs := ki.generations[gi].revs
+		if len(revs) != 0 && revs[len(revs)-1].main <= atRev {
+			return revs[len(revs)-1].main
+		}
+	}
+	return 0
+}
+
+// retain adds the revisions of the key after keyRev up to atRev to available,
+// the key being compacted at keyRev on compaction at atRev.
+func (ki *keyIndex) retain(keyRev, atRev int64, available map[revision]struct{}) {
+	for _, g := range ki.generations {
+		for _, rev := range g.revs {
+			if rev.main > keyRev && rev.main <= atRev {
+				available[rev] = struct{}{}
+			}
+		}
+	}
+}
+
+// compactedAt returns whether the version of the key at atRev is compacted,
+// the key existing at atRev but its revision there being removed.
+func (ki *keyIndex) compactedAt(atRev int64) bool {
+	for _, g := range ki.generations {
+		if !g.isEmpty() && g.created.main <= atRev && g.revs[0].main > atRev {
+			return true
+		}
+	}
+	return false
+}
+
 func (ki *keyIndex) isEmpty() bool {
 	return len(ki.generations) == 1 && ki.generations[0].isEmpty()
 }
 
+// bytes returns an estimate of the memory held by the keyIndex.
+func (ki *keyIndex) bytes() int64 {
+	n := int64(unsafe.Sizeof(*ki)) + int64(len(ki.key)) + int64(cap(ki.generations))*int64(unsafe.Sizeof(generation{}))
+	for _, g := range ki.generations {
+		n += int64(cap(g.revs)) * int64(unsafe.Sizeof(revision{}))
+	}
+	return n
+}
+
 // findGeneration finds out the generation of the keyIndex that the
 // given rev belongs to. If the given rev is at the gap of two generations,
 // which means that the key does not exist at the given rev, it returns nil.
@@ -27,6 +27,13 @@ type RangeOptions struct {
 	Limit int64
 	Rev   int64
 	Count bool
+	// Descend returns the key-value pairs in descending key order.
+	Descend bool
+	// CompactionBarrier registers a compaction barrier at the revision of the
+	// range, holding back the compaction of the index and the backend above it
+	// for the ranges at the revision to succeed once it is compacted, until the
+	// barrier expires.
+	CompactionBarrier bool
 }
 
 type RangeResult struct {
@@ -79,6 +86,11 @@ type WriteView interface {
 	// A put also increases the rev of the store, and generates one event in the event history.
 	// The returned rev is the current revision of the KV when the operation is executed.
 	Put(key, value []byte, lease lease.LeaseID) (rev int64)
+
+	// PutWithAnnotations is the same as Put, but also attaches the given
+	// user-defined annotations to the key-value pair. The annotations replace
+	// the ones of the previous revision of the key.
+	PutWithAnnotations(key, value []byte, lease lease.LeaseID, annotations map[string]string) (rev int64)
 }
 
 // TxnWrite represents a transaction that can modify the store.
@@ -96,6 +108,9 @@ func (trw *txnReadWrite) DeleteRange(key, end []byte) (n, rev int64) { panic("un
 func (trw *txnReadWrite) Put(key, value []byte, lease lease.LeaseID) (rev int64) {
 	panic("unexpected Put")
 }
+func (trw *txnReadWrite) PutWithAnnotations(key, value []byte, lease lease.LeaseID, annotations map[string]string) (rev int64) {
+	panic("unexpected PutWithAnnotations")
+}
 func (trw *txnReadWrite) Changes() []mvccpb.KeyValue { return nil }
 
 func NewReadOnlyTxnWrite(txn TxnRead) TxnWrite { return &txnReadWrite{txn} }
@@ -122,9 +137,30 @@ type KV interface {
 	// HashStorage returns HashStorage interface for KV storage.
 	HashStorage() HashStorage
 
+	// IndexBytes returns an estimate of the memory held by the index of
+	// the keys.
+	IndexBytes() int64
+
+	// Estimate returns the approximate number and size of the keys in
+	// the given range without reading the whole range from the backend.
+	Estimate(key, end []byte, sampleSize int) EstimateResult
+
+	// Tombstones returns the number and size of the tombstones of the deleted
+	// keys in the backend, which are removed by compacting past them.
+	Tombstones() TombstoneStats
+
+	// Events returns the events of the revisions from start to end, excluded,
+	// about limit at most, and the revision of the next events.
+	Events(start, end int64, limit int) ([]mvccpb.Event, int64, error)
+
 	// Compact frees all superseded keys with revisions less than rev.
 	Compact(trace *traceutil.Trace, rev int64) (<-chan struct{}, error)
 
+	// CompactWithRetention is Compact retaining the history of the keys with
+	// the prefixes of the retentions, for them to be read at the compacted
+	// revisions.
+	CompactWithRetention(trace *traceutil.Trace, rev int64, rs []Retention) (<-chan struct{}, error)
+
 	// Commit commits outstanding txns into the underlying backend.
 	Commit()
 
@@ -133,6 +169,18 @@ type KV interface {
 	Close() error
 }
 
+// WriteBatcher is a KV whose write txns can be batched, to take the locks of
+// the store once for many small txns.
+type WriteBatcher interface {
+	// BeginWriteBatch begins a write batch. The write txns until EndWriteBatch
+	// are written to consecutive revisions, visible to the read txns and to
+	// the watchers only once the batch ends: the read txns of the batch see
+	// the store as of before it. The write txns must not be concurrent.
+	BeginWriteBatch()
+	// EndWriteBatch ends the write batch.
+	EndWriteBatch()
+}
+
 // WatchableKV is a KV that can be watched.
 type WatchableKV interface {
 	KV
@@ -255,6 +255,80 @@ func testKVRangeLimit(t *testing.T, f rangeFunc) {
 	}
 }
 
+func TestKVRangeDescend(t *testing.T)    { testKVRangeDescend(t, normalRangeFunc) }
+func TestKVTxnRangeDescend(t *testing.T) { testKVRangeDescend(t, txnRangeFunc) }
+
+func testKVRangeDescend(t *testing.T, f rangeFunc) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b, tmpPath)
+
+	kvs := put3TestKVs(s)
+	rkvs := []mvccpb.KeyValue{kvs[2], kvs[1], kvs[0]}
+
+	tests := []struct {
+		limit int64
+		wkvs  []mvccpb.KeyValue
+	}{
+		{0, rkvs},
+		{1, rkvs[:1]},
+		{2, rkvs[:2]},
+		{100, rkvs},
+	}
+	for i, tt := range tests {
+		r, err := f(s, []byte("foo"), []byte("foo3"), RangeOptions{Limit: tt.limit, Descend: true})
+		if err != nil {
+			t.Fatalf("#%d: range error (%v)", i, err)
+		}
+		if !reflect.DeepEqual(r.KVs, tt.wkvs) {
+			t.Errorf("#%d: kvs = %+v, want %+v", i, r.KVs, tt.wkvs)
+		}
+		if r.Count != len(kvs) {
+			t.Errorf("#%d: count = %d, want %d", i, r.Count, len(kvs))
+		}
+	}
+}
+
+func TestKVEstimate(t *testing.T) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b, tmpPath)
+
+	for i := 0; i < 100; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%02d", i)), []byte("bar"), lease.NoLease)
+	}
+	// key "fooNN" and value "bar"
+	kvSize := int64(len("foo00") + len("bar"))
+
+	tests := []struct {
+		key, end   []byte
+		sampleSize int
+		wcount     int64
+		wsampled   int64
+	}{
+		{[]byte("foo"), []byte("fop"), 0, 100, 100},
+		{[]byte("foo"), []byte("fop"), 10, 100, 7},
+		{[]byte("foo10"), []byte("foo20"), 0, 10, 10},
+		{[]byte("foo00"), nil, 0, 1, 1},
+		{[]byte("bar"), []byte("baz"), 0, 0, 0},
+	}
+	for i, tt := range tests {
+		r := s.Estimate(tt.key, tt.end, tt.sampleSize)
+		if r.Rev != 101 {
+			t.Errorf("#%d: rev = %d, want %d", i, r.Rev, 101)
+		}
+		if r.Count != tt.wcount {
+			t.Errorf("#%d: count = %d, want %d", i, r.Count, tt.wcount)
+		}
+		if r.Sampled != tt.wsampled {
+			t.Errorf("#%d: sampled = %d, want %d", i, r.Sampled, tt.wsampled)
+		}
+		if r.Size != tt.wcount*kvSize {
+			t.Errorf("#%d: size = %d, want %d", i, r.Size, tt.wcount*kvSize)
+		}
+	}
+}
+
 func TestKVPutMultipleTimes(t *testing.T)    { testKVPutMultipleTimes(t, normalPutFunc) }
 func TestKVTxnPutMultipleTimes(t *testing.T) { testKVPutMultipleTimes(t, txnPutFunc) }
 
@@ -49,13 +49,34 @@ const (
 var restoreChunkKeys = 10000 // non-const for testing
 var defaultCompactBatchLimit = 1000
 var minimumBatchInterval = 10 * time.Millisecond
+var defaultCompactionMaxPause = time.Second
 
 type StoreConfig struct {
 	CompactionBatchLimit    int
 	CompactionSleepInterval time.Duration
+	// CompactionPreempted returns whether the compaction should pause between
+	// its batches to yield to the foreground traffic, e.g. while the backend
+	// commits are slow. The compaction never pauses if nil.
+	CompactionPreempted func() bool
+	// CompactionMaxPause is the maximum time the compaction pauses between two
+	// batches while preempted, for it to progress under sustained load.
+	CompactionMaxPause time.Duration
+	// CompactionBarrierMaxDuration is the maximum time the compaction barrier
+	// of the ranges at a revision holds back the compaction above it. The
+	// ranges do not register compaction barriers if 0.
+	CompactionBarrierMaxDuration time.Duration
+	// CompactionBarrierMaxRevisions is the maximum number of revisions the
+	// current revision moves past the revision of a compaction barrier before
+	// it expires. The compaction barriers only expire with time if 0.
+	CompactionBarrierMaxRevisions int64
 }
 
 type store struct {
+	// tombstones and tombstonesSize must use atomic operations to access;
+	// keep 64-bit aligned.
+	tombstones     int64
+	tombstonesSize int64
+
 	ReadView
 	WriteView
 
@@ -77,9 +98,29 @@ type store struct {
 	currentRev int64
 	// compactMainRev is the main revision of the last compaction.
 	compactMainRev int64
+	// retained is the history retained by the last compaction, written with
+	// heldMu locked.
+	retained []retainedHistory
+
+	// batch is the write batch begun by BeginWriteBatch, only accessed by the
+	// writer of the store.
+	batch *writeBatch
 
 	fifoSched schedule.Scheduler
 
+	// barrierMu protects barriers and compactingRev.
+	barrierMu sync.Mutex
+	// barriers maps the revisions pinned by the compaction barriers of the
+	// ranges to the expiry of their barrier.
+	barriers map[int64]time.Time
+	// compactingRev is the revision the index and the backend are compacted,
+	// or being compacted, up to.
+	compactingRev int64
+	// heldMu is read locked by the ranges holding back the compaction, for
+	// the compaction to wait for them before compacting the index and the
+	// backend.
+	heldMu sync.RWMutex
+
 	stopc chan struct{}
 
 	lg     *zap.Logger
@@ -98,6 +139,9 @@ func NewStore(lg *zap.Logger, b backend.Backend, le lease.Lessor, cfg StoreConfi
 	if cfg.CompactionSleepInterval == 0 {
 		cfg.CompactionSleepInterval = minimumBatchInterval
 	}
+	if cfg.CompactionMaxPause == 0 {
+		cfg.CompactionMaxPause = defaultCompactionMaxPause
+	}
 	s := &store{
 		cfg:     cfg,
 		b:       b,
@@ -110,6 +154,9 @@ func NewStore(lg *zap.Logger, b backend.Backend, le lease.Lessor, cfg StoreConfi
 
 		fifoSched: schedule.NewFIFOScheduler(lg),
 
+		barriers:      make(map[int64]time.Time),
+		compactingRev: -1,
+
 		stopc: make(chan struct{}),
 
 		lg: lg,
@@ -198,7 +245,7 @@ func (s *store) hashByRev(rev int64) (hash KeyValueHash, currentRev int64, err e
 	return hash, currentRev, err
 }
 
-func (s *store) updateCompactRev(rev int64) (<-chan struct{}, int64, error) {
+func (s *store) updateCompactRev(rev int64, rs []Retention) (<-chan struct{}, int64, error) {
 	s.revMu.Lock()
 	if rev <= s.compactMainRev {
 		ch := make(chan struct{})
@@ -215,6 +262,7 @@ func (s *store) updateCompactRev(rev int64) (<-chan struct{}, int64, error) {
 	s.compactMainRev = rev
 
 	SetScheduledCompact(s.b.BatchTx(), rev)
+	SetScheduledRetention(s.b.BatchTx(), rs, s.retained != nil)
 	// ensure that desired compaction is persisted
 	s.b.ForceCommit()
 
@@ -223,14 +271,18 @@ func (s *store) updateCompactRev(rev int64) (<-chan struct{}, int64, error) {
 	return nil, compactMainRev, nil
 }
 
-func (s *store) compact(trace *traceutil.Trace, rev, prevCompactRev int64) (<-chan struct{}, error) {
+func (s *store) compact(trace *traceutil.Trace, rev, prevCompactRev int64, rs []Retention) (<-chan struct{}, error) {
 	ch := make(chan struct{})
 	j := schedule.NewJob("kvstore_compact", func(ctx context.Context) {
 		if ctx.Err() != nil {
 			s.compactBarrier(ctx, ch)
 			return
 		}
-		hash, err := s.scheduleCompaction(rev, prevCompactRev)
+		if err := s.waitCompactionBarriers(ctx, rev); err != nil {
+			s.compactBarrier(ctx, ch)
+			return
+		}
+		hash, err := s.scheduleCompaction(rev, prevCompactRev, rs)
 		if err != nil {
 			s.lg.Warn("Failed compaction", zap.Error(err))
 			s.compactBarrier(context.TODO(), ch)
@@ -245,19 +297,23 @@ func (s *store) compact(trace *traceutil.Trace, rev, prevCompactRev int64) (<-ch
 	return ch, nil
 }
 
-func (s *store) compactLockfree(rev int64) (<-chan struct{}, error) {
-	ch, prevCompactRev, err := s.updateCompactRev(rev)
+func (s *store) compactLockfree(rev int64, rs []Retention) (<-chan struct{}, error) {
+	ch, prevCompactRev, err := s.updateCompactRev(rev, rs)
 	if err != nil {
 		return ch, err
 	}
 
-	return s.compact(traceutil.TODO(), rev, prevCompactRev)
+	return s.compact(traceutil.TODO(), rev, prevCompactRev, rs)
 }
 
 func (s *store) Compact(trace *traceutil.Trace, rev int64) (<-chan struct{}, error) {
+	return s.CompactWithRetention(trace, rev, nil)
+}
+
+func (s *store) CompactWithRetention(trace *traceutil.Trace, rev int64, rs []Retention) (<-chan struct{}, error) {
 	s.mu.Lock()
 
-	ch, prevCompactRev, err := s.updateCompactRev(rev)
+	ch, prevCompactRev, err := s.updateCompactRev(rev, rs)
 	trace.Step("check and update compact revision")
 	if err != nil {
 		s.mu.Unlock()
@@ -265,7 +321,7 @@ func (s *store) Compact(trace *traceutil.Trace, rev int64) (<-chan struct{}, err
 	}
 	s.mu.Unlock()
 
-	return s.compact(trace, rev, prevCompactRev)
+	return s.compact(trace, rev, prevCompactRev, rs)
 }
 
 func (s *store) Commit() {
@@ -289,10 +345,12 @@ func (s *store) Restore(b backend.Backend) error {
 		s.revMu.Lock()
 		s.currentRev = 1
 		s.compactMainRev = -1
+		s.retained = nil
 		s.revMu.Unlock()
 	}
 
 	s.fifoSched = schedule.NewFIFOScheduler(s.lg)
+	s.resetCompactionBarriers(-1)
 	s.stopc = make(chan struct{})
 
 	return s.restore()
@@ -315,6 +373,8 @@ func (s *store) restore() error {
 	if found {
 		s.revMu.Lock()
 		s.compactMainRev = finishedCompact
+		s.retained = unsafeReadFinishedRetention(tx)
+		s.resetCompactionBarriers(finishedCompact)
 
 		s.lg.Info(
 			"restored last compact revision",
@@ -326,6 +386,7 @@ func (s *store) restore() error {
 	scheduledCompact, _ := UnsafeReadScheduledCompact(tx)
 	// index keys concurrently as they're loaded in from tx
 	keysGauge.Set(0)
+	s.resetTombstones()
 	rkvc, revc := restoreIntoIndex(s.lg, s.kvindex)
 	for {
 		keys, vals := tx.UnsafeRange(schema.Key, min, max, int64(restoreChunkKeys))
@@ -335,6 +396,11 @@ func (s *store) restore() error {
 		// rkvc blocks if the total pending keys exceeds the restore
 		// chunk size to keep keys from consuming too much memory.
 		restoreChunk(s.lg, rkvc, keys, vals, keyToLease)
+		for i := range keys {
+			if isTombstone(keys[i]) {
+				s.addTombstone(1, keys[i], vals[i])
+			}
+		}
 		if len(keys) < restoreChunkKeys {
 			// partial set implies final set
 			break
@@ -359,8 +425,11 @@ func (s *store) restore() error {
 		s.revMu.Unlock()
 	}
 
+	var scheduledRetention []Retention
 	if scheduledCompact <= s.compactMainRev {
 		scheduledCompact = 0
+	} else {
+		scheduledRetention = UnsafeReadScheduledRetention(tx)
 	}
 
 	for key, lid := range keyToLease {
@@ -383,7 +452,7 @@ func (s *store) restore() error {
 	s.lg.Info("kvstore restored", zap.Int64("current-rev", s.currentRev))
 
 	if scheduledCompact != 0 {
-		if _, err := s.compactLockfree(scheduledCompact); err != nil {
+		if _, err := s.compactLockfree(scheduledCompact, scheduledRetention); err != nil {
 			s.lg.Warn("compaction encountered error", zap.Error(err))
 		}
 
@@ -484,6 +553,12 @@ func (s *store) setupMetricsReporter() {
 	reportDbOpenReadTxNMu.Lock()
 	reportDbOpenReadTxN = func() float64 { return float64(b.OpenReadTxN()) }
 	reportDbOpenReadTxNMu.Unlock()
+	reportDbTombstonesMu.Lock()
+	reportDbTombstones = func() float64 { return float64(s.Tombstones().Count) }
+	reportDbTombstonesMu.Unlock()
+	reportDbTombstonesSizeMu.Lock()
+	reportDbTombstonesSize = func() float64 { return float64(s.Tombstones().Size) }
+	reportDbTombstonesSizeMu.Unlock()
 	reportCurrentRevMu.Lock()
 	reportCurrentRev = func() float64 {
 		s.revMu.RLock()
@@ -520,3 +595,9 @@ func isTombstone(b []byte) bool {
 func (s *store) HashStorage() HashStorage {
 	return s.hashes
 }
+
+func (s *store) IndexBytes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.kvindex.Bytes()
+}
@@ -15,6 +15,7 @@
 package mvcc
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"time"
@@ -23,9 +24,26 @@ import (
 	"go.uber.org/zap"
 )
 
-func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyValueHash, error) {
+func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64, rs []Retention) (KeyValueHash, error) {
 	totalStart := time.Now()
-	keep := s.kvindex.Compact(compactMainRev)
+	retained := s.retainHistory(compactMainRev, prevCompactRev, rs)
+	// the history retained is not hashed, for the hash not to depend on the
+	// retention
+	var hashKeep map[revision]struct{}
+	if len(retained) != 0 {
+		hashKeep = s.kvindex.Keep(compactMainRev)
+	}
+	// wait for the ranges of the history retained by the previous compaction
+	s.heldMu.Lock()
+	s.revMu.Lock()
+	retainedBefore := s.retained != nil
+	s.retained = retained
+	s.revMu.Unlock()
+	keep := s.kvindex.Compact(compactMainRev, retained)
+	s.heldMu.Unlock()
+	if hashKeep == nil {
+		hashKeep = keep
+	}
 	indexCompactionPauseMs.Observe(float64(time.Since(totalStart) / time.Millisecond))
 
 	totalStart = time.Now()
@@ -39,7 +57,7 @@ func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyVal
 
 	batchNum := s.cfg.CompactionBatchLimit
 	batchInterval := s.cfg.CompactionSleepInterval
-	h := newKVHasher(prevCompactRev, compactMainRev, keep)
+	h := newKVHasher(prevCompactRev, compactMainRev, hashKeep)
 	last := make([]byte, 8+1+8)
 	for {
 		var rev revision
@@ -54,12 +72,16 @@ func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyVal
 			if _, ok := keep[rev]; !ok {
 				tx.UnsafeDelete(schema.Key, keys[i])
 				keyCompactions++
+				if isTombstone(keys[i]) {
+					s.addTombstone(-1, keys[i], values[i])
+				}
 			}
 			h.WriteKeyValue(keys[i], values[i])
 		}
 
 		if len(keys) < batchNum {
 			UnsafeSetFinishedCompact(tx, compactMainRev)
+			unsafeSetFinishedRetention(tx, retained, retainedBefore)
 			tx.Unlock()
 			hash := h.Hash()
 			s.lg.Info(
@@ -83,5 +105,136 @@ func (s *store) scheduleCompaction(compactMainRev, prevCompactRev int64) (KeyVal
 		case <-s.stopc:
 			return KeyValueHash{}, fmt.Errorf("interrupted due to stop signal")
 		}
+		if err := s.pauseCompaction(batchInterval); err != nil {
+			return KeyValueHash{}, err
+		}
+	}
+}
+
+// pauseCompaction pauses the compaction while it is preempted, checking every
+// interval, for at most CompactionMaxPause.
+func (s *store) pauseCompaction(interval time.Duration) error {
+	if s.cfg.CompactionPreempted == nil || !s.cfg.CompactionPreempted() {
+		return nil
+	}
+	dbCompactionPreemptions.Inc()
+	start := time.Now()
+	defer func() { dbCompactionPreemptedMs.Observe(float64(time.Since(start) / time.Millisecond)) }()
+	for time.Since(start) < s.cfg.CompactionMaxPause && s.cfg.CompactionPreempted() {
+		select {
+		case <-time.After(interval):
+		case <-s.stopc:
+			return fmt.Errorf("interrupted due to stop signal")
+		}
+	}
+	return nil
+}
+
+// compactionBarrierCheckInterval is the interval the compaction checks the
+// expiry of the compaction barriers holding it back.
+var compactionBarrierCheckInterval = 100 * time.Millisecond // non-const for testing
+
+// holdCompaction registers the compaction barrier of a range at rev, the
+// current revision being curRev. It returns whether the index and the backend
+// are not compacted above rev, read locking heldMu for the range if so.
+//
+// The barrier expires CompactionBarrierMaxDuration after the first range
+// registering it, or once curRev moves more than CompactionBarrierMaxRevisions
+// past rev.
+func (s *store) holdCompaction(rev, curRev int64) bool {
+	if s.cfg.CompactionBarrierMaxDuration <= 0 {
+		return false
+	}
+	s.barrierMu.Lock()
+	defer s.barrierMu.Unlock()
+	if rev < s.compactingRev || s.compactionBarrierExhausted(rev, curRev) {
+		return false
+	}
+	now := time.Now()
+	expiry, ok := s.barriers[rev]
+	if !ok {
+		expiry = now.Add(s.cfg.CompactionBarrierMaxDuration)
+		s.barriers[rev] = expiry
+	}
+	if !now.Before(expiry) {
+		return false
+	}
+	s.heldMu.RLock()
+	return true
+}
+
+func (s *store) compactionBarrierExhausted(rev, curRev int64) bool {
+	return s.cfg.CompactionBarrierMaxRevisions > 0 && curRev-rev > s.cfg.CompactionBarrierMaxRevisions
+}
+
+// waitCompactionBarriers waits for the compaction barriers below rev to expire
+// and for the ranges holding back the compaction to complete, before the index
+// and the backend are compacted up to rev.
+func (s *store) waitCompactionBarriers(ctx context.Context, rev int64) error {
+	start, waited := time.Now(), false
+	for {
+		held, ok := s.compactionBarrierBelow(rev)
+		if !ok {
+			break
+		}
+		if !waited {
+			s.lg.Info(
+				"compaction held back by compaction barrier",
+				zap.Int64("compact-revision", rev),
+				zap.Int64("barrier-revision", held),
+			)
+			waited = true
+		}
+		select {
+		case <-time.After(compactionBarrierCheckInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.stopc:
+			return fmt.Errorf("interrupted due to stop signal")
+		}
+	}
+	s.heldMu.Lock()
+	s.heldMu.Unlock()
+	if waited {
+		s.lg.Info(
+			"compaction released by compaction barriers",
+			zap.Int64("compact-revision", rev),
+			zap.Duration("held", time.Since(start)),
+		)
+	}
+	return nil
+}
+
+// compactionBarrierBelow drops the expired compaction barriers, returning the
+// revision of the oldest one below rev, if any. Otherwise, it records the index
+// and the backend being compacted up to rev.
+func (s *store) compactionBarrierBelow(rev int64) (int64, bool) {
+	s.revMu.RLock()
+	curRev := s.currentRev
+	s.revMu.RUnlock()
+
+	s.barrierMu.Lock()
+	defer s.barrierMu.Unlock()
+	now, held, ok := time.Now(), int64(0), false
+	for r, expiry := range s.barriers {
+		switch {
+		case !now.Before(expiry) || s.compactionBarrierExhausted(r, curRev):
+			delete(s.barriers, r)
+		case r < rev && (!ok || r < held):
+			held, ok = r, true
+		}
+	}
+	if !ok {
+		s.compactingRev = rev
 	}
+	return held, ok
+}
+
+// resetCompactionBarriers drops the compaction barriers, the index and the
+// backend being compacted up to compactRev.
+func (s *store) resetCompactionBarriers(compactRev int64) {
+	s.barrierMu.Lock()
+	s.barriers = make(map[int64]time.Time)
+	s.compactingRev = compactRev
+	s.barrierMu.Unlock()
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"os"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -83,7 +84,7 @@ func TestScheduleCompaction(t *testing.T) {
 		}
 		tx.Unlock()
 
-		_, err := s.scheduleCompaction(tt.rev, 0)
+		_, err := s.scheduleCompaction(tt.rev, 0, nil)
 		if err != nil {
 			t.Error(err)
 		}
@@ -106,6 +107,59 @@ func TestScheduleCompaction(t *testing.T) {
 	}
 }
 
+// TestScheduleCompactionPreempted ensures that the compaction pauses between
+// its batches while preempted, for at most CompactionMaxPause.
+func TestScheduleCompactionPreempted(t *testing.T) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	var mu sync.Mutex
+	preempted, checks := true, 0
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{
+		CompactionBatchLimit: 1,
+		CompactionPreempted: func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			checks++
+			return preempted
+		},
+		CompactionMaxPause: 50 * time.Millisecond,
+	})
+	defer cleanup(s, b, tmpPath)
+	fi := newFakeIndex()
+	fi.indexCompactRespc <- nil
+	s.kvindex = fi
+
+	tx := s.b.BatchTx()
+	tx.Lock()
+	ibytes := newRevBytes()
+	for _, rev := range []revision{{1, 0}, {2, 0}, {3, 0}} {
+		revToBytes(rev, ibytes)
+		tx.UnsafePut(schema.Key, ibytes, []byte("bar"))
+	}
+	tx.Unlock()
+
+	start := time.Now()
+	if _, err := s.scheduleCompaction(3, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	// paused after each of the 3 full batches
+	if took := time.Since(start); took < 3*s.cfg.CompactionMaxPause {
+		t.Errorf("compaction took %v, want paused at least %v", took, 3*s.cfg.CompactionMaxPause)
+	}
+
+	mu.Lock()
+	preempted, checks = false, 0
+	mu.Unlock()
+	fi.indexCompactRespc <- nil
+	if _, err := s.scheduleCompaction(3, 3, nil); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if checks != 0 {
+		t.Errorf("preemption checked %d times on a compaction of a single batch, want 0", checks)
+	}
+}
+
 func TestCompactAllAndRestore(t *testing.T) {
 	b, tmpPath := betesting.NewDefaultTmpBackend(t)
 	s0 := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
@@ -143,3 +197,73 @@ func TestCompactAllAndRestore(t *testing.T) {
 		t.Errorf("unexpect range error %v", err)
 	}
 }
+
+func TestCompactionBarrier(t *testing.T) {
+	defer func(interval time.Duration) { compactionBarrierCheckInterval = interval }(compactionBarrierCheckInterval)
+	compactionBarrierCheckInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		name string
+		cfg  StoreConfig
+		// puts is the number of puts after the compaction.
+		puts int
+	}{
+		{
+			name: "expire with time",
+			cfg:  StoreConfig{CompactionBarrierMaxDuration: 500 * time.Millisecond},
+		},
+		{
+			name: "expire with revisions",
+			cfg:  StoreConfig{CompactionBarrierMaxDuration: time.Hour, CompactionBarrierMaxRevisions: 2},
+			puts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, tmpPath := betesting.NewDefaultTmpBackend(t)
+			s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, tt.cfg)
+			defer cleanup(s, b, tmpPath)
+
+			s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+			rev := s.Rev()
+			s.Put([]byte("foo"), []byte("bar1"), lease.NoLease)
+
+			ro := RangeOptions{Rev: rev, CompactionBarrier: true}
+			if _, err := s.Range(context.TODO(), []byte("foo"), nil, ro); err != nil {
+				t.Fatal(err)
+			}
+			done, err := s.Compact(traceutil.TODO(), s.Rev())
+			if err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-done:
+				t.Fatal("compaction completed while held back by a compaction barrier")
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			r, err := s.Range(context.TODO(), []byte("foo"), nil, ro)
+			if err != nil {
+				t.Fatalf("range at the compaction barrier = %v, want nil", err)
+			}
+			if len(r.KVs) != 1 || string(r.KVs[0].Value) != "bar" {
+				t.Errorf("range at the compaction barrier = %+v, want foo=bar", r.KVs)
+			}
+			if _, err = s.Range(context.TODO(), []byte("foo"), nil, RangeOptions{Rev: rev}); err != ErrCompacted {
+				t.Errorf("range without compaction barrier = %v, want %v", err, ErrCompacted)
+			}
+
+			for i := 0; i < tt.puts; i++ {
+				s.Put([]byte("foo"), []byte("bar2"), lease.NoLease)
+			}
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the compaction barrier to expire")
+			}
+			if _, err = s.Range(context.TODO(), []byte("foo"), nil, ro); err != ErrCompacted {
+				t.Errorf("range at the expired compaction barrier = %v, want %v", err, ErrCompacted)
+			}
+		})
+	}
+}
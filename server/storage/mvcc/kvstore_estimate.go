@@ -0,0 +1,80 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.uber.org/zap"
+)
+
+// DefaultEstimateSampleSize is the number of key-value pairs read from the
+// backend by Estimate when no sample size is given.
+const DefaultEstimateSampleSize = 1000
+
+// EstimateResult is the approximate content of a key range.
+type EstimateResult struct {
+	// Rev is the revision the estimation was made at.
+	Rev int64
+	// Count is the number of keys in the range.
+	Count int64
+	// Size is the approximate total size of the keys and values in the range.
+	Size int64
+	// Sampled is the number of key-value pairs Size is extrapolated from.
+	Sampled int64
+}
+
+// Estimate returns the number of keys from key(included) to end(excluded) and
+// their approximate total size. The count comes from the in-memory index, the
+// size is extrapolated from at most sampleSize key-value pairs spread over the
+// range, so that the cost does not grow with the size of the values.
+func (s *store) Estimate(key, end []byte, sampleSize int) EstimateResult {
+	if sampleSize <= 0 {
+		sampleSize = DefaultEstimateSampleSize
+	}
+
+	s.mu.RLock()
+	s.revMu.RLock()
+	tx := s.b.ConcurrentReadTx()
+	tx.RLock() // RLock is no-op. concurrentReadTx does not need to be locked after it is created.
+	rev := s.currentRev
+	s.revMu.RUnlock()
+	defer s.mu.RUnlock()
+	defer tx.RUnlock() // RUnlock signals the end of concurrentReadTx.
+
+	revs, total := s.kvindex.SampleRevisions(key, end, rev, sampleSize)
+	res := EstimateResult{Rev: rev, Count: int64(total)}
+
+	var sampledSize int64
+	revBytes := newRevBytes()
+	for _, r := range revs {
+		revToBytes(r, revBytes)
+		_, vs := tx.UnsafeRange(schema.Key, revBytes, nil, 0)
+		if len(vs) != 1 {
+			continue
+		}
+		var kv mvccpb.KeyValue
+		if err := kv.Unmarshal(vs[0]); err != nil {
+			s.lg.Warn("failed to unmarshal mvccpb.KeyValue", zap.Error(err))
+			continue
+		}
+		sampledSize += int64(len(kv.Key) + len(kv.Value))
+		res.Sampled++
+	}
+	if res.Sampled > 0 {
+		res.Size = sampledSize * res.Count / res.Sampled
+	}
+	return res
+}
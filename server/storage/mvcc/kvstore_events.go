@@ -0,0 +1,93 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.uber.org/zap"
+)
+
+// Events returns the events of the revisions from start(included) to
+// end(excluded) in revision order, the revisions of at most about limit
+// events if positive since the events of a revision are not split, and the
+// revision to read the next events from, 0 if there are no more. It fails
+// with ErrCompacted if the revisions from start were compacted, and with
+// ErrFutureRev if end is after the next revision.
+func (s *store) Events(start, end int64, limit int) ([]mvccpb.Event, int64, error) {
+	s.mu.RLock()
+	s.revMu.RLock()
+	tx := s.b.ConcurrentReadTx()
+	tx.RLock() // RLock is no-op. concurrentReadTx does not need to be locked after it is created.
+	compactRev, rev := s.compactMainRev, s.currentRev
+	s.revMu.RUnlock()
+	defer s.mu.RUnlock()
+	defer tx.RUnlock() // RUnlock signals the end of concurrentReadTx.
+
+	if start <= compactRev {
+		return nil, 0, ErrCompacted
+	}
+	if end > rev+1 {
+		return nil, 0, ErrFutureRev
+	}
+	if start >= end {
+		return nil, 0, nil
+	}
+
+	revs, vals := unsafeRangeRevisions(tx, start, end, limit)
+	next := int64(0)
+	if limit > 0 && len(revs) >= limit {
+		// the last revision may be partial
+		last := bytesToRev(revs[len(revs)-1]).main
+		i := len(revs)
+		for i > 0 && bytesToRev(revs[i-1]).main == last {
+			i--
+		}
+		if i == 0 {
+			// a single revision of more than limit events
+			revs, vals = unsafeRangeRevisions(tx, last, last+1, 0)
+			i = len(revs)
+			last++
+		}
+		revs, vals = revs[:i], vals[:i]
+		if last < end {
+			next = last
+		}
+	}
+
+	evs := make([]mvccpb.Event, len(vals))
+	for i, v := range vals {
+		var kv mvccpb.KeyValue
+		if err := kv.Unmarshal(v); err != nil {
+			s.lg.Panic("failed to unmarshal mvccpb.KeyValue", zap.Error(err))
+		}
+		evs[i].Kv = &kv
+		if isTombstone(revs[i]) {
+			evs[i].Type = mvccpb.DELETE
+			kv.ModRevision = bytesToRev(revs[i]).main
+		}
+	}
+	return evs, next, nil
+}
+
+// unsafeRangeRevisions returns the keys and values of the revisions from
+// start(included) to end(excluded), at most limit of them if positive.
+func unsafeRangeRevisions(tx backend.ReadTx, start, end int64, limit int) (revs, vals [][]byte) {
+	startBytes, endBytes := newRevBytes(), newRevBytes()
+	revToBytes(revision{main: start}, startBytes)
+	revToBytes(revision{main: end}, endBytes)
+	return tx.UnsafeRange(schema.Key, startBytes, endBytes, int64(limit))
+}
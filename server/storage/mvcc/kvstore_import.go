@@ -0,0 +1,115 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrImportNotEmpty = errors.New("mvcc: import to a backend holding keys")
+	ErrImportUnsorted = errors.New("mvcc: imported keys not in strictly ascending order")
+)
+
+// Importer writes the key-value pairs of an initial data set directly to the
+// key bucket of a backend, all at a single revision, as a single transaction
+// putting all of them would. It bypasses raft and the key index, the index
+// of the store being built from the key bucket once opened, and is meant for
+// seeding the backend of a new member before it serves clients.
+type Importer struct {
+	lg  *zap.Logger
+	b   backend.Backend
+	rev int64
+
+	// sub is the sub revision of the next key.
+	sub     int64
+	lastKey []byte
+}
+
+// NewImporter returns an importer writing the key-value pairs to b at the
+// revision rev. The key bucket of b must be empty.
+func NewImporter(lg *zap.Logger, b backend.Backend, rev int64) (*Importer, error) {
+	if rev < 1 {
+		return nil, fmt.Errorf("mvcc: invalid import revision %d", rev)
+	}
+	tx := b.BatchTx()
+	tx.LockOutsideApply()
+	defer tx.Unlock()
+	tx.UnsafeCreateBucket(schema.Key)
+	schema.UnsafeCreateMetaBucket(tx)
+
+	min, max := newRevBytes(), newRevBytes()
+	revToBytes(revision{main: 1}, min)
+	revToBytes(revision{main: math.MaxInt64, sub: math.MaxInt64}, max)
+	if keys, _ := tx.UnsafeRange(schema.Key, min, max, 1); len(keys) != 0 {
+		return nil, ErrImportNotEmpty
+	}
+	return &Importer{lg: lg, b: b, rev: rev}, nil
+}
+
+// Put writes the key-value pair, the keys being put in strictly ascending
+// order for the keys not to be put twice at the revision.
+func (im *Importer) Put(key, value []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("mvcc: import of an empty key")
+	}
+	if im.lastKey != nil && bytes.Compare(key, im.lastKey) <= 0 {
+		return fmt.Errorf("%w: %q after %q", ErrImportUnsorted, key, im.lastKey)
+	}
+	kv := mvccpb.KeyValue{
+		Key:            key,
+		Value:          value,
+		CreateRevision: im.rev,
+		ModRevision:    im.rev,
+		Version:        1,
+	}
+	d, err := kv.Marshal()
+	if err != nil {
+		return err
+	}
+	// the write buffer of the backend keeps the key until committed
+	ibytes := newRevBytes()
+	revToBytes(revision{main: im.rev, sub: im.sub}, ibytes)
+
+	tx := im.b.BatchTx()
+	tx.LockOutsideApply()
+	// the revisions are in ascending order, as the keys of the bucket
+	tx.UnsafeSeqPut(schema.Key, ibytes, d)
+	// committed in batches of the batch limit of the backend
+	tx.Unlock()
+
+	im.sub++
+	im.lastKey = append(im.lastKey[:0], key...)
+	return nil
+}
+
+// Count returns the number of key-value pairs written.
+func (im *Importer) Count() int64 {
+	return im.sub
+}
+
+// Commit commits the key-value pairs written to the backend.
+func (im *Importer) Commit() {
+	im.b.ForceCommit()
+	im.lg.Info("imported keys", zap.Int64("revision", im.rev), zap.Int64("keys", im.sub))
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestImporter(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+
+	im, err := NewImporter(zaptest.NewLogger(t), b, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "b/c", "d"} {
+		if err = im.Put([]byte(k), []byte("v"+k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = im.Put([]byte("c"), nil); !errors.Is(err, ErrImportUnsorted) {
+		t.Errorf("put of an unsorted key = %v, want %v", err, ErrImportUnsorted)
+	}
+	if err = im.Put([]byte("d"), nil); !errors.Is(err, ErrImportUnsorted) {
+		t.Errorf("put of a key twice = %v, want %v", err, ErrImportUnsorted)
+	}
+	if im.Count() != 4 {
+		t.Errorf("count = %d, want 4", im.Count())
+	}
+	im.Commit()
+
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer s.Close()
+	if rev := s.Rev(); rev != 100 {
+		t.Errorf("rev = %d, want 100", rev)
+	}
+	r, err := s.Range(context.TODO(), []byte("b"), []byte("c"), RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wkvs := []mvccpb.KeyValue{
+		{Key: []byte("b"), Value: []byte("vb"), CreateRevision: 100, ModRevision: 100, Version: 1},
+		{Key: []byte("b/c"), Value: []byte("vb/c"), CreateRevision: 100, ModRevision: 100, Version: 1},
+	}
+	if !reflect.DeepEqual(r.KVs, wkvs) {
+		t.Errorf("kvs = %+v, want %+v", r.KVs, wkvs)
+	}
+	if rev := s.Put([]byte("a"), []byte("va2"), lease.NoLease); rev != 101 {
+		t.Errorf("rev of the put after the import = %d, want 101", rev)
+	}
+	s.Commit()
+
+	if _, err = NewImporter(zaptest.NewLogger(t), b, 200); err != ErrImportNotEmpty {
+		t.Errorf("import to a backend holding keys = %v, want %v", err, ErrImportNotEmpty)
+	}
+}
+
+// TestImporterBatch ensures the keys imported in a single batch of the backend
+// are read back at their revisions, before and after the batch is committed.
+func TestImporterBatch(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+
+	im, err := NewImporter(zaptest.NewLogger(t), b, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"a", "b", "c", "d", "e"}
+	for _, k := range keys {
+		if err = im.Put([]byte(k), []byte("v"+k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(when string) {
+		t.Helper()
+		min, max := newRevBytes(), newRevBytes()
+		revToBytes(revision{main: 1}, min)
+		revToBytes(revision{main: 101}, max)
+		tx := b.ReadTx()
+		tx.RLock()
+		revs, vals := tx.UnsafeRange(schema.Key, min, max, 0)
+		tx.RUnlock()
+		if len(revs) != len(keys) {
+			t.Fatalf("%d keys %s, want %d", len(revs), when, len(keys))
+		}
+		for i, k := range keys {
+			if rev := bytesToRev(revs[i]); rev != (revision{main: 100, sub: int64(i)}) {
+				t.Errorf("revision of %q %s = %+v, want %+v", k, when, rev, revision{main: 100, sub: int64(i)})
+			}
+			var kv mvccpb.KeyValue
+			if err := kv.Unmarshal(vals[i]); err != nil {
+				t.Fatal(err)
+			}
+			if string(kv.Key) != k || string(kv.Value) != "v"+k {
+				t.Errorf("key-value pair at sub revision %d %s = %q: %q, want %q: %q", i, when, kv.Key, kv.Value, k, "v"+k)
+			}
+		}
+	}
+	check("before commit")
+	im.Commit()
+	check("after commit")
+}
+
+func TestImporterInvalidRevision(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	defer betesting.Close(t, b)
+
+	if _, err := NewImporter(zaptest.NewLogger(t), b, 0); err == nil {
+		t.Error("import at revision 0 succeeded")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import "bytes"
+
+// Retention retains the history of the keys with the prefix on compaction,
+// for the keys to be read at the compacted revisions.
+type Retention struct {
+	Prefix []byte `json:"prefix"`
+	// Versions is the number of the latest versions of each key, as of the
+	// compaction revision, retained. If zero, the history of the keys from
+	// Revision is retained.
+	Versions int64 `json:"versions,omitempty"`
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// contains returns whether all the keys of the range [key, end) have the
+// prefix of the retention.
+func (r Retention) contains(key, end []byte) bool {
+	if len(r.Prefix) == 0 {
+		return true
+	}
+	if !bytes.HasPrefix(key, r.Prefix) {
+		return false
+	}
+	if end == nil {
+		return true
+	}
+	if len(end) == 0 {
+		// the keys from key
+		return false
+	}
+	prefixEnd := make([]byte, len(r.Prefix))
+	copy(prefixEnd, r.Prefix)
+	for i := len(prefixEnd) - 1; i >= 0; i-- {
+		if prefixEnd[i] < 0xff {
+			prefixEnd[i]++
+			return bytes.Compare(end, prefixEnd[:i+1]) <= 0
+		}
+	}
+	// the prefix is all 0xff, its keys are all the keys from it
+	return false
+}
+
+// retainedHistory is the history retained by a retention on compaction: the
+// keys with its prefix are read at the compacted revisions from the floor.
+type retainedHistory struct {
+	Retention
+	Floor int64 `json:"floor"`
+}
+
+// retainedRev returns the revision the key is compacted at on the compaction
+// at rev, retaining the history of the retentions.
+func retainedRev(ki *keyIndex, rev int64, rs []retainedHistory) int64 {
+	atRev := rev
+	for _, r := range rs {
+		if !bytes.HasPrefix(ki.key, r.Prefix) {
+			continue
+		}
+		keyRev := r.Floor
+		if r.Versions > 0 {
+			keyRev = ki.versionsRev(rev, r.Versions)
+		}
+		if keyRev < atRev {
+			atRev = keyRev
+		}
+	}
+	return atRev
+}
+
+// retainHistory returns the history retained by the retentions on the
+// compaction at rev, the previous one being at prevCompactRev.
+//
+// The keys with the prefix of a retention are read at the compacted revisions
+// from the floor of the retained history. It is the previous compaction
+// revision for a new retention, as the keys were compacted up to there, then
+// the revision the history is retained from, or, with the latest versions of
+// the keys retained, the latest tombstone of the key generations removed.
+func (s *store) retainHistory(rev, prevCompactRev int64, rs []Retention) []retainedHistory {
+	if len(rs) == 0 {
+		return nil
+	}
+	s.revMu.RLock()
+	prev := s.retained
+	s.revMu.RUnlock()
+
+	retained := make([]retainedHistory, 0, len(rs))
+	for _, r := range rs {
+		floor := prevCompactRev
+		for _, p := range prev {
+			if bytes.Equal(p.Prefix, r.Prefix) {
+				floor = p.Floor
+				break
+			}
+		}
+		if r.Versions > 0 {
+			if t := s.kvindex.TombstoneFloor(r.Prefix, rev, r.Versions); t > floor {
+				floor = t
+			}
+		} else if r.Revision > floor {
+			floor = r.Revision
+		}
+		if floor > rev {
+			floor = rev
+		}
+		if floor < 0 {
+			floor = 0
+		}
+		retained = append(retained, retainedHistory{Retention: r, Floor: floor})
+	}
+	return retained
+}
+
+// holdRetained returns whether the history of the range [key, end) is
+// retained at the compacted revision rev, read locking heldMu for the range
+// if so, for the compaction not to remove the history of the range meanwhile.
+func (s *store) holdRetained(key, end []byte, rev int64) bool {
+	s.heldMu.RLock()
+	s.revMu.RLock()
+	retained := s.retained
+	s.revMu.RUnlock()
+	for _, r := range retained {
+		if r.Floor <= rev && r.contains(key, end) {
+			if s.kvindex.CompactedAt(key, end, rev) {
+				break
+			}
+			return true
+		}
+	}
+	s.heldMu.RUnlock()
+	return false
+}
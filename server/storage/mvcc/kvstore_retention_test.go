@@ -0,0 +1,138 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCompactWithRetention(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+
+	s.Put([]byte("audit/a"), []byte("1"), lease.NoLease) // 2
+	s.Put([]byte("tmp/b"), []byte("1"), lease.NoLease)   // 3
+	s.Put([]byte("audit/a"), []byte("2"), lease.NoLease) // 4
+	s.Put([]byte("tmp/b"), []byte("2"), lease.NoLease)   // 5
+	s.Put([]byte("audit/a"), []byte("3"), lease.NoLease) // 6
+	s.Put([]byte("audit/a"), []byte("4"), lease.NoLease) // 7
+	s.Put([]byte("audit/c"), []byte("1"), lease.NoLease) // 8
+	s.DeleteRange([]byte("audit/c"), nil)                // 9
+
+	compact(t, s, 9, []Retention{{Prefix: []byte("audit/"), Versions: 2}, {Prefix: []byte("tmp/"), Revision: 4}})
+
+	tests := []struct {
+		key   string
+		end   []byte
+		rev   int64
+		wvals []string
+		werr  error
+	}{
+		{key: "audit/a", rev: 7, wvals: []string{"4"}},
+		{key: "audit/a", rev: 6, wvals: []string{"3"}},
+		{key: "audit/a", rev: 5, werr: ErrCompacted},
+		{key: "audit/", end: []byte("audit0"), rev: 6, wvals: []string{"3"}},
+		{key: "audit/", end: []byte("audit0"), rev: 8, wvals: []string{"4", "1"}},
+		{key: "audit/", end: []byte("audit0"), rev: 5, werr: ErrCompacted},
+		{key: "tmp/b", rev: 4, wvals: []string{"1"}},
+		{key: "tmp/b", rev: 3, werr: ErrCompacted},
+		// the range is not within the prefix of a retention
+		{key: "audit/", end: []byte("tmp0"), rev: 6, werr: ErrCompacted},
+		{key: "audit/", end: []byte{}, rev: 6, werr: ErrCompacted},
+		{key: "other", rev: 6, werr: ErrCompacted},
+	}
+	check := func(t *testing.T, s *store) {
+		for i, tt := range tests {
+			r, err := s.Range(context.TODO(), []byte(tt.key), tt.end, RangeOptions{Rev: tt.rev})
+			if err != tt.werr {
+				t.Fatalf("#%d: range %q-%q at %d error = %v, want %v", i, tt.key, tt.end, tt.rev, err, tt.werr)
+			}
+			if err != nil {
+				continue
+			}
+			var vals []string
+			for _, kv := range r.KVs {
+				vals = append(vals, string(kv.Value))
+			}
+			if len(vals) != len(tt.wvals) {
+				t.Fatalf("#%d: range %q-%q at %d = %v, want %v", i, tt.key, tt.end, tt.rev, vals, tt.wvals)
+			}
+			for j := range vals {
+				if vals[j] != tt.wvals[j] {
+					t.Fatalf("#%d: range %q-%q at %d = %v, want %v", i, tt.key, tt.end, tt.rev, vals, tt.wvals)
+				}
+			}
+		}
+	}
+	check(t, s)
+
+	// the retained history is restored
+	s.Close()
+	s = NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	check(t, s)
+
+	// the history is not retained without retentions anymore
+	s.Put([]byte("audit/a"), []byte("5"), lease.NoLease) // 10
+	compact(t, s, 10, nil)
+	if _, err := s.Range(context.TODO(), []byte("audit/a"), nil, RangeOptions{Rev: 7}); err != ErrCompacted {
+		t.Fatalf("range at 7 error = %v, want %v", err, ErrCompacted)
+	}
+	r, err := s.Range(context.TODO(), []byte("audit/a"), nil, RangeOptions{Rev: 10})
+	if err != nil || len(r.KVs) != 1 || string(r.KVs[0].Value) != "5" {
+		t.Fatalf("range at 10 = %v, %v, want the value 5", r, err)
+	}
+	s.Close()
+	b.Close()
+}
+
+func compact(t *testing.T, s *store, rev int64, rs []Retention) {
+	done, err := s.CompactWithRetention(traceutil.TODO(), rev, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for compaction to finish")
+	}
+}
+
+func TestRetentionContains(t *testing.T) {
+	tests := []struct {
+		prefix, key string
+		end         []byte
+		want        bool
+	}{
+		{prefix: "a/", key: "a/b", want: true},
+		{prefix: "a/", key: "b", want: false},
+		{prefix: "a/", key: "a/", end: []byte("a0"), want: true},
+		{prefix: "a/", key: "a/", end: []byte("a1"), want: false},
+		{prefix: "a/", key: "a/", end: []byte{}, want: false},
+		{prefix: "", key: "a", end: []byte{}, want: true},
+		{prefix: "\xff", key: "\xff", end: []byte("\xff\xff"), want: false},
+	}
+	for i, tt := range tests {
+		if got := (Retention{Prefix: []byte(tt.prefix)}).contains([]byte(tt.key), tt.end); got != tt.want {
+			t.Errorf("#%d: contains(%q, %q) of %q = %v, want %v", i, tt.key, tt.end, tt.prefix, got, tt.want)
+		}
+	}
+}
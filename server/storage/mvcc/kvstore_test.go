@@ -391,6 +391,7 @@ func TestStoreRestore(t *testing.T) {
 		t.Fatal(err)
 	}
 	b.tx.rangeRespc <- rangeResp{[][]byte{schema.FinishedCompactKeyName}, [][]byte{newTestRevBytes(revision{3, 0})}}
+	b.tx.rangeRespc <- rangeResp{nil, nil}
 	b.tx.rangeRespc <- rangeResp{[][]byte{schema.ScheduledCompactKeyName}, [][]byte{newTestRevBytes(revision{3, 0})}}
 
 	b.tx.rangeRespc <- rangeResp{[][]byte{putkey, delkey}, [][]byte{putkvb, delkvb}}
@@ -406,6 +407,7 @@ func TestStoreRestore(t *testing.T) {
 	}
 	wact := []testutil.Action{
 		{Name: "range", Params: []interface{}{schema.Meta, schema.FinishedCompactKeyName, []byte(nil), int64(0)}},
+		{Name: "range", Params: []interface{}{schema.Meta, schema.FinishedCompactRetentionKeyName, []byte(nil), int64(0)}},
 		{Name: "range", Params: []interface{}{schema.Meta, schema.ScheduledCompactKeyName, []byte(nil), int64(0)}},
 		{Name: "range", Params: []interface{}{schema.Key, newTestRevBytes(revision{1, 0}), newTestRevBytes(revision{math.MaxInt64, math.MaxInt64}), int64(restoreChunkKeys)}},
 	}
@@ -476,6 +478,97 @@ func TestRestoreDelete(t *testing.T) {
 	}
 }
 
+func TestStoreTombstones(t *testing.T) {
+	b, _ := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer b.Close()
+
+	s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+	s.Put([]byte("foo1"), []byte("bar"), lease.NoLease)
+	s.DeleteRange([]byte("foo"), []byte("foo2"))
+	// tombstone is a marked revision and a key-value with the key only
+	want := TombstoneStats{Count: 2, Size: int64(2*markedRevBytesLen + len("\n\x03foo") + len("\n\x04foo1"))}
+	if ts := s.Tombstones(); ts != want {
+		t.Fatalf("tombstones = %+v, want %+v", ts, want)
+	}
+
+	s.Close()
+	s = NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	if ts := s.Tombstones(); ts != want {
+		t.Fatalf("tombstones after restore = %+v, want %+v", ts, want)
+	}
+
+	s.Put([]byte("zoo"), []byte("bar"), lease.NoLease)
+	done, err := s.Compact(traceutil.TODO(), s.Rev())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if ts := s.Tombstones(); ts != (TombstoneStats{}) {
+		t.Errorf("tombstones after compaction = %+v, want none", ts)
+	}
+	s.Close()
+}
+
+func TestStoreEvents(t *testing.T) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b, tmpPath)
+
+	s.Put([]byte("foo"), []byte("bar"), lease.NoLease)  // 2
+	s.Put([]byte("foo1"), []byte("bar"), lease.NoLease) // 3
+	s.DeleteRange([]byte("foo"), []byte("foo2"))        // 4
+	s.Put([]byte("foo"), []byte("baz"), lease.NoLease)  // 5
+
+	type event struct {
+		typ mvccpb.Event_EventType
+		key string
+		rev int64
+	}
+	tests := []struct {
+		start, end int64
+		limit      int
+		evs        []event
+		next       int64
+	}{
+		{2, 6, 0, []event{{mvccpb.PUT, "foo", 2}, {mvccpb.PUT, "foo1", 3}, {mvccpb.DELETE, "foo", 4}, {mvccpb.DELETE, "foo1", 4}, {mvccpb.PUT, "foo", 5}}, 0},
+		{3, 5, 0, []event{{mvccpb.PUT, "foo1", 3}, {mvccpb.DELETE, "foo", 4}, {mvccpb.DELETE, "foo1", 4}}, 0},
+		// the events of a revision are not split
+		{2, 6, 3, []event{{mvccpb.PUT, "foo", 2}, {mvccpb.PUT, "foo1", 3}}, 4},
+		{4, 6, 1, []event{{mvccpb.DELETE, "foo", 4}, {mvccpb.DELETE, "foo1", 4}}, 5},
+		{5, 6, 1, []event{{mvccpb.PUT, "foo", 5}}, 0},
+		{6, 6, 0, nil, 0},
+	}
+	for i, tt := range tests {
+		evs, next, err := s.Events(tt.start, tt.end, tt.limit)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		var got []event
+		for _, ev := range evs {
+			got = append(got, event{ev.Type, string(ev.Kv.Key), ev.Kv.ModRevision})
+		}
+		if !reflect.DeepEqual(got, tt.evs) || next != tt.next {
+			t.Errorf("#%d: events = %v, next %d, want %v, next %d", i, got, next, tt.evs, tt.next)
+		}
+	}
+
+	if _, _, err := s.Events(2, 7, 0); err != ErrFutureRev {
+		t.Errorf("err = %v, want %v", err, ErrFutureRev)
+	}
+	done, err := s.Compact(traceutil.TODO(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if _, _, err := s.Events(3, 6, 0); err != ErrCompacted {
+		t.Errorf("err = %v, want %v", err, ErrCompacted)
+	}
+	if _, _, err := s.Events(4, 6, 0); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
 func TestRestoreContinueUnfinishedCompaction(t *testing.T) {
 	tests := []string{"recreate", "restore"}
 	for _, test := range tests {
@@ -657,6 +750,48 @@ func TestTxnPut(t *testing.T) {
 	}
 }
 
+func TestWriteBatch(t *testing.T) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b, tmpPath)
+
+	s.BeginWriteBatch()
+	for i, k := range []string{"foo", "bar"} {
+		txn := s.Write(traceutil.TODO())
+		if rev := txn.Put([]byte(k), []byte(k), lease.NoLease); rev != int64(i+2) {
+			t.Errorf("#%d: rev = %d, want %d", i, rev, i+2)
+		}
+		txn.End()
+	}
+	// no revision for a txn without changes
+	txn := s.Write(traceutil.TODO())
+	if n, rev := txn.DeleteRange([]byte("baz"), nil); n != 0 || rev != 3 {
+		t.Errorf("DeleteRange = %d, %d, want 0, 3", n, rev)
+	}
+	txn.End()
+
+	// the reads see the store as of before the batch
+	r, err := s.Range(context.TODO(), []byte("bar"), []byte("foo\x00"), RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.KVs) != 0 || r.Rev != 1 {
+		t.Errorf("range = %d keys at %d, want 0 keys at 1", len(r.KVs), r.Rev)
+	}
+	s.EndWriteBatch()
+
+	r, err = s.Range(context.TODO(), []byte("bar"), []byte("foo\x00"), RangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.KVs) != 2 || r.Rev != 3 {
+		t.Errorf("range = %d keys at %d, want 2 keys at 3", len(r.KVs), r.Rev)
+	}
+	if r.KVs[0].ModRevision != 3 || r.KVs[1].ModRevision != 2 {
+		t.Errorf("mod revisions = %d, %d, want 3, 2", r.KVs[0].ModRevision, r.KVs[1].ModRevision)
+	}
+}
+
 // TestConcurrentReadNotBlockingWrite ensures Read does not blocking Write after its creation
 func TestConcurrentReadNotBlockingWrite(t *testing.T) {
 	b, tmpPath := betesting.NewDefaultTmpBackend(t)
@@ -923,12 +1058,14 @@ func (b *fakeBackend) ConcurrentReadTx() backend.ReadTx
 func (b *fakeBackend) Hash(func(bucketName, keyName []byte) bool) (uint32, error) { return 0, nil }
 func (b *fakeBackend) Size() int64                                                { return 0 }
 func (b *fakeBackend) SizeInUse() int64                                           { return 0 }
+func (b *fakeBackend) SizePending() int64                                         { return 0 }
 func (b *fakeBackend) OpenReadTxN() int64                                         { return 0 }
 func (b *fakeBackend) Snapshot() backend.Snapshot                                 { return nil }
 func (b *fakeBackend) ForceCommit()                                               {}
 func (b *fakeBackend) Defrag() error                                              { return nil }
 func (b *fakeBackend) Close() error                                               { return nil }
 func (b *fakeBackend) SetTxPostLockInsideApplyHook(func())                        {}
+func (b *fakeBackend) SetCommitObserver(func(time.Duration))                      {}
 
 type indexGetResp struct {
 	rev     revision
@@ -962,6 +1099,26 @@ func (i *fakeIndex) Revisions(key, end []byte, atRev int64, limit int) ([]revisi
 	return rev, len(rev)
 }
 
+func (i *fakeIndex) RevisionsDescend(key, end []byte, atRev int64, limit int) ([]revision, int) {
+	_, rev := i.Range(key, end, atRev)
+	for l, r := 0, len(rev)-1; l < r; l, r = l+1, r-1 {
+		rev[l], rev[r] = rev[r], rev[l]
+	}
+	if len(rev) >= limit {
+		rev = rev[:limit]
+	}
+	return rev, len(rev)
+}
+
+func (i *fakeIndex) SampleRevisions(key, end []byte, atRev int64, n int) ([]revision, int) {
+	_, rev := i.Range(key, end, atRev)
+	total := len(rev)
+	if len(rev) >= n {
+		rev = rev[:n]
+	}
+	return rev, total
+}
+
 func (i *fakeIndex) CountRevisions(key, end []byte, atRev int64) int {
 	_, rev := i.Range(key, end, atRev)
 	return len(rev)
@@ -989,7 +1146,7 @@ func (i *fakeIndex) RangeSince(key, end []byte, rev int64) []revision {
 	r := <-i.indexRangeEventsRespc
 	return r.revs
 }
-func (i *fakeIndex) Compact(rev int64) map[revision]struct{} {
+func (i *fakeIndex) Compact(rev int64, rs []retainedHistory) map[revision]struct{} {
 	i.Recorder.Record(testutil.Action{Name: "compact", Params: []interface{}{rev}})
 	return <-i.indexCompactRespc
 }
@@ -997,7 +1154,9 @@ func (i *fakeIndex) Keep(rev int64) map[revision]struct{} {
 	i.Recorder.Record(testutil.Action{Name: "keep", Params: []interface{}{rev}})
 	return <-i.indexCompactRespc
 }
-func (i *fakeIndex) Equal(b index) bool { return false }
+func (i *fakeIndex) TombstoneFloor(prefix []byte, atRev, versions int64) int64 { return 0 }
+func (i *fakeIndex) CompactedAt(key, end []byte, atRev int64) bool             { return false }
+func (i *fakeIndex) Equal(b index) bool                                        { return false }
 
 func (i *fakeIndex) Insert(ki *keyIndex) {
 	i.Recorder.Record(testutil.Action{Name: "insert", Params: []interface{}{ki}})
@@ -1008,6 +1167,8 @@ func (i *fakeIndex) KeyIndex(ki *keyIndex) *keyIndex {
 	return nil
 }
 
+func (i *fakeIndex) Bytes() int64 { return 0 }
+
 func createBytesSlice(bytesN, sliceN int) [][]byte {
 	rs := [][]byte{}
 	for len(rs) != sliceN {
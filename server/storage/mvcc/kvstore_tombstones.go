@@ -0,0 +1,49 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import "sync/atomic"
+
+// TombstoneStats are the tombstones of the deleted keys, kept in the backend
+// until they are compacted.
+type TombstoneStats struct {
+	// Count is the number of tombstones.
+	Count int64
+	// Size is the total size in bytes of the tombstones in the backend, their
+	// revisions and deleted keys included.
+	Size int64
+}
+
+// Tombstones returns the tombstones currently in the backend.
+func (s *store) Tombstones() TombstoneStats {
+	return TombstoneStats{
+		Count: atomic.LoadInt64(&s.tombstones),
+		Size:  atomic.LoadInt64(&s.tombstonesSize),
+	}
+}
+
+// addTombstone accounts for a tombstone of the given backend key and value
+// written to, or with a negative n removed from, the backend.
+func (s *store) addTombstone(n int64, key, value []byte) {
+	atomic.AddInt64(&s.tombstones, n)
+	atomic.AddInt64(&s.tombstonesSize, n*int64(len(key)+len(value)))
+}
+
+// resetTombstones forgets the tombstones, before they are recounted
+// when restoring the store from its backend.
+func (s *store) resetTombstones() {
+	atomic.StoreInt64(&s.tombstones, 0)
+	atomic.StoreInt64(&s.tombstonesSize, 0)
+}
@@ -71,15 +71,26 @@ func (tr *storeTxnRead) rangeKeys(ctx context.Context, key, end []byte, curRev i
 	if rev <= 0 {
 		rev = curRev
 	}
-	if rev < tr.s.compactMainRev {
-		return &RangeResult{KVs: nil, Count: -1, Rev: 0}, ErrCompacted
+	if ro.CompactionBarrier && tr.s.holdCompaction(rev, curRev) {
+		defer tr.s.heldMu.RUnlock()
+	} else if rev < tr.s.compactMainRev {
+		if !tr.s.holdRetained(key, end, rev) {
+			return &RangeResult{KVs: nil, Count: -1, Rev: 0}, ErrCompacted
+		}
+		defer tr.s.heldMu.RUnlock()
 	}
 	if ro.Count {
 		total := tr.s.kvindex.CountRevisions(key, end, rev)
 		tr.trace.Step("count revisions from in-memory index tree")
 		return &RangeResult{KVs: nil, Count: total, Rev: curRev}, nil
 	}
-	revpairs, total := tr.s.kvindex.Revisions(key, end, rev, int(ro.Limit))
+	var revpairs []revision
+	var total int
+	if ro.Descend {
+		revpairs, total = tr.s.kvindex.RevisionsDescend(key, end, rev, int(ro.Limit))
+	} else {
+		revpairs, total = tr.s.kvindex.Revisions(key, end, rev, int(ro.Limit))
+	}
 	tr.trace.Step("range keys from in-memory index tree")
 	if len(revpairs) == 0 {
 		return &RangeResult{KVs: nil, Count: total, Rev: curRev}, nil
@@ -129,10 +140,29 @@ type storeTxnWrite struct {
 	// beginRev is the revision where the txn begins; it will write to the next revision.
 	beginRev int64
 	changes  []mvccpb.KeyValue
+	// batch is the write batch of the txn, if any.
+	batch *writeBatch
+}
+
+// writeBatch holds the backend lock for the write txns of a batch.
+type writeBatch struct {
+	tx backend.BatchTx
+	// rev is the revision of the last write txn of the batch.
+	rev int64
 }
 
 func (s *store) Write(trace *traceutil.Trace) TxnWrite {
 	s.mu.RLock()
+	if b := s.batch; b != nil {
+		tw := &storeTxnWrite{
+			storeTxnRead: storeTxnRead{s, b.tx, 0, 0, trace},
+			tx:           b.tx,
+			beginRev:     b.rev,
+			changes:      make([]mvccpb.KeyValue, 0, 4),
+			batch:        b,
+		}
+		return newMetricsTxnWrite(tw)
+	}
 	tx := s.b.BatchTx()
 	tx.LockInsideApply()
 	tw := &storeTxnWrite{
@@ -162,11 +192,24 @@ func (tw *storeTxnWrite) DeleteRange(key, end []byte) (int64, int64) {
 }
 
 func (tw *storeTxnWrite) Put(key, value []byte, lease lease.LeaseID) int64 {
-	tw.put(key, value, lease)
+	tw.put(key, value, lease, nil)
+	return tw.beginRev + 1
+}
+
+func (tw *storeTxnWrite) PutWithAnnotations(key, value []byte, lease lease.LeaseID, annotations map[string]string) int64 {
+	tw.put(key, value, lease, annotations)
 	return tw.beginRev + 1
 }
 
 func (tw *storeTxnWrite) End() {
+	if tw.batch != nil {
+		// the batch publishes the revision once it ends.
+		if len(tw.changes) != 0 {
+			tw.batch.rev++
+		}
+		tw.s.mu.RUnlock()
+		return
+	}
 	// only update index if the txn modifies the mvcc state.
 	if len(tw.changes) != 0 {
 		// hold revMu lock to prevent new read txns from opening until writeback.
@@ -180,7 +223,30 @@ func (tw *storeTxnWrite) End() {
 	tw.s.mu.RUnlock()
 }
 
-func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID) {
+// BeginWriteBatch holds the backend lock for the write txns until
+// EndWriteBatch, which makes their revisions visible at once.
+func (s *store) BeginWriteBatch() {
+	tx := s.b.BatchTx()
+	tx.LockInsideApply()
+	s.batch = &writeBatch{tx: tx, rev: s.currentRev}
+}
+
+func (s *store) EndWriteBatch() {
+	b := s.batch
+	s.batch = nil
+	changed := b.rev != s.currentRev
+	if changed {
+		// hold revMu lock to prevent new read txns from opening until writeback.
+		s.revMu.Lock()
+		s.currentRev = b.rev
+	}
+	b.tx.Unlock()
+	if changed {
+		s.revMu.Unlock()
+	}
+}
+
+func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID, annotations map[string]string) {
 	rev := tw.beginRev + 1
 	c := rev
 	oldLease := lease.NoLease
@@ -205,6 +271,7 @@ func (tw *storeTxnWrite) put(key, value []byte, leaseID lease.LeaseID) {
 		ModRevision:    rev,
 		Version:        ver,
 		Lease:          int64(leaseID),
+		Annotations:    annotations,
 	}
 
 	d, err := kv.Marshal()
@@ -283,6 +350,7 @@ func (tw *storeTxnWrite) delete(key []byte) {
 	}
 
 	tw.tx.UnsafeSeqPut(schema.Key, ibytes, d)
+	tw.s.addTombstone(1, ibytes, d)
 	err = tw.s.kvindex.Tombstone(key, idxRev)
 	if err != nil {
 		tw.storeTxnRead.s.lg.Fatal(
@@ -16,8 +16,10 @@ package mvcc
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 )
 
 var (
@@ -85,6 +87,26 @@ var (
 			Help:      "Total number of unsynced slow watchers.",
 		})
 
+	watchersCatchUpSec = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "watchers_catch_up_duration_seconds",
+			Help:      "The latency distribution of catching up a batch of unsynced watchers.",
+
+			// lowest bucket start of upper bound 0.001 sec (1 ms) with factor 2
+			// highest bucket start of 0.001 sec * 2^13 == 8.192 sec
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+		})
+
+	watchersCaughtUp = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "watchers_caught_up_total",
+			Help:      "Total number of unsynced watchers caught up and moved to synced.",
+		})
+
 	totalEventsCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: "etcd_debugging",
@@ -145,6 +167,26 @@ var (
 			Help:      "The unix time of the last db compaction. Resets to 0 on start.",
 		})
 
+	dbCompactionPreemptions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "db_compaction_preemptions_total",
+			Help:      "Total number of db compaction pauses yielding to the foreground traffic.",
+		})
+
+	dbCompactionPreemptedMs = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "etcd_debugging",
+			Subsystem: "mvcc",
+			Name:      "db_compaction_preempted_duration_milliseconds",
+			Help:      "Bucketed histogram of db compaction pauses yielding to the foreground traffic.",
+
+			// lowest bucket start of upper bound 1 ms with factor 2
+			// highest bucket start of 1 ms * 2^12 == 4.096 sec
+			Buckets: prometheus.ExponentialBuckets(1, 2, 13),
+		})
+
 	dbCompactionKeysCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: "etcd_debugging",
@@ -202,6 +244,38 @@ var (
 	reportDbOpenReadTxNMu sync.RWMutex
 	reportDbOpenReadTxN   = func() float64 { return 0 }
 
+	dbTombstones = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "mvcc",
+		Name:      "db_tombstones",
+		Help:      "The number of tombstones of deleted keys in the underlying database, until they are compacted.",
+	},
+		func() float64 {
+			reportDbTombstonesMu.RLock()
+			defer reportDbTombstonesMu.RUnlock()
+			return reportDbTombstones()
+		},
+	)
+	// overridden by mvcc initialization
+	reportDbTombstonesMu sync.RWMutex
+	reportDbTombstones   = func() float64 { return 0 }
+
+	dbTombstonesSize = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "etcd",
+		Subsystem: "mvcc",
+		Name:      "db_tombstones_size_in_bytes",
+		Help:      "Total size of the tombstones of deleted keys in the underlying database in bytes.",
+	},
+		func() float64 {
+			reportDbTombstonesSizeMu.RLock()
+			defer reportDbTombstonesSizeMu.RUnlock()
+			return reportDbTombstonesSize()
+		},
+	)
+	// overridden by mvcc initialization
+	reportDbTombstonesSizeMu sync.RWMutex
+	reportDbTombstonesSize   = func() float64 { return 0 }
+
 	hashSec = prometheus.NewHistogram(prometheus.HistogramOpts{
 		Namespace: "etcd",
 		Subsystem: "mvcc",
@@ -276,6 +350,8 @@ func init() {
 	prometheus.MustRegister(watchStreamGauge)
 	prometheus.MustRegister(watcherGauge)
 	prometheus.MustRegister(slowWatcherGauge)
+	prometheus.MustRegister(watchersCatchUpSec)
+	prometheus.MustRegister(watchersCaughtUp)
 	prometheus.MustRegister(totalEventsCounter)
 	prometheus.MustRegister(pendingEventsGauge)
 	prometheus.MustRegister(indexCompactionPauseMs)
@@ -283,9 +359,13 @@ func init() {
 	prometheus.MustRegister(dbCompactionTotalMs)
 	prometheus.MustRegister(dbCompactionLast)
 	prometheus.MustRegister(dbCompactionKeysCounter)
+	prometheus.MustRegister(dbCompactionPreemptions)
+	prometheus.MustRegister(dbCompactionPreemptedMs)
 	prometheus.MustRegister(dbTotalSize)
 	prometheus.MustRegister(dbTotalSizeInUse)
 	prometheus.MustRegister(dbOpenReadTxN)
+	prometheus.MustRegister(dbTombstones)
+	prometheus.MustRegister(dbTombstonesSize)
 	prometheus.MustRegister(hashSec)
 	prometheus.MustRegister(hashRevSec)
 	prometheus.MustRegister(currentRev)
@@ -300,3 +380,29 @@ func ReportEventReceived(n int) {
 	pendingEventsGauge.Sub(float64(n))
 	totalEventsCounter.Add(float64(n))
 }
+
+// pendingEventsBytes is the size of the events sent to the watchers and not
+// received yet by the external systems.
+var pendingEventsBytes int64
+
+// ReportEventBytesReceived reports that events of n bytes are received, as
+// returned by EventsBytes. This function should be called along with
+// ReportEventReceived.
+func ReportEventBytesReceived(n int64) {
+	atomic.AddInt64(&pendingEventsBytes, -n)
+}
+
+// PendingEventsBytes returns the size of the events sent to the watchers and
+// not received yet by the external systems.
+func PendingEventsBytes() int64 {
+	return atomic.LoadInt64(&pendingEventsBytes)
+}
+
+// EventsBytes returns the size of the events.
+func EventsBytes(evs []mvccpb.Event) int64 {
+	var n int64
+	for i := range evs {
+		n += int64(evs[i].Size())
+	}
+	return n
+}
@@ -53,6 +53,16 @@ func (tw *metricsTxnWrite) Put(key, value []byte, lease lease.LeaseID) (rev int6
 	return tw.TxnWrite.Put(key, value, lease)
 }
 
+func (tw *metricsTxnWrite) PutWithAnnotations(key, value []byte, lease lease.LeaseID, annotations map[string]string) (rev int64) {
+	tw.puts++
+	size := int64(len(key) + len(value))
+	for k, v := range annotations {
+		size += int64(len(k) + len(v))
+	}
+	tw.putSize += size
+	return tw.TxnWrite.PutWithAnnotations(key, value, lease, annotations)
+}
+
 func (tw *metricsTxnWrite) End() {
 	defer tw.TxnWrite.End()
 	if sum := tw.ranges + tw.puts + tw.deletes; sum > 1 {
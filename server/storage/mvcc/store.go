@@ -15,6 +15,8 @@
 package mvcc
 
 import (
+	"encoding/json"
+
 	"go.etcd.io/etcd/server/v3/storage/backend"
 	"go.etcd.io/etcd/server/v3/storage/schema"
 )
@@ -58,3 +60,59 @@ func UnsafeSetFinishedCompact(tx backend.BatchTx, value int64) {
 	revToBytes(revision{main: value}, rbytes)
 	tx.UnsafePut(schema.Meta, schema.FinishedCompactKeyName, rbytes)
 }
+
+// UnsafeReadScheduledRetention returns the retentions of the scheduled
+// compaction.
+func UnsafeReadScheduledRetention(tx backend.ReadTx) []Retention {
+	var rs []Retention
+	unsafeReadRetention(tx, schema.ScheduledCompactRetentionKeyName, &rs)
+	return rs
+}
+
+// SetScheduledRetention writes the retentions of the scheduled compaction,
+// deleting the ones written before if there are none.
+func SetScheduledRetention(tx backend.BatchTx, rs []Retention, written bool) {
+	tx.LockInsideApply()
+	defer tx.Unlock()
+	unsafeSetRetention(tx, schema.ScheduledCompactRetentionKeyName, rs, len(rs), written)
+}
+
+// unsafeReadFinishedRetention returns the history retained by the finished
+// compaction.
+func unsafeReadFinishedRetention(tx backend.ReadTx) []retainedHistory {
+	var retained []retainedHistory
+	unsafeReadRetention(tx, schema.FinishedCompactRetentionKeyName, &retained)
+	return retained
+}
+
+// unsafeSetFinishedRetention writes the history retained by the finished
+// compaction, deleting the one written before if there is none.
+func unsafeSetFinishedRetention(tx backend.BatchTx, retained []retainedHistory, written bool) {
+	unsafeSetRetention(tx, schema.FinishedCompactRetentionKeyName, retained, len(retained), written)
+}
+
+func unsafeReadRetention(tx backend.ReadTx, key []byte, v interface{}) {
+	_, vs := tx.UnsafeRange(schema.Meta, key, nil, 0)
+	if len(vs) == 0 || len(vs[0]) == 0 {
+		return
+	}
+	if err := json.Unmarshal(vs[0], v); err != nil {
+		panic(err)
+	}
+}
+
+// unsafeSetRetention writes the n retentions, the meta bucket not changing
+// without retentions unless they were written before.
+func unsafeSetRetention(tx backend.BatchTx, key []byte, v interface{}, n int, written bool) {
+	if n == 0 {
+		if written {
+			tx.UnsafeDelete(schema.Meta, key)
+		}
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	tx.UnsafePut(schema.Meta, key, b)
+}
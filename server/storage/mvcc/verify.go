@@ -0,0 +1,107 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"sort"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.uber.org/zap"
+)
+
+// VerifyIndex rebuilds the key index from the key bucket like a restoring
+// store and checks that the latest revisions of the keys found through the
+// index hash the same as the ones found by scanning the bucket. It returns
+// the hash and does not modify the backend.
+func VerifyIndex(lg *zap.Logger, tx backend.ReadTx) (uint32, error) {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	tx.RLock()
+	defer tx.RUnlock()
+
+	idx := newTreeIndex(lg)
+	rkvc, revc := restoreIntoIndex(lg, idx)
+	// latest holds the latest revision of the keys not deleted
+	latest := make(map[string]revKeyValue)
+	err := tx.UnsafeForEach(schema.Key, func(k, v []byte) error {
+		rkv := revKeyValue{key: append([]byte(nil), k...)}
+		if err := rkv.kv.Unmarshal(v); err != nil {
+			return fmt.Errorf("failed to unmarshal mvccpb.KeyValue at revision %+v (%v)", bytesToRev(k), err)
+		}
+		rkv.kstr = string(rkv.kv.Key)
+		if isTombstone(k) {
+			delete(latest, rkv.kstr)
+		} else {
+			latest[rkv.kstr] = rkv
+		}
+		rkvc <- rkv
+		return nil
+	})
+	close(rkvc)
+	currentRev := <-revc
+	if err != nil {
+		return 0, err
+	}
+
+	keys, revs := idx.Range([]byte{}, []byte{}, currentRev)
+	indexHash := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	ibytes := newRevBytes()
+	for i, key := range keys {
+		revToBytes(revs[i], ibytes)
+		_, vs := tx.UnsafeRange(schema.Key, ibytes, nil, 0)
+		if len(vs) != 1 {
+			return 0, fmt.Errorf("revision %+v of key %q in the index is not in the key bucket", revs[i], key)
+		}
+		var kv mvccpb.KeyValue
+		if err = kv.Unmarshal(vs[0]); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal mvccpb.KeyValue at revision %+v (%v)", revs[i], err)
+		}
+		writeVerifiedKeyValue(indexHash, &kv)
+	}
+
+	kstrs := make([]string, 0, len(latest))
+	for kstr := range latest {
+		kstrs = append(kstrs, kstr)
+	}
+	sort.Strings(kstrs)
+	bucketHash := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	for _, kstr := range kstrs {
+		kv := latest[kstr].kv
+		writeVerifiedKeyValue(bucketHash, &kv)
+	}
+
+	if len(keys) != len(kstrs) || indexHash.Sum32() != bucketHash.Sum32() {
+		return 0, fmt.Errorf("index rebuilt at revision %d holds %d keys with hash %d, but the key bucket holds %d keys with hash %d",
+			currentRev, len(keys), indexHash.Sum32(), len(kstrs), bucketHash.Sum32())
+	}
+	lg.Info(
+		"verified rebuilt index",
+		zap.Int64("current-revision", currentRev),
+		zap.Int("keys", len(keys)),
+		zap.Uint32("hash", indexHash.Sum32()),
+	)
+	return indexHash.Sum32(), nil
+}
+
+func writeVerifiedKeyValue(h hash.Hash32, kv *mvccpb.KeyValue) {
+	b, _ := kv.Marshal()
+	h.Write(b)
+}
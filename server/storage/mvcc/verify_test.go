@@ -0,0 +1,69 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mvcc
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/pkg/v3/traceutil"
+	"go.etcd.io/etcd/server/v3/lease"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestVerifyIndex(t *testing.T) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	s := NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b, tmpPath)
+
+	s.Put([]byte("foo"), []byte("bar"), lease.NoLease)
+	s.Put([]byte("foo"), []byte("bar1"), lease.NoLease)
+	s.Put([]byte("baz"), []byte("qux"), lease.NoLease)
+	s.Put([]byte("del"), []byte("x"), lease.NoLease)
+	s.DeleteRange([]byte("del"), nil)
+	done, err := s.Compact(traceutil.TODO(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	s.Put([]byte("del"), []byte("y"), lease.NoLease)
+	s.DeleteRange([]byte("del"), nil)
+	b.ForceCommit()
+
+	h, err := VerifyIndex(zaptest.NewLogger(t), b.ReadTx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the hash only depends on the latest revisions of the keys
+	s.Put([]byte("new"), []byte("v"), lease.NoLease)
+	s.DeleteRange([]byte("new"), nil)
+	b.ForceCommit()
+	if h2, err := VerifyIndex(zaptest.NewLogger(t), b.ReadTx()); err != nil || h2 != h {
+		t.Errorf("VerifyIndex = %d, %v; want %d, nil", h2, err, h)
+	}
+
+	// a revision which does not decode fails the verification
+	ibytes := newRevBytes()
+	revToBytes(revision{main: 100}, ibytes)
+	tx := b.BatchTx()
+	tx.Lock()
+	tx.UnsafePut(schema.Key, ibytes, []byte("corrupted"))
+	tx.Unlock()
+	b.ForceCommit()
+	if _, err = VerifyIndex(zaptest.NewLogger(t), b.ReadTx()); err == nil {
+		t.Error("expected error for corrupted key bucket")
+	}
+}
@@ -16,6 +16,7 @@ package mvcc
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.etcd.io/etcd/api/v3/mvccpb"
@@ -36,6 +37,11 @@ var (
 
 	// maxWatchersPerSync is the number of watchers to sync in a single batch
 	maxWatchersPerSync = 512
+
+	// maxSyncWatchersWorkers is the maximum number of goroutines catching up
+	// the unsynced watchers concurrently, maxWatchersPerSync each, on
+	// disjoint ranges of their keys
+	maxSyncWatchersWorkers = 4
 )
 
 type watchable interface {
@@ -62,6 +68,10 @@ type watchableStore struct {
 	// The key of the map is the key that the watcher watches on.
 	synced watcherGroup
 
+	// batchEvents are the events of the write batch of the store, notified
+	// once it ends.
+	batchEvents []revisionEvents
+
 	stopc chan struct{}
 	wg    sync.WaitGroup
 }
@@ -326,7 +336,8 @@ func (s *watchableStore) moveVictims() (moved int) {
 // syncWatchers syncs unsynced watchers by:
 //	1. choose a set of watchers from the unsynced watcher group
 //	2. iterate over the set to get the minimum revision and remove compacted watchers
-//	3. use minimum revision to get all key-value pairs and send those events to watchers
+//	3. use minimum revision to get all key-value pairs and send those events to watchers,
+//	   reading them concurrently on disjoint ranges of keys if there are many watchers
 //	4. remove synced watchers in set from unsynced group and move to synced group
 func (s *watchableStore) syncWatchers() int {
 	s.mu.Lock()
@@ -345,24 +356,21 @@ func (s *watchableStore) syncWatchers() int {
 	curRev := s.store.currentRev
 	compactionRev := s.store.compactMainRev
 
-	wg, minRev := s.unsynced.choose(maxWatchersPerSync, curRev, compactionRev)
-	minBytes, maxBytes := newRevBytes(), newRevBytes()
-	revToBytes(revision{main: minRev}, minBytes)
-	revToBytes(revision{main: curRev + 1}, maxBytes)
+	start := time.Now()
+	defer func() { watchersCatchUpSec.Observe(time.Since(start).Seconds()) }()
 
-	// UnsafeRange returns keys and values. And in boltdb, keys are revisions.
-	// values are actual key-value pairs in backend.
-	tx := s.store.b.ReadTx()
-	tx.RLock()
-	revs, vs := tx.UnsafeRange(schema.Key, minBytes, maxBytes, 0)
-	evs := kvsToEvents(s.store.lg, wg, revs, vs)
-	// Must unlock after kvsToEvents, because vs (come from boltdb memory) is not deep copy.
-	// We can only unlock after Unmarshal, which will do deep copy.
-	// Otherwise we will trigger SIGSEGV during boltdb re-mmap.
-	tx.RUnlock()
+	workers := syncWatchersWorkers(s.unsynced.size())
+	wg, minRev := s.unsynced.choose(maxWatchersPerSync*workers, curRev, compactionRev)
+	var wb watcherBatch
+	if workers > 1 && wg.size() > maxWatchersPerSync {
+		wb = s.concurrentWatcherBatch(wg.split(workers), curRev, compactionRev)
+	} else {
+		wb = s.watcherBatchSince(wg, minRev, curRev)
+	}
 
 	victims := make(watcherBatch)
-	wb := newWatcherBatch(wg, evs)
+	caughtUp := 0
+	defer func() { watchersCaughtUp.Add(float64(caughtUp)) }()
 	for w := range wg.watchers {
 		w.minRev = curRev + 1
 
@@ -371,6 +379,7 @@ func (s *watchableStore) syncWatchers() int {
 			// bring un-notified watcher to synced
 			s.synced.add(w)
 			s.unsynced.delete(w)
+			caughtUp++
 			continue
 		}
 
@@ -392,6 +401,7 @@ func (s *watchableStore) syncWatchers() int {
 				continue
 			}
 			s.synced.add(w)
+			caughtUp++
 		}
 		s.unsynced.delete(w)
 	}
@@ -406,6 +416,64 @@ func (s *watchableStore) syncWatchers() int {
 	return s.unsynced.size()
 }
 
+// syncWatchersWorkers returns the number of goroutines catching up the given
+// number of unsynced watchers, one per maxWatchersPerSync of them.
+func syncWatchersWorkers(unsynced int) int {
+	n := (unsynced + maxWatchersPerSync - 1) / maxWatchersPerSync
+	if n > maxSyncWatchersWorkers {
+		n = maxSyncWatchersWorkers
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// watcherBatchSince returns the events of the watchers of the group from minRev
+// up to curRev.
+func (s *watchableStore) watcherBatchSince(wg *watcherGroup, minRev, curRev int64) watcherBatch {
+	minBytes, maxBytes := newRevBytes(), newRevBytes()
+	revToBytes(revision{main: minRev}, minBytes)
+	revToBytes(revision{main: curRev + 1}, maxBytes)
+
+	// UnsafeRange returns keys and values. And in boltdb, keys are revisions.
+	// values are actual key-value pairs in backend.
+	tx := s.store.b.ReadTx()
+	tx.RLock()
+	revs, vs := tx.UnsafeRange(schema.Key, minBytes, maxBytes, 0)
+	evs := kvsToEvents(s.store.lg, wg, revs, vs)
+	// Must unlock after kvsToEvents, because vs (come from boltdb memory) is not deep copy.
+	// We can only unlock after Unmarshal, which will do deep copy.
+	// Otherwise we will trigger SIGSEGV during boltdb re-mmap.
+	tx.RUnlock()
+
+	return newWatcherBatch(wg, evs)
+}
+
+// concurrentWatcherBatch returns the events of the watchers of all groups,
+// reading those of each group concurrently from the minimum revision of its
+// watchers. The groups must not share watchers.
+func (s *watchableStore) concurrentWatcherBatch(wgs []watcherGroup, curRev, compactRev int64) watcherBatch {
+	wbs := make([]watcherBatch, len(wgs))
+	var wait sync.WaitGroup
+	wait.Add(len(wgs))
+	for i := range wgs {
+		go func(i int) {
+			defer wait.Done()
+			wbs[i] = s.watcherBatchSince(&wgs[i], wgs[i].minRev(compactRev), curRev)
+		}(i)
+	}
+	wait.Wait()
+
+	wb := make(watcherBatch)
+	for i := range wbs {
+		for w, eb := range wbs[i] {
+			wb[w] = eb
+		}
+	}
+	return wb
+}
+
 // kvsToEvents gets all events for the watchers from all key-value pairs
 func kvsToEvents(lg *zap.Logger, wg *watcherGroup, revs, vals [][]byte) (evs []mvccpb.Event) {
 	for i, v := range vals {
@@ -533,10 +601,14 @@ func (w *watcher) send(wr WatchResponse) bool {
 	if !progressEvent && len(wr.Events) == 0 {
 		return true
 	}
+	// accounted before the send to be received after it
+	n := EventsBytes(wr.Events)
+	atomic.AddInt64(&pendingEventsBytes, n)
 	select {
 	case w.ch <- wr:
 		return true
 	default:
+		atomic.AddInt64(&pendingEventsBytes, -n)
 		return false
 	}
 }
@@ -222,6 +222,62 @@ func TestSyncWatchers(t *testing.T) {
 	}
 }
 
+// TestSyncWatchersConcurrently tests syncing more watchers than a single
+// worker syncs, on split ranges of keys.
+func TestSyncWatchersConcurrently(t *testing.T) {
+	oldMaxWatchersPerSync := maxWatchersPerSync
+	maxWatchersPerSync = 10
+	defer func() { maxWatchersPerSync = oldMaxWatchersPerSync }()
+
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	s := &watchableStore{
+		store:    NewStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{}),
+		unsynced: newWatcherGroup(),
+		synced:   newWatcherGroup(),
+	}
+	defer func() {
+		s.store.Close()
+		os.Remove(tmpPath)
+	}()
+
+	keyN := maxWatchersPerSync*maxSyncWatchersWorkers - 1
+	for i := 0; i < keyN; i++ {
+		s.Put([]byte(fmt.Sprintf("foo%02d", i)), []byte("bar"), lease.NoLease)
+	}
+
+	w := s.NewWatchStream()
+	for i := 0; i < keyN; i++ {
+		w.Watch(WatchID(i), []byte(fmt.Sprintf("foo%02d", i)), nil, 1)
+	}
+	prefixID := WatchID(keyN)
+	w.Watch(prefixID, []byte("foo"), []byte("fop"), 1)
+
+	if left := s.syncWatchers(); left != 0 {
+		t.Fatalf("unsynced size = %d, want 0", left)
+	}
+	if s.synced.size() != keyN+1 {
+		t.Errorf("synced size = %d, want %d", s.synced.size(), keyN+1)
+	}
+
+	ch := w.(*watchStream).ch
+	if len(ch) != keyN+1 {
+		t.Fatalf("watched responses = %d, want %d", len(ch), keyN+1)
+	}
+	for i := 0; i <= keyN; i++ {
+		resp := <-ch
+		if resp.WatchID == prefixID {
+			if len(resp.Events) != keyN {
+				t.Errorf("prefix watcher events = %d, want %d", len(resp.Events), keyN)
+			}
+			continue
+		}
+		wkey := fmt.Sprintf("foo%02d", resp.WatchID)
+		if len(resp.Events) != 1 || string(resp.Events[0].Kv.Key) != wkey {
+			t.Errorf("watcher %d events = %v, want a single event on %q", resp.WatchID, resp.Events, wkey)
+		}
+	}
+}
+
 // TestWatchCompacted tests a watcher that watches on a compacted revision.
 func TestWatchCompacted(t *testing.T) {
 	b, tmpPath := betesting.NewDefaultTmpBackend(t)
@@ -432,6 +488,40 @@ func TestWatchBatchUnsynced(t *testing.T) {
 	}
 }
 
+func TestWatchWriteBatch(t *testing.T) {
+	b, tmpPath := betesting.NewDefaultTmpBackend(t)
+	s := newWatchableStore(zaptest.NewLogger(t), b, &lease.FakeLessor{}, StoreConfig{})
+	defer cleanup(s, b, tmpPath)
+
+	w := s.NewWatchStream()
+	w.Watch(0, []byte("foo"), []byte("fop"), 0)
+
+	s.BeginWriteBatch()
+	for _, k := range []string{"foo1", "foo2"} {
+		txn := s.Write(traceutil.TODO())
+		txn.Put([]byte(k), []byte("bar"), lease.NoLease)
+		txn.End()
+	}
+	select {
+	case resp := <-w.Chan():
+		t.Fatalf("unexpected events before the batch ends: %+v", resp)
+	case <-time.After(100 * time.Millisecond):
+	}
+	s.EndWriteBatch()
+
+	// a response per revision
+	for i, k := range []string{"foo1", "foo2"} {
+		select {
+		case resp := <-w.Chan():
+			if resp.Revision != int64(i+2) || len(resp.Events) != 1 || string(resp.Events[0].Kv.Key) != k {
+				t.Errorf("#%d: response = %+v, want the put of %s at %d", i, resp, k, i+2)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("#%d: failed to receive the events", i)
+		}
+	}
+}
+
 func TestNewMapwatcherToEventMap(t *testing.T) {
 	k0, k1, k2 := []byte("foo0"), []byte("foo1"), []byte("foo2")
 	v0, v1, v2 := []byte("bar0"), []byte("bar1"), []byte("bar2")
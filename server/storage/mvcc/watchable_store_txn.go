@@ -38,6 +38,12 @@ func (tw *watchableStoreTxnWrite) End() {
 		}
 	}
 
+	if tw.s.store.batch != nil {
+		tw.s.batchEvents = append(tw.s.batchEvents, revisionEvents{rev, evs})
+		tw.TxnWrite.End()
+		return
+	}
+
 	// end write txn under watchable store lock so the updates are visible
 	// when asynchronous event posting checks the current store revision
 	tw.s.mu.Lock()
@@ -54,3 +60,22 @@ type watchableStoreTxnWrite struct {
 func (s *watchableStore) Write(trace *traceutil.Trace) TxnWrite {
 	return &watchableStoreTxnWrite{s.store.Write(trace), s}
 }
+
+// revisionEvents are the events of a revision.
+type revisionEvents struct {
+	rev int64
+	evs []mvccpb.Event
+}
+
+func (s *watchableStore) EndWriteBatch() {
+	// end write batch under watchable store lock, as for a write txn, so the
+	// updates are visible when asynchronous event posting checks the current
+	// store revision
+	s.mu.Lock()
+	for _, re := range s.batchEvents {
+		s.notify(re.rev, re.evs)
+	}
+	s.batchEvents = nil
+	s.store.EndWriteBatch()
+	s.mu.Unlock()
+}
@@ -15,8 +15,10 @@
 package mvcc
 
 import (
+	"bytes"
 	"fmt"
 	"math"
+	"sort"
 
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/pkg/v3/adt"
@@ -267,6 +269,45 @@ func (wg *watcherGroup) chooseAll(curRev, compactRev int64) int64 {
 	return minRev
 }
 
+// minRev returns the minimum revision of the watchers not compacted.
+func (wg *watcherGroup) minRev(compactRev int64) int64 {
+	minRev := int64(math.MaxInt64)
+	for w := range wg.watchers {
+		if w.minRev >= compactRev && w.minRev < minRev {
+			minRev = w.minRev
+		}
+	}
+	return minRev
+}
+
+// split splits the watchers of the group into at most n groups of about the
+// same size, watching disjoint ranges of keys when their ranges do not overlap.
+func (wg *watcherGroup) split(n int) []watcherGroup {
+	ws := make([]*watcher, 0, len(wg.watchers))
+	for w := range wg.watchers {
+		ws = append(ws, w)
+	}
+	sort.Slice(ws, func(i, j int) bool {
+		if c := bytes.Compare(ws[i].key, ws[j].key); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(ws[i].end, ws[j].end) < 0
+	})
+
+	if n > len(ws) {
+		n = len(ws)
+	}
+	wgs := make([]watcherGroup, 0, n)
+	for i := 0; i < n; i++ {
+		g := newWatcherGroup()
+		for _, w := range ws[i*len(ws)/n : (i+1)*len(ws)/n] {
+			g.add(w)
+		}
+		wgs = append(wgs, g)
+	}
+	return wgs
+}
+
 // watcherSetByKey gets the set of watchers that receive events on the given key.
 func (wg *watcherGroup) watcherSetByKey(key string) watcherSet {
 	wkeys := wg.keyWatchers[key]
@@ -148,7 +148,14 @@ func (b *BackendQuota) Cost(v interface{}) int {
 
 func costPut(r *pb.PutRequest) int { return kvOverhead + len(r.Key) + len(r.Value) }
 
+// costIncrement is the cost of an increment, the value being at most the 20
+// bytes of a decimal int64.
+func costIncrement(r *pb.IncrementRequest) int { return kvOverhead + len(r.Key) + 20 }
+
 func costTxnReq(u *pb.RequestOp) int {
+	if r := u.GetRequestIncrement(); r != nil {
+		return costIncrement(r)
+	}
 	r := u.GetRequestPut()
 	if r == nil {
 		return 0
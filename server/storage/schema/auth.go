@@ -132,6 +132,9 @@ func (atx *authReadTx) UnsafeReadAuthRevision() uint64 {
 		// this can happen in the initialization phase
 		return 0
 	}
+	if len(vs[0]) != 8 {
+		atx.lg.Panic("auth revision must be 8-byte", zap.Int("length", len(vs[0])))
+	}
 	return binary.BigEndian.Uint64(vs[0])
 }
 
@@ -80,7 +80,9 @@ var (
 	ClusterClusterVersionKeyName = []byte("clusterVersion")
 	ClusterDowngradeKeyName      = []byte("downgrade")
 	// Since v3.6
-	MetaStorageVersionName = []byte("storageVersion")
+	MetaStorageVersionName           = []byte("storageVersion")
+	ScheduledCompactRetentionKeyName = []byte("scheduledCompactRetention")
+	FinishedCompactRetentionKeyName  = []byte("finishedCompactRetention")
 	// Before adding new meta key please update server/etcdserver/version
 )
 
@@ -36,6 +36,22 @@ func addNewField(bucket backend.Bucket, fieldName []byte, fieldValue []byte) sch
 	}
 }
 
+// addOptionalField represents adding a field written once used, not when
+// upgrading. Downgrade will remove the field.
+func addOptionalField(bucket backend.Bucket, fieldName []byte) schemaChange {
+	return simpleSchemaChange{
+		// the field cannot exist before the upgrade
+		upgrade: deleteKeyAction{
+			Bucket:    bucket,
+			FieldName: fieldName,
+		},
+		downgrade: deleteKeyAction{
+			Bucket:    bucket,
+			FieldName: fieldName,
+		},
+	}
+}
+
 type simpleSchemaChange struct {
 	upgrade   action
 	downgrade action
@@ -42,11 +42,17 @@ func UnsafeReadConsistentIndex(tx backend.ReadTx) (uint64, uint64) {
 	if len(vs) == 0 {
 		return 0, 0
 	}
+	if len(vs[0]) != 8 {
+		panic(fmt.Errorf("consistent index must be 8-byte, got %d bytes", len(vs[0])))
+	}
 	v := binary.BigEndian.Uint64(vs[0])
 	_, ts := tx.UnsafeRange(Meta, MetaTermKeyName, nil, 0)
 	if len(ts) == 0 {
 		return v, 0
 	}
+	if len(ts[0]) != 8 {
+		panic(fmt.Errorf("term must be 8-byte, got %d bytes", len(ts[0])))
+	}
 	t := binary.BigEndian.Uint64(ts[0])
 	return v, t
 }
@@ -0,0 +1,122 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+
+package schema
+
+import (
+	"testing"
+
+	"go.etcd.io/bbolt"
+	"go.etcd.io/etcd/client/pkg/v3/testutil"
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	betesting "go.etcd.io/etcd/server/v3/storage/backend/testing"
+	"go.uber.org/zap/zaptest"
+)
+
+// fuzzTargets are the buckets and keys the values of are decoded when the
+// backend is read. A nil key is fuzzed too.
+var fuzzTargets = []struct {
+	bucket backend.Bucket
+	key    []byte
+}{
+	{Meta, MetaConsistentIndexKeyName},
+	{Meta, MetaTermKeyName},
+	{Meta, MetaConfStateName},
+	{Meta, MetaStorageVersionName},
+	{Auth, AuthEnabledKeyName},
+	{Auth, AuthRevisionKeyName},
+	{Cluster, ClusterClusterVersionKeyName},
+	{Cluster, ClusterDowngradeKeyName},
+	{Lease, nil},
+	{Alarm, nil},
+	{Members, nil},
+	{MembersRemoved, nil},
+	{AuthUsers, nil},
+	{AuthRoles, nil},
+}
+
+// FuzzReaders reads a backend of an arbitrary value of one of the decoded
+// keys.
+func FuzzReaders(f *testing.F) {
+	for i := range fuzzTargets {
+		f.Add(uint8(i), []byte("8a7ab9cbe3b00a55"), []byte{})
+		f.Add(uint8(i), []byte("8a7ab9cbe3b00a55"), []byte{0, 0, 0, 0, 0, 0, 0, 1})
+		f.Add(uint8(i), []byte("8a7ab9cbe3b00a55"), []byte(`{"voters":[1],"auto_leave":false}`))
+		f.Add(uint8(i), []byte("8a7ab9cbe3b00a55"), []byte("3.6.0"))
+	}
+
+	f.Fuzz(func(t *testing.T, target uint8, key, value []byte) {
+		tgt := fuzzTargets[int(target)%len(fuzzTargets)]
+		if tgt.key != nil {
+			key = tgt.key
+		}
+		// the backend fails fatally on keys bbolt does not store
+		if len(key) == 0 || len(key) > bbolt.MaxKeySize {
+			return
+		}
+
+		be, _ := betesting.NewDefaultTmpBackend(t)
+		defer betesting.Close(t, be)
+		tx := be.BatchTx()
+		tx.LockOutsideApply()
+		tx.UnsafeCreateBucket(tgt.bucket)
+		tx.UnsafePut(tgt.bucket, key, value)
+		tx.Unlock()
+		be.ForceCommit()
+
+		lg := zaptest.NewLogger(t)
+		readers := []func(){
+			func() { ReadConsistentIndex(be.ReadTx()) },
+			func() {
+				rtx := be.ReadTx()
+				rtx.RLock()
+				defer rtx.RUnlock()
+				UnsafeConfStateFromBackend(lg, rtx)
+			},
+			func() { ReadStorageVersion(be.ReadTx()) },
+			func() {
+				rtx := be.ReadTx()
+				rtx.RLock()
+				defer rtx.RUnlock()
+				MustUnsafeGetAllLeases(rtx)
+			},
+			func() { NewAlarmBackend(lg, be).GetAllAlarms() },
+			func() { NewMembershipBackend(lg, be).MustReadMembersFromBackend() },
+			func() { NewMembershipBackend(lg, be).ClusterVersionFromBackend() },
+			func() { NewMembershipBackend(lg, be).DowngradeInfoFromBackend() },
+			func() {
+				atx := NewAuthBackend(lg, be).ReadTx()
+				atx.Lock()
+				defer atx.Unlock()
+				atx.UnsafeReadAuthEnabled()
+				atx.UnsafeReadAuthRevision()
+				atx.UnsafeGetAllUsers()
+				atx.UnsafeGetAllRoles()
+			},
+		}
+		for _, read := range readers {
+			panicked := true
+			testutil.AssertNoRuntimePanic(t, func() {
+				read()
+				panicked = false
+			})
+			// the server exits on the panics, the backend being left locked
+			if panicked {
+				return
+			}
+		}
+	})
+}
@@ -129,6 +129,8 @@ var (
 	schemaChanges = map[semver.Version][]schemaChange{
 		version.V3_6: {
 			addNewField(Meta, MetaStorageVersionName, emptyStorageVersion),
+			addOptionalField(Meta, ScheduledCompactRetentionKeyName),
+			addOptionalField(Meta, FinishedCompactRetentionKeyName),
 		},
 	}
 	// emptyStorageVersion is used for v3.6 Step for the first time, in all other version StoragetVersion should be set by migrator.
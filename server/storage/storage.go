@@ -29,6 +29,9 @@ type Storage interface {
 	// Save function saves ents and state to the underlying stable storage.
 	// Save MUST block until st and ents are on stable storage.
 	Save(st raftpb.HardState, ents []raftpb.Entry) error
+	// SaveNoSync function saves ents and state like Save without waiting for
+	// them to be on stable storage, returning true if Sync must be called for that.
+	SaveNoSync(st raftpb.HardState, ents []raftpb.Entry) (bool, error)
 	// SaveSnap function saves snapshot to the underlying stable storage.
 	SaveSnap(snap raftpb.Snapshot) error
 	// Close closes the Storage and performs finalization.
@@ -93,6 +96,12 @@ func (st *storage) Save(s raftpb.HardState, ents []raftpb.Entry) error {
 	return st.w.Save(s, ents)
 }
 
+func (st *storage) SaveNoSync(s raftpb.HardState, ents []raftpb.Entry) (bool, error) {
+	st.mux.RLock()
+	defer st.mux.RUnlock()
+	return st.w.SaveNoSync(s, ents)
+}
+
 func (st *storage) Close() error {
 	st.mux.Lock()
 	defer st.mux.Unlock()
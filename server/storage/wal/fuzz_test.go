@@ -0,0 +1,104 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+
+package wal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.etcd.io/etcd/client/pkg/v3/fileutil"
+	"go.etcd.io/etcd/client/pkg/v3/testutil"
+	"go.etcd.io/etcd/pkg/v3/pbutil"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/server/v3/storage/wal/walpb"
+	"go.uber.org/zap"
+)
+
+// FuzzDecodeRecord decodes the records of a WAL file of arbitrary bytes, as
+// left by a crash.
+func FuzzDecodeRecord(f *testing.F) {
+	f.Add(infoRecord)
+	f.Add(infoRecord[:14])
+	f.Add(encodeRecords(f,
+		&walpb.Record{Type: crcType},
+		&walpb.Record{Type: metadataType, Data: []byte("metadata")},
+		&walpb.Record{Type: snapshotType, Data: pbutil.MustMarshal(&walpb.Snapshot{})},
+		&walpb.Record{Type: entryType, Data: pbutil.MustMarshal(&raftpb.Entry{Index: 1, Term: 1, Data: []byte("foo")})},
+		&walpb.Record{Type: stateType, Data: pbutil.MustMarshal(&raftpb.HardState{Term: 1, Commit: 1})},
+	))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fp, err := createFileWithData(t, bytes.NewBuffer(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fp.Close()
+		d := newDecoder(fileutil.NewFileReader(fp))
+		rec := &walpb.Record{}
+		testutil.AssertNoRuntimePanic(t, func() {
+			for d.decode(rec) == nil {
+				if d.lastValidOff > int64(len(data)) {
+					t.Fatalf("last valid offset %d past the %d bytes of the file", d.lastValidOff, len(data))
+				}
+			}
+		})
+	})
+}
+
+// FuzzReadAll reads a WAL of a record of arbitrary data, its CRC being valid
+// to reach the decoding of the entries, states and snapshots.
+func FuzzReadAll(f *testing.F) {
+	f.Add(uint8(entryType), pbutil.MustMarshal(&raftpb.Entry{Index: 1, Term: 1, Data: []byte("foo")}))
+	f.Add(uint8(stateType), pbutil.MustMarshal(&raftpb.HardState{Term: 1, Commit: 1}))
+	f.Add(uint8(snapshotType), pbutil.MustMarshal(&walpb.Snapshot{Index: 1, Term: 1}))
+	f.Add(uint8(metadataType), []byte("other metadata"))
+
+	f.Fuzz(func(t *testing.T, typ uint8, data []byte) {
+		dir := t.TempDir()
+		b := encodeRecords(t,
+			&walpb.Record{Type: crcType},
+			&walpb.Record{Type: metadataType, Data: []byte("metadata")},
+			&walpb.Record{Type: snapshotType, Data: pbutil.MustMarshal(&walpb.Snapshot{})},
+			&walpb.Record{Type: int64(typ), Data: data},
+		)
+		if err := os.WriteFile(filepath.Join(dir, walName(0, 0)), b, fileutil.PrivateFileMode); err != nil {
+			t.Fatal(err)
+		}
+		w, err := Open(zap.NewNop(), dir, walpb.Snapshot{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+		testutil.AssertNoRuntimePanic(t, func() { w.ReadAll() })
+	})
+}
+
+func encodeRecords(tb testing.TB, recs ...*walpb.Record) []byte {
+	var buf bytes.Buffer
+	e := newEncoder(&buf, 0, 0)
+	for _, rec := range recs {
+		if err := e.encode(rec); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := e.flush(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -15,6 +15,7 @@
 package wal
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -25,83 +26,232 @@ import (
 	"go.uber.org/zap"
 )
 
-// Repair tries to repair ErrUnexpectedEOF in the
-// last wal file by truncating.
-func Repair(lg *zap.Logger, dirpath string) bool {
+// TailState is the state of the tail of the last wal file.
+type TailState int
+
+const (
+	// TailClean is a tail ending with a valid record, possibly followed by
+	// preallocated space.
+	TailClean TailState = iota
+	// TailTornWrite is a tail ending with records partially written by a
+	// write never synced, safely truncated.
+	TailTornWrite
+	// TailCorrupted is a tail with an invalid record fully written, e.g.
+	// a synced record damaged on disk, the records following the last valid
+	// one being lost when truncated.
+	TailCorrupted
+)
+
+func (s TailState) String() string {
+	switch s {
+	case TailClean:
+		return "clean"
+	case TailTornWrite:
+		return "torn-write"
+	case TailCorrupted:
+		return "corrupted"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// TailReport describes the tail of the last wal file.
+type TailReport struct {
+	// Path is the path of the last wal file.
+	Path  string
+	State TailState
+	// Err is the error decoding the first invalid record.
+	Err error
+	// Records is the number of valid records of the file.
+	Records int
+	// LastIndex is the index of the last valid entry of the file.
+	LastIndex uint64
+	// ValidOffset is the offset following the last valid record, the file
+	// being truncated to it when repaired.
+	ValidOffset int64
+	// DiscardedBytes is the size of the data following the last valid
+	// record, up to its last non-zero byte, lost when repaired.
+	DiscardedBytes int64
+}
+
+// Repairable returns true if the tail is truncated without losing any
+// synced record.
+func (r *TailReport) Repairable() bool { return r.State != TailCorrupted }
+
+func (r *TailReport) fields() []zap.Field {
+	return []zap.Field{
+		zap.String("path", r.Path),
+		zap.Stringer("tail-state", r.State),
+		zap.NamedError("decode-error", r.Err),
+		zap.Int("valid-records", r.Records),
+		zap.Uint64("last-valid-index", r.LastIndex),
+		zap.Int64("valid-offset", r.ValidOffset),
+		zap.Int64("discarded-bytes", r.DiscardedBytes),
+	}
+}
+
+// InspectTail classifies the tail of the last wal file, without modifying it.
+func InspectTail(lg *zap.Logger, dirpath string) (*TailReport, error) {
 	if lg == nil {
 		lg = zap.NewNop()
 	}
-	f, err := openLast(lg, dirpath)
+	names, err := readWALNames(lg, dirpath)
 	if err != nil {
-		return false
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dirpath, names[len(names)-1]))
+	if err != nil {
+		return nil, err
 	}
 	defer f.Close()
+	return inspectTail(f)
+}
 
-	lg.Info("repairing", zap.String("path", f.Name()))
-
+func inspectTail(f *os.File) (*TailReport, error) {
+	r := &TailReport{Path: f.Name()}
 	rec := &walpb.Record{}
-	decoder := newDecoder(fileutil.NewFileReader(f.File))
+	decoder := newDecoder(fileutil.NewFileReader(f))
 	for {
-		lastOffset := decoder.lastOffset()
+		r.ValidOffset = decoder.lastOffset()
 		err := decoder.decode(rec)
-		switch err {
-		case nil:
-			// update crc of the decoder when necessary
+		if err == nil {
 			switch rec.Type {
 			case crcType:
 				crc := decoder.crc.Sum32()
 				// current crc of decoder must match the crc of the record.
 				// do no need to match 0 crc, since the decoder is a new one at this case.
 				if crc != 0 && rec.Validate(crc) != nil {
-					return false
+					err = ErrCRCMismatch
+					break
 				}
 				decoder.updateCRC(rec.Crc)
+			case entryType:
+				r.LastIndex = mustUnmarshalEntry(rec.Data).Index
 			}
+		}
+		switch err {
+		case nil:
+			r.Records++
 			continue
-
 		case io.EOF:
-			lg.Info("repaired", zap.String("path", f.Name()), zap.Error(io.EOF))
-			return true
-
+			r.State = TailClean
+			return r, nil
 		case io.ErrUnexpectedEOF:
-			brokenName := f.Name() + ".broken"
-			bf, bferr := os.Create(brokenName)
-			if bferr != nil {
-				lg.Warn("failed to create backup file", zap.String("path", brokenName), zap.Error(bferr))
-				return false
-			}
-			defer bf.Close()
+			r.State = TailTornWrite
+		default:
+			r.State = TailCorrupted
+		}
+		r.Err = err
+		n, derr := nonZeroBytesFrom(f, r.ValidOffset)
+		if derr != nil {
+			return nil, derr
+		}
+		r.DiscardedBytes = n
+		return r, nil
+	}
+}
 
-			if _, err = f.Seek(0, io.SeekStart); err != nil {
-				lg.Warn("failed to read file", zap.String("path", f.Name()), zap.Error(err))
-				return false
+// nonZeroBytesFrom returns the size of the data of the file from the offset
+// up to its last non-zero byte.
+func nonZeroBytesFrom(f *os.File, off int64) (int64, error) {
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var n, read int64
+	buf := make([]byte, 32*1024)
+	for {
+		m, err := f.Read(buf)
+		for i := m - 1; i >= 0; i-- {
+			if buf[i] != 0 {
+				n = read + int64(i) + 1
+				break
 			}
+		}
+		read += int64(m)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
 
-			if _, err = io.Copy(bf, f); err != nil {
-				lg.Warn("failed to copy", zap.String("from", f.Name()), zap.String("to", brokenName), zap.Error(err))
-				return false
-			}
+// Repair tries to repair a torn write in the last wal file by truncating
+// it after its last valid record. A corrupted tail is not repaired, as its
+// records may have been synced, see ForceRepair.
+func Repair(lg *zap.Logger, dirpath string) bool {
+	_, err := repair(lg, dirpath, false)
+	return err == nil
+}
 
-			if err = f.Truncate(lastOffset); err != nil {
-				lg.Warn("failed to truncate", zap.String("path", f.Name()), zap.Error(err))
-				return false
-			}
+// ForceRepair truncates the last wal file after its last valid record,
+// whatever the state of its tail, losing the records following it.
+func ForceRepair(lg *zap.Logger, dirpath string) (*TailReport, error) {
+	return repair(lg, dirpath, true)
+}
 
-			start := time.Now()
-			if err = fileutil.Fsync(f.File); err != nil {
-				lg.Warn("failed to fsync", zap.String("path", f.Name()), zap.Error(err))
-				return false
-			}
-			walFsyncSec.Observe(time.Since(start).Seconds())
+func repair(lg *zap.Logger, dirpath string, force bool) (*TailReport, error) {
+	if lg == nil {
+		lg = zap.NewNop()
+	}
+	f, err := openLast(lg, dirpath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-			lg.Info("repaired", zap.String("path", f.Name()), zap.Error(io.ErrUnexpectedEOF))
-			return true
+	lg.Info("repairing", zap.String("path", f.Name()))
 
-		default:
-			lg.Warn("failed to repair", zap.String("path", f.Name()), zap.Error(err))
-			return false
-		}
+	r, err := inspectTail(f.File)
+	if err != nil {
+		lg.Warn("failed to read file", zap.String("path", f.Name()), zap.Error(err))
+		return nil, err
+	}
+	switch {
+	case r.State == TailClean:
+		lg.Info("repaired", zap.String("path", f.Name()), zap.Error(io.EOF))
+		return r, nil
+	case !r.Repairable() && !force:
+		lg.Warn("failed to repair, the tail of the WAL is corrupted, not torn by a partial write", r.fields()...)
+		return r, r.Err
+	}
+	if !r.Repairable() {
+		lg.Warn("truncating corrupted WAL, records following the last valid one are lost", r.fields()...)
+	}
+
+	brokenName := f.Name() + ".broken"
+	bf, err := os.Create(brokenName)
+	if err != nil {
+		lg.Warn("failed to create backup file", zap.String("path", brokenName), zap.Error(err))
+		return r, err
 	}
+	defer bf.Close()
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		lg.Warn("failed to read file", zap.String("path", f.Name()), zap.Error(err))
+		return r, err
+	}
+
+	if _, err = io.Copy(bf, f); err != nil {
+		lg.Warn("failed to copy", zap.String("from", f.Name()), zap.String("to", brokenName), zap.Error(err))
+		return r, err
+	}
+
+	if err = f.Truncate(r.ValidOffset); err != nil {
+		lg.Warn("failed to truncate", zap.String("path", f.Name()), zap.Error(err))
+		return r, err
+	}
+
+	start := time.Now()
+	if err = fileutil.Fsync(f.File); err != nil {
+		lg.Warn("failed to fsync", zap.String("path", f.Name()), zap.Error(err))
+		return r, err
+	}
+	walFsyncSec.Observe(time.Since(start).Seconds())
+
+	lg.Info("repaired", append(r.fields(), zap.String("backup-path", brokenName))...)
+	return r, nil
 }
 
 // openLast opens the last wal file for read and write.
@@ -226,3 +226,135 @@ func TestRepairFailDeleteDir(t *testing.T) {
 		t.Fatal("expect 'Repair' fail on unexpected directory deletion")
 	}
 }
+
+func TestInspectTail(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt corruptFunc
+		wstate  TailState
+	}{
+		{
+			name:    "clean",
+			corrupt: func(string, int64) error { return nil },
+			wstate:  TailClean,
+		},
+		{
+			name: "torn write",
+			corrupt: func(p string, offset int64) error {
+				f, err := openLast(zaptest.NewLogger(t), p)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				return f.Truncate(offset - 4)
+			},
+			wstate: TailTornWrite,
+		},
+		{
+			name: "corrupted",
+			corrupt: func(p string, offset int64) error {
+				f, err := openLast(zaptest.NewLogger(t), p)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				// flip a byte of the data of a record, not zeroing any sector
+				b := make([]byte, 1)
+				if _, err = f.ReadAt(b, offset-8); err != nil {
+					return err
+				}
+				b[0] ^= 0xff
+				_, err = f.WriteAt(b, offset-8)
+				return err
+			},
+			wstate: TailCorrupted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := t.TempDir()
+			offset := createWALWithEnts(t, p, makeEnts(10))
+			if err := tt.corrupt(p, offset); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := InspectTail(zaptest.NewLogger(t), p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.State != tt.wstate {
+				t.Fatalf("state = %v, want %v (err %v)", r.State, tt.wstate, r.Err)
+			}
+			if r.Repairable() != (tt.wstate != TailCorrupted) {
+				t.Errorf("repairable = %v, want %v", r.Repairable(), tt.wstate != TailCorrupted)
+			}
+			if tt.wstate == TailClean {
+				if r.LastIndex != 10 || r.ValidOffset != offset || r.DiscardedBytes != 0 {
+					t.Errorf("report = %+v, want last index 10, valid offset %d and no discarded bytes", r, offset)
+				}
+				return
+			}
+			if r.LastIndex != 9 || r.DiscardedBytes == 0 {
+				t.Errorf("report = %+v, want last index 9 and discarded bytes", r)
+			}
+		})
+	}
+}
+
+func TestForceRepairCorrupted(t *testing.T) {
+	p := t.TempDir()
+	offset := createWALWithEnts(t, p, makeEnts(10))
+
+	f, err := openLast(zaptest.NewLogger(t), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteAt([]byte{0xff}, offset-8); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if Repair(zaptest.NewLogger(t), p) {
+		t.Fatal("expect 'Repair' to fail on a corrupted tail")
+	}
+	r, err := ForceRepair(zaptest.NewLogger(t), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.State != TailCorrupted {
+		t.Fatalf("state = %v, want %v", r.State, TailCorrupted)
+	}
+
+	w, err := Open(zaptest.NewLogger(t), p, walpb.Snapshot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, _, ents, err := w.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 9 {
+		t.Fatalf("len(ents) = %d, want 9", len(ents))
+	}
+}
+
+// createWALWithEnts creates a WAL of the given entries, returning the offset
+// following its last record.
+func createWALWithEnts(t *testing.T, p string, ents [][]raftpb.Entry) int64 {
+	w, err := Create(zaptest.NewLogger(t), p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, es := range ents {
+		if err = w.Save(raftpb.HardState{}, es); err != nil {
+			t.Fatal(err)
+		}
+	}
+	offset, err := w.tail().Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return offset
+}
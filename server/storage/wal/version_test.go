@@ -132,6 +132,11 @@ func TestEtcdVersionFromMessage(t *testing.T) {
 			input:  &etcdserverpb.InternalRaftRequest{Header: &etcdserverpb.RequestHeader{AuthRevision: 1}},
 			expect: &version.V3_1,
 		},
+		{
+			name:   "Setting PutRequest annotations implies v3.6",
+			input:  &etcdserverpb.InternalRaftRequest{Put: &etcdserverpb.PutRequest{Annotations: map[string]string{"owner": "app"}}},
+			expect: &version.V3_6,
+		},
 		{
 			name:   "Setting a DowngradeInfoSetRequest implies v3.5",
 			input:  &etcdserverpb.InternalRaftRequest{DowngradeInfoSet: &membershippb.DowngradeInfoSetRequest{}},
@@ -157,6 +162,16 @@ func TestEtcdVersionFromMessage(t *testing.T) {
 			input:  &etcdserverpb.Compare{TargetUnion: &etcdserverpb.Compare_Lease{}},
 			expect: &version.V3_3,
 		},
+		{
+			name:   "Enum CompareTarget set to FENCING_TOKEN implies v3.6",
+			input:  &etcdserverpb.Compare{Target: etcdserverpb.Compare_FENCING_TOKEN},
+			expect: &version.V3_6,
+		},
+		{
+			name:   "Oneof Compare fencing token set implies v3.6",
+			input:  &etcdserverpb.Compare{TargetUnion: &etcdserverpb.Compare_FencingToken_{FencingToken: &etcdserverpb.Compare_FencingToken{}}},
+			expect: &version.V3_6,
+		},
 	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
@@ -809,7 +809,10 @@ func (w *WAL) sync() error {
 	}
 
 	start := time.Now()
+	// gofail: var walBeforeFdatasync struct{}
 	err := fileutil.Fdatasync(w.tail().File)
+	// gofail: var walFdatasyncError string
+	// err = errors.New(walFdatasyncError)
 
 	took := time.Since(start)
 	if took > warnSyncDuration {
@@ -825,6 +828,8 @@ func (w *WAL) sync() error {
 }
 
 func (w *WAL) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.sync()
 }
 
@@ -927,35 +932,56 @@ func (w *WAL) Save(st raftpb.HardState, ents []raftpb.Entry) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	mustSync, err := w.save(st, ents)
+	if err != nil || !mustSync {
+		return err
+	}
+	return w.sync()
+}
+
+// SaveNoSync saves the hard state and entries like Save without syncing
+// them, returning true if they must be synced with Sync to be on stable
+// storage. It lets the caller share a single sync between several saves.
+func (w *WAL) SaveNoSync(st raftpb.HardState, ents []raftpb.Entry) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.save(st, ents)
+}
+
+// save writes the hard state and entries, cutting the wal file if full,
+// returning true if they must be synced.
+func (w *WAL) save(st raftpb.HardState, ents []raftpb.Entry) (bool, error) {
 	// short cut, do not call sync
 	if raft.IsEmptyHardState(st) && len(ents) == 0 {
-		return nil
+		return false, nil
 	}
 
 	mustSync := raft.MustSync(st, w.state, len(ents))
 
+	// gofail: var walWriteError string
+	// return false, errors.New(walWriteError)
+
 	// TODO(xiangli): no more reference operator
 	for i := range ents {
 		if err := w.saveEntry(&ents[i]); err != nil {
-			return err
+			return false, err
 		}
 	}
 	if err := w.saveState(&st); err != nil {
-		return err
+		return false, err
 	}
 
 	curOff, err := w.tail().Seek(0, io.SeekCurrent)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if curOff < SegmentSizeBytes {
-		if mustSync {
-			return w.sync()
-		}
-		return nil
+		return mustSync, nil
 	}
 
-	return w.cut()
+	// the cut syncs the records
+	return false, w.cut()
 }
 
 func (w *WAL) SaveSnapshot(e walpb.Snapshot) error {
@@ -678,6 +678,51 @@ func TestSaveEmpty(t *testing.T) {
 	}
 }
 
+func TestSaveNoSync(t *testing.T) {
+	p := t.TempDir()
+	w, err := Create(zaptest.NewLogger(t), p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := raftpb.HardState{Term: 1, Vote: 1, Commit: 1}
+	ents := []raftpb.Entry{{Index: 1, Term: 1, Data: []byte{1}}, {Index: 2, Term: 1, Data: []byte{2}}}
+	mustSync, err := w.SaveNoSync(st, ents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mustSync {
+		t.Errorf("mustSync = false after saving entries, want true")
+	}
+	st.Commit = 2
+	if mustSync, err = w.SaveNoSync(st, nil); err != nil {
+		t.Fatal(err)
+	}
+	if mustSync {
+		t.Errorf("mustSync = true after saving the commit index, want false")
+	}
+	if err = w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	w, err = Open(zaptest.NewLogger(t), p, walpb.Snapshot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, state, entries, err := w.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state, st) {
+		t.Errorf("state = %+v, want %+v", state, st)
+	}
+	if !reflect.DeepEqual(entries, ents) {
+		t.Errorf("ents = %+v, want %+v", entries, ents)
+	}
+}
+
 func TestReleaseLockTo(t *testing.T) {
 	p := t.TempDir()
 	// create WAL
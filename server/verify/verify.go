@@ -21,6 +21,7 @@ import (
 	"go.etcd.io/etcd/raft/v3/raftpb"
 	"go.etcd.io/etcd/server/v3/storage/backend"
 	"go.etcd.io/etcd/server/v3/storage/datadir"
+	"go.etcd.io/etcd/server/v3/storage/mvcc"
 	"go.etcd.io/etcd/server/v3/storage/schema"
 	wal2 "go.etcd.io/etcd/server/v3/storage/wal"
 	"go.etcd.io/etcd/server/v3/storage/wal/walpb"
@@ -38,6 +39,13 @@ type Config struct {
 	// is expected to be exact.
 	ExactIndex bool
 
+	// WALDir is the directory of the WAL, the default one of DataDir when empty.
+	WALDir string
+
+	// Full additionally requires the WAL to hold all the committed entries, and
+	// the key index rebuilt from the backend to match its key bucket.
+	Full bool
+
 	Logger *zap.Logger
 }
 
@@ -79,7 +87,17 @@ func Verify(cfg Config) error {
 	// TODO: Perform validation of consistency of membership between
 	// backend/members & WAL confstate (and maybe storev2 if still exists).
 
-	return validateConsistentIndex(cfg, hardstate, snapshot, be)
+	if err = validateConsistentIndex(cfg, hardstate, snapshot, be); err != nil {
+		return err
+	}
+	if !cfg.Full {
+		return nil
+	}
+	if err = validateWalTail(cfg, hardstate, snapshot); err != nil {
+		return err
+	}
+	_, err = mvcc.VerifyIndex(lg, be.ReadTx())
+	return err
 }
 
 // VerifyIfEnabled performs verification according to ETCD_VERIFY env settings.
@@ -125,8 +143,15 @@ func validateConsistentIndex(cfg Config, hardstate *raftpb.HardState, snapshot *
 	return nil
 }
 
+func walDir(cfg Config) string {
+	if cfg.WALDir != "" {
+		return cfg.WALDir
+	}
+	return datadir.ToWalDir(cfg.DataDir)
+}
+
 func validateWal(cfg Config) (*walpb.Snapshot, *raftpb.HardState, error) {
-	walDir := datadir.ToWalDir(cfg.DataDir)
+	walDir := walDir(cfg)
 
 	walSnaps, err := wal2.ValidSnapshotEntries(cfg.Logger, walDir)
 	if err != nil {
@@ -140,3 +165,31 @@ func validateWal(cfg Config) (*walpb.Snapshot, *raftpb.HardState, error) {
 	}
 	return &snapshot, hardstate, nil
 }
+
+// validateWalTail checks that the entries of the WAL after the last snapshot
+// are contiguous up to the committed index.
+func validateWalTail(cfg Config, hardstate *raftpb.HardState, snapshot *walpb.Snapshot) error {
+	w, err := wal2.OpenForRead(cfg.Logger, walDir(cfg), *snapshot)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, _, ents, err := w.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	last := snapshot.Index
+	for _, ent := range ents {
+		if ent.Index != last+1 {
+			return fmt.Errorf("WAL entry index (%v) expected == previous entry index + 1 (%v)", ent.Index, last+1)
+		}
+		last = ent.Index
+	}
+	if last < hardstate.Commit {
+		return fmt.Errorf("WAL last entry index (%v) must be >= WAL.HardState.commit (%v)", last, hardstate.Commit)
+	}
+
+	cfg.Logger.Info("verification: WAL tail OK", zap.Uint64("last-entry-index", last), zap.Uint64("hardstate-commit", hardstate.Commit))
+	return nil
+}
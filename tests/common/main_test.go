@@ -53,3 +53,23 @@ type testCase struct {
 	name   string
 	config config.ClusterConfig
 }
+
+// clientProxies are the proxies the tests route their client traffic through
+// with withClientProxies.
+var clientProxies = []config.ClientProxy{config.GRPCProxy, config.Gateway}
+
+// withClientProxies returns the test cases and their variants routing the
+// client traffic through each of the client proxies, for a test to check the
+// proxies behave as the members. The variants are skipped by the runners not
+// supporting the proxies.
+func withClientProxies(tcs []testCase) []testCase {
+	ret := append([]testCase{}, tcs...)
+	for _, p := range clientProxies {
+		for _, tc := range tcs {
+			tc.name = tc.name + "-" + string(p)
+			tc.config.ClientProxy = p
+			ret = append(ret, tc)
+		}
+	}
+	return ret
+}
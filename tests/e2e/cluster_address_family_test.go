@@ -0,0 +1,103 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/tests/v3/framework/config"
+	"go.etcd.io/etcd/tests/v3/framework/e2e"
+)
+
+// TestAddressFamilies ensures the members peer and serve the clients, directly
+// or through the proxies, on IPv6 literals and dual-stack URLs.
+func TestAddressFamilies(t *testing.T) {
+	e2e.BeforeTest(t)
+	e2e.SkipIfNoIPv6(t)
+
+	ca := e2e.NewCertAuthority(t)
+	certs, err := ca.IssueCert("server", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range []e2e.AddressFamily{e2e.AddressIPv6, e2e.AddressDualStack} {
+		for _, proxy := range e2e.ClientProxies {
+			for _, tls := range []bool{false, true} {
+				family, proxy, tls := family, proxy, tls
+				t.Run(fmt.Sprintf("%v/%v/TLS=%v", family, proxy, tls), func(t *testing.T) {
+					cfg := e2e.NewConfigNoTLS()
+					if tls {
+						cfg = e2e.NewConfigTLS()
+						cfg.Certs = &certs
+					}
+					cfg.AddressFamily = family
+					cfg.ClientProxy = proxy
+					testAddressFamily(t, cfg)
+				})
+			}
+		}
+	}
+}
+
+func testAddressFamily(t *testing.T, cfg *e2e.EtcdProcessClusterConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	epc, err := e2e.NewEtcdProcessCluster(ctx, t, cfg)
+	if err != nil {
+		t.Fatalf("could not start etcd process cluster (%v)", err)
+	}
+	defer func() {
+		if errC := epc.Close(); errC != nil {
+			t.Fatalf("error closing etcd processes (%v)", errC)
+		}
+	}()
+
+	eps := epc.EndpointsV3()
+	if err = e2e.NewEtcdctl(cfg, eps[:1]).Put(ctx, "foo", "bar", config.PutOptions{}); err != nil {
+		t.Fatalf("put failed (%v)", err)
+	}
+	resp, err := e2e.NewEtcdctl(cfg, eps[len(eps)-1:]).Get(ctx, "foo", config.GetOptions{})
+	if err != nil {
+		t.Fatalf("get failed (%v)", err)
+	}
+	if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "bar" {
+		t.Fatalf("get returned %v, want foo=bar", resp.Kvs)
+	}
+
+	members, err := e2e.NewEtcdctl(cfg, eps).MemberList(ctx)
+	if err != nil {
+		t.Fatalf("member list failed (%v)", err)
+	}
+	want := 1
+	if cfg.AddressFamily == e2e.AddressDualStack {
+		want = 2
+	}
+	for _, m := range members.Members {
+		if len(m.PeerURLs) != want || len(m.ClientURLs) != want {
+			t.Fatalf("member %s advertises peer URLs %v and client URLs %v, want %d of each", m.Name, m.PeerURLs, m.ClientURLs, want)
+		}
+		// each advertised client URL serves the clients
+		for _, u := range m.ClientURLs {
+			if _, err = e2e.NewEtcdctl(cfg, []string{u}).Get(ctx, "foo", config.GetOptions{}); err != nil {
+				t.Fatalf("get through %s failed (%v)", u, err)
+			}
+		}
+	}
+}
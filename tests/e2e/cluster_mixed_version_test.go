@@ -0,0 +1,75 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/tests/v3/framework/e2e"
+)
+
+// TestMixedVersionCluster ensures that clusters mixing the last release and
+// the current binary serve requests, while their members are upgraded.
+func TestMixedVersionCluster(t *testing.T) {
+	for _, v := range []e2e.ClusterVersion{e2e.MinorityLastVersion, e2e.QuorumLastVersion, e2e.LastVersion} {
+		t.Run(string(v), func(t *testing.T) {
+			e2e.BeforeTest(t)
+
+			cfg := e2e.NewConfigNoTLS()
+			cfg.Version = v
+			cfg.KeepDataDir = true
+			cfg.BaseScheme = "unix" // to avoid port conflict
+
+			epc, err := e2e.NewEtcdProcessCluster(context.TODO(), t, cfg)
+			if err != nil {
+				t.Fatalf("could not start etcd process cluster (%v)", err)
+			}
+			defer func() {
+				if errC := epc.Close(); errC != nil {
+					t.Fatalf("error closing etcd processes (%v)", errC)
+				}
+			}()
+
+			os.Setenv("ETCDCTL_API", "3")
+			defer os.Unsetenv("ETCDCTL_API")
+			cx := ctlCtx{
+				t:           t,
+				cfg:         *e2e.NewConfigNoTLS(),
+				dialTimeout: 7 * time.Second,
+				quorum:      true,
+				epc:         epc,
+			}
+			var kvs []kv
+			for i := range epc.Procs {
+				kvs = append(kvs, kv{key: fmt.Sprintf("foo%d", i), val: "bar"})
+				if err := ctlV3Put(cx, kvs[i].key, kvs[i].val, ""); err != nil {
+					t.Fatalf("#%d: ctlV3Put error (%v)", i, err)
+				}
+
+				t.Logf("Upgrading member: %v", i)
+				if err := epc.RestartMember(context.TODO(), t, i, e2e.CurrentBinary); err != nil {
+					t.Fatalf("#%d: error upgrading etcd process (%v)", i, err)
+				}
+				if err := ctlV3Get(cx, []string{"foo", "--prefix"}, kvs...); err != nil {
+					t.Fatalf("#%d: ctlV3Get error (%v)", i, err)
+				}
+			}
+		})
+	}
+}
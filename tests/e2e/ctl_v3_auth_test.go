@@ -16,8 +16,10 @@ package e2e
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"testing"
 	"time"
 
@@ -78,6 +80,9 @@ func TestCtlV3AuthRevisionConsistency(t *testing.T) { testCtl(t, authTestRevisio
 
 func TestCtlV3AuthTestCacheReload(t *testing.T) { testCtl(t, authTestCacheReload) }
 
+func TestCtlV3AuthErrorJSON(t *testing.T) { testCtl(t, authTestErrorJSON) }
+func TestCtlV3AuthExitCodes(t *testing.T) { testCtl(t, authTestExitCodes) }
+
 func authEnableTest(cx ctlCtx) {
 	if err := authEnable(cx); err != nil {
 		cx.t.Fatal(err)
@@ -438,6 +443,53 @@ func ctlV3PutFailPerm(cx ctlCtx, key, val string) error {
 	return e2e.SpawnWithExpectWithEnv(append(cx.PrefixArgs(), "put", key, val), cx.envMap, "permission denied")
 }
 
+// authTestErrorJSON ensures the auth failures are written as JSON error
+// envelopes with their exit codes with the json output format.
+func authTestErrorJSON(cx ctlCtx) {
+	if err := authEnable(cx); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.user, cx.pass = "root", "root"
+	authSetupTestUser(cx)
+
+	cx.user, cx.pass = "test-user", "pass"
+	if err := e2e.SpawnWithExpectWithEnv(append(cx.PrefixArgs(), "-w", "json", "put", "hoo", "a"), cx.envMap,
+		`{"code":8,"error":"etcdserver: permission denied","etcd_error":"etcdserver: permission denied","retryable":false}`); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.user, cx.pass = "test-user", "wrong"
+	if err := e2e.SpawnWithExpectWithEnv(append(cx.PrefixArgs(), "-w", "json", "put", "foo", "a"), cx.envMap,
+		`{"code":7,"error":"etcdserver: authentication failed, invalid user ID or password","etcd_error":"etcdserver: authentication failed, invalid user ID or password","retryable":false}`); err != nil {
+		cx.t.Fatal(err)
+	}
+}
+
+// authTestExitCodes ensures the permission denied failure exits with 1, and
+// with its own exit code with --exit-codes.
+func authTestExitCodes(cx ctlCtx) {
+	if err := authEnable(cx); err != nil {
+		cx.t.Fatal(err)
+	}
+	cx.user, cx.pass = "root", "root"
+	authSetupTestUser(cx)
+
+	cx.user, cx.pass = "test-user", "pass"
+	for _, tt := range []struct {
+		args  []string
+		wcode int
+	}{
+		{[]string{"put", "hoo", "a"}, 1},
+		{[]string{"put", "hoo", "a", "--exit-codes"}, 8},
+	} {
+		args := append(cx.PrefixArgs(), tt.args...)
+		err := exec.Command(args[0], args[1:]...).Run()
+		var eerr *exec.ExitError
+		if !errors.As(err, &eerr) || eerr.ExitCode() != tt.wcode {
+			cx.t.Fatalf("%v: got error %v, want exit code %d", tt.args, err, tt.wcode)
+		}
+	}
+}
+
 func authSetupTestUser(cx ctlCtx) {
 	if err := ctlV3User(cx, []string{"add", "test-user", "--interactive=false"}, "User test-user created", []string{"pass"}); err != nil {
 		cx.t.Fatal(err)
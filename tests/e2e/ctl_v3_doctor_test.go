@@ -0,0 +1,40 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/tests/v3/framework/e2e"
+)
+
+func TestCtlV3Doctor(t *testing.T) {
+	testCtl(t, doctorTest, withCfg(*e2e.NewConfigNoTLS()))
+}
+
+func doctorTest(cx ctlCtx) {
+	cmdArgs := append(cx.PrefixArgs(), "doctor")
+	if err := e2e.SpawnWithExpectWithEnv(cmdArgs, cx.envMap, "No problems found"); err != nil {
+		cx.t.Fatalf("doctorTest error (%v)", err)
+	}
+
+	if _, err := ctlV3LeaseGrant(cx, 100); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs = append(cx.PrefixArgs(), "doctor", "--max-leases", "0")
+	if err := e2e.SpawnWithExpectWithEnv(cmdArgs, cx.envMap, "[WARNING] leases: 1 leases, above 0"); err != nil {
+		cx.t.Fatalf("doctorTest error (%v)", err)
+	}
+}
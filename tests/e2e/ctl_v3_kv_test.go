@@ -142,6 +142,7 @@ func getTest(cx ctlCtx) {
 		{[]string{"key", "--prefix", "--sort-by=CREATE"}, kvs}, // ASCEND by default
 		{[]string{"key", "--prefix", "--order=DESCEND", "--sort-by=CREATE"}, revkvs},
 		{[]string{"key", "--prefix", "--order=DESCEND", "--sort-by=KEY"}, revkvs},
+		{[]string{"key", "--prefix", "--descend-key"}, revkvs},
 	}
 	for i, tt := range tests {
 		if err := ctlV3Get(cx, tt.args, tt.wkv...); err != nil {
@@ -27,6 +27,7 @@ func TestCtlV3MakeMirror(t *testing.T)                 { testCtl(t, makeMirrorTe
 func TestCtlV3MakeMirrorModifyDestPrefix(t *testing.T) { testCtl(t, makeMirrorModifyDestPrefixTest) }
 func TestCtlV3MakeMirrorNoDestPrefix(t *testing.T)     { testCtl(t, makeMirrorNoDestPrefixTest) }
 func TestCtlV3MakeMirrorWithWatchRev(t *testing.T)     { testCtl(t, makeMirrorWithWatchRev) }
+func TestCtlV3MakeMirrorBidirectional(t *testing.T)    { testCtl(t, makeMirrorBidirectionalTest) }
 
 func makeMirrorTest(cx ctlCtx) {
 	var (
@@ -73,8 +74,55 @@ func makeMirrorWithWatchRev(cx ctlCtx) {
 	testMirrorCommand(cx, flags, kvs, kvs2, srcprefix, destprefix)
 }
 
-func testMirrorCommand(cx ctlCtx, flags []string, sourcekvs []kv, destkvs []kvExec, srcprefix, destprefix string) {
-	// set up another cluster to mirror with
+func makeMirrorBidirectionalTest(cx ctlCtx) {
+	mirrorctx, closeMirror := startMirrorCluster(cx)
+	defer closeMirror()
+
+	if err := ctlV3Put(cx, "key1", "val1", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err := ctlV3Put(mirrorctx, "key2", "val2", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	cmdArgs := append(cx.PrefixArgs(), "make-mirror", "--prefix", "key", "--bidirectional")
+	cmdArgs = append(cmdArgs, fmt.Sprintf("localhost:%d", mirrorctx.cfg.BasePort))
+	proc, err := e2e.SpawnCmd(cmdArgs, cx.envMap)
+	if err != nil {
+		cx.t.Fatal(err)
+	}
+	defer func() {
+		err = proc.Stop()
+		if err != nil {
+			cx.t.Fatal(err)
+		}
+	}()
+
+	if err = ctlV3Put(cx, "key3", "val3", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err = ctlV3Put(mirrorctx, "key4", "val4", ""); err != nil {
+		cx.t.Fatal(err)
+	}
+
+	// the keys written to either cluster are mirrored to the other one
+	if err = ctlV3Watch(mirrorctx, []string{"key3", "--rev", "1"}, kvExec{key: "key3", val: "val3"}); err != nil {
+		cx.t.Fatal(err)
+	}
+	if err = ctlV3Watch(cx, []string{"key4", "--rev", "1"}, kvExec{key: "key4", val: "val4"}); err != nil {
+		cx.t.Fatal(err)
+	}
+	kvs := []kv{{"key1", "val1"}, {"key2", "val2"}, {"key3", "val3"}, {"key4", "val4"}}
+	for _, ctx := range []ctlCtx{cx, mirrorctx} {
+		if err = ctlV3Get(ctx, []string{"key", "--prefix"}, kvs...); err != nil {
+			cx.t.Fatal(err)
+		}
+	}
+}
+
+// startMirrorCluster starts another cluster to mirror with, returning the
+// context of etcdctl for it and the function closing it.
+func startMirrorCluster(cx ctlCtx) (ctlCtx, func()) {
 	mirrorcfg := e2e.NewConfigAutoTLS()
 	mirrorcfg.ClusterSize = 1
 	mirrorcfg.BasePort = 10000
@@ -90,15 +138,21 @@ func testMirrorCommand(cx ctlCtx, flags []string, sourcekvs []kv, destkvs []kvEx
 	}
 	mirrorctx.epc = mirrorepc
 
-	defer func() {
+	return mirrorctx, func() {
 		if err = mirrorctx.epc.Close(); err != nil {
 			cx.t.Fatalf("error closing etcd processes (%v)", err)
 		}
-	}()
+	}
+}
+
+func testMirrorCommand(cx ctlCtx, flags []string, sourcekvs []kv, destkvs []kvExec, srcprefix, destprefix string) {
+	// set up another cluster to mirror with
+	mirrorctx, closeMirror := startMirrorCluster(cx)
+	defer closeMirror()
 
 	cmdArgs := append(cx.PrefixArgs(), "make-mirror")
 	cmdArgs = append(cmdArgs, flags...)
-	cmdArgs = append(cmdArgs, fmt.Sprintf("localhost:%d", mirrorcfg.BasePort))
+	cmdArgs = append(cmdArgs, fmt.Sprintf("localhost:%d", mirrorctx.cfg.BasePort))
 	proc, err := e2e.SpawnCmd(cmdArgs, cx.envMap)
 	if err != nil {
 		cx.t.Fatal(err)
@@ -307,3 +307,79 @@ func snapshotVersionTest(cx ctlCtx) {
 		cx.t.Fatalf("expected %q, got %q", "3.6.0", st.Version)
 	}
 }
+
+// TestCtlV3SnapshotRestoreCluster ensures a cluster restored from a snapshot
+// by "snapshot restore-cluster" starts and serves the data of the snapshot.
+func TestCtlV3SnapshotRestoreCluster(t *testing.T) {
+	e2e.BeforeTest(t)
+
+	epc, err := e2e.NewEtcdProcessCluster(context.TODO(), t, &e2e.EtcdProcessClusterConfig{
+		ClusterSize:  3,
+		InitialToken: "new",
+		KeepDataDir:  true,
+	})
+	if err != nil {
+		t.Fatalf("could not start etcd process cluster (%v)", err)
+	}
+	defer func() {
+		if errC := epc.Close(); errC != nil {
+			t.Fatalf("error closing etcd processes (%v)", errC)
+		}
+	}()
+
+	prefixArgs := []string{e2e.CtlBinPath, "--endpoints", strings.Join(epc.EndpointsV3(), ",")}
+	kvs := []kv{{"foo1", "val1"}, {"foo2", "val2"}}
+	for i := range kvs {
+		if err = e2e.SpawnWithExpect(append(prefixArgs, "put", kvs[i].key, kvs[i].val), "OK"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fpath := filepath.Join(t.TempDir(), "test.snapshot")
+	if err = e2e.SpawnWithExpect([]string{e2e.CtlBinPath, "--endpoints", epc.EndpointsV3()[0], "snapshot", "save", fpath}, "Snapshot saved at"); err != nil {
+		t.Fatal(err)
+	}
+	if err = epc.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	dataDir := t.TempDir()
+	if err = e2e.SpawnWithExpect([]string{e2e.CtlBinPath, "snapshot", "restore-cluster", fpath,
+		"--members", epc.Procs[0].Config().InitialCluster,
+		"--initial-cluster-token", "restored",
+		"--data-dir", filepath.Join(dataDir, "{name}"),
+		"--runner", "local",
+		"--etcdutl", e2e.UtlBinPath,
+	}, "Restored the snapshot on 3 members"); err != nil {
+		t.Fatal(err)
+	}
+	// the data directories exist now
+	if err = e2e.SpawnWithExpect([]string{e2e.CtlBinPath, "snapshot", "restore-cluster", fpath,
+		"--members", epc.Procs[0].Config().InitialCluster,
+		"--data-dir", filepath.Join(dataDir, "{name}"),
+		"--runner", "local",
+		"--etcdutl", e2e.UtlBinPath,
+	}, "already exists"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range epc.Procs {
+		cfg := p.Config()
+		cfg.DataDirPath = filepath.Join(dataDir, cfg.Name)
+		for i := range cfg.Args {
+			switch cfg.Args[i] {
+			case "--data-dir":
+				cfg.Args[i+1] = cfg.DataDirPath
+			case "--initial-cluster-token":
+				cfg.Args[i+1] = "restored"
+			}
+		}
+	}
+	if err = epc.Restart(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	for i := range kvs {
+		if err = e2e.SpawnWithExpect(append(prefixArgs, "get", kvs[i].key), kvs[i].val); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -15,6 +15,10 @@
 package e2e
 
 import (
+	"os"
+	"path/filepath"
+	"testing"
+
 	"go.etcd.io/etcd/tests/v3/framework/e2e"
 )
 
@@ -82,3 +86,32 @@ func ctlV3Txn(cx ctlCtx, rqs txnRequests) error {
 	}
 	return proc.Close()
 }
+
+func TestCtlV3TxnFile(t *testing.T) { testCtl(t, txnTestFile) }
+
+func txnTestFile(cx ctlCtx) {
+	if err := ctlV3Put(cx, "key1", "value1", ""); err != nil {
+		cx.t.Fatalf("txnTestFile: ctlV3Put error (%v)", err)
+	}
+	// the nested txn succeeds as key1 has its value
+	doc := `compare:
+- {key: key1, target: mod, result: ">", value: 0}
+success:
+- txn:
+    compare:
+    - {key: key1, target: value, result: "=", value: value1}
+    success:
+    - put: {key: key2, value: value2}
+- get: {key: key, prefix: true}
+failure:
+- put: {key: key1, value: created-key1}
+`
+	path := filepath.Join(cx.t.TempDir(), "txn.yaml")
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		cx.t.Fatal(err)
+	}
+	cmdArgs := append(cx.PrefixArgs(), "txn", "--file", path)
+	if err := e2e.SpawnWithExpects(cmdArgs, cx.envMap, "SUCCESS", "SUCCESS", "OK", "key1", "value1", "key2", "value2"); err != nil {
+		cx.t.Fatalf("txnTestFile: txn error (%v)", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/tests/v3/framework/e2e"
+)
+
+// TestDiskFaults ensures that a member of a slow disk keeps up with the
+// cluster, and that a member failing to write its WAL stops while the
+// others keep serving.
+func TestDiskFaults(t *testing.T) {
+	e2e.BeforeTest(t)
+
+	cfg := e2e.NewConfigNoTLS()
+	cfg.GoFailEnabled = true
+	epc, err := e2e.NewEtcdProcessCluster(context.TODO(), t, cfg)
+	if err != nil {
+		t.Fatalf("could not start etcd process cluster (%v)", err)
+	}
+	defer func() {
+		if errC := epc.Close(); errC != nil {
+			t.Logf("error closing etcd processes (%v)", errC)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err = epc.SlowFsync(ctx, 0, 100*time.Millisecond); err != nil {
+		if errors.Is(err, e2e.ErrFailpointsUnavailable) {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+	cli, err := clientv3.New(clientv3.Config{Endpoints: epc.Procs[1].EndpointsV3(), DialTimeout: 3 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	if _, err = cli.Put(ctx, "foo", "bar"); err != nil {
+		t.Fatalf("put with slow fsync: %v", err)
+	}
+	if err = epc.HealDisk(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = epc.FailDiskWrites(ctx, 0, e2e.DiskFull); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Put(ctx, "foo", "baz"); err != nil {
+		t.Fatalf("put with a member of full disk: %v", err)
+	}
+	if _, err = epc.Procs[0].Logs().ExpectWithContext(ctx, "failed to save Raft hard state and entries"); err != nil {
+		t.Fatalf("member of full disk did not fail to save its WAL: %v", err)
+	}
+	if _, err = cli.Put(ctx, "foo", "qux"); err != nil {
+		t.Fatalf("put after the member of full disk stopped: %v", err)
+	}
+}
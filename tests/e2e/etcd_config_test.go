@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -112,6 +113,51 @@ func TestEtcdUnixPeers(t *testing.T) {
 	}
 }
 
+// TestEtcdUnixSocketClientURLs checks that etcd serves and advertises clients
+// on unix sockets only, with the configured permissions.
+func TestEtcdUnixSocketClientURLs(t *testing.T) {
+	e2e.SkipInShortMode(t)
+
+	d := t.TempDir()
+	sock := "unix://" + filepath.Join(d, "etcd.sock")
+	proc, err := e2e.SpawnCmd(
+		[]string{
+			e2e.BinDir + "/etcd",
+			"--data-dir", filepath.Join(d, "data"),
+			"--name", "e1",
+			"--listen-client-urls", sock,
+			"--listen-client-socket-mode", "0660",
+			"--listen-peer-urls", "unix://etcd.unix:1",
+			"--initial-advertise-peer-urls", "unix://etcd.unix:1",
+			"--initial-cluster", "e1=unix://etcd.unix:1",
+		}, nil,
+	)
+	defer os.Remove("etcd.unix:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proc.Stop()
+	if err = e2e.WaitReadyExpectProc(context.TODO(), proc, e2e.EtcdServerReadyLines); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(filepath.Join(d, "etcd.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0660 {
+		t.Errorf("expected socket permissions 0660, got %o", perm)
+	}
+
+	if err = e2e.SpawnWithExpect([]string{e2e.CtlBinPath, "--endpoints", sock, "put", "foo", "bar"}, "OK"); err != nil {
+		t.Fatal(err)
+	}
+	// the member advertises the socket it listens on
+	if err = e2e.SpawnWithExpect([]string{e2e.CtlBinPath, "--endpoints", sock, "member", "list"}, sock); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestEtcdPeerCNAuth checks that the inter peer auth based on CN of cert is working correctly.
 func TestEtcdPeerCNAuth(t *testing.T) {
 	e2e.SkipInShortMode(t)
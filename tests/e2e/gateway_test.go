@@ -49,8 +49,52 @@ func TestGateway(t *testing.T) {
 	}
 }
 
-func startGateway(t *testing.T, endpoints string) *expect.ExpectProcess {
-	p, err := expect.NewExpect(e2e.BinPath, "gateway", "--endpoints="+endpoints, "start")
+// TestGatewayTLSTermination ensures the gateway terminates the TLS connections
+// of the clients, routing them by server name to a plain cluster.
+func TestGatewayTLSTermination(t *testing.T) {
+	testGatewayRouting(t, e2e.NewConfigNoTLS(), "--cert-file="+e2e.CertPath, "--key-file="+e2e.PrivateKeyPath)
+}
+
+// TestGatewaySNIPassthrough ensures the gateway passes the TLS connections of
+// the clients through to a TLS cluster, routed by server name.
+func TestGatewaySNIPassthrough(t *testing.T) {
+	testGatewayRouting(t, e2e.NewConfigClientTLS(),
+		"--backend-cert-file="+e2e.CertPath, "--backend-key-file="+e2e.PrivateKeyPath, "--backend-trusted-ca-file="+e2e.CaPath)
+}
+
+func testGatewayRouting(t *testing.T, cfg *e2e.EtcdProcessClusterConfig, flags ...string) {
+	e2e.BeforeTest(t)
+
+	cfg.ClusterSize = 1
+	ec, err := e2e.NewEtcdProcessCluster(context.TODO(), t, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ec.Stop()
+
+	backend := "--backend=localhost=" + strings.Join(ec.EndpointsV3(), ",")
+	p := startGateway(t, "", append(flags, backend, "--health-check-interval=1s")...)
+	defer p.Stop()
+
+	tlsFlags := []string{"--cacert=" + e2e.CaPath, "--cert=" + e2e.CertPath, "--key=" + e2e.PrivateKeyPath}
+	// the server name of the connections to localhost is localhost
+	err = e2e.SpawnWithExpect(append([]string{e2e.CtlBinPath, "--endpoints=localhost:23790", "put", "foo", "bar"}, tlsFlags...), "OK\r\n")
+	if err != nil {
+		t.Errorf("failed to finish put request through gateway: %v", err)
+	}
+	// no server name, and no default endpoints
+	err = e2e.SpawnWithExpect(append([]string{e2e.CtlBinPath, "--endpoints=" + defaultGatewayEndpoint, "--command-timeout=2s", "put", "foo", "bar"}, tlsFlags...), "context deadline exceeded")
+	if err != nil {
+		t.Errorf("put request without server name through gateway not failed: %v", err)
+	}
+}
+
+func startGateway(t *testing.T, endpoints string, flags ...string) *expect.ExpectProcess {
+	args := []string{"gateway", "start"}
+	if endpoints != "" {
+		args = append(args, "--endpoints="+endpoints)
+	}
+	p, err := expect.NewExpect(e2e.BinPath, append(args, flags...)...)
 	if err != nil {
 		t.Fatal(err)
 	}
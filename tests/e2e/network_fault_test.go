@@ -0,0 +1,110 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/tests/v3/framework/e2e"
+)
+
+// TestIsolatedLeader ensures that the members elect a new leader when the
+// leader is partitioned from them, and that it catches up once healed.
+func TestIsolatedLeader(t *testing.T) {
+	e2e.BeforeTest(t)
+
+	cfg := e2e.NewConfigNoTLS()
+	cfg.PeerProxy = true
+	epc, err := e2e.NewEtcdProcessCluster(context.TODO(), t, cfg)
+	if err != nil {
+		t.Fatalf("could not start etcd process cluster (%v)", err)
+	}
+	defer func() {
+		if errC := epc.Close(); errC != nil {
+			t.Fatalf("error closing etcd processes (%v)", errC)
+		}
+	}()
+	if err = epc.AddLatency(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	lead := leader(t, epc)
+	var leadIdx, otherIdx int
+	for i, p := range epc.Procs {
+		if p == lead {
+			leadIdx = i
+		} else {
+			otherIdx = i
+		}
+	}
+	newClient := func(i int) *clientv3.Client {
+		cli, err := clientv3.New(clientv3.Config{Endpoints: epc.Procs[i].EndpointsV3(), DialTimeout: 3 * time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cli
+	}
+	leadCli, otherCli := newClient(leadIdx), newClient(otherIdx)
+	defer leadCli.Close()
+	defer otherCli.Close()
+
+	t.Logf("Isolating leader: %v", leadIdx)
+	if err = epc.Isolate(leadIdx); err != nil {
+		t.Fatal(err)
+	}
+	// the put succeeds once the other members elected a new leader
+	poll(t, 20*time.Second, func(ctx context.Context) error {
+		_, err := otherCli.Put(ctx, "foo", "bar")
+		return err
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	_, err = leadCli.Get(ctx, "foo")
+	cancel()
+	if err == nil {
+		t.Fatal("linearizable read of isolated leader succeeded")
+	}
+
+	t.Log("Healing partition")
+	if err = epc.Heal(); err != nil {
+		t.Fatal(err)
+	}
+	poll(t, 20*time.Second, func(ctx context.Context) error {
+		resp, err := leadCli.Get(ctx, "foo")
+		if err == nil && (len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "bar") {
+			t.Fatalf("healed member got %v, want foo=bar", resp.Kvs)
+		}
+		return err
+	})
+}
+
+// poll calls f until it succeeds, failing the test after the timeout.
+func poll(t *testing.T, timeout time.Duration, f func(ctx context.Context) error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := f(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("still failing after %v: %v", timeout, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
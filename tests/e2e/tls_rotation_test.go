@@ -0,0 +1,74 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/tests/v3/framework/e2e"
+)
+
+// TestTLSCertRenewal ensures that the members reload their renewed
+// certificates without client errors, and peer with them once the previous
+// ones expired.
+func TestTLSCertRenewal(t *testing.T) {
+	e2e.BeforeTest(t)
+
+	validFor := 8 * time.Second
+	ca := e2e.NewCertAuthority(t)
+	certs, err := ca.IssueCert("server", validFor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiry := time.Now().Add(validFor)
+
+	cfg := e2e.NewConfigTLS()
+	cfg.ClientCertAuthEnabled = true
+	cfg.Certs = &certs
+	epc, err := e2e.NewEtcdProcessCluster(context.TODO(), t, cfg)
+	if err != nil {
+		t.Fatalf("could not start etcd process cluster (%v)", err)
+	}
+	defer func() {
+		if errC := epc.Close(); errC != nil {
+			t.Fatalf("error closing etcd processes (%v)", errC)
+		}
+	}()
+
+	err = epc.ExpectNoClientErrors(context.TODO(), func() error {
+		time.Sleep(time.Second)
+		t.Log("Renewing certificates")
+		if err := ca.RenewCert(certs, time.Hour); err != nil {
+			return err
+		}
+		// new connections keep being made past the expiry of the previous
+		// certificates
+		time.Sleep(time.Until(expiry.Add(time.Second)))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("client error while renewing certificates (%v)", err)
+	}
+
+	t.Log("Restarting member with the previous certificates expired")
+	if err = epc.Procs[0].Restart(context.TODO()); err != nil {
+		t.Fatalf("error restarting etcd process (%v)", err)
+	}
+	if err = epc.ExpectNoClientErrors(context.TODO(), func() error { return nil }); err != nil {
+		t.Fatalf("client error after restart (%v)", err)
+	}
+}
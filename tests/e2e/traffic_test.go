@@ -0,0 +1,78 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/tests/v3/framework/e2e"
+)
+
+// TestTrafficProfiles ensures that a healthy cluster serves the traffic of
+// each profile without failures, keeping the watch and lease guarantees.
+func TestTrafficProfiles(t *testing.T) {
+	e2e.BeforeTest(t)
+
+	epc, err := e2e.NewEtcdProcessCluster(context.TODO(), t, e2e.NewConfigNoTLS())
+	if err != nil {
+		t.Fatalf("could not start etcd process cluster (%v)", err)
+	}
+	defer func() {
+		if errC := epc.Close(); errC != nil {
+			t.Fatalf("error closing etcd processes (%v)", errC)
+		}
+	}()
+
+	for _, profile := range []e2e.TrafficProfile{e2e.KubernetesTraffic, e2e.LeaseChurnTraffic, e2e.BigValuesTraffic, e2e.WatchHeavyTraffic} {
+		profile := profile
+		t.Run(profile.Name, func(t *testing.T) {
+			cfg := e2e.TrafficConfig{Profile: profile, KeyPrefix: "/" + profile.Name + "/"}
+			if profile.ValueSize > 64*1024 {
+				cfg.QPS = 20
+			}
+			if profile.LeaseTTL > 0 {
+				// the minimum TTL, for the leases not kept alive to expire
+				cfg.Profile.LeaseTTL = 2
+			}
+			traffic, err := epc.StartTraffic(context.TODO(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(2 * time.Second)
+			report := traffic.Stop()
+			t.Log(report)
+			if report.Requests() == 0 {
+				t.Fatal("no requests sent")
+			}
+			if report.FailureRate() != 0 {
+				t.Fatalf("requests failed: %v", report)
+			}
+			if profile.Watchers > 0 && report.Events == 0 {
+				t.Fatal("no watch events received")
+			}
+			if profile.LeaseTTL > 0 {
+				// for the leases granted last to expire
+				time.Sleep(3 * time.Second)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err = epc.CheckTraffic(ctx, report); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/storage/backend"
+	"go.etcd.io/etcd/server/v3/storage/datadir"
+	"go.etcd.io/etcd/server/v3/storage/schema"
+	"go.etcd.io/etcd/tests/v3/framework/config"
+	"go.etcd.io/etcd/tests/v3/framework/e2e"
+	"go.uber.org/zap/zaptest"
+)
+
+// TestEtcdutlDBOptimize ensures a member restarts from the snapshot saved by
+// etcdutl db optimize at the consistent index of its compacted and defragmented
+// backend, keeping its data.
+func TestEtcdutlDBOptimize(t *testing.T) {
+	e2e.BeforeTest(t)
+	epc, err := e2e.NewEtcdProcessCluster(context.TODO(), t, &e2e.EtcdProcessClusterConfig{
+		ClusterSize:  1,
+		InitialToken: "new",
+		KeepDataDir:  true,
+		// no snapshot is saved by etcd, the member replaying the whole WAL
+		// unless restarting from the one saved by etcdutl
+		SnapshotCount: 10000,
+	})
+	if err != nil {
+		t.Fatalf("could not start etcd process cluster (%v)", err)
+	}
+	defer func() {
+		if errC := epc.Close(); errC != nil {
+			t.Fatalf("error closing etcd processes (%v)", errC)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cc := e2e.NewEtcdctl(epc.Cfg, epc.EndpointsV3())
+
+	for i := 0; i < 10; i++ {
+		for _, key := range []string{"a", "b"} {
+			if err = cc.Put(ctx, key, fmt.Sprintf("%s%d", key, i), config.PutOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	resp, err := cc.Get(ctx, "a", config.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rev := resp.Header.Revision
+
+	proc := epc.Procs[0]
+	dataDir := proc.Config().DataDirPath
+	if err = proc.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	index := readConsistentIndex(t, dataDir)
+
+	err = e2e.SpawnWithExpects([]string{e2e.UtlBinPath, "db", "optimize", "--data-dir", dataDir}, nil,
+		fmt.Sprintf("Compacted history up to revision %d", rev),
+		"Defragmented backend",
+		fmt.Sprintf("Saved snapshot at index %d", index),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readConsistentIndex(t, dataDir); got != index {
+		t.Fatalf("consistent index %d after optimize, want %d", got, index)
+	}
+
+	if err = proc.Restart(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	e2e.AssertProcessLogs(t, proc, fmt.Sprintf(`"recovered v2 store from snapshot","snapshot-index":%d`, index))
+
+	for _, key := range []string{"a", "b"} {
+		resp, err = cc.Get(ctx, key, config.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != key+"9" {
+			t.Fatalf("got %v for %q after restart, want %q", resp.Kvs, key, key+"9")
+		}
+	}
+	if _, err = cc.Get(ctx, "a", config.GetOptions{Revision: int(rev - 1)}); err == nil || !strings.Contains(err.Error(), "required revision has been compacted") {
+		t.Fatalf("got error %v reading revision %d, want it compacted", err, rev-1)
+	}
+	if err = cc.Put(ctx, "c", "c0", config.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// the entries following the snapshot are applied once, on top of the
+	// consistent index of the backend
+	status, err := cc.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status[0].RaftAppliedIndex <= index {
+		t.Fatalf("applied index %d after restart, want > %d", status[0].RaftAppliedIndex, index)
+	}
+	if status[0].Header.Revision != rev+1 {
+		t.Fatalf("revision %d after restart and put, want %d", status[0].Header.Revision, rev+1)
+	}
+}
+
+// TestEtcdutlDBOptimizeClusterMember ensures etcdutl db optimize refuses to
+// compact the history of a member of a cluster of several members, and that
+// the member only defragmented keeps the hash of its peers.
+func TestEtcdutlDBOptimizeClusterMember(t *testing.T) {
+	e2e.BeforeTest(t)
+	epc, err := e2e.NewEtcdProcessCluster(context.TODO(), t, &e2e.EtcdProcessClusterConfig{
+		ClusterSize:   3,
+		InitialToken:  "new",
+		KeepDataDir:   true,
+		SnapshotCount: 10000,
+	})
+	if err != nil {
+		t.Fatalf("could not start etcd process cluster (%v)", err)
+	}
+	defer func() {
+		if errC := epc.Close(); errC != nil {
+			t.Fatalf("error closing etcd processes (%v)", errC)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cc := e2e.NewEtcdctl(epc.Cfg, epc.EndpointsV3())
+
+	for i := 0; i < 10; i++ {
+		if err = cc.Put(ctx, "a", fmt.Sprintf("a%d", i), config.PutOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	proc := epc.Procs[0]
+	dataDir := proc.Config().DataDirPath
+	if err = proc.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{e2e.UtlBinPath, "db", "optimize", "--data-dir", dataDir}
+	if err = e2e.SpawnWithExpect(args, "refusing to compact the history of a member of a cluster of 3 members"); err != nil {
+		t.Fatal(err)
+	}
+	if err = e2e.SpawnWithExpects(append(args, "--skip-compaction"), nil, "Skipped compaction", "Defragmented backend", "Saved snapshot at index"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = proc.Restart(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := cc.Get(ctx, "a", config.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := cc.HashKV(ctx, resp.Header.Revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 3 {
+		t.Fatalf("got the hashes of %d members, want 3", len(hashes))
+	}
+	for _, h := range hashes[1:] {
+		if h.Hash != hashes[0].Hash || h.CompactRevision != hashes[0].CompactRevision {
+			t.Fatalf("hashes %v of the members differ after optimize", hashes)
+		}
+	}
+}
+
+func readConsistentIndex(t *testing.T, dataDir string) uint64 {
+	be := backend.NewDefaultBackend(zaptest.NewLogger(t), datadir.ToBackendFileName(dataDir))
+	defer be.Close()
+	index, _ := schema.ReadConsistentIndex(be.ReadTx())
+	return index
+}
@@ -26,10 +26,20 @@ const (
 	TickDuration = 10 * time.Millisecond
 )
 
+// ClientProxy is the proxy the client traffic of a cluster is routed through.
+type ClientProxy string
+
+const (
+	NoProxy   ClientProxy = ""
+	GRPCProxy ClientProxy = "grpc-proxy"
+	Gateway   ClientProxy = "gateway"
+)
+
 type ClusterConfig struct {
 	ClusterSize                int
 	PeerTLS                    TLSConfig
 	ClientTLS                  TLSConfig
+	ClientProxy                ClientProxy
 	QuotaBackendBytes          int64
 	DisableStrictReconfigCheck bool
 	SnapshotCount              int
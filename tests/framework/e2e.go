@@ -61,6 +61,16 @@ func (e e2eRunner) NewCluster(ctx context.Context, t testing.TB, cfg config.Clus
 	default:
 		t.Fatalf("ClientTLS config %q not supported", cfg.ClientTLS)
 	}
+	switch cfg.ClientProxy {
+	case config.NoProxy:
+		e2eConfig.ClientProxy = e2e.ClientNoProxy
+	case config.GRPCProxy:
+		e2eConfig.ClientProxy = e2e.ClientGRPCProxy
+	case config.Gateway:
+		e2eConfig.ClientProxy = e2e.ClientGateway
+	default:
+		t.Fatalf("ClientProxy config %q not supported", cfg.ClientProxy)
+	}
 	switch cfg.PeerTLS {
 	case config.NoTLS:
 		e2eConfig.IsPeerTLS = false
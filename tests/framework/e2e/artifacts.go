@@ -0,0 +1,172 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/fileutil"
+
+	"go.uber.org/zap"
+)
+
+// artifactsLogTail is the number of the last log lines of a member captured.
+const artifactsLogTail = 2000
+
+// artifactsDir returns the directory the state of the cluster of the failed
+// test is captured to.
+func (epc *EtcdProcessCluster) artifactsDir() string {
+	return filepath.Join(ArtifactsDir, testNameCleanRegex.ReplaceAllString(epc.tb.Name(), "_"))
+}
+
+// captureRunningState captures the log tail, the metrics and the goroutine
+// and heap profiles of the running members, the test having failed.
+func (epc *EtcdProcessCluster) captureRunningState() {
+	cli, err := epc.httpClient()
+	if err != nil {
+		epc.lg.Warn("failed to capture cluster state", zap.Error(err))
+		return
+	}
+	for _, ep := range epc.Procs {
+		if ep == nil {
+			continue
+		}
+		dir := filepath.Join(epc.artifactsDir(), ep.Config().Name)
+		if err = os.MkdirAll(dir, fileutil.PrivateDirMode); err != nil {
+			epc.lg.Warn("failed to capture cluster state", zap.Error(err))
+			return
+		}
+		sp := serverProcess(ep)
+		if sp == nil || sp.proc == nil {
+			continue
+		}
+		lines := sp.proc.Lines()
+		if len(lines) > artifactsLogTail {
+			lines = lines[len(lines)-artifactsLogTail:]
+		}
+		captures := map[string]func() error{
+			"log.txt": func() error {
+				return os.WriteFile(filepath.Join(dir, "log.txt"), []byte(strings.Join(lines, "")), 0600)
+			},
+			"metrics.txt":    captureURL(cli, ep.Config().Acurl+"/metrics", filepath.Join(dir, "metrics.txt")),
+			"goroutines.txt": captureURL(cli, ep.Config().Acurl+"/debug/pprof/goroutine?debug=2", filepath.Join(dir, "goroutines.txt")),
+			"heap.pb.gz":     captureURL(cli, ep.Config().Acurl+"/debug/pprof/heap", filepath.Join(dir, "heap.pb.gz")),
+		}
+		for name, capture := range captures {
+			if cerr := capture(); cerr != nil {
+				epc.lg.Warn("failed to capture member state", zap.String("name", ep.Config().Name), zap.String("file", name), zap.Error(cerr))
+			}
+		}
+	}
+}
+
+// captureDataDirs copies the data dirs of the stopped members, the test
+// having failed.
+func (epc *EtcdProcessCluster) captureDataDirs() {
+	for _, ep := range epc.Procs {
+		if ep == nil {
+			continue
+		}
+		dst := filepath.Join(epc.artifactsDir(), ep.Config().Name, "data")
+		if err := copyDir(ep.Config().DataDirPath, dst); err != nil {
+			epc.lg.Warn("failed to capture member data dir", zap.String("name", ep.Config().Name), zap.Error(err))
+		}
+	}
+	epc.tb.Logf("captured the state of the cluster to %s", epc.artifactsDir())
+}
+
+// httpClient returns a client of the client URLs of the members.
+func (epc *EtcdProcessCluster) httpClient() (*http.Client, error) {
+	tlsConfig, err := epc.clientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}, Timeout: 10 * time.Second}, nil
+}
+
+// serverProcess returns the server process of the etcd process, or nil if
+// not known.
+func serverProcess(ep EtcdProcess) *EtcdServerProcess {
+	switch p := ep.(type) {
+	case *EtcdServerProcess:
+		return p
+	case *proxyEtcdProcess:
+		return serverProcess(p.etcdProc)
+	}
+	return nil
+}
+
+// captureURL returns a capture of the response of the URL to the file.
+func captureURL(cli *http.Client, u, path string) func() error {
+	return func() error {
+		resp, err := cli.Get(u)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: %s", u, resp.Status)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, resp.Body)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
+
+// copyDir copies the files of the src directory to the dst one.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, fileutil.PrivateDirMode)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+}
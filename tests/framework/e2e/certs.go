@@ -0,0 +1,246 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+// CertFiles are the files of a certificate, its key and the CA it is
+// verified with.
+type CertFiles struct {
+	CertPath string
+	KeyPath  string
+	CaPath   string
+}
+
+// CertAuthority issues short-lived certificates for the tests, e.g. to
+// renew them while the members reload them.
+type CertAuthority struct {
+	dir  string
+	cert *x509.Certificate
+	key  crypto.Signer
+
+	mu     sync.Mutex
+	serial int64
+}
+
+// NewCertAuthority returns a certificate authority of a temporary directory
+// the certificates are written to.
+func NewCertAuthority(tb testing.TB) *CertAuthority {
+	ca := &CertAuthority{dir: tb.TempDir()}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(ca.nextSerial()),
+		Subject:               pkix.Name{CommonName: "etcd e2e CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if ca.cert, err = x509.ParseCertificate(der); err != nil {
+		tb.Fatal(err)
+	}
+	ca.key = key
+	if err = writeFileAtomic(ca.caPath(), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})); err != nil {
+		tb.Fatal(err)
+	}
+	return ca
+}
+
+func (ca *CertAuthority) caPath() string { return filepath.Join(ca.dir, "ca.crt") }
+
+func (ca *CertAuthority) nextSerial() int64 {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	ca.serial++
+	return ca.serial
+}
+
+// IssueCert issues a certificate of a new key valid for the duration, for
+// the servers and clients of localhost. It has no common name, for the
+// grpc-proxy to also connect to the members with it.
+func (ca *CertAuthority) IssueCert(name string, validFor time.Duration) (CertFiles, error) {
+	files := CertFiles{
+		CertPath: filepath.Join(ca.dir, name+".crt"),
+		KeyPath:  filepath.Join(ca.dir, name+".key.insecure"),
+		CaPath:   ca.caPath(),
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return CertFiles{}, err
+	}
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return CertFiles{}, err
+	}
+	if err = writeFileAtomic(files.KeyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})); err != nil {
+		return CertFiles{}, err
+	}
+	return files, ca.writeCert(files.CertPath, key.Public(), validFor)
+}
+
+// RenewCert replaces the certificate of the files by one valid for the
+// duration. The key is kept, the members reloading the certificate and the
+// key of each new connection not to mismatch them while written.
+func (ca *CertAuthority) RenewCert(files CertFiles, validFor time.Duration) error {
+	b, err := os.ReadFile(files.KeyPath)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return errors.New("no PEM key found in " + files.KeyPath)
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	return ca.writeCert(files.CertPath, key.Public(), validFor)
+}
+
+func (ca *CertAuthority) writeCert(path string, pub crypto.PublicKey, validFor time.Duration) error {
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.nextSerial()),
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(validFor),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// writeFileAtomic writes the file through a rename, for it not to be read
+// partially written.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// ExpectNoClientErrors sends requests to all the members through new
+// connections while f runs, e.g. renewing the certificates, returning the
+// first error of a request, or of f.
+func (epc *EtcdProcessCluster) ExpectNoClientErrors(ctx context.Context, f func() error) error {
+	tlsConfig, err := epc.clientTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	donec, errc := make(chan struct{}), make(chan error, 1)
+	go func() {
+		defer close(errc)
+		// a request is sent to each member at least once
+		for i := 0; ; i++ {
+			for _, ep := range epc.EndpointsV3() {
+				if err := putOnNewConn(ctx, ep, tlsConfig, fmt.Sprintf("rotation-%d", i)); err != nil {
+					errc <- fmt.Errorf("request to %s: %w", ep, err)
+					return
+				}
+			}
+			select {
+			case <-donec:
+				return
+			default:
+			}
+		}
+	}()
+	err = f()
+	close(donec)
+	if cerr := <-errc; cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// clientTLSConfig returns the TLS config of the clients of the members, nil
+// if they serve the clients without TLS.
+func (epc *EtcdProcessCluster) clientTLSConfig() (*tls.Config, error) {
+	if epc.Cfg.ClientTLS != ClientTLS {
+		return nil, nil
+	}
+	if epc.Cfg.IsClientAutoTLS {
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+	certs := epc.Cfg.CertFiles()
+	tlsInfo := transport.TLSInfo{CertFile: certs.CertPath, KeyFile: certs.KeyPath, TrustedCAFile: certs.CaPath}
+	return tlsInfo.ClientConfig()
+}
+
+// putOnNewConn puts a key through a new connection to the endpoint.
+func putOnNewConn(ctx context.Context, ep string, tlsConfig *tls.Config, key string) error {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{ep},
+		DialTimeout: 5 * time.Second,
+		DialOptions: []grpc.DialOption{grpc.WithBlock()},
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err = cli.Put(ctx, key, "bar")
+	return err
+}
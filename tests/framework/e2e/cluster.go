@@ -17,9 +17,11 @@ package e2e
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -40,6 +42,74 @@ const (
 	ClientTLSAndNonTLS
 )
 
+// ClientProxyType is the proxy the client traffic of a cluster is routed
+// through.
+type ClientProxyType int
+
+const (
+	ClientNoProxy ClientProxyType = iota
+	// ClientGRPCProxy routes the client traffic through a grpc-proxy per member.
+	ClientGRPCProxy
+	// ClientGateway routes the client traffic through a gateway per member.
+	ClientGateway
+)
+
+func (p ClientProxyType) String() string {
+	switch p {
+	case ClientNoProxy:
+		return "NoProxy"
+	case ClientGRPCProxy:
+		return "GRPCProxy"
+	case ClientGateway:
+		return "Gateway"
+	default:
+		return fmt.Sprintf("ClientProxyType(%d)", int(p))
+	}
+}
+
+// ClientProxies are all the client proxy types, for the tests to run against
+// each of them.
+var ClientProxies = []ClientProxyType{ClientNoProxy, ClientGRPCProxy, ClientGateway}
+
+// AddressFamily is the address family of the URLs the members and the
+// proxies listen on and advertise.
+type AddressFamily int
+
+const (
+	// AddressLocalhost uses the localhost name, resolved by the system.
+	AddressLocalhost AddressFamily = iota
+	// AddressIPv6 uses the ::1 IPv6 literal only.
+	AddressIPv6
+	// AddressDualStack uses both the 127.0.0.1 and ::1 literals, the
+	// members serving and advertising a URL of each.
+	AddressDualStack
+)
+
+func (f AddressFamily) String() string {
+	switch f {
+	case AddressLocalhost:
+		return "Localhost"
+	case AddressIPv6:
+		return "IPv6"
+	case AddressDualStack:
+		return "DualStack"
+	default:
+		return fmt.Sprintf("AddressFamily(%d)", int(f))
+	}
+}
+
+// hosts returns the hosts of the URLs of the address family.
+func (f AddressFamily) hosts() []string {
+	switch f {
+	case AddressIPv6:
+		return []string{"::1"}
+	case AddressDualStack:
+		return []string{"127.0.0.1", "::1"}
+	default:
+		return []string{"localhost"}
+	}
+}
+
 // allow alphanumerics, underscores and dashes
 var testNameCleanRegex = regexp.MustCompile(`[^a-zA-Z0-9 \-_]+`)
 
@@ -130,6 +200,11 @@ type EtcdProcessCluster struct {
 	lg    *zap.Logger
 	Cfg   *EtcdProcessClusterConfig
 	Procs []EtcdProcess
+
+	// peerProxies are the peer proxies of the members, if configured.
+	peerProxies []*peerProxy
+	// tb is the test of the cluster, its state being captured on failure.
+	tb testing.TB
 }
 
 type EtcdProcessClusterConfig struct {
@@ -139,21 +214,44 @@ type EtcdProcessClusterConfig struct {
 	EnvVars     map[string]string
 
 	ClusterSize int
+	// Version is the versions of the binaries of the members, the one of
+	// ExecPath if current.
+	Version ClusterVersion
+	// MemberVersions are the versions of the binaries of the members,
+	// overriding Version if set.
+	MemberVersions []BinaryVersion
 
 	BaseScheme string
 	BasePort   int
+	// AddressFamily is the address family of the URLs of the members and
+	// the proxies. With ::1, the TLS other than auto TLS requires Certs, the
+	// certificate of CertPath not being issued for it.
+	AddressFamily AddressFamily
 
 	MetricsURLScheme string
 
 	SnapshotCount int // default is 10000
 
-	ClientTLS             ClientConnType
+	ClientTLS ClientConnType
+	// ClientProxy is the proxy the client traffic is routed through, the
+	// grpc-proxy for all the clusters with the cluster_proxy build tag.
+	ClientProxy ClientProxyType
+	// PeerProxy forwards the peer traffic of the members through proxies
+	// injecting network faults, see SetLinkFault. The peers must not use TLS
+	// nor unix sockets.
+	PeerProxy bool
+	// GoFailEnabled serves the failpoints of the members, see Failpoints.
+	// The binaries must be built with FAILPOINTS=1.
+	GoFailEnabled         bool
 	ClientCertAuthEnabled bool
 	IsPeerTLS             bool
 	IsPeerAutoTLS         bool
 	IsClientAutoTLS       bool
 	IsClientCRL           bool
 	NoCN                  bool
+	// Certs are the certificate files of the members and their clients, the
+	// fixtures if not set.
+	Certs *CertFiles
 
 	CipherSuites []string
 
@@ -201,6 +299,28 @@ func InitEtcdProcessCluster(t testing.TB, cfg *EtcdProcessClusterConfig) (*EtcdP
 		Cfg:   cfg,
 		lg:    zaptest.NewLogger(t),
 		Procs: make([]EtcdProcess, cfg.ClusterSize),
+		tb:    t,
+	}
+
+	if cfg.AddressFamily != AddressLocalhost && cfg.Certs == nil &&
+		((cfg.ClientTLS != ClientNonTLS && !cfg.IsClientAutoTLS) || (cfg.IsPeerTLS && !cfg.IsPeerAutoTLS)) {
+		return nil, fmt.Errorf("TLS of the %v address family requires certs issued for ::1", cfg.AddressFamily)
+	}
+	if cfg.PeerProxy {
+		if cfg.PeerScheme() != "http" {
+			return nil, fmt.Errorf("peer proxy does not support the %q peer scheme", cfg.PeerScheme())
+		}
+		if cfg.AddressFamily == AddressDualStack {
+			return nil, fmt.Errorf("peer proxy does not support the %v address family", cfg.AddressFamily)
+		}
+		for i := range etcdCfgs {
+			pp, err := newPeerProxy(epc.lg, etcdCfgs[i].Purl, etcdCfgs[i].PeerListenURL)
+			if err != nil {
+				epc.Close()
+				return nil, fmt.Errorf("cannot start peer proxy: %v", err)
+			}
+			epc.peerProxies = append(epc.peerProxies, pp)
+		}
 	}
 
 	// launch etcd processes
@@ -249,6 +369,14 @@ func (cfg *EtcdProcessClusterConfig) PeerScheme() string {
 	return peerScheme
 }
 
+// memberVersion returns the version of the binary of the i-th member.
+func (cfg *EtcdProcessClusterConfig) memberVersion(i int) BinaryVersion {
+	if i < len(cfg.MemberVersions) {
+		return cfg.MemberVersions[i]
+	}
+	return cfg.Version.memberVersion(i, cfg.ClusterSize)
+}
+
 func (cfg *EtcdProcessClusterConfig) EtcdServerProcessConfigs(tb testing.TB) []*EtcdServerProcessConfig {
 	lg := zaptest.NewLogger(tb)
 
@@ -262,42 +390,63 @@ func (cfg *EtcdProcessClusterConfig) EtcdServerProcessConfigs(tb testing.TB) []*
 		cfg.SnapshotCount = etcdserver.DefaultSnapshotCount
 	}
 
+	clientProxy := cfg.ClientProxy
+	if clientProxy == ClientNoProxy {
+		clientProxy = defaultClientProxy
+	}
+
 	etcdCfgs := make([]*EtcdServerProcessConfig, cfg.ClusterSize)
 	initialCluster := make([]string, cfg.ClusterSize)
 	for i := 0; i < cfg.ClusterSize; i++ {
-		var curls []string
-		var curl, curltls string
+		var curls, purls []string
 		port := cfg.BasePort + 5*i
-		curlHost := fmt.Sprintf("localhost:%d", port)
-
-		switch cfg.ClientTLS {
-		case ClientNonTLS, ClientTLS:
-			curl = (&url.URL{Scheme: cfg.ClientScheme(), Host: curlHost}).String()
-			curls = []string{curl}
-		case ClientTLSAndNonTLS:
-			curl = (&url.URL{Scheme: "http", Host: curlHost}).String()
-			curltls = (&url.URL{Scheme: "https", Host: curlHost}).String()
-			curls = []string{curl, curltls}
+		for _, host := range cfg.AddressFamily.hosts() {
+			curlHost := net.JoinHostPort(host, strconv.Itoa(port))
+			switch cfg.ClientTLS {
+			case ClientNonTLS, ClientTLS:
+				curls = append(curls, (&url.URL{Scheme: cfg.ClientScheme(), Host: curlHost}).String())
+			case ClientTLSAndNonTLS:
+				curls = append(curls,
+					(&url.URL{Scheme: "http", Host: curlHost}).String(),
+					(&url.URL{Scheme: "https", Host: curlHost}).String(),
+				)
+			}
+			purls = append(purls, (&url.URL{Scheme: cfg.PeerScheme(), Host: net.JoinHostPort(host, strconv.Itoa(port+1))}).String())
 		}
+		curl := curls[0]
 
-		purl := url.URL{Scheme: cfg.PeerScheme(), Host: fmt.Sprintf("localhost:%d", port+1)}
+		purl := url.URL{Scheme: cfg.PeerScheme(), Host: net.JoinHostPort(cfg.AddressFamily.hosts()[0], strconv.Itoa(port+1))}
+		lpurl := purl
+		if cfg.PeerProxy {
+			lpurl = peerProxyListenURL(purl)
+		}
 
 		name := fmt.Sprintf("%s-test-%d", testNameCleanRegex.ReplaceAllString(tb.Name(), ""), i)
 		dataDirPath := cfg.DataDirPath
 		if cfg.DataDirPath == "" {
 			dataDirPath = tb.TempDir()
 		}
-		initialCluster[i] = fmt.Sprintf("%s=%s", name, purl.String())
+		var initialPeers []string
+		for _, u := range purls {
+			initialPeers = append(initialPeers, fmt.Sprintf("%s=%s", name, u))
+		}
+		initialCluster[i] = strings.Join(initialPeers, ",")
+		lpurls := purls
+		if cfg.PeerProxy {
+			lpurls = []string{lpurl.String()}
+		}
 
 		args := []string{
 			"--name", name,
 			"--listen-client-urls", strings.Join(curls, ","),
 			"--advertise-client-urls", strings.Join(curls, ","),
-			"--listen-peer-urls", purl.String(),
-			"--initial-advertise-peer-urls", purl.String(),
+			"--listen-peer-urls", strings.Join(lpurls, ","),
+			"--initial-advertise-peer-urls", strings.Join(purls, ","),
 			"--initial-cluster-token", cfg.InitialToken,
 			"--data-dir", dataDirPath,
 			"--snapshot-count", fmt.Sprintf("%d", cfg.SnapshotCount),
+			// for the goroutine and heap profiles to be captured on failure
+			"--enable-pprof",
 		}
 
 		if cfg.ForceNewCluster {
@@ -321,7 +470,7 @@ func (cfg *EtcdProcessClusterConfig) EtcdServerProcessConfigs(tb testing.TB) []*
 		if cfg.MetricsURLScheme != "" {
 			murl = (&url.URL{
 				Scheme: cfg.MetricsURLScheme,
-				Host:   fmt.Sprintf("localhost:%d", port+2),
+				Host:   net.JoinHostPort(cfg.AddressFamily.hosts()[0], strconv.Itoa(port+2)),
 			}).String()
 			args = append(args, "--listen-metrics-urls", murl)
 		}
@@ -358,19 +507,38 @@ func (cfg *EtcdProcessClusterConfig) EtcdServerProcessConfigs(tb testing.TB) []*
 			args = append(args, "--experimental-compact-hash-check-time", cfg.CompactHashCheckTime.String())
 		}
 
+		envVars := cfg.EnvVars
+		var gofailPort int
+		if cfg.GoFailEnabled {
+			gofailPort = port + gofailPortOffset
+			envVars = make(map[string]string, len(cfg.EnvVars)+1)
+			for k, v := range cfg.EnvVars {
+				envVars[k] = v
+			}
+			envVars["GOFAIL_HTTP"] = fmt.Sprintf("127.0.0.1:%d", gofailPort)
+		}
+
+		execPath := cfg.ExecPath
+		if v := cfg.memberVersion(i); v != CurrentBinary {
+			execPath = BinaryPath(tb, v)
+		}
+
 		etcdCfgs[i] = &EtcdServerProcessConfig{
-			lg:           lg,
-			ExecPath:     cfg.ExecPath,
-			Args:         args,
-			EnvVars:      cfg.EnvVars,
-			TlsArgs:      cfg.TlsArgs(),
-			DataDirPath:  dataDirPath,
-			KeepDataDir:  cfg.KeepDataDir,
-			Name:         name,
-			Purl:         purl,
-			Acurl:        curl,
-			Murl:         murl,
-			InitialToken: cfg.InitialToken,
+			lg:            lg,
+			ExecPath:      execPath,
+			Args:          args,
+			EnvVars:       envVars,
+			TlsArgs:       cfg.TlsArgs(),
+			DataDirPath:   dataDirPath,
+			KeepDataDir:   cfg.KeepDataDir,
+			Name:          name,
+			Purl:          purl,
+			PeerListenURL: lpurl,
+			Acurl:         curl,
+			Murl:          murl,
+			InitialToken:  cfg.InitialToken,
+			ClientProxy:   clientProxy,
+			GoFailPort:    gofailPort,
 		}
 	}
 
@@ -392,15 +560,24 @@ func (cfg *EtcdProcessClusterConfig) EtcdServerProcessConfigs(tb testing.TB) []*
 	return etcdCfgs
 }
 
+// CertFiles returns the certificate files of the members and their clients.
+func (cfg *EtcdProcessClusterConfig) CertFiles() CertFiles {
+	if cfg.Certs != nil {
+		return *cfg.Certs
+	}
+	return CertFiles{CertPath: CertPath, KeyPath: PrivateKeyPath, CaPath: CaPath}
+}
+
 func (cfg *EtcdProcessClusterConfig) TlsArgs() (args []string) {
+	certs := cfg.CertFiles()
 	if cfg.ClientTLS != ClientNonTLS {
 		if cfg.IsClientAutoTLS {
 			args = append(args, "--auto-tls")
 		} else {
 			tlsClientArgs := []string{
-				"--cert-file", CertPath,
-				"--key-file", PrivateKeyPath,
-				"--trusted-ca-file", CaPath,
+				"--cert-file", certs.CertPath,
+				"--key-file", certs.KeyPath,
+				"--trusted-ca-file", certs.CaPath,
 			}
 			args = append(args, tlsClientArgs...)
 
@@ -415,9 +592,9 @@ func (cfg *EtcdProcessClusterConfig) TlsArgs() (args []string) {
 			args = append(args, "--peer-auto-tls")
 		} else {
 			tlsPeerArgs := []string{
-				"--peer-cert-file", CertPath,
-				"--peer-key-file", PrivateKeyPath,
-				"--peer-trusted-ca-file", CaPath,
+				"--peer-cert-file", certs.CertPath,
+				"--peer-key-file", certs.KeyPath,
+				"--peer-trusted-ca-file", certs.CaPath,
 			}
 			args = append(args, tlsPeerArgs...)
 		}
@@ -461,6 +638,17 @@ func (epc *EtcdProcessCluster) Restart(ctx context.Context) error {
 	return epc.start(func(ep EtcdProcess) error { return ep.Restart(ctx) })
 }
 
+// RestartMember restarts the i-th member with the binary of the version, to
+// upgrade or downgrade it.
+func (epc *EtcdProcessCluster) RestartMember(ctx context.Context, tb testing.TB, i int, v BinaryVersion) error {
+	ep := epc.Procs[i]
+	if err := ep.Stop(); err != nil {
+		return err
+	}
+	ep.Config().ExecPath = BinaryPath(tb, v)
+	return ep.Start(ctx)
+}
+
 func (epc *EtcdProcessCluster) start(f func(ep EtcdProcess) error) error {
 	readyC := make(chan error, len(epc.Procs))
 	for i := range epc.Procs {
@@ -509,7 +697,14 @@ func (epc *EtcdProcessCluster) Stop() (err error) {
 
 func (epc *EtcdProcessCluster) Close() error {
 	epc.lg.Info("closing test cluster...")
+	failed := epc.tb != nil && epc.tb.Failed() && ArtifactsDir != ""
+	if failed {
+		epc.captureRunningState()
+	}
 	err := epc.Stop()
+	if failed {
+		epc.captureDataDirs()
+	}
 	for _, p := range epc.Procs {
 		// p is nil when NewEtcdProcess fails in the middle
 		// Close still gets called to clean up test data
@@ -520,6 +715,11 @@ func (epc *EtcdProcessCluster) Close() error {
 			err = cerr
 		}
 	}
+	for _, pp := range epc.peerProxies {
+		if cerr := pp.close(); cerr != nil {
+			err = cerr
+		}
+	}
 	epc.lg.Info("closed test cluster.")
 	return err
 }
@@ -17,6 +17,5 @@
 
 package e2e
 
-func NewEtcdProcess(cfg *EtcdServerProcessConfig) (EtcdProcess, error) {
-	return NewEtcdServerProcess(cfg)
-}
+// defaultClientProxy is the client proxy of the tests not configuring any.
+const defaultClientProxy = ClientNoProxy
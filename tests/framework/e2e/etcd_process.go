@@ -74,12 +74,34 @@ type EtcdServerProcessConfig struct {
 	Name string
 
 	Purl url.URL
+	// PeerListenURL is the URL the member listens for the peers on, Purl
+	// unless behind a peer proxy.
+	PeerListenURL url.URL
+	// GoFailPort is the port of the failpoints endpoint of the member, if
+	// enabled.
+	GoFailPort int
 
 	Acurl string
 	Murl  string
 
 	InitialToken   string
 	InitialCluster string
+
+	// ClientProxy is the proxy the client traffic is routed through.
+	ClientProxy ClientProxyType
+}
+
+// NewEtcdProcess returns an etcd process, its client traffic routed through
+// the client proxy of the config.
+func NewEtcdProcess(cfg *EtcdServerProcessConfig) (EtcdProcess, error) {
+	switch cfg.ClientProxy {
+	case ClientGRPCProxy:
+		return NewProxyEtcdProcess(cfg)
+	case ClientGateway:
+		return NewGatewayEtcdProcess(cfg)
+	default:
+		return NewEtcdServerProcess(cfg)
+	}
 }
 
 func NewEtcdServerProcess(cfg *EtcdServerProcessConfig) (*EtcdServerProcess, error) {
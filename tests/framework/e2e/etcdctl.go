@@ -277,9 +277,10 @@ func (ctl *EtcdctlV3) flags() map[string]string {
 			fmap["cert"] = RevokedCertPath
 			fmap["key"] = RevokedPrivateKeyPath
 		} else {
-			fmap["cacert"] = CaPath
-			fmap["cert"] = CertPath
-			fmap["key"] = PrivateKeyPath
+			certs := ctl.cfg.CertFiles()
+			fmap["cacert"] = certs.CaPath
+			fmap["cert"] = certs.CertPath
+			fmap["key"] = certs.KeyPath
 		}
 	}
 	fmap["endpoints"] = strings.Join(ctl.endpoints, ",")
@@ -320,11 +321,11 @@ func (ctl *EtcdctlV3) HashKV(ctx context.Context, rev int64) ([]*clientv3.HashKV
 		Endpoint string
 		HashKV   *clientv3.HashKVResponse
 	}
-	err := ctl.spawnJsonCmd(ctx, &epHashKVs, "endpoint", "hashkv", "--endpoints", strings.Join(ctl.endpoints, ","), "--rev", fmt.Sprint(rev))
+	err := ctl.spawnJsonCmd(ctx, &epHashKVs, "endpoint", "hashkv", "--rev", fmt.Sprint(rev))
 	if err != nil {
 		return nil, err
 	}
-	resp := make([]*clientv3.HashKVResponse, len(epHashKVs))
+	resp := make([]*clientv3.HashKVResponse, 0, len(epHashKVs))
 	for _, e := range epHashKVs {
 		resp = append(resp, e.HashKV)
 	}
@@ -0,0 +1,165 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// gofailPortOffset is the offset of the port of the failpoints endpoint of a
+// member, from its client port.
+const gofailPortOffset = 6000
+
+// ErrFailpointsUnavailable is returned when the failpoints of a member
+// cannot be set, its binary not being built with FAILPOINTS=1 or the cluster
+// not configured with GoFailEnabled.
+var ErrFailpointsUnavailable = errors.New("failpoints unavailable")
+
+// diskFailpoints are the failpoints of the disk faults.
+var diskFailpoints = []string{"walBeforeFdatasync", "beforeCommit", "walWriteError", "walFdatasyncError"}
+
+// DiskError is the error the disk writes of a member fail with.
+type DiskError string
+
+const (
+	// DiskFull fails the writes with the message of ENOSPC, as if the disk
+	// were full.
+	DiskFull DiskError = "no space left on device"
+	// DiskIOError fails the writes with the message of EIO, as if the disk
+	// were failing.
+	DiskIOError DiskError = "input/output error"
+)
+
+// Failpoints are the gofail failpoints of a member, set through its
+// GOFAIL_HTTP endpoint.
+type Failpoints struct {
+	endpoint string
+}
+
+// Failpoints returns the failpoints of the i-th member.
+func (epc *EtcdProcessCluster) Failpoints(i int) (*Failpoints, error) {
+	port := epc.Procs[i].Config().GoFailPort
+	if port == 0 {
+		return nil, ErrFailpointsUnavailable
+	}
+	return &Failpoints{endpoint: fmt.Sprintf("http://127.0.0.1:%d", port)}, nil
+}
+
+// Enable sets the failpoint to the gofail term, e.g. `sleep(100)`, `panic`
+// or `return("error")`.
+func (fp *Failpoints) Enable(ctx context.Context, name, term string) error {
+	_, err := fp.do(ctx, http.MethodPut, name, term)
+	return err
+}
+
+// Disable unsets the failpoint, which must be enabled.
+func (fp *Failpoints) Disable(ctx context.Context, name string) error {
+	_, err := fp.do(ctx, http.MethodDelete, name, "")
+	return err
+}
+
+// Enabled returns the terms of the enabled failpoints, by name.
+func (fp *Failpoints) Enabled(ctx context.Context) (map[string]string, error) {
+	b, err := fp.do(ctx, http.MethodGet, "", "")
+	if err != nil {
+		return nil, err
+	}
+	terms := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if i := strings.Index(line, "="); i > 0 {
+			terms[line[:i]] = line[i+1:]
+		}
+	}
+	return terms, nil
+}
+
+func (fp *Failpoints) do(ctx context.Context, method, name, body string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fp.endpoint+"/"+name, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailpointsUnavailable, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("failed to %s failpoint %q: %s: %s", method, name, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+// SlowFsync delays the fsyncs of the WAL and the backend commits of the
+// i-th member by d.
+func (epc *EtcdProcessCluster) SlowFsync(ctx context.Context, i int, d time.Duration) error {
+	fp, err := epc.Failpoints(i)
+	if err != nil {
+		return err
+	}
+	term := fmt.Sprintf("sleep(%d)", d.Milliseconds())
+	for _, name := range []string{"walBeforeFdatasync", "beforeCommit"} {
+		if err = fp.Enable(ctx, name, term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FailDiskWrites fails the WAL writes and fsyncs of the i-th member with the
+// disk error.
+func (epc *EtcdProcessCluster) FailDiskWrites(ctx context.Context, i int, derr DiskError) error {
+	fp, err := epc.Failpoints(i)
+	if err != nil {
+		return err
+	}
+	term := fmt.Sprintf("return(%q)", string(derr))
+	for _, name := range []string{"walWriteError", "walFdatasyncError"} {
+		if err = fp.Enable(ctx, name, term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HealDisk removes the disk faults of the i-th member.
+func (epc *EtcdProcessCluster) HealDisk(ctx context.Context, i int) error {
+	fp, err := epc.Failpoints(i)
+	if err != nil {
+		return err
+	}
+	enabled, err := fp.Enabled(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range diskFailpoints {
+		if _, ok := enabled[name]; !ok {
+			continue
+		}
+		if err = fp.Disable(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ package e2e
 import (
 	"flag"
 	"os"
+	"path/filepath"
 	"runtime"
 
 	"go.etcd.io/etcd/tests/v3/framework/integration"
@@ -41,6 +42,15 @@ var (
 	RevokedPrivateKeyPath string
 
 	FixturesDir = integration.MustAbsPath("../fixtures")
+
+	// LastReleaseVersion is the version of the last release, run by the
+	// members of LastReleaseBinary.
+	LastReleaseVersion string
+	// ReleaseCacheDir is the directory the release binaries are downloaded to.
+	ReleaseCacheDir string
+	// ArtifactsDir is the directory the state of the clusters of the failed
+	// tests is captured to, not captured if empty.
+	ArtifactsDir string
 )
 
 func InitFlags() {
@@ -52,6 +62,9 @@ func InitFlags() {
 
 	flag.StringVar(&BinDir, "bin-dir", binDirDef, "The directory for store etcd and etcdctl binaries.")
 	flag.StringVar(&CertDir, "cert-dir", certDirDef, "The directory for store certificate files.")
+	flag.StringVar(&LastReleaseVersion, "last-release-version", "v3.5.4", "The version of the last release, downloaded if etcd-last-release is not in the bin dir.")
+	flag.StringVar(&ReleaseCacheDir, "release-cache-dir", releaseCacheDirDef(), "The directory for caching the downloaded release binaries.")
+	flag.StringVar(&ArtifactsDir, "artifacts-dir", artifactsDirDef(), "The directory for capturing the data dirs, logs, metrics and profiles of the members of the failed tests, disabled if empty.")
 	flag.Parse()
 
 	BinPath = BinDir + "/etcd"
@@ -70,3 +83,18 @@ func InitFlags() {
 	CertPath3 = CertDir + "/server3.crt"
 	PrivateKeyPath3 = CertDir + "/server3.key.insecure"
 }
+
+func releaseCacheDirDef() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "etcd-e2e-releases")
+}
+
+func artifactsDirDef() string {
+	if dir := os.Getenv("ARTIFACTS"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "etcd-e2e-artifacts")
+}
@@ -0,0 +1,349 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// peerProxyPortOffset is the offset of the port the members listen for
+	// the peers on, from the one of their peer proxy.
+	peerProxyPortOffset = 5000
+	// lossRetransmitDelay is the delay of the traffic lost on a link, TCP
+	// retransmitting it after its minimum retransmission timeout.
+	lossRetransmitDelay = 200 * time.Millisecond
+)
+
+var errNoPeerProxy = errors.New("the network faults require the cluster to be configured with PeerProxy")
+
+// LinkFault is the network fault of the link between two members, applied in
+// both directions.
+type LinkFault struct {
+	// Partitioned drops the connections of the link.
+	Partitioned bool
+	// Latency is added to the traffic of the link.
+	Latency time.Duration
+	// Loss is the ratio of the traffic of the link lost. The peer traffic
+	// being over TCP, the lost traffic is delayed by lossRetransmitDelay.
+	Loss float64
+}
+
+// peerProxy forwards the peer traffic to a member, injecting the faults of
+// its links. The links are identified by the peer URLs the rafthttp requests
+// of the other members are sent with, requiring the peer traffic not to be
+// encrypted.
+type peerProxy struct {
+	lg     *zap.Logger
+	l      net.Listener
+	target string
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+	// faults are the faults of the links, by peer URL of the other member.
+	faults map[string]LinkFault
+	// conns are the connections being forwarded, by peer URL of the other
+	// member, the empty one if unknown.
+	conns  map[string]map[net.Conn]struct{}
+	closed bool
+}
+
+// newPeerProxy forwards the peer traffic of the advertised peer URL to the
+// one the member listens on.
+func newPeerProxy(lg *zap.Logger, advertise, listen url.URL) (*peerProxy, error) {
+	l, err := net.Listen("tcp", advertise.Host)
+	if err != nil {
+		return nil, err
+	}
+	p := &peerProxy{
+		lg:     lg,
+		l:      l,
+		target: listen.Host,
+		faults: make(map[string]LinkFault),
+		conns:  make(map[string]map[net.Conn]struct{}),
+	}
+	p.wg.Add(1)
+	go p.serve()
+	return p, nil
+}
+
+func (p *peerProxy) serve() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.l.Accept()
+		if err != nil {
+			return
+		}
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.forward(conn)
+		}()
+	}
+}
+
+// forward forwards the connection, its link being identified by its first
+// request.
+func (p *peerProxy) forward(conn net.Conn) {
+	var head bytes.Buffer
+	defer conn.Close()
+	br := bufio.NewReader(io.TeeReader(conn, &head))
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return
+	}
+	arrived := time.Now()
+	var peer string
+	if urls := req.Header.Get("X-PeerURLs"); urls != "" {
+		peer = strings.Split(urls, ",")[0]
+	}
+
+	dst, err := net.Dial("tcp", p.target)
+	if err != nil {
+		return
+	}
+	defer dst.Close()
+	if !p.track(peer, conn, dst) {
+		return
+	}
+	defer p.untrack(peer, conn, dst)
+	if peer == "" {
+		p.forwardRequest(conn, dst, req)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.pump(conn, dst, peer, nil)
+		close(done)
+	}()
+	// the bytes read to identify the link, the request head and maybe more,
+	// are forwarded first
+	p.pump(dst, conn, peer, &chunk{b: head.Bytes(), at: arrived})
+	<-done
+}
+
+// forwardRequest forwards the single request of a link not identified, e.g.
+// of the prober of the peers, closing the connection after its response not
+// to be reused by the raft traffic sharing its transport.
+func (p *peerProxy) forwardRequest(conn, dst net.Conn, req *http.Request) {
+	if err := req.Write(dst); err != nil {
+		return
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(dst), req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	resp.Close = true
+	resp.Write(conn)
+}
+
+// chunk is traffic read from a connection, at the time it arrived.
+type chunk struct {
+	b  []byte
+	at time.Time
+}
+
+// pump copies the traffic of the link from src to dst, after the first chunk
+// if any, delaying it by the latency and the loss of the link, until either
+// is closed.
+func (p *peerProxy) pump(dst, src net.Conn, peer string, first *chunk) {
+	// the traffic is read as it arrives, to be delayed from its arrival
+	chunks := make(chan chunk, 64)
+	go func() {
+		defer close(chunks)
+		if first != nil {
+			chunks <- *first
+		}
+		for {
+			b := make([]byte, 32*1024)
+			n, err := src.Read(b)
+			if n > 0 {
+				chunks <- chunk{b: b[:n], at: time.Now()}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	for c := range chunks {
+		f := p.fault(peer)
+		delay := f.Latency
+		if f.Loss > 0 && rand.Float64() < f.Loss {
+			delay += lossRetransmitDelay
+		}
+		time.Sleep(time.Until(c.at.Add(delay)))
+		if _, err := dst.Write(c.b); err != nil {
+			break
+		}
+	}
+	dst.Close()
+	src.Close()
+	for range chunks {
+	}
+}
+
+func (p *peerProxy) fault(peer string) LinkFault {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.faults[peer]
+}
+
+// track records the connections of the link, returning false if partitioned.
+func (p *peerProxy) track(peer string, conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || p.faults[peer].Partitioned {
+		return false
+	}
+	if p.conns[peer] == nil {
+		p.conns[peer] = make(map[net.Conn]struct{})
+	}
+	for _, c := range conns {
+		p.conns[peer][c] = struct{}{}
+	}
+	return true
+}
+
+func (p *peerProxy) untrack(peer string, conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range conns {
+		delete(p.conns[peer], c)
+	}
+}
+
+// setFault sets the fault of the link to the member of the peer URL, dropping
+// its connections if partitioned.
+func (p *peerProxy) setFault(peer string, f LinkFault) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if f == (LinkFault{}) {
+		delete(p.faults, peer)
+	} else {
+		p.faults[peer] = f
+	}
+	if f.Partitioned {
+		for c := range p.conns[peer] {
+			c.Close()
+		}
+	}
+}
+
+func (p *peerProxy) close() error {
+	p.mu.Lock()
+	p.closed = true
+	for _, conns := range p.conns {
+		for c := range conns {
+			c.Close()
+		}
+	}
+	p.mu.Unlock()
+	err := p.l.Close()
+	p.wg.Wait()
+	return err
+}
+
+// peerProxyListenURL returns the URL the member of the advertised peer URL
+// listens for the peers on, behind its peer proxy.
+func peerProxyListenURL(purl url.URL) url.URL {
+	host, port, _ := net.SplitHostPort(purl.Host)
+	p, _ := strconv.Atoi(port)
+	purl.Host = net.JoinHostPort(host, strconv.Itoa(p+peerProxyPortOffset))
+	return purl
+}
+
+// SetLinkFault sets the fault of the link between the members m1 and m2,
+// replacing the previous one.
+func (epc *EtcdProcessCluster) SetLinkFault(m1, m2 int, f LinkFault) error {
+	if len(epc.peerProxies) == 0 {
+		return errNoPeerProxy
+	}
+	epc.lg.Info("setting link fault",
+		zap.String("member-1", epc.Procs[m1].Config().Name),
+		zap.String("member-2", epc.Procs[m2].Config().Name),
+		zap.Bool("partitioned", f.Partitioned),
+		zap.Duration("latency", f.Latency),
+		zap.Float64("loss", f.Loss),
+	)
+	epc.peerProxies[m1].setFault(epc.Procs[m2].Config().Purl.String(), f)
+	epc.peerProxies[m2].setFault(epc.Procs[m1].Config().Purl.String(), f)
+	return nil
+}
+
+// updateLinkFaults updates the faults of the links of the member m, or of
+// all the members if negative.
+func (epc *EtcdProcessCluster) updateLinkFaults(m int, update func(f *LinkFault)) error {
+	if len(epc.peerProxies) == 0 {
+		return errNoPeerProxy
+	}
+	for i := range epc.Procs {
+		for j := i + 1; j < len(epc.Procs); j++ {
+			if m >= 0 && i != m && j != m {
+				continue
+			}
+			f := epc.peerProxies[i].fault(epc.Procs[j].Config().Purl.String())
+			update(&f)
+			if err := epc.SetLinkFault(i, j, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Partition drops the traffic between the members m1 and m2, until healed.
+func (epc *EtcdProcessCluster) Partition(m1, m2 int) error {
+	if len(epc.peerProxies) == 0 {
+		return errNoPeerProxy
+	}
+	f := epc.peerProxies[m1].fault(epc.Procs[m2].Config().Purl.String())
+	f.Partitioned = true
+	return epc.SetLinkFault(m1, m2, f)
+}
+
+// Isolate partitions the member m from all the other members.
+func (epc *EtcdProcessCluster) Isolate(m int) error {
+	return epc.updateLinkFaults(m, func(f *LinkFault) { f.Partitioned = true })
+}
+
+// AddLatency adds the latency to the traffic between all the members.
+func (epc *EtcdProcessCluster) AddLatency(d time.Duration) error {
+	return epc.updateLinkFaults(-1, func(f *LinkFault) { f.Latency += d })
+}
+
+// SetPacketLoss sets the ratio of the traffic lost between all the members.
+func (epc *EtcdProcessCluster) SetPacketLoss(loss float64) error {
+	return epc.updateLinkFaults(-1, func(f *LinkFault) { f.Loss = loss })
+}
+
+// Heal removes the faults of all the links.
+func (epc *EtcdProcessCluster) Heal() error {
+	return epc.updateLinkFaults(-1, func(f *LinkFault) { *f = LinkFault{} })
+}
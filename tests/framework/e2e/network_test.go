@@ -0,0 +1,91 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+)
+
+func TestPeerProxyLinkFaults(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newPeerProxy(zaptest.NewLogger(t), url.URL{Scheme: "http", Host: "127.0.0.1:0"}, *target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.close()
+
+	get := func(peer string) (time.Duration, error) {
+		req, err := http.NewRequest("GET", "http://"+p.l.Addr().String()+"/raft/stream", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-PeerURLs", peer)
+		// a new connection per request, for the faults to apply to them
+		tr := &http.Transport{DisableKeepAlives: true}
+		defer tr.CloseIdleConnections()
+		start := time.Now()
+		resp, err := (&http.Client{Transport: tr, Timeout: time.Second}).Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err == nil && string(b) != "ok" {
+			t.Fatalf("response %q, want %q", b, "ok")
+		}
+		return time.Since(start), err
+	}
+
+	if _, err = get("http://m1"); err != nil {
+		t.Fatalf("request without fault: %v", err)
+	}
+
+	p.setFault("http://m1", LinkFault{Partitioned: true})
+	if _, err = get("http://m1"); err == nil {
+		t.Fatal("request of partitioned link succeeded")
+	}
+	if _, err = get("http://m2,http://m2b"); err != nil {
+		t.Fatalf("request of another link: %v", err)
+	}
+
+	p.setFault("http://m1", LinkFault{Latency: 100 * time.Millisecond})
+	d, err := get("http://m1")
+	if err != nil {
+		t.Fatalf("request of delayed link: %v", err)
+	}
+	// delayed both ways
+	if d < 200*time.Millisecond {
+		t.Errorf("request of delayed link took %v, want at least %v", d, 200*time.Millisecond)
+	}
+
+	p.setFault("http://m1", LinkFault{})
+	if _, err = get("http://m1"); err != nil {
+		t.Fatalf("request of healed link: %v", err)
+	}
+}
@@ -0,0 +1,580 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+// TrafficRequestType is a request the traffic sends.
+type TrafficRequestType string
+
+const (
+	// TrafficGet gets a key.
+	TrafficGet TrafficRequestType = "get"
+	// TrafficList gets the keys of the traffic, by pages.
+	TrafficList TrafficRequestType = "list"
+	// TrafficPut puts a key.
+	TrafficPut TrafficRequestType = "put"
+	// TrafficDelete deletes a key.
+	TrafficDelete TrafficRequestType = "delete"
+	// TrafficCreate puts a key in a txn if it does not exist.
+	TrafficCreate TrafficRequestType = "create"
+	// TrafficUpdate gets a key and puts it in a txn if not modified since,
+	// as the kubernetes optimistic updates do.
+	TrafficUpdate TrafficRequestType = "update"
+	// TrafficPutWithLease grants a lease and puts a key attached to it.
+	TrafficPutWithLease TrafficRequestType = "put-with-lease"
+	// TrafficLeaseKeepAlive keeps one of the leases granted alive once.
+	TrafficLeaseKeepAlive TrafficRequestType = "lease-keepalive"
+	// TrafficLeaseRevoke revokes one of the leases granted.
+	TrafficLeaseRevoke TrafficRequestType = "lease-revoke"
+	// TrafficWatch is accounted for the failures of the watches, which are
+	// opened again from their last revision.
+	TrafficWatch TrafficRequestType = "watch"
+)
+
+// maxTrafficLeases is the number of the last leases granted by the traffic
+// kept alive and revoked, the older ones being left to expire.
+const maxTrafficLeases = 1000
+
+// TrafficRequest is a request of a profile, sent in proportion to its weight.
+type TrafficRequest struct {
+	Type   TrafficRequestType
+	Weight int
+}
+
+// TrafficProfile is the mix of the requests of the traffic, and the keys
+// and the values they are sent for.
+type TrafficProfile struct {
+	Name     string
+	Requests []TrafficRequest
+	// Keys is the number of the keys requested.
+	Keys int
+	// ValueSize is the size of the values put.
+	ValueSize int
+	// Watchers is the number of the watches of the keys, their events being
+	// recorded.
+	Watchers int
+	// LeaseTTL is the TTL of the leases granted, in seconds.
+	LeaseTTL int64
+}
+
+var (
+	// KubernetesTraffic lists, creates, updates and deletes objects of a
+	// few KB, an informer watching them.
+	KubernetesTraffic = TrafficProfile{
+		Name: "kubernetes",
+		Requests: []TrafficRequest{
+			{Type: TrafficList, Weight: 5},
+			{Type: TrafficGet, Weight: 25},
+			{Type: TrafficCreate, Weight: 20},
+			{Type: TrafficUpdate, Weight: 40},
+			{Type: TrafficDelete, Weight: 10},
+		},
+		Keys:      100,
+		ValueSize: 2 * 1024,
+		Watchers:  1,
+	}
+	// LeaseChurnTraffic grants, keeps alive and revokes short leases the
+	// keys are attached to, and gets the keys.
+	LeaseChurnTraffic = TrafficProfile{
+		Name: "lease-churn",
+		Requests: []TrafficRequest{
+			{Type: TrafficPutWithLease, Weight: 40},
+			{Type: TrafficGet, Weight: 20},
+			{Type: TrafficLeaseKeepAlive, Weight: 15},
+			{Type: TrafficLeaseRevoke, Weight: 25},
+		},
+		Keys:      100,
+		ValueSize: 100,
+		LeaseTTL:  5,
+	}
+	// BigValuesTraffic puts and gets values of hundreds of KB, close to the
+	// default request size limit.
+	BigValuesTraffic = TrafficProfile{
+		Name: "big-values",
+		Requests: []TrafficRequest{
+			{Type: TrafficPut, Weight: 50},
+			{Type: TrafficGet, Weight: 50},
+		},
+		Keys:      10,
+		ValueSize: 512 * 1024,
+	}
+	// WatchHeavyTraffic puts a few keys watched by many watchers.
+	WatchHeavyTraffic = TrafficProfile{
+		Name: "watch-heavy",
+		Requests: []TrafficRequest{
+			{Type: TrafficPut, Weight: 90},
+			{Type: TrafficDelete, Weight: 10},
+		},
+		Keys:      10,
+		ValueSize: 100,
+		Watchers:  100,
+	}
+)
+
+// TrafficConfig configures the traffic sent to a cluster.
+type TrafficConfig struct {
+	Profile TrafficProfile
+	// QPS is the rate the requests are sent at, by all the clients. It
+	// defaults to 100.
+	QPS int
+	// Clients is the number of the clients sending the requests
+	// concurrently, each connected to a member in turn. It defaults to one
+	// per member.
+	Clients int
+	// KeyPrefix is the prefix of the keys requested. It defaults to
+	// "/traffic/".
+	KeyPrefix string
+	// RequestTimeout is the timeout of each request. It defaults to 5s.
+	RequestTimeout time.Duration
+}
+
+// TrafficReport accounts the requests sent by the traffic.
+type TrafficReport struct {
+	Profile  string
+	Duration time.Duration
+	// Successes and Failures are the requests succeeded and failed, by type.
+	// The updates and creates not applied for the key having been modified
+	// since succeeded.
+	Successes map[TrafficRequestType]int
+	Failures  map[TrafficRequestType]int
+	// Errors are the errors of the failed requests, by message.
+	Errors map[string]int
+	// Events is the number of the events received by the watchers.
+	Events int
+	// History is the history of the traffic, checked by CheckTraffic.
+	History TrafficHistory
+}
+
+// Requests returns the number of the requests sent.
+func (r TrafficReport) Requests() int {
+	return countRequests(r.Successes) + countRequests(r.Failures)
+}
+
+// FailureRate returns the ratio of the requests sent that failed.
+func (r TrafficReport) FailureRate() float64 {
+	if r.Requests() == 0 {
+		return 0
+	}
+	return float64(countRequests(r.Failures)) / float64(r.Requests())
+}
+
+func (r TrafficReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s traffic of %v: %d requests (%.1f qps), %d failed, %d watch events",
+		r.Profile, r.Duration.Round(time.Millisecond), r.Requests(), float64(r.Requests())/r.Duration.Seconds(), countRequests(r.Failures), r.Events)
+	msgs := make([]string, 0, len(r.Errors))
+	for msg := range r.Errors {
+		msgs = append(msgs, msg)
+	}
+	sort.Strings(msgs)
+	for _, msg := range msgs {
+		fmt.Fprintf(&b, "\n\t%d x %s", r.Errors[msg], msg)
+	}
+	return b.String()
+}
+
+func countRequests(m map[TrafficRequestType]int) (n int) {
+	for _, c := range m {
+		n += c
+	}
+	return n
+}
+
+// Traffic is sent to a cluster until stopped.
+type Traffic struct {
+	cfg     TrafficConfig
+	clients []*clientv3.Client
+	limiter *rate.Limiter
+	value   string
+	start   time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	report TrafficReport
+	leases []clientv3.LeaseID
+}
+
+// StartTraffic starts sending the traffic to the members of the cluster,
+// until stopped or the context done.
+func (epc *EtcdProcessCluster) StartTraffic(ctx context.Context, cfg TrafficConfig) (*Traffic, error) {
+	if len(cfg.Profile.Requests) == 0 || cfg.Profile.Keys <= 0 {
+		return nil, fmt.Errorf("traffic profile %q has no requests or keys", cfg.Profile.Name)
+	}
+	if cfg.QPS <= 0 {
+		cfg.QPS = 100
+	}
+	if cfg.Clients <= 0 {
+		cfg.Clients = len(epc.Procs)
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "/traffic/"
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = 5 * time.Second
+	}
+	tlsConfig, err := epc.clientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   epc.EndpointsV3(),
+		DialTimeout: 5 * time.Second,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cli.Get(ctx, "/")
+	cli.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &Traffic{
+		cfg:     cfg,
+		limiter: rate.NewLimiter(rate.Limit(cfg.QPS), 1),
+		value:   strings.Repeat("a", cfg.Profile.ValueSize),
+		report: TrafficReport{
+			Profile:   cfg.Profile.Name,
+			Successes: make(map[TrafficRequestType]int),
+			Failures:  make(map[TrafficRequestType]int),
+			Errors:    make(map[string]int),
+			History: TrafficHistory{
+				KeyPrefix: cfg.KeyPrefix,
+				Start:     resp.Header.Revision,
+				Watches:   make([]TrafficWatchHistory, cfg.Profile.Watchers),
+			},
+		},
+	}
+	for i := 0; i < cfg.Clients; i++ {
+		cli, cerr := clientv3.New(clientv3.Config{
+			Endpoints:   epc.Procs[i%len(epc.Procs)].EndpointsV3(),
+			DialTimeout: 5 * time.Second,
+			DialOptions: []grpc.DialOption{grpc.WithBlock()},
+			TLS:         tlsConfig,
+		})
+		if cerr != nil {
+			tr.closeClients()
+			return nil, cerr
+		}
+		tr.clients = append(tr.clients, cli)
+	}
+
+	ctx, tr.cancel = context.WithCancel(ctx)
+	tr.start = time.Now()
+	for i := 0; i < cfg.Profile.Watchers; i++ {
+		tr.wg.Add(1)
+		go tr.watch(ctx, tr.clients[i%len(tr.clients)], &tr.report.History.Watches[i])
+	}
+	for i, cli := range tr.clients {
+		tr.wg.Add(1)
+		go tr.send(ctx, cli, rand.New(rand.NewSource(time.Now().UnixNano()+int64(i))))
+	}
+	return tr, nil
+}
+
+// Stop stops the traffic, returning its report.
+func (tr *Traffic) Stop() TrafficReport {
+	tr.cancel()
+	tr.wg.Wait()
+	tr.closeClients()
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.report.Duration = time.Since(tr.start)
+	return tr.report
+}
+
+// CheckTraffic checks the watch and lease guarantees on the history of the
+// traffic stopped, see CheckTrafficHistory.
+func (epc *EtcdProcessCluster) CheckTraffic(ctx context.Context, report TrafficReport) error {
+	tlsConfig, err := epc.clientTLSConfig()
+	if err != nil {
+		return err
+	}
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   epc.EndpointsV3(),
+		DialTimeout: 5 * time.Second,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	return CheckTrafficHistory(ctx, cli, report.History)
+}
+
+func (tr *Traffic) closeClients() {
+	for _, cli := range tr.clients {
+		cli.Close()
+	}
+}
+
+// send sends the requests of the profile through the client, at the rate of
+// the traffic.
+func (tr *Traffic) send(ctx context.Context, cli *clientv3.Client, rnd *rand.Rand) {
+	defer tr.wg.Done()
+	total := 0
+	for _, req := range tr.cfg.Profile.Requests {
+		total += req.Weight
+	}
+	for {
+		if err := tr.limiter.Wait(ctx); err != nil {
+			return
+		}
+		typ := tr.cfg.Profile.Requests[len(tr.cfg.Profile.Requests)-1].Type
+		for n, i := rnd.Intn(total), 0; i < len(tr.cfg.Profile.Requests); i++ {
+			if n < tr.cfg.Profile.Requests[i].Weight {
+				typ = tr.cfg.Profile.Requests[i].Type
+				break
+			}
+			n -= tr.cfg.Profile.Requests[i].Weight
+		}
+		key := fmt.Sprintf("%s%d", tr.cfg.KeyPrefix, rnd.Intn(tr.cfg.Profile.Keys))
+
+		reqCtx, cancel := context.WithTimeout(ctx, tr.cfg.RequestTimeout)
+		err := tr.request(reqCtx, cli, rnd, typ, key)
+		cancel()
+		// the requests interrupted by the stop are not accounted
+		if ctx.Err() != nil {
+			return
+		}
+		tr.account(typ, err)
+	}
+}
+
+func (tr *Traffic) request(ctx context.Context, cli *clientv3.Client, rnd *rand.Rand, typ TrafficRequestType, key string) error {
+	switch typ {
+	case TrafficGet:
+		resp, err := cli.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		tr.recordReads(resp.Header.Revision, resp.Kvs)
+		return nil
+	case TrafficList:
+		opts := []clientv3.OpOption{clientv3.WithRange(clientv3.GetPrefixRangeEnd(tr.cfg.KeyPrefix)), clientv3.WithLimit(50)}
+		var rev int64
+		for from := tr.cfg.KeyPrefix; ; {
+			resp, err := cli.Get(ctx, from, opts...)
+			if err != nil {
+				return err
+			}
+			if rev == 0 {
+				rev = resp.Header.Revision
+			}
+			tr.recordReads(rev, resp.Kvs)
+			if !resp.More || len(resp.Kvs) == 0 {
+				return nil
+			}
+			from = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+			// the pages are read at the revision of the first one
+			opts = append(opts[:2], clientv3.WithRev(rev))
+		}
+	case TrafficPut:
+		resp, err := cli.Put(ctx, key, tr.value)
+		if err != nil {
+			return err
+		}
+		tr.recordWrite(TrafficWrite{Key: key, Type: mvccpb.PUT, Revision: resp.Header.Revision})
+		return nil
+	case TrafficDelete:
+		resp, err := cli.Delete(ctx, key)
+		if err != nil {
+			return err
+		}
+		if resp.Deleted != 0 {
+			tr.recordWrite(TrafficWrite{Key: key, Type: mvccpb.DELETE, Revision: resp.Header.Revision})
+		}
+		return nil
+	case TrafficCreate:
+		resp, err := cli.Txn(ctx).If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).Then(clientv3.OpPut(key, tr.value)).Commit()
+		if err != nil {
+			return err
+		}
+		if resp.Succeeded {
+			tr.recordWrite(TrafficWrite{Key: key, Type: mvccpb.PUT, Revision: resp.Header.Revision})
+		}
+		return nil
+	case TrafficUpdate:
+		resp, err := cli.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		var rev int64
+		if len(resp.Kvs) == 1 {
+			rev = resp.Kvs[0].ModRevision
+		}
+		tresp, err := cli.Txn(ctx).If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).Then(clientv3.OpPut(key, tr.value)).Commit()
+		if err != nil {
+			return err
+		}
+		if tresp.Succeeded {
+			tr.recordWrite(TrafficWrite{Key: key, Type: mvccpb.PUT, Revision: tresp.Header.Revision})
+		}
+		return nil
+	case TrafficPutWithLease:
+		lresp, err := cli.Grant(ctx, tr.cfg.Profile.LeaseTTL)
+		if err != nil {
+			return err
+		}
+		tr.mu.Lock()
+		if len(tr.leases) == maxTrafficLeases {
+			tr.leases = tr.leases[1:]
+		}
+		tr.leases = append(tr.leases, lresp.ID)
+		tr.mu.Unlock()
+		resp, err := cli.Put(ctx, key, tr.value, clientv3.WithLease(lresp.ID))
+		if err != nil {
+			return err
+		}
+		tr.recordWrite(TrafficWrite{Key: key, Type: mvccpb.PUT, Revision: resp.Header.Revision, Lease: lresp.ID})
+		return nil
+	case TrafficLeaseKeepAlive:
+		id, ok := tr.lease(rnd, false)
+		if !ok {
+			return nil
+		}
+		_, err := cli.KeepAliveOnce(ctx, id)
+		return ignoreLeaseNotFound(err)
+	case TrafficLeaseRevoke:
+		id, ok := tr.lease(rnd, true)
+		if !ok {
+			return nil
+		}
+		resp, err := cli.Revoke(ctx, id)
+		if err != nil {
+			return ignoreLeaseNotFound(err)
+		}
+		tr.mu.Lock()
+		tr.report.History.Revokes = append(tr.report.History.Revokes, TrafficRevoke{Lease: id, Revision: resp.Header.Revision})
+		tr.mu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("unknown traffic request %q", typ)
+}
+
+// lease returns one of the leases granted, removed from them if revoked.
+func (tr *Traffic) lease(rnd *rand.Rand, remove bool) (clientv3.LeaseID, bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.leases) == 0 {
+		return 0, false
+	}
+	i := rnd.Intn(len(tr.leases))
+	id := tr.leases[i]
+	if remove {
+		tr.leases = append(tr.leases[:i], tr.leases[i+1:]...)
+	}
+	return id, true
+}
+
+// ignoreLeaseNotFound ignores the error of a lease which expired, or was
+// revoked by another client.
+func ignoreLeaseNotFound(err error) error {
+	if errors.Is(err, rpctypes.ErrLeaseNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (tr *Traffic) recordWrite(w TrafficWrite) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.report.History.Writes = append(tr.report.History.Writes, w)
+}
+
+// recordReads records the key-value pairs attached to a lease read at the
+// revision.
+func (tr *Traffic) recordReads(rev int64, kvs []*mvccpb.KeyValue) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for _, kv := range kvs {
+		if kv.Lease != 0 {
+			tr.report.History.Reads = append(tr.report.History.Reads, TrafficRead{
+				Key:         string(kv.Key),
+				Revision:    rev,
+				ModRevision: kv.ModRevision,
+				Lease:       clientv3.LeaseID(kv.Lease),
+			})
+		}
+	}
+}
+
+func (tr *Traffic) account(typ TrafficRequestType, err error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if err == nil {
+		tr.report.Successes[typ]++
+		return
+	}
+	tr.report.Failures[typ]++
+	tr.report.Errors[err.Error()]++
+}
+
+// watch watches the keys of the traffic, recording the events received to
+// w. The watch failed is opened again from the revision following the last
+// one received, or the one it was created at, for no event to be lost.
+func (tr *Traffic) watch(ctx context.Context, cli *clientv3.Client, w *TrafficWatchHistory) {
+	defer tr.wg.Done()
+	var next int64
+	for ctx.Err() == nil {
+		opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithCreatedNotify()}
+		if next != 0 {
+			opts = append(opts, clientv3.WithRev(next))
+		}
+		for resp := range cli.Watch(clientv3.WithRequireLeader(ctx), tr.cfg.KeyPrefix, opts...) {
+			if err := resp.Err(); err != nil {
+				if ctx.Err() == nil {
+					tr.account(TrafficWatch, err)
+				}
+				break
+			}
+			tr.mu.Lock()
+			if resp.Created && next == 0 {
+				next = resp.Header.Revision + 1
+				w.Start = next
+			}
+			if len(resp.Events) > 0 {
+				next = resp.Events[len(resp.Events)-1].Kv.ModRevision + 1
+			}
+			w.Events = append(w.Events, resp.Events...)
+			tr.report.Events += len(resp.Events)
+			tr.mu.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
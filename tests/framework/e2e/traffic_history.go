@@ -0,0 +1,302 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// TrafficHistory is the history of the requests of a traffic succeeded, and
+// of the events its watchers received.
+type TrafficHistory struct {
+	KeyPrefix string
+	// Start is the revision of the cluster before the traffic started.
+	Start int64
+	// Writes are the writes succeeded, at the revision of their response.
+	Writes []TrafficWrite
+	// Reads are the key-value pairs attached to a lease read.
+	Reads []TrafficRead
+	// Revokes are the leases revoked, at the revision of their response.
+	Revokes []TrafficRevoke
+	// Watches are the events received by each watcher.
+	Watches []TrafficWatchHistory
+}
+
+// TrafficWrite is a write of a key, creating the event of the type at the
+// revision.
+type TrafficWrite struct {
+	Key      string
+	Type     mvccpb.Event_EventType
+	Revision int64
+	Lease    clientv3.LeaseID
+}
+
+// TrafficRead is a key-value pair attached to a lease, read at the revision.
+type TrafficRead struct {
+	Key         string
+	Revision    int64
+	ModRevision int64
+	Lease       clientv3.LeaseID
+}
+
+// TrafficRevoke is a lease revoked at the revision.
+type TrafficRevoke struct {
+	Lease    clientv3.LeaseID
+	Revision int64
+}
+
+// TrafficWatchHistory is the events received by a watcher, resuming its watch
+// after a failure.
+type TrafficWatchHistory struct {
+	// Start is the revision of the first event the watcher could receive,
+	// following the one its watch was created at.
+	Start  int64
+	Events []*clientv3.Event
+}
+
+// CheckTrafficHistory checks the watch and lease guarantees on the history of
+// a traffic stopped, against the events of its keys since it started and the
+// leases left, read with cli:
+//   - the watchers receive the events in the order of their revisions, without
+//     gaps nor duplicates, also when resuming their watches,
+//   - the events are the ones of the writes succeeded,
+//   - no key is readable, nor left, attached to a lease once revoked or expired.
+func CheckTrafficHistory(ctx context.Context, cli *clientv3.Client, h TrafficHistory) error {
+	// the leases are looked up before the keys, for the keys of the leases
+	// found revoked to be deleted already
+	var gone []clientv3.LeaseID
+	for _, w := range h.Writes {
+		if w.Lease == 0 {
+			continue
+		}
+		resp, err := cli.TimeToLive(ctx, w.Lease)
+		if err != nil {
+			return err
+		}
+		if resp.TTL == -1 {
+			gone = append(gone, w.Lease)
+		}
+	}
+	resp, err := cli.Get(ctx, h.KeyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	events, err := watchHistory(ctx, cli, h.KeyPrefix, h.Start+1, resp.Header.Revision)
+	if err != nil {
+		return err
+	}
+
+	errs := checkTrafficHistory(h, events, resp.Kvs, gone)
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Errorf("%d traffic history violations:\n\t%s", len(errs), strings.Join(msgs, "\n\t"))
+}
+
+// watchHistory returns the events of the keys of the prefix from the revision
+// start to end, with their previous key-value pairs.
+func watchHistory(ctx context.Context, cli *clientv3.Client, prefix string, start, end int64) ([]*clientv3.Event, error) {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	wch := cli.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(start), clientv3.WithPrevKV())
+	// the progress is only notified once the watcher caught up
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	var events []*clientv3.Event
+	for {
+		select {
+		case resp, ok := <-wch:
+			if !ok {
+				return nil, ctx.Err()
+			}
+			if err := resp.Err(); err != nil {
+				return nil, err
+			}
+			events = append(events, resp.Events...)
+			if resp.IsProgressNotify() && resp.Header.Revision >= end {
+				return events, nil
+			}
+		case <-ticker.C:
+			if err := cli.RequestProgress(ctx); err != nil {
+				return nil, err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// checkTrafficHistory checks the history against the events of the keys of
+// the traffic since it started, the key-value pairs left and the leases gone,
+// revoked or expired, after it stopped.
+func checkTrafficHistory(h TrafficHistory, events []*clientv3.Event, kvs []*mvccpb.KeyValue, gone []clientv3.LeaseID) (errs []error) {
+	errs = append(errs, checkEventOrder("history", events)...)
+	for i, w := range h.Watches {
+		name := fmt.Sprintf("watcher %d", i)
+		errs = append(errs, checkEventOrder(name, w.Events)...)
+		errs = append(errs, checkWatchEvents(name, w, events)...)
+	}
+	errs = append(errs, checkWrites(h.Writes, events)...)
+	errs = append(errs, checkLeases(h, events, kvs, gone)...)
+	return errs
+}
+
+type eventID struct {
+	key string
+	rev int64
+}
+
+func (id eventID) String() string {
+	return fmt.Sprintf("%q at revision %d", id.key, id.rev)
+}
+
+func newEventID(ev *clientv3.Event) eventID {
+	return eventID{key: string(ev.Kv.Key), rev: ev.Kv.ModRevision}
+}
+
+// checkEventOrder checks the events are in the order of their revisions,
+// without duplicates.
+func checkEventOrder(name string, events []*clientv3.Event) (errs []error) {
+	seen := make(map[eventID]bool)
+	var rev int64
+	for _, ev := range events {
+		id := newEventID(ev)
+		switch {
+		case seen[id]:
+			errs = append(errs, fmt.Errorf("%s: duplicate event of %v", name, id))
+		case id.rev < rev:
+			errs = append(errs, fmt.Errorf("%s: event of %v after revision %d", name, id, rev))
+		}
+		seen[id] = true
+		if id.rev > rev {
+			rev = id.rev
+		}
+	}
+	return errs
+}
+
+// checkWatchEvents checks the watcher received all the events from its start
+// to the last one received, the watcher having lost the events missing.
+func checkWatchEvents(name string, w TrafficWatchHistory, events []*clientv3.Event) (errs []error) {
+	if len(w.Events) == 0 {
+		return nil
+	}
+	last := w.Events[len(w.Events)-1].Kv.ModRevision
+	received := make(map[eventID]*clientv3.Event, len(w.Events))
+	for _, ev := range w.Events {
+		received[newEventID(ev)] = ev
+	}
+	for _, ev := range events {
+		id := newEventID(ev)
+		if id.rev < w.Start || id.rev > last {
+			continue
+		}
+		got, ok := received[id]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: lost event of %v", name, id))
+		case got.Type != ev.Type:
+			errs = append(errs, fmt.Errorf("%s: %s event of %v, want %s", name, got.Type, id, ev.Type))
+		}
+		delete(received, id)
+	}
+	for id := range received {
+		errs = append(errs, fmt.Errorf("%s: event of %v not in the history", name, id))
+	}
+	return errs
+}
+
+// checkWrites checks the writes succeeded created their events.
+func checkWrites(writes []TrafficWrite, events []*clientv3.Event) (errs []error) {
+	byID := make(map[eventID]*clientv3.Event, len(events))
+	for _, ev := range events {
+		byID[newEventID(ev)] = ev
+	}
+	for _, w := range writes {
+		id := eventID{key: w.Key, rev: w.Revision}
+		ev, ok := byID[id]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s of %v not in the history", w.Type, id))
+		case ev.Type != w.Type:
+			errs = append(errs, fmt.Errorf("%s of %v, the history having a %s", w.Type, id, ev.Type))
+		case clientv3.LeaseID(ev.Kv.Lease) != w.Lease:
+			errs = append(errs, fmt.Errorf("put of %v with lease %x, the history having lease %x", id, w.Lease, ev.Kv.Lease))
+		}
+	}
+	return errs
+}
+
+// checkLeases checks no key is read or put attached to a lease after the
+// revision it was revoked at, its keys being deleted once revoked or expired.
+func checkLeases(h TrafficHistory, events []*clientv3.Event, kvs []*mvccpb.KeyValue, gone []clientv3.LeaseID) (errs []error) {
+	// revoked is the revision each lease was revoked or expired at, its keys
+	// being deleted at this revision
+	revoked := make(map[clientv3.LeaseID]int64)
+	revoke := func(id clientv3.LeaseID, rev int64) {
+		if r, ok := revoked[id]; !ok || rev < r {
+			revoked[id] = rev
+		}
+	}
+	for _, r := range h.Revokes {
+		revoke(r.Lease, r.Revision)
+	}
+	for _, ev := range events {
+		// the traffic attaches a single key to a lease, deleted or put again
+		// without the lease once the lease is revoked
+		if ev.Type == mvccpb.DELETE && ev.PrevKv != nil && ev.PrevKv.Lease != 0 {
+			revoke(clientv3.LeaseID(ev.PrevKv.Lease), ev.Kv.ModRevision)
+		}
+	}
+
+	isGone := make(map[clientv3.LeaseID]bool, len(gone))
+	for _, id := range gone {
+		isGone[id] = true
+	}
+	for _, r := range h.Revokes {
+		if !isGone[r.Lease] {
+			errs = append(errs, fmt.Errorf("lease %x revoked at revision %d still alive", r.Lease, r.Revision))
+		}
+	}
+
+	for _, r := range h.Reads {
+		if rev, ok := revoked[r.Lease]; ok && rev <= r.Revision {
+			errs = append(errs, fmt.Errorf("key %q of lease %x revoked at revision %d read at revision %d", r.Key, r.Lease, rev, r.Revision))
+		}
+	}
+	for _, ev := range events {
+		id := clientv3.LeaseID(ev.Kv.Lease)
+		if rev, ok := revoked[id]; ok && ev.Type == mvccpb.PUT && ev.Kv.ModRevision > rev {
+			errs = append(errs, fmt.Errorf("key %q put with lease %x at revision %d after it was revoked at revision %d", ev.Kv.Key, id, ev.Kv.ModRevision, rev))
+		}
+	}
+	for _, kv := range kvs {
+		if id := clientv3.LeaseID(kv.Lease); isGone[id] {
+			errs = append(errs, fmt.Errorf("key %q left attached to lease %x revoked or expired", kv.Key, id))
+		}
+	}
+	return errs
+}
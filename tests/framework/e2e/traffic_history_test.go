@@ -0,0 +1,169 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"strings"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func putEvent(key string, rev int64, lease clientv3.LeaseID) *clientv3.Event {
+	return &clientv3.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key), ModRevision: rev, Lease: int64(lease)}}
+}
+
+func deleteEvent(key string, rev int64, prev *clientv3.Event) *clientv3.Event {
+	ev := &clientv3.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte(key), ModRevision: rev}}
+	if prev != nil {
+		ev.PrevKv = prev.Kv
+	}
+	return ev
+}
+
+func TestCheckTrafficHistory(t *testing.T) {
+	// a is put at 2, b with lease 1 at 3 and deleted at 4 when lease 1 is
+	// revoked, c with lease 2 at 5 and deleted at 6 when lease 2 expires
+	a2, b3, c5 := putEvent("a", 2, 0), putEvent("b", 3, 1), putEvent("c", 5, 2)
+	b4, c6 := deleteEvent("b", 4, b3), deleteEvent("c", 6, c5)
+	events := []*clientv3.Event{a2, b3, b4, c5, c6}
+	history := func() TrafficHistory {
+		return TrafficHistory{
+			Start: 1,
+			Writes: []TrafficWrite{
+				{Key: "a", Type: mvccpb.PUT, Revision: 2},
+				{Key: "b", Type: mvccpb.PUT, Revision: 3, Lease: 1},
+				{Key: "c", Type: mvccpb.PUT, Revision: 5, Lease: 2},
+			},
+			Reads: []TrafficRead{
+				{Key: "b", Revision: 3, ModRevision: 3, Lease: 1},
+				{Key: "c", Revision: 5, ModRevision: 5, Lease: 2},
+			},
+			Revokes: []TrafficRevoke{{Lease: 1, Revision: 4}},
+			Watches: []TrafficWatchHistory{
+				{Start: 2, Events: []*clientv3.Event{a2, b3, b4, c5, c6}},
+				// resumed its watch from revision 4
+				{Start: 3, Events: []*clientv3.Event{b3, b4, c5}},
+			},
+		}
+	}
+	gone := []clientv3.LeaseID{1, 2}
+
+	tests := []struct {
+		name   string
+		modify func(h *TrafficHistory)
+		kvs    []*mvccpb.KeyValue
+		gone   []clientv3.LeaseID
+		// werr is the violation reported, none if empty.
+		werr string
+	}{
+		{
+			name:   "valid",
+			modify: func(h *TrafficHistory) {},
+			gone:   gone,
+		},
+		{
+			name:   "event out of order",
+			modify: func(h *TrafficHistory) { h.Watches[0].Events = []*clientv3.Event{a2, b4, b3, c5, c6} },
+			gone:   gone,
+			werr:   `watcher 0: event of "b" at revision 3 after revision 4`,
+		},
+		{
+			name:   "duplicate event",
+			modify: func(h *TrafficHistory) { h.Watches[1].Events = []*clientv3.Event{b3, b4, b4, c5} },
+			gone:   gone,
+			werr:   `watcher 1: duplicate event of "b" at revision 4`,
+		},
+		{
+			name:   "lost event",
+			modify: func(h *TrafficHistory) { h.Watches[1].Events = []*clientv3.Event{b3, c5} },
+			gone:   gone,
+			werr:   `watcher 1: lost event of "b" at revision 4`,
+		},
+		{
+			name:   "event not in the history",
+			modify: func(h *TrafficHistory) { h.Watches[1].Events = []*clientv3.Event{b3, b4, putEvent("d", 5, 0), c5} },
+			gone:   gone,
+			werr:   `watcher 1: event of "d" at revision 5 not in the history`,
+		},
+		{
+			name: "write not in the history",
+			modify: func(h *TrafficHistory) {
+				h.Writes = append(h.Writes, TrafficWrite{Key: "a", Type: mvccpb.DELETE, Revision: 7})
+			},
+			gone: gone,
+			werr: `DELETE of "a" at revision 7 not in the history`,
+		},
+		{
+			name: "read after revoke",
+			modify: func(h *TrafficHistory) {
+				h.Reads = append(h.Reads, TrafficRead{Key: "b", Revision: 4, ModRevision: 3, Lease: 1})
+			},
+			gone: gone,
+			werr: `key "b" of lease 1 revoked at revision 4 read at revision 4`,
+		},
+		{
+			name: "read after expiry",
+			modify: func(h *TrafficHistory) {
+				h.Reads = append(h.Reads, TrafficRead{Key: "c", Revision: 7, ModRevision: 5, Lease: 2})
+			},
+			gone: gone,
+			werr: `key "c" of lease 2 revoked at revision 6 read at revision 7`,
+		},
+		{
+			name:   "lease alive after revoke",
+			modify: func(h *TrafficHistory) {},
+			gone:   []clientv3.LeaseID{2},
+			werr:   "lease 1 revoked at revision 4 still alive",
+		},
+		{
+			name:   "key left attached to an expired lease",
+			modify: func(h *TrafficHistory) {},
+			kvs:    []*mvccpb.KeyValue{{Key: []byte("e"), ModRevision: 7, Lease: 3}},
+			gone:   []clientv3.LeaseID{1, 2, 3},
+			werr:   `key "e" left attached to lease 3 revoked or expired`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := history()
+			tt.modify(&h)
+			errs := checkTrafficHistory(h, events, tt.kvs, tt.gone)
+			if tt.werr == "" {
+				if len(errs) != 0 {
+					t.Fatalf("violations %v, want none", errs)
+				}
+				return
+			}
+			for _, err := range errs {
+				if strings.Contains(err.Error(), tt.werr) {
+					return
+				}
+			}
+			t.Fatalf("violations %v, want %q", errs, tt.werr)
+		})
+	}
+}
+
+func TestCheckTrafficHistoryPutAfterRevoke(t *testing.T) {
+	b3 := putEvent("b", 3, 1)
+	events := []*clientv3.Event{b3, deleteEvent("b", 4, b3), putEvent("c", 5, 1)}
+	h := TrafficHistory{Start: 2, Revokes: []TrafficRevoke{{Lease: 1, Revision: 4}}}
+	errs := checkTrafficHistory(h, events, nil, []clientv3.LeaseID{1})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `key "c" put with lease 1 at revision 5 after it was revoked at revision 4`) {
+		t.Fatalf("violations %v, want the put after the revoke", errs)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net"
 	"os"
 	"strings"
 	"testing"
@@ -115,6 +116,16 @@ func SkipInShortMode(t testing.TB) {
 	testutil.SkipTestIfShortMode(t, "e2e tests are not running in --short mode")
 }
 
+// SkipIfNoIPv6 skips the test if the host cannot listen on the ::1 IPv6
+// loopback.
+func SkipIfNoIPv6(t testing.TB) {
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	l.Close()
+}
+
 func mergeEnvVariables(envVars map[string]string) []string {
 	var env []string
 	// Environment variables are passed as parameter have higher priority
@@ -0,0 +1,165 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package e2e
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"go.etcd.io/etcd/client/pkg/v3/fileutil"
+)
+
+// BinaryVersion is the version of the etcd binary of a member, the current
+// build, the last release or a release tag, e.g. "v3.5.4".
+type BinaryVersion string
+
+const (
+	// CurrentBinary is the etcd binary of the bin dir.
+	CurrentBinary BinaryVersion = ""
+	// LastReleaseBinary is the binary of the last release, see
+	// LastReleaseVersion.
+	LastReleaseBinary BinaryVersion = "last-release"
+)
+
+// ClusterVersion is the versions of the binaries of the members of a cluster.
+type ClusterVersion string
+
+const (
+	// CurrentVersion runs the current binary on all the members.
+	CurrentVersion ClusterVersion = ""
+	// MinorityLastVersion runs the last release on a minority of the members,
+	// the first ones, and the current binary on the others.
+	MinorityLastVersion ClusterVersion = "minority-last-version"
+	// QuorumLastVersion runs the last release on a quorum of the members, the
+	// first ones, and the current binary on the others.
+	QuorumLastVersion ClusterVersion = "quorum-last-version"
+	// LastVersion runs the last release on all the members.
+	LastVersion ClusterVersion = "last-version"
+)
+
+// memberVersion returns the version of the binary of the i-th member of a
+// cluster of the given size.
+func (v ClusterVersion) memberVersion(i, size int) BinaryVersion {
+	var last int
+	switch v {
+	case MinorityLastVersion:
+		last = (size - 1) / 2
+	case QuorumLastVersion:
+		last = size/2 + 1
+	case LastVersion:
+		last = size
+	}
+	if i < last {
+		return LastReleaseBinary
+	}
+	return CurrentBinary
+}
+
+// downloadMu serializes the downloads of the release binaries of a test binary.
+var downloadMu sync.Mutex
+
+// BinaryPath returns the path to the etcd binary of the version. The binary
+// of the last release is the etcd-last-release of the bin dir, if any. The
+// other binaries of a release are the official ones, downloaded once to the
+// release cache dir. The test is skipped if the binary cannot be downloaded,
+// e.g. without network access.
+func BinaryPath(tb testing.TB, v BinaryVersion) string {
+	switch v {
+	case CurrentBinary:
+		return BinPath
+	case LastReleaseBinary:
+		if p := BinDir + "/etcd-last-release"; fileutil.Exist(p) {
+			return p
+		}
+		v = BinaryVersion(LastReleaseVersion)
+	}
+
+	downloadMu.Lock()
+	defer downloadMu.Unlock()
+	p := filepath.Join(ReleaseCacheDir, string(v), "etcd")
+	if fileutil.Exist(p) {
+		return p
+	}
+	if err := downloadRelease(string(v), p); err != nil {
+		tb.Skipf("etcd %s binary not available: %v", v, err)
+	}
+	return p
+}
+
+// downloadRelease downloads the etcd binary of the official release of the
+// version to the path.
+func downloadRelease(version, p string) error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("downloading releases for %s is not supported", runtime.GOOS)
+	}
+	name := fmt.Sprintf("etcd-%s-%s-%s", version, runtime.GOOS, runtime.GOARCH)
+	resp, err := http.Get(fmt.Sprintf("https://github.com/etcd-io/etcd/releases/download/%s/%s.tar.gz", version, name))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", name, resp.Status)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("etcd binary not found in %s", name)
+		}
+		if err != nil {
+			return err
+		}
+		if h.Name != path.Join(name, "etcd") {
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(p), fileutil.PrivateDirMode); err != nil {
+			return err
+		}
+		// written to a temporary file first, for the binary not to be found
+		// partially written by another test binary
+		f, err := os.CreateTemp(filepath.Dir(p), "etcd-*.tmp")
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Chmod(f.Name(), 0755)
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), p)
+		}
+		if err != nil {
+			os.Remove(f.Name())
+		}
+		return err
+	}
+}
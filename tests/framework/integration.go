@@ -43,6 +43,9 @@ func (e integrationRunner) BeforeTest(t testing.TB) {
 }
 
 func (e integrationRunner) NewCluster(ctx context.Context, t testing.TB, cfg config.ClusterConfig) Cluster {
+	if cfg.ClientProxy != config.NoProxy {
+		t.Skipf("ClientProxy config %q not supported", cfg.ClientProxy)
+	}
 	var err error
 	integrationCfg := integration.ClusterConfig{
 		Size:                       cfg.ClusterSize,
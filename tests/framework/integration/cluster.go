@@ -35,6 +35,7 @@ import (
 	"time"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/logutil"
 	"go.etcd.io/etcd/client/pkg/v3/testutil"
 	"go.etcd.io/etcd/client/pkg/v3/tlsutil"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
@@ -145,6 +146,7 @@ type ClusterConfig struct {
 
 	MaxTxnOps              uint
 	MaxRequestBytes        uint
+	MaxDeleteBatchSize     int
 	SnapshotCount          uint64
 	SnapshotCatchUpEntries uint64
 
@@ -152,6 +154,12 @@ type ClusterConfig struct {
 	GRPCKeepAliveInterval time.Duration
 	GRPCKeepAliveTimeout  time.Duration
 
+	GRPCResponseCompression string
+
+	CompactionBarrierMaxDuration time.Duration
+	HistoryRetention             []string
+	PrefixStats                  []string
+
 	ClientMaxCallSendMsgSize int
 	ClientMaxCallRecvMsgSize int
 
@@ -168,9 +176,14 @@ type ClusterConfig struct {
 	LeaseCheckpointPersist  bool
 
 	WatchProgressNotifyInterval time.Duration
+	WatchMaxStreamAge           time.Duration
 	ExperimentalMaxLearners     int
 	DisableStrictReconfigCheck  bool
 	CorruptCheckTime            time.Duration
+
+	// AutoPromoteLearnersSyncedDuration enables the promotion of the learners
+	// in sync for the duration.
+	AutoPromoteLearnersSyncedDuration time.Duration
 }
 
 type Cluster struct {
@@ -260,31 +273,39 @@ func (c *Cluster) mustNewMember(t testutil.TB) *Member {
 	c.LastMemberNum++
 	m := MustNewMember(t,
 		MemberConfig{
-			Name:                        fmt.Sprintf("m%v", memberNumber),
-			MemberNumber:                memberNumber,
-			AuthToken:                   c.Cfg.AuthToken,
-			PeerTLS:                     c.Cfg.PeerTLS,
-			ClientTLS:                   c.Cfg.ClientTLS,
-			QuotaBackendBytes:           c.Cfg.QuotaBackendBytes,
-			MaxTxnOps:                   c.Cfg.MaxTxnOps,
-			MaxRequestBytes:             c.Cfg.MaxRequestBytes,
-			SnapshotCount:               c.Cfg.SnapshotCount,
-			SnapshotCatchUpEntries:      c.Cfg.SnapshotCatchUpEntries,
-			GrpcKeepAliveMinTime:        c.Cfg.GRPCKeepAliveMinTime,
-			GrpcKeepAliveInterval:       c.Cfg.GRPCKeepAliveInterval,
-			GrpcKeepAliveTimeout:        c.Cfg.GRPCKeepAliveTimeout,
-			ClientMaxCallSendMsgSize:    c.Cfg.ClientMaxCallSendMsgSize,
-			ClientMaxCallRecvMsgSize:    c.Cfg.ClientMaxCallRecvMsgSize,
-			UseIP:                       c.Cfg.UseIP,
-			UseBridge:                   c.Cfg.UseBridge,
-			UseTCP:                      c.Cfg.UseTCP,
-			EnableLeaseCheckpoint:       c.Cfg.EnableLeaseCheckpoint,
-			LeaseCheckpointInterval:     c.Cfg.LeaseCheckpointInterval,
-			LeaseCheckpointPersist:      c.Cfg.LeaseCheckpointPersist,
-			WatchProgressNotifyInterval: c.Cfg.WatchProgressNotifyInterval,
-			ExperimentalMaxLearners:     c.Cfg.ExperimentalMaxLearners,
-			DisableStrictReconfigCheck:  c.Cfg.DisableStrictReconfigCheck,
-			CorruptCheckTime:            c.Cfg.CorruptCheckTime,
+			Name:                         fmt.Sprintf("m%v", memberNumber),
+			MemberNumber:                 memberNumber,
+			AuthToken:                    c.Cfg.AuthToken,
+			PeerTLS:                      c.Cfg.PeerTLS,
+			ClientTLS:                    c.Cfg.ClientTLS,
+			QuotaBackendBytes:            c.Cfg.QuotaBackendBytes,
+			MaxTxnOps:                    c.Cfg.MaxTxnOps,
+			MaxRequestBytes:              c.Cfg.MaxRequestBytes,
+			MaxDeleteBatchSize:           c.Cfg.MaxDeleteBatchSize,
+			SnapshotCount:                c.Cfg.SnapshotCount,
+			SnapshotCatchUpEntries:       c.Cfg.SnapshotCatchUpEntries,
+			GrpcKeepAliveMinTime:         c.Cfg.GRPCKeepAliveMinTime,
+			GrpcKeepAliveInterval:        c.Cfg.GRPCKeepAliveInterval,
+			GrpcKeepAliveTimeout:         c.Cfg.GRPCKeepAliveTimeout,
+			GRPCResponseCompression:      c.Cfg.GRPCResponseCompression,
+			CompactionBarrierMaxDuration: c.Cfg.CompactionBarrierMaxDuration,
+			HistoryRetention:             c.Cfg.HistoryRetention,
+			PrefixStats:                  c.Cfg.PrefixStats,
+			ClientMaxCallSendMsgSize:     c.Cfg.ClientMaxCallSendMsgSize,
+			ClientMaxCallRecvMsgSize:     c.Cfg.ClientMaxCallRecvMsgSize,
+			UseIP:                        c.Cfg.UseIP,
+			UseBridge:                    c.Cfg.UseBridge,
+			UseTCP:                       c.Cfg.UseTCP,
+			EnableLeaseCheckpoint:        c.Cfg.EnableLeaseCheckpoint,
+			LeaseCheckpointInterval:      c.Cfg.LeaseCheckpointInterval,
+			LeaseCheckpointPersist:       c.Cfg.LeaseCheckpointPersist,
+			WatchProgressNotifyInterval:  c.Cfg.WatchProgressNotifyInterval,
+			WatchMaxStreamAge:            c.Cfg.WatchMaxStreamAge,
+			ExperimentalMaxLearners:      c.Cfg.ExperimentalMaxLearners,
+			DisableStrictReconfigCheck:   c.Cfg.DisableStrictReconfigCheck,
+			CorruptCheckTime:             c.Cfg.CorruptCheckTime,
+
+			AutoPromoteLearnersSyncedDuration: c.Cfg.AutoPromoteLearnersSyncedDuration,
 		})
 	m.DiscoveryURL = c.Cfg.DiscoveryURL
 	return m
@@ -580,32 +601,40 @@ type Member struct {
 func (m *Member) GRPCURL() string { return m.GrpcURL }
 
 type MemberConfig struct {
-	Name                        string
-	UniqNumber                  int64
-	MemberNumber                int
-	PeerTLS                     *transport.TLSInfo
-	ClientTLS                   *transport.TLSInfo
-	AuthToken                   string
-	QuotaBackendBytes           int64
-	MaxTxnOps                   uint
-	MaxRequestBytes             uint
-	SnapshotCount               uint64
-	SnapshotCatchUpEntries      uint64
-	GrpcKeepAliveMinTime        time.Duration
-	GrpcKeepAliveInterval       time.Duration
-	GrpcKeepAliveTimeout        time.Duration
-	ClientMaxCallSendMsgSize    int
-	ClientMaxCallRecvMsgSize    int
-	UseIP                       bool
-	UseBridge                   bool
-	UseTCP                      bool
-	EnableLeaseCheckpoint       bool
-	LeaseCheckpointInterval     time.Duration
-	LeaseCheckpointPersist      bool
-	WatchProgressNotifyInterval time.Duration
-	ExperimentalMaxLearners     int
-	DisableStrictReconfigCheck  bool
-	CorruptCheckTime            time.Duration
+	Name                         string
+	UniqNumber                   int64
+	MemberNumber                 int
+	PeerTLS                      *transport.TLSInfo
+	ClientTLS                    *transport.TLSInfo
+	AuthToken                    string
+	QuotaBackendBytes            int64
+	MaxTxnOps                    uint
+	MaxRequestBytes              uint
+	MaxDeleteBatchSize           int
+	SnapshotCount                uint64
+	SnapshotCatchUpEntries       uint64
+	GrpcKeepAliveMinTime         time.Duration
+	GrpcKeepAliveInterval        time.Duration
+	GrpcKeepAliveTimeout         time.Duration
+	GRPCResponseCompression      string
+	CompactionBarrierMaxDuration time.Duration
+	HistoryRetention             []string
+	PrefixStats                  []string
+	ClientMaxCallSendMsgSize     int
+	ClientMaxCallRecvMsgSize     int
+	UseIP                        bool
+	UseBridge                    bool
+	UseTCP                       bool
+	EnableLeaseCheckpoint        bool
+	LeaseCheckpointInterval      time.Duration
+	LeaseCheckpointPersist       bool
+	WatchProgressNotifyInterval  time.Duration
+	WatchMaxStreamAge            time.Duration
+	ExperimentalMaxLearners      int
+	DisableStrictReconfigCheck   bool
+	CorruptCheckTime             time.Duration
+
+	AutoPromoteLearnersSyncedDuration time.Duration
 }
 
 // MustNewMember return an inited member with the given name. If peerTLS is
@@ -655,6 +684,7 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 	}
 	m.ElectionTicks = ElectionTicks
 	m.InitialElectionTickAdvance = true
+	m.CheckQuorum = true
 	m.TickMs = uint(framecfg.TickDuration / time.Millisecond)
 	m.QuotaBackendBytes = mcfg.QuotaBackendBytes
 	m.MaxTxnOps = mcfg.MaxTxnOps
@@ -665,6 +695,14 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 	if m.MaxRequestBytes == 0 {
 		m.MaxRequestBytes = embed.DefaultMaxRequestBytes
 	}
+	m.ExperimentalMaxDeleteBatchSize = mcfg.MaxDeleteBatchSize
+	if m.ExperimentalMaxDeleteBatchSize == 0 {
+		m.ExperimentalMaxDeleteBatchSize = embed.DefaultMaxDeleteBatchSize
+	}
+	m.ExperimentalGRPCResponseCompression = mcfg.GRPCResponseCompression
+	m.CompactionBarrierMaxDuration = mcfg.CompactionBarrierMaxDuration
+	m.HistoryRetention = mcfg.HistoryRetention
+	m.PrefixStats = mcfg.PrefixStats
 	m.SnapshotCount = etcdserver.DefaultSnapshotCount
 	if mcfg.SnapshotCount != 0 {
 		m.SnapshotCount = mcfg.SnapshotCount
@@ -706,6 +744,7 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 	m.LeaseCheckpointPersist = mcfg.LeaseCheckpointPersist
 
 	m.WatchProgressNotifyInterval = mcfg.WatchProgressNotifyInterval
+	m.WatchMaxStreamAge = mcfg.WatchMaxStreamAge
 
 	m.InitialCorruptCheck = true
 	if mcfg.CorruptCheckTime > time.Duration(0) {
@@ -719,8 +758,13 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 	}
 	m.V2Deprecation = config.V2_DEPR_DEFAULT
 	m.GrpcServerRecorder = &grpc_testing.GrpcRecorder{}
-	m.Logger = memberLogger(t, mcfg.Name)
+	m.Logger, m.LogLevels = memberLogger(t, mcfg.Name)
 	m.StrictReconfigCheck = !mcfg.DisableStrictReconfigCheck
+	if mcfg.AutoPromoteLearnersSyncedDuration != 0 {
+		m.AutoPromoteLearners = true
+		m.AutoPromoteLearnersMaxLag = embed.DefaultAutoPromoteLearnersMaxLag
+		m.AutoPromoteLearnersSyncedDuration = mcfg.AutoPromoteLearnersSyncedDuration
+	}
 	if err := m.listenGRPC(); err != nil {
 		t.Fatalf("listenGRPC FAILED: %v", err)
 	}
@@ -732,14 +776,17 @@ func MustNewMember(t testutil.TB, mcfg MemberConfig) *Member {
 	return m
 }
 
-func memberLogger(t testutil.TB, name string) *zap.Logger {
+// memberLogger returns the logger of the member, with its level adjustable at
+// runtime like the loggers built by embed.
+func memberLogger(t testutil.TB, name string) (*zap.Logger, *logutil.Levels) {
 	level := zapcore.InfoLevel
 	if os.Getenv("CLUSTER_DEBUG") != "" {
 		level = zapcore.DebugLevel
 	}
 
-	options := zaptest.WrapOptions(zap.Fields(zap.String("member", name)))
-	return zaptest.NewLogger(t, zaptest.Level(level), options).Named(name)
+	levels := logutil.NewLevels(level)
+	options := zaptest.WrapOptions(zap.Fields(zap.String("member", name)), zap.WrapCore(levels.WrapCore))
+	return zaptest.NewLogger(t, zaptest.Level(zapcore.DebugLevel), options).Named(name), levels
 }
 
 // listenGRPC starts a grpc server over a unix domain socket on the member
@@ -901,7 +948,7 @@ func (m *Member) Clone(t testutil.TB) *Member {
 	mm.ElectionTicks = m.ElectionTicks
 	mm.PeerTLSInfo = m.PeerTLSInfo
 	mm.ClientTLSInfo = m.ClientTLSInfo
-	mm.Logger = memberLogger(t, mm.Name+"c")
+	mm.Logger, mm.LogLevels = memberLogger(t, mm.Name+"c")
 	return mm
 }
 
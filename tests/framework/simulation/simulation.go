@@ -0,0 +1,329 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package simulation runs in-process raft members deterministically from a
+// seed, their clock, network and storage being simulated, to explore the
+// rare interleavings of the raft and apply loops reproducibly.
+//
+// All the events, ticks, elections, message deliveries, drops and delays,
+// proposals, crashes and restarts, are chosen by a random generator of the
+// seed, on a single goroutine. A failing seed therefore replays the same
+// history. The election timeouts of raft being randomized outside of the
+// seed, the members only campaign when the simulation decides so.
+package simulation
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+
+	"go.etcd.io/etcd/raft/v3"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+// electionTick is the election timeout of the members, never reached for
+// their elections to be triggered by the simulation.
+const electionTick = 1 << 20
+
+// Config configures a simulation.
+type Config struct {
+	// Seed seeds all the events of the simulation.
+	Seed int64
+	// Members is the number of members, 3 by default.
+	Members int
+	// DropRate is the ratio of the messages dropped by the network.
+	DropRate float64
+	// MaxDelay is the maximum number of steps a message is delayed by.
+	MaxDelay int
+	// CrashRate is the probability of a member to crash at each step, losing
+	// its unpersisted state until restarted up to MaxDowntime steps later.
+	CrashRate float64
+	// MaxDowntime is the maximum number of steps a member crashes for, 100
+	// by default.
+	MaxDowntime int
+	// Logger logs the raft events, discarded by default.
+	Logger raft.Logger
+}
+
+// Simulation is a simulation of in-process raft members.
+type Simulation struct {
+	cfg  Config
+	rand *rand.Rand
+	step int
+
+	members  []*member
+	inflight []inflight
+
+	// leaders are the leaders by term, to check at most one is elected.
+	leaders map[uint64]uint64
+	// applied are the entries applied by index, to check the members apply
+	// the same ones.
+	applied map[uint64]raftpb.Entry
+	// trace fingerprints the history of the simulation.
+	trace hash.Hash64
+}
+
+// member is a simulated member, its storage and state machine surviving
+// its crashes.
+type member struct {
+	id      uint64
+	node    *raft.RawNode
+	storage *raft.MemoryStorage
+	crashed bool
+	// restartAt is the step the member restarts at, if crashed.
+	restartAt int
+
+	// appliedIndex is the index of the last entry applied to kv.
+	appliedIndex uint64
+	kv           map[string]string
+}
+
+// inflight is a message in the network, delivered at the step.
+type inflight struct {
+	m  raftpb.Message
+	at int
+}
+
+// New returns a simulation of the config.
+func New(cfg Config) (*Simulation, error) {
+	if cfg.Members == 0 {
+		cfg.Members = 3
+	}
+	if cfg.MaxDowntime == 0 {
+		cfg.MaxDowntime = 100
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = &raft.DefaultLogger{Logger: log.New(io.Discard, "", 0)}
+	}
+	s := &Simulation{
+		cfg:     cfg,
+		rand:    rand.New(rand.NewSource(cfg.Seed)),
+		leaders: make(map[uint64]uint64),
+		applied: make(map[uint64]raftpb.Entry),
+		trace:   fnv.New64a(),
+	}
+	cs := raftpb.ConfState{}
+	for i := 1; i <= cfg.Members; i++ {
+		cs.Voters = append(cs.Voters, uint64(i))
+	}
+	for _, id := range cs.Voters {
+		st := raft.NewMemoryStorage()
+		if err := st.ApplySnapshot(raftpb.Snapshot{Metadata: raftpb.SnapshotMetadata{Index: 1, Term: 1, ConfState: cs}}); err != nil {
+			return nil, err
+		}
+		m := &member{id: id, storage: st, appliedIndex: 1, kv: make(map[string]string)}
+		if err := s.start(m); err != nil {
+			return nil, err
+		}
+		s.members = append(s.members, m)
+	}
+	return s, nil
+}
+
+// start starts the raft node of the member from its storage.
+func (s *Simulation) start(m *member) error {
+	rn, err := raft.NewRawNode(&raft.Config{
+		ID:              m.id,
+		ElectionTick:    electionTick,
+		HeartbeatTick:   1,
+		Storage:         m.storage,
+		Applied:         m.appliedIndex,
+		MaxSizePerMsg:   math.MaxUint64,
+		MaxInflightMsgs: 256,
+		PreVote:         true,
+		Logger:          s.cfg.Logger,
+	})
+	if err != nil {
+		return err
+	}
+	m.node = rn
+	m.crashed = false
+	return nil
+}
+
+// Run runs the simulation for the number of steps, returning the first
+// violation of the raft guarantees.
+func (s *Simulation) Run(steps int) error {
+	for i := 0; i < steps; i++ {
+		if err := s.Step(); err != nil {
+			return fmt.Errorf("seed %d, step %d: %w", s.cfg.Seed, s.step, err)
+		}
+	}
+	return nil
+}
+
+// Step runs a random event of the simulation.
+func (s *Simulation) Step() error {
+	s.step++
+	for _, m := range s.members {
+		if m.crashed && m.restartAt <= s.step {
+			s.record("restart", m.id, nil)
+			if err := s.start(m); err != nil {
+				return err
+			}
+		}
+	}
+	m := s.members[s.rand.Intn(len(s.members))]
+	if m.crashed {
+		return nil
+	}
+	if s.cfg.CrashRate > 0 && s.rand.Float64() < s.cfg.CrashRate {
+		s.record("crash", m.id, nil)
+		m.crashed = true
+		m.restartAt = s.step + 1 + s.rand.Intn(s.cfg.MaxDowntime)
+		return nil
+	}
+
+	switch n := s.rand.Intn(100); {
+	case n < 40:
+		s.deliver()
+	case n < 70:
+		m.node.Tick()
+	case n < 90:
+		data := []byte(fmt.Sprintf("key%d=%d", s.rand.Intn(8), s.step))
+		s.record("propose", m.id, data)
+		// dropped unless a leader is known, as by the raft loop of etcd
+		_ = m.node.Propose(data)
+	case n < 92:
+		s.record("campaign", m.id, nil)
+		if err := m.node.Campaign(); err != nil {
+			return err
+		}
+	}
+	return s.processReady(m)
+}
+
+// deliver delivers a random message due, dropped if its member crashed.
+func (s *Simulation) deliver() {
+	var due []int
+	for i, in := range s.inflight {
+		if in.at <= s.step {
+			due = append(due, i)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	i := due[s.rand.Intn(len(due))]
+	in := s.inflight[i]
+	s.inflight = append(s.inflight[:i], s.inflight[i+1:]...)
+
+	to := s.members[in.m.To-1]
+	if to.crashed {
+		return
+	}
+	s.record("deliver", in.m.To, []byte(in.m.Type.String()))
+	// the errors are of messages of stale or unknown terms, ignored by etcd
+	_ = to.node.Step(in.m)
+}
+
+// processReady persists, sends and applies the ready of the member, in the
+// order of the raft loop of etcd.
+func (s *Simulation) processReady(m *member) error {
+	if !m.node.HasReady() {
+		return nil
+	}
+	rd := m.node.Ready()
+	if rd.SoftState != nil && rd.SoftState.RaftState == raft.StateLeader {
+		term := m.node.BasicStatus().Term
+		if lead, ok := s.leaders[term]; ok && lead != m.id {
+			return fmt.Errorf("members %d and %d elected leaders of term %d", lead, m.id, term)
+		}
+		s.leaders[term] = m.id
+	}
+
+	if !raft.IsEmptyHardState(rd.HardState) {
+		if err := m.storage.SetHardState(rd.HardState); err != nil {
+			return err
+		}
+	}
+	if err := m.storage.Append(rd.Entries); err != nil {
+		return err
+	}
+	for _, msg := range rd.Messages {
+		if s.cfg.DropRate > 0 && s.rand.Float64() < s.cfg.DropRate {
+			continue
+		}
+		at := s.step
+		if s.cfg.MaxDelay > 0 {
+			at += s.rand.Intn(s.cfg.MaxDelay + 1)
+		}
+		s.inflight = append(s.inflight, inflight{m: msg, at: at})
+	}
+	for _, e := range rd.CommittedEntries {
+		if err := s.apply(m, e); err != nil {
+			return err
+		}
+	}
+	m.node.Advance(rd)
+	return nil
+}
+
+// apply applies the committed entry to the state machine of the member,
+// checking it is the one applied by the others at its index.
+func (s *Simulation) apply(m *member, e raftpb.Entry) error {
+	if e.Index <= m.appliedIndex {
+		return nil
+	}
+	if e.Index != m.appliedIndex+1 {
+		return fmt.Errorf("member %d applied index %d after %d", m.id, e.Index, m.appliedIndex)
+	}
+	if prev, ok := s.applied[e.Index]; ok {
+		if prev.Term != e.Term || !bytes.Equal(prev.Data, e.Data) {
+			return fmt.Errorf("member %d applied entry %d of term %d, %q, others of term %d, %q", m.id, e.Index, e.Term, e.Data, prev.Term, prev.Data)
+		}
+	} else {
+		s.applied[e.Index] = e
+	}
+	if e.Type == raftpb.EntryNormal && len(e.Data) > 0 {
+		kv := bytes.SplitN(e.Data, []byte("="), 2)
+		if len(kv) == 2 {
+			m.kv[string(kv[0])] = string(kv[1])
+		}
+	}
+	m.appliedIndex = e.Index
+	s.record("apply", m.id, e.Data)
+	return nil
+}
+
+// record adds the event to the trace.
+func (s *Simulation) record(event string, id uint64, data []byte) {
+	fmt.Fprintf(s.trace, "%d %s %d %q\n", s.step, event, id, data)
+}
+
+// Fingerprint returns the fingerprint of the history of the simulation, the
+// same for the same seed and config.
+func (s *Simulation) Fingerprint() uint64 {
+	return s.trace.Sum64()
+}
+
+// AppliedIndex returns the highest index of the entries applied by the
+// members.
+func (s *Simulation) AppliedIndex() uint64 {
+	return uint64(len(s.applied)) + 1
+}
+
+// KV returns the state machine of the member of the id.
+func (s *Simulation) KV(id uint64) map[string]string {
+	kv := make(map[string]string, len(s.members[id-1].kv))
+	for k, v := range s.members[id-1].kv {
+		kv[k] = v
+	}
+	return kv
+}
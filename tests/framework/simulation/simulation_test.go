@@ -0,0 +1,67 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulation
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testConfigs = []Config{
+	{},
+	{Members: 5, DropRate: 0.1, MaxDelay: 20},
+	{DropRate: 0.05, MaxDelay: 50, CrashRate: 0.001},
+}
+
+func TestSimulation(t *testing.T) {
+	for i, cfg := range testConfigs {
+		for seed := int64(0); seed < 10; seed++ {
+			cfg.Seed = seed
+			s, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = s.Run(5000); err != nil {
+				t.Fatalf("#%d: %v", i, err)
+			}
+			if s.AppliedIndex() <= 1 {
+				t.Errorf("#%d: seed %d: no entry applied", i, seed)
+			}
+		}
+	}
+}
+
+func TestSimulationDeterministic(t *testing.T) {
+	run := func(seed int64) *Simulation {
+		s, err := New(Config{Seed: seed, DropRate: 0.05, MaxDelay: 50, CrashRate: 0.001})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = s.Run(5000); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	s1, s2 := run(1), run(1)
+	if s1.Fingerprint() != s2.Fingerprint() {
+		t.Fatalf("fingerprints of seed 1 = %x, %x, want equal", s1.Fingerprint(), s2.Fingerprint())
+	}
+	if !reflect.DeepEqual(s1.KV(1), s2.KV(1)) {
+		t.Errorf("state machines of seed 1 = %v, %v, want equal", s1.KV(1), s2.KV(1))
+	}
+	if s3 := run(2); s3.Fingerprint() == s1.Fingerprint() {
+		t.Errorf("fingerprints of seeds 1 and 2 = %x, want different", s1.Fingerprint())
+	}
+}
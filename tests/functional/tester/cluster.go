@@ -52,6 +52,7 @@ type Cluster struct {
 
 	Members []*rpcpb.Member `yaml:"agent-configs"`
 	Tester  *rpcpb.Tester   `yaml:"tester-config"`
+	Soak    *Soak           `yaml:"soak-config"`
 
 	cases []Case
 
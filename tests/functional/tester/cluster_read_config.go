@@ -80,6 +80,15 @@ func read(lg *zap.Logger, fpath string) (*Cluster, error) {
 		}
 	}
 
+	if clus.Soak != nil {
+		if clus.Soak.Duration < 0 || clus.Soak.CheckInterval < 0 || clus.Soak.CaseInterval < 0 {
+			return nil, fmt.Errorf("soak durations cannot be negative (got %+v)", clus.Soak)
+		}
+		if clus.Soak.CheckInterval == 0 {
+			clus.Soak.CheckInterval = defaultSoakCheckInterval
+		}
+	}
+
 	if clus.Tester.StressKeySuffixRangeTxn > 100 {
 		return nil, fmt.Errorf("StressKeySuffixRangeTxn maximum value is 100, got %v", clus.Tester.StressKeySuffixRangeTxn)
 	}
@@ -46,6 +46,11 @@ func (clus *Cluster) Run(t *testing.T) {
 		)
 	}
 
+	if clus.Soak != nil && clus.Soak.Duration > 0 {
+		clus.runSoak(t)
+		return
+	}
+
 	var preModifiedKey int64
 	for round := 0; round < int(clus.Tester.RoundLimit) || clus.Tester.RoundLimit == -1; round++ {
 		t.Run(fmt.Sprintf("round:%v", round), func(t *testing.T) {
@@ -59,6 +59,22 @@ var (
 			Name:      "round_failed_total",
 			Help:      "Total number of failed test rounds.",
 		})
+
+	soakCheckTotalCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd",
+			Subsystem: "funcational_tester",
+			Name:      "soak_check_total",
+			Help:      "Total number of finished soak checks.",
+		})
+
+	soakCheckFailedTotalCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "etcd",
+			Subsystem: "funcational_tester",
+			Name:      "soak_check_failed_total",
+			Help:      "Total number of failed soak checks.",
+		})
 )
 
 func init() {
@@ -66,6 +82,8 @@ func init() {
 	prometheus.MustRegister(caseFailedTotalCounter)
 	prometheus.MustRegister(roundTotalCounter)
 	prometheus.MustRegister(roundFailedTotalCounter)
+	prometheus.MustRegister(soakCheckTotalCounter)
+	prometheus.MustRegister(soakCheckFailedTotalCounter)
 }
 
 func printReport() {
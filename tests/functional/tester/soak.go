@@ -0,0 +1,309 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tester
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"go.uber.org/zap"
+)
+
+// Soak configures the soak mode of the tester, which runs the cluster under
+// the configured stressers for a fixed duration instead of for rounds of
+// cases, checking its invariants periodically and injecting the configured
+// cases on a schedule.
+type Soak struct {
+	// Duration is how long the cluster is soaked, the soak mode being
+	// disabled if zero.
+	Duration time.Duration `yaml:"duration"`
+	// CheckInterval is how long the cluster is stressed between two checks
+	// of its invariants.
+	CheckInterval time.Duration `yaml:"check-interval"`
+	// CaseInterval is how often the next case is injected, no case being
+	// injected if zero.
+	CaseInterval time.Duration `yaml:"case-interval"`
+}
+
+const defaultSoakCheckInterval = time.Minute
+
+// runSoak stresses the cluster until the soak duration elapses. Every check
+// interval, the stress is paused to run the checkers and to ensure that the
+// watch of the whole keyspace observed every revision. Every case interval,
+// the next case is run in between.
+func (clus *Cluster) runSoak(t *testing.T) {
+	deadline := time.Now().Add(clus.Soak.Duration)
+	clus.lg.Info(
+		"soak START",
+		zap.Duration("duration", clus.Soak.Duration),
+		zap.Duration("check-interval", clus.Soak.CheckInterval),
+		zap.Duration("case-interval", clus.Soak.CaseInterval),
+	)
+
+	wd, err := newWatchGapDetector(clus)
+	if err != nil {
+		clus.failed(err)
+		return
+	}
+	defer func() {
+		if wd != nil {
+			wd.close()
+		}
+	}()
+
+	nextCase := time.Now().Add(clus.Soak.CaseInterval)
+	clus.cs = -1
+	for check := 0; time.Now().Before(deadline); check++ {
+		if clus.Soak.CaseInterval > 0 && !time.Now().Before(nextCase) {
+			clus.nextSoakCase()
+			fa := clus.cases[clus.cs]
+			t.Run(fmt.Sprintf("round:%v/%v_%s", clus.rd, clus.cs, fa.TestCase()), func(t *testing.T) {
+				clus.doTestCase(t, fa)
+			})
+			nextCase = time.Now().Add(clus.Soak.CaseInterval)
+		}
+
+		soakCheckTotalCounter.Inc()
+		if err = clus.doSoakCheck(wd, deadline); err == nil {
+			continue
+		}
+		soakCheckFailedTotalCounter.Inc()
+		clus.lg.Error(
+			"soak check FAIL",
+			zap.Int("check", check),
+			zap.Error(err),
+		)
+		t.Errorf("soak check %d failed: %v", check, err)
+
+		// the cluster restarts from scratch, and so does the watch
+		wd.close()
+		wd = nil
+		cs := clus.cs
+		clus.cs = -1
+		if err = clus.cleanup(err); err != nil {
+			return
+		}
+		clus.cs = cs
+		if wd, err = newWatchGapDetector(clus); err != nil {
+			clus.failed(err)
+			return
+		}
+	}
+
+	clus.lg.Info(
+		"soak PASS",
+		zap.Duration("duration", clus.Soak.Duration),
+		zap.Int("round", clus.rd),
+	)
+}
+
+// nextSoakCase moves on to the next case to inject, starting a new round once
+// all the cases were injected.
+func (clus *Cluster) nextSoakCase() {
+	clus.cs++
+	if clus.cs == len(clus.cases) {
+		clus.cs = 0
+		clus.rd++
+		roundTotalCounter.Inc()
+	}
+	if clus.cs == 0 && clus.Tester.CaseShuffle {
+		clus.shuffleCases()
+	}
+}
+
+// doSoakCheck stresses the cluster for a check interval, then checks its
+// invariants and compacts its history.
+func (clus *Cluster) doSoakCheck(wd *watchGapDetector, deadline time.Time) error {
+	if err := clus.WaitHealth(); err != nil {
+		return fmt.Errorf("wait full health error: %v", err)
+	}
+
+	stress := clus.Soak.CheckInterval
+	if left := time.Until(deadline); left < stress {
+		stress = left
+	}
+	if err := clus.stresser.Stress(); err != nil {
+		return fmt.Errorf("start stresser error: %v", err)
+	}
+	time.Sleep(stress)
+	if ems := clus.stresser.Pause(); len(ems) > 0 {
+		ess := make([]string, 0, len(ems))
+		for k, v := range ems {
+			ess = append(ess, fmt.Sprintf("%s (count: %d)", k, v))
+		}
+		clus.lg.Warn("soak stress errors", zap.Strings("errors", ess))
+	}
+
+	if err := clus.WaitHealth(); err != nil {
+		return fmt.Errorf("wait full health error: %v", err)
+	}
+	if err := clus.runCheckers(); err != nil {
+		return fmt.Errorf("consistency check error (%v)", err)
+	}
+	if err := wd.check(clus.currentRevision, 30*time.Second); err != nil {
+		return fmt.Errorf("watch check error (%v)", err)
+	}
+	clus.lg.Info(
+		"soak check PASS",
+		zap.Int64("current-revision", clus.currentRevision),
+	)
+
+	timeout := 10*time.Second + time.Duration(clus.stresser.ModifiedKeys()/compactQPS)*time.Second
+	return clus.compact(max(0, clus.currentRevision-10000), timeout)
+}
+
+// watchGapDetector watches the whole keyspace of the cluster and reports
+// any revision it missed, the revisions of the events being contiguous.
+type watchGapDetector struct {
+	lg     *zap.Logger
+	cli    *clientv3.Client
+	cancel context.CancelFunc
+	donec  chan struct{}
+
+	mu      sync.Mutex
+	lastRev int64
+	err     error
+}
+
+func newWatchGapDetector(clus *Cluster) (*watchGapDetector, error) {
+	cfg, err := clus.Members[0].CreateEtcdClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Endpoints = clus.EtcdClientEndpoints()
+	cli, err := clientv3.New(*cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watch client (%v)", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	resp, err := cli.Get(ctx, "health")
+	cancel()
+	if err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("failed to get watch start revision (%v)", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	wd := &watchGapDetector{
+		lg:      clus.lg,
+		cli:     cli,
+		cancel:  cancel,
+		donec:   make(chan struct{}),
+		lastRev: resp.Header.Revision,
+	}
+	go wd.run(ctx)
+	return wd, nil
+}
+
+// run watches from the revision following the last one observed, again and
+// again until the detector is closed or a gap is found.
+func (wd *watchGapDetector) run(ctx context.Context) {
+	defer close(wd.donec)
+	for ctx.Err() == nil {
+		rev := wd.revision() + 1
+		wctx, wcancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+		wch := wd.cli.Watch(wctx, "", clientv3.WithPrefix(), clientv3.WithRev(rev))
+		ok := wd.watch(rev, wch)
+		wcancel()
+		if !ok {
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// watch observes the events of the watch from the given revision, returning
+// false once a gap is detected.
+func (wd *watchGapDetector) watch(rev int64, wch clientv3.WatchChan) bool {
+	for resp := range wch {
+		if resp.CompactRevision != 0 {
+			wd.fail(fmt.Errorf("watch from revision %d compacted at revision %d", rev, resp.CompactRevision))
+			return false
+		}
+		if err := resp.Err(); err != nil {
+			wd.lg.Warn("watch failed, watching again", zap.Int64("revision", rev), zap.Error(err))
+			return true
+		}
+		for _, ev := range resp.Events {
+			if err := wd.observe(ev.Kv.ModRevision); err != nil {
+				wd.fail(err)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// observe records the revision of an event, the events of a revision being
+// observed together.
+func (wd *watchGapDetector) observe(rev int64) error {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	switch {
+	case rev < wd.lastRev:
+		return fmt.Errorf("watch event of revision %d after revision %d", rev, wd.lastRev)
+	case rev > wd.lastRev+1:
+		return fmt.Errorf("watch missed revisions %d to %d", wd.lastRev+1, rev-1)
+	}
+	wd.lastRev = rev
+	return nil
+}
+
+func (wd *watchGapDetector) revision() int64 {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	return wd.lastRev
+}
+
+func (wd *watchGapDetector) fail(err error) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	wd.err = err
+	wd.lg.Warn("watch gap detected", zap.Error(err))
+}
+
+// check returns an error if a gap was detected, or if the events up to the
+// given revision were not observed before the timeout.
+func (wd *watchGapDetector) check(rev int64, timeout time.Duration) error {
+	for deadline := time.Now().Add(timeout); ; time.Sleep(100 * time.Millisecond) {
+		wd.mu.Lock()
+		lastRev, err := wd.lastRev, wd.err
+		wd.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if lastRev >= rev {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("watch observed revision %d, expected revision %d in %v", lastRev, rev, timeout)
+		}
+	}
+}
+
+func (wd *watchGapDetector) close() {
+	wd.cancel()
+	<-wd.donec
+	wd.cli.Close()
+}
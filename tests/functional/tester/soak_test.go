@@ -0,0 +1,57 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tester
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_watchGapDetector_observe(t *testing.T) {
+	tests := []struct {
+		name    string
+		revs    []int64
+		wantErr bool
+	}{
+		{name: "contiguous", revs: []int64{11, 12, 13}},
+		{name: "events of the same revision", revs: []int64{11, 11, 12, 12, 12}},
+		{name: "gap", revs: []int64{11, 13}, wantErr: true},
+		{name: "first revision missed", revs: []int64{12}, wantErr: true},
+		{name: "revision going backwards", revs: []int64{11, 12, 11}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wd := &watchGapDetector{lastRev: 10}
+			var err error
+			for _, rev := range tt.revs {
+				if err = wd.observe(rev); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("observe(%v) error = %v, wantErr %v", tt.revs, err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				last := tt.revs[len(tt.revs)-1]
+				if err = wd.check(last, time.Second); err != nil {
+					t.Fatalf("check(%d) error = %v", last, err)
+				}
+				if err = wd.check(last+1, 100*time.Millisecond); err == nil {
+					t.Fatalf("check(%d) expected error for revision not observed", last+1)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3test
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/cache"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// countingKV counts the gets served by the cluster.
+type countingKV struct {
+	clientv3.KV
+	gets int64
+}
+
+func (kv *countingKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	atomic.AddInt64(&kv.gets, 1)
+	return kv.KV.Get(ctx, key, opts...)
+}
+
+func (kv *countingKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	if op.IsGet() {
+		atomic.AddInt64(&kv.gets, 1)
+	}
+	return kv.KV.Do(ctx, op)
+}
+
+func TestCacheKV(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	ctx := context.TODO()
+	if _, err := cli.Put(ctx, "foo/a", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	ckv := &countingKV{KV: cli.KV}
+	kv, closeCache := cache.NewKV(ckv, cli.Watcher, "foo/")
+	defer closeCache()
+
+	// cachedKeys waits for the serializable get of the prefix to be served
+	// from the cache, returning its keys.
+	cachedKeys := func() []string {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			gets := atomic.LoadInt64(&ckv.gets)
+			resp, err := kv.Get(ctx, "foo/", clientv3.WithPrefix(), clientv3.WithSerializable())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if atomic.LoadInt64(&ckv.gets) == gets {
+				var keys []string
+				for _, kv := range resp.Kvs {
+					keys = append(keys, string(kv.Key))
+				}
+				return keys
+			}
+		}
+		t.Fatal("get not served from the cache")
+		return nil
+	}
+	if keys := cachedKeys(); !reflect.DeepEqual(keys, []string{"foo/a"}) {
+		t.Fatalf("keys = %v, expected [foo/a]", keys)
+	}
+
+	// the writes through the wrapper are read from the cache
+	if _, err := kv.Put(ctx, "foo/b", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := cachedKeys(); !reflect.DeepEqual(keys, []string{"foo/a", "foo/b"}) {
+		t.Fatalf("keys = %v, expected [foo/a foo/b]", keys)
+	}
+	// the writes outside of the cached prefix are caught up by the progress
+	// of the watch
+	if _, err := kv.Put(ctx, "bar", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := cachedKeys(); !reflect.DeepEqual(keys, []string{"foo/a", "foo/b"}) {
+		t.Fatalf("keys = %v, expected [foo/a foo/b]", keys)
+	}
+
+	// the other writes are watched
+	if _, err := cli.Delete(ctx, "foo/a"); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		keys := cachedKeys()
+		if reflect.DeepEqual(keys, []string{"foo/b"}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("keys = %v, expected [foo/b]", keys)
+		}
+	}
+
+	// the linearizable gets are served by the cluster
+	gets := atomic.LoadInt64(&ckv.gets)
+	if _, err := kv.Get(ctx, "foo/b"); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt64(&ckv.gets) == gets {
+		t.Error("expected the linearizable get to be served by the cluster")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/pkg/v3/types"
 	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
 )
@@ -174,6 +175,8 @@ func TestMemberAddUpdateWrongURLs(t *testing.T) {
 		{"127.0.0.1:1234"},
 		// URL scheme must be http, https, unix, or unixs
 		{"localhost:1234"},
+		// named pipes are only supported for client traffic
+		{"npipe:////./pipe/etcd"},
 	}
 	for i := range tt {
 		_, err := capi.MemberAdd(context.Background(), tt[i])
@@ -292,6 +295,46 @@ func TestMemberPromote(t *testing.T) {
 	}
 }
 
+// TestMemberAutoPromoteLearner ensures that the leader promotes a learner
+// once in sync with it for the synced duration.
+func TestMemberAutoPromoteLearner(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, DisableStrictReconfigCheck: true, AutoPromoteLearnersSyncedDuration: 2 * time.Second})
+	defer clus.Terminate(t)
+
+	capi := clus.RandClient()
+	memberAddResp, err := capi.MemberAddAsLearner(context.Background(), []string{"http://127.0.0.1:1234"})
+	if err != nil {
+		t.Fatalf("failed to add member %v", err)
+	}
+	learnerID := memberAddResp.Member.ID
+
+	learnerMember := clus.MustNewMember(t, memberAddResp)
+	if err := learnerMember.Launch(); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(15 * time.Second)
+	for {
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("learner member %x not promoted", learnerID)
+		}
+
+		resp, err := capi.MemberList(context.Background())
+		if err != nil {
+			t.Fatalf("failed to list member %v", err)
+		}
+		for _, m := range resp.Members {
+			if m.ID == learnerID && !m.IsLearner {
+				return
+			}
+		}
+	}
+}
+
 // TestMemberPromoteMemberNotLearner ensures that promoting a voting member fails.
 func TestMemberPromoteMemberNotLearner(t *testing.T) {
 	integration2.BeforeTest(t)
@@ -328,6 +371,77 @@ func TestMemberPromoteMemberNotLearner(t *testing.T) {
 	}
 }
 
+// TestMemberDemote ensures that a voting member demoted for a maintenance leaves
+// the quorum, and rejoins it when promoted back.
+func TestMemberDemote(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, DisableStrictReconfigCheck: true})
+	defer clus.Terminate(t)
+
+	leaderIdx := clus.WaitLeader(t)
+	followerIdx := (leaderIdx + 1) % 3
+	cli := clus.Client(followerIdx)
+	leaderID := uint64(clus.Members[leaderIdx].Server.MemberId())
+	followerID := uint64(clus.Members[followerIdx].Server.MemberId())
+
+	if _, err := cli.MemberDemote(context.Background(), leaderID, true); err != rpctypes.ErrDemoteLeader {
+		t.Fatalf("demoting the leader error = %v, want %v", err, rpctypes.ErrDemoteLeader)
+	}
+	// the cluster of 2 voting members does not tolerate any failure
+	if _, err := cli.MemberDemote(context.Background(), followerID, false); err != rpctypes.ErrDemoteNotAcknowledged {
+		t.Fatalf("demoting not acknowledged error = %v, want %v", err, rpctypes.ErrDemoteNotAcknowledged)
+	}
+
+	resp, err := cli.MemberDemote(context.Background(), followerID, true)
+	if err != nil {
+		t.Fatalf("failed to demote member %v", err)
+	}
+	for _, m := range resp.Members {
+		if m.IsLearner != (m.ID == followerID) {
+			t.Fatalf("member %x IsLearner = %v, want only the demoted member %x a learner", m.ID, m.IsLearner, followerID)
+		}
+	}
+
+	// the demoted member rejects the requests not supported for a learner
+	lcli := clus.Client(leaderIdx)
+	if _, err = lcli.MemberDemote(context.Background(), followerID, true); err != rpctypes.ErrMemberNotVoter {
+		t.Fatalf("demoting a learner error = %v, want %v", err, rpctypes.ErrMemberNotVoter)
+	}
+	// the quorum of the 2 voting members keeps serving the writes
+	if _, err = lcli.Put(context.Background(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// retry until promote succeed or timeout
+	expectedErrKeywords := "can only promote a learner member which is in sync with leader"
+	timeout := time.After(5 * time.Second)
+	for {
+		_, err = lcli.MemberPromote(context.Background(), followerID)
+		if err == nil {
+			break
+		}
+		if !strings.Contains(err.Error(), expectedErrKeywords) {
+			t.Fatalf("unexpected error when promoting demoted member: %v", err)
+		}
+		select {
+		case <-time.After(500 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("failed all attempts to promote demoted member, last error: %v", err)
+		}
+	}
+
+	mresp, err := lcli.MemberList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mresp.Members {
+		if m.IsLearner {
+			t.Fatalf("member %x is a learner, want all members voting", m.ID)
+		}
+	}
+}
+
 // TestMemberPromoteMemberNotExist ensures that promoting a member that does not exist in cluster fails.
 func TestMemberPromoteMemberNotExist(t *testing.T) {
 	integration2.BeforeTest(t)
@@ -111,3 +111,98 @@ func TestResumeElection(t *testing.T) {
 		t.Errorf("expected new leader to be 'candidate1' got %q", string(kv.Value))
 	}
 }
+
+// TestElectionFencingToken ensures a leader that lost its leadership without
+// noticing can not write through a fence once a newer leader did.
+func TestElectionFencingToken(t *testing.T) {
+	const (
+		prefix   = "/fencing-election"
+		fence    = "/fencing-election-fence"
+		resource = "/fencing-election-resource"
+	)
+
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	write := func(e *concurrency.Election, val string) bool {
+		token := e.FencingToken()
+		resp, err := cli.Txn(ctx).
+			If(concurrency.FenceCmp(fence, token)).
+			Then(append(concurrency.FenceOps(fence, token), clientv3.OpPut(resource, val))...).
+			Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Succeeded
+	}
+
+	s1, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1 := concurrency.NewElection(s1, prefix)
+	if e1.FencingToken() != 0 {
+		t.Fatalf("FencingToken() = %d before Campaign(), want 0", e1.FencingToken())
+	}
+	if err = e1.Campaign(ctx, "e1"); err != nil {
+		t.Fatal(err)
+	}
+	if !write(e1, "e1") {
+		t.Fatal("leader e1 failed to write through the fence")
+	}
+
+	// e1 loses its leadership without noticing
+	if err = s1.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := concurrency.NewSession(cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	e2 := concurrency.NewElection(s2, prefix)
+	if err = e2.Campaign(ctx, "e2"); err != nil {
+		t.Fatal(err)
+	}
+	if e2.FencingToken() <= e1.FencingToken() {
+		t.Fatalf("fencing token of e2 %d, want greater than the one of e1 %d", e2.FencingToken(), e1.FencingToken())
+	}
+	if !write(e2, "e2") {
+		t.Fatal("leader e2 failed to write through the fence")
+	}
+	if write(e1, "e1-stale") {
+		t.Fatal("stale leader e1 wrote through the fence")
+	}
+	if !write(e2, "e2-again") {
+		t.Fatal("leader e2 failed to write through the fence again")
+	}
+
+	resp, err := cli.Txn(ctx).If(e1.IsLeader()).Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Succeeded {
+		t.Fatal("IsLeader() succeeded for the stale leader e1")
+	}
+
+	gresp, err := cli.Get(ctx, resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := string(gresp.Kvs[0].Value); v != "e2-again" {
+		t.Fatalf("resource = %q, want %q", v, "e2-again")
+	}
+	if gresp, err = cli.Get(ctx, fence+"/", clientv3.WithPrefix(), clientv3.WithCountOnly()); err != nil {
+		t.Fatal(err)
+	}
+	if gresp.Count != 1 {
+		t.Fatalf("%d tokens recorded in the fence, want 1", gresp.Count)
+	}
+}
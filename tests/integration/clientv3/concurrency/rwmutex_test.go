@@ -0,0 +1,140 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package concurrency_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestRWMutexFIFO ensures that readers share the lock, and that a writer
+// waiting for readers blocks the readers asking for the lock after it.
+func TestRWMutexFIFO(t *testing.T) {
+	const prefix = "/rwmutex-fifo"
+
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: exampleEndpoints()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		mu                  sync.Mutex
+		acquired, contended = map[bool]int{}, map[bool]int{}
+		released            = map[bool]int{}
+	)
+	hooks := concurrency.RWMutexHooks{
+		OnAcquire: func(write bool, _ time.Duration) { mu.Lock(); acquired[write]++; mu.Unlock() },
+		OnContend: func(write bool) { mu.Lock(); contended[write]++; mu.Unlock() },
+		OnRelease: func(write bool, _ time.Duration) { mu.Lock(); released[write]++; mu.Unlock() },
+	}
+	newRWMutex := func() *concurrency.RWMutex {
+		s, err := concurrency.NewSession(cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return concurrency.NewRWMutex(s, prefix, concurrency.WithRWMutexHooks(hooks))
+	}
+	r1, r2, w, r3 := newRWMutex(), newRWMutex(), newRWMutex(), newRWMutex()
+
+	if err = r1.RLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = r2.TryRLock(ctx); err != nil {
+		t.Fatalf("TryRLock() with only readers holding the lock = %v, want nil", err)
+	}
+	if err = w.TryLock(ctx); err != concurrency.ErrLocked {
+		t.Fatalf("TryLock() with readers holding the lock = %v, want %v", err, concurrency.ErrLocked)
+	}
+
+	wLocked := make(chan error, 1)
+	go func() { wLocked <- w.Lock(ctx) }()
+	// wait for the writer to wait for the lock
+	for {
+		resp, err := cli.Get(ctx, prefix+"/write/", clientv3.WithPrefix(), clientv3.WithCountOnly())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Count == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = r3.TryRLock(ctx); err != concurrency.ErrLocked {
+		t.Fatalf("TryRLock() with a writer waiting for the lock = %v, want %v", err, concurrency.ErrLocked)
+	}
+	r3Locked := make(chan error, 1)
+	go func() { r3Locked <- r3.RLock(ctx) }()
+
+	if err = r1.RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-wLocked:
+		t.Fatalf("writer acquired the lock while a reader holds it (%v)", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err = r2.RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wLocked; err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err = <-r3Locked:
+		t.Fatalf("reader acquired the lock while a writer holds it (%v)", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	resp, err := cli.Txn(ctx).If(w.IsOwner()).Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Succeeded {
+		t.Fatal("IsOwner() failed for the writer holding the lock")
+	}
+	if err = w.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-r3Locked; err != nil {
+		t.Fatal(err)
+	}
+	if err = r3.RUnlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if acquired[false] != 3 || acquired[true] != 1 {
+		t.Errorf("acquired %d read locks and %d write locks, want 3 and 1", acquired[false], acquired[true])
+	}
+	if contended[false] != 1 || contended[true] != 1 {
+		t.Errorf("contended %d read locks and %d write locks, want 1 and 1", contended[false], contended[true])
+	}
+	if released[false] != 3 || released[true] != 1 {
+		t.Errorf("released %d read locks and %d write locks, want 3 and 1", released[false], released[true])
+	}
+}
@@ -217,3 +217,58 @@ func testBalancerUnderBlackholeNoKeepAlive(t *testing.T, op func(*clientv3.Clien
 		t.Fatal(err)
 	}
 }
+
+// TestBalancerHealthScoringUnderBlackhole ensures that the health scoring
+// balancer stops sending requests to a blackholed endpoint after a few
+// failures, unlike the round robin balancer.
+func TestBalancerHealthScoringUnderBlackhole(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{
+		Size:      3,
+		UseBridge: true,
+	})
+	defer clus.Terminate(t)
+
+	eps := []string{clus.Members[0].GRPCURL(), clus.Members[1].GRPCURL(), clus.Members[2].GRPCURL()}
+	cli, err := integration2.NewClient(t, clientv3.Config{
+		Endpoints:       eps,
+		DialTimeout:     time.Second,
+		DialOptions:     []grpc.DialOption{grpc.WithBlock()},
+		HealthBalancing: &clientv3.HealthBalancingConfig{QuarantineDuration: time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	// wait for all the endpoints to be connected
+	for i := 0; i < 10; i++ {
+		if _, err = cli.Get(context.TODO(), "foo", clientv3.WithSerializable()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clus.Members[0].Bridge().Blackhole()
+	defer clus.Members[0].Bridge().Unblackhole()
+
+	failures := 0
+	for i := 0; i < 30; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		_, err = cli.Get(ctx, "foo", clientv3.WithSerializable())
+		cancel()
+		if err == nil {
+			continue
+		}
+		if !clientv3test.IsClientTimeout(err) {
+			t.Fatalf("#%d: failed with error %v", i, err)
+		}
+		failures++
+		if i >= 20 {
+			t.Errorf("#%d: failed with error %v, want the blackholed endpoint not picked anymore", i, err)
+		}
+	}
+	if failures > 4 {
+		t.Errorf("%d requests failed, want at most 4 before the blackholed endpoint is quarantined", failures)
+	}
+}
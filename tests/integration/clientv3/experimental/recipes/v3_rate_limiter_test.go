@@ -0,0 +1,104 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recipes_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	recipe "go.etcd.io/etcd/client/v3/experimental/recipes"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	ctx := context.TODO()
+	for i := 0; i < 5; i++ {
+		rl := recipe.NewRateLimiter(clus.Client(i%3), "test-rate-limiter", 0.1, 5)
+		if ok, err := rl.TryAcquire(ctx, 1); err != nil || !ok {
+			t.Fatalf("#%d: TryAcquire = %v, %v, expected the burst to be acquired", i, ok, err)
+		}
+	}
+	rl := recipe.NewRateLimiter(clus.RandClient(), "test-rate-limiter", 0.1, 5)
+	if ok, err := rl.TryAcquire(ctx, 1); err != nil || ok {
+		t.Fatalf("TryAcquire = %v, %v, expected the empty bucket to reject the acquisition", ok, err)
+	}
+	if _, err := rl.TryAcquire(ctx, 6); err != recipe.ErrBurstExceeded {
+		t.Fatalf("TryAcquire = %v, expected %v", err, recipe.ErrBurstExceeded)
+	}
+
+	// the bucket expires once full again
+	resp, err := clus.RandClient().Get(ctx, "test-rate-limiter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || resp.Kvs[0].Lease == 0 {
+		t.Fatalf("expected the bucket attached to a lease, got %+v", resp.Kvs)
+	}
+	lresp, err := clus.RandClient().TimeToLive(ctx, clientv3.LeaseID(resp.Kvs[0].Lease))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lresp.TTL < 50 {
+		t.Fatalf("lease TTL = %d, expected at least the 50s the bucket takes to fill up", lresp.TTL)
+	}
+}
+
+func TestRateLimiterAcquire(t *testing.T) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	const (
+		rate         = 20
+		burst        = 5
+		acquirers    = 5
+		acquisitions = 25
+	)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < acquirers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rl := recipe.NewRateLimiter(clus.RandClient(), "test-rate-limiter", rate, burst)
+			for j := 0; j < acquisitions/acquirers; j++ {
+				if err := rl.Acquire(context.TODO(), 1); err != nil {
+					t.Errorf("could not acquire (%v)", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the acquisitions beyond the burst wait for the refill
+	if elapsed, min := time.Since(start), time.Duration(acquisitions-burst)*time.Second/rate; elapsed < min {
+		t.Fatalf("acquired %d tokens in %v, expected at least %v", acquisitions, elapsed, min)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	rl := recipe.NewRateLimiter(clus.RandClient(), "test-rate-limiter", rate, burst)
+	if err := rl.Acquire(ctx, burst); err != context.DeadlineExceeded {
+		t.Fatalf("Acquire = %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
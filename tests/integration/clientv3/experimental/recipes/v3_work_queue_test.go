@@ -0,0 +1,147 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recipes_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	recipe "go.etcd.io/etcd/client/v3/experimental/recipes"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+func TestWorkQueueAck(t *testing.T) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	ctx := context.TODO()
+	q := recipe.NewWorkQueue(clus.RandClient(), "testwq", time.Minute, 0)
+	for i := 0; i < 3; i++ {
+		if err := q.Enqueue(ctx, fmt.Sprintf("%d", i)); err != nil {
+			t.Fatalf("error enqueuing (%v)", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		wi, err := recipe.NewWorkQueue(clus.RandClient(), "testwq", time.Minute, 0).Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("error dequeueing (%v)", err)
+		}
+		if wi.Value != fmt.Sprintf("%d", i) || wi.Attempts != 1 {
+			t.Fatalf("dequeued %q on attempt %d, expected %q on attempt 1", wi.Value, wi.Attempts, fmt.Sprintf("%d", i))
+		}
+		if err := wi.Ack(ctx); err != nil {
+			t.Fatalf("error acknowledging (%v)", err)
+		}
+	}
+
+	// the queue is empty
+	tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := q.Dequeue(tctx); err != context.DeadlineExceeded {
+		t.Fatalf("Dequeue = %v, expected %v", err, context.DeadlineExceeded)
+	}
+	resp, err := clus.RandClient().Get(ctx, "testwq/", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 0 {
+		t.Fatalf("expected no keys left, got %d", len(resp.Kvs))
+	}
+}
+
+func TestWorkQueueVisibilityTimeout(t *testing.T) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	ctx := context.TODO()
+	q := recipe.NewWorkQueue(clus.RandClient(), "testwq", time.Second, 0)
+	if err := q.Enqueue(ctx, "a"); err != nil {
+		t.Fatalf("error enqueuing (%v)", err)
+	}
+	wi, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("error dequeueing (%v)", err)
+	}
+
+	// delivered again once the claim expires
+	q2 := recipe.NewWorkQueue(clus.RandClient(), "testwq", time.Minute, 0)
+	dctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	wi2, err := q2.Dequeue(dctx)
+	if err != nil {
+		t.Fatalf("error dequeueing the expired item (%v)", err)
+	}
+	if wi2.Value != "a" || wi2.Attempts != 2 {
+		t.Fatalf("dequeued %q on attempt %d, expected %q on attempt 2", wi2.Value, wi2.Attempts, "a")
+	}
+	if err := wi.Ack(ctx); err != recipe.ErrClaimExpired {
+		t.Fatalf("Ack = %v, expected %v", err, recipe.ErrClaimExpired)
+	}
+	if err := wi.Extend(ctx); err != recipe.ErrClaimExpired {
+		t.Fatalf("Extend = %v, expected %v", err, recipe.ErrClaimExpired)
+	}
+	if err := wi2.Extend(ctx); err != nil {
+		t.Fatalf("error extending (%v)", err)
+	}
+	if err := wi2.Ack(ctx); err != nil {
+		t.Fatalf("error acknowledging (%v)", err)
+	}
+}
+
+func TestWorkQueueDeadLetters(t *testing.T) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	ctx := context.TODO()
+	q := recipe.NewWorkQueue(clus.RandClient(), "testwq", time.Minute, 2)
+	for _, v := range []string{"a", "b"} {
+		if err := q.Enqueue(ctx, v); err != nil {
+			t.Fatalf("error enqueuing (%v)", err)
+		}
+	}
+	// "a" is given up twice, "b" once then acknowledged
+	for _, expected := range []string{"a", "a", "b", "b"} {
+		wi, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("error dequeueing (%v)", err)
+		}
+		if wi.Value != expected {
+			t.Fatalf("dequeued %q, expected %q", wi.Value, expected)
+		}
+		if wi.Value == "b" && wi.Attempts == 2 {
+			err = wi.Ack(ctx)
+		} else {
+			err = wi.Nack(ctx)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dead, err := q.DeadLetters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dead, []string{"a"}) {
+		t.Fatalf("dead letters = %q, expected %q", dead, []string{"a"})
+	}
+}
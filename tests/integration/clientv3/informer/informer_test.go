@@ -0,0 +1,150 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3/informer"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// recorder records the notifications of an informer as "<type> <key>=<value>".
+type recorder chan string
+
+func (r recorder) OnAdd(kv *mvccpb.KeyValue) { r <- "add " + string(kv.Key) + "=" + string(kv.Value) }
+
+func (r recorder) OnUpdate(_, kv *mvccpb.KeyValue) {
+	r <- "update " + string(kv.Key) + "=" + string(kv.Value)
+}
+
+func (r recorder) OnDelete(kv *mvccpb.KeyValue) {
+	r <- "delete " + string(kv.Key) + "=" + string(kv.Value)
+}
+
+func (r recorder) expect(t *testing.T, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-r:
+			if got != w {
+				t.Fatalf("notification = %q, want %q", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for notification %q", w)
+		}
+	}
+}
+
+func TestInformer(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, key := range []string{"jobs/a", "jobs/b", "other"} {
+		if _, err := cli.Put(ctx, key, "pending"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inf := informer.New(cli.KV, cli.Watcher, "jobs/", informer.WithIndexers(informer.Indexers{
+		"state": func(kv *mvccpb.KeyValue) []string { return []string{string(kv.Value)} },
+	}))
+	r := make(recorder, 16)
+	inf.AddEventHandler(r)
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		inf.Run(ctx)
+	}()
+	if err := inf.WaitForSync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "add jobs/a=pending", "add jobs/b=pending")
+
+	if _, err := cli.Put(ctx, "jobs/a", "done"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Delete(ctx, "jobs/b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Put(ctx, "jobs/c", "pending"); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "update jobs/a=done", "delete jobs/b=pending", "add jobs/c=pending")
+
+	kvs, err := inf.Indexer().ByIndex("state", "pending")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || string(kvs[0].Key) != "jobs/c" {
+		t.Errorf("ByIndex(pending) = %v, want jobs/c", kvs)
+	}
+	if keys := inf.Indexer().Keys(); !reflect.DeepEqual(keys, []string{"jobs/a", "jobs/c"}) {
+		t.Errorf("Keys() = %v, want [jobs/a jobs/c]", keys)
+	}
+
+	// a handler added once synced is notified of the cache first
+	late := make(recorder, 16)
+	inf.AddEventHandler(late)
+	late.expect(t, "add jobs/a=done", "add jobs/c=pending")
+
+	cancel()
+	select {
+	case <-donec:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once canceled")
+	}
+}
+
+func TestInformerQueueHandler(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := informer.NewQueue()
+	defer q.ShutDown()
+	inf := informer.New(cli.KV, cli.Watcher, "jobs/", informer.WithResyncPeriod(100*time.Millisecond))
+	inf.AddEventHandler(informer.QueueHandler(q))
+	go inf.Run(ctx)
+	if err := inf.WaitForSync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.Put(ctx, "jobs/a", "pending"); err != nil {
+		t.Fatal(err)
+	}
+	// the key is queued by the put, then again by the resyncs
+	for i := 0; i < 3; i++ {
+		key, shutdown := q.Get()
+		if shutdown || key != "jobs/a" {
+			t.Fatalf("Get() = %q, %v, want jobs/a, false", key, shutdown)
+		}
+		q.Done(key)
+	}
+}
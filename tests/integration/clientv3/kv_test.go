@@ -107,6 +107,96 @@ func TestKVPutWithLease(t *testing.T) {
 	}
 }
 
+// TestKVIncrement ensures that concurrent increments are not lost, and return
+// the incremented values.
+func TestKVIncrement(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	const (
+		incrementers = 5
+		increments   = 20
+	)
+	ctx := context.TODO()
+	valuesc := make(chan int64, incrementers*increments)
+	errc := make(chan error, incrementers)
+	for i := 0; i < incrementers; i++ {
+		go func(kv clientv3.KV) {
+			for j := 0; j < increments; j++ {
+				resp, err := kv.Do(ctx, clientv3.OpIncrement("counter", 1))
+				if err != nil {
+					errc <- err
+					return
+				}
+				valuesc <- resp.Increment().Value
+			}
+			errc <- nil
+		}(clus.Client(i % 3))
+	}
+	for i := 0; i < incrementers; i++ {
+		if err := <-errc; err != nil {
+			t.Fatalf("couldn't increment (%v)", err)
+		}
+	}
+	close(valuesc)
+	seen := make(map[int64]bool)
+	for v := range valuesc {
+		if seen[v] {
+			t.Fatalf("value %d returned by two increments", v)
+		}
+		seen[v] = true
+	}
+
+	resp, err := clus.RandClient().Get(ctx, "counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := strconv.Itoa(incrementers * increments); string(resp.Kvs[0].Value) != w {
+		t.Fatalf("counter = %s, want %s", resp.Kvs[0].Value, w)
+	}
+
+	// decrements in a txn
+	tresp, err := clus.RandClient().Txn(ctx).
+		If(clientv3.Compare(clientv3.Value("counter"), "!=", "0")).
+		Then(clientv3.OpIncrement("counter", -incrementers*increments)).
+		Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := tresp.Responses[0].GetResponseIncrement().Value; v != 0 {
+		t.Fatalf("decremented counter = %d, want 0", v)
+	}
+}
+
+func TestKVIncrementError(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	if _, err := kv.Put(ctx, "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kv.Do(ctx, clientv3.OpIncrement("foo", 1)); err != rpctypes.ErrValueNotInteger {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrValueNotInteger, err)
+	}
+	if _, err := kv.Do(ctx, clientv3.OpIncrement("", 1)); err != rpctypes.ErrEmptyKey {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrEmptyKey, err)
+	}
+	if _, err := kv.Do(ctx, clientv3.OpIncrement("counter", 1, clientv3.WithLease(123))); err != rpctypes.ErrLeaseNotFound {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrLeaseNotFound, err)
+	}
+	_, err := kv.Txn(ctx).Then(clientv3.OpPut("counter", "1"), clientv3.OpIncrement("counter", 1)).Commit()
+	if err != rpctypes.ErrDuplicateKey {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrDuplicateKey, err)
+	}
+}
+
 // TestKVPutWithIgnoreValue ensures that Put with WithIgnoreValue does not clobber the old value.
 func TestKVPutWithIgnoreValue(t *testing.T) {
 	integration2.BeforeTest(t)
@@ -394,6 +484,58 @@ func TestKVDeleteRange(t *testing.T) {
 	}
 }
 
+// TestKVDeleteRangePaced ensures a paced delete range deletes the range in
+// batches of at most MaxDeleteBatchSize keys, each its own revision.
+func TestKVDeleteRangePaced(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3, MaxDeleteBatchSize: 3})
+	defer clus.Terminate(t)
+
+	kv := clus.RandClient()
+	ctx := context.TODO()
+
+	var wkeys []string
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("foo/%d", i)
+		if _, err := kv.Put(ctx, key, "bar"); err != nil {
+			t.Fatalf("couldn't put %q (%v)", key, err)
+		}
+		wkeys = append(wkeys, key)
+	}
+	presp, err := kv.Put(ctx, "fop", "bar")
+	if err != nil {
+		t.Fatalf("couldn't put 'fop' (%v)", err)
+	}
+
+	resp, err := kv.Delete(ctx, "foo/", clientv3.WithPrefix(), clientv3.WithPaced(), clientv3.WithPrevKV())
+	if err != nil {
+		t.Fatalf("couldn't delete range (%v)", err)
+	}
+	if resp.Deleted != 10 {
+		t.Errorf("deleted = %d, want 10", resp.Deleted)
+	}
+	var keys []string
+	for _, kv := range resp.PrevKvs {
+		keys = append(keys, string(kv.Key))
+	}
+	if !reflect.DeepEqual(keys, wkeys) {
+		t.Errorf("prev kvs = %v, want %v", keys, wkeys)
+	}
+	// 4 batches of 3, 3, 3 and 1 keys
+	if rev := resp.Header.Revision - presp.Header.Revision; rev != 4 {
+		t.Errorf("revisions = %d, want 4", rev)
+	}
+
+	gresp, err := kv.Get(ctx, "f", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatalf("couldn't get keys (%v)", err)
+	}
+	if len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Key) != "fop" {
+		t.Errorf("kvs = %v, want only 'fop'", gresp.Kvs)
+	}
+}
+
 func TestKVCompactError(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -478,6 +620,151 @@ func TestKVCompact(t *testing.T) {
 	}
 }
 
+// TestKVGetCompactionBarrier ensures the paginated gets registering a
+// compaction barrier at their revision succeed once it is compacted.
+func TestKVGetCompactionBarrier(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1, CompactionBarrierMaxDuration: time.Minute})
+	defer clus.Terminate(t)
+
+	kv := clus.Client(0)
+	ctx := context.TODO()
+
+	for i := 0; i < 4; i++ {
+		if _, err := kv.Put(ctx, fmt.Sprintf("foo%d", i), "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := kv.Get(ctx, "foo", clientv3.WithPrefix(), clientv3.WithLimit(2), clientv3.WithCompactionBarrier())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rev := resp.Header.Revision
+	for i := 0; i < 4; i++ {
+		if _, err = kv.Put(ctx, fmt.Sprintf("foo%d", i), "baz"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = kv.Compact(ctx, rev+4); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = kv.Get(ctx, "foo", clientv3.WithPrefix(), clientv3.WithRev(rev)); err != rpctypes.ErrCompacted {
+		t.Fatalf("get without compaction barrier = %v, want %v", err, rpctypes.ErrCompacted)
+	}
+	next := string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	resp, err = kv.Get(ctx, next, clientv3.WithRange(clientv3.GetPrefixRangeEnd("foo")), clientv3.WithRev(rev), clientv3.WithCompactionBarrier())
+	if err != nil {
+		t.Fatalf("get with compaction barrier = %v, want nil", err)
+	}
+	if len(resp.Kvs) != 2 || string(resp.Kvs[0].Key) != "foo2" || string(resp.Kvs[0].Value) != "bar" {
+		t.Fatalf("get with compaction barrier = %+v, want foo2=bar and foo3=bar", resp.Kvs)
+	}
+}
+
+// TestKVGetReadYourWrites ensures the serializable gets of a client reading
+// its writes wait for the member serving them to apply the writes.
+func TestKVGetReadYourWrites(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	leader := clus.WaitLeader(t)
+	follower := clus.Members[(leader+1)%3]
+	cli, err := integration2.NewClient(t, clientv3.Config{
+		Endpoints:      []string{clus.Members[leader].GRPCURL()},
+		ReadYourWrites: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	follower.InjectPartition(t, clus.Members[leader], clus.Members[(leader+2)%3])
+	presp, err := cli.Put(context.TODO(), "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli.SetEndpoints(follower.GRPCURL())
+	donec := make(chan struct{})
+	var gresp *clientv3.GetResponse
+	go func() {
+		defer close(donec)
+		gresp, err = cli.Get(context.TODO(), "foo", clientv3.WithSerializable())
+	}()
+	select {
+	case <-donec:
+		t.Fatalf("serializable get served before the member applied the write (%v)", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	follower.RecoverPartition(t, clus.Members[leader], clus.Members[(leader+2)%3])
+	select {
+	case <-donec:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for serializable get")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gresp.Header.Revision < presp.Header.Revision || len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Value) != "bar" {
+		t.Fatalf("serializable get = %+v at revision %d, want foo=bar at revision >= %d", gresp.Kvs, gresp.Header.Revision, presp.Header.Revision)
+	}
+}
+
+// TestKVGetMinRevisionWait ensures a get with a minimum revision to wait for
+// is served once the member applies the revision, and fails if it does not
+// apply it within the request timeout.
+func TestKVGetMinRevisionWait(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	leader := clus.WaitLeader(t)
+	follower := clus.Members[(leader+1)%3]
+	lcli, fcli := clus.Client(leader), clus.Client((leader+1)%3)
+
+	follower.InjectPartition(t, clus.Members[leader], clus.Members[(leader+2)%3])
+	presp, err := lcli.Put(context.TODO(), "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	donec := make(chan struct{})
+	var gresp *clientv3.GetResponse
+	go func() {
+		defer close(donec)
+		gresp, err = fcli.Get(context.TODO(), "foo", clientv3.WithSerializable(), clientv3.WithMinRevisionWait(presp.Header.Revision))
+	}()
+	select {
+	case <-donec:
+		t.Fatalf("get served before the member applied the minimum revision (%v)", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	follower.RecoverPartition(t, clus.Members[leader], clus.Members[(leader+2)%3])
+	select {
+	case <-donec:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for get")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gresp.Header.Revision < presp.Header.Revision || len(gresp.Kvs) != 1 || string(gresp.Kvs[0].Value) != "bar" {
+		t.Fatalf("get = %+v at revision %d, want foo=bar at revision >= %d", gresp.Kvs, gresp.Header.Revision, presp.Header.Revision)
+	}
+
+	_, err = fcli.Get(context.TODO(), "foo", clientv3.WithMinRevisionWait(presp.Header.Revision+100))
+	if err != rpctypes.ErrFutureRev {
+		t.Fatalf("get with a minimum revision not applied = %v, want %v", err, rpctypes.ErrFutureRev)
+	}
+}
+
 // TestKVGetRetry ensures get will retry on disconnect.
 func TestKVGetRetry(t *testing.T) {
 	integration2.BeforeTest(t)
@@ -21,6 +21,8 @@ import (
 	"io"
 	"math"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,9 +31,15 @@ import (
 	"go.uber.org/zap/zaptest"
 	"google.golang.org/grpc"
 
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/api/v3/version"
 	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/snapshot"
+	"go.etcd.io/etcd/pkg/v3/featuregate"
+	"go.etcd.io/etcd/server/v3/etcdserver"
+	"go.etcd.io/etcd/server/v3/features"
 	"go.etcd.io/etcd/server/v3/lease"
 	"go.etcd.io/etcd/server/v3/storage/backend"
 	"go.etcd.io/etcd/server/v3/storage/mvcc"
@@ -70,6 +78,274 @@ func TestMaintenanceHashKV(t *testing.T) {
 	}
 }
 
+func TestMaintenanceRangeEstimate(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	for i := 0; i < 10; i++ {
+		if _, err := cli.Put(context.Background(), fmt.Sprintf("foo%d", i), "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cli.Put(context.Background(), "zoo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := cli.RangeEstimate(context.Background(), clus.Members[0].GRPCURL(), "foo", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 10 {
+		t.Errorf("count = %d, want 10", resp.Count)
+	}
+	if want := int64(10 * len("foo0bar")); resp.TotalSize != want {
+		t.Errorf("total size = %d, want %d", resp.TotalSize, want)
+	}
+	if resp.Header.Revision != 12 {
+		t.Errorf("revision = %d, want 12", resp.Header.Revision)
+	}
+}
+
+func TestMaintenanceDefragmentEstimate(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	val := strings.Repeat("a", 4096)
+	for i := 0; i < 100; i++ {
+		if _, err := cli.Put(context.Background(), fmt.Sprintf("foo%d", i), val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dresp, err := cli.Delete(context.Background(), "foo", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ep := clus.Members[0].GRPCURL()
+	resp, err := cli.DefragmentEstimate(context.Background(), ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Tombstones != 100 {
+		t.Errorf("tombstones = %d, want 100", resp.Tombstones)
+	}
+	if resp.TombstonesSize <= 0 {
+		t.Errorf("tombstones size = %d, want positive", resp.TombstonesSize)
+	}
+
+	if _, err = cli.Compact(context.Background(), dresp.Header.Revision, clientv3.WithCompactPhysical()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Put(context.Background(), "zoo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Compact(context.Background(), dresp.Header.Revision+1, clientv3.WithCompactPhysical()); err != nil {
+		t.Fatal(err)
+	}
+	// the backend commits the compaction on its next transaction
+	clus.Members[0].Server.Backend().ForceCommit()
+	resp, err = cli.DefragmentEstimate(context.Background(), ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Tombstones != 0 || resp.TombstonesSize != 0 {
+		t.Errorf("tombstones = %d of %d bytes after compaction, want none", resp.Tombstones, resp.TombstonesSize)
+	}
+	if resp.ReclaimableSize < int64(100*len(val)) {
+		t.Errorf("reclaimable size = %d after compaction, want at least %d", resp.ReclaimableSize, 100*len(val))
+	}
+	if resp.ReclaimableSize != resp.DbSize-resp.DbSizeInUse+resp.DbSizePending {
+		t.Errorf("reclaimable size = %d, want the free and pending pages of %+v", resp.ReclaimableSize, resp)
+	}
+
+	if _, err = cli.Defragment(context.Background(), ep); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = cli.DefragmentEstimate(context.Background(), ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ReclaimableSize >= int64(100*len(val)) {
+		t.Errorf("reclaimable size = %d after defragmentation, want less than %d", resp.ReclaimableSize, 100*len(val))
+	}
+}
+
+func TestMaintenanceEvents(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ep := clus.Members[0].GRPCURL()
+	for i := 0; i < 3; i++ {
+		if _, err := cli.Put(context.Background(), "foo", "bar"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cli.Compact(context.Background(), 3, clientv3.WithCompactPhysical()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Defragment(context.Background(), ep); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := cli.Events(context.Background(), ep, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, ev := range resp.Events {
+		types = append(types, ev.Type)
+	}
+	if want := []string{etcdserver.EventMemberAdded, etcdserver.EventLeaderChanged, etcdserver.EventCompaction, etcdserver.EventDefrag}; !reflect.DeepEqual(types, want) {
+		t.Errorf("event types = %v, want %v", types, want)
+	}
+
+	resp, err = cli.Events(context.Background(), ep, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Events) != 1 || resp.Events[0].Type != etcdserver.EventDefrag {
+		t.Errorf("latest event = %v, want a single %s event", resp.Events, etcdserver.EventDefrag)
+	}
+}
+
+func TestMaintenanceFeatureGates(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	resp, err := clus.RandClient().FeatureGates(context.Background(), clus.Members[0].GRPCURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Features) != len(features.DefaultEtcdServerFeatureGates) {
+		t.Fatalf("len(features) = %d, want %d", len(resp.Features), len(features.DefaultEtcdServerFeatureGates))
+	}
+	for i, f := range resp.Features {
+		if i > 0 && resp.Features[i-1].Name >= f.Name {
+			t.Errorf("features not sorted by name: %q before %q", resp.Features[i-1].Name, f.Name)
+		}
+		spec, ok := features.DefaultEtcdServerFeatureGates[featuregate.Feature(f.Name)]
+		if !ok {
+			t.Errorf("unknown feature %q", f.Name)
+			continue
+		}
+		if f.Enabled != spec.Default || f.Default != spec.Default || f.Stage != string(spec.Stage) || f.LockToDefault != spec.LockToDefault {
+			t.Errorf("feature %q = %+v, want %+v at its default", f.Name, f, spec)
+		}
+	}
+}
+
+func TestMaintenancePrefixStats(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1, PrefixStats: []string{"a/", "b/"}})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ctx := context.Background()
+	if _, err := cli.Put(ctx, "a/foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Get(ctx, "a/", clientv3.WithPrefix()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Txn(ctx).If().Then(clientv3.OpPut("b/foo", "barbaz"), clientv3.OpDelete("c")).Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := cli.PrefixStats(ctx, clus.Members[0].GRPCURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Start <= 0 || resp.Start > time.Now().UnixNano() {
+		t.Errorf("start = %d, want the start of the member", resp.Start)
+	}
+	want := []*pb.PrefixStat{
+		{Prefix: nil, Writes: 1, WriteBytes: 1},
+		{Prefix: []byte("a/"), Reads: 1, Writes: 1, ReadBytes: 8, WriteBytes: 8},
+		{Prefix: []byte("b/"), Writes: 1, WriteBytes: 11},
+	}
+	if !reflect.DeepEqual(resp.Stats, want) {
+		t.Errorf("stats = %v, want %v", resp.Stats, want)
+	}
+}
+
+func TestMaintenanceLogLevel(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ep := clus.Members[0].GRPCURL()
+	resp, err := cli.LogLevel(context.Background(), ep, "debug", "mvcc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Level != "info" || len(resp.Subsystems) != 1 || resp.Subsystems[0].Subsystem != "mvcc" || resp.Subsystems[0].Level != "debug" {
+		t.Errorf("unexpected levels %+v", resp)
+	}
+
+	if resp, err = cli.LogLevel(context.Background(), ep, "warn", ""); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Level != "warn" || len(resp.Subsystems) != 1 {
+		t.Errorf("unexpected levels %+v", resp)
+	}
+
+	// an empty level resets the subsystem to the global level
+	if resp, err = cli.LogLevel(context.Background(), ep, "", "mvcc"); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Level != "warn" || len(resp.Subsystems) != 0 {
+		t.Errorf("unexpected levels %+v", resp)
+	}
+
+	if _, err = cli.LogLevel(context.Background(), ep, "verbose", ""); err != rpctypes.ErrInvalidLogLevel {
+		t.Errorf("err = %v, want %v", err, rpctypes.ErrInvalidLogLevel)
+	}
+}
+
+func TestMaintenanceLogRange(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	ep := clus.Members[0].GRPCURL()
+	resp, err := cli.LogRange(context.Background(), ep, "foo", time.Minute, clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Ranges) != 1 || string(resp.Ranges[0].Key) != "foo" || string(resp.Ranges[0].RangeEnd) != "fop" {
+		t.Fatalf("unexpected logged ranges %+v", resp.Ranges)
+	}
+	if !clus.Members[0].Server.IsLoggedRange([]byte("foo1"), nil) {
+		t.Error("expected foo1 to be logged")
+	}
+
+	if resp, err = cli.LogRange(context.Background(), ep, "foo", 0, clientv3.WithPrefix()); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Ranges) != 0 {
+		t.Errorf("logged ranges = %+v, want none once stopped", resp.Ranges)
+	}
+	if clus.Members[0].Server.IsLoggedRange([]byte("foo1"), nil) {
+		t.Error("expected foo1 not to be logged")
+	}
+}
+
 // TODO: Change this to fuzz test
 func TestCompactionHash(t *testing.T) {
 	integration2.BeforeTest(t)
@@ -317,6 +593,64 @@ func TestMaintenanceSnapshotWithVersionVersion(t *testing.T) {
 	}
 }
 
+func TestMaintenanceSnapshotDelta(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	if _, err := cli.Put(context.Background(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := cli.Put(context.Background(), "foo", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := resp.Header.Revision
+	for i := 0; i < 10; i++ {
+		if _, err = cli.Put(context.Background(), fmt.Sprintf("%d", i), "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = cli.Delete(context.Background(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := cli.SnapshotDelta(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	evs, manifest, err := snapshot.ReadDelta(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.SinceRevision != since || manifest.Revision != since+11 || manifest.Events != 11 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	for i, ev := range evs {
+		if ev.Kv.ModRevision != since+int64(i)+1 {
+			t.Errorf("#%d: mod revision = %d, want %d", i, ev.Kv.ModRevision, since+int64(i)+1)
+		}
+	}
+	if last := evs[len(evs)-1]; last.Type != mvccpb.DELETE || string(last.Kv.Key) != "foo" {
+		t.Errorf("last event = %+v, want the delete of foo", last)
+	}
+
+	if _, err = cli.Compact(context.Background(), since+1); err != nil {
+		t.Fatal(err)
+	}
+	rc, err = cli.SnapshotDelta(context.Background(), since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if _, _, err = snapshot.ReadDelta(rc); err != rpctypes.ErrCompacted {
+		t.Errorf("err = %v, want %v", err, rpctypes.ErrCompacted)
+	}
+}
+
 func TestMaintenanceStatus(t *testing.T) {
 	integration2.BeforeTest(t)
 
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/mirror"
 	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
 )
@@ -124,3 +125,67 @@ func TestMirrorSyncBase(t *testing.T) {
 		t.Errorf("unexpected kv count: %d", count)
 	}
 }
+
+func TestMirrorWatchWithResync(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	c := clus.Client(0)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	for _, kv := range []string{"foo/a", "foo/b", "bar"} {
+		if _, err := c.Put(ctx, kv, "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Delete(ctx, "foo/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Compact(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	recv := func(respc <-chan mirror.ResyncResponse) mirror.ResyncResponse {
+		select {
+		case resp, ok := <-respc:
+			if !ok {
+				t.Fatal("responses closed")
+			}
+			return resp
+		case <-time.After(5 * time.Second):
+			t.Fatal("failed to receive a response in five seconds")
+		}
+		return mirror.ResyncResponse{}
+	}
+	wkvs := []*mvccpb.KeyValue{{Key: []byte("foo/b"), Value: []byte("v"), CreateRevision: 3, ModRevision: 3, Version: 1}}
+
+	// the revision watched from is compacted
+	respc := mirror.WatchWithResync(ctx, c.KV, c.Watcher, "foo/", clientv3.WithPrefix(), clientv3.WithRev(2))
+	resp := recv(respc)
+	if !resp.Resync || resp.Header.Revision != 5 || !reflect.DeepEqual(resp.Kvs, wkvs) {
+		t.Fatalf("response = %+v, want the range resynced at revision 5 with %v", resp, wkvs)
+	}
+
+	// the range is listed before being watched
+	listc := mirror.WatchWithResync(ctx, c.KV, c.Watcher, "foo/", clientv3.WithPrefix())
+	if resp = recv(listc); !resp.Resync || !reflect.DeepEqual(resp.Kvs, wkvs) {
+		t.Fatalf("response = %+v, want the range listed with %v", resp, wkvs)
+	}
+
+	if _, err := c.Put(ctx, "foo/c", "v"); err != nil {
+		t.Fatal(err)
+	}
+	for _, respc := range []<-chan mirror.ResyncResponse{respc, listc} {
+		resp = recv(respc)
+		if resp.Resync || len(resp.Events) != 1 || string(resp.Events[0].Kv.Key) != "foo/c" || resp.Events[0].Kv.ModRevision != 6 {
+			t.Fatalf("response = %+v, want the put of foo/c at revision 6", resp)
+		}
+	}
+
+	cancel()
+	if _, ok := <-respc; ok {
+		t.Fatal("responses not closed with the context")
+	}
+}
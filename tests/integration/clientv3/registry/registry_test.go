@@ -0,0 +1,202 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/client/v3/registry"
+	"go.etcd.io/etcd/pkg/v3/grpc_testing"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+	"google.golang.org/grpc"
+	testpb "google.golang.org/grpc/test/grpc_testing"
+)
+
+func TestRegistryRegisterWatch(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	reg := registry.New(clus.RandClient(), "/services")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wch := reg.Watch(ctx, "foo")
+	expectInstances(t, wch, nil)
+
+	foo1 := registry.Instance{ID: "foo-1", Addr: "127.0.0.1:1", Metadata: map[string]string{"zone": "a"}}
+	r1, err := reg.Register(ctx, "foo", foo1, registry.WithTTL(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	foo1.Health = registry.Serving
+	expectInstances(t, wch, []registry.Instance{foo1})
+
+	if err = r1.SetHealth(ctx, registry.NotServing); err != nil {
+		t.Fatal(err)
+	}
+	foo1.Health = registry.NotServing
+	expectInstances(t, wch, []registry.Instance{foo1})
+
+	foo2 := registry.Instance{ID: "foo-2", Addr: "127.0.0.1:2", Health: registry.Serving}
+	r2, err := reg.Register(ctx, "foo", foo2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close(ctx)
+	expectInstances(t, wch, []registry.Instance{foo1, foo2})
+
+	if err = r1.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectInstances(t, wch, []registry.Instance{foo2})
+	if err = r1.SetHealth(ctx, registry.Serving); err != registry.ErrClosed {
+		t.Fatalf("SetHealth() after Close() = %v, want %v", err, registry.ErrClosed)
+	}
+
+	insts, _, err := reg.List(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(insts, []registry.Instance{foo2}) {
+		t.Fatalf("List() = %+v, want %+v", insts, []registry.Instance{foo2})
+	}
+}
+
+// TestRegistryRegisterLeaseExpired ensures an instance is registered again
+// after its lease expires.
+func TestRegistryRegisterLeaseExpired(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.RandClient()
+	reg := registry.New(cli, "/services")
+	ctx := context.Background()
+
+	r, err := reg.Register(ctx, "foo", registry.Instance{ID: "foo-1", Addr: "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close(ctx)
+
+	lease := r.Lease()
+	if _, err = cli.Revoke(ctx, lease); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		resp, err := cli.Get(ctx, r.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Kvs) == 1 && resp.Kvs[0].Lease != int64(lease) {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("instance not registered again after its lease %x was revoked", lease)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestRegistryResolver(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	s1PayloadBody := []byte{'1'}
+	s1 := grpc_testing.NewDummyStubServer(s1PayloadBody)
+	if err := s1.Start(nil); err != nil {
+		t.Fatal("failed to start dummy grpc server (s1)", err)
+	}
+	defer s1.Stop()
+
+	s2PayloadBody := []byte{'2'}
+	s2 := grpc_testing.NewDummyStubServer(s2PayloadBody)
+	if err := s2.Start(nil); err != nil {
+		t.Fatal("failed to start dummy grpc server (s2)", err)
+	}
+	defer s2.Stop()
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	reg := registry.New(clus.RandClient(), "/services")
+	ctx := context.Background()
+
+	r1, err := reg.Register(ctx, "foo", registry.Instance{ID: "foo-1", Addr: s1.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Close(ctx)
+	r2, err := reg.Register(ctx, "foo", registry.Instance{ID: "foo-2", Addr: s2.Addr(), Health: registry.NotServing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close(ctx)
+
+	conn, err := grpc.Dial(registry.Scheme+":///foo", grpc.WithInsecure(), grpc.WithResolvers(reg.NewResolverBuilder()))
+	if err != nil {
+		t.Fatal("failed to connect to foo", err)
+	}
+	defer conn.Close()
+
+	c := testpb.NewTestServiceClient(conn)
+	resp, err := c.UnaryCall(ctx, &testpb.SimpleRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatal("failed to invoke rpc to foo (foo-1)", err)
+	}
+	if !bytes.Equal(resp.GetPayload().GetBody(), s1PayloadBody) {
+		t.Fatalf("unexpected response from foo (foo-1): %s", resp.GetPayload().GetBody())
+	}
+
+	if err = r1.SetHealth(ctx, registry.NotServing); err != nil {
+		t.Fatal(err)
+	}
+	if err = r2.SetHealth(ctx, registry.Serving); err != nil {
+		t.Fatal(err)
+	}
+
+	// it's asynchronous for gRPC Client to update underlying connections
+	for i := 0; ; i++ {
+		time.Sleep(100 * time.Millisecond)
+		resp, err = c.UnaryCall(ctx, &testpb.SimpleRequest{})
+		if err == nil && bytes.Equal(resp.GetPayload().GetBody(), s2PayloadBody) {
+			break
+		}
+		if i == 300 {
+			t.Fatalf("rpc to foo not served by foo-2 (%v)", err)
+		}
+	}
+}
+
+func expectInstances(t *testing.T, wch <-chan []registry.Instance, want []registry.Instance) {
+	t.Helper()
+	select {
+	case insts := <-wch:
+		if len(insts) == 0 && len(want) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(insts, want) {
+			t.Fatalf("instances = %+v, want %+v", insts, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for instances %+v", want)
+	}
+}
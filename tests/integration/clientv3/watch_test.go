@@ -920,6 +920,56 @@ func TestWatchWithFilter(t *testing.T) {
 	}
 }
 
+// TestWatchWithValueFilter checks that WithValuePrefix and WithValueRegex
+// discard the put events whose value does not match, but not the delete events.
+func TestWatchWithValueFilter(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	cluster := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer cluster.Terminate(t)
+
+	client := cluster.RandClient()
+	ctx := context.Background()
+
+	wcPrefix := client.Watch(ctx, "a", clientv3.WithPrefix(), clientv3.WithValuePrefix("on"))
+	wcRegex := client.Watch(ctx, "a", clientv3.WithPrefix(), clientv3.WithValueRegex("^[0-9]+$"))
+	wcBoth := client.Watch(ctx, "a", clientv3.WithPrefix(), clientv3.WithValuePrefix("1"), clientv3.WithValueRegex("2$"))
+
+	for _, kv := range [][2]string{{"a1", "off"}, {"a2", "online"}, {"a3", "42"}, {"a4", "12"}} {
+		if _, err := client.Put(ctx, kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.Delete(ctx, "a1"); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(wc clientv3.WatchChan, keys ...string) {
+		var got []string
+		for len(got) < len(keys) {
+			resp := <-wc
+			if err := resp.Err(); err != nil {
+				t.Fatal(err)
+			}
+			for _, ev := range resp.Events {
+				got = append(got, fmt.Sprintf("%s %s", ev.Type, ev.Kv.Key))
+			}
+		}
+		if !reflect.DeepEqual(got, keys) {
+			t.Fatalf("expected events %v, got %v", keys, got)
+		}
+	}
+	expect(wcPrefix, "PUT a2", "DELETE a1")
+	expect(wcRegex, "PUT a3", "PUT a4", "DELETE a1")
+	expect(wcBoth, "PUT a4", "DELETE a1")
+
+	wcInvalid := client.Watch(ctx, "a", clientv3.WithValueRegex("("))
+	resp, ok := <-wcInvalid
+	if !ok || resp.Err() != rpctypes.ErrInvalidValueRegex {
+		t.Fatalf("expected %v, got %v", rpctypes.ErrInvalidValueRegex, resp.Err())
+	}
+}
+
 // TestWatchWithCreatedNotification checks that WithCreatedNotify returns a
 // Created watch response.
 func TestWatchWithCreatedNotification(t *testing.T) {
@@ -1211,3 +1261,149 @@ func testWatchClose(t *testing.T, wctx *watchctx) {
 		t.Fatalf("read wch got %v; expected closed channel", wresp)
 	}
 }
+
+// TestWatchResumeOnStreamExpiry ensures the watches resume on a new stream
+// once theirs exceeds its maximum age, from the revision of the progress sent
+// before closing it, thus past the compactions of the revisions not watched.
+func TestWatchResumeOnStreamExpiry(t *testing.T) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1, WatchMaxStreamAge: time.Second})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wch := cli.Watch(ctx, "foo", clientv3.WithCreatedNotify())
+	if wresp := <-wch; !wresp.Created {
+		t.Fatalf("watch response = %+v, want created", wresp)
+	}
+
+	if _, err := cli.Put(ctx, "foo", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	var rev int64
+	for i := 0; i < 3; i++ {
+		resp, err := cli.Put(ctx, "bar", "baz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rev = resp.Header.Revision
+	}
+	if _, err := cli.Compact(ctx, rev); err != nil {
+		t.Fatal(err)
+	}
+	if wresp := <-wch; wresp.Err() != nil || len(wresp.Events) != 1 || string(wresp.Events[0].Kv.Value) != "v1" {
+		t.Fatalf("watch response = %+v, want the put of v1", wresp)
+	}
+
+	// the stream expires within 1.1s
+	time.Sleep(2 * time.Second)
+	if _, err := cli.Put(ctx, "foo", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		select {
+		case wresp := <-wch:
+			// the progress sent before closing the stream is received too
+			if wresp.IsProgressNotify() {
+				continue
+			}
+			if wresp.Err() != nil || len(wresp.Events) != 1 || string(wresp.Events[0].Kv.Value) != "v2" {
+				t.Fatalf("watch response = %+v, want the put of v2", wresp)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the put of v2")
+		}
+	}
+}
+
+func TestWatchRequestWatchProgress(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy does not support WatchProgress yet")
+	}
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	wc := clus.RandClient()
+	wch1 := wc.Watch(context.Background(), "/", clientv3.WithPrefix())
+	wch2 := wc.Watch(context.Background(), "/", clientv3.WithPrefix())
+
+	if _, err := wc.Put(context.Background(), "/a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, wch := range []clientv3.WatchChan{wch1, wch2} {
+		select {
+		case resp := <-wch:
+			if len(resp.Events) != 1 {
+				t.Fatalf("resp.Events expected 1, got %d", len(resp.Events))
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("watch response expected in 3s, but timed out")
+		}
+	}
+	// put a value not being watched to increment revision
+	if _, err := wc.Put(context.Background(), "x", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wc.RequestWatchProgress(context.Background(), wch1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case resp := <-wch1:
+		if !resp.IsProgressNotify() || resp.Header.Revision != 3 {
+			t.Fatalf("expected a progress notify at revision 3, got %+v", resp)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("progress response expected in 3s, but timed out")
+	}
+	// only the requested watcher is notified
+	select {
+	case resp := <-wch2:
+		t.Fatalf("unexpected watch response %+v", resp)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if err := wc.RequestWatchProgress(context.Background(), make(clientv3.WatchChan)); err == nil {
+		t.Fatal("progress request of an unknown watch channel succeeded")
+	}
+}
+
+func TestWatchWithProgressNotifyInterval(t *testing.T) {
+	if integration2.ThroughProxy {
+		t.Skipf("grpc-proxy does not support per-watcher progress notify intervals")
+	}
+	integration2.BeforeTest(t)
+
+	// the watchers of the progress notify interval of the server are not
+	// notified in the time of the test
+	oldpi := v3rpc.GetProgressReportInterval()
+	v3rpc.SetProgressReportInterval(time.Hour)
+	defer func() { v3rpc.SetProgressReportInterval(oldpi) }()
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	wc := clus.RandClient()
+	rch := wc.Watch(context.Background(), "foo", clientv3.WithProgressNotify())
+	ich := wc.Watch(context.Background(), "foo", clientv3.WithProgressNotifyInterval(200*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case resp := <-ich:
+			if !resp.IsProgressNotify() {
+				t.Fatalf("expected a progress notify, got %+v", resp)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("#%d: progress notify expected in 2s, but timed out", i)
+		}
+	}
+	select {
+	case resp := <-rch:
+		t.Fatalf("unexpected watch response %+v", resp)
+	default:
+	}
+}
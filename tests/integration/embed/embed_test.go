@@ -27,14 +27,19 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/client/pkg/v3/testutil"
 	"go.etcd.io/etcd/client/pkg/v3/transport"
 	"go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/server/v3/embed"
 	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -185,6 +190,64 @@ func testEmbedEtcdGracefulStop(t *testing.T, secure bool) {
 	}
 }
 
+// TestEmbedEtcdInterceptors ensures the users' interceptors of the config
+// intercept the requests of the embedded server.
+func TestEmbedEtcdInterceptors(t *testing.T) {
+	testutil.SkipTestIfShortMode(t, "Cannot start embedded cluster in --short tests")
+
+	var streams int32
+	cfg := embed.NewConfig()
+	cfg.UnaryInterceptors = []grpc.UnaryServerInterceptor{
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if r, ok := req.(*etcdserverpb.PutRequest); ok && string(r.Key) == "denied" {
+				return nil, status.Error(codes.PermissionDenied, "denied")
+			}
+			return handler(ctx, req)
+		},
+	}
+	cfg.StreamInterceptors = []grpc.StreamServerInterceptor{
+		func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if info.FullMethod == "/etcdserverpb.Watch/Watch" {
+				atomic.AddInt32(&streams, 1)
+			}
+			return handler(srv, ss)
+		},
+	}
+	urls := newEmbedURLs(false, 2)
+	setupEmbedCfg(cfg, []url.URL{urls[0]}, []url.URL{urls[1]})
+	cfg.Dir = filepath.Join(t.TempDir(), "embed-etcd")
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	<-e.Server.ReadyNotify()
+
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: []string{urls[0].String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = cli.Put(ctx, "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Put(ctx, "denied", "bar"); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Put() of the denied key = %v, want %v", err, codes.PermissionDenied)
+	}
+
+	wch := cli.Watch(ctx, "foo", clientv3.WithRev(1))
+	if wresp := <-wch; wresp.Err() != nil || len(wresp.Events) != 1 {
+		t.Fatalf("Watch() = %+v, want the put of foo", wresp)
+	}
+	if n := atomic.LoadInt32(&streams); n != 1 {
+		t.Fatalf("intercepted %d watch streams, want 1", n)
+	}
+}
+
 func newEmbedURLs(secure bool, n int) (urls []url.URL) {
 	scheme := "unix"
 	if secure {
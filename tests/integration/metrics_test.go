@@ -210,3 +210,85 @@ func TestMetricsHealth(t *testing.T) {
 		t.Fatalf("expected '0' from etcd_server_health_failures, got %q", hv)
 	}
 }
+
+// TestMetricRequestSizes checks that the request size distributions observe
+// the puts and the ranges, including those of transactions.
+func TestMetricRequestSizes(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	metrics := []string{
+		"etcd_server_put_value_bytes_sum",
+		"etcd_server_txn_ops_sum",
+		"etcd_server_range_result_keys_sum",
+	}
+	// the metrics are shared by the members of the test process
+	before := make([]float64, len(metrics))
+	for i, m := range metrics {
+		before[i] = metricFloat(t, clus.Members[0], m)
+	}
+
+	kvc := integration.ToGRPC(clus.RandClient()).KV
+	if _, err := kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("foo"), Value: make([]byte, 1000)}); err != nil {
+		t.Fatal(err)
+	}
+	txn := &pb.TxnRequest{Success: []*pb.RequestOp{
+		{Request: &pb.RequestOp_RequestPut{RequestPut: &pb.PutRequest{Key: []byte("bar"), Value: make([]byte, 10)}}},
+		{Request: &pb.RequestOp_RequestRange{RequestRange: &pb.RangeRequest{Key: []byte("a"), RangeEnd: []byte("z")}}},
+	}}
+	if _, err := kvc.Txn(context.TODO(), txn); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kvc.Range(context.TODO(), &pb.RangeRequest{Key: []byte("foo")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []float64{1010, 2, 3} {
+		if d := metricFloat(t, clus.Members[0], metrics[i]) - before[i]; d != want {
+			t.Errorf("%s increased by %v, want %v", metrics[i], d, want)
+		}
+	}
+}
+
+func metricFloat(t *testing.T, m *integration.Member, name string, labels ...string) float64 {
+	s, err := m.Metric(name, labels...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// TestMetricProposalStages checks that the latency of each stage of the local
+// proposals is observed.
+func TestMetricProposalStages(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	stages := []string{"queue", "raft_commit", "apply_wait", "apply"}
+	before := make([]float64, len(stages))
+	for i, stage := range stages {
+		before[i] = metricFloat(t, clus.Members[0], "etcd_server_proposal_stage_duration_seconds_count", `stage="`+stage+`"`)
+	}
+
+	kvc := integration.ToGRPC(clus.RandClient()).KV
+	for i := 0; i < 3; i++ {
+		if _, err := kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, stage := range stages {
+		if d := metricFloat(t, clus.Members[0], "etcd_server_proposal_stage_duration_seconds_count", `stage="`+stage+`"`) - before[i]; d < 3 {
+			t.Errorf("%s stage observed %v times, want at least 3", stage, d)
+		}
+	}
+}
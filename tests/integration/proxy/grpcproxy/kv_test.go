@@ -16,7 +16,11 @@ package grpcproxy
 
 import (
 	"context"
+	"encoding/json"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
@@ -52,6 +56,89 @@ func TestKVProxyRange(t *testing.T) {
 	client.Close()
 }
 
+func TestKVProxyCacheAdmin(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	admin := grpcproxy.NewCacheAdmin([]string{"foo/"})
+	kvts := newKVProxyServerWithCacheAdmin([]string{clus.Members[0].GRPCURL()}, admin, t)
+	defer kvts.close()
+
+	mux := http.NewServeMux()
+	grpcproxy.HandleCacheAdmin(mux, admin)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client, err := integration2.NewClient(t, clientv3.Config{
+		Endpoints:   []string{kvts.l.Addr().String()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err = client.Put(ctx, "foo/a", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	// a miss then a hit under foo/, a miss under no tracked prefix
+	for _, key := range []string{"foo/a", "foo/a", "baz"} {
+		if _, err = client.Get(ctx, key, clientv3.WithSerializable()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var st grpcproxy.CacheStats
+	getCacheAdminJSON(t, http.MethodGet, srv.URL+grpcproxy.PathProxyCache, &st)
+	wst := grpcproxy.CacheStats{
+		Entries: 2,
+		Hits:    1,
+		Misses:  2,
+		Prefixes: []grpcproxy.CachePrefixStats{
+			{Prefix: "", Hits: 0, Misses: 1},
+			{Prefix: "foo/", Hits: 1, Misses: 1},
+		},
+	}
+	if !reflect.DeepEqual(st, wst) {
+		t.Fatalf("stats = %+v, want %+v", st, wst)
+	}
+
+	var fr grpcproxy.CacheFlushResponse
+	getCacheAdminJSON(t, http.MethodPost, srv.URL+grpcproxy.PathProxyCacheFlush+"?prefix=foo/", &fr)
+	if fr.Flushed != 1 {
+		t.Fatalf("flushed = %d, want 1", fr.Flushed)
+	}
+	getCacheAdminJSON(t, http.MethodGet, srv.URL+grpcproxy.PathProxyCache, &st)
+	if st.Entries != 1 {
+		t.Fatalf("entries = %d, want 1 after flushing foo/", st.Entries)
+	}
+	getCacheAdminJSON(t, http.MethodPost, srv.URL+grpcproxy.PathProxyCacheFlush, &fr)
+	if fr.Flushed != 1 {
+		t.Fatalf("flushed = %d, want 1 flushing the whole cache", fr.Flushed)
+	}
+}
+
+func getCacheAdminJSON(t *testing.T, method, url string, v interface{}) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: status = %d, want %d", method, url, resp.StatusCode, http.StatusOK)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
 type kvproxyTestServer struct {
 	kp     pb.KVServer
 	c      *clientv3.Client
@@ -66,6 +153,10 @@ func (kts *kvproxyTestServer) close() {
 }
 
 func newKVProxyServer(endpoints []string, t *testing.T) *kvproxyTestServer {
+	return newKVProxyServerWithCacheAdmin(endpoints, nil, t)
+}
+
+func newKVProxyServerWithCacheAdmin(endpoints []string, admin *grpcproxy.CacheAdmin, t *testing.T) *kvproxyTestServer {
 	cfg := clientv3.Config{
 		Endpoints:   endpoints,
 		DialTimeout: 5 * time.Second,
@@ -75,7 +166,7 @@ func newKVProxyServer(endpoints []string, t *testing.T) *kvproxyTestServer {
 		t.Fatal(err)
 	}
 
-	kvp, _ := grpcproxy.NewKvProxy(client)
+	kvp, _ := grpcproxy.NewKvProxyWithCacheAdmin(client, admin)
 
 	kvts := &kvproxyTestServer{
 		kp: kvp,
@@ -0,0 +1,195 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcproxy
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/proxy/grpcproxy"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// the TLS infos of the fixtures, resolved from the package directory before
+// the tests change their working directories.
+var (
+	testTLSInfo = transport.TLSInfo{
+		KeyFile:        integration2.MustAbsPath("../../../fixtures/server.key.insecure"),
+		CertFile:       integration2.MustAbsPath("../../../fixtures/server.crt"),
+		TrustedCAFile:  integration2.MustAbsPath("../../../fixtures/ca.crt"),
+		ClientCertAuth: true,
+	}
+	testTLSInfoNoCN = transport.TLSInfo{
+		KeyFile:       integration2.MustAbsPath("../../../fixtures/client-nocn.key.insecure"),
+		CertFile:      integration2.MustAbsPath("../../../fixtures/client-nocn.crt"),
+		TrustedCAFile: integration2.MustAbsPath("../../../fixtures/ca.crt"),
+	}
+)
+
+func TestTenantProxyFromHeader(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := serveTenantProxy(t, clus.Client(0), l, grpcproxy.TenantFromHeader("x-tenant"))
+	defer stop()
+
+	client, err := integration2.NewClient(t, clientv3.Config{
+		Endpoints:   []string{l.Addr().String()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	actx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "a")
+	bctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "b")
+
+	wctx, wcancel := context.WithCancel(bctx)
+	defer wcancel()
+	wch := client.Watch(wctx, "foo", clientv3.WithPrefix(), clientv3.WithCreatedNotify())
+	if wresp := <-wch; !wresp.Created {
+		t.Fatalf("watch response = %+v, want created", wresp)
+	}
+
+	if _, err = client.Put(actx, "foo", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Put(bctx, "foo", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	for ctx, want := range map[context.Context]string{actx: "a", bctx: "b"} {
+		resp, gerr := client.Get(ctx, "foo", clientv3.WithPrefix())
+		if gerr != nil {
+			t.Fatal(gerr)
+		}
+		if len(resp.Kvs) != 1 || string(resp.Kvs[0].Key) != "foo" || string(resp.Kvs[0].Value) != want {
+			t.Errorf("kvs = %+v, want foo=%s only", resp.Kvs, want)
+		}
+	}
+
+	select {
+	case wresp := <-wch:
+		if len(wresp.Events) != 1 || string(wresp.Events[0].Kv.Key) != "foo" || string(wresp.Events[0].Kv.Value) != "b" {
+			t.Errorf("watch events = %+v, want the put of foo=b only", wresp.Events)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the watch event")
+	}
+
+	for key, want := range map[string]string{"a/foo": "a", "b/foo": "b"} {
+		resp, gerr := clus.Client(0).Get(context.Background(), key)
+		if gerr != nil {
+			t.Fatal(gerr)
+		}
+		if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != want {
+			t.Errorf("kvs of %q = %+v, want %s", key, resp.Kvs, want)
+		}
+	}
+
+	if _, err = client.Get(context.Background(), "foo"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("error without tenant = %v, want code %v", err, codes.Unauthenticated)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "a/../b")
+	if _, err = client.Get(ctx, "foo"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("error of invalid tenant = %v, want code %v", err, codes.PermissionDenied)
+	}
+}
+
+func TestTenantProxyFromCertCN(t *testing.T) {
+	integration2.BeforeTest(t)
+
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsinfo := testTLSInfo
+	if l, err = transport.NewTLSListener(l, &tlsinfo); err != nil {
+		t.Fatal(err)
+	}
+	stop := serveTenantProxy(t, clus.Client(0), l, grpcproxy.TenantFromCertCN, grpc.Creds(grpcproxy.TenantCredentials()))
+	defer stop()
+
+	newClient := func(info transport.TLSInfo) *clientv3.Client {
+		tlscfg, cerr := info.ClientConfig()
+		if cerr != nil {
+			t.Fatal(cerr)
+		}
+		c, cerr := integration2.NewClient(t, clientv3.Config{
+			Endpoints:   []string{l.Addr().String()},
+			DialTimeout: 5 * time.Second,
+			TLS:         tlscfg,
+		})
+		if cerr != nil {
+			t.Fatal(cerr)
+		}
+		return c
+	}
+
+	client := newClient(tlsinfo)
+	defer client.Close()
+	if _, err = client.Put(context.Background(), "foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	// the common name of the client certificate is example.com
+	resp, err := clus.Client(0).Get(context.Background(), "example.com/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "bar" {
+		t.Errorf("kvs = %+v, want example.com/foo=bar", resp.Kvs)
+	}
+
+	nocn := newClient(testTLSInfoNoCN)
+	defer nocn.Close()
+	if _, err = nocn.Get(context.Background(), "foo"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("error without common name = %v, want code %v", err, codes.Unauthenticated)
+	}
+}
+
+// serveTenantProxy serves the tenant proxy of c on l, returning the func
+// stopping it.
+func serveTenantProxy(t *testing.T, c *clientv3.Client, l net.Listener, tenant grpcproxy.TenantFunc, opts ...grpc.ServerOption) func() {
+	tp := grpcproxy.NewTenantProxy(zaptest.NewLogger(t), c, "", tenant)
+	srv := grpc.NewServer(opts...)
+	pb.RegisterKVServer(srv, tp)
+	pb.RegisterWatchServer(srv, tp)
+	pb.RegisterLeaseServer(srv, tp)
+	go srv.Serve(l)
+	return func() {
+		srv.Stop()
+		l.Close()
+	}
+}
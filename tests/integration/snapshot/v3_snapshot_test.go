@@ -15,17 +15,23 @@
 package snapshot_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"go.etcd.io/etcd/client/pkg/v3/testutil"
 	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/snapshot/storage"
 	"go.etcd.io/etcd/etcdutl/v3/snapshot"
 	"go.etcd.io/etcd/server/v3/embed"
 	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
@@ -136,6 +142,135 @@ func TestSnapshotV3RestoreMulti(t *testing.T) {
 	}
 }
 
+// memStorage is an object storage holding the objects in memory.
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *memStorage) Put(_ context.Context, name string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[name] = b
+	return nil
+}
+
+func (s *memStorage) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.objects[name]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// TestSnapshotV3RestoreStorage ensures that the snapshots saved to an object
+// storage can be restored from it, and not once tampered with.
+func TestSnapshotV3RestoreStorage(t *testing.T) {
+	integration2.BeforeTest(t)
+	mem := &memStorage{objects: make(map[string][]byte)}
+	storage.Register("mem", func(bucket string, _ storage.Options) (storage.Storage, error) {
+		if bucket != "snapshots" {
+			return nil, fmt.Errorf("unknown bucket %q", bucket)
+		}
+		return mem, nil
+	})
+
+	kvs := []kv{{"foo1", "bar1"}, {"foo2", "bar2"}, {"foo3", "bar3"}}
+	createSnapshot(t, kvs, "mem://snapshots/backup/snapshot.db")
+	if _, ok := mem.objects["backup/snapshot.db"+storage.ChecksumSuffix]; !ok {
+		t.Fatal("the checksum of the snapshot is not saved")
+	}
+
+	cURLs, _, srvs := restoreCluster(t, 1, "mem://snapshots/backup/snapshot.db")
+	defer srvs[0].Close()
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: []string{cURLs[0].String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	for i := range kvs {
+		gresp, err := cli.Get(context.Background(), kvs[i].k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(gresp.Kvs[0].Value) != kvs[i].v {
+			t.Fatalf("#%d: value expected %s, got %s", i, kvs[i].v, string(gresp.Kvs[0].Value))
+		}
+	}
+
+	mem.objects["backup/snapshot.db"][0] ^= 0xff
+	sp := snapshot.NewV3(zaptest.NewLogger(t))
+	err = sp.Restore(snapshot.RestoreConfig{
+		SnapshotPath:        "mem://snapshots/backup/snapshot.db",
+		Name:                "m0",
+		OutputDataDir:       filepath.Join(t.TempDir(), "m0.etcd"),
+		PeerURLs:            []string{"http://localhost:2380"},
+		InitialCluster:      "m0=http://localhost:2380",
+		InitialClusterToken: testClusterTkn,
+	})
+	if !errors.Is(err, storage.ErrChecksumMismatch) {
+		t.Fatalf("restore of a tampered snapshot = %v, want %v", err, storage.ErrChecksumMismatch)
+	}
+}
+
+// TestSnapshotV3RestoreImport ensures that a member restored from a snapshot
+// of imported key-value pairs serves them at the revision of the import.
+func TestSnapshotV3RestoreImport(t *testing.T) {
+	integration2.BeforeTest(t)
+	kvs := []kv{{"foo1", "bar1"}, {"foo2", "bar2"}, {"foo3", "bar3"}}
+	var in bytes.Buffer
+	for _, kv := range kvs {
+		fmt.Fprintf(&in, `{"key":%q,"value":%q}`+"\n",
+			base64.StdEncoding.EncodeToString([]byte(kv.k)), base64.StdEncoding.EncodeToString([]byte(kv.v)))
+	}
+	dbPath := filepath.Join(t.TempDir(), "snapshot.db")
+	sp := snapshot.NewV3(zaptest.NewLogger(t))
+	n, err := sp.Import(&in, dbPath, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(kvs)) {
+		t.Fatalf("imported %d keys, want %d", n, len(kvs))
+	}
+
+	cURLs, _, srvs := restoreCluster(t, 1, dbPath)
+	defer srvs[0].Close()
+	cli, err := integration2.NewClient(t, clientv3.Config{Endpoints: []string{cURLs[0].String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	gresp, err := cli.Get(context.Background(), "foo", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gresp.Header.Revision != 1000 || len(gresp.Kvs) != len(kvs) {
+		t.Fatalf("got %d keys at revision %d, want %d at revision 1000", len(gresp.Kvs), gresp.Header.Revision, len(kvs))
+	}
+	for i, kv := range gresp.Kvs {
+		if string(kv.Key) != kvs[i].k || string(kv.Value) != kvs[i].v || kv.ModRevision != 1000 || kv.Version != 1 {
+			t.Errorf("#%d: got %+v, want %s=%s at revision 1000", i, kv, kvs[i].k, kvs[i].v)
+		}
+	}
+	presp, err := cli.Put(context.Background(), "foo1", "bar4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presp.Header.Revision != 1001 {
+		t.Errorf("revision of the put = %d, want 1001", presp.Header.Revision)
+	}
+
+	if _, err = sp.Import(strings.NewReader(`{"key":"Zm9v"}`), dbPath, 1); err == nil {
+		t.Error("import to an existing snapshot file succeeded")
+	}
+}
+
 // TestCorruptedBackupFileCheck tests if we can correctly identify a corrupted backup file.
 func TestCorruptedBackupFileCheck(t *testing.T) {
 	dbPath := integration2.MustAbsPath("testdata/corrupted_backup.db")
@@ -168,6 +303,13 @@ type kv struct {
 
 // creates a snapshot file and returns the file path.
 func createSnapshotFile(t *testing.T, kvs []kv) string {
+	dpPath := filepath.Join(t.TempDir(), fmt.Sprintf("snapshot%d.db", time.Now().Nanosecond()))
+	createSnapshot(t, kvs, dpPath)
+	return dpPath
+}
+
+// creates a snapshot at the file path or object URL dpPath.
+func createSnapshot(t *testing.T, kvs []kv, dpPath string) {
 	testutil.SkipTestIfShortMode(t,
 		"Snapshot creation tests are depending on embedded etcd server so are integration-level tests.")
 	clusterN := 1
@@ -208,12 +350,10 @@ func createSnapshotFile(t *testing.T, kvs []kv) string {
 	}
 
 	sp := snapshot.NewV3(zaptest.NewLogger(t))
-	dpPath := filepath.Join(t.TempDir(), fmt.Sprintf("snapshot%d.db", time.Now().Nanosecond()))
 	_, err = sp.Save(context.Background(), ccfg, dpPath)
 	if err != nil {
 		t.Fatal(err)
 	}
-	return dpPath
 }
 
 const testClusterTkn = "tkn"
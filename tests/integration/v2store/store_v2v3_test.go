@@ -0,0 +1,205 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2store_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/client/pkg/v3/testutil"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2error"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2store"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v2v3"
+	integration2 "go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+func newV2V3Store(t *testing.T) (v2store.Store, *integration2.Cluster) {
+	integration2.BeforeTest(t)
+	clus := integration2.NewCluster(t, &integration2.ClusterConfig{Size: 1})
+	return v2v3.NewStore(clus.Client(0), "/v2"), clus
+}
+
+func TestV2V3StoreGetSetDelete(t *testing.T) {
+	s, clus := newV2V3Store(t)
+	defer clus.Terminate(t)
+
+	permanent := v2store.TTLOptionSet{ExpireTime: v2store.Permanent}
+	e, err := s.Create("/foo/x", false, "bar", false, permanent)
+	testutil.AssertNil(t, err)
+	assert.Equal(t, "create", e.Action)
+	assert.Equal(t, "/foo/x", e.Node.Key)
+
+	_, err = s.Create("/foo/x", false, "baz", false, permanent)
+	assert.Equal(t, v2error.EcodeNodeExist, err.(*v2error.Error).ErrorCode)
+
+	_, err = s.Create("/foo/x/y", false, "baz", false, permanent)
+	assert.Equal(t, v2error.EcodeNotDir, err.(*v2error.Error).ErrorCode)
+
+	_, err = s.Set("/foo/y/z", false, "baz", permanent)
+	testutil.AssertNil(t, err)
+	_, err = s.Set("/foo/_hidden", false, "h", permanent)
+	testutil.AssertNil(t, err)
+
+	e, err = s.Get("/foo", true, true)
+	testutil.AssertNil(t, err)
+	assert.True(t, e.Node.Dir)
+	if assert.Len(t, e.Node.Nodes, 2) {
+		assert.Equal(t, "/foo/x", e.Node.Nodes[0].Key)
+		assert.Equal(t, "bar", *e.Node.Nodes[0].Value)
+		assert.Equal(t, "/foo/y", e.Node.Nodes[1].Key)
+		assert.True(t, e.Node.Nodes[1].Dir)
+		if assert.Len(t, e.Node.Nodes[1].Nodes, 1) {
+			assert.Equal(t, "/foo/y/z", e.Node.Nodes[1].Nodes[0].Key)
+		}
+	}
+
+	e, err = s.Update("/foo/x", "baz", permanent)
+	testutil.AssertNil(t, err)
+	assert.Equal(t, "bar", *e.PrevNode.Value)
+
+	_, err = s.Delete("/foo", false, false)
+	assert.Equal(t, v2error.EcodeNotFile, err.(*v2error.Error).ErrorCode)
+	_, err = s.Delete("/foo", true, false)
+	assert.Equal(t, v2error.EcodeDirNotEmpty, err.(*v2error.Error).ErrorCode)
+	_, err = s.Delete("/foo", true, true)
+	testutil.AssertNil(t, err)
+
+	_, err = s.Get("/foo/y/z", false, false)
+	assert.Equal(t, v2error.EcodeKeyNotFound, err.(*v2error.Error).ErrorCode)
+}
+
+func TestV2V3StoreCompareAndSwap(t *testing.T) {
+	s, clus := newV2V3Store(t)
+	defer clus.Terminate(t)
+
+	permanent := v2store.TTLOptionSet{ExpireTime: v2store.Permanent}
+	e, err := s.Create("/foo", false, "bar", false, permanent)
+	testutil.AssertNil(t, err)
+	idx := e.Node.ModifiedIndex
+
+	_, err = s.CompareAndSwap("/foo", "wrong", 0, "baz", permanent)
+	assert.Equal(t, v2error.EcodeTestFailed, err.(*v2error.Error).ErrorCode)
+	_, err = s.CompareAndSwap("/foo", "", idx+100, "baz", permanent)
+	assert.Equal(t, v2error.EcodeTestFailed, err.(*v2error.Error).ErrorCode)
+
+	e, err = s.CompareAndSwap("/foo", "bar", idx, "baz", permanent)
+	testutil.AssertNil(t, err)
+	assert.Equal(t, "compareAndSwap", e.Action)
+	assert.Equal(t, "baz", *e.Node.Value)
+
+	_, err = s.CompareAndDelete("/foo", "bar", 0)
+	assert.Equal(t, v2error.EcodeTestFailed, err.(*v2error.Error).ErrorCode)
+	e, err = s.CompareAndDelete("/foo", "baz", 0)
+	testutil.AssertNil(t, err)
+	assert.Equal(t, "compareAndDelete", e.Action)
+}
+
+func TestV2V3StoreCreateUnique(t *testing.T) {
+	s, clus := newV2V3Store(t)
+	defer clus.Terminate(t)
+
+	permanent := v2store.TTLOptionSet{ExpireTime: v2store.Permanent}
+	var keys []string
+	for i := 0; i < 3; i++ {
+		e, err := s.Create("/queue", false, "job", true, permanent)
+		testutil.AssertNil(t, err)
+		assert.True(t, strings.HasPrefix(e.Node.Key, "/queue/"))
+		keys = append(keys, e.Node.Key)
+	}
+	e, err := s.Get("/queue", false, true)
+	testutil.AssertNil(t, err)
+	if assert.Len(t, e.Node.Nodes, 3) {
+		for i, n := range e.Node.Nodes {
+			assert.Equal(t, keys[i], n.Key)
+		}
+	}
+}
+
+func TestV2V3StoreTTL(t *testing.T) {
+	s, clus := newV2V3Store(t)
+	defer clus.Terminate(t)
+
+	w, err := s.Watch("/foo", false, false, 0)
+	testutil.AssertNil(t, err)
+	defer w.Remove()
+
+	_, err = s.Create("/foo", false, "bar", false, v2store.TTLOptionSet{ExpireTime: time.Now().Add(time.Second)})
+	testutil.AssertNil(t, err)
+	e, err := s.Get("/foo", false, false)
+	testutil.AssertNil(t, err)
+	assert.True(t, e.Node.TTL > 0)
+
+	select {
+	case ev := <-w.EventChan():
+		assert.Equal(t, "create", ev.Action)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for create event")
+	}
+
+	w, err = s.Watch("/foo", false, false, 0)
+	testutil.AssertNil(t, err)
+	defer w.Remove()
+	select {
+	case ev := <-w.EventChan():
+		assert.Equal(t, "expire", ev.Action)
+		assert.Equal(t, "/foo", ev.Node.Key)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for expire event")
+	}
+}
+
+func TestV2V3StoreWatch(t *testing.T) {
+	s, clus := newV2V3Store(t)
+	defer clus.Terminate(t)
+
+	permanent := v2store.TTLOptionSet{ExpireTime: v2store.Permanent}
+	e, err := s.Create("/foo/x", false, "bar", false, permanent)
+	testutil.AssertNil(t, err)
+
+	// a watch from a past index replays the history
+	w, err := s.Watch("/foo", true, true, e.EtcdIndex)
+	testutil.AssertNil(t, err)
+	defer w.Remove()
+
+	_, err = s.Set("/foo/_hidden", false, "h", permanent)
+	testutil.AssertNil(t, err)
+	_, err = s.Update("/foo/x", "baz", permanent)
+	testutil.AssertNil(t, err)
+	_, err = s.Delete("/foo", true, true)
+	testutil.AssertNil(t, err)
+
+	for _, want := range []struct{ action, key string }{
+		{"create", "/foo/x"},
+		{"update", "/foo/x"},
+		{"delete", "/foo"},
+	} {
+		select {
+		case ev := <-w.EventChan():
+			assert.Equal(t, want.action, ev.Action)
+			assert.Equal(t, want.key, ev.Node.Key)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s event", want.action)
+		}
+	}
+
+	// a compacted index can no longer be watched
+	_, err = clus.Client(0).Compact(context.TODO(), int64(s.Index()))
+	testutil.AssertNil(t, err)
+	_, err = s.Watch("/foo", true, false, e.EtcdIndex)
+	assert.Equal(t, v2error.EcodeEventIndexCleared, err.(*v2error.Error).ErrorCode)
+}
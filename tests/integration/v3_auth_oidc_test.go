@@ -0,0 +1,134 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"google.golang.org/grpc/metadata"
+
+	"go.etcd.io/etcd/api/v3/authpb"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.etcd.io/etcd/tests/v3/framework/integration"
+)
+
+// TestV3AuthOIDC ensures the OIDC tokens have the permissions of the roles
+// their claims are mapped to, on all the members, while the etcd users still
+// authenticate.
+func TestV3AuthOIDC(t *testing.T) {
+	integration.BeforeTest(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": srv.URL, "jwks_uri": srv.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		enc := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "k1", "n": enc(key.N), "e": enc(big.NewInt(int64(key.E)))},
+		}})
+	})
+
+	dir := t.TempDir()
+	caFile, mappingFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "role-mapping.json")
+	if err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(mappingFile, []byte(`{"groups": {"team-a": ["team-a-rw"]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:      3,
+		AuthToken: fmt.Sprintf("oidc,issuer=%s,audience=etcd,ca-file=%s,role-mapping=%s", srv.URL, caFile, mappingFile),
+	})
+	defer clus.Terminate(t)
+
+	api := integration.ToGRPC(clus.Client(0))
+	if _, err = api.Auth.RoleAdd(context.TODO(), &pb.AuthRoleAddRequest{Name: "team-a-rw"}); err != nil {
+		t.Fatal(err)
+	}
+	perm := &authpb.Permission{PermType: authpb.READWRITE, Key: []byte("team-a/"), RangeEnd: []byte("team-a0")}
+	if _, err = api.Auth.RoleGrantPermission(context.TODO(), &pb.AuthRoleGrantPermissionRequest{Name: "team-a-rw", Perm: perm}); err != nil {
+		t.Fatal(err)
+	}
+	authSetupRoot(t, api.Auth)
+
+	token := func(groups ...string) string {
+		tk := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":    srv.URL,
+			"aud":    "etcd",
+			"sub":    "alice",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"groups": groups,
+		})
+		tk.Header["kid"] = "k1"
+		s, serr := tk.SignedString(key)
+		if serr != nil {
+			t.Fatal(serr)
+		}
+		return s
+	}
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.TODO(), rpctypes.TokenFieldNameGRPC, token)
+	}
+
+	teamA := withToken(token("team-a"))
+	for i := range clus.Members {
+		kv := integration.ToGRPC(clus.Client(i)).KV
+		if _, err = kv.Put(teamA, &pb.PutRequest{Key: []byte(fmt.Sprintf("team-a/%d", i)), Value: []byte("v")}); err != nil {
+			t.Fatalf("#%d: put with the role permission = %v, want nil", i, err)
+		}
+		if _, err = kv.Range(teamA, &pb.RangeRequest{Key: []byte("team-a/"), RangeEnd: []byte("team-a0"), Serializable: true}); err != nil {
+			t.Fatalf("#%d: range with the role permission = %v, want nil", i, err)
+		}
+		if _, err = kv.Put(teamA, &pb.PutRequest{Key: []byte("team-b/k"), Value: []byte("v")}); !eqErrGRPC(err, rpctypes.ErrGRPCPermissionDenied) {
+			t.Fatalf("#%d: put without the role permission = %v, want %v", i, err, rpctypes.ErrGRPCPermissionDenied)
+		}
+	}
+
+	if _, err = api.KV.Put(withToken(token("team-b")), &pb.PutRequest{Key: []byte("team-a/k"), Value: []byte("v")}); !eqErrGRPC(err, rpctypes.ErrGRPCInvalidAuthToken) {
+		t.Fatalf("put with a token mapped to no role = %v, want %v", err, rpctypes.ErrGRPCInvalidAuthToken)
+	}
+
+	resp, err := api.Auth.Authenticate(context.TODO(), &pb.AuthenticateRequest{Name: "root", Password: "123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = api.KV.Put(withToken(resp.Token), &pb.PutRequest{Key: []byte("team-b/k"), Value: []byte("v")}); err != nil {
+		t.Fatalf("put of root = %v, want nil", err)
+	}
+}
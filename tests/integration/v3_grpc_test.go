@@ -22,6 +22,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -34,7 +35,9 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
 	"google.golang.org/grpc/status"
 )
 
@@ -153,6 +156,44 @@ func TestV3CompactCurrentRev(t *testing.T) {
 	}
 }
 
+// TestV3CompactHistoryRetention ensures the history retained by the retention
+// rules is read at the compacted revisions on all the members.
+func TestV3CompactHistoryRetention(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, HistoryRetention: []string{"audit/=2"}})
+	defer clus.Terminate(t)
+
+	kvc := integration.ToGRPC(clus.RandClient()).KV
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"audit/k", "tmp/k"} {
+			if _, err := kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte(key), Value: []byte(fmt.Sprint(i))}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// the revisions of audit/k are 2, 4 and 6, the ones of tmp/k 3, 5 and 7
+	if _, err := kvc.Compact(context.TODO(), &pb.CompactionRequest{Revision: 7, Physical: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range clus.Members {
+		kvc := integration.ToGRPC(clus.Client(i)).KV
+		resp, err := kvc.Range(context.TODO(), &pb.RangeRequest{Key: []byte("audit/k"), Revision: 5, Serializable: true})
+		if err != nil {
+			t.Fatalf("#%d: range of the retained history = %v, want nil", i, err)
+		}
+		if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != "1" {
+			t.Fatalf("#%d: range of the retained history = %v, want the value 1", i, resp.Kvs)
+		}
+		if _, err = kvc.Range(context.TODO(), &pb.RangeRequest{Key: []byte("audit/k"), Revision: 3, Serializable: true}); !eqErrGRPC(err, rpctypes.ErrGRPCCompacted) {
+			t.Fatalf("#%d: range of a compacted version = %v, want %v", i, err, rpctypes.ErrGRPCCompacted)
+		}
+		if _, err = kvc.Range(context.TODO(), &pb.RangeRequest{Key: []byte("tmp/k"), Revision: 5, Serializable: true}); !eqErrGRPC(err, rpctypes.ErrGRPCCompacted) {
+			t.Fatalf("#%d: range of a key without retention = %v, want %v", i, err, rpctypes.ErrGRPCCompacted)
+		}
+	}
+}
+
 // TestV3HashKV ensures that multiple calls of HashKV on same node return same hash and compact rev.
 func TestV3HashKV(t *testing.T) {
 	integration.BeforeTest(t)
@@ -613,6 +654,69 @@ func TestV3TxnRangeCompare(t *testing.T) {
 	}
 }
 
+// TestV3TxnFencingTokenCompare tests fencing token comparisons in txns
+func TestV3TxnFencingTokenCompare(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	cli := clus.Client(0)
+	ctx := context.TODO()
+	put := func() clientv3.FencingToken {
+		lresp, err := cli.Grant(ctx, 60)
+		if err != nil {
+			t.Fatal(err)
+		}
+		presp, err := cli.Put(ctx, "lock", "x", clientv3.WithLease(lresp.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return clientv3.FencingToken{Lease: lresp.ID, CreateRevision: presp.Header.Revision}
+	}
+	held := put()
+	// the key is deleted with the lease and created again by another holder
+	if _, err := cli.Revoke(ctx, held.Lease); err != nil {
+		t.Fatal(err)
+	}
+	stale, held := held, put()
+
+	tests := []struct {
+		op  string
+		tok clientv3.FencingToken
+
+		wSuccess bool
+	}{
+		{"=", held, true},
+		{"=", stale, false},
+		{"!=", stale, true},
+		{"=", clientv3.FencingToken{Lease: held.Lease, CreateRevision: stale.CreateRevision}, false},
+		{"=", clientv3.FencingToken{Lease: stale.Lease, CreateRevision: held.CreateRevision}, false},
+		{">", stale, true},
+		{"<", stale, false},
+	}
+	for i, tt := range tests {
+		tresp, err := cli.Txn(ctx).If(clientv3.Compare(clientv3.FencingTokenValue("lock"), tt.op, tt.tok)).Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.wSuccess != tresp.Succeeded {
+			t.Errorf("#%d: expected %v, got %v", i, tt.wSuccess, tresp.Succeeded)
+		}
+	}
+
+	// no token is held once the key is deleted
+	if _, err := cli.Delete(ctx, "lock"); err != nil {
+		t.Fatal(err)
+	}
+	tresp, err := cli.Txn(ctx).If(clientv3.Compare(clientv3.FencingTokenValue("lock"), "=", held)).Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tresp.Succeeded {
+		t.Errorf("expected the token of the deleted key to fail the comparison")
+	}
+}
+
 // TestV3TxnNested tests nested txns follow paths as expected.
 func TestV3TxnNestedPath(t *testing.T) {
 	integration.BeforeTest(t)
@@ -1270,6 +1374,10 @@ func TestV3StorageQuotaAPI(t *testing.T) {
 	if !eqErrGRPC(err, rpctypes.ErrGRPCNoSpace) {
 		t.Fatalf("big put got %v, expected %v", err, rpctypes.ErrGRPCNoSpace)
 	}
+	info, ok := rpctypes.ErrorInfo(err)
+	if !ok || info.Reason != rpctypes.ReasonNoSpace || info.Metadata[rpctypes.MetadataQuotaBytes] != fmt.Sprint(quotasize) {
+		t.Fatalf("big put error info = %v, want the reason %s with the quota of %d bytes", info, rpctypes.ReasonNoSpace, quotasize)
+	}
 
 	// test big txn
 	puttxn := &pb.RequestOp{
@@ -1288,6 +1396,43 @@ func TestV3StorageQuotaAPI(t *testing.T) {
 	}
 }
 
+// TestV3ErrorDetailsCompacted ensures the ranges of a compacted revision fail
+// with the compact revision in the details of the error.
+func TestV3ErrorDetailsCompacted(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	kvc := integration.ToGRPC(clus.RandClient()).KV
+	for i := 0; i < 3; i++ {
+		if _, err := kvc.Put(context.TODO(), &pb.PutRequest{Key: []byte("foo"), Value: []byte("bar")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := kvc.Compact(context.TODO(), &pb.CompactionRequest{Revision: 3, Physical: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := kvc.Range(context.TODO(), &pb.RangeRequest{Key: []byte("foo"), Revision: 2})
+	if !eqErrGRPC(err, rpctypes.ErrGRPCCompacted) {
+		t.Fatalf("range got %v, expected %v", err, rpctypes.ErrGRPCCompacted)
+	}
+	info, ok := rpctypes.ErrorInfo(err)
+	if !ok {
+		t.Fatalf("range error %v without error info", err)
+	}
+	if info.Reason != rpctypes.ReasonCompacted || info.Domain != rpctypes.ErrorDomain {
+		t.Errorf("error info reason = %s/%s, want %s/%s", info.Domain, info.Reason, rpctypes.ErrorDomain, rpctypes.ReasonCompacted)
+	}
+	if info.Metadata[rpctypes.MetadataCompactRevision] != "3" || info.Metadata[rpctypes.MetadataCurrentRevision] != "4" {
+		t.Errorf("error info metadata = %v, want the compact revision 3 and the current revision 4", info.Metadata)
+	}
+	if _, ok = rpctypes.RetryDelay(err); ok {
+		t.Errorf("compacted error with a retry delay")
+	}
+}
+
 func TestV3RangeRequest(t *testing.T) {
 	integration.BeforeTest(t)
 	tests := []struct {
@@ -1828,6 +1973,95 @@ func TestGRPCRequireLeader(t *testing.T) {
 	}
 }
 
+// TestGRPCServiceHealth ensures the services needing a leader are reported
+// not serving by the gRPC health service once the member loses its leader.
+func TestGRPCServiceHealth(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3})
+	defer clus.Terminate(t)
+
+	client, err := integration.NewClientV3(clus.Members[0])
+	if err != nil {
+		t.Fatalf("cannot create client: %v", err)
+	}
+	defer client.Close()
+	hc := healthpb.NewHealthClient(client.ActiveConnection())
+
+	expectHealth := func(want map[string]healthpb.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		var got map[string]healthpb.HealthCheckResponse_ServingStatus
+		for i := 0; i < 50; i++ {
+			got = make(map[string]healthpb.HealthCheckResponse_ServingStatus)
+			for service := range want {
+				resp, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+				if err != nil {
+					t.Fatalf("health check of %q failed: %v", service, err)
+				}
+				got[service] = resp.Status
+			}
+			if reflect.DeepEqual(got, want) {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("health = %v, want %v", got, want)
+	}
+
+	expectHealth(map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"":                         healthpb.HealthCheckResponse_SERVING,
+		"etcdserverpb.KV":          healthpb.HealthCheckResponse_SERVING,
+		"etcdserverpb.Watch":       healthpb.HealthCheckResponse_SERVING,
+		"etcdserverpb.Lease":       healthpb.HealthCheckResponse_SERVING,
+		"etcdserverpb.Cluster":     healthpb.HealthCheckResponse_SERVING,
+		"etcdserverpb.Auth":        healthpb.HealthCheckResponse_SERVING,
+		"etcdserverpb.Maintenance": healthpb.HealthCheckResponse_SERVING,
+	})
+
+	clus.Members[1].Stop(t)
+	clus.Members[2].Stop(t)
+
+	expectHealth(map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"":                         healthpb.HealthCheckResponse_SERVING,
+		"etcdserverpb.KV":          healthpb.HealthCheckResponse_NOT_SERVING,
+		"etcdserverpb.Watch":       healthpb.HealthCheckResponse_SERVING,
+		"etcdserverpb.Lease":       healthpb.HealthCheckResponse_NOT_SERVING,
+		"etcdserverpb.Cluster":     healthpb.HealthCheckResponse_NOT_SERVING,
+		"etcdserverpb.Auth":        healthpb.HealthCheckResponse_NOT_SERVING,
+		"etcdserverpb.Maintenance": healthpb.HealthCheckResponse_SERVING,
+	})
+}
+
+// TestGRPCServiceHealthRemovedMember ensures the health of the services is
+// checked on a member restarted once removed from the cluster until it stops.
+func TestGRPCServiceHealthRemovedMember(t *testing.T) {
+	integration.BeforeTest(t)
+
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 3, DisableStrictReconfigCheck: true})
+	defer clus.Terminate(t)
+
+	removed := clus.Members[0]
+	removed.KeepDataDirTerminate = true
+	if err := clus.RemoveMember(t, clus.Members[1].Client, uint64(removed.Server.MemberId())); err != nil {
+		t.Fatalf("failed to remove member: %v", err)
+	}
+	clus.WaitLeader(t)
+
+	// the member recovers the membership without itself from its data
+	if err := removed.Restart(t); err != nil {
+		t.Fatalf("failed to restart the removed member: %v", err)
+	}
+	defer func() {
+		removed.Close()
+		os.RemoveAll(removed.ServerConfig.DataDir)
+	}()
+	select {
+	case <-removed.Server.StopNotify():
+	case <-time.After(time.Minute):
+		t.Fatalf("removed member didn't exit within %v", time.Minute)
+	}
+}
+
 func TestGRPCStreamRequireLeader(t *testing.T) {
 	integration.BeforeTest(t)
 
@@ -1953,3 +2187,122 @@ func waitForRestart(t *testing.T, kvc pb.KVClient) {
 		t.Fatalf("timed out waiting for restart: %v", err)
 	}
 }
+
+// TestV3CompressedRequests ensures that the server serves clients
+// compressing their requests with the registered compressors.
+func TestV3CompressedRequests(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1})
+	defer clus.Terminate(t)
+
+	val := strings.Repeat("a", 4096)
+	if _, err := clus.Client(0).Put(context.TODO(), "foo", val); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"gzip", "zstd"} {
+		t.Run(name, func(t *testing.T) {
+			cli, err := integration.NewClient(t, clientv3.Config{
+				Endpoints:   clus.Client(0).Endpoints(),
+				DialOptions: []grpc.DialOption{grpc.WithDefaultCallOptions(grpc.UseCompressor(name))},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cli.Close()
+
+			resp, err := cli.Get(context.TODO(), "foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != val {
+				t.Fatalf("unexpected range response %+v", resp)
+			}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			wch := cli.Watch(ctx, "foo", clientv3.WithRev(resp.Kvs[0].ModRevision))
+			select {
+			case wresp := <-wch:
+				if len(wresp.Events) != 1 || string(wresp.Events[0].Kv.Value) != val {
+					t.Fatalf("unexpected watch response %+v", wresp)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for watch response")
+			}
+		})
+	}
+}
+
+// TestV3ResponseCompression ensures that the responses above the compression
+// threshold are compressed with the configured compressor, whatever the
+// compression of the requests.
+func TestV3ResponseCompression(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1, GRPCResponseCompression: "zstd"})
+	defer clus.Terminate(t)
+
+	sh := &payloadStatsHandler{}
+	cli, err := integration.NewClient(t, clientv3.Config{
+		Endpoints:   clus.Client(0).Endpoints(),
+		DialOptions: []grpc.DialOption{grpc.WithStatsHandler(sh)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	large, small := strings.Repeat("a", 64*1024), "a"
+	for _, tt := range []struct {
+		val        string
+		compressed bool
+	}{
+		{large, true},
+		{small, false},
+	} {
+		if _, err = cli.Put(context.TODO(), "foo", tt.val); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := cli.Get(context.TODO(), "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Kvs) != 1 || string(resp.Kvs[0].Value) != tt.val {
+			t.Fatalf("unexpected range response %+v", resp)
+		}
+		in := sh.last()
+		if compressed := in.WireLength < in.Length; compressed != tt.compressed {
+			t.Errorf("range response of %d bytes sent in %d bytes, compressed = %v, want %v",
+				in.Length, in.WireLength, compressed, tt.compressed)
+		}
+	}
+}
+
+// payloadStatsHandler records the last payload received by the client.
+type payloadStatsHandler struct {
+	mu sync.Mutex
+	in stats.InPayload
+}
+
+func (h *payloadStatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h *payloadStatsHandler) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if in, ok := s.(*stats.InPayload); ok {
+		h.mu.Lock()
+		h.in = *in
+		h.mu.Unlock()
+	}
+}
+
+func (h *payloadStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *payloadStatsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+func (h *payloadStatsHandler) last() stats.InPayload {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.in
+}
@@ -424,6 +424,59 @@ func TestV3LeaseCheckpoint(t *testing.T) {
 	}
 }
 
+// TestV3LeaseCheckpointCountedDownOnLeaderChange ensures the new leader counts down the remaining TTL
+// of a lease from its last checkpoint, not to jump back to the checkpointed value on leader change.
+func TestV3LeaseCheckpointCountedDownOnLeaderChange(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{
+		Size:                    3,
+		EnableLeaseCheckpoint:   true,
+		LeaseCheckpointInterval: 10 * time.Second,
+	})
+	defer clus.Terminate(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := integration.ToGRPC(clus.RandClient())
+	lresp, err := c.Lease.LeaseGrant(ctx, &pb.LeaseGrantRequest{TTL: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lease is checkpointed once, 10s after its grant, with the 10s remaining
+	time.Sleep(14 * time.Second)
+
+	// Force a leader election
+	leaderId := clus.WaitLeader(t)
+	leader := clus.Members[leaderId]
+	leader.Stop(t)
+	time.Sleep(time.Duration(3*integration.ElectionTicks) * framecfg.TickDuration)
+	leader.Restart(t)
+
+	newLeaderId := clus.WaitLeader(t)
+	c2 := integration.ToGRPC(clus.Client(newLeaderId))
+
+	var ttlresp *pb.LeaseTimeToLiveResponse
+	for i := 0; i < 10; i++ {
+		if ttlresp, err = c2.Lease.LeaseTimeToLive(ctx, &pb.LeaseTimeToLiveRequest{ID: lresp.ID}); err == nil {
+			break
+		}
+		if status, ok := status.FromError(err); ok && status.Code() == codes.Unavailable {
+			time.Sleep(time.Millisecond * 250)
+		} else {
+			t.Fatal(err)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the 10s checkpointed less the 4s elapsed since
+	if ttlresp.TTL <= 0 || ttlresp.TTL > 6 {
+		t.Errorf("Expected lease ttl (%vs) to be in (0s, 6s]", ttlresp.TTL)
+	}
+}
+
 // TestV3LeaseExists creates a lease on a random client and confirms it exists in the cluster.
 func TestV3LeaseExists(t *testing.T) {
 	integration.BeforeTest(t)
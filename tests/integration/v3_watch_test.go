@@ -26,8 +26,11 @@ import (
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/server/v3/etcdserver/api/v3rpc"
 	"go.etcd.io/etcd/tests/v3/framework/integration"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // TestV3WatchFromCurrentRevision tests Watch APIs from current revision.
@@ -1290,3 +1293,52 @@ func TestV3WatchCloseCancelRace(t *testing.T) {
 		t.Fatalf("expected %s watch, got %s", expected, minWatches)
 	}
 }
+
+// TestV3WatchMaxStreamAge ensures the streams exceeding their maximum age are
+// closed once the progress of their watches sent.
+func TestV3WatchMaxStreamAge(t *testing.T) {
+	integration.BeforeTest(t)
+	clus := integration.NewCluster(t, &integration.ClusterConfig{Size: 1, WatchMaxStreamAge: time.Second})
+	defer clus.Terminate(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	wStream, err := integration.ToGRPC(clus.Client(0)).Watch.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wreq := &pb.WatchRequest{RequestUnion: &pb.WatchRequest_CreateRequest{
+		CreateRequest: &pb.WatchCreateRequest{Key: []byte("foo")}}}
+	if err = wStream.Send(wreq); err != nil {
+		t.Fatal(err)
+	}
+	if resp, rerr := wStream.Recv(); rerr != nil || !resp.Created {
+		t.Fatalf("resp = %+v, err = %v, want created", resp, rerr)
+	}
+
+	kvc := integration.ToGRPC(clus.Client(0)).KV
+	var rev int64
+	for i := 0; i < 3; i++ {
+		presp, perr := kvc.Put(context.Background(), &pb.PutRequest{Key: []byte("bar"), Value: []byte("baz")})
+		if perr != nil {
+			t.Fatal(perr)
+		}
+		rev = presp.Header.Revision
+	}
+
+	resp, err := wStream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Events) != 0 || resp.Header.Revision != rev {
+		t.Fatalf("resp = %+v, want the progress at revision %d", resp, rev)
+	}
+	_, err = wStream.Recv()
+	if status.Code(err) != codes.Unavailable || rpctypes.ErrorDesc(err) != rpctypes.ErrorDesc(rpctypes.ErrGRPCWatchStreamExpired) {
+		t.Fatalf("err = %v, want %v", err, rpctypes.ErrGRPCWatchStreamExpired)
+	}
+	info, ok := rpctypes.ErrorInfo(err)
+	if !ok || info.Reason != rpctypes.ReasonWatchStreamExpired || info.Metadata[rpctypes.MetadataCurrentRevision] != fmt.Sprint(rev) {
+		t.Errorf("error info = %+v, want reason %s at revision %d", info, rpctypes.ReasonWatchStreamExpired, rev)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"go.etcd.io/etcd/pkg/v3/report"
+)
+
+// metricReport reports the results of a metric of a benchmark, e.g. the
+// latency of the grants of the leases, printed and exported.
+type metricReport struct {
+	metric  string
+	results chan report.Result
+	printc  <-chan string
+	statsc  <-chan report.Stats
+}
+
+func newMetricReport(metric string) *metricReport {
+	printed, exported := newReport(), report.NewReport("%g")
+	m := &metricReport{
+		metric:  metric,
+		results: make(chan report.Result, 16),
+		printc:  printed.Run(),
+		statsc:  exported.Stats(),
+	}
+	go func() {
+		for res := range m.results {
+			printed.Results() <- res
+			exported.Results() <- res
+		}
+		close(printed.Results())
+		close(exported.Results())
+	}()
+	return m
+}
+
+func (m *metricReport) Results() chan<- report.Result { return m.results }
+
+// mustValidateExport exits if the results are to be exported in an unknown
+// format, before the benchmark runs.
+func mustValidateExport() {
+	if exportPath != "" && exportFormat != "json" && exportFormat != "csv" {
+		fmt.Fprintf(os.Stderr, "unknown export format %q, expected 'json' or 'csv'\n", exportFormat)
+		os.Exit(1)
+	}
+}
+
+// exportedMetric is the summary of a metric of a benchmark exported, the
+// durations being in seconds.
+type exportedMetric struct {
+	Benchmark     string             `json:"benchmark"`
+	Metric        string             `json:"metric"`
+	ServerVersion string             `json:"server_version"`
+	Time          time.Time          `json:"time"`
+	Count         int                `json:"count"`
+	Errors        int                `json:"errors"`
+	RPS           float64            `json:"rps"`
+	Average       float64            `json:"average"`
+	Stddev        float64            `json:"stddev"`
+	Fastest       float64            `json:"fastest"`
+	Slowest       float64            `json:"slowest"`
+	Percentiles   map[string]float64 `json:"percentiles"`
+}
+
+// printAndExport prints the reports of the benchmark once their results are
+// closed, and exports them if configured.
+func printAndExport(benchmark string, reports ...*metricReport) {
+	metrics := make([]exportedMetric, 0, len(reports))
+	for _, m := range reports {
+		fmt.Printf("\n%s:%s", m.metric, <-m.printc)
+		metrics = append(metrics, newExportedMetric(benchmark, m.metric, <-m.statsc))
+	}
+	if exportPath == "" {
+		return
+	}
+	version := serverVersion()
+	for i := range metrics {
+		metrics[i].ServerVersion = version
+	}
+	if err := exportMetrics(metrics); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to export the results to %s: %v\n", exportPath, err)
+		os.Exit(1)
+	}
+}
+
+func newExportedMetric(benchmark, metric string, stats report.Stats) exportedMetric {
+	em := exportedMetric{
+		Benchmark:   benchmark,
+		Metric:      metric,
+		Time:        time.Now().UTC(),
+		Count:       len(stats.Lats),
+		RPS:         stats.RPS,
+		Average:     stats.Average,
+		Stddev:      stats.Stddev,
+		Fastest:     stats.Fastest,
+		Slowest:     stats.Slowest,
+		Percentiles: make(map[string]float64),
+	}
+	for _, n := range stats.ErrorDist {
+		em.Errors += n
+	}
+	if len(stats.Lats) == 0 {
+		// the averages of no latencies are not numbers
+		em.Average, em.Stddev = 0, 0
+	}
+	pcs, data := report.Percentiles(stats.Lats)
+	for i := range pcs {
+		em.Percentiles[percentileName(pcs[i])] = data[i]
+	}
+	return em
+}
+
+func percentileName(pc float64) string {
+	return "p" + strconv.FormatFloat(pc, 'f', -1, 64)
+}
+
+// serverVersion returns the version of the server of the first endpoint the
+// results are exported for, "unknown" if not reachable.
+func serverVersion() string {
+	c := mustCreateConn()
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := c.Status(ctx, c.Endpoints()[0])
+	if err != nil {
+		return "unknown"
+	}
+	return resp.Version
+}
+
+func exportMetrics(metrics []exportedMetric) error {
+	f, err := os.Create(exportPath)
+	if err != nil {
+		return err
+	}
+	switch exportFormat {
+	case "json":
+		err = exportJSON(f, metrics)
+	case "csv":
+		err = exportCSV(f, metrics)
+	default:
+		err = fmt.Errorf("unknown export format %q", exportFormat)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func exportJSON(w io.Writer, metrics []exportedMetric) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(metrics)
+}
+
+// exportCSV writes a row per metric, the percentiles in columns.
+func exportCSV(w io.Writer, metrics []exportedMetric) error {
+	pcs, _ := report.Percentiles(nil)
+	header := []string{"benchmark", "metric", "server_version", "time", "count", "errors", "rps", "average", "stddev", "fastest", "slowest"}
+	for _, pc := range pcs {
+		header = append(header, percentileName(pc))
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	ftoa := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	for _, m := range metrics {
+		row := []string{
+			m.Benchmark, m.Metric, m.ServerVersion, m.Time.Format(time.RFC3339),
+			strconv.Itoa(m.Count), strconv.Itoa(m.Errors),
+			ftoa(m.RPS), ftoa(m.Average), ftoa(m.Stddev), ftoa(m.Fastest), ftoa(m.Slowest),
+		}
+		for _, pc := range pcs {
+			row = append(row, ftoa(m.Percentiles[percentileName(pc)]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
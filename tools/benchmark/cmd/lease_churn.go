@@ -0,0 +1,125 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/report"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+var leaseChurnCmd = &cobra.Command{
+	Use:   "lease-churn",
+	Short: "Benchmark lease churn",
+	Long: `Benchmarks the churn of short-lived leases, each lease being
+	granted, attached to keys, kept alive and revoked, measuring the
+	latency of each of these requests.`,
+	Run: leaseChurnFunc,
+}
+
+var (
+	leaseChurnTotal        int
+	leaseChurnRate         int
+	leaseChurnTTL          int64
+	leaseChurnKeysPerLease int
+	leaseChurnKeepalives   int
+	leaseChurnValueSize    int
+)
+
+func init() {
+	RootCmd.AddCommand(leaseChurnCmd)
+	leaseChurnCmd.Flags().IntVar(&leaseChurnTotal, "total", 10000, "Total number of leases")
+	leaseChurnCmd.Flags().IntVar(&leaseChurnRate, "rate", 0, "Maximum leases granted per second (0 is no limit)")
+	leaseChurnCmd.Flags().Int64Var(&leaseChurnTTL, "ttl", 10, "TTL of the leases, in seconds")
+	leaseChurnCmd.Flags().IntVar(&leaseChurnKeysPerLease, "keys-per-lease", 1, "Number of keys attached to each lease")
+	leaseChurnCmd.Flags().IntVar(&leaseChurnKeepalives, "keepalives", 1, "Number of keepalive requests of each lease")
+	leaseChurnCmd.Flags().IntVar(&leaseChurnValueSize, "val-size", 8, "Value size of the keys attached")
+}
+
+func leaseChurnFunc(cmd *cobra.Command, args []string) {
+	mustValidateExport()
+
+	requests := make(chan int)
+	clients := mustCreateClients(totalClients, totalConns)
+	value := string(mustRandBytes(leaseChurnValueSize))
+
+	bar = pb.New(leaseChurnTotal)
+	bar.Start()
+
+	grantR, putR := newMetricReport("grant"), newMetricReport("put")
+	keepaliveR, revokeR := newMetricReport("keepalive"), newMetricReport("revoke")
+	for i := range clients {
+		wg.Add(1)
+		go func(c *v3.Client) {
+			defer wg.Done()
+			for n := range requests {
+				st := time.Now()
+				resp, err := c.Grant(context.TODO(), leaseChurnTTL)
+				grantR.Results() <- report.Result{Err: err, Start: st, End: time.Now()}
+				if err != nil {
+					bar.Increment()
+					continue
+				}
+				for k := 0; k < leaseChurnKeysPerLease; k++ {
+					st = time.Now()
+					_, err = c.Put(context.TODO(), fmt.Sprintf("lease-churn-%d-%d", n, k), value, v3.WithLease(resp.ID))
+					putR.Results() <- report.Result{Err: err, Start: st, End: time.Now()}
+				}
+				for k := 0; k < leaseChurnKeepalives; k++ {
+					st = time.Now()
+					_, err = c.KeepAliveOnce(context.TODO(), resp.ID)
+					keepaliveR.Results() <- report.Result{Err: err, Start: st, End: time.Now()}
+				}
+				st = time.Now()
+				_, err = c.Revoke(context.TODO(), resp.ID)
+				revokeR.Results() <- report.Result{Err: err, Start: st, End: time.Now()}
+				bar.Increment()
+			}
+		}(clients[i])
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		limit := rate.Inf
+		if leaseChurnRate > 0 {
+			limit = rate.Limit(leaseChurnRate)
+		}
+		limiter := rate.NewLimiter(limit, 1)
+		for i := 0; i < leaseChurnTotal; i++ {
+			if err := limiter.Wait(context.TODO()); err != nil {
+				break
+			}
+			requests <- i
+		}
+		close(requests)
+	}()
+
+	wg.Wait()
+	close(grantR.Results())
+	close(putR.Results())
+	close(keepaliveR.Results())
+	close(revokeR.Results())
+	bar.Finish()
+
+	printAndExport("lease-churn", grantR, putR, keepaliveR, revokeR)
+}
@@ -55,6 +55,9 @@ var (
 
 	targetLeader     bool
 	autoSyncInterval time.Duration
+
+	exportPath   string
+	exportFormat string
 )
 
 func init() {
@@ -73,4 +76,7 @@ func init() {
 
 	RootCmd.PersistentFlags().BoolVar(&targetLeader, "target-leader", false, "connect only to the leader node")
 	RootCmd.PersistentFlags().DurationVar(&autoSyncInterval, "auto-sync-interval", time.Duration(0), "AutoSyncInterval is the interval to update endpoints with its latest members")
+
+	RootCmd.PersistentFlags().StringVar(&exportPath, "export-path", "", "path of the file the latency percentiles of the watch-fanout and lease-churn benchmarks are exported to")
+	RootCmd.PersistentFlags().StringVar(&exportFormat, "export-format", "json", "format of the exported results, 'json' or 'csv'")
 }
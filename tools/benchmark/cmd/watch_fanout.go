@@ -0,0 +1,141 @@
+// Copyright 2022 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/pkg/v3/report"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+// watchFanoutCmd represents the watch fan-out command
+var watchFanoutCmd = &cobra.Command{
+	Use:   "watch-fanout",
+	Short: "Benchmark watch event fan-out",
+	Long: `Benchmarks the delivery of the events of a few keys to many
+	watchers, measuring the latency between sending a put and each
+	watcher of the key receiving its event.`,
+	Run: watchFanoutFunc,
+}
+
+var (
+	watchFTotal        int
+	watchFWatchers     int
+	watchFKeys         int
+	watchFPutRate      int
+	watchFValueSize    int
+	watchFEventTimeout time.Duration
+)
+
+func init() {
+	RootCmd.AddCommand(watchFanoutCmd)
+	watchFanoutCmd.Flags().IntVar(&watchFTotal, "total", 1000, "Total number of put requests")
+	watchFanoutCmd.Flags().IntVar(&watchFWatchers, "watchers", 1000, "Total number of watchers, spread over the keys")
+	watchFanoutCmd.Flags().IntVar(&watchFKeys, "keys", 10, "Number of keys put and watched")
+	watchFanoutCmd.Flags().IntVar(&watchFPutRate, "put-rate", 100, "Number of keys to put per second")
+	watchFanoutCmd.Flags().IntVar(&watchFValueSize, "val-size", 32, "Value size of watch response, at least 8 bytes")
+	watchFanoutCmd.Flags().DurationVar(&watchFEventTimeout, "event-timeout", 10*time.Second, "Time to wait for the events of the last puts")
+}
+
+func watchFanoutFunc(cmd *cobra.Command, args []string) {
+	if watchFKeys <= 0 || watchFWatchers < watchFKeys || watchFValueSize < 8 {
+		fmt.Fprintln(os.Stderr, "expected at least one key, a watcher per key and values of at least 8 bytes")
+		os.Exit(1)
+	}
+	mustValidateExport()
+
+	keys := make([]string, watchFKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("watch-fanout-%d", i)
+	}
+	clients := mustCreateClients(totalClients, totalConns)
+	putClient := mustCreateConn()
+
+	putR, eventR := newMetricReport("put"), newMetricReport("event")
+
+	// the watchers are created before the first put, not to miss its event
+	ctx, cancel := context.WithCancel(context.Background())
+	var received int64
+	var watchWg sync.WaitGroup
+	watchersOf := make([]int, len(keys))
+	for i := 0; i < watchFWatchers; i++ {
+		wch := clients[i%len(clients)].Watch(ctx, keys[i%len(keys)], clientv3.WithCreatedNotify())
+		if resp := <-wch; resp.Err() != nil || !resp.Created {
+			fmt.Fprintf(os.Stderr, "Failed to create watcher for watch fan-out benchmark: %v\n", resp.Err())
+			os.Exit(1)
+		}
+		watchersOf[i%len(keys)]++
+		watchWg.Add(1)
+		go func() {
+			defer watchWg.Done()
+			for resp := range wch {
+				end := time.Now()
+				for _, ev := range resp.Events {
+					// the time of the put is the head of the value
+					st := time.Unix(0, int64(binary.BigEndian.Uint64(ev.Kv.Value)))
+					eventR.Results() <- report.Result{Start: st, End: end}
+				}
+				atomic.AddInt64(&received, int64(len(resp.Events)))
+			}
+		}()
+	}
+
+	bar = pb.New(watchFTotal)
+	bar.Start()
+
+	limiter := rate.NewLimiter(rate.Limit(watchFPutRate), 1)
+	value := make([]byte, watchFValueSize)
+	var expected int64
+	for i := 0; i < watchFTotal; i++ {
+		if err := limiter.Wait(context.TODO()); err != nil {
+			break
+		}
+		st := time.Now()
+		binary.BigEndian.PutUint64(value, uint64(st.UnixNano()))
+		_, err := putClient.Put(context.TODO(), keys[i%len(keys)], string(value))
+		putR.Results() <- report.Result{Err: err, Start: st, End: time.Now()}
+		if err == nil {
+			expected += int64(watchersOf[i%len(keys)])
+		}
+		bar.Increment()
+	}
+
+	for deadline := time.Now().Add(watchFEventTimeout); atomic.LoadInt64(&received) < expected && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	watchWg.Wait()
+	// the events not received are accounted as errors
+	errMissed := fmt.Errorf("event not received in %v", watchFEventTimeout)
+	for missed := expected - atomic.LoadInt64(&received); missed > 0; missed-- {
+		eventR.Results() <- report.Result{Err: errMissed}
+	}
+	close(putR.Results())
+	close(eventR.Results())
+	bar.Finish()
+
+	printAndExport("watch-fanout", putR, eventR)
+}